@@ -0,0 +1,32 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+const (
+	// HumioDriftPolicyEnforce reverts changes made to a Humio entity outside the operator, e.g. in the Humio UI
+	HumioDriftPolicyEnforce = "Enforce"
+	// HumioDriftPolicyWarn leaves changes made to a Humio entity outside the operator in place until the spec of the
+	// resource changes, and only reports them through the Drifted condition and an event
+	HumioDriftPolicyWarn = "Warn"
+	// HumioDriftPolicyImport behaves like HumioDriftPolicyWarn, and also records a JSON merge patch in the status which
+	// imports the changes into the spec of the resource, so they can be captured, e.g. in Git, rather than lost
+	HumioDriftPolicyImport = "Import"
+
+	// HumioDriftedConditionType is the type of the condition which is True while a Humio entity has been changed
+	// outside the operator and the changes are left in place
+	HumioDriftedConditionType = "Drifted"
+)
@@ -0,0 +1,49 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// Reasons reported in the status of Humio entities when they cannot be reconciled, so automation can react differently
+// to different classes of failures
+const (
+	// HumioErrorReasonAuthFailed means the Humio API rejected the API token, or the token lacks the permissions needed
+	HumioErrorReasonAuthFailed = "AuthFailed"
+	// HumioErrorReasonClusterUnreachable means the Humio cluster could not be reached
+	HumioErrorReasonClusterUnreachable = "ClusterUnreachable"
+	// HumioErrorReasonInvalidQuery means Humio rejected a query of the resource
+	HumioErrorReasonInvalidQuery = "InvalidQuery"
+	// HumioErrorReasonReferencedActionMissing means an alert refers to an action which does not exist
+	HumioErrorReasonReferencedActionMissing = "ReferencedActionMissing"
+	// HumioErrorReasonQuotaExceeded means Humio rejected a request because a rate limit or quota was exceeded
+	HumioErrorReasonQuotaExceeded = "QuotaExceeded"
+	// HumioErrorReasonConfigError means the spec of the resource is invalid, e.g. it refers to a cluster which does not
+	// exist
+	HumioErrorReasonConfigError = "ConfigError"
+	// HumioErrorReasonUnknown is reported for failures which do not fall into any of the other classes
+	HumioErrorReasonUnknown = "Unknown"
+)
+
+// The ClusterAvailable condition reports whether the Humio cluster of an entity can be reached, so it can be waited for
+// with kubectl wait and alerted on
+const (
+	// HumioClusterAvailableConditionType is the type of the condition which is False while the Humio cluster of an
+	// entity cannot be reached, e.g. while calls against the cluster fail fast after repeated connection failures
+	HumioClusterAvailableConditionType = "ClusterAvailable"
+	// HumioClusterAvailableReason is the reason of the ClusterAvailable condition while the cluster can be reached
+	HumioClusterAvailableReason = "ClusterReachable"
+	// HumioClusterUnavailableReason is the reason of the ClusterAvailable condition while the cluster cannot be reached
+	HumioClusterUnavailableReason = "ClusterUnavailable"
+)
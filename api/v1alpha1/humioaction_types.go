@@ -30,6 +30,8 @@ const (
 	HumioActionStateNotFound = "NotFound"
 	// HumioActionStateConfigError is the state of the action when user-provided specification results in configuration error, such as non-existent humio cluster
 	HumioActionStateConfigError = "ConfigError"
+	// HumioActionStateClusterUnavailable is the state of the action when the Humio cluster it targets cannot be reached
+	HumioActionStateClusterUnavailable = "ClusterUnavailable"
 )
 
 // HumioActionWebhookProperties defines the desired state of HumioActionWebhookProperties
@@ -40,6 +42,26 @@ type HumioActionWebhookProperties struct {
 	Url          string            `json:"url,omitempty"`
 	IgnoreSSL    bool              `json:"ignoreSSL,omitempty"`
 	UseProxy     bool              `json:"useProxy,omitempty"`
+	// DeliveryVerification makes the operator periodically send a probe to the webhook, and record whether it is
+	// reachable in the status of the action
+	DeliveryVerification *HumioActionWebhookDeliveryVerification `json:"deliveryVerification,omitempty"`
+}
+
+// HumioActionWebhookDeliveryVerification defines how the operator verifies a webhook is reachable. The probe is sent
+// with the method and headers of the webhook, and an X-Humio-Operator-Probe header to tell it apart from
+// notifications. Humio does not expose the retry policy it applies to notifications, so Retries and BackoffSeconds
+// only apply to the probe.
+type HumioActionWebhookDeliveryVerification struct {
+	// IntervalSeconds is how often the probe is sent. Defaults to 300.
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+	// SigningKeySource is used to obtain the key the probe is signed with from a secret. The signature is sent in the
+	// X-Humio-Operator-Signature header as "sha256=" followed by the hex encoded HMAC-SHA256 of the body.
+	// The probe is not signed when this is not set.
+	SigningKeySource VarSource `json:"signingKeySource,omitempty"`
+	// Retries is how many times a failed probe is retried before the webhook is recorded as unreachable. Defaults to 2.
+	Retries *int `json:"retries,omitempty"`
+	// BackoffSeconds is the delay before the first retry, which doubles for every following retry. Defaults to 1.
+	BackoffSeconds int `json:"backoffSeconds,omitempty"`
 }
 
 // HumioActionEmailProperties defines the desired state of HumioActionEmailProperties
@@ -58,10 +80,26 @@ type HumioActionRepositoryProperties struct {
 
 // HumioActionOpsGenieProperties defines the desired state of HumioActionOpsGenieProperties
 type HumioActionOpsGenieProperties struct {
-	ApiUrl         string    `json:"apiUrl,omitempty"`
-	GenieKey       string    `json:"genieKey,omitempty"`
+	ApiUrl   string `json:"apiUrl,omitempty"`
+	GenieKey string `json:"genieKey,omitempty"`
+	// GenieKeySource is used to obtain the API key of the OpsGenie integration from a secret instead of GenieKey
 	GenieKeySource VarSource `json:"genieKeySource,omitempty"`
 	UseProxy       bool      `json:"useProxy,omitempty"`
+	// Priority is the priority of the OpsGenie alerts, one of P1, P2, P3, P4 or P5. OpsGenie defaults to P3.
+	Priority string `json:"priority,omitempty"`
+	// Tags are added to the OpsGenie alerts
+	Tags []string `json:"tags,omitempty"`
+	// Responders are the teams, users, escalations and schedules the OpsGenie alerts are routed to, instead of the
+	// responders of the OpsGenie integration
+	Responders []HumioActionOpsGenieResponder `json:"responders,omitempty"`
+}
+
+// HumioActionOpsGenieResponder identifies a responder an OpsGenie alert is routed to
+type HumioActionOpsGenieResponder struct {
+	// Type is the type of the responder, one of team, user, escalation or schedule
+	Type string `json:"type"`
+	// Name is the name of the team, escalation or schedule, or the username of the user
+	Name string `json:"name"`
 }
 
 // HumioActionPagerDutyProperties defines the desired state of HumioActionPagerDutyProperties
@@ -95,7 +133,38 @@ type VarSource struct {
 type HumioActionVictorOpsProperties struct {
 	MessageType string `json:"messageType,omitempty"`
 	NotifyUrl   string `json:"notifyUrl,omitempty"`
-	UseProxy    bool   `json:"useProxy,omitempty"`
+	// NotifyUrlSource is used to obtain the notify URL from a secret instead of NotifyUrl, as the URL contains the
+	// API key of the integration
+	NotifyUrlSource VarSource `json:"notifyUrlSource,omitempty"`
+	// RoutingKey is appended to the notify URL to route the incidents, which allows keeping the routing key out of
+	// the notify URL
+	RoutingKey string `json:"routingKey,omitempty"`
+	// RoutingKeySource is used to obtain the routing key from a secret instead of RoutingKey
+	RoutingKeySource VarSource `json:"routingKeySource,omitempty"`
+	UseProxy         bool      `json:"useProxy,omitempty"`
+}
+
+// HumioActionFallback escalates to another action when the action fails to deliver a notification. Humio does not
+// support fallback actions, so the operator manages a relay alert which triggers the fallback action whenever Humio
+// logs a failure of this action to the humio-activity repository.
+type HumioActionFallback struct {
+	// ActionName is the name of the Humio action triggered when this action fails. It must exist in RelayViewName.
+	// Fallbacks can be chained by giving the fallback action a fallback of its own.
+	ActionName string `json:"actionName"`
+	// RelayViewName is the view or repository the relay alert is created in. It must be able to search the
+	// humio-activity repository. Defaults to "humio-activity".
+	RelayViewName string `json:"relayViewName,omitempty"`
+	// ThrottleTimeMillis is the throttle time of the relay alert in milliseconds, which limits how often the fallback
+	// action is triggered. Defaults to 300000.
+	ThrottleTimeMillis int `json:"throttleTimeMillis,omitempty"`
+}
+
+// HumioActionTemplateReference refers to a HumioActionTemplate whose message templates are inlined into the action
+type HumioActionTemplateReference struct {
+	// Name is the name of the HumioActionTemplate, which must be in the namespace of the HumioAction
+	Name string `json:"name"`
+	// Parameters are substituted into the templates of the HumioActionTemplate
+	Parameters map[string]string `json:"parameters,omitempty"`
 }
 
 // HumioActionSpec defines the desired state of HumioAction
@@ -107,6 +176,10 @@ type HumioActionSpec struct {
 	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
 	// This conflicts with ManagedClusterName.
 	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
 	// Name is the name of the Action
 	Name string `json:"name"`
 	// ViewName is the name of the Humio View under which the Action will be managed. This can also be a Repository
@@ -127,16 +200,74 @@ type HumioActionSpec struct {
 	VictorOpsProperties *HumioActionVictorOpsProperties `json:"victorOpsProperties,omitempty"`
 	// WebhookProperties indicates this is a Webhook Action, and contains the corresponding properties
 	WebhookProperties *HumioActionWebhookProperties `json:"webhookProperties,omitempty"`
+	// TemplateRef inlines the message templates of a HumioActionTemplate into the properties of the action. Values
+	// set in the properties of the action take precedence over the template.
+	TemplateRef *HumioActionTemplateReference `json:"templateRef,omitempty"`
+	// Fallback escalates to another action when this action fails to deliver a notification
+	Fallback *HumioActionFallback `json:"fallback,omitempty"`
+	// DriftPolicy controls what happens when the action is changed outside the operator, e.g. in the Humio UI. Enforce
+	// reverts the changes, while Warn leaves them in place until the spec changes, and records a Drifted condition
+	// and event. Defaults to Enforce.
+	//+kubebuilder:validation:Enum=Enforce;Warn
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+}
+
+// HumioActionFallbackRelayStatus identifies the relay alert managed for the fallback of an action
+type HumioActionFallbackRelayStatus struct {
+	// ViewName is the name of the view the relay alert was created in
+	ViewName string `json:"viewName"`
+	// AlertName is the name of the relay alert
+	AlertName string `json:"alertName"`
 }
 
 // HumioActionStatus defines the observed state of HumioAction
 type HumioActionStatus struct {
 	// State reflects the current state of the HumioAction
 	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the action failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the action is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// ID is the ID of the action inside Humio
+	ID string `json:"id,omitempty"`
+	// ClusterName is the name of the managed or external cluster the action is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the action was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// LastResyncRequest is the value of the humio.com/trigger-resync annotation when the action was last resynced
+	LastResyncRequest string `json:"lastResyncRequest,omitempty"`
+	// FallbackRelay is the relay alert managed for the fallback of the action
+	FallbackRelay *HumioActionFallbackRelayStatus `json:"fallbackRelay,omitempty"`
+	// DeliveryVerification is the outcome of the last probe sent to the webhook of the action
+	DeliveryVerification *HumioActionDeliveryVerificationStatus `json:"deliveryVerification,omitempty"`
+	// AppliedHash is a hash of the desired state of the action which was last applied, which is used to tell changes to
+	// the spec apart from changes made outside the operator
+	AppliedHash string `json:"appliedHash,omitempty"`
+	// Conditions contains the conditions of the action. The Drifted condition is True while changes made outside the
+	// operator are left in place because of the Warn drift policy.
+	// The ClusterAvailable condition is False while the Humio cluster cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// HumioActionDeliveryVerificationStatus is the outcome of a probe sent to the webhook of an action
+type HumioActionDeliveryVerificationStatus struct {
+	// Reachable is whether the webhook responded to the probe with a successful status code
+	Reachable bool `json:"reachable"`
+	// StatusCode is the status code the webhook responded with, if it responded
+	StatusCode int `json:"statusCode,omitempty"`
+	// Message contains the reason the webhook is unreachable
+	Message string `json:"message,omitempty"`
+	// LastProbeTime is when the probe was sent
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the action"
+//+kubebuilder:printcolumn:name="ID",type="string",JSONPath=".status.id",description="The ID of the action inside Humio"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the action is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the action was last synced successfully"
 
 // HumioAction is the Schema for the humioactions API
 type HumioAction struct {
@@ -0,0 +1,61 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HumioActionTemplateSpec defines the desired state of HumioActionTemplate. The templates are rendered as Go
+// templates using the parameters of the referencing action, e.g. {{ .team }}, before being inlined into the action.
+// Humio message placeholders such as {alert_name} are passed through unchanged.
+type HumioActionTemplateSpec struct {
+	// BodyTemplate is used as the body template of Email and Webhook actions referencing the template
+	BodyTemplate string `json:"bodyTemplate,omitempty"`
+	// SubjectTemplate is used as the subject template of Email actions referencing the template
+	SubjectTemplate string `json:"subjectTemplate,omitempty"`
+	// Fields are merged into the fields of Slack and Slack Post Message actions referencing the template
+	Fields map[string]string `json:"fields,omitempty"`
+	// Headers are merged into the headers of Webhook actions referencing the template
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=humioactiontemplates,scope=Namespaced
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Action Template"
+
+// HumioActionTemplate is the Schema for the humioactiontemplates API. It holds message templates shared by the
+// HumioActions referencing it.
+type HumioActionTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HumioActionTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioActionTemplateList contains a list of HumioActionTemplate
+type HumioActionTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioActionTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioActionTemplate{}, &HumioActionTemplateList{})
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioAggregateAlertStateUnknown is the Unknown state of the aggregate alert
+	HumioAggregateAlertStateUnknown = "Unknown"
+	// HumioAggregateAlertStateExists is the Exists state of the aggregate alert
+	HumioAggregateAlertStateExists = "Exists"
+	// HumioAggregateAlertStateNotFound is the NotFound state of the aggregate alert
+	HumioAggregateAlertStateNotFound = "NotFound"
+	// HumioAggregateAlertStateConfigError is the state of the aggregate alert when user-provided specification results in configuration error, such as non-existent humio cluster
+	HumioAggregateAlertStateConfigError = "ConfigError"
+	// HumioAggregateAlertStateClusterUnavailable is the state of the aggregate alert when the Humio cluster it targets cannot be reached
+	HumioAggregateAlertStateClusterUnavailable = "ClusterUnavailable"
+)
+
+const (
+	// HumioTriggerModeComplete triggers the aggregate alert once the search interval is complete, i.e. on complete buckets
+	HumioTriggerModeComplete = "CompleteMode"
+	// HumioTriggerModeImmediate triggers the aggregate alert as soon as the query has results, before the search interval
+	// is complete
+	HumioTriggerModeImmediate = "ImmediateMode"
+
+	// HumioQueryTimestampTypeEvent runs the query of the aggregate alert on the timestamps of the events
+	HumioQueryTimestampTypeEvent = "EventTimestamp"
+	// HumioQueryTimestampTypeIngest runs the query of the aggregate alert on the time the events were ingested
+	HumioQueryTimestampTypeIngest = "IngestTimestamp"
+)
+
+// HumioAggregateAlertSpec defines the desired state of HumioAggregateAlert
+type HumioAggregateAlertSpec struct {
+	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator where the Humio
+	// resources should be created.
+	// This conflicts with ExternalClusterName.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
+	// This conflicts with ManagedClusterName.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// Name is the name of the aggregate alert inside Humio
+	Name string `json:"name"`
+	// ViewName is the name of the Humio View under which the aggregate alert will be managed. This can also be a Repository
+	ViewName string `json:"viewName"`
+	// Description is the description of the aggregate alert
+	Description string `json:"description,omitempty"`
+	// QueryString is the Humio query that will trigger the aggregate alert
+	QueryString string `json:"queryString"`
+	// SearchIntervalSeconds is the time span in seconds the query searches each time it runs. Defaults to 3600
+	//+kubebuilder:validation:Minimum=60
+	SearchIntervalSeconds int `json:"searchIntervalSeconds,omitempty"`
+	// QueryTimestampType is whether the query searches by the timestamps of the events or by the time they were
+	// ingested. Defaults to EventTimestamp
+	//+kubebuilder:validation:Enum=EventTimestamp;IngestTimestamp
+	QueryTimestampType string `json:"queryTimestampType,omitempty"`
+	// TriggerMode is whether the aggregate alert triggers once the search interval is complete, or as soon as the
+	// query has results. CompleteMode waits for complete buckets, like the default of the Humio UI. Defaults to
+	// CompleteMode
+	//+kubebuilder:validation:Enum=CompleteMode;ImmediateMode
+	TriggerMode string `json:"triggerMode,omitempty"`
+	// ThrottleTimeSeconds is the throttle time in seconds. The aggregate alert is triggered at most once per the
+	// throttle time. Defaults to the search interval
+	ThrottleTimeSeconds int `json:"throttleTimeSeconds,omitempty"`
+	// ThrottleField is the field on which to throttle
+	ThrottleField string `json:"throttleField,omitempty"`
+	// Silenced will set the aggregate alert to enabled when set to false
+	Silenced bool `json:"silenced,omitempty"`
+	// Actions is the list of Humio Actions by name that will be triggered by this aggregate alert
+	Actions []string `json:"actions"`
+	// Labels are a set of labels on the aggregate alert
+	Labels []string `json:"labels,omitempty"`
+	// DriftPolicy controls what happens when the aggregate alert is changed outside the operator, e.g. in the Humio UI.
+	// Enforce reverts the changes, while Warn leaves them in place until the spec changes, and records a Drifted
+	// condition and event. Import behaves like Warn, and also records a patch importing the changes into the spec in
+	// status.driftPatch. Defaults to Enforce.
+	//+kubebuilder:validation:Enum=Enforce;Warn;Import
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+}
+
+// HumioAggregateAlertStatus defines the observed state of HumioAggregateAlert
+type HumioAggregateAlertStatus struct {
+	// State reflects the current state of the HumioAggregateAlert
+	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the aggregate alert failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the aggregate alert is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// ID is the ID of the aggregate alert inside Humio
+	ID string `json:"id,omitempty"`
+	// ClusterName is the name of the managed or external cluster the aggregate alert is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the aggregate alert was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// LastResyncRequest is the value of the humio.com/trigger-resync annotation when the aggregate alert was last resynced
+	LastResyncRequest string `json:"lastResyncRequest,omitempty"`
+	// AppliedHash is a hash of the desired state of the aggregate alert which was last applied, which is used to tell
+	// changes to the spec apart from changes made outside the operator
+	AppliedHash string `json:"appliedHash,omitempty"`
+	// SyncedGeneration is the generation of the aggregate alert when it was last synced successfully. While the
+	// generation and the applied hash are unchanged, the aggregate alert is only compared with Humio every ten minutes
+	// to detect changes made outside the operator.
+	SyncedGeneration int64 `json:"syncedGeneration,omitempty"`
+	// DriftPatch is a JSON merge patch which imports the changes made to the aggregate alert outside the operator into
+	// the spec. It is only set while the Drifted condition is True and the drift policy is Import.
+	DriftPatch string `json:"driftPatch,omitempty"`
+	// Conditions contains the conditions of the aggregate alert. The Drifted condition is True while changes made
+	// outside the operator are left in place because of the Warn drift policy.
+	// The ClusterAvailable condition is False while the Humio cluster cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioaggregatealerts,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the aggregate alert"
+//+kubebuilder:printcolumn:name="ID",type="string",JSONPath=".status.id",description="The ID of the aggregate alert inside Humio"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the aggregate alert is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the aggregate alert was last synced successfully"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Aggregate Alert"
+
+// HumioAggregateAlert is the Schema for the humioaggregatealerts API
+type HumioAggregateAlert struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioAggregateAlertSpec   `json:"spec,omitempty"`
+	Status HumioAggregateAlertStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioAggregateAlertList contains a list of HumioAggregateAlert
+type HumioAggregateAlertList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioAggregateAlert `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioAggregateAlert{}, &HumioAggregateAlertList{})
+}
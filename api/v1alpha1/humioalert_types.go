@@ -29,9 +29,14 @@ const (
 	HumioAlertStateNotFound = "NotFound"
 	// HumioAlertStateConfigError is the state of the alert when user-provided specification results in configuration error, such as non-existent humio cluster
 	HumioAlertStateConfigError = "ConfigError"
+	// HumioAlertStateClusterUnavailable is the state of the alert when the Humio cluster it targets cannot be reached
+	HumioAlertStateClusterUnavailable = "ClusterUnavailable"
 )
 
-// HumioQuery defines the desired state of the Humio query
+// HumioQuery defines the desired state of the Humio query.
+// Humio does not support setting a time zone or a trigger mode on alerts of this type. They always trigger on the
+// results of the live query as they arrive. Use HumioAggregateAlert to choose the trigger mode, or
+// HumioScheduledSearch to set the time zone.
 type HumioQuery struct {
 	// QueryString is the Humio query that will trigger the alert
 	QueryString string `json:"queryString"`
@@ -54,6 +59,10 @@ type HumioAlertSpec struct {
 	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
 	// This conflicts with ManagedClusterName.
 	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
 	// Name is the name of the alert inside Humio
 	Name string `json:"name"`
 	// ViewName is the name of the Humio View under which the Alert will be managed. This can also be a Repository
@@ -72,16 +81,51 @@ type HumioAlertSpec struct {
 	Actions []string `json:"actions"`
 	// Labels are a set of labels on the Alert
 	Labels []string `json:"labels,omitempty"`
+	// DriftPolicy controls what happens when the alert is changed outside the operator, e.g. in the Humio UI. Enforce
+	// reverts the changes, while Warn leaves them in place until the spec changes, and records a Drifted condition
+	// and event. Import behaves like Warn, and also records a patch importing the changes into the spec in
+	// status.driftPatch. Defaults to Enforce.
+	//+kubebuilder:validation:Enum=Enforce;Warn;Import
+	DriftPolicy string `json:"driftPolicy,omitempty"`
 }
 
 // HumioAlertStatus defines the observed state of HumioAlert
 type HumioAlertStatus struct {
 	// State reflects the current state of the HumioAlert
 	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the alert failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the alert is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// ID is the ID of the alert inside Humio
+	ID string `json:"id,omitempty"`
+	// ClusterName is the name of the managed or external cluster the alert is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the alert was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// LastResyncRequest is the value of the humio.com/trigger-resync annotation when the alert was last resynced
+	LastResyncRequest string `json:"lastResyncRequest,omitempty"`
+	// Silences lists the active HumioAlertSilence resources which disable the alert
+	Silences []string `json:"silences,omitempty"`
+	// AppliedHash is a hash of the desired state of the alert which was last applied, which is used to tell changes to
+	// the spec apart from changes made outside the operator
+	AppliedHash string `json:"appliedHash,omitempty"`
+	// DriftPatch is a JSON merge patch which imports the changes made to the alert outside the operator into the spec.
+	// It is only set while the Drifted condition is True and the drift policy is Import.
+	DriftPatch string `json:"driftPatch,omitempty"`
+	// Conditions contains the conditions of the alert. The Drifted condition is True while changes made outside the
+	// operator are left in place because of the Warn drift policy.
+	// The ClusterAvailable condition is False while the Humio cluster cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the alert"
+//+kubebuilder:printcolumn:name="ID",type="string",JSONPath=".status.id",description="The ID of the alert inside Humio"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the alert is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the alert was last synced successfully"
 
 // HumioAlert is the Schema for the humioalerts API
 type HumioAlert struct {
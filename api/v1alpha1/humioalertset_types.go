@@ -0,0 +1,121 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioAlertSetStateReady is the state of the alert set when all of its alerts exist in Humio
+	HumioAlertSetStateReady = "Ready"
+	// HumioAlertSetStatePending is the state of the alert set while alerts are being created or updated
+	HumioAlertSetStatePending = "Pending"
+	// HumioAlertSetStateDegraded is the state of the alert set when one or more of its alerts cannot be managed or are
+	// in the ConfigError state
+	HumioAlertSetStateDegraded = "Degraded"
+	// HumioAlertSetStateConfigError is the state of the alert set when user-provided specification results in
+	// configuration error, such as a template which cannot be rendered
+	HumioAlertSetStateConfigError = "ConfigError"
+)
+
+// HumioAlertSetSpec defines the desired state of HumioAlertSet
+type HumioAlertSetSpec struct {
+	// Template is used to create each HumioAlert of the set. The name, view name, description, query, throttle field,
+	// actions and labels of the template are rendered as Go templates with the parameters of each instance, e.g.
+	// {{ .service }}. The name of the instance is available as {{ .name }}.
+	Template HumioAlertSetTemplate `json:"template"`
+	// Instances lists the parameter sets, each of which results in a HumioAlert
+	//+kubebuilder:validation:MinItems=1
+	Instances []HumioAlertSetInstance `json:"instances"`
+}
+
+// HumioAlertSetTemplate describes the HumioAlerts of the set
+type HumioAlertSetTemplate struct {
+	// Labels are added to each HumioAlert
+	Labels map[string]string `json:"labels,omitempty"`
+	// Spec is the specification shared by each HumioAlert
+	Spec HumioAlertSpec `json:"spec"`
+}
+
+// HumioAlertSetInstance describes a single HumioAlert of the set
+type HumioAlertSetInstance struct {
+	// Name identifies the instance. The HumioAlert is named after the HumioAlertSet and the instance, separated by a
+	// dash.
+	Name string `json:"name"`
+	// Parameters are substituted into the template
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// HumioAlertSetAlertStatus describes the state of a single HumioAlert of the set
+type HumioAlertSetAlertStatus struct {
+	// Name is the name of the HumioAlert
+	Name string `json:"name"`
+	// State is the state of the HumioAlert
+	State string `json:"state,omitempty"`
+	// Message contains the reason the HumioAlert could not be managed
+	Message string `json:"message,omitempty"`
+}
+
+// HumioAlertSetStatus defines the observed state of HumioAlertSet
+type HumioAlertSetStatus struct {
+	// State reflects the aggregated state of the alerts of the HumioAlertSet
+	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the alert set failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the alert set is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// Message contains the reason the HumioAlertSet is in the ConfigError state
+	Message string `json:"message,omitempty"`
+	// ReadyAlerts is the number of alerts in the Exists state
+	ReadyAlerts int `json:"readyAlerts,omitempty"`
+	// Alerts contains the state of each alert of the set
+	Alerts []HumioAlertSetAlertStatus `json:"alerts,omitempty"`
+	// Conditions contains the conditions of the alert set. The ClusterAvailable condition is False while the Humio
+	// cluster of the alert set cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioalertsets,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the alert set"
+//+kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyAlerts",description="The number of alerts which exist in Humio"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Alert Set"
+
+// HumioAlertSet is the Schema for the humioalertsets API
+type HumioAlertSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioAlertSetSpec   `json:"spec,omitempty"`
+	Status HumioAlertSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioAlertSetList contains a list of HumioAlertSet
+type HumioAlertSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioAlertSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioAlertSet{}, &HumioAlertSetList{})
+}
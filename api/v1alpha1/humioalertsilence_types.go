@@ -0,0 +1,93 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioAlertSilenceStateActive is the state of the silence while its window is open and the selected alerts are
+	// disabled
+	HumioAlertSilenceStateActive = "Active"
+	// HumioAlertSilenceStateScheduled is the state of the silence while waiting for its window to open
+	HumioAlertSilenceStateScheduled = "Scheduled"
+	// HumioAlertSilenceStateExpired is the state of the silence when its window has closed and never opens again
+	HumioAlertSilenceStateExpired = "Expired"
+	// HumioAlertSilenceStateConfigError is the state of the silence when user-provided specification results in
+	// configuration error, such as an invalid alert selector or window
+	HumioAlertSilenceStateConfigError = "ConfigError"
+)
+
+// HumioAlertSilenceSpec defines the desired state of HumioAlertSilence
+type HumioAlertSilenceSpec struct {
+	// AlertSelector selects the HumioAlert resources in the namespace of the silence which are disabled while the
+	// silence is active
+	AlertSelector metav1.LabelSelector `json:"alertSelector"`
+	// Start is when the silence becomes active. Defaults to when the silence is created.
+	// This conflicts with MaintenanceWindow.
+	Start *metav1.Time `json:"start,omitempty"`
+	// End is when the silence stops being active, after which the selected alerts are enabled again.
+	// This conflicts with MaintenanceWindow.
+	End *metav1.Time `json:"end,omitempty"`
+	// MaintenanceWindow makes the silence active during a recurring window.
+	// This conflicts with Start and End.
+	MaintenanceWindow *HumioMaintenanceWindow `json:"maintenanceWindow,omitempty"`
+	// Comment describes why the alerts are silenced
+	Comment string `json:"comment,omitempty"`
+}
+
+// HumioAlertSilenceStatus defines the observed state of HumioAlertSilence
+type HumioAlertSilenceStatus struct {
+	// State reflects the current state of the HumioAlertSilence
+	State string `json:"state,omitempty"`
+	// Message contains the reason the HumioAlertSilence is in the ConfigError state
+	Message string `json:"message,omitempty"`
+	// Alerts lists the HumioAlert resources selected by the silence
+	Alerts []string `json:"alerts,omitempty"`
+	// NextTransitionTime is when the silence next becomes active or stops being active
+	NextTransitionTime *metav1.Time `json:"nextTransitionTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioalertsilences,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the alert silence"
+//+kubebuilder:printcolumn:name="Next Transition",type="string",JSONPath=".status.nextTransitionTime",description="When the alert silence next becomes active or stops being active"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Alert Silence"
+
+// HumioAlertSilence is the Schema for the humioalertsilences API
+type HumioAlertSilence struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioAlertSilenceSpec   `json:"spec,omitempty"`
+	Status HumioAlertSilenceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioAlertSilenceList contains a list of HumioAlertSilence
+type HumioAlertSilenceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioAlertSilence `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioAlertSilence{}, &HumioAlertSilenceList{})
+}
@@ -41,6 +41,54 @@ const (
 	// HumioClusterUpdateStrategyRollingUpdateBestEffort is the update strategy where the operator will evaluate the Humio version change and determine if the
 	// Humio pods can be updated in a rolling fashion or if they must be replaced at the same time
 	HumioClusterUpdateStrategyRollingUpdateBestEffort = "RollingUpdateBestEffort"
+	// HumioClusterUpdateStrategyBlueGreen is the update strategy where the operator will create a full set of new pods next to the existing pods, validate
+	// them, shift traffic to them and only then remove the existing pods
+	HumioClusterUpdateStrategyBlueGreen = "BlueGreen"
+	// HumioClusterConfigChangePolicyRollingRestart is the config change policy where configuration changes are applied
+	// right away by restarting the affected pods according to the update strategy
+	HumioClusterConfigChangePolicyRollingRestart = "RollingRestart"
+	// HumioClusterConfigChangePolicyDeferred is the config change policy where configuration changes are applied during
+	// the next maintenance window
+	HumioClusterConfigChangePolicyDeferred = "Deferred"
+	// HumioClusterConfigChangePolicyManual is the config change policy where configuration changes are only applied to
+	// pods which are deleted by the user
+	HumioClusterConfigChangePolicyManual = "Manual"
+	// HumioClusterComplianceProfileFIPS is the compliance profile where the workloads generated for the cluster use
+	// FIPS-validated images where available, and only accept TLS 1.2 or newer with FIPS-approved cipher suites
+	HumioClusterComplianceProfileFIPS = "FIPS"
+	// HumioNodeRoleIngest is the node role of Humio nodes which accept ingested data
+	HumioNodeRoleIngest HumioNodeRole = "ingest"
+	// HumioNodeRoleDigest is the node role of Humio nodes which are assigned digest partitions
+	HumioNodeRoleDigest HumioNodeRole = "digest"
+	// HumioNodeRoleQuery is the node role of Humio nodes which coordinate queries and serve the UI and API
+	HumioNodeRoleQuery HumioNodeRole = "query"
+	// HumioNodeRoleStorage is the node role of Humio nodes which are assigned storage partitions
+	HumioNodeRoleStorage HumioNodeRole = "storage"
+	// HumioAffinityPresetNone is the affinity preset which does not add any rules to the affinity of the Humio pods
+	HumioAffinityPresetNone = "None"
+	// HumioAffinityPresetSoftZoneSpread is the affinity preset which prefers spreading the Humio pods of a node pool
+	// across availability zones
+	HumioAffinityPresetSoftZoneSpread = "SoftZoneSpread"
+	// HumioAffinityPresetHardHostAntiAffinity is the affinity preset which requires the Humio pods of a cluster to run
+	// on different Kubernetes worker nodes
+	HumioAffinityPresetHardHostAntiAffinity = "HardHostAntiAffinity"
+	// HumioAuthMigrationStateMigrating is the state of the auth migration while the pods are restarted with the new
+	// authentication method and the migration is verified
+	HumioAuthMigrationStateMigrating = "Migrating"
+	// HumioAuthMigrationStateCompleted is the state of the auth migration once it has been verified
+	HumioAuthMigrationStateCompleted = "Completed"
+	// HumioAuthMigrationStateRolledBack is the state of the auth migration if it could not be verified in time, in
+	// which case the pods are restarted with the previous authentication method
+	HumioAuthMigrationStateRolledBack = "RolledBack"
+	// HumioClusterConditionTypeDegraded is the type of the condition which is True when the cluster is degraded, such
+	// as when its license has expired
+	HumioClusterConditionTypeDegraded = "Degraded"
+	// HumioClusterConditionTypeCrashLooping is the type of the condition which is True when Humio pods of the cluster
+	// are crash-looping
+	HumioClusterConditionTypeCrashLooping = "CrashLooping"
+	// HumioClusterConditionTypeImageVerified is the type of the condition which is True when the Humio images of all
+	// node pools with image verification enabled have been pinned to a digest with a valid signature
+	HumioClusterConditionTypeImageVerified = "ImageVerified"
 	// HumioPersistentVolumeReclaimTypeOnNodeDelete is the persistent volume reclaim type which will remove persistent volume claims when the node to which they
 	// are bound is deleted. Should only be used when running using `USING_EPHEMERAL_DISKS=true`, and typically only when using a persistent volume driver that
 	// binds each persistent volume claim to a specific node (BETA)
@@ -78,6 +126,21 @@ type HumioClusterSpec struct {
 	ESHostnameSource HumioESHostnameSource `json:"esHostnameSource,omitempty"`
 	// Path is the root URI path of the Humio cluster
 	Path string `json:"path,omitempty"`
+	// APITimeouts is used to configure the timeouts used by the operator when communicating with the Humio cluster
+	APITimeouts *HumioAPITimeouts `json:"apiTimeouts,omitempty"`
+	// UsageReporting is used to periodically collect the ingest volume, retained data and license utilization of the
+	// cluster, which are reported in the status of the HumioCluster and as Prometheus metrics of the operator
+	UsageReporting *HumioUsageReporting `json:"usageReporting,omitempty"`
+	// AdminTokenRotation is used to periodically rotate the API token the operator uses to manage the cluster. The
+	// rotation can also be triggered at any time by setting the annotation humio.com/rotate-admin-token on the
+	// HumioCluster to a new value, e.g. the current time, regardless of whether AdminTokenRotation is set.
+	AdminTokenRotation *HumioAdminTokenRotation `json:"adminTokenRotation,omitempty"`
+	// AuthMigration is used to let the operator migrate the cluster to OIDC or SAML authentication, e.g. from single
+	// user authentication. See HumioAuthMigration for the steps of the migration.
+	AuthMigration *HumioAuthMigration `json:"authMigration,omitempty"`
+	// DeadNodeUnregistration is used to let the operator unregister Humio nodes which are unavailable and no longer
+	// backed by a pod, e.g. after a scale-down or the loss of a Kubernetes worker node
+	DeadNodeUnregistration *HumioDeadNodeUnregistration `json:"deadNodeUnregistration,omitempty"`
 	// Ingress is used to set up ingress-related objects in order to reach Humio externally from the kubernetes cluster
 	Ingress HumioClusterIngressSpec `json:"ingress,omitempty"`
 	// TLS is used to define TLS specific configuration such as intra-cluster TLS settings
@@ -88,6 +151,37 @@ type HumioClusterSpec struct {
 	// HumioHeadlessServiceLabels is the set of labels added to the Kubernetes Headless Service that is used for
 	// traffic between Humio pods
 	HumioHeadlessServiceLabels map[string]string `json:"humioHeadlessServiceLabels,omitempty"`
+	// ConfigChangePolicy controls when changes to the configuration of the Humio pods, such as environment variables,
+	// are applied. Upgrades of the Humio version are always applied right away according to the update strategy.
+	// The available values are: RollingRestart, Deferred and Manual.
+	//
+	// When set to RollingRestart, the affected pods are restarted right away according to the update strategy. This is
+	// the default behavior.
+	//
+	// When set to Deferred, configuration changes accumulate and the affected pods are restarted during the next
+	// maintenance window.
+	//
+	// When set to Manual, no pods are restarted. Pods deleted by the user are recreated with the new configuration.
+	//
+	// Pods with errors are always restarted right away, so a broken configuration can be fixed without waiting.
+	//+kubebuilder:validation:Enum=RollingRestart;Deferred;Manual
+	ConfigChangePolicy string `json:"configChangePolicy,omitempty"`
+	// MaintenanceWindow is the time window in which configuration changes are applied when ConfigChangePolicy is set
+	// to Deferred
+	MaintenanceWindow *HumioMaintenanceWindow `json:"maintenanceWindow,omitempty"`
+	// CommonEnvironmentVariables is the set of environment variables applied to the humio container of all node
+	// pools. Environment variables set by a node pool take precedence, so changing a common environment variable only
+	// restarts the node pools for which its effective value changes.
+	CommonEnvironmentVariables []corev1.EnvVar `json:"commonEnvironmentVariables,omitempty"`
+	// ComplianceProfile restricts the workloads generated for the cluster to a compliance standard. The available
+	// value is FIPS.
+	//
+	// When set to FIPS, the Humio and helper containers use the FIPS-validated images configured for the operator
+	// unless an image is set explicitly, and the Humio listeners and ingresses only accept TLS 1.2 or newer with
+	// FIPS-approved cipher suites. The cluster is put into ConfigError when TLS is disabled for the cluster or the
+	// ingress, or when environmentVariables or ingress annotations override the TLS settings of the profile.
+	//+kubebuilder:validation:Enum=FIPS
+	ComplianceProfile string `json:"complianceProfile,omitempty"`
 
 	HumioNodeSpec `json:",inline"`
 
@@ -96,7 +190,9 @@ type HumioClusterSpec struct {
 }
 
 type HumioNodeSpec struct {
-	// Image is the desired humio container image, including the image tag
+	// Image is the desired humio container image, including the image tag. The image may be pinned to a digest by
+	// appending it to the tag, e.g. humio/humio-core:1.82.1@sha256:<digest>. The version of Humio is determined from the
+	// tag, so the tag must be kept when pinning the image to a digest.
 	Image string `json:"image,omitempty"`
 
 	// NodeCount is the desired number of humio cluster nodes
@@ -111,6 +207,15 @@ type HumioNodeSpec struct {
 	// DataVolumeSource is the volume that is mounted on the humio pods. This conflicts with DataVolumePersistentVolumeClaimSpecTemplate.
 	DataVolumeSource corev1.VolumeSource `json:"dataVolumeSource,omitempty"`
 
+	// DataVolumeNodePinning pins each humio pod to the Kubernetes worker node holding its data, so pods which are
+	// replaced during restarts and upgrades keep using the data on the local disks of the worker node. This is intended
+	// for bare-metal deployments using a hostPath DataVolumeSource, and requires it.
+	// The operator keeps track of the worker nodes in status.nodePoolStatus[].dataNodes. A worker node is forgotten when
+	// it is deleted, or when the node pool is scaled down while the worker node is not running any of its pods.
+	// Local persistent volumes used through DataVolumePersistentVolumeClaimSpecTemplate do not need this, as the node
+	// affinity of the persistent volumes already keeps the pods on the worker nodes holding the data.
+	DataVolumeNodePinning bool `json:"dataVolumeNodePinning,omitempty"`
+
 	// AuthServiceAccountName is the name of the Kubernetes Service Account that will be attached to the auth container in the humio pod.
 	AuthServiceAccountName string `json:"authServiceAccountName,omitempty"`
 
@@ -118,6 +223,12 @@ type HumioNodeSpec struct {
 	// This is not recommended, unless you are using auto rebalancing partitions and are running in a single availability zone.
 	DisableInitContainer bool `json:"disableInitContainer,omitempty"`
 
+	// NodeTags maps labels of the Kubernetes worker node to node tags of the Humio node. The init container looks up
+	// the labels when the pod starts, and the Humio container exposes the tags in the NODE_TAGS environment variable as
+	// a comma-separated list of tag=value pairs. A tag named "zone" replaces the availability zone of the Humio node,
+	// which is used for zone-aware partition assignment. This requires the init container.
+	NodeTags []HumioNodeTag `json:"nodeTags,omitempty"`
+
 	// EnvironmentVariablesSource is the reference to an external source of environment variables that will be merged with environmentVariables
 	EnvironmentVariablesSource []corev1.EnvFromSource `json:"environmentVariablesSource,omitempty"`
 
@@ -135,6 +246,11 @@ type HumioNodeSpec struct {
 	// ImagePullSecrets defines the imagepullsecrets for the humio pods. These secrets are not created by the operator
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 
+	// ImageRegistry overrides the registry of the humio and helper container images, e.g. registry.example.com:5000.
+	// This makes it possible to pull the images of a node pool from a private registry or a registry mirror without
+	// repeating the image tags. When the images do not include a registry, the registry is prepended to the images.
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+
 	// HelperImage is the desired helper container image, including image tag
 	HelperImage string `json:"helperImage,omitempty"`
 
@@ -176,6 +292,22 @@ type HumioNodeSpec struct {
 	// Affinity defines the affinity policies that will be attached to the humio pods
 	Affinity corev1.Affinity `json:"affinity,omitempty"`
 
+	// AffinityPreset expands to recommended pod anti-affinity rules for the humio pods. The available values are: None,
+	// SoftZoneSpread and HardHostAntiAffinity.
+	//
+	// When set to None, no rules are added. This is the default behavior.
+	//
+	// When set to SoftZoneSpread, the scheduler prefers placing the pods of the node pool in different availability
+	// zones.
+	//
+	// When set to HardHostAntiAffinity, the pods of the cluster are required to run on different Kubernetes worker
+	// nodes. This requires at least as many worker nodes as there are pods, including the extra pods created during
+	// BlueGreen updates.
+	//
+	// The rules are only added if Affinity does not define podAntiAffinity, so raw rules always take precedence.
+	// +kubebuilder:validation:Enum=None;SoftZoneSpread;HardHostAntiAffinity
+	AffinityPreset string `json:"affinityPreset,omitempty"`
+
 	// Tolerations defines the tolerations that will be attached to the humio pods
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 
@@ -201,9 +333,29 @@ type HumioNodeSpec struct {
 	// ExtraVolumes is the list of additional volumes that will be added to the Humio pod
 	ExtraVolumes []corev1.Volume `json:"extraVolumes,omitempty"`
 
+	// ExtraConfigFiles is the list of file-based configuration, such as the metadata of an identity provider, which
+	// is mounted into the Humio data directory from ConfigMaps or Secrets. The pods are restarted when the content of
+	// the files changes.
+	ExtraConfigFiles []HumioExtraConfigFile `json:"extraConfigFiles,omitempty"`
+
+	// SecretsStore mounts secrets from an external secret store, such as Vault or a cloud provider's secret manager,
+	// into the Humio container using the Secrets Store CSI driver. The pods are restarted when the driver rotates the
+	// Kubernetes Secrets it syncs.
+	SecretsStore *HumioSecretsStore `json:"secretsStore,omitempty"`
+
 	// HumioServiceAccountAnnotations is the set of annotations added to the Kubernetes Service Account that will be attached to the Humio pods
 	HumioServiceAccountAnnotations map[string]string `json:"humioServiceAccountAnnotations,omitempty"`
 
+	// WorkloadIdentity lets Humio access bucket storage with the cloud identity of its Kubernetes Service Account
+	// instead of static access keys. The operator annotates the Service Account it manages for the Humio pods, so this
+	// cannot be combined with humioServiceAccountName.
+	WorkloadIdentity *HumioWorkloadIdentity `json:"workloadIdentity,omitempty"`
+
+	// BucketStorageEncryption configures how the segment files in bucket storage are encrypted. The operator sets the
+	// matching environment variables for the cloud of the bucket storage, so they must not be set using
+	// environmentVariables.
+	BucketStorageEncryption *HumioBucketStorageEncryption `json:"bucketStorageEncryption,omitempty"`
+
 	// HumioServiceLabels is the set of labels added to the Kubernetes Service that is used to direct traffic
 	// to the Humio pods
 	HumioServiceLabels map[string]string `json:"humioServiceLabels,omitempty"`
@@ -214,6 +366,11 @@ type HumioNodeSpec struct {
 	// ImageSource is the reference to an external source identifying the image
 	ImageSource *HumioImageSource `json:"imageSource,omitempty"`
 
+	// ImageVerification enables pinning the humio container image to the digest its tag currently resolves to, and
+	// verifying the cosign signature of the image before it is rolled out. Pods are not updated to images which
+	// are not signed with the configured public key.
+	ImageVerification *HumioImageVerification `json:"imageVerification,omitempty"`
+
 	// HumioServiceType is the ServiceType of the Humio Service that is used to direct traffic to the Humio pods
 	HumioServiceType corev1.ServiceType `json:"humioServiceType,omitempty"`
 
@@ -241,12 +398,39 @@ type HumioNodeSpec struct {
 
 	// PriorityClassName is the name of the priority class that will be used by the Humio pods
 	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// DNSPolicy is the DNS policy of the Humio pods. Defaults to ClusterFirst. When set to None, DNSConfig must be set.
+	//+kubebuilder:validation:Enum=ClusterFirstWithHostNet;ClusterFirst;Default;None
+	DNSPolicy corev1.DNSPolicy `json:"dnsPolicy,omitempty"`
+
+	// DNSConfig specifies additional nameservers, search domains and resolver options for the Humio pods. This can be
+	// used to resolve Kafka, bucket storage and other endpoints in air-gapped or split-horizon DNS environments.
+	DNSConfig *corev1.PodDNSConfig `json:"dnsConfig,omitempty"`
+
+	// HostAliases are entries added to the hosts file of the Humio pods
+	HostAliases []corev1.HostAlias `json:"hostAliases,omitempty"`
+
+	// NodeRoles are the roles of the Humio nodes in the node pool. The available values are: ingest, digest, query and
+	// storage. By default, the Humio nodes have all roles.
+	//
+	// The roles are applied by setting the NODE_ROLES environment variable. Nodes with the digest or storage role run
+	// with all roles enabled. Nodes with only the ingest role are ingest-only nodes, and other nodes are http-only nodes
+	// which coordinate queries and serve the UI and API.
+	//
+	// When the operator balances partitions, digest partitions are only assigned to nodes with the digest role and
+	// storage partitions are only assigned to nodes with the storage role.
+	//+listType=set
+	NodeRoles []HumioNodeRole `json:"nodeRoles,omitempty"`
 }
 
+// HumioNodeRole is a role of a Humio node
+// +kubebuilder:validation:Enum=ingest;digest;query;storage
+type HumioNodeRole string
+
 type HumioUpdateStrategy struct {
 	// Type controls how Humio pods are updated  when changes are made to the HumioCluster resource that results
-	// in a change to the Humio pods. The available values are: OnDelete, RollingUpdate, ReplaceAllOnUpdate, and
-	// RollingUpdateBestEffort.
+	// in a change to the Humio pods. The available values are: OnDelete, RollingUpdate, ReplaceAllOnUpdate,
+	// RollingUpdateBestEffort and BlueGreen.
 	///
 	// When set to OnDelete, no Humio pods will be terminated but new pods will be created with the new spec. Replacing
 	// existing pods will require each pod to be deleted by the user.
@@ -260,11 +444,52 @@ type HumioUpdateStrategy struct {
 	//
 	// When set to RollingUpdateBestEffort, the operator will evaluate the Humio version change and determine if the
 	// Humio pods can be updated in a rolling fashion or if they must be replaced at the same time.
-	// +kubebuilder:validation:Enum=OnDelete;RollingUpdate;ReplaceAllOnUpdate;RollingUpdateBestEffort
+	//
+	// When set to BlueGreen, the operator will create a new pod for each existing pod using the new spec, while the
+	// existing pods keep serving traffic. The new pods join the same cluster and use the same bucket storage. Once the
+	// new pods are ready and have been validated, traffic is shifted to them and the existing pods are removed. This
+	// requires bucket storage and USING_EPHEMERAL_DISKS=true, and is recommended for major version upgrades.
+	// +kubebuilder:validation:Enum=OnDelete;RollingUpdate;ReplaceAllOnUpdate;RollingUpdateBestEffort;BlueGreen
 	Type string `json:"type,omitempty"`
 
 	// The minimum time in seconds that a pod must be ready before the next pod can be deleted when doing rolling update.
 	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+
+	// BlueGreen holds the settings used when Type is set to BlueGreen
+	BlueGreen *HumioUpdateStrategyBlueGreen `json:"blueGreen,omitempty"`
+}
+
+// HumioNodeTag maps a label of the Kubernetes worker node to a node tag of the Humio node
+type HumioNodeTag struct {
+	// Tag is the name of the node tag
+	//+kubebuilder:validation:MinLength=1
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9_.-]+$`
+	Tag string `json:"tag"`
+	// NodeLabel is the label of the Kubernetes worker node which holds the value of the node tag, e.g.
+	// topology.kubernetes.io/zone or node.kubernetes.io/instance-type. Nodes without the label get an empty value.
+	//+kubebuilder:validation:MinLength=1
+	NodeLabel string `json:"nodeLabel"`
+}
+
+// HumioMaintenanceWindow is a recurring time window
+type HumioMaintenanceWindow struct {
+	// Schedule is a cron expression in UTC for the start of each maintenance window, e.g. "0 2 * * 6" for every
+	// Saturday at 02:00
+	Schedule string `json:"schedule"`
+	// DurationMinutes is the length of each maintenance window. Restarts already in progress when the window closes
+	// are completed.
+	//+kubebuilder:validation:Minimum=1
+	DurationMinutes int `json:"durationMinutes"`
+}
+
+type HumioUpdateStrategyBlueGreen struct {
+	// ValidationRepository is the repository the validation query is executed against on each new pod before traffic
+	// is shifted to it. Defaults to humio.
+	ValidationRepository string `json:"validationRepository,omitempty"`
+
+	// ValidationQuery is the query executed on each new pod before traffic is shifted to it. The query must complete
+	// without errors for the update to proceed. Defaults to count().
+	ValidationQuery string `json:"validationQuery,omitempty"`
 }
 
 type HumioNodePoolSpec struct {
@@ -301,6 +526,14 @@ type HumioClusterIngressSpec struct {
 	ESSecretName string `json:"esSecretName,omitempty"`
 	// Annotations can be used to specify annotations appended to the annotations set by the operator when creating ingress-related objects
 	Annotations map[string]string `json:"annotations,omitempty"`
+	// UINodePoolName is the name of the node pool in spec.nodePools whose Service receives the UI and API traffic from
+	// the ingress. This allows running a dedicated front-end node pool, e.g. with nodeRoles set to query and no
+	// persistent storage, which can be scaled independently of the node pools storing data. Defaults to the pods defined
+	// at the top level of the HumioCluster spec.
+	UINodePoolName string `json:"uiNodePoolName,omitempty"`
+	// IngestNodePoolName is the name of the node pool in spec.nodePools whose Service receives the ingest traffic from
+	// the ingress, including the ES bulk API. Defaults to the pods defined at the top level of the HumioCluster spec.
+	IngestNodePoolName string `json:"ingestNodePoolName,omitempty"`
 }
 
 type HumioClusterTLSSpec struct {
@@ -313,6 +546,151 @@ type HumioClusterTLSSpec struct {
 // HumioClusterLicenseSpec points to the optional location of the Humio license
 type HumioClusterLicenseSpec struct {
 	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// ExpiryWarningDays is the number of days before the license expires from which Warning events are emitted and the
+	// license is reported as expiring soon. Defaults to 30.
+	//+kubebuilder:validation:Minimum=1
+	ExpiryWarningDays int `json:"expiryWarningDays,omitempty"`
+}
+
+// HumioExtraConfigFile is a configuration file which is mounted into the Humio container from a ConfigMap or Secret.
+// Exactly one of configMapKeyRef and secretKeyRef must be set.
+type HumioExtraConfigFile struct {
+	// FileName is the name of the file within the Humio data directory /data/humio-data
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9._-]+$`
+	FileName string `json:"fileName"`
+	// ConfigMapKeyRef selects the key of a ConfigMap holding the content of the file
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// SecretKeyRef selects the key of a Secret holding the content of the file
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// HumioSecretsStore references a SecretProviderClass of the Secrets Store CSI driver, see
+// https://secrets-store-csi-driver.sigs.k8s.io.
+//
+// Besides mounting the secrets as files, the driver can sync them to Kubernetes Secrets through the secretObjects of
+// the SecretProviderClass. These can be used anywhere the HumioCluster takes a Secret, such as the license, or
+// environment variables holding SMTP or bucket storage credentials, and as the bootstrap token of a
+// HumioExternalCluster. The driver only creates the synced Secrets while a pod mounts the volume, so the operator does
+// not report a synced license Secret as missing before the first pod has started.
+type HumioSecretsStore struct {
+	// SecretProviderClass is the name of the SecretProviderClass in the namespace of the HumioCluster
+	SecretProviderClass string `json:"secretProviderClass"`
+	// MountPath is the directory the secrets are mounted to in the Humio container. Defaults to /mnt/secrets-store.
+	//+kubebuilder:validation:Pattern=`^/`
+	MountPath string `json:"mountPath,omitempty"`
+	// SyncedSecrets is the list of names of the Kubernetes Secrets the driver syncs from the SecretProviderClass.
+	// The pods are restarted when the content of these Secrets changes, e.g. when the driver rotates them.
+	SyncedSecrets []string `json:"syncedSecrets,omitempty"`
+}
+
+// HumioWorkloadIdentity selects the cloud identity used by the Humio pods through IAM roles for service accounts on
+// AWS, or workload identity on GCP and Azure. Exactly one of awsRoleARN, gcpServiceAccount and azureClientID must be
+// set, and the bucket storage of the node pool must be on the matching cloud.
+type HumioWorkloadIdentity struct {
+	// AWSRoleARN is the ARN of the IAM role assumed by the Humio pods, which must allow access to the S3 bucket
+	//+kubebuilder:validation:Pattern=`^arn:aws[a-z-]*:iam::[0-9]{12}:role/.+$`
+	AWSRoleARN string `json:"awsRoleARN,omitempty"`
+	// GCPServiceAccount is the email of the Google service account impersonated by the Humio pods, which must allow
+	// access to the GCS bucket
+	GCPServiceAccount string `json:"gcpServiceAccount,omitempty"`
+	// AzureClientID is the client ID of the managed identity or application used by the Humio pods, which must allow
+	// access to the Azure storage container
+	AzureClientID string `json:"azureClientID,omitempty"`
+}
+
+// HumioBucketStorageEncryption contains the encryption settings of bucket storage. At least one of kmsKeyARN and
+// encryptionKeySecretKeyRef must be set.
+type HumioBucketStorageEncryption struct {
+	// KMSKeyARN is the ARN of the AWS KMS key, or key alias, used for server-side encryption of the objects in the S3
+	// bucket (SSE-KMS). The IAM identity of the Humio pods must be allowed to use the key.
+	//+kubebuilder:validation:Pattern=`^arn:aws[a-z-]*:kms:[a-z0-9-]+:[0-9]{12}:(key|alias)/.+$`
+	KMSKeyARN string `json:"kmsKeyARN,omitempty"`
+	// EncryptionKeySecretKeyRef selects the key of a Secret holding the customer-provided key Humio encrypts the
+	// segment files with before uploading them to the bucket
+	EncryptionKeySecretKeyRef *corev1.SecretKeySelector `json:"encryptionKeySecretKeyRef,omitempty"`
+}
+
+// HumioDeadNodeUnregistration contains the configuration of the unregistration of dead Humio nodes.
+//
+// A node is dead when it is unavailable and no pod of the cluster has its node ID. Once a node has been dead for the
+// grace period, the operator unregisters it as soon as Humio reports that it can be safely unregistered, i.e. when its
+// data is available on other nodes. For versions of Humio without automatic partition management, the operator moves
+// the partitions away from the node before that.
+type HumioDeadNodeUnregistration struct {
+	// GracePeriodSeconds is how long a node must be dead before it is unregistered. Defaults to 3600.
+	//+kubebuilder:validation:Minimum=60
+	GracePeriodSeconds int `json:"gracePeriodSeconds,omitempty"`
+}
+
+// HumioUsageReporting contains the configuration of the usage reporting of a Humio cluster
+type HumioUsageReporting struct {
+	// IntervalSeconds is how often usage is collected. Defaults to 3600.
+	//+kubebuilder:validation:Minimum=60
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+}
+
+// HumioAdminTokenRotation contains the configuration of the rotation of the API token the operator uses to manage a
+// Humio cluster.
+//
+// The operator alternates between two root users. A new API token is created for the inactive user and stored in the
+// admin token secret, and the API token of the previously active user is revoked once the revocation grace period has
+// passed. This way the old API token keeps working until everything has switched to the new API token.
+type HumioAdminTokenRotation struct {
+	// IntervalSeconds is how often the API token is rotated
+	//+kubebuilder:validation:Minimum=3600
+	IntervalSeconds int `json:"intervalSeconds"`
+	// RevocationGracePeriodSeconds is how long the previous API token keeps working after a rotation. Defaults to 300
+	// seconds.
+	//+kubebuilder:validation:Minimum=0
+	RevocationGracePeriodSeconds int `json:"revocationGracePeriodSeconds,omitempty"`
+}
+
+// HumioPendingAdminTokenRevocation is an API token replaced by a rotation of the admin API token, which is revoked once
+// the revocation grace period has passed
+type HumioPendingAdminTokenRevocation struct {
+	// Username is the root user holding the API token to revoke
+	Username string `json:"username"`
+	// RotationTime is the time the API token was replaced
+	RotationTime metav1.Time `json:"rotationTime"`
+}
+
+// HumioAuthMigration contains the configuration of a migration of a Humio cluster to another authentication method.
+//
+// The operator first creates the break-glass users as root users. It then applies the authentication method and the
+// identity provider settings to all node pools, which restarts the pods. Once all pods are ready, the migration is
+// verified by checking that the admin API token still works and that the identity provider can be reached. If the
+// migration cannot be verified before the verification timeout, the settings are removed again, which restarts the
+// pods with the previous authentication method.
+//
+// Once the migration is completed, the settings should be moved to the environment variables of the cluster before
+// AuthMigration is removed. Changing AuthMigration starts a new migration.
+type HumioAuthMigration struct {
+	// AuthenticationMethod is the authentication method to migrate to. Use oauth for OIDC and saml for SAML. SAML
+	// additionally requires IdpCertificateSecretName to be set.
+	//+kubebuilder:validation:Enum=oauth;saml
+	AuthenticationMethod string `json:"authenticationMethod"`
+	// EnvironmentVariables are the identity provider settings applied together with the authentication method, e.g.
+	// OIDC_PROVIDER and OIDC_CLIENT_ID, or SAML_IDP_SIGN_ON_URL and SAML_IDP_ENTITY_ID. They take precedence over
+	// the environment variables of the node pools.
+	EnvironmentVariables []corev1.EnvVar `json:"environmentVariables,omitempty"`
+	// BreakGlassUsers are the usernames of users which are made root users before the authentication method is
+	// changed, so they have full access when logging in through the identity provider
+	BreakGlassUsers []string `json:"breakGlassUsers,omitempty"`
+	// VerificationTimeoutSeconds is how long to wait for the migration to be verified before rolling it back.
+	// Defaults to 1800.
+	//+kubebuilder:validation:Minimum=60
+	VerificationTimeoutSeconds int `json:"verificationTimeoutSeconds,omitempty"`
+}
+
+// HumioAPITimeouts contains the timeouts used by the operator when communicating with a Humio cluster. Requests
+// against the Humio API never take longer than 30 seconds in total, regardless of these timeouts.
+type HumioAPITimeouts struct {
+	// ConnectTimeoutSeconds is how long to wait for a connection to the Humio cluster to be established
+	//+kubebuilder:validation:Minimum=1
+	ConnectTimeoutSeconds int `json:"connectTimeoutSeconds,omitempty"`
+	// ReadTimeoutSeconds is how long to wait for the Humio cluster to respond to a request
+	//+kubebuilder:validation:Minimum=1
+	ReadTimeoutSeconds int `json:"readTimeoutSeconds,omitempty"`
 }
 
 // HumioImageSource points to the external source identifying the image
@@ -321,6 +699,13 @@ type HumioImageSource struct {
 	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
 }
 
+// HumioImageVerification contains the configuration for verifying the signature of the humio container image
+type HumioImageVerification struct {
+	// CosignPublicKeySecretRef is the reference to the secret key containing the PEM encoded public key the image must
+	// be signed with, as generated by cosign generate-key-pair. Signatures are looked up in the repository of the image.
+	CosignPublicKeySecretRef *corev1.SecretKeySelector `json:"cosignPublicKeySecretRef"`
+}
+
 // HumioPersistentVolumeReclaimType is the type of reclaim which will occur on a persistent volume
 type HumioPersistentVolumeReclaimType string
 
@@ -345,6 +730,8 @@ type HumioPodStatus struct {
 type HumioLicenseStatus struct {
 	Type       string `json:"type,omitempty"`
 	Expiration string `json:"expiration,omitempty"`
+	// ExpiresInDays is the number of whole days until the license expires. It is negative once the license has expired.
+	ExpiresInDays *int `json:"expiresInDays,omitempty"`
 }
 
 // HumioNodePoolStatusList holds the list of HumioNodePoolStatus types
@@ -356,6 +743,11 @@ type HumioNodePoolStatus struct {
 	Name string `json:"name,omitempty"`
 	// State will be empty before the cluster is bootstrapped. From there it can be "Running", "Upgrading", "Restarting" or "Pending"
 	State string `json:"state,omitempty"`
+	// PendingConfigChanges is true when configuration changes to the node pool have not been applied to its pods yet,
+	// because the config change policy of the cluster is Deferred or Manual
+	PendingConfigChanges bool `json:"pendingConfigChanges,omitempty"`
+	// DataNodes are the Kubernetes worker nodes holding data of the node pool when dataVolumeNodePinning is enabled
+	DataNodes []string `json:"dataNodes,omitempty"`
 }
 
 // HumioClusterStatus defines the observed state of HumioCluster
@@ -374,10 +766,111 @@ type HumioClusterStatus struct {
 	LicenseStatus HumioLicenseStatus `json:"licenseStatus,omitempty"`
 	// NodePoolStatus shows the status of each node pool
 	NodePoolStatus HumioNodePoolStatusList `json:"nodePoolStatus,omitempty"`
+	// Usage shows the usage of the cluster, if usage reporting is enabled
+	Usage *HumioClusterUsage `json:"usage,omitempty"`
+	// DeadNodes lists the Humio nodes which are unavailable and no longer backed by a pod, if dead node
+	// unregistration is enabled
+	DeadNodes []HumioDeadNode `json:"deadNodes,omitempty"`
+	// Health shows the health of the data of the cluster as reported by Humio. Pods may be ready while the cluster is
+	// missing segments or segments are under-replicated.
+	Health *HumioClusterHealth `json:"health,omitempty"`
+	// LastAdminTokenRotationTime is the time the API token the operator uses to manage the cluster was last rotated
+	LastAdminTokenRotationTime *metav1.Time `json:"lastAdminTokenRotationTime,omitempty"`
+	// LastAdminTokenRotationRequest is the value of the humio.com/rotate-admin-token annotation which last triggered a
+	// rotation of the API token
+	LastAdminTokenRotationRequest string `json:"lastAdminTokenRotationRequest,omitempty"`
+	// PendingAdminTokenRevocation is the API token replaced by the last rotation of the admin API token, until it has
+	// been revoked
+	PendingAdminTokenRevocation *HumioPendingAdminTokenRevocation `json:"pendingAdminTokenRevocation,omitempty"`
+	// LastAdminTokenRegenerationRequest is the value of the humio.com/regenerate-admin-token annotation which last
+	// triggered a regeneration of the API token
+	LastAdminTokenRegenerationRequest string `json:"lastAdminTokenRegenerationRequest,omitempty"`
+	// AdminTokenHash is the SHA256 hash of the API token the operator last verified it can manage the cluster with. The
+	// API token itself is only kept in the admin token secret.
+	AdminTokenHash string `json:"adminTokenHash,omitempty"`
+	// AuthMigration shows the progress of the migration to another authentication method, if any
+	AuthMigration *HumioAuthMigrationStatus `json:"authMigration,omitempty"`
+	// EnvironmentVariableWarnings lists the environment variables of the node pools which are unknown or deprecated for
+	// the version of Humio they run
+	EnvironmentVariableWarnings []string `json:"environmentVariableWarnings,omitempty"`
+	// Conditions contains the conditions of the cluster. The Degraded condition is True when the license has expired,
+	// and the CrashLooping condition is True when Humio pods are crash-looping, with the probable cause in its message.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 	// ObservedGeneration shows the generation of the HumioCluster which was last observed
 	ObservedGeneration string `json:"observedGeneration,omitempty"` // TODO: We should change the type to int64 so we don't have to convert back and forth between int64 and string
 }
 
+// HumioAuthMigrationStatus is the progress of a migration of a Humio cluster to another authentication method
+type HumioAuthMigrationStatus struct {
+	// State is the state of the migration. It can be "Migrating", "Completed" or "RolledBack"
+	State string `json:"state,omitempty"`
+	// Message contains details about the state
+	Message string `json:"message,omitempty"`
+	// AuthenticationMethod is the authentication method the cluster is migrated to
+	AuthenticationMethod string `json:"authenticationMethod,omitempty"`
+	// SpecHash is the hash of the AuthMigration the state refers to
+	SpecHash string `json:"specHash,omitempty"`
+	// StartTime is the time the migration was started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+}
+
+// HumioDeadNode is a Humio node which is unavailable and no longer backed by a pod
+type HumioDeadNode struct {
+	// NodeID is the ID of the Humio node
+	NodeID int `json:"nodeID"`
+	// DetectionTime is the time the node was first detected to be dead
+	DetectionTime metav1.Time `json:"detectionTime"`
+	// Message describes why the node has not been unregistered yet
+	Message string `json:"message,omitempty"`
+}
+
+// HumioClusterHealth is the health of the data of a Humio cluster
+type HumioClusterHealth struct {
+	// MissingSegmentBytes is the size of the segments which are not available on any node of the cluster
+	MissingSegmentBytes int64 `json:"missingSegmentBytes,omitempty"`
+	// UnderReplicatedSegmentBytes is the size of the segments which are stored on fewer nodes than the replication
+	// factor requires
+	UnderReplicatedSegmentBytes int64 `json:"underReplicatedSegmentBytes,omitempty"`
+	// UnavailableNodes is the number of nodes registered in the cluster which are not available
+	UnavailableNodes int `json:"unavailableNodes,omitempty"`
+	// Message contains the error if the health could not be collected
+	Message string `json:"message,omitempty"`
+}
+
+// HumioClusterUsage is the usage of a Humio cluster
+type HumioClusterUsage struct {
+	// CollectionTime is the time the usage was collected
+	CollectionTime *metav1.Time `json:"collectionTime,omitempty"`
+	// IngestBytesLast24h is the amount of data ingested into all repositories during the last 24 hours
+	IngestBytesLast24h int64 `json:"ingestBytesLast24h,omitempty"`
+	// RetainedBytes is the amount of compressed data stored by all repositories
+	RetainedBytes int64 `json:"retainedBytes,omitempty"`
+	// LicenseSeats is the number of users allowed by the license, if the license limits the number of users
+	LicenseSeats int `json:"licenseSeats,omitempty"`
+	// LicenseSeatsUsed is the number of users of the cluster
+	LicenseSeatsUsed int `json:"licenseSeatsUsed,omitempty"`
+	// LicenseUtilization is the percentage of the seats of the license which are used
+	LicenseUtilization int32 `json:"licenseUtilization,omitempty"`
+	// Repositories shows the usage of each repository
+	Repositories []HumioRepositoryUsage `json:"repositories,omitempty"`
+	// Message contains the reason the usage could not be collected
+	Message string `json:"message,omitempty"`
+}
+
+// HumioRepositoryUsage is the usage of a single repository
+type HumioRepositoryUsage struct {
+	// Name is the name of the repository inside Humio
+	Name string `json:"name"`
+	// IngestBytesLast24h is the amount of data ingested into the repository during the last 24 hours
+	IngestBytesLast24h int64 `json:"ingestBytesLast24h,omitempty"`
+	// RetainedBytes is the amount of compressed data stored by the repository
+	RetainedBytes int64 `json:"retainedBytes,omitempty"`
+	// UncompressedBytes is the amount of data stored by the repository before compression
+	UncompressedBytes int64 `json:"uncompressedBytes,omitempty"`
+}
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:path=humioclusters,scope=Namespaced
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioClusterBackupStateIdle is the state of the backup when no backup is currently running
+	HumioClusterBackupStateIdle = "Idle"
+	// HumioClusterBackupStateRunning is the state of the backup while a backup is being taken
+	HumioClusterBackupStateRunning = "Running"
+	// HumioClusterBackupStateSucceeded is the state of a backup artifact which was stored successfully
+	HumioClusterBackupStateSucceeded = "Succeeded"
+	// HumioClusterBackupStateFailed is the state of a backup artifact which could not be stored
+	HumioClusterBackupStateFailed = "Failed"
+	// HumioClusterBackupStateConfigError is the state of the backup when user-provided specification results in
+	// configuration error, such as non-existent humio cluster
+	HumioClusterBackupStateConfigError = "ConfigError"
+)
+
+// HumioClusterBackupSpec defines the desired state of HumioClusterBackup
+type HumioClusterBackupSpec struct {
+	// ManagedClusterName refers to the HumioCluster that is backed up. The backup consists of the global data
+	// snapshot, which together with the segments in the bucket storage of the cluster allows restoring the cluster.
+	ManagedClusterName string `json:"managedClusterName"`
+	// Schedule is a cron expression, e.g. "0 2 * * *", describing when backups are taken. When empty, a single
+	// backup is taken when the HumioClusterBackup is created.
+	Schedule string `json:"schedule,omitempty"`
+	// Suspend prevents new backups from being started
+	Suspend bool `json:"suspend,omitempty"`
+	// Target is the bucket the backup artifacts are stored in
+	Target HumioClusterBackupTarget `json:"target"`
+	// Image is the container image used to upload the backup artifacts. It must provide the aws command line
+	// interface. Defaults to amazon/aws-cli:2.15.10.
+	Image string `json:"image,omitempty"`
+	// ServiceAccountName is the service account used by the pods uploading the backup artifacts, e.g. to obtain
+	// credentials using IAM roles for service accounts.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// HistoryLimit is the number of backup artifacts listed in the status. Defaults to 5.
+	//+kubebuilder:validation:Minimum=1
+	HistoryLimit int `json:"historyLimit,omitempty"`
+	// AllowWithoutBucketStorage allows taking backups of clusters which do not use bucket storage. Such backups only
+	// contain metadata, as the segments of the cluster are not stored outside the cluster.
+	AllowWithoutBucketStorage bool `json:"allowWithoutBucketStorage,omitempty"`
+}
+
+// HumioClusterBackupTarget describes the S3 compatible bucket the backup artifacts are stored in
+type HumioClusterBackupTarget struct {
+	// Bucket is the name of the bucket
+	Bucket string `json:"bucket"`
+	// Prefix is prepended to the key of all backup artifacts
+	Prefix string `json:"prefix,omitempty"`
+	// Region is the region of the bucket
+	Region string `json:"region,omitempty"`
+	// Endpoint is the URL of the S3 compatible object storage. Defaults to AWS S3.
+	Endpoint string `json:"endpoint,omitempty"`
+	// CredentialsSecretName is used to obtain the credentials used to upload backup artifacts. The secret must
+	// contain the keys "AWS_ACCESS_KEY_ID" and "AWS_SECRET_ACCESS_KEY". When empty, the credentials are obtained from
+	// the environment, e.g. using IAM roles for service accounts.
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
+}
+
+// HumioClusterBackupArtifact describes a single backup
+type HumioClusterBackupArtifact struct {
+	// Name is the name of the job taking the backup
+	Name string `json:"name"`
+	// State is the state of the backup
+	State string `json:"state"`
+	// Location is the URL of the stored global data snapshot
+	Location string `json:"location,omitempty"`
+	// SizeBytes is the size of the stored global data snapshot
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+	// BucketStorage is the bucket storage target of the cluster when the backup was taken
+	BucketStorage string `json:"bucketStorage,omitempty"`
+	// StartTime is the time the backup was started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the backup completed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Message contains the reason the backup failed
+	Message string `json:"message,omitempty"`
+}
+
+// HumioClusterBackupStatus defines the observed state of HumioClusterBackup
+type HumioClusterBackupStatus struct {
+	// State reflects the current state of the HumioClusterBackup
+	State string `json:"state,omitempty"`
+	// Message contains the reason the HumioClusterBackup is in the ConfigError state
+	Message string `json:"message,omitempty"`
+	// LastScheduleTime is the time the last backup was started
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSuccessfulTime is the time the last successful backup completed
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// Backups lists the most recent backups, newest first
+	Backups []HumioClusterBackupArtifact `json:"backups,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioclusterbackups,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the backup"
+//+kubebuilder:printcolumn:name="Last Successful",type="date",JSONPath=".status.lastSuccessfulTime",description="The time the last successful backup completed"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Cluster Backup"
+
+// HumioClusterBackup is the Schema for the humioclusterbackups API
+type HumioClusterBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioClusterBackupSpec   `json:"spec,omitempty"`
+	Status HumioClusterBackupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioClusterBackupList contains a list of HumioClusterBackup
+type HumioClusterBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioClusterBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioClusterBackup{}, &HumioClusterBackupList{})
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioClusterReplicationStateReplicating is the state of the replication when the passive cluster is configured
+	// to read the data of the active cluster and both clusters are running
+	HumioClusterReplicationStateReplicating = "Replicating"
+	// HumioClusterReplicationStateSwitching is the state of the replication while the clusters are restarted after the
+	// active cluster was changed
+	HumioClusterReplicationStateSwitching = "Switching"
+	// HumioClusterReplicationStatePending is the state of the replication while waiting for the clusters to be running
+	HumioClusterReplicationStatePending = "Pending"
+	// HumioClusterReplicationStateConfigError is the state of the replication when user-provided specification results
+	// in configuration error, such as non-existent humio cluster or clusters sharing the same bucket
+	HumioClusterReplicationStateConfigError = "ConfigError"
+
+	// HumioClusterReplicationActivePrimary makes the primary cluster the active cluster
+	HumioClusterReplicationActivePrimary = "primary"
+	// HumioClusterReplicationActiveStandby makes the standby cluster the active cluster
+	HumioClusterReplicationActiveStandby = "standby"
+)
+
+// HumioClusterReplicationSpec defines the desired state of HumioClusterReplication
+type HumioClusterReplicationSpec struct {
+	// PrimaryClusterName refers to the HumioCluster which normally receives ingest and queries
+	PrimaryClusterName string `json:"primaryClusterName"`
+	// StandbyClusterName refers to the HumioCluster which takes over when failing over
+	StandbyClusterName string `json:"standbyClusterName"`
+	// ActiveCluster selects which of the clusters is active. The passive cluster is configured to read the segments
+	// of the active cluster from its bucket storage. Changing this from primary to standby fails over, and changing it
+	// back fails back. Defaults to primary.
+	//+kubebuilder:validation:Enum=primary;standby
+	ActiveCluster string `json:"activeCluster,omitempty"`
+}
+
+// HumioClusterReplicationStatus defines the observed state of HumioClusterReplication
+type HumioClusterReplicationStatus struct {
+	// State reflects the current state of the HumioClusterReplication
+	State string `json:"state,omitempty"`
+	// Message contains the reason the HumioClusterReplication is in the ConfigError state
+	Message string `json:"message,omitempty"`
+	// ActiveCluster is the name of the HumioCluster which is currently active
+	ActiveCluster string `json:"activeCluster,omitempty"`
+	// SourceBucket is the bucket storage of the active cluster which the passive cluster reads from
+	SourceBucket string `json:"sourceBucket,omitempty"`
+	// PrimaryClusterState is the state of the primary HumioCluster
+	PrimaryClusterState string `json:"primaryClusterState,omitempty"`
+	// StandbyClusterState is the state of the standby HumioCluster
+	StandbyClusterState string `json:"standbyClusterState,omitempty"`
+	// LastSwitchTime is the time the active cluster was last changed
+	LastSwitchTime *metav1.Time `json:"lastSwitchTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioclusterreplications,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the replication"
+//+kubebuilder:printcolumn:name="Active",type="string",JSONPath=".status.activeCluster",description="The active cluster"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Cluster Replication"
+
+// HumioClusterReplication is the Schema for the humioclusterreplications API
+type HumioClusterReplication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioClusterReplicationSpec   `json:"spec,omitempty"`
+	Status HumioClusterReplicationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioClusterReplicationList contains a list of HumioClusterReplication
+type HumioClusterReplicationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioClusterReplication `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioClusterReplication{}, &HumioClusterReplicationList{})
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// HumioClusterSetStateRunning is the state of the cluster set when all of its clusters are running
+	HumioClusterSetStateRunning = "Running"
+	// HumioClusterSetStatePending is the state of the cluster set while clusters are being created or updated
+	HumioClusterSetStatePending = "Pending"
+	// HumioClusterSetStateDegraded is the state of the cluster set when one or more of its clusters cannot be managed
+	// or are in the ConfigError state
+	HumioClusterSetStateDegraded = "Degraded"
+	// HumioClusterSetStateConfigError is the state of the cluster set when user-provided specification results in
+	// configuration error, such as the same cluster being listed twice
+	HumioClusterSetStateConfigError = "ConfigError"
+
+	// HumioClusterSetDeletionPolicyDelete deletes clusters which are removed from the cluster set
+	HumioClusterSetDeletionPolicyDelete = "Delete"
+	// HumioClusterSetDeletionPolicyRetain keeps clusters which are removed from the cluster set, but stops managing
+	// them
+	HumioClusterSetDeletionPolicyRetain = "Retain"
+)
+
+// HumioClusterSetSpec defines the desired state of HumioClusterSet
+type HumioClusterSetSpec struct {
+	// Template is used to create each HumioCluster of the set
+	Template HumioClusterSetTemplate `json:"template"`
+	// Instances lists the HumioClusters of the set
+	//+kubebuilder:validation:MinItems=1
+	Instances []HumioClusterSetInstance `json:"instances"`
+	// DeletionPolicy decides what happens to clusters which are removed from the set, or when the set itself is
+	// deleted. Defaults to Delete.
+	//+kubebuilder:validation:Enum=Delete;Retain
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+}
+
+// HumioClusterSetTemplate describes the HumioClusters of the set
+type HumioClusterSetTemplate struct {
+	// Labels are added to each HumioCluster
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are added to each HumioCluster
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Spec is the specification shared by each HumioCluster
+	Spec HumioClusterSpec `json:"spec"`
+}
+
+// HumioClusterSetInstance describes a single HumioCluster of the set
+type HumioClusterSetInstance struct {
+	// Name is the name of the HumioCluster
+	Name string `json:"name"`
+	// Namespace is the namespace of the HumioCluster. Defaults to the namespace of the HumioClusterSet.
+	Namespace string `json:"namespace,omitempty"`
+	// Labels are added to the HumioCluster in addition to the labels of the template
+	Labels map[string]string `json:"labels,omitempty"`
+	// Overrides is a strategic merge patch applied to the spec of the template, e.g. to change the node count or
+	// bucket storage of a single cluster. Lists are replaced as a whole.
+	//+kubebuilder:pruning:PreserveUnknownFields
+	//+kubebuilder:validation:Schemaless
+	//+kubebuilder:validation:Type=object
+	Overrides *runtime.RawExtension `json:"overrides,omitempty"`
+}
+
+// HumioClusterSetClusterStatus describes the state of a single HumioCluster of the set
+type HumioClusterSetClusterStatus struct {
+	// Name is the name of the HumioCluster
+	Name string `json:"name"`
+	// Namespace is the namespace of the HumioCluster
+	Namespace string `json:"namespace"`
+	// State is the state of the HumioCluster
+	State string `json:"state,omitempty"`
+	// Version is the version of Humio running on the HumioCluster
+	Version string `json:"version,omitempty"`
+	// Message contains the reason the HumioCluster could not be managed
+	Message string `json:"message,omitempty"`
+}
+
+// HumioClusterSetStatus defines the observed state of HumioClusterSet
+type HumioClusterSetStatus struct {
+	// State reflects the aggregated state of the clusters of the HumioClusterSet
+	State string `json:"state,omitempty"`
+	// Message contains the reason the HumioClusterSet is in the ConfigError state
+	Message string `json:"message,omitempty"`
+	// ReadyClusters is the number of clusters in the Running state
+	ReadyClusters int `json:"readyClusters,omitempty"`
+	// Clusters contains the state of each cluster of the set
+	Clusters []HumioClusterSetClusterStatus `json:"clusters,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioclustersets,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the cluster set"
+//+kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyClusters",description="The number of running clusters"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Cluster Set"
+
+// HumioClusterSet is the Schema for the humioclustersets API
+type HumioClusterSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioClusterSetSpec   `json:"spec,omitempty"`
+	Status HumioClusterSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioClusterSetList contains a list of HumioClusterSet
+type HumioClusterSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioClusterSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioClusterSet{}, &HumioClusterSetList{})
+}
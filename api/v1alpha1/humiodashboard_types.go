@@ -0,0 +1,138 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioDashboardStateUnknown is the Unknown state of the dashboard
+	HumioDashboardStateUnknown = "Unknown"
+	// HumioDashboardStateExists is the Exists state of the dashboard
+	HumioDashboardStateExists = "Exists"
+	// HumioDashboardStateNotFound is the NotFound state of the dashboard
+	HumioDashboardStateNotFound = "NotFound"
+	// HumioDashboardStateConfigError is the state of the dashboard when user-provided specification results in
+	// configuration error, such as non-existent humio cluster or a template which cannot be rendered
+	HumioDashboardStateConfigError = "ConfigError"
+	// HumioDashboardStateClusterUnavailable is the state of the dashboard when the Humio cluster it targets cannot be reached
+	HumioDashboardStateClusterUnavailable = "ClusterUnavailable"
+)
+
+// HumioDashboardTemplateSource selects where the template of a dashboard is read from
+type HumioDashboardTemplateSource struct {
+	// ConfigMapKeyRef selects the key of a ConfigMap holding the template
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+// HumioDashboardSpec defines the desired state of HumioDashboard
+type HumioDashboardSpec struct {
+	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator where the Humio
+	// resources should be created.
+	// This conflicts with ExternalClusterName.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
+	// This conflicts with ManagedClusterName.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// Name is the name of the dashboard inside Humio. It overrides the name in the template.
+	Name string `json:"name"`
+	// ViewName is the name of the Humio View under which the dashboard will be managed. This can also be a Repository
+	ViewName string `json:"viewName"`
+	// Template is the dashboard definition in the YAML template format Humio exports dashboards in. JSON is accepted
+	// as well, as it is valid YAML.
+	// This conflicts with TemplateFrom.
+	Template string `json:"template,omitempty"`
+	// TemplateFrom reads the dashboard definition from a ConfigMap, which allows sharing it between dashboards.
+	// This conflicts with Template.
+	TemplateFrom *HumioDashboardTemplateSource `json:"templateFrom,omitempty"`
+	// Parameters are substituted into the template, which is rendered as a Go template, e.g. {{ .environment }}
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// DriftPolicy controls what happens when the dashboard is changed outside the operator, e.g. in the Humio UI. Enforce
+	// reverts the changes, while Warn leaves them in place until the spec changes, and records a Drifted condition
+	// and event. Defaults to Enforce.
+	//+kubebuilder:validation:Enum=Enforce;Warn
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+}
+
+// HumioDashboardStatus defines the observed state of HumioDashboard
+type HumioDashboardStatus struct {
+	// State reflects the current state of the HumioDashboard
+	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the dashboard failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the dashboard is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// Message contains the reason the HumioDashboard is in the ConfigError state
+	Message string `json:"message,omitempty"`
+	// ID is the ID of the dashboard inside Humio
+	ID string `json:"id,omitempty"`
+	// ClusterName is the name of the managed or external cluster the dashboard is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the dashboard was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// LastResyncRequest is the value of the humio.com/trigger-resync annotation when the dashboard was last resynced
+	LastResyncRequest string `json:"lastResyncRequest,omitempty"`
+	// DefinitionHash is a hash of the rendered template the dashboard was last created from
+	DefinitionHash string `json:"definitionHash,omitempty"`
+	// TemplateHash is a hash of the template Humio exported for the dashboard after it was created, which is used to
+	// detect changes made to the dashboard outside the operator
+	TemplateHash string `json:"templateHash,omitempty"`
+	// LastDriftTime is when changes made to the dashboard outside the operator were last reverted
+	LastDriftTime *metav1.Time `json:"lastDriftTime,omitempty"`
+	// Conditions contains the conditions of the dashboard. The Drifted condition is True while changes made outside the
+	// operator are left in place because of the Warn drift policy.
+	// The ClusterAvailable condition is False while the Humio cluster cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humiodashboards,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the dashboard"
+//+kubebuilder:printcolumn:name="ID",type="string",JSONPath=".status.id",description="The ID of the dashboard inside Humio"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the dashboard is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the dashboard was last synced successfully"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Dashboard"
+
+// HumioDashboard is the Schema for the humiodashboards API
+type HumioDashboard struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioDashboardSpec   `json:"spec,omitempty"`
+	Status HumioDashboardStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioDashboardList contains a list of HumioDashboard
+type HumioDashboardList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioDashboard `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioDashboard{}, &HumioDashboardList{})
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioDiagnosticsBundleStateRunning is the state of the diagnostics bundle while it is being stored
+	HumioDiagnosticsBundleStateRunning = "Running"
+	// HumioDiagnosticsBundleStateSucceeded is the state of a diagnostics bundle which was stored successfully
+	HumioDiagnosticsBundleStateSucceeded = "Succeeded"
+	// HumioDiagnosticsBundleStateFailed is the state of a diagnostics bundle which could not be stored
+	HumioDiagnosticsBundleStateFailed = "Failed"
+	// HumioDiagnosticsBundleStateConfigError is the state of the diagnostics bundle when user-provided specification
+	// results in configuration error, such as non-existent humio cluster
+	HumioDiagnosticsBundleStateConfigError = "ConfigError"
+)
+
+// HumioDiagnosticsBundleSpec defines the desired state of HumioDiagnosticsBundle
+type HumioDiagnosticsBundleSpec struct {
+	// ManagedClusterName refers to the HumioCluster diagnostics are collected from. The bundle contains the logs and
+	// thread dumps of the Humio pods, the state of the cluster, the events and pods of the cluster, the recent logs of
+	// the operator and the custom resources of the cluster. Values which look like credentials are redacted.
+	ManagedClusterName string `json:"managedClusterName"`
+	// Target is where the diagnostics bundle is stored
+	Target HumioDiagnosticsBundleTarget `json:"target"`
+	// LogTailLines is the number of log lines collected from each Humio pod. Defaults to 1000. The bundle is limited
+	// to 900 KiB, so the logs are truncated further for large clusters.
+	//+kubebuilder:validation:Minimum=1
+	LogTailLines int64 `json:"logTailLines,omitempty"`
+	// Image is the container image used to store the bundle. When the bundle is stored in a bucket, it must provide
+	// the aws command line interface. Defaults to busybox:1.36.1 when storing the bundle in a persistent volume claim,
+	// and amazon/aws-cli:2.15.10 when storing the bundle in a bucket.
+	Image string `json:"image,omitempty"`
+	// ServiceAccountName is the service account used by the pod storing the bundle, e.g. to obtain credentials using
+	// IAM roles for service accounts.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// HumioDiagnosticsBundleTarget describes where a diagnostics bundle is stored. Exactly one of
+// PersistentVolumeClaimName and Bucket must be set.
+type HumioDiagnosticsBundleTarget struct {
+	// PersistentVolumeClaimName is the name of a persistent volume claim the bundle is stored in. The files of the
+	// bundle are stored in a directory named after the job storing the bundle.
+	PersistentVolumeClaimName string `json:"persistentVolumeClaimName,omitempty"`
+	// Bucket is the S3 compatible bucket the bundle is stored in
+	Bucket *HumioClusterBackupTarget `json:"bucket,omitempty"`
+}
+
+// HumioDiagnosticsBundleStatus defines the observed state of HumioDiagnosticsBundle
+type HumioDiagnosticsBundleStatus struct {
+	// State reflects the current state of the HumioDiagnosticsBundle
+	State string `json:"state,omitempty"`
+	// Message contains the reason the HumioDiagnosticsBundle failed or is in the ConfigError state
+	Message string `json:"message,omitempty"`
+	// JobName is the name of the job storing the bundle
+	JobName string `json:"jobName,omitempty"`
+	// Location is where the bundle is stored
+	Location string `json:"location,omitempty"`
+	// Files lists the files of the bundle
+	Files []string `json:"files,omitempty"`
+	// SizeBytes is the size of the stored bundle
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+	// CompletionTime is the time the bundle was stored
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humiodiagnosticsbundles,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the diagnostics bundle"
+//+kubebuilder:printcolumn:name="Location",type="string",JSONPath=".status.location",description="Where the diagnostics bundle is stored"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Diagnostics Bundle"
+
+// HumioDiagnosticsBundle is the Schema for the humiodiagnosticsbundles API
+type HumioDiagnosticsBundle struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioDiagnosticsBundleSpec   `json:"spec,omitempty"`
+	Status HumioDiagnosticsBundleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioDiagnosticsBundleList contains a list of HumioDiagnosticsBundle
+type HumioDiagnosticsBundleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioDiagnosticsBundle `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioDiagnosticsBundle{}, &HumioDiagnosticsBundleList{})
+}
@@ -25,20 +25,116 @@ const (
 	HumioExternalClusterStateUnknown = "Unknown"
 	// HumioExternalClusterStateReady is the Ready state of the external cluster
 	HumioExternalClusterStateReady = "Ready"
+	// HumioExternalClusterStateUnreachable is the state of the external cluster when the operator is unable to reach it
+	HumioExternalClusterStateUnreachable = "Unreachable"
+	// HumioExternalClusterStateUnauthorized is the state of the external cluster when it is reachable, but the
+	// configured credentials are rejected
+	HumioExternalClusterStateUnauthorized = "Unauthorized"
 )
 
 // HumioExternalClusterSpec defines the desired state of HumioExternalCluster
 type HumioExternalClusterSpec struct {
 	// Url is used to connect to the Humio cluster we want to use.
+	// This cannot be used together with Cloud.
 	Url string `json:"url,omitempty"`
+	// Cloud is used to connect to an organization hosted in LogScale Cloud. The URL of the cluster is derived from
+	// the region and tenant, so Url must be left empty.
+	Cloud *HumioExternalClusterCloud `json:"cloud,omitempty"`
+	// FailoverUrls is a list of additional URLs of the same Humio cluster, e.g. load balancers in other regions. They
+	// are used in the listed order when the Humio cluster cannot be reached using Url. The operator fails back to Url
+	// as soon as it is reachable again.
+	FailoverUrls []string `json:"failoverUrls,omitempty"`
 	// APITokenSecretName is used to obtain the API token we need to use when communicating with the external Humio cluster.
 	// The secret must contain a key "token" which holds the Humio API token.
 	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// OAuth2 is used to obtain short-lived access tokens using the OAuth2 client credentials flow instead of using a
+	// static API token. Access tokens are refreshed automatically before they expire.
+	// This cannot be used together with APITokenSecretName.
+	OAuth2 *HumioExternalClusterOAuth2 `json:"oauth2,omitempty"`
 	// Insecure is used to disable TLS certificate verification when communicating with Humio clusters over TLS.
 	Insecure bool `json:"insecure,omitempty"`
 	// CASecretName is used to point to a Kubernetes secret that holds the CA that will be used to issue intra-cluster TLS certificates.
 	// The secret must contain a key "ca.crt" which holds the CA certificate in PEM format.
 	CASecretName string `json:"caSecretName,omitempty"`
+	// CAConfigMapName is used to point to a Kubernetes config map that holds the CA bundle used to verify the TLS
+	// certificate of the external Humio cluster, e.g. a bundle distributed by trust-manager. Changes to the config map
+	// are picked up automatically. This cannot be used together with CASecretName.
+	CAConfigMapName string `json:"caConfigMapName,omitempty"`
+	// CAConfigMapKey is the key in the config map referenced by CAConfigMapName which holds the CA bundle in PEM format.
+	// Defaults to "ca.crt".
+	CAConfigMapKey string `json:"caConfigMapKey,omitempty"`
+	// APITokenRotation is used to rotate the API token stored in the secret referenced by APITokenSecretName
+	// periodically.
+	APITokenRotation *HumioExternalClusterAPITokenRotation `json:"apiTokenRotation,omitempty"`
+	// ClientCertificateSecretName is used to point to a Kubernetes secret that holds the client certificate presented
+	// when connecting to the external Humio cluster, e.g. when the ingress in front of the cluster requires mutual TLS.
+	// The secret must be of type kubernetes.io/tls. Changes to the secret are picked up automatically.
+	ClientCertificateSecretName string `json:"clientCertificateSecretName,omitempty"`
+	// APITimeouts is used to configure the timeouts used by the operator when communicating with the external Humio
+	// cluster
+	APITimeouts *HumioAPITimeouts `json:"apiTimeouts,omitempty"`
+	// Proxy is used to configure an HTTP proxy which is used for connections towards the external Humio cluster.
+	Proxy *HumioExternalClusterProxy `json:"proxy,omitempty"`
+	// HealthCheckIntervalSeconds is how often the operator checks the health of the external Humio cluster and
+	// refreshes the status. Defaults to 15 seconds.
+	//+kubebuilder:validation:Minimum=1
+	HealthCheckIntervalSeconds int `json:"healthCheckIntervalSeconds,omitempty"`
+}
+
+// HumioExternalClusterCloud holds the configuration used to connect to an organization hosted in LogScale Cloud
+type HumioExternalClusterCloud struct {
+	// Region is the LogScale Cloud region hosting the organization.
+	//+kubebuilder:validation:Enum=eu;us;community
+	Region string `json:"region"`
+	// Tenant is the subdomain of a dedicated LogScale Cloud cluster, which replaces "cloud" in the hostname of the
+	// region. When empty, the shared cluster of the region is used.
+	Tenant string `json:"tenant,omitempty"`
+	// Organization is the name of the organization to manage. This is only needed when the API token belongs to a
+	// user who is a member of multiple organizations.
+	Organization string `json:"organization,omitempty"`
+	// OrganizationToken must be set when the API token is an organization API token rather than a personal API token.
+	// Organization API tokens do not belong to a user, so they are validated by listing the repositories and views of
+	// the organization, and they cannot be rotated using APITokenRotation.
+	OrganizationToken bool `json:"organizationToken,omitempty"`
+}
+
+// HumioExternalClusterOAuth2 holds the configuration used to authenticate against the external Humio cluster using
+// the OAuth2 client credentials flow
+type HumioExternalClusterOAuth2 struct {
+	// TokenURL is the token endpoint of the OIDC provider which issues access tokens for the Humio cluster.
+	TokenURL string `json:"tokenURL"`
+	// ClientSecretName is used to obtain the credentials of the OIDC client.
+	// The secret must contain the keys "client_id" and "client_secret".
+	ClientSecretName string `json:"clientSecretName"`
+	// Scopes is the list of scopes to request when obtaining access tokens.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// HumioExternalClusterAPITokenRotation holds the configuration used to rotate the API token of the external Humio
+// cluster
+type HumioExternalClusterAPITokenRotation struct {
+	// IntervalSeconds is how often the API token is rotated.
+	//+kubebuilder:validation:Minimum=3600
+	IntervalSeconds int `json:"intervalSeconds"`
+	// BootstrapTokenSecretName is used to obtain the API token used to rotate the API token of the operator. The API
+	// token must have permission to manage users, and must belong to a different user than the API token being rotated.
+	// The secret must contain a key "token" which holds the Humio API token.
+	BootstrapTokenSecretName string `json:"bootstrapTokenSecretName"`
+}
+
+// HumioExternalClusterProxy holds the proxy configuration used for connections towards the external Humio cluster.
+// Connections are tunneled through the proxy using HTTP CONNECT.
+type HumioExternalClusterProxy struct {
+	// HTTPProxy is the URL of the proxy used when the external Humio cluster is accessed over plain http.
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// HTTPSProxy is the URL of the proxy used when the external Humio cluster is accessed over https.
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// NoProxy is a comma-separated list of hosts, domains and CIDRs which should be accessed without using the proxy,
+	// using the same format as the NO_PROXY environment variable.
+	NoProxy string `json:"noProxy,omitempty"`
+	// CredentialsSecretName is used to obtain the credentials used to authenticate against the proxy.
+	// The secret must contain the keys "username" and "password".
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
 }
 
 // HumioExternalClusterStatus defines the observed state of HumioExternalCluster
@@ -47,12 +143,23 @@ type HumioExternalClusterStatus struct {
 	State string `json:"state,omitempty"`
 	// Version shows the Humio cluster version of the HumioExternalCluster
 	Version string `json:"version,omitempty"`
+	// ActiveUrl shows which of the URLs of the HumioExternalCluster is currently used
+	ActiveUrl string `json:"activeUrl,omitempty"`
+	// Username shows the user the operator was last authenticated as when communicating with the HumioExternalCluster
+	Username string `json:"username,omitempty"`
+	// Message contains the reason the last health check of the HumioExternalCluster failed
+	Message string `json:"message,omitempty"`
+	// LastHealthCheckTime is the time of the last health check of the HumioExternalCluster
+	LastHealthCheckTime *metav1.Time `json:"lastHealthCheckTime,omitempty"`
+	// LastAPITokenRotationTime is the time the API token of the HumioExternalCluster was last rotated
+	LastAPITokenRotationTime *metav1.Time `json:"lastAPITokenRotationTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:path=humioexternalclusters,scope=Namespaced
 //+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the external Humio cluster"
+//+kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version",description="The version of the external Humio cluster"
 //+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio External Cluster"
 
 // HumioExternalCluster is the Schema for the humioexternalclusters API
@@ -29,6 +29,8 @@ const (
 	HumioIngestTokenStateNotFound = "NotFound"
 	// HumioIngestTokenStateConfigError is the state of the ingest token when user-provided specification results in configuration error, such as non-existent humio cluster
 	HumioIngestTokenStateConfigError = "ConfigError"
+	// HumioIngestTokenStateClusterUnavailable is the state of the ingest token when the Humio cluster it targets cannot be reached
+	HumioIngestTokenStateClusterUnavailable = "ClusterUnavailable"
 )
 
 // HumioIngestTokenSpec defines the desired state of HumioIngestToken
@@ -40,6 +42,10 @@ type HumioIngestTokenSpec struct {
 	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
 	// This conflicts with ManagedClusterName.
 	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
 	// Name is the name of the ingest token inside Humio
 	Name string `json:"name"`
 	// ParserName is the name of the parser which will be assigned to the ingest token.
@@ -60,12 +66,26 @@ type HumioIngestTokenSpec struct {
 type HumioIngestTokenStatus struct {
 	// State reflects the current state of the HumioIngestToken
 	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the ingest token failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the ingest token is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// ClusterName is the name of the managed or external cluster the ingest token is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the ingest token was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// Conditions contains the conditions of the ingest token. The ClusterAvailable condition is False while the Humio
+	// cluster of the ingest token cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:path=humioingesttokens,scope=Namespaced
 //+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the ingest token"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the ingest token is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the ingest token was last synced successfully"
 //+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Ingest Token"
 
 // HumioIngestToken is the Schema for the humioingesttokens API
@@ -0,0 +1,142 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioMultiClusterViewStateUnknown is the Unknown state of the multi-cluster view
+	HumioMultiClusterViewStateUnknown = "Unknown"
+	// HumioMultiClusterViewStateExists is the Exists state of the multi-cluster view
+	HumioMultiClusterViewStateExists = "Exists"
+	// HumioMultiClusterViewStateNotFound is the NotFound state of the multi-cluster view
+	HumioMultiClusterViewStateNotFound = "NotFound"
+	// HumioMultiClusterViewStateConfigError is the state of the multi-cluster view when user-provided specification
+	// results in configuration error, such as non-existent humio cluster or a remote connection without a token
+	HumioMultiClusterViewStateConfigError = "ConfigError"
+	// HumioMultiClusterViewStateClusterUnavailable is the state of the multi-cluster view when the Humio cluster it
+	// targets cannot be reached
+	HumioMultiClusterViewStateClusterUnavailable = "ClusterUnavailable"
+	// HumioMultiClusterViewStateNotSupported is the state of the multi-cluster view when the Humio cluster it targets
+	// does not support multi-cluster search
+	HumioMultiClusterViewStateNotSupported = "NotSupported"
+)
+
+// HumioMultiClusterViewConnection is a connection of a multi-cluster view. A connection searching a Humio cluster
+// other than the cluster of the view is a remote connection, which requires a token for the remote cluster.
+type HumioMultiClusterViewConnection struct {
+	// ClusterIdentity identifies the connection, and is added to the results of searches as the clusteridentity tag.
+	// It must be unique within the view.
+	ClusterIdentity string `json:"clusterIdentity"`
+	// ManagedClusterName refers to a HumioCluster that is managed by the operator which the connection searches.
+	// Defaults to the cluster of the view.
+	// This conflicts with ExternalClusterName and URL.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to a HumioExternalCluster which the connection searches.
+	// This conflicts with ManagedClusterName and URL.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// URL is the URL of a remote Humio cluster which is not managed through a HumioCluster or HumioExternalCluster.
+	// It is also used to override the URL of ManagedClusterName or ExternalClusterName when the cluster of the view
+	// reaches the remote cluster through a different URL than the operator.
+	URL string `json:"url,omitempty"`
+	// ViewOrRepoName is the view or repository searched by a local connection. Remote connections search the view
+	// the token of the connection is scoped to.
+	ViewOrRepoName string `json:"viewOrRepoName,omitempty"`
+	// APITokenSecretName is the name of a secret holding a token scoped to the view searched on the remote cluster.
+	// The secret must contain a key "token". Required for remote connections.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// Filter is a query prefix applied to searches of the connection
+	Filter string `json:"filter,omitempty"`
+}
+
+// HumioMultiClusterViewSpec defines the desired state of HumioMultiClusterView
+type HumioMultiClusterViewSpec struct {
+	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator where the
+	// multi-cluster view should be created.
+	// This conflicts with ExternalClusterName.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to an object of type HumioExternalCluster where the multi-cluster view should be
+	// created.
+	// This conflicts with ManagedClusterName.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// Name is the name of the multi-cluster view inside Humio
+	Name string `json:"name"`
+	// Description contains the description of the multi-cluster view
+	Description string `json:"description,omitempty"`
+	// Connections contains the connections to the views and repositories searched by the multi-cluster view
+	//+kubebuilder:validation:MinItems=1
+	Connections []HumioMultiClusterViewConnection `json:"connections"`
+}
+
+// HumioMultiClusterViewStatus defines the observed state of HumioMultiClusterView
+type HumioMultiClusterViewStatus struct {
+	// State reflects the current state of the HumioMultiClusterView
+	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the multi-cluster view failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the multi-cluster view is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// Message contains the reason the HumioMultiClusterView is in the ConfigError or NotSupported state
+	Message string `json:"message,omitempty"`
+	// ClusterName is the name of the managed or external cluster the multi-cluster view is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the multi-cluster view was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// ConnectionTokenHashes holds a hash of the token each remote connection was last configured with, keyed by
+	// cluster identity, so connections are updated when their tokens are rotated
+	ConnectionTokenHashes map[string]string `json:"connectionTokenHashes,omitempty"`
+	// Conditions contains the conditions of the multi-cluster view. The ClusterAvailable condition is False while the Humio
+	// cluster of the multi-cluster view cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humiomulticlusterviews,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the multi-cluster view"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the multi-cluster view is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the multi-cluster view was last synced successfully"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Multi-Cluster View"
+
+// HumioMultiClusterView is the Schema for the humiomulticlusterviews API
+type HumioMultiClusterView struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioMultiClusterViewSpec   `json:"spec,omitempty"`
+	Status HumioMultiClusterViewStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioMultiClusterViewList contains a list of HumioMultiClusterView
+type HumioMultiClusterViewList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioMultiClusterView `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioMultiClusterView{}, &HumioMultiClusterViewList{})
+}
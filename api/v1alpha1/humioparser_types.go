@@ -29,6 +29,8 @@ const (
 	HumioParserStateNotFound = "NotFound"
 	// HumioParserStateConfigError is the state of the parser when user-provided specification results in configuration error, such as non-existent humio cluster
 	HumioParserStateConfigError = "ConfigError"
+	// HumioParserStateClusterUnavailable is the state of the parser when the Humio cluster it targets cannot be reached
+	HumioParserStateClusterUnavailable = "ClusterUnavailable"
 )
 
 // HumioParserSpec defines the desired state of HumioParser
@@ -40,6 +42,10 @@ type HumioParserSpec struct {
 	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
 	// This conflicts with ManagedClusterName.
 	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
 	// Name is the name of the parser inside Humio
 	Name string `json:"name,omitempty"`
 	// ParserScript contains the code for the Humio parser
@@ -51,18 +57,54 @@ type HumioParserSpec struct {
 	TagFields []string `json:"tagFields,omitempty"`
 	// TestData contains example test data to verify the parser behavior
 	TestData []string `json:"testData,omitempty"`
+	// DriftPolicy controls what happens when the parser is changed outside the operator, e.g. in the Humio UI. Enforce
+	// reverts the changes, while Warn leaves them in place until the spec changes, and records a Drifted condition
+	// and event. Import behaves like Warn, and also records a patch importing the changes into the spec in
+	// status.driftPatch. Defaults to Enforce.
+	//+kubebuilder:validation:Enum=Enforce;Warn;Import
+	DriftPolicy string `json:"driftPolicy,omitempty"`
 }
 
 // HumioParserStatus defines the observed state of HumioParser
 type HumioParserStatus struct {
 	// State reflects the current state of the HumioParser
 	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the parser failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the parser is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// ID is the ID of the parser inside Humio
+	ID string `json:"id,omitempty"`
+	// ClusterName is the name of the managed or external cluster the parser is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the parser was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// LastResyncRequest is the value of the humio.com/trigger-resync annotation when the parser was last resynced
+	LastResyncRequest string `json:"lastResyncRequest,omitempty"`
+	// AppliedHash is a hash of the desired state of the parser which was last applied, which is used to tell changes to
+	// the spec apart from changes made outside the operator
+	AppliedHash string `json:"appliedHash,omitempty"`
+	// SyncedGeneration is the generation of the parser when it was last synced successfully. While the generation and
+	// the applied hash are unchanged, the parser is only compared with Humio every ten minutes to detect changes made
+	// outside the operator.
+	SyncedGeneration int64 `json:"syncedGeneration,omitempty"`
+	// DriftPatch is a JSON merge patch which imports the changes made to the parser outside the operator into the spec.
+	// It is only set while the Drifted condition is True and the drift policy is Import.
+	DriftPatch string `json:"driftPatch,omitempty"`
+	// Conditions contains the conditions of the parser. The Drifted condition is True while changes made outside the
+	// operator are left in place because of the Warn drift policy.
+	// The ClusterAvailable condition is False while the Humio cluster cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:path=humioparsers,scope=Namespaced
 //+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the parser"
+//+kubebuilder:printcolumn:name="ID",type="string",JSONPath=".status.id",description="The ID of the parser inside Humio"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the parser is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the parser was last synced successfully"
 //+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Parser"
 
 // HumioParser is the Schema for the humioparsers API
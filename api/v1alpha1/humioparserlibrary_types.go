@@ -0,0 +1,127 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioParserLibraryStateSyncing is the state of the parser library while the Git repository is being read
+	HumioParserLibraryStateSyncing = "Syncing"
+	// HumioParserLibraryStateSynced is the state of the parser library when the parsers of the last sync were applied
+	HumioParserLibraryStateSynced = "Synced"
+	// HumioParserLibraryStateSyncFailed is the state of the parser library when the last sync failed, e.g. because the
+	// Git repository could not be cloned or contains invalid parser definitions. The parsers of the last successful
+	// sync are kept.
+	HumioParserLibraryStateSyncFailed = "SyncFailed"
+	// HumioParserLibraryStateConfigError is the state of the parser library when user-provided specification results
+	// in configuration error, such as a missing Git repository URL
+	HumioParserLibraryStateConfigError = "ConfigError"
+)
+
+// HumioParserLibrarySpec defines the desired state of HumioParserLibrary
+type HumioParserLibrarySpec struct {
+	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator where the parsers
+	// should be created.
+	// This conflicts with ExternalClusterName.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to an object of type HumioExternalCluster where the parsers should be created.
+	// This conflicts with ManagedClusterName.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for the parsers of the library.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// RepositoryName is the repository the parsers of the library are managed in
+	RepositoryName string `json:"repositoryName"`
+	// Git is the Git repository holding the parser definitions. Every file ending in .yaml or .yml below the path holds
+	// one or more parser definitions with the fields name, parserScript, tagFields and testData, which are
+	// materialized as HumioParser resources in the namespace of the library.
+	Git HumioParserLibraryGit `json:"git"`
+	// SyncIntervalSeconds is how often the Git repository is read. Defaults to 300.
+	//+kubebuilder:validation:Minimum=60
+	SyncIntervalSeconds int `json:"syncIntervalSeconds,omitempty"`
+	// Image is the container image used to clone the Git repository. It must provide git and a shell. Defaults to
+	// alpine/git:2.43.0.
+	Image string `json:"image,omitempty"`
+	// ServiceAccountName is the service account used by the pod cloning the Git repository
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// HumioParserLibraryGit describes the Git repository holding the parser definitions of a HumioParserLibrary
+type HumioParserLibraryGit struct {
+	// URL is the HTTPS URL of the Git repository
+	URL string `json:"url"`
+	// Branch is the branch the parser definitions are read from. Defaults to main.
+	Branch string `json:"branch,omitempty"`
+	// Path is the directory within the Git repository holding the parser definitions. Defaults to the root of the
+	// repository.
+	Path string `json:"path,omitempty"`
+	// CredentialsSecretName is the name of a secret holding the keys "username" and "password" used to clone the Git
+	// repository, e.g. a personal access token for the password. Public repositories are cloned without credentials.
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
+}
+
+// HumioParserLibraryStatus defines the observed state of HumioParserLibrary
+type HumioParserLibraryStatus struct {
+	// State reflects the current state of the HumioParserLibrary
+	State string `json:"state,omitempty"`
+	// Message contains the reason the HumioParserLibrary is in the SyncFailed or ConfigError state
+	Message string `json:"message,omitempty"`
+	// JobName is the name of the job cloning the Git repository while the library is syncing
+	JobName string `json:"jobName,omitempty"`
+	// Commit is the Git commit the parsers were last synced from
+	Commit string `json:"commit,omitempty"`
+	// Parsers lists the HumioParser resources materialized from the library
+	Parsers []string `json:"parsers,omitempty"`
+	// LastScheduleTime is the time the last sync was started
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSyncTime is the time the parsers were last synced successfully
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// ObservedGeneration is the generation of the HumioParserLibrary which was last synced
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioparserlibraries,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the parser library"
+//+kubebuilder:printcolumn:name="Commit",type="string",JSONPath=".status.commit",description="The Git commit the parsers were last synced from"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Parser Library"
+
+// HumioParserLibrary is the Schema for the humioparserlibraries API
+type HumioParserLibrary struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioParserLibrarySpec   `json:"spec,omitempty"`
+	Status HumioParserLibraryStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioParserLibraryList contains a list of HumioParserLibrary
+type HumioParserLibraryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioParserLibrary `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioParserLibrary{}, &HumioParserLibraryList{})
+}
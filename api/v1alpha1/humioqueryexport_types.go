@@ -0,0 +1,177 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioQueryExportStateIdle is the state of the query export when no export is currently running
+	HumioQueryExportStateIdle = "Idle"
+	// HumioQueryExportStateRunning is the state of the query export while an export is running
+	HumioQueryExportStateRunning = "Running"
+	// HumioQueryExportStateSucceeded is the state of an export which was uploaded successfully
+	HumioQueryExportStateSucceeded = "Succeeded"
+	// HumioQueryExportStateFailed is the state of an export which could not be uploaded
+	HumioQueryExportStateFailed = "Failed"
+	// HumioQueryExportStateConfigError is the state of the query export when user-provided specification results in
+	// configuration error, such as non-existent humio cluster
+	HumioQueryExportStateConfigError = "ConfigError"
+	// HumioQueryExportStateClusterUnavailable is the state of the query export when the Humio cluster it targets
+	// cannot be reached
+	HumioQueryExportStateClusterUnavailable = "ClusterUnavailable"
+
+	// HumioQueryExportFormatCSV exports the results as comma separated values
+	HumioQueryExportFormatCSV = "CSV"
+	// HumioQueryExportFormatNDJSON exports the results as newline delimited JSON
+	HumioQueryExportFormatNDJSON = "NDJSON"
+	// HumioQueryExportFormatParquet exports the results as an Apache Parquet file
+	HumioQueryExportFormatParquet = "Parquet"
+
+	// HumioQueryExportDestinationS3 uploads the results to an S3 compatible bucket
+	HumioQueryExportDestinationS3 = "S3"
+	// HumioQueryExportDestinationGCS uploads the results to a Google Cloud Storage bucket
+	HumioQueryExportDestinationGCS = "GCS"
+)
+
+// HumioQueryExportSpec defines the desired state of HumioQueryExport
+type HumioQueryExportSpec struct {
+	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator where the Humio
+	// resources should be created.
+	// This conflicts with ExternalClusterName.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
+	// This conflicts with ManagedClusterName.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// ViewName is the name of the Humio repository or view the query is executed against
+	ViewName string `json:"viewName"`
+	// QueryString is the Humio query to execute. Saved queries can be executed by invoking them, e.g. $"my-query"().
+	QueryString string `json:"queryString"`
+	// Start is the start of the time range searched by the query, either relative to the time the query is executed,
+	// e.g. "24h", or absolute in milliseconds since the epoch. Defaults to "24h".
+	Start string `json:"start,omitempty"`
+	// End is the end of the time range searched by the query, using the same format as Start. Defaults to the time
+	// the query is executed.
+	End string `json:"end,omitempty"`
+	// Schedule is a cron expression, e.g. "0 2 * * *", describing when the results are exported
+	Schedule string `json:"schedule"`
+	// Suspend prevents new exports from being started
+	Suspend bool `json:"suspend,omitempty"`
+	// Format is the file format of the exported results. Parquet requires an image which provides the duckdb command
+	// line interface in addition to the command line interface of the destination. Defaults to NDJSON.
+	//+kubebuilder:validation:Enum=CSV;NDJSON;Parquet
+	Format string `json:"format,omitempty"`
+	// Destination is the bucket the results are exported to
+	Destination HumioQueryExportDestination `json:"destination"`
+	// Image is the container image used to execute the query and upload the results. It must provide curl and the
+	// command line interface of the destination, i.e. aws for S3 and gsutil for GCS. Defaults to
+	// amazon/aws-cli:2.15.10 for S3 and google/cloud-sdk:460.0.0-slim for GCS.
+	Image string `json:"image,omitempty"`
+	// ServiceAccountName is the service account used by the pods exporting the results, e.g. to obtain credentials
+	// using IAM roles for service accounts or workload identity.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// HistoryLimit is the number of exports listed in the status. Defaults to 5.
+	//+kubebuilder:validation:Minimum=1
+	HistoryLimit int `json:"historyLimit,omitempty"`
+}
+
+// HumioQueryExportDestination describes the bucket the results are exported to
+type HumioQueryExportDestination struct {
+	// Type is the type of object storage. Defaults to S3.
+	//+kubebuilder:validation:Enum=S3;GCS
+	Type string `json:"type,omitempty"`
+	// Bucket is the name of the bucket
+	Bucket string `json:"bucket"`
+	// Key is a Go template for the key the results are stored under. The template can refer to .Name, .Namespace,
+	// .Time, which is the time the export was started, and .Extension, which is the file extension of the format.
+	// Defaults to {{ .Name }}/{{ .Time.Format "2006-01-02T15-04-05Z" }}.{{ .Extension }}
+	Key string `json:"key,omitempty"`
+	// Region is the region of the bucket. Only used for S3.
+	Region string `json:"region,omitempty"`
+	// Endpoint is the URL of the S3 compatible object storage. Defaults to AWS S3. Only used for S3.
+	Endpoint string `json:"endpoint,omitempty"`
+	// CredentialsSecretName is used to obtain the credentials used to upload the results. All keys of the secret are
+	// exposed as environment variables, e.g. "AWS_ACCESS_KEY_ID" and "AWS_SECRET_ACCESS_KEY" for S3. When empty, the
+	// credentials are obtained from the environment, e.g. using IAM roles for service accounts.
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
+}
+
+// HumioQueryExportArtifact describes a single export
+type HumioQueryExportArtifact struct {
+	// Name is the name of the job running the export
+	Name string `json:"name"`
+	// State is the state of the export
+	State string `json:"state"`
+	// Location is the URL of the exported results
+	Location string `json:"location,omitempty"`
+	// SizeBytes is the size of the exported results
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+	// StartTime is the time the export was started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the export completed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Message contains the reason the export failed
+	Message string `json:"message,omitempty"`
+}
+
+// HumioQueryExportStatus defines the observed state of HumioQueryExport
+type HumioQueryExportStatus struct {
+	// State reflects the current state of the HumioQueryExport
+	State string `json:"state,omitempty"`
+	// Message contains the reason the HumioQueryExport is in the ConfigError or ClusterUnavailable state
+	Message string `json:"message,omitempty"`
+	// LastScheduleTime is the time the last export was started
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSuccessfulTime is the time the last successful export completed
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// Exports lists the most recent exports, newest first
+	Exports []HumioQueryExportArtifact `json:"exports,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioqueryexports,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the query export"
+//+kubebuilder:printcolumn:name="Last Successful",type="date",JSONPath=".status.lastSuccessfulTime",description="The time the last successful export completed"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Query Export"
+
+// HumioQueryExport is the Schema for the humioqueryexports API
+type HumioQueryExport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioQueryExportSpec   `json:"spec,omitempty"`
+	Status HumioQueryExportStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioQueryExportList contains a list of HumioQueryExport
+type HumioQueryExportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioQueryExport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioQueryExport{}, &HumioQueryExportList{})
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioQueryJobStatePending is the state of the query job before the query has been started
+	HumioQueryJobStatePending = "Pending"
+	// HumioQueryJobStateRunning is the state of the query job while the query is running
+	HumioQueryJobStateRunning = "Running"
+	// HumioQueryJobStateSucceeded is the state of the query job when the results of the last query have been stored
+	HumioQueryJobStateSucceeded = "Succeeded"
+	// HumioQueryJobStateFailed is the state of the query job when the last query failed or its results could not be
+	// stored
+	HumioQueryJobStateFailed = "Failed"
+	// HumioQueryJobStateConfigError is the state of the query job when user-provided specification results in
+	// configuration error, such as non-existent humio cluster
+	HumioQueryJobStateConfigError = "ConfigError"
+	// HumioQueryJobStateClusterUnavailable is the state of the query job when the Humio cluster it targets cannot be
+	// reached
+	HumioQueryJobStateClusterUnavailable = "ClusterUnavailable"
+)
+
+// HumioQueryJobSpec defines the desired state of HumioQueryJob
+type HumioQueryJobSpec struct {
+	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator where the Humio
+	// resources should be created.
+	// This conflicts with ExternalClusterName.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
+	// This conflicts with ManagedClusterName.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// ViewName is the name of the Humio repository or view the query is executed against
+	ViewName string `json:"viewName"`
+	// QueryString is the Humio query to execute
+	QueryString string `json:"queryString"`
+	// Start is the start of the time range searched by the query, either relative to the time the query is executed,
+	// e.g. "24h", or absolute in milliseconds since the epoch. Defaults to "24h".
+	Start string `json:"start,omitempty"`
+	// End is the end of the time range searched by the query, using the same format as Start. Defaults to the time
+	// the query is executed.
+	End string `json:"end,omitempty"`
+	// Schedule is a cron expression, e.g. "0 2 * * *", describing when the query is executed. When empty, the query
+	// is executed once when the HumioQueryJob is created.
+	Schedule string `json:"schedule,omitempty"`
+	// Suspend prevents new queries from being started
+	Suspend bool `json:"suspend,omitempty"`
+	// Output describes where the results of the query are stored
+	Output HumioQueryJobOutput `json:"output"`
+}
+
+// HumioQueryJobOutput describes where the results of a query are stored. Exactly one of the fields must be set.
+// Results are stored as a JSON array holding the events returned by the query.
+type HumioQueryJobOutput struct {
+	// ConfigMap stores the results in a ConfigMap owned by the HumioQueryJob
+	ConfigMap *HumioQueryJobObjectOutput `json:"configMap,omitempty"`
+	// Secret stores the results in a Secret owned by the HumioQueryJob
+	Secret *HumioQueryJobObjectOutput `json:"secret,omitempty"`
+	// Webhook pushes the results to a webhook
+	Webhook *HumioQueryJobWebhookOutput `json:"webhook,omitempty"`
+}
+
+// HumioQueryJobObjectOutput describes the ConfigMap or Secret the results of a query are stored in
+type HumioQueryJobObjectOutput struct {
+	// Name is the name of the ConfigMap or Secret. It is created if it does not exist, and must not be used by
+	// anything else, as the results replace its contents.
+	Name string `json:"name"`
+	// Key is the key the results are stored under. Defaults to "results.json".
+	Key string `json:"key,omitempty"`
+}
+
+// HumioQueryJobWebhookOutput describes the webhook the results of a query are pushed to
+type HumioQueryJobWebhookOutput struct {
+	// URL is the URL the results are sent to using a POST request
+	URL string `json:"url"`
+	// Headers are added to the request sent to the webhook
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// HumioQueryJobStatus defines the observed state of HumioQueryJob
+type HumioQueryJobStatus struct {
+	// State reflects the current state of the HumioQueryJob
+	State string `json:"state,omitempty"`
+	// Message contains details about the state, such as the error of the last query
+	Message string `json:"message,omitempty"`
+	// QueryJobID is the ID of the Humio query job of the query which is currently running
+	QueryJobID string `json:"queryJobID,omitempty"`
+	// EventCount is the number of events returned by the last query which finished successfully
+	EventCount int64 `json:"eventCount,omitempty"`
+	// LastScheduleTime is the last time a query was started
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSuccessfulTime is the last time the results of a query were stored successfully
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioqueryjobs,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the query job"
+//+kubebuilder:printcolumn:name="Events",type="integer",JSONPath=".status.eventCount",description="The number of events returned by the last query"
+//+kubebuilder:printcolumn:name="Last Success",type="date",JSONPath=".status.lastSuccessfulTime",description="The last time the results of a query were stored"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Query Job"
+
+// HumioQueryJob is the Schema for the humioqueryjobs API
+type HumioQueryJob struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioQueryJobSpec   `json:"spec,omitempty"`
+	Status HumioQueryJobStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioQueryJobList contains a list of HumioQueryJob
+type HumioQueryJobList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioQueryJob `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioQueryJob{}, &HumioQueryJobList{})
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioRehydrationJobStatePending is the state of the rehydration job before it has been started
+	HumioRehydrationJobStatePending = "Pending"
+	// HumioRehydrationJobStateRunning is the state of the rehydration job while segments are being fetched
+	HumioRehydrationJobStateRunning = "Running"
+	// HumioRehydrationJobStateCompleted is the state of the rehydration job when all segments have been fetched
+	HumioRehydrationJobStateCompleted = "Completed"
+	// HumioRehydrationJobStateFailed is the state of the rehydration job when segments could not be fetched
+	HumioRehydrationJobStateFailed = "Failed"
+	// HumioRehydrationJobStateConfigError is the state of the rehydration job when user-provided specification
+	// results in configuration error, such as non-existent humio cluster
+	HumioRehydrationJobStateConfigError = "ConfigError"
+	// HumioRehydrationJobStateClusterUnavailable is the state of the rehydration job when the Humio cluster it
+	// targets cannot be reached
+	HumioRehydrationJobStateClusterUnavailable = "ClusterUnavailable"
+)
+
+// HumioRehydrationJobSpec defines the desired state of HumioRehydrationJob
+type HumioRehydrationJobSpec struct {
+	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator where the Humio
+	// resources should be created.
+	// This conflicts with ExternalClusterName.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
+	// This conflicts with ManagedClusterName.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// Repositories are the names of the repositories inside Humio for which segments are fetched from bucket storage
+	//+kubebuilder:validation:MinItems=1
+	Repositories []string `json:"repositories"`
+	// Start is the start of the time range for which segments are fetched
+	Start metav1.Time `json:"start"`
+	// End is the end of the time range for which segments are fetched. Defaults to the time the job is started.
+	End *metav1.Time `json:"end,omitempty"`
+	// TTLSecondsAfterFinished limits the lifetime of a HumioRehydrationJob that has finished. Once it has elapsed, the
+	// HumioRehydrationJob is deleted. Fetched segments are kept on the local disks of the Humio nodes until Humio
+	// evicts them according to its local storage settings.
+	//+kubebuilder:validation:Minimum=0
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// HumioRehydrationJobRepositoryStatus describes the progress of fetching segments of a single repository
+type HumioRehydrationJobRepositoryStatus struct {
+	// Name is the name of the repository inside Humio
+	Name string `json:"name"`
+	// State reflects the current state of fetching segments for the repository
+	State string `json:"state,omitempty"`
+	// QueryJobID is the ID of the Humio query job used to fetch the segments
+	QueryJobID string `json:"queryJobID,omitempty"`
+	// Progress is the percentage of the segments in the time range that has been fetched
+	Progress int32 `json:"progress,omitempty"`
+	// ProcessedBytes is the amount of data in the time range that has been fetched
+	ProcessedBytes int64 `json:"processedBytes,omitempty"`
+	// Message contains details about the state
+	Message string `json:"message,omitempty"`
+}
+
+// HumioRehydrationJobStatus defines the observed state of HumioRehydrationJob
+type HumioRehydrationJobStatus struct {
+	// State reflects the current state of the HumioRehydrationJob
+	State string `json:"state,omitempty"`
+	// Message contains details about the state
+	Message string `json:"message,omitempty"`
+	// Progress is the percentage of the segments in the time range that has been fetched across all repositories
+	Progress int32 `json:"progress,omitempty"`
+	// Repositories contains the progress of each repository
+	Repositories []HumioRehydrationJobRepositoryStatus `json:"repositories,omitempty"`
+	// StartTime is the time the job was started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the job finished
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humiorehydrationjobs,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the rehydration job"
+//+kubebuilder:printcolumn:name="Progress",type="integer",JSONPath=".status.progress",description="The percentage of segments fetched"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Rehydration Job"
+
+// HumioRehydrationJob is the Schema for the humiorehydrationjobs API
+type HumioRehydrationJob struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioRehydrationJobSpec   `json:"spec,omitempty"`
+	Status HumioRehydrationJobStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioRehydrationJobList contains a list of HumioRehydrationJob
+type HumioRehydrationJobList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioRehydrationJob `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioRehydrationJob{}, &HumioRehydrationJobList{})
+}
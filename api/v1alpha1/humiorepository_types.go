@@ -29,6 +29,8 @@ const (
 	HumioRepositoryStateNotFound = "NotFound"
 	// HumioRepositoryStateConfigError is the state of the repository when user-provided specification results in configuration error, such as non-existent humio cluster
 	HumioRepositoryStateConfigError = "ConfigError"
+	// HumioRepositoryStateClusterUnavailable is the state of the repository when the Humio cluster it targets cannot be reached
+	HumioRepositoryStateClusterUnavailable = "ClusterUnavailable"
 )
 
 // HumioRetention defines the retention for the repository
@@ -40,6 +42,51 @@ type HumioRetention struct {
 	TimeInDays      int32 `json:"timeInDays,omitempty"`
 }
 
+// HumioRepositoryIngestQuota limits how much data may be ingested into the repository. Ingest into the repository is
+// blocked while a limit is exceeded, and unblocked once the ingested data falls below the limits again. Ingested data
+// is measured using the humio-usage repository, so ingest is blocked shortly after a limit is exceeded rather than
+// right away.
+type HumioRepositoryIngestQuota struct {
+	// DailyLimitGB is the amount of data which may be ingested during the last 24 hours
+	//+kubebuilder:validation:Minimum=0
+	DailyLimitGB int32 `json:"dailyLimitGB,omitempty"`
+	// BurstLimitGB is the amount of data which may be ingested during the last hour, which limits how quickly a single
+	// service may use up the daily limit
+	//+kubebuilder:validation:Minimum=0
+	BurstLimitGB int32 `json:"burstLimitGB,omitempty"`
+}
+
+// HumioRepositoryIngestQuotaStatus reports the ingest into the repository measured against its ingest quota
+type HumioRepositoryIngestQuotaStatus struct {
+	// DailyIngestBytes is the amount of data ingested during the last 24 hours
+	DailyIngestBytes int64 `json:"dailyIngestBytes"`
+	// BurstIngestBytes is the amount of data ingested during the last hour
+	BurstIngestBytes int64 `json:"burstIngestBytes"`
+	// Blocked is whether ingest into the repository is blocked because a limit is exceeded
+	Blocked bool `json:"blocked,omitempty"`
+	// Message describes the exceeded limit while ingest is blocked
+	Message string `json:"message,omitempty"`
+	// CheckTime is when the ingested data was last measured
+	CheckTime *metav1.Time `json:"checkTime,omitempty"`
+}
+
+// HumioRepositoryStatistics describes the data stored in the repository
+type HumioRepositoryStatistics struct {
+	// SegmentCount is the number of segments holding the events of the repository
+	SegmentCount int `json:"segmentCount"`
+	// CompressedBytes is the size of the data of the repository after compression
+	CompressedBytes int64 `json:"compressedBytes"`
+	// UncompressedBytes is the size of the data of the repository before compression
+	UncompressedBytes int64 `json:"uncompressedBytes"`
+	// CompressionRatio is the uncompressed size divided by the compressed size, formatted with two decimals as floats
+	// are not supported in the status
+	CompressionRatio string `json:"compressionRatio,omitempty"`
+	// OldestEventTime is the timestamp of the oldest event stored in the repository
+	OldestEventTime *metav1.Time `json:"oldestEventTime,omitempty"`
+	// CollectionTime is when the statistics were last collected
+	CollectionTime *metav1.Time `json:"collectionTime,omitempty"`
+}
+
 // HumioRepositorySpec defines the desired state of HumioRepository
 type HumioRepositorySpec struct {
 	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator where the Humio
@@ -49,6 +96,10 @@ type HumioRepositorySpec struct {
 	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
 	// This conflicts with ManagedClusterName.
 	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
 	// Name is the name of the repository inside Humio
 	Name string `json:"name,omitempty"`
 	// Description contains the description that will be set on the repository
@@ -59,18 +110,46 @@ type HumioRepositorySpec struct {
 	// repository. This must be set to true before the operator will apply retention settings that will (or might)
 	// cause data to be deleted within the repository.
 	AllowDataDeletion bool `json:"allowDataDeletion,omitempty"`
+	// IngestQuota limits how much data may be ingested into the repository
+	IngestQuota *HumioRepositoryIngestQuota `json:"ingestQuota,omitempty"`
 }
 
 // HumioRepositoryStatus defines the observed state of HumioRepository
 type HumioRepositoryStatus struct {
 	// State reflects the current state of the HumioRepository
 	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the repository failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the repository is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// ID is the ID of the repository inside Humio
+	ID string `json:"id,omitempty"`
+	// ClusterName is the name of the managed or external cluster the repository is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the repository was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// IngestQuota reports the ingest into the repository measured against the ingest quota
+	IngestQuota *HumioRepositoryIngestQuotaStatus `json:"ingestQuota,omitempty"`
+	// RetentionPolicyName is the name of the HumioRetentionPolicy applied to the repository
+	RetentionPolicyName string `json:"retentionPolicyName,omitempty"`
+	// Statistics describes the data stored in the repository, and is collected periodically
+	Statistics *HumioRepositoryStatistics `json:"statistics,omitempty"`
+	// Conditions contains the conditions of the repository. The ClusterAvailable condition is False while the Humio
+	// cluster of the repository cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:path=humiorepositories,scope=Namespaced
 //+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the repository"
+//+kubebuilder:printcolumn:name="ID",type="string",JSONPath=".status.id",description="The ID of the repository inside Humio"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the repository is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the repository was last synced successfully"
+//+kubebuilder:printcolumn:name="Segments",type="integer",JSONPath=".status.statistics.segmentCount",description="The number of segments of the repository",priority=1
+//+kubebuilder:printcolumn:name="Compression",type="string",JSONPath=".status.statistics.compressionRatio",description="The compression ratio of the repository",priority=1
+//+kubebuilder:printcolumn:name="Oldest Event",type="date",JSONPath=".status.statistics.oldestEventTime",description="The timestamp of the oldest event of the repository",priority=1
 //+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Repository"
 
 // HumioRepository is the Schema for the humiorepositories API
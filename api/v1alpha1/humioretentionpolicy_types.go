@@ -0,0 +1,81 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioRetentionPolicyStateActive is the state of the retention policy when it is applied to the repositories it
+	// selects
+	HumioRetentionPolicyStateActive = "Active"
+	// HumioRetentionPolicyStateConfigError is the state of the retention policy when user-provided specification
+	// results in configuration error, such as an invalid repository selector
+	HumioRetentionPolicyStateConfigError = "ConfigError"
+)
+
+// HumioRetentionPolicySpec defines the desired state of HumioRetentionPolicy
+type HumioRetentionPolicySpec struct {
+	// RepositorySelector selects the HumioRepository resources in the namespace of the policy which the retention is
+	// applied to. When several policies select the same repository, the oldest policy is applied.
+	RepositorySelector metav1.LabelSelector `json:"repositorySelector"`
+	// Retention is applied to the selected repositories. Retention options which are set override the retention of
+	// the repository, while options which are left out keep the retention of the repository.
+	Retention HumioRetention `json:"retention"`
+	// AllowDataDeletion allows the policy to apply retention settings that will (or might) cause data to be deleted
+	// within the selected repositories, even if the repositories do not allow data deletion themselves.
+	AllowDataDeletion bool `json:"allowDataDeletion,omitempty"`
+}
+
+// HumioRetentionPolicyStatus defines the observed state of HumioRetentionPolicy
+type HumioRetentionPolicyStatus struct {
+	// State reflects the current state of the HumioRetentionPolicy
+	State string `json:"state,omitempty"`
+	// Message contains the reason the HumioRetentionPolicy is in the ConfigError state
+	Message string `json:"message,omitempty"`
+	// Repositories lists the HumioRepository resources the policy is applied to
+	Repositories []string `json:"repositories,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioretentionpolicies,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the retention policy"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Retention Policy"
+
+// HumioRetentionPolicy is the Schema for the humioretentionpolicies API
+type HumioRetentionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioRetentionPolicySpec   `json:"spec,omitempty"`
+	Status HumioRetentionPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioRetentionPolicyList contains a list of HumioRetentionPolicy
+type HumioRetentionPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioRetentionPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioRetentionPolicy{}, &HumioRetentionPolicyList{})
+}
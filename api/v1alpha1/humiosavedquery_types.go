@@ -0,0 +1,135 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioSavedQueryStateUnknown is the Unknown state of the saved query
+	HumioSavedQueryStateUnknown = "Unknown"
+	// HumioSavedQueryStateExists is the Exists state of the saved query
+	HumioSavedQueryStateExists = "Exists"
+	// HumioSavedQueryStateNotFound is the NotFound state of the saved query
+	HumioSavedQueryStateNotFound = "NotFound"
+	// HumioSavedQueryStateConfigError is the state of the saved query when user-provided specification results in configuration error, such as non-existent humio cluster
+	HumioSavedQueryStateConfigError = "ConfigError"
+	// HumioSavedQueryStateClusterUnavailable is the state of the saved query when the Humio cluster it targets cannot be reached
+	HumioSavedQueryStateClusterUnavailable = "ClusterUnavailable"
+)
+
+// HumioSavedQuerySpec defines the desired state of HumioSavedQuery
+type HumioSavedQuerySpec struct {
+	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator where the Humio
+	// resources should be created.
+	// This conflicts with ExternalClusterName.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
+	// This conflicts with ManagedClusterName.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// Name is the name of the saved query inside Humio
+	Name string `json:"name"`
+	// ViewName is the name of the Humio View under which the saved query will be managed. This can also be a Repository
+	ViewName string `json:"viewName"`
+	// Description is the description of the saved query
+	Description string `json:"description,omitempty"`
+	// QueryString is the Humio query of the saved query. Parameters of the query are written as ?name.
+	QueryString string `json:"queryString"`
+	// Start is the start time of the saved query. Defaults to "24h"
+	Start string `json:"start,omitempty"`
+	// End is the end time of the saved query. Defaults to "now"
+	End string `json:"end,omitempty"`
+	// IsLive sets whether the saved query is a live query
+	IsLive bool `json:"isLive,omitempty"`
+	// Arguments holds the values of the parameters of the query, keyed by parameter name
+	Arguments map[string]string `json:"arguments,omitempty"`
+	// DriftPolicy controls what happens when the saved query is changed outside the operator, e.g. in the Humio UI. Enforce
+	// reverts the changes, while Warn leaves them in place until the spec changes, and records a Drifted condition
+	// and event. Import behaves like Warn, and also records a patch importing the changes into the spec in
+	// status.driftPatch. Defaults to Enforce.
+	//+kubebuilder:validation:Enum=Enforce;Warn;Import
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+}
+
+// HumioSavedQueryStatus defines the observed state of HumioSavedQuery
+type HumioSavedQueryStatus struct {
+	// State reflects the current state of the HumioSavedQuery
+	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the saved query failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the saved query is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// ID is the ID of the saved query inside Humio, which dashboards and alerts refer to the saved query by
+	ID string `json:"id,omitempty"`
+	// ClusterName is the name of the managed or external cluster the saved query is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the saved query was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// LastResyncRequest is the value of the humio.com/trigger-resync annotation when the saved query was last resynced
+	LastResyncRequest string `json:"lastResyncRequest,omitempty"`
+	// AppliedHash is a hash of the desired state of the saved query which was last applied, which is used to tell changes to
+	// the spec apart from changes made outside the operator
+	AppliedHash string `json:"appliedHash,omitempty"`
+	// SyncedGeneration is the generation of the saved query when it was last synced successfully. While the generation and
+	// the applied hash are unchanged, the saved query is only compared with Humio every ten minutes to detect changes made
+	// outside the operator.
+	SyncedGeneration int64 `json:"syncedGeneration,omitempty"`
+	// DriftPatch is a JSON merge patch which imports the changes made to the saved query outside the operator into
+	// the spec. It is only set while the Drifted condition is True and the drift policy is Import.
+	DriftPatch string `json:"driftPatch,omitempty"`
+	// Conditions contains the conditions of the saved query. The Drifted condition is True while changes made outside the
+	// operator are left in place because of the Warn drift policy.
+	// The ClusterAvailable condition is False while the Humio cluster cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humiosavedqueries,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the saved query"
+//+kubebuilder:printcolumn:name="ID",type="string",JSONPath=".status.id",description="The ID of the saved query inside Humio"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the saved query is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the saved query was last synced successfully"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Saved Query"
+
+// HumioSavedQuery is the Schema for the humiosavedqueries API
+type HumioSavedQuery struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioSavedQuerySpec   `json:"spec,omitempty"`
+	Status HumioSavedQueryStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioSavedQueryList contains a list of HumioSavedQuery
+type HumioSavedQueryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioSavedQuery `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioSavedQuery{}, &HumioSavedQueryList{})
+}
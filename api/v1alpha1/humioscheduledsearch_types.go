@@ -0,0 +1,146 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioScheduledSearchStateUnknown is the Unknown state of the scheduled search
+	HumioScheduledSearchStateUnknown = "Unknown"
+	// HumioScheduledSearchStateExists is the Exists state of the scheduled search
+	HumioScheduledSearchStateExists = "Exists"
+	// HumioScheduledSearchStateNotFound is the NotFound state of the scheduled search
+	HumioScheduledSearchStateNotFound = "NotFound"
+	// HumioScheduledSearchStateConfigError is the state of the scheduled search when user-provided specification results in configuration error, such as non-existent humio cluster
+	HumioScheduledSearchStateConfigError = "ConfigError"
+	// HumioScheduledSearchStateClusterUnavailable is the state of the scheduled search when the Humio cluster it targets cannot be reached
+	HumioScheduledSearchStateClusterUnavailable = "ClusterUnavailable"
+)
+
+// HumioScheduledSearchSpec defines the desired state of HumioScheduledSearch
+type HumioScheduledSearchSpec struct {
+	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator where the Humio
+	// resources should be created.
+	// This conflicts with ExternalClusterName.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
+	// This conflicts with ManagedClusterName.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// Name is the name of the scheduled search inside Humio
+	Name string `json:"name"`
+	// ViewName is the name of the Humio View under which the scheduled search will be managed. This can also be a Repository
+	ViewName string `json:"viewName"`
+	// Description is the description of the scheduled search
+	Description string `json:"description,omitempty"`
+	// QueryString is the Humio query that will trigger the scheduled search
+	QueryString string `json:"queryString"`
+	// QueryStart is the start of the time span the query searches each time it runs, relative to when it runs, e.g. "1h"
+	QueryStart string `json:"queryStart"`
+	// QueryEnd is the end of the time span the query searches each time it runs, relative to when it runs. Defaults to
+	// "now"
+	QueryEnd string `json:"queryEnd,omitempty"`
+	// Schedule is the cron expression for when the scheduled search runs, e.g. "0 * * * *"
+	Schedule string `json:"schedule"`
+	// TimeZone is the time zone the schedule and the time-related functions of the query are interpreted in, e.g.
+	// "UTC" or "UTC+01:00". Defaults to "UTC"
+	TimeZone string `json:"timeZone,omitempty"`
+	// BackfillLimit is the number of runs missed while the scheduled search could not run, e.g. during an outage,
+	// which are run once it can run again
+	BackfillLimit int `json:"backfillLimit,omitempty"`
+	// Silenced will set the scheduled search to enabled when set to false
+	Silenced bool `json:"silenced,omitempty"`
+	// Actions is the list of Humio Actions by name that will be triggered by this scheduled search
+	Actions []string `json:"actions"`
+	// Labels are a set of labels on the scheduled search
+	Labels []string `json:"labels,omitempty"`
+	// DriftPolicy controls what happens when the scheduled search is changed outside the operator, e.g. in the Humio
+	// UI. Enforce reverts the changes, while Warn leaves them in place until the spec changes, and records a Drifted
+	// condition and event. Import behaves like Warn, and also records a patch importing the changes into the spec in
+	// status.driftPatch. Defaults to Enforce.
+	//+kubebuilder:validation:Enum=Enforce;Warn;Import
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+}
+
+// HumioScheduledSearchStatus defines the observed state of HumioScheduledSearch
+type HumioScheduledSearchStatus struct {
+	// State reflects the current state of the HumioScheduledSearch
+	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the scheduled search failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the scheduled search is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// ID is the ID of the scheduled search inside Humio
+	ID string `json:"id,omitempty"`
+	// ClusterName is the name of the managed or external cluster the scheduled search is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the scheduled search was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// LastResyncRequest is the value of the humio.com/trigger-resync annotation when the scheduled search was last resynced
+	LastResyncRequest string `json:"lastResyncRequest,omitempty"`
+	// AppliedHash is a hash of the desired state of the scheduled search which was last applied, which is used to tell
+	// changes to the spec apart from changes made outside the operator
+	AppliedHash string `json:"appliedHash,omitempty"`
+	// SyncedGeneration is the generation of the scheduled search when it was last synced successfully. While the
+	// generation and the applied hash are unchanged, the scheduled search is only compared with Humio every ten minutes
+	// to detect changes made outside the operator.
+	SyncedGeneration int64 `json:"syncedGeneration,omitempty"`
+	// DriftPatch is a JSON merge patch which imports the changes made to the scheduled search outside the operator into
+	// the spec. It is only set while the Drifted condition is True and the drift policy is Import.
+	DriftPatch string `json:"driftPatch,omitempty"`
+	// Conditions contains the conditions of the scheduled search. The Drifted condition is True while changes made
+	// outside the operator are left in place because of the Warn drift policy.
+	// The ClusterAvailable condition is False while the Humio cluster cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioscheduledsearches,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the scheduled search"
+//+kubebuilder:printcolumn:name="ID",type="string",JSONPath=".status.id",description="The ID of the scheduled search inside Humio"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the scheduled search is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the scheduled search was last synced successfully"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio Scheduled Search"
+
+// HumioScheduledSearch is the Schema for the humioscheduledsearches API
+type HumioScheduledSearch struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioScheduledSearchSpec   `json:"spec,omitempty"`
+	Status HumioScheduledSearchStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioScheduledSearchList contains a list of HumioScheduledSearch
+type HumioScheduledSearchList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioScheduledSearch `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioScheduledSearch{}, &HumioScheduledSearchList{})
+}
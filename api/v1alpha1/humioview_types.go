@@ -30,6 +30,8 @@ const (
 	HumioViewStateNotFound = "NotFound"
 	// HumioViewStateConfigError is the state of the view when user-provided specification results in configuration error, such as non-existent humio cluster
 	HumioViewStateConfigError = "ConfigError"
+	// HumioViewStateClusterUnavailable is the state of the view when the Humio cluster it targets cannot be reached
+	HumioViewStateClusterUnavailable = "ClusterUnavailable"
 )
 
 type HumioViewConnection struct {
@@ -48,6 +50,10 @@ type HumioViewSpec struct {
 	// ExternalClusterName refers to an object of type HumioExternalCluster where the Humio resources should be created.
 	// This conflicts with ManagedClusterName.
 	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
 	// Name is the name of the view inside Humio
 	Name string `json:"name,omitempty"`
 	// Connections contains the connections to the Humio repositories which is accessible in this view
@@ -58,12 +64,26 @@ type HumioViewSpec struct {
 type HumioViewStatus struct {
 	// State reflects the current state of the HumioView
 	State string `json:"state,omitempty"`
+	// Reason is a machine-readable code for why the last reconcile of the view failed, such as AuthFailed or
+	// ClusterUnreachable. It is empty while the view is reconciled successfully.
+	Reason string `json:"reason,omitempty"`
+	// ClusterName is the name of the managed or external cluster the view is synced to
+	ClusterName string `json:"clusterName,omitempty"`
+	// LastSyncTime is the time the view was last synced successfully. It is refreshed at most once a minute.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// Conditions contains the conditions of the view. The ClusterAvailable condition is False while the Humio
+	// cluster of the view cannot be reached.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:path=humioviews,scope=Namespaced
 //+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the view"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName",description="The cluster the view is synced to"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",description="The time the view was last synced successfully"
 //+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio View"
 
 // HumioView is the Schema for the humioviews API
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HumioViewExportStateSucceeded is the state of a view export whose manifests were written successfully
+	HumioViewExportStateSucceeded = "Succeeded"
+	// HumioViewExportStateFailed is the state of a view export which could not read the view or write the manifests
+	HumioViewExportStateFailed = "Failed"
+	// HumioViewExportStateConfigError is the state of the view export when user-provided specification results in
+	// configuration error, such as non-existent humio cluster
+	HumioViewExportStateConfigError = "ConfigError"
+)
+
+// HumioViewExportSpec defines the desired state of HumioViewExport
+type HumioViewExportSpec struct {
+	// ManagedClusterName refers to an object of type HumioCluster that is managed by the operator which the view is
+	// exported from. The exported manifests refer to the same cluster.
+	// This conflicts with ExternalClusterName.
+	ManagedClusterName string `json:"managedClusterName,omitempty"`
+	// ExternalClusterName refers to an object of type HumioExternalCluster which the view is exported from. The
+	// exported manifests refer to the same cluster.
+	// This conflicts with ManagedClusterName.
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	// APITokenSecretName is used to obtain an API token to use instead of the API token of the Humio cluster, which
+	// allows scoping the permissions of the operator for this resource.
+	// The secret must contain a key "token" which holds the Humio API token.
+	APITokenSecretName string `json:"apiTokenSecretName,omitempty"`
+	// ViewName is the name of the view or repository whose dashboards and saved queries are exported
+	ViewName string `json:"viewName"`
+	// ConfigMapName is the name of the ConfigMap the manifests are written to, with one key per manifest. Defaults
+	// to the name of the HumioViewExport. The ConfigMap is owned by the HumioViewExport.
+	ConfigMapName string `json:"configMapName,omitempty"`
+}
+
+// HumioViewExportStatus defines the observed state of HumioViewExport
+type HumioViewExportStatus struct {
+	// State reflects the current state of the HumioViewExport
+	State string `json:"state,omitempty"`
+	// Message contains the reason the HumioViewExport failed or is in the ConfigError state
+	Message string `json:"message,omitempty"`
+	// ConfigMapName is the name of the ConfigMap the manifests were written to
+	ConfigMapName string `json:"configMapName,omitempty"`
+	// Dashboards lists the names of the exported HumioDashboard manifests
+	Dashboards []string `json:"dashboards,omitempty"`
+	// SavedQueries lists the names of the exported HumioSavedQuery manifests
+	SavedQueries []string `json:"savedQueries,omitempty"`
+	// ExportTime is the time the manifests were written
+	ExportTime *metav1.Time `json:"exportTime,omitempty"`
+	// ObservedGeneration is the generation of the HumioViewExport which was last exported. Changing the spec exports
+	// the view again.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=humioviewexports,scope=Namespaced
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The state of the view export"
+//+kubebuilder:printcolumn:name="ConfigMap",type="string",JSONPath=".status.configMapName",description="The ConfigMap holding the exported manifests"
+//+operator-sdk:gen-csv:customresourcedefinitions.displayName="Humio View Export"
+
+// HumioViewExport is the Schema for the humioviewexports API
+type HumioViewExport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HumioViewExportSpec   `json:"spec,omitempty"`
+	Status HumioViewExportStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HumioViewExportList contains a list of HumioViewExport
+type HumioViewExportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HumioViewExport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HumioViewExport{}, &HumioViewExportList{})
+}
@@ -23,16 +23,32 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioAPITimeouts) DeepCopyInto(out *HumioAPITimeouts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAPITimeouts.
+func (in *HumioAPITimeouts) DeepCopy() *HumioAPITimeouts {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioAPITimeouts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioAction) DeepCopyInto(out *HumioAction) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAction.
@@ -53,6 +69,25 @@ func (in *HumioAction) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioActionDeliveryVerificationStatus) DeepCopyInto(out *HumioActionDeliveryVerificationStatus) {
+	*out = *in
+	if in.LastProbeTime != nil {
+		in, out := &in.LastProbeTime, &out.LastProbeTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionDeliveryVerificationStatus.
+func (in *HumioActionDeliveryVerificationStatus) DeepCopy() *HumioActionDeliveryVerificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioActionDeliveryVerificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioActionEmailProperties) DeepCopyInto(out *HumioActionEmailProperties) {
 	*out = *in
@@ -73,6 +108,36 @@ func (in *HumioActionEmailProperties) DeepCopy() *HumioActionEmailProperties {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioActionFallback) DeepCopyInto(out *HumioActionFallback) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionFallback.
+func (in *HumioActionFallback) DeepCopy() *HumioActionFallback {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioActionFallback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioActionFallbackRelayStatus) DeepCopyInto(out *HumioActionFallbackRelayStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionFallbackRelayStatus.
+func (in *HumioActionFallbackRelayStatus) DeepCopy() *HumioActionFallbackRelayStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioActionFallbackRelayStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioActionList) DeepCopyInto(out *HumioActionList) {
 	*out = *in
@@ -109,6 +174,16 @@ func (in *HumioActionList) DeepCopyObject() runtime.Object {
 func (in *HumioActionOpsGenieProperties) DeepCopyInto(out *HumioActionOpsGenieProperties) {
 	*out = *in
 	in.GenieKeySource.DeepCopyInto(&out.GenieKeySource)
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Responders != nil {
+		in, out := &in.Responders, &out.Responders
+		*out = make([]HumioActionOpsGenieResponder, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionOpsGenieProperties.
@@ -121,6 +196,21 @@ func (in *HumioActionOpsGenieProperties) DeepCopy() *HumioActionOpsGenieProperti
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioActionOpsGenieResponder) DeepCopyInto(out *HumioActionOpsGenieResponder) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionOpsGenieResponder.
+func (in *HumioActionOpsGenieResponder) DeepCopy() *HumioActionOpsGenieResponder {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioActionOpsGenieResponder)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioActionPagerDutyProperties) DeepCopyInto(out *HumioActionPagerDutyProperties) {
 	*out = *in
@@ -238,13 +328,23 @@ func (in *HumioActionSpec) DeepCopyInto(out *HumioActionSpec) {
 	if in.VictorOpsProperties != nil {
 		in, out := &in.VictorOpsProperties, &out.VictorOpsProperties
 		*out = new(HumioActionVictorOpsProperties)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.WebhookProperties != nil {
 		in, out := &in.WebhookProperties, &out.WebhookProperties
 		*out = new(HumioActionWebhookProperties)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TemplateRef != nil {
+		in, out := &in.TemplateRef, &out.TemplateRef
+		*out = new(HumioActionTemplateReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Fallback != nil {
+		in, out := &in.Fallback, &out.Fallback
+		*out = new(HumioActionFallback)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionSpec.
@@ -260,6 +360,27 @@ func (in *HumioActionSpec) DeepCopy() *HumioActionSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioActionStatus) DeepCopyInto(out *HumioActionStatus) {
 	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FallbackRelay != nil {
+		in, out := &in.FallbackRelay, &out.FallbackRelay
+		*out = new(HumioActionFallbackRelayStatus)
+		**out = **in
+	}
+	if in.DeliveryVerification != nil {
+		in, out := &in.DeliveryVerification, &out.DeliveryVerification
+		*out = new(HumioActionDeliveryVerificationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionStatus.
@@ -272,9 +393,120 @@ func (in *HumioActionStatus) DeepCopy() *HumioActionStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioActionTemplate) DeepCopyInto(out *HumioActionTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionTemplate.
+func (in *HumioActionTemplate) DeepCopy() *HumioActionTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioActionTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioActionTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioActionTemplateList) DeepCopyInto(out *HumioActionTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioActionTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionTemplateList.
+func (in *HumioActionTemplateList) DeepCopy() *HumioActionTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioActionTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioActionTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioActionTemplateReference) DeepCopyInto(out *HumioActionTemplateReference) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionTemplateReference.
+func (in *HumioActionTemplateReference) DeepCopy() *HumioActionTemplateReference {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioActionTemplateReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioActionTemplateSpec) DeepCopyInto(out *HumioActionTemplateSpec) {
+	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionTemplateSpec.
+func (in *HumioActionTemplateSpec) DeepCopy() *HumioActionTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioActionTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioActionVictorOpsProperties) DeepCopyInto(out *HumioActionVictorOpsProperties) {
 	*out = *in
+	in.NotifyUrlSource.DeepCopyInto(&out.NotifyUrlSource)
+	in.RoutingKeySource.DeepCopyInto(&out.RoutingKeySource)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionVictorOpsProperties.
@@ -287,6 +519,27 @@ func (in *HumioActionVictorOpsProperties) DeepCopy() *HumioActionVictorOpsProper
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioActionWebhookDeliveryVerification) DeepCopyInto(out *HumioActionWebhookDeliveryVerification) {
+	*out = *in
+	in.SigningKeySource.DeepCopyInto(&out.SigningKeySource)
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionWebhookDeliveryVerification.
+func (in *HumioActionWebhookDeliveryVerification) DeepCopy() *HumioActionWebhookDeliveryVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioActionWebhookDeliveryVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioActionWebhookProperties) DeepCopyInto(out *HumioActionWebhookProperties) {
 	*out = *in
@@ -297,6 +550,11 @@ func (in *HumioActionWebhookProperties) DeepCopyInto(out *HumioActionWebhookProp
 			(*out)[key] = val
 		}
 	}
+	if in.DeliveryVerification != nil {
+		in, out := &in.DeliveryVerification, &out.DeliveryVerification
+		*out = new(HumioActionWebhookDeliveryVerification)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioActionWebhookProperties.
@@ -310,26 +568,41 @@ func (in *HumioActionWebhookProperties) DeepCopy() *HumioActionWebhookProperties
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioAlert) DeepCopyInto(out *HumioAlert) {
+func (in *HumioAdminTokenRotation) DeepCopyInto(out *HumioAdminTokenRotation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAdminTokenRotation.
+func (in *HumioAdminTokenRotation) DeepCopy() *HumioAdminTokenRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioAdminTokenRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioAggregateAlert) DeepCopyInto(out *HumioAggregateAlert) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlert.
-func (in *HumioAlert) DeepCopy() *HumioAlert {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAggregateAlert.
+func (in *HumioAggregateAlert) DeepCopy() *HumioAggregateAlert {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioAlert)
+	out := new(HumioAggregateAlert)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioAlert) DeepCopyObject() runtime.Object {
+func (in *HumioAggregateAlert) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -337,31 +610,31 @@ func (in *HumioAlert) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioAlertList) DeepCopyInto(out *HumioAlertList) {
+func (in *HumioAggregateAlertList) DeepCopyInto(out *HumioAggregateAlertList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]HumioAlert, len(*in))
+		*out = make([]HumioAggregateAlert, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertList.
-func (in *HumioAlertList) DeepCopy() *HumioAlertList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAggregateAlertList.
+func (in *HumioAggregateAlertList) DeepCopy() *HumioAggregateAlertList {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioAlertList)
+	out := new(HumioAggregateAlertList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioAlertList) DeepCopyObject() runtime.Object {
+func (in *HumioAggregateAlertList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -369,9 +642,8 @@ func (in *HumioAlertList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioAlertSpec) DeepCopyInto(out *HumioAlertSpec) {
+func (in *HumioAggregateAlertSpec) DeepCopyInto(out *HumioAggregateAlertSpec) {
 	*out = *in
-	in.Query.DeepCopyInto(&out.Query)
 	if in.Actions != nil {
 		in, out := &in.Actions, &out.Actions
 		*out = make([]string, len(*in))
@@ -384,33 +656,44 @@ func (in *HumioAlertSpec) DeepCopyInto(out *HumioAlertSpec) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSpec.
-func (in *HumioAlertSpec) DeepCopy() *HumioAlertSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAggregateAlertSpec.
+func (in *HumioAggregateAlertSpec) DeepCopy() *HumioAggregateAlertSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioAlertSpec)
+	out := new(HumioAggregateAlertSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioAlertStatus) DeepCopyInto(out *HumioAlertStatus) {
+func (in *HumioAggregateAlertStatus) DeepCopyInto(out *HumioAggregateAlertStatus) {
 	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertStatus.
-func (in *HumioAlertStatus) DeepCopy() *HumioAlertStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAggregateAlertStatus.
+func (in *HumioAggregateAlertStatus) DeepCopy() *HumioAggregateAlertStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioAlertStatus)
+	out := new(HumioAggregateAlertStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioCluster) DeepCopyInto(out *HumioCluster) {
+func (in *HumioAlert) DeepCopyInto(out *HumioAlert) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -418,18 +701,18 @@ func (in *HumioCluster) DeepCopyInto(out *HumioCluster) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioCluster.
-func (in *HumioCluster) DeepCopy() *HumioCluster {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlert.
+func (in *HumioAlert) DeepCopy() *HumioAlert {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioCluster)
+	out := new(HumioAlert)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioCluster) DeepCopyObject() runtime.Object {
+func (in *HumioAlert) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -437,78 +720,58 @@ func (in *HumioCluster) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioClusterIngressSpec) DeepCopyInto(out *HumioClusterIngressSpec) {
+func (in *HumioAlertList) DeepCopyInto(out *HumioAlertList) {
 	*out = *in
-	if in.TLS != nil {
-		in, out := &in.TLS, &out.TLS
-		*out = new(bool)
-		**out = **in
-	}
-	if in.Annotations != nil {
-		in, out := &in.Annotations, &out.Annotations
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioAlert, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterIngressSpec.
-func (in *HumioClusterIngressSpec) DeepCopy() *HumioClusterIngressSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertList.
+func (in *HumioAlertList) DeepCopy() *HumioAlertList {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioClusterIngressSpec)
+	out := new(HumioAlertList)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioClusterLicenseSpec) DeepCopyInto(out *HumioClusterLicenseSpec) {
-	*out = *in
-	if in.SecretKeyRef != nil {
-		in, out := &in.SecretKeyRef, &out.SecretKeyRef
-		*out = new(v1.SecretKeySelector)
-		(*in).DeepCopyInto(*out)
-	}
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterLicenseSpec.
-func (in *HumioClusterLicenseSpec) DeepCopy() *HumioClusterLicenseSpec {
-	if in == nil {
-		return nil
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioAlertList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	out := new(HumioClusterLicenseSpec)
-	in.DeepCopyInto(out)
-	return out
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioClusterList) DeepCopyInto(out *HumioClusterList) {
+func (in *HumioAlertSet) DeepCopyInto(out *HumioAlertSet) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]HumioCluster, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterList.
-func (in *HumioClusterList) DeepCopy() *HumioClusterList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSet.
+func (in *HumioAlertSet) DeepCopy() *HumioAlertSet {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioClusterList)
+	out := new(HumioAlertSet)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioClusterList) DeepCopyObject() runtime.Object {
+func (in *HumioAlertSet) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -516,138 +779,168 @@ func (in *HumioClusterList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioClusterSpec) DeepCopyInto(out *HumioClusterSpec) {
+func (in *HumioAlertSetAlertStatus) DeepCopyInto(out *HumioAlertSetAlertStatus) {
 	*out = *in
-	in.License.DeepCopyInto(&out.License)
-	in.HostnameSource.DeepCopyInto(&out.HostnameSource)
-	in.ESHostnameSource.DeepCopyInto(&out.ESHostnameSource)
-	in.Ingress.DeepCopyInto(&out.Ingress)
-	if in.TLS != nil {
-		in, out := &in.TLS, &out.TLS
-		*out = new(HumioClusterTLSSpec)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSetAlertStatus.
+func (in *HumioAlertSetAlertStatus) DeepCopy() *HumioAlertSetAlertStatus {
+	if in == nil {
+		return nil
 	}
-	if in.HumioHeadlessServiceAnnotations != nil {
-		in, out := &in.HumioHeadlessServiceAnnotations, &out.HumioHeadlessServiceAnnotations
+	out := new(HumioAlertSetAlertStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioAlertSetInstance) DeepCopyInto(out *HumioAlertSetInstance) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
 		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
 	}
-	if in.HumioHeadlessServiceLabels != nil {
-		in, out := &in.HumioHeadlessServiceLabels, &out.HumioHeadlessServiceLabels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSetInstance.
+func (in *HumioAlertSetInstance) DeepCopy() *HumioAlertSetInstance {
+	if in == nil {
+		return nil
 	}
-	in.HumioNodeSpec.DeepCopyInto(&out.HumioNodeSpec)
-	if in.NodePools != nil {
-		in, out := &in.NodePools, &out.NodePools
-		*out = make([]HumioNodePoolSpec, len(*in))
+	out := new(HumioAlertSetInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioAlertSetList) DeepCopyInto(out *HumioAlertSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioAlertSet, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterSpec.
-func (in *HumioClusterSpec) DeepCopy() *HumioClusterSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSetList.
+func (in *HumioAlertSetList) DeepCopy() *HumioAlertSetList {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioClusterSpec)
+	out := new(HumioAlertSetList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioAlertSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioClusterStatus) DeepCopyInto(out *HumioClusterStatus) {
+func (in *HumioAlertSetSpec) DeepCopyInto(out *HumioAlertSetSpec) {
 	*out = *in
-	if in.PodStatus != nil {
-		in, out := &in.PodStatus, &out.PodStatus
-		*out = make(HumioPodStatusList, len(*in))
-		copy(*out, *in)
-	}
-	out.LicenseStatus = in.LicenseStatus
-	if in.NodePoolStatus != nil {
-		in, out := &in.NodePoolStatus, &out.NodePoolStatus
-		*out = make(HumioNodePoolStatusList, len(*in))
-		copy(*out, *in)
+	in.Template.DeepCopyInto(&out.Template)
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]HumioAlertSetInstance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterStatus.
-func (in *HumioClusterStatus) DeepCopy() *HumioClusterStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSetSpec.
+func (in *HumioAlertSetSpec) DeepCopy() *HumioAlertSetSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioClusterStatus)
+	out := new(HumioAlertSetSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioClusterTLSSpec) DeepCopyInto(out *HumioClusterTLSSpec) {
+func (in *HumioAlertSetStatus) DeepCopyInto(out *HumioAlertSetStatus) {
 	*out = *in
-	if in.Enabled != nil {
-		in, out := &in.Enabled, &out.Enabled
-		*out = new(bool)
-		**out = **in
+	if in.Alerts != nil {
+		in, out := &in.Alerts, &out.Alerts
+		*out = make([]HumioAlertSetAlertStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterTLSSpec.
-func (in *HumioClusterTLSSpec) DeepCopy() *HumioClusterTLSSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSetStatus.
+func (in *HumioAlertSetStatus) DeepCopy() *HumioAlertSetStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioClusterTLSSpec)
+	out := new(HumioAlertSetStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioESHostnameSource) DeepCopyInto(out *HumioESHostnameSource) {
+func (in *HumioAlertSetTemplate) DeepCopyInto(out *HumioAlertSetTemplate) {
 	*out = *in
-	if in.SecretKeyRef != nil {
-		in, out := &in.SecretKeyRef, &out.SecretKeyRef
-		*out = new(v1.SecretKeySelector)
-		(*in).DeepCopyInto(*out)
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
 	}
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioESHostnameSource.
-func (in *HumioESHostnameSource) DeepCopy() *HumioESHostnameSource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSetTemplate.
+func (in *HumioAlertSetTemplate) DeepCopy() *HumioAlertSetTemplate {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioESHostnameSource)
+	out := new(HumioAlertSetTemplate)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioExternalCluster) DeepCopyInto(out *HumioExternalCluster) {
+func (in *HumioAlertSilence) DeepCopyInto(out *HumioAlertSilence) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalCluster.
-func (in *HumioExternalCluster) DeepCopy() *HumioExternalCluster {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSilence.
+func (in *HumioAlertSilence) DeepCopy() *HumioAlertSilence {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioExternalCluster)
+	out := new(HumioAlertSilence)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioExternalCluster) DeepCopyObject() runtime.Object {
+func (in *HumioAlertSilence) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -655,31 +948,31 @@ func (in *HumioExternalCluster) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioExternalClusterList) DeepCopyInto(out *HumioExternalClusterList) {
+func (in *HumioAlertSilenceList) DeepCopyInto(out *HumioAlertSilenceList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]HumioExternalCluster, len(*in))
+		*out = make([]HumioAlertSilence, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalClusterList.
-func (in *HumioExternalClusterList) DeepCopy() *HumioExternalClusterList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSilenceList.
+func (in *HumioAlertSilenceList) DeepCopy() *HumioAlertSilenceList {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioExternalClusterList)
+	out := new(HumioAlertSilenceList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioExternalClusterList) DeepCopyObject() runtime.Object {
+func (in *HumioAlertSilenceList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -687,143 +980,1561 @@ func (in *HumioExternalClusterList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioExternalClusterSpec) DeepCopyInto(out *HumioExternalClusterSpec) {
+func (in *HumioAlertSilenceSpec) DeepCopyInto(out *HumioAlertSilenceSpec) {
 	*out = *in
+	in.AlertSelector.DeepCopyInto(&out.AlertSelector)
+	if in.Start != nil {
+		in, out := &in.Start, &out.Start
+		*out = (*in).DeepCopy()
+	}
+	if in.End != nil {
+		in, out := &in.End, &out.End
+		*out = (*in).DeepCopy()
+	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(HumioMaintenanceWindow)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalClusterSpec.
-func (in *HumioExternalClusterSpec) DeepCopy() *HumioExternalClusterSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSilenceSpec.
+func (in *HumioAlertSilenceSpec) DeepCopy() *HumioAlertSilenceSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioExternalClusterSpec)
+	out := new(HumioAlertSilenceSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioExternalClusterStatus) DeepCopyInto(out *HumioExternalClusterStatus) {
+func (in *HumioAlertSilenceStatus) DeepCopyInto(out *HumioAlertSilenceStatus) {
 	*out = *in
+	if in.Alerts != nil {
+		in, out := &in.Alerts, &out.Alerts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextTransitionTime != nil {
+		in, out := &in.NextTransitionTime, &out.NextTransitionTime
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalClusterStatus.
-func (in *HumioExternalClusterStatus) DeepCopy() *HumioExternalClusterStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSilenceStatus.
+func (in *HumioAlertSilenceStatus) DeepCopy() *HumioAlertSilenceStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioExternalClusterStatus)
+	out := new(HumioAlertSilenceStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioHostnameSource) DeepCopyInto(out *HumioHostnameSource) {
+func (in *HumioAlertSpec) DeepCopyInto(out *HumioAlertSpec) {
 	*out = *in
-	if in.SecretKeyRef != nil {
-		in, out := &in.SecretKeyRef, &out.SecretKeyRef
-		*out = new(v1.SecretKeySelector)
-		(*in).DeepCopyInto(*out)
+	in.Query.DeepCopyInto(&out.Query)
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioHostnameSource.
-func (in *HumioHostnameSource) DeepCopy() *HumioHostnameSource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertSpec.
+func (in *HumioAlertSpec) DeepCopy() *HumioAlertSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioHostnameSource)
+	out := new(HumioAlertSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioImageSource) DeepCopyInto(out *HumioImageSource) {
+func (in *HumioAlertStatus) DeepCopyInto(out *HumioAlertStatus) {
 	*out = *in
-	if in.ConfigMapRef != nil {
-		in, out := &in.ConfigMapRef, &out.ConfigMapRef
-		*out = new(v1.ConfigMapKeySelector)
-		(*in).DeepCopyInto(*out)
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Silences != nil {
+		in, out := &in.Silences, &out.Silences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioImageSource.
-func (in *HumioImageSource) DeepCopy() *HumioImageSource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAlertStatus.
+func (in *HumioAlertStatus) DeepCopy() *HumioAlertStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioImageSource)
+	out := new(HumioAlertStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioIngestToken) DeepCopyInto(out *HumioIngestToken) {
+func (in *HumioAuthMigration) DeepCopyInto(out *HumioAuthMigration) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	if in.EnvironmentVariables != nil {
+		in, out := &in.EnvironmentVariables, &out.EnvironmentVariables
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BreakGlassUsers != nil {
+		in, out := &in.BreakGlassUsers, &out.BreakGlassUsers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioIngestToken.
-func (in *HumioIngestToken) DeepCopy() *HumioIngestToken {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAuthMigration.
+func (in *HumioAuthMigration) DeepCopy() *HumioAuthMigration {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioIngestToken)
+	out := new(HumioAuthMigration)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioIngestToken) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioIngestTokenList) DeepCopyInto(out *HumioIngestTokenList) {
+func (in *HumioAuthMigrationStatus) DeepCopyInto(out *HumioAuthMigrationStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]HumioIngestToken, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioIngestTokenList.
-func (in *HumioIngestTokenList) DeepCopy() *HumioIngestTokenList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioAuthMigrationStatus.
+func (in *HumioAuthMigrationStatus) DeepCopy() *HumioAuthMigrationStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioIngestTokenList)
+	out := new(HumioAuthMigrationStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioIngestTokenList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioIngestTokenSpec) DeepCopyInto(out *HumioIngestTokenSpec) {
+func (in *HumioBucketStorageEncryption) DeepCopyInto(out *HumioBucketStorageEncryption) {
 	*out = *in
-	if in.TokenSecretLabels != nil {
-		in, out := &in.TokenSecretLabels, &out.TokenSecretLabels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	if in.EncryptionKeySecretKeyRef != nil {
+		in, out := &in.EncryptionKeySecretKeyRef, &out.EncryptionKeySecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioBucketStorageEncryption.
+func (in *HumioBucketStorageEncryption) DeepCopy() *HumioBucketStorageEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioBucketStorageEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioCluster) DeepCopyInto(out *HumioCluster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioCluster.
+func (in *HumioCluster) DeepCopy() *HumioCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterBackup) DeepCopyInto(out *HumioClusterBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterBackup.
+func (in *HumioClusterBackup) DeepCopy() *HumioClusterBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioClusterBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterBackupArtifact) DeepCopyInto(out *HumioClusterBackupArtifact) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterBackupArtifact.
+func (in *HumioClusterBackupArtifact) DeepCopy() *HumioClusterBackupArtifact {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterBackupArtifact)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterBackupList) DeepCopyInto(out *HumioClusterBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioClusterBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterBackupList.
+func (in *HumioClusterBackupList) DeepCopy() *HumioClusterBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioClusterBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterBackupSpec) DeepCopyInto(out *HumioClusterBackupSpec) {
+	*out = *in
+	out.Target = in.Target
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterBackupSpec.
+func (in *HumioClusterBackupSpec) DeepCopy() *HumioClusterBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterBackupStatus) DeepCopyInto(out *HumioClusterBackupStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Backups != nil {
+		in, out := &in.Backups, &out.Backups
+		*out = make([]HumioClusterBackupArtifact, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterBackupStatus.
+func (in *HumioClusterBackupStatus) DeepCopy() *HumioClusterBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterBackupTarget) DeepCopyInto(out *HumioClusterBackupTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterBackupTarget.
+func (in *HumioClusterBackupTarget) DeepCopy() *HumioClusterBackupTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterBackupTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterHealth) DeepCopyInto(out *HumioClusterHealth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterHealth.
+func (in *HumioClusterHealth) DeepCopy() *HumioClusterHealth {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterHealth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterIngressSpec) DeepCopyInto(out *HumioClusterIngressSpec) {
+	*out = *in
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterIngressSpec.
+func (in *HumioClusterIngressSpec) DeepCopy() *HumioClusterIngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterIngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterLicenseSpec) DeepCopyInto(out *HumioClusterLicenseSpec) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterLicenseSpec.
+func (in *HumioClusterLicenseSpec) DeepCopy() *HumioClusterLicenseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterLicenseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterList) DeepCopyInto(out *HumioClusterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterList.
+func (in *HumioClusterList) DeepCopy() *HumioClusterList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioClusterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterReplication) DeepCopyInto(out *HumioClusterReplication) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterReplication.
+func (in *HumioClusterReplication) DeepCopy() *HumioClusterReplication {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterReplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioClusterReplication) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterReplicationList) DeepCopyInto(out *HumioClusterReplicationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioClusterReplication, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterReplicationList.
+func (in *HumioClusterReplicationList) DeepCopy() *HumioClusterReplicationList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterReplicationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioClusterReplicationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterReplicationSpec) DeepCopyInto(out *HumioClusterReplicationSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterReplicationSpec.
+func (in *HumioClusterReplicationSpec) DeepCopy() *HumioClusterReplicationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterReplicationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterReplicationStatus) DeepCopyInto(out *HumioClusterReplicationStatus) {
+	*out = *in
+	if in.LastSwitchTime != nil {
+		in, out := &in.LastSwitchTime, &out.LastSwitchTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterReplicationStatus.
+func (in *HumioClusterReplicationStatus) DeepCopy() *HumioClusterReplicationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterReplicationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterSet) DeepCopyInto(out *HumioClusterSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterSet.
+func (in *HumioClusterSet) DeepCopy() *HumioClusterSet {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioClusterSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterSetClusterStatus) DeepCopyInto(out *HumioClusterSetClusterStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterSetClusterStatus.
+func (in *HumioClusterSetClusterStatus) DeepCopy() *HumioClusterSetClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterSetClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterSetInstance) DeepCopyInto(out *HumioClusterSetInstance) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterSetInstance.
+func (in *HumioClusterSetInstance) DeepCopy() *HumioClusterSetInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterSetInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterSetList) DeepCopyInto(out *HumioClusterSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioClusterSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterSetList.
+func (in *HumioClusterSetList) DeepCopy() *HumioClusterSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioClusterSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterSetSpec) DeepCopyInto(out *HumioClusterSetSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]HumioClusterSetInstance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterSetSpec.
+func (in *HumioClusterSetSpec) DeepCopy() *HumioClusterSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterSetStatus) DeepCopyInto(out *HumioClusterSetStatus) {
+	*out = *in
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]HumioClusterSetClusterStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterSetStatus.
+func (in *HumioClusterSetStatus) DeepCopy() *HumioClusterSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterSetTemplate) DeepCopyInto(out *HumioClusterSetTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterSetTemplate.
+func (in *HumioClusterSetTemplate) DeepCopy() *HumioClusterSetTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterSetTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterSpec) DeepCopyInto(out *HumioClusterSpec) {
+	*out = *in
+	in.License.DeepCopyInto(&out.License)
+	in.HostnameSource.DeepCopyInto(&out.HostnameSource)
+	in.ESHostnameSource.DeepCopyInto(&out.ESHostnameSource)
+	if in.APITimeouts != nil {
+		in, out := &in.APITimeouts, &out.APITimeouts
+		*out = new(HumioAPITimeouts)
+		**out = **in
+	}
+	if in.UsageReporting != nil {
+		in, out := &in.UsageReporting, &out.UsageReporting
+		*out = new(HumioUsageReporting)
+		**out = **in
+	}
+	if in.AdminTokenRotation != nil {
+		in, out := &in.AdminTokenRotation, &out.AdminTokenRotation
+		*out = new(HumioAdminTokenRotation)
+		**out = **in
+	}
+	if in.AuthMigration != nil {
+		in, out := &in.AuthMigration, &out.AuthMigration
+		*out = new(HumioAuthMigration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeadNodeUnregistration != nil {
+		in, out := &in.DeadNodeUnregistration, &out.DeadNodeUnregistration
+		*out = new(HumioDeadNodeUnregistration)
+		**out = **in
+	}
+	in.Ingress.DeepCopyInto(&out.Ingress)
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(HumioClusterTLSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HumioHeadlessServiceAnnotations != nil {
+		in, out := &in.HumioHeadlessServiceAnnotations, &out.HumioHeadlessServiceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.HumioHeadlessServiceLabels != nil {
+		in, out := &in.HumioHeadlessServiceLabels, &out.HumioHeadlessServiceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(HumioMaintenanceWindow)
+		**out = **in
+	}
+	if in.CommonEnvironmentVariables != nil {
+		in, out := &in.CommonEnvironmentVariables, &out.CommonEnvironmentVariables
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.HumioNodeSpec.DeepCopyInto(&out.HumioNodeSpec)
+	if in.NodePools != nil {
+		in, out := &in.NodePools, &out.NodePools
+		*out = make([]HumioNodePoolSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterSpec.
+func (in *HumioClusterSpec) DeepCopy() *HumioClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterStatus) DeepCopyInto(out *HumioClusterStatus) {
+	*out = *in
+	if in.PodStatus != nil {
+		in, out := &in.PodStatus, &out.PodStatus
+		*out = make(HumioPodStatusList, len(*in))
+		copy(*out, *in)
+	}
+	in.LicenseStatus.DeepCopyInto(&out.LicenseStatus)
+	if in.NodePoolStatus != nil {
+		in, out := &in.NodePoolStatus, &out.NodePoolStatus
+		*out = make(HumioNodePoolStatusList, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = new(HumioClusterUsage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeadNodes != nil {
+		in, out := &in.DeadNodes, &out.DeadNodes
+		*out = make([]HumioDeadNode, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Health != nil {
+		in, out := &in.Health, &out.Health
+		*out = new(HumioClusterHealth)
+		**out = **in
+	}
+	if in.LastAdminTokenRotationTime != nil {
+		in, out := &in.LastAdminTokenRotationTime, &out.LastAdminTokenRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PendingAdminTokenRevocation != nil {
+		in, out := &in.PendingAdminTokenRevocation, &out.PendingAdminTokenRevocation
+		*out = new(HumioPendingAdminTokenRevocation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AuthMigration != nil {
+		in, out := &in.AuthMigration, &out.AuthMigration
+		*out = new(HumioAuthMigrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EnvironmentVariableWarnings != nil {
+		in, out := &in.EnvironmentVariableWarnings, &out.EnvironmentVariableWarnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterStatus.
+func (in *HumioClusterStatus) DeepCopy() *HumioClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterTLSSpec) DeepCopyInto(out *HumioClusterTLSSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterTLSSpec.
+func (in *HumioClusterTLSSpec) DeepCopy() *HumioClusterTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioClusterUsage) DeepCopyInto(out *HumioClusterUsage) {
+	*out = *in
+	if in.CollectionTime != nil {
+		in, out := &in.CollectionTime, &out.CollectionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]HumioRepositoryUsage, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioClusterUsage.
+func (in *HumioClusterUsage) DeepCopy() *HumioClusterUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioClusterUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDashboard) DeepCopyInto(out *HumioDashboard) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDashboard.
+func (in *HumioDashboard) DeepCopy() *HumioDashboard {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDashboard)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioDashboard) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDashboardList) DeepCopyInto(out *HumioDashboardList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioDashboard, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDashboardList.
+func (in *HumioDashboardList) DeepCopy() *HumioDashboardList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDashboardList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioDashboardList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDashboardSpec) DeepCopyInto(out *HumioDashboardSpec) {
+	*out = *in
+	if in.TemplateFrom != nil {
+		in, out := &in.TemplateFrom, &out.TemplateFrom
+		*out = new(HumioDashboardTemplateSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDashboardSpec.
+func (in *HumioDashboardSpec) DeepCopy() *HumioDashboardSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDashboardSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDashboardStatus) DeepCopyInto(out *HumioDashboardStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDriftTime != nil {
+		in, out := &in.LastDriftTime, &out.LastDriftTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDashboardStatus.
+func (in *HumioDashboardStatus) DeepCopy() *HumioDashboardStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDashboardStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDashboardTemplateSource) DeepCopyInto(out *HumioDashboardTemplateSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDashboardTemplateSource.
+func (in *HumioDashboardTemplateSource) DeepCopy() *HumioDashboardTemplateSource {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDashboardTemplateSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDeadNode) DeepCopyInto(out *HumioDeadNode) {
+	*out = *in
+	in.DetectionTime.DeepCopyInto(&out.DetectionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDeadNode.
+func (in *HumioDeadNode) DeepCopy() *HumioDeadNode {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDeadNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDeadNodeUnregistration) DeepCopyInto(out *HumioDeadNodeUnregistration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDeadNodeUnregistration.
+func (in *HumioDeadNodeUnregistration) DeepCopy() *HumioDeadNodeUnregistration {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDeadNodeUnregistration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDiagnosticsBundle) DeepCopyInto(out *HumioDiagnosticsBundle) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDiagnosticsBundle.
+func (in *HumioDiagnosticsBundle) DeepCopy() *HumioDiagnosticsBundle {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDiagnosticsBundle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioDiagnosticsBundle) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDiagnosticsBundleList) DeepCopyInto(out *HumioDiagnosticsBundleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioDiagnosticsBundle, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDiagnosticsBundleList.
+func (in *HumioDiagnosticsBundleList) DeepCopy() *HumioDiagnosticsBundleList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDiagnosticsBundleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioDiagnosticsBundleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDiagnosticsBundleSpec) DeepCopyInto(out *HumioDiagnosticsBundleSpec) {
+	*out = *in
+	in.Target.DeepCopyInto(&out.Target)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDiagnosticsBundleSpec.
+func (in *HumioDiagnosticsBundleSpec) DeepCopy() *HumioDiagnosticsBundleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDiagnosticsBundleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDiagnosticsBundleStatus) DeepCopyInto(out *HumioDiagnosticsBundleStatus) {
+	*out = *in
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDiagnosticsBundleStatus.
+func (in *HumioDiagnosticsBundleStatus) DeepCopy() *HumioDiagnosticsBundleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDiagnosticsBundleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioDiagnosticsBundleTarget) DeepCopyInto(out *HumioDiagnosticsBundleTarget) {
+	*out = *in
+	if in.Bucket != nil {
+		in, out := &in.Bucket, &out.Bucket
+		*out = new(HumioClusterBackupTarget)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioDiagnosticsBundleTarget.
+func (in *HumioDiagnosticsBundleTarget) DeepCopy() *HumioDiagnosticsBundleTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioDiagnosticsBundleTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioESHostnameSource) DeepCopyInto(out *HumioESHostnameSource) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioESHostnameSource.
+func (in *HumioESHostnameSource) DeepCopy() *HumioESHostnameSource {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioESHostnameSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioExternalCluster) DeepCopyInto(out *HumioExternalCluster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalCluster.
+func (in *HumioExternalCluster) DeepCopy() *HumioExternalCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioExternalCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioExternalCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioExternalClusterAPITokenRotation) DeepCopyInto(out *HumioExternalClusterAPITokenRotation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalClusterAPITokenRotation.
+func (in *HumioExternalClusterAPITokenRotation) DeepCopy() *HumioExternalClusterAPITokenRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioExternalClusterAPITokenRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioExternalClusterCloud) DeepCopyInto(out *HumioExternalClusterCloud) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalClusterCloud.
+func (in *HumioExternalClusterCloud) DeepCopy() *HumioExternalClusterCloud {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioExternalClusterCloud)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioExternalClusterList) DeepCopyInto(out *HumioExternalClusterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioExternalCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalClusterList.
+func (in *HumioExternalClusterList) DeepCopy() *HumioExternalClusterList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioExternalClusterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioExternalClusterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioExternalClusterOAuth2) DeepCopyInto(out *HumioExternalClusterOAuth2) {
+	*out = *in
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalClusterOAuth2.
+func (in *HumioExternalClusterOAuth2) DeepCopy() *HumioExternalClusterOAuth2 {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioExternalClusterOAuth2)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioExternalClusterProxy) DeepCopyInto(out *HumioExternalClusterProxy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalClusterProxy.
+func (in *HumioExternalClusterProxy) DeepCopy() *HumioExternalClusterProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioExternalClusterProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioExternalClusterSpec) DeepCopyInto(out *HumioExternalClusterSpec) {
+	*out = *in
+	if in.Cloud != nil {
+		in, out := &in.Cloud, &out.Cloud
+		*out = new(HumioExternalClusterCloud)
+		**out = **in
+	}
+	if in.FailoverUrls != nil {
+		in, out := &in.FailoverUrls, &out.FailoverUrls
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OAuth2 != nil {
+		in, out := &in.OAuth2, &out.OAuth2
+		*out = new(HumioExternalClusterOAuth2)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.APITokenRotation != nil {
+		in, out := &in.APITokenRotation, &out.APITokenRotation
+		*out = new(HumioExternalClusterAPITokenRotation)
+		**out = **in
+	}
+	if in.APITimeouts != nil {
+		in, out := &in.APITimeouts, &out.APITimeouts
+		*out = new(HumioAPITimeouts)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(HumioExternalClusterProxy)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalClusterSpec.
+func (in *HumioExternalClusterSpec) DeepCopy() *HumioExternalClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioExternalClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioExternalClusterStatus) DeepCopyInto(out *HumioExternalClusterStatus) {
+	*out = *in
+	if in.LastHealthCheckTime != nil {
+		in, out := &in.LastHealthCheckTime, &out.LastHealthCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastAPITokenRotationTime != nil {
+		in, out := &in.LastAPITokenRotationTime, &out.LastAPITokenRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExternalClusterStatus.
+func (in *HumioExternalClusterStatus) DeepCopy() *HumioExternalClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioExternalClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioExtraConfigFile) DeepCopyInto(out *HumioExtraConfigFile) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioExtraConfigFile.
+func (in *HumioExtraConfigFile) DeepCopy() *HumioExtraConfigFile {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioExtraConfigFile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioHostnameSource) DeepCopyInto(out *HumioHostnameSource) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioHostnameSource.
+func (in *HumioHostnameSource) DeepCopy() *HumioHostnameSource {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioHostnameSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioImageSource) DeepCopyInto(out *HumioImageSource) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioImageSource.
+func (in *HumioImageSource) DeepCopy() *HumioImageSource {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioImageSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioImageVerification) DeepCopyInto(out *HumioImageVerification) {
+	*out = *in
+	if in.CosignPublicKeySecretRef != nil {
+		in, out := &in.CosignPublicKeySecretRef, &out.CosignPublicKeySecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioImageVerification.
+func (in *HumioImageVerification) DeepCopy() *HumioImageVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioImageVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioIngestToken) DeepCopyInto(out *HumioIngestToken) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioIngestToken.
+func (in *HumioIngestToken) DeepCopy() *HumioIngestToken {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioIngestToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioIngestToken) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioIngestTokenList) DeepCopyInto(out *HumioIngestTokenList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioIngestToken, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioIngestTokenList.
+func (in *HumioIngestTokenList) DeepCopy() *HumioIngestTokenList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioIngestTokenList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioIngestTokenList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioIngestTokenSpec) DeepCopyInto(out *HumioIngestTokenSpec) {
+	*out = *in
+	if in.TokenSecretLabels != nil {
+		in, out := &in.TokenSecretLabels, &out.TokenSecretLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
 	}
 }
 
@@ -832,266 +2543,1451 @@ func (in *HumioIngestTokenSpec) DeepCopy() *HumioIngestTokenSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioIngestTokenSpec)
+	out := new(HumioIngestTokenSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioIngestTokenStatus) DeepCopyInto(out *HumioIngestTokenStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioIngestTokenStatus.
+func (in *HumioIngestTokenStatus) DeepCopy() *HumioIngestTokenStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioIngestTokenStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioLicenseStatus) DeepCopyInto(out *HumioLicenseStatus) {
+	*out = *in
+	if in.ExpiresInDays != nil {
+		in, out := &in.ExpiresInDays, &out.ExpiresInDays
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioLicenseStatus.
+func (in *HumioLicenseStatus) DeepCopy() *HumioLicenseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioLicenseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioMaintenanceWindow) DeepCopyInto(out *HumioMaintenanceWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioMaintenanceWindow.
+func (in *HumioMaintenanceWindow) DeepCopy() *HumioMaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioMaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioMultiClusterView) DeepCopyInto(out *HumioMultiClusterView) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioMultiClusterView.
+func (in *HumioMultiClusterView) DeepCopy() *HumioMultiClusterView {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioMultiClusterView)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioMultiClusterView) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioMultiClusterViewConnection) DeepCopyInto(out *HumioMultiClusterViewConnection) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioMultiClusterViewConnection.
+func (in *HumioMultiClusterViewConnection) DeepCopy() *HumioMultiClusterViewConnection {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioMultiClusterViewConnection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioMultiClusterViewList) DeepCopyInto(out *HumioMultiClusterViewList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioMultiClusterView, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioMultiClusterViewList.
+func (in *HumioMultiClusterViewList) DeepCopy() *HumioMultiClusterViewList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioMultiClusterViewList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioMultiClusterViewList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioMultiClusterViewSpec) DeepCopyInto(out *HumioMultiClusterViewSpec) {
+	*out = *in
+	if in.Connections != nil {
+		in, out := &in.Connections, &out.Connections
+		*out = make([]HumioMultiClusterViewConnection, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioMultiClusterViewSpec.
+func (in *HumioMultiClusterViewSpec) DeepCopy() *HumioMultiClusterViewSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioMultiClusterViewSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioMultiClusterViewStatus) DeepCopyInto(out *HumioMultiClusterViewStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ConnectionTokenHashes != nil {
+		in, out := &in.ConnectionTokenHashes, &out.ConnectionTokenHashes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioMultiClusterViewStatus.
+func (in *HumioMultiClusterViewStatus) DeepCopy() *HumioMultiClusterViewStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioMultiClusterViewStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioNodePoolSpec) DeepCopyInto(out *HumioNodePoolSpec) {
+	*out = *in
+	in.HumioNodeSpec.DeepCopyInto(&out.HumioNodeSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioNodePoolSpec.
+func (in *HumioNodePoolSpec) DeepCopy() *HumioNodePoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioNodePoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioNodePoolStatus) DeepCopyInto(out *HumioNodePoolStatus) {
+	*out = *in
+	if in.DataNodes != nil {
+		in, out := &in.DataNodes, &out.DataNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioNodePoolStatus.
+func (in *HumioNodePoolStatus) DeepCopy() *HumioNodePoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioNodePoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in HumioNodePoolStatusList) DeepCopyInto(out *HumioNodePoolStatusList) {
+	{
+		in := &in
+		*out = make(HumioNodePoolStatusList, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioNodePoolStatusList.
+func (in HumioNodePoolStatusList) DeepCopy() HumioNodePoolStatusList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioNodePoolStatusList)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioNodeSpec) DeepCopyInto(out *HumioNodeSpec) {
+	*out = *in
+	in.DataVolumePersistentVolumeClaimSpecTemplate.DeepCopyInto(&out.DataVolumePersistentVolumeClaimSpecTemplate)
+	out.DataVolumePersistentVolumeClaimPolicy = in.DataVolumePersistentVolumeClaimPolicy
+	in.DataVolumeSource.DeepCopyInto(&out.DataVolumeSource)
+	if in.NodeTags != nil {
+		in, out := &in.NodeTags, &out.NodeTags
+		*out = make([]HumioNodeTag, len(*in))
+		copy(*out, *in)
+	}
+	if in.EnvironmentVariablesSource != nil {
+		in, out := &in.EnvironmentVariablesSource, &out.EnvironmentVariablesSource
+		*out = make([]v1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PodAnnotations != nil {
+		in, out := &in.PodAnnotations, &out.PodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ShareProcessNamespace != nil {
+		in, out := &in.ShareProcessNamespace, &out.ShareProcessNamespace
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.ContainerSecurityContext != nil {
+		in, out := &in.ContainerSecurityContext, &out.ContainerSecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerReadinessProbe != nil {
+		in, out := &in.ContainerReadinessProbe, &out.ContainerReadinessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerLivenessProbe != nil {
+		in, out := &in.ContainerLivenessProbe, &out.ContainerLivenessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerStartupProbe != nil {
+		in, out := &in.ContainerStartupProbe, &out.ContainerStartupProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodSecurityContext != nil {
+		in, out := &in.PodSecurityContext, &out.PodSecurityContext
+		*out = new(v1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	in.Affinity.DeepCopyInto(&out.Affinity)
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SidecarContainers != nil {
+		in, out := &in.SidecarContainers, &out.SidecarContainers
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtraHumioVolumeMounts != nil {
+		in, out := &in.ExtraHumioVolumeMounts, &out.ExtraHumioVolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtraVolumes != nil {
+		in, out := &in.ExtraVolumes, &out.ExtraVolumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtraConfigFiles != nil {
+		in, out := &in.ExtraConfigFiles, &out.ExtraConfigFiles
+		*out = make([]HumioExtraConfigFile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SecretsStore != nil {
+		in, out := &in.SecretsStore, &out.SecretsStore
+		*out = new(HumioSecretsStore)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HumioServiceAccountAnnotations != nil {
+		in, out := &in.HumioServiceAccountAnnotations, &out.HumioServiceAccountAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.WorkloadIdentity != nil {
+		in, out := &in.WorkloadIdentity, &out.WorkloadIdentity
+		*out = new(HumioWorkloadIdentity)
+		**out = **in
+	}
+	if in.BucketStorageEncryption != nil {
+		in, out := &in.BucketStorageEncryption, &out.BucketStorageEncryption
+		*out = new(HumioBucketStorageEncryption)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HumioServiceLabels != nil {
+		in, out := &in.HumioServiceLabels, &out.HumioServiceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EnvironmentVariables != nil {
+		in, out := &in.EnvironmentVariables, &out.EnvironmentVariables
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImageSource != nil {
+		in, out := &in.ImageSource, &out.ImageSource
+		*out = new(HumioImageSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageVerification != nil {
+		in, out := &in.ImageVerification, &out.ImageVerification
+		*out = new(HumioImageVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HumioServiceAnnotations != nil {
+		in, out := &in.HumioServiceAnnotations, &out.HumioServiceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodLabels != nil {
+		in, out := &in.PodLabels, &out.PodLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(HumioUpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(v1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostAliases != nil {
+		in, out := &in.HostAliases, &out.HostAliases
+		*out = make([]v1.HostAlias, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeRoles != nil {
+		in, out := &in.NodeRoles, &out.NodeRoles
+		*out = make([]HumioNodeRole, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioNodeSpec.
+func (in *HumioNodeSpec) DeepCopy() *HumioNodeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioNodeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioNodeTag) DeepCopyInto(out *HumioNodeTag) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioNodeTag.
+func (in *HumioNodeTag) DeepCopy() *HumioNodeTag {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioNodeTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioParser) DeepCopyInto(out *HumioParser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParser.
+func (in *HumioParser) DeepCopy() *HumioParser {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioParser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioParser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioParserLibrary) DeepCopyInto(out *HumioParserLibrary) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserLibrary.
+func (in *HumioParserLibrary) DeepCopy() *HumioParserLibrary {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioParserLibrary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioParserLibrary) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioParserLibraryGit) DeepCopyInto(out *HumioParserLibraryGit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserLibraryGit.
+func (in *HumioParserLibraryGit) DeepCopy() *HumioParserLibraryGit {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioParserLibraryGit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioParserLibraryList) DeepCopyInto(out *HumioParserLibraryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioParserLibrary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserLibraryList.
+func (in *HumioParserLibraryList) DeepCopy() *HumioParserLibraryList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioParserLibraryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioParserLibraryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioParserLibrarySpec) DeepCopyInto(out *HumioParserLibrarySpec) {
+	*out = *in
+	out.Git = in.Git
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserLibrarySpec.
+func (in *HumioParserLibrarySpec) DeepCopy() *HumioParserLibrarySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioParserLibrarySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioParserLibraryStatus) DeepCopyInto(out *HumioParserLibraryStatus) {
+	*out = *in
+	if in.Parsers != nil {
+		in, out := &in.Parsers, &out.Parsers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserLibraryStatus.
+func (in *HumioParserLibraryStatus) DeepCopy() *HumioParserLibraryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioParserLibraryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioParserList) DeepCopyInto(out *HumioParserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioParser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserList.
+func (in *HumioParserList) DeepCopy() *HumioParserList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioParserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioParserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioParserSpec) DeepCopyInto(out *HumioParserSpec) {
+	*out = *in
+	if in.TagFields != nil {
+		in, out := &in.TagFields, &out.TagFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TestData != nil {
+		in, out := &in.TestData, &out.TestData
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserSpec.
+func (in *HumioParserSpec) DeepCopy() *HumioParserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioParserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioParserStatus) DeepCopyInto(out *HumioParserStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserStatus.
+func (in *HumioParserStatus) DeepCopy() *HumioParserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioParserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioPendingAdminTokenRevocation) DeepCopyInto(out *HumioPendingAdminTokenRevocation) {
+	*out = *in
+	in.RotationTime.DeepCopyInto(&out.RotationTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioPendingAdminTokenRevocation.
+func (in *HumioPendingAdminTokenRevocation) DeepCopy() *HumioPendingAdminTokenRevocation {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioPendingAdminTokenRevocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioPersistentVolumeClaimPolicy) DeepCopyInto(out *HumioPersistentVolumeClaimPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioPersistentVolumeClaimPolicy.
+func (in *HumioPersistentVolumeClaimPolicy) DeepCopy() *HumioPersistentVolumeClaimPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioPersistentVolumeClaimPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioPodStatus) DeepCopyInto(out *HumioPodStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioPodStatus.
+func (in *HumioPodStatus) DeepCopy() *HumioPodStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioPodStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in HumioPodStatusList) DeepCopyInto(out *HumioPodStatusList) {
+	{
+		in := &in
+		*out = make(HumioPodStatusList, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioPodStatusList.
+func (in HumioPodStatusList) DeepCopy() HumioPodStatusList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioPodStatusList)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQuery) DeepCopyInto(out *HumioQuery) {
+	*out = *in
+	if in.DeprecatedIsLive != nil {
+		in, out := &in.DeprecatedIsLive, &out.DeprecatedIsLive
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQuery.
+func (in *HumioQuery) DeepCopy() *HumioQuery {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQuery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryExport) DeepCopyInto(out *HumioQueryExport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryExport.
+func (in *HumioQueryExport) DeepCopy() *HumioQueryExport {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryExport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioQueryExport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryExportArtifact) DeepCopyInto(out *HumioQueryExportArtifact) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryExportArtifact.
+func (in *HumioQueryExportArtifact) DeepCopy() *HumioQueryExportArtifact {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryExportArtifact)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryExportDestination) DeepCopyInto(out *HumioQueryExportDestination) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryExportDestination.
+func (in *HumioQueryExportDestination) DeepCopy() *HumioQueryExportDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryExportDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryExportList) DeepCopyInto(out *HumioQueryExportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioQueryExport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryExportList.
+func (in *HumioQueryExportList) DeepCopy() *HumioQueryExportList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryExportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioQueryExportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryExportSpec) DeepCopyInto(out *HumioQueryExportSpec) {
+	*out = *in
+	out.Destination = in.Destination
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryExportSpec.
+func (in *HumioQueryExportSpec) DeepCopy() *HumioQueryExportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryExportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryExportStatus) DeepCopyInto(out *HumioQueryExportStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Exports != nil {
+		in, out := &in.Exports, &out.Exports
+		*out = make([]HumioQueryExportArtifact, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryExportStatus.
+func (in *HumioQueryExportStatus) DeepCopy() *HumioQueryExportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryExportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryJob) DeepCopyInto(out *HumioQueryJob) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryJob.
+func (in *HumioQueryJob) DeepCopy() *HumioQueryJob {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioQueryJob) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryJobList) DeepCopyInto(out *HumioQueryJobList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioQueryJob, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryJobList.
+func (in *HumioQueryJobList) DeepCopy() *HumioQueryJobList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryJobList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioQueryJobList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryJobObjectOutput) DeepCopyInto(out *HumioQueryJobObjectOutput) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryJobObjectOutput.
+func (in *HumioQueryJobObjectOutput) DeepCopy() *HumioQueryJobObjectOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryJobObjectOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryJobOutput) DeepCopyInto(out *HumioQueryJobOutput) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(HumioQueryJobObjectOutput)
+		**out = **in
+	}
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(HumioQueryJobObjectOutput)
+		**out = **in
+	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(HumioQueryJobWebhookOutput)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryJobOutput.
+func (in *HumioQueryJobOutput) DeepCopy() *HumioQueryJobOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryJobOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryJobSpec) DeepCopyInto(out *HumioQueryJobSpec) {
+	*out = *in
+	in.Output.DeepCopyInto(&out.Output)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryJobSpec.
+func (in *HumioQueryJobSpec) DeepCopy() *HumioQueryJobSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryJobSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryJobStatus) DeepCopyInto(out *HumioQueryJobStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryJobStatus.
+func (in *HumioQueryJobStatus) DeepCopy() *HumioQueryJobStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryJobStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioQueryJobWebhookOutput) DeepCopyInto(out *HumioQueryJobWebhookOutput) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQueryJobWebhookOutput.
+func (in *HumioQueryJobWebhookOutput) DeepCopy() *HumioQueryJobWebhookOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioQueryJobWebhookOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioRehydrationJob) DeepCopyInto(out *HumioRehydrationJob) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRehydrationJob.
+func (in *HumioRehydrationJob) DeepCopy() *HumioRehydrationJob {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioRehydrationJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioRehydrationJob) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioRehydrationJobList) DeepCopyInto(out *HumioRehydrationJobList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioRehydrationJob, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRehydrationJobList.
+func (in *HumioRehydrationJobList) DeepCopy() *HumioRehydrationJobList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioRehydrationJobList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioRehydrationJobList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioRehydrationJobRepositoryStatus) DeepCopyInto(out *HumioRehydrationJobRepositoryStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRehydrationJobRepositoryStatus.
+func (in *HumioRehydrationJobRepositoryStatus) DeepCopy() *HumioRehydrationJobRepositoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioRehydrationJobRepositoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioRehydrationJobSpec) DeepCopyInto(out *HumioRehydrationJobSpec) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Start.DeepCopyInto(&out.Start)
+	if in.End != nil {
+		in, out := &in.End, &out.End
+		*out = (*in).DeepCopy()
+	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRehydrationJobSpec.
+func (in *HumioRehydrationJobSpec) DeepCopy() *HumioRehydrationJobSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioRehydrationJobSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioIngestTokenStatus) DeepCopyInto(out *HumioIngestTokenStatus) {
+func (in *HumioRehydrationJobStatus) DeepCopyInto(out *HumioRehydrationJobStatus) {
 	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]HumioRehydrationJobRepositoryStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioIngestTokenStatus.
-func (in *HumioIngestTokenStatus) DeepCopy() *HumioIngestTokenStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRehydrationJobStatus.
+func (in *HumioRehydrationJobStatus) DeepCopy() *HumioRehydrationJobStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioIngestTokenStatus)
+	out := new(HumioRehydrationJobStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioLicenseStatus) DeepCopyInto(out *HumioLicenseStatus) {
+func (in *HumioRepository) DeepCopyInto(out *HumioRepository) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioLicenseStatus.
-func (in *HumioLicenseStatus) DeepCopy() *HumioLicenseStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepository.
+func (in *HumioRepository) DeepCopy() *HumioRepository {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioLicenseStatus)
+	out := new(HumioRepository)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioRepository) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioNodePoolSpec) DeepCopyInto(out *HumioNodePoolSpec) {
+func (in *HumioRepositoryIngestQuota) DeepCopyInto(out *HumioRepositoryIngestQuota) {
 	*out = *in
-	in.HumioNodeSpec.DeepCopyInto(&out.HumioNodeSpec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioNodePoolSpec.
-func (in *HumioNodePoolSpec) DeepCopy() *HumioNodePoolSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepositoryIngestQuota.
+func (in *HumioRepositoryIngestQuota) DeepCopy() *HumioRepositoryIngestQuota {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioNodePoolSpec)
+	out := new(HumioRepositoryIngestQuota)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioNodePoolStatus) DeepCopyInto(out *HumioNodePoolStatus) {
+func (in *HumioRepositoryIngestQuotaStatus) DeepCopyInto(out *HumioRepositoryIngestQuotaStatus) {
 	*out = *in
+	if in.CheckTime != nil {
+		in, out := &in.CheckTime, &out.CheckTime
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioNodePoolStatus.
-func (in *HumioNodePoolStatus) DeepCopy() *HumioNodePoolStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepositoryIngestQuotaStatus.
+func (in *HumioRepositoryIngestQuotaStatus) DeepCopy() *HumioRepositoryIngestQuotaStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioNodePoolStatus)
+	out := new(HumioRepositoryIngestQuotaStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in HumioNodePoolStatusList) DeepCopyInto(out *HumioNodePoolStatusList) {
-	{
-		in := &in
-		*out = make(HumioNodePoolStatusList, len(*in))
-		copy(*out, *in)
+func (in *HumioRepositoryList) DeepCopyInto(out *HumioRepositoryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioRepository, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioNodePoolStatusList.
-func (in HumioNodePoolStatusList) DeepCopy() HumioNodePoolStatusList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepositoryList.
+func (in *HumioRepositoryList) DeepCopy() *HumioRepositoryList {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioNodePoolStatusList)
+	out := new(HumioRepositoryList)
 	in.DeepCopyInto(out)
-	return *out
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioRepositoryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioNodeSpec) DeepCopyInto(out *HumioNodeSpec) {
+func (in *HumioRepositorySpec) DeepCopyInto(out *HumioRepositorySpec) {
 	*out = *in
-	in.DataVolumePersistentVolumeClaimSpecTemplate.DeepCopyInto(&out.DataVolumePersistentVolumeClaimSpecTemplate)
-	out.DataVolumePersistentVolumeClaimPolicy = in.DataVolumePersistentVolumeClaimPolicy
-	in.DataVolumeSource.DeepCopyInto(&out.DataVolumeSource)
-	if in.EnvironmentVariablesSource != nil {
-		in, out := &in.EnvironmentVariablesSource, &out.EnvironmentVariablesSource
-		*out = make([]v1.EnvFromSource, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.PodAnnotations != nil {
-		in, out := &in.PodAnnotations, &out.PodAnnotations
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.ShareProcessNamespace != nil {
-		in, out := &in.ShareProcessNamespace, &out.ShareProcessNamespace
-		*out = new(bool)
+	out.Retention = in.Retention
+	if in.IngestQuota != nil {
+		in, out := &in.IngestQuota, &out.IngestQuota
+		*out = new(HumioRepositoryIngestQuota)
 		**out = **in
 	}
-	if in.ImagePullSecrets != nil {
-		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
-		*out = make([]v1.LocalObjectReference, len(*in))
-		copy(*out, *in)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepositorySpec.
+func (in *HumioRepositorySpec) DeepCopy() *HumioRepositorySpec {
+	if in == nil {
+		return nil
 	}
-	if in.ContainerSecurityContext != nil {
-		in, out := &in.ContainerSecurityContext, &out.ContainerSecurityContext
-		*out = new(v1.SecurityContext)
-		(*in).DeepCopyInto(*out)
+	out := new(HumioRepositorySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioRepositoryStatistics) DeepCopyInto(out *HumioRepositoryStatistics) {
+	*out = *in
+	if in.OldestEventTime != nil {
+		in, out := &in.OldestEventTime, &out.OldestEventTime
+		*out = (*in).DeepCopy()
 	}
-	if in.ContainerReadinessProbe != nil {
-		in, out := &in.ContainerReadinessProbe, &out.ContainerReadinessProbe
-		*out = new(v1.Probe)
-		(*in).DeepCopyInto(*out)
+	if in.CollectionTime != nil {
+		in, out := &in.CollectionTime, &out.CollectionTime
+		*out = (*in).DeepCopy()
 	}
-	if in.ContainerLivenessProbe != nil {
-		in, out := &in.ContainerLivenessProbe, &out.ContainerLivenessProbe
-		*out = new(v1.Probe)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepositoryStatistics.
+func (in *HumioRepositoryStatistics) DeepCopy() *HumioRepositoryStatistics {
+	if in == nil {
+		return nil
 	}
-	if in.ContainerStartupProbe != nil {
-		in, out := &in.ContainerStartupProbe, &out.ContainerStartupProbe
-		*out = new(v1.Probe)
-		(*in).DeepCopyInto(*out)
+	out := new(HumioRepositoryStatistics)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioRepositoryStatus) DeepCopyInto(out *HumioRepositoryStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
 	}
-	if in.PodSecurityContext != nil {
-		in, out := &in.PodSecurityContext, &out.PodSecurityContext
-		*out = new(v1.PodSecurityContext)
+	if in.IngestQuota != nil {
+		in, out := &in.IngestQuota, &out.IngestQuota
+		*out = new(HumioRepositoryIngestQuotaStatus)
 		(*in).DeepCopyInto(*out)
 	}
-	in.Resources.DeepCopyInto(&out.Resources)
-	if in.TerminationGracePeriodSeconds != nil {
-		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
-		*out = new(int64)
-		**out = **in
-	}
-	in.Affinity.DeepCopyInto(&out.Affinity)
-	if in.Tolerations != nil {
-		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]v1.Toleration, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.TopologySpreadConstraints != nil {
-		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
-		*out = make([]v1.TopologySpreadConstraint, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.SidecarContainers != nil {
-		in, out := &in.SidecarContainers, &out.SidecarContainers
-		*out = make([]v1.Container, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.ExtraHumioVolumeMounts != nil {
-		in, out := &in.ExtraHumioVolumeMounts, &out.ExtraHumioVolumeMounts
-		*out = make([]v1.VolumeMount, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.ExtraVolumes != nil {
-		in, out := &in.ExtraVolumes, &out.ExtraVolumes
-		*out = make([]v1.Volume, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.HumioServiceAccountAnnotations != nil {
-		in, out := &in.HumioServiceAccountAnnotations, &out.HumioServiceAccountAnnotations
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.HumioServiceLabels != nil {
-		in, out := &in.HumioServiceLabels, &out.HumioServiceLabels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	if in.Statistics != nil {
+		in, out := &in.Statistics, &out.Statistics
+		*out = new(HumioRepositoryStatistics)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.EnvironmentVariables != nil {
-		in, out := &in.EnvironmentVariables, &out.EnvironmentVariables
-		*out = make([]v1.EnvVar, len(*in))
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.ImageSource != nil {
-		in, out := &in.ImageSource, &out.ImageSource
-		*out = new(HumioImageSource)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.HumioServiceAnnotations != nil {
-		in, out := &in.HumioServiceAnnotations, &out.HumioServiceAnnotations
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.PodLabels != nil {
-		in, out := &in.PodLabels, &out.PodLabels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepositoryStatus.
+func (in *HumioRepositoryStatus) DeepCopy() *HumioRepositoryStatus {
+	if in == nil {
+		return nil
 	}
-	if in.UpdateStrategy != nil {
-		in, out := &in.UpdateStrategy, &out.UpdateStrategy
-		*out = new(HumioUpdateStrategy)
-		**out = **in
+	out := new(HumioRepositoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioRepositoryUsage) DeepCopyInto(out *HumioRepositoryUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepositoryUsage.
+func (in *HumioRepositoryUsage) DeepCopy() *HumioRepositoryUsage {
+	if in == nil {
+		return nil
 	}
+	out := new(HumioRepositoryUsage)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioNodeSpec.
-func (in *HumioNodeSpec) DeepCopy() *HumioNodeSpec {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioRetention) DeepCopyInto(out *HumioRetention) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRetention.
+func (in *HumioRetention) DeepCopy() *HumioRetention {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioNodeSpec)
+	out := new(HumioRetention)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioParser) DeepCopyInto(out *HumioParser) {
+func (in *HumioRetentionPolicy) DeepCopyInto(out *HumioRetentionPolicy) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParser.
-func (in *HumioParser) DeepCopy() *HumioParser {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRetentionPolicy.
+func (in *HumioRetentionPolicy) DeepCopy() *HumioRetentionPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioParser)
+	out := new(HumioRetentionPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioParser) DeepCopyObject() runtime.Object {
+func (in *HumioRetentionPolicy) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1099,31 +3995,31 @@ func (in *HumioParser) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioParserList) DeepCopyInto(out *HumioParserList) {
+func (in *HumioRetentionPolicyList) DeepCopyInto(out *HumioRetentionPolicyList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]HumioParser, len(*in))
+		*out = make([]HumioRetentionPolicy, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserList.
-func (in *HumioParserList) DeepCopy() *HumioParserList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRetentionPolicyList.
+func (in *HumioRetentionPolicyList) DeepCopy() *HumioRetentionPolicyList {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioParserList)
+	out := new(HumioRetentionPolicyList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioParserList) DeepCopyObject() runtime.Object {
+func (in *HumioRetentionPolicyList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1131,135 +4027,170 @@ func (in *HumioParserList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioParserSpec) DeepCopyInto(out *HumioParserSpec) {
+func (in *HumioRetentionPolicySpec) DeepCopyInto(out *HumioRetentionPolicySpec) {
 	*out = *in
-	if in.TagFields != nil {
-		in, out := &in.TagFields, &out.TagFields
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.TestData != nil {
-		in, out := &in.TestData, &out.TestData
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
+	in.RepositorySelector.DeepCopyInto(&out.RepositorySelector)
+	out.Retention = in.Retention
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserSpec.
-func (in *HumioParserSpec) DeepCopy() *HumioParserSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRetentionPolicySpec.
+func (in *HumioRetentionPolicySpec) DeepCopy() *HumioRetentionPolicySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioParserSpec)
+	out := new(HumioRetentionPolicySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioParserStatus) DeepCopyInto(out *HumioParserStatus) {
+func (in *HumioRetentionPolicyStatus) DeepCopyInto(out *HumioRetentionPolicyStatus) {
 	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioParserStatus.
-func (in *HumioParserStatus) DeepCopy() *HumioParserStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRetentionPolicyStatus.
+func (in *HumioRetentionPolicyStatus) DeepCopy() *HumioRetentionPolicyStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioParserStatus)
+	out := new(HumioRetentionPolicyStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioPersistentVolumeClaimPolicy) DeepCopyInto(out *HumioPersistentVolumeClaimPolicy) {
+func (in *HumioSavedQuery) DeepCopyInto(out *HumioSavedQuery) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioPersistentVolumeClaimPolicy.
-func (in *HumioPersistentVolumeClaimPolicy) DeepCopy() *HumioPersistentVolumeClaimPolicy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioSavedQuery.
+func (in *HumioSavedQuery) DeepCopy() *HumioSavedQuery {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioPersistentVolumeClaimPolicy)
+	out := new(HumioSavedQuery)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioSavedQuery) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioPodStatus) DeepCopyInto(out *HumioPodStatus) {
+func (in *HumioSavedQueryList) DeepCopyInto(out *HumioSavedQueryList) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioSavedQuery, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioPodStatus.
-func (in *HumioPodStatus) DeepCopy() *HumioPodStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioSavedQueryList.
+func (in *HumioSavedQueryList) DeepCopy() *HumioSavedQueryList {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioPodStatus)
+	out := new(HumioSavedQueryList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioSavedQueryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in HumioPodStatusList) DeepCopyInto(out *HumioPodStatusList) {
-	{
-		in := &in
-		*out = make(HumioPodStatusList, len(*in))
-		copy(*out, *in)
+func (in *HumioSavedQuerySpec) DeepCopyInto(out *HumioSavedQuerySpec) {
+	*out = *in
+	if in.Arguments != nil {
+		in, out := &in.Arguments, &out.Arguments
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioPodStatusList.
-func (in HumioPodStatusList) DeepCopy() HumioPodStatusList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioSavedQuerySpec.
+func (in *HumioSavedQuerySpec) DeepCopy() *HumioSavedQuerySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioPodStatusList)
+	out := new(HumioSavedQuerySpec)
 	in.DeepCopyInto(out)
-	return *out
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioQuery) DeepCopyInto(out *HumioQuery) {
+func (in *HumioSavedQueryStatus) DeepCopyInto(out *HumioSavedQueryStatus) {
 	*out = *in
-	if in.DeprecatedIsLive != nil {
-		in, out := &in.DeprecatedIsLive, &out.DeprecatedIsLive
-		*out = new(bool)
-		**out = **in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioQuery.
-func (in *HumioQuery) DeepCopy() *HumioQuery {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioSavedQueryStatus.
+func (in *HumioSavedQueryStatus) DeepCopy() *HumioSavedQueryStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioQuery)
+	out := new(HumioSavedQueryStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioRepository) DeepCopyInto(out *HumioRepository) {
+func (in *HumioScheduledSearch) DeepCopyInto(out *HumioScheduledSearch) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepository.
-func (in *HumioRepository) DeepCopy() *HumioRepository {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioScheduledSearch.
+func (in *HumioScheduledSearch) DeepCopy() *HumioScheduledSearch {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioRepository)
+	out := new(HumioScheduledSearch)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioRepository) DeepCopyObject() runtime.Object {
+func (in *HumioScheduledSearch) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1267,31 +4198,31 @@ func (in *HumioRepository) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioRepositoryList) DeepCopyInto(out *HumioRepositoryList) {
+func (in *HumioScheduledSearchList) DeepCopyInto(out *HumioScheduledSearchList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]HumioRepository, len(*in))
+		*out = make([]HumioScheduledSearch, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepositoryList.
-func (in *HumioRepositoryList) DeepCopy() *HumioRepositoryList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioScheduledSearchList.
+func (in *HumioScheduledSearchList) DeepCopy() *HumioScheduledSearchList {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioRepositoryList)
+	out := new(HumioScheduledSearchList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HumioRepositoryList) DeepCopyObject() runtime.Object {
+func (in *HumioScheduledSearchList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1299,47 +4230,72 @@ func (in *HumioRepositoryList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioRepositorySpec) DeepCopyInto(out *HumioRepositorySpec) {
+func (in *HumioScheduledSearchSpec) DeepCopyInto(out *HumioScheduledSearchSpec) {
 	*out = *in
-	out.Retention = in.Retention
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepositorySpec.
-func (in *HumioRepositorySpec) DeepCopy() *HumioRepositorySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioScheduledSearchSpec.
+func (in *HumioScheduledSearchSpec) DeepCopy() *HumioScheduledSearchSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioRepositorySpec)
+	out := new(HumioScheduledSearchSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioRepositoryStatus) DeepCopyInto(out *HumioRepositoryStatus) {
+func (in *HumioScheduledSearchStatus) DeepCopyInto(out *HumioScheduledSearchStatus) {
 	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRepositoryStatus.
-func (in *HumioRepositoryStatus) DeepCopy() *HumioRepositoryStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioScheduledSearchStatus.
+func (in *HumioScheduledSearchStatus) DeepCopy() *HumioScheduledSearchStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioRepositoryStatus)
+	out := new(HumioScheduledSearchStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HumioRetention) DeepCopyInto(out *HumioRetention) {
+func (in *HumioSecretsStore) DeepCopyInto(out *HumioSecretsStore) {
 	*out = *in
+	if in.SyncedSecrets != nil {
+		in, out := &in.SyncedSecrets, &out.SyncedSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioRetention.
-func (in *HumioRetention) DeepCopy() *HumioRetention {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioSecretsStore.
+func (in *HumioSecretsStore) DeepCopy() *HumioSecretsStore {
 	if in == nil {
 		return nil
 	}
-	out := new(HumioRetention)
+	out := new(HumioSecretsStore)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1347,6 +4303,11 @@ func (in *HumioRetention) DeepCopy() *HumioRetention {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioUpdateStrategy) DeepCopyInto(out *HumioUpdateStrategy) {
 	*out = *in
+	if in.BlueGreen != nil {
+		in, out := &in.BlueGreen, &out.BlueGreen
+		*out = new(HumioUpdateStrategyBlueGreen)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioUpdateStrategy.
@@ -1359,13 +4320,43 @@ func (in *HumioUpdateStrategy) DeepCopy() *HumioUpdateStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioUpdateStrategyBlueGreen) DeepCopyInto(out *HumioUpdateStrategyBlueGreen) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioUpdateStrategyBlueGreen.
+func (in *HumioUpdateStrategyBlueGreen) DeepCopy() *HumioUpdateStrategyBlueGreen {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioUpdateStrategyBlueGreen)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioUsageReporting) DeepCopyInto(out *HumioUsageReporting) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioUsageReporting.
+func (in *HumioUsageReporting) DeepCopy() *HumioUsageReporting {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioUsageReporting)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioView) DeepCopyInto(out *HumioView) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioView.
@@ -1401,6 +4392,109 @@ func (in *HumioViewConnection) DeepCopy() *HumioViewConnection {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioViewExport) DeepCopyInto(out *HumioViewExport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioViewExport.
+func (in *HumioViewExport) DeepCopy() *HumioViewExport {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioViewExport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioViewExport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioViewExportList) DeepCopyInto(out *HumioViewExportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HumioViewExport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioViewExportList.
+func (in *HumioViewExportList) DeepCopy() *HumioViewExportList {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioViewExportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HumioViewExportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioViewExportSpec) DeepCopyInto(out *HumioViewExportSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioViewExportSpec.
+func (in *HumioViewExportSpec) DeepCopy() *HumioViewExportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioViewExportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioViewExportStatus) DeepCopyInto(out *HumioViewExportStatus) {
+	*out = *in
+	if in.Dashboards != nil {
+		in, out := &in.Dashboards, &out.Dashboards
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SavedQueries != nil {
+		in, out := &in.SavedQueries, &out.SavedQueries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExportTime != nil {
+		in, out := &in.ExportTime, &out.ExportTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioViewExportStatus.
+func (in *HumioViewExportStatus) DeepCopy() *HumioViewExportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioViewExportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioViewList) DeepCopyInto(out *HumioViewList) {
 	*out = *in
@@ -1456,6 +4550,17 @@ func (in *HumioViewSpec) DeepCopy() *HumioViewSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HumioViewStatus) DeepCopyInto(out *HumioViewStatus) {
 	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioViewStatus.
@@ -1468,6 +4573,21 @@ func (in *HumioViewStatus) DeepCopy() *HumioViewStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HumioWorkloadIdentity) DeepCopyInto(out *HumioWorkloadIdentity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HumioWorkloadIdentity.
+func (in *HumioWorkloadIdentity) DeepCopy() *HumioWorkloadIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(HumioWorkloadIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VarSource) DeepCopyInto(out *VarSource) {
 	*out = *in
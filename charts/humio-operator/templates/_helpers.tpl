@@ -17,4 +17,335 @@ helm.sh/chart: '{{ include "humio.chart" . }}'
 {{- if .Values.commonLabels }}
 {{ toYaml .Values.commonLabels }}
 {{- end }}
-{{- end }}
\ No newline at end of file
+{{- end }}
+
+{{/*
+Operator deployments. A single deployment runs all controllers, unless operator.splitControllers is set, in which case
+the controllers managing HumioClusters and the controllers managing entities inside Humio run as separate deployments,
+each with its own service account.
+*/}}
+{{- define "humio.operatorComponents" -}}
+components:
+{{- if .Values.operator.splitControllers }}
+- name: '{{ .Release.Name }}-cluster'
+  component: cluster
+  controllers: Cluster
+- name: '{{ .Release.Name }}-entities'
+  component: entities
+  controllers: Entities
+{{- else }}
+- name: '{{ .Release.Name }}'
+  component: ''
+  controllers: All
+{{- end }}
+{{- end -}}
+
+{{/*
+Humio resources managed by the controllers managing HumioClusters.
+*/}}
+{{- define "humio.clusterResources" -}}
+- humioclusters
+- humioclusters/finalizers
+- humioclusters/status
+- humioexternalclusters
+- humioexternalclusters/finalizers
+- humioexternalclusters/status
+- humioclusterbackups
+- humioclusterbackups/finalizers
+- humioclusterbackups/status
+- humioclusterreplications
+- humioclusterreplications/finalizers
+- humioclusterreplications/status
+- humioqueryexports
+- humioqueryexports/finalizers
+- humioqueryexports/status
+- humioclustersets
+- humioclustersets/finalizers
+- humioclustersets/status
+- humiodiagnosticsbundles
+- humiodiagnosticsbundles/finalizers
+- humiodiagnosticsbundles/status
+- humioparserlibraries
+- humioparserlibraries/finalizers
+- humioparserlibraries/status
+{{- end -}}
+
+{{/*
+Humio resources managed by the controllers managing entities inside Humio.
+*/}}
+{{- define "humio.entityResources" -}}
+- humioparsers
+- humioparsers/finalizers
+- humioparsers/status
+- humioingesttokens
+- humioingesttokens/finalizers
+- humioingesttokens/status
+- humiorepositories
+- humiorepositories/finalizers
+- humiorepositories/status
+- humioviews
+- humioviews/finalizers
+- humioviews/status
+- humioactions
+- humioactions/finalizers
+- humioactions/status
+- humioalerts
+- humioalerts/finalizers
+- humioalerts/status
+- humioaggregatealerts
+- humioaggregatealerts/finalizers
+- humioaggregatealerts/status
+- humioscheduledsearches
+- humioscheduledsearches/finalizers
+- humioscheduledsearches/status
+- humiorehydrationjobs
+- humiorehydrationjobs/finalizers
+- humiorehydrationjobs/status
+- humioqueryjobs
+- humioqueryjobs/finalizers
+- humioqueryjobs/status
+- humioretentionpolicies
+- humioretentionpolicies/finalizers
+- humioretentionpolicies/status
+- humiomulticlusterviews
+- humiomulticlusterviews/finalizers
+- humiomulticlusterviews/status
+- humiosavedqueries
+- humiosavedqueries/finalizers
+- humiosavedqueries/status
+- humiodashboards
+- humiodashboards/finalizers
+- humiodashboards/status
+- humioviewexports
+- humioviewexports/finalizers
+- humioviewexports/status
+- humioactiontemplates
+- humioalertsets
+- humioalertsets/finalizers
+- humioalertsets/status
+- humioalertsilences
+- humioalertsilences/finalizers
+- humioalertsilences/status
+{{- end -}}
+
+{{/*
+RBAC rules for the Humio resources.
+*/}}
+{{- define "humio.humioResourceRules" -}}
+- apiGroups:
+  - core.humio.com
+  resources:
+{{ include "humio.clusterResources" . | indent 2 }}
+{{ include "humio.entityResources" . | indent 2 }}
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - patch
+  - update
+  - watch
+{{- end -}}
+
+{{/*
+Namespaced RBAC rules for the controllers managing HumioClusters.
+*/}}
+{{- define "humio.clusterControllerRules" -}}
+- apiGroups:
+  - ""
+  resources:
+  - pods
+  - pods/status
+  - services
+  - services/finalizers
+  - endpoints
+  - persistentvolumeclaims
+  - events
+  - configmaps
+  - secrets
+  - serviceaccounts
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - patch
+  - update
+  - watch
+- apiGroups:
+  - ""
+  resources:
+  - pods/log
+  verbs:
+  - get
+- apiGroups:
+  - apps
+  resources:
+  - replicasets
+  - deployments
+  verbs:
+  - get
+- apiGroups:
+  - batch
+  resources:
+  - jobs
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - watch
+- apiGroups:
+  - monitoring.coreos.com
+  resources:
+  - servicemonitors
+  verbs:
+  - get
+  - create
+- apiGroups:
+  - apps
+  resourceNames:
+  - humio-operator
+  resources:
+  - deployments/finalizers
+  verbs:
+  - update
+{{ include "humio.humioResourceRules" . }}
+{{- if .Values.operator.rbac.allowManageRoles }}
+- apiGroups:
+  - rbac.authorization.k8s.io
+  resources:
+  - roles
+  - rolebindings
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - patch
+  - update
+  - watch
+{{- end }}
+- apiGroups:
+  - networking.k8s.io
+  resources:
+  - ingresses
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - patch
+  - update
+  - watch
+{{- if .Values.certmanager }}
+- apiGroups:
+  - cert-manager.io
+  resources:
+  - certificates
+  - issuers
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - patch
+  - update
+  - watch
+{{- end }}
+{{- end -}}
+
+{{/*
+Namespaced RBAC rules for the controllers managing entities inside Humio. They only use the Humio API, so they are not
+granted access to pods, persistent volume claims or any other objects making up the clusters. They may read the
+clusters they connect to, but only change the entity resources, and the status of HumioExternalClusters which are
+reconciled along with the entities.
+*/}}
+{{- define "humio.entityControllerRules" -}}
+- apiGroups:
+  - ""
+  resources:
+  - configmaps
+  - secrets
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - patch
+  - update
+  - watch
+- apiGroups:
+  - ""
+  resources:
+  - events
+  verbs:
+  - create
+  - patch
+- apiGroups:
+  - core.humio.com
+  resources:
+  - humioclusters
+  - humioexternalclusters
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - core.humio.com
+  resources:
+  - humioexternalclusters/status
+  verbs:
+  - get
+  - patch
+  - update
+- apiGroups:
+  - core.humio.com
+  resources:
+{{ include "humio.entityResources" . | indent 2 }}
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - patch
+  - update
+  - watch
+{{- end -}}
+
+{{/*
+Whether the operator serves any webhooks.
+*/}}
+{{- define "humio.webhooksEnabled" -}}
+{{- if or .Values.operator.parserValidationWebhook.enabled .Values.operator.environmentVariablePolicy.deniedEnvironmentVariables -}}
+true
+{{- end -}}
+{{- end -}}
+
+{{/*
+Whether the operator generates and rotates the serving certificate of the webhooks itself, which is the case unless
+cert-manager is available to issue it.
+*/}}
+{{- define "humio.webhookSelfManagedCertificate" -}}
+{{- if or (not .Values.certmanager) .Values.operator.webhooks.selfManagedCertificate -}}
+true
+{{- end -}}
+{{- end -}}
+
+{{/*
+Name of the service account of the operator deployment running the controllers managing entities inside Humio.
+*/}}
+{{- define "humio.entityServiceAccountName" -}}
+{{- if .Values.operator.splitControllers -}}
+{{ .Release.Name }}-entities
+{{- else -}}
+{{ .Release.Name }}
+{{- end -}}
+{{- end -}}
+
+{{/*
+Name of the service account of the operator deployment serving the webhooks.
+*/}}
+{{- define "humio.webhookServiceAccountName" -}}
+{{ include "humio.entityServiceAccountName" . }}
+{{- end -}}
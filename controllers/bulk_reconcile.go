@@ -0,0 +1,157 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// When bulk listing is enabled, an entity which needs to be updated is updated together with every other entity of
+// the same view or repository which needs to be updated. The entities are looked up in the listings kept in the read
+// cache, diffed against their custom resources, and updated in a single batch. The other custom resources then find
+// their entities in sync when they are reconciled, and only record their status.
+
+// bulkScope identifies the entities which are listed together, which are the entities of a single view or repository
+// as seen using a single API token
+type bulkScope struct {
+	managedClusterName  string
+	externalClusterName string
+	apiTokenSecretName  string
+	searchDomainName    string
+}
+
+// inBulkScope returns whether other is another resource in the same bulk scope as obj, and is still managed by the
+// operator
+func inBulkScope(obj client.Object, scope bulkScope, other client.Object, otherScope bulkScope) bool {
+	if obj.GetName() == other.GetName() || other.GetDeletionTimestamp() != nil {
+		return false
+	}
+	return helpers.ContainsElement(other.GetFinalizers(), humioFinalizer) && scope == otherScope
+}
+
+// alertDrift compares the alert in Humio with the desired state of the HumioAlert, where effectiveAlert is the
+// HumioAlert with its silences applied. Both alerts are sanitized before they are compared.
+func alertDrift(ha, effectiveAlert *humiov1alpha1.HumioAlert, curAlert *humioapi.Alert, actionIdMap map[string]string) (driftOutcome, string, *humioapi.Alert, error) {
+	expectedAlert, err := humio.AlertTransform(effectiveAlert, actionIdMap)
+	if err != nil {
+		return driftInSync, "", nil, err
+	}
+	sanitizeAlert(curAlert)
+	sanitizeAlert(expectedAlert)
+	desiredHash := desiredStateHash(expectedAlert)
+	return evaluateDrift(ha.Spec.DriftPolicy, ha.Status.AppliedHash, desiredHash, !reflect.DeepEqual(*curAlert, *expectedAlert)), desiredHash, expectedAlert, nil
+}
+
+func alertBulkScope(ha *humiov1alpha1.HumioAlert) bulkScope {
+	return bulkScope{ha.Spec.ManagedClusterName, ha.Spec.ExternalClusterName, ha.Spec.APITokenSecretName, ha.Spec.ViewName}
+}
+
+// updateAlertsInBatch updates the given alert along with every other alert of the same view which needs to be updated
+func (r *HumioAlertReconciler) updateAlertsInBatch(ctx context.Context, config *humioapi.Config, req reconcile.Request, ha, effectiveAlert *humiov1alpha1.HumioAlert, now time.Time) error {
+	var list humiov1alpha1.HumioAlertList
+	if err := r.List(ctx, &list, client.InNamespace(ha.Namespace)); err != nil {
+		return fmt.Errorf("could not list alerts: %w", err)
+	}
+
+	batch := []*humiov1alpha1.HumioAlert{effectiveAlert}
+	for i := range list.Items {
+		other := &list.Items[i]
+		if !inBulkScope(ha, alertBulkScope(ha), other, alertBulkScope(other)) {
+			continue
+		}
+		silences, _, err := r.alertSilences(ctx, other, now)
+		if err != nil {
+			return fmt.Errorf("could not list silences of alert %s: %w", other.Name, err)
+		}
+		effectiveOther := alertWithSilences(other, silences)
+		// Alerts which do not exist yet are created when their own custom resource is reconciled
+		curAlert, err := r.HumioClient.GetAlert(config, req, other)
+		if err != nil || curAlert == nil {
+			continue
+		}
+		actionIdMap, err := r.HumioClient.GetActionIDsMapForAlerts(config, req, other)
+		if err != nil {
+			continue
+		}
+		outcome, _, _, err := alertDrift(other, effectiveOther, curAlert, actionIdMap)
+		if err == nil && outcome.updates() {
+			batch = append(batch, effectiveOther)
+		}
+	}
+
+	r.Log.Info(fmt.Sprintf("updating %d alerts of view %s in a batch", len(batch), ha.Spec.ViewName))
+	return r.HumioClient.UpdateAlerts(config, req, batch)
+}
+
+// parserDiff returns the differences between the parser in Humio and the spec of the HumioParser. Humio returns empty
+// lists where the spec leaves them out, so empty and missing lists are treated as equal.
+func parserDiff(curParser *humioapi.Parser, hp *humiov1alpha1.HumioParser) (parserScriptDiff, tagFieldsDiff, testDataDiff string) {
+	return cmp.Diff(curParser.Script, hp.Spec.ParserScript),
+		cmp.Diff(curParser.TagFields, hp.Spec.TagFields, cmpopts.EquateEmpty()),
+		cmp.Diff(curParser.Tests, hp.Spec.TestData, cmpopts.EquateEmpty())
+}
+
+// parserDrift compares the parser in Humio with the spec of the HumioParser
+func parserDrift(curParser *humioapi.Parser, hp *humiov1alpha1.HumioParser) driftOutcome {
+	parserScriptDiff, tagFieldsDiff, testDataDiff := parserDiff(curParser, hp)
+	desiredHash := desiredStateHash([]interface{}{hp.Spec.ParserScript, hp.Spec.TagFields, hp.Spec.TestData})
+	return evaluateDrift(hp.Spec.DriftPolicy, hp.Status.AppliedHash, desiredHash, parserScriptDiff != "" || tagFieldsDiff != "" || testDataDiff != "")
+}
+
+func parserBulkScope(hp *humiov1alpha1.HumioParser) bulkScope {
+	return bulkScope{hp.Spec.ManagedClusterName, hp.Spec.ExternalClusterName, hp.Spec.APITokenSecretName, hp.Spec.RepositoryName}
+}
+
+// updateParsersInBatch updates the given parser along with every other parser of the same repository which needs to be
+// updated
+func (r *HumioParserReconciler) updateParsersInBatch(ctx context.Context, config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) error {
+	var list humiov1alpha1.HumioParserList
+	if err := r.List(ctx, &list, client.InNamespace(hp.Namespace)); err != nil {
+		return fmt.Errorf("could not list parsers: %w", err)
+	}
+
+	batch := []*humiov1alpha1.HumioParser{hp}
+	for i := range list.Items {
+		other := &list.Items[i]
+		if !inBulkScope(hp, parserBulkScope(hp), other, parserBulkScope(other)) {
+			continue
+		}
+		// Parsers which do not exist yet are created when their own custom resource is reconciled
+		curParser, err := r.HumioClient.GetParser(config, req, other)
+		if err != nil || curParser == nil {
+			continue
+		}
+		if parserDrift(curParser, other).updates() {
+			batch = append(batch, other)
+		}
+	}
+
+	r.Log.Info(fmt.Sprintf("updating %d parsers of repository %s in a batch", len(batch), hp.Spec.RepositoryName))
+	return r.HumioClient.UpdateParsers(config, req, batch)
+}
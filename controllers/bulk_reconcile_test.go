@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	humiofake "github.com/humio/humio-operator/pkg/humio/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// batchRecordingClient records the parsers updated in each batch
+type batchRecordingClient struct {
+	humio.Client
+	batches [][]string
+}
+
+func (c *batchRecordingClient) UpdateParsers(config *humioapi.Config, req reconcile.Request, hps []*humiov1alpha1.HumioParser) error {
+	var names []string
+	for _, hp := range hps {
+		names = append(names, hp.Spec.Name)
+	}
+	sort.Strings(names)
+	c.batches = append(c.batches, names)
+	return c.Client.UpdateParsers(config, req, hps)
+}
+
+func TestUpdateParsersInBatch(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+
+	server := humiofake.NewServer()
+	defer server.Close()
+	config := server.Config()
+	req := reconcile.Request{}
+	humioClient := &batchRecordingClient{Client: humio.NewClient(logr.Discard(), config, "").WithReadCache(time.Hour).WithBulkListing(true)}
+
+	newParser := func(name, repositoryName string) *humiov1alpha1.HumioParser {
+		return &humiov1alpha1.HumioParser{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Finalizers: []string{humioFinalizer}},
+			Spec: humiov1alpha1.HumioParserSpec{
+				ManagedClusterName: "humio",
+				Name:               name,
+				RepositoryName:     repositoryName,
+				ParserScript:       "kvParse()",
+			},
+		}
+	}
+	changed := newParser("changed", "logs")
+	drifted := newParser("drifted", "logs")
+	inSync := newParser("in-sync", "logs")
+	otherRepository := newParser("other-repository", "audit")
+	notCreated := newParser("not-created", "logs")
+	for _, repositoryName := range []string{"logs", "audit"} {
+		if _, err := humioClient.AddRepository(config, req, &humiov1alpha1.HumioRepository{Spec: humiov1alpha1.HumioRepositorySpec{Name: repositoryName}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, hp := range []*humiov1alpha1.HumioParser{changed, drifted, inSync, otherRepository} {
+		if _, err := humioClient.AddParser(config, req, hp); err != nil {
+			t.Fatal(err)
+		}
+		hp.Status.AppliedHash = desiredStateHash([]interface{}{hp.Spec.ParserScript, hp.Spec.TagFields, hp.Spec.TestData})
+	}
+
+	// The spec of one parser changes, while two other parsers are changed inside Humio
+	changed.Spec.ParserScript = "parseJson()"
+	for _, hp := range []*humiov1alpha1.HumioParser{drifted, otherRepository} {
+		outside := hp.DeepCopy()
+		outside.Spec.ParserScript = "parseTimestamp()"
+		if _, err := humioClient.UpdateParser(config, req, outside); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &HumioParserReconciler{
+		Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(changed, drifted, inSync, otherRepository, notCreated).Build(),
+		Log:         logr.Discard(),
+		HumioClient: humioClient,
+	}
+	if err := r.updateParsersInBatch(context.Background(), config, req, changed); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(humioClient.batches) != 1 || len(humioClient.batches[0]) != 2 || humioClient.batches[0][0] != "changed" || humioClient.batches[0][1] != "drifted" {
+		t.Errorf("expected the changed and drifted parsers of the repository to be updated in one batch, got %v", humioClient.batches)
+	}
+	for _, hp := range []*humiov1alpha1.HumioParser{changed, drifted, inSync, otherRepository} {
+		parser, err := humioClient.GetParser(config, req, hp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := hp.Spec.ParserScript
+		if hp == otherRepository {
+			expected = "parseTimestamp()"
+		}
+		if parser.Script != expected {
+			t.Errorf("expected parser %s to have script %q, got %q", hp.Spec.Name, expected, parser.Script)
+		}
+		if scriptDiff, tagFieldsDiff, testDataDiff := parserDiff(parser, hp); hp != otherRepository && scriptDiff+tagFieldsDiff+testDataDiff != "" {
+			t.Errorf("expected parser %s to match its spec after the batch, got %s%s%s", hp.Spec.Name, scriptDiff, tagFieldsDiff, testDataDiff)
+		}
+	}
+}
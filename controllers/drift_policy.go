@@ -0,0 +1,126 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"reflect"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// driftOutcome describes how a difference between an entity in Humio and its desired state is handled
+type driftOutcome int
+
+const (
+	// driftInSync means the entity matches its desired state
+	driftInSync driftOutcome = iota
+	// driftApply means the desired state changed since it was last applied, so the entity is updated
+	driftApply
+	// driftRevert means the entity was changed outside the operator, and the changes are reverted
+	driftRevert
+	// driftIgnore means the entity was changed outside the operator, and the changes are left in place
+	driftIgnore
+)
+
+const (
+	driftedReasonInSync                 = "InSync"
+	driftedReasonChangedOutsideOperator = "ChangedOutsideOperator"
+)
+
+// evaluateDrift returns how an entity is handled, given whether it differs from its desired state. Changes to the
+// desired state are always applied, while changes made outside the operator are handled according to the policy.
+func evaluateDrift(policy, appliedHash, desiredHash string, differs bool) driftOutcome {
+	switch {
+	case !differs:
+		return driftInSync
+	case appliedHash != desiredHash:
+		return driftApply
+	case policy == humiov1alpha1.HumioDriftPolicyWarn, policy == humiov1alpha1.HumioDriftPolicyImport:
+		return driftIgnore
+	}
+	return driftRevert
+}
+
+// updates returns whether the entity is updated to its desired state
+func (o driftOutcome) updates() bool {
+	return o == driftApply || o == driftRevert
+}
+
+// desiredStateHash returns a hash of the desired state of an entity
+func desiredStateHash(desired interface{}) string {
+	data, _ := json.Marshal(desired)
+	return helpers.AsSHA256(string(data))
+}
+
+// applyDriftOutcome records the outcome in the applied hash and the Drifted condition of a status, and records an
+// event on the object when changes made outside the operator are first left in place
+func applyDriftOutcome(recorder record.EventRecorder, obj runtime.Object, outcome driftOutcome, desiredHash string, appliedHash *string, conditions *[]metav1.Condition, generation int64) {
+	if outcome != driftIgnore && appliedHash != nil {
+		*appliedHash = desiredHash
+	}
+	condition := metav1.Condition{
+		Type:               humiov1alpha1.HumioDriftedConditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             driftedReasonInSync,
+		Message:            "The entity matches the spec",
+		ObservedGeneration: generation,
+	}
+	if outcome == driftIgnore {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = driftedReasonChangedOutsideOperator
+		condition.Message = "The entity was changed outside the operator, and the changes are left in place until the spec changes"
+		if recorder != nil && !meta.IsStatusConditionTrue(*conditions, humiov1alpha1.HumioDriftedConditionType) {
+			recorder.Event(obj, corev1.EventTypeWarning, humiov1alpha1.HumioDriftedConditionType, condition.Message)
+		}
+	}
+	meta.SetStatusCondition(conditions, condition)
+}
+
+// driftPatch returns a JSON merge patch which changes the spec of a resource from the desired spec to the imported
+// spec, which reflects the changes made to the entity outside the operator. Only the top-level fields of the spec which
+// differ are included, and an empty string is returned if there are none.
+func driftPatch(desired, imported interface{}) string {
+	var desiredFields, importedFields map[string]interface{}
+	desiredData, _ := json.Marshal(desired)
+	importedData, _ := json.Marshal(imported)
+	_ = json.Unmarshal(desiredData, &desiredFields)
+	_ = json.Unmarshal(importedData, &importedFields)
+
+	changed := map[string]interface{}{}
+	for field, value := range importedFields {
+		if !reflect.DeepEqual(desiredFields[field], value) {
+			changed[field] = value
+		}
+	}
+	for field := range desiredFields {
+		if _, ok := importedFields[field]; !ok {
+			changed[field] = nil
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+	patch, _ := json.Marshal(map[string]interface{}{"spec": changed})
+	return string(patch)
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEvaluateDrift(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		appliedHash string
+		differs     bool
+		expected    driftOutcome
+	}{
+		{"in sync", humiov1alpha1.HumioDriftPolicyWarn, "desired", false, driftInSync},
+		{"spec changed", humiov1alpha1.HumioDriftPolicyWarn, "previous", true, driftApply},
+		{"never applied", humiov1alpha1.HumioDriftPolicyWarn, "", true, driftApply},
+		{"changed outside operator with default policy", "", "desired", true, driftRevert},
+		{"changed outside operator with enforce policy", humiov1alpha1.HumioDriftPolicyEnforce, "desired", true, driftRevert},
+		{"changed outside operator with warn policy", humiov1alpha1.HumioDriftPolicyWarn, "desired", true, driftIgnore},
+		{"changed outside operator with import policy", humiov1alpha1.HumioDriftPolicyImport, "desired", true, driftIgnore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evaluateDrift(tt.policy, tt.appliedHash, "desired", tt.differs); got != tt.expected {
+				t.Errorf("expected outcome %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyDriftOutcome(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	ha := &humiov1alpha1.HumioAlert{ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "default"}}
+	appliedHash := "previous"
+	var conditions []metav1.Condition
+
+	applyDriftOutcome(recorder, ha, driftApply, "desired", &appliedHash, &conditions, 1)
+	if appliedHash != "desired" || meta.IsStatusConditionTrue(conditions, humiov1alpha1.HumioDriftedConditionType) {
+		t.Errorf("expected applied hash to be updated without drift, got %q and %+v", appliedHash, conditions)
+	}
+
+	// Changes left in place keep the applied hash, and are reported by a single event
+	for i := 0; i < 2; i++ {
+		applyDriftOutcome(recorder, ha, driftIgnore, "other", &appliedHash, &conditions, 1)
+	}
+	if appliedHash != "desired" || !meta.IsStatusConditionTrue(conditions, humiov1alpha1.HumioDriftedConditionType) {
+		t.Errorf("expected drift to be reported, got %q and %+v", appliedHash, conditions)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected a single drift event, got %d", len(recorder.Events))
+	}
+
+	applyDriftOutcome(recorder, ha, driftRevert, "desired", &appliedHash, &conditions, 1)
+	if meta.IsStatusConditionTrue(conditions, humiov1alpha1.HumioDriftedConditionType) {
+		t.Errorf("expected reverted drift to clear the condition, got %+v", conditions)
+	}
+}
+
+func TestDriftPatch(t *testing.T) {
+	desired := humiov1alpha1.HumioParserSpec{Name: "accesslog", ParserScript: "kvParse()", TagFields: []string{"host"}}
+	tests := []struct {
+		name     string
+		imported humiov1alpha1.HumioParserSpec
+		expected string
+	}{
+		{"unchanged", desired, ""},
+		{
+			name:     "changed script",
+			imported: humiov1alpha1.HumioParserSpec{Name: "accesslog", ParserScript: "parseJson()", TagFields: []string{"host"}},
+			expected: `{"spec":{"parserScript":"parseJson()"}}`,
+		},
+		{
+			name:     "removed tag fields and added test data",
+			imported: humiov1alpha1.HumioParserSpec{Name: "accesslog", ParserScript: "kvParse()", TestData: []string{"a=b"}},
+			expected: `{"spec":{"tagFields":null,"testData":["a=b"]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := driftPatch(desired, tt.imported); got != tt.expected {
+				t.Errorf("expected patch %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
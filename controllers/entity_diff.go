@@ -0,0 +1,296 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	humioapi "github.com/humio/cli/api"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+)
+
+// EntityDiff holds the differences between the entity a custom resource expects inside Humio and the entity as it
+// currently is in Humio
+type EntityDiff struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Missing is set if the entity does not exist in Humio
+	Missing bool
+	// Diff lists the fields which differ, with the values in Humio prefixed by "-" and the values expected by the
+	// custom resource prefixed by "+". It is empty if the entity is as expected.
+	Diff string
+	// Err is set if the custom resource could not be compared with Humio
+	Err error
+}
+
+// InSync returns whether the entity in Humio is as expected by the custom resource
+func (d EntityDiff) InSync() bool {
+	return d.Err == nil && !d.Missing && d.Diff == ""
+}
+
+// entityDiffer compares a single custom resource of one kind with its entity in Humio
+type entityDiffer struct {
+	newList func() client.ObjectList
+	diff    func(ctx context.Context, r *entityDiffReconciler, config *humioapi.Config, req reconcile.Request, obj client.Object) (missing bool, diff string, err error)
+}
+
+// entityDiffReconciler holds the clients used to compare custom resources with Humio, and provides the helpers the
+// controllers use to determine the effective spec of a resource, e.g. with retention policies and silences applied
+type entityDiffReconciler struct {
+	client.Client
+	HumioClient humio.Client
+}
+
+var entityDiffers = map[string]entityDiffer{
+	"HumioRepository": {
+		newList: func() client.ObjectList { return &humiov1alpha1.HumioRepositoryList{} },
+		diff:    diffRepository,
+	},
+	"HumioView": {
+		newList: func() client.ObjectList { return &humiov1alpha1.HumioViewList{} },
+		diff:    diffView,
+	},
+	"HumioParser": {
+		newList: func() client.ObjectList { return &humiov1alpha1.HumioParserList{} },
+		diff:    diffParser,
+	},
+	"HumioIngestToken": {
+		newList: func() client.ObjectList { return &humiov1alpha1.HumioIngestTokenList{} },
+		diff:    diffIngestToken,
+	},
+	"HumioAction": {
+		newList: func() client.ObjectList { return &humiov1alpha1.HumioActionList{} },
+		diff:    diffAction,
+	},
+	"HumioAlert": {
+		newList: func() client.ObjectList { return &humiov1alpha1.HumioAlertList{} },
+		diff:    diffAlert,
+	},
+}
+
+// DiffKinds returns the kinds of custom resources which can be compared with Humio by DiffEntities
+func DiffKinds() []string {
+	kinds := make([]string, 0, len(entityDiffers))
+	for kind := range entityDiffers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// DiffEntities compares the custom resources of the given kind in the given namespace with their entities in Humio,
+// the same way the controllers do when deciding whether to update an entity. An empty kind compares the resources of
+// all kinds returned by DiffKinds, and an empty name compares all resources of a kind. Nothing is changed in Humio or
+// Kubernetes.
+func DiffEntities(ctx context.Context, k8sClient client.Client, humioClient humio.Client, namespace, kind, name string) ([]EntityDiff, error) {
+	kinds := DiffKinds()
+	if kind != "" {
+		if _, ok := entityDiffers[kind]; !ok {
+			return nil, fmt.Errorf("unsupported kind %q, must be one of %v", kind, kinds)
+		}
+		kinds = []string{kind}
+	}
+
+	r := &entityDiffReconciler{Client: k8sClient, HumioClient: humioClient}
+	var diffs []EntityDiff
+	for _, kind := range kinds {
+		differ := entityDiffers[kind]
+		list := differ.newList()
+		if err := k8sClient.List(ctx, list, client.InNamespace(namespace)); err != nil {
+			return nil, fmt.Errorf("could not list %s resources: %w", kind, err)
+		}
+		objs, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objs {
+			obj := o.(client.Object)
+			if name != "" && obj.GetName() != name {
+				continue
+			}
+			diff := EntityDiff{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+			config, err := r.clusterConfig(ctx, obj)
+			if err == nil {
+				req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}}
+				diff.Missing, diff.Diff, err = differ.diff(ctx, r, config, req, obj)
+			}
+			diff.Err = err
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
+
+// clusterConfig returns the Humio API config for the cluster the given custom resource refers to, using the API token
+// of the resource if it has one. Like entityUsesSecret, the spec fields shared by all Humio entities are read without
+// depending on the type of the resource.
+func (r *entityDiffReconciler) clusterConfig(ctx context.Context, obj client.Object) (*humioapi.Config, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	managedClusterName, _, _ := unstructured.NestedString(content, "spec", "managedClusterName")
+	externalClusterName, _, _ := unstructured.NestedString(content, "spec", "externalClusterName")
+	apiTokenSecretName, _, _ := unstructured.NestedString(content, "spec", "apiTokenSecretName")
+	cluster, err := helpers.NewCluster(ctx, r, managedClusterName, externalClusterName, obj.GetNamespace(), helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, obj.GetNamespace(), apiTokenSecretName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to obtain humio client config: %w", err)
+	}
+	if cluster == nil || cluster.Config() == nil {
+		return nil, errors.New("unable to obtain humio client config")
+	}
+	return cluster.Config(), nil
+}
+
+func diffRepository(ctx context.Context, r *entityDiffReconciler, config *humioapi.Config, req reconcile.Request, obj client.Object) (bool, string, error) {
+	hr := obj.(*humiov1alpha1.HumioRepository)
+	policy, err := (&HumioRepositoryReconciler{Client: r}).retentionPolicyForRepository(ctx, hr)
+	if err != nil {
+		return false, "", fmt.Errorf("could not get retention policy: %w", err)
+	}
+	effectiveRepository := repositoryWithRetentionPolicy(hr, policy)
+	curRepository, err := r.HumioClient.GetRepository(config, req, hr)
+	if err != nil {
+		return false, "", fmt.Errorf("could not check if repository exists: %w", err)
+	}
+	if reflect.DeepEqual(humioapi.Repository{}, *curRepository) {
+		return true, "", nil
+	}
+	// Only the fields the controller updates are compared
+	current := humioapi.Repository{
+		Description:            curRepository.Description,
+		RetentionDays:          curRepository.RetentionDays,
+		IngestRetentionSizeGB:  curRepository.IngestRetentionSizeGB,
+		StorageRetentionSizeGB: curRepository.StorageRetentionSizeGB,
+	}
+	expected := humioapi.Repository{
+		Description:            hr.Spec.Description,
+		RetentionDays:          float64(effectiveRepository.Spec.Retention.TimeInDays),
+		IngestRetentionSizeGB:  float64(effectiveRepository.Spec.Retention.IngestSizeInGB),
+		StorageRetentionSizeGB: float64(effectiveRepository.Spec.Retention.StorageSizeInGB),
+	}
+	return false, cmp.Diff(current, expected), nil
+}
+
+func diffView(_ context.Context, r *entityDiffReconciler, config *humioapi.Config, req reconcile.Request, obj client.Object) (bool, string, error) {
+	hv := obj.(*humiov1alpha1.HumioView)
+	curView, err := r.HumioClient.GetView(config, req, hv)
+	if err != nil {
+		return false, "", fmt.Errorf("could not check if view exists: %w", err)
+	}
+	if reflect.DeepEqual(humioapi.View{}, *curView) {
+		return true, "", nil
+	}
+	expectedConnections := hv.GetViewConnections()
+	if !viewConnectionsDiffer(curView.Connections, expectedConnections) {
+		return false, "", nil
+	}
+	return false, cmp.Diff(curView.Connections, expectedConnections), nil
+}
+
+func diffParser(_ context.Context, r *entityDiffReconciler, config *humioapi.Config, req reconcile.Request, obj client.Object) (bool, string, error) {
+	hp := obj.(*humiov1alpha1.HumioParser)
+	curParser, err := r.HumioClient.GetParser(config, req, hp)
+	if errors.As(err, &humioapi.EntityNotFound{}) {
+		return true, "", nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("could not check if parser exists: %w", err)
+	}
+	current := humioapi.Parser{Script: curParser.Script, TagFields: curParser.TagFields, Tests: curParser.Tests}
+	expected := humioapi.Parser{Script: hp.Spec.ParserScript, TagFields: hp.Spec.TagFields, Tests: hp.Spec.TestData}
+	return false, cmp.Diff(current, expected), nil
+}
+
+func diffIngestToken(_ context.Context, r *entityDiffReconciler, config *humioapi.Config, req reconcile.Request, obj client.Object) (bool, string, error) {
+	hit := obj.(*humiov1alpha1.HumioIngestToken)
+	curToken, err := r.HumioClient.GetIngestToken(config, req, hit)
+	if err != nil {
+		return false, "", fmt.Errorf("could not check if ingest token exists: %w", err)
+	}
+	if *curToken == (humioapi.IngestToken{}) {
+		return true, "", nil
+	}
+	// Only the assigned parser is updated by the controller, and the token itself must not be printed
+	current := humioapi.IngestToken{AssignedParser: curToken.AssignedParser}
+	expected := humioapi.IngestToken{AssignedParser: hit.Spec.ParserName}
+	return false, cmp.Diff(current, expected), nil
+}
+
+func diffAction(_ context.Context, r *entityDiffReconciler, config *humioapi.Config, req reconcile.Request, obj client.Object) (bool, string, error) {
+	ha := obj.(*humiov1alpha1.HumioAction)
+	curAction, err := r.HumioClient.GetAction(config, req, ha)
+	if errors.As(err, &humioapi.EntityNotFound{}) {
+		return true, "", nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("could not check if action exists: %w", err)
+	}
+	expectedAction, err := humio.ActionFromActionCR(ha)
+	if err != nil {
+		return false, "", fmt.Errorf("could not parse expected action: %w", err)
+	}
+	sanitizeAction(curAction)
+	sanitizeAction(expectedAction)
+	return false, cmp.Diff(*curAction, *expectedAction), nil
+}
+
+func diffAlert(ctx context.Context, r *entityDiffReconciler, config *humioapi.Config, req reconcile.Request, obj client.Object) (bool, string, error) {
+	ha := obj.(*humiov1alpha1.HumioAlert)
+	silences, _, err := (&HumioAlertReconciler{Client: r}).alertSilences(ctx, ha, time.Now())
+	if err != nil {
+		return false, "", fmt.Errorf("could not list alert silences: %w", err)
+	}
+	effectiveAlert := alertWithSilences(ha, silences)
+	curAlert, err := r.HumioClient.GetAlert(config, req, ha)
+	if errors.As(err, &humioapi.EntityNotFound{}) {
+		return true, "", nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("could not check if alert exists: %w", err)
+	}
+	actionIdMap, err := r.HumioClient.GetActionIDsMapForAlerts(config, req, ha)
+	if err != nil {
+		return false, "", fmt.Errorf("could not get action id mapping: %w", err)
+	}
+	expectedAlert, err := humio.AlertTransform(effectiveAlert, actionIdMap)
+	if err != nil {
+		return false, "", fmt.Errorf("could not parse expected alert: %w", err)
+	}
+	sanitizeAlert(curAlert)
+	sanitizeAlert(expectedAlert)
+	return false, cmp.Diff(*curAlert, *expectedAlert), nil
+}
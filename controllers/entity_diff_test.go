@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestDiffEntities(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+
+	hc := &humiov1alpha1.HumioCluster{ObjectMeta: metav1.ObjectMeta{Name: "humio", Namespace: "default"}}
+	token := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "humio-admin-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("token")},
+	}
+	hr := &humiov1alpha1.HumioRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "default"},
+		Spec: humiov1alpha1.HumioRepositorySpec{
+			ManagedClusterName: "humio",
+			Name:               "logs",
+			Description:        "application logs",
+			Retention:          humiov1alpha1.HumioRetention{TimeInDays: 30},
+		},
+	}
+	hp := &humiov1alpha1.HumioParser{
+		ObjectMeta: metav1.ObjectMeta{Name: "json", Namespace: "default"},
+		Spec: humiov1alpha1.HumioParserSpec{
+			ManagedClusterName: "humio",
+			Name:               "json",
+			RepositoryName:     "logs",
+			ParserScript:       "parseJson()",
+		},
+	}
+	orphan := &humiov1alpha1.HumioParser{
+		ObjectMeta: metav1.ObjectMeta{Name: "orphan", Namespace: "default"},
+		Spec:       humiov1alpha1.HumioParserSpec{ManagedClusterName: "missing", Name: "orphan", RepositoryName: "logs"},
+	}
+
+	tests := []struct {
+		name string
+		// changed is applied to the repository inside Humio after it has been created
+		changed  func(*humiov1alpha1.HumioRepository)
+		kind     string
+		resource string
+		want     []string
+		wantDiff []string
+		wantErr  bool
+	}{
+		{
+			name: "repository in sync",
+			kind: "HumioRepository",
+			want: []string{"HumioRepository/logs in sync"},
+		},
+		{
+			name: "repository changed inside Humio",
+			changed: func(hr *humiov1alpha1.HumioRepository) {
+				hr.Spec.Description = "changed"
+				hr.Spec.Retention.TimeInDays = 7
+			},
+			kind:     "HumioRepository",
+			want:     []string{"HumioRepository/logs differs"},
+			wantDiff: []string{`- Description: "changed"`, `+ Description: "application logs"`, "- RetentionDays: 7", "+ RetentionDays: 30"},
+		},
+		{
+			name: "parsers",
+			kind: "HumioParser",
+			want: []string{"HumioParser/json missing", "HumioParser/orphan error"},
+		},
+		{
+			name:     "single resource",
+			resource: "json",
+			want:     []string{"HumioParser/json missing"},
+		},
+		{
+			name:    "unsupported kind",
+			kind:    "HumioCluster",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hc, token, hr.DeepCopy(), hp.DeepCopy(), orphan.DeepCopy()).Build()
+			humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+			inHumio := hr.DeepCopy()
+			if tt.changed != nil {
+				tt.changed(inHumio)
+			}
+			_, _ = humioClient.AddRepository(&humioapi.Config{}, reconcile.Request{}, inHumio)
+
+			diffs, err := DiffEntities(context.Background(), k8sClient, humioClient, "default", tt.kind, tt.resource)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %t, got %v", tt.wantErr, err)
+			}
+			var got []string
+			for _, diff := range diffs {
+				got = append(got, describeEntityDiff(diff))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expected\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+			for _, line := range tt.wantDiff {
+				// The output of cmp.Diff is not stable, so whitespace is collapsed before comparing
+				if len(diffs) == 0 || !strings.Contains(strings.Join(strings.Fields(diffs[0].Diff), " "), line) {
+					t.Errorf("expected diff to contain %q, got %+v", line, diffs)
+				}
+			}
+		})
+	}
+}
+
+func describeEntityDiff(diff EntityDiff) string {
+	prefix := diff.Kind + "/" + diff.Name
+	switch {
+	case diff.Err != nil:
+		return prefix + " error"
+	case diff.Missing:
+		return prefix + " missing"
+	case diff.InSync():
+		return prefix + " in sync"
+	}
+	return prefix + " differs"
+}
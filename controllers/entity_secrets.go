@@ -0,0 +1,107 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// entitiesForSecret returns a map function which returns a reconcile request for every Humio entity of the type of
+// list which authenticates using the given secret, so rotated API tokens and certificates are used right away instead
+// of on the next periodic reconcile. Entities authenticate using the secret referenced by their apiTokenSecretName,
+// the admin token secret of their managed cluster or the secrets of their external cluster.
+func entitiesForSecret(c client.Reader, log logr.Logger, list client.ObjectList) handler.MapFunc {
+	return func(ctx context.Context, secret client.Object) []reconcile.Request {
+		var humioExternalClusters humiov1alpha1.HumioExternalClusterList
+		if err := c.List(ctx, &humioExternalClusters, client.InNamespace(secret.GetNamespace())); err != nil {
+			log.Error(err, "unable to list external clusters")
+			return nil
+		}
+		var externalClusterNames []string
+		for idx := range humioExternalClusters.Items {
+			hec := &humioExternalClusters.Items[idx]
+			if helpers.ContainsElement(humioExternalClusterSecretNames(hec), secret.GetName()) {
+				externalClusterNames = append(externalClusterNames, hec.Name)
+			}
+		}
+
+		entities, ok := list.DeepCopyObject().(client.ObjectList)
+		if !ok {
+			return nil
+		}
+		if err := c.List(ctx, entities, client.InNamespace(secret.GetNamespace())); err != nil {
+			log.Error(err, "unable to list entities")
+			return nil
+		}
+		items, err := meta.ExtractList(entities)
+		if err != nil {
+			log.Error(err, "unable to list entities")
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, item := range items {
+			entity, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			usesSecret, err := entityUsesSecret(entity, secret.GetName(), externalClusterNames)
+			if err != nil {
+				log.Error(err, fmt.Sprintf("unable to read the cluster reference of %s", entity.GetName()))
+				continue
+			}
+			if usesSecret {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: entity.GetNamespace(), Name: entity.GetName()},
+				})
+			}
+		}
+		return requests
+	}
+}
+
+// entityUsesSecret returns true if the entity authenticates using the secret with the given name. All Humio entities
+// share the spec fields managedClusterName, externalClusterName and apiTokenSecretName, which are read without
+// depending on the type of the entity.
+func entityUsesSecret(entity runtime.Object, secretName string, externalClusterNames []string) (bool, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(entity)
+	if err != nil {
+		return false, err
+	}
+	apiTokenSecretName, _, _ := unstructured.NestedString(content, "spec", "apiTokenSecretName")
+	if apiTokenSecretName == secretName {
+		return true, nil
+	}
+	managedClusterName, _, _ := unstructured.NestedString(content, "spec", "managedClusterName")
+	if managedClusterName != "" && fmt.Sprintf("%s-%s", managedClusterName, kubernetes.ServiceTokenSecretNameSuffix) == secretName {
+		return true, nil
+	}
+	externalClusterName, _, _ := unstructured.NestedString(content, "spec", "externalClusterName")
+	return externalClusterName != "" && helpers.ContainsElement(externalClusterNames, externalClusterName), nil
+}
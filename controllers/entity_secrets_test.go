@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEntitiesForSecret(t *testing.T) {
+	repository := func(name string, spec humiov1alpha1.HumioRepositorySpec) *humiov1alpha1.HumioRepository {
+		spec.Name = name
+		return &humiov1alpha1.HumioRepository{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "logging"}, Spec: spec}
+	}
+	objects := []client.Object{
+		&humiov1alpha1.HumioExternalCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cloud", Namespace: "logging"},
+			Spec:       humiov1alpha1.HumioExternalClusterSpec{APITokenSecretName: "cloud-token", CASecretName: "cloud-ca"},
+		},
+		repository("managed", humiov1alpha1.HumioRepositorySpec{ManagedClusterName: "humio"}),
+		repository("external", humiov1alpha1.HumioRepositorySpec{ExternalClusterName: "cloud"}),
+		repository("scoped", humiov1alpha1.HumioRepositorySpec{ManagedClusterName: "humio", APITokenSecretName: "scoped-token"}),
+	}
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	mapFunc := entitiesForSecret(c, logr.Discard(), &humiov1alpha1.HumioRepositoryList{})
+
+	tt := []struct {
+		secretName string
+		expected   []string
+	}{
+		{"humio-admin-token", []string{"managed", "scoped"}},
+		{"cloud-token", []string{"external"}},
+		{"cloud-ca", []string{"external"}},
+		{"scoped-token", []string{"scoped"}},
+		{"unrelated", nil},
+	}
+	for _, tc := range tt {
+		t.Run(tc.secretName, func(t *testing.T) {
+			var names []string
+			for _, request := range mapFunc(context.Background(), &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: tc.secretName, Namespace: "logging"}}) {
+				names = append(names, request.Name)
+			}
+			sort.Strings(names)
+			if len(names) != len(tc.expected) {
+				t.Fatalf("expected %v to be enqueued, got %v", tc.expected, names)
+			}
+			for idx := range names {
+				if names[idx] != tc.expected[idx] {
+					t.Errorf("expected %v to be enqueued, got %v", tc.expected, names)
+				}
+			}
+		})
+	}
+}
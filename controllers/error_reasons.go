@@ -0,0 +1,183 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// errorReason classifies the error a reconcile failed with into one of the reasons reported in the status
+func errorReason(err error) string {
+	var netErr net.Error
+	var queryErr humioapi.QueryError
+	switch {
+	case errors.Is(err, humio.ErrClusterUnavailable), errors.Is(err, helpers.ErrExternalClusterUnavailable), errors.As(err, &netErr):
+		return humiov1alpha1.HumioErrorReasonClusterUnreachable
+	case errors.Is(err, humio.ErrActionNotFound):
+		return humiov1alpha1.HumioErrorReasonReferencedActionMissing
+	case errors.As(err, &queryErr):
+		return humiov1alpha1.HumioErrorReasonInvalidQuery
+	}
+
+	// Errors returned by the Humio GraphQL API only carry a message
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "401 unauthorized"), strings.Contains(message, "403 forbidden"),
+		strings.Contains(message, "not authorized"), strings.Contains(message, "permission"):
+		return humiov1alpha1.HumioErrorReasonAuthFailed
+	case strings.Contains(message, "429 too many requests"), strings.Contains(message, "quota"),
+		strings.Contains(message, "limit exceeded"):
+		return humiov1alpha1.HumioErrorReasonQuotaExceeded
+	case strings.Contains(message, "query") && (strings.Contains(message, "invalid") || strings.Contains(message, "parse")):
+		return humiov1alpha1.HumioErrorReasonInvalidQuery
+	}
+	return humiov1alpha1.HumioErrorReasonUnknown
+}
+
+// statusErrorReason returns the reason to report for a reconcile which returned the given error and left the resource
+// in the given state. Reconciles which fail because of an invalid spec often requeue without returning an error, so
+// those are reported based on their state.
+func statusErrorReason(err error, state, currentReason string) string {
+	if err != nil {
+		return errorReason(err)
+	}
+	switch state {
+	case humiov1alpha1.HumioAlertStateClusterUnavailable:
+		return humiov1alpha1.HumioErrorReasonClusterUnreachable
+	case humiov1alpha1.HumioAlertStateConfigError:
+		if currentReason != "" {
+			return currentReason
+		}
+		return humiov1alpha1.HumioErrorReasonConfigError
+	}
+	return ""
+}
+
+// clusterAvailableCondition returns the ClusterAvailable condition for a resource whose last reconcile failed for the
+// given reason and with the given error. Reasons which say nothing about whether the Humio cluster can be reached, such
+// as an invalid spec, leave the condition as it is, which is reported by returning false.
+func clusterAvailableCondition(reason string, err error, generation int64) (metav1.Condition, bool) {
+	switch reason {
+	case humiov1alpha1.HumioErrorReasonClusterUnreachable:
+		message := "The Humio cluster cannot be reached"
+		if err != nil {
+			message = err.Error()
+		}
+		return metav1.Condition{
+			Type:               humiov1alpha1.HumioClusterAvailableConditionType,
+			Status:             metav1.ConditionFalse,
+			Reason:             humiov1alpha1.HumioClusterUnavailableReason,
+			Message:            message,
+			ObservedGeneration: generation,
+		}, true
+	case "", humiov1alpha1.HumioErrorReasonAuthFailed, humiov1alpha1.HumioErrorReasonInvalidQuery,
+		humiov1alpha1.HumioErrorReasonQuotaExceeded, humiov1alpha1.HumioErrorReasonReferencedActionMissing:
+		// The Humio API answered
+		return metav1.Condition{
+			Type:               humiov1alpha1.HumioClusterAvailableConditionType,
+			Status:             metav1.ConditionTrue,
+			Reason:             humiov1alpha1.HumioClusterAvailableReason,
+			Message:            "The Humio cluster can be reached",
+			ObservedGeneration: generation,
+		}, true
+	}
+	return metav1.Condition{}, false
+}
+
+// withClusterAvailableCondition returns the conditions found in the status of the given unstructured resource with
+// the given condition set, and whether the condition changed
+func withClusterAvailableCondition(content map[string]interface{}, condition metav1.Condition) ([]metav1.Condition, bool) {
+	var conditions []metav1.Condition
+	if raw, found, _ := unstructured.NestedSlice(content, "status", "conditions"); found {
+		data, _ := json.Marshal(raw)
+		_ = json.Unmarshal(data, &conditions)
+	}
+	current := meta.FindStatusCondition(conditions, condition.Type)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason &&
+		current.Message == condition.Message && current.ObservedGeneration == condition.ObservedGeneration {
+		return conditions, false
+	}
+	meta.SetStatusCondition(&conditions, condition)
+	return conditions, true
+}
+
+// withErrorReason records the reason the last reconcile of a resource failed in status.reason, and clears it once the
+// resource is reconciled successfully. Whether the Humio cluster of the resource can be reached is reported by the
+// ClusterAvailable condition. Prototype is an empty object of the reconciled type.
+func withErrorReason(c client.Client, prototype client.Object, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		result, err := r.Reconcile(ctx, req)
+		obj := prototype.DeepCopyObject().(client.Object)
+		if getErr := c.Get(ctx, req.NamespacedName, obj); getErr != nil {
+			return result, err
+		}
+		content, convertErr := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if convertErr != nil {
+			return result, err
+		}
+		state, _, _ := unstructured.NestedString(content, "status", "state")
+		currentReason, _, _ := unstructured.NestedString(content, "status", "reason")
+		reason := statusErrorReason(err, state, currentReason)
+		status := map[string]interface{}{}
+		if reason != currentReason {
+			var reasonValue interface{}
+			if reason != "" {
+				reasonValue = reason
+			}
+			status["reason"] = reasonValue
+		}
+		if condition, ok := clusterAvailableCondition(reason, err, obj.GetGeneration()); ok {
+			if conditions, changed := withClusterAvailableCondition(content, condition); changed {
+				status["conditions"] = conditions
+			}
+		}
+		if len(status) == 0 {
+			return result, err
+		}
+
+		patch, _ := json.Marshal(map[string]interface{}{"status": status})
+		if patchErr := c.Status().Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch)); patchErr != nil && err == nil {
+			return result, patchErr
+		}
+		return result, err
+	})
+}
+
+// humioEntityReconciler wraps the reconciler of a Humio entity with the behavior shared by all entity controllers:
+// it spreads out the first reconciles after the operator starts, yields to critical reconciles, honors the
+// force-delete and error-backoff annotations and reports why reconciles fail. The recorder may be nil.
+func humioEntityReconciler(c client.Client, recorder record.EventRecorder, prototype client.Object, r reconcile.Reconciler) reconcile.Reconciler {
+	return withStartupSpread(prototype, entityReconciler(withForceDelete(c, recorder, prototype, withErrorBackoff(c, prototype, withErrorReason(c, prototype, r)))))
+}
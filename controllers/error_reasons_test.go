@@ -0,0 +1,152 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestErrorReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"circuit breaker open", fmt.Errorf("could not get alert: %w", humio.ErrClusterUnavailable), humiov1alpha1.HumioErrorReasonClusterUnreachable},
+		{"external cluster unavailable", helpers.ErrExternalClusterUnavailable, humiov1alpha1.HumioErrorReasonClusterUnreachable},
+		{"missing action", fmt.Errorf("problem getting action for alert a: %w: b", humio.ErrActionNotFound), humiov1alpha1.HumioErrorReasonReferencedActionMissing},
+		{"unauthorized", errors.New("non-200 OK status code: 401 Unauthorized body: \"\""), humiov1alpha1.HumioErrorReasonAuthFailed},
+		{"missing permission", errors.New("user does not have the required permission"), humiov1alpha1.HumioErrorReasonAuthFailed},
+		{"rate limited", errors.New("non-200 OK status code: 429 Too Many Requests body: \"\""), humiov1alpha1.HumioErrorReasonQuotaExceeded},
+		{"invalid query", errors.New("could not parse query string"), humiov1alpha1.HumioErrorReasonInvalidQuery},
+		{"other", errors.New("something went wrong"), humiov1alpha1.HumioErrorReasonUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorReason(tt.err); got != tt.want {
+				t.Errorf("errorReason() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithErrorReason(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = humiov1alpha1.AddToScheme(scheme)
+	hp := &humiov1alpha1.HumioParser{ObjectMeta: metav1.ObjectMeta{Name: "parser", Namespace: "default"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hp).WithStatusSubresource(hp).Build()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "parser"}}
+
+	var reconcileErr error
+	var state string
+	r := withErrorReason(c, &humiov1alpha1.HumioParser{}, reconcile.Func(func(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+		current := &humiov1alpha1.HumioParser{}
+		if err := c.Get(ctx, req.NamespacedName, current); err != nil {
+			return ctrl.Result{}, err
+		}
+		current.Status.State = state
+		if err := c.Status().Update(ctx, current); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, reconcileErr
+	}))
+	reasonAfterReconcile := func() string {
+		_, _ = r.Reconcile(context.Background(), req)
+		current := &humiov1alpha1.HumioParser{}
+		if err := c.Get(context.Background(), req.NamespacedName, current); err != nil {
+			t.Fatalf("could not get parser: %s", err)
+		}
+		return current.Status.Reason
+	}
+
+	state, reconcileErr = humiov1alpha1.HumioParserStateUnknown, errors.New("non-200 OK status code: 403 Forbidden")
+	if got := reasonAfterReconcile(); got != humiov1alpha1.HumioErrorReasonAuthFailed {
+		t.Errorf("expected reason %s after failed reconcile, got %q", humiov1alpha1.HumioErrorReasonAuthFailed, got)
+	}
+	state, reconcileErr = humiov1alpha1.HumioParserStateClusterUnavailable, nil
+	if got := reasonAfterReconcile(); got != humiov1alpha1.HumioErrorReasonClusterUnreachable {
+		t.Errorf("expected reason %s for unavailable cluster, got %q", humiov1alpha1.HumioErrorReasonClusterUnreachable, got)
+	}
+	state = humiov1alpha1.HumioParserStateExists
+	if got := reasonAfterReconcile(); got != "" {
+		t.Errorf("expected reason to be cleared after successful reconcile, got %q", got)
+	}
+}
+
+func TestWithErrorReasonReportsClusterAvailableCondition(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = humiov1alpha1.AddToScheme(scheme)
+	hp := &humiov1alpha1.HumioParser{
+		ObjectMeta: metav1.ObjectMeta{Name: "parser", Namespace: "default"},
+		Status: humiov1alpha1.HumioParserStatus{Conditions: []metav1.Condition{{
+			Type:               humiov1alpha1.HumioDriftedConditionType,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Drifted",
+			LastTransitionTime: metav1.Now(),
+		}}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hp).WithStatusSubresource(hp).Build()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "parser"}}
+
+	var reconcileErr error
+	r := withErrorReason(c, &humiov1alpha1.HumioParser{}, reconcile.Func(func(context.Context, ctrl.Request) (ctrl.Result, error) {
+		return ctrl.Result{}, reconcileErr
+	}))
+	conditionsAfterReconcile := func() []metav1.Condition {
+		_, _ = r.Reconcile(context.Background(), req)
+		current := &humiov1alpha1.HumioParser{}
+		if err := c.Get(context.Background(), req.NamespacedName, current); err != nil {
+			t.Fatalf("could not get parser: %s", err)
+		}
+		return current.Status.Conditions
+	}
+
+	reconcileErr = fmt.Errorf("could not get parser: %w", humio.ErrClusterUnavailable)
+	conditions := conditionsAfterReconcile()
+	condition := meta.FindStatusCondition(conditions, humiov1alpha1.HumioClusterAvailableConditionType)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != humiov1alpha1.HumioClusterUnavailableReason {
+		t.Errorf("expected condition %s to be False with reason %s while the cluster is unavailable, got %+v", humiov1alpha1.HumioClusterAvailableConditionType, humiov1alpha1.HumioClusterUnavailableReason, condition)
+	}
+	if !meta.IsStatusConditionTrue(conditions, humiov1alpha1.HumioDriftedConditionType) {
+		t.Error("expected other conditions to be kept")
+	}
+
+	// Failures which say nothing about the cluster leave the condition as it is
+	reconcileErr = errors.New("something went wrong")
+	if !meta.IsStatusConditionFalse(conditionsAfterReconcile(), humiov1alpha1.HumioClusterAvailableConditionType) {
+		t.Error("expected condition to be left as it is after an unrelated failure")
+	}
+
+	reconcileErr = nil
+	if !meta.IsStatusConditionTrue(conditionsAfterReconcile(), humiov1alpha1.HumioClusterAvailableConditionType) {
+		t.Errorf("expected condition %s to be True once the cluster can be reached", humiov1alpha1.HumioClusterAvailableConditionType)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// forceDeleteAnnotation removes the finalizer of a resource which is being deleted without deleting it inside
+	// Humio when set to "true", e.g. because the Humio cluster it was created in has been decommissioned
+	forceDeleteAnnotation = "humio.com/force-delete"
+	// finalizerTimeoutAnnotation sets for how long to try deleting a resource inside Humio, e.g. "1h", before giving up
+	// and removing the finalizer anyway
+	finalizerTimeoutAnnotation = "humio.com/finalizer-timeout"
+)
+
+// forceDeleteReason returns why the finalizer of a resource which is being deleted should be removed without deleting
+// the resource inside Humio, and when to check again if that is not the case yet. A zero duration means the finalizer
+// is only removed once the resource is deleted inside Humio.
+func forceDeleteReason(obj metav1.Object, now time.Time) (string, time.Duration, bool) {
+	if obj.GetAnnotations()[forceDeleteAnnotation] == "true" {
+		return fmt.Sprintf("requested by annotation %s", forceDeleteAnnotation), 0, true
+	}
+	timeout, ok := annotationDuration(obj, finalizerTimeoutAnnotation)
+	if !ok || obj.GetDeletionTimestamp() == nil {
+		return "", 0, false
+	}
+	remaining := obj.GetDeletionTimestamp().Add(timeout).Sub(now)
+	if remaining <= 0 {
+		return fmt.Sprintf("finalizer timeout of %s set by annotation %s expired", timeout, finalizerTimeoutAnnotation), 0, true
+	}
+	return "", remaining, false
+}
+
+// withForceDelete removes the finalizer of resources which are being deleted without deleting them inside Humio when
+// the force-delete annotation is set or the finalizer timeout expires, which lets namespaces be cleaned up after the
+// Humio cluster is gone. Prototype is an empty object of the reconciled type.
+func withForceDelete(c client.Client, recorder record.EventRecorder, prototype client.Object, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		obj := prototype.DeepCopyObject().(client.Object)
+		if err := c.Get(ctx, req.NamespacedName, obj); err != nil ||
+			obj.GetDeletionTimestamp() == nil || !helpers.ContainsElement(obj.GetFinalizers(), humioFinalizer) {
+			return r.Reconcile(ctx, req)
+		}
+
+		reason, checkAfter, force := forceDeleteReason(obj, time.Now())
+		if !force {
+			result, err := r.Reconcile(ctx, req)
+			if err == nil && checkAfter > 0 && (result.RequeueAfter == 0 || result.RequeueAfter > checkAfter) {
+				result.RequeueAfter = checkAfter
+			}
+			return result, err
+		}
+
+		message := fmt.Sprintf("Removing finalizer without deleting the resource inside Humio: %s", reason)
+		log.FromContext(ctx).Info(message)
+		if recorder != nil {
+			recorder.Event(obj, corev1.EventTypeWarning, "ForceDeleted", message)
+		}
+		obj.SetFinalizers(helpers.RemoveElement(obj.GetFinalizers(), humioFinalizer))
+		return ctrl.Result{}, c.Update(ctx, obj)
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestForceDeleteReason(t *testing.T) {
+	now := time.Now()
+	deletedAt := metav1.NewTime(now.Add(-10 * time.Minute))
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		deletion       *metav1.Time
+		wantForce      bool
+		wantCheckAfter time.Duration
+	}{
+		{"no annotations", nil, &deletedAt, false, 0},
+		{"force delete", map[string]string{forceDeleteAnnotation: "true"}, &deletedAt, true, 0},
+		{"force delete disabled", map[string]string{forceDeleteAnnotation: "false"}, &deletedAt, false, 0},
+		{"timeout expired", map[string]string{finalizerTimeoutAnnotation: "5m"}, &deletedAt, true, 0},
+		{"timeout not expired", map[string]string{finalizerTimeoutAnnotation: "1h"}, &deletedAt, false, 50 * time.Minute},
+		{"timeout while not deleted", map[string]string{finalizerTimeoutAnnotation: "5m"}, nil, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hp := &humiov1alpha1.HumioParser{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations, DeletionTimestamp: tt.deletion}}
+			_, checkAfter, force := forceDeleteReason(hp, now)
+			if force != tt.wantForce || checkAfter != tt.wantCheckAfter {
+				t.Errorf("forceDeleteReason() = %s, %t, want %s, %t", checkAfter, force, tt.wantCheckAfter, tt.wantForce)
+			}
+		})
+	}
+}
+
+func TestWithForceDelete(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = humiov1alpha1.AddToScheme(scheme)
+	deletedAt := metav1.NewTime(time.Now().Add(-time.Minute))
+	forced := &humiov1alpha1.HumioRepository{ObjectMeta: metav1.ObjectMeta{
+		Name:              "forced",
+		Namespace:         "default",
+		Annotations:       map[string]string{forceDeleteAnnotation: "true"},
+		Finalizers:        []string{humioFinalizer},
+		DeletionTimestamp: &deletedAt,
+	}}
+	waiting := &humiov1alpha1.HumioRepository{ObjectMeta: metav1.ObjectMeta{
+		Name:              "waiting",
+		Namespace:         "default",
+		Annotations:       map[string]string{finalizerTimeoutAnnotation: "1h"},
+		Finalizers:        []string{humioFinalizer},
+		DeletionTimestamp: &deletedAt,
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(forced, waiting).Build()
+	recorder := record.NewFakeRecorder(1)
+
+	r := withForceDelete(c, recorder, &humiov1alpha1.HumioRepository{}, reconcile.Func(func(context.Context, ctrl.Request) (ctrl.Result, error) {
+		return ctrl.Result{RequeueAfter: 2 * time.Hour}, nil
+	}))
+
+	forcedKey := types.NamespacedName{Namespace: "default", Name: "forced"}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: forcedKey}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := c.Get(context.Background(), forcedKey, &humiov1alpha1.HumioRepository{}); !k8serrors.IsNotFound(err) {
+		t.Errorf("expected repository to be deleted once the finalizer is removed, got %v", err)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected a warning event for the skipped delete, got %d events", len(recorder.Events))
+	}
+
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "waiting"}})
+	if err != nil || result.RequeueAfter <= 0 || result.RequeueAfter > time.Hour {
+		t.Errorf("expected reconcile to be requeued when the finalizer timeout expires, got %+v and %v", result, err)
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-cmp/cmp"
@@ -28,9 +30,13 @@ import (
 	"github.com/humio/humio-operator/pkg/helpers"
 	"github.com/humio/humio-operator/pkg/humio"
 	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -41,11 +47,15 @@ type HumioActionReconciler struct {
 	Log         logr.Logger
 	HumioClient humio.Client
 	Namespace   string
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioactions,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioactions/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioactions/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioactiontemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (r *HumioActionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	if r.Namespace != "" {
@@ -73,6 +83,17 @@ func (r *HumioActionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	r.Log = r.Log.WithValues("Request.UID", ha.UID)
 
 	cluster, err := helpers.NewCluster(ctx, r, ha.Spec.ManagedClusterName, ha.Spec.ExternalClusterName, ha.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, ha.Namespace, ha.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		err = r.setState(ctx, humiov1alpha1.HumioActionStateClusterUnavailable, ha)
+		if err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set action state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
 	if err != nil || cluster == nil || cluster.Config() == nil {
 		r.Log.Error(err, "unable to obtain humio client config")
 		err = r.setState(ctx, humiov1alpha1.HumioActionStateConfigError, ha)
@@ -82,11 +103,25 @@ func (r *HumioActionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return reconcile.Result{}, err
 	}
 
+	if request, requested := resyncRequested(ha, ha.Status.LastResyncRequest); requested {
+		r.Log.Info(fmt.Sprintf("resyncing action as requested by annotation %s=%s", triggerResyncAnnotation, request))
+		r.HumioClient.InvalidateReadCache(cluster.Config())
+	}
+
 	err = r.resolveSecrets(ctx, ha)
 	if err != nil {
 		return reconcile.Result{}, r.logErrorAndReturn(err, "could not resolve secret references")
 	}
 
+	if err := r.resolveTemplate(ctx, ha); err != nil {
+		r.Log.Error(err, "unable to resolve action template")
+		err = r.setState(ctx, humiov1alpha1.HumioActionStateConfigError, ha)
+		if err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set action state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
 	if _, err := humio.ActionFromActionCR(ha); err != nil {
 		r.Log.Error(err, "unable to validate action")
 		err = r.setState(ctx, humiov1alpha1.HumioActionStateConfigError, ha)
@@ -95,9 +130,21 @@ func (r *HumioActionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 		return reconcile.Result{}, err
 	}
+	if _, err := humio.ActionFallbackRelayAlert(ha); err != nil {
+		r.Log.Error(err, "unable to validate action fallback")
+		err = r.setState(ctx, humiov1alpha1.HumioActionStateConfigError, ha)
+		if err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set action state")
+		}
+		return reconcile.Result{}, err
+	}
 
 	defer func(ctx context.Context, humioClient humio.Client, ha *humiov1alpha1.HumioAction) {
 		curAction, err := r.HumioClient.GetAction(cluster.Config(), req, ha)
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			_ = r.setState(ctx, humiov1alpha1.HumioActionStateClusterUnavailable, ha)
+			return
+		}
 		if errors.As(err, &humioapi.EntityNotFound{}) {
 			_ = r.setState(ctx, humiov1alpha1.HumioActionStateNotFound, ha)
 			return
@@ -123,13 +170,18 @@ func (r *HumioActionReconciler) reconcileHumioAction(ctx context.Context, config
 			// finalization logic fails, don't remove the finalizer so
 			// that we can retry during the next reconciliation.
 			r.Log.Info("Deleting Action")
+			if err := r.deleteFallbackRelay(ctx, config, req, ha); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "Delete fallback relay alert returned error")
+			}
 			if err := r.HumioClient.DeleteAction(config, req, ha); err != nil {
 				return reconcile.Result{}, r.logErrorAndReturn(err, "Delete Action returned error")
 			}
 
 			r.Log.Info("Action Deleted. Removing finalizer")
+			// Patch only the finalizers, as the spec holds resolved secrets and templates at this point
+			patch := client.MergeFrom(ha.DeepCopy())
 			ha.SetFinalizers(helpers.RemoveElement(ha.GetFinalizers(), humioFinalizer))
-			err := r.Update(ctx, ha)
+			err := r.Patch(ctx, ha, patch)
 			if err != nil {
 				return reconcile.Result{}, err
 			}
@@ -142,8 +194,9 @@ func (r *HumioActionReconciler) reconcileHumioAction(ctx context.Context, config
 	// Add finalizer for this CR
 	if !helpers.ContainsElement(ha.GetFinalizers(), humioFinalizer) {
 		r.Log.Info("Finalizer not present, adding finalizer to Action")
+		patch := client.MergeFrom(ha.DeepCopy())
 		ha.SetFinalizers(append(ha.GetFinalizers(), humioFinalizer))
-		err := r.Update(ctx, ha)
+		err := r.Patch(ctx, ha, patch)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
@@ -178,9 +231,24 @@ func (r *HumioActionReconciler) reconcileHumioAction(ctx context.Context, config
 	if err != nil {
 		return reconcile.Result{}, r.logErrorAndReturn(err, "could not parse expected action")
 	}
+	if curAction.Type != expectedAction.Type {
+		// Humio cannot change the type of an action, e.g. when routing options are added to an OpsGenie action, so
+		// the action is replaced
+		r.Log.Info(fmt.Sprintf("Action type changed from %s to %s, replacing action", curAction.Type, expectedAction.Type))
+		if err := r.HumioClient.DeleteAction(config, req, ha); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "could not delete action")
+		}
+		return reconcile.Result{Requeue: true}, nil
+	}
+	actionID := curAction.ID
 	sanitizeAction(curAction)
 	sanitizeAction(expectedAction)
-	if !cmp.Equal(*curAction, *expectedAction) {
+	desiredHash := desiredStateHash(expectedAction)
+	outcome := evaluateDrift(ha.Spec.DriftPolicy, ha.Status.AppliedHash, desiredHash, !cmp.Equal(*curAction, *expectedAction))
+	if outcome == driftIgnore {
+		r.Log.Info("Action was changed outside the operator, leaving the changes in place because of the drift policy")
+	}
+	if outcome.updates() {
 		r.Log.Info("Action differs, triggering update")
 		action, err := r.HumioClient.UpdateAction(config, req, ha)
 		if err != nil {
@@ -190,11 +258,100 @@ func (r *HumioActionReconciler) reconcileHumioAction(ctx context.Context, config
 			r.Log.Info(fmt.Sprintf("Updated action %q", ha.Spec.Name))
 		}
 	}
+	if err := r.setSyncStatus(ctx, outcome, desiredHash, actionID, time.Now(), ha); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set action sync status")
+	}
+
+	if err := r.reconcileFallbackRelay(ctx, config, req, ha); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not reconcile fallback relay alert")
+	}
 
-	r.Log.Info("done reconciling, will requeue after 15 seconds")
+	nextProbe, err := r.reconcileDeliveryVerification(ctx, ha, time.Now())
+	if err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not verify webhook delivery")
+	}
+	requeue := requeueInterval(ha, 0)
+	if nextProbe > 0 && (requeue == 0 || nextProbe < requeue) {
+		r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s to probe the webhook", nextProbe))
+		return reconcile.Result{RequeueAfter: nextProbe}, nil
+	}
+	if requeue > 0 {
+		r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+		return reconcile.Result{RequeueAfter: requeue}, nil
+	}
+	r.Log.Info("done reconciling")
 	return reconcile.Result{}, nil
 }
 
+// reconcileFallbackRelay creates or updates the relay alert triggering the fallback action, and deletes the relay
+// alert previously created for the action when the fallback is removed or moved to another view.
+func (r *HumioActionReconciler) reconcileFallbackRelay(ctx context.Context, config *humioapi.Config, req ctrl.Request, ha *humiov1alpha1.HumioAction) error {
+	relayAlert, err := humio.ActionFallbackRelayAlert(ha)
+	if err != nil {
+		return err
+	}
+	if current := ha.Status.FallbackRelay; current != nil {
+		if relayAlert == nil || current.ViewName != relayAlert.Spec.ViewName || current.AlertName != relayAlert.Spec.Name {
+			if err := r.deleteFallbackRelay(ctx, config, req, ha); err != nil {
+				return fmt.Errorf("could not delete previous fallback relay alert: %w", err)
+			}
+		}
+	}
+	if relayAlert == nil {
+		return nil
+	}
+
+	curAlert, err := r.HumioClient.GetAlert(config, req, relayAlert)
+	if errors.As(err, &humioapi.EntityNotFound{}) {
+		r.Log.Info("Fallback relay alert doesn't exist. Now adding alert", "Alert", relayAlert.Spec.Name, "View", relayAlert.Spec.ViewName)
+		if _, err := r.HumioClient.AddAlert(config, req, relayAlert); err != nil {
+			return fmt.Errorf("could not create fallback relay alert: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("could not check if fallback relay alert exists: %w", err)
+	} else {
+		actionIdMap, err := r.HumioClient.GetActionIDsMapForAlerts(config, req, relayAlert)
+		if err != nil {
+			return fmt.Errorf("could not get action id mapping: %w", err)
+		}
+		expectedAlert, err := humio.AlertTransform(relayAlert, actionIdMap)
+		if err != nil {
+			return fmt.Errorf("could not parse expected fallback relay alert: %w", err)
+		}
+		sanitizeAlert(curAlert)
+		if !reflect.DeepEqual(*curAlert, *expectedAlert) {
+			r.Log.Info("Fallback relay alert differs, triggering update", "Alert", relayAlert.Spec.Name)
+			if _, err := r.HumioClient.UpdateAlert(config, req, relayAlert); err != nil {
+				return fmt.Errorf("could not update fallback relay alert: %w", err)
+			}
+		}
+	}
+
+	return r.setFallbackRelay(ctx, &humiov1alpha1.HumioActionFallbackRelayStatus{
+		ViewName:  relayAlert.Spec.ViewName,
+		AlertName: relayAlert.Spec.Name,
+	}, ha)
+}
+
+// deleteFallbackRelay deletes the relay alert recorded in the status of the action, if any
+func (r *HumioActionReconciler) deleteFallbackRelay(ctx context.Context, config *humioapi.Config, req ctrl.Request, ha *humiov1alpha1.HumioAction) error {
+	current := ha.Status.FallbackRelay
+	if current == nil {
+		return nil
+	}
+	r.Log.Info("Deleting fallback relay alert", "Alert", current.AlertName, "View", current.ViewName)
+	relayAlert := &humiov1alpha1.HumioAlert{
+		Spec: humiov1alpha1.HumioAlertSpec{
+			Name:     current.AlertName,
+			ViewName: current.ViewName,
+		},
+	}
+	if err := r.HumioClient.DeleteAlert(config, req, relayAlert); err != nil && !errors.As(err, &humioapi.EntityNotFound{}) {
+		return err
+	}
+	return r.setFallbackRelay(ctx, nil, ha)
+}
+
 func (r *HumioActionReconciler) resolveSecrets(ctx context.Context, ha *humiov1alpha1.HumioAction) error {
 	var err error
 
@@ -212,6 +369,17 @@ func (r *HumioActionReconciler) resolveSecrets(ctx context.Context, ha *humiov1a
 		}
 	}
 
+	if ha.Spec.VictorOpsProperties != nil {
+		ha.Spec.VictorOpsProperties.NotifyUrl, err = r.resolveField(ctx, ha.Namespace, ha.Spec.VictorOpsProperties.NotifyUrl, ha.Spec.VictorOpsProperties.NotifyUrlSource)
+		if err != nil {
+			return fmt.Errorf("victorOpsProperties.notifyUrlSource.%v", err)
+		}
+		ha.Spec.VictorOpsProperties.RoutingKey, err = r.resolveField(ctx, ha.Namespace, ha.Spec.VictorOpsProperties.RoutingKey, ha.Spec.VictorOpsProperties.RoutingKeySource)
+		if err != nil {
+			return fmt.Errorf("victorOpsProperties.routingKeySource.%v", err)
+		}
+	}
+
 	if ha.Spec.HumioRepositoryProperties != nil {
 		ha.Spec.HumioRepositoryProperties.IngestToken, err = r.resolveField(ctx, ha.Namespace, ha.Spec.HumioRepositoryProperties.IngestToken, ha.Spec.HumioRepositoryProperties.IngestTokenSource)
 		if err != nil {
@@ -245,11 +413,60 @@ func (r *HumioActionReconciler) resolveField(ctx context.Context, namespace, val
 	return "", nil
 }
 
+// humioActionSecretNames returns the names of the secrets the properties of the action are read from
+func humioActionSecretNames(ha *humiov1alpha1.HumioAction) []string {
+	var sources []humiov1alpha1.VarSource
+	if ha.Spec.SlackPostMessageProperties != nil {
+		sources = append(sources, ha.Spec.SlackPostMessageProperties.ApiTokenSource)
+	}
+	if ha.Spec.OpsGenieProperties != nil {
+		sources = append(sources, ha.Spec.OpsGenieProperties.GenieKeySource)
+	}
+	if ha.Spec.VictorOpsProperties != nil {
+		sources = append(sources, ha.Spec.VictorOpsProperties.NotifyUrlSource, ha.Spec.VictorOpsProperties.RoutingKeySource)
+	}
+	if ha.Spec.HumioRepositoryProperties != nil {
+		sources = append(sources, ha.Spec.HumioRepositoryProperties.IngestTokenSource)
+	}
+	if ha.Spec.WebhookProperties != nil && ha.Spec.WebhookProperties.DeliveryVerification != nil {
+		sources = append(sources, ha.Spec.WebhookProperties.DeliveryVerification.SigningKeySource)
+	}
+	var secretNames []string
+	for _, source := range sources {
+		if source.SecretKeyRef != nil {
+			secretNames = append(secretNames, source.SecretKeyRef.Name)
+		}
+	}
+	return secretNames
+}
+
+// actionsForSecret returns a reconcile request for every HumioAction reading properties from the given secret, so
+// rotated credentials are applied to the actions
+func (r *HumioActionReconciler) actionsForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	var humioActions humiov1alpha1.HumioActionList
+	if err := r.List(ctx, &humioActions, client.InNamespace(secret.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list actions")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, ha := range humioActions.Items {
+		if helpers.ContainsElement(humioActionSecretNames(&ha), secret.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: ha.Namespace, Name: ha.Name},
+			})
+		}
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *HumioActionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&humiov1alpha1.HumioAction{}).
-		Complete(r)
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioActionList{}))).
+		Watches(&humiov1alpha1.HumioActionTemplate{}, handler.EnqueueRequestsFromMapFunc(r.actionsForTemplate)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.actionsForSecret)).
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioAction{}, r))
 }
 
 func (r *HumioActionReconciler) setState(ctx context.Context, state string, hr *humiov1alpha1.HumioAction) error {
@@ -261,6 +478,28 @@ func (r *HumioActionReconciler) setState(ctx context.Context, state string, hr *
 	return r.Status().Update(ctx, hr)
 }
 
+func (r *HumioActionReconciler) setFallbackRelay(ctx context.Context, relay *humiov1alpha1.HumioActionFallbackRelayStatus, ha *humiov1alpha1.HumioAction) error {
+	if reflect.DeepEqual(ha.Status.FallbackRelay, relay) {
+		return nil
+	}
+	ha.Status.FallbackRelay = relay
+	return r.Status().Update(ctx, ha)
+}
+
+func (r *HumioActionReconciler) setSyncStatus(ctx context.Context, outcome driftOutcome, desiredHash, id string, now time.Time, ha *humiov1alpha1.HumioAction) error {
+	status := ha.Status.DeepCopy()
+	applyDriftOutcome(r.Recorder, ha, outcome, desiredHash, &status.AppliedHash, &status.Conditions, ha.Generation)
+	status.ID = id
+	status.ClusterName = syncClusterName(ha.Spec.ManagedClusterName, ha.Spec.ExternalClusterName)
+	status.LastSyncTime = syncTime(status.LastSyncTime, now)
+	status.LastResyncRequest = ha.Annotations[triggerResyncAnnotation]
+	if reflect.DeepEqual(ha.Status, *status) {
+		return nil
+	}
+	ha.Status = *status
+	return r.Status().Update(ctx, ha)
+}
+
 func (r *HumioActionReconciler) logErrorAndReturn(err error, msg string) error {
 	r.Log.Error(err, msg)
 	return fmt.Errorf("%s: %w", msg, err)
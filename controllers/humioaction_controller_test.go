@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileFallbackRelay(t *testing.T) {
+	ha := &humiov1alpha1.HumioAction{
+		ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "default"},
+		Spec: humiov1alpha1.HumioActionSpec{
+			Name:     "slack",
+			ViewName: "web",
+			Fallback: &humiov1alpha1.HumioActionFallback{ActionName: "pagerduty"},
+		},
+	}
+	scheme := runtime.NewScheme()
+	_ = humiov1alpha1.AddToScheme(scheme)
+	humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+	r := &HumioActionReconciler{
+		Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(ha).WithStatusSubresource(ha).Build(),
+		HumioClient: humioClient,
+		Log:         logr.Discard(),
+	}
+	ctx := context.Background()
+	config := &humioapi.Config{}
+	req := reconcile.Request{}
+
+	if err := r.reconcileFallbackRelay(ctx, config, req, ha); err != nil {
+		t.Fatal(err)
+	}
+	relayAlert, _ := humio.ActionFallbackRelayAlert(ha)
+	alert, err := humioClient.GetAlert(config, req, relayAlert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alert.Name != "fallback-web-slack" || len(alert.Actions) != 1 {
+		t.Errorf("expected relay alert triggering the fallback action, got %+v", alert)
+	}
+	if ha.Status.FallbackRelay == nil || ha.Status.FallbackRelay.ViewName != "humio-activity" || ha.Status.FallbackRelay.AlertName != "fallback-web-slack" {
+		t.Errorf("expected relay alert to be recorded in status, got %+v", ha.Status.FallbackRelay)
+	}
+
+	// Removing the fallback deletes the relay alert
+	ha.Spec.Fallback = nil
+	if err := r.reconcileFallbackRelay(ctx, config, req, ha); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := humioClient.GetAlert(config, req, relayAlert); err == nil {
+		t.Errorf("expected relay alert to be deleted")
+	}
+	if ha.Status.FallbackRelay != nil {
+		t.Errorf("expected relay alert to be removed from status, got %+v", ha.Status.FallbackRelay)
+	}
+}
+
+func TestResolveVictorOpsSecrets(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "victorops", Namespace: "default"},
+		Data: map[string][]byte{
+			"notifyUrl":  []byte("https://alert.victorops.com/integrations/generic/20131114/alert/api-key/"),
+			"routingKey": []byte("database"),
+		},
+	}
+	ha := &humiov1alpha1.HumioAction{
+		ObjectMeta: metav1.ObjectMeta{Name: "victorops", Namespace: "default"},
+		Spec: humiov1alpha1.HumioActionSpec{
+			Name:     "victorops",
+			ViewName: "web",
+			VictorOpsProperties: &humiov1alpha1.HumioActionVictorOpsProperties{
+				MessageType: "critical",
+				NotifyUrlSource: humiov1alpha1.VarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "victorops"}, Key: "notifyUrl"},
+				},
+				RoutingKeySource: humiov1alpha1.VarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "victorops"}, Key: "routingKey"},
+				},
+			},
+		},
+	}
+	other := &humiov1alpha1.HumioAction{
+		ObjectMeta: metav1.ObjectMeta{Name: "email", Namespace: "default"},
+		Spec: humiov1alpha1.HumioActionSpec{
+			Name:            "email",
+			ViewName:        "web",
+			EmailProperties: &humiov1alpha1.HumioActionEmailProperties{Recipients: []string{"ops@example.com"}},
+		},
+	}
+	scheme := runtime.NewScheme()
+	_ = humiov1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	r := &HumioActionReconciler{
+		Client:     fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, ha, other).Build(),
+		BaseLogger: logr.Discard(),
+		Log:        logr.Discard(),
+	}
+
+	requests := r.actionsForSecret(context.Background(), secret)
+	if len(requests) != 1 || requests[0].Name != "victorops" {
+		t.Errorf("expected the secret to only enqueue the action reading from it, got %v", requests)
+	}
+
+	resolved := ha.DeepCopy()
+	if err := r.resolveSecrets(context.Background(), resolved); err != nil {
+		t.Fatal(err)
+	}
+	action, err := humio.ActionFromActionCR(resolved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "https://alert.victorops.com/integrations/generic/20131114/alert/api-key/database"; action.VictorOpsAction.NotifyUrl != expected {
+		t.Errorf("expected notify url %q, got %q", expected, action.VictorOpsAction.NotifyUrl)
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// webhookProbeHeader tells probes sent to verify webhooks apart from notifications
+	webhookProbeHeader = "X-Humio-Operator-Probe"
+	// webhookProbeSignatureHeader holds the signature of the probe body
+	webhookProbeSignatureHeader = "X-Humio-Operator-Signature"
+
+	webhookProbeDefaultIntervalSeconds = 300
+	webhookProbeDefaultRetries         = 2
+	webhookProbeDefaultBackoffSeconds  = 1
+)
+
+var (
+	// webhookProbeClient is used to send probes to webhooks
+	webhookProbeClient = &http.Client{Timeout: 10 * time.Second}
+	// webhookProbeInsecureClient is used to send probes to webhooks which ignore SSL errors
+	webhookProbeInsecureClient = &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}, // #nosec G402
+	}
+)
+
+// webhookProbe is the body of a probe sent to a webhook
+type webhookProbe struct {
+	Probe     bool   `json:"probe"`
+	Action    string `json:"action"`
+	ViewName  string `json:"viewName"`
+	Timestamp string `json:"timestamp"`
+}
+
+// reconcileDeliveryVerification sends a probe to the webhook of the action when the last probe is older than the
+// interval, and records the outcome in the status. It returns how long until the next probe is due, or zero if the
+// delivery is not verified.
+func (r *HumioActionReconciler) reconcileDeliveryVerification(ctx context.Context, ha *humiov1alpha1.HumioAction, now time.Time) (time.Duration, error) {
+	if ha.Spec.WebhookProperties == nil || ha.Spec.WebhookProperties.DeliveryVerification == nil {
+		return 0, r.setDeliveryVerification(ctx, nil, ha)
+	}
+	verification := ha.Spec.WebhookProperties.DeliveryVerification
+	interval := time.Duration(webhookProbeDefaultIntervalSeconds) * time.Second
+	if verification.IntervalSeconds > 0 {
+		interval = time.Duration(verification.IntervalSeconds) * time.Second
+	}
+	if current := ha.Status.DeliveryVerification; current != nil && current.LastProbeTime != nil {
+		if next := current.LastProbeTime.Add(interval); next.After(now) {
+			return next.Sub(now), nil
+		}
+	}
+
+	signingKey, err := r.resolveField(ctx, ha.Namespace, "", verification.SigningKeySource)
+	if err != nil {
+		return 0, fmt.Errorf("webhookProperties.deliveryVerification.signingKeySource.%v", err)
+	}
+	status := verifyWebhookDelivery(ctx, ha, signingKey, now)
+	if !status.Reachable {
+		r.Log.Info(fmt.Sprintf("webhook of action is unreachable: %s", status.Message))
+	}
+	return interval, r.setDeliveryVerification(ctx, status, ha)
+}
+
+// verifyWebhookDelivery sends a probe to the webhook of the action, retrying failed probes with an exponential
+// backoff, and returns the outcome of the last probe
+func verifyWebhookDelivery(ctx context.Context, ha *humiov1alpha1.HumioAction, signingKey string, now time.Time) *humiov1alpha1.HumioActionDeliveryVerificationStatus {
+	verification := ha.Spec.WebhookProperties.DeliveryVerification
+	retries := webhookProbeDefaultRetries
+	if verification.Retries != nil {
+		retries = *verification.Retries
+	}
+	backoff := time.Duration(webhookProbeDefaultBackoffSeconds) * time.Second
+	if verification.BackoffSeconds > 0 {
+		backoff = time.Duration(verification.BackoffSeconds) * time.Second
+	}
+
+	probeTime := metav1.NewTime(now)
+	status := &humiov1alpha1.HumioActionDeliveryVerificationStatus{LastProbeTime: &probeTime}
+	for attempt := 0; ; attempt++ {
+		statusCode, err := probeWebhook(ctx, ha, signingKey, now)
+		status.StatusCode = statusCode
+		switch {
+		case err != nil:
+			status.Message = err.Error()
+		case statusCode < http.StatusOK || statusCode >= http.StatusMultipleChoices:
+			status.Message = fmt.Sprintf("webhook responded with status %d", statusCode)
+		default:
+			status.Reachable = true
+			status.Message = ""
+			return status
+		}
+		if attempt >= retries {
+			return status
+		}
+		select {
+		case <-ctx.Done():
+			return status
+		case <-time.After(backoff << attempt):
+		}
+	}
+}
+
+// probeWebhook sends a single probe to the webhook of the action, and returns the status code of the response
+func probeWebhook(ctx context.Context, ha *humiov1alpha1.HumioAction, signingKey string, now time.Time) (int, error) {
+	webhook := ha.Spec.WebhookProperties
+	body, err := json.Marshal(webhookProbe{
+		Probe:     true,
+		Action:    ha.Spec.Name,
+		ViewName:  ha.Spec.ViewName,
+		Timestamp: now.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, webhook.Method, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for name, value := range webhook.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookProbeHeader, "true")
+	if signingKey != "" {
+		req.Header.Set(webhookProbeSignatureHeader, "sha256="+webhookProbeSignature(signingKey, body))
+	}
+
+	httpClient := webhookProbeClient
+	if webhook.IgnoreSSL {
+		httpClient = webhookProbeInsecureClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// webhookProbeSignature returns the hex encoded HMAC-SHA256 of the probe body
+func webhookProbeSignature(signingKey string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (r *HumioActionReconciler) setDeliveryVerification(ctx context.Context, status *humiov1alpha1.HumioActionDeliveryVerificationStatus, ha *humiov1alpha1.HumioAction) error {
+	if reflect.DeepEqual(ha.Status.DeliveryVerification, status) {
+		return nil
+	}
+	ha.Status.DeliveryVerification = status
+	return r.Status().Update(ctx, ha)
+}
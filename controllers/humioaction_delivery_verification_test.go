@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileDeliveryVerification(t *testing.T) {
+	var probes int
+	var failures int
+	var signatureValid bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		body, _ := io.ReadAll(r.Body)
+		signatureValid = r.Header.Get(webhookProbeHeader) == "true" &&
+			r.Header.Get("Authorization") == "Bearer token" &&
+			r.Header.Get(webhookProbeSignatureHeader) == "sha256="+webhookProbeSignature("signing-key", body)
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "default"},
+		Data:       map[string][]byte{"signingKey": []byte("signing-key")},
+	}
+	ha := &humiov1alpha1.HumioAction{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "default"},
+		Spec: humiov1alpha1.HumioActionSpec{
+			Name:     "webhook",
+			ViewName: "web",
+			WebhookProperties: &humiov1alpha1.HumioActionWebhookProperties{
+				Url:          server.URL,
+				Method:       http.MethodPost,
+				Headers:      map[string]string{"Authorization": "Bearer token"},
+				BodyTemplate: "{alert_name}",
+				DeliveryVerification: &humiov1alpha1.HumioActionWebhookDeliveryVerification{
+					IntervalSeconds: 60,
+					SigningKeySource: humiov1alpha1.VarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "webhook"}, Key: "signingKey"},
+					},
+					Retries: helpers.IntPtr(1),
+				},
+			},
+		},
+	}
+	scheme := runtime.NewScheme()
+	_ = humiov1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	r := &HumioActionReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, ha).WithStatusSubresource(ha).Build(),
+		Log:    logr.Discard(),
+	}
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	// A failed probe is retried
+	failures = 1
+	next, err := r.reconcileDeliveryVerification(ctx, ha, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := ha.Status.DeliveryVerification
+	if probes != 2 || status == nil || !status.Reachable || status.StatusCode != http.StatusAccepted || next != time.Minute {
+		t.Errorf("expected the webhook to be reachable after a retry, got %d probes and status %+v", probes, status)
+	}
+	if !signatureValid {
+		t.Errorf("expected the probe to be signed and carry the headers of the webhook")
+	}
+
+	// No probe is sent before the interval has passed
+	next, err = r.reconcileDeliveryVerification(ctx, ha, now.Add(20*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probes != 2 || next != 40*time.Second {
+		t.Errorf("expected the next probe in 40s, got %d probes and next probe in %s", probes, next)
+	}
+
+	// The webhook is unreachable when all retries fail
+	failures = 2
+	if _, err := r.reconcileDeliveryVerification(ctx, ha, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	status = ha.Status.DeliveryVerification
+	if probes != 4 || status.Reachable || status.StatusCode != http.StatusServiceUnavailable || status.Message == "" {
+		t.Errorf("expected the webhook to be unreachable, got %d probes and status %+v", probes, status)
+	}
+
+	// Disabling the verification clears the status
+	ha.Spec.WebhookProperties.DeliveryVerification = nil
+	next, err = r.reconcileDeliveryVerification(ctx, ha, now.Add(2*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != 0 || ha.Status.DeliveryVerification != nil {
+		t.Errorf("expected the verification status to be cleared, got %+v", ha.Status.DeliveryVerification)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// resolveTemplate inlines the HumioActionTemplate referenced by the action into the properties of the action
+func (r *HumioActionReconciler) resolveTemplate(ctx context.Context, ha *humiov1alpha1.HumioAction) error {
+	ref := ha.Spec.TemplateRef
+	if ref == nil {
+		return nil
+	}
+	hat := &humiov1alpha1.HumioActionTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: ha.Namespace, Name: ref.Name}, hat); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("templateRef refers to HumioActionTemplate %s which does not exist in namespace %s", ref.Name, ha.Namespace)
+		}
+		return fmt.Errorf("unable to get HumioActionTemplate %s: %w", ref.Name, err)
+	}
+	rendered, err := renderHumioActionTemplate(hat.Spec, ref.Parameters)
+	if err != nil {
+		return fmt.Errorf("unable to render HumioActionTemplate %s: %w", ref.Name, err)
+	}
+	inlineHumioActionTemplate(ha, rendered)
+	return nil
+}
+
+// renderHumioActionTemplate substitutes the parameters into each of the templates. Parameters referenced by the
+// templates must be set.
+func renderHumioActionTemplate(spec humiov1alpha1.HumioActionTemplateSpec, parameters map[string]string) (humiov1alpha1.HumioActionTemplateSpec, error) {
+	if parameters == nil {
+		parameters = map[string]string{}
+	}
+	render := func(name, text string) (string, error) {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("invalid template %s: %w", name, err)
+		}
+		var rendered bytes.Buffer
+		if err := tmpl.Execute(&rendered, parameters); err != nil {
+			return "", fmt.Errorf("invalid template %s: %w", name, err)
+		}
+		return rendered.String(), nil
+	}
+	renderMap := func(name string, m map[string]string) (map[string]string, error) {
+		if m == nil {
+			return nil, nil
+		}
+		rendered := make(map[string]string, len(m))
+		for k, v := range m {
+			value, err := render(fmt.Sprintf("%s.%s", name, k), v)
+			if err != nil {
+				return nil, err
+			}
+			rendered[k] = value
+		}
+		return rendered, nil
+	}
+
+	var rendered humiov1alpha1.HumioActionTemplateSpec
+	var err error
+	if rendered.BodyTemplate, err = render("bodyTemplate", spec.BodyTemplate); err != nil {
+		return rendered, err
+	}
+	if rendered.SubjectTemplate, err = render("subjectTemplate", spec.SubjectTemplate); err != nil {
+		return rendered, err
+	}
+	if rendered.Fields, err = renderMap("fields", spec.Fields); err != nil {
+		return rendered, err
+	}
+	if rendered.Headers, err = renderMap("headers", spec.Headers); err != nil {
+		return rendered, err
+	}
+	return rendered, nil
+}
+
+// inlineHumioActionTemplate copies the rendered templates into the properties of the action which support them.
+// Values set in the properties of the action take precedence over the template.
+func inlineHumioActionTemplate(ha *humiov1alpha1.HumioAction, rendered humiov1alpha1.HumioActionTemplateSpec) {
+	if p := ha.Spec.EmailProperties; p != nil {
+		if p.BodyTemplate == "" {
+			p.BodyTemplate = rendered.BodyTemplate
+		}
+		if p.SubjectTemplate == "" {
+			p.SubjectTemplate = rendered.SubjectTemplate
+		}
+	}
+	if p := ha.Spec.WebhookProperties; p != nil {
+		if p.BodyTemplate == "" {
+			p.BodyTemplate = rendered.BodyTemplate
+		}
+		p.Headers = mergeActionTemplateValues(rendered.Headers, p.Headers)
+	}
+	if p := ha.Spec.SlackProperties; p != nil {
+		p.Fields = mergeActionTemplateValues(rendered.Fields, p.Fields)
+	}
+	if p := ha.Spec.SlackPostMessageProperties; p != nil {
+		p.Fields = mergeActionTemplateValues(rendered.Fields, p.Fields)
+	}
+}
+
+// mergeActionTemplateValues returns the values of the template overridden by the values of the action
+func mergeActionTemplateValues(template, action map[string]string) map[string]string {
+	if len(template) == 0 {
+		return action
+	}
+	merged := make(map[string]string, len(template)+len(action))
+	for k, v := range template {
+		merged[k] = v
+	}
+	for k, v := range action {
+		merged[k] = v
+	}
+	return merged
+}
+
+// actionsForTemplate returns a reconcile request for every HumioAction referencing the given HumioActionTemplate, so
+// changes to the template are applied to the actions right away
+func (r *HumioActionReconciler) actionsForTemplate(ctx context.Context, hat client.Object) []reconcile.Request {
+	var humioActions humiov1alpha1.HumioActionList
+	if err := r.List(ctx, &humioActions, client.InNamespace(hat.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list actions")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, ha := range humioActions.Items {
+		if ha.Spec.TemplateRef != nil && ha.Spec.TemplateRef.Name == hat.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: ha.Namespace, Name: ha.Name},
+			})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestResolveActionTemplate(t *testing.T) {
+	hat := &humiov1alpha1.HumioActionTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "incident", Namespace: "default"},
+		Spec: humiov1alpha1.HumioActionTemplateSpec{
+			BodyTemplate:    `{"alert": "{alert_name}", "team": "{{ .team }}"}`,
+			SubjectTemplate: "[{{ .team }}] {alert_name}",
+			Fields:          map[string]string{"team": "{{ .team }}", "query": "{query}"},
+			Headers:         map[string]string{"X-Team": "{{ .team }}"},
+		},
+	}
+	tests := []struct {
+		name     string
+		spec     humiov1alpha1.HumioActionSpec
+		expected humiov1alpha1.HumioActionSpec
+		wantErr  bool
+	}{
+		{
+			name: "no template",
+			spec: humiov1alpha1.HumioActionSpec{
+				SlackProperties: &humiov1alpha1.HumioActionSlackProperties{Fields: map[string]string{"query": "{query}"}},
+			},
+			expected: humiov1alpha1.HumioActionSpec{
+				SlackProperties: &humiov1alpha1.HumioActionSlackProperties{Fields: map[string]string{"query": "{query}"}},
+			},
+		},
+		{
+			name: "webhook",
+			spec: humiov1alpha1.HumioActionSpec{
+				TemplateRef:       &humiov1alpha1.HumioActionTemplateReference{Name: "incident", Parameters: map[string]string{"team": "ops"}},
+				WebhookProperties: &humiov1alpha1.HumioActionWebhookProperties{Url: "https://example.com", Headers: map[string]string{"Content-Type": "application/json"}},
+			},
+			expected: humiov1alpha1.HumioActionSpec{
+				TemplateRef: &humiov1alpha1.HumioActionTemplateReference{Name: "incident", Parameters: map[string]string{"team": "ops"}},
+				WebhookProperties: &humiov1alpha1.HumioActionWebhookProperties{
+					Url:          "https://example.com",
+					BodyTemplate: `{"alert": "{alert_name}", "team": "ops"}`,
+					Headers:      map[string]string{"Content-Type": "application/json", "X-Team": "ops"},
+				},
+			},
+		},
+		{
+			name: "action values take precedence",
+			spec: humiov1alpha1.HumioActionSpec{
+				TemplateRef:     &humiov1alpha1.HumioActionTemplateReference{Name: "incident", Parameters: map[string]string{"team": "ops"}},
+				EmailProperties: &humiov1alpha1.HumioActionEmailProperties{SubjectTemplate: "custom"},
+				SlackProperties: &humiov1alpha1.HumioActionSlackProperties{Fields: map[string]string{"team": "sre"}},
+			},
+			expected: humiov1alpha1.HumioActionSpec{
+				TemplateRef:     &humiov1alpha1.HumioActionTemplateReference{Name: "incident", Parameters: map[string]string{"team": "ops"}},
+				EmailProperties: &humiov1alpha1.HumioActionEmailProperties{SubjectTemplate: "custom", BodyTemplate: `{"alert": "{alert_name}", "team": "ops"}`},
+				SlackProperties: &humiov1alpha1.HumioActionSlackProperties{Fields: map[string]string{"team": "sre", "query": "{query}"}},
+			},
+		},
+		{
+			name: "missing parameter",
+			spec: humiov1alpha1.HumioActionSpec{
+				TemplateRef:     &humiov1alpha1.HumioActionTemplateReference{Name: "incident"},
+				SlackProperties: &humiov1alpha1.HumioActionSlackProperties{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing template",
+			spec: humiov1alpha1.HumioActionSpec{
+				TemplateRef:     &humiov1alpha1.HumioActionTemplateReference{Name: "missing"},
+				SlackProperties: &humiov1alpha1.HumioActionSlackProperties{},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = humiov1alpha1.AddToScheme(scheme)
+			r := &HumioActionReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(hat).Build(),
+				Log:    logr.Discard(),
+			}
+			ha := &humiov1alpha1.HumioAction{
+				ObjectMeta: metav1.ObjectMeta{Name: "action", Namespace: "default"},
+				Spec:       tt.spec,
+			}
+			err := r.resolveTemplate(context.Background(), ha)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(ha.Spec, tt.expected) {
+				t.Errorf("expected spec %+v, got %+v", tt.expected, ha.Spec)
+			}
+		})
+	}
+}
+
+func TestActionsForTemplate(t *testing.T) {
+	referencing := &humiov1alpha1.HumioAction{
+		ObjectMeta: metav1.ObjectMeta{Name: "referencing", Namespace: "default"},
+		Spec:       humiov1alpha1.HumioActionSpec{TemplateRef: &humiov1alpha1.HumioActionTemplateReference{Name: "incident"}},
+	}
+	other := &humiov1alpha1.HumioAction{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+		Spec:       humiov1alpha1.HumioActionSpec{TemplateRef: &humiov1alpha1.HumioActionTemplateReference{Name: "other"}},
+	}
+	otherNamespace := &humiov1alpha1.HumioAction{
+		ObjectMeta: metav1.ObjectMeta{Name: "referencing", Namespace: "other"},
+		Spec:       humiov1alpha1.HumioActionSpec{TemplateRef: &humiov1alpha1.HumioActionTemplateReference{Name: "incident"}},
+	}
+	scheme := runtime.NewScheme()
+	_ = humiov1alpha1.AddToScheme(scheme)
+	r := &HumioActionReconciler{
+		Client:     fake.NewClientBuilder().WithScheme(scheme).WithObjects(referencing, other, otherNamespace).Build(),
+		BaseLogger: logr.Discard(),
+	}
+
+	requests := r.actionsForTemplate(context.Background(), &humiov1alpha1.HumioActionTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "incident", Namespace: "default"},
+	})
+	expected := []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "default", Name: "referencing"}}}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+}
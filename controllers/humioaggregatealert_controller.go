@@ -0,0 +1,264 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// HumioAggregateAlertReconciler reconciles a HumioAggregateAlert object
+type HumioAggregateAlertReconciler struct {
+	client.Client
+	BaseLogger  logr.Logger
+	Log         logr.Logger
+	HumioClient humio.Client
+	Namespace   string
+	Recorder    record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioaggregatealerts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioaggregatealerts/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioaggregatealerts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+func (r *HumioAggregateAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioAggregateAlert")
+
+	haa := &humiov1alpha1.HumioAggregateAlert{}
+	if err := r.Get(ctx, req.NamespacedName, haa); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", haa.UID)
+
+	cluster, err := helpers.NewCluster(ctx, r, haa.Spec.ManagedClusterName, haa.Spec.ExternalClusterName, haa.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, haa.Namespace, haa.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		if err := r.setState(ctx, humiov1alpha1.HumioAggregateAlertStateClusterUnavailable, haa); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set aggregate alert state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		r.Log.Error(err, "unable to obtain humio client config")
+		if err := r.setState(ctx, humiov1alpha1.HumioAggregateAlertStateConfigError, haa); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set aggregate alert state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	if request, requested := resyncRequested(haa, haa.Status.LastResyncRequest); requested {
+		r.Log.Info(fmt.Sprintf("resyncing aggregate alert as requested by annotation %s=%s", triggerResyncAnnotation, request))
+		r.HumioClient.InvalidateReadCache(cluster.Config())
+	}
+
+	if haa.GetDeletionTimestamp() != nil {
+		r.Log.Info("Aggregate alert marked to be deleted")
+		if helpers.ContainsElement(haa.GetFinalizers(), humioFinalizer) {
+			r.Log.Info("Deleting aggregate alert")
+			if err := r.HumioClient.DeleteAggregateAlert(cluster.Config(), req, haa); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "Delete aggregate alert returned error")
+			}
+			r.Log.Info("Aggregate alert deleted. Removing finalizer")
+			haa.SetFinalizers(helpers.RemoveElement(haa.GetFinalizers(), humioFinalizer))
+			if err := r.Update(ctx, haa); err != nil {
+				return reconcile.Result{}, err
+			}
+			r.Log.Info("Finalizer removed successfully")
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if !helpers.ContainsElement(haa.GetFinalizers(), humioFinalizer) {
+		r.Log.Info("Finalizer not present, adding finalizer to aggregate alert")
+		haa.SetFinalizers(append(haa.GetFinalizers(), humioFinalizer))
+		if err := r.Update(ctx, haa); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{Requeue: true}, nil
+	}
+
+	if haa.Status.State == humiov1alpha1.HumioAggregateAlertStateExists &&
+		syncUnchanged(haa, haa.Status.SyncedGeneration, haa.Status.AppliedHash, aggregateAlertDesiredHash(haa), haa.Status.LastResyncRequest, haa.Status.LastSyncTime, time.Now()) {
+		requeue := requeueInterval(haa, time.Second*15)
+		r.Log.Info(fmt.Sprintf("aggregate alert is unchanged since it was last synced, will requeue after %s", requeue))
+		return reconcile.Result{RequeueAfter: requeue}, nil
+	}
+
+	status, err := r.reconcileAggregateAlert(cluster.Config(), req, haa)
+	if err != nil {
+		state := humiov1alpha1.HumioAggregateAlertStateConfigError
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			state = humiov1alpha1.HumioAggregateAlertStateClusterUnavailable
+		}
+		if err := r.setState(ctx, state, haa); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set aggregate alert state")
+		}
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not reconcile aggregate alert")
+	}
+
+	status.SyncedGeneration = haa.Generation
+	status.ClusterName = syncClusterName(haa.Spec.ManagedClusterName, haa.Spec.ExternalClusterName)
+	status.LastSyncTime = syncTime(status.LastSyncTime, time.Now())
+	status.LastResyncRequest = haa.Annotations[triggerResyncAnnotation]
+	if err := r.setStatus(ctx, status, haa); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set aggregate alert status")
+	}
+
+	requeue := requeueInterval(haa, time.Second*15)
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+	return reconcile.Result{RequeueAfter: requeue}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioAggregateAlertReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioAggregateAlert{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioAggregateAlertList{}))).
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioAggregateAlert{}, r))
+}
+
+// reconcileAggregateAlert creates the aggregate alert if it does not exist, and updates it if it has drifted from the spec
+// unless the drift policy leaves changes made outside the operator in place. It returns the status describing the
+// aggregate alert.
+func (r *HumioAggregateAlertReconciler) reconcileAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (humiov1alpha1.HumioAggregateAlertStatus, error) {
+	status := *haa.Status.DeepCopy()
+	status.State = humiov1alpha1.HumioAggregateAlertStateExists
+	expectedAggregateAlert := humio.AggregateAlertTransform(haa)
+	expectedAggregateAlert.ID = ""
+	desiredHash := aggregateAlertDesiredHash(haa)
+
+	curAggregateAlert, err := r.HumioClient.GetAggregateAlert(config, req, haa)
+	if err != nil {
+		return status, fmt.Errorf("could not check if aggregate alert exists: %w", err)
+	}
+	if curAggregateAlert.ID == "" {
+		r.Log.Info("aggregate alert doesn't exist. Now adding aggregate alert")
+		addedAggregateAlert, err := r.HumioClient.AddAggregateAlert(config, req, haa)
+		if err != nil {
+			return status, fmt.Errorf("could not create aggregate alert: %w", err)
+		}
+		r.Log.Info("created aggregate alert", "AggregateAlert", haa.Spec.Name)
+		applyDriftOutcome(r.Recorder, haa, driftApply, desiredHash, &status.AppliedHash, &status.Conditions, haa.Generation)
+		status.DriftPatch = ""
+		status.ID = addedAggregateAlert.ID
+		return status, nil
+	}
+
+	expectedAggregateAlert.ID = curAggregateAlert.ID
+	outcome := evaluateDrift(haa.Spec.DriftPolicy, status.AppliedHash, desiredHash, !reflect.DeepEqual(*curAggregateAlert, *expectedAggregateAlert))
+	switch outcome {
+	case driftIgnore:
+		r.Log.Info("aggregate alert was changed outside the operator, leaving the changes in place because of the drift policy")
+	case driftApply, driftRevert:
+		r.Log.Info(fmt.Sprintf("aggregate alert differs, triggering update, expected %#v, got: %#v", expectedAggregateAlert, curAggregateAlert))
+		if _, err := r.HumioClient.UpdateAggregateAlert(config, req, haa); err != nil {
+			return status, fmt.Errorf("could not update aggregate alert: %w", err)
+		}
+	}
+	applyDriftOutcome(r.Recorder, haa, outcome, desiredHash, &status.AppliedHash, &status.Conditions, haa.Generation)
+	status.DriftPatch = ""
+	if outcome == driftIgnore && haa.Spec.DriftPolicy == humiov1alpha1.HumioDriftPolicyImport {
+		status.DriftPatch = driftPatch(haa.Spec, importedAggregateAlertSpec(haa.Spec, curAggregateAlert))
+	}
+	status.ID = curAggregateAlert.ID
+	return status, nil
+}
+
+// aggregateAlertDesiredHash returns a hash of the desired state of the aggregate alert. The ID is assigned by Humio, so it is
+// not part of the desired state.
+func aggregateAlertDesiredHash(haa *humiov1alpha1.HumioAggregateAlert) string {
+	expectedAggregateAlert := humio.AggregateAlertTransform(haa)
+	expectedAggregateAlert.ID = ""
+	return desiredStateHash(expectedAggregateAlert)
+}
+
+// importedAggregateAlertSpec returns the spec of the aggregate alert with the changes made to the aggregate alert inside
+// Humio. The defaults Humio fills in for unset fields are not imported.
+func importedAggregateAlertSpec(spec humiov1alpha1.HumioAggregateAlertSpec, aggregateAlert *humio.AggregateAlert) humiov1alpha1.HumioAggregateAlertSpec {
+	defaults := humio.AggregateAlertTransform(&humiov1alpha1.HumioAggregateAlert{})
+	spec.Description = aggregateAlert.Description
+	spec.QueryString = aggregateAlert.QueryString
+	if spec.SearchIntervalSeconds != 0 || aggregateAlert.SearchIntervalSeconds != defaults.SearchIntervalSeconds {
+		spec.SearchIntervalSeconds = aggregateAlert.SearchIntervalSeconds
+	}
+	if spec.QueryTimestampType != "" || aggregateAlert.QueryTimestampType != defaults.QueryTimestampType {
+		spec.QueryTimestampType = aggregateAlert.QueryTimestampType
+	}
+	if spec.TriggerMode != "" || aggregateAlert.TriggerMode != defaults.TriggerMode {
+		spec.TriggerMode = aggregateAlert.TriggerMode
+	}
+	if spec.ThrottleTimeSeconds != 0 || aggregateAlert.ThrottleTimeSeconds != aggregateAlert.SearchIntervalSeconds {
+		spec.ThrottleTimeSeconds = aggregateAlert.ThrottleTimeSeconds
+	}
+	spec.ThrottleField = aggregateAlert.ThrottleField
+	spec.Silenced = !aggregateAlert.Enabled
+	spec.Actions = aggregateAlert.Actions
+	spec.Labels = aggregateAlert.Labels
+	return spec
+}
+
+func (r *HumioAggregateAlertReconciler) setState(ctx context.Context, state string, haa *humiov1alpha1.HumioAggregateAlert) error {
+	status := *haa.Status.DeepCopy()
+	status.State = state
+	return r.setStatus(ctx, status, haa)
+}
+
+func (r *HumioAggregateAlertReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioAggregateAlertStatus, haa *humiov1alpha1.HumioAggregateAlert) error {
+	if reflect.DeepEqual(haa.Status, status) {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting aggregate alert state to %s", status.State))
+	haa.Status = status
+	return r.Status().Update(ctx, haa)
+}
+
+func (r *HumioAggregateAlertReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileAggregateAlert(t *testing.T) {
+	humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+	r := &HumioAggregateAlertReconciler{
+		HumioClient: humioClient,
+		Log:         logr.Discard(),
+	}
+	haa := &humiov1alpha1.HumioAggregateAlert{
+		Spec: humiov1alpha1.HumioAggregateAlertSpec{
+			Name:        "errors",
+			ViewName:    "view",
+			QueryString: "error = true | count() | _count > 0",
+			Actions:     []string{"email"},
+		},
+	}
+	config := &humioapi.Config{}
+	req := reconcile.Request{}
+
+	created, err := r.reconcileAggregateAlert(config, req, haa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	haa.Status = created
+	aggregateAlert, err := humioClient.GetAggregateAlert(config, req, haa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := humio.AggregateAlert{
+		ID:                    created.ID,
+		Name:                  "errors",
+		QueryString:           "error = true | count() | _count > 0",
+		SearchIntervalSeconds: 3600,
+		QueryTimestampType:    humiov1alpha1.HumioQueryTimestampTypeEvent,
+		TriggerMode:           humiov1alpha1.HumioTriggerModeComplete,
+		ThrottleTimeSeconds:   3600,
+		Enabled:               true,
+		Actions:               []string{"email"},
+	}
+	if created.ID == "" || !reflect.DeepEqual(*aggregateAlert, expected) {
+		t.Fatalf("expected aggregate alert to be created with defaults %+v, got %+v", expected, aggregateAlert)
+	}
+
+	unchanged, err := r.reconcileAggregateAlert(config, req, haa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unchanged, created) {
+		t.Errorf("expected unchanged status %+v, got %+v", created, unchanged)
+	}
+
+	// Changing the trigger mode updates the aggregate alert in place
+	haa.Spec.TriggerMode = humiov1alpha1.HumioTriggerModeImmediate
+	haa.Spec.QueryTimestampType = humiov1alpha1.HumioQueryTimestampTypeIngest
+	updated, err := r.reconcileAggregateAlert(config, req, haa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggregateAlert, err = humioClient.GetAggregateAlert(config, req, haa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ID != created.ID || aggregateAlert.TriggerMode != humiov1alpha1.HumioTriggerModeImmediate || aggregateAlert.QueryTimestampType != humiov1alpha1.HumioQueryTimestampTypeIngest {
+		t.Errorf("expected trigger mode and query timestamp type to be updated in place, got %+v", aggregateAlert)
+	}
+
+	// The Import drift policy records a patch which imports a trigger mode changed in Humio into the spec
+	haa.Status = updated
+	haa.Spec.DriftPolicy = humiov1alpha1.HumioDriftPolicyImport
+	haa.Spec.TriggerMode = humiov1alpha1.HumioTriggerModeComplete
+	if _, err := humioClient.UpdateAggregateAlert(config, req, haa); err != nil {
+		t.Fatal(err)
+	}
+	haa.Spec.TriggerMode = humiov1alpha1.HumioTriggerModeImmediate
+	imported, err := r.reconcileAggregateAlert(config, req, haa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"spec":{"triggerMode":"CompleteMode"}}`; imported.DriftPatch != expected {
+		t.Errorf("expected drift patch %s, got %s", expected, imported.DriftPatch)
+	}
+}
@@ -22,16 +22,20 @@ import (
 	"fmt"
 	"github.com/humio/humio-operator/pkg/kubernetes"
 	"reflect"
+	"time"
 
 	humioapi "github.com/humio/cli/api"
 
 	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/go-logr/logr"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
 	"github.com/humio/humio-operator/pkg/humio"
@@ -44,11 +48,14 @@ type HumioAlertReconciler struct {
 	Log         logr.Logger
 	HumioClient humio.Client
 	Namespace   string
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioalerts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioalerts/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioalerts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioalertsilences,verbs=get;list;watch
 
 func (r *HumioAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	if r.Namespace != "" {
@@ -76,6 +83,17 @@ func (r *HumioAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	r.Log = r.Log.WithValues("Request.UID", ha.UID)
 
 	cluster, err := helpers.NewCluster(ctx, r, ha.Spec.ManagedClusterName, ha.Spec.ExternalClusterName, ha.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, ha.Namespace, ha.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		err = r.setState(ctx, humiov1alpha1.HumioAlertStateClusterUnavailable, ha)
+		if err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set alert state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
 	if err != nil || cluster == nil || cluster.Config() == nil {
 		r.Log.Error(err, "unable to obtain humio client config")
 		err = r.setState(ctx, humiov1alpha1.HumioAlertStateConfigError, ha)
@@ -85,8 +103,17 @@ func (r *HumioAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return reconcile.Result{}, err
 	}
 
+	if request, requested := resyncRequested(ha, ha.Status.LastResyncRequest); requested {
+		r.Log.Info(fmt.Sprintf("resyncing alert as requested by annotation %s=%s", triggerResyncAnnotation, request))
+		r.HumioClient.InvalidateReadCache(cluster.Config())
+	}
+
 	defer func(ctx context.Context, humioClient humio.Client, ha *humiov1alpha1.HumioAlert) {
 		curAlert, err := r.HumioClient.GetAlert(cluster.Config(), req, ha)
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			_ = r.setState(ctx, humiov1alpha1.HumioAlertStateClusterUnavailable, ha)
+			return
+		}
 		if errors.As(err, &humioapi.EntityNotFound{}) {
 			_ = r.setState(ctx, humiov1alpha1.HumioAlertStateNotFound, ha)
 			return
@@ -140,12 +167,23 @@ func (r *HumioAlertReconciler) reconcileHumioAlert(ctx context.Context, config *
 		return reconcile.Result{Requeue: true}, nil
 	}
 
+	r.Log.Info("Checking if alert is silenced")
+	now := time.Now()
+	silences, nextSilenceTransition, err := r.alertSilences(ctx, ha, now)
+	if err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not list alert silences")
+	}
+	if err := r.setSilences(ctx, silences, ha); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set alert silences")
+	}
+	effectiveAlert := alertWithSilences(ha, silences)
+
 	r.Log.Info("Checking if alert needs to be created")
 	// Add Alert
 	curAlert, err := r.HumioClient.GetAlert(config, req, ha)
 	if errors.As(err, &humioapi.EntityNotFound{}) {
 		r.Log.Info("Alert doesn't exist. Now adding alert")
-		addedAlert, err := r.HumioClient.AddAlert(config, req, ha)
+		addedAlert, err := r.HumioClient.AddAlert(config, req, effectiveAlert)
 		if err != nil {
 			return reconcile.Result{}, r.logErrorAndReturn(err, "could not create alert")
 		}
@@ -167,26 +205,55 @@ func (r *HumioAlertReconciler) reconcileHumioAlert(ctx context.Context, config *
 	if err != nil {
 		return reconcile.Result{}, r.logErrorAndReturn(err, "could not get action id mapping")
 	}
-	expectedAlert, err := humio.AlertTransform(ha, actionIdMap)
+	alertID := curAlert.ID
+	outcome, desiredHash, expectedAlert, err := alertDrift(ha, effectiveAlert, curAlert, actionIdMap)
 	if err != nil {
 		return reconcile.Result{}, r.logErrorAndReturn(err, "could not parse expected Alert")
 	}
-
-	sanitizeAlert(curAlert)
-	if !reflect.DeepEqual(*curAlert, *expectedAlert) {
+	if outcome == driftIgnore {
+		r.Log.Info("Alert was changed outside the operator, leaving the changes in place because of the drift policy")
+	}
+	if outcome.updates() {
 		r.Log.Info(fmt.Sprintf("Alert differs, triggering update, expected %#v, got: %#v",
 			expectedAlert,
 			curAlert))
-		alert, err := r.HumioClient.UpdateAlert(config, req, ha)
-		if err != nil {
-			return reconcile.Result{}, r.logErrorAndReturn(err, "could not update alert")
-		}
-		if alert != nil {
-			r.Log.Info(fmt.Sprintf("Updated alert %q", alert.Name))
+		if helpers.UseHumioClientBulkListing() {
+			if err := r.updateAlertsInBatch(ctx, config, req, ha, effectiveAlert, now); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "could not update alerts")
+			}
+		} else {
+			alert, err := r.HumioClient.UpdateAlert(config, req, effectiveAlert)
+			if err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "could not update alert")
+			}
+			if alert != nil {
+				r.Log.Info(fmt.Sprintf("Updated alert %q", alert.Name))
+			}
 		}
 	}
+	var patch string
+	if outcome == driftIgnore && ha.Spec.DriftPolicy == humiov1alpha1.HumioDriftPolicyImport {
+		// Silences are applied on top of the spec, so they are not mistaken for changes made outside the operator
+		patch = driftPatch(effectiveAlert.Spec, importedAlertSpec(effectiveAlert.Spec, curAlert, actionIdMap))
+	}
+	if err := r.setSyncStatus(ctx, outcome, desiredHash, patch, alertID, now, ha); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set alert sync status")
+	}
+
+	if err := r.reconcileTestFire(ctx, config, req, ha); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not test fire alert")
+	}
 
-	r.Log.Info("done reconciling, will requeue after 15 seconds")
+	requeue := requeueInterval(ha, 0)
+	if !nextSilenceTransition.IsZero() && (requeue == 0 || nextSilenceTransition.Sub(now) < requeue) {
+		r.Log.Info(fmt.Sprintf("done reconciling, will requeue when silences change at %s", nextSilenceTransition.Format(time.RFC3339)))
+		return reconcile.Result{RequeueAfter: nextSilenceTransition.Sub(now)}, nil
+	}
+	if requeue > 0 {
+		r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+		return reconcile.Result{RequeueAfter: requeue}, nil
+	}
+	r.Log.Info("done reconciling")
 	return reconcile.Result{}, nil
 }
 
@@ -194,7 +261,9 @@ func (r *HumioAlertReconciler) reconcileHumioAlert(ctx context.Context, config *
 func (r *HumioAlertReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&humiov1alpha1.HumioAlert{}).
-		Complete(r)
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioAlertList{}))).
+		Watches(&humiov1alpha1.HumioAlertSilence{}, handler.EnqueueRequestsFromMapFunc(r.alertsForSilence)).
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioAlert{}, r))
 }
 
 func (r *HumioAlertReconciler) setState(ctx context.Context, state string, ha *humiov1alpha1.HumioAlert) error {
@@ -206,6 +275,21 @@ func (r *HumioAlertReconciler) setState(ctx context.Context, state string, ha *h
 	return r.Status().Update(ctx, ha)
 }
 
+func (r *HumioAlertReconciler) setSyncStatus(ctx context.Context, outcome driftOutcome, desiredHash, patch, id string, now time.Time, ha *humiov1alpha1.HumioAlert) error {
+	status := ha.Status.DeepCopy()
+	applyDriftOutcome(r.Recorder, ha, outcome, desiredHash, &status.AppliedHash, &status.Conditions, ha.Generation)
+	status.DriftPatch = patch
+	status.ID = id
+	status.ClusterName = syncClusterName(ha.Spec.ManagedClusterName, ha.Spec.ExternalClusterName)
+	status.LastSyncTime = syncTime(status.LastSyncTime, now)
+	status.LastResyncRequest = ha.Annotations[triggerResyncAnnotation]
+	if reflect.DeepEqual(ha.Status, *status) {
+		return nil
+	}
+	ha.Status = *status
+	return r.Status().Update(ctx, ha)
+}
+
 func (r *HumioAlertReconciler) logErrorAndReturn(err error, msg string) error {
 	r.Log.Error(err, msg)
 	return fmt.Errorf("%s: %w", msg, err)
@@ -216,3 +300,34 @@ func sanitizeAlert(alert *humioapi.Alert) {
 	alert.ID = ""
 	alert.LastError = ""
 }
+
+// importedAlertSpec returns the spec of the alert with the changes made to the alert inside Humio. Actions are referred
+// to by ID inside Humio, so the actions are only imported if all of them are known to the spec.
+func importedAlertSpec(spec humiov1alpha1.HumioAlertSpec, alert *humioapi.Alert, actionIdMap map[string]string) humiov1alpha1.HumioAlertSpec {
+	spec.Query.QueryString = alert.QueryString
+	if spec.Query.Start != "" || alert.QueryStart != "24h" {
+		spec.Query.Start = alert.QueryStart
+	}
+	spec.Description = alert.Description
+	spec.ThrottleTimeMillis = alert.ThrottleTimeMillis
+	spec.ThrottleField = alert.ThrottleField
+	spec.Silenced = !alert.Enabled
+	spec.Labels = alert.Labels
+
+	actionNames := make(map[string]string, len(actionIdMap))
+	for name, id := range actionIdMap {
+		actionNames[id] = name
+	}
+	actions := make([]string, 0, len(alert.Actions))
+	for _, id := range alert.Actions {
+		name, ok := actionNames[id]
+		if !ok {
+			return spec
+		}
+		actions = append(actions, name)
+	}
+	if len(actions) > 0 || len(spec.Actions) > 0 {
+		spec.Actions = actions
+	}
+	return spec
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+func TestImportedAlertSpec(t *testing.T) {
+	spec := humiov1alpha1.HumioAlertSpec{
+		Name:     "errors",
+		ViewName: "web",
+		Query:    humiov1alpha1.HumioQuery{QueryString: "#level=ERROR"},
+		Actions:  []string{"email"},
+	}
+	actionIdMap := map[string]string{"email": "1", "slack": "2"}
+
+	tests := []struct {
+		name     string
+		alert    humioapi.Alert
+		expected string
+	}{
+		{
+			name:  "unchanged with defaults filled in by Humio",
+			alert: humioapi.Alert{Name: "errors", QueryString: "#level=ERROR", QueryStart: "24h", Enabled: true, Actions: []string{"1"}},
+		},
+		{
+			name:     "changed query and actions",
+			alert:    humioapi.Alert{Name: "errors", QueryString: "#level=FATAL", QueryStart: "1h", Enabled: true, Actions: []string{"1", "2"}},
+			expected: `{"spec":{"actions":["email","slack"],"query":{"queryString":"#level=FATAL","start":"1h"}}}`,
+		},
+		{
+			name:     "disabled with unknown action",
+			alert:    humioapi.Alert{Name: "errors", QueryString: "#level=ERROR", QueryStart: "24h", Actions: []string{"3"}},
+			expected: `{"spec":{"silenced":true}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imported := importedAlertSpec(spec, &tt.alert, actionIdMap)
+			if got := driftPatch(spec, imported); got != tt.expected {
+				t.Errorf("expected patch %s, got %s", tt.expected, got)
+			}
+			if !reflect.DeepEqual(spec.Actions, []string{"email"}) {
+				t.Errorf("expected spec to be left unchanged, got actions %v", spec.Actions)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// alertTestFireAnnotation makes the operator invoke the actions of the alert once with a synthetic event when set
+	// to "true". The annotation is removed once the actions have been invoked.
+	alertTestFireAnnotation = "humio.com/test-fire"
+
+	alertTestFireSucceededReason = "TestFireSucceeded"
+	alertTestFireFailedReason    = "TestFireFailed"
+)
+
+// alertTestFireEventData returns the synthetic event the actions of the alert are invoked with
+func alertTestFireEventData(ha *humiov1alpha1.HumioAlert, now time.Time) (string, error) {
+	event, err := json.Marshal(map[string]string{
+		"@timestamp": strconv.FormatInt(now.UnixMilli(), 10),
+		"#repo":      ha.Spec.ViewName,
+		"message":    fmt.Sprintf("Test event for alert %s sent by humio-operator", ha.Spec.Name),
+	})
+	return string(event), err
+}
+
+// reconcileTestFire invokes each action of the alert once with a synthetic event when requested using the test-fire
+// annotation, and records the outcome for each action as an event on the HumioAlert
+func (r *HumioAlertReconciler) reconcileTestFire(ctx context.Context, config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) error {
+	if ha.Annotations[alertTestFireAnnotation] != "true" {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("test firing alert as requested by annotation %s", alertTestFireAnnotation))
+
+	eventData, err := alertTestFireEventData(ha, time.Now())
+	if err != nil {
+		return fmt.Errorf("unable to construct test event: %w", err)
+	}
+	if len(ha.Spec.Actions) == 0 {
+		r.recordEvent(ha, corev1.EventTypeWarning, alertTestFireFailedReason, "Alert has no actions to test fire")
+	}
+	for _, actionName := range ha.Spec.Actions {
+		action, err := r.HumioClient.GetAction(config, req, &humiov1alpha1.HumioAction{
+			Spec: humiov1alpha1.HumioActionSpec{
+				Name:     actionName,
+				ViewName: ha.Spec.ViewName,
+			},
+		})
+		if err != nil {
+			r.recordEvent(ha, corev1.EventTypeWarning, alertTestFireFailedReason, fmt.Sprintf("Unable to get action %s: %s", actionName, err))
+			continue
+		}
+		result, err := r.HumioClient.TestAction(config, req, ha.Spec.ViewName, action, ha.Spec.Name, eventData)
+		if err != nil {
+			r.recordEvent(ha, corev1.EventTypeWarning, alertTestFireFailedReason, fmt.Sprintf("Unable to test fire action %s: %s", actionName, err))
+			continue
+		}
+		if !result.Success {
+			r.recordEvent(ha, corev1.EventTypeWarning, alertTestFireFailedReason, fmt.Sprintf("Action %s failed: %s", actionName, result.Message))
+			continue
+		}
+		r.recordEvent(ha, corev1.EventTypeNormal, alertTestFireSucceededReason, fmt.Sprintf("Action %s succeeded: %s", actionName, result.Message))
+	}
+
+	delete(ha.Annotations, alertTestFireAnnotation)
+	if err := r.Update(ctx, ha); err != nil {
+		return fmt.Errorf("unable to remove annotation %s: %w", alertTestFireAnnotation, err)
+	}
+	return nil
+}
+
+func (r *HumioAlertReconciler) recordEvent(ha *humiov1alpha1.HumioAlert, eventType, reason, message string) {
+	r.Log.Info(message, "Reason", reason)
+	if r.Recorder != nil {
+		r.Recorder.Event(ha, eventType, reason, message)
+	}
+}
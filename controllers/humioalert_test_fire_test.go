@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileTestFire(t *testing.T) {
+	tests := []struct {
+		name           string
+		annotation     string
+		actions        []string
+		failures       map[string]string
+		expectedTested []string
+		expectedEvents []string
+	}{
+		{
+			name:    "not requested",
+			actions: []string{"slack"},
+		},
+		{
+			name:       "not true",
+			annotation: "false",
+			actions:    []string{"slack"},
+		},
+		{
+			name:           "all actions succeed",
+			annotation:     "true",
+			actions:        []string{"slack", "pagerduty"},
+			expectedTested: []string{"slack", "slack"},
+			expectedEvents: []string{"Normal TestFireSucceeded Action slack succeeded", "Normal TestFireSucceeded Action pagerduty succeeded"},
+		},
+		{
+			name:           "action fails",
+			annotation:     "true",
+			actions:        []string{"slack"},
+			failures:       map[string]string{"slack": "invalid_token"},
+			expectedTested: []string{"slack"},
+			expectedEvents: []string{"Warning TestFireFailed Action slack failed: invalid_token"},
+		},
+		{
+			name:           "no actions",
+			annotation:     "true",
+			expectedEvents: []string{"Warning TestFireFailed Alert has no actions"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ha := &humiov1alpha1.HumioAlert{
+				ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "default", Annotations: map[string]string{}},
+				Spec:       humiov1alpha1.HumioAlertSpec{Name: "alert", ViewName: "web", Actions: tt.actions},
+			}
+			if tt.annotation != "" {
+				ha.Annotations[alertTestFireAnnotation] = tt.annotation
+			}
+			humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+			// The mock client holds a single action, which is returned for every action name
+			if _, err := humioClient.AddAction(&humioapi.Config{}, reconcile.Request{}, &humiov1alpha1.HumioAction{
+				Spec: humiov1alpha1.HumioActionSpec{Name: "slack", SlackProperties: &humiov1alpha1.HumioActionSlackProperties{Url: "https://hooks.slack.com/services/x", Fields: map[string]string{"query": "{query}"}}},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			for actionName, message := range tt.failures {
+				humioClient.SetTestActionFailure(actionName, message)
+			}
+			scheme := runtime.NewScheme()
+			_ = humiov1alpha1.AddToScheme(scheme)
+			recorder := record.NewFakeRecorder(10)
+			r := &HumioAlertReconciler{
+				Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(ha).Build(),
+				HumioClient: humioClient,
+				Log:         logr.Discard(),
+				Recorder:    recorder,
+			}
+
+			if err := r.reconcileTestFire(context.Background(), &humioapi.Config{}, reconcile.Request{}, ha); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(humioClient.TestedActions(), tt.expectedTested) {
+				t.Errorf("expected tested actions %v, got %v", tt.expectedTested, humioClient.TestedActions())
+			}
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			if len(events) != len(tt.expectedEvents) {
+				t.Fatalf("expected events %v, got %v", tt.expectedEvents, events)
+			}
+			for i, event := range events {
+				if !strings.HasPrefix(event, tt.expectedEvents[i]) {
+					t.Errorf("expected event %q, got %q", tt.expectedEvents[i], event)
+				}
+			}
+
+			var updated humiov1alpha1.HumioAlert
+			if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "alert"}, &updated); err != nil {
+				t.Fatal(err)
+			}
+			value, ok := updated.Annotations[alertTestFireAnnotation]
+			if tt.annotation == "true" && ok {
+				t.Errorf("expected annotation to be removed after test firing")
+			}
+			if tt.annotation != "true" && value != tt.annotation {
+				t.Errorf("expected annotation %q to be left alone, got %q", tt.annotation, value)
+			}
+		})
+	}
+}
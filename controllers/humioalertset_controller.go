@@ -0,0 +1,306 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// humioAlertSetLabelName identifies the HumioAlertSet managing a HumioAlert
+const humioAlertSetLabelName = "humio.com/alert-set"
+
+// HumioAlertSetReconciler reconciles a HumioAlertSet object
+type HumioAlertSetReconciler struct {
+	client.Client
+	BaseLogger logr.Logger
+	Log        logr.Logger
+	Namespace  string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioalertsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioalertsets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioalertsets/finalizers,verbs=update
+
+func (r *HumioAlertSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioAlertSet")
+
+	has := &humiov1alpha1.HumioAlertSet{}
+	if err := r.Get(ctx, req.NamespacedName, has); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", has.UID)
+
+	// The alerts of the set are owned by it, so they are garbage collected when the set is deleted
+	if has.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+
+	desired, err := humioAlertSetAlerts(has)
+	if err != nil {
+		r.Log.Error(err, "invalid alert set configuration")
+		status := *has.Status.DeepCopy()
+		status.State = humiov1alpha1.HumioAlertSetStateConfigError
+		status.Message = err.Error()
+		if err := r.setStatus(ctx, status, has); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set alert set status")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	var alerts []humiov1alpha1.HumioAlertSetAlertStatus
+	for i := range desired {
+		alertStatus := humiov1alpha1.HumioAlertSetAlertStatus{Name: desired[i].Name}
+		ha, err := r.ensureAlert(ctx, has, &desired[i])
+		if err != nil {
+			r.Log.Error(err, fmt.Sprintf("unable to manage alert %s", desired[i].Name))
+			alertStatus.Message = err.Error()
+		} else {
+			alertStatus.State = ha.Status.State
+		}
+		alerts = append(alerts, alertStatus)
+	}
+
+	if err := r.removeAlerts(ctx, has, desired); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to remove alerts which are no longer part of the alert set")
+	}
+
+	if err := r.setStatus(ctx, humioAlertSetStatus(alerts), has); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set alert set status")
+	}
+
+	requeue := requeueInterval(has, time.Second*60)
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+	return reconcile.Result{RequeueAfter: requeue}, nil
+}
+
+// humioAlertSetAlerts returns the HumioAlerts described by the instances of the alert set
+func humioAlertSetAlerts(has *humiov1alpha1.HumioAlertSet) ([]humiov1alpha1.HumioAlert, error) {
+	seenInstances := map[string]bool{}
+	seenAlerts := map[string]string{}
+	var alerts []humiov1alpha1.HumioAlert
+	for _, instance := range has.Spec.Instances {
+		if seenInstances[instance.Name] {
+			return nil, fmt.Errorf("instance %s is listed more than once", instance.Name)
+		}
+		seenInstances[instance.Name] = true
+
+		name := fmt.Sprintf("%s-%s", has.Name, instance.Name)
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("instance %s results in invalid alert name %s: %s", instance.Name, name, strings.Join(errs, ", "))
+		}
+		if _, ok := instance.Parameters["name"]; ok {
+			return nil, fmt.Errorf("instance %s sets the parameter name, which is reserved for the name of the instance", instance.Name)
+		}
+		parameters := map[string]string{"name": instance.Name}
+		for k, v := range instance.Parameters {
+			parameters[k] = v
+		}
+		spec, err := renderHumioAlertSetTemplate(has.Spec.Template.Spec, parameters)
+		if err != nil {
+			return nil, fmt.Errorf("unable to render template for instance %s: %w", instance.Name, err)
+		}
+		alertKey := fmt.Sprintf("%s/%s", spec.ViewName, spec.Name)
+		if other, ok := seenAlerts[alertKey]; ok {
+			return nil, fmt.Errorf("instances %s and %s both result in alert %s in view %s, use a parameter in the name of the template", other, instance.Name, spec.Name, spec.ViewName)
+		}
+		seenAlerts[alertKey] = instance.Name
+
+		labels := map[string]string{}
+		for k, v := range has.Spec.Template.Labels {
+			labels[k] = v
+		}
+		labels[humioAlertSetLabelName] = has.Name
+
+		alerts = append(alerts, humiov1alpha1.HumioAlert{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: has.Namespace,
+				Labels:    labels,
+			},
+			Spec: spec,
+		})
+	}
+	return alerts, nil
+}
+
+// renderHumioAlertSetTemplate substitutes the parameters into the string fields of the alert template. Parameters
+// referenced by the template must be set.
+func renderHumioAlertSetTemplate(spec humiov1alpha1.HumioAlertSpec, parameters map[string]string) (humiov1alpha1.HumioAlertSpec, error) {
+	rendered := *spec.DeepCopy()
+	var err error
+	render := func(field string, value *string) {
+		if err != nil || *value == "" {
+			return
+		}
+		var tmpl *template.Template
+		tmpl, err = template.New(field).Option("missingkey=error").Parse(*value)
+		if err != nil {
+			err = fmt.Errorf("invalid template %s: %w", field, err)
+			return
+		}
+		var out bytes.Buffer
+		if err = tmpl.Execute(&out, parameters); err != nil {
+			err = fmt.Errorf("invalid template %s: %w", field, err)
+			return
+		}
+		*value = out.String()
+	}
+	render("name", &rendered.Name)
+	render("viewName", &rendered.ViewName)
+	render("description", &rendered.Description)
+	render("query.queryString", &rendered.Query.QueryString)
+	render("query.start", &rendered.Query.Start)
+	render("throttleField", &rendered.ThrottleField)
+	for i := range rendered.Actions {
+		render(fmt.Sprintf("actions[%d]", i), &rendered.Actions[i])
+	}
+	for i := range rendered.Labels {
+		render(fmt.Sprintf("labels[%d]", i), &rendered.Labels[i])
+	}
+	return rendered, err
+}
+
+// ensureAlert creates the given HumioAlert, or updates it if it differs from the template. Alerts which exist but are
+// not owned by the alert set are left untouched.
+func (r *HumioAlertSetReconciler) ensureAlert(ctx context.Context, has *humiov1alpha1.HumioAlertSet, desired *humiov1alpha1.HumioAlert) (*humiov1alpha1.HumioAlert, error) {
+	ha := &humiov1alpha1.HumioAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      desired.Name,
+			Namespace: desired.Namespace,
+		},
+	}
+	result, err := controllerutil.CreateOrUpdate(ctx, r, ha, func() error {
+		if ha.ResourceVersion != "" && !metav1.IsControlledBy(ha, has) {
+			return fmt.Errorf("alert already exists and is not part of this alert set")
+		}
+		if ha.Labels == nil {
+			ha.Labels = map[string]string{}
+		}
+		for k, v := range desired.Labels {
+			ha.Labels[k] = v
+		}
+		ha.Spec = desired.Spec
+		return controllerutil.SetControllerReference(has, ha, r.Scheme())
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result != controllerutil.OperationResultNone {
+		r.Log.Info(fmt.Sprintf("alert %s %s", ha.Name, result))
+	}
+	return ha, nil
+}
+
+// removeAlerts deletes the alerts of the alert set which are no longer listed as instances
+func (r *HumioAlertSetReconciler) removeAlerts(ctx context.Context, has *humiov1alpha1.HumioAlertSet, desired []humiov1alpha1.HumioAlert) error {
+	wanted := map[string]bool{}
+	for _, ha := range desired {
+		wanted[ha.Name] = true
+	}
+	var humioAlerts humiov1alpha1.HumioAlertList
+	if err := r.List(ctx, &humioAlerts, client.InNamespace(has.Namespace), client.MatchingLabels{humioAlertSetLabelName: has.Name}); err != nil {
+		return err
+	}
+	for i := range humioAlerts.Items {
+		ha := &humioAlerts.Items[i]
+		if wanted[ha.Name] || !metav1.IsControlledBy(ha, has) {
+			continue
+		}
+		r.Log.Info(fmt.Sprintf("deleting alert %s", ha.Name))
+		if err := r.Delete(ctx, ha); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// humioAlertSetStatus aggregates the state of the alerts of the alert set
+func humioAlertSetStatus(alerts []humiov1alpha1.HumioAlertSetAlertStatus) humiov1alpha1.HumioAlertSetStatus {
+	status := humiov1alpha1.HumioAlertSetStatus{
+		State:  humiov1alpha1.HumioAlertSetStateReady,
+		Alerts: alerts,
+	}
+	degraded := false
+	for _, alert := range alerts {
+		switch {
+		case alert.Message != "" || alert.State == humiov1alpha1.HumioAlertStateConfigError:
+			degraded = true
+		case alert.State == humiov1alpha1.HumioAlertStateExists:
+			status.ReadyAlerts++
+		}
+	}
+	switch {
+	case degraded:
+		status.State = humiov1alpha1.HumioAlertSetStateDegraded
+	case status.ReadyAlerts < len(alerts):
+		status.State = humiov1alpha1.HumioAlertSetStatePending
+	}
+	return status
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioAlertSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioAlertSet{}).
+		Owns(&humiov1alpha1.HumioAlert{}).
+		Complete(humioEntityReconciler(r, nil, &humiov1alpha1.HumioAlertSet{}, r))
+}
+
+func (r *HumioAlertSetReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioAlertSetStatus, has *humiov1alpha1.HumioAlertSet) error {
+	if reflect.DeepEqual(has.Status, status) {
+		return nil
+	}
+	if has.Status.State != status.State {
+		r.Log.Info(fmt.Sprintf("setting alert set state to %s", status.State))
+	}
+	has.Status = status
+	return r.Status().Update(ctx, has)
+}
+
+func (r *HumioAlertSetReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
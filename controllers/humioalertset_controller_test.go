@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func testHumioAlertSet() *humiov1alpha1.HumioAlertSet {
+	return &humiov1alpha1.HumioAlertSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "latency", Namespace: "default", UID: "alert-set-uid"},
+		Spec: humiov1alpha1.HumioAlertSetSpec{
+			Template: humiov1alpha1.HumioAlertSetTemplate{
+				Labels: map[string]string{"team": "ops"},
+				Spec: humiov1alpha1.HumioAlertSpec{
+					ManagedClusterName: "humio",
+					Name:               "{{ .name }} latency",
+					ViewName:           "services",
+					Query: humiov1alpha1.HumioQuery{
+						QueryString: `service = "{{ .name }}" | avg(latency) | _avg > {{ .threshold }}`,
+					},
+					ThrottleTimeMillis: 60000,
+					Actions:            []string{"{{ .team }}-slack"},
+				},
+			},
+			Instances: []humiov1alpha1.HumioAlertSetInstance{
+				{Name: "checkout", Parameters: map[string]string{"threshold": "500", "team": "payments"}},
+				{Name: "search", Parameters: map[string]string{"threshold": "200", "team": "discovery"}},
+			},
+		},
+	}
+}
+
+func TestHumioAlertSetAlerts(t *testing.T) {
+	alerts, err := humioAlertSetAlerts(testHumioAlertSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(alerts))
+	}
+	checkout := alerts[0]
+	if checkout.Name != "latency-checkout" || checkout.Labels[humioAlertSetLabelName] != "latency" || checkout.Labels["team"] != "ops" {
+		t.Errorf("unexpected metadata %+v", checkout.ObjectMeta)
+	}
+	if checkout.Spec.Name != "checkout latency" ||
+		checkout.Spec.Query.QueryString != `service = "checkout" | avg(latency) | _avg > 500` ||
+		checkout.Spec.Actions[0] != "payments-slack" ||
+		checkout.Spec.ThrottleTimeMillis != 60000 ||
+		checkout.Spec.ManagedClusterName != "humio" {
+		t.Errorf("unexpected spec %+v", checkout.Spec)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(has *humiov1alpha1.HumioAlertSet)
+	}{
+		{"duplicate instance", func(has *humiov1alpha1.HumioAlertSet) {
+			has.Spec.Instances[1].Name = "checkout"
+		}},
+		{"missing parameter", func(has *humiov1alpha1.HumioAlertSet) {
+			delete(has.Spec.Instances[1].Parameters, "threshold")
+		}},
+		{"reserved parameter", func(has *humiov1alpha1.HumioAlertSet) {
+			has.Spec.Instances[1].Parameters["name"] = "other"
+		}},
+		{"same alert name", func(has *humiov1alpha1.HumioAlertSet) {
+			has.Spec.Template.Spec.Name = "latency"
+		}},
+		{"invalid instance name", func(has *humiov1alpha1.HumioAlertSet) {
+			has.Spec.Instances[1].Name = "Search Service"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			has := testHumioAlertSet()
+			tt.mutate(has)
+			if _, err := humioAlertSetAlerts(has); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestHumioAlertSetReconcile(t *testing.T) {
+	has := testHumioAlertSet()
+	unowned := &humiov1alpha1.HumioAlert{ObjectMeta: metav1.ObjectMeta{Name: "latency-search", Namespace: "default"}}
+	scheme := runtime.NewScheme()
+	_ = humiov1alpha1.AddToScheme(scheme)
+	r := &HumioAlertSetReconciler{
+		Client:     fake.NewClientBuilder().WithScheme(scheme).WithObjects(has, unowned).WithStatusSubresource(has).Build(),
+		BaseLogger: logr.Discard(),
+	}
+	ctx := context.Background()
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "latency"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	var checkout humiov1alpha1.HumioAlert
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "latency-checkout"}, &checkout); err != nil {
+		t.Fatal(err)
+	}
+	if !metav1.IsControlledBy(&checkout, has) {
+		t.Errorf("expected alert to be owned by the alert set")
+	}
+	if err := r.Get(ctx, req.NamespacedName, has); err != nil {
+		t.Fatal(err)
+	}
+	if has.Status.State != humiov1alpha1.HumioAlertSetStateDegraded || len(has.Status.Alerts) != 2 || has.Status.Alerts[1].Message == "" {
+		t.Errorf("expected unowned alert to degrade the alert set, got %+v", has.Status)
+	}
+
+	// Changes to the template are applied to the alerts
+	has.Spec.Template.Spec.ThrottleTimeMillis = 120000
+	has.Spec.Instances = []humiov1alpha1.HumioAlertSetInstance{
+		has.Spec.Instances[0],
+		{Name: "cart", Parameters: map[string]string{"threshold": "300", "team": "payments"}},
+	}
+	if err := r.Update(ctx, has); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "latency-checkout"}, &checkout); err != nil {
+		t.Fatal(err)
+	}
+	if checkout.Spec.ThrottleTimeMillis != 120000 {
+		t.Errorf("expected template change to be applied, got %+v", checkout.Spec)
+	}
+
+	// Alerts of removed instances are deleted, while alerts not owned by the set are left alone
+	if err := r.Get(ctx, req.NamespacedName, has); err != nil {
+		t.Fatal(err)
+	}
+	has.Spec.Instances = has.Spec.Instances[1:]
+	if err := r.Update(ctx, has); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	var alerts humiov1alpha1.HumioAlertList
+	if err := r.List(ctx, &alerts, client.MatchingLabels{humioAlertSetLabelName: "latency"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts.Items) != 1 || alerts.Items[0].Name != "latency-cart" {
+		t.Errorf("expected only the alert of the remaining instance, got %+v", alerts.Items)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "latency-search"}, unowned); err != nil {
+		t.Errorf("expected unowned alert to be left alone: %s", err)
+	}
+}
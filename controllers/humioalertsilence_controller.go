@@ -0,0 +1,267 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// HumioAlertSilenceReconciler reconciles a HumioAlertSilence object. The selected alerts are disabled by the
+// HumioAlert controller, so this controller only reports the window of the silence and which alerts it selects.
+type HumioAlertSilenceReconciler struct {
+	client.Client
+	BaseLogger logr.Logger
+	Log        logr.Logger
+	Namespace  string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioalertsilences,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioalertsilences/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioalertsilences/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioalerts,verbs=get;list;watch
+
+func (r *HumioAlertSilenceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioAlertSilence")
+
+	hals := &humiov1alpha1.HumioAlertSilence{}
+	if err := r.Get(ctx, req.NamespacedName, hals); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hals.UID)
+
+	now := time.Now()
+	status := humiov1alpha1.HumioAlertSilenceStatus{}
+	selector, err := metav1.LabelSelectorAsSelector(&hals.Spec.AlertSelector)
+	if err != nil {
+		status.State = humiov1alpha1.HumioAlertSilenceStateConfigError
+		status.Message = fmt.Sprintf("invalid alert selector: %s", err)
+		return reconcile.Result{}, r.setStatus(ctx, status, hals)
+	}
+	active, next, err := alertSilenceWindow(hals, now)
+	if err != nil {
+		status.State = humiov1alpha1.HumioAlertSilenceStateConfigError
+		status.Message = err.Error()
+		return reconcile.Result{}, r.setStatus(ctx, status, hals)
+	}
+	switch {
+	case active:
+		status.State = humiov1alpha1.HumioAlertSilenceStateActive
+	case next.IsZero():
+		status.State = humiov1alpha1.HumioAlertSilenceStateExpired
+	default:
+		status.State = humiov1alpha1.HumioAlertSilenceStateScheduled
+	}
+	if !next.IsZero() {
+		nextTransitionTime := metav1.NewTime(next)
+		status.NextTransitionTime = &nextTransitionTime
+	}
+
+	var alerts humiov1alpha1.HumioAlertList
+	if err := r.List(ctx, &alerts, client.InNamespace(hals.Namespace)); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to list alerts")
+	}
+	for _, ha := range alerts.Items {
+		if selector.Matches(labels.Set(ha.Labels)) {
+			status.Alerts = append(status.Alerts, ha.Name)
+		}
+	}
+	sort.Strings(status.Alerts)
+	if err := r.setStatus(ctx, status, hals); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set alert silence status")
+	}
+
+	if next.IsZero() {
+		r.Log.Info("done reconciling, the silence never changes again")
+		return reconcile.Result{}, nil
+	}
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue at %s", next.Format(time.RFC3339)))
+	return reconcile.Result{RequeueAfter: next.Sub(now)}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioAlertSilenceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioAlertSilence{}).
+		Watches(&humiov1alpha1.HumioAlert{}, handler.EnqueueRequestsFromMapFunc(r.silencesForAlert)).
+		Complete(entityReconciler(r))
+}
+
+// silencesForAlert returns a reconcile request for every HumioAlertSilence in the namespace of the given alert, as
+// changes to the labels of the alert may change which silences select it
+func (r *HumioAlertSilenceReconciler) silencesForAlert(ctx context.Context, ha client.Object) []reconcile.Request {
+	var silences humiov1alpha1.HumioAlertSilenceList
+	if err := r.List(ctx, &silences, client.InNamespace(ha.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list alert silences")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, hals := range silences.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: hals.Namespace, Name: hals.Name},
+		})
+	}
+	return requests
+}
+
+func (r *HumioAlertSilenceReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioAlertSilenceStatus, hals *humiov1alpha1.HumioAlertSilence) error {
+	if reflect.DeepEqual(hals.Status, status) {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting alert silence state to %s, selecting %d alerts", status.State, len(status.Alerts)))
+	hals.Status = status
+	return r.Status().Update(ctx, hals)
+}
+
+func (r *HumioAlertSilenceReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// alertSilenceWindow returns whether the silence is active at the given time, and when it next becomes active or
+// stops being active. The returned time is zero if the silence never changes again.
+func alertSilenceWindow(hals *humiov1alpha1.HumioAlertSilence, now time.Time) (bool, time.Time, error) {
+	if window := hals.Spec.MaintenanceWindow; window != nil {
+		if hals.Spec.Start != nil || hals.Spec.End != nil {
+			return false, time.Time{}, fmt.Errorf("maintenanceWindow conflicts with start and end")
+		}
+		open, start, err := maintenanceWindowOpen(window, now)
+		if err != nil || !open {
+			return false, start, err
+		}
+		return true, start.Add(time.Duration(window.DurationMinutes) * time.Minute), nil
+	}
+	if hals.Spec.End == nil {
+		return false, time.Time{}, fmt.Errorf("exactly one of end and maintenanceWindow must be set")
+	}
+	start := hals.CreationTimestamp.Time
+	if hals.Spec.Start != nil {
+		start = hals.Spec.Start.Time
+	}
+	end := hals.Spec.End.Time
+	if !end.After(start) {
+		return false, time.Time{}, fmt.Errorf("end must be after start")
+	}
+	switch {
+	case now.Before(start):
+		return false, start, nil
+	case now.Before(end):
+		return true, end, nil
+	}
+	return false, time.Time{}, nil
+}
+
+// activeAlertSilences returns the names of the silences which are active for the given alert at the given time, and
+// the earliest time one of the silences selecting the alert becomes active or stops being active. Silences with an
+// invalid selector or window are ignored.
+func activeAlertSilences(silences []humiov1alpha1.HumioAlertSilence, ha *humiov1alpha1.HumioAlert, now time.Time) ([]string, time.Time) {
+	var active []string
+	var next time.Time
+	for i := range silences {
+		hals := &silences[i]
+		selector, err := metav1.LabelSelectorAsSelector(&hals.Spec.AlertSelector)
+		if err != nil || !selector.Matches(labels.Set(ha.Labels)) {
+			continue
+		}
+		isActive, transition, err := alertSilenceWindow(hals, now)
+		if err != nil {
+			continue
+		}
+		if isActive {
+			active = append(active, hals.Name)
+		}
+		if !transition.IsZero() && (next.IsZero() || transition.Before(next)) {
+			next = transition
+		}
+	}
+	sort.Strings(active)
+	return active, next
+}
+
+// alertWithSilences returns the alert as it should exist in Humio, which is disabled while any silence is active
+func alertWithSilences(ha *humiov1alpha1.HumioAlert, silences []string) *humiov1alpha1.HumioAlert {
+	if len(silences) == 0 {
+		return ha
+	}
+	effective := ha.DeepCopy()
+	effective.Spec.Silenced = true
+	return effective
+}
+
+// alertSilences returns the names of the silences which are active for the given alert, and when the silences
+// selecting the alert next change
+func (r *HumioAlertReconciler) alertSilences(ctx context.Context, ha *humiov1alpha1.HumioAlert, now time.Time) ([]string, time.Time, error) {
+	var silences humiov1alpha1.HumioAlertSilenceList
+	if err := r.List(ctx, &silences, client.InNamespace(ha.Namespace)); err != nil {
+		return nil, time.Time{}, err
+	}
+	active, next := activeAlertSilences(silences.Items, ha, now)
+	return active, next, nil
+}
+
+func (r *HumioAlertReconciler) setSilences(ctx context.Context, silences []string, ha *humiov1alpha1.HumioAlert) error {
+	if reflect.DeepEqual(ha.Status.Silences, silences) {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting active silences to %v", silences))
+	ha.Status.Silences = silences
+	return r.Status().Update(ctx, ha)
+}
+
+// alertsForSilence returns a reconcile request for every HumioAlert in the namespace of the given silence. All alerts
+// are reconciled, as alerts may no longer be selected by the silence.
+func (r *HumioAlertReconciler) alertsForSilence(ctx context.Context, hals client.Object) []reconcile.Request {
+	var alerts humiov1alpha1.HumioAlertList
+	if err := r.List(ctx, &alerts, client.InNamespace(hals.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list alerts")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, ha := range alerts.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: ha.Namespace, Name: ha.Name},
+		})
+	}
+	return requests
+}
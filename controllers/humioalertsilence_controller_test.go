@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestAlertSilenceWindow(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hour int) *metav1.Time {
+		t := metav1.NewTime(time.Date(2024, 3, 1, hour, 0, 0, 0, time.UTC))
+		return &t
+	}
+	tests := []struct {
+		name           string
+		spec           humiov1alpha1.HumioAlertSilenceSpec
+		now            time.Time
+		expectedActive bool
+		expectedNext   time.Time
+		expectedErr    bool
+	}{
+		{
+			name:         "before fixed range",
+			spec:         humiov1alpha1.HumioAlertSilenceSpec{Start: at(20), End: at(23)},
+			now:          at(14).Time,
+			expectedNext: at(20).Time,
+		},
+		{
+			name:           "within fixed range",
+			spec:           humiov1alpha1.HumioAlertSilenceSpec{Start: at(20), End: at(23)},
+			now:            at(21).Time,
+			expectedActive: true,
+			expectedNext:   at(23).Time,
+		},
+		{
+			name: "after fixed range",
+			spec: humiov1alpha1.HumioAlertSilenceSpec{Start: at(20), End: at(23)},
+			now:  at(23).Time,
+		},
+		{
+			name:           "start defaults to creation",
+			spec:           humiov1alpha1.HumioAlertSilenceSpec{End: at(23)},
+			now:            at(13).Time,
+			expectedActive: true,
+			expectedNext:   at(23).Time,
+		},
+		{
+			name:         "before maintenance window",
+			spec:         humiov1alpha1.HumioAlertSilenceSpec{MaintenanceWindow: &humiov1alpha1.HumioMaintenanceWindow{Schedule: "0 20 * * *", DurationMinutes: 180}},
+			now:          at(14).Time,
+			expectedNext: at(20).Time,
+		},
+		{
+			name:           "within maintenance window",
+			spec:           humiov1alpha1.HumioAlertSilenceSpec{MaintenanceWindow: &humiov1alpha1.HumioMaintenanceWindow{Schedule: "0 20 * * *", DurationMinutes: 180}},
+			now:            at(21).Time,
+			expectedActive: true,
+			expectedNext:   at(23).Time,
+		},
+		{
+			name:        "no window",
+			spec:        humiov1alpha1.HumioAlertSilenceSpec{},
+			expectedErr: true,
+		},
+		{
+			name:        "end before start",
+			spec:        humiov1alpha1.HumioAlertSilenceSpec{Start: at(20), End: at(19)},
+			expectedErr: true,
+		},
+		{
+			name:        "maintenance window and end",
+			spec:        humiov1alpha1.HumioAlertSilenceSpec{End: at(23), MaintenanceWindow: &humiov1alpha1.HumioMaintenanceWindow{Schedule: "0 20 * * *", DurationMinutes: 180}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid schedule",
+			spec:        humiov1alpha1.HumioAlertSilenceSpec{MaintenanceWindow: &humiov1alpha1.HumioMaintenanceWindow{Schedule: "daily", DurationMinutes: 180}},
+			expectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hals := &humiov1alpha1.HumioAlertSilence{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+				Spec:       tt.spec,
+			}
+			active, next, err := alertSilenceWindow(hals, tt.now)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("expected error %t, got %v", tt.expectedErr, err)
+			}
+			if active != tt.expectedActive || !next.Equal(tt.expectedNext) {
+				t.Errorf("expected active %t until %s, got active %t until %s", tt.expectedActive, tt.expectedNext, active, next)
+			}
+		})
+	}
+}
+
+func TestReconcileHumioAlertSilences(t *testing.T) {
+	now := time.Now()
+	start := metav1.NewTime(now.Add(-time.Hour))
+	end := metav1.NewTime(now.Add(time.Hour))
+	later := metav1.NewTime(now.Add(2 * time.Hour))
+	ha := &humiov1alpha1.HumioAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "default", Labels: map[string]string{"team": "ops"}, Finalizers: []string{humioFinalizer}},
+		Spec: humiov1alpha1.HumioAlertSpec{
+			Name:     "alert",
+			ViewName: "web",
+			Query:    humiov1alpha1.HumioQuery{QueryString: "error = true"},
+			Actions:  []string{"slack"},
+		},
+	}
+	silences := []*humiov1alpha1.HumioAlertSilence{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "active", Namespace: "default"},
+			Spec: humiov1alpha1.HumioAlertSilenceSpec{
+				AlertSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}},
+				Start:         &start,
+				End:           &end,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-team", Namespace: "default"},
+			Spec: humiov1alpha1.HumioAlertSilenceSpec{
+				AlertSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+				Start:         &start,
+				End:           &later,
+			},
+		},
+	}
+	scheme := runtime.NewScheme()
+	_ = humiov1alpha1.AddToScheme(scheme)
+	humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+	r := &HumioAlertReconciler{
+		Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(ha, silences[0], silences[1]).WithStatusSubresource(ha).Build(),
+		HumioClient: humioClient,
+		Log:         logr.Discard(),
+	}
+	config := &humioapi.Config{}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "alert"}}
+
+	reconcileAlert := func() (*humiov1alpha1.HumioAlert, reconcile.Result) {
+		var fetched humiov1alpha1.HumioAlert
+		if err := r.Get(context.Background(), req.NamespacedName, &fetched); err != nil {
+			t.Fatal(err)
+		}
+		result, err := r.reconcileHumioAlert(context.Background(), config, &fetched, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &fetched, result
+	}
+
+	// The first reconcile creates the alert, and the second compares it to the existing alert
+	for i := 0; i < 2; i++ {
+		fetched, result := reconcileAlert()
+		if !reflect.DeepEqual(fetched.Status.Silences, []string{"active"}) {
+			t.Errorf("expected silences [active], got %v", fetched.Status.Silences)
+		}
+		if fetched.Spec.Silenced {
+			t.Errorf("expected the spec of the alert to be left alone")
+		}
+		if i == 1 && (result.RequeueAfter <= 0 || result.RequeueAfter > time.Hour) {
+			t.Errorf("expected requeue when the silence ends, got %s", result.RequeueAfter)
+		}
+	}
+	curAlert, err := humioClient.GetAlert(config, req, ha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curAlert.Enabled {
+		t.Errorf("expected alert to be disabled while silenced")
+	}
+
+	if err := r.Delete(context.Background(), silences[0]); err != nil {
+		t.Fatal(err)
+	}
+	fetched, _ := reconcileAlert()
+	if len(fetched.Status.Silences) != 0 {
+		t.Errorf("expected no silences, got %v", fetched.Status.Silences)
+	}
+	curAlert, err = humioClient.GetAlert(config, req, ha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !curAlert.Enabled {
+		t.Errorf("expected alert to be enabled again when the silence is removed")
+	}
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// rotateAdminTokenAnnotation triggers a rotation of the admin API token whenever its value changes
+	rotateAdminTokenAnnotation = "humio.com/rotate-admin-token" // #nosec G101
+	// regenerateAdminTokenAnnotation triggers a regeneration of the admin API token whenever its value changes. Unlike
+	// a rotation, the current API token stops working right away, which is what you want after a suspected leak.
+	regenerateAdminTokenAnnotation = "humio.com/regenerate-admin-token" // #nosec G101
+
+	// adminTokenUsername is the root user created by the auth sidecar, and adminTokenAlternateUsername is the root user
+	// the operator alternates with when rotating the admin API token
+	adminTokenUsername          = "admin"
+	adminTokenAlternateUsername = "admin-alternate"
+
+	// defaultAdminTokenRevocationGracePeriod is how long the previous admin API token keeps working after a rotation,
+	// unless configured otherwise
+	defaultAdminTokenRevocationGracePeriod = time.Minute * 5
+)
+
+// adminTokenRotationDue returns whether the admin API token of the cluster should be rotated according to the rotation
+// interval. Rotation intervals are counted from the creation of the HumioCluster until the admin API token is rotated
+// for the first time.
+func adminTokenRotationDue(hc *humiov1alpha1.HumioCluster, now time.Time) bool {
+	if hc.Spec.AdminTokenRotation == nil || hc.Spec.AdminTokenRotation.IntervalSeconds <= 0 {
+		return false
+	}
+	lastRotation := hc.CreationTimestamp
+	if hc.Status.LastAdminTokenRotationTime != nil {
+		lastRotation = *hc.Status.LastAdminTokenRotationTime
+	}
+	interval := time.Second * time.Duration(hc.Spec.AdminTokenRotation.IntervalSeconds)
+	return !now.Before(lastRotation.Add(interval))
+}
+
+// adminTokenRotationRequested returns the value of the rotate annotation and whether it requests a rotation which has
+// not been carried out yet
+func adminTokenRotationRequested(hc *humiov1alpha1.HumioCluster) (string, bool) {
+	request := hc.Annotations[rotateAdminTokenAnnotation]
+	return request, request != "" && request != hc.Status.LastAdminTokenRotationRequest
+}
+
+// adminTokenRegenerationRequested returns the value of the regenerate annotation and whether it requests a regeneration
+// which has not been carried out yet
+func adminTokenRegenerationRequested(hc *humiov1alpha1.HumioCluster) (string, bool) {
+	request := hc.Annotations[regenerateAdminTokenAnnotation]
+	return request, request != "" && request != hc.Status.LastAdminTokenRegenerationRequest
+}
+
+// adminTokenSecretName returns the name of the secret holding the API token the operator uses to manage the cluster
+func adminTokenSecretName(hc *humiov1alpha1.HumioCluster) string {
+	return fmt.Sprintf("%s-%s", hc.Name, kubernetes.ServiceTokenSecretNameSuffix)
+}
+
+// adminTokenCache holds the last admin API token which was verified to work for each HumioCluster, so the admin token
+// secret can be repaired if it is deleted or emptied. Only the hash of the API token is persisted in the status of the
+// HumioCluster, which is how the API token is picked up again from the secret after the operator restarts. The zero
+// value is ready to use.
+type adminTokenCache struct {
+	mutex  sync.Mutex
+	tokens map[types.NamespacedName]string
+}
+
+func (c *adminTokenCache) get(key types.NamespacedName) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.tokens[key]
+}
+
+func (c *adminTokenCache) set(key types.NamespacedName, token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.tokens == nil {
+		c.tokens = map[types.NamespacedName]string{}
+	}
+	c.tokens[key] = token
+}
+
+func (c *adminTokenCache) delete(key types.NamespacedName) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.tokens, key)
+}
+
+// nextAdminTokenUsername returns the root user which should hold the admin API token after rotating the API token of
+// the given user
+func nextAdminTokenUsername(username string) string {
+	if username == adminTokenAlternateUsername {
+		return adminTokenUsername
+	}
+	return adminTokenAlternateUsername
+}
+
+// adminTokenRevocationGracePeriod returns how long the previous admin API token keeps working after a rotation
+func adminTokenRevocationGracePeriod(hc *humiov1alpha1.HumioCluster) time.Duration {
+	if hc.Spec.AdminTokenRotation == nil || hc.Spec.AdminTokenRotation.RevocationGracePeriodSeconds == 0 {
+		return defaultAdminTokenRevocationGracePeriod
+	}
+	return time.Second * time.Duration(hc.Spec.AdminTokenRotation.RevocationGracePeriodSeconds)
+}
+
+// adminTokenRevocationDue returns whether the grace period of the pending revocation of a previous admin API token has
+// passed
+func adminTokenRevocationDue(hc *humiov1alpha1.HumioCluster, now time.Time) bool {
+	revocation := hc.Status.PendingAdminTokenRevocation
+	return revocation != nil && !now.Before(revocation.RotationTime.Add(adminTokenRevocationGracePeriod(hc)))
+}
+
+// ensureAdminTokenRotated rotates the admin API token when the rotation interval has elapsed or a rotation has been
+// requested using the rotate annotation, and revokes the API token replaced by the previous rotation once its grace
+// period has passed. The given config is updated to use the new API token.
+func (r *HumioClusterReconciler) ensureAdminTokenRotated(ctx context.Context, hc *humiov1alpha1.HumioCluster, config *humioapi.Config, req reconcile.Request) error {
+	now := metav1.Now()
+	if adminTokenRevocationDue(hc, now.Time) {
+		if err := r.revokeAdminToken(hc, config, req); err != nil {
+			return r.logErrorAndReturn(err, "unable to revoke previous admin token")
+		}
+		if _, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withPendingAdminTokenRevocation(nil)); err != nil {
+			return err
+		}
+	}
+
+	request, requested := adminTokenRotationRequested(hc)
+	if !requested && !adminTokenRotationDue(hc, now.Time) {
+		return nil
+	}
+	if requested {
+		r.Log.Info(fmt.Sprintf("rotating admin token as requested by annotation %s=%s", rotateAdminTokenAnnotation, request))
+	}
+
+	previousUsername, err := r.rotateAdminToken(ctx, hc, config, req)
+	if err != nil {
+		return r.logErrorAndReturn(err, "unable to rotate admin token")
+	}
+	// A revocation still pending from an earlier rotation is superseded, as rotating the API token of the user it
+	// belongs to has already revoked the API token
+	r.adminTokens.set(types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name}, config.Token)
+	_, err = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+		withAdminTokenRotation(now, request).
+		withAdminTokenHash(helpers.AsSHA256(config.Token)).
+		withPendingAdminTokenRevocation(&humiov1alpha1.HumioPendingAdminTokenRevocation{
+			Username:     previousUsername,
+			RotationTime: now,
+		}))
+	return err
+}
+
+// rotateAdminToken creates a new API token for the inactive root user, verifies it and stores it in the admin token
+// secret. The API token of the previously active root user is left working, so components still using it keep working
+// until they pick up the new one, and the previously active root user is returned so its API token can be revoked later.
+func (r *HumioClusterReconciler) rotateAdminToken(ctx context.Context, hc *humiov1alpha1.HumioCluster, config *humioapi.Config, req reconcile.Request) (string, error) {
+	username, err := r.HumioClient.TestAPIToken(config, req)
+	if err != nil {
+		return "", fmt.Errorf("unable to authenticate using current admin token: %w", err)
+	}
+	nextUsername := nextAdminTokenUsername(username)
+
+	r.Log.Info(fmt.Sprintf("rotating admin token from user %s to user %s", username, nextUsername))
+	if err = r.HumioClient.EnsureRootUser(config, req, nextUsername); err != nil {
+		return "", fmt.Errorf("unable to ensure root user %s: %w", nextUsername, err)
+	}
+	newToken, err := r.HumioClient.RotateUserAPIToken(config, req, nextUsername)
+	if err != nil {
+		return "", fmt.Errorf("unable to create api token for user %s: %w", nextUsername, err)
+	}
+	newConfig := *config
+	newConfig.Token = newToken
+	if _, err = r.HumioClient.TestAPIToken(&newConfig, req); err != nil {
+		return "", fmt.Errorf("unable to authenticate using new admin token: %w", err)
+	}
+
+	if err = r.storeAdminToken(ctx, hc, newToken); err != nil {
+		return "", err
+	}
+	config.Token = newToken
+	r.Log.Info("successfully rotated admin token")
+	return username, nil
+}
+
+// revokeAdminToken revokes the API token replaced by the previous rotation of the admin API token
+func (r *HumioClusterReconciler) revokeAdminToken(hc *humiov1alpha1.HumioCluster, config *humioapi.Config, req reconcile.Request) error {
+	username := hc.Status.PendingAdminTokenRevocation.Username
+	// Rotating the API token of the previously active user revokes the old API token. The resulting API token is
+	// discarded, so the user is left without a usable API token until it becomes active again.
+	if _, err := r.HumioClient.RotateUserAPIToken(config, req, username); err != nil {
+		return fmt.Errorf("unable to revoke api token of user %s: %w", username, err)
+	}
+	r.Log.Info(fmt.Sprintf("revoked previous admin token of user %s", username))
+	return nil
+}
+
+// ensureAdminTokenRegenerated replaces the admin API token with a new API token for the same user when a regeneration
+// has been requested using the regenerate annotation. The given config is updated to use the new API token.
+func (r *HumioClusterReconciler) ensureAdminTokenRegenerated(ctx context.Context, hc *humiov1alpha1.HumioCluster, config *humioapi.Config, req reconcile.Request) error {
+	request, requested := adminTokenRegenerationRequested(hc)
+	if !requested {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("regenerating admin token as requested by annotation %s=%s", regenerateAdminTokenAnnotation, request))
+
+	username, err := r.HumioClient.TestAPIToken(config, req)
+	if err != nil {
+		return r.logErrorAndReturn(err, "unable to authenticate using current admin token")
+	}
+	// Rotating the API token of the user revokes the current API token right away
+	newToken, err := r.HumioClient.RotateUserAPIToken(config, req, username)
+	if err != nil {
+		return r.logErrorAndReturn(err, fmt.Sprintf("unable to regenerate api token for user %s", username))
+	}
+	// Keep the new API token around, so the admin token secret can be repaired if storing it fails
+	r.adminTokens.set(types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name}, newToken)
+	if err = r.storeAdminToken(ctx, hc, newToken); err != nil {
+		return r.logErrorAndReturn(err, "unable to store regenerated admin token")
+	}
+	config.Token = newToken
+	r.Log.Info("successfully regenerated admin token")
+
+	_, err = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+		withAdminTokenRegeneration(request).
+		withAdminTokenHash(helpers.AsSHA256(newToken)))
+	return err
+}
+
+// rememberAdminToken keeps the admin API token used by the given config, so the admin token secret can be repaired
+// later, and records its hash in the status. The API token is only verified when its hash differs from the one in the
+// status.
+func (r *HumioClusterReconciler) rememberAdminToken(ctx context.Context, hc *humiov1alpha1.HumioCluster, config *humioapi.Config, req reconcile.Request) error {
+	key := types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name}
+	if config.Token == "" || r.adminTokens.get(key) == config.Token {
+		return nil
+	}
+	hash := helpers.AsSHA256(config.Token)
+	if hash == hc.Status.AdminTokenHash {
+		r.adminTokens.set(key, config.Token)
+		return nil
+	}
+	if _, err := r.HumioClient.TestAPIToken(config, req); err != nil {
+		r.Log.Error(err, "unable to verify admin token")
+		return nil
+	}
+	r.adminTokens.set(key, config.Token)
+	_, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withAdminTokenHash(hash))
+	return err
+}
+
+// ensureAdminTokenSecret recreates the admin token secret from the last admin API token known to work, if the secret
+// has been deleted or no longer holds an API token. After the operator restarts, the API token in the secret is known
+// again as soon as it matches the hash in the status. Without a known API token, the secret is left to the auth sidecar.
+func (r *HumioClusterReconciler) ensureAdminTokenSecret(ctx context.Context, hc *humiov1alpha1.HumioCluster) error {
+	key := types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name}
+	adminTokenSecret, err := kubernetes.GetSecret(ctx, r, adminTokenSecretName(hc), hc.Namespace)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return r.logErrorAndReturn(err, "unable to get admin token secret")
+	}
+	if err == nil && len(adminTokenSecret.Data["token"]) > 0 {
+		token := string(adminTokenSecret.Data["token"])
+		if hc.Status.AdminTokenHash != "" && helpers.AsSHA256(token) == hc.Status.AdminTokenHash {
+			r.adminTokens.set(key, token)
+		}
+		return nil
+	}
+
+	token := r.adminTokens.get(key)
+	if token == "" {
+		if hc.Status.AdminTokenHash != "" {
+			r.Log.Info(fmt.Sprintf("unable to repair admin token secret %s as the admin token is not known, leaving it to the auth sidecar", adminTokenSecretName(hc)))
+		}
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("repairing admin token secret %s", adminTokenSecretName(hc)))
+	if err = r.storeAdminToken(ctx, hc, token); err != nil {
+		return r.logErrorAndReturn(err, "unable to repair admin token secret")
+	}
+	return nil
+}
+
+// storeAdminToken writes the given API token to the admin token secret, creating the secret if it does not exist
+func (r *HumioClusterReconciler) storeAdminToken(ctx context.Context, hc *humiov1alpha1.HumioCluster, token string) error {
+	adminTokenSecret, err := kubernetes.GetSecret(ctx, r, adminTokenSecretName(hc), hc.Namespace)
+	if k8serrors.IsNotFound(err) {
+		adminTokenSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      adminTokenSecretName(hc),
+				Namespace: hc.Namespace,
+				Labels:    kubernetes.LabelsForHumio(hc.Name),
+			},
+			Data: map[string][]byte{"token": []byte(token)},
+			Type: corev1.SecretTypeOpaque,
+		}
+		if err = r.Create(ctx, adminTokenSecret, client.FieldOwner(kubernetes.FieldManager)); err != nil {
+			return fmt.Errorf("unable to create admin token secret: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get admin token secret: %w", err)
+	}
+	if adminTokenSecret.Data == nil {
+		adminTokenSecret.Data = map[string][]byte{}
+	}
+	adminTokenSecret.Data["token"] = []byte(token)
+	if err = r.Update(ctx, adminTokenSecret, client.FieldOwner(kubernetes.FieldManager)); err != nil {
+		return fmt.Errorf("unable to update admin token secret: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestAdminTokenRotation(t *testing.T) {
+	now := metav1.Now()
+	hourAgo := metav1.NewTime(now.Add(-time.Hour))
+	minuteAgo := metav1.NewTime(now.Add(-time.Minute))
+	rotation := &humiov1alpha1.HumioAdminTokenRotation{IntervalSeconds: 3600}
+
+	tt := []struct {
+		name          string
+		rotation      *humiov1alpha1.HumioAdminTokenRotation
+		lastRotation  *metav1.Time
+		annotation    string
+		lastRequest   string
+		due           bool
+		requested     bool
+		expectedValue string
+	}{
+		{
+			name: "rotation not configured",
+		},
+		{
+			name:         "rotated within interval",
+			rotation:     rotation,
+			lastRotation: &minuteAgo,
+		},
+		{
+			name:         "rotated before interval",
+			rotation:     rotation,
+			lastRotation: &hourAgo,
+			due:          true,
+		},
+		{
+			name:          "rotation requested",
+			annotation:    "2024-01-01T00:00:00Z",
+			requested:     true,
+			expectedValue: "2024-01-01T00:00:00Z",
+		},
+		{
+			name:          "rotation request already handled",
+			annotation:    "2024-01-01T00:00:00Z",
+			lastRequest:   "2024-01-01T00:00:00Z",
+			expectedValue: "2024-01-01T00:00:00Z",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					CreationTimestamp: hourAgo,
+					Annotations:       map[string]string{rotateAdminTokenAnnotation: tc.annotation},
+				},
+				Spec: humiov1alpha1.HumioClusterSpec{AdminTokenRotation: tc.rotation},
+				Status: humiov1alpha1.HumioClusterStatus{
+					LastAdminTokenRotationTime:    tc.lastRotation,
+					LastAdminTokenRotationRequest: tc.lastRequest,
+				},
+			}
+			if due := adminTokenRotationDue(hc, now.Time); due != tc.due {
+				t.Errorf("adminTokenRotationDue() = %v, want %v", due, tc.due)
+			}
+			if value, requested := adminTokenRotationRequested(hc); requested != tc.requested || value != tc.expectedValue {
+				t.Errorf("adminTokenRotationRequested() = %q, %v, want %q, %v", value, requested, tc.expectedValue, tc.requested)
+			}
+		})
+	}
+
+	if next := nextAdminTokenUsername(adminTokenUsername); next != adminTokenAlternateUsername {
+		t.Errorf("expected rotation from %s to %s, got %s", adminTokenUsername, adminTokenAlternateUsername, next)
+	}
+	if next := nextAdminTokenUsername(adminTokenAlternateUsername); next != adminTokenUsername {
+		t.Errorf("expected rotation from %s to %s, got %s", adminTokenAlternateUsername, adminTokenUsername, next)
+	}
+}
+
+func TestAdminTokenRegeneration(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	ctx := context.Background()
+
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "humiocluster",
+			Namespace:   "logging",
+			Annotations: map[string]string{regenerateAdminTokenAnnotation: "2024-01-01T00:00:00Z"},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster-admin-token", Namespace: "logging"},
+		Data:       map[string][]byte{"token": []byte("leaked")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hc, secret).WithStatusSubresource(hc).Build()
+	r := &HumioClusterReconciler{Client: c, Log: logr.Discard(), HumioClient: humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)}
+	config := &humioapi.Config{Token: "leaked"}
+	secretKey := types.NamespacedName{Namespace: "logging", Name: "humiocluster-admin-token"}
+
+	if err := r.ensureAdminTokenRegenerated(ctx, hc, config, reconcile.Request{}); err != nil {
+		t.Fatalf("ensureAdminTokenRegenerated() error = %v", err)
+	}
+	if config.Token != "mockrotatedtoken" {
+		t.Errorf("expected config to use the regenerated token, got %s", config.Token)
+	}
+	if err := c.Get(ctx, secretKey, secret); err != nil || string(secret.Data["token"]) != "mockrotatedtoken" {
+		t.Errorf("expected secret to hold the regenerated token, got %s, %v", secret.Data["token"], err)
+	}
+
+	stored := &humiov1alpha1.HumioCluster{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "logging", Name: "humiocluster"}, stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status.LastAdminTokenRegenerationRequest != "2024-01-01T00:00:00Z" {
+		t.Errorf("expected regeneration request to be recorded in the status, got %q", stored.Status.LastAdminTokenRegenerationRequest)
+	}
+	if stored.Status.AdminTokenHash != helpers.AsSHA256("mockrotatedtoken") {
+		t.Errorf("expected the status to hold the hash of the regenerated token, got %q", stored.Status.AdminTokenHash)
+	}
+
+	// A request which has been handled does not regenerate the token again
+	config.Token = "current"
+	if err := r.ensureAdminTokenRegenerated(ctx, stored, config, reconcile.Request{}); err != nil {
+		t.Fatalf("ensureAdminTokenRegenerated() error = %v", err)
+	}
+	if config.Token != "current" {
+		t.Errorf("expected handled regeneration request not to replace the token, got %s", config.Token)
+	}
+}
+
+func TestAdminTokenSecretRepairAfterRestart(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	ctx := context.Background()
+	secretKey := types.NamespacedName{Namespace: "logging", Name: "humiocluster-admin-token"}
+
+	tt := []struct {
+		name           string
+		adminTokenHash string
+		repaired       bool
+	}{
+		{
+			name:           "token in secret matches the hash in the status",
+			adminTokenHash: helpers.AsSHA256("token"),
+			repaired:       true,
+		},
+		{
+			name:           "token in secret was not verified by the operator",
+			adminTokenHash: helpers.AsSHA256("other"),
+		},
+		{
+			name: "no hash in the status",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+				Status:     humiov1alpha1.HumioClusterStatus{AdminTokenHash: tc.adminTokenHash},
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "humiocluster-admin-token", Namespace: "logging"},
+				Data:       map[string][]byte{"token": []byte("token")},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hc, secret).WithStatusSubresource(hc).Build()
+			// A new reconciler starts out without any known admin tokens, like after a restart of the operator
+			r := &HumioClusterReconciler{Client: c, Log: logr.Discard(), HumioClient: humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)}
+
+			if err := r.ensureAdminTokenSecret(ctx, hc); err != nil {
+				t.Fatalf("ensureAdminTokenSecret() error = %v", err)
+			}
+			if err := c.Delete(ctx, secret); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.ensureAdminTokenSecret(ctx, hc); err != nil {
+				t.Fatalf("ensureAdminTokenSecret() error = %v", err)
+			}
+
+			repaired := &corev1.Secret{}
+			err := c.Get(ctx, secretKey, repaired)
+			if tc.repaired && (err != nil || string(repaired.Data["token"]) != "token") {
+				t.Errorf("expected secret to be recreated with the known token, got %s, %v", repaired.Data["token"], err)
+			}
+			if !tc.repaired && !k8serrors.IsNotFound(err) {
+				t.Errorf("expected secret to be left to the auth sidecar, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRememberAdminToken(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	ctx := context.Background()
+
+	hc := &humiov1alpha1.HumioCluster{ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hc).WithStatusSubresource(hc).Build()
+	r := &HumioClusterReconciler{Client: c, Log: logr.Discard(), HumioClient: humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)}
+
+	if err := r.rememberAdminToken(ctx, hc, &humioapi.Config{Token: "token"}, reconcile.Request{}); err != nil {
+		t.Fatalf("rememberAdminToken() error = %v", err)
+	}
+	if hc.Status.AdminTokenHash != helpers.AsSHA256("token") {
+		t.Errorf("expected the status to hold the hash of the verified token, got %q", hc.Status.AdminTokenHash)
+	}
+	if token := r.adminTokens.get(types.NamespacedName{Namespace: "logging", Name: "humiocluster"}); token != "token" {
+		t.Errorf("expected the verified token to be known, got %q", token)
+	}
+}
+
+func TestAdminTokenRevocationAfterGracePeriod(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	ctx := context.Background()
+
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "humiocluster",
+			Namespace:   "logging",
+			Annotations: map[string]string{rotateAdminTokenAnnotation: "2024-01-01T00:00:00Z"},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster-admin-token", Namespace: "logging"},
+		Data:       map[string][]byte{"token": []byte("token")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hc, secret).WithStatusSubresource(hc).Build()
+	r := &HumioClusterReconciler{Client: c, Log: logr.Discard(), HumioClient: humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)}
+	config := &humioapi.Config{Token: "token"}
+
+	if err := r.ensureAdminTokenRotated(ctx, hc, config, reconcile.Request{}); err != nil {
+		t.Fatalf("ensureAdminTokenRotated() error = %v", err)
+	}
+	revocation := hc.Status.PendingAdminTokenRevocation
+	if revocation == nil || revocation.Username != "mockuser" {
+		t.Fatalf("expected revocation of the token of mockuser to be pending, got %+v", revocation)
+	}
+	if config.Token != "mockrotatedtoken" {
+		t.Errorf("expected config to use the rotated token, got %s", config.Token)
+	}
+
+	if err := r.ensureAdminTokenRotated(ctx, hc, config, reconcile.Request{}); err != nil {
+		t.Fatalf("ensureAdminTokenRotated() error = %v", err)
+	}
+	if hc.Status.PendingAdminTokenRevocation == nil {
+		t.Fatal("expected revocation to wait for the grace period")
+	}
+
+	hc.Status.PendingAdminTokenRevocation.RotationTime = metav1.NewTime(time.Now().Add(-defaultAdminTokenRevocationGracePeriod))
+	if err := r.ensureAdminTokenRotated(ctx, hc, config, reconcile.Request{}); err != nil {
+		t.Fatalf("ensureAdminTokenRotated() error = %v", err)
+	}
+	if hc.Status.PendingAdminTokenRevocation != nil {
+		t.Errorf("expected previous token to be revoked after the grace period, got %+v", hc.Status.PendingAdminTokenRevocation)
+	}
+}
@@ -31,11 +31,14 @@ import (
 )
 
 const (
-	certHashAnnotation         = "humio.com/certificate-hash"
-	podHashAnnotation          = "humio.com/pod-hash"
-	PodRevisionAnnotation      = "humio.com/pod-revision"
-	envVarSourceHashAnnotation = "humio.com/env-var-source-hash"
-	pvcHashAnnotation          = "humio_pvc_hash"
+	certHashAnnotation             = "humio.com/certificate-hash"
+	podHashAnnotation              = "humio.com/pod-hash"
+	PodRevisionAnnotation          = "humio.com/pod-revision"
+	envVarSourceHashAnnotation     = "humio.com/env-var-source-hash"
+	extraConfigFilesHashAnnotation = "humio.com/extra-config-files-hash"
+	secretsStoreHashAnnotation     = "humio.com/secrets-store-hash"
+	envVarSecretsHashAnnotation    = "humio.com/env-var-secrets-hash"
+	pvcHashAnnotation              = "humio_pvc_hash"
 )
 
 func (r *HumioClusterReconciler) incrementHumioClusterPodRevision(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool) (int, error) {
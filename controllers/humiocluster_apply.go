@@ -0,0 +1,40 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyConflictReason is the reason of the events emitted when the operator cannot apply an object because another
+// field manager owns fields the operator sets
+const applyConflictReason = "ApplyConflict"
+
+// apply creates or updates the object owned by the cluster using server-side apply. Conflicts with fields managed by
+// users or other controllers are reported as Warning events on the cluster, as they require someone to resolve them.
+func (r *HumioClusterReconciler) apply(ctx context.Context, hc *humiov1alpha1.HumioCluster, obj client.Object) error {
+	err := kubernetes.Apply(ctx, r, obj)
+	if kubernetes.IsApplyConflict(err) && r.Recorder != nil {
+		r.Recorder.Event(hc, corev1.EventTypeWarning, applyConflictReason, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	authMigrationDefaultVerificationTimeoutSeconds = 1800
+
+	authenticationMethodOAuth = "oauth"
+	authenticationMethodSAML  = "saml"
+)
+
+// authMigrationHTTPClient is used to verify the identity provider can be reached during an auth migration
+var authMigrationHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// authMigrationSpecHash returns the hash of the auth migration, which is used to detect when a new migration is started
+func authMigrationSpecHash(authMigration *humiov1alpha1.HumioAuthMigration) string {
+	return helpers.AsSHA256(authMigration)
+}
+
+// authMigrationInProgressOrCompleted returns whether the auth migration of the cluster has been started and not rolled
+// back. The settings of the auth migration are applied to the pods in that case.
+func authMigrationInProgressOrCompleted(hc *humiov1alpha1.HumioCluster) bool {
+	status := hc.Status.AuthMigration
+	if hc.Spec.AuthMigration == nil || status == nil || status.SpecHash != authMigrationSpecHash(hc.Spec.AuthMigration) {
+		return false
+	}
+	return status.State == humiov1alpha1.HumioAuthMigrationStateMigrating || status.State == humiov1alpha1.HumioAuthMigrationStateCompleted
+}
+
+// authMigrationEnvironmentVariables returns the environment variables which apply the auth migration of the cluster,
+// or nil if the settings of the auth migration should not be applied
+func authMigrationEnvironmentVariables(hc *humiov1alpha1.HumioCluster) []corev1.EnvVar {
+	if !authMigrationInProgressOrCompleted(hc) {
+		return nil
+	}
+	envVars := []corev1.EnvVar{
+		{Name: "AUTHENTICATION_METHOD", Value: hc.Spec.AuthMigration.AuthenticationMethod},
+	}
+	for _, envVar := range hc.Spec.AuthMigration.EnvironmentVariables {
+		envVars = AppendEnvVarToEnvVarsIfNotAlreadyPresent(envVars, envVar)
+	}
+	return envVars
+}
+
+// authMigrationTimedOut returns whether the auth migration of the cluster has not been verified within the
+// verification timeout
+func authMigrationTimedOut(hc *humiov1alpha1.HumioCluster, now time.Time) bool {
+	status := hc.Status.AuthMigration
+	if !authMigrationInProgressOrCompleted(hc) || status.State != humiov1alpha1.HumioAuthMigrationStateMigrating || status.StartTime == nil {
+		return false
+	}
+	timeoutSeconds := hc.Spec.AuthMigration.VerificationTimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = authMigrationDefaultVerificationTimeoutSeconds
+	}
+	return !now.Before(status.StartTime.Add(time.Second * time.Duration(timeoutSeconds)))
+}
+
+// ensureTimedOutAuthMigrationIsRolledBack rolls back the auth migration of the cluster if it has not been verified
+// within the verification timeout. This removes the settings of the auth migration from the pods, so the pods are
+// restarted with the previous authentication method.
+func (r *HumioClusterReconciler) ensureTimedOutAuthMigrationIsRolledBack(ctx context.Context, hc *humiov1alpha1.HumioCluster) error {
+	if !authMigrationTimedOut(hc, time.Now()) {
+		return nil
+	}
+	status := *hc.Status.AuthMigration
+	r.Log.Info(fmt.Sprintf("rolling back migration to authentication method %s as it could not be verified in time: %s", status.AuthenticationMethod, status.Message))
+	status.State = humiov1alpha1.HumioAuthMigrationStateRolledBack
+	status.Message = fmt.Sprintf("migration could not be verified in time: %s", status.Message)
+	_, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withAuthMigration(&status))
+	return err
+}
+
+// ensureAuthMigration drives the auth migration of the cluster. It creates the break-glass users and starts the
+// migration, and once all pods have been restarted with the new authentication method, it verifies the migration.
+func (r *HumioClusterReconciler) ensureAuthMigration(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnps []*HumioNodePool, config *humioapi.Config, req reconcile.Request) error {
+	if hc.Spec.AuthMigration == nil {
+		if hc.Status.AuthMigration != nil {
+			_, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withAuthMigration(nil))
+			return err
+		}
+		return nil
+	}
+
+	authMigration := hc.Spec.AuthMigration
+	specHash := authMigrationSpecHash(authMigration)
+	if hc.Status.AuthMigration == nil || hc.Status.AuthMigration.SpecHash != specHash {
+		for _, username := range authMigration.BreakGlassUsers {
+			if err := r.HumioClient.EnsureRootUser(config, req, username); err != nil {
+				return r.logErrorAndReturn(err, fmt.Sprintf("unable to create break-glass user %s", username))
+			}
+		}
+		r.Log.Info(fmt.Sprintf("starting migration to authentication method %s", authMigration.AuthenticationMethod))
+		now := metav1.Now()
+		_, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withAuthMigration(&humiov1alpha1.HumioAuthMigrationStatus{
+			State:                humiov1alpha1.HumioAuthMigrationStateMigrating,
+			Message:              "waiting for pods to be restarted with the new authentication method",
+			AuthenticationMethod: authMigration.AuthenticationMethod,
+			SpecHash:             specHash,
+			StartTime:            &now,
+		}))
+		return err
+	}
+
+	status := *hc.Status.AuthMigration
+	if status.State != humiov1alpha1.HumioAuthMigrationStateMigrating {
+		return nil
+	}
+
+	restarted, err := r.authMigrationPodsRestarted(ctx, hnps, authMigration.AuthenticationMethod)
+	if err != nil {
+		return r.logErrorAndReturn(err, "unable to check if pods use the new authentication method")
+	}
+	if !restarted {
+		return nil
+	}
+
+	if err = r.verifyAuthMigration(ctx, authMigration, config, req); err != nil {
+		r.Log.Info(fmt.Sprintf("migration to authentication method %s not verified yet: %s", authMigration.AuthenticationMethod, err))
+		status.Message = err.Error()
+	} else {
+		r.Log.Info(fmt.Sprintf("migration to authentication method %s completed", authMigration.AuthenticationMethod))
+		status.State = humiov1alpha1.HumioAuthMigrationStateCompleted
+		status.Message = ""
+	}
+	_, err = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withAuthMigration(&status))
+	return err
+}
+
+// authMigrationPodsRestarted returns whether all pods of the node pools run with the given authentication method
+func (r *HumioClusterReconciler) authMigrationPodsRestarted(ctx context.Context, hnps []*HumioNodePool, authenticationMethod string) (bool, error) {
+	for _, hnp := range hnps {
+		pods, err := kubernetes.ListPods(ctx, r, hnp.GetNamespace(), hnp.GetNodePoolLabels())
+		if err != nil {
+			return false, err
+		}
+		for idx := range pods {
+			humioIdx, err := kubernetes.GetContainerIndexByName(pods[idx], HumioContainerName)
+			if err != nil {
+				return false, err
+			}
+			if !EnvVarHasValue(pods[idx].Spec.Containers[humioIdx].Env, "AUTHENTICATION_METHOD", authenticationMethod) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// verifyAuthMigration verifies the operator can still manage the cluster using its API token, and that the identity
+// provider users log in through can be reached
+func (r *HumioClusterReconciler) verifyAuthMigration(ctx context.Context, authMigration *humiov1alpha1.HumioAuthMigration, config *humioapi.Config, req reconcile.Request) error {
+	if _, err := r.HumioClient.TestAPIToken(config, req); err != nil {
+		return fmt.Errorf("unable to authenticate using admin token: %w", err)
+	}
+	return verifyIdentityProvider(ctx, authMigration)
+}
+
+// verifyIdentityProvider checks the identity provider configured by the auth migration responds. For OIDC, the
+// discovery document of the provider must be available unless the endpoints are configured explicitly. For SAML, the
+// sign-on URL of the identity provider must respond.
+func verifyIdentityProvider(ctx context.Context, authMigration *humiov1alpha1.HumioAuthMigration) error {
+	var verificationURL string
+	discovery := false
+	switch authMigration.AuthenticationMethod {
+	case authenticationMethodOAuth:
+		if provider := EnvVarValue(authMigration.EnvironmentVariables, "OIDC_PROVIDER"); provider != "" {
+			verificationURL = strings.TrimSuffix(provider, "/") + "/.well-known/openid-configuration"
+			discovery = true
+		} else {
+			verificationURL = EnvVarValue(authMigration.EnvironmentVariables, "OIDC_AUTHORIZATION_ENDPOINT")
+		}
+	case authenticationMethodSAML:
+		verificationURL = EnvVarValue(authMigration.EnvironmentVariables, "SAML_IDP_SIGN_ON_URL")
+	}
+	if verificationURL == "" {
+		return fmt.Errorf("unable to determine the url of the identity provider for authentication method %s", authMigration.AuthenticationMethod)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, verificationURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid identity provider url %s: %w", verificationURL, err)
+	}
+	resp, err := authMigrationHTTPClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("unable to reach identity provider at %s: %w", verificationURL, err)
+	}
+	defer resp.Body.Close()
+
+	// Sign-on and authorization endpoints commonly reject requests without the parameters of a login flow, so only
+	// server errors are treated as failures for those
+	if resp.StatusCode >= http.StatusInternalServerError || (discovery && resp.StatusCode >= http.StatusBadRequest) {
+		return fmt.Errorf("identity provider at %s responded with status %d", verificationURL, resp.StatusCode)
+	}
+	return nil
+}
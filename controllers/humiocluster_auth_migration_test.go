@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newAuthMigrationTestCluster(state string, startTime time.Time) *humiov1alpha1.HumioCluster {
+	authMigration := &humiov1alpha1.HumioAuthMigration{
+		AuthenticationMethod: authenticationMethodOAuth,
+		EnvironmentVariables: []corev1.EnvVar{
+			{Name: "OIDC_PROVIDER", Value: "https://idp.example.com"},
+			{Name: "AUTHENTICATION_METHOD", Value: "single-user"},
+		},
+		VerificationTimeoutSeconds: 600,
+	}
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+		Spec: humiov1alpha1.HumioClusterSpec{
+			AuthMigration: authMigration,
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				EnvironmentVariables: []corev1.EnvVar{
+					{Name: "AUTHENTICATION_METHOD", Value: "single-user"},
+				},
+			},
+		},
+	}
+	if state != "" {
+		start := metav1.NewTime(startTime)
+		hc.Status.AuthMigration = &humiov1alpha1.HumioAuthMigrationStatus{
+			State:     state,
+			SpecHash:  authMigrationSpecHash(authMigration),
+			StartTime: &start,
+		}
+	}
+	return hc
+}
+
+func TestAuthMigrationEnvironmentVariables(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name                 string
+		state                string
+		changeSpec           bool
+		expectedAuthMethod   string
+		expectedOIDCProvider string
+	}{
+		{name: "not started", expectedAuthMethod: "single-user"},
+		{name: "migrating", state: humiov1alpha1.HumioAuthMigrationStateMigrating, expectedAuthMethod: authenticationMethodOAuth, expectedOIDCProvider: "https://idp.example.com"},
+		{name: "completed", state: humiov1alpha1.HumioAuthMigrationStateCompleted, expectedAuthMethod: authenticationMethodOAuth, expectedOIDCProvider: "https://idp.example.com"},
+		{name: "rolled back", state: humiov1alpha1.HumioAuthMigrationStateRolledBack, expectedAuthMethod: "single-user"},
+		{name: "spec changed after migration started", state: humiov1alpha1.HumioAuthMigrationStateMigrating, changeSpec: true, expectedAuthMethod: "single-user"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := newAuthMigrationTestCluster(tc.state, now)
+			if tc.changeSpec {
+				hc.Spec.AuthMigration.BreakGlassUsers = []string{"alice"}
+			}
+			envVars := NewHumioNodeManagerFromHumioCluster(hc).GetEnvironmentVariables()
+			if value := EnvVarValue(envVars, "AUTHENTICATION_METHOD"); value != tc.expectedAuthMethod {
+				t.Errorf("expected AUTHENTICATION_METHOD %q, got %q", tc.expectedAuthMethod, value)
+			}
+			if value := EnvVarValue(envVars, "OIDC_PROVIDER"); value != tc.expectedOIDCProvider {
+				t.Errorf("expected OIDC_PROVIDER %q, got %q", tc.expectedOIDCProvider, value)
+			}
+		})
+	}
+}
+
+func TestAuthMigrationTimedOut(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name      string
+		state     string
+		startTime time.Time
+		timedOut  bool
+	}{
+		{name: "not started", startTime: now.Add(-time.Hour)},
+		{name: "migrating within timeout", state: humiov1alpha1.HumioAuthMigrationStateMigrating, startTime: now.Add(-time.Minute)},
+		{name: "migrating after timeout", state: humiov1alpha1.HumioAuthMigrationStateMigrating, startTime: now.Add(-time.Hour), timedOut: true},
+		{name: "completed after timeout", state: humiov1alpha1.HumioAuthMigrationStateCompleted, startTime: now.Add(-time.Hour)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := newAuthMigrationTestCluster(tc.state, tc.startTime)
+			if timedOut := authMigrationTimedOut(hc, now); timedOut != tc.timedOut {
+				t.Errorf("authMigrationTimedOut() = %v, want %v", timedOut, tc.timedOut)
+			}
+		})
+	}
+}
+
+func TestVerifyIdentityProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthy/.well-known/openid-configuration":
+			w.WriteHeader(http.StatusOK)
+		case "/saml/sso":
+			w.WriteHeader(http.StatusBadRequest)
+		case "/broken/sso":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		name                 string
+		authenticationMethod string
+		envVars              []corev1.EnvVar
+		expectedErr          bool
+	}{
+		{name: "oidc discovery available", authenticationMethod: authenticationMethodOAuth, envVars: []corev1.EnvVar{{Name: "OIDC_PROVIDER", Value: server.URL + "/healthy/"}}},
+		{name: "oidc discovery missing", authenticationMethod: authenticationMethodOAuth, envVars: []corev1.EnvVar{{Name: "OIDC_PROVIDER", Value: server.URL + "/missing"}}, expectedErr: true},
+		{name: "saml sign-on rejects request without login flow", authenticationMethod: authenticationMethodSAML, envVars: []corev1.EnvVar{{Name: "SAML_IDP_SIGN_ON_URL", Value: server.URL + "/saml/sso"}}},
+		{name: "saml sign-on failing", authenticationMethod: authenticationMethodSAML, envVars: []corev1.EnvVar{{Name: "SAML_IDP_SIGN_ON_URL", Value: server.URL + "/broken/sso"}}, expectedErr: true},
+		{name: "no identity provider url", authenticationMethod: authenticationMethodSAML, expectedErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyIdentityProvider(context.Background(), &humiov1alpha1.HumioAuthMigration{
+				AuthenticationMethod: tc.authenticationMethod,
+				EnvironmentVariables: tc.envVars,
+			})
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %t, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
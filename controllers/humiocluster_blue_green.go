@@ -0,0 +1,259 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// podConditionTypeBlueGreenTraffic is the readiness gate of pods created during a blue/green update. Pods with the
+	// readiness gate are not added to the endpoints of the node pool service until the operator has validated them
+	// and set the condition.
+	podConditionTypeBlueGreenTraffic corev1.PodConditionType = "humio.com/blue-green-traffic"
+
+	blueGreenDefaultValidationRepository = "humio"
+	blueGreenDefaultValidationQuery      = "count()"
+
+	// blueGreenRequeue is how often we check on the progress of a blue/green update
+	blueGreenRequeue = time.Second * 10
+)
+
+// ensureValidUpdateStrategy validates that the node pool has what the BlueGreen update strategy relies on. The new
+// pods must be able to serve all data of the existing pods, so bucket storage is required, and the disks of the
+// existing pods are discarded when they are decommissioned, so ephemeral disks are required.
+func (r *HumioClusterReconciler) ensureValidUpdateStrategy(hnp *HumioNodePool) error {
+	if hnp.GetNodeCount() <= 0 || hnp.GetUpdateStrategy().Type != humiov1alpha1.HumioClusterUpdateStrategyBlueGreen {
+		return nil
+	}
+	if nodePoolBucketStorage(hnp) == "" {
+		return r.logErrorAndReturn(fmt.Errorf("the %s update strategy requires bucket storage to be configured",
+			humiov1alpha1.HumioClusterUpdateStrategyBlueGreen), "invalid update strategy")
+	}
+	if !EnvVarHasValue(hnp.GetEnvironmentVariables(), "USING_EPHEMERAL_DISKS", "true") {
+		return r.logErrorAndReturn(fmt.Errorf("the %s update strategy requires USING_EPHEMERAL_DISKS=true",
+			humiov1alpha1.HumioClusterUpdateStrategyBlueGreen), "invalid update strategy")
+	}
+	return nil
+}
+
+// ensureBlueGreenUpdate replaces the pods of the node pool using the BlueGreen update strategy. Pods which do not run
+// the current pod revision are the blue pods, and pods running the current pod revision are the green pods. Green pods
+// are created next to the blue pods, and each green pod is validated before traffic is shifted to it. Once all green
+// pods receive traffic, the blue pods are deleted. If validation fails, the blue pods keep serving traffic.
+func (r *HumioClusterReconciler) ensureBlueGreenUpdate(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool, foundPodList []corev1.Pod) (reconcile.Result, error) {
+	bluePods, greenPods := splitBlueGreenPods(hnp, foundPodList)
+	r.Log.Info(fmt.Sprintf("blue/green update in progress, found %d blue pods and %d green pods", len(bluePods), len(greenPods)))
+
+	if len(greenPods) < hnp.GetNodeCount() {
+		return r.ensureGreenPodsExist(ctx, hc, hnp, foundPodList, len(bluePods), len(greenPods))
+	}
+
+	for _, pod := range greenPods {
+		if !podConditionIsTrue(pod, corev1.ContainersReady) {
+			return r.blueGreenUpdateProgress(ctx, hc, fmt.Sprintf("waiting for new pod %s to become ready", pod.Name))
+		}
+	}
+
+	var trafficShifted bool
+	for idx, pod := range greenPods {
+		if podConditionIsTrue(pod, podConditionTypeBlueGreenTraffic) {
+			continue
+		}
+		if err := r.validateGreenPod(ctx, hc, hnp, pod); err != nil {
+			return r.blueGreenUpdateProgress(ctx, hc, fmt.Sprintf("validation of new pod %s failed, existing pods "+
+				"keep serving traffic: %s", pod.Name, err))
+		}
+		r.Log.Info(fmt.Sprintf("new pod %s passed validation, shifting traffic to it", pod.Name))
+		if err := r.setBlueGreenTrafficCondition(ctx, &greenPods[idx]); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, fmt.Sprintf("could not shift traffic to pod %s", pod.Name))
+		}
+		trafficShifted = true
+	}
+	if trafficShifted {
+		return r.blueGreenUpdateProgress(ctx, hc, "shifted traffic to new pods")
+	}
+
+	for _, pod := range greenPods {
+		if !podConditionIsTrue(pod, corev1.PodReady) {
+			return r.blueGreenUpdateProgress(ctx, hc, fmt.Sprintf("waiting for new pod %s to receive traffic", pod.Name))
+		}
+	}
+
+	for idx, pod := range bluePods {
+		r.Log.Info(fmt.Sprintf("decommissioning pod %s", pod.Name))
+		if err := r.Delete(ctx, &bluePods[idx]); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, fmt.Sprintf("could not delete pod %s", pod.Name))
+		}
+		if err := r.deletePodPersistentVolumeClaim(ctx, hnp, pod); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{RequeueAfter: time.Second + 1}, nil
+}
+
+// ensureGreenPodsExist creates the missing green pods. The green pods need their own disks and node certificates, as
+// the blue pods keep theirs until they are decommissioned.
+func (r *HumioClusterReconciler) ensureGreenPodsExist(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool, foundPodList []corev1.Pod, blueCount, greenCount int) (reconcile.Result, error) {
+	expectedCount := blueCount + hnp.GetNodeCount()
+	if err := r.ensureHumioNodeCertificateCount(ctx, hc, hnp, expectedCount); err != nil {
+		return reconcile.Result{}, err
+	}
+	for i := greenCount; i < hnp.GetNodeCount(); i++ {
+		if err := r.ensurePersistentVolumeClaimCount(ctx, hc, hnp, expectedCount); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	var newPods []corev1.Pod
+	pvcClaimNamesInUse := make(map[string]struct{})
+	dataNodesInUse := make(map[string]struct{})
+	for i := greenCount; i < hnp.GetNodeCount(); i++ {
+		attachments, err := r.newPodAttachments(ctx, hnp, foundPodList, pvcClaimNamesInUse, dataNodesInUse)
+		if err != nil {
+			return reconcile.Result{RequeueAfter: time.Second * 5}, r.logErrorAndReturn(err, "failed to get pod attachments")
+		}
+		attachments.readinessGates = []corev1.PodReadinessGate{{ConditionType: podConditionTypeBlueGreenTraffic}}
+		pod, err := r.createPod(ctx, hc, hnp, attachments, newPods)
+		if err != nil {
+			return reconcile.Result{RequeueAfter: time.Second * 5}, r.logErrorAndReturn(err, "unable to create pod")
+		}
+		newPods = append(newPods, *pod)
+		humioClusterPrometheusMetrics.Counters.PodsCreated.Inc()
+	}
+
+	if err := r.waitForNewPods(ctx, hnp, foundPodList, newPods); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "failed to validate new pod")
+	}
+	return r.blueGreenUpdateProgress(ctx, hc, fmt.Sprintf("created %d new pods", len(newPods)))
+}
+
+// validateGreenPod checks the health of a green pod and that it is able to serve searches. The pod is contacted
+// directly, as it does not receive traffic from the node pool service yet.
+func (r *HumioClusterReconciler) validateGreenPod(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool, pod corev1.Pod) error {
+	cluster, err := helpers.NewCluster(ctx, r, hc.Name, "", hc.Namespace, helpers.UseCertManager(), true)
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		return fmt.Errorf("unable to obtain humio client config: %w", err)
+	}
+	config := *cluster.Config()
+	podURL := *config.Address
+	podURL.Host = fmt.Sprintf("%s.%s.%s:%d", pod.Name, headlessServiceName(hc.Name), hc.Namespace, HumioPort)
+	config.Address = &podURL
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name}}
+	status, err := r.HumioClient.Status(&config, req)
+	if err != nil {
+		return fmt.Errorf("unable to get status: %w", err)
+	}
+	if status.IsDown() {
+		return fmt.Errorf("pod reports status %s", status.Status)
+	}
+	desiredVersion, err := HumioVersionFromString(hnp.GetImage())
+	if err != nil {
+		return err
+	}
+	if !desiredVersion.IsLatest() && !strings.HasPrefix(status.Version, desiredVersion.SemVer().String()) {
+		return fmt.Errorf("pod reports version %s, expected %s", status.Version, desiredVersion.SemVer().String())
+	}
+
+	repository, query := hnp.GetBlueGreenValidationQuery()
+	if err = r.HumioClient.TestQuery(&config, req, repository, query); err != nil {
+		return fmt.Errorf("validation query failed: %w", err)
+	}
+	return nil
+}
+
+// setBlueGreenTrafficCondition marks the pod as validated, which makes it ready and adds it to the endpoints of the
+// node pool service
+func (r *HumioClusterReconciler) setBlueGreenTrafficCondition(ctx context.Context, pod *corev1.Pod) error {
+	pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{
+		Type:               podConditionTypeBlueGreenTraffic,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "Validated",
+		Message:            "pod passed blue/green validation",
+	})
+	return r.Status().Update(ctx, pod)
+}
+
+// deletePodPersistentVolumeClaim deletes the pvc used by a decommissioned pod, if any
+func (r *HumioClusterReconciler) deletePodPersistentVolumeClaim(ctx context.Context, hnp *HumioNodePool, pod corev1.Pod) error {
+	if !hnp.PVCsEnabled() {
+		return nil
+	}
+	pvcList, err := r.pvcList(ctx, hnp)
+	if err != nil {
+		return r.logErrorAndReturn(err, "failed to list pvcs")
+	}
+	pvc, err := FindPvcForPod(pvcList, pod)
+	if err != nil {
+		// the pod did not use a pvc
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("deleting pvc %s of decommissioned pod %s", pvc.Name, pod.Name))
+	if err = r.Delete(ctx, &pvc); err != nil {
+		return r.logErrorAndReturn(err, fmt.Sprintf("could not delete pvc %s", pvc.Name))
+	}
+	return nil
+}
+
+func (r *HumioClusterReconciler) blueGreenUpdateProgress(ctx context.Context, hc *humiov1alpha1.HumioCluster, msg string) (reconcile.Result, error) {
+	r.Log.Info(msg)
+	if _, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+		withMessage(msg)); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: blueGreenRequeue}, nil
+}
+
+// splitBlueGreenPods splits the pods of a node pool into the pods running a previous pod revision and the pods running
+// the current pod revision. Pods being deleted are ignored.
+func splitBlueGreenPods(hnp *HumioNodePool, pods []corev1.Pod) ([]corev1.Pod, []corev1.Pod) {
+	_, revision := hnp.GetHumioClusterNodePoolRevisionAnnotation()
+	var bluePods, greenPods []corev1.Pod
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Annotations[PodRevisionAnnotation] == strconv.Itoa(revision) {
+			greenPods = append(greenPods, pod)
+		} else {
+			bluePods = append(bluePods, pod)
+		}
+	}
+	return bluePods, greenPods
+}
+
+func podConditionIsTrue(pod corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSplitBlueGreenPods(t *testing.T) {
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "default"},
+	}
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+	hnp.SetHumioClusterNodePoolRevisionAnnotation(2)
+
+	pod := func(name, revision string, deleting bool) corev1.Pod {
+		p := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{PodRevisionAnnotation: revision},
+		}}
+		if deleting {
+			now := metav1.Now()
+			p.DeletionTimestamp = &now
+		}
+		return p
+	}
+	pods := []corev1.Pod{
+		pod("blue-1", "1", false),
+		pod("green-1", "2", false),
+		pod("blue-2", "1", false),
+		pod("blue-3", "1", true),
+		pod("green-2", "2", false),
+	}
+
+	bluePods, greenPods := splitBlueGreenPods(hnp, pods)
+	if got := podNames(bluePods); got != "blue-1,blue-2" {
+		t.Errorf("splitBlueGreenPods() blue = %s, want blue-1,blue-2", got)
+	}
+	if got := podNames(greenPods); got != "green-1,green-2" {
+		t.Errorf("splitBlueGreenPods() green = %s, want green-1,green-2", got)
+	}
+}
+
+func TestPodSpecAsSHA256IgnoresReadinessGates(t *testing.T) {
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "default"},
+	}
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+	pod, err := ConstructPod(hnp, "humiocluster-core-abcdef", &podAttachments{})
+	if err != nil {
+		t.Fatalf("ConstructPod() error = %v", err)
+	}
+	gatedPod, err := ConstructPod(hnp, "humiocluster-core-abcdef", &podAttachments{
+		readinessGates: []corev1.PodReadinessGate{{ConditionType: podConditionTypeBlueGreenTraffic}},
+	})
+	if err != nil {
+		t.Fatalf("ConstructPod() error = %v", err)
+	}
+	if len(gatedPod.Spec.ReadinessGates) != 1 {
+		t.Fatalf("ConstructPod() readiness gates = %v, want the blue/green traffic gate", gatedPod.Spec.ReadinessGates)
+	}
+	if podSpecAsSHA256(hnp, *pod) != podSpecAsSHA256(hnp, *gatedPod) {
+		t.Errorf("podSpecAsSHA256() differs for pods which only differ in readiness gates")
+	}
+}
+
+func TestGetBlueGreenValidationQuery(t *testing.T) {
+	tests := []struct {
+		name           string
+		updateStrategy *humiov1alpha1.HumioUpdateStrategy
+		wantRepository string
+		wantQuery      string
+	}{
+		{
+			name:           "defaults",
+			updateStrategy: &humiov1alpha1.HumioUpdateStrategy{Type: humiov1alpha1.HumioClusterUpdateStrategyBlueGreen},
+			wantRepository: "humio",
+			wantQuery:      "count()",
+		},
+		{
+			name: "custom query",
+			updateStrategy: &humiov1alpha1.HumioUpdateStrategy{
+				Type: humiov1alpha1.HumioClusterUpdateStrategyBlueGreen,
+				BlueGreen: &humiov1alpha1.HumioUpdateStrategyBlueGreen{
+					ValidationRepository: "audit",
+					ValidationQuery:      "#type=accesslog | count()",
+				},
+			},
+			wantRepository: "audit",
+			wantQuery:      "#type=accesslog | count()",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				Spec: humiov1alpha1.HumioClusterSpec{
+					HumioNodeSpec: humiov1alpha1.HumioNodeSpec{UpdateStrategy: tt.updateStrategy},
+				},
+			}
+			repository, query := NewHumioNodeManagerFromHumioCluster(hc).GetBlueGreenValidationQuery()
+			if repository != tt.wantRepository || query != tt.wantQuery {
+				t.Errorf("GetBlueGreenValidationQuery() = %s, %s, want %s, %s", repository, query, tt.wantRepository, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func podNames(pods []corev1.Pod) string {
+	var names string
+	for idx, pod := range pods {
+		if idx > 0 {
+			names += ","
+		}
+		names += pod.Name
+	}
+	return names
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// bucketStorageEncryptionKeyEnvVars maps the bucket storage scheme of each cloud to the environment variable holding
+// the customer-provided encryption key
+var bucketStorageEncryptionKeyEnvVars = map[string]string{
+	"s3":    "S3_STORAGE_ENCRYPTION_KEY",
+	"gs":    "GCP_STORAGE_ENCRYPTION_KEY",
+	"azure": "AZURE_STORAGE_ENCRYPTION_KEY",
+}
+
+// bucketStorageKMSKeyARNEnvVar is the environment variable holding the KMS key used for SSE-KMS on S3
+const bucketStorageKMSKeyARNEnvVar = "S3_STORAGE_KMS_KEY_ARN"
+
+// bucketStorageScheme returns the scheme of the bucket storage configured by the user for the node pool, or an empty
+// string if bucket storage is not configured. Unlike nodePoolBucketStorage, it only looks at the environment variables
+// set by the user, so it can be used while building the default environment variables.
+func bucketStorageScheme(hnp *HumioNodePool) string {
+	for _, envVar := range hnp.humioNodeSpec.EnvironmentVariables {
+		for scheme, name := range map[string]string{"s3": "S3_STORAGE_BUCKET", "gs": "GCP_STORAGE_BUCKET", "azure": "AZURE_STORAGE_BUCKET"} {
+			if envVar.Name == name && envVar.Value != "" {
+				return scheme
+			}
+		}
+	}
+	return ""
+}
+
+// bucketStorageEncryptionEnvironmentVariables returns the environment variables configuring the encryption of the
+// bucket storage of the node pool
+func bucketStorageEncryptionEnvironmentVariables(hnp *HumioNodePool) []corev1.EnvVar {
+	encryption := hnp.GetBucketStorageEncryption()
+	if encryption == nil {
+		return nil
+	}
+	scheme := bucketStorageScheme(hnp)
+	var envVars []corev1.EnvVar
+	if encryption.KMSKeyARN != "" && scheme == "s3" {
+		envVars = append(envVars, corev1.EnvVar{Name: bucketStorageKMSKeyARNEnvVar, Value: encryption.KMSKeyARN})
+	}
+	if name, ok := bucketStorageEncryptionKeyEnvVars[scheme]; ok && encryption.EncryptionKeySecretKeyRef != nil {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:      name,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: encryption.EncryptionKeySecretKeyRef},
+		})
+	}
+	return envVars
+}
+
+// validateBucketStorageEncryption returns an error if the encryption settings of the node pool cannot be applied to its
+// bucket storage
+func validateBucketStorageEncryption(hnp *HumioNodePool) error {
+	encryption := hnp.GetBucketStorageEncryption()
+	if encryption == nil {
+		return nil
+	}
+	if encryption.KMSKeyARN == "" && encryption.EncryptionKeySecretKeyRef == nil {
+		return fmt.Errorf("bucketStorageEncryption of node pool %s must set at least one of kmsKeyARN and encryptionKeySecretKeyRef", hnp.GetNodePoolName())
+	}
+	scheme := bucketStorageScheme(hnp)
+	if scheme == "" {
+		return fmt.Errorf("bucketStorageEncryption of node pool %s requires bucket storage to be configured", hnp.GetNodePoolName())
+	}
+	if encryption.KMSKeyARN != "" && scheme != "s3" {
+		return fmt.Errorf("bucketStorageEncryption of node pool %s sets kmsKeyARN, which is only supported for S3 bucket storage", hnp.GetNodePoolName())
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestBucketStorageEncryption(t *testing.T) {
+	kmsKeyARN := "arn:aws:kms:us-west-2:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	encryptionKey := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "bucket-encryption"}, Key: "key"}
+
+	testCases := []struct {
+		name                 string
+		encryption           *humiov1alpha1.HumioBucketStorageEncryption
+		environmentVariables []corev1.EnvVar
+		expectedEnvVars      []string
+		expectError          bool
+	}{
+		{
+			name:                 "sse-kms on s3",
+			encryption:           &humiov1alpha1.HumioBucketStorageEncryption{KMSKeyARN: kmsKeyARN},
+			environmentVariables: []corev1.EnvVar{{Name: "S3_STORAGE_BUCKET", Value: "humio-storage"}},
+			expectedEnvVars:      []string{"S3_STORAGE_KMS_KEY_ARN"},
+		},
+		{
+			name:                 "sse-kms and customer-provided key on s3",
+			encryption:           &humiov1alpha1.HumioBucketStorageEncryption{KMSKeyARN: kmsKeyARN, EncryptionKeySecretKeyRef: encryptionKey},
+			environmentVariables: []corev1.EnvVar{{Name: "S3_STORAGE_BUCKET", Value: "humio-storage"}},
+			expectedEnvVars:      []string{"S3_STORAGE_KMS_KEY_ARN", "S3_STORAGE_ENCRYPTION_KEY"},
+		},
+		{
+			name:                 "customer-provided key on gcs",
+			encryption:           &humiov1alpha1.HumioBucketStorageEncryption{EncryptionKeySecretKeyRef: encryptionKey},
+			environmentVariables: []corev1.EnvVar{{Name: "GCP_STORAGE_BUCKET", Value: "humio-storage"}},
+			expectedEnvVars:      []string{"GCP_STORAGE_ENCRYPTION_KEY"},
+		},
+		{
+			name:                 "sse-kms on gcs",
+			encryption:           &humiov1alpha1.HumioBucketStorageEncryption{KMSKeyARN: kmsKeyARN},
+			environmentVariables: []corev1.EnvVar{{Name: "GCP_STORAGE_BUCKET", Value: "humio-storage"}},
+			expectError:          true,
+		},
+		{
+			name:        "no bucket storage",
+			encryption:  &humiov1alpha1.HumioBucketStorageEncryption{EncryptionKeySecretKeyRef: encryptionKey},
+			expectError: true,
+		},
+		{
+			name:                 "no encryption settings",
+			encryption:           &humiov1alpha1.HumioBucketStorageEncryption{},
+			environmentVariables: []corev1.EnvVar{{Name: "S3_STORAGE_BUCKET", Value: "humio-storage"}},
+			expectError:          true,
+		},
+		{
+			name:       "encryption key also set using environment variables",
+			encryption: &humiov1alpha1.HumioBucketStorageEncryption{EncryptionKeySecretKeyRef: encryptionKey},
+			environmentVariables: []corev1.EnvVar{
+				{Name: "S3_STORAGE_BUCKET", Value: "humio-storage"},
+				{Name: "S3_STORAGE_ENCRYPTION_KEY", Value: "plain-text"},
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := newPodHashTestCluster()
+			hc.Spec.BucketStorageEncryption = tc.encryption
+			hc.Spec.EnvironmentVariables = append(hc.Spec.EnvironmentVariables, tc.environmentVariables...)
+			hnp := NewHumioNodeManagerFromHumioCluster(hc)
+
+			err := validateBucketStorageEncryption(hnp)
+			if err == nil {
+				err = validateEnvironmentVariables(hnp)
+			}
+			if (err != nil) != tc.expectError {
+				t.Fatalf("validation error = %v, expectError %v", err, tc.expectError)
+			}
+			for _, name := range tc.expectedEnvVars {
+				if !EnvVarHasKey(hnp.GetEnvironmentVariables(), name) {
+					t.Errorf("expected environment variable %s to be set, got %v", name, hnp.GetEnvironmentVariables())
+				}
+			}
+		})
+	}
+}
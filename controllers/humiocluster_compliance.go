@@ -0,0 +1,93 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// fipsTLSProtocols are the TLS versions accepted by the Humio listeners with the FIPS compliance profile
+	fipsTLSProtocols = "TLSv1.3,TLSv1.2"
+	// fipsTLSCipherSuites are the FIPS-approved cipher suites accepted by the Humio listeners, using the JSSE names
+	fipsTLSCipherSuites = "TLS_AES_256_GCM_SHA384,TLS_AES_128_GCM_SHA256," +
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384," +
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"
+	// fipsNginxCiphers are the FIPS-approved TLS 1.2 cipher suites accepted by the ingresses, using the OpenSSL names
+	fipsNginxCiphers = "ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384:" +
+		"ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256"
+)
+
+// complianceProfileEnvironmentVariables returns the environment variables restricting the TLS settings of the Humio
+// listeners of the node pool for its compliance profile
+func complianceProfileEnvironmentVariables(hnp *HumioNodePool) []corev1.EnvVar {
+	if hnp.complianceProfile != humiov1alpha1.HumioClusterComplianceProfileFIPS || !hnp.TLSEnabled() {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{Name: "TLS_PROTOCOLS", Value: fipsTLSProtocols},
+		{Name: "TLS_CIPHER_SUITES", Value: fipsTLSCipherSuites},
+	}
+}
+
+// complianceProfileIngressAnnotations returns the annotations restricting the TLS settings of the ingresses of the
+// cluster for its compliance profile
+func complianceProfileIngressAnnotations(hc *humiov1alpha1.HumioCluster) map[string]string {
+	annotations := map[string]string{}
+	if hc.Spec.ComplianceProfile != humiov1alpha1.HumioClusterComplianceProfileFIPS {
+		return annotations
+	}
+	annotations["nginx.ingress.kubernetes.io/ssl-ciphers"] = fipsNginxCiphers
+	annotations["nginx.ingress.kubernetes.io/ssl-prefer-server-ciphers"] = "true"
+	if helpers.TLSEnabled(hc) {
+		annotations["nginx.ingress.kubernetes.io/proxy-ssl-protocols"] = "TLSv1.2 TLSv1.3"
+		annotations["nginx.ingress.kubernetes.io/proxy-ssl-ciphers"] = fipsNginxCiphers
+	}
+	return annotations
+}
+
+// validateComplianceProfile returns an error if the HumioCluster disables or overrides settings required by its
+// compliance profile. Overrides of the environment variables of the profile are rejected by
+// validateEnvironmentVariables.
+func validateComplianceProfile(hc *humiov1alpha1.HumioCluster) error {
+	if hc.Spec.ComplianceProfile != humiov1alpha1.HumioClusterComplianceProfileFIPS {
+		return nil
+	}
+	if !helpers.TLSEnabled(hc) {
+		return fmt.Errorf("complianceProfile %s requires TLS to be enabled", hc.Spec.ComplianceProfile)
+	}
+	if hc.Spec.Ingress.Enabled && !ingressTLSOrDefault(hc) {
+		return fmt.Errorf("complianceProfile %s requires TLS to be enabled for the ingress", hc.Spec.ComplianceProfile)
+	}
+	var overrides []string
+	for k := range complianceProfileIngressAnnotations(hc) {
+		if _, ok := hc.Spec.Ingress.Annotations[k]; ok {
+			overrides = append(overrides, k)
+		}
+	}
+	if len(overrides) > 0 {
+		sort.Strings(overrides)
+		return fmt.Errorf("complianceProfile %s does not allow the ingress annotations %s to be overridden", hc.Spec.ComplianceProfile, strings.Join(overrides, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateComplianceProfile(t *testing.T) {
+	testCases := []struct {
+		name        string
+		mutate      func(hc *humiov1alpha1.HumioCluster)
+		expectError bool
+	}{
+		{
+			name: "tls enabled",
+		},
+		{
+			name: "no compliance profile",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.ComplianceProfile = ""
+				hc.Spec.TLS.Enabled = helpers.BoolPtr(false)
+			},
+		},
+		{
+			name: "tls disabled",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.TLS.Enabled = helpers.BoolPtr(false)
+			},
+			expectError: true,
+		},
+		{
+			name: "ingress tls disabled",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.Ingress.Enabled = true
+				hc.Spec.Ingress.TLS = helpers.BoolPtr(false)
+			},
+			expectError: true,
+		},
+		{
+			name: "ingress ciphers overridden",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.Ingress.Enabled = true
+				hc.Spec.Ingress.Annotations = map[string]string{"nginx.ingress.kubernetes.io/ssl-ciphers": "ALL"}
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("USE_CERTMANAGER", "true")
+			hc := newPodHashTestCluster()
+			hc.Spec.ComplianceProfile = humiov1alpha1.HumioClusterComplianceProfileFIPS
+			hc.Spec.TLS = &humiov1alpha1.HumioClusterTLSSpec{Enabled: helpers.BoolPtr(true)}
+			if tc.mutate != nil {
+				tc.mutate(hc)
+			}
+			if err := validateComplianceProfile(hc); (err != nil) != tc.expectError {
+				t.Errorf("validateComplianceProfile() error = %v, expectError %v", err, tc.expectError)
+			}
+		})
+	}
+}
+
+func TestComplianceProfileFIPS(t *testing.T) {
+	t.Setenv("USE_CERTMANAGER", "true")
+	t.Setenv("HUMIO_OPERATOR_FIPS_IMAGE", "humio/humio-core:1.100.0-fips")
+	hc := newPodHashTestCluster()
+	hc.Spec.Image = ""
+	hc.Spec.ComplianceProfile = humiov1alpha1.HumioClusterComplianceProfileFIPS
+	hc.Spec.TLS = &humiov1alpha1.HumioClusterTLSSpec{Enabled: helpers.BoolPtr(true)}
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+
+	if hnp.GetImage() != "humio/humio-core:1.100.0-fips" {
+		t.Errorf("expected the FIPS image to be used, got %s", hnp.GetImage())
+	}
+	if hnp.GetHelperImage() != HelperImage {
+		t.Errorf("expected the default helper image to be used when no FIPS helper image is configured, got %s", hnp.GetHelperImage())
+	}
+	if !EnvVarHasValue(hnp.GetEnvironmentVariables(), "TLS_PROTOCOLS", fipsTLSProtocols) {
+		t.Errorf("expected TLS_PROTOCOLS to be %s, got %v", fipsTLSProtocols, hnp.GetEnvironmentVariables())
+	}
+	annotations := constructNginxIngressAnnotations(hc, "humio.example.com", "humiocluster", nil)
+	if annotations["nginx.ingress.kubernetes.io/ssl-ciphers"] != fipsNginxCiphers {
+		t.Errorf("expected the ingress ciphers to be restricted, got %v", annotations)
+	}
+
+	hc.Spec.EnvironmentVariables = append(hc.Spec.EnvironmentVariables, corev1.EnvVar{Name: "TLS_PROTOCOLS", Value: "TLSv1.1"})
+	if err := validateEnvironmentVariables(NewHumioNodeManagerFromHumioCluster(hc)); err == nil {
+		t.Errorf("expected overriding TLS_PROTOCOLS to be rejected")
+	}
+
+	hc.Spec.Image = "humio/humio-core:1.99.0"
+	if image := NewHumioNodeManagerFromHumioCluster(hc).GetImage(); image != "humio/humio-core:1.99.0" {
+		t.Errorf("expected an explicitly set image to be used, got %s", image)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+)
+
+// configChangeAllowed returns whether the pods of the node pool may be restarted now to apply configuration changes.
+// If not, the returned message describes when the changes are applied.
+func configChangeAllowed(hnp *HumioNodePool, now time.Time) (bool, string, error) {
+	switch hnp.GetConfigChangePolicy() {
+	case humiov1alpha1.HumioClusterConfigChangePolicyManual:
+		return false, fmt.Sprintf("configuration changes to node pool %s are pending until its pods are deleted", hnp.GetNodePoolName()), nil
+	case humiov1alpha1.HumioClusterConfigChangePolicyDeferred:
+		open, next, err := maintenanceWindowOpen(hnp.GetMaintenanceWindow(), now)
+		if err != nil {
+			return false, "", err
+		}
+		if open {
+			return true, "", nil
+		}
+		if next.IsZero() {
+			return false, fmt.Sprintf("configuration changes to node pool %s are pending, but the maintenance window never opens", hnp.GetNodePoolName()), nil
+		}
+		return false, fmt.Sprintf("configuration changes to node pool %s are pending until the next maintenance window at %s", hnp.GetNodePoolName(), next.Format(time.RFC3339)), nil
+	}
+	return true, "", nil
+}
+
+// maintenanceWindowOpen returns whether now falls within the maintenance window, and otherwise when the next
+// maintenance window opens
+func maintenanceWindowOpen(window *humiov1alpha1.HumioMaintenanceWindow, now time.Time) (bool, time.Time, error) {
+	if window == nil {
+		return false, time.Time{}, fmt.Errorf("maintenanceWindow must be set when configChangePolicy is %s", humiov1alpha1.HumioClusterConfigChangePolicyDeferred)
+	}
+	schedule, err := helpers.ParseCronSchedule(window.Schedule)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid maintenanceWindow: %w", err)
+	}
+	if window.DurationMinutes <= 0 {
+		return false, time.Time{}, fmt.Errorf("invalid maintenanceWindow: durationMinutes must be positive")
+	}
+	// The first window starting after now minus the duration is either open, or the next window to open
+	now = now.UTC()
+	start := schedule.Next(now.Add(-time.Duration(window.DurationMinutes) * time.Minute))
+	if start.IsZero() {
+		return false, time.Time{}, nil
+	}
+	return !start.After(now), start, nil
+}
+
+// validateConfigChangePolicy returns an error if configuration changes could never be applied
+func validateConfigChangePolicy(hc *humiov1alpha1.HumioCluster) error {
+	if hc.Spec.ConfigChangePolicy != humiov1alpha1.HumioClusterConfigChangePolicyDeferred {
+		return nil
+	}
+	_, _, err := maintenanceWindowOpen(hc.Spec.MaintenanceWindow, time.Now())
+	return err
+}
+
+func nodePoolHasPendingConfigChanges(hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool) bool {
+	for _, nodePoolStatus := range hc.Status.NodePoolStatus {
+		if nodePoolStatus.Name == hnp.GetNodePoolName() {
+			return nodePoolStatus.PendingConfigChanges
+		}
+	}
+	return false
+}
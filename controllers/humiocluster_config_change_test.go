@@ -0,0 +1,124 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+func TestConfigChangeAllowed(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	window := func(schedule string, durationMinutes int) *humiov1alpha1.HumioMaintenanceWindow {
+		return &humiov1alpha1.HumioMaintenanceWindow{Schedule: schedule, DurationMinutes: durationMinutes}
+	}
+
+	tt := []struct {
+		name              string
+		policy            string
+		maintenanceWindow *humiov1alpha1.HumioMaintenanceWindow
+		allowed           bool
+		wantErr           bool
+	}{
+		{
+			name:    "default policy",
+			allowed: true,
+		},
+		{
+			name:    "rolling restart",
+			policy:  humiov1alpha1.HumioClusterConfigChangePolicyRollingRestart,
+			allowed: true,
+		},
+		{
+			name:    "manual",
+			policy:  humiov1alpha1.HumioClusterConfigChangePolicyManual,
+			allowed: false,
+		},
+		{
+			name:              "deferred within maintenance window",
+			policy:            humiov1alpha1.HumioClusterConfigChangePolicyDeferred,
+			maintenanceWindow: window("0 11 * * 3", 120),
+			allowed:           true,
+		},
+		{
+			name:              "deferred at start of maintenance window",
+			policy:            humiov1alpha1.HumioClusterConfigChangePolicyDeferred,
+			maintenanceWindow: window("0 12 * * *", 60),
+			allowed:           true,
+		},
+		{
+			name:              "deferred after maintenance window closed",
+			policy:            humiov1alpha1.HumioClusterConfigChangePolicyDeferred,
+			maintenanceWindow: window("0 11 * * *", 60),
+			allowed:           false,
+		},
+		{
+			name:              "deferred before maintenance window",
+			policy:            humiov1alpha1.HumioClusterConfigChangePolicyDeferred,
+			maintenanceWindow: window("0 2 * * 6", 240),
+			allowed:           false,
+		},
+		{
+			name:    "deferred without maintenance window",
+			policy:  humiov1alpha1.HumioClusterConfigChangePolicyDeferred,
+			wantErr: true,
+		},
+		{
+			name:              "deferred with invalid schedule",
+			policy:            humiov1alpha1.HumioClusterConfigChangePolicyDeferred,
+			maintenanceWindow: window("every saturday", 60),
+			wantErr:           true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				Spec: humiov1alpha1.HumioClusterSpec{
+					ConfigChangePolicy: tc.policy,
+					MaintenanceWindow:  tc.maintenanceWindow,
+				},
+			}
+			allowed, message, err := configChangeAllowed(NewHumioNodeManagerFromHumioCluster(hc), now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %t, got %v", tc.wantErr, err)
+			}
+			if allowed != tc.allowed {
+				t.Errorf("expected allowed %t, got %t", tc.allowed, allowed)
+			}
+			if !allowed && !tc.wantErr && message == "" {
+				t.Errorf("expected a message describing when the changes are applied")
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowOpenReturnsNextWindow(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	open, next, err := maintenanceWindowOpen(&humiov1alpha1.HumioMaintenanceWindow{Schedule: "0 2 * * 6", DurationMinutes: 240}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if open {
+		t.Errorf("expected maintenance window to be closed")
+	}
+	if expected := time.Date(2024, time.January, 13, 2, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("expected next maintenance window at %s, got %s", expected, next)
+	}
+}
@@ -32,7 +32,9 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/go-logr/logr"
@@ -50,6 +52,10 @@ type HumioClusterReconciler struct {
 	Log         logr.Logger
 	HumioClient humio.Client
 	Namespace   string
+	Recorder    record.EventRecorder
+
+	adminTokens   adminTokenCache
+	podSpecHashes podSpecHashCache
 }
 
 type ctxHumioClusterPoolFunc func(context.Context, *humiov1alpha1.HumioCluster, *HumioNodePool) error
@@ -59,12 +65,17 @@ const (
 	// MaximumMinReadyRequeue The maximum requeue time to set for the MinReadySeconds functionality - this is to avoid a scenario where we
 	// requeue for hours into the future.
 	MaximumMinReadyRequeue = time.Second * 300
+	// runningRequeue is how long to wait before reconciling a cluster without any ongoing changes again. Changes to
+	// the resources of the cluster, and to the secrets and config maps it reads, trigger a reconcile right away.
+	runningRequeue = time.Second * 60
 )
 
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioclusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioclusters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioclusters/finalizers,verbs=update
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=create;delete;get;list;patch;update;watch
+//+kubebuilder:rbac:groups=core,resources=pods/status,verbs=get;patch;update
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=services,verbs=create;delete;get;list;patch;update;watch
 //+kubebuilder:rbac:groups=core,resources=services/finalizers,verbs=create;delete;get;list;patch;update;watch
 //+kubebuilder:rbac:groups=core,resources=endpoints,verbs=create;delete;get;list;patch;update;watch
@@ -92,6 +103,8 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			r.adminTokens.delete(req.NamespacedName)
+			r.podSpecHashes.delete(req.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -100,16 +113,24 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 	r.Log = r.Log.WithValues("Request.UID", hc.UID)
 
+	if err := r.ensureTimedOutAuthMigrationIsRolledBack(ctx, hc); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to roll back auth migration")
+	}
+
 	var humioNodePools HumioNodePoolList
 	humioNodePools.Add(NewHumioNodeManagerFromHumioCluster(hc))
 	for idx := range hc.Spec.NodePools {
 		humioNodePools.Add(NewHumioNodeManagerFromHumioNodePool(hc, &hc.Spec.NodePools[idx]))
 	}
+	r.podSpecHashes.retainNodePools(req.NamespacedName, humioNodePools.Items)
 
 	emptyResult := reconcile.Result{}
 
+	// The status reported at the end of every reconcile is collected from the deferred functions below and written in
+	// a single update
+	deferredStatus := statusOptions()
 	defer func(ctx context.Context, humioClient humio.Client, hc *humiov1alpha1.HumioCluster) {
-		_, _ = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+		_, _ = r.updateStatus(ctx, r.Client.Status(), hc, deferredStatus.
 			withObservedGeneration(hc.GetGeneration()))
 	}(ctx, r.HumioClient, hc)
 
@@ -124,11 +145,51 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 				withMessage(err.Error()).
 				withNodePoolState(humiov1alpha1.HumioClusterStateConfigError, pool.GetNodePoolName()))
 		}
+		if err := r.ensureImageVerified(ctx, pool); err != nil {
+			return r.imageNotVerified(ctx, hc, pool, err)
+		}
 		if err := r.ensureValidStorageConfiguration(pool); err != nil {
 			return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
 				withMessage(err.Error()).
 				withNodePoolState(humiov1alpha1.HumioClusterStateConfigError, pool.GetNodePoolName()))
 		}
+		if err := r.ensureValidUpdateStrategy(pool); err != nil {
+			return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+				withMessage(err.Error()).
+				withNodePoolState(humiov1alpha1.HumioClusterStateConfigError, pool.GetNodePoolName()))
+		}
+		if err := validateEnvironmentVariables(pool); err != nil {
+			return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+				withMessage(r.logErrorAndReturn(err, "invalid environment variables").Error()).
+				withNodePoolState(humiov1alpha1.HumioClusterStateConfigError, pool.GetNodePoolName()))
+		}
+		if err := validateExtraConfigFiles(pool); err != nil {
+			return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+				withMessage(r.logErrorAndReturn(err, "invalid extra config files").Error()).
+				withNodePoolState(humiov1alpha1.HumioClusterStateConfigError, pool.GetNodePoolName()))
+		}
+		if err := validateWorkloadIdentity(pool); err != nil {
+			return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+				withMessage(r.logErrorAndReturn(err, "invalid workload identity").Error()).
+				withNodePoolState(humiov1alpha1.HumioClusterStateConfigError, pool.GetNodePoolName()))
+		}
+		if err := validateBucketStorageEncryption(pool); err != nil {
+			return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+				withMessage(r.logErrorAndReturn(err, "invalid bucket storage encryption").Error()).
+				withNodePoolState(humiov1alpha1.HumioClusterStateConfigError, pool.GetNodePoolName()))
+		}
+	}
+
+	if err := r.ensureEnvironmentVariableWarningsReported(ctx, hc, humioNodePools.Filter(NodePoolFilterHasNode)); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to report environment variable warnings")
+	}
+
+	if err := r.ensureCrashLoopsReported(ctx, hc, humioNodePools.Items); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to report crash-looping pods")
+	}
+
+	if err := r.ensureImageVerificationReported(ctx, hc, humioNodePools.Filter(NodePoolFilterHasNode)); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to report image verification")
 	}
 
 	for _, fun := range []ctxHumioClusterFunc{
@@ -153,23 +214,30 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	defer func(ctx context.Context, humioClient humio.Client, hc *humiov1alpha1.HumioCluster) {
-		opts := statusOptions()
 		podStatusList, err := r.getPodStatusList(ctx, hc, humioNodePools.Filter(NodePoolFilterHasNode))
 		if err != nil {
 			r.Log.Error(err, "unable to get pod status list")
 		}
-		_, _ = r.updateStatus(ctx, r.Client.Status(), hc, opts.
+		if _, err = r.withDataNodes(ctx, deferredStatus, humioNodePools.Filter(NodePoolFilterHasNode)); err != nil {
+			r.Log.Error(err, "unable to get data nodes")
+		}
+		deferredStatus.
 			withPods(podStatusList).
-			withNodeCount(len(podStatusList)))
+			withNodeCount(len(podStatusList))
 	}(ctx, r.HumioClient, hc)
 
-	for _, pool := range humioNodePools.Items {
+	if _, err := reconcileNodePoolsWithoutResult(ctx, humioNodePools.Items, func(ctx context.Context, pool *HumioNodePool) error {
 		if err := r.ensureOrphanedPvcsAreDeleted(ctx, hc, pool); err != nil {
-			return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
-				withMessage(err.Error()))
+			return err
 		}
+		return r.ensurePodsPinnedToDeletedNodesAreDeleted(ctx, pool)
+	}); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(err.Error()))
 	}
 
+	// Pods are replaced one node pool at a time, as nodePoolAllowsMaintenanceOperations only lets a node pool be
+	// upgraded or restarted once the node pools before it are done
 	for _, pool := range humioNodePools.Items {
 		if r.nodePoolAllowsMaintenanceOperations(hc, pool, humioNodePools.Items) {
 			// TODO: result should be controlled and returned by the status
@@ -195,6 +263,24 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 			withState(humiov1alpha1.HumioClusterStateConfigError))
 	}
 
+	if err := validateNodeRoles(humioNodePools.Items); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(r.logErrorAndReturn(err, "invalid node roles").Error()).
+			withState(humiov1alpha1.HumioClusterStateConfigError))
+	}
+
+	if err := validateConfigChangePolicy(hc); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(r.logErrorAndReturn(err, "invalid config change policy").Error()).
+			withState(humiov1alpha1.HumioClusterStateConfigError))
+	}
+
+	if err := validateComplianceProfile(hc); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(r.logErrorAndReturn(err, "invalid compliance profile").Error()).
+			withState(humiov1alpha1.HumioClusterStateConfigError))
+	}
+
 	if hc.Status.State == "" {
 		// TODO: migrate to updateStatus()
 		err := r.setState(ctx, humiov1alpha1.HumioClusterStateRunning, hc)
@@ -226,7 +312,7 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 	}
 
-	for _, pool := range humioNodePools.Filter(NodePoolFilterHasNode) {
+	if _, err := reconcileNodePoolsWithoutResult(ctx, humioNodePools.Filter(NodePoolFilterHasNode), func(ctx context.Context, pool *HumioNodePool) error {
 		for _, fun := range []ctxHumioClusterPoolFunc{
 			r.ensureService,
 			r.ensureHumioPodPermissions,
@@ -236,10 +322,13 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 			r.ensureExtraKafkaConfigsConfigMap,
 		} {
 			if err := fun(ctx, hc, pool); err != nil {
-				return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
-					withMessage(err.Error()))
+				return err
 			}
 		}
+		return nil
+	}); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(err.Error()))
 	}
 
 	for _, pool := range humioNodePools.Filter(NodePoolFilterHasNode) {
@@ -255,26 +344,26 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 	}
 
-	for _, pool := range humioNodePools.Filter(NodePoolFilterHasNode) {
-		if err := r.ensurePersistentVolumeClaimsExist(ctx, hc, pool); err != nil {
-			opts := statusOptions()
-			if hc.Status.State != humiov1alpha1.HumioClusterStateRestarting && hc.Status.State != humiov1alpha1.HumioClusterStateUpgrading {
-				opts.withNodePoolState(humiov1alpha1.HumioClusterStatePending, pool.GetNodePoolName())
-			}
-			return r.updateStatus(ctx, r.Client.Status(), hc, opts.
-				withMessage(err.Error()))
+	if pool, err := reconcileNodePoolsWithoutResult(ctx, humioNodePools.Filter(NodePoolFilterHasNode), func(ctx context.Context, pool *HumioNodePool) error {
+		return r.ensurePersistentVolumeClaimsExist(ctx, hc, pool)
+	}); err != nil {
+		opts := statusOptions()
+		if hc.Status.State != humiov1alpha1.HumioClusterStateRestarting && hc.Status.State != humiov1alpha1.HumioClusterStateUpgrading {
+			opts.withNodePoolState(humiov1alpha1.HumioClusterStatePending, pool.GetNodePoolName())
 		}
+		return r.updateStatus(ctx, r.Client.Status(), hc, opts.
+			withMessage(err.Error()))
 	}
 
 	// TODO: result should be controlled and returned by the status
-	for _, pool := range humioNodePools.Filter(NodePoolFilterHasNode) {
-		if result, err := r.ensurePodsExist(ctx, hc, pool); result != emptyResult || err != nil {
-			if err != nil {
-				_, _ = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
-					withMessage(err.Error()))
-			}
-			return result, err
+	if _, result, err := reconcileNodePools(ctx, humioNodePools.Filter(NodePoolFilterHasNode), func(ctx context.Context, pool *HumioNodePool) (reconcile.Result, error) {
+		return r.ensurePodsExist(ctx, hc, pool)
+	}); result != emptyResult || err != nil {
+		if err != nil {
+			_, _ = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+				withMessage(err.Error()))
 		}
+		return result, err
 	}
 
 	for _, nodePool := range humioNodePools.Filter(NodePoolFilterDoesNotHaveNodes) {
@@ -284,6 +373,11 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 	}
 
+	if err := r.ensureAdminTokenSecret(ctx, hc); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(err.Error()))
+	}
+
 	// TODO: result should be controlled and returned by the status
 	if len(r.nodePoolsInMaintenance(hc, humioNodePools.Filter(NodePoolFilterHasNode))) == 0 {
 		if result, err := r.ensureLicense(ctx, hc, req); result != emptyResult || err != nil {
@@ -304,13 +398,12 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	defer func(ctx context.Context, humioClient humio.Client, hc *humiov1alpha1.HumioCluster) {
-		opts := statusOptions()
 		if hc.Status.State == humiov1alpha1.HumioClusterStateRunning {
 			status, err := humioClient.Status(cluster.Config(), req)
 			if err != nil {
 				r.Log.Error(err, "unable to get cluster status")
 			}
-			_, _ = r.updateStatus(ctx, r.Client.Status(), hc, opts.withVersion(status.Version))
+			deferredStatus.withVersion(status.Version)
 		}
 	}(ctx, r.HumioClient, hc)
 
@@ -335,7 +428,34 @@ func (r *HumioClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 	}
 
-	if err = r.ensurePartitionsAreBalanced(hc, cluster.Config(), req); err != nil {
+	if err = r.ensurePartitionsAreBalanced(ctx, hc, humioNodePools.Items, cluster.Config(), req); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(err.Error()))
+	}
+
+	if err = r.ensureAuthMigration(ctx, hc, humioNodePools.Items, cluster.Config(), req); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(err.Error()))
+	}
+
+	if err = r.ensureAdminTokenRegenerated(ctx, hc, cluster.Config(), req); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(err.Error()))
+	}
+
+	if err = r.ensureAdminTokenRotated(ctx, hc, cluster.Config(), req); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(err.Error()))
+	}
+	if err = r.rememberAdminToken(ctx, hc, cluster.Config(), req); err != nil {
+		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(err.Error()))
+	}
+
+	r.ensureUsageReported(ctx, hc, cluster.Config(), req)
+	r.ensureHealthReported(ctx, hc, cluster.Config(), req)
+
+	if err = r.ensureDeadNodesUnregistered(ctx, hc, humioNodePools.Items, cluster.Config(), req); err != nil {
 		return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
 			withMessage(err.Error()))
 	}
@@ -366,7 +486,9 @@ func (r *HumioClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.PersistentVolumeClaim{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&networkingv1.Ingress{}).
-		Complete(r)
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.clustersForSecret)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.clustersForConfigMap)).
+		Complete(criticalReconciler(r))
 }
 
 func (r *HumioClusterReconciler) nodePoolPodsReady(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool) (bool, error) {
@@ -438,6 +560,12 @@ func (r *HumioClusterReconciler) validateInitialPodSpec(hc *humiov1alpha1.HumioC
 	if _, err := ConstructPod(hnp, "", &podAttachments{}); err != nil {
 		return r.logErrorAndReturn(err, "failed to validate pod spec")
 	}
+	if err := validateNodeTags(hnp); err != nil {
+		return r.logErrorAndReturn(err, "failed to validate node tags")
+	}
+	if err := validateDataVolumeNodePinning(hnp); err != nil {
+		return r.logErrorAndReturn(err, "failed to validate data volume node pinning")
+	}
 	return nil
 }
 
@@ -474,7 +602,7 @@ func (r *HumioClusterReconciler) ensureExtraKafkaConfigsConfigMap(ctx context.Co
 				return r.logErrorAndReturn(err, "could not set controller reference")
 			}
 			r.Log.Info(fmt.Sprintf("creating configMap: %s", configMap.Name))
-			if err = r.Create(ctx, configMap); err != nil {
+			if err = r.Create(ctx, configMap, client.FieldOwner(kubernetes.FieldManager)); err != nil {
 				return r.logErrorAndReturn(err, "unable to create extra kafka configs configmap")
 			}
 			r.Log.Info(fmt.Sprintf("successfully created extra kafka configs configmap name %s", configMap.Name))
@@ -486,12 +614,15 @@ func (r *HumioClusterReconciler) ensureExtraKafkaConfigsConfigMap(ctx context.Co
 	return nil
 }
 
-// getEnvVarSource returns the environment variables from either the configMap or secret that is referenced by envVarSource
+// getEnvVarSource returns the environment variables from either the configMap or secret that is referenced by envVarSource.
+// Only the environment variables which take effect on the pods of the node pool are returned, so node pools sharing
+// the same configMap or secret are only restarted when a value they actually use changes.
 func (r *HumioClusterReconciler) getEnvVarSource(ctx context.Context, hnp *HumioNodePool) (*map[string]string, error) {
 	var envVarConfigMapName string
 	var envVarSecretName string
 	fullEnvVarKeyValues := map[string]string{}
 	for _, envVarSource := range hnp.GetEnvironmentVariablesSource() {
+		sourceKeyValues := map[string]string{}
 		if envVarSource.ConfigMapRef != nil {
 			envVarConfigMapName = envVarSource.ConfigMapRef.Name
 			configMap, err := kubernetes.GetConfigMap(ctx, r, envVarConfigMapName, hnp.GetNamespace())
@@ -502,7 +633,7 @@ func (r *HumioClusterReconciler) getEnvVarSource(ctx context.Context, hnp *Humio
 				return nil, fmt.Errorf("unable to get configMap with name %s in namespace %s", envVarConfigMapName, hnp.GetNamespace())
 			}
 			for k, v := range configMap.Data {
-				fullEnvVarKeyValues[k] = v
+				sourceKeyValues[k] = v
 			}
 		}
 		if envVarSource.SecretRef != nil {
@@ -515,9 +646,16 @@ func (r *HumioClusterReconciler) getEnvVarSource(ctx context.Context, hnp *Humio
 				return nil, fmt.Errorf("unable to get secret with name %s in namespace %s", envVarSecretName, hnp.GetNamespace())
 			}
 			for k, v := range secret.Data {
-				fullEnvVarKeyValues[k] = string(v)
+				sourceKeyValues[k] = string(v)
 			}
 		}
+		for k, v := range sourceKeyValues {
+			fullEnvVarKeyValues[envVarSource.Prefix+k] = v
+		}
+	}
+	// Environment variables set on the container take precedence over the ones from envVarSource
+	for _, envVar := range hnp.GetEnvironmentVariables() {
+		delete(fullEnvVarKeyValues, envVar.Name)
 	}
 	if len(fullEnvVarKeyValues) == 0 {
 		return nil, nil
@@ -569,7 +707,7 @@ func (r *HumioClusterReconciler) ensureViewGroupPermissionsConfigMap(ctx context
 			}
 
 			r.Log.Info(fmt.Sprintf("creating configMap: %s", configMap.Name))
-			if err = r.Create(ctx, configMap); err != nil {
+			if err = r.Create(ctx, configMap, client.FieldOwner(kubernetes.FieldManager)); err != nil {
 				return r.logErrorAndReturn(err, "unable to create view group permissions configmap")
 			}
 			r.Log.Info(fmt.Sprintf("successfully created view group permissions configmap name %s", configMap.Name))
@@ -607,7 +745,7 @@ func (r *HumioClusterReconciler) ensureRolePermissionsConfigMap(ctx context.Cont
 			}
 
 			r.Log.Info(fmt.Sprintf("creating configMap: %s", configMap.Name))
-			if err = r.Create(ctx, configMap); err != nil {
+			if err = r.Create(ctx, configMap, client.FieldOwner(kubernetes.FieldManager)); err != nil {
 				return r.logErrorAndReturn(err, "unable to create role permissions configmap")
 			}
 			r.Log.Info(fmt.Sprintf("successfully created role permissions configmap name %s", configMap.Name))
@@ -648,8 +786,8 @@ func (r *HumioClusterReconciler) ensureIngress(ctx context.Context, hc *humiov1a
 	if !hc.Spec.Ingress.Enabled {
 		return nil
 	}
-	if len(hc.Spec.NodePools) > 0 {
-		return fmt.Errorf("ingress only supported if pods belong to HumioCluster.Spec.NodeCount")
+	if err := validateIngressNodePools(hc); err != nil {
+		return err
 	}
 	if len(hc.Spec.Ingress.Controller) == 0 {
 		return r.logErrorAndReturn(fmt.Errorf("ingress enabled but no controller specified"), "could not ensure ingress")
@@ -753,43 +891,32 @@ func (r *HumioClusterReconciler) ensureNginxIngress(ctx context.Context, hc *hum
 		}
 
 		existingIngress, err := kubernetes.GetIngress(ctx, r, desiredIngress.Name, hc.Namespace)
-		if err != nil {
-			if k8serrors.IsNotFound(err) {
-				if err := controllerutil.SetControllerReference(hc, desiredIngress, r.Scheme()); err != nil {
-					return r.logErrorAndReturn(err, "could not set controller reference")
-				}
-				if createIngress {
-					r.Log.Info(fmt.Sprintf("creating ingress: %s", desiredIngress.Name))
-					err = r.Create(ctx, desiredIngress)
-					if err != nil {
-						return r.logErrorAndReturn(err, "unable to create ingress")
-					}
-					r.Log.Info(fmt.Sprintf("successfully created ingress with name %s", desiredIngress.Name))
-					humioClusterPrometheusMetrics.Counters.IngressesCreated.Inc()
-				}
-				continue
-			}
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return r.logErrorAndReturn(err, "unable to get ingress")
 		}
+		exists := err == nil
 
 		if !createIngress {
-			r.Log.Info(fmt.Sprintf("hostname not defined for ingress object, deleting ingress object with name %s", existingIngress.Name))
-			err = r.Delete(ctx, existingIngress)
-			if err != nil {
-				return r.logErrorAndReturn(err, "unable to delete ingress object")
+			if exists {
+				r.Log.Info(fmt.Sprintf("hostname not defined for ingress object, deleting ingress object with name %s", existingIngress.Name))
+				if err = r.Delete(ctx, existingIngress); err != nil {
+					return r.logErrorAndReturn(err, "unable to delete ingress object")
+				}
+				r.Log.Info(fmt.Sprintf("successfully deleted ingress %+#v", desiredIngress))
 			}
-			r.Log.Info(fmt.Sprintf("successfully deleted ingress %+#v", desiredIngress))
 			continue
 		}
 
-		if !r.ingressesMatch(existingIngress, desiredIngress) {
-			r.Log.Info(fmt.Sprintf("ingress object already exists, there is a difference between expected vs existing, updating ingress object with name %s", desiredIngress.Name))
-			existingIngress.Annotations = desiredIngress.Annotations
-			existingIngress.Labels = desiredIngress.Labels
-			existingIngress.Spec = desiredIngress.Spec
-			err = r.Update(ctx, existingIngress)
-			if err != nil {
-				return r.logErrorAndReturn(err, "could not update ingress")
-			}
+		if err := controllerutil.SetControllerReference(hc, desiredIngress, r.Scheme()); err != nil {
+			return r.logErrorAndReturn(err, "could not set controller reference")
+		}
+		// Annotations added to the ingress by others, such as cert-manager, are kept when applying the ingress
+		if err = r.apply(ctx, hc, desiredIngress); err != nil {
+			return r.logErrorAndReturn(err, fmt.Sprintf("could not apply ingress %s", desiredIngress.Name))
+		}
+		if !exists {
+			r.Log.Info(fmt.Sprintf("successfully created ingress with name %s", desiredIngress.Name))
+			humioClusterPrometheusMetrics.Counters.IngressesCreated.Inc()
 		}
 	}
 	return nil
@@ -923,7 +1050,7 @@ func (r *HumioClusterReconciler) ensureValidCAIssuer(ctx context.Context, hc *hu
 			}
 			// should only create it if it doesn't exist
 			r.Log.Info(fmt.Sprintf("creating CA Issuer: %s", caIssuer.Name))
-			if err = r.Create(ctx, &caIssuer); err != nil {
+			if err = r.Create(ctx, &caIssuer, client.FieldOwner(kubernetes.FieldManager)); err != nil {
 				return r.logErrorAndReturn(err, "could not create CA Issuer")
 			}
 			return nil
@@ -971,7 +1098,7 @@ func (r *HumioClusterReconciler) ensureValidCASecret(ctx context.Context, hc *hu
 		return r.logErrorAndReturn(err, "could not set controller reference")
 	}
 	r.Log.Info(fmt.Sprintf("creating CA secret: %s", caSecret.Name))
-	err = r.Create(ctx, caSecret)
+	err = r.Create(ctx, caSecret, client.FieldOwner(kubernetes.FieldManager))
 	if err != nil {
 		return r.logErrorAndReturn(err, "could not create secret with CA")
 	}
@@ -999,7 +1126,7 @@ func (r *HumioClusterReconciler) ensureHumioClusterKeystoreSecret(ctx context.Co
 				return r.logErrorAndReturn(err, "could not set controller reference")
 			}
 			r.Log.Info(fmt.Sprintf("creating secret: %s", secret.Name))
-			if err := r.Create(ctx, secret); err != nil {
+			if err := r.Create(ctx, secret, client.FieldOwner(kubernetes.FieldManager)); err != nil {
 				return r.logErrorAndReturn(err, "could not create secret")
 			}
 			return nil
@@ -1032,7 +1159,7 @@ func (r *HumioClusterReconciler) ensureHumioClusterCACertBundle(ctx context.Cont
 			return r.logErrorAndReturn(err, "could not set controller reference")
 		}
 		r.Log.Info(fmt.Sprintf("creating certificate: %s", cert.Name))
-		if err := r.Create(ctx, &cert); err != nil {
+		if err := r.Create(ctx, &cert, client.FieldOwner(kubernetes.FieldManager)); err != nil {
 			return r.logErrorAndReturn(err, "could not create certificate")
 		}
 		return nil
@@ -1045,6 +1172,11 @@ func (r *HumioClusterReconciler) ensureHumioClusterCACertBundle(ctx context.Cont
 }
 
 func (r *HumioClusterReconciler) ensureHumioNodeCertificates(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool) error {
+	return r.ensureHumioNodeCertificateCount(ctx, hc, hnp, hnp.GetNodeCount())
+}
+
+// ensureHumioNodeCertificateCount ensures there are at least the given number of node certificates for the node pool
+func (r *HumioClusterReconciler) ensureHumioNodeCertificateCount(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool, count int) error {
 	if !hnp.TLSEnabled() {
 		return nil
 	}
@@ -1053,7 +1185,7 @@ func (r *HumioClusterReconciler) ensureHumioNodeCertificates(ctx context.Context
 	if err != nil {
 		return r.logErrorAndReturn(err, "failed to get node certificate count")
 	}
-	for i := existingNodeCertCount; i < hnp.GetNodeCount(); i++ {
+	for i := existingNodeCertCount; i < count; i++ {
 		certificate := ConstructNodeCertificate(hnp, kubernetes.RandomString())
 
 		certificate.Annotations[certHashAnnotation] = GetDesiredCertHash(hnp)
@@ -1062,7 +1194,7 @@ func (r *HumioClusterReconciler) ensureHumioNodeCertificates(ctx context.Context
 			return r.logErrorAndReturn(err, "could not set controller reference")
 		}
 		r.Log.Info(fmt.Sprintf("creating node certificate: %s", certificate.Name))
-		if err = r.Create(ctx, &certificate); err != nil {
+		if err = r.Create(ctx, &certificate, client.FieldOwner(kubernetes.FieldManager)); err != nil {
 			return r.logErrorAndReturn(err, "could create node certificate")
 		}
 
@@ -1082,7 +1214,7 @@ func (r *HumioClusterReconciler) ensureInitClusterRole(ctx context.Context, hnp
 			// TODO: We cannot use controllerutil.SetControllerReference() as ClusterRole is cluster-wide and owner is namespaced.
 			// We probably need another way to ensure we clean them up. Perhaps we can use finalizers?
 			r.Log.Info(fmt.Sprintf("creating cluster role: %s", clusterRole.Name))
-			err = r.Create(ctx, clusterRole)
+			err = r.Create(ctx, clusterRole, client.FieldOwner(kubernetes.FieldManager))
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to create init cluster role")
 			}
@@ -1103,7 +1235,7 @@ func (r *HumioClusterReconciler) ensureAuthRole(ctx context.Context, hc *humiov1
 				return r.logErrorAndReturn(err, "could not set controller reference")
 			}
 			r.Log.Info(fmt.Sprintf("creating role: %s", role.Name))
-			err = r.Create(ctx, role)
+			err = r.Create(ctx, role, client.FieldOwner(kubernetes.FieldManager))
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to create auth role")
 			}
@@ -1129,7 +1261,7 @@ func (r *HumioClusterReconciler) ensureInitClusterRoleBinding(ctx context.Contex
 			// TODO: We cannot use controllerutil.SetControllerReference() as ClusterRoleBinding is cluster-wide and owner is namespaced.
 			// We probably need another way to ensure we clean them up. Perhaps we can use finalizers?
 			r.Log.Info(fmt.Sprintf("creating cluster role: %s", clusterRole.Name))
-			err = r.Create(ctx, clusterRole)
+			err = r.Create(ctx, clusterRole, client.FieldOwner(kubernetes.FieldManager))
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to create init cluster role binding")
 			}
@@ -1156,7 +1288,7 @@ func (r *HumioClusterReconciler) ensureAuthRoleBinding(ctx context.Context, hc *
 				return r.logErrorAndReturn(err, "could not set controller reference")
 			}
 			r.Log.Info(fmt.Sprintf("creating role binding: %s", roleBinding.Name))
-			err = r.Create(ctx, roleBinding)
+			err = r.Create(ctx, roleBinding, client.FieldOwner(kubernetes.FieldManager))
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to create auth role binding")
 			}
@@ -1212,7 +1344,7 @@ func (r *HumioClusterReconciler) ensureServiceAccountExists(ctx context.Context,
 			return r.logErrorAndReturn(err, "could not set controller reference")
 		}
 		r.Log.Info(fmt.Sprintf("creating service account: %s", serviceAccount.Name))
-		err = r.Create(ctx, serviceAccount)
+		err = r.Create(ctx, serviceAccount, client.FieldOwner(kubernetes.FieldManager))
 		if err != nil {
 			return r.logErrorAndReturn(err, fmt.Sprintf("unable to create service account %s", serviceAccount.Name))
 		}
@@ -1242,7 +1374,7 @@ func (r *HumioClusterReconciler) ensureServiceAccountSecretExists(ctx context.Co
 			return r.logErrorAndReturn(err, "could not set controller reference")
 		}
 		r.Log.Info(fmt.Sprintf("creating secret: %s", secret.Name))
-		err = r.Create(ctx, secret)
+		err = r.Create(ctx, secret, client.FieldOwner(kubernetes.FieldManager))
 		if err != nil {
 			return r.logErrorAndReturn(err, fmt.Sprintf("unable to create service account secret %s", secret.Name))
 		}
@@ -1305,8 +1437,7 @@ func (r *HumioClusterReconciler) ensureLabels(ctx context.Context, config *humio
 				labels := hnp.GetNodePoolLabels()
 				labels[kubernetes.NodeIdLabelName] = strconv.Itoa(node.Id)
 				r.Log.Info(fmt.Sprintf("setting labels for pod %s, labels=%v", pod.Name, labels))
-				pod.SetLabels(labels)
-				if err := r.Update(ctx, &foundPodList[idx]); err != nil {
+				if err := kubernetes.ApplyLabels(ctx, r, &foundPodList[idx], labels); err != nil {
 					return r.logErrorAndReturn(err, fmt.Sprintf("failed to update labels on pod %s", pod.Name))
 				}
 				if hnp.PVCsEnabled() {
@@ -1335,8 +1466,7 @@ func (r *HumioClusterReconciler) ensurePvcLabels(ctx context.Context, hnp *Humio
 	labels := hnp.GetNodePoolLabels()
 	labels[kubernetes.NodeIdLabelName] = strconv.Itoa(nodeId)
 	r.Log.Info(fmt.Sprintf("setting labels for pvc %s, labels=%v", pvc.Name, labels))
-	pvc.SetLabels(labels)
-	if err := r.Update(ctx, &pvc); err != nil {
+	if err := kubernetes.ApplyLabels(ctx, r, &pvc, labels); err != nil {
 		return r.logErrorAndReturn(err, fmt.Sprintf("failed to update labels on pvc %s", pod.Name))
 	}
 	return nil
@@ -1432,6 +1562,10 @@ func (r *HumioClusterReconciler) ensureLicenseIsValid(ctx context.Context, hc *h
 
 	licenseSecret, err := kubernetes.GetSecret(ctx, r, licenseSecretKeySelector.Name, hc.Namespace)
 	if err != nil {
+		if k8serrors.IsNotFound(err) && isSyncedBySecretsStore(hc, licenseSecretKeySelector.Name) {
+			r.Log.Info(fmt.Sprintf("license secret %s does not exist yet, waiting for it to be synced by the secrets store", licenseSecretKeySelector.Name))
+			return nil
+		}
 		return err
 	}
 	if _, ok := licenseSecret.Data[licenseSecretKeySelector.Key]; !ok {
@@ -1465,12 +1599,7 @@ func (r *HumioClusterReconciler) ensureLicense(ctx context.Context, hc *humiov1a
 
 	defer func(ctx context.Context, hc *humiov1alpha1.HumioCluster) {
 		if existingLicense != nil {
-			licenseStatus := humiov1alpha1.HumioLicenseStatus{
-				Type:       "onprem",
-				Expiration: existingLicense.ExpiresAt(),
-			}
-			_, _ = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
-				withLicense(licenseStatus))
+			r.reportLicenseExpiry(ctx, hc, existingLicense.ExpiresAt())
 		}
 	}(ctx, hc)
 
@@ -1526,7 +1655,7 @@ func (r *HumioClusterReconciler) ensureLicense(ctx context.Context, hc *humiov1a
 	return reconcile.Result{}, nil
 }
 
-func (r *HumioClusterReconciler) ensurePartitionsAreBalanced(hc *humiov1alpha1.HumioCluster, config *humioapi.Config, req reconcile.Request) error {
+func (r *HumioClusterReconciler) ensurePartitionsAreBalanced(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnps []*HumioNodePool, config *humioapi.Config, req reconcile.Request) error {
 	humioVersion, _ := HumioVersionFromString(NewHumioNodeManagerFromHumioCluster(hc).GetImage())
 	if ok, _ := humioVersion.AtLeast(HumioVersionWithAutomaticPartitionManagement); ok {
 		return nil
@@ -1546,6 +1675,16 @@ func (r *HumioClusterReconciler) ensurePartitionsAreBalanced(hc *humiov1alpha1.H
 	if err != nil {
 		return r.logErrorAndReturn(err, "could not get suggested storage layout")
 	}
+	if nodePoolsRestrictRole(hnps, humiov1alpha1.HumioNodeRoleStorage) {
+		storageNodeIDs, err := r.nodeIDsWithRole(ctx, hnps, humiov1alpha1.HumioNodeRoleStorage)
+		if err != nil {
+			return r.logErrorAndReturn(err, "could not get nodes with storage role")
+		}
+		if len(storageNodeIDs) == 0 {
+			return r.logErrorAndReturn(fmt.Errorf("no nodes with storage role"), "could not restrict storage partitions")
+		}
+		suggestedStorageLayout = restrictStoragePartitions(suggestedStorageLayout, storageNodeIDs)
+	}
 	currentStorageLayoutInput := helpers.MapStoragePartition(currentClusterInfo.StoragePartitions, helpers.ToStoragePartitionInput)
 	if !reflect.DeepEqual(currentStorageLayoutInput, suggestedStorageLayout) {
 		r.Log.Info(fmt.Sprintf("triggering update of storage partitions to use suggested layout, current: %#+v, suggested: %#+v", currentClusterInfo.StoragePartitions, suggestedStorageLayout))
@@ -1558,6 +1697,16 @@ func (r *HumioClusterReconciler) ensurePartitionsAreBalanced(hc *humiov1alpha1.H
 	if err != nil {
 		return r.logErrorAndReturn(err, "could not get suggested ingest layout")
 	}
+	if nodePoolsRestrictRole(hnps, humiov1alpha1.HumioNodeRoleDigest) {
+		digestNodeIDs, err := r.nodeIDsWithRole(ctx, hnps, humiov1alpha1.HumioNodeRoleDigest)
+		if err != nil {
+			return r.logErrorAndReturn(err, "could not get nodes with digest role")
+		}
+		if len(digestNodeIDs) == 0 {
+			return r.logErrorAndReturn(fmt.Errorf("no nodes with digest role"), "could not restrict ingest partitions")
+		}
+		suggestedIngestLayout = restrictIngestPartitions(suggestedIngestLayout, digestNodeIDs)
+	}
 	currentIngestLayoutInput := helpers.MapIngestPartition(currentClusterInfo.IngestPartitions, helpers.ToIngestPartitionInput)
 	if !reflect.DeepEqual(currentIngestLayoutInput, suggestedIngestLayout) {
 		r.Log.Info(fmt.Sprintf("triggering update of ingest partitions to use suggested layout, current: %#+v, suggested: %#+v", currentClusterInfo.IngestPartitions, suggestedIngestLayout))
@@ -1571,49 +1720,26 @@ func (r *HumioClusterReconciler) ensurePartitionsAreBalanced(hc *humiov1alpha1.H
 
 func (r *HumioClusterReconciler) ensureService(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool) error {
 	r.Log.Info("ensuring service")
-	existingService, err := kubernetes.GetService(ctx, r, hnp.GetNodePoolName(), hnp.GetNamespace())
 	service := ConstructService(hnp)
-	if k8serrors.IsNotFound(err) {
-		if err := controllerutil.SetControllerReference(hc, service, r.Scheme()); err != nil {
-			return r.logErrorAndReturn(err, "could not set controller reference")
-		}
-		r.Log.Info(fmt.Sprintf("creating service %s of type %s with Humio port %d and ES port %d", service.Name, service.Spec.Type, hnp.GetHumioServicePort(), hnp.GetHumioESServicePort()))
-		if err = r.Create(ctx, service); err != nil {
-			return r.logErrorAndReturn(err, "unable to create service for HumioCluster")
-		}
-		return nil
+	if err := controllerutil.SetControllerReference(hc, service, r.Scheme()); err != nil {
+		return r.logErrorAndReturn(err, "could not set controller reference")
 	}
-
-	if servicesMatchTest, err := servicesMatch(existingService, service); !servicesMatchTest || err != nil {
-		r.Log.Info(fmt.Sprintf("service %s requires update: %s", existingService.Name, err))
-		updateService(existingService, service)
-		if err = r.Update(ctx, existingService); err != nil {
-			return r.logErrorAndReturn(err, fmt.Sprintf("could not update service %s", service.Name))
-		}
+	// The service is applied on every reconcile, which does not change it unless the desired service differs, while
+	// keeping annotations and labels added by others, such as service meshes
+	if err := r.apply(ctx, hc, service); err != nil {
+		return r.logErrorAndReturn(err, fmt.Sprintf("could not apply service %s", service.Name))
 	}
 	return nil
 }
 
 func (r *HumioClusterReconciler) ensureHeadlessServiceExists(ctx context.Context, hc *humiov1alpha1.HumioCluster) error {
 	r.Log.Info("ensuring headless service")
-	existingService, err := kubernetes.GetService(ctx, r, headlessServiceName(hc.Name), hc.Namespace)
 	service := constructHeadlessService(hc)
-	if k8serrors.IsNotFound(err) {
-		if err := controllerutil.SetControllerReference(hc, service, r.Scheme()); err != nil {
-			return r.logErrorAndReturn(err, "could not set controller reference")
-		}
-		err = r.Create(ctx, service)
-		if err != nil {
-			return r.logErrorAndReturn(err, "unable to create headless service for HumioCluster")
-		}
-		return nil
+	if err := controllerutil.SetControllerReference(hc, service, r.Scheme()); err != nil {
+		return r.logErrorAndReturn(err, "could not set controller reference")
 	}
-	if servicesMatchTest, err := servicesMatch(existingService, service); !servicesMatchTest || err != nil {
-		r.Log.Info(fmt.Sprintf("service %s requires update: %s", existingService.Name, err))
-		updateService(existingService, service)
-		if err = r.Update(ctx, existingService); err != nil {
-			return r.logErrorAndReturn(err, fmt.Sprintf("could not update service %s", service.Name))
-		}
+	if err := r.apply(ctx, hc, service); err != nil {
+		return r.logErrorAndReturn(err, fmt.Sprintf("could not apply headless service %s", service.Name))
 	}
 	return nil
 }
@@ -1627,8 +1753,7 @@ func (r *HumioClusterReconciler) ensureNodePoolSpecificResourcesHaveLabelWithNod
 	}
 	for idx, pod := range allPods {
 		if _, found := pod.Labels[kubernetes.NodePoolLabelName]; !found {
-			allPods[idx].SetLabels(hnp.GetPodLabels())
-			err = r.Client.Update(ctx, &allPods[idx])
+			err = kubernetes.ApplyLabels(ctx, r, &allPods[idx], hnp.GetPodLabels())
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to update pod")
 			}
@@ -1642,8 +1767,7 @@ func (r *HumioClusterReconciler) ensureNodePoolSpecificResourcesHaveLabelWithNod
 		}
 		for idx, cert := range allNodeCertificates {
 			if _, found := cert.Labels[kubernetes.NodePoolLabelName]; !found {
-				allNodeCertificates[idx].SetLabels(hnp.GetNodePoolLabels())
-				err = r.Client.Update(ctx, &allNodeCertificates[idx])
+				err = kubernetes.ApplyLabels(ctx, r, &allNodeCertificates[idx], hnp.GetNodePoolLabels())
 				if err != nil {
 					return r.logErrorAndReturn(err, "unable to update node certificate")
 				}
@@ -1658,8 +1782,7 @@ func (r *HumioClusterReconciler) ensureNodePoolSpecificResourcesHaveLabelWithNod
 		}
 		for idx, pvc := range allPVCs {
 			if _, found := pvc.Labels[kubernetes.NodePoolLabelName]; !found {
-				allPVCs[idx].SetLabels(hnp.GetNodePoolLabels())
-				err = r.Client.Update(ctx, &allPVCs[idx])
+				err = kubernetes.ApplyLabels(ctx, r, &allPVCs[idx], hnp.GetNodePoolLabels())
 				if err != nil {
 					return r.logErrorAndReturn(err, "unable to update pvc")
 				}
@@ -1670,8 +1793,7 @@ func (r *HumioClusterReconciler) ensureNodePoolSpecificResourcesHaveLabelWithNod
 	if !hnp.HumioServiceAccountIsSetByUser() {
 		serviceAccount, err := kubernetes.GetServiceAccount(ctx, r.Client, hnp.GetHumioServiceAccountName(), hnp.GetNamespace())
 		if err == nil {
-			serviceAccount.SetLabels(hnp.GetNodePoolLabels())
-			err = r.Client.Update(ctx, serviceAccount)
+			err = kubernetes.ApplyLabels(ctx, r, serviceAccount, hnp.GetNodePoolLabels())
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to update humio service account")
 			}
@@ -1686,8 +1808,7 @@ func (r *HumioClusterReconciler) ensureNodePoolSpecificResourcesHaveLabelWithNod
 	if !hnp.InitServiceAccountIsSetByUser() {
 		serviceAccount, err := kubernetes.GetServiceAccount(ctx, r.Client, hnp.GetInitServiceAccountName(), hnp.GetNamespace())
 		if err == nil {
-			serviceAccount.SetLabels(hnp.GetNodePoolLabels())
-			err = r.Client.Update(ctx, serviceAccount)
+			err = kubernetes.ApplyLabels(ctx, r, serviceAccount, hnp.GetNodePoolLabels())
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to update init service account")
 			}
@@ -1700,8 +1821,7 @@ func (r *HumioClusterReconciler) ensureNodePoolSpecificResourcesHaveLabelWithNod
 
 		clusterRole, err := kubernetes.GetClusterRole(ctx, r.Client, hnp.GetInitClusterRoleName())
 		if err == nil {
-			clusterRole.SetLabels(hnp.GetNodePoolLabels())
-			err = r.Client.Update(ctx, clusterRole)
+			err = kubernetes.ApplyLabels(ctx, r, clusterRole, hnp.GetNodePoolLabels())
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to update init cluster role")
 			}
@@ -1714,8 +1834,7 @@ func (r *HumioClusterReconciler) ensureNodePoolSpecificResourcesHaveLabelWithNod
 
 		clusterRoleBinding, err := kubernetes.GetClusterRoleBinding(ctx, r.Client, hnp.GetInitClusterRoleBindingName())
 		if err == nil {
-			clusterRoleBinding.SetLabels(hnp.GetNodePoolLabels())
-			err = r.Client.Update(ctx, clusterRoleBinding)
+			err = kubernetes.ApplyLabels(ctx, r, clusterRoleBinding, hnp.GetNodePoolLabels())
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to update init cluster role binding")
 			}
@@ -1730,8 +1849,7 @@ func (r *HumioClusterReconciler) ensureNodePoolSpecificResourcesHaveLabelWithNod
 	if !hnp.AuthServiceAccountIsSetByUser() {
 		serviceAccount, err := kubernetes.GetServiceAccount(ctx, r.Client, hnp.GetAuthServiceAccountName(), hnp.GetNamespace())
 		if err == nil {
-			serviceAccount.SetLabels(hnp.GetNodePoolLabels())
-			err = r.Client.Update(ctx, serviceAccount)
+			err = kubernetes.ApplyLabels(ctx, r, serviceAccount, hnp.GetNodePoolLabels())
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to update auth service account")
 			}
@@ -1744,8 +1862,7 @@ func (r *HumioClusterReconciler) ensureNodePoolSpecificResourcesHaveLabelWithNod
 
 		role, err := kubernetes.GetRole(ctx, r.Client, hnp.GetAuthRoleName(), hnp.GetNamespace())
 		if err == nil {
-			role.SetLabels(hnp.GetNodePoolLabels())
-			err = r.Client.Update(ctx, role)
+			err = kubernetes.ApplyLabels(ctx, r, role, hnp.GetNodePoolLabels())
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to update auth role")
 			}
@@ -1758,8 +1875,7 @@ func (r *HumioClusterReconciler) ensureNodePoolSpecificResourcesHaveLabelWithNod
 
 		roleBinding, err := kubernetes.GetRoleBinding(ctx, r.Client, hnp.GetAuthRoleBindingName(), hnp.GetNamespace())
 		if err == nil {
-			roleBinding.SetLabels(hnp.GetNodePoolLabels())
-			err = r.Client.Update(ctx, roleBinding)
+			err = kubernetes.ApplyLabels(ctx, r, roleBinding, hnp.GetNodePoolLabels())
 			if err != nil {
 				return r.logErrorAndReturn(err, "unable to update auth role binding")
 			}
@@ -2030,7 +2146,7 @@ func (r *HumioClusterReconciler) ensureHumioServiceAccountAnnotations(ctx contex
 		r.Log.Info(fmt.Sprintf("service account annotations do not match: annotations %s, got %s. updating service account %s",
 			serviceAccountAnnotationsString, existingServiceAccountAnnotationsString, existingServiceAccount.Name))
 		existingServiceAccount.Annotations = serviceAccount.Annotations
-		if err = r.Update(ctx, existingServiceAccount); err != nil {
+		if err = r.Update(ctx, existingServiceAccount, client.FieldOwner(kubernetes.FieldManager)); err != nil {
 			return false, r.logErrorAndReturn(err, fmt.Sprintf("could not update service account %s", existingServiceAccount.Name))
 		}
 
@@ -2082,6 +2198,27 @@ func (r *HumioClusterReconciler) ensureMismatchedPodsAreDeleted(ctx context.Cont
 		attachments.envVarSourceData = envVarSourceData
 	}
 
+	extraConfigFilesData, err := r.getExtraConfigFilesData(ctx, hnp)
+	if err != nil {
+		result, _ := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withMessage(r.logErrorAndReturn(err, "got error when getting pod extraConfigFiles").Error()).
+			withState(humiov1alpha1.HumioClusterStateConfigError))
+		return result, err
+	}
+	attachments.extraConfigFilesData = extraConfigFilesData
+
+	secretsStoreData, err := r.getSecretsStoreData(ctx, hnp)
+	if err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "got error when getting pod secretsStore data")
+	}
+	attachments.secretsStoreData = secretsStoreData
+
+	envVarSecretsData, err := r.getEnvVarSecretsData(ctx, hnp)
+	if err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "got error when getting pod envVarSecrets data")
+	}
+	attachments.envVarSecretsData = envVarSecretsData
+
 	// prioritize deleting the pods with errors
 	var podList []corev1.Pod
 	if podsStatus.havePodsWithErrors() {
@@ -2105,6 +2242,32 @@ func (r *HumioClusterReconciler) ensureMismatchedPodsAreDeleted(ctx context.Cont
 		return reconcile.Result{RequeueAfter: time.Second + 1}, nil
 	}
 
+	// Configuration changes are held back according to the config change policy, unless the restart has already begun
+	// or pods with errors need to be replaced
+	pendingConfigChanges := false
+	if desiredLifecycleState.WantsRestart() && !desiredLifecycleState.WantsUpgrade() && !podsStatus.havePodsWithErrors() &&
+		(hc.Status.State == humiov1alpha1.HumioClusterStateRunning || hc.Status.State == humiov1alpha1.HumioClusterStateConfigError) {
+		allowed, message, err := configChangeAllowed(hnp, time.Now())
+		if err != nil {
+			return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+				withMessage(r.logErrorAndReturn(err, "invalid config change policy").Error()).
+				withState(humiov1alpha1.HumioClusterStateConfigError))
+		}
+		if !allowed {
+			r.Log.Info(message)
+			pendingConfigChanges = true
+		}
+	}
+	if pendingConfigChanges != nodePoolHasPendingConfigChanges(hc, hnp) {
+		if _, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withNodePoolPendingConfigChanges(pendingConfigChanges, hnp.GetNodePoolName())); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set pending config changes")
+		}
+	}
+	if pendingConfigChanges {
+		return reconcile.Result{}, nil
+	}
+
 	// If we are currently deleting pods, then check if the cluster state is Running or in a ConfigError state. If it
 	// is, then change to an appropriate state depending on the restart policy.
 	// If the cluster state is set as per the restart policy:
@@ -2133,7 +2296,20 @@ func (r *HumioClusterReconciler) ensureMismatchedPodsAreDeleted(ctx context.Cont
 			}
 		}
 	}
+	if hnp.GetUpdateStrategy().Type == humiov1alpha1.HumioClusterUpdateStrategyBlueGreen &&
+		(desiredLifecycleState.WantsUpgrade() || desiredLifecycleState.WantsRestart()) {
+		return r.ensureBlueGreenUpdate(ctx, hc, hnp, foundPodList)
+	}
 	if desiredLifecycleState.ShouldDeletePod() {
+		// Replacing a crash-looping pod resets the back-off of the kubelet, so back off before replacing it
+		if crashLoop := getPodCrashLoop(desiredLifecycleState.pod); crashLoop != nil {
+			if remaining := crashLoop.remainingBackOff(time.Now()); remaining > 0 {
+				r.Log.Info(fmt.Sprintf("pod %s should be deleted, but backing off for %s because it is crash-looping",
+					desiredLifecycleState.pod.Name, remaining))
+				return reconcile.Result{RequeueAfter: remaining}, nil
+			}
+		}
+
 		if hc.Status.State == humiov1alpha1.HumioClusterStateRestarting && podsStatus.waitingOnPods() && desiredLifecycleState.ShouldRollingRestart() {
 			r.Log.Info(fmt.Sprintf("pod %s should be deleted, but waiting because not all other pods are "+
 				"ready. waitingOnPods=%v, clusterState=%s", desiredLifecycleState.pod.Name,
@@ -2210,33 +2386,6 @@ func (r *HumioClusterReconciler) ensureMismatchedPodsAreDeleted(ctx context.Cont
 	return reconcile.Result{}, nil
 }
 
-func (r *HumioClusterReconciler) ingressesMatch(ingress *networkingv1.Ingress, desiredIngress *networkingv1.Ingress) bool {
-	// Kubernetes 1.18 introduced a new field, PathType. For older versions PathType is returned as nil,
-	// so we explicitly set the value before comparing ingress objects.
-	// When minimum supported Kubernetes version is 1.18, we can drop this.
-	pathTypeImplementationSpecific := networkingv1.PathTypeImplementationSpecific
-	for ruleIdx, rule := range ingress.Spec.Rules {
-		for pathIdx := range rule.HTTP.Paths {
-			if ingress.Spec.Rules[ruleIdx].HTTP.Paths[pathIdx].PathType == nil {
-				ingress.Spec.Rules[ruleIdx].HTTP.Paths[pathIdx].PathType = &pathTypeImplementationSpecific
-			}
-		}
-	}
-
-	if !reflect.DeepEqual(ingress.Spec, desiredIngress.Spec) {
-		r.Log.Info(fmt.Sprintf("ingress specs do not match: got %+v, wanted %+v", ingress.Spec, desiredIngress.Spec))
-		return false
-	}
-
-	ingressAnnotations := helpers.MapToSortedString(ingress.Annotations)
-	desiredIngressAnnotations := helpers.MapToSortedString(desiredIngress.Annotations)
-	if ingressAnnotations != desiredIngressAnnotations {
-		r.Log.Info(fmt.Sprintf("ingress annotations do not match: got %s, wanted %s", ingressAnnotations, desiredIngressAnnotations))
-		return false
-	}
-	return true
-}
-
 func (r *HumioClusterReconciler) ensurePodsExist(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool) (reconcile.Result, error) {
 	// Ensure we have pods for the defined NodeCount.
 	// If scaling down, we will handle the extra/obsolete pods later.
@@ -2246,10 +2395,11 @@ func (r *HumioClusterReconciler) ensurePodsExist(ctx context.Context, hc *humiov
 	}
 	var expectedPodsList []corev1.Pod
 	pvcClaimNamesInUse := make(map[string]struct{})
+	dataNodesInUse := make(map[string]struct{})
 
 	if len(foundPodList) < hnp.GetNodeCount() {
 		for i := 1; i+len(foundPodList) <= hnp.GetNodeCount(); i++ {
-			attachments, err := r.newPodAttachments(ctx, hnp, foundPodList, pvcClaimNamesInUse)
+			attachments, err := r.newPodAttachments(ctx, hnp, foundPodList, pvcClaimNamesInUse, dataNodesInUse)
 			if err != nil {
 				return reconcile.Result{RequeueAfter: time.Second * 5}, r.logErrorAndReturn(err, "failed to get pod attachments")
 			}
@@ -2276,6 +2426,11 @@ func (r *HumioClusterReconciler) ensurePodsExist(ctx context.Context, hc *humiov
 }
 
 func (r *HumioClusterReconciler) ensurePersistentVolumeClaimsExist(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool) error {
+	return r.ensurePersistentVolumeClaimCount(ctx, hc, hnp, hnp.GetNodeCount())
+}
+
+// ensurePersistentVolumeClaimCount adds a pvc for the node pool if there are fewer than the given number of pvcs
+func (r *HumioClusterReconciler) ensurePersistentVolumeClaimCount(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool, count int) error {
 	if !hnp.PVCsEnabled() {
 		r.Log.Info("pvcs are disabled. skipping")
 		return nil
@@ -2288,15 +2443,15 @@ func (r *HumioClusterReconciler) ensurePersistentVolumeClaimsExist(ctx context.C
 	}
 	r.Log.Info(fmt.Sprintf("found %d pvcs", len(foundPersistentVolumeClaims)))
 
-	if len(foundPersistentVolumeClaims) < hnp.GetNodeCount() {
-		r.Log.Info(fmt.Sprintf("pvc count of %d is less than %d. adding more", len(foundPersistentVolumeClaims), hnp.GetNodeCount()))
+	if len(foundPersistentVolumeClaims) < count {
+		r.Log.Info(fmt.Sprintf("pvc count of %d is less than %d. adding more", len(foundPersistentVolumeClaims), count))
 		pvc := constructPersistentVolumeClaim(hnp)
 		pvc.Annotations[pvcHashAnnotation] = helpers.AsSHA256(pvc.Spec)
 		if err := controllerutil.SetControllerReference(hc, pvc, r.Scheme()); err != nil {
 			return r.logErrorAndReturn(err, "could not set controller reference")
 		}
 		r.Log.Info(fmt.Sprintf("creating pvc: %s", pvc.Name))
-		if err = r.Create(ctx, pvc); err != nil {
+		if err = r.Create(ctx, pvc, client.FieldOwner(kubernetes.FieldManager)); err != nil {
 			return r.logErrorAndReturn(err, "unable to create pvc")
 		}
 		r.Log.Info(fmt.Sprintf("successfully created pvc %s for HumioCluster %s", pvc.Name, hnp.GetNodePoolName()))
@@ -0,0 +1,178 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// crashLoopRestartThreshold is the number of restarts of the Humio container of a pod which is not ready, after
+	// which the pod is considered to be crash-looping
+	crashLoopRestartThreshold = 3
+	// crashLoopBackOffInitial and crashLoopBackOffMaximum bound how long a crash-looping pod is kept before the
+	// operator replaces it. The back-off doubles with each restart of the Humio container.
+	crashLoopBackOffInitial = 10 * time.Second
+	crashLoopBackOffMaximum = 10 * time.Minute
+	// crashLoopLogLines is the number of log lines of the last run of the Humio container reported as the probable
+	// cause of a crash loop
+	crashLoopLogLines = 10
+
+	containerStateCrashLoopBackOff = "CrashLoopBackOff"
+
+	crashLoopBackOffReason = "CrashLoopBackOff"
+	noCrashLoopReason      = "NoCrashLoop"
+)
+
+// podCrashLoop describes a pod whose Humio container is crash-looping
+type podCrashLoop struct {
+	pod          corev1.Pod
+	restartCount int32
+	exitCode     int32
+	reason       string
+	logLines     string
+}
+
+// getPodCrashLoop returns the crash loop of the Humio container of the pod, or nil if it is not crash-looping
+func getPodCrashLoop(pod corev1.Pod) *podCrashLoop {
+	if pod.DeletionTimestamp != nil {
+		return nil
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.Name != HumioContainerName || containerStatus.Ready {
+			continue
+		}
+		waitingInBackOff := containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason == containerStateCrashLoopBackOff
+		if !waitingInBackOff && containerStatus.RestartCount < crashLoopRestartThreshold {
+			return nil
+		}
+		crashLoop := &podCrashLoop{
+			pod:          pod,
+			restartCount: containerStatus.RestartCount,
+		}
+		if terminated := containerStatus.LastTerminationState.Terminated; terminated != nil {
+			crashLoop.exitCode = terminated.ExitCode
+			crashLoop.reason = terminated.Reason
+			crashLoop.logLines = lastLines(terminated.Message, crashLoopLogLines)
+		}
+		return crashLoop
+	}
+	return nil
+}
+
+// message returns the probable cause of the crash loop
+func (c *podCrashLoop) message() string {
+	message := fmt.Sprintf("pod %s is crash-looping after %d restarts", c.pod.Name, c.restartCount)
+	if c.reason != "" || c.exitCode != 0 {
+		message = fmt.Sprintf("%s, last exit code %d (%s)", message, c.exitCode, c.reason)
+	}
+	if c.logLines != "" {
+		message = fmt.Sprintf("%s, last log lines:\n%s", message, c.logLines)
+	}
+	return message
+}
+
+// remainingBackOff returns how long the operator should wait before replacing the crash-looping pod. Replacing the pod
+// resets the back-off of the kubelet, so the pod is kept for longer the more often the Humio container has restarted.
+func (c *podCrashLoop) remainingBackOff(now time.Time) time.Duration {
+	backOff := crashLoopBackOffInitial
+	for i := int32(1); i < c.restartCount && backOff < crashLoopBackOffMaximum; i++ {
+		backOff *= 2
+	}
+	if backOff > crashLoopBackOffMaximum {
+		backOff = crashLoopBackOffMaximum
+	}
+	return c.pod.CreationTimestamp.Add(backOff).Sub(now)
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// crashLoopingCondition returns the CrashLooping condition of the cluster for the given crash loops
+func crashLoopingCondition(crashLoops []*podCrashLoop) metav1.Condition {
+	if len(crashLoops) == 0 {
+		return metav1.Condition{
+			Type:    humiov1alpha1.HumioClusterConditionTypeCrashLooping,
+			Status:  metav1.ConditionFalse,
+			Reason:  noCrashLoopReason,
+			Message: "no pods are crash-looping",
+		}
+	}
+	var messages []string
+	for _, crashLoop := range crashLoops {
+		messages = append(messages, crashLoop.message())
+	}
+	return metav1.Condition{
+		Type:    humiov1alpha1.HumioClusterConditionTypeCrashLooping,
+		Status:  metav1.ConditionTrue,
+		Reason:  crashLoopBackOffReason,
+		Message: strings.Join(messages, "\n"),
+	}
+}
+
+// ensureCrashLoopsReported sets the CrashLooping condition of the cluster with the probable cause of pods which are
+// crash-looping. A Warning event is emitted for each crash-looping pod when the cluster starts crash-looping.
+func (r *HumioClusterReconciler) ensureCrashLoopsReported(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnps []*HumioNodePool) error {
+	var crashLoops []*podCrashLoop
+	for _, hnp := range hnps {
+		pods, err := kubernetes.ListPods(ctx, r, hnp.GetNamespace(), hnp.GetNodePoolLabels())
+		if err != nil {
+			return fmt.Errorf("failed to list pods of node pool %s: %w", hnp.GetNodePoolName(), err)
+		}
+		for _, pod := range pods {
+			if crashLoop := getPodCrashLoop(pod); crashLoop != nil {
+				crashLoops = append(crashLoops, crashLoop)
+			}
+		}
+	}
+	sort.Slice(crashLoops, func(i, j int) bool {
+		return crashLoops[i].pod.Name < crashLoops[j].pod.Name
+	})
+
+	condition := crashLoopingCondition(crashLoops)
+	existing := meta.FindStatusCondition(hc.Status.Conditions, humiov1alpha1.HumioClusterConditionTypeCrashLooping)
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason && existing.Message == condition.Message {
+		return nil
+	}
+
+	if condition.Status == metav1.ConditionTrue && (existing == nil || existing.Status != metav1.ConditionTrue) {
+		for _, crashLoop := range crashLoops {
+			r.Log.Info(crashLoop.message())
+			if r.Recorder != nil {
+				r.Recorder.Event(hc, corev1.EventTypeWarning, crashLoopBackOffReason, crashLoop.message())
+			}
+		}
+	}
+	_, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+		withCondition(condition))
+	return err
+}
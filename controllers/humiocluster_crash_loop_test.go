@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func crashLoopingPod(name string, labels map[string]string, restartCount int32) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:         HumioContainerName,
+					RestartCount: restartCount,
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{Reason: containerStateCrashLoopBackOff},
+					},
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							ExitCode: 137,
+							Reason:   "OOMKilled",
+							Message:  "line 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\nline 11\nline 12\n",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGetPodCrashLoop(t *testing.T) {
+	restarting := crashLoopingPod("restarting", nil, crashLoopRestartThreshold)
+	restarting.Status.ContainerStatuses[0].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	recovered := crashLoopingPod("recovered", nil, crashLoopRestartThreshold)
+	recovered.Status.ContainerStatuses[0].Ready = true
+	recovered.Status.ContainerStatuses[0].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	fewRestarts := crashLoopingPod("few-restarts", nil, 1)
+	fewRestarts.Status.ContainerStatuses[0].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+
+	tt := []struct {
+		name            string
+		pod             corev1.Pod
+		expectCrashLoop bool
+	}{
+		{
+			name:            "waiting in back-off",
+			pod:             crashLoopingPod("back-off", nil, 1),
+			expectCrashLoop: true,
+		},
+		{
+			name:            "restarted too often",
+			pod:             restarting,
+			expectCrashLoop: true,
+		},
+		{
+			name: "recovered",
+			pod:  recovered,
+		},
+		{
+			name: "few restarts",
+			pod:  fewRestarts,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			crashLoop := getPodCrashLoop(tc.pod)
+			if (crashLoop != nil) != tc.expectCrashLoop {
+				t.Fatalf("expected crash loop %v, got %+v", tc.expectCrashLoop, crashLoop)
+			}
+			if crashLoop == nil {
+				return
+			}
+			message := crashLoop.message()
+			if !strings.Contains(message, "last exit code 137 (OOMKilled)") {
+				t.Errorf("expected exit code and reason in message, got %q", message)
+			}
+			if !strings.HasSuffix(message, "line 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\nline 11\nline 12") ||
+				strings.Contains(message, "line 2\n") {
+				t.Errorf("expected the last %d log lines in message, got %q", crashLoopLogLines, message)
+			}
+		})
+	}
+}
+
+func TestPodCrashLoopRemainingBackOff(t *testing.T) {
+	now := time.Now()
+	tt := []struct {
+		name         string
+		restartCount int32
+		age          time.Duration
+		expected     time.Duration
+	}{
+		{
+			name:         "first restart",
+			restartCount: 1,
+			expected:     crashLoopBackOffInitial,
+		},
+		{
+			name:         "back-off doubles",
+			restartCount: 3,
+			age:          10 * time.Second,
+			expected:     30 * time.Second,
+		},
+		{
+			name:         "back-off is capped",
+			restartCount: 30,
+			expected:     crashLoopBackOffMaximum,
+		},
+		{
+			name:         "back-off elapsed",
+			restartCount: 2,
+			age:          time.Minute,
+			expected:     -40 * time.Second,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := crashLoopingPod("pod", nil, tc.restartCount)
+			pod.CreationTimestamp = metav1.NewTime(now.Add(-tc.age))
+			crashLoop := getPodCrashLoop(pod)
+			if remaining := crashLoop.remainingBackOff(now); remaining != tc.expected {
+				t.Errorf("expected remaining back-off %s, got %s", tc.expected, remaining)
+			}
+		})
+	}
+}
+
+func TestEnsureCrashLoopsReported(t *testing.T) {
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "crash-loop", Namespace: "default"},
+	}
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+	pod := crashLoopingPod("crash-loop-core-abcde", hnp.GetNodePoolLabels(), 5)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	recorder := record.NewFakeRecorder(10)
+	r := &HumioClusterReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects([]client.Object{hc, &pod}...).WithStatusSubresource(hc).Build(),
+		Log:      logr.Discard(),
+		Recorder: recorder,
+	}
+
+	getCondition := func() *metav1.Condition {
+		var updated humiov1alpha1.HumioCluster
+		if err := r.Get(context.Background(), types.NamespacedName{Name: hc.Name, Namespace: hc.Namespace}, &updated); err != nil {
+			t.Fatal(err)
+		}
+		*hc = updated
+		return meta.FindStatusCondition(updated.Status.Conditions, humiov1alpha1.HumioClusterConditionTypeCrashLooping)
+	}
+
+	if err := r.ensureCrashLoopsReported(context.Background(), hc, []*HumioNodePool{hnp}); err != nil {
+		t.Fatal(err)
+	}
+	condition := getCondition()
+	if condition == nil || condition.Status != metav1.ConditionTrue || !strings.Contains(condition.Message, pod.Name) {
+		t.Errorf("expected CrashLooping condition to be True for pod %s, got %+v", pod.Name, condition)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one event, got %d", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.Contains(event, crashLoopBackOffReason) {
+		t.Errorf("expected %s event, got %q", crashLoopBackOffReason, event)
+	}
+
+	// The event is only emitted when the cluster starts crash-looping
+	pod.Status.ContainerStatuses[0].RestartCount++
+	if err := r.Update(context.Background(), &pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ensureCrashLoopsReported(context.Background(), hc, []*HumioNodePool{hnp}); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event while the cluster is still crash-looping, got %d", len(recorder.Events))
+	}
+
+	if err := r.Delete(context.Background(), &pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ensureCrashLoopsReported(context.Background(), hc, []*HumioNodePool{hnp}); err != nil {
+		t.Fatal(err)
+	}
+	if condition = getCondition(); condition == nil || condition.Status != metav1.ConditionFalse {
+		t.Errorf("expected CrashLooping condition to be False, got %+v", condition)
+	}
+}
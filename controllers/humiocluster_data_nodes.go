@@ -0,0 +1,162 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// validateDataVolumeNodePinning returns an error if the pods of the node pool cannot be pinned to the worker nodes
+// holding their data
+func validateDataVolumeNodePinning(hnp *HumioNodePool) error {
+	if !hnp.DataVolumeNodePinningEnabled() {
+		return nil
+	}
+	if hnp.GetDataVolumeSource().HostPath == nil {
+		return fmt.Errorf("dataVolumeNodePinning requires dataVolumeSource to use hostPath")
+	}
+	return nil
+}
+
+// podDataNode returns the worker node holding the data of the pod. This is the node the pod is scheduled on, or the
+// node the pod is pinned to if it is not scheduled yet.
+func podDataNode(pod corev1.Pod) string {
+	if pod.Spec.NodeName != "" {
+		return pod.Spec.NodeName
+	}
+	return pod.Spec.NodeSelector[corev1.LabelHostname]
+}
+
+// findNextAvailableDataNode returns the first worker node holding data of the node pool which is not used by any of
+// the pods, or an empty string if there is none. The returned worker node is added to dataNodesInUse.
+func findNextAvailableDataNode(dataNodes []string, podList []corev1.Pod, dataNodesInUse map[string]struct{}) string {
+	for _, pod := range podList {
+		if dataNode := podDataNode(pod); dataNode != "" {
+			dataNodesInUse[dataNode] = struct{}{}
+		}
+	}
+	for _, dataNode := range dataNodes {
+		if _, found := dataNodesInUse[dataNode]; !found {
+			dataNodesInUse[dataNode] = struct{}{}
+			return dataNode
+		}
+	}
+	return ""
+}
+
+// updatedDataNodes returns the worker nodes holding data of the node pool. Worker nodes the pods are scheduled on are
+// added, worker nodes which no longer exist are removed, and worker nodes not used by any pods are removed when there are
+// more worker nodes than the node count of the node pool.
+func updatedDataNodes(dataNodes []string, podList []corev1.Pod, nodeCount int, nodeExists func(string) (bool, error)) ([]string, error) {
+	inUse := map[string]bool{}
+	for _, pod := range podList {
+		if dataNode := podDataNode(pod); dataNode != "" {
+			inUse[dataNode] = true
+		}
+	}
+
+	updated := []string{}
+	seen := map[string]bool{}
+	for _, dataNode := range dataNodes {
+		if seen[dataNode] {
+			continue
+		}
+		seen[dataNode] = true
+		exists, err := nodeExists(dataNode)
+		if err != nil {
+			return dataNodes, err
+		}
+		if exists {
+			updated = append(updated, dataNode)
+		}
+	}
+	for _, pod := range podList {
+		if dataNode := podDataNode(pod); dataNode != "" && !seen[dataNode] && pod.Spec.NodeName != "" {
+			seen[dataNode] = true
+			updated = append(updated, dataNode)
+		}
+	}
+
+	for idx := len(updated) - 1; idx >= 0 && len(updated) > nodeCount; idx-- {
+		if !inUse[updated[idx]] {
+			updated = append(updated[:idx], updated[idx+1:]...)
+		}
+	}
+	return updated, nil
+}
+
+// withDataNodes records the worker nodes holding data of the node pools which have dataVolumeNodePinning enabled
+func (r *HumioClusterReconciler) withDataNodes(ctx context.Context, opts *optionBuilder, hnps []*HumioNodePool) (*optionBuilder, error) {
+	nodeExists := func(nodeName string) (bool, error) {
+		if _, err := kubernetes.GetNode(ctx, r, nodeName); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	for _, hnp := range hnps {
+		if !hnp.DataVolumeNodePinningEnabled() {
+			continue
+		}
+		pods, err := kubernetes.ListPods(ctx, r, hnp.GetNamespace(), hnp.GetNodePoolLabels())
+		if err != nil {
+			return opts, fmt.Errorf("failed to list pods of node pool %s: %w", hnp.GetNodePoolName(), err)
+		}
+		dataNodes, err := updatedDataNodes(hnp.GetDataNodes(), pods, hnp.GetNodeCount(), nodeExists)
+		if err != nil {
+			return opts, fmt.Errorf("failed to update data nodes of node pool %s: %w", hnp.GetNodePoolName(), err)
+		}
+		opts = opts.withNodePoolDataNodes(dataNodes, hnp.GetNodePoolName())
+	}
+	return opts, nil
+}
+
+// ensurePodsPinnedToDeletedNodesAreDeleted deletes pods which cannot be scheduled because they are pinned to a worker
+// node which no longer exists. The data on the worker node is gone, so the pods are recreated without being pinned.
+func (r *HumioClusterReconciler) ensurePodsPinnedToDeletedNodesAreDeleted(ctx context.Context, hnp *HumioNodePool) error {
+	if !hnp.DataVolumeNodePinningEnabled() {
+		return nil
+	}
+	pods, err := kubernetes.ListPods(ctx, r, hnp.GetNamespace(), hnp.GetNodePoolLabels())
+	if err != nil {
+		return r.logErrorAndReturn(err, "failed to list pods")
+	}
+	for idx, pod := range pods {
+		dataNode := podDataNode(pod)
+		if pod.Spec.NodeName != "" || dataNode == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if _, err := kubernetes.GetNode(ctx, r, dataNode); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return r.logErrorAndReturn(err, fmt.Sprintf("could not get node %s", dataNode))
+			}
+			r.Log.Info(fmt.Sprintf("deleting pod %s as it is pinned to node %s which no longer exists", pod.Name, dataNode))
+			if err := r.Delete(ctx, &pods[idx]); err != nil {
+				return r.logErrorAndReturn(err, fmt.Sprintf("could not delete pod %s", pod.Name))
+			}
+			hnp.removeDataNode(dataNode)
+		}
+	}
+	return nil
+}
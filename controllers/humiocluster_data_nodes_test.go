@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func scheduledPod(nodeName string) corev1.Pod {
+	return corev1.Pod{Spec: corev1.PodSpec{NodeName: nodeName}}
+}
+
+func pinnedPod(nodeName string) corev1.Pod {
+	return corev1.Pod{Spec: corev1.PodSpec{NodeSelector: map[string]string{corev1.LabelHostname: nodeName}}}
+}
+
+func TestFindNextAvailableDataNode(t *testing.T) {
+	dataNodes := []string{"node-1", "node-2", "node-3"}
+	pods := []corev1.Pod{scheduledPod("node-1"), pinnedPod("node-2")}
+	dataNodesInUse := map[string]struct{}{}
+
+	if dataNode := findNextAvailableDataNode(dataNodes, pods, dataNodesInUse); dataNode != "node-3" {
+		t.Errorf("expected node-3, got %q", dataNode)
+	}
+	if dataNode := findNextAvailableDataNode(dataNodes, pods, dataNodesInUse); dataNode != "" {
+		t.Errorf("expected no available data node, got %q", dataNode)
+	}
+}
+
+func TestUpdatedDataNodes(t *testing.T) {
+	existingNodes := map[string]bool{"node-1": true, "node-2": true, "node-3": true, "node-4": true}
+	nodeExists := func(nodeName string) (bool, error) {
+		return existingNodes[nodeName], nil
+	}
+
+	for _, tc := range []struct {
+		name      string
+		dataNodes []string
+		pods      []corev1.Pod
+		nodeCount int
+		expected  []string
+	}{
+		{
+			name:      "new pods are recorded",
+			pods:      []corev1.Pod{scheduledPod("node-1"), scheduledPod("node-2"), pinnedPod("node-3")},
+			nodeCount: 3,
+			expected:  []string{"node-1", "node-2"},
+		},
+		{
+			name:      "data nodes of deleted pods are kept",
+			dataNodes: []string{"node-1", "node-2", "node-3"},
+			pods:      []corev1.Pod{scheduledPod("node-1"), scheduledPod("node-3")},
+			nodeCount: 3,
+			expected:  []string{"node-1", "node-2", "node-3"},
+		},
+		{
+			name:      "deleted worker nodes are removed",
+			dataNodes: []string{"node-1", "node-5", "node-3"},
+			pods:      []corev1.Pod{scheduledPod("node-1"), scheduledPod("node-3"), scheduledPod("node-4")},
+			nodeCount: 3,
+			expected:  []string{"node-1", "node-3", "node-4"},
+		},
+		{
+			name:      "unused data nodes are removed when scaling down",
+			dataNodes: []string{"node-1", "node-2", "node-3"},
+			pods:      []corev1.Pod{scheduledPod("node-1"), scheduledPod("node-3")},
+			nodeCount: 2,
+			expected:  []string{"node-1", "node-3"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := updatedDataNodes(tc.dataNodes, tc.pods, tc.nodeCount, nodeExists)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDataVolumeNodePinning(t *testing.T) {
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+		Spec: humiov1alpha1.HumioClusterSpec{
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				DataVolumeNodePinning: true,
+				DataVolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: "/mnt/nvme/humio"},
+				},
+			},
+		},
+		Status: humiov1alpha1.HumioClusterStatus{
+			NodePoolStatus: humiov1alpha1.HumioNodePoolStatusList{
+				{Name: "humiocluster", State: humiov1alpha1.HumioClusterStateRunning, DataNodes: []string{"node-1"}},
+			},
+		},
+	}
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+	if err := validateDataVolumeNodePinning(hnp); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(hnp.GetDataNodes(), []string{"node-1"}) {
+		t.Errorf("expected data nodes from status, got %v", hnp.GetDataNodes())
+	}
+
+	unpinnedPod, _ := ConstructPod(hnp, "", &podAttachments{dataVolumeSource: hnp.GetDataVolumeSource()})
+	pinnedPod, _ := ConstructPod(hnp, "", &podAttachments{dataVolumeSource: hnp.GetDataVolumeSource(), dataNodeName: "node-1"})
+	if pinnedPod.Spec.NodeSelector[corev1.LabelHostname] != "node-1" {
+		t.Errorf("expected pod to be pinned to node-1, got %v", pinnedPod.Spec.NodeSelector)
+	}
+	if podSpecAsSHA256(hnp, *unpinnedPod) != podSpecAsSHA256(hnp, *pinnedPod) {
+		t.Errorf("expected pinning to not change the pod hash")
+	}
+
+	hc.Spec.DataVolumeSource = corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	if err := validateDataVolumeNodePinning(NewHumioNodeManagerFromHumioCluster(hc)); err == nil {
+		t.Errorf("expected error when data volume source is not a hostPath")
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	deadNodeUnregistrationDefaultGracePeriodSeconds = 3600
+
+	nodeUnregisteredReason = "NodeUnregistered"
+)
+
+// deadClusterNodes returns the nodes of the cluster which are unavailable and not backed by any of the pods with the
+// given node IDs. Nodes which were already dead keep their detection time.
+func deadClusterNodes(cluster humioapi.Cluster, podNodeIDs map[int]bool, previous []humiov1alpha1.HumioDeadNode, now metav1.Time) []humiov1alpha1.HumioDeadNode {
+	detectionTimes := map[int]metav1.Time{}
+	for _, deadNode := range previous {
+		detectionTimes[deadNode.NodeID] = deadNode.DetectionTime
+	}
+
+	var deadNodes []humiov1alpha1.HumioDeadNode
+	for _, node := range cluster.Nodes {
+		if node.IsAvailable || podNodeIDs[node.Id] {
+			continue
+		}
+		detectionTime, ok := detectionTimes[node.Id]
+		if !ok {
+			detectionTime = now
+		}
+		deadNodes = append(deadNodes, humiov1alpha1.HumioDeadNode{NodeID: node.Id, DetectionTime: detectionTime})
+	}
+	return deadNodes
+}
+
+// deadNodeGracePeriodElapsed returns whether the node has been dead for longer than the grace period
+func deadNodeGracePeriodElapsed(hc *humiov1alpha1.HumioCluster, deadNode humiov1alpha1.HumioDeadNode, now time.Time) bool {
+	gracePeriodSeconds := hc.Spec.DeadNodeUnregistration.GracePeriodSeconds
+	if gracePeriodSeconds <= 0 {
+		gracePeriodSeconds = deadNodeUnregistrationDefaultGracePeriodSeconds
+	}
+	return !now.Before(deadNode.DetectionTime.Add(time.Second * time.Duration(gracePeriodSeconds)))
+}
+
+// ensureDeadNodesUnregistered unregisters the nodes of the cluster which have been dead for longer than the grace
+// period, once their data is available on other nodes
+func (r *HumioClusterReconciler) ensureDeadNodesUnregistered(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnps []*HumioNodePool, config *humioapi.Config, req reconcile.Request) error {
+	if hc.Spec.DeadNodeUnregistration == nil {
+		if len(hc.Status.DeadNodes) > 0 {
+			_, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withDeadNodes(nil))
+			return err
+		}
+		return nil
+	}
+
+	cluster, err := r.HumioClient.GetClusters(config, req)
+	if err != nil {
+		return r.logErrorAndReturn(err, "could not get cluster info")
+	}
+	podNodeIDs, err := r.podNodeIDs(ctx, hnps)
+	if err != nil {
+		return r.logErrorAndReturn(err, "could not get node ids of pods")
+	}
+	nodes := map[int]humioapi.ClusterNode{}
+	for _, node := range cluster.Nodes {
+		nodes[node.Id] = node
+	}
+
+	humioVersion, _ := HumioVersionFromString(NewHumioNodeManagerFromHumioCluster(hc).GetImage())
+	automaticPartitionManagement, _ := humioVersion.AtLeast(HumioVersionWithAutomaticPartitionManagement)
+
+	now := metav1.Now()
+	var remainingDeadNodes []humiov1alpha1.HumioDeadNode
+	for _, deadNode := range deadClusterNodes(cluster, podNodeIDs, hc.Status.DeadNodes, now) {
+		if !deadNodeGracePeriodElapsed(hc, deadNode, now.Time) {
+			deadNode.Message = "waiting for grace period to elapse"
+			remainingDeadNodes = append(remainingDeadNodes, deadNode)
+			continue
+		}
+		if !nodes[deadNode.NodeID].CanBeSafelyUnregistered {
+			if !automaticPartitionManagement {
+				if err = r.HumioClient.MoveRoutesAwayFromClusterNode(config, req, deadNode.NodeID); err != nil {
+					return r.logErrorAndReturn(err, fmt.Sprintf("could not move partitions away from dead node %d", deadNode.NodeID))
+				}
+			}
+			deadNode.Message = "waiting for data of the node to be replicated to other nodes"
+			remainingDeadNodes = append(remainingDeadNodes, deadNode)
+			continue
+		}
+
+		r.Log.Info(fmt.Sprintf("unregistering node %d which has been dead since %s", deadNode.NodeID, deadNode.DetectionTime.Format(time.RFC3339)))
+		if err = r.HumioClient.UnregisterClusterNode(config, req, deadNode.NodeID); err != nil {
+			return r.logErrorAndReturn(err, fmt.Sprintf("could not unregister dead node %d", deadNode.NodeID))
+		}
+		if r.Recorder != nil {
+			r.Recorder.Event(hc, corev1.EventTypeNormal, nodeUnregisteredReason,
+				fmt.Sprintf("Unregistered node %d which has been dead since %s", deadNode.NodeID, deadNode.DetectionTime.Format(time.RFC3339)))
+		}
+	}
+
+	if reflect.DeepEqual(remainingDeadNodes, hc.Status.DeadNodes) {
+		return nil
+	}
+	_, err = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withDeadNodes(remainingDeadNodes))
+	return err
+}
+
+// podNodeIDs returns the Humio node IDs of the pods in the node pools. This relies on the node ID labels set on the pods
+// by ensureLabels.
+func (r *HumioClusterReconciler) podNodeIDs(ctx context.Context, hnps []*HumioNodePool) (map[int]bool, error) {
+	nodeIDs := map[int]bool{}
+	for _, hnp := range hnps {
+		pods, err := kubernetes.ListPods(ctx, r, hnp.GetNamespace(), hnp.GetNodePoolLabels())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods of node pool %s: %w", hnp.GetNodePoolName(), err)
+		}
+		for _, pod := range pods {
+			nodeIDStr, ok := pod.Labels[kubernetes.NodeIdLabelName]
+			if !ok {
+				continue
+			}
+			nodeID, err := strconv.Atoi(nodeIDStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid node id %s on pod %s: %w", nodeIDStr, pod.Name, err)
+			}
+			nodeIDs[nodeID] = true
+		}
+	}
+	return nodeIDs, nil
+}
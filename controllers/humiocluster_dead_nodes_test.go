@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestEnsureDeadNodesUnregistered(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	tt := []struct {
+		name              string
+		spec              *humiov1alpha1.HumioDeadNodeUnregistration
+		nodes             []humioapi.ClusterNode
+		previous          []humiov1alpha1.HumioDeadNode
+		expectedDeadNodes []int
+		expectedNodes     []int
+		expectedEvent     bool
+	}{
+		{
+			name:          "disabled",
+			nodes:         []humioapi.ClusterNode{{Id: 1, IsAvailable: false, CanBeSafelyUnregistered: true}},
+			expectedNodes: []int{1},
+		},
+		{
+			name:          "disabled clears dead nodes",
+			nodes:         []humioapi.ClusterNode{{Id: 1, IsAvailable: false}},
+			previous:      []humiov1alpha1.HumioDeadNode{{NodeID: 1, DetectionTime: metav1.NewTime(now)}},
+			expectedNodes: []int{1},
+		},
+		{
+			name:          "available node without pod is kept",
+			spec:          &humiov1alpha1.HumioDeadNodeUnregistration{GracePeriodSeconds: 60},
+			nodes:         []humioapi.ClusterNode{{Id: 1, IsAvailable: true, CanBeSafelyUnregistered: true}},
+			expectedNodes: []int{1},
+		},
+		{
+			name:          "unavailable node with pod is kept",
+			spec:          &humiov1alpha1.HumioDeadNodeUnregistration{GracePeriodSeconds: 60},
+			nodes:         []humioapi.ClusterNode{{Id: 0, IsAvailable: false, CanBeSafelyUnregistered: true}},
+			expectedNodes: []int{0},
+		},
+		{
+			name:              "newly dead node waits for grace period",
+			spec:              &humiov1alpha1.HumioDeadNodeUnregistration{GracePeriodSeconds: 60},
+			nodes:             []humioapi.ClusterNode{{Id: 1, IsAvailable: false, CanBeSafelyUnregistered: true}},
+			expectedDeadNodes: []int{1},
+			expectedNodes:     []int{1},
+		},
+		{
+			name:              "dead node waits for data to be replicated",
+			spec:              &humiov1alpha1.HumioDeadNodeUnregistration{GracePeriodSeconds: 60},
+			nodes:             []humioapi.ClusterNode{{Id: 1, IsAvailable: false, CanBeSafelyUnregistered: false}},
+			previous:          []humiov1alpha1.HumioDeadNode{{NodeID: 1, DetectionTime: metav1.NewTime(now.Add(-time.Hour))}},
+			expectedDeadNodes: []int{1},
+			expectedNodes:     []int{1},
+		},
+		{
+			name:          "dead node is unregistered after grace period",
+			spec:          &humiov1alpha1.HumioDeadNodeUnregistration{GracePeriodSeconds: 60},
+			nodes:         []humioapi.ClusterNode{{Id: 0, IsAvailable: true}, {Id: 1, IsAvailable: false, CanBeSafelyUnregistered: true}},
+			previous:      []humiov1alpha1.HumioDeadNode{{NodeID: 1, DetectionTime: metav1.NewTime(now.Add(-time.Hour))}},
+			expectedNodes: []int{0},
+			expectedEvent: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "dead-nodes", Namespace: "default"},
+				Spec:       humiov1alpha1.HumioClusterSpec{DeadNodeUnregistration: tc.spec},
+				Status:     humiov1alpha1.HumioClusterStatus{DeadNodes: tc.previous},
+			}
+			hnp := NewHumioNodeManagerFromHumioCluster(hc)
+			podLabels := hnp.GetNodePoolLabels()
+			podLabels[kubernetes.NodeIdLabelName] = "0"
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "dead-nodes-core-abcde", Namespace: hc.Namespace, Labels: podLabels}}
+
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = humiov1alpha1.AddToScheme(scheme)
+			recorder := record.NewFakeRecorder(10)
+			humioClient := humio.NewMockClient(humioapi.Cluster{Nodes: tc.nodes}, nil, nil, nil)
+			r := &HumioClusterReconciler{
+				Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects([]client.Object{hc, pod}...).WithStatusSubresource(hc).Build(),
+				HumioClient: humioClient,
+				Log:         logr.Discard(),
+				Recorder:    recorder,
+			}
+
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: hc.Name, Namespace: hc.Namespace}}
+			if err := r.ensureDeadNodesUnregistered(context.Background(), hc, []*HumioNodePool{hnp}, &humioapi.Config{}, req); err != nil {
+				t.Fatal(err)
+			}
+
+			var updated humiov1alpha1.HumioCluster
+			if err := r.Get(context.Background(), req.NamespacedName, &updated); err != nil {
+				t.Fatal(err)
+			}
+			var deadNodes []int
+			for _, deadNode := range updated.Status.DeadNodes {
+				deadNodes = append(deadNodes, deadNode.NodeID)
+				for _, previous := range tc.previous {
+					if previous.NodeID == deadNode.NodeID && !previous.DetectionTime.Equal(&deadNode.DetectionTime) {
+						t.Errorf("expected detection time of node %d to be kept", deadNode.NodeID)
+					}
+				}
+			}
+			if !equalInts(deadNodes, tc.expectedDeadNodes) {
+				t.Errorf("expected dead nodes %v, got %v", tc.expectedDeadNodes, deadNodes)
+			}
+
+			cluster, _ := humioClient.GetClusters(&humioapi.Config{}, req)
+			var nodes []int
+			for _, node := range cluster.Nodes {
+				nodes = append(nodes, node.Id)
+			}
+			if !equalInts(nodes, tc.expectedNodes) {
+				t.Errorf("expected cluster nodes %v, got %v", tc.expectedNodes, nodes)
+			}
+
+			var event string
+			select {
+			case event = <-recorder.Events:
+			default:
+			}
+			if tc.expectedEvent != strings.Contains(event, nodeUnregisteredReason) {
+				t.Errorf("expected event %v, got %q", tc.expectedEvent, event)
+			}
+		})
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -24,17 +24,19 @@ import (
 
 	"github.com/humio/humio-operator/pkg/kubernetes"
 
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/humio/humio-operator/pkg/helpers"
 
 	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
 	Image                        = "humio/humio-core:1.100.0"
-	HelperImage                  = "humio/humio-operator-helper:3568eb1e7041beaf70d48e71a3d5fc6c8cfb9a6f"
+	HelperImage                  = "humio/humio-operator-helper:d12dae36ba72eb43fbc83144e8027e7bc0de701c"
 	targetReplicationFactor      = 2
 	storagePartitionsCount       = 24
 	digestPartitionsCount        = 24
@@ -69,6 +71,8 @@ const (
 
 type HumioNodePool struct {
 	clusterName              string
+	clusterUID               types.UID
+	clusterGeneration        int64
 	nodePoolName             string
 	namespace                string
 	hostname                 string
@@ -87,16 +91,24 @@ type HumioNodePool struct {
 	ingress                  humiov1alpha1.HumioClusterIngressSpec
 	clusterAnnotations       map[string]string
 	priorityClassName        string
+	configChangePolicy       string
+	maintenanceWindow        *humiov1alpha1.HumioMaintenanceWindow
+	complianceProfile        string
+	dataNodes                []string
+	// authMigrationEnvironmentVariables are applied to the pods while an auth migration is in progress or completed
+	authMigrationEnvironmentVariables []corev1.EnvVar
 }
 
 func NewHumioNodeManagerFromHumioCluster(hc *humiov1alpha1.HumioCluster) *HumioNodePool {
 	return &HumioNodePool{
-		namespace:        hc.Namespace,
-		clusterName:      hc.Name,
-		hostname:         hc.Spec.Hostname,
-		esHostname:       hc.Spec.ESHostname,
-		hostnameSource:   hc.Spec.HostnameSource,
-		esHostnameSource: hc.Spec.ESHostnameSource,
+		namespace:         hc.Namespace,
+		clusterName:       hc.Name,
+		clusterUID:        hc.UID,
+		clusterGeneration: hc.Generation,
+		hostname:          hc.Spec.Hostname,
+		esHostname:        hc.Spec.ESHostname,
+		hostnameSource:    hc.Spec.HostnameSource,
+		esHostnameSource:  hc.Spec.ESHostnameSource,
 		humioNodeSpec: humiov1alpha1.HumioNodeSpec{
 			Image:     hc.Spec.Image,
 			NodeCount: hc.Spec.NodeCount,
@@ -105,11 +117,13 @@ func NewHumioNodeManagerFromHumioCluster(hc *humiov1alpha1.HumioCluster) *HumioN
 			DataVolumeSource:                            hc.Spec.DataVolumeSource,
 			AuthServiceAccountName:                      hc.Spec.AuthServiceAccountName,
 			DisableInitContainer:                        hc.Spec.DisableInitContainer,
+			NodeTags:                                    hc.Spec.NodeTags,
 			EnvironmentVariablesSource:                  hc.Spec.EnvironmentVariablesSource,
 			PodAnnotations:                              hc.Spec.PodAnnotations,
 			ShareProcessNamespace:                       hc.Spec.ShareProcessNamespace,
 			HumioServiceAccountName:                     hc.Spec.HumioServiceAccountName,
 			ImagePullSecrets:                            hc.Spec.ImagePullSecrets,
+			ImageRegistry:                               hc.Spec.ImageRegistry,
 			HelperImage:                                 hc.Spec.HelperImage,
 			ImagePullPolicy:                             hc.Spec.ImagePullPolicy,
 			ContainerSecurityContext:                    hc.Spec.ContainerSecurityContext,
@@ -127,10 +141,15 @@ func NewHumioNodeManagerFromHumioCluster(hc *humiov1alpha1.HumioCluster) *HumioN
 			NodeUUIDPrefix:                              hc.Spec.NodeUUIDPrefix,
 			ExtraHumioVolumeMounts:                      hc.Spec.ExtraHumioVolumeMounts,
 			ExtraVolumes:                                hc.Spec.ExtraVolumes,
+			ExtraConfigFiles:                            hc.Spec.ExtraConfigFiles,
+			SecretsStore:                                hc.Spec.SecretsStore,
 			HumioServiceAccountAnnotations:              hc.Spec.HumioServiceAccountAnnotations,
+			WorkloadIdentity:                            hc.Spec.WorkloadIdentity,
+			BucketStorageEncryption:                     hc.Spec.BucketStorageEncryption,
 			HumioServiceLabels:                          hc.Spec.HumioServiceLabels,
-			EnvironmentVariables:                        hc.Spec.EnvironmentVariables,
+			EnvironmentVariables:                        mergeCommonEnvironmentVariables(hc.Spec.CommonEnvironmentVariables, hc.Spec.EnvironmentVariables),
 			ImageSource:                                 hc.Spec.ImageSource,
+			ImageVerification:                           hc.Spec.ImageVerification,
 			HumioESServicePort:                          hc.Spec.HumioESServicePort,
 			HumioServicePort:                            hc.Spec.HumioServicePort,
 			HumioServiceType:                            hc.Spec.HumioServiceType,
@@ -139,29 +158,42 @@ func NewHumioNodeManagerFromHumioCluster(hc *humiov1alpha1.HumioCluster) *HumioN
 			PodLabels:                                   hc.Spec.PodLabels,
 			UpdateStrategy:                              hc.Spec.UpdateStrategy,
 			PriorityClassName:                           hc.Spec.PriorityClassName,
+			DNSPolicy:                                   hc.Spec.DNSPolicy,
+			DNSConfig:                                   hc.Spec.DNSConfig,
+			HostAliases:                                 hc.Spec.HostAliases,
+			AffinityPreset:                              hc.Spec.AffinityPreset,
+			DataVolumeNodePinning:                       hc.Spec.DataVolumeNodePinning,
+			NodeRoles:                                   hc.Spec.NodeRoles,
 		},
-		tls:                      hc.Spec.TLS,
-		idpCertificateSecretName: hc.Spec.IdpCertificateSecretName,
-		viewGroupPermissions:     hc.Spec.ViewGroupPermissions,
-		rolePermissions:          hc.Spec.RolePermissions,
-		targetReplicationFactor:  hc.Spec.TargetReplicationFactor,
-		storagePartitionsCount:   hc.Spec.StoragePartitionsCount,
-		digestPartitionsCount:    hc.Spec.DigestPartitionsCount,
-		path:                     hc.Spec.Path,
-		ingress:                  hc.Spec.Ingress,
-		clusterAnnotations:       hc.Annotations,
+		tls:                               hc.Spec.TLS,
+		idpCertificateSecretName:          hc.Spec.IdpCertificateSecretName,
+		viewGroupPermissions:              hc.Spec.ViewGroupPermissions,
+		rolePermissions:                   hc.Spec.RolePermissions,
+		targetReplicationFactor:           hc.Spec.TargetReplicationFactor,
+		storagePartitionsCount:            hc.Spec.StoragePartitionsCount,
+		digestPartitionsCount:             hc.Spec.DigestPartitionsCount,
+		path:                              hc.Spec.Path,
+		ingress:                           hc.Spec.Ingress,
+		clusterAnnotations:                hc.Annotations,
+		configChangePolicy:                hc.Spec.ConfigChangePolicy,
+		maintenanceWindow:                 hc.Spec.MaintenanceWindow,
+		complianceProfile:                 hc.Spec.ComplianceProfile,
+		dataNodes:                         nodePoolStatusDataNodes(hc, hc.Name),
+		authMigrationEnvironmentVariables: authMigrationEnvironmentVariables(hc),
 	}
 }
 
 func NewHumioNodeManagerFromHumioNodePool(hc *humiov1alpha1.HumioCluster, hnp *humiov1alpha1.HumioNodePoolSpec) *HumioNodePool {
 	return &HumioNodePool{
-		namespace:        hc.Namespace,
-		clusterName:      hc.Name,
-		nodePoolName:     hnp.Name,
-		hostname:         hc.Spec.Hostname,
-		esHostname:       hc.Spec.ESHostname,
-		hostnameSource:   hc.Spec.HostnameSource,
-		esHostnameSource: hc.Spec.ESHostnameSource,
+		namespace:         hc.Namespace,
+		clusterName:       hc.Name,
+		clusterUID:        hc.UID,
+		clusterGeneration: hc.Generation,
+		nodePoolName:      hnp.Name,
+		hostname:          hc.Spec.Hostname,
+		esHostname:        hc.Spec.ESHostname,
+		hostnameSource:    hc.Spec.HostnameSource,
+		esHostnameSource:  hc.Spec.ESHostnameSource,
 		humioNodeSpec: humiov1alpha1.HumioNodeSpec{
 			Image:     hnp.Image,
 			NodeCount: hnp.NodeCount,
@@ -169,11 +201,13 @@ func NewHumioNodeManagerFromHumioNodePool(hc *humiov1alpha1.HumioCluster, hnp *h
 			DataVolumeSource:               hnp.DataVolumeSource,
 			AuthServiceAccountName:         hnp.AuthServiceAccountName,
 			DisableInitContainer:           hnp.DisableInitContainer,
+			NodeTags:                       hnp.NodeTags,
 			EnvironmentVariablesSource:     hnp.EnvironmentVariablesSource,
 			PodAnnotations:                 hnp.PodAnnotations,
 			ShareProcessNamespace:          hnp.ShareProcessNamespace,
 			HumioServiceAccountName:        hnp.HumioServiceAccountName,
 			ImagePullSecrets:               hnp.ImagePullSecrets,
+			ImageRegistry:                  hnp.ImageRegistry,
 			HelperImage:                    hnp.HelperImage,
 			ImagePullPolicy:                hnp.ImagePullPolicy,
 			ContainerSecurityContext:       hnp.ContainerSecurityContext,
@@ -191,10 +225,15 @@ func NewHumioNodeManagerFromHumioNodePool(hc *humiov1alpha1.HumioCluster, hnp *h
 			NodeUUIDPrefix:                 hnp.NodeUUIDPrefix,
 			ExtraHumioVolumeMounts:         hnp.ExtraHumioVolumeMounts,
 			ExtraVolumes:                   hnp.ExtraVolumes,
+			ExtraConfigFiles:               hnp.ExtraConfigFiles,
+			SecretsStore:                   hnp.SecretsStore,
 			HumioServiceAccountAnnotations: hnp.HumioServiceAccountAnnotations,
+			WorkloadIdentity:               hnp.WorkloadIdentity,
+			BucketStorageEncryption:        hnp.BucketStorageEncryption,
 			HumioServiceLabels:             hnp.HumioServiceLabels,
-			EnvironmentVariables:           hnp.EnvironmentVariables,
+			EnvironmentVariables:           mergeCommonEnvironmentVariables(hc.Spec.CommonEnvironmentVariables, hnp.EnvironmentVariables),
 			ImageSource:                    hnp.ImageSource,
+			ImageVerification:              hnp.ImageVerification,
 			HumioESServicePort:             hnp.HumioESServicePort,
 			HumioServicePort:               hnp.HumioServicePort,
 			HumioServiceType:               hnp.HumioServiceType,
@@ -203,20 +242,42 @@ func NewHumioNodeManagerFromHumioNodePool(hc *humiov1alpha1.HumioCluster, hnp *h
 			PodLabels:                      hnp.PodLabels,
 			UpdateStrategy:                 hnp.UpdateStrategy,
 			PriorityClassName:              hnp.PriorityClassName,
+			DNSPolicy:                      hnp.DNSPolicy,
+			DNSConfig:                      hnp.DNSConfig,
+			HostAliases:                    hnp.HostAliases,
+			AffinityPreset:                 hnp.AffinityPreset,
+			DataVolumeNodePinning:          hnp.DataVolumeNodePinning,
+			NodeRoles:                      hnp.NodeRoles,
 		},
-		tls:                      hc.Spec.TLS,
-		idpCertificateSecretName: hc.Spec.IdpCertificateSecretName,
-		viewGroupPermissions:     hc.Spec.ViewGroupPermissions,
-		rolePermissions:          hc.Spec.RolePermissions,
-		targetReplicationFactor:  hc.Spec.TargetReplicationFactor,
-		storagePartitionsCount:   hc.Spec.StoragePartitionsCount,
-		digestPartitionsCount:    hc.Spec.DigestPartitionsCount,
-		path:                     hc.Spec.Path,
-		ingress:                  hc.Spec.Ingress,
-		clusterAnnotations:       hc.Annotations,
+		tls:                               hc.Spec.TLS,
+		idpCertificateSecretName:          hc.Spec.IdpCertificateSecretName,
+		viewGroupPermissions:              hc.Spec.ViewGroupPermissions,
+		rolePermissions:                   hc.Spec.RolePermissions,
+		targetReplicationFactor:           hc.Spec.TargetReplicationFactor,
+		storagePartitionsCount:            hc.Spec.StoragePartitionsCount,
+		digestPartitionsCount:             hc.Spec.DigestPartitionsCount,
+		path:                              hc.Spec.Path,
+		ingress:                           hc.Spec.Ingress,
+		clusterAnnotations:                hc.Annotations,
+		configChangePolicy:                hc.Spec.ConfigChangePolicy,
+		maintenanceWindow:                 hc.Spec.MaintenanceWindow,
+		complianceProfile:                 hc.Spec.ComplianceProfile,
+		dataNodes:                         nodePoolStatusDataNodes(hc, strings.Join([]string{hc.Name, hnp.Name}, "-")),
+		authMigrationEnvironmentVariables: authMigrationEnvironmentVariables(hc),
 	}
 }
 
+// nodePoolStatusDataNodes returns the Kubernetes worker nodes holding data of the given node pool as recorded in the
+// status of the HumioCluster
+func nodePoolStatusDataNodes(hc *humiov1alpha1.HumioCluster, nodePoolName string) []string {
+	for _, nodePoolStatus := range hc.Status.NodePoolStatus {
+		if nodePoolStatus.Name == nodePoolName {
+			return nodePoolStatus.DataNodes
+		}
+	}
+	return nil
+}
+
 func (hnp HumioNodePool) GetClusterName() string {
 	return hnp.clusterName
 }
@@ -242,20 +303,30 @@ func (hnp *HumioNodePool) SetImage(image string) {
 
 func (hnp *HumioNodePool) GetImage() string {
 	if hnp.humioNodeSpec.Image != "" {
-		return hnp.humioNodeSpec.Image
+		return imageWithRegistry(hnp.humioNodeSpec.Image, hnp.humioNodeSpec.ImageRegistry)
+	}
+	if fipsImage := helpers.GetFIPSImage(); fipsImage != "" && hnp.complianceProfile == humiov1alpha1.HumioClusterComplianceProfileFIPS {
+		return imageWithRegistry(fipsImage, hnp.humioNodeSpec.ImageRegistry)
 	}
-	return Image
+	return imageWithRegistry(Image, hnp.humioNodeSpec.ImageRegistry)
 }
 
 func (hnp HumioNodePool) GetImageSource() *humiov1alpha1.HumioImageSource {
 	return hnp.humioNodeSpec.ImageSource
 }
 
+func (hnp HumioNodePool) GetImageVerification() *humiov1alpha1.HumioImageVerification {
+	return hnp.humioNodeSpec.ImageVerification
+}
+
 func (hnp HumioNodePool) GetHelperImage() string {
 	if hnp.humioNodeSpec.HelperImage != "" {
-		return hnp.humioNodeSpec.HelperImage
+		return imageWithRegistry(hnp.humioNodeSpec.HelperImage, hnp.humioNodeSpec.ImageRegistry)
+	}
+	if fipsHelperImage := helpers.GetFIPSHelperImage(); fipsHelperImage != "" && hnp.complianceProfile == humiov1alpha1.HumioClusterComplianceProfileFIPS {
+		return imageWithRegistry(fipsHelperImage, hnp.humioNodeSpec.ImageRegistry)
 	}
-	return HelperImage
+	return imageWithRegistry(HelperImage, hnp.humioNodeSpec.ImageRegistry)
 }
 
 func (hnp HumioNodePool) GetImagePullSecrets() []corev1.LocalObjectReference {
@@ -323,6 +394,10 @@ func (hnp HumioNodePool) GetIngress() humiov1alpha1.HumioClusterIngressSpec {
 func (hnp HumioNodePool) GetEnvironmentVariables() []corev1.EnvVar {
 	var envVar []corev1.EnvVar
 
+	for _, env := range hnp.authMigrationEnvironmentVariables {
+		envVar = AppendEnvVarToEnvVarsIfNotAlreadyPresent(envVar, env)
+	}
+
 	for _, env := range hnp.humioNodeSpec.EnvironmentVariables {
 		envVar = AppendEnvVarToEnvVarsIfNotAlreadyPresent(envVar, env)
 	}
@@ -393,6 +468,16 @@ func (hnp HumioNodePool) GetEnvironmentVariables() []corev1.EnvVar {
 		}
 	}
 
+	if nodeRoles := hnp.GetNodeRolesEnvVarValue(); nodeRoles != "" {
+		envDefaults = append(envDefaults, corev1.EnvVar{
+			Name:  "NODE_ROLES",
+			Value: nodeRoles,
+		})
+	}
+
+	envDefaults = append(envDefaults, bucketStorageEncryptionEnvironmentVariables(&hnp)...)
+	envDefaults = append(envDefaults, complianceProfileEnvironmentVariables(&hnp)...)
+
 	for _, defaultEnvVar := range envDefaults {
 		envVar = AppendEnvVarToEnvVarsIfNotAlreadyPresent(envVar, defaultEnvVar)
 	}
@@ -462,6 +547,9 @@ func (hnp HumioNodePool) GetPodLabels() map[string]string {
 			labels[k] = v
 		}
 	}
+	if hnp.humioNodeSpec.WorkloadIdentity != nil && hnp.humioNodeSpec.WorkloadIdentity.AzureClientID != "" {
+		labels[azureWorkloadIdentityUseLabel] = "true"
+	}
 	return labels
 }
 
@@ -589,7 +677,24 @@ func (hnp HumioNodePool) GetHumioServiceAccountName() string {
 }
 
 func (hnp HumioNodePool) GetHumioServiceAccountAnnotations() map[string]string {
-	return hnp.humioNodeSpec.HumioServiceAccountAnnotations
+	if hnp.humioNodeSpec.WorkloadIdentity == nil {
+		return hnp.humioNodeSpec.HumioServiceAccountAnnotations
+	}
+	annotations := workloadIdentityServiceAccountAnnotations(hnp.humioNodeSpec.WorkloadIdentity)
+	for k, v := range hnp.humioNodeSpec.HumioServiceAccountAnnotations {
+		if _, ok := annotations[k]; !ok {
+			annotations[k] = v
+		}
+	}
+	return annotations
+}
+
+func (hnp HumioNodePool) GetWorkloadIdentity() *humiov1alpha1.HumioWorkloadIdentity {
+	return hnp.humioNodeSpec.WorkloadIdentity
+}
+
+func (hnp HumioNodePool) GetBucketStorageEncryption() *humiov1alpha1.HumioBucketStorageEncryption {
+	return hnp.humioNodeSpec.BucketStorageEncryption
 }
 
 func (hnp HumioNodePool) GetContainerReadinessProbe() *corev1.Probe {
@@ -676,6 +781,44 @@ func (hnp HumioNodePool) GetPodSecurityContext() *corev1.PodSecurityContext {
 }
 
 func (hnp HumioNodePool) GetAffinity() *corev1.Affinity {
+	affinity := hnp.getAffinityWithoutPreset()
+	if affinity.PodAntiAffinity != nil {
+		return affinity
+	}
+
+	switch hnp.humioNodeSpec.AffinityPreset {
+	case humiov1alpha1.HumioAffinityPresetSoftZoneSpread:
+		affinity = affinity.DeepCopy()
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: hnp.GetNodePoolLabels(),
+						},
+						TopologyKey: corev1.LabelTopologyZone,
+					},
+				},
+			},
+		}
+	case humiov1alpha1.HumioAffinityPresetHardHostAntiAffinity:
+		affinity = affinity.DeepCopy()
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: kubernetes.LabelsForHumio(hnp.GetClusterName()),
+					},
+					TopologyKey: corev1.LabelHostname,
+				},
+			},
+		}
+	}
+	return affinity
+}
+
+func (hnp HumioNodePool) getAffinityWithoutPreset() *corev1.Affinity {
 	if hnp.humioNodeSpec.Affinity == (corev1.Affinity{}) {
 		return &corev1.Affinity{
 			NodeAffinity: &corev1.NodeAffinity{
@@ -792,6 +935,21 @@ func (hnp HumioNodePool) GetExtraVolumes() []corev1.Volume {
 	return hnp.humioNodeSpec.ExtraVolumes
 }
 
+func (hnp HumioNodePool) GetExtraConfigFiles() []humiov1alpha1.HumioExtraConfigFile {
+	return hnp.humioNodeSpec.ExtraConfigFiles
+}
+
+func (hnp HumioNodePool) GetSecretsStore() *humiov1alpha1.HumioSecretsStore {
+	return hnp.humioNodeSpec.SecretsStore
+}
+
+func (hnp HumioNodePool) GetSecretsStoreMountPath() string {
+	if hnp.humioNodeSpec.SecretsStore == nil || hnp.humioNodeSpec.SecretsStore.MountPath == "" {
+		return secretsStoreDefaultMountPath
+	}
+	return hnp.humioNodeSpec.SecretsStore.MountPath
+}
+
 func (hnp HumioNodePool) GetHumioServiceAnnotations() map[string]string {
 	return hnp.humioNodeSpec.HumioServiceAnnotations
 }
@@ -828,6 +986,10 @@ func (hnp HumioNodePool) InitContainerDisabled() bool {
 	return hnp.humioNodeSpec.DisableInitContainer
 }
 
+func (hnp HumioNodePool) GetNodeTags() []humiov1alpha1.HumioNodeTag {
+	return hnp.humioNodeSpec.NodeTags
+}
+
 func (hnp HumioNodePool) PVCsEnabled() bool {
 	emptyPersistentVolumeClaimSpec := corev1.PersistentVolumeClaimSpec{}
 	return !reflect.DeepEqual(hnp.humioNodeSpec.DataVolumePersistentVolumeClaimSpecTemplate, emptyPersistentVolumeClaimSpec)
@@ -864,10 +1026,100 @@ func (hnp HumioNodePool) GetUpdateStrategy() *humiov1alpha1.HumioUpdateStrategy
 	}
 }
 
+func (hnp HumioNodePool) GetConfigChangePolicy() string {
+	if hnp.configChangePolicy != "" {
+		return hnp.configChangePolicy
+	}
+	return humiov1alpha1.HumioClusterConfigChangePolicyRollingRestart
+}
+
+func (hnp HumioNodePool) GetMaintenanceWindow() *humiov1alpha1.HumioMaintenanceWindow {
+	return hnp.maintenanceWindow
+}
+
+// GetBlueGreenValidationQuery returns the repository and query used to validate new pods during blue/green updates
+func (hnp HumioNodePool) GetBlueGreenValidationQuery() (string, string) {
+	repository := blueGreenDefaultValidationRepository
+	query := blueGreenDefaultValidationQuery
+	if blueGreen := hnp.GetUpdateStrategy().BlueGreen; blueGreen != nil {
+		if blueGreen.ValidationRepository != "" {
+			repository = blueGreen.ValidationRepository
+		}
+		if blueGreen.ValidationQuery != "" {
+			query = blueGreen.ValidationQuery
+		}
+	}
+	return repository, query
+}
+
+func (hnp HumioNodePool) GetNodeRoles() []humiov1alpha1.HumioNodeRole {
+	return hnp.humioNodeSpec.NodeRoles
+}
+
+// HasNodeRole returns true if the Humio nodes of the node pool have the given role. Node pools without explicit roles
+// have all roles.
+func (hnp HumioNodePool) HasNodeRole(role humiov1alpha1.HumioNodeRole) bool {
+	if len(hnp.humioNodeSpec.NodeRoles) == 0 {
+		return true
+	}
+	for _, nodeRole := range hnp.humioNodeSpec.NodeRoles {
+		if nodeRole == role {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNodeRolesEnvVarValue returns the value of the NODE_ROLES environment variable matching the roles of the node
+// pool, or an empty string if the node pool has no explicit roles
+func (hnp HumioNodePool) GetNodeRolesEnvVarValue() string {
+	if len(hnp.humioNodeSpec.NodeRoles) == 0 {
+		return ""
+	}
+	if hnp.HasNodeRole(humiov1alpha1.HumioNodeRoleDigest) || hnp.HasNodeRole(humiov1alpha1.HumioNodeRoleStorage) {
+		return "all"
+	}
+	if !hnp.HasNodeRole(humiov1alpha1.HumioNodeRoleQuery) {
+		return "ingestonly"
+	}
+	return "httponly"
+}
+
+func (hnp HumioNodePool) DataVolumeNodePinningEnabled() bool {
+	return hnp.humioNodeSpec.DataVolumeNodePinning
+}
+
+func (hnp HumioNodePool) GetDataNodes() []string {
+	return hnp.dataNodes
+}
+
+// removeDataNode forgets a worker node holding data of the node pool, so new pods are not pinned to it
+func (hnp *HumioNodePool) removeDataNode(nodeName string) {
+	var dataNodes []string
+	for _, dataNode := range hnp.dataNodes {
+		if dataNode != nodeName {
+			dataNodes = append(dataNodes, dataNode)
+		}
+	}
+	hnp.dataNodes = dataNodes
+}
+
 func (hnp HumioNodePool) GetPriorityClassName() string {
 	return hnp.humioNodeSpec.PriorityClassName
 }
 
+func (hnp HumioNodePool) GetDNSPolicy() corev1.DNSPolicy {
+	return hnp.humioNodeSpec.DNSPolicy
+}
+
+func (hnp HumioNodePool) GetDNSConfig() *corev1.PodDNSConfig {
+	return hnp.humioNodeSpec.DNSConfig
+}
+
+func (hnp HumioNodePool) GetHostAliases() []corev1.HostAlias {
+	return hnp.humioNodeSpec.HostAliases
+}
+
 func (hnp HumioNodePool) OkToDeletePvc() bool {
 	return hnp.GetDataVolumePersistentVolumeClaimPolicy().ReclaimType == humiov1alpha1.HumioPersistentVolumeReclaimTypeOnNodeDelete
 }
@@ -888,6 +1140,20 @@ func RolePermissionsConfigMapName(hc *humiov1alpha1.HumioCluster) string {
 	return fmt.Sprintf("%s-%s", hc.Name, rolePermissionsConfigMapNameSuffix)
 }
 
+// mergeCommonEnvironmentVariables returns the environment variables of a node pool with the common environment
+// variables of the cluster appended, unless the node pool sets them itself
+func mergeCommonEnvironmentVariables(commonEnvVars []corev1.EnvVar, envVars []corev1.EnvVar) []corev1.EnvVar {
+	if len(commonEnvVars) == 0 {
+		return envVars
+	}
+	merged := make([]corev1.EnvVar, len(envVars), len(envVars)+len(commonEnvVars))
+	copy(merged, envVars)
+	for _, commonEnvVar := range commonEnvVars {
+		merged = AppendEnvVarToEnvVarsIfNotAlreadyPresent(merged, commonEnvVar)
+	}
+	return merged
+}
+
 func AppendEnvVarToEnvVarsIfNotAlreadyPresent(envVars []corev1.EnvVar, defaultEnvVar corev1.EnvVar) []corev1.EnvVar {
 	for _, envVar := range envVars {
 		if envVar.Name == defaultEnvVar.Name {
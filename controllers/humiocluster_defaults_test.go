@@ -17,7 +17,12 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -28,6 +33,10 @@ import (
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 var _ = Describe("HumioCluster Defaults", func() {
@@ -573,6 +582,47 @@ func Test_constructContainerArgs(t *testing.T) {
 				},
 			},
 		},
+		{
+			"node tags",
+			fields{
+				&humiov1alpha1.HumioCluster{
+					Spec: humiov1alpha1.HumioClusterSpec{
+						HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+							NodeTags: []humiov1alpha1.HumioNodeTag{
+								{Tag: "rack", NodeLabel: "example.com/rack"},
+							},
+						},
+					},
+				},
+				[]string{
+					"if [ -f /shared/node-tags ]; then export NODE_TAGS=$(cat /shared/node-tags); fi",
+					"export ZONE=",
+				},
+				[]string{
+					"export NODE_TAGS=$(cat /shared/node-tags) &&",
+				},
+			},
+		},
+		{
+			"node tags without init container",
+			fields{
+				&humiov1alpha1.HumioCluster{
+					Spec: humiov1alpha1.HumioClusterSpec{
+						HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+							DisableInitContainer: true,
+							NodeTags: []humiov1alpha1.HumioNodeTag{
+								{Tag: "rack", NodeLabel: "example.com/rack"},
+							},
+						},
+					},
+				},
+				[]string{},
+				[]string{
+					"export NODE_TAGS=",
+					"export ZONE=",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -595,3 +645,259 @@ func Test_constructContainerArgs(t *testing.T) {
 		})
 	}
 }
+
+// Test_constructContainerArgsNodeTags runs the generated shell commands, to make sure the humio container still starts
+// when the init container of an older helper image did not write the node tags file
+func Test_constructContainerArgsNodeTags(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+	hnp := NewHumioNodeManagerFromHumioCluster(&humiov1alpha1.HumioCluster{
+		Spec: humiov1alpha1.HumioClusterSpec{
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				NodeTags: []humiov1alpha1.HumioNodeTag{{Tag: "rack", NodeLabel: "example.com/rack"}},
+			},
+		},
+	})
+	args, err := ConstructContainerArgs(hnp, []corev1.EnvVar{{Name: "CORES", Value: "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// nodeTags is written to the node tags file, unless it is empty
+		nodeTags string
+		want     string
+	}{
+		{"node tags file missing", "", "unset"},
+		{"node tags file present", "rack=a", "rack=a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.nodeTags != "" {
+				if err := os.WriteFile(filepath.Join(dir, "node-tags"), []byte(tt.nodeTags), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			script := strings.ReplaceAll(args[1], sharedPath, dir)
+			script = strings.Replace(script, fmt.Sprintf("exec bash %s/run.sh", humioAppPath), `echo -n "${NODE_TAGS-unset}"`, 1)
+			out, err := exec.Command(bash, "-c", script).Output()
+			if err != nil {
+				t.Fatalf("expected the container args to run, got %v", err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("expected NODE_TAGS to be %q, got %q", tt.want, out)
+			}
+		})
+	}
+}
+
+func Test_commonEnvironmentVariablesOnlyRestartAffectedNodePools(t *testing.T) {
+	newCluster := func(commonValue string) *humiov1alpha1.HumioCluster {
+		return &humiov1alpha1.HumioCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+			Spec: humiov1alpha1.HumioClusterSpec{
+				CommonEnvironmentVariables: []corev1.EnvVar{
+					{Name: "INGEST_FEED_MAX_PARALLEL", Value: commonValue},
+					{Name: "KAFKA_SERVERS", Value: "kafka:9092"},
+				},
+				NodePools: []humiov1alpha1.HumioNodePoolSpec{
+					{Name: "ingest"},
+					{
+						Name: "query",
+						HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+							EnvironmentVariables: []corev1.EnvVar{{Name: "INGEST_FEED_MAX_PARALLEL", Value: "1"}},
+						},
+					},
+				},
+			},
+		}
+	}
+	podHash := func(hc *humiov1alpha1.HumioCluster, i int) string {
+		hnp := NewHumioNodeManagerFromHumioNodePool(hc, &hc.Spec.NodePools[i])
+		pod, err := ConstructPod(hnp, "humiocluster-core-abcdef", &podAttachments{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return podSpecAsSHA256(hnp, *pod)
+	}
+
+	before, after := newCluster("4"), newCluster("8")
+	if podHash(before, 0) == podHash(after, 0) {
+		t.Errorf("expected pod of node pool using the common environment variable to change")
+	}
+	if podHash(before, 1) != podHash(after, 1) {
+		t.Errorf("expected pod of node pool overriding the common environment variable to be unchanged")
+	}
+
+	hnp := NewHumioNodeManagerFromHumioNodePool(after, &after.Spec.NodePools[1])
+	envVars := hnp.GetEnvironmentVariables()
+	if value := EnvVarValue(envVars, "INGEST_FEED_MAX_PARALLEL"); value != "1" {
+		t.Errorf("expected node pool environment variable to take precedence, got %s", value)
+	}
+	if value := EnvVarValue(envVars, "KAFKA_SERVERS"); value != "kafka:9092" {
+		t.Errorf("expected common environment variable to be set, got %s", value)
+	}
+	if len(after.Spec.NodePools[1].EnvironmentVariables) != 1 {
+		t.Errorf("expected node pool spec to be left untouched, got %v", after.Spec.NodePools[1].EnvironmentVariables)
+	}
+}
+
+func Test_getEnvVarSourceOnlyContainsEffectiveEnvironmentVariables(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared-config", Namespace: "logging"},
+		Data: map[string]string{
+			"INGEST_FEED_MAX_PARALLEL": "4",
+			"MAX_SERIES_LIMIT":         "1000",
+		},
+	}
+	r := &HumioClusterReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()}
+
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+		Spec: humiov1alpha1.HumioClusterSpec{
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				EnvironmentVariablesSource: []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "shared-config"}}},
+					{Prefix: "EXTRA_", ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "shared-config"}}},
+				},
+				EnvironmentVariables: []corev1.EnvVar{{Name: "INGEST_FEED_MAX_PARALLEL", Value: "1"}},
+			},
+		},
+	}
+	data, err := r.getEnvVarSource(context.Background(), NewHumioNodeManagerFromHumioCluster(hc))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]string{
+		"MAX_SERIES_LIMIT":               "1000",
+		"EXTRA_INGEST_FEED_MAX_PARALLEL": "4",
+		"EXTRA_MAX_SERIES_LIMIT":         "1000",
+	}
+	if data == nil || !reflect.DeepEqual(*data, expected) {
+		t.Errorf("expected %v, got %v", expected, data)
+	}
+}
+
+func Test_affinityPreset(t *testing.T) {
+	rawPodAntiAffinity := &corev1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+			{TopologyKey: "example.com/rack"},
+		},
+	}
+
+	for _, tc := range []struct {
+		name             string
+		affinityPreset   string
+		affinity         corev1.Affinity
+		expectedTopology string
+		expectedRequired bool
+		expectedNone     bool
+	}{
+		{name: "no preset", expectedNone: true},
+		{name: "none", affinityPreset: humiov1alpha1.HumioAffinityPresetNone, expectedNone: true},
+		{name: "soft zone spread", affinityPreset: humiov1alpha1.HumioAffinityPresetSoftZoneSpread, expectedTopology: corev1.LabelTopologyZone},
+		{name: "hard host anti-affinity", affinityPreset: humiov1alpha1.HumioAffinityPresetHardHostAntiAffinity, expectedTopology: corev1.LabelHostname, expectedRequired: true},
+		{name: "raw affinity takes precedence", affinityPreset: humiov1alpha1.HumioAffinityPresetSoftZoneSpread, affinity: corev1.Affinity{PodAntiAffinity: rawPodAntiAffinity}, expectedTopology: "example.com/rack", expectedRequired: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+				Spec: humiov1alpha1.HumioClusterSpec{
+					HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+						AffinityPreset: tc.affinityPreset,
+						Affinity:       tc.affinity,
+					},
+				},
+			}
+			affinity := NewHumioNodeManagerFromHumioCluster(hc).GetAffinity()
+			if tc.expectedNone {
+				if affinity.PodAntiAffinity != nil {
+					t.Errorf("did not expect pod anti-affinity, got %v", affinity.PodAntiAffinity)
+				}
+				return
+			}
+			if affinity.PodAntiAffinity == nil {
+				t.Fatalf("expected pod anti-affinity")
+			}
+			var topologyKey string
+			if tc.expectedRequired {
+				topologyKey = affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].TopologyKey
+			} else {
+				topologyKey = affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm.TopologyKey
+			}
+			if topologyKey != tc.expectedTopology {
+				t.Errorf("expected topology key %s, got %s", tc.expectedTopology, topologyKey)
+			}
+			if tc.affinity == (corev1.Affinity{}) && affinity.NodeAffinity == nil {
+				t.Errorf("expected default node affinity to be kept")
+			}
+			if hc.Spec.Affinity.PodAntiAffinity != tc.affinity.PodAntiAffinity {
+				t.Errorf("did not expect the affinity of the HumioCluster to be modified")
+			}
+		})
+	}
+}
+
+func Test_imageRegistry(t *testing.T) {
+	for _, tc := range []struct {
+		name                string
+		image               string
+		helperImage         string
+		imageRegistry       string
+		expectedImage       string
+		expectedHelperImage string
+	}{
+		{
+			name:                "no registry override",
+			image:               "humio/humio-core:1.82.1",
+			expectedImage:       "humio/humio-core:1.82.1",
+			expectedHelperImage: HelperImage,
+		},
+		{
+			name:                "registry prepended to docker hub images",
+			image:               "humio/humio-core:1.82.1",
+			imageRegistry:       "registry.example.com:5000",
+			expectedImage:       "registry.example.com:5000/humio/humio-core:1.82.1",
+			expectedHelperImage: "registry.example.com:5000/" + HelperImage,
+		},
+		{
+			name:                "registry replaced and digest kept",
+			image:               "staging.example.com/humio/humio-core:1.82.1@sha256:38c78710107dc76f4f809b457328ff1c6764ae4244952a5fa7d76f6e67ea2390",
+			helperImage:         "localhost/humio/humio-operator-helper:latest",
+			imageRegistry:       "registry.example.com/",
+			expectedImage:       "registry.example.com/humio/humio-core:1.82.1@sha256:38c78710107dc76f4f809b457328ff1c6764ae4244952a5fa7d76f6e67ea2390",
+			expectedHelperImage: "registry.example.com/humio/humio-operator-helper:latest",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+				Spec: humiov1alpha1.HumioClusterSpec{
+					NodePools: []humiov1alpha1.HumioNodePoolSpec{
+						{
+							Name: "canary",
+							HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+								Image:         tc.image,
+								HelperImage:   tc.helperImage,
+								ImageRegistry: tc.imageRegistry,
+							},
+						},
+					},
+				},
+			}
+			hnp := NewHumioNodeManagerFromHumioNodePool(hc, &hc.Spec.NodePools[0])
+			if image := hnp.GetImage(); image != tc.expectedImage {
+				t.Errorf("expected image %s, got %s", tc.expectedImage, image)
+			}
+			if helperImage := hnp.GetHelperImage(); helperImage != tc.expectedHelperImage {
+				t.Errorf("expected helper image %s, got %s", tc.expectedHelperImage, helperImage)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const environmentVariableWarningReason = "EnvironmentVariableWarning"
+
+// humioEnvironmentVariableSetting describes the versions of Humio which support a setting configured using an
+// environment variable
+type humioEnvironmentVariableSetting struct {
+	// deprecatedIn is the first version of Humio in which the setting is deprecated, if any
+	deprecatedIn string
+}
+
+// humioEnvironmentVariableSettings is the schema of the settings of Humio which are known to the operator. Settings
+// which are not part of the schema are still passed on to the Humio pods, but are reported as unknown.
+var humioEnvironmentVariableSettings = map[string]humioEnvironmentVariableSetting{
+	"AUTHENTICATION_METHOD":                             {},
+	"AUTO_CREATE_USER_ON_SUCCESSFUL_LOGIN":              {},
+	"AUTO_UPDATE_GROUP_MEMBERSHIPS_ON_SUCCESSFUL_LOGIN": {},
+	"BOOTSTRAP_HOST_ID":                                 {},
+	"CORES":                                             {},
+	"DEFAULT_PARTITION_COUNT":                           {deprecatedIn: HumioVersionWithAutomaticPartitionManagement},
+	"DIGEST_REPLICATION_FACTOR":                         {},
+	"ENABLE_IOC_SERVICE":                                {},
+	"ENABLE_ORGANIZATIONS":                              {},
+	"HUMIO_DEBUG_OPTS":                                  {},
+	"HUMIO_GC_OPTS":                                     {},
+	"HUMIO_JVM_ARGS":                                    {},
+	"HUMIO_JVM_LOG_OPTS":                                {},
+	"HUMIO_JVM_PERFORMANCE_OPTS":                        {},
+	"HUMIO_KAFKA_TOPIC_PREFIX":                          {},
+	"HUMIO_LOG4J_CONFIGURATION":                         {},
+	"HUMIO_MEMORY_OPTS":                                 {},
+	"HUMIO_OPTS":                                        {},
+	"INGEST_QUEUE_INITIAL_PARTITIONS":                   {deprecatedIn: HumioVersionWithAutomaticPartitionManagement},
+	"INITIAL_DISABLED_NODE_TASK":                        {},
+	"KAFKA_MANAGED_BY_HUMIO":                            {},
+	"KAFKA_SERVERS":                                     {},
+	"LOCAL_STORAGE_MIN_AGE_DAYS":                        {},
+	"LOCAL_STORAGE_PERCENTAGE":                          {},
+	"MAX_EVENT_FIELD_COUNT":                             {},
+	"MAX_EVENT_SIZE":                                    {},
+	"MAX_INGEST_REQUEST_SIZE":                           {},
+	"NODE_ROLES":                                        {},
+	"ORGANIZATION_MODE":                                 {},
+	"PUBLIC_URL":                                        {},
+	"QUERY_COORDINATOR":                                 {},
+	"READ_GROUP_PERMISSIONS_FROM_FILE":                  {},
+	"ROOT_USER_NAME":                                    {},
+	"SINGLE_USER_PASSWORD":                              {},
+	"SINGLE_USER_USERNAME":                              {},
+	"STORAGE_REPLICATION_FACTOR":                        {},
+	"USING_EPHEMERAL_DISKS":                             {},
+	"ZOOKEEPER_PREFIX_FOR_NODE_UUID":                    {deprecatedIn: HumioVersionWithoutOldVhostSelection},
+	"ZOOKEEPER_URL":                                     {},
+	"ZOOKEEPER_URL_FOR_NODE_UUID":                       {deprecatedIn: HumioVersionWithoutOldVhostSelection},
+}
+
+// humioEnvironmentVariablePrefixes are the prefixes of families of settings of Humio, such as the settings of bucket
+// storage or an identity provider, which are known to the operator without listing every setting
+var humioEnvironmentVariablePrefixes = []string{
+	"AZURE_",
+	"GCP_",
+	"KAFKA_ADMIN_",
+	"KAFKA_COMMON_",
+	"KAFKA_CONSUMER_",
+	"KAFKA_PRODUCER_",
+	"LDAP_",
+	"OIDC_",
+	"S3_",
+	"SAML_",
+}
+
+// operatorManagedEnvironmentVariables returns the environment variables of the Humio container which are managed by
+// the operator for the node pool, and which must not be set using environmentVariables
+func operatorManagedEnvironmentVariables(hnp *HumioNodePool) []string {
+	names := []string{"THIS_POD_IP", "POD_NAME", "POD_NAMESPACE", "HUMIO_PORT", "ELASTIC_PORT", "EXTERNAL_URL"}
+	if hnp.TLSEnabled() {
+		names = append(names, "TLS_TRUSTSTORE_LOCATION", "TLS_KEYSTORE_LOCATION", "TLS_TRUSTSTORE_PASSWORD", "TLS_KEYSTORE_PASSWORD", "TLS_KEY_PASSWORD")
+	}
+	for _, envVar := range bucketStorageEncryptionEnvironmentVariables(hnp) {
+		names = append(names, envVar.Name)
+	}
+	for _, envVar := range complianceProfileEnvironmentVariables(hnp) {
+		names = append(names, envVar.Name)
+	}
+	return names
+}
+
+// validateEnvironmentVariables returns an error if the environment variables of the node pool set any of the
+// environment variables managed by the operator
+func validateEnvironmentVariables(hnp *HumioNodePool) error {
+	var conflicts []string
+	for _, name := range operatorManagedEnvironmentVariables(hnp) {
+		if EnvVarHasKey(hnp.humioNodeSpec.EnvironmentVariables, name) {
+			conflicts = append(conflicts, name)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("environmentVariables of node pool %s must not set %s as they are managed by the operator", hnp.GetNodePoolName(), strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// environmentVariableWarnings returns the environment variables of the node pool which are unknown or deprecated for
+// the version of Humio the node pool runs
+func environmentVariableWarnings(hnp *HumioNodePool) []string {
+	humioVersion, err := HumioVersionFromString(hnp.GetImage())
+	if err != nil {
+		return nil
+	}
+
+	operatorManaged := map[string]bool{}
+	for _, name := range operatorManagedEnvironmentVariables(hnp) {
+		operatorManaged[name] = true
+	}
+
+	var warnings []string
+	for _, envVar := range hnp.humioNodeSpec.EnvironmentVariables {
+		// Environment variables managed by the operator are rejected by validateEnvironmentVariables instead
+		if operatorManaged[envVar.Name] {
+			continue
+		}
+		setting, known := lookupHumioEnvironmentVariableSetting(envVar.Name)
+		if !known {
+			warnings = append(warnings, fmt.Sprintf("node pool %s: environment variable %s is not a known setting of Humio", hnp.GetNodePoolName(), envVar.Name))
+			continue
+		}
+		if setting.deprecatedIn == "" {
+			continue
+		}
+		if deprecated, _ := humioVersion.AtLeast(setting.deprecatedIn); deprecated {
+			warnings = append(warnings, fmt.Sprintf("node pool %s: environment variable %s is deprecated since Humio version %s", hnp.GetNodePoolName(), envVar.Name, setting.deprecatedIn))
+		}
+	}
+	return warnings
+}
+
+func lookupHumioEnvironmentVariableSetting(name string) (humioEnvironmentVariableSetting, bool) {
+	if setting, ok := humioEnvironmentVariableSettings[name]; ok {
+		return setting, true
+	}
+	for _, prefix := range humioEnvironmentVariablePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return humioEnvironmentVariableSetting{}, true
+		}
+	}
+	return humioEnvironmentVariableSetting{}, false
+}
+
+// ensureEnvironmentVariableWarningsReported reports the environment variables of the node pools which are unknown or
+// deprecated. Warning events are only emitted when the warnings change, such as when the image version changes.
+func (r *HumioClusterReconciler) ensureEnvironmentVariableWarningsReported(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnps []*HumioNodePool) error {
+	var warnings []string
+	for _, hnp := range hnps {
+		warnings = append(warnings, environmentVariableWarnings(hnp)...)
+	}
+	sort.Strings(warnings)
+	if reflect.DeepEqual(warnings, hc.Status.EnvironmentVariableWarnings) ||
+		(len(warnings) == 0 && len(hc.Status.EnvironmentVariableWarnings) == 0) {
+		return nil
+	}
+
+	for _, warning := range warnings {
+		r.Log.Info(warning)
+		if r.Recorder != nil {
+			r.Recorder.Event(hc, corev1.EventTypeWarning, environmentVariableWarningReason, warning)
+		}
+	}
+	_, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withEnvironmentVariableWarnings(warnings))
+	return err
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEnvironmentVariablesValidation(t *testing.T) {
+	tt := []struct {
+		name             string
+		image            string
+		tlsEnabled       bool
+		envVars          []corev1.EnvVar
+		expectError      bool
+		expectedWarnings []string
+	}{
+		{
+			name:    "known settings",
+			image:   "humio/humio-core:1.82.1",
+			envVars: []corev1.EnvVar{{Name: "KAFKA_SERVERS"}, {Name: "HUMIO_MEMORY_OPTS"}, {Name: "S3_STORAGE_BUCKET"}},
+		},
+		{
+			name:             "unknown setting",
+			image:            "humio/humio-core:1.82.1",
+			envVars:          []corev1.EnvVar{{Name: "HUMIO_MEMORY_OPT"}},
+			expectedWarnings: []string{"node pool humiocluster: environment variable HUMIO_MEMORY_OPT is not a known setting of Humio"},
+		},
+		{
+			name:    "setting deprecated in later version",
+			image:   "humio/humio-core:1.76.0",
+			envVars: []corev1.EnvVar{{Name: "ZOOKEEPER_URL_FOR_NODE_UUID"}},
+		},
+		{
+			name:             "deprecated setting",
+			image:            "humio/humio-core:1.82.1",
+			envVars:          []corev1.EnvVar{{Name: "ZOOKEEPER_URL_FOR_NODE_UUID"}},
+			expectedWarnings: []string{"node pool humiocluster: environment variable ZOOKEEPER_URL_FOR_NODE_UUID is deprecated since Humio version 1.80.0"},
+		},
+		{
+			name:        "operator managed setting",
+			image:       "humio/humio-core:1.82.1",
+			envVars:     []corev1.EnvVar{{Name: "HUMIO_PORT", Value: "8081"}},
+			expectError: true,
+		},
+		{
+			name:             "tls setting without tls",
+			image:            "humio/humio-core:1.82.1",
+			envVars:          []corev1.EnvVar{{Name: "TLS_KEYSTORE_LOCATION"}},
+			expectedWarnings: []string{"node pool humiocluster: environment variable TLS_KEYSTORE_LOCATION is not a known setting of Humio"},
+		},
+		{
+			name:        "tls setting with tls",
+			image:       "humio/humio-core:1.82.1",
+			tlsEnabled:  true,
+			envVars:     []corev1.EnvVar{{Name: "TLS_KEYSTORE_LOCATION"}},
+			expectError: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+				Spec: humiov1alpha1.HumioClusterSpec{
+					HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+						Image:                tc.image,
+						EnvironmentVariables: tc.envVars,
+					},
+					TLS: &humiov1alpha1.HumioClusterTLSSpec{Enabled: helpers.BoolPtr(tc.tlsEnabled)},
+				},
+			}
+			if tc.tlsEnabled {
+				t.Setenv("USE_CERTMANAGER", "true")
+			}
+			hnp := NewHumioNodeManagerFromHumioCluster(hc)
+			if err := validateEnvironmentVariables(hnp); (err != nil) != tc.expectError {
+				t.Errorf("validateEnvironmentVariables() error = %v, expectError %v", err, tc.expectError)
+			}
+			if warnings := environmentVariableWarnings(hnp); !reflect.DeepEqual(warnings, tc.expectedWarnings) {
+				t.Errorf("environmentVariableWarnings() = %v, want %v", warnings, tc.expectedWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const extraConfigFilesVolumeName = "extra-config-files"
+
+// validateExtraConfigFiles returns an error if the extra config files of the node pool are invalid
+func validateExtraConfigFiles(hnp *HumioNodePool) error {
+	fileNames := map[string]bool{
+		ViewGroupPermissionsFilename: true,
+		RolePermissionsFilename:      true,
+	}
+	for _, file := range hnp.GetExtraConfigFiles() {
+		if (file.ConfigMapKeyRef == nil) == (file.SecretKeyRef == nil) {
+			return fmt.Errorf("extraConfigFile %s must set exactly one of configMapKeyRef and secretKeyRef", file.FileName)
+		}
+		if fileNames[file.FileName] {
+			return fmt.Errorf("extraConfigFile conflicts with existing file name: %s", file.FileName)
+		}
+		fileNames[file.FileName] = true
+	}
+	return nil
+}
+
+// getExtraConfigFilesData returns the content of the extra config files of the node pool by file name, which is used
+// to restart the pods when the content of the files changes
+func (r *HumioClusterReconciler) getExtraConfigFilesData(ctx context.Context, hnp *HumioNodePool) (*map[string]string, error) {
+	if len(hnp.GetExtraConfigFiles()) == 0 {
+		return nil, nil
+	}
+	data := map[string]string{}
+	for _, file := range hnp.GetExtraConfigFiles() {
+		if file.ConfigMapKeyRef != nil {
+			configMap, err := kubernetes.GetConfigMap(ctx, r, file.ConfigMapKeyRef.Name, hnp.GetNamespace())
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					return nil, fmt.Errorf("extraConfigFile %s was set but no configMap exists by name %s in namespace %s", file.FileName, file.ConfigMapKeyRef.Name, hnp.GetNamespace())
+				}
+				return nil, fmt.Errorf("unable to get configMap with name %s in namespace %s", file.ConfigMapKeyRef.Name, hnp.GetNamespace())
+			}
+			value, ok := configMap.Data[file.ConfigMapKeyRef.Key]
+			if !ok {
+				return nil, fmt.Errorf("extraConfigFile %s was set but key %s was not found for configMap %s in namespace %s", file.FileName, file.ConfigMapKeyRef.Key, file.ConfigMapKeyRef.Name, hnp.GetNamespace())
+			}
+			data[file.FileName] = value
+		}
+		if file.SecretKeyRef != nil {
+			secret, err := kubernetes.GetSecret(ctx, r, file.SecretKeyRef.Name, hnp.GetNamespace())
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					return nil, fmt.Errorf("extraConfigFile %s was set but no secret exists by name %s in namespace %s", file.FileName, file.SecretKeyRef.Name, hnp.GetNamespace())
+				}
+				return nil, fmt.Errorf("unable to get secret with name %s in namespace %s", file.SecretKeyRef.Name, hnp.GetNamespace())
+			}
+			value, ok := secret.Data[file.SecretKeyRef.Key]
+			if !ok {
+				return nil, fmt.Errorf("extraConfigFile %s was set but key %s was not found for secret %s in namespace %s", file.FileName, file.SecretKeyRef.Key, file.SecretKeyRef.Name, hnp.GetNamespace())
+			}
+			data[file.FileName] = string(value)
+		}
+	}
+	return &data, nil
+}
+
+// extraConfigFilesVolume returns the projected volume holding the extra config files of the node pool, and the volume
+// mounts placing each file in the Humio data directory
+func extraConfigFilesVolume(hnp *HumioNodePool, mode int32) (corev1.Volume, []corev1.VolumeMount) {
+	volume := corev1.Volume{
+		Name: extraConfigFilesVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				DefaultMode: &mode,
+			},
+		},
+	}
+	var volumeMounts []corev1.VolumeMount
+	for _, file := range hnp.GetExtraConfigFiles() {
+		if file.ConfigMapKeyRef != nil {
+			volume.Projected.Sources = append(volume.Projected.Sources, corev1.VolumeProjection{
+				ConfigMap: &corev1.ConfigMapProjection{
+					LocalObjectReference: file.ConfigMapKeyRef.LocalObjectReference,
+					Items:                []corev1.KeyToPath{{Key: file.ConfigMapKeyRef.Key, Path: file.FileName}},
+				},
+			})
+		}
+		if file.SecretKeyRef != nil {
+			volume.Projected.Sources = append(volume.Projected.Sources, corev1.VolumeProjection{
+				Secret: &corev1.SecretProjection{
+					LocalObjectReference: file.SecretKeyRef.LocalObjectReference,
+					Items:                []corev1.KeyToPath{{Key: file.SecretKeyRef.Key, Path: file.FileName}},
+				},
+			})
+		}
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      extraConfigFilesVolumeName,
+			ReadOnly:  true,
+			MountPath: fmt.Sprintf("%s/%s", HumioDataPath, file.FileName),
+			SubPath:   file.FileName,
+		})
+	}
+	return volume, volumeMounts
+}
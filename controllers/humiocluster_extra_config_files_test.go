@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateExtraConfigFiles(t *testing.T) {
+	configMapRef := &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "idp"}, Key: "metadata.xml"}
+	secretRef := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "idp"}, Key: "metadata.xml"}
+
+	tt := []struct {
+		name        string
+		files       []humiov1alpha1.HumioExtraConfigFile
+		expectError bool
+	}{
+		{
+			name: "no files",
+		},
+		{
+			name: "configmap and secret files",
+			files: []humiov1alpha1.HumioExtraConfigFile{
+				{FileName: "idp-metadata.xml", ConfigMapKeyRef: configMapRef},
+				{FileName: "functions.json", SecretKeyRef: secretRef},
+			},
+		},
+		{
+			name:        "no source",
+			files:       []humiov1alpha1.HumioExtraConfigFile{{FileName: "idp-metadata.xml"}},
+			expectError: true,
+		},
+		{
+			name:        "both sources",
+			files:       []humiov1alpha1.HumioExtraConfigFile{{FileName: "idp-metadata.xml", ConfigMapKeyRef: configMapRef, SecretKeyRef: secretRef}},
+			expectError: true,
+		},
+		{
+			name: "duplicate file name",
+			files: []humiov1alpha1.HumioExtraConfigFile{
+				{FileName: "idp-metadata.xml", ConfigMapKeyRef: configMapRef},
+				{FileName: "idp-metadata.xml", SecretKeyRef: secretRef},
+			},
+			expectError: true,
+		},
+		{
+			name:        "conflicts with view group permissions",
+			files:       []humiov1alpha1.HumioExtraConfigFile{{FileName: ViewGroupPermissionsFilename, ConfigMapKeyRef: configMapRef}},
+			expectError: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := newPodHashTestCluster()
+			hc.Spec.ExtraConfigFiles = tc.files
+			if err := validateExtraConfigFiles(NewHumioNodeManagerFromHumioCluster(hc)); (err != nil) != tc.expectError {
+				t.Errorf("validateExtraConfigFiles() error = %v, expectError %v", err, tc.expectError)
+			}
+		})
+	}
+}
+
+func TestExtraConfigFilesRestartPods(t *testing.T) {
+	hc := newPodHashTestCluster()
+	hc.Spec.ExtraConfigFiles = []humiov1alpha1.HumioExtraConfigFile{
+		{
+			FileName:        "idp-metadata.xml",
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "idp"}, Key: "metadata.xml"},
+		},
+	}
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "idp", Namespace: hc.Namespace},
+		Data:       map[string]string{"metadata.xml": "<EntityDescriptor/>"},
+	}
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	r := &HumioClusterReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build(),
+		Log:    logr.Discard(),
+	}
+
+	constructPod := func() *corev1.Pod {
+		data, err := r.getExtraConfigFilesData(context.Background(), hnp)
+		if err != nil {
+			t.Fatalf("getExtraConfigFilesData() error = %v", err)
+		}
+		pod, err := ConstructPod(hnp, "", &podAttachments{extraConfigFilesData: data})
+		if err != nil {
+			t.Fatalf("ConstructPod() error = %v", err)
+		}
+		pod.Annotations[podHashAnnotation] = podSpecAsSHA256(hnp, *pod)
+		pod.Annotations[PodRevisionAnnotation] = "0"
+		return pod
+	}
+	pod := constructPod()
+
+	humioIdx, _ := kubernetes.GetContainerIndexByName(*pod, HumioContainerName)
+	var mounted bool
+	for _, volumeMount := range pod.Spec.Containers[humioIdx].VolumeMounts {
+		if volumeMount.Name == extraConfigFilesVolumeName && volumeMount.MountPath == HumioDataPath+"/idp-metadata.xml" {
+			mounted = true
+		}
+	}
+	if !mounted {
+		t.Errorf("expected idp-metadata.xml to be mounted into %s, got %v", HumioDataPath, pod.Spec.Containers[humioIdx].VolumeMounts)
+	}
+	if pod.Annotations[extraConfigFilesHashAnnotation] == "" {
+		t.Errorf("expected annotation %s to be set", extraConfigFilesHashAnnotation)
+	}
+
+	if match, err := r.podsMatch(hnp, *pod, *constructPod()); err != nil || !match {
+		t.Errorf("expected pods to match before the config file changes, got %v, %v", match, err)
+	}
+	configMap.Data["metadata.xml"] = "<EntityDescriptor entityID=\"new\"/>"
+	if err := r.Update(context.Background(), configMap); err != nil {
+		t.Fatal(err)
+	}
+	if match, err := r.podsMatch(hnp, *pod, *constructPod()); err != nil || match {
+		t.Errorf("expected pods not to match after the config file changes, got %v, %v", match, err)
+	}
+}
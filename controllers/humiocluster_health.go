@@ -0,0 +1,101 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var (
+	humioClusterMissingSegmentBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "humiocluster_missing_segment_bytes",
+		Help: "Size of the segments which are not available on any node of the cluster",
+	}, humioClusterUsageLabels)
+	humioClusterUnderReplicatedSegmentBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "humiocluster_under_replicated_segment_bytes",
+		Help: "Size of the segments which are stored on fewer nodes than the replication factor requires",
+	}, humioClusterUsageLabels)
+	humioClusterUnavailableNodes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "humiocluster_unavailable_nodes",
+		Help: "Number of nodes registered in the cluster which are not available",
+	}, humioClusterUsageLabels)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		humioClusterMissingSegmentBytes,
+		humioClusterUnderReplicatedSegmentBytes,
+		humioClusterUnavailableNodes,
+	)
+}
+
+// ensureHealthReported collects the health of the data of the cluster from the cluster management API. Health
+// reporting is best effort, so errors are reported in the health status rather than failing the reconcile. The status
+// is only updated when the health changes.
+func (r *HumioClusterReconciler) ensureHealthReported(ctx context.Context, hc *humiov1alpha1.HumioCluster, config *humioapi.Config, req reconcile.Request) {
+	var health *humiov1alpha1.HumioClusterHealth
+	cluster, err := r.HumioClient.GetClusters(config, req)
+	if err != nil {
+		r.Log.Error(err, "unable to get cluster health")
+		health = &humiov1alpha1.HumioClusterHealth{Message: fmt.Sprintf("unable to get cluster health: %s", err)}
+		if hc.Status.Health != nil {
+			// Keep reporting the last known health, as the cluster management API is typically unavailable while the
+			// pods are restarted
+			health.MissingSegmentBytes = hc.Status.Health.MissingSegmentBytes
+			health.UnderReplicatedSegmentBytes = hc.Status.Health.UnderReplicatedSegmentBytes
+			health.UnavailableNodes = hc.Status.Health.UnavailableNodes
+		}
+	} else {
+		health = newHumioClusterHealth(cluster)
+		setHumioClusterHealthMetrics(hc, health)
+	}
+
+	if reflect.DeepEqual(health, hc.Status.Health) {
+		return
+	}
+	if _, err = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withHealth(health)); err != nil {
+		r.Log.Error(err, "unable to update cluster health")
+	}
+}
+
+func newHumioClusterHealth(cluster humioapi.Cluster) *humiov1alpha1.HumioClusterHealth {
+	health := &humiov1alpha1.HumioClusterHealth{
+		MissingSegmentBytes:         int64(cluster.MissingSegmentSize),
+		UnderReplicatedSegmentBytes: int64(cluster.UnderReplicatedSegmentSize),
+	}
+	for _, node := range cluster.Nodes {
+		if !node.IsAvailable {
+			health.UnavailableNodes++
+		}
+	}
+	return health
+}
+
+func setHumioClusterHealthMetrics(hc *humiov1alpha1.HumioCluster, health *humiov1alpha1.HumioClusterHealth) {
+	labels := prometheus.Labels{"namespace": hc.Namespace, "cluster": hc.Name}
+	humioClusterMissingSegmentBytes.With(labels).Set(float64(health.MissingSegmentBytes))
+	humioClusterUnderReplicatedSegmentBytes.With(labels).Set(float64(health.UnderReplicatedSegmentBytes))
+	humioClusterUnavailableNodes.With(labels).Set(float64(health.UnavailableNodes))
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+func TestNewHumioClusterHealth(t *testing.T) {
+	tt := []struct {
+		name     string
+		cluster  humioapi.Cluster
+		expected *humiov1alpha1.HumioClusterHealth
+	}{
+		{
+			name: "healthy",
+			cluster: humioapi.Cluster{
+				Nodes: []humioapi.ClusterNode{{Id: 1, IsAvailable: true}, {Id: 2, IsAvailable: true}},
+			},
+			expected: &humiov1alpha1.HumioClusterHealth{},
+		},
+		{
+			name: "unavailable node with under-replicated and missing segments",
+			cluster: humioapi.Cluster{
+				Nodes:                      []humioapi.ClusterNode{{Id: 1, IsAvailable: true}, {Id: 2, IsAvailable: false}},
+				MissingSegmentSize:         1024,
+				UnderReplicatedSegmentSize: 4096,
+			},
+			expected: &humiov1alpha1.HumioClusterHealth{
+				MissingSegmentBytes:         1024,
+				UnderReplicatedSegmentBytes: 4096,
+				UnavailableNodes:            1,
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if health := newHumioClusterHealth(tc.cluster); !reflect.DeepEqual(health, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, health)
+			}
+		})
+	}
+}
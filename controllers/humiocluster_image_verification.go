@@ -0,0 +1,225 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	"github.com/humio/humio-operator/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// imageVerificationTTL is how long the digest an image tag resolved to is used before the tag is resolved and the
+	// signature is verified again. Failed verifications are retried after imageVerificationFailureTTL.
+	imageVerificationTTL        = 5 * time.Minute
+	imageVerificationFailureTTL = time.Minute
+
+	imageVerifiedReason             = "ImageVerified"
+	imageSignatureNotFoundReason    = "SignatureNotFound"
+	imageVerificationFailedReason   = "ImageVerificationFailed"
+	imageVerificationDisabledReason = "ImageVerificationDisabled"
+)
+
+// imageVerification is the outcome of resolving and verifying an image
+type imageVerification struct {
+	pinnedImage string
+	err         error
+	expires     time.Time
+}
+
+// imageVerifications caches the outcome of verifying each image with each public key, so the registry is not queried
+// on every reconcile
+var (
+	imageVerifications      = map[string]imageVerification{}
+	imageVerificationsMutex sync.Mutex
+)
+
+// imageVerificationError is returned when the image of a node pool could not be verified. Reason is used as the reason
+// of the ImageVerified condition.
+type imageVerificationError struct {
+	reason string
+	err    error
+}
+
+func (e *imageVerificationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *imageVerificationError) Unwrap() error {
+	return e.err
+}
+
+// ensureImageVerified pins the Humio image of the node pool to the digest its tag resolves to, if image verification
+// is enabled for the node pool. An error is returned if the image is not signed with the configured public key, in
+// which case the node pool must not be rolled out.
+func (r *HumioClusterReconciler) ensureImageVerified(ctx context.Context, hnp *HumioNodePool) error {
+	verification := hnp.GetImageVerification()
+	if verification == nil {
+		return nil
+	}
+	if verification.CosignPublicKeySecretRef == nil {
+		return &imageVerificationError{reason: imageVerificationFailedReason, err: errors.New("imageVerification.cosignPublicKeySecretRef must be set")}
+	}
+
+	keyRef := verification.CosignPublicKeySecretRef
+	secret, err := kubernetes.GetSecret(ctx, r, keyRef.Name, hnp.GetNamespace())
+	if err != nil {
+		return &imageVerificationError{reason: imageVerificationFailedReason, err: fmt.Errorf("unable to get secret %s containing the cosign public key: %w", keyRef.Name, err)}
+	}
+	publicKey, ok := secret.Data[keyRef.Key]
+	if !ok {
+		return &imageVerificationError{reason: imageVerificationFailedReason, err: fmt.Errorf("imageVerification was set but key %s was not found for secret %s in namespace %s", keyRef.Key, keyRef.Name, hnp.GetNamespace())}
+	}
+
+	image := hnp.GetImage()
+	cacheKey := fmt.Sprintf("%s/%x", image, sha256.Sum256(publicKey))
+	imageVerificationsMutex.Lock()
+	cached, found := imageVerifications[cacheKey]
+	imageVerificationsMutex.Unlock()
+	if !found || time.Now().After(cached.expires) {
+		credentials, err := r.imagePullCredentials(ctx, hnp)
+		if err != nil {
+			return &imageVerificationError{reason: imageVerificationFailedReason, err: err}
+		}
+		cached = imageVerification{expires: time.Now().Add(imageVerificationTTL)}
+		cached.pinnedImage, cached.err = verifyImage(ctx, image, publicKey, credentials)
+		if cached.err != nil {
+			cached.expires = time.Now().Add(imageVerificationFailureTTL)
+		} else if cached.pinnedImage != image {
+			r.Log.Info(fmt.Sprintf("pinning image %s of node pool %s to %s", image, hnp.GetNodePoolName(), cached.pinnedImage))
+		}
+		imageVerificationsMutex.Lock()
+		imageVerifications[cacheKey] = cached
+		imageVerificationsMutex.Unlock()
+	}
+	if cached.err != nil {
+		return cached.err
+	}
+	hnp.SetImage(cached.pinnedImage)
+	return nil
+}
+
+// imagePullCredentials returns the registry credentials from the image pull secrets of the node pool
+func (r *HumioClusterReconciler) imagePullCredentials(ctx context.Context, hnp *HumioNodePool) (map[string]registry.Credentials, error) {
+	credentials := map[string]registry.Credentials{}
+	for _, pullSecret := range hnp.GetImagePullSecrets() {
+		secret, err := kubernetes.GetSecret(ctx, r, pullSecret.Name, hnp.GetNamespace())
+		if err != nil {
+			return nil, fmt.Errorf("unable to get image pull secret %s: %w", pullSecret.Name, err)
+		}
+		if data, ok := secret.Data[corev1.DockerConfigJsonKey]; ok {
+			if err := registry.CredentialsFromDockerConfig(data, credentials); err != nil {
+				return nil, fmt.Errorf("unable to read image pull secret %s: %w", pullSecret.Name, err)
+			}
+		}
+	}
+	return credentials, nil
+}
+
+// verifyImage resolves the image to a digest and verifies that the digest is signed with the public key. The image is
+// returned pinned to the digest, keeping the tag so the version of Humio can still be determined from the image.
+func verifyImage(ctx context.Context, image string, publicKey []byte, credentials map[string]registry.Credentials) (string, error) {
+	key, err := registry.ParsePublicKey(publicKey)
+	if err != nil {
+		return "", &imageVerificationError{reason: imageVerificationFailedReason, err: fmt.Errorf("invalid cosign public key: %w", err)}
+	}
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return "", &imageVerificationError{reason: imageVerificationFailedReason, err: err}
+	}
+	c := registry.NewClient(credentials)
+	digest, err := c.Digest(ctx, ref)
+	if err != nil {
+		return "", &imageVerificationError{reason: imageVerificationFailedReason, err: fmt.Errorf("unable to resolve digest of image %s: %w", image, err)}
+	}
+	if err := c.VerifySignature(ctx, ref, digest, key); err != nil {
+		if errors.Is(err, registry.ErrNoSignature) {
+			return "", &imageVerificationError{reason: imageSignatureNotFoundReason, err: fmt.Errorf("image %s resolved to %s, which is not signed with the configured cosign public key", image, digest)}
+		}
+		return "", &imageVerificationError{reason: imageVerificationFailedReason, err: fmt.Errorf("unable to verify signature of image %s: %w", image, err)}
+	}
+	return strings.SplitN(image, "@", 2)[0] + "@" + digest, nil
+}
+
+// imageNotVerified sets the ImageVerified condition of the cluster to False and puts the node pool into ConfigError,
+// which stops the node pool from being rolled out. A Warning event is emitted when the verification starts failing.
+func (r *HumioClusterReconciler) imageNotVerified(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnp *HumioNodePool, err error) (reconcile.Result, error) {
+	reason := imageVerificationFailedReason
+	var verificationErr *imageVerificationError
+	if errors.As(err, &verificationErr) {
+		reason = verificationErr.reason
+	}
+	message := fmt.Sprintf("image of node pool %s could not be verified: %s", hnp.GetNodePoolName(), err)
+	existing := meta.FindStatusCondition(hc.Status.Conditions, humiov1alpha1.HumioClusterConditionTypeImageVerified)
+	if r.Recorder != nil && (existing == nil || existing.Message != message) {
+		r.Recorder.Event(hc, corev1.EventTypeWarning, reason, message)
+	}
+	return r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+		withMessage(r.logErrorAndReturn(err, "unable to verify image").Error()).
+		withCondition(metav1.Condition{
+			Type:    humiov1alpha1.HumioClusterConditionTypeImageVerified,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		}).
+		withNodePoolState(humiov1alpha1.HumioClusterStateConfigError, hnp.GetNodePoolName()))
+}
+
+// ensureImageVerificationReported sets the ImageVerified condition of the cluster to True once the images of all node
+// pools with image verification enabled have been verified. The condition is only added to clusters using image
+// verification, and is set to False if image verification is disabled again.
+func (r *HumioClusterReconciler) ensureImageVerificationReported(ctx context.Context, hc *humiov1alpha1.HumioCluster, hnps []*HumioNodePool) error {
+	var verified []string
+	for _, hnp := range hnps {
+		if hnp.GetImageVerification() != nil {
+			verified = append(verified, hnp.GetImage())
+		}
+	}
+	existing := meta.FindStatusCondition(hc.Status.Conditions, humiov1alpha1.HumioClusterConditionTypeImageVerified)
+	condition := metav1.Condition{
+		Type:    humiov1alpha1.HumioClusterConditionTypeImageVerified,
+		Status:  metav1.ConditionTrue,
+		Reason:  imageVerifiedReason,
+		Message: fmt.Sprintf("verified images: %s", strings.Join(verified, ", ")),
+	}
+	if len(verified) == 0 {
+		if existing == nil {
+			return nil
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = imageVerificationDisabledReason
+		condition.Message = "image verification is not enabled for any node pool"
+	}
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason && existing.Message == condition.Message {
+		return nil
+	}
+	_, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+		withCondition(condition))
+	return err
+}
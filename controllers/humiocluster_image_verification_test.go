@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// signedImageRegistry serves the humio/humio-core:1.100.0 image, which is signed with key if signed is true
+func signedImageRegistry(t *testing.T, key *ecdsa.PrivateKey, signed bool) (*httptest.Server, string) {
+	manifest := []byte(`{"schemaVersion":2}`)
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+	payload := []byte(fmt.Sprintf(`{"critical":{"image":{"docker-manifest-digest":%q}}}`, digest))
+	payloadHash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, payloadHash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := map[string][]byte{
+		"/v2/humio/humio-core/manifests/1.100.0":                         manifest,
+		fmt.Sprintf("/v2/humio/humio-core/blobs/sha256:%x", payloadHash): payload,
+	}
+	if signed {
+		content["/v2/humio/humio-core/manifests/"+strings.Replace(digest, ":", "-", 1)+".sig"] = []byte(fmt.Sprintf(
+			`{"layers":[{"digest":"sha256:%x","annotations":{"dev.cosignproject.cosign/signature":%q}}]}`,
+			payloadHash, base64.StdEncoding.EncodeToString(signature)))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := content[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	return server, digest
+}
+
+func TestEnsureImageVerified(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cosign", Namespace: "default"},
+		Data:       map[string][]byte{"cosign.pub": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})},
+	}
+
+	for _, signed := range []bool{true, false} {
+		t.Run(fmt.Sprintf("signed=%t", signed), func(t *testing.T) {
+			server, digest := signedImageRegistry(t, key, signed)
+			defer server.Close()
+			image := fmt.Sprintf("%s/humio/humio-core:1.100.0", strings.TrimPrefix(server.URL, "http://"))
+
+			hc := &humiov1alpha1.HumioCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "verified", Namespace: "default"},
+			}
+			hc.Spec.Image = image
+			hc.Spec.NodeCount = 1
+			hc.Spec.ImageVerification = &humiov1alpha1.HumioImageVerification{
+				CosignPublicKeySecretRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: publicKey.Name},
+					Key:                  "cosign.pub",
+				},
+			}
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = humiov1alpha1.AddToScheme(scheme)
+			r := &HumioClusterReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects([]client.Object{hc, publicKey}...).WithStatusSubresource(hc).Build(),
+				Log:      logr.Discard(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			hnp := NewHumioNodeManagerFromHumioCluster(hc)
+			ctx := context.Background()
+
+			err := r.ensureImageVerified(ctx, hnp)
+			if signed {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected := image + "@" + digest; hnp.GetImage() != expected {
+					t.Errorf("expected image to be pinned to %s, got %s", expected, hnp.GetImage())
+				}
+				if err := r.ensureImageVerificationReported(ctx, hc, []*HumioNodePool{hnp}); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				if err == nil {
+					t.Fatal("expected an unsigned image to be rejected")
+				}
+				if _, err := r.imageNotVerified(ctx, hc, hnp, err); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var updated humiov1alpha1.HumioCluster
+			if err := r.Get(ctx, types.NamespacedName{Name: hc.Name, Namespace: hc.Namespace}, &updated); err != nil {
+				t.Fatal(err)
+			}
+			condition := meta.FindStatusCondition(updated.Status.Conditions, humiov1alpha1.HumioClusterConditionTypeImageVerified)
+			if condition == nil {
+				t.Fatal("expected the ImageVerified condition to be set")
+			}
+			if signed && condition.Status != metav1.ConditionTrue {
+				t.Errorf("expected the image to be verified, got %s: %s", condition.Reason, condition.Message)
+			}
+			if !signed {
+				if condition.Status != metav1.ConditionFalse || condition.Reason != imageSignatureNotFoundReason {
+					t.Errorf("expected the image not to be verified, got %s %s", condition.Status, condition.Reason)
+				}
+				if len(updated.Status.NodePoolStatus) != 1 || updated.Status.NodePoolStatus[0].State != humiov1alpha1.HumioClusterStateConfigError {
+					t.Errorf("expected the node pool to be in ConfigError, got %+v", updated.Status.NodePoolStatus)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+)
+
+// splitImageRegistry splits an image reference into its registry and the remainder of the reference. The registry is
+// empty if the image reference does not contain one, in which case the image is pulled from Docker Hub.
+func splitImageRegistry(image string) (string, string) {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 {
+		return "", image
+	}
+	if strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost" {
+		return parts[0], parts[1]
+	}
+	return "", image
+}
+
+// imageWithRegistry returns the image reference pulled from the given registry instead of the registry of the image.
+// The image is returned as is if registry is empty.
+func imageWithRegistry(image, registry string) string {
+	if registry == "" {
+		return image
+	}
+	_, remainder := splitImageRegistry(image)
+	return strings.TrimSuffix(registry, "/") + "/" + remainder
+}
+
+// imageTag returns the tag of the image reference, or an empty string if the image reference does not contain a tag.
+// The digest of the image is ignored.
+func imageTag(image string) string {
+	_, remainder := splitImageRegistry(strings.SplitN(image, "@", 2)[0])
+	parts := strings.SplitN(remainder, ":", 2)
+	if len(parts) == 1 {
+		return ""
+	}
+	return parts[1]
+}
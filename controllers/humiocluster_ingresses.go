@@ -27,7 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func constructNginxIngressAnnotations(hc *humiov1alpha1.HumioCluster, hostname string, ingressSpecificAnnotations map[string]string) map[string]string {
+func constructNginxIngressAnnotations(hc *humiov1alpha1.HumioCluster, hostname string, serviceName string, ingressSpecificAnnotations map[string]string) map[string]string {
 	annotations := make(map[string]string)
 	annotations["nginx.ingress.kubernetes.io/configuration-snippet"] = `
 more_set_headers "Expect-CT: max-age=604800, enforce";
@@ -49,8 +49,8 @@ more_set_headers "X-XSS-Protection: 1; mode=block";`
 
 	if helpers.TLSEnabled(hc) {
 		annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "HTTPS"
-		annotations["nginx.ingress.kubernetes.io/proxy-ssl-name"] = fmt.Sprintf("%s.%s", hc.Name, hc.Namespace)
-		annotations["nginx.ingress.kubernetes.io/proxy-ssl-server-name"] = fmt.Sprintf("%s.%s", hc.Name, hc.Namespace)
+		annotations["nginx.ingress.kubernetes.io/proxy-ssl-name"] = fmt.Sprintf("%s.%s", serviceName, hc.Namespace)
+		annotations["nginx.ingress.kubernetes.io/proxy-ssl-server-name"] = fmt.Sprintf("%s.%s", serviceName, hc.Namespace)
 		annotations["nginx.ingress.kubernetes.io/proxy-ssl-secret"] = fmt.Sprintf("%s/%s", hc.Namespace, hc.Name)
 		annotations["nginx.ingress.kubernetes.io/proxy-ssl-verify"] = "on"
 	}
@@ -58,26 +58,32 @@ more_set_headers "X-XSS-Protection: 1; mode=block";`
 	for k, v := range ingressSpecificAnnotations {
 		annotations[k] = v
 	}
+	for k, v := range complianceProfileIngressAnnotations(hc) {
+		annotations[k] = v
+	}
 	return annotations
 }
 
 func ConstructGeneralIngress(hc *humiov1alpha1.HumioCluster, hostname string) *networkingv1.Ingress {
+	serviceName := ingressServiceName(hc, hc.Spec.Ingress.UINodePoolName)
 	annotations := make(map[string]string)
 	annotations["nginx.ingress.kubernetes.io/proxy-body-size"] = "512m"
 	annotations["nginx.ingress.kubernetes.io/proxy-http-version"] = "1.1"
 	annotations["nginx.ingress.kubernetes.io/proxy-read-timeout"] = "25"
 	return constructIngress(
 		hc,
+		serviceName,
 		fmt.Sprintf("%s-general", hc.Name),
 		hostname,
 		[]string{humioPathOrDefault(hc)},
 		HumioPort,
 		certificateSecretNameOrDefault(hc),
-		constructNginxIngressAnnotations(hc, hostname, annotations),
+		constructNginxIngressAnnotations(hc, hostname, serviceName, annotations),
 	)
 }
 
 func ConstructStreamingQueryIngress(hc *humiov1alpha1.HumioCluster, hostname string) *networkingv1.Ingress {
+	serviceName := ingressServiceName(hc, hc.Spec.Ingress.UINodePoolName)
 	annotations := make(map[string]string)
 	annotations["nginx.ingress.kubernetes.io/proxy-body-size"] = "512m"
 	annotations["nginx.ingress.kubernetes.io/proxy-http-version"] = "1.1"
@@ -86,16 +92,18 @@ func ConstructStreamingQueryIngress(hc *humiov1alpha1.HumioCluster, hostname str
 	annotations["nginx.ingress.kubernetes.io/proxy-buffering"] = "off"
 	return constructIngress(
 		hc,
+		serviceName,
 		fmt.Sprintf("%s-streaming-query", hc.Name),
 		hostname,
 		[]string{fmt.Sprintf("%sapi/v./(dataspaces|repositories)/[^/]+/query$", humioPathOrDefault(hc))},
 		HumioPort,
 		certificateSecretNameOrDefault(hc),
-		constructNginxIngressAnnotations(hc, hostname, annotations),
+		constructNginxIngressAnnotations(hc, hostname, serviceName, annotations),
 	)
 }
 
 func ConstructIngestIngress(hc *humiov1alpha1.HumioCluster, hostname string) *networkingv1.Ingress {
+	serviceName := ingressServiceName(hc, hc.Spec.Ingress.IngestNodePoolName)
 	annotations := make(map[string]string)
 	annotations["nginx.ingress.kubernetes.io/proxy-body-size"] = "512m"
 	annotations["nginx.ingress.kubernetes.io/proxy-http-version"] = "1.1"
@@ -103,6 +111,7 @@ func ConstructIngestIngress(hc *humiov1alpha1.HumioCluster, hostname string) *ne
 	annotations["nginx.ingress.kubernetes.io/use-regex"] = "true"
 	return constructIngress(
 		hc,
+		serviceName,
 		fmt.Sprintf("%s-ingest", hc.Name),
 		hostname,
 		[]string{
@@ -113,27 +122,29 @@ func ConstructIngestIngress(hc *humiov1alpha1.HumioCluster, hostname string) *ne
 		},
 		HumioPort,
 		certificateSecretNameOrDefault(hc),
-		constructNginxIngressAnnotations(hc, hostname, annotations),
+		constructNginxIngressAnnotations(hc, hostname, serviceName, annotations),
 	)
 }
 
 func ConstructESIngestIngress(hc *humiov1alpha1.HumioCluster, esHostname string) *networkingv1.Ingress {
+	serviceName := ingressServiceName(hc, hc.Spec.Ingress.IngestNodePoolName)
 	annotations := make(map[string]string)
 	annotations["nginx.ingress.kubernetes.io/proxy-body-size"] = "512m"
 	annotations["nginx.ingress.kubernetes.io/proxy-http-version"] = "1.1"
 	annotations["nginx.ingress.kubernetes.io/proxy-read-timeout"] = "90"
 	return constructIngress(
 		hc,
+		serviceName,
 		fmt.Sprintf("%s-es-ingest", hc.Name),
 		esHostname,
 		[]string{humioPathOrDefault(hc)},
 		elasticPort,
 		esCertificateSecretNameOrDefault(hc),
-		constructNginxIngressAnnotations(hc, esHostname, annotations),
+		constructNginxIngressAnnotations(hc, esHostname, serviceName, annotations),
 	)
 }
 
-func constructIngress(hc *humiov1alpha1.HumioCluster, name string, hostname string, paths []string, port int, secretName string, annotations map[string]string) *networkingv1.Ingress {
+func constructIngress(hc *humiov1alpha1.HumioCluster, serviceName string, name string, hostname string, paths []string, port int, secretName string, annotations map[string]string) *networkingv1.Ingress {
 	var httpIngressPaths []networkingv1.HTTPIngressPath
 	pathTypeImplementationSpecific := networkingv1.PathTypeImplementationSpecific
 	for _, path := range paths {
@@ -142,7 +153,7 @@ func constructIngress(hc *humiov1alpha1.HumioCluster, name string, hostname stri
 			PathType: &pathTypeImplementationSpecific,
 			Backend: networkingv1.IngressBackend{
 				Service: &networkingv1.IngressServiceBackend{
-					Name: (*ConstructService(NewHumioNodeManagerFromHumioCluster(hc))).Name,
+					Name: serviceName,
 					Port: networkingv1.ServiceBackendPort{
 						Number: int32(port),
 					},
@@ -184,3 +195,38 @@ func constructIngress(hc *humiov1alpha1.HumioCluster, name string, hostname stri
 	}
 	return &ingress
 }
+
+// ingressServiceName returns the name of the Service of the given node pool, or the Service of the pods defined at the
+// top level of the HumioCluster spec if no node pool is given
+func ingressServiceName(hc *humiov1alpha1.HumioCluster, nodePoolName string) string {
+	for idx := range hc.Spec.NodePools {
+		if nodePoolName != "" && hc.Spec.NodePools[idx].Name == nodePoolName {
+			return ConstructService(NewHumioNodeManagerFromHumioNodePool(hc, &hc.Spec.NodePools[idx])).Name
+		}
+	}
+	return ConstructService(NewHumioNodeManagerFromHumioCluster(hc)).Name
+}
+
+// validateIngressNodePools returns an error if the ingress would send traffic to a node pool which does not exist, or to
+// the pods defined at the top level of the HumioCluster spec while there are none
+func validateIngressNodePools(hc *humiov1alpha1.HumioCluster) error {
+	for _, nodePoolName := range []string{hc.Spec.Ingress.UINodePoolName, hc.Spec.Ingress.IngestNodePoolName} {
+		if nodePoolName == "" {
+			if len(hc.Spec.NodePools) > 0 && hc.Spec.NodeCount == 0 {
+				return fmt.Errorf("ingress requires pods at the top level of the HumioCluster spec, unless ingress.uiNodePoolName and ingress.ingestNodePoolName are set")
+			}
+			continue
+		}
+		found := false
+		for _, pool := range hc.Spec.NodePools {
+			if pool.Name == nodePoolName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("ingress refers to node pool %s which is not defined in nodePools", nodePoolName)
+		}
+	}
+	return nil
+}
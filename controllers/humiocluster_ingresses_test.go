@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newIngressTestCluster(nodeCount int, ingress humiov1alpha1.HumioClusterIngressSpec) *humiov1alpha1.HumioCluster {
+	return &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+		Spec: humiov1alpha1.HumioClusterSpec{
+			Ingress:       ingress,
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{NodeCount: nodeCount},
+			NodePools: []humiov1alpha1.HumioNodePoolSpec{
+				{
+					Name: "frontend",
+					HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+						NodeCount: 2,
+						NodeRoles: []humiov1alpha1.HumioNodeRole{humiov1alpha1.HumioNodeRoleQuery},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestIngressTargetsNodePools(t *testing.T) {
+	hc := newIngressTestCluster(3, humiov1alpha1.HumioClusterIngressSpec{
+		Enabled:        true,
+		Controller:     "nginx",
+		UINodePoolName: "frontend",
+	})
+	if err := validateIngressNodePools(hc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		backendName string
+	}{
+		{name: "general", backendName: ConstructGeneralIngress(hc, "humio.example.com").Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name},
+		{name: "streaming query", backendName: ConstructStreamingQueryIngress(hc, "humio.example.com").Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name},
+	} {
+		if tc.backendName != "humiocluster-frontend" {
+			t.Errorf("expected %s ingress to target the frontend node pool, got %s", tc.name, tc.backendName)
+		}
+	}
+	for _, tc := range []struct {
+		name        string
+		backendName string
+	}{
+		{name: "ingest", backendName: ConstructIngestIngress(hc, "humio.example.com").Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name},
+		{name: "es ingest", backendName: ConstructESIngestIngress(hc, "es.humio.example.com").Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name},
+	} {
+		if tc.backendName != "humiocluster" {
+			t.Errorf("expected %s ingress to target the top level pods, got %s", tc.name, tc.backendName)
+		}
+	}
+}
+
+func TestValidateIngressNodePools(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		nodeCount   int
+		ingress     humiov1alpha1.HumioClusterIngressSpec
+		expectedErr bool
+	}{
+		{name: "top level pods", nodeCount: 3},
+		{name: "no top level pods", nodeCount: 0, expectedErr: true},
+		{name: "no top level pods with node pools", nodeCount: 0, ingress: humiov1alpha1.HumioClusterIngressSpec{UINodePoolName: "frontend", IngestNodePoolName: "frontend"}},
+		{name: "unknown node pool", nodeCount: 3, ingress: humiov1alpha1.HumioClusterIngressSpec{UINodePoolName: "missing"}, expectedErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateIngressNodePools(newIngressTestCluster(tc.nodeCount, tc.ingress))
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %t, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	licenseExpiryWarningDaysDefault = 30
+
+	licenseExpiringSoonReason = "LicenseExpiringSoon"
+	licenseExpiredReason      = "LicenseExpired"
+	licenseValidReason        = "LicenseValid"
+)
+
+var (
+	humioClusterLicenseExpirationTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "humiocluster_license_expiration_timestamp_seconds",
+		Help: "Time the license of the cluster expires, in seconds since the epoch",
+	}, humioClusterUsageLabels)
+	humioClusterLicenseExpiringSoon = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "humiocluster_license_expiring_soon",
+		Help: "1 if the license of the cluster expires within the expiry warning days of the cluster or has expired, 0 otherwise",
+	}, humioClusterUsageLabels)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		humioClusterLicenseExpirationTimestamp,
+		humioClusterLicenseExpiringSoon,
+	)
+}
+
+// licenseExpiryWarningDays returns the number of days before the license expires from which the license is reported
+// as expiring soon
+func licenseExpiryWarningDays(hc *humiov1alpha1.HumioCluster) int {
+	if hc.Spec.License.ExpiryWarningDays > 0 {
+		return hc.Spec.License.ExpiryWarningDays
+	}
+	return licenseExpiryWarningDaysDefault
+}
+
+// licenseExpiresInDays returns the number of whole days from now until the license expires. The result is negative
+// once the license has expired.
+func licenseExpiresInDays(expiresAt, now time.Time) int {
+	return int(math.Floor(expiresAt.Sub(now).Hours() / 24))
+}
+
+// licenseDegradedCondition returns the Degraded condition of the cluster for a license expiring at the given time
+func licenseDegradedCondition(expiresAt, now time.Time) metav1.Condition {
+	if !now.Before(expiresAt) {
+		return metav1.Condition{
+			Type:    humiov1alpha1.HumioClusterConditionTypeDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  licenseExpiredReason,
+			Message: fmt.Sprintf("license expired at %s", expiresAt.Format(time.RFC3339)),
+		}
+	}
+	return metav1.Condition{
+		Type:    humiov1alpha1.HumioClusterConditionTypeDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  licenseValidReason,
+		Message: fmt.Sprintf("license expires at %s", expiresAt.Format(time.RFC3339)),
+	}
+}
+
+// reportLicenseExpiry updates the license status, the Degraded condition and the license metrics of the cluster for a
+// license expiring at the given time. A Warning event is emitted once per day when the license is about to expire or
+// has expired.
+func (r *HumioClusterReconciler) reportLicenseExpiry(ctx context.Context, hc *humiov1alpha1.HumioCluster, expiration string) {
+	licenseStatus := humiov1alpha1.HumioLicenseStatus{
+		Type:       "onprem",
+		Expiration: expiration,
+	}
+	expiresAt, err := time.Parse(time.RFC3339, expiration)
+	if err != nil {
+		r.Log.Error(err, fmt.Sprintf("unable to parse license expiration %s", expiration))
+		_, _ = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+			withLicense(licenseStatus))
+		return
+	}
+
+	now := time.Now()
+	expiresInDays := licenseExpiresInDays(expiresAt, now)
+	licenseStatus.ExpiresInDays = &expiresInDays
+	warningDays := licenseExpiryWarningDays(hc)
+	expiringSoon := expiresInDays < warningDays
+
+	labels := prometheus.Labels{"namespace": hc.Namespace, "cluster": hc.Name}
+	humioClusterLicenseExpirationTimestamp.With(labels).Set(float64(expiresAt.Unix()))
+	if expiringSoon {
+		humioClusterLicenseExpiringSoon.With(labels).Set(1)
+	} else {
+		humioClusterLicenseExpiringSoon.With(labels).Set(0)
+	}
+
+	// Events are only emitted when the number of days until the license expires changes, so every reconcile does not
+	// emit another event
+	previous := hc.Status.LicenseStatus.ExpiresInDays
+	if r.Recorder != nil && (previous == nil || *previous != expiresInDays) {
+		if !now.Before(expiresAt) {
+			r.Recorder.Event(hc, corev1.EventTypeWarning, licenseExpiredReason,
+				fmt.Sprintf("The license of the cluster expired at %s", expiration))
+		} else if expiringSoon {
+			r.Recorder.Event(hc, corev1.EventTypeWarning, licenseExpiringSoonReason,
+				fmt.Sprintf("The license of the cluster expires in %d days at %s", expiresInDays, expiration))
+		}
+	}
+
+	_, _ = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().
+		withLicense(licenseStatus).
+		withCondition(licenseDegradedCondition(expiresAt, now)))
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReportLicenseExpiry(t *testing.T) {
+	tt := []struct {
+		name              string
+		expiresIn         time.Duration
+		warningDays       int
+		previousDays      *int
+		expectedDays      int
+		expectedEvent     string
+		expectedDegraded  metav1.ConditionStatus
+		expectedSoonGauge bool
+	}{
+		{
+			name:             "license valid",
+			expiresIn:        90*24*time.Hour + time.Hour,
+			expectedDays:     90,
+			expectedDegraded: metav1.ConditionFalse,
+		},
+		{
+			name:              "license expiring soon",
+			expiresIn:         10*24*time.Hour + time.Hour,
+			expectedDays:      10,
+			expectedEvent:     licenseExpiringSoonReason,
+			expectedDegraded:  metav1.ConditionFalse,
+			expectedSoonGauge: true,
+		},
+		{
+			name:              "license expiring soon already reported today",
+			expiresIn:         10*24*time.Hour + time.Hour,
+			previousDays:      helpers.IntPtr(10),
+			expectedDays:      10,
+			expectedDegraded:  metav1.ConditionFalse,
+			expectedSoonGauge: true,
+		},
+		{
+			name:             "license outside custom warning days",
+			expiresIn:        10*24*time.Hour + time.Hour,
+			warningDays:      7,
+			expectedDays:     10,
+			expectedDegraded: metav1.ConditionFalse,
+		},
+		{
+			name:              "license expired",
+			expiresIn:         -time.Hour,
+			expectedDays:      -1,
+			expectedEvent:     licenseExpiredReason,
+			expectedDegraded:  metav1.ConditionTrue,
+			expectedSoonGauge: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "license", Namespace: "default"},
+				Spec: humiov1alpha1.HumioClusterSpec{
+					License: humiov1alpha1.HumioClusterLicenseSpec{ExpiryWarningDays: tc.warningDays},
+				},
+				Status: humiov1alpha1.HumioClusterStatus{
+					LicenseStatus: humiov1alpha1.HumioLicenseStatus{ExpiresInDays: tc.previousDays},
+				},
+			}
+			scheme := runtime.NewScheme()
+			_ = humiov1alpha1.AddToScheme(scheme)
+			recorder := record.NewFakeRecorder(10)
+			r := &HumioClusterReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(hc).WithStatusSubresource(hc).Build(),
+				Log:      logr.Discard(),
+				Recorder: recorder,
+			}
+
+			expiration := time.Now().Add(tc.expiresIn).UTC().Format(time.RFC3339)
+			r.reportLicenseExpiry(context.Background(), hc, expiration)
+
+			var updated humiov1alpha1.HumioCluster
+			if err := r.Get(context.Background(), types.NamespacedName{Name: hc.Name, Namespace: hc.Namespace}, &updated); err != nil {
+				t.Fatal(err)
+			}
+			licenseStatus := updated.Status.LicenseStatus
+			if licenseStatus.Expiration != expiration {
+				t.Errorf("expected expiration %s, got %s", expiration, licenseStatus.Expiration)
+			}
+			if licenseStatus.ExpiresInDays == nil || *licenseStatus.ExpiresInDays != tc.expectedDays {
+				t.Errorf("expected license to expire in %d days, got %v", tc.expectedDays, licenseStatus.ExpiresInDays)
+			}
+			condition := meta.FindStatusCondition(updated.Status.Conditions, humiov1alpha1.HumioClusterConditionTypeDegraded)
+			if condition == nil || condition.Status != tc.expectedDegraded {
+				t.Errorf("expected Degraded condition %s, got %+v", tc.expectedDegraded, condition)
+			}
+
+			expectedSoon := 0.0
+			if tc.expectedSoonGauge {
+				expectedSoon = 1
+			}
+			labels := prometheus.Labels{"namespace": hc.Namespace, "cluster": hc.Name}
+			if soon := testutil.ToFloat64(humioClusterLicenseExpiringSoon.With(labels)); soon != expectedSoon {
+				t.Errorf("expected humiocluster_license_expiring_soon to be %v, got %v", expectedSoon, soon)
+			}
+
+			var event string
+			select {
+			case event = <-recorder.Events:
+			default:
+			}
+			if tc.expectedEvent == "" && event != "" {
+				t.Errorf("expected no event, got %q", event)
+			}
+			if tc.expectedEvent != "" && !strings.Contains(event, tc.expectedEvent) {
+				t.Errorf("expected %s event, got %q", tc.expectedEvent, event)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// maxConcurrentNodePoolReconciles is the maximum number of node pools of a single HumioCluster whose resources are
+// reconciled at the same time
+const maxConcurrentNodePoolReconciles = 4
+
+// ctxNodePoolResultFunc reconciles a single node pool, returning a non-empty result if the reconcile should stop and be
+// requeued
+type ctxNodePoolResultFunc func(context.Context, *HumioNodePool) (reconcile.Result, error)
+
+// reconcileNodePools calls fun for each of the node pools, with at most maxConcurrentNodePoolReconciles calls running
+// at the same time. It waits for all calls to return, and returns the first node pool, in the order given, whose call
+// returned an error or a non-empty result, along with that result and error. The node pools returned by
+// reconcileNodePools are nil if all calls returned an empty result without an error.
+//
+// Only steps which do not depend on the state of the other node pools may be reconciled using reconcileNodePools.
+// Steps which must happen one node pool at a time, such as replacing the pods of a node pool during upgrades and
+// restarts, are reconciled sequentially.
+func reconcileNodePools(ctx context.Context, pools []*HumioNodePool, fun ctxNodePoolResultFunc) (*HumioNodePool, reconcile.Result, error) {
+	results := make([]reconcile.Result, len(pools))
+	errs := make([]error, len(pools))
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, maxConcurrentNodePoolReconciles)
+	for idx := range pools {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(idx int) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			results[idx], errs[idx] = fun(ctx, pools[idx])
+		}(idx)
+	}
+	wg.Wait()
+
+	for idx, pool := range pools {
+		if errs[idx] != nil || results[idx] != (reconcile.Result{}) {
+			return pool, results[idx], errs[idx]
+		}
+	}
+	return nil, reconcile.Result{}, nil
+}
+
+// reconcileNodePoolsWithoutResult calls fun for each of the node pools like reconcileNodePools, for steps which only
+// return an error
+func reconcileNodePoolsWithoutResult(ctx context.Context, pools []*HumioNodePool, fun func(context.Context, *HumioNodePool) error) (*HumioNodePool, error) {
+	pool, _, err := reconcileNodePools(ctx, pools, func(ctx context.Context, hnp *HumioNodePool) (reconcile.Result, error) {
+		return reconcile.Result{}, fun(ctx, hnp)
+	})
+	return pool, err
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileNodePools(t *testing.T) {
+	hc := &humiov1alpha1.HumioCluster{ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"}}
+	var pools []*HumioNodePool
+	for i := 0; i < 10; i++ {
+		hc.Spec.NodePools = append(hc.Spec.NodePools, humiov1alpha1.HumioNodePoolSpec{Name: fmt.Sprintf("pool-%d", i)})
+		pools = append(pools, NewHumioNodeManagerFromHumioNodePool(hc, &hc.Spec.NodePools[i]))
+	}
+
+	var running, maxRunning int32
+	var mutex sync.Mutex
+	reconciled := map[string]bool{}
+	failing := map[string]bool{"humiocluster-pool-3": true, "humiocluster-pool-7": true}
+	pool, result, err := reconcileNodePools(context.Background(), pools, func(ctx context.Context, hnp *HumioNodePool) (reconcile.Result, error) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			seen := atomic.LoadInt32(&maxRunning)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		reconciled[hnp.GetNodePoolName()] = true
+		mutex.Unlock()
+		if failing[hnp.GetNodePoolName()] {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("unable to reconcile %s", hnp.GetNodePoolName())
+		}
+		return reconcile.Result{}, nil
+	})
+
+	if len(reconciled) != len(pools) {
+		t.Errorf("expected all %d node pools to be reconciled, got %d", len(pools), len(reconciled))
+	}
+	if maxRunning > maxConcurrentNodePoolReconciles {
+		t.Errorf("expected at most %d node pools to be reconciled at the same time, got %d", maxConcurrentNodePoolReconciles, maxRunning)
+	}
+	if maxRunning < 2 {
+		t.Errorf("expected node pools to be reconciled concurrently, got %d at most", maxRunning)
+	}
+	if pool == nil || pool.GetNodePoolName() != "humiocluster-pool-3" {
+		t.Fatalf("expected the first failing node pool to be returned, got %v", pool)
+	}
+	if err == nil || err.Error() != "unable to reconcile humiocluster-pool-3" || !result.Requeue {
+		t.Errorf("expected the result and error of the first failing node pool, got %+v, %v", result, err)
+	}
+
+	pool, err = reconcileNodePoolsWithoutResult(context.Background(), pools, func(ctx context.Context, hnp *HumioNodePool) error {
+		return nil
+	})
+	if pool != nil || err != nil {
+		t.Errorf("expected no node pool to be returned, got %v, %v", pool, err)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	graphql "github.com/cli/shurcooL-graphql"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+)
+
+// validateNodeRoles returns an error if no node pool would be assigned digest or storage partitions
+func validateNodeRoles(hnps []*HumioNodePool) error {
+	for _, role := range []humiov1alpha1.HumioNodeRole{humiov1alpha1.HumioNodeRoleDigest, humiov1alpha1.HumioNodeRoleStorage} {
+		found := false
+		for _, hnp := range hnps {
+			if hnp.GetNodeCount() > 0 && hnp.HasNodeRole(role) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("at least one node pool with nodes must have the %s role", role)
+		}
+	}
+	return nil
+}
+
+// nodePoolsRestrictRole returns true if any of the node pools do not have the given role, in which case partitions
+// for the role must only be assigned to a subset of the Humio nodes
+func nodePoolsRestrictRole(hnps []*HumioNodePool, role humiov1alpha1.HumioNodeRole) bool {
+	for _, hnp := range hnps {
+		if !hnp.HasNodeRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeIDsWithRole returns the sorted Humio node IDs of the pods in the node pools with the given role. This relies on
+// the node ID labels set on the pods by ensureLabels.
+func (r *HumioClusterReconciler) nodeIDsWithRole(ctx context.Context, hnps []*HumioNodePool, role humiov1alpha1.HumioNodeRole) ([]int, error) {
+	var nodeIDs []int
+	for _, hnp := range hnps {
+		if !hnp.HasNodeRole(role) {
+			continue
+		}
+		pods, err := kubernetes.ListPods(ctx, r, hnp.GetNamespace(), hnp.GetNodePoolLabels())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods of node pool %s: %w", hnp.GetNodePoolName(), err)
+		}
+		for _, pod := range pods {
+			nodeIDStr, ok := pod.Labels[kubernetes.NodeIdLabelName]
+			if !ok {
+				continue
+			}
+			nodeID, err := strconv.Atoi(nodeIDStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid node id %s on pod %s: %w", nodeIDStr, pod.Name, err)
+			}
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+	}
+	sort.Ints(nodeIDs)
+	return nodeIDs, nil
+}
+
+// restrictStoragePartitions returns the storage partition layout where only the eligible nodes are assigned partitions
+func restrictStoragePartitions(layout []humioapi.StoragePartitionInput, eligibleNodeIDs []int) []humioapi.StoragePartitionInput {
+	restricted := make([]humioapi.StoragePartitionInput, 0, len(layout))
+	for _, partition := range layout {
+		restricted = append(restricted, humioapi.StoragePartitionInput{
+			ID:      partition.ID,
+			NodeIDs: restrictPartitionNodeIDs(int(partition.ID), partition.NodeIDs, eligibleNodeIDs),
+		})
+	}
+	return restricted
+}
+
+// restrictIngestPartitions returns the ingest partition layout where only the eligible nodes are assigned partitions
+func restrictIngestPartitions(layout []humioapi.IngestPartitionInput, eligibleNodeIDs []int) []humioapi.IngestPartitionInput {
+	restricted := make([]humioapi.IngestPartitionInput, 0, len(layout))
+	for _, partition := range layout {
+		restricted = append(restricted, humioapi.IngestPartitionInput{
+			ID:      partition.ID,
+			NodeIDs: restrictPartitionNodeIDs(int(partition.ID), partition.NodeIDs, eligibleNodeIDs),
+		})
+	}
+	return restricted
+}
+
+// restrictPartitionNodeIDs keeps the eligible nodes assigned to a partition, and replaces the other nodes with eligible
+// nodes picked round-robin by partition ID, so the partition keeps its replication factor where possible
+func restrictPartitionNodeIDs(partitionID int, nodeIDs []graphql.Int, eligibleNodeIDs []int) []graphql.Int {
+	eligible := map[int]bool{}
+	for _, nodeID := range eligibleNodeIDs {
+		eligible[nodeID] = true
+	}
+
+	restricted := []graphql.Int{}
+	assigned := map[int]bool{}
+	for _, nodeID := range nodeIDs {
+		if eligible[int(nodeID)] && !assigned[int(nodeID)] {
+			restricted = append(restricted, nodeID)
+			assigned[int(nodeID)] = true
+		}
+	}
+	for i := 0; len(restricted) < len(nodeIDs) && i < len(eligibleNodeIDs); i++ {
+		candidate := eligibleNodeIDs[(partitionID+i)%len(eligibleNodeIDs)]
+		if !assigned[candidate] {
+			restricted = append(restricted, graphql.Int(candidate))
+			assigned[candidate] = true
+		}
+	}
+	return restricted
+}
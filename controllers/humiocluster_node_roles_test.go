@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	graphql "github.com/cli/shurcooL-graphql"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetNodeRolesEnvVarValue(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		nodeRoles []humiov1alpha1.HumioNodeRole
+		expected  string
+	}{
+		{name: "no roles", expected: ""},
+		{name: "ingest only", nodeRoles: []humiov1alpha1.HumioNodeRole{humiov1alpha1.HumioNodeRoleIngest}, expected: "ingestonly"},
+		{name: "query only", nodeRoles: []humiov1alpha1.HumioNodeRole{humiov1alpha1.HumioNodeRoleQuery}, expected: "httponly"},
+		{name: "ingest and query", nodeRoles: []humiov1alpha1.HumioNodeRole{humiov1alpha1.HumioNodeRoleIngest, humiov1alpha1.HumioNodeRoleQuery}, expected: "httponly"},
+		{name: "storage", nodeRoles: []humiov1alpha1.HumioNodeRole{humiov1alpha1.HumioNodeRoleStorage}, expected: "all"},
+		{name: "digest and query", nodeRoles: []humiov1alpha1.HumioNodeRole{humiov1alpha1.HumioNodeRoleDigest, humiov1alpha1.HumioNodeRoleQuery}, expected: "all"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+				Spec: humiov1alpha1.HumioClusterSpec{
+					HumioNodeSpec: humiov1alpha1.HumioNodeSpec{NodeRoles: tc.nodeRoles},
+				},
+			}
+			hnp := NewHumioNodeManagerFromHumioCluster(hc)
+			if got := hnp.GetNodeRolesEnvVarValue(); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+			if tc.expected == "" && EnvVarHasKey(hnp.GetEnvironmentVariables(), "NODE_ROLES") {
+				t.Errorf("did not expect NODE_ROLES to be set")
+			}
+			if tc.expected != "" && !EnvVarHasValue(hnp.GetEnvironmentVariables(), "NODE_ROLES", tc.expected) {
+				t.Errorf("expected NODE_ROLES to be set to %s, got %v", tc.expected, hnp.GetEnvironmentVariables())
+			}
+		})
+	}
+}
+
+func TestNodeRolesUserEnvironmentVariableTakesPrecedence(t *testing.T) {
+	hc := &humiov1alpha1.HumioCluster{
+		Spec: humiov1alpha1.HumioClusterSpec{
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				NodeRoles:            []humiov1alpha1.HumioNodeRole{humiov1alpha1.HumioNodeRoleQuery},
+				EnvironmentVariables: []corev1.EnvVar{{Name: "NODE_ROLES", Value: "all"}},
+			},
+		},
+	}
+	if !EnvVarHasValue(NewHumioNodeManagerFromHumioCluster(hc).GetEnvironmentVariables(), "NODE_ROLES", "all") {
+		t.Errorf("expected NODE_ROLES from environment variables to take precedence")
+	}
+}
+
+func TestValidateNodeRoles(t *testing.T) {
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+		Spec: humiov1alpha1.HumioClusterSpec{
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				NodeCount: 3,
+				NodeRoles: []humiov1alpha1.HumioNodeRole{humiov1alpha1.HumioNodeRoleQuery},
+			},
+		},
+	}
+	queryPool := NewHumioNodeManagerFromHumioCluster(hc)
+	storagePool := NewHumioNodeManagerFromHumioNodePool(hc, &humiov1alpha1.HumioNodePoolSpec{
+		Name: "storage",
+		HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+			NodeCount: 3,
+			NodeRoles: []humiov1alpha1.HumioNodeRole{humiov1alpha1.HumioNodeRoleDigest, humiov1alpha1.HumioNodeRoleStorage},
+		},
+	})
+
+	if err := validateNodeRoles([]*HumioNodePool{queryPool}); err == nil {
+		t.Errorf("expected error when no node pool has the digest and storage roles")
+	}
+	if err := validateNodeRoles([]*HumioNodePool{queryPool, storagePool}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if !nodePoolsRestrictRole([]*HumioNodePool{queryPool, storagePool}, humiov1alpha1.HumioNodeRoleStorage) {
+		t.Errorf("expected storage role to be restricted")
+	}
+	if nodePoolsRestrictRole([]*HumioNodePool{storagePool}, humiov1alpha1.HumioNodeRoleStorage) {
+		t.Errorf("did not expect storage role to be restricted")
+	}
+}
+
+func TestRestrictStoragePartitions(t *testing.T) {
+	layout := []humioapi.StoragePartitionInput{
+		{ID: 0, NodeIDs: []graphql.Int{1, 2}},
+		{ID: 1, NodeIDs: []graphql.Int{2, 3}},
+		{ID: 2, NodeIDs: []graphql.Int{3, 4}},
+		{ID: 3, NodeIDs: []graphql.Int{4, 1}},
+	}
+	// Nodes 3 and 4 are query nodes
+	expected := []humioapi.StoragePartitionInput{
+		{ID: 0, NodeIDs: []graphql.Int{1, 2}},
+		{ID: 1, NodeIDs: []graphql.Int{2, 1}},
+		{ID: 2, NodeIDs: []graphql.Int{1, 2}},
+		{ID: 3, NodeIDs: []graphql.Int{1, 2}},
+	}
+	if got := restrictStoragePartitions(layout, []int{1, 2}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// The replication factor cannot exceed the number of eligible nodes
+	expected = []humioapi.StoragePartitionInput{
+		{ID: 0, NodeIDs: []graphql.Int{1}},
+		{ID: 1, NodeIDs: []graphql.Int{1}},
+		{ID: 2, NodeIDs: []graphql.Int{1}},
+		{ID: 3, NodeIDs: []graphql.Int{1}},
+	}
+	if got := restrictStoragePartitions(layout, []int{1}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestRestrictIngestPartitions(t *testing.T) {
+	layout := []humioapi.IngestPartitionInput{
+		{ID: 0, NodeIDs: []graphql.Int{1, 3}},
+		{ID: 1, NodeIDs: []graphql.Int{2, 1}},
+	}
+	expected := []humioapi.IngestPartitionInput{
+		{ID: 0, NodeIDs: []graphql.Int{1, 2}},
+		{ID: 1, NodeIDs: []graphql.Int{2, 1}},
+	}
+	if got := restrictIngestPartitions(layout, []int{1, 2}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podSpecHashes holds the hashes of a pod spec which are compared with the hash annotation of the pods
+type podSpecHashes struct {
+	hash       string
+	legacyHash string
+}
+
+// cachedPodSpecHashes is the hashes of the desired pod spec of a node pool, along with the fingerprint of the inputs the
+// desired pod spec was constructed from
+type cachedPodSpecHashes struct {
+	fingerprint string
+	hashes      podSpecHashes
+}
+
+// podSpecHashCache caches the hashes of the desired pod spec of each node pool, so the desired pod spec is not
+// sanitized, marshalled and hashed on every reconcile while neither the HumioCluster nor the Secrets and ConfigMaps
+// the pods depend on have changed. Entries are kept per HumioCluster and node pool name. The zero value is ready to
+// use.
+type podSpecHashCache struct {
+	mutex  sync.Mutex
+	hashes map[types.NamespacedName]map[string]cachedPodSpecHashes
+}
+
+func (c *podSpecHashCache) get(cluster types.NamespacedName, nodePoolName string) (cachedPodSpecHashes, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cached, found := c.hashes[cluster][nodePoolName]
+	return cached, found
+}
+
+func (c *podSpecHashCache) set(cluster types.NamespacedName, nodePoolName string, cached cachedPodSpecHashes) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.hashes == nil {
+		c.hashes = map[types.NamespacedName]map[string]cachedPodSpecHashes{}
+	}
+	if c.hashes[cluster] == nil {
+		c.hashes[cluster] = map[string]cachedPodSpecHashes{}
+	}
+	c.hashes[cluster][nodePoolName] = cached
+}
+
+// delete drops the cached hashes of all node pools of the given HumioCluster
+func (c *podSpecHashCache) delete(cluster types.NamespacedName) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.hashes, cluster)
+}
+
+// retainNodePools drops the cached hashes of node pools of the given HumioCluster which are no longer part of it
+func (c *podSpecHashCache) retainNodePools(cluster types.NamespacedName, hnps []*HumioNodePool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for nodePoolName := range c.hashes[cluster] {
+		retained := false
+		for _, hnp := range hnps {
+			if hnp.GetNodePoolName() == nodePoolName {
+				retained = true
+				break
+			}
+		}
+		if !retained {
+			delete(c.hashes[cluster], nodePoolName)
+		}
+	}
+}
+
+// newPodSpecHashes returns the hashes of the spec of the pod
+func newPodSpecHashes(hnp *HumioNodePool, pod corev1.Pod) podSpecHashes {
+	return podSpecHashes{
+		hash:       podSpecAsSHA256(hnp, pod),
+		legacyHash: legacyPodSpecAsSHA256(hnp, pod),
+	}
+}
+
+// desiredPodFingerprint returns a fingerprint of everything the desired pod spec of the node pool is constructed from.
+// The generation of the HumioCluster covers its spec, while the image, data nodes and auth migration are covered
+// separately as they may change without the generation changing. The attachments cover the Secrets and ConfigMaps
+// the pods depend on.
+func desiredPodFingerprint(hnp *HumioNodePool, attachments *podAttachments) (string, error) {
+	b, err := json.Marshal(struct {
+		Image                        string
+		ClusterAnnotations           map[string]string
+		DataNodes                    []string
+		AuthMigrationEnvVars         []corev1.EnvVar
+		DataVolumeSource             corev1.VolumeSource
+		InitServiceAccountSecretName string
+		AuthServiceAccountSecretName string
+		EnvVarSourceData             *map[string]string
+		ExtraConfigFilesData         *map[string]string
+		SecretsStoreData             *map[string]string
+		EnvVarSecretsData            *map[string]string
+		ReadinessGates               []corev1.PodReadinessGate
+		DataNodeName                 string
+	}{
+		Image:                        hnp.GetImage(),
+		ClusterAnnotations:           hnp.clusterAnnotations,
+		DataNodes:                    hnp.GetDataNodes(),
+		AuthMigrationEnvVars:         hnp.authMigrationEnvironmentVariables,
+		DataVolumeSource:             attachments.dataVolumeSource,
+		InitServiceAccountSecretName: attachments.initServiceAccountSecretName,
+		AuthServiceAccountSecretName: attachments.authServiceAccountSecretName,
+		EnvVarSourceData:             attachments.envVarSourceData,
+		ExtraConfigFilesData:         attachments.extraConfigFilesData,
+		SecretsStoreData:             attachments.secretsStoreData,
+		EnvVarSecretsData:            attachments.envVarSecretsData,
+		ReadinessGates:               attachments.readinessGates,
+		DataNodeName:                 attachments.dataNodeName,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%d/%s", hnp.clusterUID, hnp.clusterGeneration, helpers.AsSHA256(string(b))), nil
+}
+
+// getDesiredPodSpecHashes returns the hashes of the desired pod spec of the node pool, reusing the hashes from an
+// earlier reconcile if the fingerprint of the desired pod is unchanged. Nothing is cached for node pools of clusters
+// without a generation, as changes to the spec of the cluster would not be detected.
+func (c *podSpecHashCache) getDesiredPodSpecHashes(hnp *HumioNodePool, attachments *podAttachments, desiredPod corev1.Pod) podSpecHashes {
+	if hnp.clusterGeneration == 0 {
+		return newPodSpecHashes(hnp, desiredPod)
+	}
+	fingerprint, err := desiredPodFingerprint(hnp, attachments)
+	if err != nil {
+		return newPodSpecHashes(hnp, desiredPod)
+	}
+	cluster := types.NamespacedName{Namespace: hnp.GetNamespace(), Name: hnp.GetClusterName()}
+
+	if cached, found := c.get(cluster, hnp.GetNodePoolName()); found && cached.fingerprint == fingerprint {
+		return cached.hashes
+	}
+
+	hashes := newPodSpecHashes(hnp, desiredPod)
+	c.set(cluster, hnp.GetNodePoolName(), cachedPodSpecHashes{fingerprint: fingerprint, hashes: hashes})
+	return hashes
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestGetDesiredPodSpecHashes(t *testing.T) {
+	hc := newPodHashTestCluster()
+	hc.UID = "a1b2c3"
+	hc.Generation = 1
+	attachments := &podAttachments{envVarSecretsData: &map[string]string{"smtp": "first"}}
+	var cache podSpecHashCache
+
+	desiredPod := func(hnp *HumioNodePool, attachments *podAttachments) corev1.Pod {
+		pod, err := ConstructPod(hnp, "", attachments)
+		if err != nil {
+			t.Fatalf("ConstructPod() error = %v", err)
+		}
+		return *pod
+	}
+
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+	pod := desiredPod(hnp, attachments)
+	hashes := cache.getDesiredPodSpecHashes(hnp, attachments, pod)
+	if hashes != newPodSpecHashes(hnp, pod) {
+		t.Fatalf("expected the hashes of the desired pod, got %+v", hashes)
+	}
+
+	// The cached hashes are used as long as neither the cluster nor the attachments change, even if the pod passed
+	// in differs
+	otherPod := pod.DeepCopy()
+	otherPod.Spec.Hostname = "other"
+	if cached := cache.getDesiredPodSpecHashes(hnp, attachments, *otherPod); cached != hashes {
+		t.Errorf("expected the cached hashes %+v, got %+v", hashes, cached)
+	}
+
+	tt := []struct {
+		name        string
+		hnp         func() *HumioNodePool
+		attachments *podAttachments
+	}{
+		{
+			name: "generation changed",
+			hnp: func() *HumioNodePool {
+				changed := hc.DeepCopy()
+				changed.Generation = 2
+				changed.Spec.NodeCount = 5
+				return NewHumioNodeManagerFromHumioCluster(changed)
+			},
+			attachments: attachments,
+		},
+		{
+			name: "cluster recreated",
+			hnp: func() *HumioNodePool {
+				changed := hc.DeepCopy()
+				changed.UID = "d4e5f6"
+				changed.Spec.NodeCount = 5
+				return NewHumioNodeManagerFromHumioCluster(changed)
+			},
+			attachments: attachments,
+		},
+		{
+			name: "image pinned",
+			hnp: func() *HumioNodePool {
+				changed := NewHumioNodeManagerFromHumioCluster(hc)
+				changed.SetImage("humio/humio-core:1.82.1@sha256:0123")
+				return changed
+			},
+			attachments: attachments,
+		},
+		{
+			name:        "secret rotated",
+			hnp:         func() *HumioNodePool { return NewHumioNodeManagerFromHumioCluster(hc) },
+			attachments: &podAttachments{envVarSecretsData: &map[string]string{"smtp": "rotated"}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Start out with the hashes of the original cluster cached
+			cache.getDesiredPodSpecHashes(hnp, attachments, pod)
+
+			changedHnp := tc.hnp()
+			changedPod := desiredPod(changedHnp, tc.attachments)
+			if got := cache.getDesiredPodSpecHashes(changedHnp, tc.attachments, changedPod); got != newPodSpecHashes(changedHnp, changedPod) {
+				t.Errorf("expected the hashes to be recomputed, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestGetPodDesiredLifecycleStateUsesCachedHashes(t *testing.T) {
+	hc := newPodHashTestCluster()
+	hc.UID = "a1b2c3"
+	hc.Generation = 1
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+	attachments := &podAttachments{}
+	r := &HumioClusterReconciler{Log: logr.Discard()}
+
+	var pods []corev1.Pod
+	for _, name := range []string{"humiocluster-core-a", "humiocluster-core-b"} {
+		pod, err := ConstructPod(hnp, name, attachments)
+		if err != nil {
+			t.Fatalf("ConstructPod() error = %v", err)
+		}
+		pod.Annotations[podHashAnnotation] = podSpecAsSHA256(hnp, *pod)
+		pod.Annotations[PodRevisionAnnotation] = "0"
+		pods = append(pods, *pod)
+	}
+
+	for i := 0; i < 2; i++ {
+		state, err := r.getPodDesiredLifecycleState(hnp, pods, attachments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.configurationDifference != nil {
+			t.Errorf("expected pods matching the desired pod not to be restarted, got %+v", state)
+		}
+	}
+
+	changed := hc.DeepCopy()
+	changed.Generation = 2
+	changed.Spec.EnvironmentVariables = append(changed.Spec.EnvironmentVariables, corev1.EnvVar{Name: "QUERY_COORDINATOR", Value: "false"})
+	state, err := r.getPodDesiredLifecycleState(NewHumioNodeManagerFromHumioCluster(changed), pods, attachments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.configurationDifference == nil {
+		t.Error("expected pods to be restarted after the spec of the cluster changed")
+	}
+}
+
+func TestPodSpecHashCacheEviction(t *testing.T) {
+	hc := newPodHashTestCluster()
+	hc.UID = "a1b2c3"
+	hc.Generation = 1
+	hc.Spec.NodePools = []humiov1alpha1.HumioNodePoolSpec{
+		{Name: "ingest", HumioNodeSpec: humiov1alpha1.HumioNodeSpec{Image: "humio/humio-core:1.82.1", NodeCount: 2}},
+	}
+	cluster := types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name}
+	mainPool := NewHumioNodeManagerFromHumioCluster(hc)
+	ingestPool := NewHumioNodeManagerFromHumioNodePool(hc, &hc.Spec.NodePools[0])
+
+	tt := []struct {
+		name          string
+		evict         func(cache *podSpecHashCache)
+		expectedPools []string
+	}{
+		{
+			name:          "node pools in the spec are retained",
+			evict:         func(cache *podSpecHashCache) { cache.retainNodePools(cluster, []*HumioNodePool{mainPool, ingestPool}) },
+			expectedPools: []string{mainPool.GetNodePoolName(), ingestPool.GetNodePoolName()},
+		},
+		{
+			name:          "removed node pool is evicted",
+			evict:         func(cache *podSpecHashCache) { cache.retainNodePools(cluster, []*HumioNodePool{mainPool}) },
+			expectedPools: []string{mainPool.GetNodePoolName()},
+		},
+		{
+			name:  "deleted cluster is evicted",
+			evict: func(cache *podSpecHashCache) { cache.delete(cluster) },
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cache podSpecHashCache
+			for _, hnp := range []*HumioNodePool{mainPool, ingestPool} {
+				attachments := &podAttachments{}
+				pod, err := ConstructPod(hnp, "", attachments)
+				if err != nil {
+					t.Fatalf("ConstructPod() error = %v", err)
+				}
+				cache.getDesiredPodSpecHashes(hnp, attachments, *pod)
+			}
+
+			tc.evict(&cache)
+
+			if len(cache.hashes[cluster]) != len(tc.expectedPools) {
+				t.Errorf("expected %d cached node pools, got %d", len(tc.expectedPools), len(cache.hashes[cluster]))
+			}
+			for _, nodePoolName := range tc.expectedPools {
+				if _, found := cache.get(cluster, nodePoolName); !found {
+					t.Errorf("expected hashes of node pool %s to be cached", nodePoolName)
+				}
+			}
+		})
+	}
+}
@@ -35,6 +35,9 @@ func (p *podLifecycleState) ShouldRollingRestart() bool {
 	if p.nodePool.GetUpdateStrategy().Type == humiov1alpha1.HumioClusterUpdateStrategyReplaceAllOnUpdate {
 		return false
 	}
+	if p.nodePool.GetUpdateStrategy().Type == humiov1alpha1.HumioClusterUpdateStrategyBlueGreen {
+		return false
+	}
 	if p.nodePool.GetUpdateStrategy().Type == humiov1alpha1.HumioClusterUpdateStrategyRollingUpdate {
 		return true
 	}
@@ -99,6 +102,10 @@ func (p *podLifecycleState) ShouldDeletePod() bool {
 	if p.nodePool.GetUpdateStrategy().Type == humiov1alpha1.HumioClusterUpdateStrategyOnDelete {
 		return false
 	}
+	// pods are only deleted by the blue/green update once their replacements receive traffic
+	if p.nodePool.GetUpdateStrategy().Type == humiov1alpha1.HumioClusterUpdateStrategyBlueGreen {
+		return false
+	}
 	return p.WantsUpgrade() || p.WantsRestart()
 }
 
@@ -61,6 +61,11 @@ type podAttachments struct {
 	initServiceAccountSecretName string
 	authServiceAccountSecretName string
 	envVarSourceData             *map[string]string
+	extraConfigFilesData         *map[string]string
+	secretsStoreData             *map[string]string
+	envVarSecretsData            *map[string]string
+	readinessGates               []corev1.PodReadinessGate
+	dataNodeName                 string
 }
 
 // nodeUUIDTemplateVars contains the variables that are allowed to be rendered for the nodeUUID string
@@ -90,6 +95,10 @@ func ConstructContainerArgs(hnp *HumioNodePool, podEnvVars []corev1.EnvVar) ([]s
 
 	if !hnp.InitContainerDisabled() {
 		shellCommands = append(shellCommands, fmt.Sprintf("export ZONE=$(cat %s/availability-zone)", sharedPath))
+		if len(hnp.GetNodeTags()) > 0 {
+			// Helper images from before node tags were introduced do not write the file, so only export it if present
+			shellCommands = append(shellCommands, fmt.Sprintf("if [ -f %[1]s/node-tags ]; then export NODE_TAGS=$(cat %[1]s/node-tags); fi", sharedPath))
+		}
 	}
 
 	hnpResources := hnp.GetResources()
@@ -103,6 +112,40 @@ func ConstructContainerArgs(hnp *HumioNodePool, podEnvVars []corev1.EnvVar) ([]s
 	return []string{"-c", strings.Join(shellCommands, " && ")}, nil
 }
 
+// nodeTagLabels returns the node tag mapping in the format read by the init container, which is a comma-separated
+// list of tag=label pairs
+func nodeTagLabels(nodeTags []humiov1alpha1.HumioNodeTag) string {
+	pairs := make([]string, 0, len(nodeTags))
+	for _, nodeTag := range nodeTags {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", nodeTag.Tag, nodeTag.NodeLabel))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// validateNodeTags returns an error if the node tags of the node pool cannot be looked up by the init container
+func validateNodeTags(hnp *HumioNodePool) error {
+	if len(hnp.GetNodeTags()) == 0 {
+		return nil
+	}
+	if hnp.InitContainerDisabled() {
+		return fmt.Errorf("nodeTags requires the init container, but disableInitContainer is set")
+	}
+	seen := map[string]bool{}
+	for _, nodeTag := range hnp.GetNodeTags() {
+		if nodeTag.Tag == "" || nodeTag.NodeLabel == "" {
+			return fmt.Errorf("node tags must have both a tag and a nodeLabel")
+		}
+		if strings.ContainsAny(nodeTag.Tag, "=,") || strings.ContainsAny(nodeTag.NodeLabel, "=,") {
+			return fmt.Errorf("node tag %s must not contain '=' or ','", nodeTag.Tag)
+		}
+		if seen[nodeTag.Tag] {
+			return fmt.Errorf("node tag %s is defined more than once", nodeTag.Tag)
+		}
+		seen[nodeTag.Tag] = true
+	}
+	return nil
+}
+
 // constructNodeUUIDPrefix checks the value of the nodeUUID prefix and attempts to render it as a template. If the template
 // renders {{.Zone}} as the string set to containsZoneIdentifier, then we can be assured that the desired outcome is
 // that the zone in included inside the nodeUUID prefix.
@@ -136,9 +179,8 @@ func ConstructPod(hnp *HumioNodePool, humioNodeName string, attachments *podAtta
 	var pod corev1.Pod
 	mode := int32(420)
 	productVersion := "unknown"
-	imageSplit := strings.SplitN(hnp.GetImage(), ":", 2)
-	if len(imageSplit) == 2 {
-		productVersion = imageSplit[1]
+	if tag := imageTag(hnp.GetImage()); tag != "" {
+		productVersion = tag
 	}
 	userID := int64(65534)
 
@@ -155,6 +197,7 @@ func ConstructPod(hnp *HumioNodePool, humioNodeName string, attachments *podAtta
 			ImagePullSecrets:      hnp.GetImagePullSecrets(),
 			Subdomain:             headlessServiceName(hnp.GetClusterName()),
 			Hostname:              humioNodeName,
+			ReadinessGates:        attachments.readinessGates,
 			Containers: []corev1.Container{
 				{
 					Name:            AuthContainerName,
@@ -285,6 +328,9 @@ func ConstructPod(hnp *HumioNodePool, humioNodeName string, attachments *podAtta
 					StartupProbe:    hnp.GetContainerStartupProbe(),
 					Resources:       hnp.GetResources(),
 					SecurityContext: hnp.GetContainerSecurityContext(),
+					// The last log lines are used as the termination message, so the probable cause of a crash loop
+					// can be reported
+					TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
 				},
 			},
 			Volumes: []corev1.Volume{
@@ -337,6 +383,35 @@ func ConstructPod(hnp *HumioNodePool, humioNodeName string, attachments *podAtta
 		}
 	}
 
+	// Add an annotation with the hash of the content of the extra config files to trigger pod restarts when they change
+	if attachments.extraConfigFilesData != nil {
+		b, err := json.Marshal(attachments.extraConfigFilesData)
+		if err != nil {
+			return &corev1.Pod{}, fmt.Errorf("error trying to JSON encode extraConfigFilesData: %w", err)
+		}
+		pod.Annotations[extraConfigFilesHashAnnotation] = helpers.AsSHA256(string(b))
+	}
+
+	// Add an annotation with the hash of the Secrets synced by the Secrets Store CSI driver to trigger pod restarts
+	// when they are rotated
+	if attachments.secretsStoreData != nil {
+		b, err := json.Marshal(attachments.secretsStoreData)
+		if err != nil {
+			return &corev1.Pod{}, fmt.Errorf("error trying to JSON encode secretsStoreData: %w", err)
+		}
+		pod.Annotations[secretsStoreHashAnnotation] = helpers.AsSHA256(string(b))
+	}
+
+	// Add an annotation with the hash of the secret keys referenced by the environment variables to trigger pod
+	// restarts when the secrets are rotated, e.g. by the External Secrets Operator
+	if attachments.envVarSecretsData != nil {
+		b, err := json.Marshal(attachments.envVarSecretsData)
+		if err != nil {
+			return &corev1.Pod{}, fmt.Errorf("error trying to JSON encode envVarSecretsData: %w", err)
+		}
+		pod.Annotations[envVarSecretsHashAnnotation] = helpers.AsSHA256(string(b))
+	}
+
 	if EnvVarHasValue(pod.Spec.Containers[humioIdx].Env, "AUTHENTICATION_METHOD", "saml") {
 		pod.Spec.Containers[humioIdx].Env = append(pod.Spec.Containers[humioIdx].Env, corev1.EnvVar{
 			Name:  "SAML_IDP_CERTIFICATE",
@@ -358,6 +433,12 @@ func ConstructPod(hnp *HumioNodePool, humioNodeName string, attachments *podAtta
 		})
 	}
 
+	if attachments.dataNodeName != "" {
+		pod.Spec.NodeSelector = map[string]string{
+			corev1.LabelHostname: attachments.dataNodeName,
+		}
+	}
+
 	if !hnp.InitContainerDisabled() {
 		pod.Spec.InitContainers = []corev1.Container{
 			{
@@ -417,6 +498,18 @@ func ConstructPod(hnp *HumioNodePool, humioNodeName string, attachments *podAtta
 				},
 			},
 		}
+		if len(hnp.GetNodeTags()) > 0 {
+			pod.Spec.InitContainers[0].Env = append(pod.Spec.InitContainers[0].Env,
+				corev1.EnvVar{
+					Name:  "NODE_TAG_LABELS",
+					Value: nodeTagLabels(hnp.GetNodeTags()),
+				},
+				corev1.EnvVar{
+					Name:  "NODE_TAGS_TARGET_FILE",
+					Value: fmt.Sprintf("%s/node-tags", sharedPath),
+				},
+			)
+		}
 		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
 			Name: "init-service-account-secret",
 			VolumeSource: corev1.VolumeSource{
@@ -499,6 +592,18 @@ func ConstructPod(hnp *HumioNodePool, humioNodeName string, attachments *podAtta
 		})
 	}
 
+	if len(hnp.GetExtraConfigFiles()) > 0 {
+		volume, volumeMounts := extraConfigFilesVolume(hnp, mode)
+		pod.Spec.Containers[humioIdx].VolumeMounts = append(pod.Spec.Containers[humioIdx].VolumeMounts, volumeMounts...)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+	}
+
+	if hnp.GetSecretsStore() != nil {
+		volume, volumeMount := secretsStoreVolume(hnp)
+		pod.Spec.Containers[humioIdx].VolumeMounts = append(pod.Spec.Containers[humioIdx].VolumeMounts, volumeMount)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+	}
+
 	for _, sidecar := range hnp.GetSidecarContainers() {
 		for _, existingContainer := range pod.Spec.Containers {
 			if sidecar.Name == existingContainer.Name {
@@ -625,6 +730,19 @@ func ConstructPod(hnp *HumioNodePool, humioNodeName string, attachments *podAtta
 		pod.Spec.PriorityClassName = priorityClassName
 	}
 
+	if hnp.GetDNSPolicy() == corev1.DNSNone && hnp.GetDNSConfig() == nil {
+		return &corev1.Pod{}, fmt.Errorf("dnsConfig must be set when dnsPolicy is %s", corev1.DNSNone)
+	}
+	if dnsPolicy := hnp.GetDNSPolicy(); dnsPolicy != "" {
+		pod.Spec.DNSPolicy = dnsPolicy
+	}
+	if dnsConfig := hnp.GetDNSConfig(); dnsConfig != nil {
+		pod.Spec.DNSConfig = dnsConfig
+	}
+	if hostAliases := hnp.GetHostAliases(); len(hostAliases) > 0 {
+		pod.Spec.HostAliases = hostAliases
+	}
+
 	if EnvVarHasValue(pod.Spec.Containers[humioIdx].Env, "ENABLE_ORGANIZATIONS", "true") && EnvVarHasKey(pod.Spec.Containers[humioIdx].Env, "ORGANIZATION_MODE") {
 		authIdx, err := kubernetes.GetContainerIndexByName(pod, AuthContainerName)
 		if err != nil {
@@ -692,13 +810,12 @@ func EnvVarHasKey(envVars []corev1.EnvVar, key string) bool {
 // This modifies the input pod object before returning it.
 func sanitizePod(hnp *HumioNodePool, pod *corev1.Pod) *corev1.Pod {
 	// TODO: For volume mount containing service account secret, set name to empty string
-	sanitizedVolumes := make([]corev1.Volume, 0)
-	emptyPersistentVolumeClaimSource := corev1.PersistentVolumeClaimVolumeSource{}
+	sanitizedVolumes := make([]corev1.Volume, 0, len(pod.Spec.Volumes))
 	hostname := fmt.Sprintf("%s-core-%s", hnp.GetNodePoolName(), "")
 	mode := int32(420)
 
 	for idx, container := range pod.Spec.Containers {
-		sanitizedEnvVars := make([]corev1.EnvVar, 0)
+		sanitizedEnvVars := make([]corev1.EnvVar, 0, len(container.Env))
 		if container.Name == HumioContainerName {
 			for _, envVar := range container.Env {
 				if envVar.Name == "EXTERNAL_URL" {
@@ -733,12 +850,9 @@ func sanitizePod(hnp *HumioNodePool, pod *corev1.Pod) *corev1.Pod {
 	}
 
 	for _, volume := range pod.Spec.Volumes {
-		if volume.Name == "humio-data" && reflect.DeepEqual(volume.PersistentVolumeClaim, emptyPersistentVolumeClaimSource) {
-			sanitizedVolumes = append(sanitizedVolumes, corev1.Volume{
-				Name:         "humio-data",
-				VolumeSource: hnp.GetDataVolumeSource(),
-			})
-		} else if volume.Name == "humio-data" && !reflect.DeepEqual(volume.PersistentVolumeClaim, emptyPersistentVolumeClaimSource) {
+		// The data volume is always hashed as the claim template, also when the node pool uses a data volume source.
+		// Existing pods carry hashes computed this way, so changing it would restart them.
+		if volume.Name == "humio-data" {
 			sanitizedVolumes = append(sanitizedVolumes, corev1.Volume{
 				Name:         "humio-data",
 				VolumeSource: hnp.GetDataVolumePersistentVolumeClaimSpecTemplate(""),
@@ -793,6 +907,17 @@ func sanitizePod(hnp *HumioNodePool, pod *corev1.Pod) *corev1.Pod {
 	pod.Spec.Tolerations = hnp.GetTolerations()
 	pod.Spec.TopologySpreadConstraints = hnp.GetTopologySpreadConstraints()
 
+	// Readiness gates are only added to pods created during blue/green updates, and do not affect how the pod runs
+	pod.Spec.ReadinessGates = nil
+
+	// The worker node a pod is pinned to depends on which worker nodes hold data of the node pool
+	if hnp.DataVolumeNodePinningEnabled() {
+		delete(pod.Spec.NodeSelector, corev1.LabelHostname)
+		if len(pod.Spec.NodeSelector) == 0 {
+			pod.Spec.NodeSelector = nil
+		}
+	}
+
 	for i := range pod.Spec.InitContainers {
 		pod.Spec.InitContainers[i].ImagePullPolicy = hnp.GetImagePullPolicy()
 		pod.Spec.InitContainers[i].TerminationMessagePath = ""
@@ -819,8 +944,130 @@ func sanitizePod(hnp *HumioNodePool, pod *corev1.Pod) *corev1.Pod {
 	return pod
 }
 
-// podSpecAsSHA256 looks at the pod spec minus known nondeterministic fields and returns a sha256 hash of the spec
+// normalizePodSpec rewrites fields of a pod spec which can be expressed in several equivalent ways, so cosmetic changes
+// to the HumioCluster do not change the hash of the pod spec. Fields left empty are set to the defaults Kubernetes
+// would use, empty structs are removed and lists where the order has no meaning are sorted.
+// This modifies the input pod spec.
+func normalizePodSpec(spec *corev1.PodSpec) {
+	defaultMode := int32(420)
+	for i := range spec.Volumes {
+		if secret := spec.Volumes[i].Secret; secret != nil && secret.DefaultMode == nil {
+			secret.DefaultMode = &defaultMode
+		}
+		if configMap := spec.Volumes[i].ConfigMap; configMap != nil && configMap.DefaultMode == nil {
+			configMap.DefaultMode = &defaultMode
+		}
+	}
+	for i := range spec.InitContainers {
+		normalizeContainer(&spec.InitContainers[i])
+	}
+	for i := range spec.Containers {
+		normalizeContainer(&spec.Containers[i])
+	}
+
+	if spec.SecurityContext != nil && reflect.DeepEqual(*spec.SecurityContext, corev1.PodSecurityContext{}) {
+		spec.SecurityContext = nil
+	}
+	if spec.Affinity != nil && reflect.DeepEqual(*spec.Affinity, corev1.Affinity{}) {
+		spec.Affinity = nil
+	}
+	if len(spec.NodeSelector) == 0 {
+		spec.NodeSelector = nil
+	}
+	if spec.DNSPolicy == corev1.DNSClusterFirst {
+		spec.DNSPolicy = ""
+	}
+	// The tolerations may be shared with the node pool, so they are copied before sorting
+	if len(spec.Tolerations) == 0 {
+		spec.Tolerations = nil
+	} else {
+		spec.Tolerations = append([]corev1.Toleration(nil), spec.Tolerations...)
+	}
+	sort.SliceStable(spec.Tolerations, func(i, j int) bool {
+		return tolerationSortKey(spec.Tolerations[i]) < tolerationSortKey(spec.Tolerations[j])
+	})
+	sort.SliceStable(spec.ImagePullSecrets, func(i, j int) bool {
+		return spec.ImagePullSecrets[i].Name < spec.ImagePullSecrets[j].Name
+	})
+}
+
+func tolerationSortKey(toleration corev1.Toleration) string {
+	var seconds string
+	if toleration.TolerationSeconds != nil {
+		seconds = strconv.FormatInt(*toleration.TolerationSeconds, 10)
+	}
+	return strings.Join([]string{toleration.Key, string(toleration.Operator), toleration.Value, string(toleration.Effect), seconds}, "\x00")
+}
+
+func normalizeContainer(container *corev1.Container) {
+	for i := range container.Ports {
+		if container.Ports[i].Protocol == "" {
+			container.Ports[i].Protocol = corev1.ProtocolTCP
+		}
+	}
+	for i := range container.Env {
+		if valueFrom := container.Env[i].ValueFrom; valueFrom != nil && valueFrom.FieldRef != nil && valueFrom.FieldRef.APIVersion == "" {
+			valueFrom.FieldRef.APIVersion = "v1"
+		}
+	}
+	for _, probe := range []*corev1.Probe{container.LivenessProbe, container.ReadinessProbe, container.StartupProbe} {
+		normalizeProbe(probe)
+	}
+
+	// Requests default to the limits when only limits are set
+	for name, limit := range container.Resources.Limits {
+		if _, ok := container.Resources.Requests[name]; !ok {
+			if container.Resources.Requests == nil {
+				container.Resources.Requests = corev1.ResourceList{}
+			}
+			container.Resources.Requests[name] = limit
+		}
+	}
+	if len(container.Resources.Requests) == 0 {
+		container.Resources.Requests = nil
+	}
+	if len(container.Resources.Limits) == 0 {
+		container.Resources.Limits = nil
+	}
+	if container.SecurityContext != nil && reflect.DeepEqual(*container.SecurityContext, corev1.SecurityContext{}) {
+		container.SecurityContext = nil
+	}
+}
+
+func normalizeProbe(probe *corev1.Probe) {
+	if probe == nil {
+		return
+	}
+	if probe.TimeoutSeconds == 0 {
+		probe.TimeoutSeconds = 1
+	}
+	if probe.PeriodSeconds == 0 {
+		probe.PeriodSeconds = 10
+	}
+	if probe.SuccessThreshold == 0 {
+		probe.SuccessThreshold = 1
+	}
+	if probe.FailureThreshold == 0 {
+		probe.FailureThreshold = 3
+	}
+	if probe.HTTPGet != nil && probe.HTTPGet.Scheme == "" {
+		probe.HTTPGet.Scheme = corev1.URISchemeHTTP
+	}
+}
+
+// podSpecAsSHA256 looks at the pod spec minus known nondeterministic fields and returns a sha256 hash of the
+// normalized spec
 func podSpecAsSHA256(hnp *HumioNodePool, sourcePod corev1.Pod) string {
+	pod := sourcePod.DeepCopy()
+	sanitizedPod := sanitizePod(hnp, pod)
+	normalizePodSpec(&sanitizedPod.Spec)
+	b, _ := json.Marshal(sanitizedPod.Spec)
+	return helpers.AsSHA256(string(b))
+}
+
+// legacyPodSpecAsSHA256 returns the hash of the pod spec as computed before the pod spec was normalized. Pods created
+// by earlier versions of the operator carry this hash, and are not restarted as long as their spec is unchanged.
+func legacyPodSpecAsSHA256(hnp *HumioNodePool, sourcePod corev1.Pod) string {
 	pod := sourcePod.DeepCopy()
 	sanitizedPod := sanitizePod(hnp, pod)
 	b, _ := json.Marshal(sanitizedPod.Spec)
@@ -852,6 +1099,30 @@ func (r *HumioClusterReconciler) createPod(ctx context.Context, hc *humiov1alpha
 		pod.Annotations[envVarSourceHashAnnotation] = helpers.AsSHA256(string(b))
 	}
 
+	if attachments.extraConfigFilesData != nil {
+		b, err := json.Marshal(attachments.extraConfigFilesData)
+		if err != nil {
+			return &corev1.Pod{}, fmt.Errorf("error trying to JSON encode extraConfigFilesData: %w", err)
+		}
+		pod.Annotations[extraConfigFilesHashAnnotation] = helpers.AsSHA256(string(b))
+	}
+
+	if attachments.secretsStoreData != nil {
+		b, err := json.Marshal(attachments.secretsStoreData)
+		if err != nil {
+			return &corev1.Pod{}, fmt.Errorf("error trying to JSON encode secretsStoreData: %w", err)
+		}
+		pod.Annotations[secretsStoreHashAnnotation] = helpers.AsSHA256(string(b))
+	}
+
+	if attachments.envVarSecretsData != nil {
+		b, err := json.Marshal(attachments.envVarSecretsData)
+		if err != nil {
+			return &corev1.Pod{}, fmt.Errorf("error trying to JSON encode envVarSecretsData: %w", err)
+		}
+		pod.Annotations[envVarSecretsHashAnnotation] = helpers.AsSHA256(string(b))
+	}
+
 	if hnp.TLSEnabled() {
 		pod.Annotations[certHashAnnotation] = podNameAndCertHash.certificateHash
 	}
@@ -861,7 +1132,7 @@ func (r *HumioClusterReconciler) createPod(ctx context.Context, hc *humiov1alpha
 	r.setPodRevision(pod, podRevision)
 
 	r.Log.Info(fmt.Sprintf("creating pod %s", pod.Name))
-	err = r.Create(ctx, pod)
+	err = r.Create(ctx, pod, client.FieldOwner(kubernetes.FieldManager))
 	if err != nil {
 		return &corev1.Pod{}, err
 	}
@@ -906,6 +1177,12 @@ func (r *HumioClusterReconciler) waitForNewPods(ctx context.Context, hnp *HumioN
 }
 
 func (r *HumioClusterReconciler) podsMatch(hnp *HumioNodePool, pod corev1.Pod, desiredPod corev1.Pod) (bool, error) {
+	return r.podsMatchHashes(hnp, pod, desiredPod, newPodSpecHashes(hnp, desiredPod))
+}
+
+// podsMatchHashes compares the pod with the desired pod, using the given hashes of the desired pod spec. The pod specs
+// are only diffed when the pods do not match, to log the difference.
+func (r *HumioClusterReconciler) podsMatchHashes(hnp *HumioNodePool, pod corev1.Pod, desiredPod corev1.Pod, desiredHashes podSpecHashes) (bool, error) {
 	if _, ok := pod.Annotations[podHashAnnotation]; !ok {
 		return false, fmt.Errorf("did not find annotation with pod hash")
 	}
@@ -916,12 +1193,15 @@ func (r *HumioClusterReconciler) podsMatch(hnp *HumioNodePool, pod corev1.Pod, d
 	var specMatches bool
 	var revisionMatches bool
 	var envVarSourceMatches bool
+	var extraConfigFilesMatches bool
+	var secretsStoreMatches bool
+	var envVarSecretsMatches bool
 	var certHasAnnotationMatches bool
 
-	desiredPodHash := podSpecAsSHA256(hnp, desiredPod)
+	desiredPodHash := desiredHashes.hash
 	_, existingPodRevision := hnp.GetHumioClusterNodePoolRevisionAnnotation()
 	r.setPodRevision(&desiredPod, existingPodRevision)
-	if pod.Annotations[podHashAnnotation] == desiredPodHash {
+	if pod.Annotations[podHashAnnotation] == desiredPodHash || pod.Annotations[podHashAnnotation] == desiredHashes.legacyHash {
 		specMatches = true
 	}
 	if pod.Annotations[PodRevisionAnnotation] == desiredPod.Annotations[PodRevisionAnnotation] {
@@ -937,6 +1217,32 @@ func (r *HumioClusterReconciler) podsMatch(hnp *HumioNodePool, pod corev1.Pod, d
 			envVarSourceMatches = true
 		}
 	}
+	if _, ok := pod.Annotations[extraConfigFilesHashAnnotation]; ok {
+		if pod.Annotations[extraConfigFilesHashAnnotation] == desiredPod.Annotations[extraConfigFilesHashAnnotation] {
+			extraConfigFilesMatches = true
+		}
+	} else {
+		// Ignore extraConfigFiles hash if it's not in either the current pod or the desired pod
+		if _, ok := desiredPod.Annotations[extraConfigFilesHashAnnotation]; !ok {
+			extraConfigFilesMatches = true
+		}
+	}
+	// Only compare the secretsStore hash if it's in both the current pod and the desired pod. The driver syncs the
+	// Secrets while the first pod mounts the volume, and a pod created before then already mounted the current secrets
+	secretsStoreMatches = true
+	if _, ok := pod.Annotations[secretsStoreHashAnnotation]; ok {
+		if _, ok := desiredPod.Annotations[secretsStoreHashAnnotation]; ok {
+			secretsStoreMatches = pod.Annotations[secretsStoreHashAnnotation] == desiredPod.Annotations[secretsStoreHashAnnotation]
+		}
+	}
+	// Only compare the envVarSecrets hash if it's in both the current pod and the desired pod, so pods created before
+	// the hash was introduced are not restarted
+	envVarSecretsMatches = true
+	if _, ok := pod.Annotations[envVarSecretsHashAnnotation]; ok {
+		if _, ok := desiredPod.Annotations[envVarSecretsHashAnnotation]; ok {
+			envVarSecretsMatches = pod.Annotations[envVarSecretsHashAnnotation] == desiredPod.Annotations[envVarSecretsHashAnnotation]
+		}
+	}
 	if _, ok := pod.Annotations[certHashAnnotation]; ok {
 		if pod.Annotations[certHashAnnotation] == desiredPod.Annotations[certHashAnnotation] {
 			certHasAnnotationMatches = true
@@ -948,10 +1254,16 @@ func (r *HumioClusterReconciler) podsMatch(hnp *HumioNodePool, pod corev1.Pod, d
 		}
 	}
 
+	if specMatches && revisionMatches && envVarSourceMatches && extraConfigFilesMatches && secretsStoreMatches && envVarSecretsMatches && certHasAnnotationMatches {
+		return true, nil
+	}
+
 	currentPodCopy := pod.DeepCopy()
 	desiredPodCopy := desiredPod.DeepCopy()
 	sanitizedCurrentPod := sanitizePod(hnp, currentPodCopy)
 	sanitizedDesiredPod := sanitizePod(hnp, desiredPodCopy)
+	normalizePodSpec(&sanitizedCurrentPod.Spec)
+	normalizePodSpec(&sanitizedDesiredPod.Spec)
 	podSpecDiff := cmp.Diff(sanitizedCurrentPod.Spec, sanitizedDesiredPod.Spec)
 	if !specMatches {
 		r.Log.Info(fmt.Sprintf("pod annotation %s does not match desired pod: got %+v, expected %+v", podHashAnnotation, pod.Annotations[podHashAnnotation], desiredPodHash), "podSpecDiff", podSpecDiff)
@@ -965,6 +1277,18 @@ func (r *HumioClusterReconciler) podsMatch(hnp *HumioNodePool, pod corev1.Pod, d
 		r.Log.Info(fmt.Sprintf("pod annotation %s does not match desired pod: got %+v, expected %+v", envVarSourceHashAnnotation, pod.Annotations[envVarSourceHashAnnotation], desiredPod.Annotations[envVarSourceHashAnnotation]), "podSpecDiff", podSpecDiff)
 		return false, nil
 	}
+	if !extraConfigFilesMatches {
+		r.Log.Info(fmt.Sprintf("pod annotation %s does not match desired pod: got %+v, expected %+v", extraConfigFilesHashAnnotation, pod.Annotations[extraConfigFilesHashAnnotation], desiredPod.Annotations[extraConfigFilesHashAnnotation]), "podSpecDiff", podSpecDiff)
+		return false, nil
+	}
+	if !secretsStoreMatches {
+		r.Log.Info(fmt.Sprintf("pod annotation %s does not match desired pod: got %+v, expected %+v", secretsStoreHashAnnotation, pod.Annotations[secretsStoreHashAnnotation], desiredPod.Annotations[secretsStoreHashAnnotation]), "podSpecDiff", podSpecDiff)
+		return false, nil
+	}
+	if !envVarSecretsMatches {
+		r.Log.Info(fmt.Sprintf("pod annotation %s does not match desired pod: got %+v, expected %+v", envVarSecretsHashAnnotation, pod.Annotations[envVarSecretsHashAnnotation], desiredPod.Annotations[envVarSecretsHashAnnotation]), "podSpecDiff", podSpecDiff)
+		return false, nil
+	}
 	if !certHasAnnotationMatches {
 		r.Log.Info(fmt.Sprintf("pod annotation %s does not match desired pod: got %+v, expected %+v", certHashAnnotation, pod.Annotations[certHashAnnotation], desiredPod.Annotations[certHashAnnotation]), "podSpecDiff", podSpecDiff)
 		return false, nil
@@ -973,6 +1297,16 @@ func (r *HumioClusterReconciler) podsMatch(hnp *HumioNodePool, pod corev1.Pod, d
 }
 
 func (r *HumioClusterReconciler) getPodDesiredLifecycleState(hnp *HumioNodePool, foundPodList []corev1.Pod, attachments *podAttachments) (podLifecycleState, error) {
+	// the desired pod is the same for all pods of the node pool, so it is only constructed and hashed once
+	desiredPod, err := ConstructPod(hnp, "", attachments)
+	if err != nil {
+		return podLifecycleState{}, r.logErrorAndReturn(err, "could not construct pod")
+	}
+	if hnp.TLSEnabled() {
+		desiredPod.Annotations[certHashAnnotation] = GetDesiredCertHash(hnp)
+	}
+	desiredHashes := r.podSpecHashes.getDesiredPodSpecHashes(hnp, attachments, *desiredPod)
+
 	for _, pod := range foundPodList {
 		podLifecycleStateValue := NewPodLifecycleState(*hnp, pod)
 
@@ -982,15 +1316,7 @@ func (r *HumioClusterReconciler) getPodDesiredLifecycleState(hnp *HumioNodePool,
 		}
 
 		// if pod spec differs, we want to delete it
-		desiredPod, err := ConstructPod(hnp, "", attachments)
-		if err != nil {
-			return podLifecycleState{}, r.logErrorAndReturn(err, "could not construct pod")
-		}
-		if hnp.TLSEnabled() {
-			desiredPod.Annotations[certHashAnnotation] = GetDesiredCertHash(hnp)
-		}
-
-		podsMatch, err := r.podsMatch(hnp, pod, *desiredPod)
+		podsMatch, err := r.podsMatchHashes(hnp, pod, *desiredPod, desiredHashes)
 		if err != nil {
 			r.Log.Error(err, "failed to check if pods match")
 		}
@@ -1089,7 +1415,7 @@ func findHumioNodeNameAndCertHash(ctx context.Context, c client.Client, hnp *Hum
 	return podNameAndCertificateHash{}, fmt.Errorf("found %d certificates but none of them are available to use", len(certificates))
 }
 
-func (r *HumioClusterReconciler) newPodAttachments(ctx context.Context, hnp *HumioNodePool, foundPodList []corev1.Pod, pvcClaimNamesInUse map[string]struct{}) (*podAttachments, error) {
+func (r *HumioClusterReconciler) newPodAttachments(ctx context.Context, hnp *HumioNodePool, foundPodList []corev1.Pod, pvcClaimNamesInUse map[string]struct{}, dataNodesInUse map[string]struct{}) (*podAttachments, error) {
 	pvcList, err := r.pvcList(ctx, hnp)
 	if err != nil {
 		return &podAttachments{}, fmt.Errorf("problem getting pvc list: %w", err)
@@ -1102,6 +1428,10 @@ func (r *HumioClusterReconciler) newPodAttachments(ctx context.Context, hnp *Hum
 	if volumeSource.PersistentVolumeClaim != nil {
 		pvcClaimNamesInUse[volumeSource.PersistentVolumeClaim.ClaimName] = struct{}{}
 	}
+	var dataNodeName string
+	if hnp.DataVolumeNodePinningEnabled() {
+		dataNodeName = findNextAvailableDataNode(hnp.GetDataNodes(), foundPodList, dataNodesInUse)
+	}
 	authSASecretName, err := r.getAuthServiceAccountSecretName(ctx, hnp)
 	if err != nil {
 		return &podAttachments{}, fmt.Errorf("unable get auth service account secret for HumioCluster: %w", err)
@@ -1110,10 +1440,27 @@ func (r *HumioClusterReconciler) newPodAttachments(ctx context.Context, hnp *Hum
 	if authSASecretName == "" {
 		return &podAttachments{}, errors.New("unable to create Pod for HumioCluster: the auth service account secret does not exist")
 	}
+	extraConfigFilesData, err := r.getExtraConfigFilesData(ctx, hnp)
+	if err != nil {
+		return &podAttachments{}, fmt.Errorf("unable to create Pod for HumioCluster: %w", err)
+	}
+	secretsStoreData, err := r.getSecretsStoreData(ctx, hnp)
+	if err != nil {
+		return &podAttachments{}, fmt.Errorf("unable to create Pod for HumioCluster: %w", err)
+	}
+	envVarSecretsData, err := r.getEnvVarSecretsData(ctx, hnp)
+	if err != nil {
+		return &podAttachments{}, fmt.Errorf("unable to create Pod for HumioCluster: %w", err)
+	}
+
 	if hnp.InitContainerDisabled() {
 		return &podAttachments{
 			dataVolumeSource:             volumeSource,
 			authServiceAccountSecretName: authSASecretName,
+			extraConfigFilesData:         extraConfigFilesData,
+			secretsStoreData:             secretsStoreData,
+			envVarSecretsData:            envVarSecretsData,
+			dataNodeName:                 dataNodeName,
 		}, nil
 	}
 
@@ -1135,6 +1482,10 @@ func (r *HumioClusterReconciler) newPodAttachments(ctx context.Context, hnp *Hum
 		initServiceAccountSecretName: initSASecretName,
 		authServiceAccountSecretName: authSASecretName,
 		envVarSourceData:             envVarSourceData,
+		extraConfigFilesData:         extraConfigFilesData,
+		secretsStoreData:             secretsStoreData,
+		envVarSecretsData:            envVarSecretsData,
+		dataNodeName:                 dataNodeName,
 	}, nil
 }
 
@@ -0,0 +1,348 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newPodHashTestCluster() *humiov1alpha1.HumioCluster {
+	return &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging"},
+		Spec: humiov1alpha1.HumioClusterSpec{
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				Image:     "humio/humio-core:1.82.1",
+				NodeCount: 3,
+				EnvironmentVariables: []corev1.EnvVar{
+					{Name: "KAFKA_SERVERS", Value: "kafka:9092"},
+					{Name: "ZOOKEEPER_URL", Value: "zookeeper:2181"},
+				},
+				Tolerations: []corev1.Toleration{
+					{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "humio", Effect: corev1.TaintEffectNoSchedule},
+					{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+				},
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry-a"}, {Name: "registry-b"}},
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("2"),
+						corev1.ResourceMemory: resource.MustParse("4Gi"),
+					},
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("2"),
+						corev1.ResourceMemory: resource.MustParse("4Gi"),
+					},
+				},
+				ContainerReadinessProbe: &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/api/v1/is-node-up", Port: intstr.FromInt(HumioPort)},
+					},
+					InitialDelaySeconds: 30,
+				},
+				PodAnnotations: map[string]string{"team": "platform"},
+				PodLabels:      map[string]string{"cost-center": "1234"},
+			},
+		},
+	}
+}
+
+func TestPodSpecAsSHA256RestartRelevantFields(t *testing.T) {
+	tt := []struct {
+		name          string
+		mutate        func(hc *humiov1alpha1.HumioCluster)
+		restartNeeded bool
+	}{
+		{
+			name: "environment variables reordered",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				env := hc.Spec.EnvironmentVariables
+				env[0], env[1] = env[1], env[0]
+			},
+		},
+		{
+			name: "tolerations reordered",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				tolerations := hc.Spec.Tolerations
+				tolerations[0], tolerations[1] = tolerations[1], tolerations[0]
+			},
+		},
+		{
+			name: "image pull secrets reordered",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry-b"}, {Name: "registry-a"}}
+			},
+		},
+		{
+			name: "pod annotations changed",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.PodAnnotations = map[string]string{"team": "observability", "owner": "someone"}
+			},
+		},
+		{
+			name: "pod labels changed",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.PodLabels = map[string]string{"cost-center": "5678"}
+			},
+		},
+		{
+			name: "cluster annotations changed",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Annotations = map[string]string{"example.com/note": "changed"}
+			},
+		},
+		{
+			name: "node count changed",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.NodeCount = 5
+			},
+		},
+		{
+			name: "requests omitted as they default to limits",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.Resources.Requests = nil
+			},
+		},
+		{
+			name: "quantity written in other units",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.Resources.Limits[corev1.ResourceMemory] = resource.MustParse("4096Mi")
+				hc.Spec.Resources.Requests[corev1.ResourceCPU] = resource.MustParse("2000m")
+			},
+		},
+		{
+			name: "probe defaults set explicitly",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				probe := hc.Spec.ContainerReadinessProbe
+				probe.HTTPGet.Scheme = corev1.URISchemeHTTP
+				probe.TimeoutSeconds = 1
+				probe.PeriodSeconds = 10
+				probe.SuccessThreshold = 1
+				probe.FailureThreshold = 3
+			},
+		},
+		{
+			name: "default dns policy set explicitly",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.DNSPolicy = corev1.DNSClusterFirst
+			},
+		},
+		{
+			name: "environment variable changed",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.EnvironmentVariables[0].Value = "other-kafka:9092"
+			},
+			restartNeeded: true,
+		},
+		{
+			name: "image changed",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.Image = "humio/humio-core:1.82.2"
+			},
+			restartNeeded: true,
+		},
+		{
+			name: "memory limit changed",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.Resources.Limits[corev1.ResourceMemory] = resource.MustParse("8Gi")
+			},
+			restartNeeded: true,
+		},
+		{
+			name: "toleration added",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.Tolerations = append(hc.Spec.Tolerations, corev1.Toleration{Key: "gpu", Operator: corev1.TolerationOpExists})
+			},
+			restartNeeded: true,
+		},
+		{
+			name: "dns config added",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.DNSConfig = &corev1.PodDNSConfig{Searches: []string{"corp.example.com"}}
+			},
+			restartNeeded: true,
+		},
+		{
+			name: "host alias added",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.HostAliases = []corev1.HostAlias{{IP: "10.0.0.10", Hostnames: []string{"kafka.corp.example.com"}}}
+			},
+			restartNeeded: true,
+		},
+		{
+			name: "probe threshold changed",
+			mutate: func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.ContainerReadinessProbe.FailureThreshold = 5
+			},
+			restartNeeded: true,
+		},
+	}
+
+	podHash := func(hc *humiov1alpha1.HumioCluster) string {
+		hnp := NewHumioNodeManagerFromHumioCluster(hc)
+		pod, err := ConstructPod(hnp, "", &podAttachments{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return podSpecAsSHA256(hnp, *pod)
+	}
+	baseHash := podHash(newPodHashTestCluster())
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := newPodHashTestCluster()
+			tc.mutate(hc)
+			if restartNeeded := podHash(hc) != baseHash; restartNeeded != tc.restartNeeded {
+				t.Errorf("expected restart needed %t, got %t", tc.restartNeeded, restartNeeded)
+			}
+		})
+	}
+}
+
+func TestPodSpecAsSHA256DoesNotModifyNodePool(t *testing.T) {
+	hc := newPodHashTestCluster()
+	tolerations := hc.Spec.Tolerations
+	tolerations[0], tolerations[1] = tolerations[1], tolerations[0]
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+	pod, _ := ConstructPod(hnp, "", &podAttachments{})
+
+	podSpecAsSHA256(hnp, *pod)
+	if hc.Spec.Tolerations[0].Key != "spot" {
+		t.Errorf("expected tolerations of the node pool to keep their order, got %v", hc.Spec.Tolerations)
+	}
+}
+
+func TestPodsMatchAcceptsLegacyPodHash(t *testing.T) {
+	r := &HumioClusterReconciler{Log: logr.Discard()}
+	hnp := NewHumioNodeManagerFromHumioCluster(newPodHashTestCluster())
+	desiredPod, err := ConstructPod(hnp, "", &podAttachments{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, revision := hnp.GetHumioClusterNodePoolRevisionAnnotation()
+
+	for _, tc := range []struct {
+		name    string
+		hash    string
+		matches bool
+	}{
+		{name: "current hash", hash: podSpecAsSHA256(hnp, *desiredPod), matches: true},
+		{name: "hash from earlier operator versions", hash: legacyPodSpecAsSHA256(hnp, *desiredPod), matches: true},
+		{name: "hash of other spec", hash: "other", matches: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := desiredPod.DeepCopy()
+			pod.Annotations[podHashAnnotation] = tc.hash
+			r.setPodRevision(pod, revision)
+			matches, err := r.podsMatch(hnp, *pod, *desiredPod)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if matches != tc.matches {
+				t.Errorf("expected pods to match %t, got %t", tc.matches, matches)
+			}
+		})
+	}
+}
+
+func TestNodeTags(t *testing.T) {
+	nodeTags := []humiov1alpha1.HumioNodeTag{
+		{Tag: "zone", NodeLabel: "topology.kubernetes.io/zone"},
+		{Tag: "instance-type", NodeLabel: "node.kubernetes.io/instance-type"},
+	}
+
+	for _, tc := range []struct {
+		name                 string
+		nodeTags             []humiov1alpha1.HumioNodeTag
+		disableInitContainer bool
+		expectedLabels       string
+		expectedErr          bool
+	}{
+		{name: "no node tags"},
+		{name: "node tags", nodeTags: nodeTags, expectedLabels: "zone=topology.kubernetes.io/zone,instance-type=node.kubernetes.io/instance-type"},
+		{name: "node tags without init container", nodeTags: nodeTags, disableInitContainer: true, expectedErr: true},
+		{name: "duplicate node tag", nodeTags: append(nodeTags, humiov1alpha1.HumioNodeTag{Tag: "zone", NodeLabel: "rack"}), expectedErr: true},
+		{name: "node tag with separator", nodeTags: []humiov1alpha1.HumioNodeTag{{Tag: "rack", NodeLabel: "a,b"}}, expectedErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := newPodHashTestCluster()
+			hc.Spec.NodeTags = tc.nodeTags
+			hc.Spec.DisableInitContainer = tc.disableInitContainer
+			hnp := NewHumioNodeManagerFromHumioCluster(hc)
+
+			err := validateNodeTags(hnp)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %t, got %v", tc.expectedErr, err)
+			}
+			if tc.expectedErr {
+				return
+			}
+
+			pod, err := ConstructPod(hnp, "", &podAttachments{})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			var labels string
+			for _, envVar := range pod.Spec.InitContainers[0].Env {
+				if envVar.Name == "NODE_TAG_LABELS" {
+					labels = envVar.Value
+				}
+			}
+			if labels != tc.expectedLabels {
+				t.Errorf("expected NODE_TAG_LABELS %q, got %q", tc.expectedLabels, labels)
+			}
+		})
+	}
+}
+
+func TestPodDNSSettings(t *testing.T) {
+	hc := newPodHashTestCluster()
+	hc.Spec.DNSPolicy = corev1.DNSNone
+	if _, err := ConstructPod(NewHumioNodeManagerFromHumioCluster(hc), "", &podAttachments{}); err == nil {
+		t.Errorf("expected error when dnsPolicy is None without dnsConfig")
+	}
+
+	hc.Spec.DNSConfig = &corev1.PodDNSConfig{Nameservers: []string{"10.0.0.53"}}
+	hc.Spec.HostAliases = []corev1.HostAlias{{IP: "10.0.0.10", Hostnames: []string{"kafka.corp.example.com"}}}
+	pod, err := ConstructPod(NewHumioNodeManagerFromHumioCluster(hc), "", &podAttachments{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if pod.Spec.DNSPolicy != corev1.DNSNone {
+		t.Errorf("expected dns policy %s, got %s", corev1.DNSNone, pod.Spec.DNSPolicy)
+	}
+	if pod.Spec.DNSConfig == nil || pod.Spec.DNSConfig.Nameservers[0] != "10.0.0.53" {
+		t.Errorf("expected dns config to be set, got %v", pod.Spec.DNSConfig)
+	}
+	if len(pod.Spec.HostAliases) != 1 {
+		t.Errorf("expected host aliases to be set, got %v", pod.Spec.HostAliases)
+	}
+}
+
+func BenchmarkConstructPod(b *testing.B) {
+	hnp := NewHumioNodeManagerFromHumioCluster(newPodHashTestCluster())
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ConstructPod(hnp, "humiocluster-core-abcdef", &podAttachments{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPodSpecAsSHA256(b *testing.B) {
+	hnp := NewHumioNodeManagerFromHumioCluster(newPodHashTestCluster())
+	pod, err := ConstructPod(hnp, "humiocluster-core-abcdef", &podAttachments{})
+	if err != nil {
+		b.Fatalf("unexpected error: %s", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		podSpecAsSHA256(hnp, *pod)
+	}
+}
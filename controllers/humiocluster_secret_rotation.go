@@ -0,0 +1,168 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// humioClusterSecretNames returns the names of the secrets the HumioCluster reads, but does not own, such as secrets
+// managed by the External Secrets Operator
+func humioClusterSecretNames(hc *humiov1alpha1.HumioCluster) []string {
+	var secretNames []string
+	if licenseSecretKeySelector := licenseSecretKeyRefOrDefault(hc); licenseSecretKeySelector != nil {
+		secretNames = append(secretNames, licenseSecretKeySelector.Name)
+	}
+	if useExistingCA(hc) {
+		secretNames = append(secretNames, getCASecretName(hc))
+	}
+	if hc.Spec.IdpCertificateSecretName != "" {
+		secretNames = append(secretNames, hc.Spec.IdpCertificateSecretName)
+	}
+	humioNodePools := []*HumioNodePool{NewHumioNodeManagerFromHumioCluster(hc)}
+	for idx := range hc.Spec.NodePools {
+		humioNodePools = append(humioNodePools, NewHumioNodeManagerFromHumioNodePool(hc, &hc.Spec.NodePools[idx]))
+	}
+	for _, hnp := range humioNodePools {
+		for _, envVar := range hnp.GetEnvironmentVariables() {
+			if envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil {
+				secretNames = append(secretNames, envVar.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+		for _, envVarSource := range hnp.GetEnvironmentVariablesSource() {
+			if envVarSource.SecretRef != nil {
+				secretNames = append(secretNames, envVarSource.SecretRef.Name)
+			}
+		}
+		for _, file := range hnp.GetExtraConfigFiles() {
+			if file.SecretKeyRef != nil {
+				secretNames = append(secretNames, file.SecretKeyRef.Name)
+			}
+		}
+		if hnp.GetSecretsStore() != nil {
+			secretNames = append(secretNames, hnp.GetSecretsStore().SyncedSecrets...)
+		}
+		if verification := hnp.GetImageVerification(); verification != nil && verification.CosignPublicKeySecretRef != nil {
+			secretNames = append(secretNames, verification.CosignPublicKeySecretRef.Name)
+		}
+	}
+	return secretNames
+}
+
+// humioClusterConfigMapNames returns the names of the config maps the HumioCluster reads, but does not own
+func humioClusterConfigMapNames(hc *humiov1alpha1.HumioCluster) []string {
+	var configMapNames []string
+	humioNodePools := []*HumioNodePool{NewHumioNodeManagerFromHumioCluster(hc)}
+	for idx := range hc.Spec.NodePools {
+		humioNodePools = append(humioNodePools, NewHumioNodeManagerFromHumioNodePool(hc, &hc.Spec.NodePools[idx]))
+	}
+	for _, hnp := range humioNodePools {
+		for _, envVar := range hnp.GetEnvironmentVariables() {
+			if envVar.ValueFrom != nil && envVar.ValueFrom.ConfigMapKeyRef != nil {
+				configMapNames = append(configMapNames, envVar.ValueFrom.ConfigMapKeyRef.Name)
+			}
+		}
+		for _, envVarSource := range hnp.GetEnvironmentVariablesSource() {
+			if envVarSource.ConfigMapRef != nil {
+				configMapNames = append(configMapNames, envVarSource.ConfigMapRef.Name)
+			}
+		}
+		for _, file := range hnp.GetExtraConfigFiles() {
+			if file.ConfigMapKeyRef != nil {
+				configMapNames = append(configMapNames, file.ConfigMapKeyRef.Name)
+			}
+		}
+	}
+	return configMapNames
+}
+
+// clustersForSecret returns a reconcile request for every HumioCluster reading the given secret, so rotated
+// credentials are rolled out to the pods without waiting for the next periodic reconcile
+func (r *HumioClusterReconciler) clustersForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	var humioClusters humiov1alpha1.HumioClusterList
+	if err := r.List(ctx, &humioClusters, client.InNamespace(secret.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list clusters")
+		return nil
+	}
+	var requests []reconcile.Request
+	for idx := range humioClusters.Items {
+		hc := &humioClusters.Items[idx]
+		// The admin token secret is watched, so it is repaired right away if it is deleted
+		if helpers.ContainsElement(humioClusterSecretNames(hc), secret.GetName()) || secret.GetName() == adminTokenSecretName(hc) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// clustersForConfigMap returns a reconcile request for every HumioCluster reading the given config map, so changed
+// configuration is rolled out to the pods without waiting for the next periodic reconcile
+func (r *HumioClusterReconciler) clustersForConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	var humioClusters humiov1alpha1.HumioClusterList
+	if err := r.List(ctx, &humioClusters, client.InNamespace(configMap.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list clusters")
+		return nil
+	}
+	var requests []reconcile.Request
+	for idx := range humioClusters.Items {
+		hc := &humioClusters.Items[idx]
+		if helpers.ContainsElement(humioClusterConfigMapNames(hc), configMap.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// getEnvVarSecretsData returns the values of the secret keys referenced by the environment variables of the node pool
+// by secret name and key, which is used to restart the pods when the secrets are rotated. Missing secrets and keys are
+// left out, as the kubelet reports them when starting the container.
+func (r *HumioClusterReconciler) getEnvVarSecretsData(ctx context.Context, hnp *HumioNodePool) (*map[string]string, error) {
+	data := map[string]string{}
+	for _, envVar := range hnp.GetEnvironmentVariables() {
+		if envVar.ValueFrom == nil || envVar.ValueFrom.SecretKeyRef == nil {
+			continue
+		}
+		secretKeyRef := envVar.ValueFrom.SecretKeyRef
+		secret, err := kubernetes.GetSecret(ctx, r, secretKeyRef.Name, hnp.GetNamespace())
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("unable to get secret with name %s in namespace %s", secretKeyRef.Name, hnp.GetNamespace())
+		}
+		if value, ok := secret.Data[secretKeyRef.Key]; ok {
+			data[fmt.Sprintf("%s/%s", secretKeyRef.Name, secretKeyRef.Key)] = string(value)
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return &data, nil
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHumioClusterSecretNames(t *testing.T) {
+	hc := newPodHashTestCluster()
+	hc.Spec.License.SecretKeyRef = &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "license"}, Key: "data"}
+	hc.Spec.NodePools = []humiov1alpha1.HumioNodePoolSpec{
+		{
+			Name: "ingest",
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				EnvironmentVariables: []corev1.EnvVar{
+					{Name: "SMTP_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "smtp"}, Key: "password"}}},
+				},
+				EnvironmentVariablesSource: []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "bucket"}}},
+				},
+			},
+		},
+	}
+
+	secretNames := humioClusterSecretNames(hc)
+	for _, secretName := range []string{"license", "smtp", "bucket"} {
+		if !helpers.ContainsElement(secretNames, secretName) {
+			t.Errorf("expected %s to be in the secret names of the cluster, got %v", secretName, secretNames)
+		}
+	}
+	if helpers.ContainsElement(secretNames, "registry-a") {
+		t.Errorf("expected image pull secrets not to be in the secret names of the cluster, got %v", secretNames)
+	}
+}
+
+func TestEnvVarSecretsRestartPods(t *testing.T) {
+	hc := newPodHashTestCluster()
+	hc.Spec.EnvironmentVariables = append(hc.Spec.EnvironmentVariables, corev1.EnvVar{
+		Name:      "SMTP_PASSWORD",
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "smtp"}, Key: "password"}},
+	})
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "smtp", Namespace: hc.Namespace},
+		Data:       map[string][]byte{"password": []byte("first")},
+	}
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	r := &HumioClusterReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(),
+		Log:    logr.Discard(),
+	}
+
+	constructPod := func() *corev1.Pod {
+		data, err := r.getEnvVarSecretsData(context.Background(), hnp)
+		if err != nil {
+			t.Fatalf("getEnvVarSecretsData() error = %v", err)
+		}
+		pod, err := ConstructPod(hnp, "", &podAttachments{envVarSecretsData: data})
+		if err != nil {
+			t.Fatalf("ConstructPod() error = %v", err)
+		}
+		pod.Annotations[podHashAnnotation] = podSpecAsSHA256(hnp, *pod)
+		pod.Annotations[PodRevisionAnnotation] = "0"
+		return pod
+	}
+	pod := constructPod()
+	if pod.Annotations[envVarSecretsHashAnnotation] == "" {
+		t.Errorf("expected annotation %s to be set", envVarSecretsHashAnnotation)
+	}
+
+	legacyPod := pod.DeepCopy()
+	delete(legacyPod.Annotations, envVarSecretsHashAnnotation)
+	if match, err := r.podsMatch(hnp, *legacyPod, *constructPod()); err != nil || !match {
+		t.Errorf("expected pods without the annotation to match, got %v, %v", match, err)
+	}
+	if match, err := r.podsMatch(hnp, *pod, *constructPod()); err != nil || !match {
+		t.Errorf("expected pods to match before the secret is rotated, got %v, %v", match, err)
+	}
+	secret.Data["password"] = []byte("rotated")
+	if err := r.Update(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if match, err := r.podsMatch(hnp, *pod, *constructPod()); err != nil || match {
+		t.Errorf("expected pods not to match after the secret is rotated, got %v, %v", match, err)
+	}
+}
+
+func TestClustersForConfigMap(t *testing.T) {
+	hc := newPodHashTestCluster()
+	hc.Spec.EnvironmentVariablesSource = []corev1.EnvFromSource{
+		{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "humio-env"}}},
+	}
+	hc.Spec.NodePools = []humiov1alpha1.HumioNodePoolSpec{
+		{
+			Name: "ingest",
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				ExtraConfigFiles: []humiov1alpha1.HumioExtraConfigFile{
+					{FileName: "tuning.conf", ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "ingest-tuning"}, Key: "tuning.conf"}},
+				},
+			},
+		},
+	}
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	r := &HumioClusterReconciler{
+		Client:     fake.NewClientBuilder().WithScheme(scheme).WithObjects(hc).Build(),
+		BaseLogger: logr.Discard(),
+	}
+
+	for _, configMapName := range []string{"humio-env", "ingest-tuning"} {
+		requests := r.clustersForConfigMap(context.Background(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: hc.Namespace}})
+		if len(requests) != 1 || requests[0].Name != hc.Name {
+			t.Errorf("expected config map %s to enqueue the cluster, got %v", configMapName, requests)
+		}
+	}
+	if requests := r.clustersForConfigMap(context.Background(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: hc.Namespace}}); len(requests) != 0 {
+		t.Errorf("expected an unrelated config map not to enqueue the cluster, got %v", requests)
+	}
+}
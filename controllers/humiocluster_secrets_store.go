@@ -0,0 +1,100 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	secretsStoreVolumeName       = "secrets-store"
+	secretsStoreCSIDriver        = "secrets-store.csi.k8s.io"
+	secretsStoreDefaultMountPath = "/mnt/secrets-store"
+)
+
+// secretsStoreVolume returns the CSI volume of the Secrets Store CSI driver for the node pool, and the volume mount
+// for the Humio container
+func secretsStoreVolume(hnp *HumioNodePool) (corev1.Volume, corev1.VolumeMount) {
+	volume := corev1.Volume{
+		Name: secretsStoreVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			CSI: &corev1.CSIVolumeSource{
+				Driver:   secretsStoreCSIDriver,
+				ReadOnly: helpers.BoolPtr(true),
+				VolumeAttributes: map[string]string{
+					"secretProviderClass": hnp.GetSecretsStore().SecretProviderClass,
+				},
+			},
+		},
+	}
+	volumeMount := corev1.VolumeMount{
+		Name:      secretsStoreVolumeName,
+		ReadOnly:  true,
+		MountPath: hnp.GetSecretsStoreMountPath(),
+	}
+	return volume, volumeMount
+}
+
+// getSecretsStoreData returns the content of the Secrets synced by the Secrets Store CSI driver by secret name and key,
+// which is used to restart the pods when the driver rotates them. It returns nil while any of the Secrets is missing,
+// as the driver only creates them once a pod mounts the volume.
+func (r *HumioClusterReconciler) getSecretsStoreData(ctx context.Context, hnp *HumioNodePool) (*map[string]string, error) {
+	if hnp.GetSecretsStore() == nil || len(hnp.GetSecretsStore().SyncedSecrets) == 0 {
+		return nil, nil
+	}
+	data := map[string]string{}
+	for _, secretName := range hnp.GetSecretsStore().SyncedSecrets {
+		secret, err := kubernetes.GetSecret(ctx, r, secretName, hnp.GetNamespace())
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unable to get secret with name %s in namespace %s", secretName, hnp.GetNamespace())
+		}
+		for k, v := range secret.Data {
+			data[fmt.Sprintf("%s/%s", secretName, k)] = string(v)
+		}
+	}
+	return &data, nil
+}
+
+// isSyncedBySecretsStore returns whether the secret is synced by the Secrets Store CSI driver for the cluster or any of
+// its node pools
+func isSyncedBySecretsStore(hc *humiov1alpha1.HumioCluster, secretName string) bool {
+	secretsStores := []*humiov1alpha1.HumioSecretsStore{hc.Spec.SecretsStore}
+	for _, nodePool := range hc.Spec.NodePools {
+		secretsStores = append(secretsStores, nodePool.SecretsStore)
+	}
+	for _, secretsStore := range secretsStores {
+		if secretsStore == nil {
+			continue
+		}
+		for _, syncedSecret := range secretsStore.SyncedSecrets {
+			if syncedSecret == secretName {
+				return true
+			}
+		}
+	}
+	return false
+}
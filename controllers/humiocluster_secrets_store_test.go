@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSecretsStoreRestartPods(t *testing.T) {
+	hc := newPodHashTestCluster()
+	hc.Spec.SecretsStore = &humiov1alpha1.HumioSecretsStore{
+		SecretProviderClass: "humio-credentials",
+		SyncedSecrets:       []string{"humio-license"},
+	}
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	r := &HumioClusterReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Log:    logr.Discard(),
+	}
+
+	constructPod := func() *corev1.Pod {
+		data, err := r.getSecretsStoreData(context.Background(), hnp)
+		if err != nil {
+			t.Fatalf("getSecretsStoreData() error = %v", err)
+		}
+		pod, err := ConstructPod(hnp, "", &podAttachments{secretsStoreData: data})
+		if err != nil {
+			t.Fatalf("ConstructPod() error = %v", err)
+		}
+		pod.Annotations[podHashAnnotation] = podSpecAsSHA256(hnp, *pod)
+		pod.Annotations[PodRevisionAnnotation] = "0"
+		return pod
+	}
+	pod := constructPod()
+
+	var volume *corev1.Volume
+	for idx := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[idx].Name == secretsStoreVolumeName {
+			volume = &pod.Spec.Volumes[idx]
+		}
+	}
+	if volume == nil || volume.CSI == nil || volume.CSI.Driver != secretsStoreCSIDriver || volume.CSI.VolumeAttributes["secretProviderClass"] != "humio-credentials" {
+		t.Errorf("expected a CSI volume for SecretProviderClass humio-credentials, got %+v", volume)
+	}
+	humioIdx, _ := kubernetes.GetContainerIndexByName(*pod, HumioContainerName)
+	var mounted bool
+	for _, volumeMount := range pod.Spec.Containers[humioIdx].VolumeMounts {
+		if volumeMount.Name == secretsStoreVolumeName && volumeMount.MountPath == secretsStoreDefaultMountPath {
+			mounted = true
+		}
+	}
+	if !mounted {
+		t.Errorf("expected the secrets store to be mounted to %s, got %v", secretsStoreDefaultMountPath, pod.Spec.Containers[humioIdx].VolumeMounts)
+	}
+	if _, ok := pod.Annotations[secretsStoreHashAnnotation]; ok {
+		t.Errorf("expected annotation %s not to be set before the secrets are synced", secretsStoreHashAnnotation)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "humio-license", Namespace: hc.Namespace},
+		Data:       map[string][]byte{"license": []byte("first")},
+	}
+	if err := r.Create(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if match, err := r.podsMatch(hnp, *pod, *constructPod()); err != nil || !match {
+		t.Errorf("expected pods to match once the secrets are synced, got %v, %v", match, err)
+	}
+	pod = constructPod()
+	if pod.Annotations[secretsStoreHashAnnotation] == "" {
+		t.Errorf("expected annotation %s to be set", secretsStoreHashAnnotation)
+	}
+
+	secret.Data["license"] = []byte("rotated")
+	if err := r.Update(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if match, err := r.podsMatch(hnp, *pod, *constructPod()); err != nil || match {
+		t.Errorf("expected pods not to match after the secrets are rotated, got %v, %v", match, err)
+	}
+}
+
+func TestIsSyncedBySecretsStore(t *testing.T) {
+	hc := &humiov1alpha1.HumioCluster{
+		Spec: humiov1alpha1.HumioClusterSpec{
+			NodePools: []humiov1alpha1.HumioNodePoolSpec{
+				{
+					Name: "ingest",
+					HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+						SecretsStore: &humiov1alpha1.HumioSecretsStore{SyncedSecrets: []string{"humio-license"}},
+					},
+				},
+			},
+		},
+	}
+	if !isSyncedBySecretsStore(hc, "humio-license") {
+		t.Errorf("expected humio-license to be synced by the secrets store of node pool ingest")
+	}
+	if isSyncedBySecretsStore(hc, "smtp") {
+		t.Errorf("expected smtp not to be synced by the secrets store")
+	}
+}
@@ -21,7 +21,10 @@ import (
 	"fmt"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -52,6 +55,16 @@ type stateOption struct {
 	nodePoolName string
 }
 
+type nodePoolPendingConfigChangesOption struct {
+	nodePoolName string
+	pending      bool
+}
+
+type nodePoolDataNodesOption struct {
+	nodePoolName string
+	dataNodes    []string
+}
+
 type versionOption struct {
 	version string
 }
@@ -64,6 +77,47 @@ type licenseOption struct {
 	license humiov1alpha1.HumioLicenseStatus
 }
 
+type usageOption struct {
+	usage *humiov1alpha1.HumioClusterUsage
+}
+
+type deadNodesOption struct {
+	deadNodes []humiov1alpha1.HumioDeadNode
+}
+
+type healthOption struct {
+	health *humiov1alpha1.HumioClusterHealth
+}
+
+type adminTokenRotationOption struct {
+	rotationTime    metav1.Time
+	rotationRequest string
+}
+
+type pendingAdminTokenRevocationOption struct {
+	revocation *humiov1alpha1.HumioPendingAdminTokenRevocation
+}
+
+type adminTokenRegenerationOption struct {
+	regenerationRequest string
+}
+
+type adminTokenHashOption struct {
+	hash string
+}
+
+type authMigrationOption struct {
+	authMigration *humiov1alpha1.HumioAuthMigrationStatus
+}
+
+type environmentVariableWarningsOption struct {
+	warnings []string
+}
+
+type conditionOption struct {
+	condition metav1.Condition
+}
+
 type nodeCountOption struct {
 	nodeCount int
 }
@@ -104,6 +158,22 @@ func (o *optionBuilder) withNodePoolState(state string, nodePoolName string) *op
 	return o
 }
 
+func (o *optionBuilder) withNodePoolPendingConfigChanges(pending bool, nodePoolName string) *optionBuilder {
+	o.options = append(o.options, nodePoolPendingConfigChangesOption{
+		nodePoolName: nodePoolName,
+		pending:      pending,
+	})
+	return o
+}
+
+func (o *optionBuilder) withNodePoolDataNodes(dataNodes []string, nodePoolName string) *optionBuilder {
+	o.options = append(o.options, nodePoolDataNodesOption{
+		nodePoolName: nodePoolName,
+		dataNodes:    dataNodes,
+	})
+	return o
+}
+
 func (o *optionBuilder) withVersion(version string) *optionBuilder {
 	o.options = append(o.options, versionOption{
 		version: version,
@@ -125,6 +195,77 @@ func (o *optionBuilder) withLicense(license humiov1alpha1.HumioLicenseStatus) *o
 	return o
 }
 
+func (o *optionBuilder) withUsage(usage *humiov1alpha1.HumioClusterUsage) *optionBuilder {
+	o.options = append(o.options, usageOption{
+		usage: usage,
+	})
+	return o
+}
+
+func (o *optionBuilder) withDeadNodes(deadNodes []humiov1alpha1.HumioDeadNode) *optionBuilder {
+	o.options = append(o.options, deadNodesOption{
+		deadNodes: deadNodes,
+	})
+	return o
+}
+
+func (o *optionBuilder) withHealth(health *humiov1alpha1.HumioClusterHealth) *optionBuilder {
+	o.options = append(o.options, healthOption{
+		health: health,
+	})
+	return o
+}
+
+func (o *optionBuilder) withAdminTokenRotation(rotationTime metav1.Time, rotationRequest string) *optionBuilder {
+	o.options = append(o.options, adminTokenRotationOption{
+		rotationTime:    rotationTime,
+		rotationRequest: rotationRequest,
+	})
+	return o
+}
+
+func (o *optionBuilder) withPendingAdminTokenRevocation(revocation *humiov1alpha1.HumioPendingAdminTokenRevocation) *optionBuilder {
+	o.options = append(o.options, pendingAdminTokenRevocationOption{
+		revocation: revocation,
+	})
+	return o
+}
+
+func (o *optionBuilder) withAdminTokenRegeneration(regenerationRequest string) *optionBuilder {
+	o.options = append(o.options, adminTokenRegenerationOption{
+		regenerationRequest: regenerationRequest,
+	})
+	return o
+}
+
+func (o *optionBuilder) withAdminTokenHash(hash string) *optionBuilder {
+	o.options = append(o.options, adminTokenHashOption{
+		hash: hash,
+	})
+	return o
+}
+
+func (o *optionBuilder) withAuthMigration(authMigration *humiov1alpha1.HumioAuthMigrationStatus) *optionBuilder {
+	o.options = append(o.options, authMigrationOption{
+		authMigration: authMigration,
+	})
+	return o
+}
+
+func (o *optionBuilder) withEnvironmentVariableWarnings(warnings []string) *optionBuilder {
+	o.options = append(o.options, environmentVariableWarningsOption{
+		warnings: warnings,
+	})
+	return o
+}
+
+func (o *optionBuilder) withCondition(condition metav1.Condition) *optionBuilder {
+	o.options = append(o.options, conditionOption{
+		condition: condition,
+	})
+	return o
+}
+
 func (o *optionBuilder) withNodeCount(nodeCount int) *optionBuilder {
 	o.options = append(o.options, nodeCountOption{
 		nodeCount: nodeCount,
@@ -177,7 +318,48 @@ func (s stateOption) GetResult() (reconcile.Result, error) {
 	if s.state == humiov1alpha1.HumioClusterStateConfigError {
 		return reconcile.Result{RequeueAfter: time.Second * 10}, nil
 	}
-	return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	return reconcile.Result{RequeueAfter: runningRequeue}, nil
+}
+
+func (p nodePoolPendingConfigChangesOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	for idx, nodePoolStatus := range hc.Status.NodePoolStatus {
+		if nodePoolStatus.Name == p.nodePoolName {
+			hc.Status.NodePoolStatus[idx].PendingConfigChanges = p.pending
+			return
+		}
+	}
+	if p.pending {
+		// The node pool is not being restarted while its configuration changes are pending
+		hc.Status.NodePoolStatus = append(hc.Status.NodePoolStatus, humiov1alpha1.HumioNodePoolStatus{
+			Name:                 p.nodePoolName,
+			State:                humiov1alpha1.HumioClusterStateRunning,
+			PendingConfigChanges: true,
+		})
+	}
+}
+
+func (nodePoolPendingConfigChangesOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (d nodePoolDataNodesOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	for idx, nodePoolStatus := range hc.Status.NodePoolStatus {
+		if nodePoolStatus.Name == d.nodePoolName {
+			hc.Status.NodePoolStatus[idx].DataNodes = d.dataNodes
+			return
+		}
+	}
+	if len(d.dataNodes) > 0 {
+		hc.Status.NodePoolStatus = append(hc.Status.NodePoolStatus, humiov1alpha1.HumioNodePoolStatus{
+			Name:      d.nodePoolName,
+			State:     humiov1alpha1.HumioClusterStateRunning,
+			DataNodes: d.dataNodes,
+		})
+	}
+}
+
+func (nodePoolDataNodesOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
 }
 
 func (v versionOption) Apply(hc *humiov1alpha1.HumioCluster) {
@@ -204,6 +386,89 @@ func (licenseOption) GetResult() (reconcile.Result, error) {
 	return reconcile.Result{}, nil
 }
 
+func (u usageOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	hc.Status.Usage = u.usage
+}
+
+func (usageOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (d deadNodesOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	hc.Status.DeadNodes = d.deadNodes
+}
+
+func (deadNodesOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (h healthOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	hc.Status.Health = h.health
+}
+
+func (healthOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (a adminTokenRotationOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	hc.Status.LastAdminTokenRotationTime = &a.rotationTime
+	hc.Status.LastAdminTokenRotationRequest = a.rotationRequest
+}
+
+func (adminTokenRotationOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (p pendingAdminTokenRevocationOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	hc.Status.PendingAdminTokenRevocation = p.revocation
+}
+
+func (pendingAdminTokenRevocationOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (a adminTokenRegenerationOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	hc.Status.LastAdminTokenRegenerationRequest = a.regenerationRequest
+}
+
+func (adminTokenRegenerationOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (a adminTokenHashOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	hc.Status.AdminTokenHash = a.hash
+}
+
+func (adminTokenHashOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (a authMigrationOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	hc.Status.AuthMigration = a.authMigration
+}
+
+func (authMigrationOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (e environmentVariableWarningsOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	hc.Status.EnvironmentVariableWarnings = e.warnings
+}
+
+func (environmentVariableWarningsOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (c conditionOption) Apply(hc *humiov1alpha1.HumioCluster) {
+	condition := c.condition
+	condition.ObservedGeneration = hc.GetGeneration()
+	meta.SetStatusCondition(&hc.Status.Conditions, condition)
+}
+
+func (conditionOption) GetResult() (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
 func (n nodeCountOption) Apply(hc *humiov1alpha1.HumioCluster) {
 	hc.Status.NodeCount = n.nodeCount
 }
@@ -227,9 +492,15 @@ func (r *HumioClusterReconciler) updateStatus(ctx context.Context, statusWriter
 		if err != nil {
 			return err
 		}
+		status := hc.Status.DeepCopy()
 		for _, opt := range opts {
 			opt.Apply(hc)
 		}
+		// Skip the write if the options did not change the status, as every write triggers another reconcile and
+		// may conflict with writes from other reconciles
+		if equality.Semantic.DeepEqual(*status, hc.Status) {
+			return nil
+		}
 		return statusWriter.Update(ctx, hc)
 	}); err != nil {
 		return reconcile.Result{}, err
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUpdateStatusSkipsUnchangedStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "humiocluster", Namespace: "logging", Generation: 2},
+		Status:     humiov1alpha1.HumioClusterStatus{State: humiov1alpha1.HumioClusterStateRunning, NodeCount: 3},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hc).WithStatusSubresource(hc).Build()
+	r := &HumioClusterReconciler{Client: c, Log: logr.Discard()}
+	ctx := context.Background()
+
+	if _, err := r.updateStatus(ctx, c.Status(), hc, statusOptions().withObservedGeneration(2)); err != nil {
+		t.Fatal(err)
+	}
+	written := hc.ResourceVersion
+
+	if _, err := r.updateStatus(ctx, c.Status(), hc, statusOptions().
+		withObservedGeneration(2).
+		withNodeCount(3).
+		withState(humiov1alpha1.HumioClusterStateRunning)); err != nil {
+		t.Fatal(err)
+	}
+	if hc.ResourceVersion != written {
+		t.Errorf("expected an unchanged status not to be written, resource version changed from %s to %s", written, hc.ResourceVersion)
+	}
+
+	if _, err := r.updateStatus(ctx, c.Status(), hc, statusOptions().withNodeCount(4)); err != nil {
+		t.Fatal(err)
+	}
+	if hc.ResourceVersion == written || hc.Status.NodeCount != 4 {
+		t.Errorf("expected a changed status to be written, got resource version %s and node count %d", hc.ResourceVersion, hc.Status.NodeCount)
+	}
+}
@@ -278,13 +278,12 @@ func (r *HumioClusterReconciler) updateNodeCertificates(ctx context.Context, hc
 					currentCertificateSuffix := currentCertificateNameSubstrings[len(currentCertificateNameSubstrings)-1]
 
 					desiredCertificate := ConstructNodeCertificate(hnp, currentCertificateSuffix)
-					desiredCertificate.ResourceVersion = currentCertificate.ResourceVersion
 					desiredCertificate.Annotations[certHashAnnotation] = desiredCertificateHash
 					r.Log.Info(fmt.Sprintf("updating node TLS certificate with name %s", desiredCertificate.Name))
 					if err := controllerutil.SetControllerReference(hc, &desiredCertificate, r.Scheme()); err != nil {
 						return r.logErrorAndReturn(err, "could not set controller reference")
 					}
-					return r.Update(ctx, &desiredCertificate)
+					return r.apply(ctx, hc, &desiredCertificate)
 				}
 				return r.Status().Update(ctx, hc)
 			})
@@ -0,0 +1,211 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const humioClusterUsageDefaultIntervalSeconds = 3600
+
+var (
+	humioClusterUsageLabels    = []string{"namespace", "cluster"}
+	humioRepositoryUsageLabels = []string{"namespace", "cluster", "repository"}
+
+	humioRepositoryIngestBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "humiocluster_repository_ingest_bytes_last_24h",
+		Help: "Bytes ingested into the repository during the last 24 hours",
+	}, humioRepositoryUsageLabels)
+	humioRepositoryRetainedBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "humiocluster_repository_retained_bytes",
+		Help: "Compressed bytes stored by the repository",
+	}, humioRepositoryUsageLabels)
+	humioRepositoryUncompressedBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "humiocluster_repository_uncompressed_bytes",
+		Help: "Bytes stored by the repository before compression",
+	}, humioRepositoryUsageLabels)
+	humioClusterLicenseSeats = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "humiocluster_license_seats",
+		Help: "Number of users allowed by the license of the cluster",
+	}, humioClusterUsageLabels)
+	humioClusterLicenseSeatsUsed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "humiocluster_license_seats_used",
+		Help: "Number of users of the cluster",
+	}, humioClusterUsageLabels)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		humioRepositoryIngestBytes,
+		humioRepositoryRetainedBytes,
+		humioRepositoryUncompressedBytes,
+		humioClusterLicenseSeats,
+		humioClusterLicenseSeatsUsed,
+	)
+}
+
+// ensureUsageReported collects the usage of the cluster once the reporting interval has elapsed. Usage reporting is
+// best effort, so errors are reported in the usage status rather than failing the reconcile.
+func (r *HumioClusterReconciler) ensureUsageReported(ctx context.Context, hc *humiov1alpha1.HumioCluster, config *humioapi.Config, req reconcile.Request) {
+	if hc.Spec.UsageReporting == nil {
+		if hc.Status.Usage != nil {
+			deleteHumioClusterUsageMetrics(hc)
+			_, _ = r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withUsage(nil))
+		}
+		return
+	}
+
+	now := metav1.Now()
+	if !humioClusterUsageDue(hc, now.Time) {
+		return
+	}
+
+	r.Log.Info("collecting usage")
+	usage := r.collectUsage(config, req, now)
+	setHumioClusterUsageMetrics(hc, usage)
+	if _, err := r.updateStatus(ctx, r.Client.Status(), hc, statusOptions().withUsage(usage)); err != nil {
+		r.Log.Error(err, "unable to set usage status")
+	}
+}
+
+func (r *HumioClusterReconciler) collectUsage(config *humioapi.Config, req reconcile.Request, now metav1.Time) *humiov1alpha1.HumioClusterUsage {
+	var messages []string
+	repositories, err := r.HumioClient.GetRepositoryUsage(config, req)
+	if err != nil {
+		r.Log.Error(err, "unable to get repository usage")
+		messages = append(messages, fmt.Sprintf("unable to get repository usage: %s", err))
+	}
+	ingest, err := r.HumioClient.GetIngestUsage(config, req)
+	if err != nil {
+		r.Log.Error(err, "unable to get ingest usage")
+		messages = append(messages, fmt.Sprintf("unable to get ingest usage: %s", err))
+	}
+
+	var seats, seatsUsed int
+	license, err := r.HumioClient.GetLicense(config, req)
+	if err != nil {
+		r.Log.Error(err, "unable to get license")
+		messages = append(messages, fmt.Sprintf("unable to get license: %s", err))
+	}
+	if onPremLicense, ok := license.(humioapi.OnPremLicense); ok {
+		seats = onPremLicense.NumberOfSeats
+	}
+	if seats > 0 {
+		seatsUsed, err = r.HumioClient.CountUsers(config, req)
+		if err != nil {
+			r.Log.Error(err, "unable to count users")
+			messages = append(messages, fmt.Sprintf("unable to count users: %s", err))
+		}
+	}
+
+	usage := newHumioClusterUsage(repositories, ingest, seats, seatsUsed, now)
+	for i, message := range messages {
+		if i > 0 {
+			usage.Message += ", "
+		}
+		usage.Message += message
+	}
+	return usage
+}
+
+// newHumioClusterUsage combines the usage of each repository with the ingest usage, which may include repositories
+// which have since been deleted
+func newHumioClusterUsage(repositories []humio.RepositoryUsage, ingest map[string]int64, seats, seatsUsed int, now metav1.Time) *humiov1alpha1.HumioClusterUsage {
+	usage := &humiov1alpha1.HumioClusterUsage{
+		CollectionTime:   &now,
+		LicenseSeats:     seats,
+		LicenseSeatsUsed: seatsUsed,
+	}
+	if seats > 0 {
+		usage.LicenseUtilization = int32(seatsUsed * 100 / seats)
+	}
+
+	byName := map[string]*humiov1alpha1.HumioRepositoryUsage{}
+	for _, repository := range repositories {
+		byName[repository.Name] = &humiov1alpha1.HumioRepositoryUsage{
+			Name:              repository.Name,
+			RetainedBytes:     repository.CompressedByteSize,
+			UncompressedBytes: repository.UncompressedByteSize,
+		}
+	}
+	for name, bytes := range ingest {
+		if _, ok := byName[name]; !ok {
+			byName[name] = &humiov1alpha1.HumioRepositoryUsage{Name: name}
+		}
+		byName[name].IngestBytesLast24h = bytes
+	}
+	for _, repository := range byName {
+		usage.IngestBytesLast24h += repository.IngestBytesLast24h
+		usage.RetainedBytes += repository.RetainedBytes
+		usage.Repositories = append(usage.Repositories, *repository)
+	}
+	sort.Slice(usage.Repositories, func(i, j int) bool {
+		return usage.Repositories[i].Name < usage.Repositories[j].Name
+	})
+	return usage
+}
+
+func humioClusterUsageDue(hc *humiov1alpha1.HumioCluster, now time.Time) bool {
+	if hc.Status.Usage == nil || hc.Status.Usage.CollectionTime == nil {
+		return true
+	}
+	interval := hc.Spec.UsageReporting.IntervalSeconds
+	if interval <= 0 {
+		interval = humioClusterUsageDefaultIntervalSeconds
+	}
+	return !hc.Status.Usage.CollectionTime.Add(time.Duration(interval) * time.Second).After(now)
+}
+
+func setHumioClusterUsageMetrics(hc *humiov1alpha1.HumioCluster, usage *humiov1alpha1.HumioClusterUsage) {
+	// Repositories which have been deleted must not be reported anymore
+	deleteHumioClusterUsageMetrics(hc)
+	for _, repository := range usage.Repositories {
+		labels := prometheus.Labels{"namespace": hc.Namespace, "cluster": hc.Name, "repository": repository.Name}
+		humioRepositoryIngestBytes.With(labels).Set(float64(repository.IngestBytesLast24h))
+		humioRepositoryRetainedBytes.With(labels).Set(float64(repository.RetainedBytes))
+		humioRepositoryUncompressedBytes.With(labels).Set(float64(repository.UncompressedBytes))
+	}
+	if usage.LicenseSeats > 0 {
+		labels := prometheus.Labels{"namespace": hc.Namespace, "cluster": hc.Name}
+		humioClusterLicenseSeats.With(labels).Set(float64(usage.LicenseSeats))
+		humioClusterLicenseSeatsUsed.With(labels).Set(float64(usage.LicenseSeatsUsed))
+	}
+}
+
+func deleteHumioClusterUsageMetrics(hc *humiov1alpha1.HumioCluster) {
+	labels := prometheus.Labels{"namespace": hc.Namespace, "cluster": hc.Name}
+	for _, gauge := range []*prometheus.GaugeVec{
+		humioRepositoryIngestBytes,
+		humioRepositoryRetainedBytes,
+		humioRepositoryUncompressedBytes,
+		humioClusterLicenseSeats,
+		humioClusterLicenseSeatsUsed,
+	} {
+		gauge.DeletePartialMatch(labels)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewHumioClusterUsage(t *testing.T) {
+	now := metav1.NewTime(time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC))
+	usage := newHumioClusterUsage(
+		[]humio.RepositoryUsage{
+			{Name: "web", CompressedByteSize: 100, UncompressedByteSize: 1000},
+			{Name: "audit", CompressedByteSize: 10, UncompressedByteSize: 50},
+		},
+		map[string]int64{"web": 2000, "deleted": 300},
+		20, 5, now,
+	)
+
+	expected := &humiov1alpha1.HumioClusterUsage{
+		CollectionTime:     &now,
+		IngestBytesLast24h: 2300,
+		RetainedBytes:      110,
+		LicenseSeats:       20,
+		LicenseSeatsUsed:   5,
+		LicenseUtilization: 25,
+		Repositories: []humiov1alpha1.HumioRepositoryUsage{
+			{Name: "audit", RetainedBytes: 10, UncompressedBytes: 50},
+			{Name: "deleted", IngestBytesLast24h: 300},
+			{Name: "web", IngestBytesLast24h: 2000, RetainedBytes: 100, UncompressedBytes: 1000},
+		},
+	}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Errorf("expected %+v, got %+v", expected, usage)
+	}
+}
+
+func TestHumioClusterUsageDue(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	collected := func(ago time.Duration) *humiov1alpha1.HumioClusterUsage {
+		collectionTime := metav1.NewTime(now.Add(-ago))
+		return &humiov1alpha1.HumioClusterUsage{CollectionTime: &collectionTime}
+	}
+
+	tt := []struct {
+		name            string
+		intervalSeconds int
+		usage           *humiov1alpha1.HumioClusterUsage
+		due             bool
+	}{
+		{
+			name: "never collected",
+			due:  true,
+		},
+		{
+			name:  "collected within default interval",
+			usage: collected(30 * time.Minute),
+			due:   false,
+		},
+		{
+			name:  "default interval elapsed",
+			usage: collected(time.Hour),
+			due:   true,
+		},
+		{
+			name:            "custom interval elapsed",
+			intervalSeconds: 600,
+			usage:           collected(30 * time.Minute),
+			due:             true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &humiov1alpha1.HumioCluster{
+				Spec:   humiov1alpha1.HumioClusterSpec{UsageReporting: &humiov1alpha1.HumioUsageReporting{IntervalSeconds: tc.intervalSeconds}},
+				Status: humiov1alpha1.HumioClusterStatus{Usage: tc.usage},
+			}
+			if due := humioClusterUsageDue(hc, now); due != tc.due {
+				t.Errorf("expected due %t, got %t", tc.due, due)
+			}
+		})
+	}
+}
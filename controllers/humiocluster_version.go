@@ -20,22 +20,16 @@ type HumioVersion struct {
 
 func HumioVersionFromString(image string) (*HumioVersion, error) {
 	var humioVersion HumioVersion
-	nodeImage := strings.SplitN(image, "@", 2)
-	nodeImage = strings.SplitN(nodeImage[0], ":", 2)
+	tag := imageTag(image)
 
 	// if there is no docker tag, then we can assume latest
-	if len(nodeImage) == 1 {
-		humioVersion.assumeLatest = true
-		return &humioVersion, nil
-	}
-
-	if nodeImage[1] == "latest" || nodeImage[1] == "master" {
+	if tag == "" || tag == "latest" || tag == "master" {
 		humioVersion.assumeLatest = true
 		return &humioVersion, nil
 	}
 
 	// strip commit SHA if it exists
-	nodeImage = strings.SplitN(nodeImage[1], "-", 2)
+	nodeImage := strings.SplitN(tag, "-", 2)
 
 	nodeImageVersion, err := semver.NewVersion(nodeImage[0])
 	if err != nil {
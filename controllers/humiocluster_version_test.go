@@ -38,6 +38,14 @@ func Test_HumioVersionFromString(t *testing.T) {
 				expectedErr:             false,
 			},
 		},
+		{
+			"image from registry with port",
+			fields{
+				userDefinedImageVersion: "registry.example.com:5000/humio/humio-core:1.82.1@sha256:38c78710107dc76f4f809b457328ff1c6764ae4244952a5fa7d76f6e67ea2390",
+				expectedImageVersion:    "1.82.1",
+				expectedErr:             false,
+			},
+		},
 		{
 			"short image version",
 			fields{
@@ -104,6 +112,16 @@ func Test_humioVersion_AtLeast(t *testing.T) {
 				expectedErr:             false,
 			},
 		},
+		{
+			"image from registry with port",
+			fields{
+				userDefinedImageVersion: "registry.example.com:5000/humio/humio-core:1.82.1@sha256:38c78710107dc76f4f809b457328ff1c6764ae4244952a5fa7d76f6e67ea2390",
+				imageVersionOlder:       "1.81.0",
+				imageVersionExact:       "1.82.1",
+				imageVersionNewer:       "1.83.0",
+				expectedErr:             false,
+			},
+		},
 		{
 			"short image version",
 			fields{
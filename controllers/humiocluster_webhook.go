@@ -0,0 +1,197 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+)
+
+// environmentVariablePolicyKey is the key of the environment variable policy ConfigMap holding the denied environment
+// variables
+const environmentVariablePolicyKey = "deniedEnvironmentVariables"
+
+// deniedEnvironmentVariable is an environment variable users may not set on a HumioCluster. Name may end with *, in
+// which case it matches all environment variables starting with the rest of the name.
+type deniedEnvironmentVariable struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (d deniedEnvironmentVariable) matches(name string) bool {
+	if prefix, found := strings.CutSuffix(d.Name, "*"); found {
+		return strings.HasPrefix(name, prefix)
+	}
+	return d.Name == name
+}
+
+// HumioClusterValidator validates HumioCluster resources against the environment variable policy of the operator, and
+// rejects clusters setting environment variables which are denied by the policy, such as flags bypassing
+// authentication. The policy is read from a ConfigMap on every request, so changes to it apply immediately.
+type HumioClusterValidator struct {
+	// Reader is used to read the policy, as it may live outside the namespaces watched by the manager
+	Reader          client.Reader
+	Log             logr.Logger
+	PolicyNamespace string
+	PolicyName      string
+}
+
+//+kubebuilder:webhook:path=/validate-core-humio-com-v1alpha1-humiocluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.humio.com,resources=humioclusters,verbs=create;update,versions=v1alpha1,name=vhumiocluster.core.humio.com,admissionReviewVersions=v1
+
+// SetupWebhookWithManager registers the validating webhook with the Manager.
+func (v *HumioClusterValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&humiov1alpha1.HumioCluster{}).
+		WithValidator(v).
+		Complete()
+}
+
+func (v *HumioClusterValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	hc, ok := obj.(*humiov1alpha1.HumioCluster)
+	if !ok {
+		return nil, fmt.Errorf("expected a HumioCluster but got a %T", obj)
+	}
+	return nil, v.validate(ctx, nil, hc)
+}
+
+func (v *HumioClusterValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldHc, ok := oldObj.(*humiov1alpha1.HumioCluster)
+	if !ok {
+		return nil, fmt.Errorf("expected a HumioCluster but got a %T", oldObj)
+	}
+	hc, ok := newObj.(*humiov1alpha1.HumioCluster)
+	if !ok {
+		return nil, fmt.Errorf("expected a HumioCluster but got a %T", newObj)
+	}
+	// Never reject updates of clusters being deleted, such as removing the finalizer
+	if hc.GetDeletionTimestamp() != nil {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, oldHc, hc)
+}
+
+func (v *HumioClusterValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns an error listing the environment variables of the cluster which are denied by the policy. On
+// updates, environment variables which are unchanged from the previous version of the cluster are allowed, so adding
+// an environment variable to the policy does not block unrelated updates of clusters already setting it.
+func (v *HumioClusterValidator) validate(ctx context.Context, oldHc, hc *humiov1alpha1.HumioCluster) error {
+	denied, err := v.deniedEnvironmentVariables(ctx)
+	if err != nil {
+		v.Log.Error(err, "unable to get environment variable policy")
+		return fmt.Errorf("could not get the environment variable policy of the operator: %w", err)
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+
+	var previous map[string][]corev1.EnvVar
+	if oldHc != nil {
+		previous = humioClusterEnvironmentVariables(oldHc)
+	}
+	envVars := humioClusterEnvironmentVariables(hc)
+	fields := make([]string, 0, len(envVars))
+	for field := range envVars {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var violations []string
+	for _, field := range fields {
+		for _, envVar := range envVars[field] {
+			if environmentVariableUnchanged(previous[field], envVar) {
+				continue
+			}
+			for _, d := range denied {
+				if !d.matches(envVar.Name) {
+					continue
+				}
+				violation := fmt.Sprintf("%s must not set %s", field, envVar.Name)
+				if d.Reason != "" {
+					violation = fmt.Sprintf("%s: %s", violation, d.Reason)
+				}
+				violations = append(violations, violation)
+				break
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("environment variables are denied by the policy of the operator: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// deniedEnvironmentVariables returns the environment variables denied by the policy
+func (v *HumioClusterValidator) deniedEnvironmentVariables(ctx context.Context) ([]deniedEnvironmentVariable, error) {
+	var policy corev1.ConfigMap
+	if err := v.Reader.Get(ctx, types.NamespacedName{Namespace: v.PolicyNamespace, Name: v.PolicyName}, &policy); err != nil {
+		return nil, err
+	}
+	return parseEnvironmentVariablePolicy(policy.Data[environmentVariablePolicyKey])
+}
+
+func parseEnvironmentVariablePolicy(policy string) ([]deniedEnvironmentVariable, error) {
+	var denied []deniedEnvironmentVariable
+	if err := yaml.Unmarshal([]byte(policy), &denied); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", environmentVariablePolicyKey, err)
+	}
+	for _, d := range denied {
+		if d.Name == "" || d.Name == "*" {
+			return nil, fmt.Errorf("%s must only contain names, or prefixes ending with *", environmentVariablePolicyKey)
+		}
+	}
+	return denied, nil
+}
+
+// humioClusterEnvironmentVariables returns the environment variables users set on the cluster, by the field setting
+// them
+func humioClusterEnvironmentVariables(hc *humiov1alpha1.HumioCluster) map[string][]corev1.EnvVar {
+	envVars := map[string][]corev1.EnvVar{
+		"spec.environmentVariables":       hc.Spec.EnvironmentVariables,
+		"spec.commonEnvironmentVariables": hc.Spec.CommonEnvironmentVariables,
+	}
+	for _, nodePool := range hc.Spec.NodePools {
+		envVars[fmt.Sprintf("spec.nodePools[%s].environmentVariables", nodePool.Name)] = nodePool.EnvironmentVariables
+	}
+	if hc.Spec.AuthMigration != nil {
+		envVars["spec.authMigration.environmentVariables"] = hc.Spec.AuthMigration.EnvironmentVariables
+	}
+	return envVars
+}
+
+func environmentVariableUnchanged(previous []corev1.EnvVar, envVar corev1.EnvVar) bool {
+	for _, p := range previous {
+		if reflect.DeepEqual(p, envVar) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHumioClusterValidator(t *testing.T) {
+	policy := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "humio-operator-environment-variable-policy", Namespace: "humio-operator"},
+		Data: map[string]string{
+			environmentVariablePolicyKey: "- name: AUTHENTICATION_METHOD\n  reason: authentication is managed by the platform team\n- name: DANGEROUS_*\n",
+		},
+	}
+	cluster := func(mutate func(hc *humiov1alpha1.HumioCluster)) *humiov1alpha1.HumioCluster {
+		hc := &humiov1alpha1.HumioCluster{ObjectMeta: metav1.ObjectMeta{Name: "humio", Namespace: "default"}}
+		hc.Spec.EnvironmentVariables = []corev1.EnvVar{{Name: "HUMIO_MEMORY_OPTS", Value: "-Xss2m"}}
+		if mutate != nil {
+			mutate(hc)
+		}
+		return hc
+	}
+
+	tt := []struct {
+		name          string
+		old           *humiov1alpha1.HumioCluster
+		cluster       *humiov1alpha1.HumioCluster
+		expectedError string
+	}{
+		{
+			name:    "allowed environment variables",
+			cluster: cluster(nil),
+		},
+		{
+			name: "denied environment variable",
+			cluster: cluster(func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.EnvironmentVariables = append(hc.Spec.EnvironmentVariables, corev1.EnvVar{Name: "AUTHENTICATION_METHOD", Value: "none"})
+			}),
+			expectedError: "spec.environmentVariables must not set AUTHENTICATION_METHOD: authentication is managed by the platform team",
+		},
+		{
+			name: "denied prefix in node pool",
+			cluster: cluster(func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.NodePools = []humiov1alpha1.HumioNodePoolSpec{{Name: "ingest"}}
+				hc.Spec.NodePools[0].EnvironmentVariables = []corev1.EnvVar{{Name: "DANGEROUS_FLAG", Value: "true"}}
+			}),
+			expectedError: "spec.nodePools[ingest].environmentVariables must not set DANGEROUS_FLAG",
+		},
+		{
+			name: "denied common environment variable",
+			cluster: cluster(func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.CommonEnvironmentVariables = []corev1.EnvVar{{Name: "DANGEROUS_FLAG", Value: "true"}}
+			}),
+			expectedError: "spec.commonEnvironmentVariables must not set DANGEROUS_FLAG",
+		},
+		{
+			name: "unchanged denied environment variable on update",
+			old: cluster(func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.EnvironmentVariables = append(hc.Spec.EnvironmentVariables, corev1.EnvVar{Name: "DANGEROUS_FLAG", Value: "true"})
+			}),
+			cluster: cluster(func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.EnvironmentVariables = append(hc.Spec.EnvironmentVariables, corev1.EnvVar{Name: "DANGEROUS_FLAG", Value: "true"})
+				hc.Spec.NodeCount = 3
+			}),
+		},
+		{
+			name: "changed denied environment variable on update",
+			old: cluster(func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.EnvironmentVariables = append(hc.Spec.EnvironmentVariables, corev1.EnvVar{Name: "DANGEROUS_FLAG", Value: "true"})
+			}),
+			cluster: cluster(func(hc *humiov1alpha1.HumioCluster) {
+				hc.Spec.EnvironmentVariables = append(hc.Spec.EnvironmentVariables, corev1.EnvVar{Name: "DANGEROUS_FLAG", Value: "false"})
+			}),
+			expectedError: "spec.environmentVariables must not set DANGEROUS_FLAG",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			v := &HumioClusterValidator{
+				Reader:          fake.NewClientBuilder().WithScheme(scheme).WithObjects(policy).Build(),
+				Log:             logr.Discard(),
+				PolicyNamespace: policy.Namespace,
+				PolicyName:      policy.Name,
+			}
+
+			var err error
+			if tc.old != nil {
+				_, err = v.ValidateUpdate(context.Background(), tc.old, tc.cluster)
+			} else {
+				_, err = v.ValidateCreate(context.Background(), tc.cluster)
+			}
+			if tc.expectedError == "" && err != nil {
+				t.Errorf("expected cluster to be admitted, got %v", err)
+			}
+			if tc.expectedError != "" && (err == nil || !strings.Contains(err.Error(), tc.expectedError)) {
+				t.Errorf("expected error containing %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestParseEnvironmentVariablePolicy(t *testing.T) {
+	if _, err := parseEnvironmentVariablePolicy("- name: '*'"); err == nil {
+		t.Error("expected denying all environment variables to be rejected")
+	}
+	if _, err := parseEnvironmentVariablePolicy("not a list"); err == nil {
+		t.Error("expected an invalid policy to be rejected")
+	}
+	denied, err := parseEnvironmentVariablePolicy("")
+	if err != nil || len(denied) != 0 {
+		t.Errorf("expected an empty policy to deny nothing, got %v and %v", denied, err)
+	}
+}
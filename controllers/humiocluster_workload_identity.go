@@ -0,0 +1,98 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+const (
+	awsRoleARNAnnotation          = "eks.amazonaws.com/role-arn"
+	gcpServiceAccountAnnotation   = "iam.gke.io/gcp-service-account"
+	azureClientIDAnnotation       = "azure.workload.identity/client-id"
+	azureWorkloadIdentityUseLabel = "azure.workload.identity/use"
+)
+
+// workloadIdentityStaticCredentials maps the bucket storage scheme of each cloud to the environment variables holding
+// static credentials for it, which would take precedence over the workload identity
+var workloadIdentityStaticCredentials = map[string][]string{
+	"s3":    {"S3_STORAGE_ACCESSKEY", "S3_STORAGE_SECRETKEY"},
+	"gs":    {"GCP_STORAGE_ACCOUNT_JSON_FILE"},
+	"azure": {"AZURE_STORAGE_ACCOUNTKEY"},
+}
+
+// workloadIdentityServiceAccountAnnotations returns the annotations which bind the Kubernetes Service Account to the
+// cloud identity
+func workloadIdentityServiceAccountAnnotations(workloadIdentity *humiov1alpha1.HumioWorkloadIdentity) map[string]string {
+	annotations := map[string]string{}
+	if workloadIdentity.AWSRoleARN != "" {
+		annotations[awsRoleARNAnnotation] = workloadIdentity.AWSRoleARN
+	}
+	if workloadIdentity.GCPServiceAccount != "" {
+		annotations[gcpServiceAccountAnnotation] = workloadIdentity.GCPServiceAccount
+	}
+	if workloadIdentity.AzureClientID != "" {
+		annotations[azureClientIDAnnotation] = workloadIdentity.AzureClientID
+	}
+	return annotations
+}
+
+// workloadIdentityScheme returns the bucket storage scheme of the cloud the workload identity belongs to
+func workloadIdentityScheme(workloadIdentity *humiov1alpha1.HumioWorkloadIdentity) string {
+	switch {
+	case workloadIdentity.AWSRoleARN != "":
+		return "s3"
+	case workloadIdentity.GCPServiceAccount != "":
+		return "gs"
+	default:
+		return "azure"
+	}
+}
+
+// validateWorkloadIdentity returns an error if the workload identity of the node pool cannot be used to access its
+// bucket storage. The operator runs with its own identity, so it cannot check the permissions of the cloud identity
+// itself, which Humio reports when it fails to access the bucket.
+func validateWorkloadIdentity(hnp *HumioNodePool) error {
+	workloadIdentity := hnp.GetWorkloadIdentity()
+	if workloadIdentity == nil {
+		return nil
+	}
+	if len(workloadIdentityServiceAccountAnnotations(workloadIdentity)) != 1 {
+		return fmt.Errorf("workloadIdentity of node pool %s must set exactly one of awsRoleARN, gcpServiceAccount and azureClientID", hnp.GetNodePoolName())
+	}
+	if hnp.HumioServiceAccountIsSetByUser() {
+		return fmt.Errorf("workloadIdentity of node pool %s cannot be used with humioServiceAccountName, as the operator must annotate the service account", hnp.GetNodePoolName())
+	}
+	scheme := workloadIdentityScheme(workloadIdentity)
+	bucketStorage := nodePoolBucketStorage(hnp)
+	if !strings.HasPrefix(bucketStorage, scheme+"://") {
+		return fmt.Errorf("workloadIdentity of node pool %s is for %s, but the bucket storage of the node pool is %q", hnp.GetNodePoolName(), scheme, bucketStorage)
+	}
+	var staticCredentials []string
+	for _, name := range workloadIdentityStaticCredentials[scheme] {
+		if EnvVarHasKey(hnp.GetEnvironmentVariables(), name) {
+			staticCredentials = append(staticCredentials, name)
+		}
+	}
+	if len(staticCredentials) > 0 {
+		return fmt.Errorf("workloadIdentity of node pool %s cannot be used with static credentials in %s", hnp.GetNodePoolName(), strings.Join(staticCredentials, ", "))
+	}
+	return nil
+}
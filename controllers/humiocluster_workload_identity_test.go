@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateWorkloadIdentity(t *testing.T) {
+	awsRole := &humiov1alpha1.HumioWorkloadIdentity{AWSRoleARN: "arn:aws:iam::123456789012:role/humio"}
+	s3Bucket := corev1.EnvVar{Name: "S3_STORAGE_BUCKET", Value: "humio-storage"}
+
+	testCases := []struct {
+		name                    string
+		workloadIdentity        *humiov1alpha1.HumioWorkloadIdentity
+		environmentVariables    []corev1.EnvVar
+		humioServiceAccountName string
+		expectError             bool
+	}{
+		{
+			name: "no workload identity",
+		},
+		{
+			name:                 "aws role with s3 bucket",
+			workloadIdentity:     awsRole,
+			environmentVariables: []corev1.EnvVar{s3Bucket},
+		},
+		{
+			name:                 "gcp service account with gcs bucket",
+			workloadIdentity:     &humiov1alpha1.HumioWorkloadIdentity{GCPServiceAccount: "humio@project.iam.gserviceaccount.com"},
+			environmentVariables: []corev1.EnvVar{{Name: "GCP_STORAGE_BUCKET", Value: "humio-storage"}},
+		},
+		{
+			name:                 "multiple identities",
+			workloadIdentity:     &humiov1alpha1.HumioWorkloadIdentity{AWSRoleARN: awsRole.AWSRoleARN, AzureClientID: "00000000-0000-0000-0000-000000000000"},
+			environmentVariables: []corev1.EnvVar{s3Bucket},
+			expectError:          true,
+		},
+		{
+			name:             "no bucket storage",
+			workloadIdentity: awsRole,
+			expectError:      true,
+		},
+		{
+			name:                 "bucket storage on another cloud",
+			workloadIdentity:     awsRole,
+			environmentVariables: []corev1.EnvVar{{Name: "AZURE_STORAGE_BUCKET", Value: "humio-storage"}},
+			expectError:          true,
+		},
+		{
+			name:                 "static credentials",
+			workloadIdentity:     awsRole,
+			environmentVariables: []corev1.EnvVar{s3Bucket, {Name: "S3_STORAGE_SECRETKEY", Value: "secret"}},
+			expectError:          true,
+		},
+		{
+			name:                    "service account set by user",
+			workloadIdentity:        awsRole,
+			environmentVariables:    []corev1.EnvVar{s3Bucket},
+			humioServiceAccountName: "humio",
+			expectError:             true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := newPodHashTestCluster()
+			hc.Spec.WorkloadIdentity = tc.workloadIdentity
+			hc.Spec.EnvironmentVariables = append(hc.Spec.EnvironmentVariables, tc.environmentVariables...)
+			hc.Spec.HumioServiceAccountName = tc.humioServiceAccountName
+			if err := validateWorkloadIdentity(NewHumioNodeManagerFromHumioCluster(hc)); (err != nil) != tc.expectError {
+				t.Errorf("validateWorkloadIdentity() error = %v, expectError %v", err, tc.expectError)
+			}
+		})
+	}
+}
+
+func TestWorkloadIdentityServiceAccountAnnotations(t *testing.T) {
+	hc := newPodHashTestCluster()
+	hc.Spec.HumioServiceAccountAnnotations = map[string]string{"team": "logging"}
+	hc.Spec.WorkloadIdentity = &humiov1alpha1.HumioWorkloadIdentity{AzureClientID: "00000000-0000-0000-0000-000000000000"}
+	hnp := NewHumioNodeManagerFromHumioCluster(hc)
+
+	annotations := hnp.GetHumioServiceAccountAnnotations()
+	if annotations[azureClientIDAnnotation] != hc.Spec.WorkloadIdentity.AzureClientID || annotations["team"] != "logging" {
+		t.Errorf("expected the service account to be annotated with the client ID and user annotations, got %v", annotations)
+	}
+	if len(hc.Spec.HumioServiceAccountAnnotations) != 1 {
+		t.Errorf("expected the service account annotations of the spec not to be modified, got %v", hc.Spec.HumioServiceAccountAnnotations)
+	}
+	if hnp.GetPodLabels()[azureWorkloadIdentityUseLabel] != "true" {
+		t.Errorf("expected the pods to be labelled with %s", azureWorkloadIdentityUseLabel)
+	}
+}
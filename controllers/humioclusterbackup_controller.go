@@ -0,0 +1,442 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	humioClusterBackupDefaultImage        = awsCLIImage
+	humioClusterBackupDefaultHistoryLimit = 5
+	humioClusterBackupLabel               = "humio.com/cluster-backup"
+	globalDataSnapshotFilename            = "global-data-snapshot.json"
+)
+
+// humioClusterBackupScript copies the global data snapshot before uploading it, so the uploaded snapshot is not
+// affected by Humio writing a new snapshot during the upload. The size of the snapshot is reported using the
+// termination message of the container.
+const humioClusterBackupScript = `set -e
+cp "$SNAPSHOT_PATH" /tmp/snapshot.json
+aws ${AWS_ENDPOINT_URL:+--endpoint-url "$AWS_ENDPOINT_URL"} s3 cp /tmp/snapshot.json "$BACKUP_LOCATION"
+wc -c < /tmp/snapshot.json | tr -d ' ' > /dev/termination-log
+`
+
+// HumioClusterBackupReconciler reconciles a HumioClusterBackup object
+type HumioClusterBackupReconciler struct {
+	client.Client
+	BaseLogger logr.Logger
+	Log        logr.Logger
+	Namespace  string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioclusterbackups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioclusterbackups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioclusterbackups/finalizers,verbs=update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+
+func (r *HumioClusterBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioClusterBackup")
+
+	// Fetch the HumioClusterBackup instance
+	hcb := &humiov1alpha1.HumioClusterBackup{}
+	err := r.Get(ctx, req.NamespacedName, hcb)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hcb.UID)
+
+	status := *hcb.Status.DeepCopy()
+	status.Message = ""
+	now := metav1.Now()
+
+	if err := r.updateBackupArtifacts(ctx, hcb, &status); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to update state of backups")
+	}
+
+	due, next, err := humioClusterBackupDue(hcb, now.Time)
+	if err != nil {
+		status.State = humiov1alpha1.HumioClusterBackupStateConfigError
+		status.Message = err.Error()
+		return reconcile.Result{}, r.setStatus(ctx, status, hcb)
+	}
+
+	if due && !hcb.Spec.Suspend && !humioClusterBackupRunning(status) {
+		artifact, err := r.startBackup(ctx, hcb, now)
+		if err != nil {
+			r.Log.Error(err, "unable to start backup")
+			status.State = humiov1alpha1.HumioClusterBackupStateConfigError
+			status.Message = err.Error()
+			if err := r.setStatus(ctx, status, hcb); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set backup status")
+			}
+			return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+		}
+		status.LastScheduleTime = &now
+		status.Backups = append([]humiov1alpha1.HumioClusterBackupArtifact{artifact}, status.Backups...)
+	}
+
+	if err := r.trimBackupHistory(ctx, hcb, &status); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to clean up old backups")
+	}
+
+	status.State = humiov1alpha1.HumioClusterBackupStateIdle
+	if humioClusterBackupRunning(status) {
+		status.State = humiov1alpha1.HumioClusterBackupStateRunning
+	}
+	if err := r.setStatus(ctx, status, hcb); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set backup status")
+	}
+
+	if status.State == humiov1alpha1.HumioClusterBackupStateRunning {
+		return reconcile.Result{RequeueAfter: time.Second * 10}, nil
+	}
+	if !next.IsZero() {
+		r.Log.Info(fmt.Sprintf("next backup scheduled at %s", next.Format(time.RFC3339)))
+		return reconcile.Result{RequeueAfter: next.Sub(now.Time)}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// humioClusterBackupDue returns whether a backup should be started now, and when the following backup is scheduled
+func humioClusterBackupDue(hcb *humiov1alpha1.HumioClusterBackup, now time.Time) (bool, time.Time, error) {
+	return helpers.CronScheduleDue(hcb.Spec.Schedule, hcb.CreationTimestamp.Time, hcb.Status.LastScheduleTime, now)
+}
+
+func humioClusterBackupRunning(status humiov1alpha1.HumioClusterBackupStatus) bool {
+	for _, artifact := range status.Backups {
+		if artifact.State == humiov1alpha1.HumioClusterBackupStateRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// startBackup creates the job which uploads the global data snapshot of a running Humio pod. The job runs on the same
+// node as the Humio pod and mounts its data volume read-only.
+func (r *HumioClusterBackupReconciler) startBackup(ctx context.Context, hcb *humiov1alpha1.HumioClusterBackup, now metav1.Time) (humiov1alpha1.HumioClusterBackupArtifact, error) {
+	var hc humiov1alpha1.HumioCluster
+	err := r.Get(ctx, types.NamespacedName{Namespace: hcb.Namespace, Name: hcb.Spec.ManagedClusterName}, &hc)
+	if err != nil {
+		return humiov1alpha1.HumioClusterBackupArtifact{}, fmt.Errorf("unable to get cluster %s: %w", hcb.Spec.ManagedClusterName, err)
+	}
+
+	bucketStorage := humioClusterBucketStorage(&hc)
+	if bucketStorage == "" && !hcb.Spec.AllowWithoutBucketStorage {
+		return humiov1alpha1.HumioClusterBackupArtifact{}, fmt.Errorf("cluster %s does not use bucket storage, so a backup of its metadata cannot be used to restore it", hc.Name)
+	}
+
+	pods, err := kubernetes.ListPods(ctx, r, hcb.Namespace, kubernetes.MatchingLabelsForHumio(hc.Name))
+	if err != nil {
+		return humiov1alpha1.HumioClusterBackupArtifact{}, fmt.Errorf("unable to list pods: %w", err)
+	}
+	var sourcePod *corev1.Pod
+	var dataVolume corev1.Volume
+	for i := range pods {
+		if pods[i].Status.Phase != corev1.PodRunning || pods[i].DeletionTimestamp != nil {
+			continue
+		}
+		for _, volume := range pods[i].Spec.Volumes {
+			if volume.Name == "humio-data" && (volume.PersistentVolumeClaim != nil || volume.HostPath != nil) {
+				sourcePod, dataVolume = &pods[i], volume
+				break
+			}
+		}
+		if sourcePod != nil {
+			break
+		}
+	}
+	if sourcePod == nil {
+		return humiov1alpha1.HumioClusterBackupArtifact{}, fmt.Errorf("no running pod of cluster %s with a data volume that can be mounted by the backup", hc.Name)
+	}
+
+	job := constructHumioClusterBackupJob(hcb, sourcePod, dataVolume, now)
+	if err := controllerutil.SetControllerReference(hcb, job, r.Scheme()); err != nil {
+		return humiov1alpha1.HumioClusterBackupArtifact{}, err
+	}
+	r.Log.Info(fmt.Sprintf("creating backup job %s using pod %s", job.Name, sourcePod.Name))
+	if err := r.Create(ctx, job); err != nil {
+		return humiov1alpha1.HumioClusterBackupArtifact{}, fmt.Errorf("unable to create backup job: %w", err)
+	}
+
+	return humiov1alpha1.HumioClusterBackupArtifact{
+		Name:          job.Name,
+		State:         humiov1alpha1.HumioClusterBackupStateRunning,
+		Location:      humioClusterBackupLocation(hcb, job.Name),
+		BucketStorage: bucketStorage,
+		StartTime:     &now,
+	}, nil
+}
+
+// humioClusterBucketStorage returns the bucket used for bucket storage by the given cluster, if any
+func humioClusterBucketStorage(hc *humiov1alpha1.HumioCluster) string {
+	return nodePoolBucketStorage(NewHumioNodeManagerFromHumioCluster(hc))
+}
+
+// nodePoolBucketStorage returns the location of the bucket storage configured for the node pool, or an empty string
+// if bucket storage is not configured
+func nodePoolBucketStorage(hnp *HumioNodePool) string {
+	schemes := map[string]string{
+		"S3_STORAGE_BUCKET":    "s3",
+		"GCP_STORAGE_BUCKET":   "gs",
+		"AZURE_STORAGE_BUCKET": "azure",
+	}
+	for _, envVar := range hnp.GetEnvironmentVariables() {
+		if scheme, ok := schemes[envVar.Name]; ok && envVar.Value != "" {
+			return fmt.Sprintf("%s://%s", scheme, envVar.Value)
+		}
+	}
+	return ""
+}
+
+func humioClusterBackupLocation(hcb *humiov1alpha1.HumioClusterBackup, name string) string {
+	key := strings.Trim(hcb.Spec.Target.Prefix, "/")
+	if key != "" {
+		key += "/"
+	}
+	return fmt.Sprintf("s3://%s/%s%s/%s", hcb.Spec.Target.Bucket, key, name, globalDataSnapshotFilename)
+}
+
+func constructHumioClusterBackupJob(hcb *humiov1alpha1.HumioClusterBackup, sourcePod *corev1.Pod, dataVolume corev1.Volume, now metav1.Time) *batchv1.Job {
+	name := newJobName(hcb.Name, now)
+
+	image := hcb.Spec.Image
+	if image == "" {
+		image = humioClusterBackupDefaultImage
+	}
+
+	if dataVolume.PersistentVolumeClaim != nil {
+		dataVolume.PersistentVolumeClaim = dataVolume.PersistentVolumeClaim.DeepCopy()
+		dataVolume.PersistentVolumeClaim.ReadOnly = true
+	}
+
+	env := []corev1.EnvVar{
+		{Name: "SNAPSHOT_PATH", Value: fmt.Sprintf("%s/%s", HumioDataPath, globalDataSnapshotFilename)},
+		{Name: "BACKUP_LOCATION", Value: humioClusterBackupLocation(hcb, name)},
+	}
+	if hcb.Spec.Target.Region != "" {
+		env = append(env, corev1.EnvVar{Name: "AWS_REGION", Value: hcb.Spec.Target.Region})
+	}
+	if hcb.Spec.Target.Endpoint != "" {
+		env = append(env, corev1.EnvVar{Name: "AWS_ENDPOINT_URL", Value: hcb.Spec.Target.Endpoint})
+	}
+	var envFrom []corev1.EnvFromSource
+	if hcb.Spec.Target.CredentialsSecretName != "" {
+		envFrom = append(envFrom, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: hcb.Spec.Target.CredentialsSecretName},
+			},
+		})
+	}
+
+	backoffLimit := int32(2)
+	labels := map[string]string{
+		humioClusterBackupLabel:        hcb.Name,
+		"app.kubernetes.io/managed-by": "humio-operator",
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: hcb.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					NodeName:           sourcePod.Spec.NodeName,
+					ServiceAccountName: hcb.Spec.ServiceAccountName,
+					SecurityContext:    sourcePod.Spec.SecurityContext,
+					ImagePullSecrets:   sourcePod.Spec.ImagePullSecrets,
+					Tolerations:        sourcePod.Spec.Tolerations,
+					Containers: []corev1.Container{
+						{
+							Name:    "backup",
+							Image:   image,
+							Command: []string{"/bin/sh", "-c", humioClusterBackupScript},
+							Env:     env,
+							EnvFrom: envFrom,
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      dataVolume.Name,
+									MountPath: HumioDataPath,
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{dataVolume},
+				},
+			},
+		},
+	}
+}
+
+// updateBackupArtifacts updates the state of running backups from their jobs
+func (r *HumioClusterBackupReconciler) updateBackupArtifacts(ctx context.Context, hcb *humiov1alpha1.HumioClusterBackup, status *humiov1alpha1.HumioClusterBackupStatus) error {
+	for i := range status.Backups {
+		artifact := &status.Backups[i]
+		if artifact.State != humiov1alpha1.HumioClusterBackupStateRunning {
+			continue
+		}
+		var job batchv1.Job
+		err := r.Get(ctx, types.NamespacedName{Namespace: hcb.Namespace, Name: artifact.Name}, &job)
+		if k8serrors.IsNotFound(err) {
+			artifact.State = humiov1alpha1.HumioClusterBackupStateFailed
+			artifact.Message = "backup job was deleted before it completed"
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				artifact.State = humiov1alpha1.HumioClusterBackupStateSucceeded
+				artifact.CompletionTime = job.Status.CompletionTime
+				artifact.SizeBytes = r.backupSize(ctx, &job)
+				status.LastSuccessfulTime = job.Status.CompletionTime
+				r.Log.Info(fmt.Sprintf("backup %s stored at %s", artifact.Name, artifact.Location))
+			case batchv1.JobFailed:
+				completionTime := condition.LastTransitionTime
+				artifact.State = humiov1alpha1.HumioClusterBackupStateFailed
+				artifact.CompletionTime = &completionTime
+				artifact.Message = condition.Message
+				r.Log.Info(fmt.Sprintf("backup %s failed: %s", artifact.Name, condition.Message))
+			}
+		}
+	}
+	return nil
+}
+
+// backupSize returns the size of the uploaded snapshot, as reported in the termination message of the backup pod
+func (r *HumioClusterBackupReconciler) backupSize(ctx context.Context, job *batchv1.Job) int64 {
+	size, err := jobReportedSize(ctx, r, job)
+	if err != nil {
+		r.Log.Error(err, "unable to list backup pods")
+	}
+	return size
+}
+
+// jobReportedSize returns the size reported in the termination message of a pod of the job which completed
+// successfully, or zero if no such pod reported a size
+func jobReportedSize(ctx context.Context, c client.Client, job *batchv1.Job) (int64, error) {
+	var pods corev1.PodList
+	if err := c.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return 0, err
+	}
+	for _, pod := range pods.Items {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			terminated := containerStatus.State.Terminated
+			if terminated == nil || terminated.ExitCode != 0 {
+				continue
+			}
+			size, err := strconv.ParseInt(strings.TrimSpace(terminated.Message), 10, 64)
+			if err == nil {
+				return size, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// trimBackupHistory removes the oldest backups from the status along with their jobs. The backup artifacts stored in
+// the bucket are left untouched, so their retention can be managed using the lifecycle rules of the bucket.
+func (r *HumioClusterBackupReconciler) trimBackupHistory(ctx context.Context, hcb *humiov1alpha1.HumioClusterBackup, status *humiov1alpha1.HumioClusterBackupStatus) error {
+	historyLimit := hcb.Spec.HistoryLimit
+	if historyLimit <= 0 {
+		historyLimit = humioClusterBackupDefaultHistoryLimit
+	}
+	if len(status.Backups) <= historyLimit {
+		return nil
+	}
+	for _, artifact := range status.Backups[historyLimit:] {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: hcb.Namespace, Name: artifact.Name}}
+		err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	status.Backups = status.Backups[:historyLimit]
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioClusterBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioClusterBackup{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+func (r *HumioClusterBackupReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioClusterBackupStatus, hcb *humiov1alpha1.HumioClusterBackup) error {
+	if reflect.DeepEqual(hcb.Status, status) {
+		return nil
+	}
+	if hcb.Status.State != status.State {
+		r.Log.Info(fmt.Sprintf("setting backup state to %s", status.State))
+	}
+	hcb.Status = status
+	return r.Status().Update(ctx, hcb)
+}
+
+func (r *HumioClusterBackupReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHumioClusterBackupDue(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC)
+	created := metav1.NewTime(now.Add(-48 * time.Hour))
+	today := metav1.NewTime(time.Date(2024, time.January, 10, 2, 0, 0, 0, time.UTC))
+	yesterday := metav1.NewTime(time.Date(2024, time.January, 9, 2, 0, 0, 0, time.UTC))
+
+	tt := []struct {
+		name         string
+		schedule     string
+		lastSchedule *metav1.Time
+		due          bool
+		next         time.Time
+	}{
+		{
+			name: "one-off backup not taken yet",
+			due:  true,
+		},
+		{
+			name:         "one-off backup already taken",
+			lastSchedule: &today,
+			due:          false,
+		},
+		{
+			name:         "scheduled backup missed",
+			schedule:     "0 2 * * *",
+			lastSchedule: &yesterday,
+			due:          true,
+			next:         time.Date(2024, time.January, 11, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "scheduled backup already taken",
+			schedule:     "0 2 * * *",
+			lastSchedule: &today,
+			due:          false,
+			next:         time.Date(2024, time.January, 11, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "first scheduled backup",
+			schedule: "0 2 * * *",
+			due:      true,
+			next:     time.Date(2024, time.January, 11, 2, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hcb := &humiov1alpha1.HumioClusterBackup{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Spec:       humiov1alpha1.HumioClusterBackupSpec{Schedule: tc.schedule},
+				Status:     humiov1alpha1.HumioClusterBackupStatus{LastScheduleTime: tc.lastSchedule},
+			}
+			due, next, err := humioClusterBackupDue(hcb, now)
+			if err != nil {
+				t.Fatalf("humioClusterBackupDue() error = %v", err)
+			}
+			if due != tc.due {
+				t.Errorf("humioClusterBackupDue() due = %v, want %v", due, tc.due)
+			}
+			if !next.Equal(tc.next) {
+				t.Errorf("humioClusterBackupDue() next = %s, want %s", next, tc.next)
+			}
+		})
+	}
+}
+
+func TestConstructHumioClusterBackupJob(t *testing.T) {
+	hcb := &humiov1alpha1.HumioClusterBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec: humiov1alpha1.HumioClusterBackupSpec{
+			Target: humiov1alpha1.HumioClusterBackupTarget{Bucket: "bucket", Prefix: "/cluster/"},
+		},
+	}
+	sourcePod := &corev1.Pod{Spec: corev1.PodSpec{NodeName: "node-1"}}
+	dataVolume := corev1.Volume{
+		Name: "humio-data",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "humio-data-pvc"},
+		},
+	}
+	now := metav1.NewTime(time.Date(2024, time.January, 10, 2, 0, 0, 0, time.UTC))
+
+	job := constructHumioClusterBackupJob(hcb, sourcePod, dataVolume, now)
+
+	if !strings.HasPrefix(job.Name, "backup-2401100200-") {
+		t.Errorf("expected job name starting with %s, got %s", "backup-2401100200-", job.Name)
+	}
+	podSpec := job.Spec.Template.Spec
+	if podSpec.NodeName != "node-1" {
+		t.Errorf("expected job to run on the node of the source pod, got %q", podSpec.NodeName)
+	}
+	if !podSpec.Volumes[0].PersistentVolumeClaim.ReadOnly {
+		t.Error("expected data volume to be mounted read-only")
+	}
+	if dataVolume.PersistentVolumeClaim.ReadOnly {
+		t.Error("expected data volume of the source pod to be left untouched")
+	}
+	for _, env := range podSpec.Containers[0].Env {
+		if env.Name == "BACKUP_LOCATION" && env.Value != "s3://bucket/cluster/"+job.Name+"/global-data-snapshot.json" {
+			t.Errorf("unexpected backup location %s", env.Value)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// bucketStorageRecoveryEnvVars maps the environment variables configuring the bucket storage of a cluster to the
+// environment variables which allow another cluster to read the segments stored in that bucket
+var bucketStorageRecoveryEnvVars = map[string]string{
+	"S3_STORAGE_BUCKET":            "S3_RECOVER_FROM_BUCKET",
+	"S3_STORAGE_REGION":            "S3_RECOVER_FROM_REGION",
+	"S3_STORAGE_ENDPOINT_BASE":     "S3_RECOVER_FROM_ENDPOINT_BASE",
+	"S3_STORAGE_ACCESSKEY":         "S3_RECOVER_FROM_ACCESSKEY",
+	"S3_STORAGE_SECRETKEY":         "S3_RECOVER_FROM_SECRETKEY",
+	"S3_STORAGE_ENCRYPTION_KEY":    "S3_RECOVER_FROM_ENCRYPTION_KEY",
+	"S3_STORAGE_PATH_STYLE_ACCESS": "S3_RECOVER_FROM_PATH_STYLE_ACCESS",
+	"GCP_STORAGE_BUCKET":           "GCP_RECOVER_FROM_BUCKET",
+	"GCP_STORAGE_ENCRYPTION_KEY":   "GCP_RECOVER_FROM_ENCRYPTION_KEY",
+}
+
+// recoveryEnvVarPrefixes are the prefixes of the environment variables managed by HumioClusterReplication on the
+// passive cluster
+var recoveryEnvVarPrefixes = []string{"S3_RECOVER_FROM_", "GCP_RECOVER_FROM_"}
+
+// HumioClusterReplicationReconciler reconciles a HumioClusterReplication object
+type HumioClusterReplicationReconciler struct {
+	client.Client
+	BaseLogger logr.Logger
+	Log        logr.Logger
+	Namespace  string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioclusterreplications,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioclusterreplications/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioclusterreplications/finalizers,verbs=update
+
+func (r *HumioClusterReplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioClusterReplication")
+
+	// Fetch the HumioClusterReplication instance
+	hcr := &humiov1alpha1.HumioClusterReplication{}
+	err := r.Get(ctx, req.NamespacedName, hcr)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hcr.UID)
+
+	status := *hcr.Status.DeepCopy()
+	status.Message = ""
+
+	active, passive, err := r.getClusters(ctx, hcr)
+	if err == nil {
+		status.SourceBucket, err = validateHumioClusterReplication(active, passive)
+	}
+	if err != nil {
+		r.Log.Error(err, "invalid replication configuration")
+		status.State = humiov1alpha1.HumioClusterReplicationStateConfigError
+		status.Message = err.Error()
+		if err := r.setStatus(ctx, status, hcr); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set replication status")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	switching := hcr.Status.State == humiov1alpha1.HumioClusterReplicationStateSwitching
+	if status.ActiveCluster != active.Name {
+		if status.ActiveCluster != "" {
+			r.Log.Info(fmt.Sprintf("switching active cluster from %s to %s", status.ActiveCluster, active.Name))
+			now := metav1.Now()
+			status.LastSwitchTime = &now
+			switching = true
+		}
+		status.ActiveCluster = active.Name
+	}
+
+	// Configure the passive cluster to read the segments of the active cluster
+	recoveryEnvVars := humioClusterRecoveryEnvVars(active)
+	if setRecoveryEnvVars(passive, recoveryEnvVars) {
+		r.Log.Info(fmt.Sprintf("configuring cluster %s to read segments from %s", passive.Name, status.SourceBucket))
+		if err := r.Update(ctx, passive); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to configure passive cluster")
+		}
+	}
+	// After a switch, the previously passive cluster must stop reading the segments of the other cluster
+	if setRecoveryEnvVars(active, nil) {
+		r.Log.Info(fmt.Sprintf("configuring cluster %s to stop reading segments from another cluster", active.Name))
+		if err := r.Update(ctx, active); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to configure active cluster")
+		}
+	}
+
+	if active.Name == hcr.Spec.PrimaryClusterName {
+		status.PrimaryClusterState, status.StandbyClusterState = active.Status.State, passive.Status.State
+	} else {
+		status.PrimaryClusterState, status.StandbyClusterState = passive.Status.State, active.Status.State
+	}
+	switch {
+	case active.Status.State == humiov1alpha1.HumioClusterStateRunning && passive.Status.State == humiov1alpha1.HumioClusterStateRunning:
+		status.State = humiov1alpha1.HumioClusterReplicationStateReplicating
+	case switching:
+		status.State = humiov1alpha1.HumioClusterReplicationStateSwitching
+	default:
+		status.State = humiov1alpha1.HumioClusterReplicationStatePending
+	}
+	if err := r.setStatus(ctx, status, hcr); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set replication status")
+	}
+
+	r.Log.Info("done reconciling, will requeue after 60 seconds")
+	return reconcile.Result{RequeueAfter: time.Second * 60}, nil
+}
+
+// getClusters returns the active and passive cluster of the given replication
+func (r *HumioClusterReplicationReconciler) getClusters(ctx context.Context, hcr *humiov1alpha1.HumioClusterReplication) (*humiov1alpha1.HumioCluster, *humiov1alpha1.HumioCluster, error) {
+	if hcr.Spec.PrimaryClusterName == hcr.Spec.StandbyClusterName {
+		return nil, nil, fmt.Errorf("primary and standby cluster must be different clusters")
+	}
+	activeName, passiveName := hcr.Spec.PrimaryClusterName, hcr.Spec.StandbyClusterName
+	if hcr.Spec.ActiveCluster == humiov1alpha1.HumioClusterReplicationActiveStandby {
+		activeName, passiveName = passiveName, activeName
+	}
+
+	var active, passive humiov1alpha1.HumioCluster
+	if err := r.Get(ctx, types.NamespacedName{Namespace: hcr.Namespace, Name: activeName}, &active); err != nil {
+		return nil, nil, fmt.Errorf("unable to get cluster %s: %w", activeName, err)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: hcr.Namespace, Name: passiveName}, &passive); err != nil {
+		return nil, nil, fmt.Errorf("unable to get cluster %s: %w", passiveName, err)
+	}
+	return &active, &passive, nil
+}
+
+// validateHumioClusterReplication returns the bucket storage of the active cluster, or an error if the passive cluster
+// cannot be configured to read from it
+func validateHumioClusterReplication(active, passive *humiov1alpha1.HumioCluster) (string, error) {
+	activeBucket := humioClusterBucketStorage(active)
+	if activeBucket == "" {
+		return "", fmt.Errorf("cluster %s does not use bucket storage", active.Name)
+	}
+	if passiveBucket := humioClusterBucketStorage(passive); passiveBucket == activeBucket {
+		return "", fmt.Errorf("clusters %s and %s must not use the same bucket %s", active.Name, passive.Name, activeBucket)
+	}
+	return activeBucket, nil
+}
+
+// humioClusterRecoveryEnvVars returns the environment variables which allow a cluster to read the segments stored in
+// the bucket storage of the given cluster. Values referencing secrets are kept, as both clusters live in the same
+// namespace.
+func humioClusterRecoveryEnvVars(hc *humiov1alpha1.HumioCluster) []corev1.EnvVar {
+	var envVars []corev1.EnvVar
+	for _, envVar := range NewHumioNodeManagerFromHumioCluster(hc).GetEnvironmentVariables() {
+		if name, ok := bucketStorageRecoveryEnvVars[envVar.Name]; ok {
+			envVar.Name = name
+			envVars = append(envVars, envVar)
+		}
+	}
+	return envVars
+}
+
+// setRecoveryEnvVars replaces the recovery environment variables of the cluster and all its node pools, and returns
+// whether the cluster was changed
+func setRecoveryEnvVars(hc *humiov1alpha1.HumioCluster, recoveryEnvVars []corev1.EnvVar) bool {
+	changed := false
+	update := func(envVars []corev1.EnvVar) []corev1.EnvVar {
+		var updated []corev1.EnvVar
+		for _, envVar := range envVars {
+			if !isRecoveryEnvVar(envVar.Name) {
+				updated = append(updated, envVar)
+			}
+		}
+		updated = append(updated, recoveryEnvVars...)
+		if len(envVars) == 0 && len(updated) == 0 || reflect.DeepEqual(envVars, updated) {
+			return envVars
+		}
+		changed = true
+		return updated
+	}
+	hc.Spec.EnvironmentVariables = update(hc.Spec.EnvironmentVariables)
+	for i := range hc.Spec.NodePools {
+		hc.Spec.NodePools[i].EnvironmentVariables = update(hc.Spec.NodePools[i].EnvironmentVariables)
+	}
+	return changed
+}
+
+func isRecoveryEnvVar(name string) bool {
+	for _, prefix := range recoveryEnvVarPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioClusterReplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioClusterReplication{}).
+		Watches(&humiov1alpha1.HumioCluster{}, handler.EnqueueRequestsFromMapFunc(r.replicationsForCluster)).
+		Complete(r)
+}
+
+// replicationsForCluster returns a reconcile request for every HumioClusterReplication involving the given cluster, so
+// changes to the bucket storage or state of the cluster are reflected right away
+func (r *HumioClusterReplicationReconciler) replicationsForCluster(ctx context.Context, hc client.Object) []reconcile.Request {
+	var humioClusterReplications humiov1alpha1.HumioClusterReplicationList
+	if err := r.List(ctx, &humioClusterReplications, client.InNamespace(hc.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list cluster replications")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, hcr := range humioClusterReplications.Items {
+		if hcr.Spec.PrimaryClusterName == hc.GetName() || hcr.Spec.StandbyClusterName == hc.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: hcr.Namespace, Name: hcr.Name},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *HumioClusterReplicationReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioClusterReplicationStatus, hcr *humiov1alpha1.HumioClusterReplication) error {
+	if reflect.DeepEqual(hcr.Status, status) {
+		return nil
+	}
+	if hcr.Status.State != status.State {
+		r.Log.Info(fmt.Sprintf("setting replication state to %s", status.State))
+	}
+	hcr.Status = status
+	return r.Status().Update(ctx, hcr)
+}
+
+func (r *HumioClusterReplicationReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
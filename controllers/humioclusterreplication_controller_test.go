@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHumioClusterReplicationRecoveryEnvVars(t *testing.T) {
+	secretKeyRef := &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "primary-bucket"},
+			Key:                  "secret-key",
+		},
+	}
+	primary := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "primary"},
+		Spec: humiov1alpha1.HumioClusterSpec{
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				EnvironmentVariables: []corev1.EnvVar{
+					{Name: "S3_STORAGE_BUCKET", Value: "primary-bucket"},
+					{Name: "S3_STORAGE_REGION", Value: "us-east-1"},
+					{Name: "S3_STORAGE_SECRETKEY", ValueFrom: secretKeyRef},
+				},
+			},
+		},
+	}
+	standby := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "standby"},
+		Spec: humiov1alpha1.HumioClusterSpec{
+			HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+				EnvironmentVariables: []corev1.EnvVar{
+					{Name: "S3_STORAGE_BUCKET", Value: "standby-bucket"},
+					{Name: "S3_RECOVER_FROM_BUCKET", Value: "old-bucket"},
+				},
+			},
+			NodePools: []humiov1alpha1.HumioNodePoolSpec{{Name: "pool"}},
+		},
+	}
+
+	sourceBucket, err := validateHumioClusterReplication(primary, standby)
+	if err != nil {
+		t.Fatalf("validateHumioClusterReplication() error = %v", err)
+	}
+	if sourceBucket != "s3://primary-bucket" {
+		t.Errorf("expected source bucket %s, got %s", "s3://primary-bucket", sourceBucket)
+	}
+
+	if !setRecoveryEnvVars(standby, humioClusterRecoveryEnvVars(primary)) {
+		t.Fatal("expected standby cluster to be changed")
+	}
+	expected := []corev1.EnvVar{
+		{Name: "S3_STORAGE_BUCKET", Value: "standby-bucket"},
+		{Name: "S3_RECOVER_FROM_BUCKET", Value: "primary-bucket"},
+		{Name: "S3_RECOVER_FROM_REGION", Value: "us-east-1"},
+		{Name: "S3_RECOVER_FROM_SECRETKEY", ValueFrom: secretKeyRef},
+	}
+	for _, envVar := range expected {
+		if !containsEnvVar(standby.Spec.EnvironmentVariables, envVar) {
+			t.Errorf("expected cluster to have environment variable %+v, got %+v", envVar, standby.Spec.EnvironmentVariables)
+		}
+	}
+	if len(standby.Spec.EnvironmentVariables) != len(expected) {
+		t.Errorf("expected %d environment variables, got %+v", len(expected), standby.Spec.EnvironmentVariables)
+	}
+	if len(standby.Spec.NodePools[0].EnvironmentVariables) != 3 {
+		t.Errorf("expected node pool to read from the primary bucket, got %+v", standby.Spec.NodePools[0].EnvironmentVariables)
+	}
+	if setRecoveryEnvVars(standby, humioClusterRecoveryEnvVars(primary)) {
+		t.Error("expected standby cluster to be unchanged when already configured")
+	}
+
+	standby.Spec.EnvironmentVariables[0].Value = "primary-bucket"
+	if _, err := validateHumioClusterReplication(primary, standby); err == nil {
+		t.Error("expected error when both clusters use the same bucket")
+	}
+}
+
+func TestHumioClusterReplicationSwitch(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+
+	cluster := func(name, bucket string) *humiov1alpha1.HumioCluster {
+		return &humiov1alpha1.HumioCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: humiov1alpha1.HumioClusterSpec{
+				HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+					EnvironmentVariables: []corev1.EnvVar{{Name: "S3_STORAGE_BUCKET", Value: bucket}},
+				},
+				NodePools: []humiov1alpha1.HumioNodePoolSpec{{Name: "pool"}},
+			},
+		}
+	}
+	hcr := &humiov1alpha1.HumioClusterReplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "replication", Namespace: "default"},
+		Spec: humiov1alpha1.HumioClusterReplicationSpec{
+			PrimaryClusterName: "primary",
+			StandbyClusterName: "standby",
+			ActiveCluster:      humiov1alpha1.HumioClusterReplicationActivePrimary,
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(cluster("primary", "primary-bucket"), cluster("standby", "standby-bucket"), hcr).
+		WithStatusSubresource(hcr).Build()
+	r := &HumioClusterReplicationReconciler{Client: c, BaseLogger: logr.Discard()}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "replication"}}
+
+	tests := []struct {
+		activeCluster string
+		readsFrom     map[string]string
+	}{
+		{activeCluster: humiov1alpha1.HumioClusterReplicationActivePrimary, readsFrom: map[string]string{"primary": "", "standby": "primary-bucket"}},
+		{activeCluster: humiov1alpha1.HumioClusterReplicationActiveStandby, readsFrom: map[string]string{"primary": "standby-bucket", "standby": ""}},
+		{activeCluster: humiov1alpha1.HumioClusterReplicationActivePrimary, readsFrom: map[string]string{"primary": "", "standby": "primary-bucket"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.activeCluster, func(t *testing.T) {
+			var current humiov1alpha1.HumioClusterReplication
+			if err := c.Get(context.Background(), req.NamespacedName, &current); err != nil {
+				t.Fatal(err)
+			}
+			current.Spec.ActiveCluster = tt.activeCluster
+			if err := c.Update(context.Background(), &current); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			for name, bucket := range tt.readsFrom {
+				var hc humiov1alpha1.HumioCluster
+				if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &hc); err != nil {
+					t.Fatal(err)
+				}
+				for _, envVars := range [][]corev1.EnvVar{hc.Spec.EnvironmentVariables, hc.Spec.NodePools[0].EnvironmentVariables} {
+					got := ""
+					for _, envVar := range envVars {
+						if envVar.Name == "S3_RECOVER_FROM_BUCKET" {
+							got = envVar.Value
+						}
+					}
+					if got != bucket {
+						t.Errorf("expected cluster %s to read segments from %q, got %q", name, bucket, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func containsEnvVar(envVars []corev1.EnvVar, envVar corev1.EnvVar) bool {
+	for _, e := range envVars {
+		if e.Name == envVar.Name && e.Value == envVar.Value && (e.ValueFrom == nil) == (envVar.ValueFrom == nil) {
+			return true
+		}
+	}
+	return false
+}
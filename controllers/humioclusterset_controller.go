@@ -0,0 +1,405 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// humioClusterSetLabelName and humioClusterSetNamespaceLabelName identify the HumioClusterSet managing a
+	// HumioCluster. Clusters may live in other namespaces than the set, so owner references cannot be used.
+	humioClusterSetLabelName          = "humio.com/cluster-set"
+	humioClusterSetNamespaceLabelName = "humio.com/cluster-set-namespace"
+	// humioClusterSetSpecHashAnnotation holds the hash of the spec the cluster set last applied to a HumioCluster.
+	// The spec is only updated when it changes, so other controllers such as HumioClusterReplication may still adjust
+	// the clusters of the set.
+	humioClusterSetSpecHashAnnotation = "humio.com/cluster-set-spec-hash"
+)
+
+// HumioClusterSetReconciler reconciles a HumioClusterSet object
+type HumioClusterSetReconciler struct {
+	client.Client
+	BaseLogger logr.Logger
+	Log        logr.Logger
+	Namespace  string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioclustersets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioclustersets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioclustersets/finalizers,verbs=update
+
+func (r *HumioClusterSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioClusterSet")
+
+	// Fetch the HumioClusterSet instance
+	hcs := &humiov1alpha1.HumioClusterSet{}
+	err := r.Get(ctx, req.NamespacedName, hcs)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hcs.UID)
+
+	if hcs.GetDeletionTimestamp() != nil {
+		r.Log.Info("Cluster set marked to be deleted")
+		if helpers.ContainsElement(hcs.GetFinalizers(), humioFinalizer) {
+			if err := r.finalize(ctx, hcs); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "Finalizer method returned error")
+			}
+			r.Log.Info("Finalizer done. Removing finalizer")
+			hcs.SetFinalizers(helpers.RemoveElement(hcs.GetFinalizers(), humioFinalizer))
+			if err := r.Update(ctx, hcs); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if !helpers.ContainsElement(hcs.GetFinalizers(), humioFinalizer) {
+		r.Log.Info("Finalizer not present, adding finalizer to cluster set")
+		hcs.SetFinalizers(append(hcs.GetFinalizers(), humioFinalizer))
+		if err := r.Update(ctx, hcs); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "Failed to update HumioClusterSet with finalizer")
+		}
+	}
+
+	desired, err := humioClusterSetClusters(hcs)
+	if err != nil {
+		r.Log.Error(err, "invalid cluster set configuration")
+		status := *hcs.Status.DeepCopy()
+		status.State = humiov1alpha1.HumioClusterSetStateConfigError
+		status.Message = err.Error()
+		if err := r.setStatus(ctx, status, hcs); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster set status")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	var clusters []humiov1alpha1.HumioClusterSetClusterStatus
+	for i := range desired {
+		clusterStatus := humiov1alpha1.HumioClusterSetClusterStatus{
+			Name:      desired[i].Name,
+			Namespace: desired[i].Namespace,
+		}
+		hc, err := r.ensureCluster(ctx, hcs, &desired[i])
+		if err != nil {
+			r.Log.Error(err, fmt.Sprintf("unable to manage cluster %s/%s", desired[i].Namespace, desired[i].Name))
+			clusterStatus.Message = err.Error()
+		} else {
+			clusterStatus.State = hc.Status.State
+			clusterStatus.Version = hc.Status.Version
+		}
+		clusters = append(clusters, clusterStatus)
+	}
+
+	if err := r.removeClusters(ctx, hcs, desired); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to remove clusters which are no longer part of the cluster set")
+	}
+
+	status := humioClusterSetStatus(clusters)
+	if err := r.setStatus(ctx, status, hcs); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster set status")
+	}
+
+	r.Log.Info("done reconciling, will requeue after 60 seconds")
+	return reconcile.Result{RequeueAfter: time.Second * 60}, nil
+}
+
+// humioClusterSetClusters returns the HumioClusters described by the instances of the cluster set
+func humioClusterSetClusters(hcs *humiov1alpha1.HumioClusterSet) ([]humiov1alpha1.HumioCluster, error) {
+	seen := map[types.NamespacedName]bool{}
+	var clusters []humiov1alpha1.HumioCluster
+	for _, instance := range hcs.Spec.Instances {
+		namespace := instance.Namespace
+		if namespace == "" {
+			namespace = hcs.Namespace
+		}
+		key := types.NamespacedName{Namespace: namespace, Name: instance.Name}
+		if seen[key] {
+			return nil, fmt.Errorf("cluster %s is listed more than once", key)
+		}
+		seen[key] = true
+
+		spec, err := applyHumioClusterSetOverrides(hcs.Spec.Template.Spec, instance.Overrides)
+		if err != nil {
+			return nil, fmt.Errorf("invalid overrides for cluster %s: %w", key, err)
+		}
+
+		labels := map[string]string{}
+		for k, v := range hcs.Spec.Template.Labels {
+			labels[k] = v
+		}
+		for k, v := range instance.Labels {
+			labels[k] = v
+		}
+		labels[humioClusterSetLabelName] = hcs.Name
+		labels[humioClusterSetNamespaceLabelName] = hcs.Namespace
+
+		annotations := map[string]string{}
+		for k, v := range hcs.Spec.Template.Annotations {
+			annotations[k] = v
+		}
+		annotations[humioClusterSetSpecHashAnnotation] = helpers.AsSHA256(spec)
+
+		clusters = append(clusters, humiov1alpha1.HumioCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        instance.Name,
+				Namespace:   namespace,
+				Labels:      labels,
+				Annotations: annotations,
+			},
+			Spec: spec,
+		})
+	}
+	return clusters, nil
+}
+
+// applyHumioClusterSetOverrides applies the overrides of an instance as a strategic merge patch to the spec of the
+// template. Unknown fields are rejected so typos in the overrides do not go unnoticed.
+func applyHumioClusterSetOverrides(spec humiov1alpha1.HumioClusterSpec, overrides *runtime.RawExtension) (humiov1alpha1.HumioClusterSpec, error) {
+	if overrides == nil || len(overrides.Raw) == 0 {
+		return *spec.DeepCopy(), nil
+	}
+	original, err := json.Marshal(spec)
+	if err != nil {
+		return humiov1alpha1.HumioClusterSpec{}, err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(original, overrides.Raw, humiov1alpha1.HumioClusterSpec{})
+	if err != nil {
+		return humiov1alpha1.HumioClusterSpec{}, err
+	}
+	var result humiov1alpha1.HumioClusterSpec
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return humiov1alpha1.HumioClusterSpec{}, err
+	}
+	return result, nil
+}
+
+// ensureCluster creates the given HumioCluster, or updates it if the spec of the cluster set has changed since it was
+// last applied. Clusters which exist but are not part of the cluster set are left untouched.
+func (r *HumioClusterSetReconciler) ensureCluster(ctx context.Context, hcs *humiov1alpha1.HumioClusterSet, desired *humiov1alpha1.HumioCluster) (*humiov1alpha1.HumioCluster, error) {
+	var hc humiov1alpha1.HumioCluster
+	err := r.Get(ctx, types.NamespacedName{Namespace: desired.Namespace, Name: desired.Name}, &hc)
+	if k8serrors.IsNotFound(err) {
+		r.Log.Info(fmt.Sprintf("creating cluster %s/%s", desired.Namespace, desired.Name))
+		if err := r.Create(ctx, desired); err != nil {
+			return nil, fmt.Errorf("unable to create cluster: %w", err)
+		}
+		return desired, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get cluster: %w", err)
+	}
+	if !humioClusterSetOwnsCluster(hcs, &hc) {
+		return nil, fmt.Errorf("cluster already exists and is not part of this cluster set")
+	}
+
+	changed := hc.Annotations[humioClusterSetSpecHashAnnotation] != desired.Annotations[humioClusterSetSpecHashAnnotation]
+	if changed {
+		hc.Spec = desired.Spec
+	}
+	if hc.Labels == nil {
+		hc.Labels = map[string]string{}
+	}
+	for k, v := range desired.Labels {
+		if hc.Labels[k] != v {
+			hc.Labels[k] = v
+			changed = true
+		}
+	}
+	if hc.Annotations == nil {
+		hc.Annotations = map[string]string{}
+	}
+	for k, v := range desired.Annotations {
+		if hc.Annotations[k] != v {
+			hc.Annotations[k] = v
+			changed = true
+		}
+	}
+	if changed {
+		r.Log.Info(fmt.Sprintf("updating cluster %s/%s", hc.Namespace, hc.Name))
+		if err := r.Update(ctx, &hc); err != nil {
+			return nil, fmt.Errorf("unable to update cluster: %w", err)
+		}
+	}
+	return &hc, nil
+}
+
+// removeClusters deletes or releases the clusters of the cluster set which are no longer listed as instances,
+// depending on the deletion policy
+func (r *HumioClusterSetReconciler) removeClusters(ctx context.Context, hcs *humiov1alpha1.HumioClusterSet, desired []humiov1alpha1.HumioCluster) error {
+	wanted := map[types.NamespacedName]bool{}
+	for _, hc := range desired {
+		wanted[types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name}] = true
+	}
+	clusters, err := r.listClusters(ctx, hcs)
+	if err != nil {
+		return err
+	}
+	for i := range clusters {
+		if wanted[types.NamespacedName{Namespace: clusters[i].Namespace, Name: clusters[i].Name}] {
+			continue
+		}
+		if err := r.removeCluster(ctx, hcs, &clusters[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HumioClusterSetReconciler) finalize(ctx context.Context, hcs *humiov1alpha1.HumioClusterSet) error {
+	clusters, err := r.listClusters(ctx, hcs)
+	if err != nil {
+		return err
+	}
+	for i := range clusters {
+		if err := r.removeCluster(ctx, hcs, &clusters[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HumioClusterSetReconciler) removeCluster(ctx context.Context, hcs *humiov1alpha1.HumioClusterSet, hc *humiov1alpha1.HumioCluster) error {
+	if hcs.Spec.DeletionPolicy == humiov1alpha1.HumioClusterSetDeletionPolicyRetain {
+		r.Log.Info(fmt.Sprintf("releasing cluster %s/%s", hc.Namespace, hc.Name))
+		delete(hc.Labels, humioClusterSetLabelName)
+		delete(hc.Labels, humioClusterSetNamespaceLabelName)
+		delete(hc.Annotations, humioClusterSetSpecHashAnnotation)
+		return r.Update(ctx, hc)
+	}
+	r.Log.Info(fmt.Sprintf("deleting cluster %s/%s", hc.Namespace, hc.Name))
+	if err := r.Delete(ctx, hc); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// listClusters returns the clusters of the cluster set across all namespaces
+func (r *HumioClusterSetReconciler) listClusters(ctx context.Context, hcs *humiov1alpha1.HumioClusterSet) ([]humiov1alpha1.HumioCluster, error) {
+	var humioClusters humiov1alpha1.HumioClusterList
+	if err := r.List(ctx, &humioClusters, client.MatchingLabels{
+		humioClusterSetLabelName:          hcs.Name,
+		humioClusterSetNamespaceLabelName: hcs.Namespace,
+	}); err != nil {
+		return nil, err
+	}
+	return humioClusters.Items, nil
+}
+
+func humioClusterSetOwnsCluster(hcs *humiov1alpha1.HumioClusterSet, hc *humiov1alpha1.HumioCluster) bool {
+	return hc.Labels[humioClusterSetLabelName] == hcs.Name && hc.Labels[humioClusterSetNamespaceLabelName] == hcs.Namespace
+}
+
+// humioClusterSetStatus aggregates the state of the clusters of the cluster set
+func humioClusterSetStatus(clusters []humiov1alpha1.HumioClusterSetClusterStatus) humiov1alpha1.HumioClusterSetStatus {
+	status := humiov1alpha1.HumioClusterSetStatus{
+		State:    humiov1alpha1.HumioClusterSetStateRunning,
+		Clusters: clusters,
+	}
+	degraded := false
+	for _, cluster := range clusters {
+		switch {
+		case cluster.Message != "" || cluster.State == humiov1alpha1.HumioClusterStateConfigError:
+			degraded = true
+		case cluster.State == humiov1alpha1.HumioClusterStateRunning:
+			status.ReadyClusters++
+		}
+	}
+	switch {
+	case degraded:
+		status.State = humiov1alpha1.HumioClusterSetStateDegraded
+	case status.ReadyClusters < len(clusters):
+		status.State = humiov1alpha1.HumioClusterSetStatePending
+	}
+	return status
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioClusterSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioClusterSet{}).
+		Watches(&humiov1alpha1.HumioCluster{}, handler.EnqueueRequestsFromMapFunc(clusterSetForCluster)).
+		Complete(r)
+}
+
+// clusterSetForCluster returns a reconcile request for the HumioClusterSet managing the given cluster, so changes to
+// the state of the cluster are reflected right away
+func clusterSetForCluster(_ context.Context, hc client.Object) []reconcile.Request {
+	labels := hc.GetLabels()
+	if labels[humioClusterSetLabelName] == "" || labels[humioClusterSetNamespaceLabelName] == "" {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{
+			Namespace: labels[humioClusterSetNamespaceLabelName],
+			Name:      labels[humioClusterSetLabelName],
+		},
+	}}
+}
+
+func (r *HumioClusterSetReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioClusterSetStatus, hcs *humiov1alpha1.HumioClusterSet) error {
+	if reflect.DeepEqual(hcs.Status, status) {
+		return nil
+	}
+	if hcs.Status.State != status.State {
+		r.Log.Info(fmt.Sprintf("setting cluster set state to %s", status.State))
+	}
+	hcs.Status = status
+	return r.Status().Update(ctx, hcs)
+}
+
+func (r *HumioClusterSetReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
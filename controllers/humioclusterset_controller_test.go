@@ -0,0 +1,170 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestHumioClusterSetClusters(t *testing.T) {
+	hcs := &humiov1alpha1.HumioClusterSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "fleet", Namespace: "platform"},
+		Spec: humiov1alpha1.HumioClusterSetSpec{
+			Template: humiov1alpha1.HumioClusterSetTemplate{
+				Labels: map[string]string{"team": "platform"},
+				Spec: humiov1alpha1.HumioClusterSpec{
+					HumioNodeSpec: humiov1alpha1.HumioNodeSpec{
+						Image:     "humio/humio-core:1.82.1",
+						NodeCount: 3,
+					},
+					TargetReplicationFactor: 2,
+				},
+			},
+			Instances: []humiov1alpha1.HumioClusterSetInstance{
+				{Name: "eu"},
+				{
+					Name:      "us",
+					Namespace: "logscale-us",
+					Labels:    map[string]string{"region": "us"},
+					Overrides: &runtime.RawExtension{Raw: []byte(`{"nodeCount": 6}`)},
+				},
+			},
+		},
+	}
+
+	clusters, err := humioClusterSetClusters(hcs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %d", len(clusters))
+	}
+
+	eu, us := clusters[0], clusters[1]
+	if eu.Namespace != "platform" || us.Namespace != "logscale-us" {
+		t.Errorf("expected namespaces platform and logscale-us, got %s and %s", eu.Namespace, us.Namespace)
+	}
+	if eu.Spec.NodeCount != 3 || us.Spec.NodeCount != 6 {
+		t.Errorf("expected node counts 3 and 6, got %d and %d", eu.Spec.NodeCount, us.Spec.NodeCount)
+	}
+	if us.Spec.Image != "humio/humio-core:1.82.1" || us.Spec.TargetReplicationFactor != 2 {
+		t.Errorf("expected overrides to keep the rest of the template, got %+v", us.Spec)
+	}
+	if us.Labels["team"] != "platform" || us.Labels["region"] != "us" || us.Labels[humioClusterSetLabelName] != "fleet" || us.Labels[humioClusterSetNamespaceLabelName] != "platform" {
+		t.Errorf("unexpected labels %v", us.Labels)
+	}
+	if eu.Annotations[humioClusterSetSpecHashAnnotation] == us.Annotations[humioClusterSetSpecHashAnnotation] {
+		t.Errorf("expected clusters with different specs to have different hashes")
+	}
+	if hcs.Spec.Template.Spec.NodeCount != 3 {
+		t.Errorf("expected template to be left untouched, got node count %d", hcs.Spec.Template.Spec.NodeCount)
+	}
+}
+
+func TestHumioClusterSetClustersInvalid(t *testing.T) {
+	tt := []struct {
+		name      string
+		instances []humiov1alpha1.HumioClusterSetInstance
+	}{
+		{
+			name:      "duplicate instance",
+			instances: []humiov1alpha1.HumioClusterSetInstance{{Name: "eu"}, {Name: "eu", Namespace: "platform"}},
+		},
+		{
+			name: "unknown field in overrides",
+			instances: []humiov1alpha1.HumioClusterSetInstance{
+				{Name: "eu", Overrides: &runtime.RawExtension{Raw: []byte(`{"nodeCnt": 6}`)}},
+			},
+		},
+		{
+			name: "malformed overrides",
+			instances: []humiov1alpha1.HumioClusterSetInstance{
+				{Name: "eu", Overrides: &runtime.RawExtension{Raw: []byte(`{"nodeCount": `)}},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hcs := &humiov1alpha1.HumioClusterSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "fleet", Namespace: "platform"},
+				Spec:       humiov1alpha1.HumioClusterSetSpec{Instances: tc.instances},
+			}
+			if _, err := humioClusterSetClusters(hcs); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestHumioClusterSetStatus(t *testing.T) {
+	tt := []struct {
+		name     string
+		clusters []humiov1alpha1.HumioClusterSetClusterStatus
+		state    string
+		ready    int
+	}{
+		{
+			name: "all running",
+			clusters: []humiov1alpha1.HumioClusterSetClusterStatus{
+				{Name: "eu", State: humiov1alpha1.HumioClusterStateRunning},
+				{Name: "us", State: humiov1alpha1.HumioClusterStateRunning},
+			},
+			state: humiov1alpha1.HumioClusterSetStateRunning,
+			ready: 2,
+		},
+		{
+			name: "one upgrading",
+			clusters: []humiov1alpha1.HumioClusterSetClusterStatus{
+				{Name: "eu", State: humiov1alpha1.HumioClusterStateRunning},
+				{Name: "us", State: humiov1alpha1.HumioClusterStateUpgrading},
+			},
+			state: humiov1alpha1.HumioClusterSetStatePending,
+			ready: 1,
+		},
+		{
+			name: "one not managed",
+			clusters: []humiov1alpha1.HumioClusterSetClusterStatus{
+				{Name: "eu", State: humiov1alpha1.HumioClusterStateRunning},
+				{Name: "us", Message: "cluster already exists and is not part of this cluster set"},
+			},
+			state: humiov1alpha1.HumioClusterSetStateDegraded,
+			ready: 1,
+		},
+		{
+			name: "one misconfigured",
+			clusters: []humiov1alpha1.HumioClusterSetClusterStatus{
+				{Name: "eu", State: humiov1alpha1.HumioClusterStateConfigError},
+			},
+			state: humiov1alpha1.HumioClusterSetStateDegraded,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			status := humioClusterSetStatus(tc.clusters)
+			if status.State != tc.state {
+				t.Errorf("expected state %s, got %s", tc.state, status.State)
+			}
+			if status.ReadyClusters != tc.ready {
+				t.Errorf("expected %d ready clusters, got %d", tc.ready, status.ReadyClusters)
+			}
+		})
+	}
+}
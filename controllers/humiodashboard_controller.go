@@ -0,0 +1,308 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// HumioDashboardReconciler reconciles a HumioDashboard object
+type HumioDashboardReconciler struct {
+	client.Client
+	BaseLogger  logr.Logger
+	Log         logr.Logger
+	HumioClient humio.Client
+	Namespace   string
+	Recorder    record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiodashboards,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiodashboards/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiodashboards/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+func (r *HumioDashboardReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioDashboard")
+
+	hd := &humiov1alpha1.HumioDashboard{}
+	if err := r.Get(ctx, req.NamespacedName, hd); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hd.UID)
+
+	cluster, err := helpers.NewCluster(ctx, r, hd.Spec.ManagedClusterName, hd.Spec.ExternalClusterName, hd.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hd.Namespace, hd.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		if err := r.setState(ctx, humiov1alpha1.HumioDashboardStateClusterUnavailable, "", hd); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set dashboard state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		r.Log.Error(err, "unable to obtain humio client config")
+		if err := r.setState(ctx, humiov1alpha1.HumioDashboardStateConfigError, "unable to obtain humio client config", hd); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set dashboard state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	if request, requested := resyncRequested(hd, hd.Status.LastResyncRequest); requested {
+		r.Log.Info(fmt.Sprintf("resyncing dashboard as requested by annotation %s=%s", triggerResyncAnnotation, request))
+		r.HumioClient.InvalidateReadCache(cluster.Config())
+	}
+
+	if hd.GetDeletionTimestamp() != nil {
+		r.Log.Info("Dashboard marked to be deleted")
+		if helpers.ContainsElement(hd.GetFinalizers(), humioFinalizer) {
+			r.Log.Info("Deleting dashboard")
+			if err := r.HumioClient.DeleteDashboard(cluster.Config(), req, hd); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "Delete dashboard returned error")
+			}
+			r.Log.Info("Dashboard deleted. Removing finalizer")
+			hd.SetFinalizers(helpers.RemoveElement(hd.GetFinalizers(), humioFinalizer))
+			if err := r.Update(ctx, hd); err != nil {
+				return reconcile.Result{}, err
+			}
+			r.Log.Info("Finalizer removed successfully")
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if !helpers.ContainsElement(hd.GetFinalizers(), humioFinalizer) {
+		r.Log.Info("Finalizer not present, adding finalizer to dashboard")
+		hd.SetFinalizers(append(hd.GetFinalizers(), humioFinalizer))
+		if err := r.Update(ctx, hd); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{Requeue: true}, nil
+	}
+
+	definition, err := r.dashboardDefinition(ctx, hd)
+	if err != nil {
+		r.Log.Error(err, "invalid dashboard template")
+		if err := r.setState(ctx, humiov1alpha1.HumioDashboardStateConfigError, err.Error(), hd); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set dashboard state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	now := time.Now()
+	status, err := r.reconcileDashboard(cluster.Config(), req, hd, definition, now)
+	if err != nil {
+		state := humiov1alpha1.HumioDashboardStateUnknown
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			state = humiov1alpha1.HumioDashboardStateClusterUnavailable
+		}
+		if err := r.setState(ctx, state, "", hd); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set dashboard state")
+		}
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not reconcile dashboard")
+	}
+	status.ClusterName = syncClusterName(hd.Spec.ManagedClusterName, hd.Spec.ExternalClusterName)
+	status.LastSyncTime = syncTime(status.LastSyncTime, now)
+	status.LastResyncRequest = hd.Annotations[triggerResyncAnnotation]
+	if err := r.setStatus(ctx, status, hd); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set dashboard status")
+	}
+
+	requeue := requeueInterval(hd, time.Second*15)
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+	return reconcile.Result{RequeueAfter: requeue}, nil
+}
+
+// dashboardsForTemplateConfigMap returns a reconcile request for every HumioDashboard which reads its template from
+// the given config map, so changes to shared templates are applied right away
+func (r *HumioDashboardReconciler) dashboardsForTemplateConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	var humioDashboards humiov1alpha1.HumioDashboardList
+	if err := r.List(ctx, &humioDashboards, client.InNamespace(configMap.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list dashboards")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, hd := range humioDashboards.Items {
+		if hd.Spec.TemplateFrom != nil && hd.Spec.TemplateFrom.ConfigMapKeyRef != nil && hd.Spec.TemplateFrom.ConfigMapKeyRef.Name == configMap.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: hd.Namespace, Name: hd.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioDashboardReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioDashboard{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioDashboardList{}))).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.dashboardsForTemplateConfigMap)).
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioDashboard{}, r))
+}
+
+// reconcileDashboard creates the dashboard from the definition, and recreates it when the definition changes or the
+// dashboard is changed outside the operator. Humio cannot update a dashboard from a template, so the dashboard is
+// replaced, which gives it a new ID. It returns the status describing the dashboard.
+func (r *HumioDashboardReconciler) reconcileDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard, definition string, now time.Time) (humiov1alpha1.HumioDashboardStatus, error) {
+	status := *hd.Status.DeepCopy()
+	status.State = humiov1alpha1.HumioDashboardStateExists
+	status.Message = ""
+	definitionHash := helpers.AsSHA256(definition)
+
+	curDashboard, err := r.HumioClient.GetDashboard(config, req, hd)
+	if err != nil {
+		return status, fmt.Errorf("could not check if dashboard exists: %w", err)
+	}
+	if curDashboard.ID != "" {
+		templateHash := helpers.AsSHA256(curDashboard.TemplateYaml)
+		switch {
+		case status.DefinitionHash != definitionHash:
+			r.Log.Info("dashboard definition changed, replacing dashboard")
+		case status.TemplateHash == "":
+			status.ID = curDashboard.ID
+			status.TemplateHash = templateHash
+			applyDriftOutcome(r.Recorder, hd, driftInSync, "", nil, &status.Conditions, hd.Generation)
+			return status, nil
+		case status.TemplateHash != templateHash && hd.Spec.DriftPolicy == humiov1alpha1.HumioDriftPolicyWarn:
+			r.Log.Info("dashboard was changed outside the operator, leaving the changes in place because of the drift policy")
+			status.ID = curDashboard.ID
+			applyDriftOutcome(r.Recorder, hd, driftIgnore, "", nil, &status.Conditions, hd.Generation)
+			return status, nil
+		case status.TemplateHash != templateHash:
+			r.Log.Info("dashboard was changed outside the operator, replacing dashboard")
+			driftTime := metav1.NewTime(now)
+			status.LastDriftTime = &driftTime
+		default:
+			status.ID = curDashboard.ID
+			applyDriftOutcome(r.Recorder, hd, driftInSync, "", nil, &status.Conditions, hd.Generation)
+			return status, nil
+		}
+		if err := r.HumioClient.DeleteDashboard(config, req, hd); err != nil {
+			return status, fmt.Errorf("could not delete dashboard: %w", err)
+		}
+	}
+
+	r.Log.Info("creating dashboard")
+	addedDashboard, err := r.HumioClient.AddDashboard(config, req, hd, definition)
+	if err != nil {
+		return status, fmt.Errorf("could not create dashboard: %w", err)
+	}
+	status.ID = addedDashboard.ID
+	status.DefinitionHash = definitionHash
+	applyDriftOutcome(r.Recorder, hd, driftApply, "", nil, &status.Conditions, hd.Generation)
+	// The template Humio exports for the new dashboard is recorded on the next reconcile
+	status.TemplateHash = ""
+	return status, nil
+}
+
+// dashboardDefinition returns the template of the dashboard rendered with its parameters
+func (r *HumioDashboardReconciler) dashboardDefinition(ctx context.Context, hd *humiov1alpha1.HumioDashboard) (string, error) {
+	hasTemplateFrom := hd.Spec.TemplateFrom != nil && hd.Spec.TemplateFrom.ConfigMapKeyRef != nil
+	if (hd.Spec.Template == "") == !hasTemplateFrom {
+		return "", fmt.Errorf("exactly one of template and templateFrom.configMapKeyRef must be set")
+	}
+	dashboardTemplate := hd.Spec.Template
+	if hasTemplateFrom {
+		ref := hd.Spec.TemplateFrom.ConfigMapKeyRef
+		configMap, err := kubernetes.GetConfigMap(ctx, r, ref.Name, hd.Namespace)
+		if err != nil {
+			return "", fmt.Errorf("unable to get configMap %s holding the dashboard template: %w", ref.Name, err)
+		}
+		value, ok := configMap.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("key %s was not found in configMap %s", ref.Key, ref.Name)
+		}
+		dashboardTemplate = value
+	}
+	return renderHumioDashboardTemplate(dashboardTemplate, hd.Spec.Parameters)
+}
+
+// renderHumioDashboardTemplate substitutes the parameters into the dashboard template, and verifies the result is
+// valid YAML. Parameters referenced by the template must be set.
+func renderHumioDashboardTemplate(dashboardTemplate string, parameters map[string]string) (string, error) {
+	tmpl, err := template.New("dashboard").Option("missingkey=error").Parse(dashboardTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid dashboard template: %w", err)
+	}
+	if parameters == nil {
+		parameters = map[string]string{}
+	}
+	var definition bytes.Buffer
+	if err := tmpl.Execute(&definition, parameters); err != nil {
+		return "", fmt.Errorf("invalid dashboard template: %w", err)
+	}
+	if _, err := utilyaml.ToJSON(definition.Bytes()); err != nil {
+		return "", fmt.Errorf("dashboard template is not valid YAML: %w", err)
+	}
+	return definition.String(), nil
+}
+
+func (r *HumioDashboardReconciler) setState(ctx context.Context, state, message string, hd *humiov1alpha1.HumioDashboard) error {
+	status := *hd.Status.DeepCopy()
+	status.State = state
+	status.Message = message
+	return r.setStatus(ctx, status, hd)
+}
+
+func (r *HumioDashboardReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioDashboardStatus, hd *humiov1alpha1.HumioDashboard) error {
+	if reflect.DeepEqual(hd.Status, status) {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting dashboard state to %s", status.State))
+	hd.Status = status
+	return r.Status().Update(ctx, hd)
+}
+
+func (r *HumioDashboardReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
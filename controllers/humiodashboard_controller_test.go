@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRenderHumioDashboardTemplate(t *testing.T) {
+	tt := []struct {
+		name          string
+		template      string
+		parameters    map[string]string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:       "parameters substituted",
+			template:   "name: overview\nwidgets:\n  errors:\n    queryString: env = {{ .environment }}\n",
+			parameters: map[string]string{"environment": "production"},
+			expected:   "name: overview\nwidgets:\n  errors:\n    queryString: env = production\n",
+		},
+		{
+			name:     "json template",
+			template: `{"name": "overview", "widgets": {}}`,
+			expected: `{"name": "overview", "widgets": {}}`,
+		},
+		{
+			name:          "missing parameter",
+			template:      "name: {{ .environment }}\n",
+			expectedError: true,
+		},
+		{
+			name:          "invalid yaml",
+			template:      "name: [overview\n",
+			expectedError: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			definition, err := renderHumioDashboardTemplate(tc.template, tc.parameters)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %t, got %v", tc.expectedError, err)
+			}
+			if definition != tc.expected {
+				t.Errorf("expected definition %q, got %q", tc.expected, definition)
+			}
+		})
+	}
+}
+
+func TestDashboardDefinitionFromConfigMap(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "templates", Namespace: "default"},
+		Data:       map[string]string{"overview.yaml": "name: {{ .environment }}\n"},
+	}
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	r := &HumioDashboardReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build(),
+		Log:    logr.Discard(),
+	}
+	hd := &humiov1alpha1.HumioDashboard{
+		ObjectMeta: metav1.ObjectMeta{Name: "overview", Namespace: "default"},
+		Spec: humiov1alpha1.HumioDashboardSpec{
+			TemplateFrom: &humiov1alpha1.HumioDashboardTemplateSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "templates"},
+					Key:                  "overview.yaml",
+				},
+			},
+			Parameters: map[string]string{"environment": "staging"},
+		},
+	}
+
+	definition, err := r.dashboardDefinition(context.Background(), hd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if definition != "name: staging\n" {
+		t.Errorf("unexpected definition %q", definition)
+	}
+
+	hd.Spec.Template = "name: inline\n"
+	if _, err := r.dashboardDefinition(context.Background(), hd); err == nil {
+		t.Errorf("expected setting both template and templateFrom to be rejected")
+	}
+}
+
+func TestReconcileDashboard(t *testing.T) {
+	humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+	r := &HumioDashboardReconciler{
+		HumioClient: humioClient,
+		Log:         logr.Discard(),
+	}
+	hd := &humiov1alpha1.HumioDashboard{
+		Spec: humiov1alpha1.HumioDashboardSpec{Name: "overview", ViewName: "view"},
+	}
+	config := &humioapi.Config{}
+	req := reconcile.Request{}
+	now := time.Now().Truncate(time.Second)
+
+	status, err := r.reconcileDashboard(config, req, hd, "name: overview\n", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.ID == "" || status.DefinitionHash == "" || status.TemplateHash != "" {
+		t.Fatalf("expected dashboard to be created, got %+v", status)
+	}
+	hd.Status = status
+
+	// The exported template is recorded once the dashboard exists
+	status, err = r.reconcileDashboard(config, req, hd, "name: overview\n", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.ID != hd.Status.ID || status.TemplateHash == "" {
+		t.Fatalf("expected exported template to be recorded, got %+v", status)
+	}
+	hd.Status = status
+
+	// Changes made in the Humio UI are reverted
+	humioClient.SetDashboardTemplate("view", "overview", "name: overview\nwidgets: {}\n")
+	status, err = r.reconcileDashboard(config, req, hd, "name: overview\n", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.ID == hd.Status.ID || status.LastDriftTime == nil || !status.LastDriftTime.Time.Equal(now) {
+		t.Errorf("expected dashboard changed outside the operator to be replaced, got %+v", status)
+	}
+	hd.Status = status
+	dashboard, _ := humioClient.GetDashboard(config, req, hd)
+	if dashboard.TemplateYaml != "name: overview\n" {
+		t.Errorf("expected dashboard to be recreated from the definition, got %q", dashboard.TemplateYaml)
+	}
+
+	// Changing the definition replaces the dashboard
+	status, err = r.reconcileDashboard(config, req, hd, "name: overview\nwidgets: {}\n", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.ID == hd.Status.ID || status.DefinitionHash == hd.Status.DefinitionHash {
+		t.Errorf("expected dashboard to be replaced after the definition changed, got %+v", status)
+	}
+}
@@ -0,0 +1,645 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8sclientset "k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	humioDiagnosticsBundleDefaultPVCImage     = "busybox:1.36.1"
+	humioDiagnosticsBundleDefaultBucketImage  = awsCLIImage
+	humioDiagnosticsBundleDefaultLogTailLines = 1000
+	// humioDiagnosticsBundleMaxSize keeps the files of the bundle below the size limit of the secret they are passed
+	// to the job in
+	humioDiagnosticsBundleMaxSize = 900 * 1024
+	humioDiagnosticsBundleLabel   = "humio.com/diagnostics-bundle"
+	humioDiagnosticsBundlePath    = "/diagnostics"
+	humioDiagnosticsBundlePVCPath = "/bundle"
+	diagnosticsRedactedValue      = "<redacted>"
+)
+
+// humioDiagnosticsBundleScript stores the files of the bundle mounted from the secret. The secret volume holds hidden
+// directories next to the files, which are skipped. The size of the bundle is reported using the termination message
+// of the container.
+const humioDiagnosticsBundleScript = `set -e
+cd /diagnostics
+case "$BUNDLE_LOCATION" in
+  s3://*) aws ${AWS_ENDPOINT_URL:+--endpoint-url "$AWS_ENDPOINT_URL"} s3 cp --recursive --exclude '..*' . "$BUNDLE_LOCATION" ;;
+  *) mkdir -p "$BUNDLE_LOCATION" && cp -L * "$BUNDLE_LOCATION" ;;
+esac
+cat * | wc -c | tr -d ' ' > /dev/termination-log
+`
+
+// diagnosticsBundleResourceKinds are the kinds of custom resources of the cluster included in diagnostics bundles
+var diagnosticsBundleResourceKinds = []string{
+	"HumioCluster",
+	"HumioRepository",
+	"HumioParser",
+	"HumioIngestToken",
+	"HumioView",
+	"HumioAction",
+	"HumioAlert",
+}
+
+// diagnosticsSensitiveNameFragments identify fields and environment variables holding credentials, which are
+// redacted from diagnostics bundles
+var diagnosticsSensitiveNameFragments = []string{
+	"password",
+	"secret",
+	"token",
+	"credential",
+	"apikey",
+	"accesskey",
+	"privatekey",
+	"encryptionkey",
+	"geniekey",
+	"routingkey",
+}
+
+// HumioDiagnosticsBundleReconciler reconciles a HumioDiagnosticsBundle object
+type HumioDiagnosticsBundleReconciler struct {
+	client.Client
+	// Clientset is used to get the logs of the Humio pods, which are not available through the controller-runtime
+	// client. Logs are left out of the bundle if it is nil.
+	Clientset k8sclientset.Interface
+	// OperatorLogs holds the recent logs of the operator. They are left out of the bundle if it is nil.
+	OperatorLogs *helpers.RecentLogs
+	HumioClient  humio.Client
+	BaseLogger   logr.Logger
+	Log          logr.Logger
+	Namespace    string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiodiagnosticsbundles,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiodiagnosticsbundles/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiodiagnosticsbundles/finalizers,verbs=update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
+
+func (r *HumioDiagnosticsBundleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioDiagnosticsBundle")
+
+	// Fetch the HumioDiagnosticsBundle instance
+	hdb := &humiov1alpha1.HumioDiagnosticsBundle{}
+	err := r.Get(ctx, req.NamespacedName, hdb)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hdb.UID)
+
+	status := *hdb.Status.DeepCopy()
+	switch status.State {
+	case humiov1alpha1.HumioDiagnosticsBundleStateSucceeded, humiov1alpha1.HumioDiagnosticsBundleStateFailed:
+		// Diagnostics bundles are collected once. A new bundle is collected by creating a new HumioDiagnosticsBundle.
+		return reconcile.Result{}, nil
+	case humiov1alpha1.HumioDiagnosticsBundleStateRunning:
+		if err := r.updateBundleState(ctx, hdb, &status); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to update state of diagnostics bundle")
+		}
+	default:
+		status.Message = ""
+		if err := r.startBundle(ctx, hdb, &status, metav1.Now()); err != nil {
+			r.Log.Error(err, "unable to collect diagnostics bundle")
+			status.State = humiov1alpha1.HumioDiagnosticsBundleStateConfigError
+			status.Message = err.Error()
+			if err := r.setStatus(ctx, status, hdb); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set diagnostics bundle status")
+			}
+			return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+		}
+	}
+
+	if err := r.setStatus(ctx, status, hdb); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set diagnostics bundle status")
+	}
+	if status.State == humiov1alpha1.HumioDiagnosticsBundleStateRunning {
+		return reconcile.Result{RequeueAfter: time.Second * 10}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+func validateHumioDiagnosticsBundle(hdb *humiov1alpha1.HumioDiagnosticsBundle) error {
+	if (hdb.Spec.Target.PersistentVolumeClaimName == "") == (hdb.Spec.Target.Bucket == nil) {
+		return fmt.Errorf("exactly one of target.persistentVolumeClaimName and target.bucket must be specified")
+	}
+	if hdb.Spec.Target.Bucket != nil && hdb.Spec.Target.Bucket.Bucket == "" {
+		return fmt.Errorf("target.bucket.bucket must be specified")
+	}
+	return nil
+}
+
+// startBundle collects the diagnostics of the cluster into a secret, and creates the job which stores them in the
+// target of the bundle
+func (r *HumioDiagnosticsBundleReconciler) startBundle(ctx context.Context, hdb *humiov1alpha1.HumioDiagnosticsBundle, status *humiov1alpha1.HumioDiagnosticsBundleStatus, now metav1.Time) error {
+	if err := validateHumioDiagnosticsBundle(hdb); err != nil {
+		return err
+	}
+	var hc humiov1alpha1.HumioCluster
+	if err := r.Get(ctx, types.NamespacedName{Namespace: hdb.Namespace, Name: hdb.Spec.ManagedClusterName}, &hc); err != nil {
+		return fmt.Errorf("unable to get cluster %s: %w", hdb.Spec.ManagedClusterName, err)
+	}
+
+	name := newJobName(hdb.Name, now)
+	files := r.collectDiagnostics(ctx, hdb, &hc)
+	limitDiagnosticsBundleSize(files, humioDiagnosticsBundleMaxSize)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: hdb.Namespace,
+			Labels:    map[string]string{humioDiagnosticsBundleLabel: hdb.Name, "app.kubernetes.io/managed-by": "humio-operator"},
+		},
+		Data: files,
+	}
+	if err := controllerutil.SetControllerReference(hdb, secret, r.Scheme()); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, secret); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("unable to store diagnostics: %w", err)
+	}
+
+	job := constructHumioDiagnosticsBundleJob(hdb, name)
+	if err := controllerutil.SetControllerReference(hdb, job, r.Scheme()); err != nil {
+		return err
+	}
+	r.Log.Info(fmt.Sprintf("creating diagnostics bundle job %s storing %d files", job.Name, len(files)))
+	if err := r.Create(ctx, job); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("unable to create diagnostics bundle job: %w", err)
+	}
+
+	status.State = humiov1alpha1.HumioDiagnosticsBundleStateRunning
+	status.JobName = name
+	status.Location = humioDiagnosticsBundleLocation(hdb, name)
+	status.Files = nil
+	for fileName := range files {
+		status.Files = append(status.Files, fileName)
+	}
+	sort.Strings(status.Files)
+	return nil
+}
+
+// collectDiagnostics returns the files of the diagnostics bundle of the cluster. Diagnostics which cannot be collected
+// are reported in errors.txt, so a bundle is collected even when the cluster is unhealthy.
+func (r *HumioDiagnosticsBundleReconciler) collectDiagnostics(ctx context.Context, hdb *humiov1alpha1.HumioDiagnosticsBundle, hc *humiov1alpha1.HumioCluster) map[string][]byte {
+	files := map[string][]byte{}
+	var collectErrors []string
+	addError := func(err error, msg string) {
+		r.Log.Error(err, msg)
+		collectErrors = append(collectErrors, fmt.Sprintf("%s: %s", msg, err))
+	}
+	addJSON := func(fileName string, value interface{}) {
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			addError(err, fmt.Sprintf("unable to encode %s", fileName))
+			return
+		}
+		files[fileName] = data
+	}
+
+	for _, kind := range diagnosticsBundleResourceKinds {
+		resources, err := r.listClusterResources(ctx, hc, kind)
+		if err != nil {
+			addError(err, fmt.Sprintf("unable to list %s resources", kind))
+			continue
+		}
+		addJSON(fmt.Sprintf("%ss.json", strings.ToLower(kind)), resources)
+	}
+
+	pods, err := kubernetes.ListPods(ctx, r, hc.Namespace, kubernetes.MatchingLabelsForHumio(hc.Name))
+	if err != nil {
+		addError(err, "unable to list pods")
+	}
+	podsValue, err := redactedDiagnosticsValue(pods)
+	if err != nil {
+		addError(err, "unable to redact pods")
+	} else {
+		addJSON("pods.json", podsValue)
+	}
+
+	involvedObjects := map[string]bool{hc.Name: true}
+	for _, pod := range pods {
+		involvedObjects[pod.Name] = true
+	}
+	var events corev1.EventList
+	if err := r.List(ctx, &events, client.InNamespace(hc.Namespace)); err != nil {
+		addError(err, "unable to list events")
+	}
+	var clusterEvents []corev1.Event
+	for _, event := range events.Items {
+		if involvedObjects[event.InvolvedObject.Name] {
+			clusterEvents = append(clusterEvents, event)
+		}
+	}
+	addJSON("events.json", clusterEvents)
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name}}
+	var config *humioapi.Config
+	cluster, err := helpers.NewCluster(ctx, r, hc.Name, "", hc.Namespace, helpers.UseCertManager(), true)
+	if err == nil && (cluster == nil || cluster.Config() == nil) {
+		err = fmt.Errorf("cluster config is not available")
+	}
+	if err != nil {
+		addError(err, "unable to obtain humio client config")
+	} else {
+		config = cluster.Config()
+		clusterState, err := r.HumioClient.GetClusters(config, req)
+		if err != nil {
+			addError(err, "unable to get cluster state")
+		} else {
+			addJSON("cluster.json", clusterState)
+		}
+	}
+
+	logTailLines := hdb.Spec.LogTailLines
+	if logTailLines <= 0 {
+		logTailLines = humioDiagnosticsBundleDefaultLogTailLines
+	}
+	for _, pod := range pods {
+		if r.Clientset != nil {
+			logs, err := r.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container: HumioContainerName,
+				TailLines: &logTailLines,
+			}).DoRaw(ctx)
+			if err != nil {
+				addError(err, fmt.Sprintf("unable to get logs of pod %s", pod.Name))
+			} else {
+				files[fmt.Sprintf("logs-%s.log", pod.Name)] = logs
+			}
+		}
+
+		if config == nil || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		// The thread dump is taken by the node serving the request, so each pod is contacted directly
+		podConfig := *config
+		podURL := *config.Address
+		podURL.Host = fmt.Sprintf("%s.%s.%s:%d", pod.Name, headlessServiceName(hc.Name), hc.Namespace, HumioPort)
+		podConfig.Address = &podURL
+		threadDump, err := r.HumioClient.GetThreadDump(&podConfig, req)
+		if err != nil {
+			addError(err, fmt.Sprintf("unable to get thread dump of pod %s", pod.Name))
+			continue
+		}
+		files[fmt.Sprintf("threaddump-%s.txt", pod.Name)] = []byte(threadDump)
+	}
+
+	if lines := r.OperatorLogs.Lines(); len(lines) > 0 {
+		files["operator.log"] = []byte(strings.Join(lines, "\n") + "\n")
+	}
+	if len(collectErrors) > 0 {
+		files["errors.txt"] = []byte(strings.Join(collectErrors, "\n") + "\n")
+	}
+	return files
+}
+
+// listClusterResources returns the redacted custom resources of the given kind which belong to the cluster
+func (r *HumioDiagnosticsBundleReconciler) listClusterResources(ctx context.Context, hc *humiov1alpha1.HumioCluster, kind string) ([]interface{}, error) {
+	var list unstructured.UnstructuredList
+	list.SetGroupVersionKind(humiov1alpha1.GroupVersion.WithKind(kind + "List"))
+	if err := r.List(ctx, &list, client.InNamespace(hc.Namespace)); err != nil {
+		return nil, err
+	}
+	var resources []interface{}
+	for _, item := range list.Items {
+		if kind == "HumioCluster" && item.GetName() != hc.Name {
+			continue
+		}
+		if managedClusterName, _, _ := unstructured.NestedString(item.Object, "spec", "managedClusterName"); kind != "HumioCluster" && managedClusterName != hc.Name {
+			continue
+		}
+		// The last applied configuration holds the spec without redaction
+		annotations := item.GetAnnotations()
+		delete(annotations, corev1.LastAppliedConfigAnnotation)
+		item.SetAnnotations(annotations)
+		item.SetManagedFields(nil)
+		if kind == "HumioAction" {
+			redactHumioActionProperties(item.Object)
+		}
+		resources = append(resources, redactDiagnostics(item.Object))
+	}
+	return resources, nil
+}
+
+// redactHumioActionProperties redacts the properties of an action, as the properties of most action types hold
+// credentials such as webhook URLs and API keys
+func redactHumioActionProperties(action map[string]interface{}) {
+	spec, ok := action["spec"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for key := range spec {
+		switch key {
+		case "name", "managedClusterName", "externalClusterName", "viewName":
+		default:
+			spec[key] = diagnosticsRedactedValue
+		}
+	}
+}
+
+// redactedDiagnosticsValue returns the value as generic JSON values with credentials redacted
+func redactedDiagnosticsValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return redactDiagnostics(generic), nil
+}
+
+// redactDiagnostics redacts string fields with names which look like credentials, and the values of environment
+// variables with such names. References to secrets are kept.
+func redactDiagnostics(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if name, ok := v["name"].(string); ok && isSensitiveDiagnosticsName(name) {
+			if _, ok := v["value"].(string); ok {
+				v["value"] = diagnosticsRedactedValue
+			}
+		}
+		for key, field := range v {
+			if _, ok := field.(string); ok {
+				lowerKey := strings.ToLower(key)
+				if isSensitiveDiagnosticsName(key) && !strings.HasSuffix(lowerKey, "name") && !strings.HasSuffix(lowerKey, "ref") {
+					v[key] = diagnosticsRedactedValue
+				}
+				continue
+			}
+			v[key] = redactDiagnostics(field)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redactDiagnostics(v[i])
+		}
+		return v
+	}
+	return value
+}
+
+func isSensitiveDiagnosticsName(name string) bool {
+	normalized := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+	for _, fragment := range diagnosticsSensitiveNameFragments {
+		if strings.Contains(normalized, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitDiagnosticsBundleSize truncates the largest files of the bundle until the bundle fits within the given size.
+// The end of each file is kept, as it holds the most recent log lines.
+func limitDiagnosticsBundleSize(files map[string][]byte, maxSize int) {
+	const marker = "[truncated]\n"
+	for {
+		total := 0
+		largest := ""
+		for fileName, data := range files {
+			total += len(data)
+			if largest == "" || len(data) > len(files[largest]) || (len(data) == len(files[largest]) && fileName < largest) {
+				largest = fileName
+			}
+		}
+		if total <= maxSize || len(files[largest]) <= len(marker) {
+			return
+		}
+		data := files[largest]
+		cut := total - maxSize + len(marker)
+		if cut > len(data) {
+			cut = len(data)
+		}
+		files[largest] = append([]byte(marker), data[cut:]...)
+	}
+}
+
+// humioDiagnosticsBundleLocation returns where the bundle is stored, as reported in the status
+func humioDiagnosticsBundleLocation(hdb *humiov1alpha1.HumioDiagnosticsBundle, name string) string {
+	if bucket := hdb.Spec.Target.Bucket; bucket != nil {
+		key := strings.Trim(bucket.Prefix, "/")
+		if key != "" {
+			key += "/"
+		}
+		return fmt.Sprintf("s3://%s/%s%s/", bucket.Bucket, key, name)
+	}
+	return fmt.Sprintf("pvc://%s/%s/", hdb.Spec.Target.PersistentVolumeClaimName, name)
+}
+
+func constructHumioDiagnosticsBundleJob(hdb *humiov1alpha1.HumioDiagnosticsBundle, name string) *batchv1.Job {
+	image := hdb.Spec.Image
+	volumes := []corev1.Volume{
+		{
+			Name:         "diagnostics",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: name}},
+		},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      "diagnostics",
+			MountPath: humioDiagnosticsBundlePath,
+			ReadOnly:  true,
+		},
+	}
+	var env []corev1.EnvVar
+	var envFrom []corev1.EnvFromSource
+	if bucket := hdb.Spec.Target.Bucket; bucket != nil {
+		if image == "" {
+			image = humioDiagnosticsBundleDefaultBucketImage
+		}
+		env = append(env, corev1.EnvVar{Name: "BUNDLE_LOCATION", Value: humioDiagnosticsBundleLocation(hdb, name)})
+		if bucket.Region != "" {
+			env = append(env, corev1.EnvVar{Name: "AWS_REGION", Value: bucket.Region})
+		}
+		if bucket.Endpoint != "" {
+			env = append(env, corev1.EnvVar{Name: "AWS_ENDPOINT_URL", Value: bucket.Endpoint})
+		}
+		if bucket.CredentialsSecretName != "" {
+			envFrom = append(envFrom, corev1.EnvFromSource{
+				SecretRef: &corev1.SecretEnvSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: bucket.CredentialsSecretName},
+				},
+			})
+		}
+	} else {
+		if image == "" {
+			image = humioDiagnosticsBundleDefaultPVCImage
+		}
+		env = append(env, corev1.EnvVar{Name: "BUNDLE_LOCATION", Value: fmt.Sprintf("%s/%s", humioDiagnosticsBundlePVCPath, name)})
+		volumes = append(volumes, corev1.Volume{
+			Name: "bundle",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: hdb.Spec.Target.PersistentVolumeClaimName},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "bundle",
+			MountPath: humioDiagnosticsBundlePVCPath,
+		})
+	}
+
+	backoffLimit := int32(2)
+	labels := map[string]string{
+		humioDiagnosticsBundleLabel:    hdb.Name,
+		"app.kubernetes.io/managed-by": "humio-operator",
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: hdb.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: hdb.Spec.ServiceAccountName,
+					Containers: []corev1.Container{
+						{
+							Name:         "diagnostics",
+							Image:        image,
+							Command:      []string{"/bin/sh", "-c", humioDiagnosticsBundleScript},
+							Env:          env,
+							EnvFrom:      envFrom,
+							VolumeMounts: volumeMounts,
+						},
+					},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+}
+
+// updateBundleState updates the state of the bundle from its job. The secret holding the diagnostics is deleted once
+// the job has finished, as it holds logs of the cluster.
+func (r *HumioDiagnosticsBundleReconciler) updateBundleState(ctx context.Context, hdb *humiov1alpha1.HumioDiagnosticsBundle, status *humiov1alpha1.HumioDiagnosticsBundleStatus) error {
+	var job batchv1.Job
+	err := r.Get(ctx, types.NamespacedName{Namespace: hdb.Namespace, Name: status.JobName}, &job)
+	if k8serrors.IsNotFound(err) {
+		status.State = humiov1alpha1.HumioDiagnosticsBundleStateFailed
+		status.Message = "diagnostics bundle job was deleted before it completed"
+		return r.deleteBundleSecret(ctx, hdb, status.JobName)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			status.State = humiov1alpha1.HumioDiagnosticsBundleStateSucceeded
+			status.CompletionTime = job.Status.CompletionTime
+			size, err := jobReportedSize(ctx, r, &job)
+			if err != nil {
+				r.Log.Error(err, "unable to list diagnostics bundle pods")
+			}
+			status.SizeBytes = size
+			r.Log.Info(fmt.Sprintf("diagnostics bundle stored at %s", status.Location))
+		case batchv1.JobFailed:
+			completionTime := condition.LastTransitionTime
+			status.State = humiov1alpha1.HumioDiagnosticsBundleStateFailed
+			status.CompletionTime = &completionTime
+			status.Message = condition.Message
+			r.Log.Info(fmt.Sprintf("diagnostics bundle job failed: %s", condition.Message))
+		default:
+			continue
+		}
+		return r.deleteBundleSecret(ctx, hdb, status.JobName)
+	}
+	return nil
+}
+
+func (r *HumioDiagnosticsBundleReconciler) deleteBundleSecret(ctx context.Context, hdb *humiov1alpha1.HumioDiagnosticsBundle, name string) error {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: hdb.Namespace, Name: name}}
+	if err := r.Delete(ctx, secret); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioDiagnosticsBundleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioDiagnosticsBundle{}).
+		Owns(&batchv1.Job{}).
+		Complete(withReconcileContext(r))
+}
+
+func (r *HumioDiagnosticsBundleReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioDiagnosticsBundleStatus, hdb *humiov1alpha1.HumioDiagnosticsBundle) error {
+	if reflect.DeepEqual(hdb.Status, status) {
+		return nil
+	}
+	if hdb.Status.State != status.State {
+		r.Log.Info(fmt.Sprintf("setting diagnostics bundle state to %s", status.State))
+	}
+	hdb.Status = status
+	return r.Status().Update(ctx, hdb)
+}
+
+func (r *HumioDiagnosticsBundleReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRedactDiagnostics(t *testing.T) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(`{
+		"spec": {
+			"password": "hunter2",
+			"tokenSecretName": "ingest-token",
+			"apiKeyRef": "api-key",
+			"name": "repository",
+			"environmentVariables": [
+				{"name": "S3_STORAGE_SECRET_KEY", "value": "secret"},
+				{"name": "HUMIO_MEMORY_OPTS", "value": "-Xmx2g"},
+				{"name": "ENCRYPTION_KEY", "valueFrom": {"secretKeyRef": {"name": "storage", "key": "key"}}}
+			]
+		}
+	}`), &value); err != nil {
+		t.Fatal(err)
+	}
+	spec := redactDiagnostics(value).(map[string]interface{})["spec"].(map[string]interface{})
+
+	if spec["password"] != diagnosticsRedactedValue {
+		t.Errorf("expected password to be redacted, got %v", spec["password"])
+	}
+	for _, key := range []string{"tokenSecretName", "apiKeyRef", "name"} {
+		if spec[key] == diagnosticsRedactedValue {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+	envVars := spec["environmentVariables"].([]interface{})
+	if value := envVars[0].(map[string]interface{})["value"]; value != diagnosticsRedactedValue {
+		t.Errorf("expected value of S3_STORAGE_SECRET_KEY to be redacted, got %v", value)
+	}
+	if value := envVars[1].(map[string]interface{})["value"]; value != "-Xmx2g" {
+		t.Errorf("expected value of HUMIO_MEMORY_OPTS to be kept, got %v", value)
+	}
+	secretKeyRef := envVars[2].(map[string]interface{})["valueFrom"].(map[string]interface{})["secretKeyRef"].(map[string]interface{})
+	if secretKeyRef["name"] != "storage" || secretKeyRef["key"] != "key" {
+		t.Errorf("expected secret reference of ENCRYPTION_KEY to be kept, got %v", secretKeyRef)
+	}
+}
+
+func TestLimitDiagnosticsBundleSize(t *testing.T) {
+	tt := []struct {
+		name     string
+		files    map[string][]byte
+		maxSize  int
+		expected map[string]string
+	}{
+		{
+			name:     "within size",
+			files:    map[string][]byte{"a": []byte("0123456789"), "b": []byte("01234")},
+			maxSize:  15,
+			expected: map[string]string{"a": "0123456789", "b": "01234"},
+		},
+		{
+			name:     "largest file is truncated from the start",
+			files:    map[string][]byte{"a": []byte(strings.Repeat("x", 40) + "0123456789"), "b": []byte("01234")},
+			maxSize:  27,
+			expected: map[string]string{"a": "[truncated]\n0123456789", "b": "01234"},
+		},
+		{
+			name:     "several files are truncated",
+			files:    map[string][]byte{"a": []byte(strings.Repeat("x", 20) + "abc"), "b": []byte(strings.Repeat("y", 20) + "def")},
+			maxSize:  30,
+			expected: map[string]string{"a": "[truncated]\n", "b": "[truncated]\nyyydef"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			limitDiagnosticsBundleSize(tc.files, tc.maxSize)
+			total := 0
+			for fileName, data := range tc.files {
+				total += len(data)
+				if string(data) != tc.expected[fileName] {
+					t.Errorf("expected %s to be %q, got %q", fileName, tc.expected[fileName], data)
+				}
+			}
+			if total > tc.maxSize {
+				t.Errorf("expected bundle to be at most %d bytes, got %d", tc.maxSize, total)
+			}
+		})
+	}
+}
+
+func TestValidateHumioDiagnosticsBundle(t *testing.T) {
+	tt := []struct {
+		name    string
+		target  humiov1alpha1.HumioDiagnosticsBundleTarget
+		wantErr bool
+	}{
+		{
+			name:   "persistent volume claim",
+			target: humiov1alpha1.HumioDiagnosticsBundleTarget{PersistentVolumeClaimName: "diagnostics"},
+		},
+		{
+			name:   "bucket",
+			target: humiov1alpha1.HumioDiagnosticsBundleTarget{Bucket: &humiov1alpha1.HumioClusterBackupTarget{Bucket: "bucket"}},
+		},
+		{
+			name:    "no target",
+			wantErr: true,
+		},
+		{
+			name: "both targets",
+			target: humiov1alpha1.HumioDiagnosticsBundleTarget{
+				PersistentVolumeClaimName: "diagnostics",
+				Bucket:                    &humiov1alpha1.HumioClusterBackupTarget{Bucket: "bucket"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "bucket without name",
+			target:  humiov1alpha1.HumioDiagnosticsBundleTarget{Bucket: &humiov1alpha1.HumioClusterBackupTarget{}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hdb := &humiov1alpha1.HumioDiagnosticsBundle{Spec: humiov1alpha1.HumioDiagnosticsBundleSpec{Target: tc.target}}
+			if err := validateHumioDiagnosticsBundle(hdb); (err != nil) != tc.wantErr {
+				t.Errorf("validateHumioDiagnosticsBundle() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestConstructHumioDiagnosticsBundleJob(t *testing.T) {
+	tt := []struct {
+		name             string
+		target           humiov1alpha1.HumioDiagnosticsBundleTarget
+		expectedImage    string
+		expectedLocation string
+		expectedVolumes  int
+	}{
+		{
+			name:             "persistent volume claim",
+			target:           humiov1alpha1.HumioDiagnosticsBundleTarget{PersistentVolumeClaimName: "diagnostics"},
+			expectedImage:    humioDiagnosticsBundleDefaultPVCImage,
+			expectedLocation: "/bundle/bundle-2401100200",
+			expectedVolumes:  2,
+		},
+		{
+			name: "bucket",
+			target: humiov1alpha1.HumioDiagnosticsBundleTarget{Bucket: &humiov1alpha1.HumioClusterBackupTarget{
+				Bucket:                "bucket",
+				Prefix:                "/support/",
+				Region:                "eu-west-1",
+				CredentialsSecretName: "credentials",
+			}},
+			expectedImage:    humioDiagnosticsBundleDefaultBucketImage,
+			expectedLocation: "s3://bucket/support/bundle-2401100200/",
+			expectedVolumes:  1,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hdb := &humiov1alpha1.HumioDiagnosticsBundle{
+				ObjectMeta: metav1.ObjectMeta{Name: "bundle", Namespace: "default"},
+				Spec:       humiov1alpha1.HumioDiagnosticsBundleSpec{Target: tc.target},
+			}
+			name := "bundle-2401100200"
+			job := constructHumioDiagnosticsBundleJob(hdb, name)
+
+			podSpec := job.Spec.Template.Spec
+			if podSpec.Volumes[0].Secret == nil || podSpec.Volumes[0].Secret.SecretName != name {
+				t.Errorf("expected secret %s to be mounted, got %+v", name, podSpec.Volumes[0])
+			}
+			if len(podSpec.Volumes) != tc.expectedVolumes {
+				t.Errorf("expected %d volumes, got %d", tc.expectedVolumes, len(podSpec.Volumes))
+			}
+			container := podSpec.Containers[0]
+			if container.Image != tc.expectedImage {
+				t.Errorf("expected image %s, got %s", tc.expectedImage, container.Image)
+			}
+			if container.Env[0].Name != "BUNDLE_LOCATION" || container.Env[0].Value != tc.expectedLocation {
+				t.Errorf("expected bundle location %s, got %+v", tc.expectedLocation, container.Env[0])
+			}
+			if tc.target.Bucket != nil && (len(container.EnvFrom) != 1 || container.EnvFrom[0].SecretRef.Name != "credentials") {
+				t.Errorf("expected credentials to be passed from secret, got %+v", container.EnvFrom)
+			}
+		})
+	}
+}
+
+func TestStartHumioDiagnosticsBundle(t *testing.T) {
+	hc := &humiov1alpha1.HumioCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "diagnostics", Namespace: "default"},
+	}
+	action := &humiov1alpha1.HumioAction{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "default"},
+		Spec: humiov1alpha1.HumioActionSpec{
+			ManagedClusterName: hc.Name,
+			Name:               "webhook",
+			WebhookProperties:  &humiov1alpha1.HumioActionWebhookProperties{Url: "https://example.com/hook?key=secret"},
+		},
+	}
+	otherAction := &humiov1alpha1.HumioAction{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+		Spec:       humiov1alpha1.HumioActionSpec{ManagedClusterName: "other", Name: "other"},
+	}
+	hdb := &humiov1alpha1.HumioDiagnosticsBundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle", Namespace: "default"},
+		Spec: humiov1alpha1.HumioDiagnosticsBundleSpec{
+			ManagedClusterName: hc.Name,
+			Target:             humiov1alpha1.HumioDiagnosticsBundleTarget{PersistentVolumeClaimName: "diagnostics"},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	r := &HumioDiagnosticsBundleReconciler{
+		Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects([]client.Object{hc, action, otherAction, hdb}...).Build(),
+		HumioClient: humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil),
+		Log:         logr.Discard(),
+	}
+
+	var status humiov1alpha1.HumioDiagnosticsBundleStatus
+	now := metav1.NewTime(time.Date(2024, time.January, 10, 2, 0, 0, 0, time.UTC))
+	if err := r.startBundle(context.Background(), hdb, &status, now); err != nil {
+		t.Fatal(err)
+	}
+	if status.State != humiov1alpha1.HumioDiagnosticsBundleStateRunning || !strings.HasPrefix(status.JobName, "bundle-2401100200-") {
+		t.Errorf("expected a bundle job starting with bundle-2401100200- to be running, got %+v", status)
+	}
+
+	var secret corev1.Secret
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: hdb.Namespace, Name: status.JobName}, &secret); err != nil {
+		t.Fatal(err)
+	}
+	if len(secret.Data) != len(status.Files) {
+		t.Errorf("expected files %v to be stored, got %d files", status.Files, len(secret.Data))
+	}
+	actions := string(secret.Data["humioactions.json"])
+	if !strings.Contains(actions, `"webhook"`) || strings.Contains(actions, `"other"`) {
+		t.Errorf("expected only the actions of the cluster, got %s", actions)
+	}
+	if strings.Contains(actions, "key=secret") {
+		t.Errorf("expected action properties to be redacted, got %s", actions)
+	}
+	if _, ok := secret.Data["humioclusters.json"]; !ok {
+		t.Errorf("expected cluster resource in bundle, got %v", status.Files)
+	}
+}
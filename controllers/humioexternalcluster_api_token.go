@@ -0,0 +1,117 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// apiTokenRotationDue returns whether the API token of the external cluster should be rotated. Rotation intervals are
+// counted from the creation of the HumioExternalCluster until the API token is rotated for the first time.
+func (r *HumioExternalClusterReconciler) apiTokenRotationDue(hec *humiov1alpha1.HumioExternalCluster, now metav1.Time) bool {
+	if hec.Spec.APITokenRotation == nil || hec.Spec.APITokenRotation.IntervalSeconds <= 0 {
+		return false
+	}
+	lastRotation := hec.CreationTimestamp
+	if hec.Status.LastAPITokenRotationTime != nil {
+		lastRotation = *hec.Status.LastAPITokenRotationTime
+	}
+	interval := time.Second * time.Duration(hec.Spec.APITokenRotation.IntervalSeconds)
+	return !now.Time.Before(lastRotation.Add(interval))
+}
+
+// isAuthenticationError returns whether the given error means Humio rejected the API token. Other errors, like
+// timeouts and server errors, say nothing about the API token, so it must not be rotated because of them.
+func isAuthenticationError(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "401 unauthorized")
+}
+
+// rotateAPIToken uses the bootstrap API token to rotate the API token of the given user, stores the new API token in
+// the API token secret of the external cluster and verifies the new API token works. Humio revokes the previous API
+// token as part of the rotation.
+func (r *HumioExternalClusterReconciler) rotateAPIToken(ctx context.Context, req ctrl.Request, cluster helpers.ClusterInterface, hec *humiov1alpha1.HumioExternalCluster, username string) error {
+	if hec.Spec.APITokenSecretName == "" {
+		return fmt.Errorf("api token rotation requires an api token secret name")
+	}
+	if hec.Spec.APITokenRotation.BootstrapTokenSecretName == "" {
+		return fmt.Errorf("no bootstrap token secret name specified")
+	}
+
+	var bootstrapToken corev1.Secret
+	err := r.Get(ctx, types.NamespacedName{
+		Namespace: hec.Namespace,
+		Name:      hec.Spec.APITokenRotation.BootstrapTokenSecretName,
+	}, &bootstrapToken)
+	if err != nil {
+		return fmt.Errorf("unable to get secret containing bootstrap token: %w", err)
+	}
+	if len(bootstrapToken.Data["token"]) == 0 {
+		return fmt.Errorf("secret %s does not contain a bootstrap token in key \"token\"", hec.Spec.APITokenRotation.BootstrapTokenSecretName)
+	}
+
+	config := *cluster.Config()
+	config.Token = string(bootstrapToken.Data["token"])
+	bootstrapUsername, err := r.HumioClient.TestAPIToken(&config, req)
+	if err != nil {
+		return fmt.Errorf("unable to authenticate using bootstrap token: %w", err)
+	}
+	if bootstrapUsername == username {
+		return fmt.Errorf("bootstrap token must belong to a different user than the api token being rotated")
+	}
+
+	r.Log.Info(fmt.Sprintf("rotating api token of user %s", username))
+	newToken, err := r.HumioClient.RotateUserAPIToken(&config, req, username)
+	if err != nil {
+		return fmt.Errorf("unable to rotate api token: %w", err)
+	}
+
+	// The previous API token no longer works at this point, so if the secret cannot be updated the API token will be
+	// rotated again on the next reconcile
+	var apiToken corev1.Secret
+	err = r.Get(ctx, types.NamespacedName{
+		Namespace: hec.Namespace,
+		Name:      hec.Spec.APITokenSecretName,
+	}, &apiToken)
+	if err != nil {
+		return fmt.Errorf("unable to get secret containing api token: %w", err)
+	}
+	if apiToken.Data == nil {
+		apiToken.Data = map[string][]byte{}
+	}
+	apiToken.Data["token"] = []byte(newToken)
+	err = r.Update(ctx, &apiToken)
+	if err != nil {
+		return fmt.Errorf("unable to update secret containing api token: %w", err)
+	}
+
+	config.Token = newToken
+	if _, err = r.HumioClient.TestAPIToken(&config, req); err != nil {
+		return fmt.Errorf("unable to authenticate using rotated api token: %w", err)
+	}
+	r.Log.Info("successfully rotated api token")
+	return nil
+}
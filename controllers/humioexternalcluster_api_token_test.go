@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAPITokenRotationDue(t *testing.T) {
+	now := metav1.Now()
+	hourAgo := metav1.NewTime(now.Add(-time.Hour))
+	minuteAgo := metav1.NewTime(now.Add(-time.Minute))
+	rotation := &humiov1alpha1.HumioExternalClusterAPITokenRotation{IntervalSeconds: 3600}
+
+	tt := []struct {
+		name         string
+		rotation     *humiov1alpha1.HumioExternalClusterAPITokenRotation
+		created      metav1.Time
+		lastRotation *metav1.Time
+		due          bool
+	}{
+		{
+			name:    "rotation not configured",
+			created: hourAgo,
+			due:     false,
+		},
+		{
+			name:     "never rotated and created within interval",
+			rotation: rotation,
+			created:  minuteAgo,
+			due:      false,
+		},
+		{
+			name:     "never rotated and created before interval",
+			rotation: rotation,
+			created:  hourAgo,
+			due:      true,
+		},
+		{
+			name:         "rotated within interval",
+			rotation:     rotation,
+			created:      hourAgo,
+			lastRotation: &minuteAgo,
+			due:          false,
+		},
+		{
+			name:         "rotated before interval",
+			rotation:     rotation,
+			created:      hourAgo,
+			lastRotation: &hourAgo,
+			due:          true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hec := &humiov1alpha1.HumioExternalCluster{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: tc.created},
+				Spec:       humiov1alpha1.HumioExternalClusterSpec{APITokenRotation: tc.rotation},
+				Status:     humiov1alpha1.HumioExternalClusterStatus{LastAPITokenRotationTime: tc.lastRotation},
+			}
+			r := &HumioExternalClusterReconciler{}
+			if due := r.apiTokenRotationDue(hec, now); due != tc.due {
+				t.Errorf("apiTokenRotationDue() = %v, want %v", due, tc.due)
+			}
+		})
+	}
+}
+
+func TestIsAuthenticationError(t *testing.T) {
+	tt := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"no error", nil, false},
+		{"unauthorized", errors.New("non-200 OK status code: 401 Unauthorized body: \"\""), true},
+		{"service unavailable", errors.New("non-200 OK status code: 503 Service Unavailable body: \"fault injected by humio-operator\""), false},
+		{"timeout", errors.New("Post \"https://humio.example.com/graphql\": net/http: timeout awaiting response headers"), false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isAuthenticationError(tc.err); got != tc.want {
+				t.Errorf("isAuthenticationError() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
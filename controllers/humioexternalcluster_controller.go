@@ -19,15 +19,23 @@ package controllers
 import (
 	"context"
 	"fmt"
+	humioapi "github.com/humio/cli/api"
 	"github.com/humio/humio-operator/pkg/helpers"
 	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"net/url"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"time"
 
 	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
 	"github.com/humio/humio-operator/pkg/humio"
@@ -45,6 +53,7 @@ type HumioExternalClusterReconciler struct {
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioexternalclusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioexternalclusters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioexternalclusters/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;update
 
 func (r *HumioExternalClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	if r.Namespace != "" {
@@ -64,6 +73,7 @@ func (r *HumioExternalClusterReconciler) Reconcile(ctx context.Context, req ctrl
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			helpers.ForgetOAuth2TokenSource(req.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -72,6 +82,10 @@ func (r *HumioExternalClusterReconciler) Reconcile(ctx context.Context, req ctrl
 
 	r.Log = r.Log.WithValues("Request.UID", hec.UID)
 
+	if hec.Spec.OAuth2 == nil {
+		helpers.ForgetOAuth2TokenSource(req.NamespacedName)
+	}
+
 	if hec.Status.State == "" {
 		err := r.setState(ctx, humiov1alpha1.HumioExternalClusterStateUnknown, hec)
 		if err != nil {
@@ -79,45 +93,181 @@ func (r *HumioExternalClusterReconciler) Reconcile(ctx context.Context, req ctrl
 		}
 	}
 
-	cluster, err := helpers.NewCluster(ctx, r, "", hec.Name, hec.Namespace, helpers.UseCertManager(), true)
+	healthCheckInterval := time.Second * 15
+	if hec.Spec.HealthCheckIntervalSeconds > 0 {
+		healthCheckInterval = time.Second * time.Duration(hec.Spec.HealthCheckIntervalSeconds)
+	}
+
+	cluster, err := helpers.NewExternalCluster(ctx, r, hec.Name, hec.Namespace)
 	if err != nil || cluster.Config() == nil {
 		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to obtain humio client config")
 	}
 
-	err = r.HumioClient.TestAPIToken(cluster.Config(), req)
+	now := metav1.Now()
+	status := humiov1alpha1.HumioExternalClusterStatus{
+		State:                    humiov1alpha1.HumioExternalClusterStateReady,
+		LastHealthCheckTime:      &now,
+		LastAPITokenRotationTime: hec.Status.LastAPITokenRotationTime,
+	}
+	humioStatus, err := r.failover(req, cluster, hec, &status)
 	if err != nil {
-		r.Log.Error(err, "unable to test if the API token is works")
-		err = r.Client.Get(ctx, req.NamespacedName, hec)
-		if err != nil {
-			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to get cluster state")
+		status.State = humiov1alpha1.HumioExternalClusterStateUnreachable
+		status.Message = fmt.Sprintf("unable to get status: %s", err)
+	} else {
+		status.Version = humioStatus.Version
+		if hec.Spec.Cloud != nil && hec.Spec.Cloud.OrganizationToken {
+			err = r.HumioClient.TestOrganizationAPIToken(cluster.Config(), req)
+		} else {
+			status.Username, err = r.HumioClient.TestAPIToken(cluster.Config(), req)
 		}
-		err = r.setState(ctx, humiov1alpha1.HumioExternalClusterStateUnknown, hec)
 		if err != nil {
-			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster state")
+			r.Log.Error(err, "unable to test if the API token is works")
+			status.Username = hec.Status.Username
+			if isAuthenticationError(err) {
+				status.State = humiov1alpha1.HumioExternalClusterStateUnauthorized
+				status.Message = fmt.Sprintf("unable to authenticate: %s", err)
+			} else {
+				status.State = humiov1alpha1.HumioExternalClusterStateUnreachable
+				status.Message = fmt.Sprintf("unable to test api token: %s", err)
+			}
+		}
+	}
+
+	if hec.Spec.APITokenRotation != nil {
+		rotate := false
+		switch status.State {
+		case humiov1alpha1.HumioExternalClusterStateReady:
+			rotate = r.apiTokenRotationDue(hec, now)
+		case humiov1alpha1.HumioExternalClusterStateUnauthorized:
+			// The API token may have been revoked by a rotation where the new API token could not be stored, so
+			// rotating it again is how we recover
+			rotate = status.Username != ""
+		}
+		if rotate {
+			err = r.rotateAPIToken(ctx, req, cluster, hec, status.Username)
+			if err != nil {
+				r.Log.Error(err, "unable to rotate API token")
+				status.Message = fmt.Sprintf("unable to rotate api token: %s", err)
+			} else {
+				status.State = humiov1alpha1.HumioExternalClusterStateReady
+				status.Message = ""
+				status.LastAPITokenRotationTime = &now
+			}
 		}
-		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
 	}
 
 	err = r.Client.Get(ctx, req.NamespacedName, hec)
 	if err != nil {
 		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to get cluster state")
 	}
-	if hec.Status.State != humiov1alpha1.HumioExternalClusterStateReady {
-		err = r.setState(ctx, humiov1alpha1.HumioExternalClusterStateReady, hec)
+	err = r.setStatus(ctx, status, hec)
+	if err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster status")
+	}
+
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", healthCheckInterval))
+	return reconcile.Result{RequeueAfter: healthCheckInterval}, nil
+}
+
+// failover checks the URLs of the external cluster in order, and updates the configuration of the cluster to use the
+// first URL where the status of the Humio cluster can be obtained
+func (r *HumioExternalClusterReconciler) failover(req ctrl.Request, cluster helpers.ClusterInterface, hec *humiov1alpha1.HumioExternalCluster, status *humiov1alpha1.HumioExternalClusterStatus) (humioapi.StatusResponse, error) {
+	var humioStatus humioapi.StatusResponse
+	var err error
+	for _, externalClusterUrl := range helpers.ExternalClusterUrls(hec) {
+		config := *cluster.Config()
+		config.Address, err = url.Parse(externalClusterUrl)
 		if err != nil {
-			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster state")
+			return humioapi.StatusResponse{}, err
+		}
+		humioStatus, err = r.HumioClient.Status(&config, req)
+		if err != nil {
+			r.Log.Error(err, fmt.Sprintf("unable to get status of the external cluster using %s", externalClusterUrl))
+			continue
 		}
+		if externalClusterUrl != hec.Status.ActiveUrl {
+			r.Log.Info(fmt.Sprintf("using %s to communicate with the external cluster", externalClusterUrl))
+		}
+		cluster.Config().Address = config.Address
+		status.ActiveUrl = externalClusterUrl
+		return humioStatus, nil
 	}
-
-	r.Log.Info("done reconciling, will requeue after 15 seconds")
-	return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	status.ActiveUrl = hec.Status.ActiveUrl
+	return humioapi.StatusResponse{}, err
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *HumioExternalClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&humiov1alpha1.HumioExternalCluster{}).
-		Complete(r)
+		// Every health check updates the status, so only changes to the spec should trigger a reconcile. Health checks
+		// are scheduled by requeueing.
+		For(&humiov1alpha1.HumioExternalCluster{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.externalClustersForCAConfigMap)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.externalClustersForSecret)).
+		Complete(criticalReconciler(r))
+}
+
+// externalClustersForCAConfigMap returns a reconcile request for every HumioExternalCluster which loads its CA bundle
+// from the given config map, so rotated CA bundles are validated right away
+func (r *HumioExternalClusterReconciler) externalClustersForCAConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	var humioExternalClusters humiov1alpha1.HumioExternalClusterList
+	if err := r.List(ctx, &humioExternalClusters, client.InNamespace(configMap.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list external clusters")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, hec := range humioExternalClusters.Items {
+		if hec.Spec.CAConfigMapName == configMap.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: hec.Namespace, Name: hec.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// humioExternalClusterSecretNames returns the names of the secrets the HumioExternalCluster reads credentials and
+// certificates from
+func humioExternalClusterSecretNames(hec *humiov1alpha1.HumioExternalCluster) []string {
+	var secretNames []string
+	for _, secretName := range []string{hec.Spec.APITokenSecretName, hec.Spec.CASecretName, hec.Spec.ClientCertificateSecretName} {
+		if secretName != "" {
+			secretNames = append(secretNames, secretName)
+		}
+	}
+	if hec.Spec.OAuth2 != nil {
+		secretNames = append(secretNames, hec.Spec.OAuth2.ClientSecretName)
+	}
+	if hec.Spec.APITokenRotation != nil {
+		secretNames = append(secretNames, hec.Spec.APITokenRotation.BootstrapTokenSecretName)
+	}
+	if hec.Spec.Proxy != nil && hec.Spec.Proxy.CredentialsSecretName != "" {
+		secretNames = append(secretNames, hec.Spec.Proxy.CredentialsSecretName)
+	}
+	return secretNames
+}
+
+// externalClustersForSecret returns a reconcile request for every HumioExternalCluster which reads the given secret,
+// so rotated API tokens, CA certificates and client certificates are validated and used right away
+func (r *HumioExternalClusterReconciler) externalClustersForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	var humioExternalClusters humiov1alpha1.HumioExternalClusterList
+	if err := r.List(ctx, &humioExternalClusters, client.InNamespace(secret.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list external clusters")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, hec := range humioExternalClusters.Items {
+		if helpers.ContainsElement(humioExternalClusterSecretNames(&hec), secret.GetName()) {
+			if hec.Spec.OAuth2 != nil && hec.Spec.OAuth2.ClientSecretName == secret.GetName() {
+				// The client credentials may have changed, so drop the cached token source and its access token
+				helpers.ForgetOAuth2TokenSource(types.NamespacedName{Namespace: hec.Namespace, Name: hec.Name})
+			}
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: hec.Namespace, Name: hec.Name},
+			})
+		}
+	}
+	return requests
 }
 
 func (r *HumioExternalClusterReconciler) logErrorAndReturn(err error, msg string) error {
@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
 )
 
 func (r *HumioExternalClusterReconciler) setState(ctx context.Context, state string, hec *humiov1alpha1.HumioExternalCluster) error {
@@ -31,3 +32,28 @@ func (r *HumioExternalClusterReconciler) setState(ctx context.Context, state str
 	hec.Status.State = state
 	return r.Status().Update(ctx, hec)
 }
+
+func (r *HumioExternalClusterReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioExternalClusterStatus, hec *humiov1alpha1.HumioExternalCluster) error {
+	if !externalClusterStatusChanged(hec.Status, status) {
+		return nil
+	}
+	if hec.Status.State != status.State {
+		r.Log.Info(fmt.Sprintf("setting external cluster state to %s", status.State))
+	}
+	hec.Status = status
+	return r.Status().Update(ctx, hec)
+}
+
+// externalClusterStatusChanged returns true if the status must be written. The last health check time changes on every
+// health check, so it is only refreshed once it gets older than lastSyncRefreshInterval if nothing else changed.
+func externalClusterStatusChanged(current, desired humiov1alpha1.HumioExternalClusterStatus) bool {
+	if current.LastHealthCheckTime == nil || desired.LastHealthCheckTime == nil {
+		return !equality.Semantic.DeepEqual(current, desired)
+	}
+	if desired.LastHealthCheckTime.Sub(current.LastHealthCheckTime.Time) >= lastSyncRefreshInterval {
+		return true
+	}
+	withoutHealthCheckTime := desired.DeepCopy()
+	withoutHealthCheckTime.LastHealthCheckTime = current.LastHealthCheckTime
+	return !equality.Semantic.DeepEqual(current, *withoutHealthCheckTime)
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExternalClusterStatusChanged(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	checked := func(ago time.Duration, state string) humiov1alpha1.HumioExternalClusterStatus {
+		checkTime := metav1.NewTime(now.Add(-ago))
+		return humiov1alpha1.HumioExternalClusterStatus{State: state, Version: "1.100.0", LastHealthCheckTime: &checkTime}
+	}
+	ready := humiov1alpha1.HumioExternalClusterStateReady
+	unreachable := humiov1alpha1.HumioExternalClusterStateUnreachable
+
+	tt := []struct {
+		name    string
+		current humiov1alpha1.HumioExternalClusterStatus
+		desired humiov1alpha1.HumioExternalClusterStatus
+		changed bool
+	}{
+		{"only recent health check time changed", checked(30*time.Second, ready), checked(0, ready), false},
+		{"only stale health check time changed", checked(2*time.Minute, ready), checked(0, ready), true},
+		{"state changed", checked(30*time.Second, ready), checked(0, unreachable), true},
+		{"never checked", humiov1alpha1.HumioExternalClusterStatus{}, checked(0, ready), true},
+		{"unchanged", checked(0, ready), checked(0, ready), false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := externalClusterStatusChanged(tc.current, tc.desired); got != tc.changed {
+				t.Errorf("externalClusterStatusChanged() = %t, want %t", got, tc.changed)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/go-logr/logr"
 	humioapi "github.com/humio/cli/api"
@@ -25,9 +26,12 @@ import (
 	"github.com/humio/humio-operator/pkg/kubernetes"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"reflect"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"time"
 
@@ -44,6 +48,7 @@ type HumioIngestTokenReconciler struct {
 	Log         logr.Logger
 	HumioClient humio.Client
 	Namespace   string
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioingesttokens,verbs=get;list;watch;create;update;patch;delete
@@ -77,6 +82,17 @@ func (r *HumioIngestTokenReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	r.Log = r.Log.WithValues("Request.UID", hit.UID)
 
 	cluster, err := helpers.NewCluster(ctx, r, hit.Spec.ManagedClusterName, hit.Spec.ExternalClusterName, hit.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hit.Namespace, hit.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		err = r.setState(ctx, humiov1alpha1.HumioIngestTokenStateClusterUnavailable, hit)
+		if err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
 	if err != nil || cluster == nil || cluster.Config() == nil {
 		r.Log.Error(err, "unable to obtain humio client config")
 		err = r.setState(ctx, humiov1alpha1.HumioIngestTokenStateConfigError, hit)
@@ -124,6 +140,10 @@ func (r *HumioIngestTokenReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 	defer func(ctx context.Context, humioClient humio.Client, hit *humiov1alpha1.HumioIngestToken) {
 		curToken, err := humioClient.GetIngestToken(cluster.Config(), req, hit)
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			_ = r.setState(ctx, humiov1alpha1.HumioIngestTokenStateClusterUnavailable, hit)
+			return
+		}
 		if err != nil {
 			_ = r.setState(ctx, humiov1alpha1.HumioIngestTokenStateUnknown, hit)
 			return
@@ -171,21 +191,27 @@ func (r *HumioIngestTokenReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return reconcile.Result{}, fmt.Errorf("could not ensure token secret exists: %w", err)
 	}
 
+	if err := r.setSyncStatus(ctx, time.Now(), hit); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set ingest token sync status")
+	}
+
 	// TODO: handle updates to ingest token name and repositoryName. Right now we just create the new ingest token,
 	// and "leak/leave behind" the old token.
 	// A solution could be to add an annotation that includes the "old name" so we can see if it was changed.
 	// A workaround for now is to delete the ingest token CR and create it again.
 
-	r.Log.Info("done reconciling, will requeue after 15 seconds")
-	return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	requeue := requeueInterval(hit, time.Second*15)
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+	return reconcile.Result{RequeueAfter: requeue}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *HumioIngestTokenReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&humiov1alpha1.HumioIngestToken{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioIngestTokenList{}))).
 		Owns(&corev1.Secret{}).
-		Complete(r)
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioIngestToken{}, r))
 }
 
 func (r *HumioIngestTokenReconciler) finalize(ctx context.Context, config *humioapi.Config, req reconcile.Request, hit *humiov1alpha1.HumioIngestToken) error {
@@ -260,6 +286,17 @@ func (r *HumioIngestTokenReconciler) setState(ctx context.Context, state string,
 	return r.Status().Update(ctx, hit)
 }
 
+func (r *HumioIngestTokenReconciler) setSyncStatus(ctx context.Context, now time.Time, hit *humiov1alpha1.HumioIngestToken) error {
+	status := hit.Status.DeepCopy()
+	status.ClusterName = syncClusterName(hit.Spec.ManagedClusterName, hit.Spec.ExternalClusterName)
+	status.LastSyncTime = syncTime(status.LastSyncTime, now)
+	if reflect.DeepEqual(hit.Status, *status) {
+		return nil
+	}
+	hit.Status = *status
+	return r.Status().Update(ctx, hit)
+}
+
 func (r *HumioIngestTokenReconciler) logErrorAndReturn(err error, msg string) error {
 	r.Log.Error(err, msg)
 	return fmt.Errorf("%s: %w", msg, err)
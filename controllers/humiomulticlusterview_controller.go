@@ -0,0 +1,366 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// HumioMultiClusterViewReconciler reconciles a HumioMultiClusterView object
+type HumioMultiClusterViewReconciler struct {
+	client.Client
+	BaseLogger  logr.Logger
+	Log         logr.Logger
+	HumioClient humio.Client
+	Namespace   string
+	Recorder    record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiomulticlusterviews,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiomulticlusterviews/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiomulticlusterviews/finalizers,verbs=update
+
+func (r *HumioMultiClusterViewReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioMultiClusterView")
+
+	hmcv := &humiov1alpha1.HumioMultiClusterView{}
+	if err := r.Get(ctx, req.NamespacedName, hmcv); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hmcv.UID)
+
+	cluster, err := helpers.NewCluster(ctx, r, hmcv.Spec.ManagedClusterName, hmcv.Spec.ExternalClusterName, hmcv.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hmcv.Namespace, hmcv.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		if err := r.setState(ctx, humiov1alpha1.HumioMultiClusterViewStateClusterUnavailable, "", hmcv); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		r.Log.Error(err, "unable to obtain humio client config")
+		if err := r.setState(ctx, humiov1alpha1.HumioMultiClusterViewStateConfigError, "unable to obtain humio client config", hmcv); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	if hmcv.GetDeletionTimestamp() != nil {
+		r.Log.Info("Multi-cluster view marked to be deleted")
+		if helpers.ContainsElement(hmcv.GetFinalizers(), humioFinalizer) {
+			if err := r.finalize(cluster.Config(), req, hmcv); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "Finalizer method returned error")
+			}
+			r.Log.Info("Multi-cluster view deleted. Removing finalizer")
+			hmcv.SetFinalizers(helpers.RemoveElement(hmcv.GetFinalizers(), humioFinalizer))
+			if err := r.Update(ctx, hmcv); err != nil {
+				return reconcile.Result{}, err
+			}
+			r.Log.Info("Finalizer removed successfully")
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if !helpers.ContainsElement(hmcv.GetFinalizers(), humioFinalizer) {
+		r.Log.Info("Finalizer not present, adding finalizer to multi-cluster view")
+		hmcv.SetFinalizers(append(hmcv.GetFinalizers(), humioFinalizer))
+		if err := r.Update(ctx, hmcv); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{Requeue: true}, nil
+	}
+
+	desired, tokenHashes, err := r.desiredConnections(ctx, hmcv)
+	if err != nil {
+		r.Log.Error(err, "invalid multi-cluster view connections")
+		if err := r.setState(ctx, humiov1alpha1.HumioMultiClusterViewStateConfigError, err.Error(), hmcv); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set multi-cluster view state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	if err := r.reconcileMultiClusterView(cluster.Config(), req, hmcv, desired, tokenHashes); err != nil {
+		state := humiov1alpha1.HumioMultiClusterViewStateUnknown
+		message := ""
+		switch {
+		case errors.Is(err, humio.ErrClusterUnavailable):
+			state = humiov1alpha1.HumioMultiClusterViewStateClusterUnavailable
+		case isMultiClusterSearchUnsupported(err):
+			state = humiov1alpha1.HumioMultiClusterViewStateNotSupported
+			message = "the Humio cluster does not support multi-cluster search"
+		}
+		if err := r.setState(ctx, state, message, hmcv); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set multi-cluster view state")
+		}
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not reconcile multi-cluster view")
+	}
+
+	status := humiov1alpha1.HumioMultiClusterViewStatus{
+		State:                 humiov1alpha1.HumioMultiClusterViewStateExists,
+		ClusterName:           syncClusterName(hmcv.Spec.ManagedClusterName, hmcv.Spec.ExternalClusterName),
+		LastSyncTime:          syncTime(hmcv.Status.LastSyncTime, time.Now()),
+		ConnectionTokenHashes: tokenHashes,
+	}
+	if err := r.setStatus(ctx, status, hmcv); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set multi-cluster view status")
+	}
+
+	requeue := requeueInterval(hmcv, time.Second*15)
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+	return reconcile.Result{RequeueAfter: requeue}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioMultiClusterViewReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioMultiClusterView{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioMultiClusterViewList{}))).
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioMultiClusterView{}, r))
+}
+
+// reconcileMultiClusterView creates the multi-cluster view if it does not exist, and adds, updates and deletes its
+// connections to match the desired connections
+func (r *HumioMultiClusterViewReconciler) reconcileMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, desired []humio.MultiClusterViewConnection, tokenHashes map[string]string) error {
+	curView, err := r.HumioClient.GetMultiClusterView(config, req, hmcv)
+	if err != nil {
+		return fmt.Errorf("could not check if multi-cluster view exists: %w", err)
+	}
+	if curView.Name == "" {
+		r.Log.Info("multi-cluster view doesn't exist. Now adding multi-cluster view")
+		if err := r.HumioClient.AddMultiClusterView(config, req, hmcv); err != nil {
+			return fmt.Errorf("could not create multi-cluster view: %w", err)
+		}
+		curView = &humio.MultiClusterView{Name: hmcv.Spec.Name, Description: hmcv.Spec.Description}
+	}
+
+	if curView.Description != hmcv.Spec.Description {
+		r.Log.Info(fmt.Sprintf("multi-cluster view description differs, triggering update, expected %q, got: %q", hmcv.Spec.Description, curView.Description))
+		if err := r.HumioClient.UpdateMultiClusterViewDescription(config, req, hmcv); err != nil {
+			return fmt.Errorf("could not update multi-cluster view description: %w", err)
+		}
+	}
+
+	add, update, remove := diffMultiClusterViewConnections(curView.Connections, desired, tokenHashes, hmcv.Status.ConnectionTokenHashes)
+	for _, connectionID := range remove {
+		r.Log.Info(fmt.Sprintf("deleting multi-cluster view connection %s", connectionID))
+		if err := r.HumioClient.DeleteMultiClusterViewConnection(config, req, hmcv, connectionID); err != nil {
+			return fmt.Errorf("could not delete multi-cluster view connection: %w", err)
+		}
+	}
+	for _, connection := range update {
+		r.Log.Info(fmt.Sprintf("updating multi-cluster view connection %s", connection.ClusterIdentity))
+		if err := r.HumioClient.UpdateMultiClusterViewConnection(config, req, hmcv, connection); err != nil {
+			return fmt.Errorf("could not update multi-cluster view connection %s: %w", connection.ClusterIdentity, err)
+		}
+	}
+	for _, connection := range add {
+		r.Log.Info(fmt.Sprintf("adding multi-cluster view connection %s", connection.ClusterIdentity))
+		if err := r.HumioClient.AddMultiClusterViewConnection(config, req, hmcv, connection); err != nil {
+			return fmt.Errorf("could not add multi-cluster view connection %s: %w", connection.ClusterIdentity, err)
+		}
+	}
+	return nil
+}
+
+// desiredConnections returns the connections of the multi-cluster view with the URLs and tokens of remote connections
+// resolved, along with a hash of the token of each remote connection keyed by cluster identity
+func (r *HumioMultiClusterViewReconciler) desiredConnections(ctx context.Context, hmcv *humiov1alpha1.HumioMultiClusterView) ([]humio.MultiClusterViewConnection, map[string]string, error) {
+	var connections []humio.MultiClusterViewConnection
+	tokenHashes := map[string]string{}
+	clusterIdentities := map[string]bool{}
+	for _, c := range hmcv.Spec.Connections {
+		if c.ClusterIdentity == "" {
+			return nil, nil, fmt.Errorf("connections must specify clusterIdentity")
+		}
+		if clusterIdentities[c.ClusterIdentity] {
+			return nil, nil, fmt.Errorf("connections must have unique cluster identities, got %s more than once", c.ClusterIdentity)
+		}
+		clusterIdentities[c.ClusterIdentity] = true
+		if c.ManagedClusterName != "" && c.ExternalClusterName != "" {
+			return nil, nil, fmt.Errorf("connection %s cannot have both managedClusterName and externalClusterName set", c.ClusterIdentity)
+		}
+
+		connection := humio.MultiClusterViewConnection{
+			ClusterIdentity: c.ClusterIdentity,
+			QueryPrefix:     c.Filter,
+		}
+		if !isRemoteMultiClusterViewConnection(hmcv, c) {
+			if c.ViewOrRepoName == "" {
+				return nil, nil, fmt.Errorf("local connection %s must specify viewOrRepoName", c.ClusterIdentity)
+			}
+			connection.TargetViewName = c.ViewOrRepoName
+			connections = append(connections, connection)
+			continue
+		}
+
+		if c.APITokenSecretName == "" {
+			return nil, nil, fmt.Errorf("remote connection %s must specify apiTokenSecretName", c.ClusterIdentity)
+		}
+		connection.PublicURL = c.URL
+		if connection.PublicURL == "" {
+			remoteCluster, err := helpers.NewCluster(ctx, r, c.ManagedClusterName, c.ExternalClusterName, hmcv.Namespace, helpers.UseCertManager(), false)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to obtain the cluster of remote connection %s: %w", c.ClusterIdentity, err)
+			}
+			remoteURL, err := remoteCluster.Url(ctx, r)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to obtain the URL of remote connection %s: %w", c.ClusterIdentity, err)
+			}
+			connection.PublicURL = remoteURL.String()
+		}
+		var secret corev1.Secret
+		if err := r.Get(ctx, types.NamespacedName{Namespace: hmcv.Namespace, Name: c.APITokenSecretName}, &secret); err != nil {
+			return nil, nil, fmt.Errorf("unable to get token secret of remote connection %s: %w", c.ClusterIdentity, err)
+		}
+		token := string(secret.Data["token"])
+		if token == "" {
+			return nil, nil, fmt.Errorf("token secret %s of remote connection %s does not contain a token", c.APITokenSecretName, c.ClusterIdentity)
+		}
+		connection.Token = token
+		tokenHashes[c.ClusterIdentity] = helpers.AsSHA256(token)
+		connections = append(connections, connection)
+	}
+	return connections, tokenHashes, nil
+}
+
+// isRemoteMultiClusterViewConnection returns whether the connection searches another cluster than the cluster of the
+// multi-cluster view
+func isRemoteMultiClusterViewConnection(hmcv *humiov1alpha1.HumioMultiClusterView, c humiov1alpha1.HumioMultiClusterViewConnection) bool {
+	if c.URL != "" {
+		return true
+	}
+	if c.ManagedClusterName != "" {
+		return c.ManagedClusterName != hmcv.Spec.ManagedClusterName
+	}
+	if c.ExternalClusterName != "" {
+		return c.ExternalClusterName != hmcv.Spec.ExternalClusterName
+	}
+	return false
+}
+
+// diffMultiClusterViewConnections returns the connections to add, the connections to update and the IDs of the
+// connections to delete for the current connections of a multi-cluster view to match the desired connections.
+// Connections are matched by cluster identity. Remote connections are updated when their token changes, and
+// connections changing between local and remote are replaced.
+func diffMultiClusterViewConnections(current, desired []humio.MultiClusterViewConnection, tokenHashes, lastTokenHashes map[string]string) (add, update []humio.MultiClusterViewConnection, remove []string) {
+	currentByIdentity := map[string]humio.MultiClusterViewConnection{}
+	for _, connection := range current {
+		if _, ok := currentByIdentity[connection.ClusterIdentity]; connection.ClusterIdentity == "" || ok {
+			remove = append(remove, connection.ID)
+			continue
+		}
+		currentByIdentity[connection.ClusterIdentity] = connection
+	}
+
+	desiredIdentities := map[string]bool{}
+	for _, connection := range desired {
+		desiredIdentities[connection.ClusterIdentity] = true
+		cur, ok := currentByIdentity[connection.ClusterIdentity]
+		if !ok {
+			add = append(add, connection)
+			continue
+		}
+		if cur.IsRemote() != connection.IsRemote() {
+			remove = append(remove, cur.ID)
+			add = append(add, connection)
+			continue
+		}
+		if cur.TargetViewName != connection.TargetViewName || cur.PublicURL != connection.PublicURL || cur.QueryPrefix != connection.QueryPrefix ||
+			(connection.IsRemote() && tokenHashes[connection.ClusterIdentity] != lastTokenHashes[connection.ClusterIdentity]) {
+			connection.ID = cur.ID
+			update = append(update, connection)
+		}
+	}
+	for _, connection := range current {
+		if _, ok := currentByIdentity[connection.ClusterIdentity]; ok && !desiredIdentities[connection.ClusterIdentity] {
+			remove = append(remove, connection.ID)
+		}
+	}
+	return add, update, remove
+}
+
+// isMultiClusterSearchUnsupported returns whether the error was caused by the Humio cluster not knowing the GraphQL
+// fields used for multi-cluster search
+func isMultiClusterSearchUnsupported(err error) bool {
+	return strings.Contains(err.Error(), "Cannot query field") || strings.Contains(err.Error(), "Unknown type")
+}
+
+func (r *HumioMultiClusterViewReconciler) finalize(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	curView, err := r.HumioClient.GetMultiClusterView(config, req, hmcv)
+	if err != nil {
+		return err
+	}
+	if curView.Name == "" {
+		return nil
+	}
+	return r.HumioClient.DeleteMultiClusterView(config, req, hmcv)
+}
+
+func (r *HumioMultiClusterViewReconciler) setState(ctx context.Context, state, message string, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	status := *hmcv.Status.DeepCopy()
+	status.State = state
+	status.Message = message
+	return r.setStatus(ctx, status, hmcv)
+}
+
+func (r *HumioMultiClusterViewReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioMultiClusterViewStatus, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	if reflect.DeepEqual(hmcv.Status, status) {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting multi-cluster view state to %s", status.State))
+	hmcv.Status = status
+	return r.Status().Update(ctx, hmcv)
+}
+
+func (r *HumioMultiClusterViewReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
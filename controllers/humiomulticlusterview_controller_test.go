@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestDiffMultiClusterViewConnections(t *testing.T) {
+	local := humio.MultiClusterViewConnection{ClusterIdentity: "eu", TargetViewName: "repo"}
+	remote := humio.MultiClusterViewConnection{ClusterIdentity: "us", PublicURL: "https://us.example.com", Token: "token"}
+	withID := func(c humio.MultiClusterViewConnection, id string) humio.MultiClusterViewConnection {
+		c.ID = id
+		c.Token = ""
+		return c
+	}
+
+	tt := []struct {
+		name            string
+		current         []humio.MultiClusterViewConnection
+		desired         []humio.MultiClusterViewConnection
+		tokenHashes     map[string]string
+		lastTokenHashes map[string]string
+		expectedAdd     []humio.MultiClusterViewConnection
+		expectedUpdate  []humio.MultiClusterViewConnection
+		expectedRemove  []string
+	}{
+		{
+			name:        "new view",
+			desired:     []humio.MultiClusterViewConnection{local, remote},
+			expectedAdd: []humio.MultiClusterViewConnection{local, remote},
+		},
+		{
+			name:            "unchanged",
+			current:         []humio.MultiClusterViewConnection{withID(local, "1"), withID(remote, "2")},
+			desired:         []humio.MultiClusterViewConnection{local, remote},
+			tokenHashes:     map[string]string{"us": "a"},
+			lastTokenHashes: map[string]string{"us": "a"},
+		},
+		{
+			name:            "rotated token",
+			current:         []humio.MultiClusterViewConnection{withID(remote, "2")},
+			desired:         []humio.MultiClusterViewConnection{remote},
+			tokenHashes:     map[string]string{"us": "b"},
+			lastTokenHashes: map[string]string{"us": "a"},
+			expectedUpdate:  []humio.MultiClusterViewConnection{{ID: "2", ClusterIdentity: "us", PublicURL: "https://us.example.com", Token: "token"}},
+		},
+		{
+			name:           "changed filter",
+			current:        []humio.MultiClusterViewConnection{withID(local, "1")},
+			desired:        []humio.MultiClusterViewConnection{{ClusterIdentity: "eu", TargetViewName: "repo", QueryPrefix: "#type=accesslog"}},
+			expectedUpdate: []humio.MultiClusterViewConnection{{ID: "1", ClusterIdentity: "eu", TargetViewName: "repo", QueryPrefix: "#type=accesslog"}},
+		},
+		{
+			name:           "local connection made remote",
+			current:        []humio.MultiClusterViewConnection{withID(local, "1")},
+			desired:        []humio.MultiClusterViewConnection{{ClusterIdentity: "eu", PublicURL: "https://eu.example.com", Token: "token"}},
+			expectedAdd:    []humio.MultiClusterViewConnection{{ClusterIdentity: "eu", PublicURL: "https://eu.example.com", Token: "token"}},
+			expectedRemove: []string{"1"},
+		},
+		{
+			name:           "removed and untagged connections",
+			current:        []humio.MultiClusterViewConnection{withID(local, "1"), withID(remote, "2"), {ID: "3", TargetViewName: "other"}},
+			desired:        []humio.MultiClusterViewConnection{local},
+			expectedRemove: []string{"3", "2"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			add, update, remove := diffMultiClusterViewConnections(tc.current, tc.desired, tc.tokenHashes, tc.lastTokenHashes)
+			if !reflect.DeepEqual(add, tc.expectedAdd) {
+				t.Errorf("expected add %+v, got %+v", tc.expectedAdd, add)
+			}
+			if !reflect.DeepEqual(update, tc.expectedUpdate) {
+				t.Errorf("expected update %+v, got %+v", tc.expectedUpdate, update)
+			}
+			if !reflect.DeepEqual(remove, tc.expectedRemove) {
+				t.Errorf("expected remove %v, got %v", tc.expectedRemove, remove)
+			}
+		})
+	}
+}
+
+func TestDesiredMultiClusterViewConnections(t *testing.T) {
+	tokenSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "us-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("secret-token")},
+	}
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	r := &HumioMultiClusterViewReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tokenSecret).Build(),
+		Log:    logr.Discard(),
+	}
+
+	tt := []struct {
+		name          string
+		connections   []humiov1alpha1.HumioMultiClusterViewConnection
+		expected      []humio.MultiClusterViewConnection
+		expectedError bool
+	}{
+		{
+			name: "local and remote connections",
+			connections: []humiov1alpha1.HumioMultiClusterViewConnection{
+				{ClusterIdentity: "eu", ViewOrRepoName: "repo"},
+				{ClusterIdentity: "eu-filtered", ManagedClusterName: "humio", ViewOrRepoName: "repo", Filter: "#type=accesslog"},
+				{ClusterIdentity: "us", URL: "https://us.example.com", APITokenSecretName: "us-token"},
+			},
+			expected: []humio.MultiClusterViewConnection{
+				{ClusterIdentity: "eu", TargetViewName: "repo"},
+				{ClusterIdentity: "eu-filtered", TargetViewName: "repo", QueryPrefix: "#type=accesslog"},
+				{ClusterIdentity: "us", PublicURL: "https://us.example.com", Token: "secret-token"},
+			},
+		},
+		{
+			name: "duplicate cluster identity",
+			connections: []humiov1alpha1.HumioMultiClusterViewConnection{
+				{ClusterIdentity: "eu", ViewOrRepoName: "repo"},
+				{ClusterIdentity: "eu", ViewOrRepoName: "other"},
+			},
+			expectedError: true,
+		},
+		{
+			name:          "local connection without view",
+			connections:   []humiov1alpha1.HumioMultiClusterViewConnection{{ClusterIdentity: "eu"}},
+			expectedError: true,
+		},
+		{
+			name:          "remote connection without token",
+			connections:   []humiov1alpha1.HumioMultiClusterViewConnection{{ClusterIdentity: "us", URL: "https://us.example.com"}},
+			expectedError: true,
+		},
+		{
+			name:          "remote connection with missing token secret",
+			connections:   []humiov1alpha1.HumioMultiClusterViewConnection{{ClusterIdentity: "us", URL: "https://us.example.com", APITokenSecretName: "missing"}},
+			expectedError: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hmcv := &humiov1alpha1.HumioMultiClusterView{
+				ObjectMeta: metav1.ObjectMeta{Name: "view", Namespace: "default"},
+				Spec:       humiov1alpha1.HumioMultiClusterViewSpec{ManagedClusterName: "humio", Name: "view", Connections: tc.connections},
+			}
+			connections, tokenHashes, err := r.desiredConnections(context.Background(), hmcv)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %t, got %v", tc.expectedError, err)
+			}
+			if !reflect.DeepEqual(connections, tc.expected) {
+				t.Errorf("expected connections %+v, got %+v", tc.expected, connections)
+			}
+			if err == nil && tokenHashes["us"] != helpers.AsSHA256("secret-token") {
+				t.Errorf("expected token hash of remote connection, got %v", tokenHashes)
+			}
+		})
+	}
+}
+
+func TestReconcileMultiClusterView(t *testing.T) {
+	humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+	r := &HumioMultiClusterViewReconciler{
+		HumioClient: humioClient,
+		Log:         logr.Discard(),
+	}
+	hmcv := &humiov1alpha1.HumioMultiClusterView{
+		Spec: humiov1alpha1.HumioMultiClusterViewSpec{Name: "view", Description: "all regions"},
+	}
+	local := humio.MultiClusterViewConnection{ClusterIdentity: "eu", TargetViewName: "repo"}
+	remote := humio.MultiClusterViewConnection{ClusterIdentity: "us", PublicURL: "https://us.example.com", Token: "token"}
+	tokenHashes := map[string]string{"us": helpers.AsSHA256("token")}
+
+	if err := r.reconcileMultiClusterView(&humioapi.Config{}, reconcile.Request{}, hmcv, []humio.MultiClusterViewConnection{local, remote}, tokenHashes); err != nil {
+		t.Fatal(err)
+	}
+	view, err := humioClient.GetMultiClusterView(&humioapi.Config{}, reconcile.Request{}, hmcv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Description != "all regions" || len(view.Connections) != 2 {
+		t.Fatalf("expected view with two connections, got %+v", view)
+	}
+
+	hmcv.Status.ConnectionTokenHashes = tokenHashes
+	if err := r.reconcileMultiClusterView(&humioapi.Config{}, reconcile.Request{}, hmcv, []humio.MultiClusterViewConnection{local}, tokenHashes); err != nil {
+		t.Fatal(err)
+	}
+	view, _ = humioClient.GetMultiClusterView(&humioapi.Config{}, reconcile.Request{}, hmcv)
+	if len(view.Connections) != 1 || view.Connections[0].ClusterIdentity != "eu" {
+		t.Errorf("expected remote connection to be removed, got %+v", view.Connections)
+	}
+}
@@ -20,19 +20,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"sort"
+	"reflect"
 	"time"
 
-	"github.com/google/go-cmp/cmp"
 	humioapi "github.com/humio/cli/api"
 	"github.com/humio/humio-operator/pkg/helpers"
 	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
 	"github.com/humio/humio-operator/pkg/humio"
@@ -45,11 +47,13 @@ type HumioParserReconciler struct {
 	Log         logr.Logger
 	HumioClient humio.Client
 	Namespace   string
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioparsers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioparsers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioparsers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (r *HumioParserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	if r.Namespace != "" {
@@ -78,6 +82,17 @@ func (r *HumioParserReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	r.Log = r.Log.WithValues("Request.UID", hp.UID)
 
 	cluster, err := helpers.NewCluster(ctx, r, hp.Spec.ManagedClusterName, hp.Spec.ExternalClusterName, hp.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hp.Namespace, hp.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		err = r.setState(ctx, humiov1alpha1.HumioParserStateClusterUnavailable, hp)
+		if err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
 	if err != nil || cluster == nil || cluster.Config() == nil {
 		r.Log.Error(err, "unable to obtain humio client config")
 		err = r.setState(ctx, humiov1alpha1.HumioParserStateConfigError, hp)
@@ -87,6 +102,11 @@ func (r *HumioParserReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
 	}
 
+	if request, requested := resyncRequested(hp, hp.Status.LastResyncRequest); requested {
+		r.Log.Info(fmt.Sprintf("resyncing parser as requested by annotation %s=%s", triggerResyncAnnotation, request))
+		r.HumioClient.InvalidateReadCache(cluster.Config())
+	}
+
 	r.Log.Info("Checking if parser is marked to be deleted")
 	// Check if the HumioParser instance is marked to be deleted, which is
 	// indicated by the deletion timestamp being set.
@@ -123,8 +143,20 @@ func (r *HumioParserReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
+	desiredHash := desiredStateHash([]interface{}{hp.Spec.ParserScript, hp.Spec.TagFields, hp.Spec.TestData})
+	if hp.Status.State == humiov1alpha1.HumioParserStateExists &&
+		syncUnchanged(hp, hp.Status.SyncedGeneration, hp.Status.AppliedHash, desiredHash, hp.Status.LastResyncRequest, hp.Status.LastSyncTime, time.Now()) {
+		requeue := requeueInterval(hp, time.Second*15)
+		r.Log.Info(fmt.Sprintf("parser is unchanged since it was last synced, will requeue after %s", requeue))
+		return reconcile.Result{RequeueAfter: requeue}, nil
+	}
+
 	defer func(ctx context.Context, humioClient humio.Client, hp *humiov1alpha1.HumioParser) {
 		curParser, err := humioClient.GetParser(cluster.Config(), req, hp)
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			_ = r.setState(ctx, humiov1alpha1.HumioParserStateClusterUnavailable, hp)
+			return
+		}
 		if errors.As(err, &humioapi.EntityNotFound{}) {
 			_ = r.setState(ctx, humiov1alpha1.HumioParserStateNotFound, hp)
 			return
@@ -153,44 +185,46 @@ func (r *HumioParserReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return reconcile.Result{}, r.logErrorAndReturn(err, "could not check if parser exists")
 	}
 
-	currentTagFields := make([]string, len(curParser.TagFields))
-	expectedTagFields := make([]string, len(hp.Spec.TagFields))
-	currentTests := make([]string, len(curParser.Tests))
-	expectedTests := make([]string, len(hp.Spec.TestData))
-	_ = copy(currentTagFields, curParser.TagFields)
-	_ = copy(expectedTagFields, hp.Spec.TagFields)
-	_ = copy(currentTests, curParser.Tests)
-	_ = copy(expectedTests, hp.Spec.TestData)
-	sort.Strings(currentTagFields)
-	sort.Strings(expectedTagFields)
-	sort.Strings(currentTests)
-	sort.Strings(expectedTests)
-	parserScriptDiff := cmp.Diff(curParser.Script, hp.Spec.ParserScript)
-	tagFieldsDiff := cmp.Diff(curParser.TagFields, hp.Spec.TagFields)
-	testDataDiff := cmp.Diff(curParser.Tests, hp.Spec.TestData)
-
-	if parserScriptDiff != "" || tagFieldsDiff != "" || testDataDiff != "" {
+	parserScriptDiff, tagFieldsDiff, testDataDiff := parserDiff(curParser, hp)
+	outcome := parserDrift(curParser, hp)
+	if outcome == driftIgnore {
+		r.Log.Info("parser was changed outside the operator, leaving the changes in place because of the drift policy")
+	}
+	if outcome.updates() {
 		r.Log.Info("parser information differs, triggering update", "parserScriptDiff", parserScriptDiff, "tagFieldsDiff", tagFieldsDiff, "testDataDiff", testDataDiff)
-		_, err = r.HumioClient.UpdateParser(cluster.Config(), req, hp)
+		if helpers.UseHumioClientBulkListing() {
+			err = r.updateParsersInBatch(ctx, cluster.Config(), req, hp)
+		} else {
+			_, err = r.HumioClient.UpdateParser(cluster.Config(), req, hp)
+		}
 		if err != nil {
 			return reconcile.Result{}, r.logErrorAndReturn(err, "could not update parser")
 		}
 	}
+	var patch string
+	if outcome == driftIgnore && hp.Spec.DriftPolicy == humiov1alpha1.HumioDriftPolicyImport {
+		patch = driftPatch(hp.Spec, importedParserSpec(hp.Spec, curParser))
+	}
+	if err := r.setSyncStatus(ctx, outcome, desiredHash, patch, curParser.ID, time.Now(), hp); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set parser sync status")
+	}
 
 	// TODO: handle updates to parser name and repositoryName. Right now we just create the new parser,
 	// and "leak/leave behind" the old parser.
 	// A solution could be to add an annotation that includes the "old name" so we can see if it was changed.
 	// A workaround for now is to delete the parser CR and create it again.
 
-	r.Log.Info("done reconciling, will requeue after 15 seconds")
-	return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	requeue := requeueInterval(hp, time.Second*15)
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+	return reconcile.Result{RequeueAfter: requeue}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *HumioParserReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&humiov1alpha1.HumioParser{}).
-		Complete(r)
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioParserList{}))).
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioParser{}, r))
 }
 
 func (r *HumioParserReconciler) finalize(ctx context.Context, config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) error {
@@ -226,7 +260,31 @@ func (r *HumioParserReconciler) setState(ctx context.Context, state string, hp *
 	return r.Status().Update(ctx, hp)
 }
 
+func (r *HumioParserReconciler) setSyncStatus(ctx context.Context, outcome driftOutcome, desiredHash, patch, id string, now time.Time, hp *humiov1alpha1.HumioParser) error {
+	status := hp.Status.DeepCopy()
+	applyDriftOutcome(r.Recorder, hp, outcome, desiredHash, &status.AppliedHash, &status.Conditions, hp.Generation)
+	status.DriftPatch = patch
+	status.ID = id
+	status.SyncedGeneration = hp.Generation
+	status.ClusterName = syncClusterName(hp.Spec.ManagedClusterName, hp.Spec.ExternalClusterName)
+	status.LastSyncTime = syncTime(status.LastSyncTime, now)
+	status.LastResyncRequest = hp.Annotations[triggerResyncAnnotation]
+	if reflect.DeepEqual(hp.Status, *status) {
+		return nil
+	}
+	hp.Status = *status
+	return r.Status().Update(ctx, hp)
+}
+
 func (r *HumioParserReconciler) logErrorAndReturn(err error, msg string) error {
 	r.Log.Error(err, msg)
 	return fmt.Errorf("%s: %w", msg, err)
 }
+
+// importedParserSpec returns the spec of the parser with the changes made to the parser inside Humio
+func importedParserSpec(spec humiov1alpha1.HumioParserSpec, parser *humioapi.Parser) humiov1alpha1.HumioParserSpec {
+	spec.ParserScript = parser.Script
+	spec.TagFields = parser.TagFields
+	spec.TestData = parser.Tests
+	return spec
+}
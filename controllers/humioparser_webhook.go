@@ -0,0 +1,113 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// HumioParserValidator validates HumioParser resources by testing the parser script against the test data of the
+// parser on the Humio cluster, and rejects parsers which fail to parse any of the test events. Parsers are admitted
+// with a warning if the cluster cannot be reached, so the webhook does not block changes while the cluster is down.
+type HumioParserValidator struct {
+	client.Client
+	HumioClient humio.Client
+	Log         logr.Logger
+}
+
+//+kubebuilder:webhook:path=/validate-core-humio-com-v1alpha1-humioparser,mutating=false,failurePolicy=ignore,sideEffects=None,groups=core.humio.com,resources=humioparsers,verbs=create;update,versions=v1alpha1,name=vhumioparser.core.humio.com,admissionReviewVersions=v1
+
+// SetupWebhookWithManager registers the validating webhook with the Manager.
+func (v *HumioParserValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&humiov1alpha1.HumioParser{}).
+		WithValidator(v).
+		Complete()
+}
+
+func (v *HumioParserValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	hp, ok := obj.(*humiov1alpha1.HumioParser)
+	if !ok {
+		return nil, fmt.Errorf("expected a HumioParser but got a %T", obj)
+	}
+	return v.validate(ctx, hp)
+}
+
+func (v *HumioParserValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldHp, ok := oldObj.(*humiov1alpha1.HumioParser)
+	if !ok {
+		return nil, fmt.Errorf("expected a HumioParser but got a %T", oldObj)
+	}
+	hp, ok := newObj.(*humiov1alpha1.HumioParser)
+	if !ok {
+		return nil, fmt.Errorf("expected a HumioParser but got a %T", newObj)
+	}
+	// Only test the parser when the script or the test data changes, so updates such as removing the finalizer of a
+	// parser are never rejected
+	if hp.GetDeletionTimestamp() != nil ||
+		(oldHp.Spec.ParserScript == hp.Spec.ParserScript && reflect.DeepEqual(oldHp.Spec.TestData, hp.Spec.TestData)) {
+		return nil, nil
+	}
+	return v.validate(ctx, hp)
+}
+
+func (v *HumioParserValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate tests the parser against the cluster and returns an error if any of the test events could not be parsed.
+// Parsers without test data are admitted without being tested.
+func (v *HumioParserValidator) validate(ctx context.Context, hp *humiov1alpha1.HumioParser) (admission.Warnings, error) {
+	if len(hp.Spec.TestData) == 0 {
+		return nil, nil
+	}
+	log := v.Log.WithValues("Request.Namespace", hp.Namespace, "Request.Name", hp.Name, "Request.Type", helpers.GetTypeName(v))
+
+	cluster, err := helpers.NewCluster(ctx, v, hp.Spec.ManagedClusterName, hp.Spec.ExternalClusterName, hp.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, v, cluster, hp.Namespace, hp.Spec.APITokenSecretName)
+	}
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		log.Error(err, "unable to obtain humio client config, parser was not tested")
+		return admission.Warnings{"parser was not tested as the Humio cluster is unavailable"}, nil
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: hp.Namespace, Name: hp.Name}}
+	parseErrors, err := v.HumioClient.TestParser(cluster.Config(), req, hp)
+	if err != nil {
+		log.Error(err, "unable to test parser")
+		return admission.Warnings{fmt.Sprintf("parser was not tested as the Humio cluster returned an error: %s", err)}, nil
+	}
+	if len(parseErrors) > 0 {
+		return nil, fmt.Errorf("parser failed to parse %d of %d test events: %s", len(parseErrors), len(hp.Spec.TestData), strings.Join(parseErrors, "; "))
+	}
+	return nil, nil
+}
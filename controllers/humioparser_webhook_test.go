@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHumioParserValidator(t *testing.T) {
+	cluster := &humiov1alpha1.HumioCluster{ObjectMeta: metav1.ObjectMeta{Name: "humio", Namespace: "default"}}
+	apiToken := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("humio-%s", kubernetes.ServiceTokenSecretNameSuffix), Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("token")},
+	}
+	parser := func(clusterName string, testData ...string) *humiov1alpha1.HumioParser {
+		return &humiov1alpha1.HumioParser{
+			ObjectMeta: metav1.ObjectMeta{Name: "parser", Namespace: "default"},
+			Spec: humiov1alpha1.HumioParserSpec{
+				ManagedClusterName: clusterName,
+				Name:               "parser",
+				RepositoryName:     "repository",
+				ParserScript:       "kvParse()",
+				TestData:           testData,
+			},
+		}
+	}
+
+	tt := []struct {
+		name             string
+		parser           *humiov1alpha1.HumioParser
+		parseErrors      []string
+		expectedError    bool
+		expectedWarnings int
+	}{
+		{
+			name:   "test events are parsed",
+			parser: parser("humio", "a=b"),
+		},
+		{
+			name:          "test events fail to parse",
+			parser:        parser("humio", "a=b", "invalid"),
+			parseErrors:   []string{"could not parse event"},
+			expectedError: true,
+		},
+		{
+			name:        "parser without test data is not tested",
+			parser:      parser("humio"),
+			parseErrors: []string{"could not parse event"},
+		},
+		{
+			name:             "cluster is unavailable",
+			parser:           parser("missing", "a=b"),
+			parseErrors:      []string{"could not parse event"},
+			expectedWarnings: 1,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = humiov1alpha1.AddToScheme(scheme)
+			humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+			humioClient.SetParserTestErrors(tc.parseErrors)
+			v := &HumioParserValidator{
+				Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, apiToken).Build(),
+				HumioClient: humioClient,
+				Log:         logr.Discard(),
+			}
+
+			warnings, err := v.ValidateCreate(context.Background(), tc.parser)
+			if (err != nil) != tc.expectedError {
+				t.Errorf("expected error: %t, got %v", tc.expectedError, err)
+			}
+			if len(warnings) != tc.expectedWarnings {
+				t.Errorf("expected %d warnings, got %v", tc.expectedWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestHumioParserValidatorUpdate(t *testing.T) {
+	humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+	humioClient.SetParserTestErrors([]string{"could not parse event"})
+	v := &HumioParserValidator{
+		Client:      fake.NewClientBuilder().Build(),
+		HumioClient: humioClient,
+		Log:         logr.Discard(),
+	}
+	hp := &humiov1alpha1.HumioParser{
+		Spec: humiov1alpha1.HumioParserSpec{ParserScript: "kvParse()", TestData: []string{"invalid"}},
+	}
+	updated := hp.DeepCopy()
+	updated.Finalizers = nil
+
+	// The parser is not tested when neither the script nor the test data changes
+	warnings, err := v.ValidateUpdate(context.Background(), hp, updated)
+	if err != nil || len(warnings) != 0 {
+		t.Errorf("expected update to be admitted without testing the parser, got warnings %v and error %v", warnings, err)
+	}
+}
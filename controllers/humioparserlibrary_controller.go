@@ -0,0 +1,501 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	k8sclientset "k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	humioParserLibraryDefaultImage               = "alpine/git:2.43.0"
+	humioParserLibraryDefaultBranch              = "main"
+	humioParserLibraryDefaultSyncIntervalSeconds = 300
+	humioParserLibraryLabel                      = "humio.com/parser-library"
+	humioParserLibraryContainerName              = "sync"
+	// humioParserLibraryMaxSize is the maximum size of the parser definitions read from the logs of the sync job
+	humioParserLibraryMaxSize = 10 * 1024 * 1024
+)
+
+// humioParserLibraryScript clones the Git repository and writes the parser definitions to stdout as YAML documents,
+// which the operator reads from the logs of the container. Errors are written to the termination message of the
+// container, which holds the commit the definitions were read from when the clone succeeds.
+const humioParserLibraryScript = `set -e
+exec 2>/dev/termination-log
+if [ -n "$GIT_USERNAME" ]; then
+  git config --global credential.helper '!f() { echo "username=$GIT_USERNAME"; echo "password=$GIT_PASSWORD"; }; f'
+fi
+git clone --quiet --depth 1 --branch "$GIT_BRANCH" "$GIT_URL" /tmp/library
+cd "/tmp/library/$GIT_PATH"
+find . -type f \( -name '*.yaml' -o -name '*.yml' \) | sort | while read -r file; do
+  echo "---"
+  cat "$file"
+  echo
+done
+git rev-parse HEAD > /dev/termination-log
+`
+
+var humioParserLibraryInvalidNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// humioParserLibraryDefinition is a parser definition read from the Git repository of a HumioParserLibrary
+type humioParserLibraryDefinition struct {
+	Name         string   `json:"name"`
+	ParserScript string   `json:"parserScript"`
+	TagFields    []string `json:"tagFields,omitempty"`
+	TestData     []string `json:"testData,omitempty"`
+}
+
+// HumioParserLibraryReconciler reconciles a HumioParserLibrary object
+type HumioParserLibraryReconciler struct {
+	client.Client
+	// Clientset is used to read the parser definitions from the logs of the sync job, which are not available through
+	// the controller-runtime client
+	Clientset  k8sclientset.Interface
+	BaseLogger logr.Logger
+	Log        logr.Logger
+	Namespace  string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioparserlibraries,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioparserlibraries/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioparserlibraries/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioparsers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
+
+func (r *HumioParserLibraryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioParserLibrary")
+
+	hpl := &humiov1alpha1.HumioParserLibrary{}
+	if err := r.Get(ctx, req.NamespacedName, hpl); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hpl.UID)
+
+	status := *hpl.Status.DeepCopy()
+	now := metav1.Now()
+	if status.State == humiov1alpha1.HumioParserLibraryStateSyncing {
+		if err := r.updateSyncState(ctx, hpl, &status, now); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to update state of parser library sync")
+		}
+	} else if status.ObservedGeneration != hpl.Generation || status.LastScheduleTime == nil ||
+		!now.Time.Before(status.LastScheduleTime.Add(humioParserLibrarySyncInterval(hpl))) {
+		if err := r.startSync(ctx, hpl, &status, now); err != nil {
+			r.Log.Error(err, "unable to sync parser library")
+			status.State = humiov1alpha1.HumioParserLibraryStateConfigError
+			status.Message = err.Error()
+			status.ObservedGeneration = hpl.Generation
+			if err := r.setStatus(ctx, status, hpl); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set parser library status")
+			}
+			return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+		}
+	}
+
+	if err := r.setStatus(ctx, status, hpl); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set parser library status")
+	}
+	if status.State == humiov1alpha1.HumioParserLibraryStateSyncing {
+		return reconcile.Result{RequeueAfter: time.Second * 10}, nil
+	}
+	return reconcile.Result{RequeueAfter: status.LastScheduleTime.Add(humioParserLibrarySyncInterval(hpl)).Sub(now.Time)}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioParserLibraryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioParserLibrary{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioParserLibraryList{}))).
+		Owns(&batchv1.Job{}).
+		Complete(entityReconciler(r))
+}
+
+func humioParserLibrarySyncInterval(hpl *humiov1alpha1.HumioParserLibrary) time.Duration {
+	if hpl.Spec.SyncIntervalSeconds <= 0 {
+		return time.Second * humioParserLibraryDefaultSyncIntervalSeconds
+	}
+	return time.Second * time.Duration(hpl.Spec.SyncIntervalSeconds)
+}
+
+func validateHumioParserLibrary(hpl *humiov1alpha1.HumioParserLibrary) error {
+	if hpl.Spec.Git.URL == "" {
+		return fmt.Errorf("git.url must be specified")
+	}
+	if hpl.Spec.RepositoryName == "" {
+		return fmt.Errorf("repositoryName must be specified")
+	}
+	if strings.Contains(hpl.Spec.Git.Path, "..") {
+		return fmt.Errorf("git.path must be within the Git repository")
+	}
+	return nil
+}
+
+// startSync creates the job which clones the Git repository of the library
+func (r *HumioParserLibraryReconciler) startSync(ctx context.Context, hpl *humiov1alpha1.HumioParserLibrary, status *humiov1alpha1.HumioParserLibraryStatus, now metav1.Time) error {
+	if err := validateHumioParserLibrary(hpl); err != nil {
+		return err
+	}
+
+	job := constructHumioParserLibraryJob(hpl, now)
+	if err := controllerutil.SetControllerReference(hpl, job, r.Scheme()); err != nil {
+		return err
+	}
+	r.Log.Info(fmt.Sprintf("creating parser library sync job %s cloning %s", job.Name, hpl.Spec.Git.URL))
+	if err := r.Create(ctx, job); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("unable to create parser library sync job: %w", err)
+	}
+
+	status.State = humiov1alpha1.HumioParserLibraryStateSyncing
+	status.Message = ""
+	status.JobName = job.Name
+	status.LastScheduleTime = &now
+	status.ObservedGeneration = hpl.Generation
+	return nil
+}
+
+func constructHumioParserLibraryJob(hpl *humiov1alpha1.HumioParserLibrary, now metav1.Time) *batchv1.Job {
+	name := newJobName(hpl.Name, now)
+
+	image := hpl.Spec.Image
+	if image == "" {
+		image = humioParserLibraryDefaultImage
+	}
+	branch := hpl.Spec.Git.Branch
+	if branch == "" {
+		branch = humioParserLibraryDefaultBranch
+	}
+	env := []corev1.EnvVar{
+		{Name: "HOME", Value: "/tmp"},
+		{Name: "GIT_URL", Value: hpl.Spec.Git.URL},
+		{Name: "GIT_BRANCH", Value: branch},
+		{Name: "GIT_PATH", Value: strings.Trim(hpl.Spec.Git.Path, "/")},
+	}
+	if secretName := hpl.Spec.Git.CredentialsSecretName; secretName != "" {
+		for _, key := range []string{"username", "password"} {
+			env = append(env, corev1.EnvVar{
+				Name: fmt.Sprintf("GIT_%s", strings.ToUpper(key)),
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  key,
+				}},
+			})
+		}
+	}
+
+	backoffLimit := int32(2)
+	labels := map[string]string{
+		humioParserLibraryLabel:        hpl.Name,
+		"app.kubernetes.io/managed-by": "humio-operator",
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: hpl.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: hpl.Spec.ServiceAccountName,
+					Containers: []corev1.Container{
+						{
+							Name:    humioParserLibraryContainerName,
+							Image:   image,
+							Command: []string{"/bin/sh", "-c", humioParserLibraryScript},
+							Env:     env,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// updateSyncState updates the state of the library from its sync job, and applies the parser definitions read by
+// the job once it has completed. The job is deleted once it has finished.
+func (r *HumioParserLibraryReconciler) updateSyncState(ctx context.Context, hpl *humiov1alpha1.HumioParserLibrary, status *humiov1alpha1.HumioParserLibraryStatus, now metav1.Time) error {
+	var job batchv1.Job
+	err := r.Get(ctx, types.NamespacedName{Namespace: hpl.Namespace, Name: status.JobName}, &job)
+	if k8serrors.IsNotFound(err) {
+		status.State = humiov1alpha1.HumioParserLibraryStateSyncFailed
+		status.Message = "parser library sync job was deleted before it completed"
+		status.JobName = ""
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			commit, logs := r.syncJobOutput(ctx, &job, true)
+			if logs == nil {
+				err = fmt.Errorf("unable to read parser definitions from sync job %s", job.Name)
+			} else {
+				err = r.applyParserLibrary(ctx, hpl, commit, logs, status)
+			}
+			if err != nil {
+				r.Log.Error(err, "unable to apply parser library")
+				status.State = humiov1alpha1.HumioParserLibraryStateSyncFailed
+				status.Message = err.Error()
+			} else {
+				status.State = humiov1alpha1.HumioParserLibraryStateSynced
+				status.Message = ""
+				status.LastSyncTime = &now
+				r.Log.Info(fmt.Sprintf("synced %d parsers from commit %s", len(status.Parsers), status.Commit))
+			}
+		case batchv1.JobFailed:
+			message, _ := r.syncJobOutput(ctx, &job, false)
+			if message == "" {
+				message = condition.Message
+			}
+			status.State = humiov1alpha1.HumioParserLibraryStateSyncFailed
+			status.Message = fmt.Sprintf("unable to clone %s: %s", hpl.Spec.Git.URL, message)
+			r.Log.Info(fmt.Sprintf("parser library sync job failed: %s", status.Message))
+		default:
+			continue
+		}
+		status.JobName = ""
+		err := r.Delete(ctx, &job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	return nil
+}
+
+// syncJobOutput returns the termination message of the last finished pod of the given sync job, and the logs of the
+// pod if requested. The termination message holds the commit the definitions were read from if the pod succeeded, and
+// the error otherwise.
+func (r *HumioParserLibraryReconciler) syncJobOutput(ctx context.Context, job *batchv1.Job, withLogs bool) (string, []byte) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		r.Log.Error(err, "unable to list parser library sync pods")
+		return "", nil
+	}
+	for _, pod := range pods.Items {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			terminated := containerStatus.State.Terminated
+			if terminated == nil || (withLogs && terminated.ExitCode != 0) {
+				continue
+			}
+			message := strings.TrimSpace(terminated.Message)
+			if !withLogs {
+				return message, nil
+			}
+			if r.Clientset == nil {
+				return message, nil
+			}
+			limitBytes := int64(humioParserLibraryMaxSize)
+			logs, err := r.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container:  humioParserLibraryContainerName,
+				LimitBytes: &limitBytes,
+			}).DoRaw(ctx)
+			if err != nil {
+				r.Log.Error(err, fmt.Sprintf("unable to get logs of pod %s", pod.Name))
+				return message, nil
+			}
+			return message, logs
+		}
+	}
+	return "", nil
+}
+
+// applyParserLibrary materializes the parser definitions read from the given commit as HumioParser resources, and
+// deletes the parsers of the library which are no longer defined. The parsers are left unchanged if any of the
+// definitions is invalid.
+func (r *HumioParserLibraryReconciler) applyParserLibrary(ctx context.Context, hpl *humiov1alpha1.HumioParserLibrary, commit string, data []byte, status *humiov1alpha1.HumioParserLibraryStatus) error {
+	definitions, err := parseHumioParserLibraryDefinitions(data)
+	if err != nil {
+		return err
+	}
+	parsers, err := constructHumioParserLibraryParsers(hpl, definitions)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for _, desired := range parsers {
+		hp := &humiov1alpha1.HumioParser{ObjectMeta: metav1.ObjectMeta{Namespace: desired.Namespace, Name: desired.Name}}
+		op, err := controllerutil.CreateOrUpdate(ctx, r, hp, func() error {
+			if hp.Labels[humioParserLibraryLabel] != "" && hp.Labels[humioParserLibraryLabel] != hpl.Name {
+				return fmt.Errorf("parser %s belongs to parser library %s", hp.Name, hp.Labels[humioParserLibraryLabel])
+			}
+			if hp.ResourceVersion != "" && hp.Labels[humioParserLibraryLabel] == "" {
+				return fmt.Errorf("parser %s already exists and is not managed by the parser library", hp.Name)
+			}
+			if hp.Labels == nil {
+				hp.Labels = map[string]string{}
+			}
+			hp.Labels[humioParserLibraryLabel] = hpl.Name
+			hp.Spec = desired.Spec
+			return controllerutil.SetControllerReference(hpl, hp, r.Scheme())
+		})
+		if err != nil {
+			return err
+		}
+		if op != controllerutil.OperationResultNone {
+			r.Log.Info(fmt.Sprintf("parser %s %s", hp.Name, op))
+		}
+		names[hp.Name] = true
+	}
+
+	var existing humiov1alpha1.HumioParserList
+	if err := r.List(ctx, &existing, client.InNamespace(hpl.Namespace), client.MatchingLabels{humioParserLibraryLabel: hpl.Name}); err != nil {
+		return err
+	}
+	for i := range existing.Items {
+		hp := &existing.Items[i]
+		if names[hp.Name] {
+			continue
+		}
+		r.Log.Info(fmt.Sprintf("deleting parser %s which is no longer defined in the parser library", hp.Name))
+		if err := r.Delete(ctx, hp); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	status.Commit = commit
+	status.Parsers = nil
+	for name := range names {
+		status.Parsers = append(status.Parsers, name)
+	}
+	sort.Strings(status.Parsers)
+	return nil
+}
+
+// parseHumioParserLibraryDefinitions parses the YAML documents written by the sync job. Empty documents are skipped.
+func parseHumioParserLibraryDefinitions(data []byte) ([]humioParserLibraryDefinition, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	var definitions []humioParserLibraryDefinition
+	for {
+		var definition humioParserLibraryDefinition
+		err := decoder.Decode(&definition)
+		if errors.Is(err, io.EOF) {
+			return definitions, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse parser definition: %w", err)
+		}
+		if reflect.DeepEqual(definition, humioParserLibraryDefinition{}) {
+			continue
+		}
+		definitions = append(definitions, definition)
+	}
+}
+
+// constructHumioParserLibraryParsers returns the HumioParser resources for the given parser definitions. Resources
+// are named after the library and the parser.
+func constructHumioParserLibraryParsers(hpl *humiov1alpha1.HumioParserLibrary, definitions []humioParserLibraryDefinition) ([]humiov1alpha1.HumioParser, error) {
+	var parsers []humiov1alpha1.HumioParser
+	resourceNames := map[string]string{}
+	for _, definition := range definitions {
+		if definition.Name == "" || definition.ParserScript == "" {
+			return nil, fmt.Errorf("parser definitions must specify name and parserScript, got parser %q", definition.Name)
+		}
+		name := humioParserLibraryParserName(hpl, definition.Name)
+		if other, ok := resourceNames[name]; ok {
+			return nil, fmt.Errorf("parsers %q and %q both map to the resource name %s", other, definition.Name, name)
+		}
+		resourceNames[name] = definition.Name
+		parsers = append(parsers, humiov1alpha1.HumioParser{
+			ObjectMeta: metav1.ObjectMeta{Namespace: hpl.Namespace, Name: name},
+			Spec: humiov1alpha1.HumioParserSpec{
+				ManagedClusterName:  hpl.Spec.ManagedClusterName,
+				ExternalClusterName: hpl.Spec.ExternalClusterName,
+				APITokenSecretName:  hpl.Spec.APITokenSecretName,
+				Name:                definition.Name,
+				ParserScript:        definition.ParserScript,
+				RepositoryName:      hpl.Spec.RepositoryName,
+				TagFields:           definition.TagFields,
+				TestData:            definition.TestData,
+			},
+		})
+	}
+	return parsers, nil
+}
+
+// humioParserLibraryParserName returns the name of the HumioParser resource for the parser with the given name
+func humioParserLibraryParserName(hpl *humiov1alpha1.HumioParserLibrary, parserName string) string {
+	name := humioParserLibraryInvalidNameCharacters.ReplaceAllString(strings.ToLower(parserName), "-")
+	name = strings.Trim(fmt.Sprintf("%s-%s", hpl.Name, strings.Trim(name, "-")), "-")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-")
+	}
+	return name
+}
+
+func (r *HumioParserLibraryReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioParserLibraryStatus, hpl *humiov1alpha1.HumioParserLibrary) error {
+	if reflect.DeepEqual(hpl.Status, status) {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting parser library state to %s", status.State))
+	hpl.Status = status
+	return r.Status().Update(ctx, hpl)
+}
+
+func (r *HumioParserLibraryReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseHumioParserLibraryDefinitions(t *testing.T) {
+	tt := []struct {
+		name          string
+		data          string
+		expected      []humioParserLibraryDefinition
+		expectedError bool
+	}{
+		{
+			name: "several files",
+			data: "---\nname: accesslog\nparserScript: kvParse()\ntagFields:\n- method\n\n---\nname: json\nparserScript: parseJson()\ntestData:\n- '{}'\n\n",
+			expected: []humioParserLibraryDefinition{
+				{Name: "accesslog", ParserScript: "kvParse()", TagFields: []string{"method"}},
+				{Name: "json", ParserScript: "parseJson()", TestData: []string{"{}"}},
+			},
+		},
+		{
+			name: "file with several definitions",
+			data: "---\nname: a\nparserScript: kvParse()\n---\nname: b\nparserScript: kvParse()\n\n",
+			expected: []humioParserLibraryDefinition{
+				{Name: "a", ParserScript: "kvParse()"},
+				{Name: "b", ParserScript: "kvParse()"},
+			},
+		},
+		{
+			name: "no definitions",
+			data: "",
+		},
+		{
+			name:          "invalid yaml",
+			data:          "---\nname: [a\n",
+			expectedError: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			definitions, err := parseHumioParserLibraryDefinitions([]byte(tc.data))
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %t, got %v", tc.expectedError, err)
+			}
+			if !reflect.DeepEqual(definitions, tc.expected) {
+				t.Errorf("expected definitions %+v, got %+v", tc.expected, definitions)
+			}
+		})
+	}
+}
+
+func TestConstructHumioParserLibraryParsers(t *testing.T) {
+	hpl := &humiov1alpha1.HumioParserLibrary{
+		ObjectMeta: metav1.ObjectMeta{Name: "library", Namespace: "default"},
+		Spec:       humiov1alpha1.HumioParserLibrarySpec{ManagedClusterName: "humio", RepositoryName: "repository"},
+	}
+
+	parsers, err := constructHumioParserLibraryParsers(hpl, []humioParserLibraryDefinition{{Name: "Access Log", ParserScript: "kvParse()"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsers[0].Name != "library-access-log" || parsers[0].Spec.Name != "Access Log" ||
+		parsers[0].Spec.ManagedClusterName != "humio" || parsers[0].Spec.RepositoryName != "repository" {
+		t.Errorf("unexpected parser %+v", parsers[0])
+	}
+
+	if _, err := constructHumioParserLibraryParsers(hpl, []humioParserLibraryDefinition{{Name: "accesslog"}}); err == nil {
+		t.Errorf("expected definition without parser script to be rejected")
+	}
+	_, err = constructHumioParserLibraryParsers(hpl, []humioParserLibraryDefinition{
+		{Name: "access log", ParserScript: "kvParse()"},
+		{Name: "access_log", ParserScript: "kvParse()"},
+	})
+	if err == nil {
+		t.Errorf("expected definitions mapping to the same resource name to be rejected")
+	}
+}
+
+func TestConstructHumioParserLibraryJob(t *testing.T) {
+	hpl := &humiov1alpha1.HumioParserLibrary{
+		ObjectMeta: metav1.ObjectMeta{Name: "library", Namespace: "default"},
+		Spec: humiov1alpha1.HumioParserLibrarySpec{
+			Git: humiov1alpha1.HumioParserLibraryGit{
+				URL:                   "https://github.com/example/parsers.git",
+				Path:                  "/parsers/",
+				CredentialsSecretName: "git-credentials",
+			},
+		},
+	}
+	now := metav1.NewTime(time.Date(2023, 6, 1, 12, 30, 0, 0, time.UTC))
+
+	job := constructHumioParserLibraryJob(hpl, now)
+	if !strings.HasPrefix(job.Name, "library-2306011230-") {
+		t.Errorf("unexpected job name %s", job.Name)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if container.Image != humioParserLibraryDefaultImage {
+		t.Errorf("expected default image, got %s", container.Image)
+	}
+	env := map[string]string{}
+	for _, envVar := range container.Env {
+		env[envVar.Name] = envVar.Value
+		if envVar.ValueFrom != nil {
+			env[envVar.Name] = envVar.ValueFrom.SecretKeyRef.Name + "/" + envVar.ValueFrom.SecretKeyRef.Key
+		}
+	}
+	expected := map[string]string{
+		"HOME":         "/tmp",
+		"GIT_URL":      "https://github.com/example/parsers.git",
+		"GIT_BRANCH":   humioParserLibraryDefaultBranch,
+		"GIT_PATH":     "parsers",
+		"GIT_USERNAME": "git-credentials/username",
+		"GIT_PASSWORD": "git-credentials/password",
+	}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("expected env %v, got %v", expected, env)
+	}
+}
+
+func TestApplyParserLibrary(t *testing.T) {
+	hpl := &humiov1alpha1.HumioParserLibrary{
+		ObjectMeta: metav1.ObjectMeta{Name: "library", Namespace: "default", UID: "library-uid"},
+		Spec:       humiov1alpha1.HumioParserLibrarySpec{ManagedClusterName: "humio", RepositoryName: "repository"},
+	}
+	unmanaged := &humiov1alpha1.HumioParser{ObjectMeta: metav1.ObjectMeta{Name: "library-unmanaged", Namespace: "default"}}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	r := &HumioParserLibraryReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(hpl, unmanaged).Build(),
+		Log:    logr.Discard(),
+	}
+	ctx := context.Background()
+
+	var status humiov1alpha1.HumioParserLibraryStatus
+	data := "---\nname: a\nparserScript: kvParse()\n---\nname: b\nparserScript: parseJson()\n"
+	if err := r.applyParserLibrary(ctx, hpl, "abc123", []byte(data), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Commit != "abc123" || !reflect.DeepEqual(status.Parsers, []string{"library-a", "library-b"}) {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	data = "---\nname: a\nparserScript: parseTimestamp()\n"
+	if err := r.applyParserLibrary(ctx, hpl, "def456", []byte(data), &status); err != nil {
+		t.Fatal(err)
+	}
+	var hp humiov1alpha1.HumioParser
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "library-a"}, &hp); err != nil {
+		t.Fatal(err)
+	}
+	if hp.Spec.ParserScript != "parseTimestamp()" || hp.Labels[humioParserLibraryLabel] != "library" || len(hp.OwnerReferences) != 1 {
+		t.Errorf("expected parser to be updated and owned by the library, got %+v", hp)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "library-b"}, &hp); err == nil {
+		t.Errorf("expected parser removed from the library to be deleted")
+	}
+
+	data = "---\nname: unmanaged\nparserScript: kvParse()\n"
+	if err := r.applyParserLibrary(ctx, hpl, "ghi789", []byte(data), &status); err == nil {
+		t.Errorf("expected existing parser not managed by the library to be left alone")
+	}
+}
@@ -0,0 +1,534 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	humioQueryExportDefaultS3Image      = awsCLIImage
+	humioQueryExportDefaultGCSImage     = "google/cloud-sdk:460.0.0-slim"
+	humioQueryExportDefaultHistoryLimit = 5
+	humioQueryExportDefaultKey          = `{{ .Name }}/{{ .Time.Format "2006-01-02T15-04-05Z" }}.{{ .Extension }}`
+	humioQueryExportLabel               = "humio.com/query-export"
+	// humioQueryExportSecretNameSuffix is the suffix of the secret holding the API token and CA certificate used by
+	// the export jobs to query the Humio cluster
+	humioQueryExportSecretNameSuffix = "query-export"
+)
+
+// humioQueryExportScript executes the query using the query endpoint of Humio, which returns the results in the
+// format given by the Accept header. Parquet files are converted from NDJSON using duckdb. The size of the exported
+// results is reported using the termination message of the container.
+const humioQueryExportScript = `set -e
+if [ -n "$HUMIO_CA_CERTIFICATE" ]; then
+  printf '%s' "$HUMIO_CA_CERTIFICATE" > /tmp/ca.crt
+  set -- --cacert /tmp/ca.crt
+fi
+curl -sSf "$@" ${HUMIO_INSECURE:+--insecure} -X POST \
+  -H "Authorization: Bearer $HUMIO_API_TOKEN" \
+  ${HUMIO_PROXY_ORGANIZATION:+-H "ProxyOrganization: $HUMIO_PROXY_ORGANIZATION"} \
+  -H "Content-Type: application/json" \
+  -H "Accept: $QUERY_ACCEPT" \
+  --data "$QUERY" -o /tmp/results "$HUMIO_QUERY_URL"
+if [ "$EXPORT_FORMAT" = "Parquet" ]; then
+  duckdb -c "COPY (SELECT * FROM read_json_auto('/tmp/results', format='newline_delimited')) TO '/tmp/results.parquet' (FORMAT PARQUET)"
+  mv /tmp/results.parquet /tmp/results
+fi
+case "$EXPORT_LOCATION" in
+  gs://*) gsutil cp /tmp/results "$EXPORT_LOCATION" ;;
+  *) aws ${AWS_ENDPOINT_URL:+--endpoint-url "$AWS_ENDPOINT_URL"} s3 cp /tmp/results "$EXPORT_LOCATION" ;;
+esac
+wc -c < /tmp/results | tr -d ' ' > /dev/termination-log
+`
+
+// humioQueryExportFormats maps each format to the Accept header requested from Humio and the file extension
+var humioQueryExportFormats = map[string]struct{ accept, extension string }{
+	humiov1alpha1.HumioQueryExportFormatCSV:     {"text/csv", "csv"},
+	humiov1alpha1.HumioQueryExportFormatNDJSON:  {"application/x-ndjson", "ndjson"},
+	humiov1alpha1.HumioQueryExportFormatParquet: {"application/x-ndjson", "parquet"},
+}
+
+// humioQueryExportKeyData is the data available to the key template of a HumioQueryExport
+type humioQueryExportKeyData struct {
+	Name      string
+	Namespace string
+	Time      time.Time
+	Extension string
+}
+
+// HumioQueryExportReconciler reconciles a HumioQueryExport object
+type HumioQueryExportReconciler struct {
+	client.Client
+	BaseLogger logr.Logger
+	Log        logr.Logger
+	Namespace  string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioqueryexports,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioqueryexports/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioqueryexports/finalizers,verbs=update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+
+func (r *HumioQueryExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioQueryExport")
+
+	// Fetch the HumioQueryExport instance
+	hqe := &humiov1alpha1.HumioQueryExport{}
+	err := r.Get(ctx, req.NamespacedName, hqe)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hqe.UID)
+
+	status := *hqe.Status.DeepCopy()
+	status.Message = ""
+	now := metav1.Now()
+
+	if err := r.updateExportArtifacts(ctx, hqe, &status); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to update state of exports")
+	}
+
+	if err := validateHumioQueryExport(hqe); err != nil {
+		status.State = humiov1alpha1.HumioQueryExportStateConfigError
+		status.Message = err.Error()
+		return reconcile.Result{}, r.setStatus(ctx, status, hqe)
+	}
+
+	due, next, err := helpers.CronScheduleDue(hqe.Spec.Schedule, hqe.CreationTimestamp.Time, status.LastScheduleTime, now.Time)
+	if err != nil {
+		status.State = humiov1alpha1.HumioQueryExportStateConfigError
+		status.Message = err.Error()
+		return reconcile.Result{}, r.setStatus(ctx, status, hqe)
+	}
+
+	if due && !hqe.Spec.Suspend && !humioQueryExportRunning(status) {
+		cluster, err := helpers.NewCluster(ctx, r, hqe.Spec.ManagedClusterName, hqe.Spec.ExternalClusterName, hqe.Namespace, helpers.UseCertManager(), true)
+		if err == nil {
+			err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hqe.Namespace, hqe.Spec.APITokenSecretName)
+		}
+		if err == nil && (cluster == nil || cluster.Config() == nil) {
+			err = fmt.Errorf("unable to obtain humio client config")
+		}
+		var artifact humiov1alpha1.HumioQueryExportArtifact
+		if err == nil {
+			artifact, err = r.startExport(ctx, hqe, cluster.Config(), now)
+		}
+		if err != nil {
+			r.Log.Error(err, "unable to start export")
+			status.State = humiov1alpha1.HumioQueryExportStateConfigError
+			if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+				status.State = humiov1alpha1.HumioQueryExportStateClusterUnavailable
+			}
+			status.Message = err.Error()
+			if err := r.setStatus(ctx, status, hqe); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set query export status")
+			}
+			return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+		}
+		status.LastScheduleTime = &now
+		status.Exports = append([]humiov1alpha1.HumioQueryExportArtifact{artifact}, status.Exports...)
+	}
+
+	if err := r.trimExportHistory(ctx, hqe, &status); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to clean up old exports")
+	}
+
+	status.State = humiov1alpha1.HumioQueryExportStateIdle
+	if humioQueryExportRunning(status) {
+		status.State = humiov1alpha1.HumioQueryExportStateRunning
+	}
+	if err := r.setStatus(ctx, status, hqe); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set query export status")
+	}
+
+	if status.State == humiov1alpha1.HumioQueryExportStateRunning {
+		return reconcile.Result{RequeueAfter: time.Second * 10}, nil
+	}
+	if !next.IsZero() {
+		r.Log.Info(fmt.Sprintf("next export scheduled at %s", next.Format(time.RFC3339)))
+		return reconcile.Result{RequeueAfter: next.Sub(now.Time)}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+func validateHumioQueryExport(hqe *humiov1alpha1.HumioQueryExport) error {
+	if hqe.Spec.ViewName == "" {
+		return fmt.Errorf("viewName must be specified")
+	}
+	if hqe.Spec.QueryString == "" {
+		return fmt.Errorf("queryString must be specified")
+	}
+	if hqe.Spec.Schedule == "" {
+		return fmt.Errorf("schedule must be specified")
+	}
+	if hqe.Spec.Destination.Bucket == "" {
+		return fmt.Errorf("bucket of the destination must be specified")
+	}
+	if hqe.Spec.Format == humiov1alpha1.HumioQueryExportFormatParquet && hqe.Spec.Image == "" {
+		return fmt.Errorf("exporting as Parquet requires an image which provides duckdb")
+	}
+	_, err := humioQueryExportKey(hqe, time.Time{})
+	return err
+}
+
+func humioQueryExportRunning(status humiov1alpha1.HumioQueryExportStatus) bool {
+	for _, artifact := range status.Exports {
+		if artifact.State == humiov1alpha1.HumioQueryExportStateRunning {
+			return true
+		}
+	}
+	return false
+}
+
+func humioQueryExportFormat(hqe *humiov1alpha1.HumioQueryExport) string {
+	if hqe.Spec.Format == "" {
+		return humiov1alpha1.HumioQueryExportFormatNDJSON
+	}
+	return hqe.Spec.Format
+}
+
+func humioQueryExportDestinationType(hqe *humiov1alpha1.HumioQueryExport) string {
+	if hqe.Spec.Destination.Type == "" {
+		return humiov1alpha1.HumioQueryExportDestinationS3
+	}
+	return hqe.Spec.Destination.Type
+}
+
+// humioQueryExportKey renders the key template of the export started at the given time
+func humioQueryExportKey(hqe *humiov1alpha1.HumioQueryExport, startTime time.Time) (string, error) {
+	keyTemplate := hqe.Spec.Destination.Key
+	if keyTemplate == "" {
+		keyTemplate = humioQueryExportDefaultKey
+	}
+	tmpl, err := template.New("key").Option("missingkey=error").Parse(keyTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid key template: %w", err)
+	}
+	var key bytes.Buffer
+	err = tmpl.Execute(&key, humioQueryExportKeyData{
+		Name:      hqe.Name,
+		Namespace: hqe.Namespace,
+		Time:      startTime.UTC(),
+		Extension: humioQueryExportFormats[humioQueryExportFormat(hqe)].extension,
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid key template: %w", err)
+	}
+	return strings.TrimPrefix(key.String(), "/"), nil
+}
+
+func humioQueryExportLocation(hqe *humiov1alpha1.HumioQueryExport, key string) string {
+	scheme := "s3"
+	if humioQueryExportDestinationType(hqe) == humiov1alpha1.HumioQueryExportDestinationGCS {
+		scheme = "gs"
+	}
+	return fmt.Sprintf("%s://%s/%s", scheme, hqe.Spec.Destination.Bucket, key)
+}
+
+// startExport stores the API token and CA certificate used to query the Humio cluster in a secret, and creates the
+// job which executes the query and uploads the results. The secret is updated for every export, as API tokens
+// obtained using OAuth2 are short-lived.
+func (r *HumioQueryExportReconciler) startExport(ctx context.Context, hqe *humiov1alpha1.HumioQueryExport, config *humioapi.Config, now metav1.Time) (humiov1alpha1.HumioQueryExportArtifact, error) {
+	key, err := humioQueryExportKey(hqe, now.Time)
+	if err != nil {
+		return humiov1alpha1.HumioQueryExportArtifact{}, err
+	}
+	location := humioQueryExportLocation(hqe, key)
+
+	secret := &corev1.Secret{}
+	secretName := fmt.Sprintf("%s-%s", hqe.Name, humioQueryExportSecretNameSuffix)
+	secretData := map[string][]byte{
+		"token":  []byte(config.Token),
+		"ca.crt": []byte(config.CACertificatePEM),
+	}
+	err = r.Get(ctx, types.NamespacedName{Namespace: hqe.Namespace, Name: secretName}, secret)
+	if k8serrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: hqe.Namespace,
+				Labels:    map[string]string{humioQueryExportLabel: hqe.Name, "app.kubernetes.io/managed-by": "humio-operator"},
+			},
+			Data: secretData,
+		}
+		if err := controllerutil.SetControllerReference(hqe, secret, r.Scheme()); err != nil {
+			return humiov1alpha1.HumioQueryExportArtifact{}, err
+		}
+		err = r.Create(ctx, secret)
+	} else if err == nil {
+		if !metav1.IsControlledBy(secret, hqe) {
+			return humiov1alpha1.HumioQueryExportArtifact{}, fmt.Errorf("secret %s already exists and is not owned by the query export", secretName)
+		}
+		secret.Data = secretData
+		err = r.Update(ctx, secret)
+	}
+	if err != nil {
+		return humiov1alpha1.HumioQueryExportArtifact{}, fmt.Errorf("unable to store api token for export: %w", err)
+	}
+
+	job, err := constructHumioQueryExportJob(hqe, config, secretName, location, now)
+	if err != nil {
+		return humiov1alpha1.HumioQueryExportArtifact{}, err
+	}
+	if err := controllerutil.SetControllerReference(hqe, job, r.Scheme()); err != nil {
+		return humiov1alpha1.HumioQueryExportArtifact{}, err
+	}
+	r.Log.Info(fmt.Sprintf("creating export job %s uploading to %s", job.Name, location))
+	if err := r.Create(ctx, job); err != nil {
+		return humiov1alpha1.HumioQueryExportArtifact{}, fmt.Errorf("unable to create export job: %w", err)
+	}
+
+	return humiov1alpha1.HumioQueryExportArtifact{
+		Name:      job.Name,
+		State:     humiov1alpha1.HumioQueryExportStateRunning,
+		Location:  location,
+		StartTime: &now,
+	}, nil
+}
+
+func constructHumioQueryExportJob(hqe *humiov1alpha1.HumioQueryExport, config *humioapi.Config, secretName, location string, now metav1.Time) (*batchv1.Job, error) {
+	name := newJobName(hqe.Name, now)
+
+	image := hqe.Spec.Image
+	if image == "" {
+		image = humioQueryExportDefaultS3Image
+		if humioQueryExportDestinationType(hqe) == humiov1alpha1.HumioQueryExportDestinationGCS {
+			image = humioQueryExportDefaultGCSImage
+		}
+	}
+
+	start := hqe.Spec.Start
+	if start == "" {
+		start = humioQueryJobDefaultStart
+	}
+	query, err := json.Marshal(humioapi.Query{
+		QueryString: hqe.Spec.QueryString,
+		Start:       start,
+		End:         hqe.Spec.End,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	format := humioQueryExportFormat(hqe)
+	env := []corev1.EnvVar{
+		{Name: "HUMIO_QUERY_URL", Value: config.Address.JoinPath("api/v1/repositories", hqe.Spec.ViewName, "query").String()},
+		{
+			Name: "HUMIO_API_TOKEN",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  "token",
+			}},
+		},
+		{
+			Name: "HUMIO_CA_CERTIFICATE",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  "ca.crt",
+			}},
+		},
+		{Name: "QUERY", Value: string(query)},
+		{Name: "QUERY_ACCEPT", Value: humioQueryExportFormats[format].accept},
+		{Name: "EXPORT_FORMAT", Value: format},
+		{Name: "EXPORT_LOCATION", Value: location},
+	}
+	if config.Insecure && config.Address.Scheme == "https" {
+		env = append(env, corev1.EnvVar{Name: "HUMIO_INSECURE", Value: "true"})
+	}
+	if config.ProxyOrganization != "" {
+		env = append(env, corev1.EnvVar{Name: "HUMIO_PROXY_ORGANIZATION", Value: config.ProxyOrganization})
+	}
+	if hqe.Spec.Destination.Region != "" {
+		env = append(env, corev1.EnvVar{Name: "AWS_REGION", Value: hqe.Spec.Destination.Region})
+	}
+	if hqe.Spec.Destination.Endpoint != "" {
+		env = append(env, corev1.EnvVar{Name: "AWS_ENDPOINT_URL", Value: hqe.Spec.Destination.Endpoint})
+	}
+	var envFrom []corev1.EnvFromSource
+	if hqe.Spec.Destination.CredentialsSecretName != "" {
+		envFrom = append(envFrom, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: hqe.Spec.Destination.CredentialsSecretName},
+			},
+		})
+	}
+
+	backoffLimit := int32(2)
+	labels := map[string]string{
+		humioQueryExportLabel:          hqe.Name,
+		"app.kubernetes.io/managed-by": "humio-operator",
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: hqe.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: hqe.Spec.ServiceAccountName,
+					Containers: []corev1.Container{
+						{
+							Name:    "export",
+							Image:   image,
+							Command: []string{"/bin/sh", "-c", humioQueryExportScript},
+							Env:     env,
+							EnvFrom: envFrom,
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// updateExportArtifacts updates the state of running exports from their jobs
+func (r *HumioQueryExportReconciler) updateExportArtifacts(ctx context.Context, hqe *humiov1alpha1.HumioQueryExport, status *humiov1alpha1.HumioQueryExportStatus) error {
+	for i := range status.Exports {
+		artifact := &status.Exports[i]
+		if artifact.State != humiov1alpha1.HumioQueryExportStateRunning {
+			continue
+		}
+		var job batchv1.Job
+		err := r.Get(ctx, types.NamespacedName{Namespace: hqe.Namespace, Name: artifact.Name}, &job)
+		if k8serrors.IsNotFound(err) {
+			artifact.State = humiov1alpha1.HumioQueryExportStateFailed
+			artifact.Message = "export job was deleted before it completed"
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				artifact.State = humiov1alpha1.HumioQueryExportStateSucceeded
+				artifact.CompletionTime = job.Status.CompletionTime
+				artifact.SizeBytes, err = jobReportedSize(ctx, r, &job)
+				if err != nil {
+					r.Log.Error(err, "unable to list export pods")
+				}
+				status.LastSuccessfulTime = job.Status.CompletionTime
+				r.Log.Info(fmt.Sprintf("export %s uploaded to %s", artifact.Name, artifact.Location))
+			case batchv1.JobFailed:
+				completionTime := condition.LastTransitionTime
+				artifact.State = humiov1alpha1.HumioQueryExportStateFailed
+				artifact.CompletionTime = &completionTime
+				artifact.Message = condition.Message
+				r.Log.Info(fmt.Sprintf("export %s failed: %s", artifact.Name, condition.Message))
+			}
+		}
+	}
+	return nil
+}
+
+// trimExportHistory removes the oldest exports from the status along with their jobs. The exported results stored in
+// the bucket are left untouched, so their retention can be managed using the lifecycle rules of the bucket.
+func (r *HumioQueryExportReconciler) trimExportHistory(ctx context.Context, hqe *humiov1alpha1.HumioQueryExport, status *humiov1alpha1.HumioQueryExportStatus) error {
+	historyLimit := hqe.Spec.HistoryLimit
+	if historyLimit <= 0 {
+		historyLimit = humioQueryExportDefaultHistoryLimit
+	}
+	if len(status.Exports) <= historyLimit {
+		return nil
+	}
+	for _, artifact := range status.Exports[historyLimit:] {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: hqe.Namespace, Name: artifact.Name}}
+		err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	status.Exports = status.Exports[:historyLimit]
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioQueryExportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioQueryExport{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioQueryExportList{}))).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+func (r *HumioQueryExportReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioQueryExportStatus, hqe *humiov1alpha1.HumioQueryExport) error {
+	if reflect.DeepEqual(hqe.Status, status) {
+		return nil
+	}
+	if hqe.Status.State != status.State {
+		r.Log.Info(fmt.Sprintf("setting query export state to %s", status.State))
+	}
+	hqe.Status = status
+	return r.Status().Update(ctx, hqe)
+}
+
+func (r *HumioQueryExportReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
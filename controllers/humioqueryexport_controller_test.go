@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHumioQueryExportKey(t *testing.T) {
+	startTime := time.Date(2024, time.January, 10, 2, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name     string
+		format   string
+		key      string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "default key",
+			expected: "export/2024-01-10T02-00-00Z.ndjson",
+		},
+		{
+			name:     "templated key",
+			format:   humiov1alpha1.HumioQueryExportFormatCSV,
+			key:      `/{{ .Namespace }}/{{ .Time.Format "2006/01/02" }}/{{ .Name }}.{{ .Extension }}`,
+			expected: "logging/2024/01/10/export.csv",
+		},
+		{
+			name:    "unknown field",
+			key:     "{{ .Bucket }}",
+			wantErr: true,
+		},
+		{
+			name:    "invalid template",
+			key:     "{{ .Name",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hqe := &humiov1alpha1.HumioQueryExport{
+				ObjectMeta: metav1.ObjectMeta{Name: "export", Namespace: "logging"},
+				Spec: humiov1alpha1.HumioQueryExportSpec{
+					Format:      tc.format,
+					Destination: humiov1alpha1.HumioQueryExportDestination{Bucket: "bucket", Key: tc.key},
+				},
+			}
+			key, err := humioQueryExportKey(hqe, startTime)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("humioQueryExportKey() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if key != tc.expected {
+				t.Errorf("humioQueryExportKey() = %s, want %s", key, tc.expected)
+			}
+		})
+	}
+}
+
+func TestConstructHumioQueryExportJob(t *testing.T) {
+	hqe := &humiov1alpha1.HumioQueryExport{
+		ObjectMeta: metav1.ObjectMeta{Name: "export", Namespace: "default"},
+		Spec: humiov1alpha1.HumioQueryExportSpec{
+			ViewName:    "audit logs",
+			QueryString: "count()",
+			Format:      humiov1alpha1.HumioQueryExportFormatCSV,
+			Destination: humiov1alpha1.HumioQueryExportDestination{Type: humiov1alpha1.HumioQueryExportDestinationGCS, Bucket: "bucket"},
+		},
+	}
+	address, _ := url.Parse("https://humio.example.com/")
+	now := metav1.NewTime(time.Date(2024, time.January, 10, 2, 0, 0, 0, time.UTC))
+
+	job, err := constructHumioQueryExportJob(hqe, &humioapi.Config{Address: address}, "export-query-export", "gs://bucket/export.csv", now)
+	if err != nil {
+		t.Fatalf("constructHumioQueryExportJob() error = %v", err)
+	}
+
+	if !strings.HasPrefix(job.Name, "export-2401100200-") {
+		t.Errorf("expected job name starting with %s, got %s", "export-2401100200-", job.Name)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if container.Image != humioQueryExportDefaultGCSImage {
+		t.Errorf("expected image %s, got %s", humioQueryExportDefaultGCSImage, container.Image)
+	}
+	expected := map[string]string{
+		"HUMIO_QUERY_URL": "https://humio.example.com/api/v1/repositories/audit%20logs/query",
+		"QUERY":           `{"queryString":"count()","start":"24h"}`,
+		"QUERY_ACCEPT":    "text/csv",
+		"EXPORT_LOCATION": "gs://bucket/export.csv",
+	}
+	for _, env := range container.Env {
+		if want, ok := expected[env.Name]; ok && env.Value != want {
+			t.Errorf("expected %s to be %s, got %s", env.Name, want, env.Value)
+		}
+		if env.Name == "HUMIO_API_TOKEN" && (env.ValueFrom == nil || env.ValueFrom.SecretKeyRef.Name != "export-query-export") {
+			t.Errorf("expected api token to be read from the export secret")
+		}
+	}
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+const (
+	humioQueryJobDefaultStart     = "24h"
+	humioQueryJobDefaultOutputKey = "results.json"
+	// humioQueryJobMaxObjectSize is the maximum size of the results stored in a ConfigMap or Secret, as objects
+	// larger than 1MiB are rejected by the Kubernetes API
+	humioQueryJobMaxObjectSize = 1024 * 1024
+	humioQueryJobPollInterval  = time.Second * 2
+)
+
+// humioQueryJobWebhookClient is used to push query results to webhooks
+var humioQueryJobWebhookClient = &http.Client{Timeout: 30 * time.Second}
+
+// HumioQueryJobReconciler reconciles a HumioQueryJob object
+type HumioQueryJobReconciler struct {
+	client.Client
+	BaseLogger  logr.Logger
+	Log         logr.Logger
+	HumioClient humio.Client
+	Namespace   string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioqueryjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioqueryjobs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioqueryjobs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+
+func (r *HumioQueryJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioQueryJob")
+
+	// Fetch the HumioQueryJob instance
+	hqj := &humiov1alpha1.HumioQueryJob{}
+	err := r.Get(ctx, req.NamespacedName, hqj)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hqj.UID)
+
+	// Query jobs which are no longer polled are stopped by Humio, so deleting a HumioQueryJob with a running query
+	// does not require any cleanup inside Humio.
+	if hqj.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+
+	now := metav1.Now()
+	status := *hqj.Status.DeepCopy()
+
+	if err := validateHumioQueryJob(hqj); err != nil {
+		status.State = humiov1alpha1.HumioQueryJobStateConfigError
+		status.Message = err.Error()
+		return reconcile.Result{}, r.setStatus(ctx, status, hqj)
+	}
+
+	if status.QueryJobID == "" {
+		if hqj.Spec.Suspend {
+			r.Log.Info("query job is suspended, skipping")
+			return reconcile.Result{}, nil
+		}
+		due, next, err := helpers.CronScheduleDue(hqj.Spec.Schedule, hqj.CreationTimestamp.Time, status.LastScheduleTime, now.Time)
+		if err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to parse schedule")
+		}
+		if !due {
+			if status.State == "" {
+				status.State = humiov1alpha1.HumioQueryJobStatePending
+			}
+			if err := r.setStatus(ctx, status, hqj); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set query job status")
+			}
+			return humioQueryJobRequeue(next, now.Time), nil
+		}
+	}
+
+	cluster, err := helpers.NewCluster(ctx, r, hqj.Spec.ManagedClusterName, hqj.Spec.ExternalClusterName, hqj.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hqj.Namespace, hqj.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		status.State = humiov1alpha1.HumioQueryJobStateClusterUnavailable
+		status.Message = err.Error()
+		if err := r.setStatus(ctx, status, hqj); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set query job status")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		r.Log.Error(err, "unable to obtain humio client config")
+		status.State = humiov1alpha1.HumioQueryJobStateConfigError
+		if err != nil {
+			status.Message = err.Error()
+		}
+		if err := r.setStatus(ctx, status, hqj); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set query job status")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	if status.QueryJobID == "" {
+		id, err := r.HumioClient.CreateQueryJob(cluster.Config(), req, hqj.Spec.ViewName, humioQueryJobQuery(hqj))
+		// The query counts as scheduled even if it could not be started, so a failing query is retried at the next
+		// scheduled time rather than immediately
+		status.LastScheduleTime = &now
+		if err != nil {
+			r.Log.Error(err, "unable to start query")
+			status.State = humiov1alpha1.HumioQueryJobStateFailed
+			status.Message = fmt.Sprintf("unable to start query: %s", err)
+		} else {
+			r.Log.Info(fmt.Sprintf("started query job %s", id))
+			status.State = humiov1alpha1.HumioQueryJobStateRunning
+			status.Message = ""
+			status.QueryJobID = id
+		}
+		if err := r.setStatus(ctx, status, hqj); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set query job status")
+		}
+		if status.QueryJobID == "" {
+			return r.requeueForSchedule(hqj, now.Time)
+		}
+		return reconcile.Result{RequeueAfter: humioQueryJobPollInterval}, nil
+	}
+
+	result, err := r.HumioClient.PollQueryJob(cluster.Config(), req, hqj.Spec.ViewName, status.QueryJobID)
+	if err != nil {
+		r.Log.Error(err, fmt.Sprintf("unable to poll query job %s", status.QueryJobID))
+		status.State = humiov1alpha1.HumioQueryJobStateFailed
+		status.Message = fmt.Sprintf("unable to poll query: %s", err)
+		status.QueryJobID = ""
+		if err := r.setStatus(ctx, status, hqj); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set query job status")
+		}
+		return r.requeueForSchedule(hqj, now.Time)
+	}
+	if !result.Done && !result.Cancelled {
+		// Query jobs must be polled regularly, otherwise Humio stops them
+		return reconcile.Result{RequeueAfter: humioQueryJobPollInterval}, nil
+	}
+
+	if err := r.HumioClient.DeleteQueryJob(cluster.Config(), req, hqj.Spec.ViewName, status.QueryJobID); err != nil {
+		r.Log.Error(err, fmt.Sprintf("unable to delete query job %s", status.QueryJobID))
+	}
+	status.QueryJobID = ""
+	switch {
+	case result.Cancelled:
+		status.State = humiov1alpha1.HumioQueryJobStateFailed
+		status.Message = "query was cancelled"
+	default:
+		if err := r.storeResults(ctx, hqj, result.Events); err != nil {
+			r.Log.Error(err, "unable to store query results")
+			status.State = humiov1alpha1.HumioQueryJobStateFailed
+			status.Message = fmt.Sprintf("unable to store query results: %s", err)
+			break
+		}
+		r.Log.Info(fmt.Sprintf("stored %d events returned by query", len(result.Events)))
+		status.State = humiov1alpha1.HumioQueryJobStateSucceeded
+		status.Message = ""
+		status.EventCount = int64(len(result.Events))
+		status.LastSuccessfulTime = &now
+	}
+	if err := r.setStatus(ctx, status, hqj); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set query job status")
+	}
+	return r.requeueForSchedule(hqj, now.Time)
+}
+
+// requeueForSchedule requeues the HumioQueryJob when the next query is scheduled to run
+func (r *HumioQueryJobReconciler) requeueForSchedule(hqj *humiov1alpha1.HumioQueryJob, now time.Time) (reconcile.Result, error) {
+	_, next, err := helpers.CronScheduleDue(hqj.Spec.Schedule, hqj.CreationTimestamp.Time, hqj.Status.LastScheduleTime, now)
+	if err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to parse schedule")
+	}
+	return humioQueryJobRequeue(next, now), nil
+}
+
+func humioQueryJobRequeue(next time.Time, now time.Time) reconcile.Result {
+	if next.IsZero() {
+		return reconcile.Result{}
+	}
+	return reconcile.Result{RequeueAfter: next.Sub(now)}
+}
+
+func validateHumioQueryJob(hqj *humiov1alpha1.HumioQueryJob) error {
+	if hqj.Spec.ViewName == "" {
+		return fmt.Errorf("viewName must be specified")
+	}
+	if hqj.Spec.QueryString == "" {
+		return fmt.Errorf("queryString must be specified")
+	}
+	if hqj.Spec.Schedule != "" {
+		if _, err := helpers.ParseCronSchedule(hqj.Spec.Schedule); err != nil {
+			return err
+		}
+	}
+	output := hqj.Spec.Output
+	outputs := 0
+	for _, set := range []bool{output.ConfigMap != nil, output.Secret != nil, output.Webhook != nil} {
+		if set {
+			outputs++
+		}
+	}
+	if outputs != 1 {
+		return fmt.Errorf("exactly one of configMap, secret and webhook must be specified as output")
+	}
+	if output.ConfigMap != nil && output.ConfigMap.Name == "" {
+		return fmt.Errorf("name of the configMap output must be specified")
+	}
+	if output.Secret != nil && output.Secret.Name == "" {
+		return fmt.Errorf("name of the secret output must be specified")
+	}
+	if output.Webhook != nil && output.Webhook.URL == "" {
+		return fmt.Errorf("url of the webhook output must be specified")
+	}
+	return nil
+}
+
+func humioQueryJobQuery(hqj *humiov1alpha1.HumioQueryJob) humioapi.Query {
+	start := hqj.Spec.Start
+	if start == "" {
+		start = humioQueryJobDefaultStart
+	}
+	return humioapi.Query{
+		QueryString: hqj.Spec.QueryString,
+		Start:       start,
+		End:         hqj.Spec.End,
+	}
+}
+
+func humioQueryJobOutputKey(output *humiov1alpha1.HumioQueryJobObjectOutput) string {
+	if output.Key == "" {
+		return humioQueryJobDefaultOutputKey
+	}
+	return output.Key
+}
+
+// storeResults stores the events returned by a query as a JSON array in the configured output
+func (r *HumioQueryJobReconciler) storeResults(ctx context.Context, hqj *humiov1alpha1.HumioQueryJob, events []map[string]interface{}) error {
+	if events == nil {
+		events = []map[string]interface{}{}
+	}
+	results, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	output := hqj.Spec.Output
+	if output.Webhook != nil {
+		return pushHumioQueryJobResults(ctx, output.Webhook, results)
+	}
+	if len(results) > humioQueryJobMaxObjectSize {
+		return fmt.Errorf("results of %d bytes exceed the maximum size of %d bytes", len(results), humioQueryJobMaxObjectSize)
+	}
+	if output.ConfigMap != nil {
+		configMap := &corev1.ConfigMap{}
+		return r.createOrUpdateOutput(ctx, hqj, output.ConfigMap.Name, configMap, func() {
+			configMap.Data = map[string]string{humioQueryJobOutputKey(output.ConfigMap): string(results)}
+		})
+	}
+	secret := &corev1.Secret{}
+	return r.createOrUpdateOutput(ctx, hqj, output.Secret.Name, secret, func() {
+		secret.Data = map[string][]byte{humioQueryJobOutputKey(output.Secret): results}
+	})
+}
+
+// createOrUpdateOutput creates or updates the ConfigMap or Secret holding the results of the HumioQueryJob. Objects
+// which already exist are only updated if they are owned by the HumioQueryJob, so the results never overwrite
+// objects which are managed by something else.
+func (r *HumioQueryJobReconciler) createOrUpdateOutput(ctx context.Context, hqj *humiov1alpha1.HumioQueryJob, name string, obj client.Object, setResults func()) error {
+	err := r.Get(ctx, types.NamespacedName{Namespace: hqj.Namespace, Name: name}, obj)
+	if k8serrors.IsNotFound(err) {
+		obj.SetNamespace(hqj.Namespace)
+		obj.SetName(name)
+		if err := controllerutil.SetControllerReference(hqj, obj, r.Scheme()); err != nil {
+			return err
+		}
+		setResults()
+		return r.Create(ctx, obj)
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(obj, hqj) {
+		return fmt.Errorf("%s already exists and is not owned by the query job", name)
+	}
+	setResults()
+	return r.Update(ctx, obj)
+}
+
+// pushHumioQueryJobResults sends the results to the webhook using a POST request
+func pushHumioQueryJobResults(ctx context.Context, webhook *humiov1alpha1.HumioQueryJobWebhookOutput, results []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(results))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range webhook.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := humioQueryJobWebhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioQueryJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioQueryJob{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioQueryJobList{}))).
+		Complete(withReconcileContext(r))
+}
+
+func (r *HumioQueryJobReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioQueryJobStatus, hqj *humiov1alpha1.HumioQueryJob) error {
+	if reflect.DeepEqual(hqj.Status, status) {
+		return nil
+	}
+	if hqj.Status.State != status.State {
+		r.Log.Info(fmt.Sprintf("setting query job state to %s", status.State))
+	}
+	hqj.Status = status
+	return r.Status().Update(ctx, hqj)
+}
+
+func (r *HumioQueryJobReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateHumioQueryJob(t *testing.T) {
+	configMap := &humiov1alpha1.HumioQueryJobObjectOutput{Name: "results"}
+	webhook := &humiov1alpha1.HumioQueryJobWebhookOutput{URL: "https://example.com/hook"}
+
+	tt := []struct {
+		name    string
+		spec    humiov1alpha1.HumioQueryJobSpec
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			spec: humiov1alpha1.HumioQueryJobSpec{ViewName: "humio", QueryString: "count()", Output: humiov1alpha1.HumioQueryJobOutput{ConfigMap: configMap}},
+		},
+		{
+			name:    "missing query",
+			spec:    humiov1alpha1.HumioQueryJobSpec{ViewName: "humio", Output: humiov1alpha1.HumioQueryJobOutput{ConfigMap: configMap}},
+			wantErr: true,
+		},
+		{
+			name:    "no output",
+			spec:    humiov1alpha1.HumioQueryJobSpec{ViewName: "humio", QueryString: "count()"},
+			wantErr: true,
+		},
+		{
+			name:    "multiple outputs",
+			spec:    humiov1alpha1.HumioQueryJobSpec{ViewName: "humio", QueryString: "count()", Output: humiov1alpha1.HumioQueryJobOutput{ConfigMap: configMap, Webhook: webhook}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid schedule",
+			spec:    humiov1alpha1.HumioQueryJobSpec{ViewName: "humio", QueryString: "count()", Schedule: "daily", Output: humiov1alpha1.HumioQueryJobOutput{Webhook: webhook}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateHumioQueryJob(&humiov1alpha1.HumioQueryJob{Spec: tc.spec})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("validateHumioQueryJob() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestHumioQueryJobQuery(t *testing.T) {
+	hqj := &humiov1alpha1.HumioQueryJob{Spec: humiov1alpha1.HumioQueryJobSpec{QueryString: "count()"}}
+	query := humioQueryJobQuery(hqj)
+	if query.Start != humioQueryJobDefaultStart || query.End != "" {
+		t.Errorf("humioQueryJobQuery() start = %q, end = %q, want %q and empty end", query.Start, query.End, humioQueryJobDefaultStart)
+	}
+
+	hqj.Spec.Start = "7d"
+	hqj.Spec.End = "1d"
+	query = humioQueryJobQuery(hqj)
+	if query.Start != "7d" || query.End != "1d" {
+		t.Errorf("humioQueryJobQuery() start = %q, end = %q, want \"7d\" and \"1d\"", query.Start, query.End)
+	}
+}
+
+func TestHumioQueryJobStoreResultsInConfigMap(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+
+	hqj := &humiov1alpha1.HumioQueryJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "extract", Namespace: "default", UID: "1234"},
+		Spec: humiov1alpha1.HumioQueryJobSpec{
+			Output: humiov1alpha1.HumioQueryJobOutput{ConfigMap: &humiov1alpha1.HumioQueryJobObjectOutput{Name: "results"}},
+		},
+	}
+	unowned := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unowned", Namespace: "default"}}
+	r := &HumioQueryJobReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(hqj, unowned).Build()}
+
+	events := []map[string]interface{}{{"_count": "42"}}
+	for i := 0; i < 2; i++ {
+		if err := r.storeResults(context.Background(), hqj, events); err != nil {
+			t.Fatalf("storeResults() error = %v", err)
+		}
+	}
+	var configMap corev1.ConfigMap
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "results"}, &configMap); err != nil {
+		t.Fatalf("unable to get configmap: %v", err)
+	}
+	if got, want := configMap.Data[humioQueryJobDefaultOutputKey], `[{"_count":"42"}]`; got != want {
+		t.Errorf("configmap data = %s, want %s", got, want)
+	}
+	if !metav1.IsControlledBy(&configMap, hqj) {
+		t.Errorf("configmap is not owned by the query job")
+	}
+
+	hqj.Spec.Output.ConfigMap.Name = "unowned"
+	if err := r.storeResults(context.Background(), hqj, events); err == nil {
+		t.Errorf("storeResults() expected error when the configmap is not owned by the query job")
+	}
+}
+
+func TestPushHumioQueryJobResults(t *testing.T) {
+	var body, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		authorization = r.Header.Get("Authorization")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	webhook := &humiov1alpha1.HumioQueryJobWebhookOutput{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}
+	if err := pushHumioQueryJobResults(context.Background(), webhook, []byte(`[]`)); err != nil {
+		t.Fatalf("pushHumioQueryJobResults() error = %v", err)
+	}
+	if body != "[]" || authorization != "Bearer secret" {
+		t.Errorf("webhook received body %q with authorization %q", body, authorization)
+	}
+
+	webhook.URL = server.URL + "/fail"
+	if err := pushHumioQueryJobResults(context.Background(), webhook, []byte(`[]`)); err == nil {
+		t.Errorf("pushHumioQueryJobResults() expected error when the webhook fails")
+	}
+}
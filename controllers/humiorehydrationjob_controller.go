@@ -0,0 +1,330 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+// humioRehydrationQuery is the query used to fetch segments. Searching a time range makes Humio fetch all segments in
+// the time range from bucket storage onto the local disks of the Humio nodes.
+const humioRehydrationQuery = "count()"
+
+// HumioRehydrationJobReconciler reconciles a HumioRehydrationJob object
+type HumioRehydrationJobReconciler struct {
+	client.Client
+	BaseLogger  logr.Logger
+	Log         logr.Logger
+	HumioClient humio.Client
+	Namespace   string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiorehydrationjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiorehydrationjobs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiorehydrationjobs/finalizers,verbs=update
+
+func (r *HumioRehydrationJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioRehydrationJob")
+
+	// Fetch the HumioRehydrationJob instance
+	hrj := &humiov1alpha1.HumioRehydrationJob{}
+	err := r.Get(ctx, req.NamespacedName, hrj)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hrj.UID)
+
+	// Query jobs which are no longer polled are stopped by Humio, so deleting a HumioRehydrationJob which is still
+	// running does not require any cleanup inside Humio.
+	if hrj.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+
+	now := metav1.Now()
+	status := *hrj.Status.DeepCopy()
+	status.Message = ""
+
+	if humioRehydrationJobFinished(status) {
+		expired, remaining := humioRehydrationJobExpired(hrj, now.Time)
+		if expired {
+			r.Log.Info("deleting finished rehydration job as its ttl has elapsed")
+			if err := r.Delete(ctx, hrj); err != nil && !k8serrors.IsNotFound(err) {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "unable to delete rehydration job")
+			}
+			return reconcile.Result{}, nil
+		}
+		if remaining > 0 {
+			return reconcile.Result{RequeueAfter: remaining}, nil
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if err := validateHumioRehydrationJob(hrj); err != nil {
+		status.State = humiov1alpha1.HumioRehydrationJobStateConfigError
+		status.Message = err.Error()
+		return reconcile.Result{}, r.setStatus(ctx, status, hrj)
+	}
+
+	cluster, err := helpers.NewCluster(ctx, r, hrj.Spec.ManagedClusterName, hrj.Spec.ExternalClusterName, hrj.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hrj.Namespace, hrj.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		status.State = humiov1alpha1.HumioRehydrationJobStateClusterUnavailable
+		status.Message = err.Error()
+		if err := r.setStatus(ctx, status, hrj); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set rehydration job status")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		r.Log.Error(err, "unable to obtain humio client config")
+		status.State = humiov1alpha1.HumioRehydrationJobStateConfigError
+		if err != nil {
+			status.Message = err.Error()
+		}
+		if err := r.setStatus(ctx, status, hrj); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set rehydration job status")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	if status.StartTime == nil {
+		r.Log.Info("starting rehydration job")
+		status.StartTime = &now
+		status.Repositories = nil
+		for _, repository := range hrj.Spec.Repositories {
+			status.Repositories = append(status.Repositories, humiov1alpha1.HumioRehydrationJobRepositoryStatus{
+				Name:  repository,
+				State: humiov1alpha1.HumioRehydrationJobStatePending,
+			})
+		}
+	}
+
+	query := humioRehydrationJobQuery(hrj, status.StartTime.Time)
+	for idx := range status.Repositories {
+		r.reconcileRepository(cluster.Config(), req, query, &status.Repositories[idx])
+	}
+
+	status.State, status.Progress = humioRehydrationJobProgress(status.Repositories)
+	if humioRehydrationJobFinished(status) {
+		r.Log.Info(fmt.Sprintf("rehydration job finished with state %s", status.State))
+		status.CompletionTime = &now
+	}
+	if err := r.setStatus(ctx, status, hrj); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set rehydration job status")
+	}
+
+	if humioRehydrationJobFinished(status) {
+		if hrj.Spec.TTLSecondsAfterFinished == nil {
+			return reconcile.Result{}, nil
+		}
+		_, remaining := humioRehydrationJobExpired(hrj, now.Time)
+		return reconcile.Result{Requeue: true, RequeueAfter: remaining}, nil
+	}
+	// Query jobs must be polled regularly, otherwise Humio stops them
+	return reconcile.Result{RequeueAfter: time.Second * 5}, nil
+}
+
+// reconcileRepository starts or polls the query job fetching the segments of a single repository. Query jobs which
+// could not be polled, e.g. because they were stopped by Humio, are started again. As the segments fetched by the
+// previous query job are already on local disk, the new query job quickly catches up.
+func (r *HumioRehydrationJobReconciler) reconcileRepository(config *humioapi.Config, req reconcile.Request, query humioapi.Query, repository *humiov1alpha1.HumioRehydrationJobRepositoryStatus) {
+	if repository.State == humiov1alpha1.HumioRehydrationJobStateCompleted || repository.State == humiov1alpha1.HumioRehydrationJobStateFailed {
+		return
+	}
+
+	if repository.QueryJobID == "" {
+		id, err := r.HumioClient.CreateQueryJob(config, req, repository.Name, query)
+		if err != nil {
+			r.Log.Error(err, fmt.Sprintf("unable to start query job for repository %s", repository.Name))
+			repository.Message = err.Error()
+			return
+		}
+		r.Log.Info(fmt.Sprintf("started query job %s for repository %s", id, repository.Name))
+		repository.QueryJobID = id
+		repository.State = humiov1alpha1.HumioRehydrationJobStateRunning
+		repository.Message = ""
+		return
+	}
+
+	result, err := r.HumioClient.PollQueryJob(config, req, repository.Name, repository.QueryJobID)
+	if err != nil {
+		r.Log.Error(err, fmt.Sprintf("unable to poll query job %s for repository %s, starting a new query job", repository.QueryJobID, repository.Name))
+		repository.QueryJobID = ""
+		repository.Message = err.Error()
+		return
+	}
+
+	repository.Progress = humioQueryJobProgress(result)
+	repository.ProcessedBytes = int64(result.Metadata.ProcessedBytes)
+	repository.Message = ""
+	switch {
+	case result.Cancelled:
+		repository.State = humiov1alpha1.HumioRehydrationJobStateFailed
+		repository.Message = "query job was cancelled"
+	case result.Done:
+		repository.State = humiov1alpha1.HumioRehydrationJobStateCompleted
+		repository.Progress = 100
+	default:
+		return
+	}
+	if err := r.HumioClient.DeleteQueryJob(config, req, repository.Name, repository.QueryJobID); err != nil {
+		r.Log.Error(err, fmt.Sprintf("unable to delete query job %s", repository.QueryJobID))
+	}
+	repository.QueryJobID = ""
+}
+
+func validateHumioRehydrationJob(hrj *humiov1alpha1.HumioRehydrationJob) error {
+	if len(hrj.Spec.Repositories) == 0 {
+		return fmt.Errorf("at least one repository must be specified")
+	}
+	if hrj.Spec.End != nil && !hrj.Spec.End.After(hrj.Spec.Start.Time) {
+		return fmt.Errorf("end must be after start")
+	}
+	return nil
+}
+
+// humioRehydrationJobQuery returns the query searching the time range of the rehydration job. When no end is
+// specified, the time range ends when the job was started, so query jobs started again search the same time range.
+func humioRehydrationJobQuery(hrj *humiov1alpha1.HumioRehydrationJob, startTime time.Time) humioapi.Query {
+	end := startTime
+	if hrj.Spec.End != nil {
+		end = hrj.Spec.End.Time
+	}
+	return humioapi.Query{
+		QueryString: humioRehydrationQuery,
+		Start:       strconv.FormatInt(hrj.Spec.Start.UnixMilli(), 10),
+		End:         strconv.FormatInt(end.UnixMilli(), 10),
+	}
+}
+
+// humioQueryJobProgress returns how much of the work of a query job is done, as a percentage
+func humioQueryJobProgress(result humioapi.QueryResult) int32 {
+	if result.Done {
+		return 100
+	}
+	if result.Metadata.TotalWork == 0 {
+		return 0
+	}
+	return int32(result.Metadata.WorkDone * 100 / result.Metadata.TotalWork)
+}
+
+// humioRehydrationJobProgress returns the state and the overall progress of the rehydration job based on the
+// progress of each repository
+func humioRehydrationJobProgress(repositories []humiov1alpha1.HumioRehydrationJobRepositoryStatus) (string, int32) {
+	if len(repositories) == 0 {
+		return humiov1alpha1.HumioRehydrationJobStatePending, 0
+	}
+	var progress int32
+	var running, failed bool
+	for _, repository := range repositories {
+		progress += repository.Progress
+		switch repository.State {
+		case humiov1alpha1.HumioRehydrationJobStateFailed:
+			failed = true
+		case humiov1alpha1.HumioRehydrationJobStateCompleted:
+		default:
+			running = true
+		}
+	}
+	progress /= int32(len(repositories))
+	if running {
+		return humiov1alpha1.HumioRehydrationJobStateRunning, progress
+	}
+	if failed {
+		return humiov1alpha1.HumioRehydrationJobStateFailed, progress
+	}
+	return humiov1alpha1.HumioRehydrationJobStateCompleted, progress
+}
+
+func humioRehydrationJobFinished(status humiov1alpha1.HumioRehydrationJobStatus) bool {
+	return status.State == humiov1alpha1.HumioRehydrationJobStateCompleted || status.State == humiov1alpha1.HumioRehydrationJobStateFailed
+}
+
+// humioRehydrationJobExpired returns whether the ttl of a finished rehydration job has elapsed, and if not, how long
+// remains until it elapses. A zero duration is returned when no ttl is set.
+func humioRehydrationJobExpired(hrj *humiov1alpha1.HumioRehydrationJob, now time.Time) (bool, time.Duration) {
+	if hrj.Spec.TTLSecondsAfterFinished == nil || hrj.Status.CompletionTime == nil {
+		return false, 0
+	}
+	expiry := hrj.Status.CompletionTime.Add(time.Duration(*hrj.Spec.TTLSecondsAfterFinished) * time.Second)
+	if !expiry.After(now) {
+		return true, 0
+	}
+	return false, expiry.Sub(now)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioRehydrationJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioRehydrationJob{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioRehydrationJobList{}))).
+		Complete(withReconcileContext(r))
+}
+
+func (r *HumioRehydrationJobReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioRehydrationJobStatus, hrj *humiov1alpha1.HumioRehydrationJob) error {
+	if reflect.DeepEqual(hrj.Status, status) {
+		return nil
+	}
+	if hrj.Status.State != status.State {
+		r.Log.Info(fmt.Sprintf("setting rehydration job state to %s", status.State))
+	}
+	hrj.Status = status
+	return r.Status().Update(ctx, hrj)
+}
+
+func (r *HumioRehydrationJobReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
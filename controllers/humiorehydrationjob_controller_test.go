@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHumioRehydrationJobQuery(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	startTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	hrj := &humiov1alpha1.HumioRehydrationJob{
+		Spec: humiov1alpha1.HumioRehydrationJobSpec{Start: metav1.NewTime(start)},
+	}
+	query := humioRehydrationJobQuery(hrj, startTime)
+	if query.Start != "1704067200000" || query.End != "1709251200000" {
+		t.Errorf("humioRehydrationJobQuery() without end = %s-%s, want 1704067200000-1709251200000", query.Start, query.End)
+	}
+
+	endTime := metav1.NewTime(end)
+	hrj.Spec.End = &endTime
+	query = humioRehydrationJobQuery(hrj, startTime)
+	if query.Start != "1704067200000" || query.End != "1704153600000" {
+		t.Errorf("humioRehydrationJobQuery() with end = %s-%s, want 1704067200000-1704153600000", query.Start, query.End)
+	}
+}
+
+func TestHumioQueryJobProgress(t *testing.T) {
+	tests := []struct {
+		name   string
+		result humioapi.QueryResult
+		want   int32
+	}{
+		{"no work", humioapi.QueryResult{}, 0},
+		{"partial", humioapi.QueryResult{Metadata: humioapi.QueryResultMetadata{WorkDone: 25, TotalWork: 200}}, 12},
+		{"done", humioapi.QueryResult{Done: true, Metadata: humioapi.QueryResultMetadata{WorkDone: 25, TotalWork: 200}}, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := humioQueryJobProgress(tt.result); got != tt.want {
+				t.Errorf("humioQueryJobProgress() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHumioRehydrationJobProgress(t *testing.T) {
+	repository := func(state string, progress int32) humiov1alpha1.HumioRehydrationJobRepositoryStatus {
+		return humiov1alpha1.HumioRehydrationJobRepositoryStatus{State: state, Progress: progress}
+	}
+	tests := []struct {
+		name         string
+		repositories []humiov1alpha1.HumioRehydrationJobRepositoryStatus
+		wantState    string
+		wantProgress int32
+	}{
+		{
+			name:      "not started",
+			wantState: humiov1alpha1.HumioRehydrationJobStatePending,
+		},
+		{
+			name: "running",
+			repositories: []humiov1alpha1.HumioRehydrationJobRepositoryStatus{
+				repository(humiov1alpha1.HumioRehydrationJobStateCompleted, 100),
+				repository(humiov1alpha1.HumioRehydrationJobStateRunning, 50),
+			},
+			wantState:    humiov1alpha1.HumioRehydrationJobStateRunning,
+			wantProgress: 75,
+		},
+		{
+			name: "failed repository while others are running",
+			repositories: []humiov1alpha1.HumioRehydrationJobRepositoryStatus{
+				repository(humiov1alpha1.HumioRehydrationJobStateFailed, 10),
+				repository(humiov1alpha1.HumioRehydrationJobStatePending, 0),
+			},
+			wantState:    humiov1alpha1.HumioRehydrationJobStateRunning,
+			wantProgress: 5,
+		},
+		{
+			name: "failed",
+			repositories: []humiov1alpha1.HumioRehydrationJobRepositoryStatus{
+				repository(humiov1alpha1.HumioRehydrationJobStateFailed, 10),
+				repository(humiov1alpha1.HumioRehydrationJobStateCompleted, 100),
+			},
+			wantState:    humiov1alpha1.HumioRehydrationJobStateFailed,
+			wantProgress: 55,
+		},
+		{
+			name: "completed",
+			repositories: []humiov1alpha1.HumioRehydrationJobRepositoryStatus{
+				repository(humiov1alpha1.HumioRehydrationJobStateCompleted, 100),
+				repository(humiov1alpha1.HumioRehydrationJobStateCompleted, 100),
+			},
+			wantState:    humiov1alpha1.HumioRehydrationJobStateCompleted,
+			wantProgress: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, progress := humioRehydrationJobProgress(tt.repositories)
+			if state != tt.wantState || progress != tt.wantProgress {
+				t.Errorf("humioRehydrationJobProgress() = %s, %d, want %s, %d", state, progress, tt.wantState, tt.wantProgress)
+			}
+		})
+	}
+}
+
+func TestHumioRehydrationJobExpired(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ttl := int32(3600)
+	completed := metav1.NewTime(now.Add(-30 * time.Minute))
+	expiredCompletion := metav1.NewTime(now.Add(-2 * time.Hour))
+
+	tests := []struct {
+		name           string
+		ttl            *int32
+		completionTime *metav1.Time
+		wantExpired    bool
+		wantRemaining  time.Duration
+	}{
+		{"no ttl", nil, &completed, false, 0},
+		{"not finished", &ttl, nil, false, 0},
+		{"ttl remaining", &ttl, &completed, false, 30 * time.Minute},
+		{"ttl elapsed", &ttl, &expiredCompletion, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hrj := &humiov1alpha1.HumioRehydrationJob{
+				Spec:   humiov1alpha1.HumioRehydrationJobSpec{TTLSecondsAfterFinished: tt.ttl},
+				Status: humiov1alpha1.HumioRehydrationJobStatus{CompletionTime: tt.completionTime},
+			}
+			expired, remaining := humioRehydrationJobExpired(hrj, now)
+			if expired != tt.wantExpired || remaining != tt.wantRemaining {
+				t.Errorf("humioRehydrationJobExpired() = %v, %s, want %v, %s", expired, remaining, tt.wantExpired, tt.wantRemaining)
+			}
+		})
+	}
+}
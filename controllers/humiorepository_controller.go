@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"time"
@@ -25,12 +26,15 @@ import (
 	humioapi "github.com/humio/cli/api"
 	"github.com/humio/humio-operator/pkg/helpers"
 	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/go-logr/logr"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
 	"github.com/humio/humio-operator/pkg/humio"
@@ -43,11 +47,13 @@ type HumioRepositoryReconciler struct {
 	Log         logr.Logger
 	HumioClient humio.Client
 	Namespace   string
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=core.humio.com,resources=humiorepositories,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.humio.com,resources=humiorepositories/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core.humio.com,resources=humiorepositories/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioretentionpolicies,verbs=get;list;watch
 
 func (r *HumioRepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	if r.Namespace != "" {
@@ -76,6 +82,17 @@ func (r *HumioRepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	r.Log = r.Log.WithValues("Request.UID", hr.UID)
 
 	cluster, err := helpers.NewCluster(ctx, r, hr.Spec.ManagedClusterName, hr.Spec.ExternalClusterName, hr.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hr.Namespace, hr.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		err = r.setState(ctx, humiov1alpha1.HumioRepositoryStateClusterUnavailable, hr)
+		if err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
 	if err != nil || cluster == nil || cluster.Config() == nil {
 		r.Log.Error(err, "unable to obtain humio client config")
 		err = r.setState(ctx, humiov1alpha1.HumioRepositoryStateConfigError, hr)
@@ -123,6 +140,10 @@ func (r *HumioRepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 
 	defer func(ctx context.Context, humioClient humio.Client, hr *humiov1alpha1.HumioRepository) {
 		curRepository, err := humioClient.GetRepository(cluster.Config(), req, hr)
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			_ = r.setState(ctx, humiov1alpha1.HumioRepositoryStateClusterUnavailable, hr)
+			return
+		}
 		if err != nil {
 			_ = r.setState(ctx, humiov1alpha1.HumioRepositoryStateUnknown, hr)
 			return
@@ -135,6 +156,16 @@ func (r *HumioRepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		_ = r.setState(ctx, humiov1alpha1.HumioRepositoryStateExists, hr)
 	}(ctx, r.HumioClient, hr)
 
+	// Retention policies selecting the repository override its retention
+	policy, err := r.retentionPolicyForRepository(ctx, hr)
+	if err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not get retention policy")
+	}
+	if err := r.setRetentionPolicyName(ctx, policy, hr); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not set retention policy")
+	}
+	effectiveRepository := repositoryWithRetentionPolicy(hr, policy)
+
 	// Get current repository
 	r.Log.Info("get current repository")
 	curRepository, err := r.HumioClient.GetRepository(cluster.Config(), req, hr)
@@ -146,7 +177,7 @@ func (r *HumioRepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	if reflect.DeepEqual(emptyRepository, *curRepository) {
 		r.Log.Info("repository doesn't exist. Now adding repository")
 		// create repository
-		_, err := r.HumioClient.AddRepository(cluster.Config(), req, hr)
+		_, err := r.HumioClient.AddRepository(cluster.Config(), req, effectiveRepository)
 		if err != nil {
 			return reconcile.Result{}, r.logErrorAndReturn(err, "could not create repository")
 		}
@@ -155,38 +186,53 @@ func (r *HumioRepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	}
 
 	if (curRepository.Description != hr.Spec.Description) ||
-		(curRepository.RetentionDays != float64(hr.Spec.Retention.TimeInDays)) ||
-		(curRepository.IngestRetentionSizeGB != float64(hr.Spec.Retention.IngestSizeInGB)) ||
-		(curRepository.StorageRetentionSizeGB != float64(hr.Spec.Retention.StorageSizeInGB)) {
+		(curRepository.RetentionDays != float64(effectiveRepository.Spec.Retention.TimeInDays)) ||
+		(curRepository.IngestRetentionSizeGB != float64(effectiveRepository.Spec.Retention.IngestSizeInGB)) ||
+		(curRepository.StorageRetentionSizeGB != float64(effectiveRepository.Spec.Retention.StorageSizeInGB)) {
 		r.Log.Info(fmt.Sprintf("repository information differs, triggering update, expected %v/%v/%v/%v, got: %v/%v/%v/%v",
 			hr.Spec.Description,
-			float64(hr.Spec.Retention.TimeInDays),
-			float64(hr.Spec.Retention.IngestSizeInGB),
-			float64(hr.Spec.Retention.StorageSizeInGB),
+			float64(effectiveRepository.Spec.Retention.TimeInDays),
+			float64(effectiveRepository.Spec.Retention.IngestSizeInGB),
+			float64(effectiveRepository.Spec.Retention.StorageSizeInGB),
 			curRepository.Description,
 			curRepository.RetentionDays,
 			curRepository.IngestRetentionSizeGB,
 			curRepository.StorageRetentionSizeGB))
-		_, err = r.HumioClient.UpdateRepository(cluster.Config(), req, hr)
+		_, err = r.HumioClient.UpdateRepository(cluster.Config(), req, effectiveRepository)
 		if err != nil {
 			return reconcile.Result{}, r.logErrorAndReturn(err, "could not update repository")
 		}
 	}
 
+	if err := r.reconcileIngestQuota(ctx, cluster.Config(), req, hr, time.Now()); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not reconcile ingest quota")
+	}
+
+	if err := r.reconcileStatistics(ctx, cluster.Config(), req, hr, time.Now()); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not set repository statistics")
+	}
+
+	if err := r.setSyncStatus(ctx, curRepository.ID, time.Now(), hr); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set repository sync status")
+	}
+
 	// TODO: handle updates to repositoryName. Right now we just create the new repository,
 	// and "leak/leave behind" the old repository.
 	// A solution could be to add an annotation that includes the "old name" so we can see if it was changed.
 	// A workaround for now is to delete the repository CR and create it again.
 
-	r.Log.Info("done reconciling, will requeue after 15 seconds")
-	return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	requeue := requeueInterval(hr, time.Second*15)
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+	return reconcile.Result{RequeueAfter: requeue}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *HumioRepositoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&humiov1alpha1.HumioRepository{}).
-		Complete(r)
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioRepositoryList{}))).
+		Watches(&humiov1alpha1.HumioRetentionPolicy{}, handler.EnqueueRequestsFromMapFunc(r.repositoriesForRetentionPolicy)).
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioRepository{}, r))
 }
 
 func (r *HumioRepositoryReconciler) finalize(ctx context.Context, config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) error {
@@ -222,6 +268,18 @@ func (r *HumioRepositoryReconciler) setState(ctx context.Context, state string,
 	return r.Status().Update(ctx, hr)
 }
 
+func (r *HumioRepositoryReconciler) setSyncStatus(ctx context.Context, id string, now time.Time, hr *humiov1alpha1.HumioRepository) error {
+	status := hr.Status.DeepCopy()
+	status.ID = id
+	status.ClusterName = syncClusterName(hr.Spec.ManagedClusterName, hr.Spec.ExternalClusterName)
+	status.LastSyncTime = syncTime(status.LastSyncTime, now)
+	if reflect.DeepEqual(hr.Status, *status) {
+		return nil
+	}
+	hr.Status = *status
+	return r.Status().Update(ctx, hr)
+}
+
 func (r *HumioRepositoryReconciler) logErrorAndReturn(err error, msg string) error {
 	r.Log.Error(err, msg)
 	return fmt.Errorf("%s: %w", msg, err)
@@ -0,0 +1,105 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ingestQuotaCheckInterval is how often the ingested data is measured. Each measurement runs two queries against
+	// the humio-usage repository, so it is not done on every reconcile.
+	ingestQuotaCheckInterval = 5 * time.Minute
+	// ingestQuotaBlockDuration is how long ingest is blocked at a time. The block is renewed on every check while a
+	// limit is exceeded, and expires by itself if the operator stops managing the repository.
+	ingestQuotaBlockDuration = 3 * ingestQuotaCheckInterval
+	ingestQuotaDailyStart    = "24h"
+	ingestQuotaBurstStart    = "1h"
+	ingestQuotaBytesPerGB    = 1000 * 1000 * 1000
+)
+
+// ingestQuotaExceededMessage returns a description of the limit of the quota which is exceeded by the ingested data,
+// or an empty string if no limit is exceeded. Limits which are zero are not enforced.
+func ingestQuotaExceededMessage(quota *humiov1alpha1.HumioRepositoryIngestQuota, dailyIngestBytes, burstIngestBytes int64) string {
+	if quota.DailyLimitGB > 0 && dailyIngestBytes > int64(quota.DailyLimitGB)*ingestQuotaBytesPerGB {
+		return fmt.Sprintf("ingested %d bytes during the last 24 hours, which exceeds the daily limit of %d GB", dailyIngestBytes, quota.DailyLimitGB)
+	}
+	if quota.BurstLimitGB > 0 && burstIngestBytes > int64(quota.BurstLimitGB)*ingestQuotaBytesPerGB {
+		return fmt.Sprintf("ingested %d bytes during the last hour, which exceeds the burst limit of %d GB", burstIngestBytes, quota.BurstLimitGB)
+	}
+	return ""
+}
+
+// reconcileIngestQuota measures the data ingested into the repository, and blocks ingest while a limit of the ingest
+// quota is exceeded. Ingest is unblocked once the ingested data falls below the limits, or the quota is removed.
+func (r *HumioRepositoryReconciler) reconcileIngestQuota(ctx context.Context, config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository, now time.Time) error {
+	status := hr.Status.IngestQuota
+	if hr.Spec.IngestQuota == nil {
+		if status == nil {
+			return nil
+		}
+		if status.Blocked {
+			r.Log.Info("unblocking ingest as the ingest quota was removed")
+			if err := r.HumioClient.UnblockIngest(config, req, hr.Spec.Name); err != nil {
+				return fmt.Errorf("could not unblock ingest: %w", err)
+			}
+		}
+		hr.Status.IngestQuota = nil
+		return r.Status().Update(ctx, hr)
+	}
+	if status != nil && status.CheckTime != nil && now.Sub(status.CheckTime.Time) < ingestQuotaCheckInterval {
+		return nil
+	}
+
+	dailyIngestBytes, err := r.HumioClient.GetRepositoryIngestUsage(config, req, hr.Spec.Name, ingestQuotaDailyStart)
+	if err != nil {
+		return fmt.Errorf("could not get daily ingest: %w", err)
+	}
+	burstIngestBytes, err := r.HumioClient.GetRepositoryIngestUsage(config, req, hr.Spec.Name, ingestQuotaBurstStart)
+	if err != nil {
+		return fmt.Errorf("could not get burst ingest: %w", err)
+	}
+	message := ingestQuotaExceededMessage(hr.Spec.IngestQuota, dailyIngestBytes, burstIngestBytes)
+	if message != "" {
+		r.Log.Info(fmt.Sprintf("blocking ingest for %s as the ingest quota is exceeded: %s", ingestQuotaBlockDuration, message))
+		if err := r.HumioClient.BlockIngest(config, req, hr.Spec.Name, ingestQuotaBlockDuration); err != nil {
+			return fmt.Errorf("could not block ingest: %w", err)
+		}
+	} else if status != nil && status.Blocked {
+		r.Log.Info("unblocking ingest as the ingest quota is no longer exceeded")
+		if err := r.HumioClient.UnblockIngest(config, req, hr.Spec.Name); err != nil {
+			return fmt.Errorf("could not unblock ingest: %w", err)
+		}
+	}
+
+	checkTime := metav1.NewTime(now)
+	hr.Status.IngestQuota = &humiov1alpha1.HumioRepositoryIngestQuotaStatus{
+		DailyIngestBytes: dailyIngestBytes,
+		BurstIngestBytes: burstIngestBytes,
+		Blocked:          message != "",
+		Message:          message,
+		CheckTime:        &checkTime,
+	}
+	return r.Status().Update(ctx, hr)
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestIngestQuotaExceededMessage(t *testing.T) {
+	tt := []struct {
+		name             string
+		quota            humiov1alpha1.HumioRepositoryIngestQuota
+		dailyIngestBytes int64
+		burstIngestBytes int64
+		expected         string
+	}{
+		{
+			name:             "within limits",
+			quota:            humiov1alpha1.HumioRepositoryIngestQuota{DailyLimitGB: 10, BurstLimitGB: 2},
+			dailyIngestBytes: 10 * ingestQuotaBytesPerGB,
+			burstIngestBytes: 2 * ingestQuotaBytesPerGB,
+		},
+		{
+			name:             "daily limit exceeded",
+			quota:            humiov1alpha1.HumioRepositoryIngestQuota{DailyLimitGB: 10, BurstLimitGB: 2},
+			dailyIngestBytes: 10*ingestQuotaBytesPerGB + 1,
+			expected:         "daily limit of 10 GB",
+		},
+		{
+			name:             "burst limit exceeded",
+			quota:            humiov1alpha1.HumioRepositoryIngestQuota{DailyLimitGB: 10, BurstLimitGB: 2},
+			dailyIngestBytes: 3 * ingestQuotaBytesPerGB,
+			burstIngestBytes: 3 * ingestQuotaBytesPerGB,
+			expected:         "burst limit of 2 GB",
+		},
+		{
+			name:             "no limits",
+			dailyIngestBytes: 100 * ingestQuotaBytesPerGB,
+			burstIngestBytes: 100 * ingestQuotaBytesPerGB,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			message := ingestQuotaExceededMessage(&tc.quota, tc.dailyIngestBytes, tc.burstIngestBytes)
+			if (message == "") != (tc.expected == "") || !strings.Contains(message, tc.expected) {
+				t.Errorf("expected message containing %q, got %q", tc.expected, message)
+			}
+		})
+	}
+}
+
+func TestReconcileIngestQuota(t *testing.T) {
+	hr := &humiov1alpha1.HumioRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "noisy", Namespace: "default"},
+		Spec: humiov1alpha1.HumioRepositorySpec{
+			Name:        "noisy",
+			IngestQuota: &humiov1alpha1.HumioRepositoryIngestQuota{DailyLimitGB: 10},
+		},
+	}
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+	r := &HumioRepositoryReconciler{
+		Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(hr).WithStatusSubresource(hr).Build(),
+		HumioClient: humioClient,
+		Log:         logr.Discard(),
+	}
+	req := reconcile.Request{}
+	now := time.Now().Truncate(time.Second)
+
+	humioClient.SetRepositoryIngestUsage("noisy", ingestQuotaDailyStart, 11*ingestQuotaBytesPerGB)
+	if err := r.reconcileIngestQuota(context.Background(), &humioapi.Config{}, req, hr, now); err != nil {
+		t.Fatal(err)
+	}
+	if !humioClient.IngestBlocked("noisy") || hr.Status.IngestQuota == nil || !hr.Status.IngestQuota.Blocked {
+		t.Fatalf("expected ingest to be blocked, got %+v", hr.Status.IngestQuota)
+	}
+	if hr.Status.IngestQuota.DailyIngestBytes != 11*ingestQuotaBytesPerGB {
+		t.Errorf("expected daily ingest to be reported, got %d", hr.Status.IngestQuota.DailyIngestBytes)
+	}
+
+	// The ingested data is only measured once per check interval
+	humioClient.SetRepositoryIngestUsage("noisy", ingestQuotaDailyStart, ingestQuotaBytesPerGB)
+	if err := r.reconcileIngestQuota(context.Background(), &humioapi.Config{}, req, hr, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if !humioClient.IngestBlocked("noisy") {
+		t.Errorf("expected ingest to still be blocked before the next check")
+	}
+
+	if err := r.reconcileIngestQuota(context.Background(), &humioapi.Config{}, req, hr, now.Add(ingestQuotaCheckInterval)); err != nil {
+		t.Fatal(err)
+	}
+	if humioClient.IngestBlocked("noisy") || hr.Status.IngestQuota.Blocked {
+		t.Errorf("expected ingest to be unblocked, got %+v", hr.Status.IngestQuota)
+	}
+
+	humioClient.SetRepositoryIngestUsage("noisy", ingestQuotaDailyStart, 11*ingestQuotaBytesPerGB)
+	if err := r.reconcileIngestQuota(context.Background(), &humioapi.Config{}, req, hr, now.Add(2*ingestQuotaCheckInterval)); err != nil {
+		t.Fatal(err)
+	}
+	hr.Spec.IngestQuota = nil
+	if err := r.reconcileIngestQuota(context.Background(), &humioapi.Config{}, req, hr, now.Add(2*ingestQuotaCheckInterval)); err != nil {
+		t.Fatal(err)
+	}
+	if humioClient.IngestBlocked("noisy") || hr.Status.IngestQuota != nil {
+		t.Errorf("expected ingest to be unblocked and the status to be cleared after removing the quota, got %+v", hr.Status.IngestQuota)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// repositoryStatisticsCollectionInterval is how often the statistics of a repository are collected. Collecting them
+// lists every segment of the repository, so it is not done on every reconcile.
+const repositoryStatisticsCollectionInterval = 10 * time.Minute
+
+// repositoryStatisticsStatus returns the status reporting the given repository statistics collected at the given time
+func repositoryStatisticsStatus(statistics *humio.RepositoryStatistics, now time.Time) *humiov1alpha1.HumioRepositoryStatistics {
+	collectionTime := metav1.NewTime(now)
+	status := &humiov1alpha1.HumioRepositoryStatistics{
+		SegmentCount:      statistics.SegmentCount,
+		CompressedBytes:   statistics.CompressedByteSize,
+		UncompressedBytes: statistics.UncompressedByteSize,
+		CollectionTime:    &collectionTime,
+	}
+	if statistics.CompressedByteSize > 0 {
+		status.CompressionRatio = fmt.Sprintf("%.2f", float64(statistics.UncompressedByteSize)/float64(statistics.CompressedByteSize))
+	}
+	if statistics.OldestEventTime != nil {
+		oldestEventTime := metav1.NewTime(*statistics.OldestEventTime)
+		status.OldestEventTime = &oldestEventTime
+	}
+	return status
+}
+
+// reconcileStatistics collects the statistics of the repository into its status once per collection interval.
+// Statistics are informational, so failing to collect them keeps the previously collected statistics rather than
+// failing the reconcile.
+func (r *HumioRepositoryReconciler) reconcileStatistics(ctx context.Context, config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository, now time.Time) error {
+	status := hr.Status.Statistics
+	if status != nil && status.CollectionTime != nil && now.Sub(status.CollectionTime.Time) < repositoryStatisticsCollectionInterval {
+		return nil
+	}
+
+	statistics, err := r.HumioClient.GetRepositoryStatistics(config, req, hr.Spec.Name)
+	if err != nil {
+		r.Log.Error(err, "could not collect repository statistics")
+		return nil
+	}
+	hr.Status.Statistics = repositoryStatisticsStatus(statistics, now)
+	return r.Status().Update(ctx, hr)
+}
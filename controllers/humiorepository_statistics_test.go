@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRepositoryStatisticsStatus(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	oldest := now.Add(-30 * 24 * time.Hour)
+
+	status := repositoryStatisticsStatus(&humio.RepositoryStatistics{
+		CompressedByteSize:   1000,
+		UncompressedByteSize: 12345,
+		SegmentCount:         42,
+		OldestEventTime:      &oldest,
+	}, now)
+	if status.SegmentCount != 42 || status.CompressedBytes != 1000 || status.UncompressedBytes != 12345 {
+		t.Errorf("unexpected statistics %+v", status)
+	}
+	if status.CompressionRatio != "12.35" {
+		t.Errorf("expected compression ratio 12.35, got %s", status.CompressionRatio)
+	}
+	if status.OldestEventTime == nil || !status.OldestEventTime.Time.Equal(oldest) || !status.CollectionTime.Time.Equal(now) {
+		t.Errorf("unexpected timestamps %+v", status)
+	}
+
+	status = repositoryStatisticsStatus(&humio.RepositoryStatistics{}, now)
+	if status.CompressionRatio != "" || status.OldestEventTime != nil {
+		t.Errorf("expected no compression ratio or oldest event for an empty repository, got %+v", status)
+	}
+}
+
+func TestReconcileRepositoryStatistics(t *testing.T) {
+	hr := &humiov1alpha1.HumioRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       humiov1alpha1.HumioRepositorySpec{Name: "web"},
+	}
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+	r := &HumioRepositoryReconciler{
+		Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(hr).WithStatusSubresource(hr).Build(),
+		HumioClient: humioClient,
+		Log:         logr.Discard(),
+	}
+	req := reconcile.Request{}
+	now := time.Now().Truncate(time.Second)
+
+	humioClient.SetRepositoryStatistics("web", humio.RepositoryStatistics{CompressedByteSize: 100, UncompressedByteSize: 1000, SegmentCount: 3})
+	if err := r.reconcileStatistics(context.Background(), &humioapi.Config{}, req, hr, now); err != nil {
+		t.Fatal(err)
+	}
+	if hr.Status.Statistics == nil || hr.Status.Statistics.SegmentCount != 3 || hr.Status.Statistics.CompressionRatio != "10.00" {
+		t.Fatalf("expected statistics to be collected, got %+v", hr.Status.Statistics)
+	}
+
+	// Statistics are only collected once per collection interval
+	humioClient.SetRepositoryStatistics("web", humio.RepositoryStatistics{CompressedByteSize: 100, UncompressedByteSize: 1000, SegmentCount: 5})
+	if err := r.reconcileStatistics(context.Background(), &humioapi.Config{}, req, hr, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if hr.Status.Statistics.SegmentCount != 3 {
+		t.Errorf("expected statistics to be kept before the next collection, got %+v", hr.Status.Statistics)
+	}
+
+	if err := r.reconcileStatistics(context.Background(), &humioapi.Config{}, req, hr, now.Add(repositoryStatisticsCollectionInterval)); err != nil {
+		t.Fatal(err)
+	}
+	if hr.Status.Statistics.SegmentCount != 5 {
+		t.Errorf("expected statistics to be collected again, got %+v", hr.Status.Statistics)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// HumioRetentionPolicyReconciler reconciles a HumioRetentionPolicy object. The retention of the policy is applied by
+// the HumioRepository controller, so this controller only reports which repositories the policy is applied to.
+type HumioRetentionPolicyReconciler struct {
+	client.Client
+	BaseLogger logr.Logger
+	Log        logr.Logger
+	Namespace  string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioretentionpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioretentionpolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioretentionpolicies/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiorepositories,verbs=get;list;watch
+
+func (r *HumioRetentionPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioRetentionPolicy")
+
+	hrp := &humiov1alpha1.HumioRetentionPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, hrp); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	status := humiov1alpha1.HumioRetentionPolicyStatus{State: humiov1alpha1.HumioRetentionPolicyStateActive}
+	if _, err := metav1.LabelSelectorAsSelector(&hrp.Spec.RepositorySelector); err != nil {
+		status.State = humiov1alpha1.HumioRetentionPolicyStateConfigError
+		status.Message = fmt.Sprintf("invalid repository selector: %s", err)
+		return reconcile.Result{}, r.setStatus(ctx, status, hrp)
+	}
+
+	var policies humiov1alpha1.HumioRetentionPolicyList
+	if err := r.List(ctx, &policies, client.InNamespace(hrp.Namespace)); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to list retention policies")
+	}
+	var repositories humiov1alpha1.HumioRepositoryList
+	if err := r.List(ctx, &repositories, client.InNamespace(hrp.Namespace)); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to list repositories")
+	}
+	for _, hr := range repositories.Items {
+		if policy := selectRetentionPolicy(policies.Items, &hr); policy != nil && policy.Name == hrp.Name {
+			status.Repositories = append(status.Repositories, hr.Name)
+		}
+	}
+	sort.Strings(status.Repositories)
+	return reconcile.Result{}, r.setStatus(ctx, status, hrp)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioRetentionPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioRetentionPolicy{}).
+		Watches(&humiov1alpha1.HumioRepository{}, handler.EnqueueRequestsFromMapFunc(r.retentionPoliciesForRepository)).
+		Complete(entityReconciler(r))
+}
+
+// retentionPoliciesForRepository returns a reconcile request for every HumioRetentionPolicy in the namespace of the
+// given repository, as changes to the labels of the repository may change which policy is applied to it
+func (r *HumioRetentionPolicyReconciler) retentionPoliciesForRepository(ctx context.Context, hr client.Object) []reconcile.Request {
+	var policies humiov1alpha1.HumioRetentionPolicyList
+	if err := r.List(ctx, &policies, client.InNamespace(hr.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list retention policies")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, hrp := range policies.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: hrp.Namespace, Name: hrp.Name},
+		})
+	}
+	return requests
+}
+
+func (r *HumioRetentionPolicyReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioRetentionPolicyStatus, hrp *humiov1alpha1.HumioRetentionPolicy) error {
+	if reflect.DeepEqual(hrp.Status, status) {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting retention policy state to %s, applied to %d repositories", status.State, len(status.Repositories)))
+	hrp.Status = status
+	return r.Status().Update(ctx, hrp)
+}
+
+func (r *HumioRetentionPolicyReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// selectRetentionPolicy returns the retention policy applied to the given repository, or nil if no policy selects it.
+// When several policies select the repository, the oldest policy is applied. Policies with invalid selectors are
+// ignored.
+func selectRetentionPolicy(policies []humiov1alpha1.HumioRetentionPolicy, hr *humiov1alpha1.HumioRepository) *humiov1alpha1.HumioRetentionPolicy {
+	var selected *humiov1alpha1.HumioRetentionPolicy
+	for i := range policies {
+		policy := &policies[i]
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.RepositorySelector)
+		if err != nil || !selector.Matches(labels.Set(hr.Labels)) {
+			continue
+		}
+		if selected == nil || policy.CreationTimestamp.Before(&selected.CreationTimestamp) ||
+			(policy.CreationTimestamp.Equal(&selected.CreationTimestamp) && policy.Name < selected.Name) {
+			selected = policy
+		}
+	}
+	return selected
+}
+
+// repositoryWithRetentionPolicy returns the repository with the retention of the given policy applied. Retention
+// options which are not set by the policy keep the retention of the repository.
+func repositoryWithRetentionPolicy(hr *humiov1alpha1.HumioRepository, policy *humiov1alpha1.HumioRetentionPolicy) *humiov1alpha1.HumioRepository {
+	if policy == nil {
+		return hr
+	}
+	effective := hr.DeepCopy()
+	if policy.Spec.Retention.TimeInDays != 0 {
+		effective.Spec.Retention.TimeInDays = policy.Spec.Retention.TimeInDays
+	}
+	if policy.Spec.Retention.IngestSizeInGB != 0 {
+		effective.Spec.Retention.IngestSizeInGB = policy.Spec.Retention.IngestSizeInGB
+	}
+	if policy.Spec.Retention.StorageSizeInGB != 0 {
+		effective.Spec.Retention.StorageSizeInGB = policy.Spec.Retention.StorageSizeInGB
+	}
+	effective.Spec.AllowDataDeletion = hr.Spec.AllowDataDeletion || policy.Spec.AllowDataDeletion
+	return effective
+}
+
+// retentionPolicyForRepository returns the retention policy applied to the given repository, or nil if no policy
+// selects it
+func (r *HumioRepositoryReconciler) retentionPolicyForRepository(ctx context.Context, hr *humiov1alpha1.HumioRepository) (*humiov1alpha1.HumioRetentionPolicy, error) {
+	var policies humiov1alpha1.HumioRetentionPolicyList
+	if err := r.List(ctx, &policies, client.InNamespace(hr.Namespace)); err != nil {
+		return nil, err
+	}
+	return selectRetentionPolicy(policies.Items, hr), nil
+}
+
+func (r *HumioRepositoryReconciler) setRetentionPolicyName(ctx context.Context, policy *humiov1alpha1.HumioRetentionPolicy, hr *humiov1alpha1.HumioRepository) error {
+	policyName := ""
+	if policy != nil {
+		policyName = policy.Name
+	}
+	if hr.Status.RetentionPolicyName == policyName {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting retention policy to %q", policyName))
+	hr.Status.RetentionPolicyName = policyName
+	return r.Status().Update(ctx, hr)
+}
+
+// repositoriesForRetentionPolicy returns a reconcile request for every HumioRepository in the namespace of the given
+// retention policy. All repositories are reconciled, as repositories may no longer be selected by the policy.
+func (r *HumioRepositoryReconciler) repositoriesForRetentionPolicy(ctx context.Context, hrp client.Object) []reconcile.Request {
+	var repositories humiov1alpha1.HumioRepositoryList
+	if err := r.List(ctx, &repositories, client.InNamespace(hrp.GetNamespace())); err != nil {
+		r.BaseLogger.Error(err, "unable to list repositories")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, hr := range repositories.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: hr.Namespace, Name: hr.Name},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func retentionPolicy(name string, created time.Time, selector metav1.LabelSelector, retention humiov1alpha1.HumioRetention) humiov1alpha1.HumioRetentionPolicy {
+	return humiov1alpha1.HumioRetentionPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.NewTime(created)},
+		Spec:       humiov1alpha1.HumioRetentionPolicySpec{RepositorySelector: selector, Retention: retention},
+	}
+}
+
+func TestSelectRetentionPolicy(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	standard := metav1.LabelSelector{MatchLabels: map[string]string{"retention": "standard"}}
+	invalid := metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "retention", Operator: "Unknown"}}}
+
+	tt := []struct {
+		name     string
+		policies []humiov1alpha1.HumioRetentionPolicy
+		labels   map[string]string
+		expected string
+	}{
+		{
+			name:     "no matching policy",
+			policies: []humiov1alpha1.HumioRetentionPolicy{retentionPolicy("standard", now, standard, humiov1alpha1.HumioRetention{})},
+			labels:   map[string]string{"retention": "long"},
+		},
+		{
+			name: "oldest policy is applied",
+			policies: []humiov1alpha1.HumioRetentionPolicy{
+				retentionPolicy("newer", now, standard, humiov1alpha1.HumioRetention{}),
+				retentionPolicy("older", now.Add(-time.Hour), standard, humiov1alpha1.HumioRetention{}),
+			},
+			labels:   map[string]string{"retention": "standard"},
+			expected: "older",
+		},
+		{
+			name: "empty selector matches all repositories",
+			policies: []humiov1alpha1.HumioRetentionPolicy{
+				retentionPolicy("all", now, metav1.LabelSelector{}, humiov1alpha1.HumioRetention{}),
+			},
+			expected: "all",
+		},
+		{
+			name: "invalid selector is ignored",
+			policies: []humiov1alpha1.HumioRetentionPolicy{
+				retentionPolicy("invalid", now.Add(-time.Hour), invalid, humiov1alpha1.HumioRetention{}),
+				retentionPolicy("standard", now, standard, humiov1alpha1.HumioRetention{}),
+			},
+			labels:   map[string]string{"retention": "standard"},
+			expected: "standard",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hr := &humiov1alpha1.HumioRepository{ObjectMeta: metav1.ObjectMeta{Name: "repository", Labels: tc.labels}}
+			policy := selectRetentionPolicy(tc.policies, hr)
+			name := ""
+			if policy != nil {
+				name = policy.Name
+			}
+			if name != tc.expected {
+				t.Errorf("expected policy %q, got %q", tc.expected, name)
+			}
+		})
+	}
+}
+
+func TestRepositoryWithRetentionPolicy(t *testing.T) {
+	hr := &humiov1alpha1.HumioRepository{
+		Spec: humiov1alpha1.HumioRepositorySpec{
+			Retention: humiov1alpha1.HumioRetention{TimeInDays: 90, StorageSizeInGB: 10},
+		},
+	}
+	policy := retentionPolicy("standard", time.Now(), metav1.LabelSelector{}, humiov1alpha1.HumioRetention{TimeInDays: 30, IngestSizeInGB: 50})
+	policy.Spec.AllowDataDeletion = true
+
+	effective := repositoryWithRetentionPolicy(hr, &policy)
+	expected := humiov1alpha1.HumioRetention{TimeInDays: 30, IngestSizeInGB: 50, StorageSizeInGB: 10}
+	if effective.Spec.Retention != expected {
+		t.Errorf("expected retention %+v, got %+v", expected, effective.Spec.Retention)
+	}
+	if !effective.Spec.AllowDataDeletion {
+		t.Errorf("expected data deletion to be allowed by the policy")
+	}
+	if hr.Spec.Retention.TimeInDays != 90 || hr.Spec.AllowDataDeletion {
+		t.Errorf("expected repository to be left unchanged, got %+v", hr.Spec)
+	}
+	if repositoryWithRetentionPolicy(hr, nil) != hr {
+		t.Errorf("expected repository without policy to be returned as is")
+	}
+}
+
+func TestHumioRetentionPolicyStatus(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	standard := metav1.LabelSelector{MatchLabels: map[string]string{"retention": "standard"}}
+	older := retentionPolicy("older", now.Add(-time.Hour), standard, humiov1alpha1.HumioRetention{TimeInDays: 30})
+	newer := retentionPolicy("newer", now, metav1.LabelSelector{}, humiov1alpha1.HumioRetention{TimeInDays: 7})
+	repositories := []client.Object{
+		&humiov1alpha1.HumioRepository{ObjectMeta: metav1.ObjectMeta{Name: "standard", Namespace: "default", Labels: map[string]string{"retention": "standard"}}},
+		&humiov1alpha1.HumioRepository{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}},
+	}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	r := &HumioRetentionPolicyReconciler{
+		Client:     fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(repositories, &older, &newer)...).WithStatusSubresource(&older, &newer).Build(),
+		BaseLogger: logr.Discard(),
+	}
+
+	for name, expected := range map[string][]string{"older": {"standard"}, "newer": {"other"}} {
+		key := types.NamespacedName{Namespace: "default", Name: name}
+		if _, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		var hrp humiov1alpha1.HumioRetentionPolicy
+		if err := r.Get(context.Background(), key, &hrp); err != nil {
+			t.Fatal(err)
+		}
+		if hrp.Status.State != humiov1alpha1.HumioRetentionPolicyStateActive || len(hrp.Status.Repositories) != 1 || hrp.Status.Repositories[0] != expected[0] {
+			t.Errorf("expected policy %s to be applied to %v, got %+v", name, expected, hrp.Status)
+		}
+	}
+}
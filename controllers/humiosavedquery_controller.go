@@ -0,0 +1,255 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// HumioSavedQueryReconciler reconciles a HumioSavedQuery object
+type HumioSavedQueryReconciler struct {
+	client.Client
+	BaseLogger  logr.Logger
+	Log         logr.Logger
+	HumioClient humio.Client
+	Namespace   string
+	Recorder    record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiosavedqueries,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiosavedqueries/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiosavedqueries/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+func (r *HumioSavedQueryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioSavedQuery")
+
+	hsq := &humiov1alpha1.HumioSavedQuery{}
+	if err := r.Get(ctx, req.NamespacedName, hsq); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hsq.UID)
+
+	cluster, err := helpers.NewCluster(ctx, r, hsq.Spec.ManagedClusterName, hsq.Spec.ExternalClusterName, hsq.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hsq.Namespace, hsq.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		if err := r.setState(ctx, humiov1alpha1.HumioSavedQueryStateClusterUnavailable, hsq); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set saved query state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		r.Log.Error(err, "unable to obtain humio client config")
+		if err := r.setState(ctx, humiov1alpha1.HumioSavedQueryStateConfigError, hsq); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set saved query state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	if request, requested := resyncRequested(hsq, hsq.Status.LastResyncRequest); requested {
+		r.Log.Info(fmt.Sprintf("resyncing saved query as requested by annotation %s=%s", triggerResyncAnnotation, request))
+		r.HumioClient.InvalidateReadCache(cluster.Config())
+	}
+
+	if hsq.GetDeletionTimestamp() != nil {
+		r.Log.Info("Saved query marked to be deleted")
+		if helpers.ContainsElement(hsq.GetFinalizers(), humioFinalizer) {
+			r.Log.Info("Deleting saved query")
+			if err := r.HumioClient.DeleteSavedQuery(cluster.Config(), req, hsq); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "Delete saved query returned error")
+			}
+			r.Log.Info("Saved query deleted. Removing finalizer")
+			hsq.SetFinalizers(helpers.RemoveElement(hsq.GetFinalizers(), humioFinalizer))
+			if err := r.Update(ctx, hsq); err != nil {
+				return reconcile.Result{}, err
+			}
+			r.Log.Info("Finalizer removed successfully")
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if !helpers.ContainsElement(hsq.GetFinalizers(), humioFinalizer) {
+		r.Log.Info("Finalizer not present, adding finalizer to saved query")
+		hsq.SetFinalizers(append(hsq.GetFinalizers(), humioFinalizer))
+		if err := r.Update(ctx, hsq); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{Requeue: true}, nil
+	}
+
+	if hsq.Status.State == humiov1alpha1.HumioSavedQueryStateExists &&
+		syncUnchanged(hsq, hsq.Status.SyncedGeneration, hsq.Status.AppliedHash, savedQueryDesiredHash(hsq), hsq.Status.LastResyncRequest, hsq.Status.LastSyncTime, time.Now()) {
+		requeue := requeueInterval(hsq, time.Second*15)
+		r.Log.Info(fmt.Sprintf("saved query is unchanged since it was last synced, will requeue after %s", requeue))
+		return reconcile.Result{RequeueAfter: requeue}, nil
+	}
+
+	status, err := r.reconcileSavedQuery(cluster.Config(), req, hsq)
+	if err != nil {
+		state := humiov1alpha1.HumioSavedQueryStateConfigError
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			state = humiov1alpha1.HumioSavedQueryStateClusterUnavailable
+		}
+		if err := r.setState(ctx, state, hsq); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set saved query state")
+		}
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not reconcile saved query")
+	}
+
+	status.SyncedGeneration = hsq.Generation
+	status.ClusterName = syncClusterName(hsq.Spec.ManagedClusterName, hsq.Spec.ExternalClusterName)
+	status.LastSyncTime = syncTime(status.LastSyncTime, time.Now())
+	status.LastResyncRequest = hsq.Annotations[triggerResyncAnnotation]
+	if err := r.setStatus(ctx, status, hsq); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set saved query status")
+	}
+
+	requeue := requeueInterval(hsq, time.Second*15)
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+	return reconcile.Result{RequeueAfter: requeue}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioSavedQueryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioSavedQuery{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioSavedQueryList{}))).
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioSavedQuery{}, r))
+}
+
+// reconcileSavedQuery creates the saved query if it does not exist, and updates it if it has drifted from the spec
+// unless the drift policy leaves changes made outside the operator in place. It returns the status describing the
+// saved query.
+func (r *HumioSavedQueryReconciler) reconcileSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (humiov1alpha1.HumioSavedQueryStatus, error) {
+	status := *hsq.Status.DeepCopy()
+	status.State = humiov1alpha1.HumioSavedQueryStateExists
+	expectedSavedQuery := humio.SavedQueryTransform(hsq)
+	expectedSavedQuery.ID = ""
+	desiredHash := savedQueryDesiredHash(hsq)
+
+	curSavedQuery, err := r.HumioClient.GetSavedQuery(config, req, hsq)
+	if err != nil {
+		return status, fmt.Errorf("could not check if saved query exists: %w", err)
+	}
+	if curSavedQuery.ID == "" {
+		r.Log.Info("saved query doesn't exist. Now adding saved query")
+		addedSavedQuery, err := r.HumioClient.AddSavedQuery(config, req, hsq)
+		if err != nil {
+			return status, fmt.Errorf("could not create saved query: %w", err)
+		}
+		r.Log.Info("created saved query", "SavedQuery", hsq.Spec.Name)
+		applyDriftOutcome(r.Recorder, hsq, driftApply, desiredHash, &status.AppliedHash, &status.Conditions, hsq.Generation)
+		status.DriftPatch = ""
+		status.ID = addedSavedQuery.ID
+		return status, nil
+	}
+
+	expectedSavedQuery.ID = curSavedQuery.ID
+	outcome := evaluateDrift(hsq.Spec.DriftPolicy, status.AppliedHash, desiredHash, !reflect.DeepEqual(*curSavedQuery, *expectedSavedQuery))
+	switch outcome {
+	case driftIgnore:
+		r.Log.Info("saved query was changed outside the operator, leaving the changes in place because of the drift policy")
+	case driftApply, driftRevert:
+		r.Log.Info(fmt.Sprintf("saved query differs, triggering update, expected %#v, got: %#v", expectedSavedQuery, curSavedQuery))
+		if _, err := r.HumioClient.UpdateSavedQuery(config, req, hsq); err != nil {
+			return status, fmt.Errorf("could not update saved query: %w", err)
+		}
+	}
+	applyDriftOutcome(r.Recorder, hsq, outcome, desiredHash, &status.AppliedHash, &status.Conditions, hsq.Generation)
+	status.DriftPatch = ""
+	if outcome == driftIgnore && hsq.Spec.DriftPolicy == humiov1alpha1.HumioDriftPolicyImport {
+		status.DriftPatch = driftPatch(hsq.Spec, importedSavedQuerySpec(hsq.Spec, curSavedQuery))
+	}
+	status.ID = curSavedQuery.ID
+	return status, nil
+}
+
+// savedQueryDesiredHash returns a hash of the desired state of the saved query. The ID is assigned by Humio, so it is
+// not part of the desired state.
+func savedQueryDesiredHash(hsq *humiov1alpha1.HumioSavedQuery) string {
+	expectedSavedQuery := humio.SavedQueryTransform(hsq)
+	expectedSavedQuery.ID = ""
+	return desiredStateHash(expectedSavedQuery)
+}
+
+// importedSavedQuerySpec returns the spec of the saved query with the changes made to the saved query inside Humio.
+// The defaults Humio fills in for the start and end of the query are not imported.
+func importedSavedQuerySpec(spec humiov1alpha1.HumioSavedQuerySpec, savedQuery *humio.SavedQuery) humiov1alpha1.HumioSavedQuerySpec {
+	spec.Description = savedQuery.Description
+	spec.QueryString = savedQuery.QueryString
+	if spec.Start != "" || savedQuery.Start != "24h" {
+		spec.Start = savedQuery.Start
+	}
+	if spec.End != "" || savedQuery.End != "now" {
+		spec.End = savedQuery.End
+	}
+	spec.IsLive = savedQuery.IsLive
+	spec.Arguments = savedQuery.Arguments
+	return spec
+}
+
+func (r *HumioSavedQueryReconciler) setState(ctx context.Context, state string, hsq *humiov1alpha1.HumioSavedQuery) error {
+	status := *hsq.Status.DeepCopy()
+	status.State = state
+	return r.setStatus(ctx, status, hsq)
+}
+
+func (r *HumioSavedQueryReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioSavedQueryStatus, hsq *humiov1alpha1.HumioSavedQuery) error {
+	if reflect.DeepEqual(hsq.Status, status) {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting saved query state to %s", status.State))
+	hsq.Status = status
+	return r.Status().Update(ctx, hsq)
+}
+
+func (r *HumioSavedQueryReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileSavedQuery(t *testing.T) {
+	humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+	r := &HumioSavedQueryReconciler{
+		HumioClient: humioClient,
+		Log:         logr.Discard(),
+	}
+	hsq := &humiov1alpha1.HumioSavedQuery{
+		Spec: humiov1alpha1.HumioSavedQuerySpec{
+			Name:        "errors",
+			ViewName:    "view",
+			QueryString: "loglevel = ?level",
+			Arguments:   map[string]string{"level": "ERROR"},
+		},
+	}
+	config := &humioapi.Config{}
+	req := reconcile.Request{}
+
+	created, err := r.reconcileSavedQuery(config, req, hsq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsq.Status = created
+	savedQuery, err := humioClient.GetSavedQuery(config, req, hsq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || savedQuery.ID != created.ID || savedQuery.Start != "24h" || savedQuery.End != "now" {
+		t.Fatalf("expected saved query to be created with defaults, got %+v", savedQuery)
+	}
+
+	unchanged, err := r.reconcileSavedQuery(config, req, hsq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unchanged, created) {
+		t.Errorf("expected unchanged status %+v, got %+v", created, unchanged)
+	}
+
+	// Changes made in Humio are reverted to match the spec
+	hsq.Spec.Arguments = map[string]string{"level": "WARN"}
+	if _, err := humioClient.UpdateSavedQuery(config, req, hsq); err != nil {
+		t.Fatal(err)
+	}
+	hsq.Spec.Arguments = map[string]string{"level": "ERROR"}
+	updated, err := r.reconcileSavedQuery(config, req, hsq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedQuery, err = humioClient.GetSavedQuery(config, req, hsq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ID != created.ID || savedQuery.Arguments["level"] != "ERROR" {
+		t.Errorf("expected drifted saved query to be updated in place, got %+v", savedQuery)
+	}
+
+	// Changes made in Humio are left in place with the Warn drift policy, until the spec changes
+	hsq.Spec.DriftPolicy = humiov1alpha1.HumioDriftPolicyWarn
+	hsq.Spec.Arguments = map[string]string{"level": "WARN"}
+	if _, err := humioClient.UpdateSavedQuery(config, req, hsq); err != nil {
+		t.Fatal(err)
+	}
+	hsq.Spec.Arguments = map[string]string{"level": "ERROR"}
+	drifted, err := r.reconcileSavedQuery(config, req, hsq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedQuery, err = humioClient.GetSavedQuery(config, req, hsq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if savedQuery.Arguments["level"] != "WARN" || !meta.IsStatusConditionTrue(drifted.Conditions, humiov1alpha1.HumioDriftedConditionType) {
+		t.Errorf("expected drifted saved query to be left in place and reported, got %+v and conditions %+v", savedQuery, drifted.Conditions)
+	}
+	hsq.Status = drifted
+	hsq.Spec.Arguments = map[string]string{"level": "FATAL"}
+	changed, err := r.reconcileSavedQuery(config, req, hsq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedQuery, err = humioClient.GetSavedQuery(config, req, hsq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if savedQuery.Arguments["level"] != "FATAL" || meta.IsStatusConditionTrue(changed.Conditions, humiov1alpha1.HumioDriftedConditionType) {
+		t.Errorf("expected spec change to be applied, got %+v and conditions %+v", savedQuery, changed.Conditions)
+	}
+
+	// The Import drift policy also records a patch which imports the changes made in Humio into the spec
+	hsq.Status = changed
+	hsq.Spec.DriftPolicy = humiov1alpha1.HumioDriftPolicyImport
+	hsq.Spec.Arguments = map[string]string{"level": "WARN"}
+	if _, err := humioClient.UpdateSavedQuery(config, req, hsq); err != nil {
+		t.Fatal(err)
+	}
+	hsq.Spec.Arguments = map[string]string{"level": "FATAL"}
+	imported, err := r.reconcileSavedQuery(config, req, hsq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"spec":{"arguments":{"level":"WARN"}}}`; imported.DriftPatch != expected {
+		t.Errorf("expected drift patch %s, got %s", expected, imported.DriftPatch)
+	}
+}
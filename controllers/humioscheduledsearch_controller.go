@@ -0,0 +1,260 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// HumioScheduledSearchReconciler reconciles a HumioScheduledSearch object
+type HumioScheduledSearchReconciler struct {
+	client.Client
+	BaseLogger  logr.Logger
+	Log         logr.Logger
+	HumioClient humio.Client
+	Namespace   string
+	Recorder    record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioscheduledsearches,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioscheduledsearches/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioscheduledsearches/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+func (r *HumioScheduledSearchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioScheduledSearch")
+
+	hss := &humiov1alpha1.HumioScheduledSearch{}
+	if err := r.Get(ctx, req.NamespacedName, hss); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hss.UID)
+
+	cluster, err := helpers.NewCluster(ctx, r, hss.Spec.ManagedClusterName, hss.Spec.ExternalClusterName, hss.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hss.Namespace, hss.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		if err := r.setState(ctx, humiov1alpha1.HumioScheduledSearchStateClusterUnavailable, hss); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set scheduled search state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		r.Log.Error(err, "unable to obtain humio client config")
+		if err := r.setState(ctx, humiov1alpha1.HumioScheduledSearchStateConfigError, hss); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set scheduled search state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	if request, requested := resyncRequested(hss, hss.Status.LastResyncRequest); requested {
+		r.Log.Info(fmt.Sprintf("resyncing scheduled search as requested by annotation %s=%s", triggerResyncAnnotation, request))
+		r.HumioClient.InvalidateReadCache(cluster.Config())
+	}
+
+	if hss.GetDeletionTimestamp() != nil {
+		r.Log.Info("Scheduled search marked to be deleted")
+		if helpers.ContainsElement(hss.GetFinalizers(), humioFinalizer) {
+			r.Log.Info("Deleting scheduled search")
+			if err := r.HumioClient.DeleteScheduledSearch(cluster.Config(), req, hss); err != nil {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "Delete scheduled search returned error")
+			}
+			r.Log.Info("Scheduled search deleted. Removing finalizer")
+			hss.SetFinalizers(helpers.RemoveElement(hss.GetFinalizers(), humioFinalizer))
+			if err := r.Update(ctx, hss); err != nil {
+				return reconcile.Result{}, err
+			}
+			r.Log.Info("Finalizer removed successfully")
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if !helpers.ContainsElement(hss.GetFinalizers(), humioFinalizer) {
+		r.Log.Info("Finalizer not present, adding finalizer to scheduled search")
+		hss.SetFinalizers(append(hss.GetFinalizers(), humioFinalizer))
+		if err := r.Update(ctx, hss); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{Requeue: true}, nil
+	}
+
+	if hss.Status.State == humiov1alpha1.HumioScheduledSearchStateExists &&
+		syncUnchanged(hss, hss.Status.SyncedGeneration, hss.Status.AppliedHash, scheduledSearchDesiredHash(hss), hss.Status.LastResyncRequest, hss.Status.LastSyncTime, time.Now()) {
+		requeue := requeueInterval(hss, time.Second*15)
+		r.Log.Info(fmt.Sprintf("scheduled search is unchanged since it was last synced, will requeue after %s", requeue))
+		return reconcile.Result{RequeueAfter: requeue}, nil
+	}
+
+	status, err := r.reconcileScheduledSearch(cluster.Config(), req, hss)
+	if err != nil {
+		state := humiov1alpha1.HumioScheduledSearchStateConfigError
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			state = humiov1alpha1.HumioScheduledSearchStateClusterUnavailable
+		}
+		if err := r.setState(ctx, state, hss); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set scheduled search state")
+		}
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not reconcile scheduled search")
+	}
+
+	status.SyncedGeneration = hss.Generation
+	status.ClusterName = syncClusterName(hss.Spec.ManagedClusterName, hss.Spec.ExternalClusterName)
+	status.LastSyncTime = syncTime(status.LastSyncTime, time.Now())
+	status.LastResyncRequest = hss.Annotations[triggerResyncAnnotation]
+	if err := r.setStatus(ctx, status, hss); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set scheduled search status")
+	}
+
+	requeue := requeueInterval(hss, time.Second*15)
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+	return reconcile.Result{RequeueAfter: requeue}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioScheduledSearchReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioScheduledSearch{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioScheduledSearchList{}))).
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioScheduledSearch{}, r))
+}
+
+// reconcileScheduledSearch creates the scheduled search if it does not exist, and updates it if it has drifted from the spec
+// unless the drift policy leaves changes made outside the operator in place. It returns the status describing the
+// scheduled search.
+func (r *HumioScheduledSearchReconciler) reconcileScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (humiov1alpha1.HumioScheduledSearchStatus, error) {
+	status := *hss.Status.DeepCopy()
+	status.State = humiov1alpha1.HumioScheduledSearchStateExists
+	expectedScheduledSearch := humio.ScheduledSearchTransform(hss)
+	expectedScheduledSearch.ID = ""
+	desiredHash := scheduledSearchDesiredHash(hss)
+
+	curScheduledSearch, err := r.HumioClient.GetScheduledSearch(config, req, hss)
+	if err != nil {
+		return status, fmt.Errorf("could not check if scheduled search exists: %w", err)
+	}
+	if curScheduledSearch.ID == "" {
+		r.Log.Info("scheduled search doesn't exist. Now adding scheduled search")
+		addedScheduledSearch, err := r.HumioClient.AddScheduledSearch(config, req, hss)
+		if err != nil {
+			return status, fmt.Errorf("could not create scheduled search: %w", err)
+		}
+		r.Log.Info("created scheduled search", "ScheduledSearch", hss.Spec.Name)
+		applyDriftOutcome(r.Recorder, hss, driftApply, desiredHash, &status.AppliedHash, &status.Conditions, hss.Generation)
+		status.DriftPatch = ""
+		status.ID = addedScheduledSearch.ID
+		return status, nil
+	}
+
+	expectedScheduledSearch.ID = curScheduledSearch.ID
+	outcome := evaluateDrift(hss.Spec.DriftPolicy, status.AppliedHash, desiredHash, !reflect.DeepEqual(*curScheduledSearch, *expectedScheduledSearch))
+	switch outcome {
+	case driftIgnore:
+		r.Log.Info("scheduled search was changed outside the operator, leaving the changes in place because of the drift policy")
+	case driftApply, driftRevert:
+		r.Log.Info(fmt.Sprintf("scheduled search differs, triggering update, expected %#v, got: %#v", expectedScheduledSearch, curScheduledSearch))
+		if _, err := r.HumioClient.UpdateScheduledSearch(config, req, hss); err != nil {
+			return status, fmt.Errorf("could not update scheduled search: %w", err)
+		}
+	}
+	applyDriftOutcome(r.Recorder, hss, outcome, desiredHash, &status.AppliedHash, &status.Conditions, hss.Generation)
+	status.DriftPatch = ""
+	if outcome == driftIgnore && hss.Spec.DriftPolicy == humiov1alpha1.HumioDriftPolicyImport {
+		status.DriftPatch = driftPatch(hss.Spec, importedScheduledSearchSpec(hss.Spec, curScheduledSearch))
+	}
+	status.ID = curScheduledSearch.ID
+	return status, nil
+}
+
+// scheduledSearchDesiredHash returns a hash of the desired state of the scheduled search. The ID is assigned by Humio, so it is
+// not part of the desired state.
+func scheduledSearchDesiredHash(hss *humiov1alpha1.HumioScheduledSearch) string {
+	expectedScheduledSearch := humio.ScheduledSearchTransform(hss)
+	expectedScheduledSearch.ID = ""
+	return desiredStateHash(expectedScheduledSearch)
+}
+
+// importedScheduledSearchSpec returns the spec of the scheduled search with the changes made to the scheduled search
+// inside Humio. The defaults Humio fills in for unset fields are not imported.
+func importedScheduledSearchSpec(spec humiov1alpha1.HumioScheduledSearchSpec, scheduledSearch *humio.ScheduledSearch) humiov1alpha1.HumioScheduledSearchSpec {
+	defaults := humio.ScheduledSearchTransform(&humiov1alpha1.HumioScheduledSearch{})
+	spec.Description = scheduledSearch.Description
+	spec.QueryString = scheduledSearch.QueryString
+	spec.QueryStart = scheduledSearch.QueryStart
+	if spec.QueryEnd != "" || scheduledSearch.QueryEnd != defaults.QueryEnd {
+		spec.QueryEnd = scheduledSearch.QueryEnd
+	}
+	spec.Schedule = scheduledSearch.Schedule
+	if spec.TimeZone != "" || scheduledSearch.TimeZone != defaults.TimeZone {
+		spec.TimeZone = scheduledSearch.TimeZone
+	}
+	spec.BackfillLimit = scheduledSearch.BackfillLimit
+	spec.Silenced = !scheduledSearch.Enabled
+	spec.Actions = scheduledSearch.Actions
+	spec.Labels = scheduledSearch.Labels
+	return spec
+}
+
+func (r *HumioScheduledSearchReconciler) setState(ctx context.Context, state string, hss *humiov1alpha1.HumioScheduledSearch) error {
+	status := *hss.Status.DeepCopy()
+	status.State = state
+	return r.setStatus(ctx, status, hss)
+}
+
+func (r *HumioScheduledSearchReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioScheduledSearchStatus, hss *humiov1alpha1.HumioScheduledSearch) error {
+	if reflect.DeepEqual(hss.Status, status) {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting scheduled search state to %s", status.State))
+	hss.Status = status
+	return r.Status().Update(ctx, hss)
+}
+
+func (r *HumioScheduledSearchReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileScheduledSearch(t *testing.T) {
+	humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+	r := &HumioScheduledSearchReconciler{
+		HumioClient: humioClient,
+		Log:         logr.Discard(),
+	}
+	hss := &humiov1alpha1.HumioScheduledSearch{
+		Spec: humiov1alpha1.HumioScheduledSearchSpec{
+			Name:        "daily-errors",
+			ViewName:    "view",
+			QueryString: "error = true | bucket(span=1d)",
+			QueryStart:  "1d",
+			Schedule:    "0 8 * * *",
+			Actions:     []string{"email"},
+		},
+	}
+	config := &humioapi.Config{}
+	req := reconcile.Request{}
+
+	created, err := r.reconcileScheduledSearch(config, req, hss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hss.Status = created
+	scheduledSearch, err := humioClient.GetScheduledSearch(config, req, hss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || scheduledSearch.ID != created.ID || scheduledSearch.TimeZone != "UTC" || scheduledSearch.QueryEnd != "now" {
+		t.Fatalf("expected scheduled search to be created with defaults, got %+v", scheduledSearch)
+	}
+
+	unchanged, err := r.reconcileScheduledSearch(config, req, hss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unchanged, created) {
+		t.Errorf("expected unchanged status %+v, got %+v", created, unchanged)
+	}
+
+	// Changing the time zone updates the scheduled search in place
+	hss.Spec.TimeZone = "UTC+01:00"
+	updated, err := r.reconcileScheduledSearch(config, req, hss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduledSearch, err = humioClient.GetScheduledSearch(config, req, hss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ID != created.ID || scheduledSearch.TimeZone != "UTC+01:00" {
+		t.Errorf("expected time zone to be updated in place, got %+v", scheduledSearch)
+	}
+
+	// A time zone changed in Humio is reverted to match the spec
+	hss.Spec.TimeZone = "UTC-05:00"
+	if _, err := humioClient.UpdateScheduledSearch(config, req, hss); err != nil {
+		t.Fatal(err)
+	}
+	hss.Spec.TimeZone = "UTC+01:00"
+	if _, err := r.reconcileScheduledSearch(config, req, hss); err != nil {
+		t.Fatal(err)
+	}
+	scheduledSearch, err = humioClient.GetScheduledSearch(config, req, hss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scheduledSearch.TimeZone != "UTC+01:00" {
+		t.Errorf("expected drifted time zone to be reverted, got %+v", scheduledSearch)
+	}
+
+	// The Import drift policy records a patch which imports a time zone changed in Humio into the spec
+	hss.Status = updated
+	hss.Spec.DriftPolicy = humiov1alpha1.HumioDriftPolicyImport
+	hss.Spec.TimeZone = "UTC-05:00"
+	if _, err := humioClient.UpdateScheduledSearch(config, req, hss); err != nil {
+		t.Fatal(err)
+	}
+	hss.Spec.TimeZone = "UTC+01:00"
+	imported, err := r.reconcileScheduledSearch(config, req, hss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"spec":{"timeZone":"UTC-05:00"}}`; imported.DriftPatch != expected {
+		t.Errorf("expected drift patch %s, got %s", expected, imported.DriftPatch)
+	}
+}
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -28,9 +29,12 @@ import (
 	"github.com/humio/humio-operator/pkg/helpers"
 	"github.com/humio/humio-operator/pkg/humio"
 	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
@@ -43,6 +47,7 @@ type HumioViewReconciler struct {
 	Log         logr.Logger
 	HumioClient humio.Client
 	Namespace   string
+	Recorder    record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=core.humio.com,resources=humioviews,verbs=get;list;watch;create;update;patch;delete
@@ -76,6 +81,17 @@ func (r *HumioViewReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	r.Log = r.Log.WithValues("Request.UID", hv.UID)
 
 	cluster, err := helpers.NewCluster(ctx, r, hv.Spec.ManagedClusterName, hv.Spec.ExternalClusterName, hv.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hv.Namespace, hv.Spec.APITokenSecretName)
+	}
+	if errors.Is(err, helpers.ErrExternalClusterUnavailable) {
+		r.Log.Error(err, "external cluster is unavailable")
+		err = r.setState(ctx, humiov1alpha1.HumioViewStateClusterUnavailable, hv)
+		if err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set cluster state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
 	if err != nil || cluster == nil || cluster.Config() == nil {
 		r.Log.Error(err, "unable to obtain humio client config")
 		err = r.setState(ctx, humiov1alpha1.HumioParserStateConfigError, hv)
@@ -87,6 +103,10 @@ func (r *HumioViewReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	defer func(ctx context.Context, humioClient humio.Client, hv *humiov1alpha1.HumioView) {
 		curView, err := r.HumioClient.GetView(cluster.Config(), req, hv)
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			_ = r.setState(ctx, humiov1alpha1.HumioViewStateClusterUnavailable, hv)
+			return
+		}
 		if err != nil {
 			_ = r.setState(ctx, humiov1alpha1.HumioViewStateUnknown, hv)
 			return
@@ -170,8 +190,13 @@ func (r *HumioViewReconciler) reconcileHumioView(ctx context.Context, config *hu
 		}
 	}
 
-	r.Log.Info("done reconciling, will requeue after 15 seconds")
-	return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	if err := r.setSyncStatus(ctx, time.Now(), hv); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set view sync status")
+	}
+
+	requeue := requeueInterval(hv, time.Second*15)
+	r.Log.Info(fmt.Sprintf("done reconciling, will requeue after %s", requeue))
+	return reconcile.Result{RequeueAfter: requeue}, nil
 }
 
 // viewConnectionsDiffer returns whether two slices of connections differ.
@@ -209,7 +234,8 @@ func sortConnections(connections []humioapi.ViewConnection) {
 func (r *HumioViewReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&humiov1alpha1.HumioView{}).
-		Complete(r)
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioViewList{}))).
+		Complete(humioEntityReconciler(r, r.Recorder, &humiov1alpha1.HumioView{}, r))
 }
 
 func (r *HumioViewReconciler) setState(ctx context.Context, state string, hr *humiov1alpha1.HumioView) error {
@@ -221,6 +247,17 @@ func (r *HumioViewReconciler) setState(ctx context.Context, state string, hr *hu
 	return r.Status().Update(ctx, hr)
 }
 
+func (r *HumioViewReconciler) setSyncStatus(ctx context.Context, now time.Time, hv *humiov1alpha1.HumioView) error {
+	status := hv.Status.DeepCopy()
+	status.ClusterName = syncClusterName(hv.Spec.ManagedClusterName, hv.Spec.ExternalClusterName)
+	status.LastSyncTime = syncTime(status.LastSyncTime, now)
+	if reflect.DeepEqual(hv.Status, *status) {
+		return nil
+	}
+	hv.Status = *status
+	return r.Status().Update(ctx, hv)
+}
+
 func (r *HumioViewReconciler) logErrorAndReturn(err error, msg string) error {
 	r.Log.Error(err, msg)
 	return fmt.Errorf("%s: %w", msg, err)
@@ -0,0 +1,285 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+)
+
+// humioViewExportMaxConfigMapBytes is the maximum size of the manifests written to the ConfigMap, leaving room for
+// the metadata of the ConfigMap below the 1 MiB limit of Kubernetes objects
+const humioViewExportMaxConfigMapBytes = 900 * 1024
+
+var humioViewExportInvalidNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// HumioViewExportReconciler reconciles a HumioViewExport object
+type HumioViewExportReconciler struct {
+	client.Client
+	BaseLogger  logr.Logger
+	Log         logr.Logger
+	HumioClient humio.Client
+	Namespace   string
+}
+
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioviewexports,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioviewexports/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioviewexports/finalizers,verbs=update
+
+func (r *HumioViewExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Namespace != "" {
+		if r.Namespace != req.Namespace {
+			return reconcile.Result{}, nil
+		}
+	}
+
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling HumioViewExport")
+
+	hve := &humiov1alpha1.HumioViewExport{}
+	if err := r.Get(ctx, req.NamespacedName, hve); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.Log = r.Log.WithValues("Request.UID", hve.UID)
+
+	// The view is exported once per generation, so the exported manifests do not change underneath the user
+	if hve.Status.ObservedGeneration == hve.Generation && hve.Status.State == humiov1alpha1.HumioViewExportStateSucceeded {
+		return reconcile.Result{}, nil
+	}
+
+	cluster, err := helpers.NewCluster(ctx, r, hve.Spec.ManagedClusterName, hve.Spec.ExternalClusterName, hve.Namespace, helpers.UseCertManager(), true)
+	if err == nil {
+		err = helpers.SetAPITokenFromSecret(ctx, r, cluster, hve.Namespace, hve.Spec.APITokenSecretName)
+	}
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		r.Log.Error(err, "unable to obtain humio client config")
+		if err := r.setState(ctx, humiov1alpha1.HumioViewExportStateConfigError, "unable to obtain humio client config", hve); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set view export state")
+		}
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	dashboards, err := r.HumioClient.ListDashboards(cluster.Config(), req, hve.Spec.ViewName)
+	if err == nil {
+		var savedQueries []humio.SavedQuery
+		savedQueries, err = r.HumioClient.ListSavedQueries(cluster.Config(), req, hve.Spec.ViewName)
+		if err == nil {
+			err = r.writeViewExport(ctx, hve, dashboards, savedQueries, metav1.Now())
+		}
+	}
+	if err != nil {
+		if err := r.setState(ctx, humiov1alpha1.HumioViewExportStateFailed, err.Error(), hve); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, "unable to set view export state")
+		}
+		if errors.Is(err, humio.ErrClusterUnavailable) {
+			return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+		}
+		return reconcile.Result{}, r.logErrorAndReturn(err, "could not export view")
+	}
+
+	r.Log.Info("done exporting view")
+	return reconcile.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HumioViewExportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&humiov1alpha1.HumioViewExport{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(entitiesForSecret(r, r.BaseLogger, &humiov1alpha1.HumioViewExportList{}))).
+		Owns(&corev1.ConfigMap{}).
+		Complete(withReconcileContext(r))
+}
+
+// writeViewExport writes the manifests of the dashboards and saved queries to the ConfigMap of the view export, and
+// records the export in the status
+func (r *HumioViewExportReconciler) writeViewExport(ctx context.Context, hve *humiov1alpha1.HumioViewExport, dashboards []humio.Dashboard, savedQueries []humio.SavedQuery, now metav1.Time) error {
+	data, status, err := constructHumioViewExportManifests(hve, dashboards, savedQueries)
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      humioViewExportConfigMapName(hve),
+			Namespace: hve.Namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r, configMap, func() error {
+		if configMap.ResourceVersion != "" && !metav1.IsControlledBy(configMap, hve) {
+			return fmt.Errorf("configMap %s already exists and is not owned by the view export", configMap.Name)
+		}
+		configMap.Data = data
+		return controllerutil.SetControllerReference(hve, configMap, r.Scheme())
+	})
+	if err != nil {
+		return fmt.Errorf("could not write manifests: %w", err)
+	}
+
+	status.State = humiov1alpha1.HumioViewExportStateSucceeded
+	status.ConfigMapName = configMap.Name
+	status.ExportTime = &now
+	status.ObservedGeneration = hve.Generation
+	return r.setStatus(ctx, status, hve)
+}
+
+// constructHumioViewExportManifests returns the HumioDashboard and HumioSavedQuery manifests for the dashboards and
+// saved queries of the view, keyed by file name, along with the status listing the exported resources
+func constructHumioViewExportManifests(hve *humiov1alpha1.HumioViewExport, dashboards []humio.Dashboard, savedQueries []humio.SavedQuery) (map[string]string, humiov1alpha1.HumioViewExportStatus, error) {
+	data := map[string]string{}
+	status := humiov1alpha1.HumioViewExportStatus{}
+	size := 0
+	add := func(kind, name string, manifest interface{}) error {
+		out, err := yaml.Marshal(manifest)
+		if err != nil {
+			return fmt.Errorf("could not marshal %s %s: %w", kind, name, err)
+		}
+		size += len(out)
+		if size > humioViewExportMaxConfigMapBytes {
+			return fmt.Errorf("the exported manifests exceed the maximum size of %d bytes", humioViewExportMaxConfigMapBytes)
+		}
+		data[fmt.Sprintf("%s-%s.yaml", strings.ToLower(kind), name)] = string(out)
+		return nil
+	}
+
+	names := map[string]bool{}
+	for _, dashboard := range dashboards {
+		name := humioViewExportResourceName(hve.Spec.ViewName, dashboard.Name, names)
+		hd := humiov1alpha1.HumioDashboard{
+			TypeMeta:   metav1.TypeMeta{APIVersion: humiov1alpha1.GroupVersion.String(), Kind: "HumioDashboard"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: humiov1alpha1.HumioDashboardSpec{
+				ManagedClusterName:  hve.Spec.ManagedClusterName,
+				ExternalClusterName: hve.Spec.ExternalClusterName,
+				Name:                dashboard.Name,
+				ViewName:            hve.Spec.ViewName,
+				// The template of a HumioDashboard is rendered as a Go template, so template actions are escaped
+				Template: strings.ReplaceAll(dashboard.TemplateYaml, "{{", `{{"{{"}}`),
+			},
+		}
+		if err := add(hd.Kind, name, manifestWithoutStatus(&hd)); err != nil {
+			return nil, status, err
+		}
+		status.Dashboards = append(status.Dashboards, name)
+	}
+
+	names = map[string]bool{}
+	for _, savedQuery := range savedQueries {
+		name := humioViewExportResourceName(hve.Spec.ViewName, savedQuery.Name, names)
+		hsq := humiov1alpha1.HumioSavedQuery{
+			TypeMeta:   metav1.TypeMeta{APIVersion: humiov1alpha1.GroupVersion.String(), Kind: "HumioSavedQuery"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: humiov1alpha1.HumioSavedQuerySpec{
+				ManagedClusterName:  hve.Spec.ManagedClusterName,
+				ExternalClusterName: hve.Spec.ExternalClusterName,
+				Name:                savedQuery.Name,
+				ViewName:            hve.Spec.ViewName,
+				Description:         savedQuery.Description,
+				QueryString:         savedQuery.QueryString,
+				Start:               savedQuery.Start,
+				End:                 savedQuery.End,
+				IsLive:              savedQuery.IsLive,
+				Arguments:           savedQuery.Arguments,
+			},
+		}
+		if err := add(hsq.Kind, name, manifestWithoutStatus(&hsq)); err != nil {
+			return nil, status, err
+		}
+		status.SavedQueries = append(status.SavedQueries, name)
+	}
+	return data, status, nil
+}
+
+// manifestWithoutStatus returns the object as a map without the status and the creation timestamp, which are not
+// part of a manifest
+func manifestWithoutStatus(obj interface{}) map[string]interface{} {
+	out, _ := yaml.Marshal(obj)
+	manifest := map[string]interface{}{}
+	_ = yaml.Unmarshal(out, &manifest)
+	delete(manifest, "status")
+	if metadata, ok := manifest["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "creationTimestamp")
+	}
+	return manifest
+}
+
+// humioViewExportResourceName returns a unique resource name for the exported dashboard or saved query with the given
+// name. Names already in use are recorded in names.
+func humioViewExportResourceName(viewName, name string, names map[string]bool) string {
+	base := humioViewExportInvalidNameCharacters.ReplaceAllString(strings.ToLower(fmt.Sprintf("%s-%s", viewName, name)), "-")
+	base = strings.Trim(base, "-")
+	if len(base) > 240 {
+		base = strings.TrimRight(base[:240], "-")
+	}
+	resourceName := base
+	for i := 2; names[resourceName]; i++ {
+		resourceName = fmt.Sprintf("%s-%d", base, i)
+	}
+	names[resourceName] = true
+	return resourceName
+}
+
+func humioViewExportConfigMapName(hve *humiov1alpha1.HumioViewExport) string {
+	if hve.Spec.ConfigMapName != "" {
+		return hve.Spec.ConfigMapName
+	}
+	return hve.Name
+}
+
+func (r *HumioViewExportReconciler) setState(ctx context.Context, state, message string, hve *humiov1alpha1.HumioViewExport) error {
+	status := *hve.Status.DeepCopy()
+	status.State = state
+	status.Message = message
+	return r.setStatus(ctx, status, hve)
+}
+
+func (r *HumioViewExportReconciler) setStatus(ctx context.Context, status humiov1alpha1.HumioViewExportStatus, hve *humiov1alpha1.HumioViewExport) error {
+	if reflect.DeepEqual(hve.Status, status) {
+		return nil
+	}
+	r.Log.Info(fmt.Sprintf("setting view export state to %s", status.State))
+	hve.Status = status
+	return r.Status().Update(ctx, hve)
+}
+
+func (r *HumioViewExportReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
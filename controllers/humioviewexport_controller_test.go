@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func TestConstructHumioViewExportManifests(t *testing.T) {
+	hve := &humiov1alpha1.HumioViewExport{
+		ObjectMeta: metav1.ObjectMeta{Name: "export", Namespace: "default"},
+		Spec:       humiov1alpha1.HumioViewExportSpec{ManagedClusterName: "humio", ViewName: "Web"},
+	}
+	dashboards := []humio.Dashboard{
+		{ID: "1", Name: "Overview", TemplateYaml: "name: Overview\nwidgets:\n  text:\n    content: \"{{ not a parameter }}\"\n"},
+		{ID: "2", Name: "overview!", TemplateYaml: "name: overview!\n"},
+	}
+	savedQueries := []humio.SavedQuery{
+		{ID: "3", Name: "Errors", QueryString: "loglevel = ?level", Start: "1h", End: "now", Arguments: map[string]string{"level": "ERROR"}},
+	}
+
+	data, status, err := constructHumioViewExportManifests(hve, dashboards, savedQueries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(status.Dashboards, []string{"web-overview", "web-overview-2"}) || !reflect.DeepEqual(status.SavedQueries, []string{"web-errors"}) {
+		t.Errorf("unexpected exported resources %+v", status)
+	}
+
+	var hd humiov1alpha1.HumioDashboard
+	if err := yaml.UnmarshalStrict([]byte(data["humiodashboard-web-overview.yaml"]), &hd); err != nil {
+		t.Fatal(err)
+	}
+	if hd.Kind != "HumioDashboard" || hd.Spec.ManagedClusterName != "humio" || hd.Spec.ViewName != "Web" || hd.Spec.Name != "Overview" {
+		t.Errorf("unexpected dashboard manifest %+v", hd)
+	}
+	definition, err := renderHumioDashboardTemplate(hd.Spec.Template, hd.Spec.Parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if definition != dashboards[0].TemplateYaml {
+		t.Errorf("expected exported template to render to the original template, got %q", definition)
+	}
+	if strings.Contains(data["humiodashboard-web-overview.yaml"], "status") || strings.Contains(data["humiodashboard-web-overview.yaml"], "creationTimestamp") {
+		t.Errorf("expected manifest without status, got %s", data["humiodashboard-web-overview.yaml"])
+	}
+
+	var hsq humiov1alpha1.HumioSavedQuery
+	if err := yaml.UnmarshalStrict([]byte(data["humiosavedquery-web-errors.yaml"]), &hsq); err != nil {
+		t.Fatal(err)
+	}
+	if hsq.Spec.QueryString != "loglevel = ?level" || hsq.Spec.Start != "1h" || hsq.Spec.Arguments["level"] != "ERROR" {
+		t.Errorf("unexpected saved query manifest %+v", hsq)
+	}
+}
+
+func TestWriteViewExport(t *testing.T) {
+	hve := &humiov1alpha1.HumioViewExport{
+		ObjectMeta: metav1.ObjectMeta{Name: "export", Namespace: "default", UID: "export-uid", Generation: 2},
+		Spec:       humiov1alpha1.HumioViewExportSpec{ManagedClusterName: "humio", ViewName: "web"},
+	}
+	taken := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "taken", Namespace: "default"}}
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	r := &HumioViewExportReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(hve, taken).WithStatusSubresource(hve).Build(),
+		Log:    logr.Discard(),
+	}
+	ctx := context.Background()
+
+	if err := r.writeViewExport(ctx, hve, []humio.Dashboard{{Name: "overview", TemplateYaml: "name: overview\n"}}, nil, metav1.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var configMap corev1.ConfigMap
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "export"}, &configMap); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := configMap.Data["humiodashboard-web-overview.yaml"]; !ok || !metav1.IsControlledBy(&configMap, hve) {
+		t.Errorf("expected owned ConfigMap holding the dashboard manifest, got %+v", configMap)
+	}
+	if hve.Status.State != humiov1alpha1.HumioViewExportStateSucceeded || hve.Status.ObservedGeneration != 2 || hve.Status.ConfigMapName != "export" {
+		t.Errorf("unexpected status %+v", hve.Status)
+	}
+
+	hve.Spec.ConfigMapName = "taken"
+	if err := r.writeViewExport(ctx, hve, nil, nil, metav1.Now()); err == nil {
+		t.Errorf("expected existing ConfigMap not owned by the export to be left alone")
+	}
+}
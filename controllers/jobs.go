@@ -0,0 +1,46 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// awsCLIImage is the default image of jobs which use the aws command line interface
+	awsCLIImage = "amazon/aws-cli:2.15.10"
+
+	// jobNameMaxLength is the maximum length of job names, as they are used as the value of the job-name label
+	jobNameMaxLength = 63
+)
+
+// newJobName returns the name of a job started at the given time on behalf of the resource with the given name. The
+// name of the resource is truncated to keep the job name within the limits of label values. Like GenerateName, a
+// random suffix keeps apart the names of jobs started within the same minute, while the name is known before the job
+// is created, so it can be used in the spec of the job.
+func newJobName(resourceName string, now metav1.Time) string {
+	suffix := fmt.Sprintf("-%s-%s", now.UTC().Format("0601021504"), kubernetes.RandomString())
+	prefix := resourceName
+	if len(prefix) > jobNameMaxLength-len(suffix) {
+		prefix = strings.TrimRight(prefix[:jobNameMaxLength-len(suffix)], "-.")
+	}
+	return prefix + suffix
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewJobName(t *testing.T) {
+	now := metav1.NewTime(time.Date(2024, time.January, 10, 2, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name         string
+		resourceName string
+		wantPrefix   string
+	}{
+		{"short name", "backup", "backup-2401100200-"},
+		{"long name", strings.Repeat("a", 60), strings.Repeat("a", 45) + "-2401100200-"},
+		{"truncated at separator", strings.Repeat("a", 44) + "-b", strings.Repeat("a", 44) + "-2401100200-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := newJobName(tt.resourceName, now)
+			if !strings.HasPrefix(name, tt.wantPrefix) || len(name) > jobNameMaxLength {
+				t.Errorf("expected a name of at most %d characters starting with %s, got %s", jobNameMaxLength, tt.wantPrefix, name)
+			}
+		})
+	}
+
+	if newJobName("backup", now) == newJobName("backup", now) {
+		t.Error("expected jobs started within the same minute to get different names")
+	}
+}
@@ -0,0 +1,317 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// NamespaceRepositoryAnnotation is the name of the Humio repository provisioned for the annotated namespace
+	NamespaceRepositoryAnnotation = "humio.com/repository"
+	// NamespaceParsersAnnotation is the name of a config map in the annotated namespace holding the parsers of the
+	// repository. Each key of the config map is the name of a parser, and the value is its parser script.
+	NamespaceParsersAnnotation = "humio.com/parsers"
+	// NamespaceIngestTokenParserAnnotation is the name of the parser assigned to the ingest token of the repository
+	NamespaceIngestTokenParserAnnotation = "humio.com/ingest-token-parser"
+	// NamespaceIngestTokenSecretAnnotation is the name of the secret the ingest token is stored in, in the annotated
+	// namespace. Defaults to "humio-ingest-token".
+	NamespaceIngestTokenSecretAnnotation = "humio.com/ingest-token-secret"
+
+	namespaceProvisioningLabel                  = "humio.com/provisioned-for-namespace"
+	namespaceProvisioningDefaultTokenSecretName = "humio-ingest-token"
+)
+
+// NamespaceProvisioningReconciler provisions a repository, parsers and an ingest token on a managed Humio cluster for
+// every namespace annotated with humio.com/repository, and stores the ingest token in a secret in the namespace.
+// The repository, parsers and ingest token are created as HumioRepository, HumioParser and HumioIngestToken resources
+// in the namespace of the cluster, so they are managed by the regular controllers. The parsers and the ingest token
+// are removed when the annotation or the namespace is removed, while the repository is kept so no data is deleted.
+type NamespaceProvisioningReconciler struct {
+	client.Client
+	BaseLogger       logr.Logger
+	Log              logr.Logger
+	ClusterNamespace string
+	ClusterName      string
+}
+
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humiorepositories,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioparsers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.humio.com,resources=humioingesttokens,verbs=get;list;watch;create;update;patch;delete
+
+func (r *NamespaceProvisioningReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	r.Log = r.BaseLogger.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "Request.Type", helpers.GetTypeName(r), "Reconcile.ID", kubernetes.RandomString())
+	r.Log.Info("Reconciling Namespace")
+
+	var namespace corev1.Namespace
+	if err := r.Get(ctx, req.NamespacedName, &namespace); err != nil {
+		if k8serrors.IsNotFound(err) {
+			// The provisioned parsers and ingest token are owned by the namespace, and are garbage collected
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	repositoryName := namespace.Annotations[NamespaceRepositoryAnnotation]
+	if repositoryName == "" || namespace.DeletionTimestamp != nil {
+		return reconcile.Result{}, r.deprovision(ctx, namespace.Name)
+	}
+
+	repository := &humiov1alpha1.HumioRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: namespaceProvisioningResourceName(namespace.Name), Namespace: r.ClusterNamespace},
+	}
+	if err := r.ensureProvisioned(ctx, namespace.Name, repository, nil, func() {
+		repository.Spec.ManagedClusterName = r.ClusterName
+		repository.Spec.Name = repositoryName
+		repository.Spec.Description = fmt.Sprintf("Logs of namespace %s", namespace.Name)
+	}); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to provision repository")
+	}
+
+	parsers, err := r.namespaceParsers(ctx, &namespace)
+	if err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to get parsers")
+	}
+	parserResourceNames := map[string]bool{}
+	for parserName, parserScript := range parsers {
+		parserName, parserScript := parserName, parserScript
+		parser := &humiov1alpha1.HumioParser{
+			ObjectMeta: metav1.ObjectMeta{Name: namespaceProvisioningParserResourceName(namespace.Name, parserName), Namespace: r.ClusterNamespace},
+		}
+		parserResourceNames[parser.Name] = true
+		if err := r.ensureProvisioned(ctx, namespace.Name, parser, &namespace, func() {
+			parser.Spec.ManagedClusterName = r.ClusterName
+			parser.Spec.Name = parserName
+			parser.Spec.RepositoryName = repositoryName
+			parser.Spec.ParserScript = parserScript
+		}); err != nil {
+			return reconcile.Result{}, r.logErrorAndReturn(err, fmt.Sprintf("unable to provision parser %s", parserName))
+		}
+	}
+	var existingParsers humiov1alpha1.HumioParserList
+	if err := r.List(ctx, &existingParsers, client.InNamespace(r.ClusterNamespace), client.MatchingLabels{namespaceProvisioningLabel: namespace.Name}); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to list parsers")
+	}
+	for i := range existingParsers.Items {
+		if !parserResourceNames[existingParsers.Items[i].Name] {
+			r.Log.Info(fmt.Sprintf("deleting parser %s which was removed from namespace %s", existingParsers.Items[i].Spec.Name, namespace.Name))
+			if err := r.Delete(ctx, &existingParsers.Items[i]); err != nil && !k8serrors.IsNotFound(err) {
+				return reconcile.Result{}, r.logErrorAndReturn(err, "unable to delete parser")
+			}
+		}
+	}
+
+	ingestToken := &humiov1alpha1.HumioIngestToken{
+		ObjectMeta: metav1.ObjectMeta{Name: namespaceProvisioningResourceName(namespace.Name), Namespace: r.ClusterNamespace},
+	}
+	if err := r.ensureProvisioned(ctx, namespace.Name, ingestToken, &namespace, func() {
+		ingestToken.Spec.ManagedClusterName = r.ClusterName
+		ingestToken.Spec.Name = namespace.Name
+		ingestToken.Spec.RepositoryName = repositoryName
+		ingestToken.Spec.ParserName = namespace.Annotations[NamespaceIngestTokenParserAnnotation]
+		ingestToken.Spec.TokenSecretName = namespaceProvisioningTokenSecretName(namespace.Name)
+		ingestToken.Spec.TokenSecretLabels = map[string]string{namespaceProvisioningLabel: namespace.Name}
+	}); err != nil {
+		return reconcile.Result{}, r.logErrorAndReturn(err, "unable to provision ingest token")
+	}
+
+	return reconcile.Result{}, r.ensureIngestTokenSecretCopied(ctx, &namespace)
+}
+
+// ensureProvisioned creates or updates the given resource provisioned for the namespace. Resources with
+// an owner are garbage collected when the owner is deleted.
+func (r *NamespaceProvisioningReconciler) ensureProvisioned(ctx context.Context, namespaceName string, obj client.Object, owner *corev1.Namespace, setSpec func()) error {
+	result, err := controllerutil.CreateOrUpdate(ctx, r, obj, func() error {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[namespaceProvisioningLabel] = namespaceName
+		labels["app.kubernetes.io/managed-by"] = "humio-operator"
+		obj.SetLabels(labels)
+		setSpec()
+		if owner == nil {
+			return nil
+		}
+		return controllerutil.SetOwnerReference(owner, obj, r.Scheme())
+	})
+	if err != nil {
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		r.Log.Info(fmt.Sprintf("%s %T %s for namespace %s", result, obj, obj.GetName(), namespaceName))
+	}
+	return nil
+}
+
+// namespaceParsers returns the parser scripts of the namespace by parser name
+func (r *NamespaceProvisioningReconciler) namespaceParsers(ctx context.Context, namespace *corev1.Namespace) (map[string]string, error) {
+	configMapName := namespace.Annotations[NamespaceParsersAnnotation]
+	if configMapName == "" {
+		return nil, nil
+	}
+	var configMap corev1.ConfigMap
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace.Name, Name: configMapName}, &configMap); err != nil {
+		return nil, err
+	}
+	return configMap.Data, nil
+}
+
+// ensureIngestTokenSecretCopied copies the ingest token from the secret created by the HumioIngestToken controller in
+// the namespace of the cluster, to the secret in the provisioned namespace
+func (r *NamespaceProvisioningReconciler) ensureIngestTokenSecretCopied(ctx context.Context, namespace *corev1.Namespace) error {
+	var tokenSecret corev1.Secret
+	err := r.Get(ctx, types.NamespacedName{Namespace: r.ClusterNamespace, Name: namespaceProvisioningTokenSecretName(namespace.Name)}, &tokenSecret)
+	if k8serrors.IsNotFound(err) {
+		// The secret is watched, so the namespace is reconciled again once the ingest token has been created
+		r.Log.Info(fmt.Sprintf("waiting for ingest token of namespace %s to be created", namespace.Name))
+		return nil
+	}
+	if err != nil {
+		return r.logErrorAndReturn(err, "unable to get ingest token secret")
+	}
+
+	secretName := namespace.Annotations[NamespaceIngestTokenSecretAnnotation]
+	if secretName == "" {
+		secretName = namespaceProvisioningDefaultTokenSecretName
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: namespace.Name},
+	}
+	var existingSecrets corev1.SecretList
+	if err := r.List(ctx, &existingSecrets, client.InNamespace(namespace.Name), client.MatchingLabels{namespaceProvisioningLabel: namespace.Name}); err != nil {
+		return r.logErrorAndReturn(err, "unable to list ingest token secrets")
+	}
+	for i := range existingSecrets.Items {
+		if existingSecrets.Items[i].Name != secretName {
+			if err := r.Delete(ctx, &existingSecrets.Items[i]); err != nil && !k8serrors.IsNotFound(err) {
+				return r.logErrorAndReturn(err, "unable to delete renamed ingest token secret")
+			}
+		}
+	}
+	if err := r.ensureProvisioned(ctx, namespace.Name, secret, nil, func() {
+		secret.Data = map[string][]byte{"token": tokenSecret.Data["token"]}
+	}); err != nil {
+		return r.logErrorAndReturn(err, "unable to store ingest token secret")
+	}
+	return nil
+}
+
+// deprovision removes the parsers, ingest token and ingest token secret provisioned for the namespace. The repository
+// is kept, as deleting it would delete its data.
+func (r *NamespaceProvisioningReconciler) deprovision(ctx context.Context, namespaceName string) error {
+	var parsers humiov1alpha1.HumioParserList
+	if err := r.List(ctx, &parsers, client.InNamespace(r.ClusterNamespace), client.MatchingLabels{namespaceProvisioningLabel: namespaceName}); err != nil {
+		return r.logErrorAndReturn(err, "unable to list parsers")
+	}
+	var ingestTokens humiov1alpha1.HumioIngestTokenList
+	if err := r.List(ctx, &ingestTokens, client.InNamespace(r.ClusterNamespace), client.MatchingLabels{namespaceProvisioningLabel: namespaceName}); err != nil {
+		return r.logErrorAndReturn(err, "unable to list ingest tokens")
+	}
+	var secrets corev1.SecretList
+	if err := r.List(ctx, &secrets, client.InNamespace(namespaceName), client.MatchingLabels{namespaceProvisioningLabel: namespaceName}); err != nil {
+		return r.logErrorAndReturn(err, "unable to list ingest token secrets")
+	}
+
+	var objs []client.Object
+	for i := range parsers.Items {
+		objs = append(objs, &parsers.Items[i])
+	}
+	for i := range ingestTokens.Items {
+		objs = append(objs, &ingestTokens.Items[i])
+	}
+	for i := range secrets.Items {
+		objs = append(objs, &secrets.Items[i])
+	}
+	for _, obj := range objs {
+		r.Log.Info(fmt.Sprintf("deleting %T %s provisioned for namespace %s", obj, obj.GetName(), namespaceName))
+		if err := r.Delete(ctx, obj); err != nil && !k8serrors.IsNotFound(err) {
+			return r.logErrorAndReturn(err, fmt.Sprintf("unable to delete %s", obj.GetName()))
+		}
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NamespaceProvisioningReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Namespace{}).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.namespaceForParsersConfigMap)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(namespaceForIngestTokenSecret)).
+		Complete(r)
+}
+
+// namespaceForParsersConfigMap returns a reconcile request for the namespace of the given config map if it holds the
+// parsers of the namespace, so changed parsers are provisioned right away
+func (r *NamespaceProvisioningReconciler) namespaceForParsersConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	var namespace corev1.Namespace
+	if err := r.Get(ctx, types.NamespacedName{Name: configMap.GetNamespace()}, &namespace); err != nil {
+		return nil
+	}
+	if namespace.Annotations[NamespaceParsersAnnotation] != configMap.GetName() {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: namespace.Name}}}
+}
+
+// namespaceForIngestTokenSecret returns a reconcile request for the namespace the given ingest token secret is
+// provisioned for, so the ingest token is copied once it has been created, and restored if it is changed
+func namespaceForIngestTokenSecret(_ context.Context, secret client.Object) []reconcile.Request {
+	namespaceName, ok := secret.GetLabels()[namespaceProvisioningLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: namespaceName}}}
+}
+
+func namespaceProvisioningResourceName(namespaceName string) string {
+	return fmt.Sprintf("namespace-%s", namespaceName)
+}
+
+// namespaceProvisioningParserResourceName returns the name of the HumioParser resource of the given parser. Parser
+// names may contain characters which are not allowed in resource names.
+func namespaceProvisioningParserResourceName(namespaceName, parserName string) string {
+	return fmt.Sprintf("namespace-%s-%s", namespaceName, strings.ToLower(strings.ReplaceAll(parserName, "_", "-")))
+}
+
+func namespaceProvisioningTokenSecretName(namespaceName string) string {
+	return fmt.Sprintf("namespace-%s-ingest-token", namespaceName)
+}
+
+func (r *NamespaceProvisioningReconciler) logErrorAndReturn(err error, msg string) error {
+	r.Log.Error(err, msg)
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestNamespaceProvisioning(t *testing.T) {
+	ctx := context.Background()
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "checkout",
+			Annotations: map[string]string{
+				NamespaceRepositoryAnnotation:        "checkout-logs",
+				NamespaceParsersAnnotation:           "checkout-parsers",
+				NamespaceIngestTokenParserAnnotation: "access_log",
+			},
+		},
+	}
+	parsers := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout-parsers", Namespace: "checkout"},
+		Data: map[string]string{
+			"access_log": "parseJson()",
+			"audit":      "kvParse()",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+	r := &NamespaceProvisioningReconciler{
+		Client:           fake.NewClientBuilder().WithScheme(scheme).WithObjects([]client.Object{namespace, parsers}...).Build(),
+		BaseLogger:       logr.Discard(),
+		ClusterNamespace: "logging",
+		ClusterName:      "humio",
+	}
+	reconcileNamespace := func() {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: namespace.Name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exists := func(obj client.Object, namespaceName, name string) bool {
+		err := r.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: name}, obj)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+
+	reconcileNamespace()
+	var repository humiov1alpha1.HumioRepository
+	if !exists(&repository, "logging", "namespace-checkout") || repository.Spec.Name != "checkout-logs" || repository.Spec.ManagedClusterName != "humio" {
+		t.Errorf("expected repository checkout-logs to be provisioned, got %+v", repository.Spec)
+	}
+	var parser humiov1alpha1.HumioParser
+	if !exists(&parser, "logging", "namespace-checkout-access-log") || parser.Spec.Name != "access_log" || parser.Spec.ParserScript != "parseJson()" {
+		t.Errorf("expected parser access_log to be provisioned, got %+v", parser.Spec)
+	}
+	var ingestToken humiov1alpha1.HumioIngestToken
+	if !exists(&ingestToken, "logging", "namespace-checkout") || ingestToken.Spec.RepositoryName != "checkout-logs" || ingestToken.Spec.ParserName != "access_log" {
+		t.Errorf("expected ingest token to be provisioned, got %+v", ingestToken.Spec)
+	}
+	if len(ingestToken.OwnerReferences) != 1 || ingestToken.OwnerReferences[0].Name != namespace.Name {
+		t.Errorf("expected ingest token to be owned by the namespace, got %+v", ingestToken.OwnerReferences)
+	}
+	var secret corev1.Secret
+	if exists(&secret, "checkout", namespaceProvisioningDefaultTokenSecretName) {
+		t.Errorf("expected no ingest token secret before the ingest token has been created")
+	}
+
+	// The HumioIngestToken controller stores the ingest token in the namespace of the cluster
+	if err := r.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: ingestToken.Spec.TokenSecretName, Namespace: "logging", Labels: ingestToken.Spec.TokenSecretLabels},
+		Data:       map[string][]byte{"token": []byte("ingest-token")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reconcileNamespace()
+	if !exists(&secret, "checkout", namespaceProvisioningDefaultTokenSecretName) || string(secret.Data["token"]) != "ingest-token" {
+		t.Errorf("expected ingest token to be copied to the namespace, got %v", secret.Data)
+	}
+
+	delete(parsers.Data, "audit")
+	if err := r.Update(ctx, parsers); err != nil {
+		t.Fatal(err)
+	}
+	reconcileNamespace()
+	if exists(&parser, "logging", "namespace-checkout-audit") {
+		t.Errorf("expected parser audit to be deleted after being removed from the config map")
+	}
+
+	namespace.Annotations = nil
+	if err := r.Update(ctx, namespace); err != nil {
+		t.Fatal(err)
+	}
+	reconcileNamespace()
+	if exists(&parser, "logging", "namespace-checkout-access-log") || exists(&ingestToken, "logging", "namespace-checkout") ||
+		exists(&secret, "checkout", namespaceProvisioningDefaultTokenSecretName) {
+		t.Errorf("expected parsers, ingest token and ingest token secret to be deleted after the annotation was removed")
+	}
+	if !exists(&repository, "logging", "namespace-checkout") {
+		t.Errorf("expected repository to be kept after the annotation was removed")
+	}
+}
+
+func TestNamespaceForIngestTokenSecret(t *testing.T) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "token", Labels: map[string]string{namespaceProvisioningLabel: "checkout"}}}
+	if requests := namespaceForIngestTokenSecret(context.Background(), secret); len(requests) != 1 || requests[0].Name != "checkout" {
+		t.Errorf("expected request for namespace checkout, got %v", requests)
+	}
+	if requests := namespaceForIngestTokenSecret(context.Background(), &corev1.Secret{}); len(requests) != 0 {
+		t.Errorf("expected no requests for unrelated secret, got %v", requests)
+	}
+}
@@ -0,0 +1,412 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// entityLedgerName is the name of the ConfigMap in each namespace which records the entities the operator synced
+	// to Humio for the resources in the namespace
+	entityLedgerName = "humio-operator-entity-ledger"
+	entityLedgerKey  = "entities.json"
+	// entityLedgerLabel marks the ledger ConfigMaps, so they can be listed across namespaces
+	entityLedgerLabel        = "humio.com/entity-ledger"
+	orphanedEntityGCInterval = time.Hour
+)
+
+// ledgerEntry is an entity inside Humio the operator synced for a resource
+type ledgerEntry struct {
+	Kind                string `json:"kind"`
+	ManagedClusterName  string `json:"managedClusterName,omitempty"`
+	ExternalClusterName string `json:"externalClusterName,omitempty"`
+	ViewName            string `json:"viewName,omitempty"`
+	Name                string `json:"name"`
+	// Resource is the name of the resource the entity was synced for
+	Resource string `json:"resource"`
+	// AllowDataDeletion is set for repositories whose resource allowed deleting data
+	AllowDataDeletion bool `json:"allowDataDeletion,omitempty"`
+}
+
+// entity identifies the entity inside Humio, regardless of which resource it was synced for
+func (e ledgerEntry) entity() string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", e.Kind, e.ManagedClusterName, e.ExternalClusterName, e.ViewName, e.Name)
+}
+
+func (e ledgerEntry) String() string {
+	if e.ViewName != "" {
+		return fmt.Sprintf("%s %q in %q", e.Kind, e.Name, e.ViewName)
+	}
+	return fmt.Sprintf("%s %q", e.Kind, e.Name)
+}
+
+// OrphanedEntityCollector finds alerts, parsers and repositories the operator created inside Humio whose resource no
+// longer exists, e.g. because the resource was renamed, force-deleted or had its finalizer removed by hand, and reports
+// or deletes them depending on Mode. The entities synced for each namespace are recorded in a ledger ConfigMap, so
+// only entities the operator created are ever touched. Repositories are only deleted if their resource allowed data
+// deletion. Resources are listed in the namespaces watched by the manager.
+type OrphanedEntityCollector struct {
+	Client      client.Client
+	HumioClient humio.Client
+	Log         logr.Logger
+	Recorder    record.EventRecorder
+	Mode        string
+}
+
+// Start implements manager.Runnable
+func (o *OrphanedEntityCollector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(orphanedEntityGCInterval)
+	defer ticker.Stop()
+	for {
+		if err := o.collect(ctx); err != nil {
+			o.Log.Error(err, "unable to collect orphaned entities")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (o *OrphanedEntityCollector) NeedLeaderElection() bool {
+	return true
+}
+
+func (o *OrphanedEntityCollector) collect(ctx context.Context) error {
+	referenced, err := o.referencedEntities(ctx)
+	if err != nil {
+		return err
+	}
+	var ledgers corev1.ConfigMapList
+	if err := o.Client.List(ctx, &ledgers, client.MatchingLabels{entityLedgerLabel: "true"}); err != nil {
+		return fmt.Errorf("unable to list entity ledgers: %w", err)
+	}
+	recorded := map[string][]ledgerEntry{}
+	for _, ledger := range ledgers.Items {
+		if ledger.Name != entityLedgerName {
+			continue
+		}
+		var entries []ledgerEntry
+		if err := json.Unmarshal([]byte(ledger.Data[entityLedgerKey]), &entries); err != nil {
+			o.Log.Error(err, fmt.Sprintf("ignoring invalid entity ledger in namespace %s", ledger.Namespace))
+			continue
+		}
+		recorded[ledger.Namespace] = entries
+	}
+
+	namespaces := map[string]bool{}
+	for namespace := range referenced {
+		namespaces[namespace] = true
+	}
+	for namespace := range recorded {
+		namespaces[namespace] = true
+	}
+	references := o.clusterReferences(ctx, referenced)
+	for namespace := range namespaces {
+		kept := append([]ledgerEntry{}, referenced[namespace]...)
+		for _, entry := range orphanedEntries(recorded[namespace], referenced[namespace]) {
+			if o.collectOrphan(ctx, namespace, entry, references) {
+				kept = append(kept, entry)
+			}
+		}
+		if err := o.writeLedger(ctx, namespace, kept); err != nil {
+			o.Log.Error(err, fmt.Sprintf("unable to write entity ledger in namespace %s", namespace))
+		}
+	}
+	return nil
+}
+
+// referencedEntities returns the entities which were synced to Humio for the resources which currently exist, by
+// namespace
+func (o *OrphanedEntityCollector) referencedEntities(ctx context.Context) (map[string][]ledgerEntry, error) {
+	referenced := map[string][]ledgerEntry{}
+	var alerts humiov1alpha1.HumioAlertList
+	if err := o.Client.List(ctx, &alerts); err != nil {
+		return nil, fmt.Errorf("unable to list alerts: %w", err)
+	}
+	for _, ha := range alerts.Items {
+		if ha.Status.ID == "" {
+			continue
+		}
+		referenced[ha.Namespace] = append(referenced[ha.Namespace], ledgerEntry{
+			Kind: "HumioAlert", ManagedClusterName: ha.Spec.ManagedClusterName, ExternalClusterName: ha.Spec.ExternalClusterName,
+			ViewName: ha.Spec.ViewName, Name: ha.Spec.Name, Resource: ha.Name,
+		})
+	}
+	var parsers humiov1alpha1.HumioParserList
+	if err := o.Client.List(ctx, &parsers); err != nil {
+		return nil, fmt.Errorf("unable to list parsers: %w", err)
+	}
+	for _, hp := range parsers.Items {
+		if hp.Status.ID == "" {
+			continue
+		}
+		referenced[hp.Namespace] = append(referenced[hp.Namespace], ledgerEntry{
+			Kind: "HumioParser", ManagedClusterName: hp.Spec.ManagedClusterName, ExternalClusterName: hp.Spec.ExternalClusterName,
+			ViewName: hp.Spec.RepositoryName, Name: hp.Spec.Name, Resource: hp.Name,
+		})
+	}
+	var repositories humiov1alpha1.HumioRepositoryList
+	if err := o.Client.List(ctx, &repositories); err != nil {
+		return nil, fmt.Errorf("unable to list repositories: %w", err)
+	}
+	for _, hr := range repositories.Items {
+		if hr.Status.ID == "" {
+			continue
+		}
+		referenced[hr.Namespace] = append(referenced[hr.Namespace], ledgerEntry{
+			Kind: "HumioRepository", ManagedClusterName: hr.Spec.ManagedClusterName, ExternalClusterName: hr.Spec.ExternalClusterName,
+			Name: hr.Spec.Name, Resource: hr.Name, AllowDataDeletion: hr.Spec.AllowDataDeletion,
+		})
+	}
+	return referenced, nil
+}
+
+// clusterReferences holds the entities referenced by the resources in all namespaces, by the URL of the Humio cluster
+// they were synced to. Resources in different namespaces may manage entities on the same Humio cluster, so an entity
+// is only orphaned if no resource in any namespace references it.
+type clusterReferences struct {
+	// resources maps the entities to the resource referencing them
+	resources map[string]string
+	// complete is false if the URL of the Humio cluster of some resources could not be resolved
+	complete bool
+}
+
+func clusterEntityKey(config *humioapi.Config, entry ledgerEntry) string {
+	address := ""
+	if config.Address != nil {
+		address = config.Address.String()
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", address, entry.Kind, entry.ViewName, entry.Name)
+}
+
+// referencedBy returns the resource referencing the given entity on the Humio cluster of the given config, if any
+func (c clusterReferences) referencedBy(config *humioapi.Config, entry ledgerEntry) (string, bool) {
+	resource, found := c.resources[clusterEntityKey(config, entry)]
+	return resource, found
+}
+
+// clusterReferences resolves the Humio cluster of every referenced entity
+func (o *OrphanedEntityCollector) clusterReferences(ctx context.Context, referenced map[string][]ledgerEntry) clusterReferences {
+	references := clusterReferences{resources: map[string]string{}, complete: true}
+	configs := map[string]*humioapi.Config{}
+	for namespace, entries := range referenced {
+		for _, entry := range entries {
+			clusterKey := fmt.Sprintf("%s/%s/%s", namespace, entry.ManagedClusterName, entry.ExternalClusterName)
+			config, resolved := configs[clusterKey]
+			if !resolved {
+				cluster, err := helpers.NewCluster(ctx, o.Client, entry.ManagedClusterName, entry.ExternalClusterName, namespace, helpers.UseCertManager(), false)
+				if err == nil && cluster != nil {
+					config = cluster.Config()
+				}
+				// Resources whose cluster no longer exists cannot manage any entities
+				if config == nil && !k8serrors.IsNotFound(err) {
+					o.Log.Error(err, fmt.Sprintf("unable to resolve the cluster of resources in namespace %s", namespace))
+					references.complete = false
+				}
+				configs[clusterKey] = config
+			}
+			if config != nil {
+				references.resources[clusterEntityKey(config, entry)] = fmt.Sprintf("%s/%s", namespace, entry.Resource)
+			}
+		}
+	}
+	return references
+}
+
+// orphanedEntries returns the recorded entities which no longer belong to any resource
+func orphanedEntries(recorded, referenced []ledgerEntry) []ledgerEntry {
+	current := map[string]bool{}
+	for _, entry := range referenced {
+		current[entry.entity()] = true
+	}
+	var orphaned []ledgerEntry
+	for _, entry := range recorded {
+		if !current[entry.entity()] {
+			orphaned = append(orphaned, entry)
+			current[entry.entity()] = true
+		}
+	}
+	return orphaned
+}
+
+// collectOrphan reports or deletes the given orphaned entity, and returns whether it must be kept in the ledger
+func (o *OrphanedEntityCollector) collectOrphan(ctx context.Context, namespace string, entry ledgerEntry, references clusterReferences) bool {
+	var clusterObj client.Object = &humiov1alpha1.HumioCluster{}
+	clusterName := entry.ManagedClusterName
+	if entry.ExternalClusterName != "" {
+		clusterObj = &humiov1alpha1.HumioExternalCluster{}
+		clusterName = entry.ExternalClusterName
+	}
+	err := o.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: clusterName}, clusterObj)
+	if k8serrors.IsNotFound(err) {
+		o.Log.Info(fmt.Sprintf("forgetting %s of resource %s/%s as cluster %s no longer exists", entry, namespace, entry.Resource, clusterName))
+		return false
+	}
+	if err != nil {
+		o.Log.Error(err, fmt.Sprintf("unable to get cluster %s", clusterName))
+		return true
+	}
+	cluster, err := helpers.NewCluster(ctx, o.Client, entry.ManagedClusterName, entry.ExternalClusterName, namespace, helpers.UseCertManager(), true)
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		o.Log.Error(err, fmt.Sprintf("unable to obtain humio client config for cluster %s", clusterName))
+		return true
+	}
+
+	keep, message, err := o.handleOrphan(cluster.Config(), namespace, entry, references)
+	switch {
+	case err != nil:
+		o.Log.Error(err, fmt.Sprintf("unable to collect orphaned %s of resource %s/%s", entry, namespace, entry.Resource))
+	case message != "" && keep:
+		o.Log.Info(message)
+		if o.Recorder != nil {
+			o.Recorder.Event(clusterObj, corev1.EventTypeWarning, "OrphanedEntity", message)
+		}
+	case message != "":
+		o.Log.Info(message)
+		if o.Recorder != nil {
+			o.Recorder.Event(clusterObj, corev1.EventTypeNormal, "OrphanedEntityDeleted", message)
+		}
+	}
+	return keep
+}
+
+// handleOrphan reports or deletes the given orphaned entity inside Humio. It returns whether the entity must be kept in
+// the ledger, and a message describing what was done, which is empty if the entity no longer exists.
+func (o *OrphanedEntityCollector) handleOrphan(config *humioapi.Config, namespace string, entry ledgerEntry, references clusterReferences) (bool, string, error) {
+	// The entity is managed by a resource in another namespace, which records it in its own ledger
+	if resource, found := references.referencedBy(config, entry); found {
+		o.Log.Info(fmt.Sprintf("forgetting %s of resource %s/%s as it is referenced by resource %s", entry, namespace, entry.Resource, resource))
+		return false, "", nil
+	}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: entry.Resource}}
+	exists, err := o.entityExists(config, req, entry)
+	if err != nil {
+		return true, "", err
+	}
+	if !exists {
+		return false, "", nil
+	}
+	if o.Mode != helpers.OrphanedEntityGCModeDelete {
+		return true, fmt.Sprintf("%s was created for resource %s/%s, which no longer exists", entry, namespace, entry.Resource), nil
+	}
+	if !references.complete {
+		return true, "", fmt.Errorf("not deleting %s as it is unknown whether resources in other namespaces reference it", entry)
+	}
+	if entry.Kind == "HumioRepository" && !entry.AllowDataDeletion {
+		return true, fmt.Sprintf("%s was created for resource %s/%s, which no longer exists, and is not deleted as the resource did not allow data deletion", entry, namespace, entry.Resource), nil
+	}
+	if err := o.deleteEntity(config, req, entry); err != nil {
+		return true, "", err
+	}
+	return false, fmt.Sprintf("deleted %s as resource %s/%s it was created for no longer exists", entry, namespace, entry.Resource), nil
+}
+
+func (o *OrphanedEntityCollector) entityExists(config *humioapi.Config, req reconcile.Request, entry ledgerEntry) (bool, error) {
+	switch entry.Kind {
+	case "HumioAlert":
+		alert, err := o.HumioClient.GetAlert(config, req, &humiov1alpha1.HumioAlert{Spec: humiov1alpha1.HumioAlertSpec{Name: entry.Name, ViewName: entry.ViewName}})
+		if errors.As(err, &humioapi.EntityNotFound{}) {
+			return false, nil
+		}
+		return alert != nil && alert.Name != "", err
+	case "HumioParser":
+		_, err := o.HumioClient.GetParser(config, req, &humiov1alpha1.HumioParser{Spec: humiov1alpha1.HumioParserSpec{Name: entry.Name, RepositoryName: entry.ViewName}})
+		if errors.As(err, &humioapi.EntityNotFound{}) {
+			return false, nil
+		}
+		return err == nil, err
+	case "HumioRepository":
+		repository, err := o.HumioClient.GetRepository(config, req, &humiov1alpha1.HumioRepository{Spec: humiov1alpha1.HumioRepositorySpec{Name: entry.Name}})
+		if err != nil {
+			return false, err
+		}
+		return repository != nil && repository.Name != "", nil
+	}
+	return false, fmt.Errorf("unknown entity kind %s", entry.Kind)
+}
+
+func (o *OrphanedEntityCollector) deleteEntity(config *humioapi.Config, req reconcile.Request, entry ledgerEntry) error {
+	switch entry.Kind {
+	case "HumioAlert":
+		return o.HumioClient.DeleteAlert(config, req, &humiov1alpha1.HumioAlert{Spec: humiov1alpha1.HumioAlertSpec{Name: entry.Name, ViewName: entry.ViewName}})
+	case "HumioParser":
+		return o.HumioClient.DeleteParser(config, req, &humiov1alpha1.HumioParser{Spec: humiov1alpha1.HumioParserSpec{Name: entry.Name, RepositoryName: entry.ViewName}})
+	case "HumioRepository":
+		return o.HumioClient.DeleteRepository(config, req, &humiov1alpha1.HumioRepository{Spec: humiov1alpha1.HumioRepositorySpec{Name: entry.Name, AllowDataDeletion: entry.AllowDataDeletion}})
+	}
+	return fmt.Errorf("unknown entity kind %s", entry.Kind)
+}
+
+// writeLedger records the given entries in the ledger of the namespace, removing the ledger when there are none
+func (o *OrphanedEntityCollector) writeLedger(ctx context.Context, namespace string, entries []ledgerEntry) error {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].entity()+entries[i].Resource < entries[j].entity()+entries[j].Resource
+	})
+	var ledger corev1.ConfigMap
+	err := o.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: entityLedgerName}, &ledger)
+	if k8serrors.IsNotFound(err) {
+		if len(entries) == 0 {
+			return nil
+		}
+		data, _ := json.Marshal(entries)
+		return o.Client.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      entityLedgerName,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "humio-operator", entityLedgerLabel: "true"},
+			},
+			Data: map[string]string{entityLedgerKey: string(data)},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return o.Client.Delete(ctx, &ledger)
+	}
+	data, _ := json.Marshal(entries)
+	if ledger.Data[entityLedgerKey] == string(data) {
+		return nil
+	}
+	if ledger.Data == nil {
+		ledger.Data = map[string]string{}
+	}
+	ledger.Data[entityLedgerKey] = string(data)
+	return o.Client.Update(ctx, &ledger)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestOrphanedEntries(t *testing.T) {
+	alert := ledgerEntry{Kind: "HumioAlert", ManagedClusterName: "humio", ViewName: "web", Name: "errors", Resource: "errors"}
+	renamed := ledgerEntry{Kind: "HumioAlert", ManagedClusterName: "humio", ViewName: "web", Name: "errors-v2", Resource: "errors"}
+	movedResource := ledgerEntry{Kind: "HumioAlert", ManagedClusterName: "humio", ViewName: "web", Name: "errors", Resource: "web-errors"}
+	repository := ledgerEntry{Kind: "HumioRepository", ManagedClusterName: "humio", Name: "web", Resource: "web"}
+
+	tests := []struct {
+		name       string
+		recorded   []ledgerEntry
+		referenced []ledgerEntry
+		want       []ledgerEntry
+	}{
+		{"nothing recorded", nil, []ledgerEntry{alert}, nil},
+		{"unchanged", []ledgerEntry{alert, repository}, []ledgerEntry{alert, repository}, nil},
+		{"resource deleted", []ledgerEntry{alert, repository}, []ledgerEntry{alert}, []ledgerEntry{repository}},
+		{"entity renamed", []ledgerEntry{alert}, []ledgerEntry{renamed}, []ledgerEntry{alert}},
+		{"entity taken over by another resource", []ledgerEntry{alert}, []ledgerEntry{movedResource}, nil},
+		{"recorded twice", []ledgerEntry{alert, movedResource}, nil, []ledgerEntry{alert}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orphanedEntries(tt.recorded, tt.referenced); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orphanedEntries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleOrphan(t *testing.T) {
+	config := &humioapi.Config{}
+	parser := ledgerEntry{Kind: "HumioParser", ManagedClusterName: "humio", ViewName: "web", Name: "accesslog", Resource: "accesslog"}
+	repository := ledgerEntry{Kind: "HumioRepository", ManagedClusterName: "humio", Name: "web", Resource: "web"}
+
+	tests := []struct {
+		name       string
+		mode       string
+		entry      ledgerEntry
+		wantKeep   bool
+		wantExists bool
+	}{
+		{"report", helpers.OrphanedEntityGCModeReport, parser, true, true},
+		{"delete", helpers.OrphanedEntityGCModeDelete, parser, false, false},
+		{"delete repository without data deletion", helpers.OrphanedEntityGCModeDelete, repository, true, true},
+	}
+	complete := clusterReferences{resources: map[string]string{}, complete: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+			req := reconcile.Request{}
+			_, _ = humioClient.AddParser(config, req, &humiov1alpha1.HumioParser{Spec: humiov1alpha1.HumioParserSpec{Name: parser.Name}})
+			_, _ = humioClient.AddRepository(config, req, &humiov1alpha1.HumioRepository{Spec: humiov1alpha1.HumioRepositorySpec{Name: repository.Name}})
+			o := &OrphanedEntityCollector{HumioClient: humioClient, Log: logr.Discard(), Mode: tt.mode}
+
+			keep, message, err := o.handleOrphan(config, "default", tt.entry, complete)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if keep != tt.wantKeep || message == "" {
+				t.Errorf("handleOrphan() = %t, %q, want %t and a message", keep, message, tt.wantKeep)
+			}
+			exists, err := o.entityExists(config, req, tt.entry)
+			if err != nil || exists != tt.wantExists {
+				t.Errorf("expected entity to exist: %t, got %t and %v", tt.wantExists, exists, err)
+			}
+
+			// Entities which were already deleted are forgotten
+			_ = o.deleteEntity(config, req, tt.entry)
+			if keep, message, err := o.handleOrphan(config, "default", tt.entry, complete); keep || message != "" || err != nil {
+				t.Errorf("expected deleted entity to be forgotten, got %t, %q and %v", keep, message, err)
+			}
+		})
+	}
+}
+
+func TestHandleOrphanReferencedElsewhere(t *testing.T) {
+	address, _ := url.Parse("https://humio.example.com/")
+	config := &humioapi.Config{Address: address}
+	parser := ledgerEntry{Kind: "HumioParser", ManagedClusterName: "humio", ViewName: "web", Name: "accesslog", Resource: "accesslog"}
+	req := reconcile.Request{}
+
+	tests := []struct {
+		name       string
+		references clusterReferences
+		wantKeep   bool
+		wantErr    bool
+	}{
+		{
+			name: "referenced by resource in another namespace",
+			references: clusterReferences{complete: true, resources: map[string]string{
+				clusterEntityKey(config, parser): "other/accesslog",
+			}},
+		},
+		{
+			name:       "references incomplete",
+			references: clusterReferences{resources: map[string]string{}},
+			wantKeep:   true,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			humioClient := humio.NewMockClient(humioapi.Cluster{}, nil, nil, nil)
+			_, _ = humioClient.AddParser(config, req, &humiov1alpha1.HumioParser{Spec: humiov1alpha1.HumioParserSpec{Name: parser.Name}})
+			o := &OrphanedEntityCollector{HumioClient: humioClient, Log: logr.Discard(), Mode: helpers.OrphanedEntityGCModeDelete}
+
+			keep, _, err := o.handleOrphan(config, "default", parser, tt.references)
+			if keep != tt.wantKeep || (err != nil) != tt.wantErr {
+				t.Errorf("handleOrphan() = %t, %v, want %t and error %t", keep, err, tt.wantKeep, tt.wantErr)
+			}
+			if exists, err := o.entityExists(config, req, parser); err != nil || !exists {
+				t.Errorf("expected entity not to be deleted, got %t and %v", exists, err)
+			}
+		})
+	}
+}
+
+func TestWriteLedger(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	o := &OrphanedEntityCollector{Client: c, Log: logr.Discard()}
+	key := types.NamespacedName{Namespace: "default", Name: entityLedgerName}
+	entries := []ledgerEntry{
+		{Kind: "HumioRepository", ManagedClusterName: "humio", Name: "web", Resource: "web"},
+		{Kind: "HumioAlert", ManagedClusterName: "humio", ViewName: "web", Name: "errors", Resource: "errors"},
+	}
+
+	if err := o.writeLedger(context.Background(), "default", entries); err != nil {
+		t.Fatal(err)
+	}
+	var ledger corev1.ConfigMap
+	if err := c.Get(context.Background(), key, &ledger); err != nil {
+		t.Fatalf("expected ledger to be created: %s", err)
+	}
+	if ledger.Labels[entityLedgerLabel] != "true" || ledger.Data[entityLedgerKey] == "" {
+		t.Errorf("expected labeled ledger holding the entries, got %+v", ledger)
+	}
+
+	if err := o.writeLedger(context.Background(), "default", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.Background(), key, &ledger); !k8serrors.IsNotFound(err) {
+		t.Errorf("expected empty ledger to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// entityReconcileMaxWait is for how long an entity reconcile is deferred while critical reconciles are running before
+// it runs anyway, so entities are not starved while clusters are reconciled continuously
+const entityReconcileMaxWait = 10 * time.Second
+
+// entityReconcileRequeueDelay is after how long a deferred entity reconcile is retried
+const entityReconcileRequeueDelay = time.Second
+
+// reconcilePriorities is shared by all controllers, as every controller has its own work queue
+var reconcilePriorities = newReconcilePriorityGate()
+
+// reconcilePriorityGate lets critical reconciles, such as those managing the pods of a cluster, preempt reconciles of
+// Humio entities. Entity reconciles are requeued while critical reconciles are running, so they do not compete for the
+// Kubernetes and Humio API budgets during backlogs, e.g. after the operator restarts. Entity reconciles are requeued
+// rather than blocked, so they do not hold on to the workers of their controllers.
+type reconcilePriorityGate struct {
+	mutex    sync.Mutex
+	critical int
+}
+
+func newReconcilePriorityGate() *reconcilePriorityGate {
+	return &reconcilePriorityGate{}
+}
+
+// enterCritical marks a critical reconcile as running until the returned function is called
+func (g *reconcilePriorityGate) enterCritical() func() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.critical++
+	return func() {
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+
+		g.critical--
+	}
+}
+
+// criticalRunning returns whether any critical reconciles are running
+func (g *reconcilePriorityGate) criticalRunning() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.critical > 0
+}
+
+// criticalReconciler marks reconciles of the given reconciler as critical, so entity reconciles are deferred until they
+// are done
+func criticalReconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	r = withReconcileContext(r)
+	return reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		defer reconcilePriorities.enterCritical()()
+		return r.Reconcile(ctx, req)
+	})
+}
+
+// entityReconciler makes reconciles of the given reconciler be deferred while critical reconciles are running
+func entityReconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	return deferWhileCritical(reconcilePriorities, entityReconcileMaxWait, withReconcileContext(r))
+}
+
+// deferWhileCritical requeues reconciles of the given reconciler while critical reconciles are running on the gate. A
+// request which has been deferred for maxWait runs on its next attempt, even if critical reconciles are still running.
+func deferWhileCritical(g *reconcilePriorityGate, maxWait time.Duration, r reconcile.Reconciler) reconcile.Reconciler {
+	var mutex sync.Mutex
+	deferredSince := map[reconcile.Request]time.Time{}
+	return reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		mutex.Lock()
+		since, deferred := deferredSince[req]
+		if g.criticalRunning() && (!deferred || time.Since(since) < maxWait) {
+			if !deferred {
+				deferredSince[req] = time.Now()
+			}
+			mutex.Unlock()
+			return ctrl.Result{RequeueAfter: entityReconcileRequeueDelay}, nil
+		}
+		delete(deferredSince, req)
+		mutex.Unlock()
+		return r.Reconcile(ctx, req)
+	})
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcilePriorityGate(t *testing.T) {
+	g := newReconcilePriorityGate()
+	ctx := context.Background()
+	var reconciled int
+	r := deferWhileCritical(g, 50*time.Millisecond, reconcile.Func(func(context.Context, ctrl.Request) (ctrl.Result, error) {
+		reconciled++
+		return ctrl.Result{}, nil
+	}))
+	req := ctrl.Request{}
+
+	if result, _ := r.Reconcile(ctx, req); reconciled != 1 || result.RequeueAfter != 0 {
+		t.Errorf("expected entity reconcile to run right away while no critical reconciles are running, got %+v", result)
+	}
+
+	leaveFirst := g.enterCritical()
+	leaveSecond := g.enterCritical()
+	leaveFirst()
+	start := time.Now()
+	result, _ := r.Reconcile(ctx, req)
+	if reconciled != 1 || result.RequeueAfter != entityReconcileRequeueDelay {
+		t.Errorf("expected entity reconcile to be requeued while a critical reconcile is running, got %+v", result)
+	}
+	if time.Since(start) > 10*time.Millisecond {
+		t.Error("expected entity reconcile to be requeued without waiting")
+	}
+	leaveSecond()
+	if result, _ := r.Reconcile(ctx, req); reconciled != 2 || result.RequeueAfter != 0 {
+		t.Errorf("expected entity reconcile to run once critical reconciles are done, got %+v", result)
+	}
+
+	// Entity reconciles are not starved by critical reconciles which keep running
+	leave := g.enterCritical()
+	defer leave()
+	_, _ = r.Reconcile(ctx, req)
+	if reconciled != 2 {
+		t.Error("expected entity reconcile to be requeued while a critical reconcile is running")
+	}
+	time.Sleep(50 * time.Millisecond)
+	_, _ = r.Reconcile(ctx, req)
+	if reconciled != 3 {
+		t.Error("expected entity reconcile to run once it has been deferred for the max wait")
+	}
+	_, _ = r.Reconcile(ctx, req)
+	if reconciled != 3 {
+		t.Error("expected the max wait to start over after the entity reconcile has run")
+	}
+}
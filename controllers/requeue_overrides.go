@@ -0,0 +1,83 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// requeueIntervalAnnotation overrides how often a resource is reconciled after it was reconciled successfully,
+	// e.g. "1h" for parsers which rarely change, or "30s" for critical alerts
+	requeueIntervalAnnotation = "humio.com/requeue-interval"
+	// errorBackoffAnnotation overrides how long to wait before retrying a reconcile of a resource which failed, instead
+	// of the exponential backoff of the controller
+	errorBackoffAnnotation = "humio.com/error-backoff"
+)
+
+// annotationDuration returns the positive duration held by the given annotation. Missing and invalid values are
+// reported as not set.
+func annotationDuration(obj metav1.Object, annotation string) (time.Duration, bool) {
+	value, ok := obj.GetAnnotations()[annotation]
+	if !ok {
+		return 0, false
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, false
+	}
+	return duration, true
+}
+
+// requeueInterval returns for how long to wait before reconciling a resource again after it was reconciled
+// successfully, which is the given default unless the requeue-interval annotation overrides it. Zero means the
+// resource is only reconciled again when it changes.
+func requeueInterval(obj metav1.Object, defaultInterval time.Duration) time.Duration {
+	if interval, ok := annotationDuration(obj, requeueIntervalAnnotation); ok {
+		return interval
+	}
+	return defaultInterval
+}
+
+// withErrorBackoff retries failed reconciles of resources with the error-backoff annotation after the given backoff,
+// instead of the exponential backoff of the controller. Prototype is an empty object of the reconciled type.
+func withErrorBackoff(c client.Reader, prototype client.Object, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		result, err := r.Reconcile(ctx, req)
+		if err == nil {
+			return result, nil
+		}
+		obj := prototype.DeepCopyObject().(client.Object)
+		if getErr := c.Get(ctx, req.NamespacedName, obj); getErr != nil {
+			return result, err
+		}
+		backoff, ok := annotationDuration(obj, errorBackoffAnnotation)
+		if !ok {
+			return result, err
+		}
+		log.FromContext(ctx).Error(err, fmt.Sprintf("reconcile failed, will retry after %s as set by annotation %s", backoff, errorBackoffAnnotation))
+		return ctrl.Result{RequeueAfter: backoff}, nil
+	})
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRequeueInterval(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        time.Duration
+	}{
+		{"default", nil, 15 * time.Second},
+		{"override", map[string]string{requeueIntervalAnnotation: "1h"}, time.Hour},
+		{"invalid override", map[string]string{requeueIntervalAnnotation: "hourly"}, 15 * time.Second},
+		{"negative override", map[string]string{requeueIntervalAnnotation: "-30s"}, 15 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hp := &humiov1alpha1.HumioParser{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			if got := requeueInterval(hp, 15*time.Second); got != tt.want {
+				t.Errorf("requeueInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithErrorBackoff(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = humiov1alpha1.AddToScheme(scheme)
+	withBackoff := &humiov1alpha1.HumioAlert{ObjectMeta: metav1.ObjectMeta{
+		Name:        "with-backoff",
+		Namespace:   "default",
+		Annotations: map[string]string{errorBackoffAnnotation: "2m"},
+	}}
+	withoutBackoff := &humiov1alpha1.HumioAlert{ObjectMeta: metav1.ObjectMeta{Name: "without-backoff", Namespace: "default"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(withBackoff, withoutBackoff).Build()
+
+	reconcileErr := errors.New("humio unavailable")
+	failing := withErrorBackoff(c, &humiov1alpha1.HumioAlert{}, reconcile.Func(func(context.Context, ctrl.Request) (ctrl.Result, error) {
+		return ctrl.Result{}, reconcileErr
+	}))
+
+	result, err := failing.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "with-backoff"}})
+	if err != nil || result.RequeueAfter != 2*time.Minute {
+		t.Errorf("expected failed reconcile to be retried after the backoff of the annotation, got %+v and %v", result, err)
+	}
+	result, err = failing.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "without-backoff"}})
+	if !errors.Is(err, reconcileErr) || result.RequeueAfter != 0 {
+		t.Errorf("expected error to be returned without the annotation, got %+v and %v", result, err)
+	}
+	result, err = failing.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "deleted"}})
+	if !errors.Is(err, reconcileErr) {
+		t.Errorf("expected error to be returned when the resource cannot be read, got %+v and %v", result, err)
+	}
+}
@@ -0,0 +1,33 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// triggerResyncAnnotation triggers a resync of a resource against Humio whenever its value changes, e.g. after the
+// entity was fixed by hand in Humio. Changing the annotation reconciles the resource right away, and the resync reads
+// the entity from Humio instead of the read cache.
+const triggerResyncAnnotation = "humio.com/trigger-resync"
+
+// resyncRequested returns the value of the trigger-resync annotation and whether it requests a resync which has not
+// been carried out yet
+func resyncRequested(obj metav1.Object, lastResyncRequest string) (string, bool) {
+	request := obj.GetAnnotations()[triggerResyncAnnotation]
+	return request, request != "" && request != lastResyncRequest
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResyncRequested(t *testing.T) {
+	tests := []struct {
+		name              string
+		annotations       map[string]string
+		lastResyncRequest string
+		wantRequest       string
+		wantRequested     bool
+	}{
+		{"no annotation", nil, "", "", false},
+		{"new request", map[string]string{triggerResyncAnnotation: "2023-06-01T12:00:00Z"}, "", "2023-06-01T12:00:00Z", true},
+		{"changed request", map[string]string{triggerResyncAnnotation: "2023-06-02T12:00:00Z"}, "2023-06-01T12:00:00Z", "2023-06-02T12:00:00Z", true},
+		{"handled request", map[string]string{triggerResyncAnnotation: "2023-06-01T12:00:00Z"}, "2023-06-01T12:00:00Z", "2023-06-01T12:00:00Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ha := &humiov1alpha1.HumioAlert{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			request, requested := resyncRequested(ha, tt.lastResyncRequest)
+			if request != tt.wantRequest || requested != tt.wantRequested {
+				t.Errorf("resyncRequested() = (%q, %t), want (%q, %t)", request, requested, tt.wantRequest, tt.wantRequested)
+			}
+		})
+	}
+}
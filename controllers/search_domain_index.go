@@ -0,0 +1,81 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// SearchDomainIndexPrimer lists the repositories and views of every HumioCluster and HumioExternalCluster once when
+// the operator starts, so the initial reconcile of the HumioRepositories and HumioViews of each cluster is served from
+// the search domain index of the Humio client instead of issuing a request per resource. Clusters which cannot be
+// reached are skipped and indexed on their first lookup instead.
+type SearchDomainIndexPrimer struct {
+	Client      client.Client
+	HumioClient humio.Client
+	Log         logr.Logger
+}
+
+// Start implements manager.Runnable
+func (p *SearchDomainIndexPrimer) Start(ctx context.Context) error {
+	var humioClusters humiov1alpha1.HumioClusterList
+	if err := p.Client.List(ctx, &humioClusters); err != nil {
+		p.Log.Error(err, "unable to list humio clusters")
+	}
+	for _, hc := range humioClusters.Items {
+		p.prime(ctx, hc.Name, "", hc.Namespace)
+	}
+
+	var humioExternalClusters humiov1alpha1.HumioExternalClusterList
+	if err := p.Client.List(ctx, &humioExternalClusters); err != nil {
+		p.Log.Error(err, "unable to list humio external clusters")
+	}
+	for _, hec := range humioExternalClusters.Items {
+		p.prime(ctx, "", hec.Name, hec.Namespace)
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (p *SearchDomainIndexPrimer) NeedLeaderElection() bool {
+	return true
+}
+
+func (p *SearchDomainIndexPrimer) prime(ctx context.Context, managedClusterName, externalClusterName, namespace string) {
+	if ctx.Err() != nil {
+		return
+	}
+	clusterName := managedClusterName + externalClusterName
+	cluster, err := helpers.NewCluster(ctx, p.Client, managedClusterName, externalClusterName, namespace, helpers.UseCertManager(), true)
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		p.Log.Error(err, fmt.Sprintf("unable to obtain humio client config for cluster %s", clusterName))
+		return
+	}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: clusterName}}
+	if err := p.HumioClient.PrimeSearchDomainIndex(cluster.Config(), req); err != nil {
+		p.Log.Error(err, fmt.Sprintf("unable to index the search domains of cluster %s", clusterName))
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/helpers"
+	"github.com/humio/humio-operator/pkg/humio"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	selfMonitoringResourceName        = "humio-operator-self-monitoring"
+	selfMonitoringIngestTokenName     = "humio-operator"
+	selfMonitoringParserName          = "json"
+	selfMonitoringRetentionTimeInDays = 30
+	selfMonitoringInterval            = time.Second * 30
+)
+
+// SelfMonitoring provisions a repository and an ingest token for the operator on a managed Humio cluster, and points
+// the log shipper of the operator at them. The repository and ingest token are created as HumioRepository and
+// HumioIngestToken resources, so they are managed by the regular controllers and can be tuned afterwards, e.g. to
+// change the retention.
+type SelfMonitoring struct {
+	Client         client.Client
+	Log            logr.Logger
+	Namespace      string
+	ClusterName    string
+	RepositoryName string
+	Shipper        *humio.HumioIngestLogShipper
+}
+
+// Start implements manager.Runnable
+func (s *SelfMonitoring) Start(ctx context.Context) error {
+	ticker := time.NewTicker(selfMonitoringInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.reconcile(ctx); err != nil {
+			s.Log.Error(err, "unable to set up self-monitoring")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica of the operator ships its own logs.
+func (s *SelfMonitoring) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *SelfMonitoring) reconcile(ctx context.Context) error {
+	labels := map[string]string{"app.kubernetes.io/managed-by": "humio-operator"}
+	repository := &humiov1alpha1.HumioRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: selfMonitoringResourceName, Namespace: s.Namespace, Labels: labels},
+		Spec: humiov1alpha1.HumioRepositorySpec{
+			ManagedClusterName: s.ClusterName,
+			Name:               s.RepositoryName,
+			Description:        "Logs of the humio-operator",
+			Retention:          humiov1alpha1.HumioRetention{TimeInDays: selfMonitoringRetentionTimeInDays},
+		},
+	}
+	if err := s.createIfNotFound(ctx, repository); err != nil {
+		return err
+	}
+	ingestToken := &humiov1alpha1.HumioIngestToken{
+		ObjectMeta: metav1.ObjectMeta{Name: selfMonitoringResourceName, Namespace: s.Namespace, Labels: labels},
+		Spec: humiov1alpha1.HumioIngestTokenSpec{
+			ManagedClusterName: s.ClusterName,
+			Name:               selfMonitoringIngestTokenName,
+			RepositoryName:     s.RepositoryName,
+			ParserName:         selfMonitoringParserName,
+			TokenSecretName:    selfMonitoringTokenSecretName(),
+		},
+	}
+	if err := s.createIfNotFound(ctx, ingestToken); err != nil {
+		return err
+	}
+
+	var tokenSecret corev1.Secret
+	err := s.Client.Get(ctx, types.NamespacedName{Namespace: s.Namespace, Name: selfMonitoringTokenSecretName()}, &tokenSecret)
+	if k8serrors.IsNotFound(err) {
+		s.Log.Info("waiting for self-monitoring ingest token to be created")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get self-monitoring ingest token: %w", err)
+	}
+
+	cluster, err := helpers.NewCluster(ctx, s.Client, s.ClusterName, "", s.Namespace, helpers.UseCertManager(), false)
+	if err != nil || cluster == nil || cluster.Config() == nil {
+		return fmt.Errorf("unable to obtain humio client config: %w", err)
+	}
+	return s.Shipper.SetTarget(cluster.Config().Address.String(), string(tokenSecret.Data["token"]), cluster.Config().CACertificatePEM)
+}
+
+func (s *SelfMonitoring) createIfNotFound(ctx context.Context, obj client.Object) error {
+	err := s.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object))
+	if !k8serrors.IsNotFound(err) {
+		return err
+	}
+	s.Log.Info(fmt.Sprintf("creating %T %s for self-monitoring", obj, obj.GetName()))
+	if err := s.Client.Create(ctx, obj); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func selfMonitoringTokenSecretName() string {
+	return fmt.Sprintf("%s-ingest-token", selfMonitoringResourceName)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// startupReconcileWindow is for how long after the first reconcile of the operator the first reconciles of entities
+// are spread out. The window starts with the first reconcile rather than when the process starts, as standby
+// replicas only start reconciling once they are elected leader. Entities which are first reconciled after the window,
+// e.g. because they were just created, are reconciled right away.
+const startupReconcileWindow = 15 * time.Minute
+
+// startupReconciles is shared by all controllers, so the first reconciles of all entity types together stay within
+// the startup reconcile rate
+var startupReconciles = newStartupReconcileSpreader(0)
+
+// SetStartupReconcileRate spreads out the first reconcile of each Humio entity after the operator starts to the given
+// number of entities per second. A rate of zero reconciles all entities right away.
+func SetStartupReconcileRate(reconcilesPerSecond float64) {
+	startupReconciles.setRate(reconcilesPerSecond)
+}
+
+// startupReconcileSpreader hands out a slot for the first reconcile of each entity, so reconciling thousands of
+// entities after a restart does not hit the Kubernetes and Humio APIs all at once. Slots are spaced by the interval,
+// and each entity is reconciled at a random time within its slot.
+type startupReconcileSpreader struct {
+	mutex    sync.Mutex
+	started  time.Time
+	interval time.Duration
+	next     time.Time
+	seen     map[string]struct{}
+}
+
+func newStartupReconcileSpreader(reconcilesPerSecond float64) *startupReconcileSpreader {
+	s := &startupReconcileSpreader{seen: map[string]struct{}{}}
+	s.setRate(reconcilesPerSecond)
+	return s
+}
+
+func (s *startupReconcileSpreader) setRate(reconcilesPerSecond float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.interval = 0
+	if reconcilesPerSecond > 0 {
+		s.interval = time.Duration(float64(time.Second) / reconcilesPerSecond)
+	}
+}
+
+// delay returns for how long to wait before the entity with the given key is reconciled. Only the first reconcile of
+// each entity within the startup reconcile window is delayed.
+func (s *startupReconcileSpreader) delay(key string, now time.Time) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.interval <= 0 || s.seen == nil {
+		return 0
+	}
+	if s.started.IsZero() {
+		s.started = now
+	}
+	if now.Sub(s.started) > startupReconcileWindow {
+		s.seen = nil
+		return 0
+	}
+	if _, found := s.seen[key]; found {
+		return 0
+	}
+	s.seen[key] = struct{}{}
+
+	slot := s.next
+	if slot.Before(now) {
+		slot = now
+	}
+	s.next = slot.Add(s.interval)
+	return slot.Sub(now) + time.Duration(rand.Int63n(int64(s.interval)))
+}
+
+// withStartupSpread delays the first reconcile of each entity after the operator starts according to
+// startupReconciles. Prototype is an empty object of the reconciled type.
+func withStartupSpread(prototype client.Object, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		if delay := startupReconciles.delay(fmt.Sprintf("%T/%s", prototype, req), time.Now()); delay > 0 {
+			log.FromContext(ctx).Info(fmt.Sprintf("delaying the first reconcile after the operator started by %s", delay.Round(time.Millisecond)))
+			return reconcile.Result{RequeueAfter: delay}, nil
+		}
+		return r.Reconcile(ctx, req)
+	})
+}
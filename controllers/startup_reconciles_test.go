@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestStartupReconcileSpreader(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	s := newStartupReconcileSpreader(10)
+
+	for i, key := range []string{"a", "b", "c", "d"} {
+		delay := s.delay(key, now)
+		slot := time.Duration(i) * 100 * time.Millisecond
+		if delay < slot || delay >= slot+100*time.Millisecond {
+			t.Errorf("expected the first reconcile of %s within slot %s, got %s", key, slot, delay)
+		}
+	}
+	if delay := s.delay("a", now); delay != 0 {
+		t.Errorf("expected later reconciles not to be delayed, got %s", delay)
+	}
+
+	// Slots in the past are not handed out, so entities seen after a pause are not delayed by more than a slot
+	if delay := s.delay("e", now.Add(time.Minute)); delay >= 100*time.Millisecond {
+		t.Errorf("expected the first reconcile after a pause within the first slot, got %s", delay)
+	}
+	if delay := s.delay("f", now.Add(startupReconcileWindow+time.Second)); delay != 0 {
+		t.Errorf("expected reconciles after the startup window not to be delayed, got %s", delay)
+	}
+
+	if delay := newStartupReconcileSpreader(0).delay("a", now); delay != 0 {
+		t.Errorf("expected no delay when disabled, got %s", delay)
+	}
+}
+
+func TestWithStartupSpread(t *testing.T) {
+	defer func(s *startupReconcileSpreader) { startupReconciles = s }(startupReconciles)
+	startupReconciles = newStartupReconcileSpreader(0.001)
+
+	var reconciled int
+	r := withStartupSpread(&humiov1alpha1.HumioParser{}, reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		reconciled++
+		return ctrl.Result{}, nil
+	}))
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "logging", Name: "parser"}}
+
+	result, err := r.Reconcile(context.Background(), req)
+	if err != nil || result.RequeueAfter <= 0 {
+		t.Errorf("expected the first reconcile to be requeued, got %+v, %v", result, err)
+	}
+	if result, err := r.Reconcile(context.Background(), req); err != nil || result.RequeueAfter != 0 {
+		t.Errorf("expected the requeued reconcile to run, got %+v, %v", result, err)
+	}
+	if reconciled != 1 {
+		t.Errorf("expected the entity to be reconciled once after its delay, got %d reconciles", reconciled)
+	}
+}
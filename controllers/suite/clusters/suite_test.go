@@ -183,6 +183,7 @@ var _ = BeforeSuite(func() {
 		HumioClient: humioClientForHumioCluster,
 		BaseLogger:  log,
 		Namespace:   testProcessNamespace,
+		Recorder:    k8sManager.GetEventRecorderFor("humiocluster-controller"),
 	}).SetupWithManager(k8sManager)
 	Expect(err).NotTo(HaveOccurred())
 
@@ -686,6 +686,9 @@ var _ = Describe("Humio Resources Controllers", func() {
 				k8sClient.Get(ctx, key, fetchedExternalCluster)
 				return fetchedExternalCluster.Status.State
 			}, testTimeout, suite.TestInterval).Should(Equal(humiov1alpha1.HumioExternalClusterStateReady))
+			Expect(fetchedExternalCluster.Status.Version).ToNot(BeEmpty())
+			Expect(fetchedExternalCluster.Status.Username).ToNot(BeEmpty())
+			Expect(fetchedExternalCluster.Status.LastHealthCheckTime).ToNot(BeNil())
 
 			suite.UsingClusterBy(clusterKey.Name, "HumioExternalCluster: Successfully deleting it")
 			Expect(k8sClient.Delete(ctx, fetchedExternalCluster)).To(Succeed())
@@ -167,6 +167,7 @@ var _ = BeforeSuite(func() {
 		HumioClient: humioClient,
 		BaseLogger:  log,
 		Namespace:   clusterKey.Namespace,
+		Recorder:    k8sManager.GetEventRecorderFor("humiocluster-controller"),
 	}).SetupWithManager(k8sManager)
 	Expect(err).NotTo(HaveOccurred())
 
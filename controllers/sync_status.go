@@ -0,0 +1,61 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// lastSyncRefreshInterval is how old the last sync time of a resource may get before it is refreshed. Refreshing it on
+// every reconcile would update the status, and with it trigger another reconcile, every time a resource is reconciled.
+const lastSyncRefreshInterval = time.Minute
+
+// syncVerifyInterval is how often a resource which has not changed since it was last synced is compared with its
+// entity in Humio, to detect changes made outside the operator
+const syncVerifyInterval = 10 * time.Minute
+
+// syncUnchanged returns true if the resource has not changed since it was last synced with the given generation and
+// applied hash, and was last compared with Humio within syncVerifyInterval, so comparing it with Humio again can be
+// skipped. Resources with a pending resync request are always compared with Humio.
+func syncUnchanged(obj metav1.Object, syncedGeneration int64, appliedHash, desiredHash, lastResyncRequest string, lastSyncTime *metav1.Time, now time.Time) bool {
+	if _, requested := resyncRequested(obj, lastResyncRequest); requested {
+		return false
+	}
+	return obj.GetDeletionTimestamp() == nil &&
+		syncedGeneration == obj.GetGeneration() &&
+		appliedHash != "" && appliedHash == desiredHash &&
+		lastSyncTime != nil && now.Sub(lastSyncTime.Time) < syncVerifyInterval
+}
+
+// syncClusterName returns the name of the managed or external cluster a resource is synced to
+func syncClusterName(managedClusterName, externalClusterName string) string {
+	if managedClusterName != "" {
+		return managedClusterName
+	}
+	return externalClusterName
+}
+
+// syncTime returns the last sync time of a resource which was synced successfully at the given time
+func syncTime(lastSyncTime *metav1.Time, now time.Time) *metav1.Time {
+	if lastSyncTime != nil && now.Sub(lastSyncTime.Time) < lastSyncRefreshInterval {
+		return lastSyncTime
+	}
+	t := metav1.NewTime(now)
+	return &t
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSyncClusterName(t *testing.T) {
+	tests := []struct {
+		name     string
+		managed  string
+		external string
+		want     string
+	}{
+		{"managed cluster", "humiocluster", "", "humiocluster"},
+		{"external cluster", "", "humioexternalcluster", "humioexternalcluster"},
+		{"no cluster", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := syncClusterName(tt.managed, tt.external); got != tt.want {
+				t.Errorf("syncClusterName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncTime(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	recent := metav1.NewTime(now.Add(-30 * time.Second))
+	stale := metav1.NewTime(now.Add(-2 * time.Minute))
+
+	tests := []struct {
+		name         string
+		lastSyncTime *metav1.Time
+		want         time.Time
+	}{
+		{"never synced", nil, now},
+		{"synced recently", &recent, recent.Time},
+		{"synced a while ago", &stale, now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := syncTime(tt.lastSyncTime, now)
+			if got == nil || !got.Time.Equal(tt.want) {
+				t.Errorf("syncTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncUnchanged(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	recent := metav1.NewTime(now.Add(-5 * time.Minute))
+	stale := metav1.NewTime(now.Add(-15 * time.Minute))
+	deleted := metav1.NewTime(now)
+
+	tests := []struct {
+		name              string
+		meta              metav1.ObjectMeta
+		syncedGeneration  int64
+		appliedHash       string
+		lastResyncRequest string
+		lastSyncTime      *metav1.Time
+		want              bool
+	}{
+		{"unchanged", metav1.ObjectMeta{Generation: 2}, 2, "hash", "", &recent, true},
+		{"generation changed", metav1.ObjectMeta{Generation: 3}, 2, "hash", "", &recent, false},
+		{"desired state changed", metav1.ObjectMeta{Generation: 2}, 2, "other", "", &recent, false},
+		{"never synced", metav1.ObjectMeta{Generation: 2}, 0, "", "", nil, false},
+		{"verified a while ago", metav1.ObjectMeta{Generation: 2}, 2, "hash", "", &stale, false},
+		{"resync requested", metav1.ObjectMeta{Generation: 2, Annotations: map[string]string{triggerResyncAnnotation: "2"}}, 2, "hash", "1", &recent, false},
+		{"resync carried out", metav1.ObjectMeta{Generation: 2, Annotations: map[string]string{triggerResyncAnnotation: "1"}}, 2, "hash", "1", &recent, true},
+		{"marked to be deleted", metav1.ObjectMeta{Generation: 2, DeletionTimestamp: &deleted}, 2, "hash", "", &recent, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := syncUnchanged(&tt.meta, tt.syncedGeneration, tt.appliedHash, "hash", tt.lastResyncRequest, tt.lastSyncTime, now); got != tt.want {
+				t.Errorf("syncUnchanged() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	webhookCACertificateValidity    = 10 * 365 * 24 * time.Hour
+	webhookServingCertValidity      = 365 * 24 * time.Hour
+	webhookCertificateRenewBefore   = 30 * 24 * time.Hour
+	webhookCertificateCheckInterval = time.Hour
+	webhookCertificateRetryInterval = 10 * time.Second
+
+	webhookCACertificateKey = "ca.crt"
+	webhookCAPrivateKeyKey  = "ca.key"
+)
+
+// WebhookCertificateManager generates the serving certificate of the admission webhooks, and rotates it before it
+// expires, so the webhooks can be served without cert-manager. The certificate and the CA signing it are stored in a
+// secret, which is shared by all replicas of the operator, and the CA is injected into the webhook configuration. The
+// CA is kept across rotations of the serving certificate, so replicas still serving the previous certificate remain
+// trusted until they pick up the new one.
+type WebhookCertificateManager struct {
+	// Client is used to write the secret and the webhook configuration
+	Client client.Client
+	// Reader is used to read the secret and the webhook configuration, as they may live outside the namespaces watched
+	// by the manager
+	Reader                   client.Reader
+	Log                      logr.Logger
+	Namespace                string
+	SecretName               string
+	ServiceName              string
+	WebhookConfigurationName string
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+}
+
+// GetCertificate returns the current serving certificate, and is meant to be set on the tls.Config of the webhook
+// server
+func (m *WebhookCertificateManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.certificate == nil {
+		return nil, fmt.Errorf("webhook certificate is not available yet")
+	}
+	return m.certificate, nil
+}
+
+// Start implements manager.Runnable
+func (m *WebhookCertificateManager) Start(ctx context.Context) error {
+	for {
+		interval := webhookCertificateCheckInterval
+		if err := m.ensureCertificate(ctx); err != nil {
+			m.Log.Error(err, "unable to ensure webhook certificate")
+			interval = webhookCertificateRetryInterval
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica serves the webhooks, so every replica
+// needs the certificate.
+func (m *WebhookCertificateManager) NeedLeaderElection() bool {
+	return false
+}
+
+func (m *WebhookCertificateManager) dnsNames() []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", m.ServiceName, m.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", m.ServiceName, m.Namespace),
+	}
+}
+
+// ensureCertificate makes sure the secret holds a serving certificate which is valid for a while yet, loads it, and
+// injects its CA into the webhook configuration
+func (m *WebhookCertificateManager) ensureCertificate(ctx context.Context) error {
+	var secret corev1.Secret
+	err := m.Reader.Get(ctx, types.NamespacedName{Namespace: m.Namespace, Name: m.SecretName}, &secret)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("could not get webhook certificate secret: %w", err)
+	}
+	if k8serrors.IsNotFound(err) {
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: m.Namespace,
+				Name:      m.SecretName,
+			},
+			Type: corev1.SecretTypeTLS,
+		}
+	}
+
+	if renew, reason := webhookCertificateNeedsRenewal(secret.Data, m.dnsNames(), time.Now()); renew {
+		m.Log.Info(fmt.Sprintf("generating webhook certificate as %s", reason))
+		data, err := generateWebhookCertificate(secret.Data, m.dnsNames(), time.Now())
+		if err != nil {
+			return fmt.Errorf("could not generate webhook certificate: %w", err)
+		}
+		secret.Data = data
+		// Another replica may update the secret at the same time, in which case the conflict makes us retry and load the
+		// certificate it generated
+		if secret.ResourceVersion == "" {
+			err = m.Client.Create(ctx, &secret)
+		} else {
+			err = m.Client.Update(ctx, &secret)
+		}
+		if err != nil {
+			return fmt.Errorf("could not store webhook certificate: %w", err)
+		}
+	}
+
+	certificate, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return fmt.Errorf("could not load webhook certificate: %w", err)
+	}
+	m.mu.Lock()
+	m.certificate = &certificate
+	m.mu.Unlock()
+
+	return m.injectCABundle(ctx, secret.Data[webhookCACertificateKey])
+}
+
+// injectCABundle sets the CA on all webhooks of the webhook configuration, so the API server trusts the certificate
+func (m *WebhookCertificateManager) injectCABundle(ctx context.Context, caBundle []byte) error {
+	if m.WebhookConfigurationName == "" {
+		return nil
+	}
+	var webhookConfiguration admissionregistrationv1.ValidatingWebhookConfiguration
+	if err := m.Reader.Get(ctx, types.NamespacedName{Name: m.WebhookConfigurationName}, &webhookConfiguration); err != nil {
+		return fmt.Errorf("could not get webhook configuration: %w", err)
+	}
+	changed := false
+	for idx := range webhookConfiguration.Webhooks {
+		if !bytes.Equal(webhookConfiguration.Webhooks[idx].ClientConfig.CABundle, caBundle) {
+			webhookConfiguration.Webhooks[idx].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	m.Log.Info("injecting webhook CA into webhook configuration", "WebhookConfiguration", m.WebhookConfigurationName)
+	if err := m.Client.Update(ctx, &webhookConfiguration); err != nil {
+		return fmt.Errorf("could not update webhook configuration: %w", err)
+	}
+	return nil
+}
+
+// webhookCertificateNeedsRenewal returns whether the certificate stored in the secret data is missing, does not cover
+// the DNS names of the webhook service, or expires within webhookCertificateRenewBefore, and why
+func webhookCertificateNeedsRenewal(data map[string][]byte, dnsNames []string, now time.Time) (bool, string) {
+	ca, _, err := parseWebhookCA(data)
+	if err != nil {
+		return true, fmt.Sprintf("the CA is not usable: %s", err)
+	}
+	if now.Add(webhookCertificateRenewBefore).After(ca.NotAfter) {
+		return true, "the CA is about to expire"
+	}
+	if _, err := tls.X509KeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey]); err != nil {
+		return true, fmt.Sprintf("the certificate is not usable: %s", err)
+	}
+	certificate, err := parseCertificatePEM(data[corev1.TLSCertKey])
+	if err != nil {
+		return true, fmt.Sprintf("the certificate is not usable: %s", err)
+	}
+	if err := certificate.CheckSignatureFrom(ca); err != nil {
+		return true, "the certificate is not signed by the CA"
+	}
+	if now.Add(webhookCertificateRenewBefore).After(certificate.NotAfter) {
+		return true, "the certificate is about to expire"
+	}
+	for _, dnsName := range dnsNames {
+		if err := certificate.VerifyHostname(dnsName); err != nil {
+			return true, fmt.Sprintf("the certificate does not cover %s", dnsName)
+		}
+	}
+	return false, ""
+}
+
+// generateWebhookCertificate returns secret data holding a new serving certificate for the given DNS names. The CA in
+// the existing secret data is reused unless it is missing or about to expire.
+func generateWebhookCertificate(data map[string][]byte, dnsNames []string, now time.Time) (map[string][]byte, error) {
+	ca, caPrivateKey, err := parseWebhookCA(data)
+	if err != nil || now.Add(webhookCertificateRenewBefore).After(ca.NotAfter) {
+		ca, caPrivateKey, err = generateWebhookCA(now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(webhookServingCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, template, ca, &privateKey.PublicKey, caPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{
+		webhookCACertificateKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}),
+		webhookCAPrivateKeyKey:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(caPrivateKey)}),
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateBytes}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}),
+	}, nil
+}
+
+func generateWebhookCA(now time.Time) (*x509.Certificate, *rsa.PrivateKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "humio-operator-webhook-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(webhookCACertificateValidity),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caBytes, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(caBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ca, privateKey, nil
+}
+
+func parseWebhookCA(data map[string][]byte) (*x509.Certificate, *rsa.PrivateKey, error) {
+	ca, err := parseCertificatePEM(data[webhookCACertificateKey])
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(data[webhookCAPrivateKeyKey])
+	if block == nil {
+		return nil, nil, fmt.Errorf("could not decode %s", webhookCAPrivateKeyKey)
+	}
+	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ca, privateKey, nil
+}
+
+func parseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
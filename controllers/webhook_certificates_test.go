@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWebhookCertificateRotation(t *testing.T) {
+	dnsNames := []string{"humio-operator-webhook.logging.svc", "humio-operator-webhook.logging.svc.cluster.local"}
+	now := time.Now()
+
+	if renew, _ := webhookCertificateNeedsRenewal(nil, dnsNames, now); !renew {
+		t.Fatal("expected a missing certificate to need renewal")
+	}
+	data, err := generateWebhookCertificate(nil, dnsNames, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renew, reason := webhookCertificateNeedsRenewal(data, dnsNames, now); renew {
+		t.Fatalf("expected a new certificate not to need renewal, got %s", reason)
+	}
+	if renew, _ := webhookCertificateNeedsRenewal(data, []string{"humio-operator-webhook.other.svc"}, now); !renew {
+		t.Error("expected a certificate not covering the service to need renewal")
+	}
+
+	later := now.Add(webhookServingCertValidity - webhookCertificateRenewBefore + time.Hour)
+	if renew, _ := webhookCertificateNeedsRenewal(data, dnsNames, later); !renew {
+		t.Fatal("expected a certificate about to expire to need renewal")
+	}
+	renewed, err := generateWebhookCertificate(data, dnsNames, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(renewed[webhookCACertificateKey], data[webhookCACertificateKey]) {
+		t.Error("expected the CA to be kept when renewing the certificate")
+	}
+	if bytes.Equal(renewed[corev1.TLSCertKey], data[corev1.TLSCertKey]) {
+		t.Error("expected the certificate to be renewed")
+	}
+	if renew, reason := webhookCertificateNeedsRenewal(renewed, dnsNames, later); renew {
+		t.Errorf("expected the renewed certificate not to need renewal, got %s", reason)
+	}
+}
+
+func TestWebhookCertificateManager(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	webhookConfiguration := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "humio-operator-logging"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "vhumioparser.core.humio.com"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(webhookConfiguration).Build()
+	m := &WebhookCertificateManager{
+		Client:                   c,
+		Reader:                   c,
+		Log:                      logr.Discard(),
+		Namespace:                "logging",
+		SecretName:               "humio-operator-webhook-cert",
+		ServiceName:              "humio-operator-webhook",
+		WebhookConfigurationName: "humio-operator-logging",
+	}
+	ctx := context.Background()
+
+	if _, err := m.GetCertificate(nil); err == nil {
+		t.Fatal("expected no certificate before it is generated")
+	}
+	if err := m.ensureCertificate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetCertificate(nil); err != nil {
+		t.Fatalf("expected the certificate to be loaded, got %s", err)
+	}
+
+	var secret corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "logging", Name: "humio-operator-webhook-cert"}, &secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "humio-operator-logging"}, webhookConfiguration); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(webhookConfiguration.Webhooks[0].ClientConfig.CABundle, secret.Data[webhookCACertificateKey]) {
+		t.Error("expected the CA to be injected into the webhook configuration")
+	}
+
+	// Another replica loads the certificate from the secret instead of generating its own
+	other := &WebhookCertificateManager{
+		Client:                   c,
+		Reader:                   c,
+		Log:                      logr.Discard(),
+		Namespace:                m.Namespace,
+		SecretName:               m.SecretName,
+		ServiceName:              m.ServiceName,
+		WebhookConfigurationName: m.WebhookConfigurationName,
+	}
+	if err := other.ensureCertificate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var unchanged corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "logging", Name: "humio-operator-webhook-cert"}, &unchanged); err != nil {
+		t.Fatal(err)
+	}
+	if unchanged.ResourceVersion != secret.ResourceVersion {
+		t.Error("expected the certificate in the secret to be reused")
+	}
+}
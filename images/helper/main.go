@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	graphql "github.com/cli/shurcooL-graphql"
@@ -423,7 +424,8 @@ func authMode() {
 }
 
 // initMode looks up the availability zone of the Kubernetes node defined in environment variable NODE_NAME and saves
-// the result to the file defined in environment variable TARGET_FILE
+// the result to the file defined in environment variable TARGET_FILE. If environment variable NODE_TAG_LABELS is set,
+// the node tags are looked up as well and saved to the file defined in environment variable NODE_TAGS_TARGET_FILE.
 func initMode() {
 	nodeName, found := os.LookupEnv("NODE_NAME")
 	if !found || nodeName == "" {
@@ -435,6 +437,12 @@ func initMode() {
 		panic("environment variable TARGET_FILE not set or empty")
 	}
 
+	nodeTagLabels, _ := os.LookupEnv("NODE_TAG_LABELS")
+	nodeTagsTargetFile, _ := os.LookupEnv("NODE_TAGS_TARGET_FILE")
+	if nodeTagLabels != "" && nodeTagsTargetFile == "" {
+		panic("environment variable NODE_TAGS_TARGET_FILE not set or empty")
+	}
+
 	ctx := context.Background()
 
 	clientset := newKubernetesClientset()
@@ -447,6 +455,27 @@ func initMode() {
 		if !found {
 			zone, _ = node.Labels[corev1.LabelZoneFailureDomain]
 		}
+
+		if nodeTagLabels != "" {
+			var nodeTags []string
+			for _, pair := range strings.Split(nodeTagLabels, ",") {
+				tag, label, ok := strings.Cut(pair, "=")
+				if !ok {
+					panic(fmt.Sprintf("invalid node tag mapping %q, expected tag=label", pair))
+				}
+				value := node.Labels[label]
+				// The zone tag replaces the availability zone, so partitions are assigned across the same zones as the tag
+				if tag == "zone" {
+					zone = value
+				}
+				nodeTags = append(nodeTags, fmt.Sprintf("%s=%s", tag, value))
+			}
+			err := os.WriteFile(nodeTagsTargetFile, []byte(strings.Join(nodeTags, ",")), 0644) // #nosec G306
+			if err != nil {
+				panic(fmt.Sprintf("unable to write file with node tags: %s", err))
+			}
+		}
+
 		err := os.WriteFile(targetFile, []byte(zone), 0644) // #nosec G306
 		if err != nil {
 			panic(fmt.Sprintf("unable to write file with availability zone information: %s", err))
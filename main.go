@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"os"
@@ -27,6 +28,8 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	humioapi "github.com/humio/cli/api"
+	uberzap "go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -36,10 +39,13 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 
+	"github.com/humio/humio-operator/pkg/conformance"
 	"github.com/humio/humio-operator/pkg/helpers"
 	"github.com/humio/humio-operator/pkg/humio"
+	humiokubernetes "github.com/humio/humio-operator/pkg/kubernetes"
 
 	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
 	"github.com/humio/humio-operator/controllers"
@@ -63,6 +69,13 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "conformance" {
+		os.Exit(runConformance(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiff(os.Args[2:]))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
@@ -76,24 +89,90 @@ func main() {
 	var log logr.Logger
 	zapLog, _ := helpers.NewLogger()
 	defer zapLog.Sync()
+	selfMonitoringNamespace, selfMonitoringClusterName, selfMonitoringRepositoryName, selfMonitoringErr := helpers.GetSelfMonitoringConfig()
+	var logShipper *humio.HumioIngestLogShipper
+	if selfMonitoringClusterName != "" {
+		// The log shipper reports its own errors using the logger without shipping, so they do not loop back
+		logShipper = humio.NewHumioIngestLogShipper(zapr.NewLogger(zapLog))
+		zapLog = zapLog.WithOptions(uberzap.WrapCore(logShipper.WrapCore))
+	}
+	// The recent logs of the operator are included in diagnostics bundles
+	recentLogs := helpers.NewRecentLogs(helpers.RecentLogsSize)
+	zapLog = zapLog.WithOptions(uberzap.WrapCore(recentLogs.WrapCore))
 	log = zapr.NewLogger(zapLog).WithValues("Operator.Commit", commit, "Operator.Date", date, "Operator.Version", version)
 	ctrl.SetLogger(log)
 
 	ctrl.Log.Info("starting humio-operator")
 
+	if selfMonitoringErr != nil {
+		ctrl.Log.Error(selfMonitoringErr, "unable to get self-monitoring configuration")
+		os.Exit(1)
+	}
+
+	namespaceProvisioningNamespace, namespaceProvisioningClusterName, err := helpers.GetNamespaceProvisioningConfig()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get namespace provisioning configuration")
+		os.Exit(1)
+	}
+
+	orphanedEntityGCMode, err := helpers.GetOrphanedEntityGCMode()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get orphaned entity garbage collection mode")
+		os.Exit(1)
+	}
+	startupReconcileRate, err := helpers.GetStartupReconcileRate()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get startup reconcile rate")
+		os.Exit(1)
+	}
+	controllers.SetStartupReconcileRate(startupReconcileRate)
+
+	controllerSet, err := helpers.GetControllerSet()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get the set of controllers to run")
+		os.Exit(1)
+	}
+
 	watchNamespace, err := helpers.GetWatchNamespace()
 	if err != nil {
 		ctrl.Log.Error(err, "unable to get WatchNamespace, "+
 			"the manager will watch and manage resources in all namespaces")
 	}
 
+	environmentVariablePolicyNamespace, environmentVariablePolicyName, err := helpers.GetEnvironmentVariablePolicyConfig()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get environment variable policy configuration")
+		os.Exit(1)
+	}
+
+	webhookCertNamespace, webhookCertSecretName, webhookServiceName, webhookConfigurationName, err := helpers.GetWebhookCertificateConfig()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get webhook certificate configuration")
+		os.Exit(1)
+	}
+	webhookOptions := webhook.Options{Port: 9443}
+	var webhookCertificates *controllers.WebhookCertificateManager
+	if webhookCertSecretName != "" {
+		// The serving certificate is generated and rotated by the operator instead of being mounted
+		webhookCertificates = &controllers.WebhookCertificateManager{
+			Log:                      log.WithName("webhook-certificates"),
+			Namespace:                webhookCertNamespace,
+			SecretName:               webhookCertSecretName,
+			ServiceName:              webhookServiceName,
+			WebhookConfigurationName: webhookConfigurationName,
+		}
+		webhookOptions.TLSOpts = []func(*tls.Config){func(c *tls.Config) {
+			c.GetCertificate = webhookCertificates.GetCertificate
+		}}
+	}
+
 	options := ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
-		WebhookServer:          webhook.NewServer(webhook.Options{Port: 9443}),
+		WebhookServer:          webhook.NewServer(webhookOptions),
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "d7845218.humio.com",
+		LeaderElectionID:       helpers.LeaderElectionID(controllerSet),
 		Cache:                  cache.Options{Namespaces: strings.Split(watchNamespace, ",")},
 	}
 
@@ -112,72 +191,373 @@ func main() {
 
 	userAgent := fmt.Sprintf("humio-operator/%s (%s on %s)", version, commit, date)
 
-	if err = (&controllers.HumioExternalClusterReconciler{
-		Client:      mgr.GetClient(),
-		HumioClient: humio.NewClient(log, &humioapi.Config{}, userAgent),
-		BaseLogger:  log,
-	}).SetupWithManager(mgr); err != nil {
-		ctrl.Log.Error(err, "unable to create controller", "controller", "HumioExternalCluster")
+	readCacheTTL, err := helpers.GetHumioClientReadCacheTTL()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get humio client read cache ttl")
 		os.Exit(1)
 	}
-	if err = (&controllers.HumioClusterReconciler{
-		Client:      mgr.GetClient(),
-		HumioClient: humio.NewClient(log, &humioapi.Config{}, userAgent),
-		BaseLogger:  log,
-	}).SetupWithManager(mgr); err != nil {
-		ctrl.Log.Error(err, "unable to create controller", "controller", "HumioCluster")
+	if helpers.UseHumioClientBulkListing() && readCacheTTL <= 0 {
+		ctrl.Log.Error(fmt.Errorf("HUMIO_CLIENT_BULK_LISTING requires HUMIO_CLIENT_READ_CACHE_TTL to be set"), "invalid humio client configuration")
 		os.Exit(1)
 	}
-	if err = (&controllers.HumioIngestTokenReconciler{
-		Client:      mgr.GetClient(),
-		HumioClient: humio.NewClient(log, &humioapi.Config{}, userAgent),
-		BaseLogger:  log,
-	}).SetupWithManager(mgr); err != nil {
-		ctrl.Log.Error(err, "unable to create controller", "controller", "HumioIngestToken")
+	searchDomainIndexResyncPeriod, err := helpers.GetHumioClientSearchDomainIndexResyncPeriod()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get humio client search domain index resync period")
 		os.Exit(1)
 	}
-	if err = (&controllers.HumioParserReconciler{
-		Client:      mgr.GetClient(),
-		HumioClient: humio.NewClient(log, &humioapi.Config{}, userAgent),
-		BaseLogger:  log,
-	}).SetupWithManager(mgr); err != nil {
-		ctrl.Log.Error(err, "unable to create controller", "controller", "HumioParser")
+	apiBudget, err := helpers.GetHumioClientAPIBudget()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get humio client api budget")
 		os.Exit(1)
 	}
-	if err = (&controllers.HumioRepositoryReconciler{
-		Client:      mgr.GetClient(),
-		HumioClient: humio.NewClient(log, &humioapi.Config{}, userAgent),
-		BaseLogger:  log,
-	}).SetupWithManager(mgr); err != nil {
-		ctrl.Log.Error(err, "unable to create controller", "controller", "HumioRepository")
+	faultInjectionRate, faultInjectionDelay, err := helpers.GetHumioClientFaultInjectionConfig()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get humio client fault injection configuration")
 		os.Exit(1)
 	}
-	if err = (&controllers.HumioViewReconciler{
-		Client:      mgr.GetClient(),
-		HumioClient: humio.NewClient(log, &humioapi.Config{}, userAgent),
-		BaseLogger:  log,
-	}).SetupWithManager(mgr); err != nil {
-		ctrl.Log.Error(err, "unable to create controller", "controller", "HumioView")
-		os.Exit(1)
+	if faultInjectionRate > 0 {
+		ctrl.Log.Info("injecting faults into calls against the Humio API", "rate", faultInjectionRate, "delay", faultInjectionDelay.String())
 	}
-	if err = (&controllers.HumioActionReconciler{
-		Client:      mgr.GetClient(),
-		HumioClient: humio.NewClient(log, &humioapi.Config{}, userAgent),
-		BaseLogger:  log,
-	}).SetupWithManager(mgr); err != nil {
-		ctrl.Log.Error(err, "unable to create controller", "controller", "HumioAction")
+	auditIngestURL, auditIngestToken, err := helpers.GetAuditIngestConfig()
+	if err != nil {
+		ctrl.Log.Error(err, "unable to get audit ingest configuration")
 		os.Exit(1)
 	}
-	if err = (&controllers.HumioAlertReconciler{
-		Client:      mgr.GetClient(),
-		HumioClient: humio.NewClient(log, &humioapi.Config{}, userAgent),
-		BaseLogger:  log,
-	}).SetupWithManager(mgr); err != nil {
-		ctrl.Log.Error(err, "unable to create controller", "controller", "HumioAlert")
-		os.Exit(1)
+	var auditSinks []humio.AuditSink
+	if auditIngestURL != "" {
+		auditSinks = append(auditSinks, humio.NewHumioIngestAuditSink(log, auditIngestURL, auditIngestToken))
+	}
+	if logShipper != nil {
+		auditSinks = append(auditSinks, logShipper)
+	}
+	// All reconcilers share a single client, so connections towards each Humio cluster are pooled across reconcilers.
+	// HumioClusters are reconciled using a priority client drawing from the same API budget.
+	baseHumioClient := humio.NewClient(log, &humioapi.Config{}, userAgent).
+		WithReadCache(readCacheTTL).
+		WithBulkListing(helpers.UseHumioClientBulkListing()).
+		WithSearchDomainIndex(searchDomainIndexResyncPeriod).
+		WithAPIBudget(apiBudget).
+		WithFaultInjection(faultInjectionRate, faultInjectionDelay)
+	humioClient := humio.NewAuditedClient(humio.NewInstrumentedClient(baseHumioClient), log, auditSinks...)
+	priorityHumioClient := humio.NewAuditedClient(humio.NewInstrumentedClient(baseHumioClient.PriorityClient()), log, auditSinks...)
+
+	// Changes to Kubernetes objects are audited separately, so they can be told apart from changes made by humans
+	k8sClient := mgr.GetClient()
+	auditEventsNamespace, auditWebhookURL := helpers.GetKubernetesAuditConfig()
+	var kubernetesAuditSinks []humiokubernetes.AuditSink
+	if auditEventsNamespace != "" {
+		kubernetesAuditSinks = append(kubernetesAuditSinks, humiokubernetes.NewEventAuditSink(log, mgr.GetClient(), auditEventsNamespace))
+	}
+	if auditWebhookURL != "" {
+		kubernetesAuditSinks = append(kubernetesAuditSinks, humiokubernetes.NewWebhookAuditSink(log, auditWebhookURL))
+	}
+	if len(kubernetesAuditSinks) > 0 {
+		k8sClient = humiokubernetes.NewAuditedClient(mgr.GetClient(), log, kubernetesAuditSinks...)
+	}
+
+	// The controllers managing the Humio clusters themselves need access to pods and persistent volume claims, while
+	// the controllers managing entities inside Humio only use the Humio API, so they may run as separate deployments
+	// with their own, more restricted, RBAC.
+	if controllerSet != helpers.ControllerSetEntities {
+		if err = (&controllers.HumioClusterReconciler{
+			Client:      k8sClient,
+			HumioClient: priorityHumioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humiocluster-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioCluster")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioClusterBackupReconciler{
+			Client:     k8sClient,
+			BaseLogger: log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioClusterBackup")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioClusterReplicationReconciler{
+			Client:     k8sClient,
+			BaseLogger: log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioClusterReplication")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioQueryExportReconciler{
+			Client:     k8sClient,
+			BaseLogger: log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioQueryExport")
+			os.Exit(1)
+		}
+		clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			ctrl.Log.Error(err, "unable to create kubernetes clientset")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioDiagnosticsBundleReconciler{
+			Client:       k8sClient,
+			Clientset:    clientset,
+			OperatorLogs: recentLogs,
+			HumioClient:  humioClient,
+			BaseLogger:   log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioDiagnosticsBundle")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioParserLibraryReconciler{
+			Client:     k8sClient,
+			Clientset:  clientset,
+			BaseLogger: log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioParserLibrary")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioClusterSetReconciler{
+			Client:     k8sClient,
+			BaseLogger: log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioClusterSet")
+			os.Exit(1)
+		}
+		if namespaceProvisioningClusterName != "" {
+			if err = (&controllers.NamespaceProvisioningReconciler{
+				Client:           k8sClient,
+				BaseLogger:       log,
+				ClusterNamespace: namespaceProvisioningNamespace,
+				ClusterName:      namespaceProvisioningClusterName,
+			}).SetupWithManager(mgr); err != nil {
+				ctrl.Log.Error(err, "unable to create controller", "controller", "NamespaceProvisioning")
+				os.Exit(1)
+			}
+		}
+	}
+	if controllerSet != helpers.ControllerSetCluster {
+		if err = (&controllers.HumioExternalClusterReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioExternalCluster")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioIngestTokenReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humioingesttoken-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioIngestToken")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioParserReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humioparser-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioParser")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioRepositoryReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humiorepository-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioRepository")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioViewReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humioview-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioView")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioActionReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humioaction-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioAction")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioAlertReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humioalert-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioAlert")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioAggregateAlertReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humioaggregatealert-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioAggregateAlert")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioScheduledSearchReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humioscheduledsearch-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioScheduledSearch")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioRehydrationJobReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioRehydrationJob")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioQueryJobReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioQueryJob")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioRetentionPolicyReconciler{
+			Client:     k8sClient,
+			BaseLogger: log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioRetentionPolicy")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioMultiClusterViewReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humiomulticlusterview-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioMultiClusterView")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioSavedQueryReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humiosavedquery-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioSavedQuery")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioDashboardReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+			Recorder:    mgr.GetEventRecorderFor("humiodashboard-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioDashboard")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioViewExportReconciler{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			BaseLogger:  log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioViewExport")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioAlertSetReconciler{
+			Client:     k8sClient,
+			BaseLogger: log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioAlertSet")
+			os.Exit(1)
+		}
+		if err = (&controllers.HumioAlertSilenceReconciler{
+			Client:     k8sClient,
+			BaseLogger: log,
+		}).SetupWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create controller", "controller", "HumioAlertSilence")
+			os.Exit(1)
+		}
+	}
+
+	// The webhooks are served by the deployment running the entity controllers
+	if controllerSet != helpers.ControllerSetCluster {
+		if helpers.UseParserValidationWebhook() {
+			if err = (&controllers.HumioParserValidator{
+				Client:      k8sClient,
+				HumioClient: humioClient,
+				Log:         log,
+			}).SetupWebhookWithManager(mgr); err != nil {
+				ctrl.Log.Error(err, "unable to create webhook", "webhook", "HumioParser")
+				os.Exit(1)
+			}
+		}
+		if environmentVariablePolicyName != "" {
+			if err = (&controllers.HumioClusterValidator{
+				Reader:          mgr.GetAPIReader(),
+				Log:             log,
+				PolicyNamespace: environmentVariablePolicyNamespace,
+				PolicyName:      environmentVariablePolicyName,
+			}).SetupWebhookWithManager(mgr); err != nil {
+				ctrl.Log.Error(err, "unable to create webhook", "webhook", "HumioCluster")
+				os.Exit(1)
+			}
+		}
+		if webhookCertificates != nil {
+			webhookCertificates.Client = k8sClient
+			webhookCertificates.Reader = mgr.GetAPIReader()
+			if err = mgr.Add(webhookCertificates); err != nil {
+				ctrl.Log.Error(err, "unable to set up webhook certificates")
+				os.Exit(1)
+			}
+		}
 	}
 	//+kubebuilder:scaffold:builder
 
+	if logShipper != nil {
+		if err = mgr.Add(&controllers.SelfMonitoring{
+			Client:         k8sClient,
+			Log:            log.WithName("self-monitoring"),
+			Namespace:      selfMonitoringNamespace,
+			ClusterName:    selfMonitoringClusterName,
+			RepositoryName: selfMonitoringRepositoryName,
+			Shipper:        logShipper,
+		}); err != nil {
+			ctrl.Log.Error(err, "unable to set up self-monitoring")
+			os.Exit(1)
+		}
+	}
+
+	if orphanedEntityGCMode != "" && controllerSet != helpers.ControllerSetCluster {
+		if err = mgr.Add(&controllers.OrphanedEntityCollector{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			Log:         log.WithName("orphaned-entity-gc"),
+			Recorder:    mgr.GetEventRecorderFor("orphaned-entity-gc"),
+			Mode:        orphanedEntityGCMode,
+		}); err != nil {
+			ctrl.Log.Error(err, "unable to set up orphaned entity garbage collection")
+			os.Exit(1)
+		}
+	}
+
+	if searchDomainIndexResyncPeriod > 0 && controllerSet != helpers.ControllerSetCluster {
+		if err = mgr.Add(&controllers.SearchDomainIndexPrimer{
+			Client:      k8sClient,
+			HumioClient: humioClient,
+			Log:         log.WithName("search-domain-index"),
+		}); err != nil {
+			ctrl.Log.Error(err, "unable to set up priming of the search domain index")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		ctrl.Log.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -193,3 +573,91 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// runConformance runs the conformance subcommand, which checks that a deployed operator manages entities in a Humio
+// cluster, and returns the exit code
+func runConformance(args []string) int {
+	var opts conformance.Options
+	flags := flag.NewFlagSet("conformance", flag.ContinueOnError)
+	flags.StringVar(&opts.Namespace, "namespace", "default", "The namespace to create the canary resources in.")
+	flags.StringVar(&opts.ManagedClusterName, "managed-cluster-name", "", "The name of the HumioCluster to check.")
+	flags.StringVar(&opts.ExternalClusterName, "external-cluster-name", "", "The name of the HumioExternalCluster to check.")
+	flags.DurationVar(&opts.Timeout, "timeout", conformance.DefaultTimeout, "How long each step waits for the operator.")
+	flags.DurationVar(&opts.PollInterval, "poll-interval", conformance.DefaultPollInterval, "How often the canary resources are checked while waiting for the operator.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to get kubeconfig: %s\n", err)
+		return 1
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create kubernetes client: %s\n", err)
+		return 1
+	}
+
+	report := conformance.Run(ctrl.SetupSignalHandler(), c, opts)
+	if err := report.Write(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to write report: %s\n", err)
+		return 1
+	}
+	if !report.Passed() {
+		return 1
+	}
+	return 0
+}
+
+// runDiff runs the diff subcommand, which prints the differences between custom resources and their entities in Humio.
+// Like diff(1), it returns 0 if all entities are as expected, 1 if any differ or are missing and 2 on errors. Managed
+// clusters are reached through their in-cluster service, so it is meant to be run inside the operator pod.
+func runDiff(args []string) int {
+	var namespace, kind, name string
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.StringVar(&namespace, "namespace", "", "The namespace of the resources to compare. All namespaces are compared when empty.")
+	flags.StringVar(&kind, "kind", "", fmt.Sprintf("The kind of the resources to compare, one of %s. All kinds are compared when empty.", strings.Join(controllers.DiffKinds(), ", ")))
+	flags.StringVar(&name, "name", "", "The name of the resource to compare. All resources are compared when empty.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to get kubeconfig: %s\n", err)
+		return 2
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create kubernetes client: %s\n", err)
+		return 2
+	}
+	userAgent := fmt.Sprintf("humio-operator/%s (%s on %s)", version, commit, date)
+	humioClient := humio.NewClient(logr.Discard(), &humioapi.Config{}, userAgent)
+
+	diffs, err := controllers.DiffEntities(ctrl.SetupSignalHandler(), c, humioClient, namespace, kind, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to compare resources: %s\n", err)
+		return 2
+	}
+	exitCode := 0
+	for _, diff := range diffs {
+		resource := fmt.Sprintf("%s %s/%s", diff.Kind, diff.Namespace, diff.Name)
+		switch {
+		case diff.Err != nil:
+			fmt.Printf("%s: unable to compare: %s\n", resource, diff.Err)
+			exitCode = 2
+		case diff.Missing:
+			fmt.Printf("%s: missing in Humio\n", resource)
+		case diff.Diff != "":
+			fmt.Printf("%s: differs (-humio +expected)\n%s\n", resource, diff.Diff)
+		default:
+			fmt.Printf("%s: in sync\n", resource)
+		}
+		if !diff.InSync() && exitCode == 0 {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
@@ -0,0 +1,332 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conformance verifies that a deployed operator manages entities in a Humio cluster. It creates a set of
+// canary resources, waits for the operator to create them in Humio, updates one of them, deletes them again and
+// reports the outcome of each step. It is meant to be run as a smoke test, e.g. after upgrading the operator.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+const (
+	// DefaultTimeout is how long each step waits for the operator by default
+	DefaultTimeout = 2 * time.Minute
+	// DefaultPollInterval is how often the resources are checked while waiting for the operator by default
+	DefaultPollInterval = 2 * time.Second
+
+	canaryPrefix = "conformance-canary-"
+)
+
+// Options configures a conformance check
+type Options struct {
+	// Namespace is the namespace the canary resources are created in
+	Namespace string
+	// ManagedClusterName is the name of the HumioCluster the canary resources are created in. Exactly one of
+	// ManagedClusterName and ExternalClusterName must be set.
+	ManagedClusterName string
+	// ExternalClusterName is the name of the HumioExternalCluster the canary resources are created in
+	ExternalClusterName string
+	// Timeout is how long each step waits for the operator. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// PollInterval is how often the resources are checked while waiting for the operator. Defaults to
+	// DefaultPollInterval.
+	PollInterval time.Duration
+}
+
+// Result is the outcome of a single step of a conformance check
+type Result struct {
+	Step     string
+	Duration time.Duration
+	Err      error
+}
+
+// Passed returns whether the step succeeded
+func (r Result) Passed() bool {
+	return r.Err == nil
+}
+
+// Report holds the results of the steps of a conformance check in the order they ran
+type Report struct {
+	Results []Result
+}
+
+// Passed returns whether all steps succeeded
+func (r Report) Passed() bool {
+	for _, result := range r.Results {
+		if !result.Passed() {
+			return false
+		}
+	}
+	return len(r.Results) > 0
+}
+
+// Write writes a line per step followed by a summary
+func (r Report) Write(w io.Writer) error {
+	failed := 0
+	for _, result := range r.Results {
+		line := fmt.Sprintf("PASS  %s (%s)\n", result.Step, result.Duration.Round(time.Millisecond))
+		if !result.Passed() {
+			failed++
+			line = fmt.Sprintf("FAIL  %s (%s): %s\n", result.Step, result.Duration.Round(time.Millisecond), result.Err)
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	summary := fmt.Sprintf("\nconformance check passed: %d steps\n", len(r.Results))
+	if !r.Passed() {
+		summary = fmt.Sprintf("\nconformance check failed: %d of %d steps failed\n", failed, len(r.Results))
+	}
+	_, err := io.WriteString(w, summary)
+	return err
+}
+
+type checker struct {
+	client client.Client
+	opts   Options
+	report Report
+	// created holds the canary resources in the order they were created, so they can be deleted in reverse order
+	created []client.Object
+}
+
+// Run runs a conformance check against the operator managing the cluster given in the options. It creates a canary
+// repository, parser, action and alert, waits for each of them to exist in Humio, updates the parser and waits for the
+// update to be synced. Finally, it deletes all canary resources which were created, also when an earlier step failed,
+// and waits for the operator to remove them from Humio.
+func Run(ctx context.Context, c client.Client, opts Options) Report {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	ch := &checker{client: c, opts: opts}
+	if (opts.ManagedClusterName == "") == (opts.ExternalClusterName == "") {
+		ch.step("validate options", func() error {
+			return errors.New("must specify exactly one of the managed cluster name and the external cluster name")
+		})
+		return ch.report
+	}
+
+	name := canaryPrefix + utilrand.String(6)
+	repository := &humiov1alpha1.HumioRepository{
+		ObjectMeta: ch.objectMeta(name),
+		Spec: humiov1alpha1.HumioRepositorySpec{
+			ManagedClusterName:  opts.ManagedClusterName,
+			ExternalClusterName: opts.ExternalClusterName,
+			Name:                name,
+			Description:         "Created by the humio-operator conformance check",
+			Retention:           humiov1alpha1.HumioRetention{TimeInDays: 1},
+			AllowDataDeletion:   true,
+		},
+	}
+	parser := &humiov1alpha1.HumioParser{
+		ObjectMeta: ch.objectMeta(name),
+		Spec: humiov1alpha1.HumioParserSpec{
+			ManagedClusterName:  opts.ManagedClusterName,
+			ExternalClusterName: opts.ExternalClusterName,
+			Name:                name,
+			RepositoryName:      name,
+			ParserScript:        "kvParse()",
+			TestData:            []string{"canary=true"},
+		},
+	}
+	action := &humiov1alpha1.HumioAction{
+		ObjectMeta: ch.objectMeta(name),
+		Spec: humiov1alpha1.HumioActionSpec{
+			ManagedClusterName:  opts.ManagedClusterName,
+			ExternalClusterName: opts.ExternalClusterName,
+			Name:                name,
+			ViewName:            name,
+			EmailProperties: &humiov1alpha1.HumioActionEmailProperties{
+				Recipients: []string{"canary@example.com"},
+			},
+		},
+	}
+	alert := &humiov1alpha1.HumioAlert{
+		ObjectMeta: ch.objectMeta(name),
+		Spec: humiov1alpha1.HumioAlertSpec{
+			ManagedClusterName:  opts.ManagedClusterName,
+			ExternalClusterName: opts.ExternalClusterName,
+			Name:                name,
+			ViewName:            name,
+			Query:               humiov1alpha1.HumioQuery{QueryString: "canary=true", Start: "1h"},
+			// The alert is silenced so the canary never sends any notifications
+			Silenced: true,
+			Actions:  []string{name},
+		},
+	}
+
+	if ch.createAll(ctx, repository, parser, action, alert) {
+		ch.updateParser(ctx, parser)
+	}
+	ch.cleanup(ctx)
+	return ch.report
+}
+
+func (ch *checker) objectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: ch.opts.Namespace,
+		Labels:    map[string]string{"app.kubernetes.io/created-by": "humio-operator-conformance"},
+	}
+}
+
+// step runs fn and records its outcome in the report
+func (ch *checker) step(name string, fn func() error) bool {
+	start := time.Now()
+	err := fn()
+	ch.report.Results = append(ch.report.Results, Result{Step: name, Duration: time.Since(start), Err: err})
+	return err == nil
+}
+
+// createAll creates the given resources in order and stops at the first one which does not come to exist in Humio
+func (ch *checker) createAll(ctx context.Context, objs ...client.Object) bool {
+	for _, obj := range objs {
+		if !ch.create(ctx, obj) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ch *checker) create(ctx context.Context, obj client.Object) bool {
+	return ch.step(fmt.Sprintf("create %s %s", kind(obj), obj.GetName()), func() error {
+		if err := ch.client.Create(ctx, obj); err != nil {
+			return err
+		}
+		ch.created = append(ch.created, obj)
+		return ch.waitFor(ctx, obj, func(found bool) (bool, string) {
+			if !found {
+				return false, "not found"
+			}
+			state, reason := entityState(obj)
+			return state == "Exists", describeState(state, reason)
+		})
+	})
+}
+
+func (ch *checker) updateParser(ctx context.Context, parser *humiov1alpha1.HumioParser) bool {
+	return ch.step(fmt.Sprintf("update %s %s", kind(parser), parser.GetName()), func() error {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			if err := ch.client.Get(ctx, client.ObjectKeyFromObject(parser), parser); err != nil {
+				return err
+			}
+			parser.Spec.ParserScript = "kvParse() | canary := lower(canary)"
+			return ch.client.Update(ctx, parser)
+		})
+		if err != nil {
+			return err
+		}
+		return ch.waitFor(ctx, parser, func(found bool) (bool, string) {
+			if !found {
+				return false, "not found"
+			}
+			synced := parser.Status.SyncedGeneration == parser.Generation && parser.Status.State == humiov1alpha1.HumioParserStateExists
+			return synced, fmt.Sprintf("generation %d synced up to generation %d, %s",
+				parser.Generation, parser.Status.SyncedGeneration, describeState(parser.Status.State, parser.Status.Reason))
+		})
+	})
+}
+
+// cleanup deletes the canary resources in reverse order of creation, so e.g. the alert is removed before the action
+// it references. The operator removes the finalizers once the entities are deleted in Humio, so a resource being gone
+// from Kubernetes means it is gone from Humio as well.
+func (ch *checker) cleanup(ctx context.Context) {
+	for i := len(ch.created) - 1; i >= 0; i-- {
+		obj := ch.created[i]
+		ch.step(fmt.Sprintf("delete %s %s", kind(obj), obj.GetName()), func() error {
+			if err := ch.client.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			return ch.waitFor(ctx, obj, func(found bool) (bool, string) {
+				return !found, fmt.Sprintf("still exists with finalizers %v", obj.GetFinalizers())
+			})
+		})
+	}
+}
+
+// waitFor fetches obj until done returns true or the timeout expires. done is told whether obj was found. On timeout,
+// the error includes the last description returned by done.
+func (ch *checker) waitFor(ctx context.Context, obj client.Object, done func(found bool) (bool, string)) error {
+	var description string
+	err := wait.PollUntilContextTimeout(ctx, ch.opts.PollInterval, ch.opts.Timeout, true, func(ctx context.Context) (bool, error) {
+		err := ch.client.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if client.IgnoreNotFound(err) != nil {
+			description = err.Error()
+			return false, nil
+		}
+		var ok bool
+		ok, description = done(err == nil)
+		return ok, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("timed out after %s: %s", ch.opts.Timeout, description)
+	}
+	return err
+}
+
+func kind(obj client.Object) string {
+	switch obj.(type) {
+	case *humiov1alpha1.HumioRepository:
+		return "HumioRepository"
+	case *humiov1alpha1.HumioParser:
+		return "HumioParser"
+	case *humiov1alpha1.HumioAction:
+		return "HumioAction"
+	case *humiov1alpha1.HumioAlert:
+		return "HumioAlert"
+	}
+	return fmt.Sprintf("%T", obj)
+}
+
+func entityState(obj client.Object) (string, string) {
+	switch o := obj.(type) {
+	case *humiov1alpha1.HumioRepository:
+		return o.Status.State, o.Status.Reason
+	case *humiov1alpha1.HumioParser:
+		return o.Status.State, o.Status.Reason
+	case *humiov1alpha1.HumioAction:
+		return o.Status.State, o.Status.Reason
+	case *humiov1alpha1.HumioAlert:
+		return o.Status.State, o.Status.Reason
+	}
+	return "", ""
+}
+
+func describeState(state, reason string) string {
+	if state == "" {
+		return "no state reported yet"
+	}
+	if reason == "" {
+		return fmt.Sprintf("state is %s", state)
+	}
+	return fmt.Sprintf("state is %s: %s", state, reason)
+}
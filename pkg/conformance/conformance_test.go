@@ -0,0 +1,118 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+// operatorClient marks every resource as existing when it is created, like the operator would
+type operatorClient struct {
+	client.Client
+}
+
+func (c operatorClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	switch o := obj.(type) {
+	case *humiov1alpha1.HumioRepository:
+		o.Status.State = humiov1alpha1.HumioRepositoryStateExists
+	case *humiov1alpha1.HumioParser:
+		o.Status.State = humiov1alpha1.HumioParserStateExists
+	case *humiov1alpha1.HumioAction:
+		o.Status.State = humiov1alpha1.HumioActionStateExists
+	case *humiov1alpha1.HumioAlert:
+		o.Status.State = humiov1alpha1.HumioAlertStateExists
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestRun(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = humiov1alpha1.AddToScheme(scheme)
+
+	tests := []struct {
+		name       string
+		operator   bool
+		opts       Options
+		wantSteps  []string
+		wantFailed string
+	}{
+		{
+			name:       "no cluster name",
+			opts:       Options{Namespace: "default"},
+			wantSteps:  []string{"validate options"},
+			wantFailed: "must specify exactly one",
+		},
+		{
+			name:       "operator not running",
+			opts:       Options{Namespace: "default", ManagedClusterName: "humiocluster"},
+			wantSteps:  []string{"create HumioRepository", "delete HumioRepository"},
+			wantFailed: "timed out after 50ms: no state reported yet",
+		},
+		{
+			name:     "operator running",
+			operator: true,
+			opts:     Options{Namespace: "default", ExternalClusterName: "humiocluster"},
+			wantSteps: []string{
+				"create HumioRepository", "create HumioParser", "create HumioAction", "create HumioAlert",
+				"update HumioParser",
+				"delete HumioAlert", "delete HumioAction", "delete HumioParser", "delete HumioRepository",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c client.Client = fake.NewClientBuilder().WithScheme(scheme).Build()
+			if tt.operator {
+				c = operatorClient{c}
+			}
+			tt.opts.Timeout = 50 * time.Millisecond
+			tt.opts.PollInterval = 10 * time.Millisecond
+
+			report := Run(context.Background(), c, tt.opts)
+			if len(report.Results) != len(tt.wantSteps) {
+				t.Fatalf("expected steps %v, got %+v", tt.wantSteps, report.Results)
+			}
+			for i, result := range report.Results {
+				if !strings.HasPrefix(result.Step, tt.wantSteps[i]) {
+					t.Errorf("expected step %d to be %q, got %q", i, tt.wantSteps[i], result.Step)
+				}
+			}
+			if tt.wantFailed == "" {
+				if !report.Passed() {
+					t.Errorf("expected the check to pass, got %+v", report.Results)
+				}
+				return
+			}
+			if report.Passed() || report.Results[0].Err == nil || !strings.Contains(report.Results[0].Err.Error(), tt.wantFailed) {
+				t.Errorf("expected the first step to fail with %q, got %+v", tt.wantFailed, report.Results)
+			}
+		})
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	report := Report{Results: []Result{
+		{Step: "create HumioRepository canary", Duration: 1500 * time.Millisecond},
+		{Step: "create HumioParser canary", Duration: time.Second, Err: errors.New("timed out")},
+	}}
+	var out strings.Builder
+	if err := report.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "PASS  create HumioRepository canary (1.5s)\n" +
+		"FAIL  create HumioParser canary (1s): timed out\n" +
+		"\nconformance check failed: 1 of 2 steps failed\n"
+	if out.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"sync"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clientCertificates holds the client certificate to present to each Humio cluster host. The Humio API config has no
+// field for client certificates, so transports look up the certificate during every TLS handshake. This also means
+// rotated certificates are used for new connections as soon as the configuration of the cluster is constructed again.
+var (
+	clientCertificates      = map[string]*tls.Certificate{}
+	clientCertificatesMutex sync.RWMutex
+)
+
+// GetClientCertificate returns a callback for tls.Config which presents the client certificate configured for the
+// given Humio cluster host, if any
+func GetClientCertificate(host string) func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		clientCertificatesMutex.RLock()
+		defer clientCertificatesMutex.RUnlock()
+		if cert, ok := clientCertificates[host]; ok {
+			return cert, nil
+		}
+		// An empty certificate tells the server we have no client certificate
+		return &tls.Certificate{}, nil
+	}
+}
+
+// GetClientCertificateFingerprint returns a fingerprint of the client certificate configured for the given Humio
+// cluster host, or an empty string if there is none. This is used to detect when the client certificate is rotated.
+func GetClientCertificateFingerprint(host string) string {
+	clientCertificatesMutex.RLock()
+	defer clientCertificatesMutex.RUnlock()
+	cert, ok := clientCertificates[host]
+	if !ok || len(cert.Certificate) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(cert.Certificate[0]))
+}
+
+// setClientCertificate loads the client certificate of the given HumioExternalCluster and makes it available for
+// connections towards all URLs of the cluster. The secret must be of type kubernetes.io/tls.
+func setClientCertificate(ctx context.Context, k8sClient client.Client, hec *humiov1alpha1.HumioExternalCluster) error {
+	var cert *tls.Certificate
+	if hec.Spec.ClientCertificateSecretName != "" {
+		var clientCertificate corev1.Secret
+		err := k8sClient.Get(ctx, types.NamespacedName{
+			Namespace: hec.Namespace,
+			Name:      hec.Spec.ClientCertificateSecretName,
+		}, &clientCertificate)
+		if err != nil {
+			return fmt.Errorf("unable to get secret containing client certificate: %w", err)
+		}
+		keyPair, err := tls.X509KeyPair(clientCertificate.Data[corev1.TLSCertKey], clientCertificate.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			return fmt.Errorf("unable to load client certificate from secret %s: %w", hec.Spec.ClientCertificateSecretName, err)
+		}
+		cert = &keyPair
+	}
+
+	clientCertificatesMutex.Lock()
+	defer clientCertificatesMutex.Unlock()
+	for _, externalClusterUrl := range ExternalClusterUrls(hec) {
+		u, err := url.Parse(externalClusterUrl)
+		if err != nil {
+			return err
+		}
+		if cert == nil {
+			delete(clientCertificates, u.Host)
+			continue
+		}
+		clientCertificates[u.Host] = cert
+	}
+	return nil
+}
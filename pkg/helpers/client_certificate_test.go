@@ -0,0 +1,116 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func generateClientCertificate(t *testing.T) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %s", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "humio-operator"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %s", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unable to marshal key: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestCluster_ExternalClusterClientCertificate(t *testing.T) {
+	certPEM, keyPEM := generateClientCertificate(t)
+	externalHumioCluster := humiov1alpha1.HumioExternalCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mtls-external-cluster",
+			Namespace: "namespace",
+		},
+		Spec: humiov1alpha1.HumioExternalClusterSpec{
+			Url:                         "https://mtls.example.com/",
+			FailoverUrls:                []string{"https://mtls-failover.example.com:8443/"},
+			APITokenSecretName:          "mtls-api-token",
+			ClientCertificateSecretName: "mtls-client-certificate",
+		},
+	}
+	apiTokenSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mtls-api-token",
+			Namespace: "namespace",
+		},
+		Data: map[string][]byte{
+			"token": []byte("token"),
+		},
+	}
+	clientCertificate := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mtls-client-certificate",
+			Namespace: "namespace",
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(humiov1alpha1.GroupVersion, &externalHumioCluster)
+	cl := fake.NewClientBuilder().WithRuntimeObjects(&externalHumioCluster, &apiTokenSecret, &clientCertificate).Build()
+
+	_, err := NewCluster(context.Background(), cl, "", externalHumioCluster.Name, externalHumioCluster.Namespace, false, true)
+	if err != nil {
+		t.Fatalf("unable to obtain humio client config: %s", err)
+	}
+
+	for _, host := range []string{"mtls.example.com", "mtls-failover.example.com:8443"} {
+		cert, err := GetClientCertificate(host)(nil)
+		if err != nil || len(cert.Certificate) != 1 {
+			t.Errorf("expected client certificate for %s, got %v (%v)", host, cert, err)
+		}
+	}
+	cert, err := GetClientCertificate("other.example.com")(nil)
+	if err != nil || len(cert.Certificate) != 0 {
+		t.Errorf("expected no client certificate for other hosts, got %v (%v)", cert, err)
+	}
+}
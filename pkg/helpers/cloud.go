@@ -0,0 +1,64 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// logScaleCloudDomains maps the regions of LogScale Cloud to the domain hosting the clusters of the region
+var logScaleCloudDomains = map[string]string{
+	"eu":        "humio.com",
+	"us":        "us.humio.com",
+	"community": "community.humio.com",
+}
+
+// ExternalClusterCloudUrl returns the URL of the LogScale Cloud cluster described by the given configuration
+func ExternalClusterCloudUrl(cloud *humiov1alpha1.HumioExternalClusterCloud) (string, error) {
+	domain, ok := logScaleCloudDomains[cloud.Region]
+	if !ok {
+		return "", fmt.Errorf("unknown LogScale Cloud region %q", cloud.Region)
+	}
+	subdomain := "cloud"
+	if cloud.Tenant != "" {
+		if errs := validation.IsDNS1123Label(cloud.Tenant); len(errs) > 0 {
+			return "", fmt.Errorf("invalid LogScale Cloud tenant %q: %s", cloud.Tenant, strings.Join(errs, ", "))
+		}
+		subdomain = cloud.Tenant
+	}
+	return fmt.Sprintf("https://%s.%s/", subdomain, domain), nil
+}
+
+// validateExternalClusterCloud returns an error if the LogScale Cloud configuration of the given HumioExternalCluster
+// cannot be used
+func validateExternalClusterCloud(hec *humiov1alpha1.HumioExternalCluster) error {
+	if hec.Spec.Cloud == nil {
+		return nil
+	}
+	if hec.Spec.Url != "" {
+		return fmt.Errorf("cannot have both url and cloud configuration set at the same time")
+	}
+	if hec.Spec.Cloud.OrganizationToken && hec.Spec.APITokenRotation != nil {
+		return fmt.Errorf("api token rotation is not possible when using an organization api token")
+	}
+	_, err := ExternalClusterCloudUrl(hec.Spec.Cloud)
+	return err
+}
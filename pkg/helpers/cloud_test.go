@@ -0,0 +1,140 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"context"
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestExternalClusterCloudUrl(t *testing.T) {
+	tests := []struct {
+		name        string
+		cloud       humiov1alpha1.HumioExternalClusterCloud
+		expectedUrl string
+		expectError bool
+	}{
+		{"eu region", humiov1alpha1.HumioExternalClusterCloud{Region: "eu"}, "https://cloud.humio.com/", false},
+		{"us region", humiov1alpha1.HumioExternalClusterCloud{Region: "us"}, "https://cloud.us.humio.com/", false},
+		{"community region", humiov1alpha1.HumioExternalClusterCloud{Region: "community"}, "https://cloud.community.humio.com/", false},
+		{"dedicated tenant", humiov1alpha1.HumioExternalClusterCloud{Region: "us", Tenant: "acme"}, "https://acme.us.humio.com/", false},
+		{"unknown region", humiov1alpha1.HumioExternalClusterCloud{Region: "mars"}, "", true},
+		{"invalid tenant", humiov1alpha1.HumioExternalClusterCloud{Region: "eu", Tenant: "acme.evil.com/"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExternalClusterCloudUrl(&tt.cloud)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("ExternalClusterCloudUrl() error = %v, expectError %v", err, tt.expectError)
+			}
+			if got != tt.expectedUrl {
+				t.Errorf("ExternalClusterCloudUrl() = %s, want %s", got, tt.expectedUrl)
+			}
+		})
+	}
+}
+
+func TestCluster_ExternalClusterCloud(t *testing.T) {
+	apiTokenSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cloud-api-token",
+			Namespace: "namespace",
+		},
+		Data: map[string][]byte{
+			"token": []byte("token"),
+		},
+	}
+
+	tests := []struct {
+		name                 string
+		spec                 humiov1alpha1.HumioExternalClusterSpec
+		expectedAddress      string
+		expectedOrganization string
+		expectError          bool
+	}{
+		{
+			"organization in region",
+			humiov1alpha1.HumioExternalClusterSpec{
+				Cloud:              &humiov1alpha1.HumioExternalClusterCloud{Region: "eu", Organization: "acme"},
+				APITokenSecretName: "cloud-api-token",
+			},
+			"https://cloud.humio.com/",
+			"acme",
+			false,
+		},
+		{
+			"url and cloud set",
+			humiov1alpha1.HumioExternalClusterSpec{
+				Url:                "https://humio.example.com/",
+				Cloud:              &humiov1alpha1.HumioExternalClusterCloud{Region: "eu"},
+				APITokenSecretName: "cloud-api-token",
+			},
+			"",
+			"",
+			true,
+		},
+		{
+			"rotation of organization token",
+			humiov1alpha1.HumioExternalClusterSpec{
+				Cloud:              &humiov1alpha1.HumioExternalClusterCloud{Region: "eu", OrganizationToken: true},
+				APITokenSecretName: "cloud-api-token",
+				APITokenRotation: &humiov1alpha1.HumioExternalClusterAPITokenRotation{
+					IntervalSeconds:          3600,
+					BootstrapTokenSecretName: "bootstrap-token",
+				},
+			},
+			"",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			externalHumioCluster := humiov1alpha1.HumioExternalCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "cloud-external-cluster",
+					Namespace: "namespace",
+				},
+				Spec: tt.spec,
+			}
+
+			s := scheme.Scheme
+			s.AddKnownTypes(humiov1alpha1.GroupVersion, &externalHumioCluster)
+			cl := fake.NewClientBuilder().WithRuntimeObjects(&externalHumioCluster, &apiTokenSecret).Build()
+
+			cluster, err := NewCluster(context.Background(), cl, "", externalHumioCluster.Name, externalHumioCluster.Namespace, false, true)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("NewCluster() error = %v, expectError %v", err, tt.expectError)
+			}
+			if tt.expectError {
+				return
+			}
+			if cluster.Config().Address.String() != tt.expectedAddress {
+				t.Errorf("expected address %s, got %s", tt.expectedAddress, cluster.Config().Address)
+			}
+			if cluster.Config().ProxyOrganization != tt.expectedOrganization {
+				t.Errorf("expected organization %s, got %s", tt.expectedOrganization, cluster.Config().ProxyOrganization)
+			}
+		})
+	}
+}
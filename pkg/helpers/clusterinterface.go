@@ -18,6 +18,7 @@ package helpers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -32,6 +33,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// ErrExternalClusterUnavailable is returned when constructing the configuration for a HumioExternalCluster whose last
+// health check failed
+var ErrExternalClusterUnavailable = errors.New("external humio cluster unavailable")
+
 type ClusterInterface interface {
 	Url(context.Context, client.Client) (*url.URL, error)
 	Name() string
@@ -45,6 +50,7 @@ type Cluster struct {
 	namespace           string
 	certManagerEnabled  bool
 	withAPIToken        bool
+	ignoreHealth        bool
 	humioConfig         *humioapi.Config
 }
 
@@ -76,6 +82,31 @@ func NewCluster(ctx context.Context, k8sClient client.Client, managedClusterName
 	return cluster, nil
 }
 
+// NewExternalCluster returns the cluster for the given HumioExternalCluster without checking the result of its last
+// health check. This is used when performing the health check.
+func NewExternalCluster(ctx context.Context, k8sClient client.Client, externalClusterName, namespace string) (ClusterInterface, error) {
+	if externalClusterName == "" {
+		return nil, fmt.Errorf("must have ExternalClusterName set")
+	}
+	if namespace == "" {
+		return nil, fmt.Errorf("must have non-empty namespace set")
+	}
+	cluster := Cluster{
+		externalClusterName: externalClusterName,
+		namespace:           namespace,
+		withAPIToken:        true,
+		ignoreHealth:        true,
+	}
+
+	humioConfig, err := cluster.constructHumioConfig(ctx, k8sClient, true)
+	if err != nil {
+		return nil, err
+	}
+	cluster.humioConfig = humioConfig
+
+	return cluster, nil
+}
+
 func (c Cluster) Url(ctx context.Context, k8sClient client.Client) (*url.URL, error) {
 	if c.managedClusterName != "" {
 		// Lookup ManagedHumioCluster resource to figure out if we expect to use TLS or not
@@ -111,13 +142,32 @@ func (c Cluster) Url(ctx context.Context, k8sClient client.Client) (*url.URL, er
 		return nil, err
 	}
 
-	baseURL, err := url.Parse(humioExternalCluster.Spec.Url)
+	baseURL, err := url.Parse(ActiveExternalClusterUrl(&humioExternalCluster))
 	if err != nil {
 		return nil, err
 	}
 	return baseURL, nil
 }
 
+// ExternalClusterUrls returns all URLs of the given HumioExternalCluster in the order they should be tried
+func ExternalClusterUrls(hec *humiov1alpha1.HumioExternalCluster) []string {
+	primaryUrl := hec.Spec.Url
+	if hec.Spec.Cloud != nil {
+		// An invalid cloud configuration is reported when constructing the configuration of the cluster
+		primaryUrl, _ = ExternalClusterCloudUrl(hec.Spec.Cloud)
+	}
+	return append([]string{primaryUrl}, hec.Spec.FailoverUrls...)
+}
+
+// ActiveExternalClusterUrl returns the URL of the given HumioExternalCluster which passed the last health check,
+// falling back to the primary URL
+func ActiveExternalClusterUrl(hec *humiov1alpha1.HumioExternalCluster) string {
+	if hec.Status.ActiveUrl != "" && ContainsElement(ExternalClusterUrls(hec), hec.Status.ActiveUrl) {
+		return hec.Status.ActiveUrl
+	}
+	return ExternalClusterUrls(hec)[0]
+}
+
 // Name returns the name of the Humio cluster
 func (c Cluster) Name() string {
 	if c.managedClusterName != "" {
@@ -153,6 +203,7 @@ func (c Cluster) constructHumioConfig(ctx context.Context, k8sClient client.Clie
 		config := &humioapi.Config{
 			Address: clusterURL,
 		}
+		setRequestTimeouts(clusterURL.Host, humioManagedCluster.Spec.APITimeouts)
 
 		var apiToken corev1.Secret
 		if withAPIToken {
@@ -197,42 +248,97 @@ func (c Cluster) constructHumioConfig(ctx context.Context, k8sClient client.Clie
 		return nil, err
 	}
 
-	if humioExternalCluster.Spec.Url == "" {
+	if err := validateExternalClusterCloud(&humioExternalCluster); err != nil {
+		return nil, err
+	}
+
+	if humioExternalCluster.Spec.Url == "" && humioExternalCluster.Spec.Cloud == nil {
 		return nil, fmt.Errorf("no url specified")
 	}
 
-	if humioExternalCluster.Spec.APITokenSecretName == "" {
-		return nil, fmt.Errorf("no api token secret name specified")
+	if !c.ignoreHealth {
+		switch humioExternalCluster.Status.State {
+		case humiov1alpha1.HumioExternalClusterStateUnreachable, humiov1alpha1.HumioExternalClusterStateUnauthorized:
+			return nil, fmt.Errorf("%w: %s is %s: %s", ErrExternalClusterUnavailable, c.externalClusterName,
+				strings.ToLower(humioExternalCluster.Status.State), humioExternalCluster.Status.Message)
+		}
+	}
+
+	if humioExternalCluster.Spec.APITokenSecretName == "" && humioExternalCluster.Spec.OAuth2 == nil {
+		return nil, fmt.Errorf("no api token secret name or oauth2 configuration specified")
 	}
 
-	if strings.HasPrefix(humioExternalCluster.Spec.Url, "http://") && !humioExternalCluster.Spec.Insecure {
-		return nil, fmt.Errorf("not possible to run secure cluster with plain http")
+	if humioExternalCluster.Spec.APITokenSecretName != "" && humioExternalCluster.Spec.OAuth2 != nil {
+		return nil, fmt.Errorf("cannot have both api token secret name and oauth2 configuration set at the same time")
+	}
+
+	for _, externalClusterUrl := range ExternalClusterUrls(&humioExternalCluster) {
+		if strings.HasPrefix(externalClusterUrl, "http://") && !humioExternalCluster.Spec.Insecure {
+			return nil, fmt.Errorf("not possible to run secure cluster with plain http")
+		}
+		u, err := url.Parse(externalClusterUrl)
+		if err != nil {
+			return nil, err
+		}
+		setRequestTimeouts(u.Host, humioExternalCluster.Spec.APITimeouts)
 	}
 
 	// Get API token
-	var apiToken corev1.Secret
-	err = k8sClient.Get(ctx, types.NamespacedName{
-		Namespace: c.namespace,
-		Name:      humioExternalCluster.Spec.APITokenSecretName,
-	}, &apiToken)
+	var token string
+	if humioExternalCluster.Spec.OAuth2 != nil {
+		token, err = getOAuth2AccessToken(ctx, k8sClient, &humioExternalCluster)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var apiToken corev1.Secret
+		err = k8sClient.Get(ctx, types.NamespacedName{
+			Namespace: c.namespace,
+			Name:      humioExternalCluster.Spec.APITokenSecretName,
+		}, &apiToken)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get secret containing api token: %w", err)
+		}
+		token = string(apiToken.Data["token"])
+	}
+
+	clusterURL, err := url.Parse(ActiveExternalClusterUrl(&humioExternalCluster))
+	if err != nil {
+		return nil, err
+	}
+
+	// The dialer is always set, so changes to the proxy configuration are also honored by transports that are kept
+	// across reconciles
+	proxyDialer, err := getProxyDialer(ctx, k8sClient, &humioExternalCluster, clusterURL)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get secret containing api token: %w", err)
+		return nil, err
 	}
 
-	clusterURL, err := url.Parse(humioExternalCluster.Spec.Url)
+	err = setClientCertificate(ctx, k8sClient, &humioExternalCluster)
 	if err != nil {
 		return nil, err
 	}
 
+	var organization string
+	if humioExternalCluster.Spec.Cloud != nil {
+		organization = humioExternalCluster.Spec.Cloud.Organization
+	}
+
 	// If we do not use TLS, return a config without CA certificate
 	if humioExternalCluster.Spec.Insecure {
 		return &humioapi.Config{
-			Address:  clusterURL,
-			Token:    string(apiToken.Data["token"]),
-			Insecure: humioExternalCluster.Spec.Insecure,
+			Address:           clusterURL,
+			ProxyOrganization: organization,
+			Token:             token,
+			Insecure:          humioExternalCluster.Spec.Insecure,
+			DialContext:       proxyDialer.DialContext,
 		}, nil
 	}
 
+	if humioExternalCluster.Spec.CASecretName != "" && humioExternalCluster.Spec.CAConfigMapName != "" {
+		return nil, fmt.Errorf("cannot have both CA secret name and CA config map name set at the same time")
+	}
+
 	// If CA secret is specified, return a configuration which loads the CA
 	if humioExternalCluster.Spec.CASecretName != "" {
 		var caCertificate corev1.Secret
@@ -244,16 +350,74 @@ func (c Cluster) constructHumioConfig(ctx context.Context, k8sClient client.Clie
 			return nil, fmt.Errorf("unable to get CA certificate: %w", err)
 		}
 		return &humioapi.Config{
-			Address:          clusterURL,
-			Token:            string(apiToken.Data["token"]),
-			CACertificatePEM: string(caCertificate.Data["ca.crt"]),
-			Insecure:         humioExternalCluster.Spec.Insecure,
+			Address:           clusterURL,
+			ProxyOrganization: organization,
+			Token:             token,
+			CACertificatePEM:  string(caCertificate.Data["ca.crt"]),
+			Insecure:          humioExternalCluster.Spec.Insecure,
+			DialContext:       proxyDialer.DialContext,
+		}, nil
+	}
+
+	// If CA config map is specified, return a configuration which loads the CA bundle
+	if humioExternalCluster.Spec.CAConfigMapName != "" {
+		var caBundle corev1.ConfigMap
+		err = k8sClient.Get(ctx, types.NamespacedName{
+			Namespace: c.namespace,
+			Name:      humioExternalCluster.Spec.CAConfigMapName,
+		}, &caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get CA bundle: %w", err)
+		}
+		caBundleKey := humioExternalCluster.Spec.CAConfigMapKey
+		if caBundleKey == "" {
+			caBundleKey = "ca.crt"
+		}
+		if caBundle.Data[caBundleKey] == "" {
+			return nil, fmt.Errorf("config map %s does not contain a CA bundle in key %q", humioExternalCluster.Spec.CAConfigMapName, caBundleKey)
+		}
+		return &humioapi.Config{
+			Address:           clusterURL,
+			ProxyOrganization: organization,
+			Token:             token,
+			CACertificatePEM:  caBundle.Data[caBundleKey],
+			Insecure:          humioExternalCluster.Spec.Insecure,
+			DialContext:       proxyDialer.DialContext,
 		}, nil
 	}
 
 	return &humioapi.Config{
-		Address:  clusterURL,
-		Token:    string(apiToken.Data["token"]),
-		Insecure: humioExternalCluster.Spec.Insecure,
+		Address:           clusterURL,
+		ProxyOrganization: organization,
+		Token:             token,
+		Insecure:          humioExternalCluster.Spec.Insecure,
+		DialContext:       proxyDialer.DialContext,
 	}, nil
 }
+
+// SetAPITokenFromSecret replaces the API token in the configuration of the cluster with the API token stored in the
+// given secret. The secret must contain a key "token" which holds the Humio API token. If secretName is empty, the
+// configuration is left untouched.
+func SetAPITokenFromSecret(ctx context.Context, k8sClient client.Client, cluster ClusterInterface, namespace, secretName string) error {
+	if secretName == "" {
+		return nil
+	}
+	if cluster == nil || cluster.Config() == nil {
+		return fmt.Errorf("no cluster configuration to set api token for")
+	}
+
+	var apiToken corev1.Secret
+	err := k8sClient.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      secretName,
+	}, &apiToken)
+	if err != nil {
+		return fmt.Errorf("unable to get secret containing api token: %w", err)
+	}
+	token, found := apiToken.Data["token"]
+	if !found || len(token) == 0 {
+		return fmt.Errorf("secret %s does not contain an api token in key \"token\"", secretName)
+	}
+	cluster.Config().Token = string(token)
+	return nil
+}
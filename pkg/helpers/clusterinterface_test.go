@@ -18,6 +18,7 @@ package helpers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"testing"
@@ -507,3 +508,188 @@ func TestCluster_NewCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestCluster_SetAPITokenFromSecret(t *testing.T) {
+	externalHumioCluster := humiov1alpha1.HumioExternalCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "external-cluster",
+			Namespace: "namespace",
+		},
+		Spec: humiov1alpha1.HumioExternalClusterSpec{
+			Url:                "https://humio.example.com/",
+			APITokenSecretName: "cluster-api-token",
+		},
+	}
+	clusterAPITokenSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-api-token",
+			Namespace: "namespace",
+		},
+		Data: map[string][]byte{
+			"token": []byte("cluster-token"),
+		},
+	}
+	scopedAPITokenSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "scoped-api-token",
+			Namespace: "namespace",
+		},
+		Data: map[string][]byte{
+			"token": []byte("scoped-token"),
+		},
+	}
+	emptyAPITokenSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "empty-api-token",
+			Namespace: "namespace",
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(humiov1alpha1.GroupVersion, &externalHumioCluster)
+	cl := fake.NewClientBuilder().WithRuntimeObjects(&externalHumioCluster, &clusterAPITokenSecret, &scopedAPITokenSecret, &emptyAPITokenSecret).Build()
+
+	tests := []struct {
+		name          string
+		secretName    string
+		expectedToken string
+		wantErr       bool
+	}{
+		{"no secret keeps the cluster api token", "", "cluster-token", false},
+		{"secret overrides the cluster api token", "scoped-api-token", "scoped-token", false},
+		{"missing secret", "missing-api-token", "cluster-token", true},
+		{"secret without token", "empty-api-token", "cluster-token", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := NewCluster(context.Background(), cl, "", externalHumioCluster.Name, externalHumioCluster.Namespace, false, true)
+			if err != nil {
+				t.Fatalf("unable to obtain humio client config: %s", err)
+			}
+
+			err = SetAPITokenFromSecret(context.Background(), cl, cluster, externalHumioCluster.Namespace, tt.secretName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetAPITokenFromSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if cluster.Config().Token != tt.expectedToken {
+				t.Errorf("expected api token %s, got %s", tt.expectedToken, cluster.Config().Token)
+			}
+		})
+	}
+}
+
+func TestCluster_ExternalClusterUnavailable(t *testing.T) {
+	externalHumioCluster := humiov1alpha1.HumioExternalCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "unreachable-external-cluster",
+			Namespace: "namespace",
+		},
+		Spec: humiov1alpha1.HumioExternalClusterSpec{
+			Url:                "https://humio.example.com/",
+			APITokenSecretName: "unreachable-api-token",
+		},
+		Status: humiov1alpha1.HumioExternalClusterStatus{
+			State:   humiov1alpha1.HumioExternalClusterStateUnreachable,
+			Message: "connection refused",
+		},
+	}
+	apiTokenSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "unreachable-api-token",
+			Namespace: "namespace",
+		},
+		Data: map[string][]byte{
+			"token": []byte("token"),
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(humiov1alpha1.GroupVersion, &externalHumioCluster)
+	cl := fake.NewClientBuilder().WithRuntimeObjects(&externalHumioCluster, &apiTokenSecret).Build()
+
+	_, err := NewCluster(context.Background(), cl, "", externalHumioCluster.Name, externalHumioCluster.Namespace, false, true)
+	if !errors.Is(err, ErrExternalClusterUnavailable) {
+		t.Errorf("expected %v, got %v", ErrExternalClusterUnavailable, err)
+	}
+
+	cluster, err := NewExternalCluster(context.Background(), cl, externalHumioCluster.Name, externalHumioCluster.Namespace)
+	if err != nil {
+		t.Fatalf("expected health check to obtain humio client config, got %s", err)
+	}
+	if cluster.Config().Token != "token" {
+		t.Errorf("expected api token %s, got %s", "token", cluster.Config().Token)
+	}
+}
+
+func TestCluster_ExternalClusterCAConfigMap(t *testing.T) {
+	externalHumioCluster := humiov1alpha1.HumioExternalCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ca-bundle-external-cluster",
+			Namespace: "namespace",
+		},
+		Spec: humiov1alpha1.HumioExternalClusterSpec{
+			Url:                "https://humio.example.com/",
+			APITokenSecretName: "ca-bundle-api-token",
+			CAConfigMapName:    "ca-bundle",
+			CAConfigMapKey:     "trust-bundle.pem",
+		},
+	}
+	apiTokenSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ca-bundle-api-token",
+			Namespace: "namespace",
+		},
+		Data: map[string][]byte{
+			"token": []byte("token"),
+		},
+	}
+	caBundle := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ca-bundle",
+			Namespace: "namespace",
+		},
+		Data: map[string]string{
+			"trust-bundle.pem": "-----BEGIN CERTIFICATE-----",
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(humiov1alpha1.GroupVersion, &externalHumioCluster)
+	cl := fake.NewClientBuilder().WithRuntimeObjects(&externalHumioCluster, &apiTokenSecret, &caBundle).Build()
+
+	cluster, err := NewCluster(context.Background(), cl, "", externalHumioCluster.Name, externalHumioCluster.Namespace, false, true)
+	if err != nil {
+		t.Fatalf("unable to obtain humio client config: %s", err)
+	}
+	if cluster.Config().CACertificatePEM != caBundle.Data["trust-bundle.pem"] {
+		t.Errorf("expected CA bundle %s, got %s", caBundle.Data["trust-bundle.pem"], cluster.Config().CACertificatePEM)
+	}
+}
+
+func TestActiveExternalClusterUrl(t *testing.T) {
+	tests := []struct {
+		name      string
+		activeUrl string
+		expected  string
+	}{
+		{"no active url uses primary url", "", "https://primary.example.com/"},
+		{"active failover url", "https://secondary.example.com/", "https://secondary.example.com/"},
+		{"active url no longer listed uses primary url", "https://removed.example.com/", "https://primary.example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hec := &humiov1alpha1.HumioExternalCluster{
+				Spec: humiov1alpha1.HumioExternalClusterSpec{
+					Url:          "https://primary.example.com/",
+					FailoverUrls: []string{"https://secondary.example.com/"},
+				},
+				Status: humiov1alpha1.HumioExternalClusterStatus{
+					ActiveUrl: tt.activeUrl,
+				},
+			}
+			if got := ActiveExternalClusterUrl(hec); got != tt.expected {
+				t.Errorf("ActiveExternalClusterUrl() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
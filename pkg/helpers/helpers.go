@@ -18,11 +18,15 @@ package helpers
 
 import (
 	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	graphql "github.com/cli/shurcooL-graphql"
 	uberzap "go.uber.org/zap"
@@ -108,6 +112,20 @@ func UseCertManager() bool {
 	return found && certmanagerEnabled == "true"
 }
 
+// UseHumioClientBulkListing returns whether the operator should look up alerts and actions by listing all of them in
+// the view at once
+func UseHumioClientBulkListing() bool {
+	bulkListingEnabled, found := os.LookupEnv("HUMIO_CLIENT_BULK_LISTING")
+	return found && bulkListingEnabled == "true"
+}
+
+// UseParserValidationWebhook returns whether the operator should serve the validating webhook which tests HumioParser
+// resources against the cluster before they are admitted
+func UseParserValidationWebhook() bool {
+	webhookEnabled, found := os.LookupEnv("HUMIO_OPERATOR_PARSER_VALIDATION_WEBHOOK")
+	return found && webhookEnabled == "true"
+}
+
 // TLSEnabled returns whether we a cluster should configure TLS or not
 func TLSEnabled(hc *humiov1alpha1.HumioCluster) bool {
 	if hc.Spec.TLS == nil {
@@ -123,8 +141,13 @@ func TLSEnabled(hc *humiov1alpha1.HumioCluster) bool {
 // AsSHA256 does a sha 256 hash on an object and returns the result
 func AsSHA256(o interface{}) string {
 	h := sha256.New()
-	_, _ = h.Write([]byte(fmt.Sprintf("%v", o)))
-	return fmt.Sprintf("%x", h.Sum(nil))
+	// Strings are formatted as themselves, so they skip fmt to avoid copying large inputs such as serialized pod specs
+	if s, ok := o.(string); ok {
+		_, _ = io.WriteString(h, s)
+	} else {
+		_, _ = fmt.Fprintf(h, "%v", o)
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // BoolPtr returns a bool pointer to the specified boolean value
@@ -179,3 +202,242 @@ func GetWatchNamespace() (string, error) {
 	}
 	return ns, nil
 }
+
+// GetHumioClientReadCacheTTL returns for how long results of read calls against the Humio API may be cached.
+// Caching is disabled unless HUMIO_CLIENT_READ_CACHE_TTL is set to a positive duration such as "10s".
+func GetHumioClientReadCacheTTL() (time.Duration, error) {
+	readCacheTTL, found := os.LookupEnv("HUMIO_CLIENT_READ_CACHE_TTL")
+	if !found || readCacheTTL == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(readCacheTTL)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse HUMIO_CLIENT_READ_CACHE_TTL: %w", err)
+	}
+	return ttl, nil
+}
+
+// GetHumioClientSearchDomainIndexResyncPeriod returns how often the index of the repositories and views of each Humio
+// cluster is rebuilt. The index is disabled unless HUMIO_CLIENT_SEARCH_DOMAIN_INDEX_RESYNC_PERIOD is set to a positive
+// duration such as "10m".
+func GetHumioClientSearchDomainIndexResyncPeriod() (time.Duration, error) {
+	resyncPeriod, found := os.LookupEnv("HUMIO_CLIENT_SEARCH_DOMAIN_INDEX_RESYNC_PERIOD")
+	if !found || resyncPeriod == "" {
+		return 0, nil
+	}
+	period, err := time.ParseDuration(resyncPeriod)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse HUMIO_CLIENT_SEARCH_DOMAIN_INDEX_RESYNC_PERIOD: %w", err)
+	}
+	return period, nil
+}
+
+// GetHumioClientAPIBudget returns the number of requests per second the operator may send to each Humio cluster.
+// The budget is unlimited unless HUMIO_CLIENT_API_BUDGET is set to a positive number such as "20".
+func GetHumioClientAPIBudget() (float64, error) {
+	apiBudget, found := os.LookupEnv("HUMIO_CLIENT_API_BUDGET")
+	if !found || apiBudget == "" {
+		return 0, nil
+	}
+	requestsPerSecond, err := strconv.ParseFloat(apiBudget, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse HUMIO_CLIENT_API_BUDGET: %w", err)
+	}
+	return requestsPerSecond, nil
+}
+
+// GetHumioClientFaultInjectionConfig returns the fraction of calls against the Humio API which fail or are slowed down
+// on purpose, and how long injected slow calls and timeouts take. This is meant for resilience testing in staging
+// environments only. Fault injection is disabled unless HUMIO_CLIENT_FAULT_INJECTION_RATE is set to a fraction such
+// as "0.05". HUMIO_CLIENT_FAULT_INJECTION_DELAY defaults to "5s".
+func GetHumioClientFaultInjectionConfig() (float64, time.Duration, error) {
+	faultInjectionRate, found := os.LookupEnv("HUMIO_CLIENT_FAULT_INJECTION_RATE")
+	if !found || faultInjectionRate == "" {
+		return 0, 0, nil
+	}
+	rate, err := strconv.ParseFloat(faultInjectionRate, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to parse HUMIO_CLIENT_FAULT_INJECTION_RATE: %w", err)
+	}
+	if rate < 0 || rate > 1 {
+		return 0, 0, fmt.Errorf("HUMIO_CLIENT_FAULT_INJECTION_RATE must be between 0 and 1, got %s", faultInjectionRate)
+	}
+	delay := 5 * time.Second
+	if faultInjectionDelay := os.Getenv("HUMIO_CLIENT_FAULT_INJECTION_DELAY"); faultInjectionDelay != "" {
+		if delay, err = time.ParseDuration(faultInjectionDelay); err != nil {
+			return 0, 0, fmt.Errorf("unable to parse HUMIO_CLIENT_FAULT_INJECTION_DELAY: %w", err)
+		}
+	}
+	return rate, delay, nil
+}
+
+// GetStartupReconcileRate returns the number of Humio entities per second which are reconciled for the first time
+// after the operator starts. The first reconciles are not spread out unless HUMIO_OPERATOR_STARTUP_RECONCILE_RATE is
+// set to a positive number such as "20".
+func GetStartupReconcileRate() (float64, error) {
+	startupReconcileRate, found := os.LookupEnv("HUMIO_OPERATOR_STARTUP_RECONCILE_RATE")
+	if !found || startupReconcileRate == "" {
+		return 0, nil
+	}
+	reconcilesPerSecond, err := strconv.ParseFloat(startupReconcileRate, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse HUMIO_OPERATOR_STARTUP_RECONCILE_RATE: %w", err)
+	}
+	return reconcilesPerSecond, nil
+}
+
+// GetAuditIngestConfig returns the URL of the Humio cluster and the ingest token used to ship the audit trail of
+// changes performed by the operator. Audit records are only logged unless both HUMIO_AUDIT_INGEST_URL and
+// HUMIO_AUDIT_INGEST_TOKEN are set.
+func GetAuditIngestConfig() (string, string, error) {
+	auditIngestURL := os.Getenv("HUMIO_AUDIT_INGEST_URL")
+	auditIngestToken := os.Getenv("HUMIO_AUDIT_INGEST_TOKEN")
+	if auditIngestURL == "" && auditIngestToken == "" {
+		return "", "", nil
+	}
+	if auditIngestURL == "" || auditIngestToken == "" {
+		return "", "", fmt.Errorf("HUMIO_AUDIT_INGEST_URL and HUMIO_AUDIT_INGEST_TOKEN must be set together")
+	}
+	return auditIngestURL, auditIngestToken, nil
+}
+
+// GetSelfMonitoringConfig returns the namespace and name of the HumioCluster the operator ships its own logs to, and
+// the name of the repository the logs are stored in. Self-monitoring is disabled unless
+// HUMIO_OPERATOR_SELF_MONITORING_CLUSTER is set to the HumioCluster in the form "namespace/name".
+// HUMIO_OPERATOR_SELF_MONITORING_REPOSITORY defaults to "humio-operator".
+func GetSelfMonitoringConfig() (string, string, string, error) {
+	cluster := os.Getenv("HUMIO_OPERATOR_SELF_MONITORING_CLUSTER")
+	if cluster == "" {
+		return "", "", "", nil
+	}
+	namespace, name, found := strings.Cut(cluster, "/")
+	if !found || namespace == "" || name == "" {
+		return "", "", "", fmt.Errorf("HUMIO_OPERATOR_SELF_MONITORING_CLUSTER must be in the form \"namespace/name\", got %q", cluster)
+	}
+	repositoryName := os.Getenv("HUMIO_OPERATOR_SELF_MONITORING_REPOSITORY")
+	if repositoryName == "" {
+		repositoryName = "humio-operator"
+	}
+	return namespace, name, repositoryName, nil
+}
+
+// GetNamespaceProvisioningConfig returns the namespace and name of the HumioCluster repositories are provisioned on
+// for namespaces annotated with humio.com/repository. Namespace provisioning is disabled unless
+// HUMIO_OPERATOR_NAMESPACE_PROVISIONING_CLUSTER is set to the HumioCluster in the form "namespace/name".
+func GetNamespaceProvisioningConfig() (string, string, error) {
+	cluster := os.Getenv("HUMIO_OPERATOR_NAMESPACE_PROVISIONING_CLUSTER")
+	if cluster == "" {
+		return "", "", nil
+	}
+	namespace, name, found := strings.Cut(cluster, "/")
+	if !found || namespace == "" || name == "" {
+		return "", "", fmt.Errorf("HUMIO_OPERATOR_NAMESPACE_PROVISIONING_CLUSTER must be in the form \"namespace/name\", got %q", cluster)
+	}
+	return namespace, name, nil
+}
+
+const (
+	// OrphanedEntityGCModeReport reports entities the operator created inside Humio whose resource no longer exists
+	OrphanedEntityGCModeReport = "Report"
+	// OrphanedEntityGCModeDelete deletes entities the operator created inside Humio whose resource no longer exists
+	OrphanedEntityGCModeDelete = "Delete"
+)
+
+// GetOrphanedEntityGCMode returns what to do with alerts, parsers and repositories the operator created inside Humio
+// whose resource no longer exists. Garbage collection is disabled unless HUMIO_OPERATOR_ORPHANED_ENTITY_GC is set to
+// "Report" or "Delete".
+func GetOrphanedEntityGCMode() (string, error) {
+	mode := os.Getenv("HUMIO_OPERATOR_ORPHANED_ENTITY_GC")
+	switch mode {
+	case "", OrphanedEntityGCModeReport, OrphanedEntityGCModeDelete:
+		return mode, nil
+	}
+	return "", fmt.Errorf("HUMIO_OPERATOR_ORPHANED_ENTITY_GC must be %q or %q, got %q", OrphanedEntityGCModeReport, OrphanedEntityGCModeDelete, mode)
+}
+
+// GetWebhookCertificateConfig returns the namespace and name of the secret the operator stores the serving certificate
+// of the webhooks in, the name of the webhook service in the same namespace and the name of the webhook configuration
+// the CA is injected into. The operator only manages the certificate if HUMIO_OPERATOR_WEBHOOK_CERT_SECRET is set to the
+// secret in the form "namespace/name", otherwise the certificate is expected to be mounted, e.g. by cert-manager.
+func GetWebhookCertificateConfig() (string, string, string, string, error) {
+	secret := os.Getenv("HUMIO_OPERATOR_WEBHOOK_CERT_SECRET")
+	if secret == "" {
+		return "", "", "", "", nil
+	}
+	namespace, name, found := strings.Cut(secret, "/")
+	if !found || namespace == "" || name == "" {
+		return "", "", "", "", fmt.Errorf("HUMIO_OPERATOR_WEBHOOK_CERT_SECRET must be in the form \"namespace/name\", got %q", secret)
+	}
+	serviceName := os.Getenv("HUMIO_OPERATOR_WEBHOOK_SERVICE")
+	if serviceName == "" {
+		return "", "", "", "", fmt.Errorf("HUMIO_OPERATOR_WEBHOOK_SERVICE must be set when HUMIO_OPERATOR_WEBHOOK_CERT_SECRET is set")
+	}
+	return namespace, name, serviceName, os.Getenv("HUMIO_OPERATOR_WEBHOOK_CONFIGURATION"), nil
+}
+
+// GetEnvironmentVariablePolicyConfig returns the namespace and name of the ConfigMap holding the environment variables
+// users may not set on HumioClusters. The policy is only enforced if HUMIO_OPERATOR_ENVIRONMENT_VARIABLE_POLICY is set
+// to the ConfigMap in the form "namespace/name".
+func GetEnvironmentVariablePolicyConfig() (string, string, error) {
+	policy := os.Getenv("HUMIO_OPERATOR_ENVIRONMENT_VARIABLE_POLICY")
+	if policy == "" {
+		return "", "", nil
+	}
+	namespace, name, found := strings.Cut(policy, "/")
+	if !found || namespace == "" || name == "" {
+		return "", "", fmt.Errorf("HUMIO_OPERATOR_ENVIRONMENT_VARIABLE_POLICY must be in the form \"namespace/name\", got %q", policy)
+	}
+	return namespace, name, nil
+}
+
+const (
+	// ControllerSetAll runs all controllers in a single deployment
+	ControllerSetAll = "All"
+	// ControllerSetCluster runs the controllers managing Humio clusters and the Kubernetes objects making them up
+	ControllerSetCluster = "Cluster"
+	// ControllerSetEntities runs the controllers managing entities inside Humio, such as alerts, parsers and
+	// repositories, which only need access to the Humio API
+	ControllerSetEntities = "Entities"
+)
+
+// GetControllerSet returns which controllers the operator runs, as set by HUMIO_OPERATOR_CONTROLLERS. Defaults to
+// ControllerSetAll.
+func GetControllerSet() (string, error) {
+	set := os.Getenv("HUMIO_OPERATOR_CONTROLLERS")
+	switch set {
+	case "":
+		return ControllerSetAll, nil
+	case ControllerSetAll, ControllerSetCluster, ControllerSetEntities:
+		return set, nil
+	}
+	return "", fmt.Errorf("HUMIO_OPERATOR_CONTROLLERS must be %q, %q or %q, got %q", ControllerSetAll, ControllerSetCluster, ControllerSetEntities, set)
+}
+
+// LeaderElectionID returns the leader election ID for the given set of controllers, so deployments running different
+// sets of controllers do not compete for the same lease
+func LeaderElectionID(controllerSet string) string {
+	if controllerSet == ControllerSetAll {
+		return "d7845218.humio.com"
+	}
+	return fmt.Sprintf("%s.d7845218.humio.com", strings.ToLower(controllerSet))
+}
+
+// GetFIPSImage returns the FIPS-validated Humio image used by clusters with the FIPS compliance profile which do not set
+// an image, or an empty string if HUMIO_OPERATOR_FIPS_IMAGE is not set
+func GetFIPSImage() string {
+	return os.Getenv("HUMIO_OPERATOR_FIPS_IMAGE")
+}
+
+// GetFIPSHelperImage returns the FIPS-validated helper image used by clusters with the FIPS compliance profile which do
+// not set a helper image, or an empty string if HUMIO_OPERATOR_FIPS_HELPER_IMAGE is not set
+func GetFIPSHelperImage() string {
+	return os.Getenv("HUMIO_OPERATOR_FIPS_HELPER_IMAGE")
+}
+
+// GetKubernetesAuditConfig returns the namespace the operator records an Event in, and the URL of the webhook it posts
+// an audit record to, for every change it makes to Kubernetes objects. Either may be empty, in which case that sink is
+// disabled, and the changes are only audited if HUMIO_OPERATOR_AUDIT_EVENTS_NAMESPACE or
+// HUMIO_OPERATOR_AUDIT_WEBHOOK_URL is set.
+func GetKubernetesAuditConfig() (string, string) {
+	return os.Getenv("HUMIO_OPERATOR_AUDIT_EVENTS_NAMESPACE"), os.Getenv("HUMIO_OPERATOR_AUDIT_WEBHOOK_URL")
+}
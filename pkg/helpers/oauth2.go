@@ -0,0 +1,127 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// oauth2TokenTimeout is the timeout used for requests against the token endpoint of the OIDC provider
+const oauth2TokenTimeout = 30 * time.Second
+
+// oauth2TokenSourceEntry holds a token source along with the configuration it was created from, so we can detect when
+// the configuration of the HumioExternalCluster changes
+type oauth2TokenSourceEntry struct {
+	config      clientcredentials.Config
+	tokenSource oauth2.TokenSource
+}
+
+var (
+	oauth2TokenSources      = map[types.NamespacedName]oauth2TokenSourceEntry{}
+	oauth2TokenSourcesMutex sync.Mutex
+)
+
+// getOAuth2AccessToken returns an access token for the given HumioExternalCluster using the OAuth2 client credentials
+// flow. Token sources are kept across reconciles, so a new access token is only requested once the current one is
+// about to expire.
+func getOAuth2AccessToken(ctx context.Context, k8sClient client.Client, hec *humiov1alpha1.HumioExternalCluster) (string, error) {
+	if hec.Spec.OAuth2.TokenURL == "" {
+		return "", fmt.Errorf("no oauth2 token url specified")
+	}
+	if hec.Spec.OAuth2.ClientSecretName == "" {
+		return "", fmt.Errorf("no oauth2 client secret name specified")
+	}
+
+	key := types.NamespacedName{Namespace: hec.Namespace, Name: hec.Name}
+	var clientSecret corev1.Secret
+	err := k8sClient.Get(ctx, types.NamespacedName{
+		Namespace: hec.Namespace,
+		Name:      hec.Spec.OAuth2.ClientSecretName,
+	}, &clientSecret)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			ForgetOAuth2TokenSource(key)
+		}
+		return "", fmt.Errorf("unable to get secret containing oauth2 client credentials: %w", err)
+	}
+	if len(clientSecret.Data["client_id"]) == 0 || len(clientSecret.Data["client_secret"]) == 0 {
+		ForgetOAuth2TokenSource(key)
+		return "", fmt.Errorf("secret %s must contain the keys \"client_id\" and \"client_secret\"", hec.Spec.OAuth2.ClientSecretName)
+	}
+
+	config := clientcredentials.Config{
+		ClientID:     string(clientSecret.Data["client_id"]),
+		ClientSecret: string(clientSecret.Data["client_secret"]),
+		TokenURL:     hec.Spec.OAuth2.TokenURL,
+		Scopes:       hec.Spec.OAuth2.Scopes,
+	}
+
+	token, err := oauth2TokenSource(key, config).Token()
+	if err != nil {
+		return "", fmt.Errorf("unable to obtain oauth2 access token: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+// oauth2TokenSource returns the cached token source for the given HumioExternalCluster, or creates a new one if none
+// exists or the client credentials configuration has changed
+func oauth2TokenSource(key types.NamespacedName, config clientcredentials.Config) oauth2.TokenSource {
+	oauth2TokenSourcesMutex.Lock()
+	defer oauth2TokenSourcesMutex.Unlock()
+
+	entry, ok := oauth2TokenSources[key]
+	if ok && oauth2ConfigEqual(entry.config, config) {
+		return entry.tokenSource
+	}
+
+	// The token source outlives the reconcile, so it must not use the context of the reconcile when refreshing tokens
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: oauth2TokenTimeout})
+	entry = oauth2TokenSourceEntry{
+		config:      config,
+		tokenSource: config.TokenSource(ctx),
+	}
+	oauth2TokenSources[key] = entry
+	return entry.tokenSource
+}
+
+// ForgetOAuth2TokenSource drops the cached token source of the given HumioExternalCluster. It must be called when the
+// HumioExternalCluster is deleted or its client credentials change, so cached access tokens are not kept around.
+func ForgetOAuth2TokenSource(key types.NamespacedName) {
+	oauth2TokenSourcesMutex.Lock()
+	defer oauth2TokenSourcesMutex.Unlock()
+	delete(oauth2TokenSources, key)
+}
+
+func oauth2ConfigEqual(a, b clientcredentials.Config) bool {
+	return a.ClientID == b.ClientID &&
+		a.ClientSecret == b.ClientSecret &&
+		a.TokenURL == b.TokenURL &&
+		strings.Join(a.Scopes, " ") == strings.Join(b.Scopes, " ")
+}
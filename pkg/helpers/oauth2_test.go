@@ -0,0 +1,126 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"golang.org/x/oauth2/clientcredentials"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCluster_OAuth2AccessToken(t *testing.T) {
+	var requests, expiresIn int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"access-token-%d","token_type":"Bearer","expires_in":%d}`, n, atomic.LoadInt32(&expiresIn))
+	}))
+	defer tokenServer.Close()
+
+	externalHumioCluster := humiov1alpha1.HumioExternalCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "oauth2-external-cluster",
+			Namespace: "namespace",
+		},
+		Spec: humiov1alpha1.HumioExternalClusterSpec{
+			Url: "https://humio.example.com/",
+			OAuth2: &humiov1alpha1.HumioExternalClusterOAuth2{
+				TokenURL:         tokenServer.URL,
+				ClientSecretName: "oauth2-client",
+			},
+		},
+	}
+	oauth2ClientSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "oauth2-client",
+			Namespace: "namespace",
+		},
+		Data: map[string][]byte{
+			"client_id":     []byte("client-id"),
+			"client_secret": []byte("client-secret"),
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(humiov1alpha1.GroupVersion, &externalHumioCluster)
+	cl := fake.NewClientBuilder().WithRuntimeObjects(&externalHumioCluster, &oauth2ClientSecret).Build()
+
+	tests := []struct {
+		name          string
+		expiresIn     int32
+		expectedToken string
+	}{
+		{"first reconcile obtains an access token", 1, "access-token-1"},
+		{"access token about to expire is refreshed", 3600, "access-token-2"},
+		{"valid access token is reused", 3600, "access-token-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&expiresIn, tt.expiresIn)
+			cluster, err := NewCluster(context.Background(), cl, "", externalHumioCluster.Name, externalHumioCluster.Namespace, false, true)
+			if err != nil {
+				t.Fatalf("unable to obtain humio client config: %s", err)
+			}
+			if cluster.Config().Token != tt.expectedToken {
+				t.Errorf("expected api token %s, got %s", tt.expectedToken, cluster.Config().Token)
+			}
+		})
+	}
+}
+
+func TestForgetOAuth2TokenSource(t *testing.T) {
+	key := types.NamespacedName{Namespace: "namespace", Name: "forgotten-external-cluster"}
+	config := clientcredentials.Config{ClientID: "client-id", ClientSecret: "client-secret", TokenURL: "https://oidc.example.com/token"}
+
+	tests := []struct {
+		name   string
+		forget bool
+		cached bool
+	}{
+		{"token source is cached", false, true},
+		{"forgotten token source is evicted", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oauth2TokenSource(key, config)
+			if tt.forget {
+				ForgetOAuth2TokenSource(key)
+			}
+			oauth2TokenSourcesMutex.Lock()
+			_, ok := oauth2TokenSources[key]
+			oauth2TokenSourcesMutex.Unlock()
+			if ok != tt.cached {
+				t.Errorf("expected token source cached to be %t, got %t", tt.cached, ok)
+			}
+		})
+	}
+}
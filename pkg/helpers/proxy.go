@@ -0,0 +1,233 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"golang.org/x/net/http/httpproxy"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// proxyDialer dials connections towards a single Humio cluster host using a single proxy configuration, either directly
+// or by tunneling through an HTTP proxy using CONNECT. Clusters on the same host using different proxies get different
+// dialers, and changes to the proxy configuration of a cluster give it a new dialer.
+type proxyDialer struct {
+	key string
+
+	mutex     sync.RWMutex
+	scheme    string
+	proxyFunc func(*url.URL) (*url.URL, error)
+	username  string
+	password  string
+
+	dialer net.Dialer
+}
+
+var (
+	proxyDialers      = map[string]*proxyDialer{}
+	proxyDialersMutex sync.Mutex
+
+	// proxyDialerDialContext identifies the DialContext method values of proxy dialers
+	proxyDialerDialContext = reflect.ValueOf((&proxyDialer{}).DialContext).Pointer()
+	errProxyKeyRequested   = errors.New("proxy key requested")
+)
+
+// proxyKeyContextKey is set on the context of a dial to ask a proxy dialer for its key instead of dialing
+type proxyKeyContextKey struct{}
+
+// ProxyKey returns the key of the proxy dialer behind the given dial function, which identifies the host and proxy
+// configuration the dialer connects with. Connections dialed with different keys must not be pooled together. An empty
+// key is returned for dial functions which are not proxy dialers, without calling them.
+func ProxyKey(dial func(ctx context.Context, network, addr string) (net.Conn, error)) string {
+	if dial == nil || reflect.ValueOf(dial).Pointer() != proxyDialerDialContext {
+		return ""
+	}
+	var key string
+	_, _ = dial(context.WithValue(context.Background(), proxyKeyContextKey{}, &key), "", "")
+	return key
+}
+
+// proxyDialerKey returns the key of the dialer for the given host and proxy configuration. The credentials are hashed,
+// so they do not show up where the key is logged.
+func proxyDialerKey(host string, proxy *humiov1alpha1.HumioExternalClusterProxy, username, password string) string {
+	if proxy == nil {
+		return host
+	}
+	return host + "/" + AsSHA256(strings.Join([]string{proxy.HTTPProxy, proxy.HTTPSProxy, proxy.NoProxy, username, password}, "\x00"))
+}
+
+// getProxyDialer makes sure all URLs of the given HumioExternalCluster have a dialer for the current proxy
+// configuration of the cluster, and returns the dialer for the given URL. Dialers are kept per host and proxy
+// configuration, so clusters on the same host using different proxies never share connections. The credentials secret
+// must contain the keys "username" and "password".
+func getProxyDialer(ctx context.Context, k8sClient client.Client, hec *humiov1alpha1.HumioExternalCluster, clusterURL *url.URL) (*proxyDialer, error) {
+	var proxyFunc func(*url.URL) (*url.URL, error)
+	var username, password string
+	if hec.Spec.Proxy != nil {
+		proxyFunc = (&httpproxy.Config{
+			HTTPProxy:  hec.Spec.Proxy.HTTPProxy,
+			HTTPSProxy: hec.Spec.Proxy.HTTPSProxy,
+			NoProxy:    hec.Spec.Proxy.NoProxy,
+		}).ProxyFunc()
+
+		if hec.Spec.Proxy.CredentialsSecretName != "" {
+			var credentials corev1.Secret
+			err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: hec.Namespace,
+				Name:      hec.Spec.Proxy.CredentialsSecretName,
+			}, &credentials)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get secret containing proxy credentials: %w", err)
+			}
+			username = string(credentials.Data["username"])
+			password = string(credentials.Data["password"])
+			if username == "" {
+				return nil, fmt.Errorf("secret %s does not contain a proxy username in key \"username\"", hec.Spec.Proxy.CredentialsSecretName)
+			}
+		}
+	}
+
+	proxyDialersMutex.Lock()
+	defer proxyDialersMutex.Unlock()
+
+	for _, externalClusterUrl := range ExternalClusterUrls(hec) {
+		u, err := url.Parse(externalClusterUrl)
+		if err != nil {
+			return nil, err
+		}
+		key := proxyDialerKey(u.Host, hec.Spec.Proxy, username, password)
+		pd, ok := proxyDialers[key]
+		if !ok {
+			pd = &proxyDialer{
+				key: key,
+				dialer: net.Dialer{
+					Timeout:   30 * time.Second,
+					KeepAlive: 30 * time.Second,
+				},
+			}
+			proxyDialers[key] = pd
+		}
+		pd.update(u.Scheme, proxyFunc, username, password)
+	}
+	pd, ok := proxyDialers[proxyDialerKey(clusterURL.Host, hec.Spec.Proxy, username, password)]
+	if !ok {
+		return nil, fmt.Errorf("no dialer for %s", clusterURL.Host)
+	}
+	return pd, nil
+}
+
+func (pd *proxyDialer) update(scheme string, proxyFunc func(*url.URL) (*url.URL, error), username, password string) {
+	pd.mutex.Lock()
+	defer pd.mutex.Unlock()
+	pd.scheme = scheme
+	pd.proxyFunc = proxyFunc
+	pd.username = username
+	pd.password = password
+}
+
+// DialContext connects to the given address, tunneling through the proxy if one is configured for the address
+func (pd *proxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if key, ok := ctx.Value(proxyKeyContextKey{}).(*string); ok {
+		*key = pd.key
+		return nil, errProxyKeyRequested
+	}
+
+	pd.mutex.RLock()
+	scheme, proxyFunc, username, password := pd.scheme, pd.proxyFunc, pd.username, pd.password
+	pd.mutex.RUnlock()
+
+	if proxyFunc == nil {
+		return pd.dialer.DialContext(ctx, network, addr)
+	}
+	proxyURL, err := proxyFunc(&url.URL{Scheme: scheme, Host: addr})
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine proxy for %s: %w", addr, err)
+	}
+	if proxyURL == nil {
+		return pd.dialer.DialContext(ctx, network, addr)
+	}
+
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	conn, err := pd.dialer.DialContext(ctx, network, proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to proxy %s: %w", proxyAddr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("unable to establish tls connection to proxy %s: %w", proxyAddr, err)
+		}
+		conn = tlsConn
+	}
+
+	connectReq := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if username == "" && proxyURL.User != nil {
+		username = proxyURL.User.Username()
+		password, _ = proxyURL.User.Password()
+	}
+	if username != "" {
+		connectReq.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	}
+	if err := connectReq.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unable to send CONNECT request to proxy %s: %w", proxyAddr, err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), connectReq)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unable to read CONNECT response from proxy %s: %w", proxyAddr, err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", proxyAddr, addr, resp.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
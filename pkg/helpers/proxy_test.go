@@ -0,0 +1,211 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// startConnectProxy starts a proxy which accepts CONNECT requests and sends the requested target and the
+// Proxy-Authorization header of each request on the returned channel
+func startConnectProxy(t *testing.T) (net.Listener, chan [2]string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to start proxy: %s", err)
+	}
+	requests := make(chan [2]string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			req, err := http.ReadRequest(bufio.NewReader(conn))
+			if err != nil || req.Method != http.MethodConnect {
+				_ = conn.Close()
+				continue
+			}
+			requests <- [2]string{req.Host, req.Header.Get("Proxy-Authorization")}
+			_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+			_ = conn.Close()
+		}
+	}()
+	return listener, requests
+}
+
+func TestCluster_ExternalClusterProxy(t *testing.T) {
+	proxy, requests := startConnectProxy(t)
+	defer proxy.Close()
+
+	externalHumioCluster := humiov1alpha1.HumioExternalCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "proxy-external-cluster",
+			Namespace: "namespace",
+		},
+		Spec: humiov1alpha1.HumioExternalClusterSpec{
+			Url:                "https://humio.example.com/",
+			APITokenSecretName: "proxy-api-token",
+			Proxy: &humiov1alpha1.HumioExternalClusterProxy{
+				HTTPSProxy:            "http://" + proxy.Addr().String(),
+				NoProxy:               "internal.example.com",
+				CredentialsSecretName: "proxy-credentials",
+			},
+		},
+	}
+	apiTokenSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "proxy-api-token",
+			Namespace: "namespace",
+		},
+		Data: map[string][]byte{
+			"token": []byte("token"),
+		},
+	}
+	proxyCredentials := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "proxy-credentials",
+			Namespace: "namespace",
+		},
+		Data: map[string][]byte{
+			"username": []byte("user"),
+			"password": []byte("pass"),
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(humiov1alpha1.GroupVersion, &externalHumioCluster)
+	cl := fake.NewClientBuilder().WithRuntimeObjects(&externalHumioCluster, &apiTokenSecret, &proxyCredentials).Build()
+
+	cluster, err := NewCluster(context.Background(), cl, "", externalHumioCluster.Name, externalHumioCluster.Namespace, false, true)
+	if err != nil {
+		t.Fatalf("unable to obtain humio client config: %s", err)
+	}
+	if cluster.Config().DialContext == nil {
+		t.Fatal("expected config to use a dialer honoring the proxy configuration")
+	}
+
+	conn, err := cluster.Config().DialContext(context.Background(), "tcp", "humio.example.com:443")
+	if err != nil {
+		t.Fatalf("expected connection through proxy, got %s", err)
+	}
+	_ = conn.Close()
+	request := <-requests
+	if request[0] != "humio.example.com:443" {
+		t.Errorf("expected proxy to be asked to connect to %s, got %s", "humio.example.com:443", request[0])
+	}
+	expectedAuthorization := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	if request[1] != expectedAuthorization {
+		t.Errorf("expected proxy authorization %s, got %s", expectedAuthorization, request[1])
+	}
+
+	// Hosts matching NoProxy are dialed directly, which fails as the host does not exist
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, _ = cluster.Config().DialContext(ctx, "tcp", "internal.example.com:443")
+	select {
+	case request := <-requests:
+		t.Errorf("expected %s to bypass the proxy, got request for %s", "internal.example.com", request[0])
+	default:
+	}
+}
+
+func TestCluster_ExternalClustersOnSameHostWithDifferentProxies(t *testing.T) {
+	var objects []runtime.Object
+	var proxies []net.Listener
+	var requests []chan [2]string
+	for _, name := range []string{"first", "second"} {
+		proxy, proxyRequests := startConnectProxy(t)
+		defer proxy.Close()
+		proxies = append(proxies, proxy)
+		requests = append(requests, proxyRequests)
+		objects = append(objects,
+			&humiov1alpha1.HumioExternalCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "namespace",
+				},
+				Spec: humiov1alpha1.HumioExternalClusterSpec{
+					Url:                "https://shared.example.com/",
+					APITokenSecretName: name + "-api-token",
+					Proxy: &humiov1alpha1.HumioExternalClusterProxy{
+						HTTPSProxy: "http://" + proxy.Addr().String(),
+					},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name + "-api-token",
+					Namespace: "namespace",
+				},
+				Data: map[string][]byte{
+					"token": []byte("token"),
+				},
+			},
+		)
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(humiov1alpha1.GroupVersion, &humiov1alpha1.HumioExternalCluster{})
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objects...).Build()
+
+	var clusters []ClusterInterface
+	for _, name := range []string{"first", "second"} {
+		cluster, err := NewCluster(context.Background(), cl, "", name, "namespace", false, true)
+		if err != nil {
+			t.Fatalf("unable to obtain humio client config: %s", err)
+		}
+		clusters = append(clusters, cluster)
+	}
+	if ProxyKey(clusters[0].Config().DialContext) == ProxyKey(clusters[1].Config().DialContext) {
+		t.Error("expected clusters using different proxies to have different proxy keys")
+	}
+
+	// Each cluster keeps dialing through its own proxy, also after the other cluster has been configured
+	for i, cluster := range clusters {
+		conn, err := cluster.Config().DialContext(context.Background(), "tcp", "shared.example.com:443")
+		if err != nil {
+			t.Fatalf("expected connection through proxy %s, got %s", proxies[i].Addr(), err)
+		}
+		_ = conn.Close()
+		select {
+		case request := <-requests[i]:
+			if request[0] != "shared.example.com:443" {
+				t.Errorf("expected proxy to be asked to connect to %s, got %s", "shared.example.com:443", request[0])
+			}
+		case <-time.After(time.Second):
+			t.Errorf("expected cluster %d to connect through its own proxy", i)
+		}
+		select {
+		case <-requests[1-i]:
+			t.Errorf("expected cluster %d not to connect through the proxy of the other cluster", i)
+		default:
+		}
+	}
+}
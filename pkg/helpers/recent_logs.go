@@ -0,0 +1,114 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"bytes"
+	"sync"
+
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RecentLogsSize is the number of log lines of the operator kept in memory by RecentLogs
+const RecentLogsSize = 2000
+
+// RecentLogs keeps the most recent log lines of the operator in memory, so they can be included in diagnostics
+// bundles without access to the logs of the operator pod
+type RecentLogs struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// NewRecentLogs returns a RecentLogs which keeps the given number of lines
+func NewRecentLogs(size int) *RecentLogs {
+	return &RecentLogs{lines: make([]string, size)}
+}
+
+// WrapCore returns a core which writes to the given core and also keeps the entries. It can be passed to
+// zap.WrapCore.
+func (l *RecentLogs) WrapCore(core zapcore.Core) zapcore.Core {
+	encoderConfig := uberzap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	return zapcore.NewTee(core, &recentLogsCore{
+		LevelEnabler: core,
+		encoder:      zapcore.NewJSONEncoder(encoderConfig),
+		logs:         l,
+	})
+}
+
+// Lines returns the kept log lines, oldest first
+func (l *RecentLogs) Lines() []string {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]string(nil), l.lines[:l.next]...)
+	}
+	return append(append([]string(nil), l.lines[l.next:]...), l.lines[:l.next]...)
+}
+
+func (l *RecentLogs) add(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines[l.next] = line
+	l.next++
+	if l.next == len(l.lines) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// recentLogsCore is a zapcore.Core which encodes entries as JSON and keeps them in RecentLogs
+type recentLogsCore struct {
+	zapcore.LevelEnabler
+	encoder zapcore.Encoder
+	logs    *RecentLogs
+}
+
+func (c *recentLogsCore) With(fields []zapcore.Field) zapcore.Core {
+	encoder := c.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	return &recentLogsCore{LevelEnabler: c.LevelEnabler, encoder: encoder, logs: c.logs}
+}
+
+func (c *recentLogsCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *recentLogsCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	c.logs.add(string(bytes.TrimSuffix(buf.Bytes(), []byte(zapcore.DefaultLineEnding))))
+	buf.Free()
+	return nil
+}
+
+func (c *recentLogsCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,42 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRecentLogs(t *testing.T) {
+	recentLogs := NewRecentLogs(3)
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(uberzap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), uberzap.InfoLevel)
+	logger := uberzap.New(core).WithOptions(uberzap.WrapCore(recentLogs.WrapCore)).With(uberzap.String("Request.Name", "example"))
+
+	logger.Debug("not enabled")
+	if lines := recentLogs.Lines(); len(lines) != 0 {
+		t.Fatalf("expected no lines, got %v", lines)
+	}
+
+	for i := 0; i < 5; i++ {
+		logger.Info(fmt.Sprintf("line %d", i))
+	}
+	lines := recentLogs.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %v", lines)
+	}
+	for i, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("expected line to be JSON, got %s", line)
+		}
+		if expected := fmt.Sprintf("line %d", i+2); entry["msg"] != expected {
+			t.Errorf("expected line %d to be %q, got %v", i, expected, entry["msg"])
+		}
+		if entry["Request.Name"] != "example" {
+			t.Errorf("expected fields to be kept, got %v", entry)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"sync"
+	"time"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+// RequestTimeouts holds the timeouts used for requests against a single Humio cluster. Zero values mean the defaults
+// of the Humio API client are used.
+type RequestTimeouts struct {
+	Connect time.Duration
+	Read    time.Duration
+}
+
+// requestTimeouts holds the timeouts for each Humio cluster host. Like client certificates, the Humio API config has
+// no fields for these, so they are looked up by host when transports are created.
+var (
+	requestTimeouts      = map[string]RequestTimeouts{}
+	requestTimeoutsMutex sync.RWMutex
+)
+
+// GetRequestTimeouts returns the timeouts configured for the given Humio cluster host
+func GetRequestTimeouts(host string) RequestTimeouts {
+	requestTimeoutsMutex.RLock()
+	defer requestTimeoutsMutex.RUnlock()
+	return requestTimeouts[host]
+}
+
+func setRequestTimeouts(host string, apiTimeouts *humiov1alpha1.HumioAPITimeouts) {
+	requestTimeoutsMutex.Lock()
+	defer requestTimeoutsMutex.Unlock()
+	if apiTimeouts == nil {
+		delete(requestTimeouts, host)
+		return
+	}
+	requestTimeouts[host] = RequestTimeouts{
+		Connect: time.Second * time.Duration(apiTimeouts.ConnectTimeoutSeconds),
+		Read:    time.Second * time.Duration(apiTimeouts.ReadTimeoutSeconds),
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package humio
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -41,6 +42,12 @@ const (
 	ActionTypeHumioRepo        = "HumioRepo"
 	ActionTypeEmail            = "Email"
 	ActionTypeOpsGenie         = "OpsGenie"
+
+	// ActionFallbackRelayViewName is the default view of the relay alerts triggering fallback actions. Humio logs
+	// failed actions to this repository.
+	ActionFallbackRelayViewName = "humio-activity"
+	// ActionFallbackThrottleTimeMillis is the default throttle time of the relay alerts triggering fallback actions
+	ActionFallbackThrottleTimeMillis = 300000
 )
 
 func CRActionFromAPIAction(action *humioapi.Action) (*humiov1alpha1.HumioAction, error) {
@@ -136,14 +143,14 @@ func CRActionFromAPIAction(action *humioapi.Action) (*humiov1alpha1.HumioAction,
 			UseProxy:     action.WebhookAction.UseProxy,
 		}
 	}
-	if reflect.ValueOf(action.EmailAction).IsZero() &&
-		reflect.ValueOf(action.HumioRepoAction).IsZero() &&
-		reflect.ValueOf(action.OpsGenieAction).IsZero() &&
-		reflect.ValueOf(action.PagerDutyAction).IsZero() &&
-		reflect.ValueOf(action.SlackAction).IsZero() &&
-		reflect.ValueOf(action.SlackPostMessageAction).IsZero() &&
-		reflect.ValueOf(action.VictorOpsAction).IsZero() &&
-		reflect.ValueOf(action.WebhookAction).IsZero() {
+	if ha.Spec.EmailProperties == nil &&
+		ha.Spec.HumioRepositoryProperties == nil &&
+		ha.Spec.OpsGenieProperties == nil &&
+		ha.Spec.PagerDutyProperties == nil &&
+		ha.Spec.SlackProperties == nil &&
+		ha.Spec.SlackPostMessageProperties == nil &&
+		ha.Spec.VictorOpsProperties == nil &&
+		ha.Spec.WebhookProperties == nil {
 		return nil, fmt.Errorf("no action configuration specified")
 	}
 
@@ -231,9 +238,29 @@ func opsGenieAction(hn *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
 	if hn.Spec.OpsGenieProperties.ApiUrl == "" {
 		errorList = append(errorList, "property opsGenieProperties.apiUrl is required")
 	}
+	if hn.Spec.OpsGenieProperties.Priority != "" {
+		acceptedPriorities := []string{"P1", "P2", "P3", "P4", "P5"}
+		if !stringInList(hn.Spec.OpsGenieProperties.Priority, acceptedPriorities) {
+			errorList = append(errorList, fmt.Sprintf("unsupported priority for opsGenieProperties: %q. must be one of: %s",
+				hn.Spec.OpsGenieProperties.Priority, strings.Join(acceptedPriorities, ", ")))
+		}
+	}
+	for _, responder := range hn.Spec.OpsGenieProperties.Responders {
+		acceptedResponderTypes := []string{"team", "user", "escalation", "schedule"}
+		if !stringInList(responder.Type, acceptedResponderTypes) {
+			errorList = append(errorList, fmt.Sprintf("unsupported responder type for opsGenieProperties: %q. must be one of: %s",
+				responder.Type, strings.Join(acceptedResponderTypes, ", ")))
+		}
+		if responder.Name == "" {
+			errorList = append(errorList, "property opsGenieProperties.responders.name is required")
+		}
+	}
 	if len(errorList) > 0 {
 		return ifErrors(action, ActionTypeOpsGenie, errorList)
 	}
+	if hn.Spec.OpsGenieProperties.Priority != "" || len(hn.Spec.OpsGenieProperties.Tags) > 0 || len(hn.Spec.OpsGenieProperties.Responders) > 0 {
+		return opsGenieAlertAPIAction(action, hn.Spec.OpsGenieProperties)
+	}
 	action.Type = humioapi.ActionTypeOpsGenie
 	action.OpsGenieAction.GenieKey = hn.Spec.OpsGenieProperties.GenieKey
 	action.OpsGenieAction.ApiUrl = hn.Spec.OpsGenieProperties.ApiUrl
@@ -242,6 +269,59 @@ func opsGenieAction(hn *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
 	return action, nil
 }
 
+// opsGenieAlert is the body of a request to the OpsGenie Alert API
+type opsGenieAlert struct {
+	Message     string              `json:"message"`
+	Description string              `json:"description"`
+	Priority    string              `json:"priority,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Responders  []opsGenieResponder `json:"responders,omitempty"`
+	Details     map[string]string   `json:"details"`
+	Source      string              `json:"source"`
+}
+
+type opsGenieResponder struct {
+	Type     string `json:"type"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// opsGenieAlertAPIAction returns a webhook action creating alerts through the OpsGenie Alert API. Humio's OpsGenie
+// action cannot set the priority, tags or responders of the alerts it creates, so those are only available by
+// calling the API directly.
+func opsGenieAlertAPIAction(action *humioapi.Action, properties *humiov1alpha1.HumioActionOpsGenieProperties) (*humioapi.Action, error) {
+	body := opsGenieAlert{
+		Message:     "{alert_name}",
+		Description: "{alert_description}",
+		Priority:    properties.Priority,
+		Tags:        properties.Tags,
+		Details:     map[string]string{"url": "{url}"},
+		Source:      "Humio",
+	}
+	for _, responder := range properties.Responders {
+		if responder.Type == "user" {
+			body.Responders = append(body.Responders, opsGenieResponder{Type: responder.Type, Username: responder.Name})
+			continue
+		}
+		body.Responders = append(body.Responders, opsGenieResponder{Type: responder.Type, Name: responder.Name})
+	}
+	bodyTemplate, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("could not create opsgenie alert body: %w", err)
+	}
+
+	action.Type = humioapi.ActionTypeWebhook
+	action.WebhookAction.BodyTemplate = string(bodyTemplate)
+	action.WebhookAction.Method = http.MethodPost
+	action.WebhookAction.Url = strings.TrimSuffix(properties.ApiUrl, "/") + "/v2/alerts"
+	action.WebhookAction.UseProxy = properties.UseProxy
+	action.WebhookAction.Headers = []humioapi.HttpHeaderEntryInput{
+		{Header: "Authorization", Value: "GenieKey " + properties.GenieKey},
+		{Header: "Content-Type", Value: "application/json"},
+	}
+	return action, nil
+}
+
 func pagerDutyAction(hn *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
 	var errorList []string
 	action, err := baseAction(hn)
@@ -294,7 +374,7 @@ func slackAction(hn *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
 	action.Type = humioapi.ActionTypeSlack
 	action.SlackAction.Url = hn.Spec.SlackProperties.Url
 	action.SlackAction.UseProxy = hn.Spec.SlackProperties.UseProxy
-	action.SlackAction.Fields = []humioapi.SlackFieldEntryInput{}
+	action.SlackAction.Fields = make([]humioapi.SlackFieldEntryInput, 0, len(hn.Spec.SlackProperties.Fields))
 	for k, v := range hn.Spec.SlackProperties.Fields {
 		action.SlackAction.Fields = append(action.SlackAction.Fields,
 			humioapi.SlackFieldEntryInput{
@@ -330,7 +410,7 @@ func slackPostMessageAction(hn *humiov1alpha1.HumioAction) (*humioapi.Action, er
 	action.SlackPostMessageAction.ApiToken = hn.Spec.SlackPostMessageProperties.ApiToken
 	action.SlackPostMessageAction.Channels = hn.Spec.SlackPostMessageProperties.Channels
 	action.SlackPostMessageAction.UseProxy = hn.Spec.SlackPostMessageProperties.UseProxy
-	action.SlackPostMessageAction.Fields = []humioapi.SlackFieldEntryInput{}
+	action.SlackPostMessageAction.Fields = make([]humioapi.SlackFieldEntryInput, 0, len(hn.Spec.SlackPostMessageProperties.Fields))
 	for k, v := range hn.Spec.SlackPostMessageProperties.Fields {
 		action.SlackPostMessageAction.Fields = append(action.SlackPostMessageAction.Fields,
 			humioapi.SlackFieldEntryInput{
@@ -368,9 +448,13 @@ func victorOpsAction(hn *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
 	if len(errorList) > 0 {
 		return ifErrors(action, ActionTypeVictorOps, errorList)
 	}
+	notifyUrl := hn.Spec.VictorOpsProperties.NotifyUrl
+	if hn.Spec.VictorOpsProperties.RoutingKey != "" {
+		notifyUrl = strings.TrimSuffix(notifyUrl, "/") + "/" + url.PathEscape(hn.Spec.VictorOpsProperties.RoutingKey)
+	}
 	action.Type = humioapi.ActionTypeVictorOps
 	action.VictorOpsAction.MessageType = messageType
-	action.VictorOpsAction.NotifyUrl = hn.Spec.VictorOpsProperties.NotifyUrl
+	action.VictorOpsAction.NotifyUrl = notifyUrl
 	action.VictorOpsAction.UseProxy = hn.Spec.VictorOpsProperties.UseProxy
 
 	return action, nil
@@ -412,7 +496,7 @@ func webhookAction(hn *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
 	action.WebhookAction.Method = method
 	action.WebhookAction.Url = hn.Spec.WebhookProperties.Url
 	action.WebhookAction.UseProxy = hn.Spec.WebhookProperties.UseProxy
-	action.WebhookAction.Headers = []humioapi.HttpHeaderEntryInput{}
+	action.WebhookAction.Headers = make([]humioapi.HttpHeaderEntryInput, 0, len(hn.Spec.WebhookProperties.Headers))
 	for k, v := range hn.Spec.WebhookProperties.Headers {
 		action.WebhookAction.Headers = append(action.WebhookAction.Headers,
 			humioapi.HttpHeaderEntryInput{
@@ -487,3 +571,46 @@ func stringInList(s string, l []string) bool {
 	}
 	return false
 }
+
+// ActionFallbackRelayAlert returns the relay alert which triggers the fallback action of the action whenever Humio logs
+// a failure of the action to the humio-activity repository. It returns nil if the action has no fallback.
+func ActionFallbackRelayAlert(ha *humiov1alpha1.HumioAction) (*humiov1alpha1.HumioAlert, error) {
+	fallback := ha.Spec.Fallback
+	if fallback == nil {
+		return nil, nil
+	}
+	if fallback.ActionName == "" {
+		return nil, fmt.Errorf("property fallback.actionName is required")
+	}
+	relayViewName := fallback.RelayViewName
+	if relayViewName == "" {
+		relayViewName = ActionFallbackRelayViewName
+	}
+	if relayViewName == ha.Spec.ViewName && fallback.ActionName == ha.Spec.Name {
+		return nil, fmt.Errorf("action %s cannot be its own fallback", ha.Spec.Name)
+	}
+	throttleTimeMillis := fallback.ThrottleTimeMillis
+	if throttleTimeMillis == 0 {
+		throttleTimeMillis = ActionFallbackThrottleTimeMillis
+	}
+
+	return &humiov1alpha1.HumioAlert{
+		Spec: humiov1alpha1.HumioAlertSpec{
+			Name:     fmt.Sprintf("fallback-%s-%s", ha.Spec.ViewName, ha.Spec.Name),
+			ViewName: relayViewName,
+			Query: humiov1alpha1.HumioQuery{
+				QueryString: fmt.Sprintf("#category=Alerts #kind=Error actionName=%s viewName=%s",
+					humioStringLiteral(ha.Spec.Name), humioStringLiteral(ha.Spec.ViewName)),
+				Start: "1h",
+			},
+			Description:        fmt.Sprintf("Triggers action %s when action %s in view %s fails. Managed by humio-operator.", fallback.ActionName, ha.Spec.Name, ha.Spec.ViewName),
+			ThrottleTimeMillis: throttleTimeMillis,
+			Actions:            []string{fallback.ActionName},
+		},
+	}, nil
+}
+
+// humioStringLiteral quotes the value as a string literal in the Humio query language
+func humioStringLiteral(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,45 @@
+package humio
+
+import (
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+// AggregateAlertTransform returns the aggregate alert described by the HumioAggregateAlert, with the defaults Humio
+// applies to unset fields filled in so it can be compared to the aggregate alert returned by Humio
+func AggregateAlertTransform(haa *humiov1alpha1.HumioAggregateAlert) *AggregateAlert {
+	aggregateAlert := &AggregateAlert{
+		ID:                    haa.Status.ID,
+		Name:                  haa.Spec.Name,
+		Description:           haa.Spec.Description,
+		QueryString:           haa.Spec.QueryString,
+		SearchIntervalSeconds: haa.Spec.SearchIntervalSeconds,
+		QueryTimestampType:    haa.Spec.QueryTimestampType,
+		TriggerMode:           haa.Spec.TriggerMode,
+		ThrottleTimeSeconds:   haa.Spec.ThrottleTimeSeconds,
+		ThrottleField:         haa.Spec.ThrottleField,
+		Enabled:               !haa.Spec.Silenced,
+		Actions:               copyStrings(haa.Spec.Actions),
+		Labels:                copyStrings(haa.Spec.Labels),
+	}
+	if aggregateAlert.SearchIntervalSeconds == 0 {
+		aggregateAlert.SearchIntervalSeconds = 3600
+	}
+	if aggregateAlert.QueryTimestampType == "" {
+		aggregateAlert.QueryTimestampType = humiov1alpha1.HumioQueryTimestampTypeEvent
+	}
+	if aggregateAlert.TriggerMode == "" {
+		aggregateAlert.TriggerMode = humiov1alpha1.HumioTriggerModeComplete
+	}
+	if aggregateAlert.ThrottleTimeSeconds == 0 {
+		aggregateAlert.ThrottleTimeSeconds = aggregateAlert.SearchIntervalSeconds
+	}
+	return aggregateAlert
+}
+
+// copyStrings returns a copy of the strings, or nil if there are none, matching how lists returned by Humio are read
+func copyStrings(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
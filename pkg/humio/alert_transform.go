@@ -61,10 +61,11 @@ func AlertHydrate(ha *humiov1alpha1.HumioAlert, alert *humioapi.Alert, actionIdM
 func actionIdsFromActionMap(actionList []string, actionIdMap map[string]string) []string {
 	var actionIds []string
 	for _, action := range actionList {
-		for actionName, actionId := range actionIdMap {
-			if actionName == action {
-				actionIds = append(actionIds, actionId)
+		if actionId, ok := actionIdMap[action]; ok {
+			if actionIds == nil {
+				actionIds = make([]string, 0, len(actionList))
 			}
+			actionIds = append(actionIds, actionId)
 		}
 	}
 	return actionIds
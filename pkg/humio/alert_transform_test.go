@@ -0,0 +1,57 @@
+package humio
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+func TestActionIdsFromActionMap(t *testing.T) {
+	actionIdMap := map[string]string{"email": "id-email", "slack": "id-slack", "webhook": "id-webhook"}
+	tests := []struct {
+		name       string
+		actionList []string
+		want       []string
+	}{
+		{"keeps order of the alert actions", []string{"webhook", "email"}, []string{"id-webhook", "id-email"}},
+		{"skips unknown actions", []string{"email", "missing"}, []string{"id-email"}},
+		{"no known actions", []string{"missing"}, nil},
+		{"no actions", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := actionIdsFromActionMap(tt.actionList, actionIdMap); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("actionIdsFromActionMap() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkAlertTransform(b *testing.B) {
+	actionIdMap := make(map[string]string, 500)
+	for i := 0; i < 500; i++ {
+		actionIdMap[fmt.Sprintf("action-%d", i)] = fmt.Sprintf("id-%d", i)
+	}
+	ha := &humiov1alpha1.HumioAlert{
+		Spec: humiov1alpha1.HumioAlertSpec{
+			Name:     "alert",
+			ViewName: "humio",
+			Query: humiov1alpha1.HumioQuery{
+				QueryString: "#repo = humio | error = true",
+				Start:       "1h",
+			},
+			Actions: []string{"action-1", "action-100", "action-250", "action-499"},
+			Labels:  []string{"team-a", "severity-high"},
+		},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := AlertTransform(ha, actionIdMap); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
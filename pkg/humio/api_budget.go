@@ -0,0 +1,162 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// apiBudgetReservedFraction is the part of the budget of each cluster which is held back for priority calls
+	apiBudgetReservedFraction = 0.2
+
+	// apiBudgetMaxWait is how long a call waits for the budget before giving up, so the reconcile fails and is requeued
+	// instead of holding on to a worker
+	apiBudgetMaxWait = 30 * time.Second
+)
+
+// apiBudget limits the rate of calls against each Humio cluster using a token bucket per cluster. Priority calls may
+// use the tokens held back from other calls, and other calls wait while priority calls are waiting. A nil apiBudget
+// is valid and never limits any calls.
+type apiBudget struct {
+	rate     float64
+	burst    float64
+	reserved float64
+
+	mutex   sync.Mutex
+	buckets map[string]*apiBucket
+}
+
+type apiBucket struct {
+	tokens          float64
+	last            time.Time
+	priorityWaiting int
+}
+
+func newAPIBudget(requestsPerSecond float64) *apiBudget {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	burst := math.Max(requestsPerSecond, 1)
+	return &apiBudget{
+		rate:     requestsPerSecond,
+		burst:    burst,
+		reserved: math.Floor(burst * apiBudgetReservedFraction),
+		buckets:  map[string]*apiBucket{},
+	}
+}
+
+// wait blocks until a call against the given cluster fits within the budget, or returns an error if the context is
+// done first
+func (b *apiBudget) wait(ctx context.Context, cluster string, priority bool) error {
+	if b == nil {
+		return nil
+	}
+	waiting := false
+	for {
+		delay := b.take(cluster, priority, waiting, time.Now())
+		if delay == 0 {
+			return nil
+		}
+		waiting = true
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if priority {
+				b.stopWaiting(cluster)
+			}
+			return fmt.Errorf("gave up waiting for the api budget of %s: %w", cluster, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// stopWaiting records that a priority call against the given cluster gave up waiting, so other calls no longer wait
+// for it
+func (b *apiBudget) stopWaiting(cluster string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if bucket, ok := b.buckets[cluster]; ok && bucket.priorityWaiting > 0 {
+		bucket.priorityWaiting--
+	}
+}
+
+// chargeAPIBudget returns a proxy function for a transport which makes every request wait for the api budget of the
+// given cluster before it is sent, and then looks up the proxy for the request using the given proxy function. A
+// request which gives up waiting, at the latest at the given deadline if there is one, fails without reaching Humio.
+func (h *ClientConfig) chargeAPIBudget(cluster string, proxy func(*http.Request) (*url.URL, error), deadline time.Time) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		waitUntil := time.Now().Add(apiBudgetMaxWait)
+		if !deadline.IsZero() && deadline.Before(waitUntil) {
+			waitUntil = deadline
+		}
+		ctx, cancel := context.WithDeadline(req.Context(), waitUntil)
+		err := h.apiBudget.wait(ctx, cluster, h.priority)
+		cancel()
+		if err != nil {
+			h.logger.Info("api budget exceeded, failing call against the Humio API", "host", cluster)
+			return nil, err
+		}
+		if proxy == nil {
+			return nil, nil
+		}
+		return proxy(req)
+	}
+}
+
+// take takes a token from the bucket of the given cluster if one is available to the call, and otherwise returns how
+// long to wait before trying again. Waiting tells whether the call has already been waiting for a token.
+func (b *apiBudget) take(cluster string, priority, waiting bool, now time.Time) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	bucket, ok := b.buckets[cluster]
+	if !ok {
+		bucket = &apiBucket{tokens: b.burst, last: now}
+		b.buckets[cluster] = bucket
+	}
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens = math.Min(b.burst, bucket.tokens+elapsed.Seconds()*b.rate)
+		bucket.last = now
+	}
+
+	floor := 0.0
+	if !priority {
+		if bucket.priorityWaiting > 0 {
+			return time.Duration(float64(time.Second) / b.rate)
+		}
+		floor = b.reserved
+	}
+	if bucket.tokens >= floor+1 {
+		bucket.tokens--
+		if priority && waiting {
+			bucket.priorityWaiting--
+		}
+		return 0
+	}
+	if priority && !waiting {
+		bucket.priorityWaiting++
+	}
+	return time.Duration((floor + 1 - bucket.tokens) / b.rate * float64(time.Second))
+}
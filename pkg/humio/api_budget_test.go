@@ -0,0 +1,123 @@
+package humio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	"github.com/humio/humio-operator/pkg/humio/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestAPIBudget(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	budget := newAPIBudget(10)
+
+	// Other calls leave the reserved part of the budget to priority calls
+	for i := 0; i < 8; i++ {
+		if delay := budget.take("humio", false, false, now); delay != 0 {
+			t.Fatalf("expected call %d to fit within the budget, got delay %s", i, delay)
+		}
+	}
+	if delay := budget.take("humio", false, false, now); delay != 100*time.Millisecond {
+		t.Errorf("expected call to wait for the budget to refill, got delay %s", delay)
+	}
+	for i := 0; i < 2; i++ {
+		if delay := budget.take("humio", true, false, now); delay != 0 {
+			t.Fatalf("expected priority call %d to use the reserved budget, got delay %s", i, delay)
+		}
+	}
+
+	// Budgets are tracked per cluster
+	if delay := budget.take("other", false, false, now); delay != 0 {
+		t.Errorf("expected call against another cluster to fit within its own budget, got delay %s", delay)
+	}
+
+	// Other calls wait while priority calls are waiting
+	if delay := budget.take("humio", true, false, now); delay != 100*time.Millisecond {
+		t.Errorf("expected priority call to wait for the budget to refill, got delay %s", delay)
+	}
+	now = now.Add(time.Second)
+	if delay := budget.take("humio", false, true, now); delay == 0 {
+		t.Error("expected call to wait while a priority call is waiting")
+	}
+	if delay := budget.take("humio", true, true, now); delay != 0 {
+		t.Errorf("expected waiting priority call to fit within the refilled budget, got delay %s", delay)
+	}
+	if delay := budget.take("humio", false, true, now); delay != 0 {
+		t.Errorf("expected call to fit within the budget once no priority calls are waiting, got delay %s", delay)
+	}
+}
+
+func TestAPIBudgetDisabled(t *testing.T) {
+	budget := newAPIBudget(0)
+	if budget != nil {
+		t.Fatal("expected budget to be disabled")
+	}
+	if err := budget.wait(context.Background(), "humio", false); err != nil {
+		t.Errorf("expected disabled budget not to limit calls, got %s", err)
+	}
+}
+
+func TestAPIBudgetWaitCancelled(t *testing.T) {
+	budget := newAPIBudget(1)
+	if err := budget.wait(context.Background(), "humio", true); err != nil {
+		t.Fatalf("expected first call to fit within the budget, got %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := budget.wait(ctx, "humio", true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected wait to give up once the context is done, got %v", err)
+	}
+	if waiting := budget.buckets["humio"].priorityWaiting; waiting != 0 {
+		t.Errorf("expected priority call which gave up to no longer be waiting, got %d waiting", waiting)
+	}
+}
+
+func TestPriorityClient(t *testing.T) {
+	client := NewClient(logr.Discard(), &humioapi.Config{}, "test").WithAPIBudget(10)
+	priorityClient := client.PriorityClient()
+	if !priorityClient.priority || client.priority {
+		t.Error("expected only the priority client to make priority calls")
+	}
+	if priorityClient.apiBudget != client.apiBudget || priorityClient.readCache != client.readCache || priorityClient.transportsMutex != client.transportsMutex {
+		t.Error("expected the priority client to share the budget, read cache and transport pool")
+	}
+}
+
+func TestAPIBudgetChargedPerRequest(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	config := server.Config()
+	client := NewClient(logr.Discard(), config, "test").WithAPIBudget(1000)
+
+	// A single client sending several requests is charged for each of them
+	humioClient := client.GetHumioClient(config, reconcile.Request{})
+	for i := 0; i < 3; i++ {
+		if _, err := humioClient.Views().List(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tokens := client.apiBudget.buckets[config.Address.Host].tokens; tokens > 998 {
+		t.Errorf("expected every request to be charged to the budget, got %f tokens left", tokens)
+	}
+
+	// Requests which give up waiting for the budget fail without reaching Humio
+	client.apiBudget.buckets[config.Address.Host].tokens = 0
+	client.apiBudget.rate = 0.001
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetHumioClient(config, reconcile.Request{}).HTTPRequestContext(ctx, "GET", "api/v1/status", nil, humioapi.JSONContentType); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected request to give up waiting for the budget, got %v", err)
+	}
+
+	// Priority calls are sent using their own transport, which charges them as priority calls
+	priorityClient := client.PriorityClient()
+	if client.getTransport(*config) == priorityClient.getTransport(*config) {
+		t.Error("expected priority calls to use their own transport")
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const auditSinkBufferSize = 1000
+
+// HumioIngestAuditSink ships audit records to a Humio repository using the structured ingest API. Records are sent
+// in the background, so an unavailable audit repository never blocks reconciles. Records are dropped and logged if
+// the buffer is full.
+type HumioIngestAuditSink struct {
+	logger     logr.Logger
+	url        string
+	token      string
+	httpClient *http.Client
+	records    chan AuditRecord
+}
+
+// NewHumioIngestAuditSink returns a sink which ingests audit records into the repository of the given ingest token
+// on the Humio cluster at the given URL
+func NewHumioIngestAuditSink(logger logr.Logger, url, token string) *HumioIngestAuditSink {
+	s := &HumioIngestAuditSink{
+		logger:     logger.WithName("audit-sink"),
+		url:        strings.TrimSuffix(url, "/") + "/api/v1/ingest/humio-structured",
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		records:    make(chan AuditRecord, auditSinkBufferSize),
+	}
+	go s.run()
+	return s
+}
+
+// Write queues the record for ingestion
+func (s *HumioIngestAuditSink) Write(record AuditRecord) {
+	select {
+	case s.records <- record:
+	default:
+		s.logger.Info("dropping audit record as the buffer is full", "Audit.Kind", record.Kind,
+			"Audit.Namespace", record.Namespace, "Audit.Name", record.Name, "Audit.Operation", record.Operation)
+	}
+}
+
+func (s *HumioIngestAuditSink) run() {
+	for record := range s.records {
+		if err := s.send(record); err != nil {
+			s.logger.Error(err, "unable to ingest audit record", "Audit.Kind", record.Kind,
+				"Audit.Namespace", record.Namespace, "Audit.Name", record.Name, "Audit.Operation", record.Operation)
+		}
+	}
+}
+
+type humioStructuredEvent struct {
+	Timestamp  string      `json:"timestamp"`
+	Attributes AuditRecord `json:"attributes"`
+}
+
+type humioStructuredEvents struct {
+	Tags   map[string]string      `json:"tags"`
+	Events []humioStructuredEvent `json:"events"`
+}
+
+func (s *HumioIngestAuditSink) send(record AuditRecord) error {
+	body, err := json.Marshal([]humioStructuredEvents{{
+		Tags: map[string]string{"source": "humio-operator"},
+		Events: []humioStructuredEvent{{
+			Timestamp:  record.Time.Format(time.RFC3339Nano),
+			Attributes: record,
+		}},
+	}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected response from %s: %s", s.url, resp.Status)
+	}
+	return nil
+}
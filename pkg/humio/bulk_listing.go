@@ -0,0 +1,322 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	graphql "github.com/cli/shurcooL-graphql"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// maxBatchSize is the largest number of writes sent to Humio in a single request
+const maxBatchSize = 50
+
+// WithBulkListing makes lookups of alerts, actions and parsers fetch all alerts, actions or parsers of the view or
+// repository in a single call, and lookups of repositories and views fetch all repositories or views of the cluster in
+// a single call. The listings are stored in the read cache, so reconciling every entity in a view costs one list call
+// per cache period instead of one call per custom resource. Bulk listing requires the read cache to be enabled.
+//
+// Alerts and parsers which have drifted from their custom resources can be updated together using UpdateAlerts and
+// UpdateParsers, which send up to maxBatchSize updates in each request.
+func (h *ClientConfig) WithBulkListing(enabled bool) *ClientConfig {
+	h.bulkListing = enabled
+	return h
+}
+
+func (h *ClientConfig) bulkListingEnabled() bool {
+	return h.bulkListing && h.readCache != nil
+}
+
+// bulkGetAlert looks up an alert from the listing of all alerts in the view, listing the alerts if needed
+func (h *ClientConfig) bulkGetAlert(config *humioapi.Config, req reconcile.Request, viewName, alertName string) (*humioapi.Alert, error) {
+	key := newReadCacheKey(config, readCacheKindAlert, viewName, alertName)
+	if _, listed := h.readCache.get(key.scopeKey()); !listed {
+		alerts, err := h.GetHumioClient(config, req).Alerts().List(viewName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list alerts: %w", err)
+		}
+		// The scope key is set before the entries so it never outlives them
+		h.readCache.set(key.scopeKey(), struct{}{})
+		for _, alert := range alerts {
+			h.readCache.set(newReadCacheKey(config, readCacheKindAlert, viewName, alert.Name), alert)
+		}
+	}
+
+	if cached, ok := h.readCache.get(key); ok {
+		alert := cached.(humioapi.Alert)
+		return &alert, nil
+	}
+	return nil, humioapi.AlertNotFound(alertName)
+}
+
+// bulkGetAction looks up an action from the listing of all actions in the view, listing the actions if needed
+func (h *ClientConfig) bulkGetAction(config *humioapi.Config, req reconcile.Request, viewName, actionName string) (*humioapi.Action, error) {
+	if _, err := h.listActions(config, req, viewName); err != nil {
+		return nil, err
+	}
+
+	if cached, ok := h.readCache.get(newReadCacheKey(config, readCacheKindAction, viewName, actionName)); ok {
+		action := cached.(humioapi.Action)
+		return &action, nil
+	}
+	return nil, humioapi.ActionNotFound(actionName)
+}
+
+// listActions returns all actions in the view. The listing is stored in the read cache along with each of the
+// actions, so every alert and action in the view shares a single list call per cache period.
+func (h *ClientConfig) listActions(config *humioapi.Config, req reconcile.Request, viewName string) ([]humioapi.Action, error) {
+	scopeKey := newReadCacheKey(config, readCacheKindAction, viewName, "")
+	if cached, listed := h.readCache.get(scopeKey); listed {
+		return cached.([]humioapi.Action), nil
+	}
+
+	actions, err := h.GetHumioClient(config, req).Actions().List(viewName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list actions: %w", err)
+	}
+	// The scope key is set before the entries so it never outlives them
+	h.readCache.set(scopeKey, actions)
+	for _, action := range actions {
+		h.readCache.set(newReadCacheKey(config, readCacheKindAction, viewName, action.Name), action)
+	}
+	return actions, nil
+}
+
+// bulkGetParser looks up a parser from the listing of all parsers in the repository, listing the parsers if needed
+func (h *ClientConfig) bulkGetParser(config *humioapi.Config, req reconcile.Request, repositoryName, parserName string) (*humioapi.Parser, error) {
+	key := newReadCacheKey(config, readCacheKindParser, repositoryName, parserName)
+	if _, listed := h.readCache.get(key.scopeKey()); !listed {
+		var query struct {
+			Repository struct {
+				Parsers []struct {
+					ID         string
+					Name       string
+					SourceCode string
+					TestData   []string
+					TagFields  []string
+				} `graphql:"parsers"`
+			} `graphql:"repository(name: $repositoryName)"`
+		}
+		err := h.GetHumioClient(config, req).Query(&query, map[string]interface{}{
+			"repositoryName": graphql.String(repositoryName),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list parsers: %w", err)
+		}
+		// The scope key is set before the entries so it never outlives them
+		h.readCache.set(key.scopeKey(), struct{}{})
+		for _, p := range query.Repository.Parsers {
+			h.readCache.set(newReadCacheKey(config, readCacheKindParser, repositoryName, p.Name), humioapi.Parser{
+				ID:        p.ID,
+				Name:      p.Name,
+				Tests:     p.TestData,
+				Script:    p.SourceCode,
+				TagFields: p.TagFields,
+			})
+		}
+	}
+
+	if cached, ok := h.readCache.get(key); ok {
+		parser := cached.(humioapi.Parser)
+		return &parser, nil
+	}
+	return nil, humioapi.ParserNotFound(parserName)
+}
+
+// bulkGetRepository looks up a repository from the listing of all repositories of the cluster, listing the
+// repositories if needed. An empty repository is returned if the repository does not exist.
+func (h *ClientConfig) bulkGetRepository(config *humioapi.Config, req reconcile.Request, name string) (*humioapi.Repository, error) {
+	key := newReadCacheKey(config, readCacheKindRepository, "", name)
+	if _, listed := h.readCache.get(key.scopeKey()); !listed {
+		var query struct {
+			Repositories []humioapi.Repository `graphql:"repositories"`
+		}
+		if err := h.GetHumioClient(config, req).Query(&query, nil); err != nil {
+			return &humioapi.Repository{}, fmt.Errorf("unable to list repositories: %w", err)
+		}
+		// The scope key is set before the entries so it never outlives them
+		h.readCache.set(key.scopeKey(), struct{}{})
+		for _, repository := range query.Repositories {
+			h.readCache.set(newReadCacheKey(config, readCacheKindRepository, "", repository.Name), repository)
+		}
+	}
+
+	if cached, ok := h.readCache.get(key); ok {
+		repository := cached.(humioapi.Repository)
+		return &repository, nil
+	}
+	return &humioapi.Repository{}, nil
+}
+
+// bulkGetView looks up a view from the listing of all views and repositories of the cluster, listing them if needed.
+// Views may also be looked up by the name of a repository. An empty view is returned if the view does not exist.
+func (h *ClientConfig) bulkGetView(config *humioapi.Config, req reconcile.Request, name string) (*humioapi.View, error) {
+	key := newReadCacheKey(config, readCacheKindView, "", name)
+	if _, listed := h.readCache.get(key.scopeKey()); !listed {
+		var query struct {
+			SearchDomains []humioapi.ViewQueryData `graphql:"searchDomains"`
+		}
+		if err := h.GetHumioClient(config, req).Query(&query, nil); err != nil {
+			return &humioapi.View{}, fmt.Errorf("unable to list views: %w", err)
+		}
+		// The scope key is set before the entries so it never outlives them
+		h.readCache.set(key.scopeKey(), struct{}{})
+		for _, sd := range query.SearchDomains {
+			connections := make([]humioapi.ViewConnection, len(sd.ViewInfo.Connections))
+			for i, connection := range sd.ViewInfo.Connections {
+				connections[i] = humioapi.ViewConnection{
+					RepoName: connection.Repository.Name,
+					Filter:   connection.Filter,
+				}
+			}
+			h.readCache.set(newReadCacheKey(config, readCacheKindView, "", sd.Name), humioapi.View{
+				Name:        sd.Name,
+				Description: sd.Description,
+				Connections: connections,
+			})
+		}
+	}
+
+	if cached, ok := h.readCache.get(key); ok {
+		view := cached.(humioapi.View)
+		return &view, nil
+	}
+	return &humioapi.View{}, nil
+}
+
+// UpdateAlerts updates the alerts of the given HumioAlerts to match their specs. The updates are sent in batches of up
+// to maxBatchSize in a single request. All the alerts must already exist.
+func (h *ClientConfig) UpdateAlerts(config *humioapi.Config, req reconcile.Request, has []*humiov1alpha1.HumioAlert) error {
+	mutations := make([]batchedMutation, 0, len(has))
+	for _, ha := range has {
+		defer h.readCache.invalidate(newReadCacheKey(config, readCacheKindAlert, ha.Spec.ViewName, ha.Spec.Name))
+
+		actionIdMap, err := h.GetActionIDsMapForAlerts(config, req, ha)
+		if err != nil {
+			return fmt.Errorf("could not get action id mapping: %w", err)
+		}
+		alert, err := AlertTransform(ha, actionIdMap)
+		if err != nil {
+			return err
+		}
+		currentAlert, err := h.GetAlert(config, req, ha)
+		if err != nil || currentAlert == nil {
+			return fmt.Errorf("could not find alert with name: %q", alert.Name)
+		}
+
+		var throttleField *graphql.String
+		if alert.ThrottleField != "" {
+			field := graphql.String(alert.ThrottleField)
+			throttleField = &field
+		}
+		mutations = append(mutations, batchedMutation{
+			field: "updateAlert(input: { id: $id, viewName: $viewName, name: $alertName, description: $description, queryString: $queryString, queryStart: $queryStart, throttleTimeMillis: $throttleTimeMillis, throttleField: $throttleField, enabled: $enabled, actions: $actions, labels: $labels })",
+			variables: map[string]interface{}{
+				"id":                 graphql.String(currentAlert.ID),
+				"viewName":           graphql.String(ha.Spec.ViewName),
+				"alertName":          graphql.String(alert.Name),
+				"description":        graphql.String(alert.Description),
+				"queryString":        graphql.String(alert.QueryString),
+				"queryStart":         graphql.String(alert.QueryStart),
+				"throttleField":      throttleField,
+				"throttleTimeMillis": humioapi.Long(alert.ThrottleTimeMillis),
+				"enabled":            graphql.Boolean(alert.Enabled),
+				"actions":            graphqlStrings(alert.Actions),
+				"labels":             graphqlStrings(alert.Labels),
+			},
+		})
+	}
+	if err := mutateBatch(h.GetHumioClient(config, req), mutations); err != nil {
+		return fmt.Errorf("got error when attempting to update alerts: %w", err)
+	}
+	return nil
+}
+
+// UpdateParsers updates the parsers of the given HumioParsers to match their specs. The updates are sent in batches of
+// up to maxBatchSize in a single request.
+func (h *ClientConfig) UpdateParsers(config *humioapi.Config, req reconcile.Request, hps []*humiov1alpha1.HumioParser) error {
+	mutations := make([]batchedMutation, 0, len(hps))
+	for _, hp := range hps {
+		defer h.readCache.invalidate(newReadCacheKey(config, readCacheKindParser, hp.Spec.RepositoryName, hp.Spec.Name))
+
+		mutations = append(mutations, batchedMutation{
+			field: "createParser(input: { name: $name, repositoryName: $repositoryName, testData: $testData, tagFields: $tagFields, sourceCode: $sourceCode, force: $force })",
+			variables: map[string]interface{}{
+				"name":           graphql.String(hp.Spec.Name),
+				"repositoryName": graphql.String(hp.Spec.RepositoryName),
+				"testData":       graphqlStrings(hp.Spec.TestData),
+				"tagFields":      graphqlStrings(hp.Spec.TagFields),
+				"sourceCode":     graphql.String(hp.Spec.ParserScript),
+				"force":          graphql.Boolean(true),
+			},
+		})
+	}
+	if err := mutateBatch(h.GetHumioClient(config, req), mutations); err != nil {
+		return fmt.Errorf("got error when attempting to update parsers: %w", err)
+	}
+	return nil
+}
+
+// batchedMutation is a single mutation field sent along with other mutations in a single request. The variables are
+// referred to by name in the field, e.g. $name.
+type batchedMutation struct {
+	field     string
+	variables map[string]interface{}
+}
+
+var batchVariablePattern = regexp.MustCompile(`\$(\w+)`)
+
+// mutateBatch sends the mutations in requests of up to maxBatchSize mutations each. Each mutation is given its own
+// alias, and its variables are suffixed with its position in the request, so the same mutation can be sent many times
+// in one request.
+func mutateBatch(client *humioapi.Client, mutations []batchedMutation) error {
+	for start := 0; start < len(mutations); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(mutations) {
+			end = len(mutations)
+		}
+
+		fields := make([]reflect.StructField, 0, end-start)
+		variables := map[string]interface{}{}
+		for i, mutation := range mutations[start:end] {
+			suffix := fmt.Sprintf("_%d", i)
+			fields = append(fields, reflect.StructField{
+				Name: fmt.Sprintf("Mutation%d", i),
+				// We have to make a selection, so just take __typename
+				Type: reflect.TypeOf(struct {
+					Typename graphql.String `graphql:"__typename"`
+				}{}),
+				Tag: reflect.StructTag(fmt.Sprintf(`graphql:"m%d: %s"`, i, batchVariablePattern.ReplaceAllString(mutation.field, "$$${1}"+suffix))),
+			})
+			for name, value := range mutation.variables {
+				variables[name+suffix] = value
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if err := client.Mutate(reflect.New(reflect.StructOf(fields)).Interface(), variables); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package humio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestListActionsIsSharedAcrossLookups(t *testing.T) {
+	var listCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&listCalls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"searchDomain":{"actions":[{"__typename":"EmailAction","id":"a1","name":"email"}]}}}`))
+	}))
+	defer server.Close()
+	address, _ := url.Parse(server.URL + "/")
+	config := &humioapi.Config{Address: address, Token: "token"}
+
+	tt := []struct {
+		name          string
+		readCacheTTL  time.Duration
+		expectedCalls int32
+	}{
+		{"read cache disabled", 0, 3},
+		{"read cache enabled", time.Hour, 1},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(&listCalls, 0)
+			h := NewClient(logr.Discard(), &humioapi.Config{}, "").WithReadCache(tc.readCacheTTL)
+			for i := 0; i < 3; i++ {
+				actions, err := h.listActions(config, reconcile.Request{}, "logs")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(actions) != 1 || actions[0].ID != "a1" {
+					t.Fatalf("expected the listed action, got %+v", actions)
+				}
+			}
+			if calls := atomic.LoadInt32(&listCalls); calls != tc.expectedCalls {
+				t.Errorf("expected %d list calls, got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+
+	h := NewClient(logr.Discard(), &humioapi.Config{}, "").WithReadCache(time.Hour)
+	atomic.StoreInt32(&listCalls, 0)
+	if _, err := h.listActions(config, reconcile.Request{}, "logs"); err != nil {
+		t.Fatal(err)
+	}
+	if action, err := h.bulkGetAction(config, reconcile.Request{}, "logs", "email"); err != nil || action.ID != "a1" {
+		t.Errorf("expected the listed action to be looked up without listing again, got %+v, %v", action, err)
+	}
+	h.readCache.invalidate(newReadCacheKey(config, readCacheKindAction, "logs", "email"))
+	if _, err := h.listActions(config, reconcile.Request{}, "logs"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := atomic.LoadInt32(&listCalls); calls != 2 {
+		t.Errorf("expected the actions to be listed again after a change to an action, got %d list calls", calls)
+	}
+}
+
+// countingServer counts the queries and mutations sent to the fake API
+type countingServer struct {
+	*httptest.Server
+	queries   int32
+	mutations int32
+}
+
+func newCountingServer(server *fake.Server) *countingServer {
+	c := &countingServer{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if strings.Contains(string(body), `"query":"mutation`) {
+			atomic.AddInt32(&c.mutations, 1)
+		} else {
+			atomic.AddInt32(&c.queries, 1)
+		}
+		server.ServeHTTP(w, r)
+	}))
+	return c
+}
+
+func (c *countingServer) config() *humioapi.Config {
+	address, _ := url.Parse(c.URL + "/")
+	return &humioapi.Config{Address: address, Token: fake.Token}
+}
+
+func (c *countingServer) reset() {
+	atomic.StoreInt32(&c.queries, 0)
+	atomic.StoreInt32(&c.mutations, 0)
+}
+
+func TestBulkListingLooksUpEntitiesInOneCall(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	counting := newCountingServer(server)
+	defer counting.Close()
+	config := counting.config()
+	req := reconcile.Request{}
+	h := NewClient(logr.Discard(), config, "").WithReadCache(time.Hour).WithBulkListing(true)
+
+	repository := &humiov1alpha1.HumioRepository{Spec: humiov1alpha1.HumioRepositorySpec{Name: "logs"}}
+	if _, err := h.AddRepository(config, req, repository); err != nil {
+		t.Fatal(err)
+	}
+	view := &humiov1alpha1.HumioView{Spec: humiov1alpha1.HumioViewSpec{
+		Name:        "web",
+		Connections: []humiov1alpha1.HumioViewConnection{{RepositoryName: "logs", Filter: "*"}},
+	}}
+	if _, err := h.AddView(config, req, view); err != nil {
+		t.Fatal(err)
+	}
+	var parsers []*humiov1alpha1.HumioParser
+	for _, name := range []string{"access", "audit", "error"} {
+		hp := &humiov1alpha1.HumioParser{Spec: humiov1alpha1.HumioParserSpec{Name: name, RepositoryName: "logs", ParserScript: "kvParse()"}}
+		if _, err := h.AddParser(config, req, hp); err != nil {
+			t.Fatal(err)
+		}
+		parsers = append(parsers, hp)
+	}
+
+	counting.reset()
+	for _, hp := range parsers {
+		parser, err := h.GetParser(config, req, hp)
+		if err != nil || parser.Name != hp.Spec.Name || parser.Script != "kvParse()" {
+			t.Fatalf("expected parser %s to be listed, got %+v, %v", hp.Spec.Name, parser, err)
+		}
+	}
+	missingParser := &humiov1alpha1.HumioParser{Spec: humiov1alpha1.HumioParserSpec{Name: "missing", RepositoryName: "logs"}}
+	if _, err := h.GetParser(config, req, missingParser); !errors.As(err, &humioapi.EntityNotFound{}) {
+		t.Errorf("expected a missing parser to be reported as not found, got %v", err)
+	}
+	if got, err := h.GetRepository(config, req, repository); err != nil || got.Name != "logs" {
+		t.Errorf("expected the repository to be listed, got %+v, %v", got, err)
+	}
+	missingRepository := &humiov1alpha1.HumioRepository{Spec: humiov1alpha1.HumioRepositorySpec{Name: "missing"}}
+	if got, err := h.GetRepository(config, req, missingRepository); err != nil || got.Name != "" {
+		t.Errorf("expected a missing repository to be empty, got %+v, %v", got, err)
+	}
+	got, err := h.GetView(config, req, view)
+	if err != nil || got.Name != "web" || len(got.Connections) != 1 || got.Connections[0].RepoName != "logs" {
+		t.Errorf("expected the view to be listed with its connections, got %+v, %v", got, err)
+	}
+	if queries := atomic.LoadInt32(&counting.queries); queries != 3 {
+		t.Errorf("expected one list call each for parsers, repositories and views, got %d calls", queries)
+	}
+
+	counting.reset()
+	for _, hp := range parsers {
+		hp.Spec.ParserScript = "parseJson()"
+	}
+	if err := h.UpdateParsers(config, req, parsers); err != nil {
+		t.Fatal(err)
+	}
+	if mutations := atomic.LoadInt32(&counting.mutations); mutations != 1 {
+		t.Errorf("expected the parsers to be updated in a single call, got %d calls", mutations)
+	}
+	for _, hp := range parsers {
+		if parser, err := h.GetParser(config, req, hp); err != nil || parser.Script != "parseJson()" {
+			t.Errorf("expected parser %s to be updated, got %+v, %v", hp.Spec.Name, parser, err)
+		}
+	}
+
+	var alerts []*humiov1alpha1.HumioAlert
+	for _, name := range []string{"errors", "latency", "traffic"} {
+		ha := &humiov1alpha1.HumioAlert{Spec: humiov1alpha1.HumioAlertSpec{
+			Name:     name,
+			ViewName: "web",
+			Query:    humiov1alpha1.HumioQuery{QueryString: "count()"},
+		}}
+		if _, err := h.AddAlert(config, req, ha); err != nil {
+			t.Fatal(err)
+		}
+		alerts = append(alerts, ha)
+	}
+	counting.reset()
+	for _, ha := range alerts {
+		ha.Spec.Query.QueryString = "count() | _count > 10"
+	}
+	if err := h.UpdateAlerts(config, req, alerts); err != nil {
+		t.Fatal(err)
+	}
+	if mutations := atomic.LoadInt32(&counting.mutations); mutations != 1 {
+		t.Errorf("expected the alerts to be updated in a single call, got %d calls", mutations)
+	}
+	for _, ha := range alerts {
+		if alert, err := h.GetAlert(config, req, ha); err != nil || alert.QueryString != "count() | _count > 10" {
+			t.Errorf("expected alert %s to be updated, got %+v, %v", ha.Spec.Name, alert, err)
+		}
+	}
+}
+
+func TestMutateBatchSplitsLargeBatches(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	counting := newCountingServer(server)
+	defer counting.Close()
+	config := counting.config()
+	req := reconcile.Request{}
+	h := NewClient(logr.Discard(), config, "").WithReadCache(time.Hour).WithBulkListing(true)
+
+	if _, err := h.AddRepository(config, req, &humiov1alpha1.HumioRepository{Spec: humiov1alpha1.HumioRepositorySpec{Name: "logs"}}); err != nil {
+		t.Fatal(err)
+	}
+	parsers := make([]*humiov1alpha1.HumioParser, maxBatchSize+1)
+	for i := range parsers {
+		parsers[i] = &humiov1alpha1.HumioParser{Spec: humiov1alpha1.HumioParserSpec{
+			Name:           strings.Repeat("p", i+1),
+			RepositoryName: "logs",
+			ParserScript:   "kvParse()",
+		}}
+	}
+	counting.reset()
+	if err := h.UpdateParsers(config, req, parsers); err != nil {
+		t.Fatal(err)
+	}
+	if mutations := atomic.LoadInt32(&counting.mutations); mutations != 2 {
+		t.Errorf("expected %d parsers to be updated in 2 calls, got %d calls", len(parsers), mutations)
+	}
+	for _, hp := range parsers {
+		if _, err := h.GetParser(config, req, hp); err != nil {
+			t.Errorf("expected parser %s to exist, got %v", hp.Spec.Name, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// circuitBreakerFailureThreshold is the number of consecutive connection failures towards a Humio cluster before
+	// the circuit breaker opens
+	circuitBreakerFailureThreshold = 3
+	// circuitBreakerProbeInterval is how often an open circuit breaker probes the Humio cluster for recovery
+	circuitBreakerProbeInterval = 10 * time.Second
+)
+
+// ErrClusterUnavailable is returned by calls against a Humio cluster while the circuit breaker for that cluster is open
+var ErrClusterUnavailable = errors.New("humio cluster unavailable")
+
+type dialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// circuitBreaker tracks connection failures towards a single Humio cluster. Once the number of consecutive failures
+// reaches failureThreshold the breaker opens, and all new connections fail fast with ErrClusterUnavailable until a
+// background probe is able to connect to the cluster again.
+type circuitBreaker struct {
+	host             string
+	failureThreshold int
+	probeInterval    time.Duration
+	probeDialer      dialContextFunc
+	logger           logr.Logger
+
+	mutex    sync.Mutex
+	failures int
+	open     bool
+}
+
+func newCircuitBreaker(logger logr.Logger, host string) *circuitBreaker {
+	return &circuitBreaker{
+		host:             host,
+		failureThreshold: circuitBreakerFailureThreshold,
+		probeInterval:    circuitBreakerProbeInterval,
+		probeDialer:      (&net.Dialer{Timeout: circuitBreakerProbeInterval}).DialContext,
+		logger:           logger.WithValues("CircuitBreaker.Host", host),
+	}
+}
+
+// IsOpen returns whether connections towards the cluster are currently failing fast
+func (cb *circuitBreaker) IsOpen() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.open
+}
+
+// DialContext wraps the given dial function so connection attempts are tracked by the circuit breaker. If dial is
+// nil, a default net.Dialer is used.
+func (cb *circuitBreaker) DialContext(dial dialContextFunc) dialContextFunc {
+	if dial == nil {
+		dial = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if cb.IsOpen() {
+			return nil, fmt.Errorf("%w: circuit breaker open for %s", ErrClusterUnavailable, cb.host)
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			// Connection attempts cancelled by the caller say nothing about the health of the cluster
+			if ctx.Err() == nil {
+				cb.recordFailure(network, addr)
+			}
+			return nil, err
+		}
+		cb.recordSuccess()
+		return conn, nil
+	}
+}
+
+func (cb *circuitBreaker) recordSuccess() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.failures = 0
+}
+
+func (cb *circuitBreaker) recordFailure(network, addr string) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.failures++
+	if cb.open || cb.failures < cb.failureThreshold {
+		return
+	}
+	cb.logger.Info(fmt.Sprintf("opening circuit breaker after %d consecutive connection failures", cb.failures))
+	cb.open = true
+	go cb.probe(network, addr)
+}
+
+// probe periodically tries to connect to the cluster and closes the circuit breaker once it succeeds
+func (cb *circuitBreaker) probe(network, addr string) {
+	ticker := time.NewTicker(cb.probeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), cb.probeInterval)
+		conn, err := cb.probeDialer(ctx, network, addr)
+		cancel()
+		if err != nil {
+			cb.logger.Info(fmt.Sprintf("circuit breaker probe failed: %s", err))
+			continue
+		}
+		_ = conn.Close()
+
+		cb.mutex.Lock()
+		cb.open = false
+		cb.failures = 0
+		cb.mutex.Unlock()
+		cb.logger.Info("closing circuit breaker, cluster is reachable again")
+		return
+	}
+}
@@ -0,0 +1,82 @@
+package humio
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	cb := newCircuitBreaker(logr.Discard(), "humio.example.com")
+	cb.probeInterval = time.Hour
+
+	dialErr := errors.New("connection refused")
+	dials := 0
+	dial := cb.DialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials++
+		return nil, dialErr
+	})
+
+	for i := 0; i < circuitBreakerFailureThreshold; i++ {
+		if _, err := dial(context.Background(), "tcp", "humio.example.com:443"); !errors.Is(err, dialErr) {
+			t.Fatalf("expected dial error, got %v", err)
+		}
+	}
+	if !cb.IsOpen() {
+		t.Fatalf("expected circuit breaker to be open after %d failures", circuitBreakerFailureThreshold)
+	}
+
+	_, err := dial(context.Background(), "tcp", "humio.example.com:443")
+	if !errors.Is(err, ErrClusterUnavailable) {
+		t.Errorf("expected %v, got %v", ErrClusterUnavailable, err)
+	}
+	if dials != circuitBreakerFailureThreshold {
+		t.Errorf("expected open circuit breaker to fail fast without dialing, got %d dials", dials)
+	}
+}
+
+func TestCircuitBreakerClosesWhenProbeSucceeds(t *testing.T) {
+	cb := newCircuitBreaker(logr.Discard(), "humio.example.com")
+	cb.probeInterval = time.Millisecond
+	cb.probeDialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	}
+
+	dial := cb.DialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	})
+	for i := 0; i < circuitBreakerFailureThreshold; i++ {
+		_, _ = dial(context.Background(), "tcp", "humio.example.com:443")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cb.IsOpen() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected circuit breaker to close after successful probe")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCircuitBreakerIgnoresCancelledDials(t *testing.T) {
+	cb := newCircuitBreaker(logr.Discard(), "humio.example.com")
+	cb.probeInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dial := cb.DialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, ctx.Err()
+	})
+	for i := 0; i < circuitBreakerFailureThreshold; i++ {
+		_, _ = dial(ctx, "tcp", "humio.example.com:443")
+	}
+	if cb.IsOpen() {
+		t.Error("expected cancelled dials to not open the circuit breaker")
+	}
+}
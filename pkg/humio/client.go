@@ -17,11 +17,19 @@ limitations under the License.
 package humio
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"reflect"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -34,6 +42,24 @@ import (
 	"github.com/humio/humio-operator/pkg/helpers"
 )
 
+const (
+	// testQueryStart is the start of the search interval used by TestQuery
+	testQueryStart = "10m"
+	// testQueryTimeout is how long TestQuery waits for the query to complete
+	testQueryTimeout = 30 * time.Second
+	// ingestUsageRepository is the repository in which Humio stores usage measurements of each repository
+	ingestUsageRepository = "humio-usage"
+	// ingestUsageQuery sums the data ingested into each repository, measured after fields removed by parsers are
+	// dropped, which is what counts towards the ingest limits of the license
+	ingestUsageQuery = "groupBy(repo, function=sum(ingestAfterFieldRemovalSize, as=bytes))"
+	// ingestUsageStart is the start of the search interval used by GetIngestUsage
+	ingestUsageStart = "24h"
+	// ingestUsageTimeout is how long GetIngestUsage waits for the query to complete
+	ingestUsageTimeout = 60 * time.Second
+	// threadDumpPath is the path of the debug API returning a thread dump of the Humio node serving the request
+	threadDumpPath = "api/v1/threaddump"
+)
+
 // Client is the interface that can be mocked
 type Client interface {
 	ClusterClient
@@ -44,6 +70,15 @@ type Client interface {
 	LicenseClient
 	ActionsClient
 	AlertsClient
+	UsersClient
+	QueryJobsClient
+	UsageClient
+	DiagnosticsClient
+	MultiClusterViewsClient
+	SavedQueriesClient
+	DashboardsClient
+	AggregateAlertsClient
+	ScheduledSearchesClient
 }
 
 type ClusterClient interface {
@@ -52,10 +87,16 @@ type ClusterClient interface {
 	UpdateIngestPartitionScheme(*humioapi.Config, reconcile.Request, []humioapi.IngestPartitionInput) error
 	SuggestedStoragePartitions(*humioapi.Config, reconcile.Request) ([]humioapi.StoragePartitionInput, error)
 	SuggestedIngestPartitions(*humioapi.Config, reconcile.Request) ([]humioapi.IngestPartitionInput, error)
+	MoveRoutesAwayFromClusterNode(*humioapi.Config, reconcile.Request, int) error
+	UnregisterClusterNode(*humioapi.Config, reconcile.Request, int) error
 	GetHumioClient(*humioapi.Config, reconcile.Request) *humioapi.Client
 	ClearHumioClientConnections()
+	InvalidateReadCache(*humioapi.Config)
+	PrimeSearchDomainIndex(*humioapi.Config, reconcile.Request) error
 	GetBaseURL(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioCluster) *url.URL
-	TestAPIToken(*humioapi.Config, reconcile.Request) error
+	TestAPIToken(*humioapi.Config, reconcile.Request) (string, error)
+	TestOrganizationAPIToken(*humioapi.Config, reconcile.Request) error
+	TestQuery(*humioapi.Config, reconcile.Request, string, string) error
 	Status(*humioapi.Config, reconcile.Request) (humioapi.StatusResponse, error)
 }
 
@@ -70,7 +111,9 @@ type ParsersClient interface {
 	AddParser(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioParser) (*humioapi.Parser, error)
 	GetParser(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioParser) (*humioapi.Parser, error)
 	UpdateParser(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioParser) (*humioapi.Parser, error)
+	UpdateParsers(*humioapi.Config, reconcile.Request, []*humiov1alpha1.HumioParser) error
 	DeleteParser(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioParser) error
+	TestParser(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioParser) ([]string, error)
 }
 
 type RepositoriesClient interface {
@@ -78,6 +121,19 @@ type RepositoriesClient interface {
 	GetRepository(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioRepository) (*humioapi.Repository, error)
 	UpdateRepository(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioRepository) (*humioapi.Repository, error)
 	DeleteRepository(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioRepository) error
+	GetRepositoryIngestUsage(*humioapi.Config, reconcile.Request, string, string) (int64, error)
+	BlockIngest(*humioapi.Config, reconcile.Request, string, time.Duration) error
+	UnblockIngest(*humioapi.Config, reconcile.Request, string) error
+	GetRepositoryStatistics(*humioapi.Config, reconcile.Request, string) (*RepositoryStatistics, error)
+}
+
+// RepositoryStatistics describes the data stored in a repository
+type RepositoryStatistics struct {
+	CompressedByteSize   int64
+	UncompressedByteSize int64
+	SegmentCount         int
+	// OldestEventTime is the timestamp of the oldest event stored in the repository, or nil if it holds no events
+	OldestEventTime *time.Time
 }
 
 type ViewsClient interface {
@@ -87,17 +143,144 @@ type ViewsClient interface {
 	DeleteView(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioView) error
 }
 
+type MultiClusterViewsClient interface {
+	GetMultiClusterView(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioMultiClusterView) (*MultiClusterView, error)
+	AddMultiClusterView(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioMultiClusterView) error
+	UpdateMultiClusterViewDescription(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioMultiClusterView) error
+	DeleteMultiClusterView(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioMultiClusterView) error
+	AddMultiClusterViewConnection(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioMultiClusterView, MultiClusterViewConnection) error
+	UpdateMultiClusterViewConnection(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioMultiClusterView, MultiClusterViewConnection) error
+	DeleteMultiClusterViewConnection(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioMultiClusterView, string) error
+}
+
+// MultiClusterView is a view which searches views and repositories on several Humio clusters
+type MultiClusterView struct {
+	Name        string
+	Description string
+	Connections []MultiClusterViewConnection
+}
+
+// MultiClusterViewConnection is a connection of a multi-cluster view. Local connections search a view or repository
+// on the cluster of the multi-cluster view, while remote connections search the view their token is scoped to on
+// another cluster.
+type MultiClusterViewConnection struct {
+	ID              string
+	ClusterIdentity string
+	// TargetViewName is the view or repository searched by a local connection
+	TargetViewName string
+	// PublicURL is the URL of the cluster searched by a remote connection
+	PublicURL   string
+	QueryPrefix string
+	// Token is the token used by a remote connection. It is never returned by the Humio API.
+	Token string
+}
+
+// IsRemote returns whether the connection searches another cluster than the cluster of the multi-cluster view
+func (c MultiClusterViewConnection) IsRemote() bool {
+	return c.PublicURL != ""
+}
+
+type SavedQueriesClient interface {
+	AddSavedQuery(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error)
+	GetSavedQuery(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error)
+	UpdateSavedQuery(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error)
+	DeleteSavedQuery(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioSavedQuery) error
+	ListSavedQueries(*humioapi.Config, reconcile.Request, string) ([]SavedQuery, error)
+}
+
+// SavedQuery is a query saved in a view, which dashboards and alerts can refer to by ID
+type SavedQuery struct {
+	ID          string
+	Name        string
+	Description string
+	QueryString string
+	Start       string
+	End         string
+	IsLive      bool
+	// Arguments holds the values of the parameters of the query, keyed by parameter name
+	Arguments map[string]string
+}
+
+type DashboardsClient interface {
+	AddDashboard(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioDashboard, string) (*Dashboard, error)
+	GetDashboard(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioDashboard) (*Dashboard, error)
+	DeleteDashboard(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioDashboard) error
+	ListDashboards(*humioapi.Config, reconcile.Request, string) ([]Dashboard, error)
+}
+
+// Dashboard is a dashboard in a view
+type Dashboard struct {
+	ID   string
+	Name string
+	// TemplateYaml is the definition of the dashboard in the YAML template format, as exported by Humio
+	TemplateYaml string
+}
+
+type AggregateAlertsClient interface {
+	AddAggregateAlert(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error)
+	GetAggregateAlert(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error)
+	UpdateAggregateAlert(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error)
+	DeleteAggregateAlert(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAggregateAlert) error
+	ListAggregateAlerts(*humioapi.Config, reconcile.Request, string) ([]AggregateAlert, error)
+}
+
+// AggregateAlert is an alert which runs its query on a schedule and triggers on the aggregated results, as opposed to
+// the legacy alerts managed by HumioAlert
+type AggregateAlert struct {
+	ID                    string
+	Name                  string
+	Description           string
+	QueryString           string
+	SearchIntervalSeconds int
+	QueryTimestampType    string
+	TriggerMode           string
+	ThrottleTimeSeconds   int
+	ThrottleField         string
+	Enabled               bool
+	// Actions holds the names of the actions triggered by the aggregate alert
+	Actions []string
+	Labels  []string
+}
+
+type ScheduledSearchesClient interface {
+	AddScheduledSearch(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error)
+	GetScheduledSearch(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error)
+	UpdateScheduledSearch(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error)
+	DeleteScheduledSearch(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioScheduledSearch) error
+	ListScheduledSearches(*humioapi.Config, reconcile.Request, string) ([]ScheduledSearch, error)
+}
+
+// ScheduledSearch is a query which runs on a cron schedule in a given time zone, and triggers actions when it has
+// results
+type ScheduledSearch struct {
+	ID            string
+	Name          string
+	Description   string
+	QueryString   string
+	QueryStart    string
+	QueryEnd      string
+	Schedule      string
+	TimeZone      string
+	BackfillLimit int
+	Enabled       bool
+	// Actions holds the names of the actions triggered by the scheduled search
+	Actions []string
+	Labels  []string
+}
+
 type ActionsClient interface {
 	AddAction(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAction) (*humioapi.Action, error)
 	GetAction(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAction) (*humioapi.Action, error)
 	UpdateAction(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAction) (*humioapi.Action, error)
 	DeleteAction(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAction) error
+	TestAction(*humioapi.Config, reconcile.Request, string, *humioapi.Action, string, string) (*TestActionResult, error)
 }
 
 type AlertsClient interface {
 	AddAlert(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAlert) (*humioapi.Alert, error)
 	GetAlert(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAlert) (*humioapi.Alert, error)
 	UpdateAlert(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAlert) (*humioapi.Alert, error)
+	UpdateAlerts(*humioapi.Config, reconcile.Request, []*humiov1alpha1.HumioAlert) error
 	DeleteAlert(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAlert) error
 	GetActionIDsMapForAlerts(*humioapi.Config, reconcile.Request, *humiov1alpha1.HumioAlert) (map[string]string, error)
 }
@@ -107,22 +290,63 @@ type LicenseClient interface {
 	InstallLicense(*humioapi.Config, reconcile.Request, string) error
 }
 
+type UsersClient interface {
+	RotateUserAPIToken(*humioapi.Config, reconcile.Request, string) (string, error)
+	EnsureRootUser(*humioapi.Config, reconcile.Request, string) error
+}
+
+type UsageClient interface {
+	GetRepositoryUsage(*humioapi.Config, reconcile.Request) ([]RepositoryUsage, error)
+	GetIngestUsage(*humioapi.Config, reconcile.Request) (map[string]int64, error)
+	CountUsers(*humioapi.Config, reconcile.Request) (int, error)
+}
+
+// RepositoryUsage is the amount of data stored by a repository
+type RepositoryUsage struct {
+	Name                 string
+	CompressedByteSize   int64 `graphql:"compressedByteSize"`
+	UncompressedByteSize int64 `graphql:"uncompressedByteSize"`
+}
+
+type DiagnosticsClient interface {
+	GetThreadDump(*humioapi.Config, reconcile.Request) (string, error)
+}
+
+type QueryJobsClient interface {
+	CreateQueryJob(*humioapi.Config, reconcile.Request, string, humioapi.Query) (string, error)
+	PollQueryJob(*humioapi.Config, reconcile.Request, string, string) (humioapi.QueryResult, error)
+	DeleteQueryJob(*humioapi.Config, reconcile.Request, string, string) error
+}
+
 // ClientConfig stores our Humio api client
 type ClientConfig struct {
-	humioClients      map[humioClientKey]*humioClientConnection
-	humioClientsMutex sync.Mutex
-	logger            logr.Logger
-	userAgent         string
+	transports           map[string]*humioTransport
+	transportsMutex      *sync.Mutex
+	circuitBreakers      map[string]*circuitBreaker
+	circuitBreakersMutex *sync.Mutex
+	readCache            *readCache
+	bulkListing          bool
+	searchDomainIndex    *searchDomainIndex
+	apiBudget            *apiBudget
+	faultInjector        *faultInjector
+	priority             bool
+	logger               logr.Logger
+	userAgent            string
 }
 
-type humioClientKey struct {
-	namespace, name string
-	authenticated   bool
+// humioTransportSettings holds everything that affects connections towards a Humio cluster. A pooled transport is
+// replaced when any of these change, e.g. when the CA or client certificate of the cluster is rotated.
+type humioTransportSettings struct {
+	insecure                     bool
+	caCertificatePEM             string
+	timeouts                     helpers.RequestTimeouts
+	clientCertificateFingerprint string
 }
 
-type humioClientConnection struct {
-	client    *humioapi.Client
+// humioTransport is the transport shared by all requests towards a single Humio cluster
+type humioTransport struct {
 	transport *http.Transport
+	settings  humioTransportSettings
 }
 
 // NewClient returns a ClientConfig
@@ -134,66 +358,211 @@ func NewClient(logger logr.Logger, config *humioapi.Config, userAgent string) *C
 // NewClientWithTransport returns a ClientConfig using an existing http.Transport
 func NewClientWithTransport(logger logr.Logger, config *humioapi.Config, userAgent string, transport *http.Transport) *ClientConfig {
 	return &ClientConfig{
-		logger:       logger,
-		userAgent:    userAgent,
-		humioClients: map[humioClientKey]*humioClientConnection{},
+		logger:               logger,
+		userAgent:            userAgent,
+		transports:           map[string]*humioTransport{},
+		transportsMutex:      &sync.Mutex{},
+		circuitBreakers:      map[string]*circuitBreaker{},
+		circuitBreakersMutex: &sync.Mutex{},
 	}
 }
 
-// GetHumioClient takes a Humio API config as input and returns an API client that uses this config
-func (h *ClientConfig) GetHumioClient(config *humioapi.Config, req ctrl.Request) *humioapi.Client {
-	h.humioClientsMutex.Lock()
-	defer h.humioClientsMutex.Unlock()
+// WithReadCache enables caching of results from read calls against the Humio API for the given amount of time.
+// Cached entries are invalidated whenever the operator changes the corresponding entity. A ttl of zero disables the
+// cache.
+func (h *ClientConfig) WithReadCache(ttl time.Duration) *ClientConfig {
+	h.readCache = newReadCache(ttl)
+	return h
+}
 
-	config.UserAgent = h.userAgent
-	key := humioClientKey{
-		namespace:     req.Namespace,
-		name:          req.Name,
-		authenticated: config.Token != "",
+// WithAPIBudget limits the rate of calls against each Humio cluster to the given number of requests per second. The
+// budget is shared by all reconcilers using this client, including those using the priority client. Zero disables
+// the budget.
+func (h *ClientConfig) WithAPIBudget(requestsPerSecond float64) *ClientConfig {
+	h.apiBudget = newAPIBudget(requestsPerSecond)
+	return h
+}
+
+// WithFaultInjection makes the given fraction of calls against the Humio API time out, fail with a server error or be
+// delayed by the given amount of time, to test how the operator and the alerting on it cope with an unhealthy Humio
+// cluster. It is meant for staging environments only. A rate of zero disables fault injection.
+func (h *ClientConfig) WithFaultInjection(rate float64, delay time.Duration) *ClientConfig {
+	h.faultInjector = newFaultInjector(rate, delay)
+	return h
+}
+
+// PriorityClient returns a client sharing the read cache and the API budget with this client, whose calls may use the
+// part of the API budget held back from other calls. It is meant for reconciling HumioClusters, so a flood of
+// reconciles of other custom resources cannot starve pod management. Its calls use their own pooled connections, as
+// the budget is charged by the transport the calls are sent with.
+func (h *ClientConfig) PriorityClient() *ClientConfig {
+	return &ClientConfig{
+		transports:           h.transports,
+		transportsMutex:      h.transportsMutex,
+		circuitBreakers:      h.circuitBreakers,
+		circuitBreakersMutex: h.circuitBreakersMutex,
+		readCache:            h.readCache,
+		bulkListing:          h.bulkListing,
+		searchDomainIndex:    h.searchDomainIndex,
+		apiBudget:            h.apiBudget,
+		faultInjector:        h.faultInjector,
+		priority:             true,
+		logger:               h.logger,
+		userAgent:            h.userAgent,
 	}
+}
 
-	c := h.humioClients[key]
-	if c == nil {
-		transport := humioapi.NewHttpTransport(*config)
-		c = &humioClientConnection{
-			client:    humioapi.NewClientWithTransport(*config, transport),
-			transport: transport,
+// GetHumioClient takes a Humio API config as input and returns an API client that uses this config. Transports are
+// pooled per Humio cluster, so connections and TLS sessions are reused by all reconcilers and custom resources
+// communicating with the same Humio cluster. Clients are cheap to create and always use the API token of the given
+// config, so refreshed or rotated API tokens do not cause connections to be dropped. A client is created for every call,
+// which is where faults are injected when fault injection is enabled. Every request sent by the client waits for the
+// API budget of the cluster. The calls of the client fail without reaching Humio once the reconcile of the given
+// request is done, and are given up at the deadline of the reconcile.
+func (h *ClientConfig) GetHumioClient(config *humioapi.Config, req ctrl.Request) *humioapi.Client {
+	config.UserAgent = h.userAgent
+	if config.Address != nil {
+		deadline, err := reconcileDeadline(req)
+		if err != nil {
+			return humioapi.NewClientWithTransport(*config, newFailingTransport(fmt.Errorf("reconcile of %s is done: %w", req, err)))
 		}
-	} else {
-		existingConfig := c.client.Config()
-		equal := existingConfig.Token == config.Token &&
-			existingConfig.Insecure == config.Insecure &&
-			existingConfig.CACertificatePEM == config.CACertificatePEM &&
-			existingConfig.ProxyOrganization == config.ProxyOrganization &&
-			existingConfig.Address.String() == config.Address.String()
-
-		// If the cluster address or SSL configuration has changed, we must create a new transport
-		if !equal {
-			transport := humioapi.NewHttpTransport(*config)
-			c = &humioClientConnection{
-				client:    humioapi.NewClientWithTransport(*config, transport),
-				transport: transport,
-			}
-
+		if transport := h.faultInjector.inject(h.logger, config.Address.Host); transport != nil {
+			return humioapi.NewClientWithTransport(*config, transport)
 		}
-		if c.transport == nil {
-			c.transport = humioapi.NewHttpTransport(*config)
+		// Calls which must finish before the client would time them out are given a transport of their own, which
+		// gives up at the deadline
+		if !deadline.IsZero() && time.Until(deadline) < humioClientTimeout {
+			return humioapi.NewClientWithTransport(*config, h.newHttpTransport(*config, helpers.GetRequestTimeouts(config.Address.Host), deadline))
 		}
-		// Always create a new client and use the existing transport. Since we're using the same transport, connections
-		// will be cached.
-		c.client = humioapi.NewClientWithTransport(*config, c.transport)
+	}
+	return humioapi.NewClientWithTransport(*config, h.getTransport(*config))
+}
+
+// getTransport returns the pooled transport for the Humio cluster of the given config. If the settings of the
+// cluster have changed since the transport was created, the transport is replaced and its idle connections closed.
+func (h *ClientConfig) getTransport(config humioapi.Config) *http.Transport {
+	if config.Address == nil {
+		return h.newHttpTransport(config, helpers.RequestTimeouts{}, time.Time{})
+	}
+
+	settings := humioTransportSettings{
+		insecure:                     config.Insecure,
+		caCertificatePEM:             config.CACertificatePEM,
+		timeouts:                     helpers.GetRequestTimeouts(config.Address.Host),
+		clientCertificateFingerprint: helpers.GetClientCertificateFingerprint(config.Address.Host),
+	}
+
+	h.transportsMutex.Lock()
+	defer h.transportsMutex.Unlock()
+
+	// Clusters on the same address using different proxies are given separate transports, and so are priority calls
+	key := config.Address.String()
+	if proxyKey := helpers.ProxyKey(config.DialContext); proxyKey != "" {
+		key += " via " + proxyKey
+	}
+	if h.priority {
+		key += " (priority)"
+	}
+	t, ok := h.transports[key]
+	if ok && t.settings == settings {
+		return t.transport
+	}
+	if ok {
+		h.logger.Info(fmt.Sprintf("connection settings for %s changed, replacing connections", key))
+		t.transport.CloseIdleConnections()
+	}
+	t = &humioTransport{
+		transport: h.newHttpTransport(config, settings.timeouts, time.Time{}),
+		settings:  settings,
+	}
+	h.transports[key] = t
+	return t.transport
+}
+
+// getCircuitBreaker returns the circuit breaker for the Humio cluster running on the given host
+func (h *ClientConfig) getCircuitBreaker(host string) *circuitBreaker {
+	h.circuitBreakersMutex.Lock()
+	defer h.circuitBreakersMutex.Unlock()
+
+	cb, ok := h.circuitBreakers[host]
+	if !ok {
+		cb = newCircuitBreaker(h.logger, host)
+		h.circuitBreakers[host] = cb
+	}
+	return cb
+}
+
+// newHttpTransport returns a transport for the given config where connections towards the Humio cluster are guarded
+// by the circuit breaker for that cluster, and which presents the client certificate configured for that cluster. If
+// a deadline is given, the transport is meant for a single call which is given up at the deadline.
+func (h *ClientConfig) newHttpTransport(config humioapi.Config, timeouts helpers.RequestTimeouts, deadline time.Time) *http.Transport {
+	if config.Address == nil {
+		return humioapi.NewHttpTransport(config)
+	}
+	config.DialContext = h.getCircuitBreaker(config.Address.Host).DialContext(withConnectTimeout(config.DialContext, timeouts.Connect))
+	transport := humioapi.NewHttpTransport(config)
+	if timeouts.Read > 0 {
+		transport.ResponseHeaderTimeout = timeouts.Read
+	}
+
+	// Every request is charged to the API budget of the cluster. The transport has no hook around a round trip, so
+	// this is done where the proxy for the request is looked up, which happens for every request sent over HTTP/1.1.
+	// HTTP/2 is not used, as requests on pooled HTTP/2 connections skip that lookup.
+	if h.apiBudget != nil {
+		transport.Proxy = h.chargeAPIBudget(config.Address.Host, transport.Proxy, deadline)
+		transport.ForceAttemptHTTP2 = false
+	}
+
+	// The deadline is set outside the circuit breaker, so calls given up at the deadline do not count as failures
+	if !deadline.IsZero() {
+		transport.DialContext = withDeadline(transport.DialContext, deadline)
+		transport.DisableKeepAlives = true
 	}
 
-	h.humioClients[key] = c
+	// Present a client certificate if one is configured for the cluster, and resume TLS sessions when connections
+	// are reestablished
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	transport.TLSClientConfig.GetClientCertificate = helpers.GetClientCertificate(config.Address.Host)
+	transport.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	return transport
+}
 
-	return c.client
+// withConnectTimeout wraps the given dial function so connection attempts are given up after the given timeout. If
+// dial is nil, a default net.Dialer is used.
+func withConnectTimeout(dial dialContextFunc, timeout time.Duration) dialContextFunc {
+	if timeout <= 0 {
+		return dial
+	}
+	if dial == nil {
+		dial = (&net.Dialer{KeepAlive: 30 * time.Second}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return dial(ctx, network, addr)
+	}
 }
 
 func (h *ClientConfig) ClearHumioClientConnections() {
-	h.humioClientsMutex.Lock()
-	defer h.humioClientsMutex.Unlock()
+	h.transportsMutex.Lock()
+	defer h.transportsMutex.Unlock()
+
+	for key, t := range h.transports {
+		t.transport.CloseIdleConnections()
+		delete(h.transports, key)
+	}
+}
 
-	h.humioClients = make(map[humioClientKey]*humioClientConnection)
+// InvalidateReadCache removes all cached results of read calls against the Humio cluster of the given config, so the
+// next calls read the current state from Humio
+func (h *ClientConfig) InvalidateReadCache(config *humioapi.Config) {
+	if config.Address == nil {
+		return
+	}
+	h.readCache.invalidateCluster(config.Address.String())
+	h.searchDomainIndex.invalidateCluster(config.Address.String())
 }
 
 // Status returns the status of the humio cluster
@@ -243,6 +612,24 @@ func (h *ClientConfig) SuggestedIngestPartitions(config *humioapi.Config, req re
 	return h.GetHumioClient(config, req).Clusters().SuggestedIngestPartitions()
 }
 
+// MoveRoutesAwayFromClusterNode moves the storage and ingest partitions away from the given node. This is only
+// supported by versions of Humio without automatic partition management.
+func (h *ClientConfig) MoveRoutesAwayFromClusterNode(config *humioapi.Config, req reconcile.Request, nodeID int) error {
+	if err := h.GetHumioClient(config, req).Clusters().ClusterMoveStorageRouteAwayFromNode(nodeID); err != nil {
+		return fmt.Errorf("could not move storage partitions away from node %d: %w", nodeID, err)
+	}
+	if err := h.GetHumioClient(config, req).Clusters().ClusterMoveIngestRoutesAwayFromNode(nodeID); err != nil {
+		return fmt.Errorf("could not move ingest partitions away from node %d: %w", nodeID, err)
+	}
+	return nil
+}
+
+// UnregisterClusterNode unregisters the given node from the cluster. Humio refuses to unregister the node if it holds
+// data which is not available on other nodes.
+func (h *ClientConfig) UnregisterClusterNode(config *humioapi.Config, req reconcile.Request, nodeID int) error {
+	return h.GetHumioClient(config, req).ClusterNodes().Unregister(nodeID, false)
+}
+
 // GetBaseURL returns the base URL for given HumioCluster
 func (h *ClientConfig) GetBaseURL(config *humioapi.Config, req reconcile.Request, hc *humiov1alpha1.HumioCluster) *url.URL {
 	protocol := "https"
@@ -254,12 +641,60 @@ func (h *ClientConfig) GetBaseURL(config *humioapi.Config, req reconcile.Request
 
 }
 
-// TestAPIToken tests if an API token is valid by fetching the username that the API token belongs to
-func (h *ClientConfig) TestAPIToken(config *humioapi.Config, req reconcile.Request) error {
-	_, err := h.GetHumioClient(config, req).Viewer().Username()
+// TestAPIToken tests if an API token is valid by fetching the username that the API token belongs to, and returns
+// that username
+func (h *ClientConfig) TestAPIToken(config *humioapi.Config, req reconcile.Request) (string, error) {
+	return h.GetHumioClient(config, req).Viewer().Username()
+}
+
+// TestOrganizationAPIToken tests if an organization API token is valid. Organization API tokens do not belong to a
+// user, so the token is tested by listing the repositories and views of the organization instead.
+func (h *ClientConfig) TestOrganizationAPIToken(config *humioapi.Config, req reconcile.Request) error {
+	_, err := h.GetHumioClient(config, req).Views().List()
+	return err
+}
+
+// TestQuery runs the given query against the given repository and waits for it to complete. This is used to validate
+// that a Humio cluster is able to serve searches.
+func (h *ClientConfig) TestQuery(config *humioapi.Config, req reconcile.Request, repository, query string) error {
+	_, err := runQuery(h.GetHumioClient(config, req), repository, humioapi.Query{
+		QueryString: query,
+		Start:       testQueryStart,
+	}, testQueryTimeout)
 	return err
 }
 
+// runQuery executes the query and waits for it to complete, returning the final result
+func runQuery(client *humioapi.Client, repository string, query humioapi.Query, timeout time.Duration) (humioapi.QueryResult, error) {
+	id, err := client.QueryJobs().Create(repository, query)
+	if err != nil {
+		return humioapi.QueryResult{}, fmt.Errorf("could not start query: %w", err)
+	}
+	defer func() {
+		_ = client.QueryJobs().Delete(repository, id)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for {
+		result, err := client.QueryJobs().PollContext(ctx, repository, id)
+		if err != nil {
+			return humioapi.QueryResult{}, fmt.Errorf("could not poll query: %w", err)
+		}
+		if result.Cancelled {
+			return humioapi.QueryResult{}, fmt.Errorf("query was cancelled")
+		}
+		if result.Done {
+			return result, nil
+		}
+		select {
+		case <-ctx.Done():
+			return humioapi.QueryResult{}, fmt.Errorf("query did not complete within %s", timeout)
+		case <-time.After(time.Duration(result.Metadata.PollAfter) * time.Millisecond):
+		}
+	}
+}
+
 func (h *ClientConfig) AddIngestToken(config *humioapi.Config, req reconcile.Request, hit *humiov1alpha1.HumioIngestToken) (*humioapi.IngestToken, error) {
 	return h.GetHumioClient(config, req).IngestTokens().Add(hit.Spec.RepositoryName, hit.Spec.Name, hit.Spec.ParserName)
 }
@@ -297,11 +732,26 @@ func (h *ClientConfig) AddParser(config *humioapi.Config, req reconcile.Request,
 		&parser,
 		false,
 	)
+	h.readCache.invalidate(newReadCacheKey(config, readCacheKindParser, hp.Spec.RepositoryName, hp.Spec.Name))
 	return &parser, err
 }
 
 func (h *ClientConfig) GetParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) (*humioapi.Parser, error) {
-	return h.GetHumioClient(config, req).Parsers().Get(hp.Spec.RepositoryName, hp.Spec.Name)
+	key := newReadCacheKey(config, readCacheKindParser, hp.Spec.RepositoryName, hp.Spec.Name)
+	if cached, ok := h.readCache.get(key); ok {
+		parser := cached.(humioapi.Parser)
+		return &parser, nil
+	}
+
+	if h.bulkListingEnabled() {
+		return h.bulkGetParser(config, req, hp.Spec.RepositoryName, hp.Spec.Name)
+	}
+
+	parser, err := h.GetHumioClient(config, req).Parsers().Get(hp.Spec.RepositoryName, hp.Spec.Name)
+	if err == nil && parser != nil {
+		h.readCache.set(key, *parser)
+	}
+	return parser, err
 }
 
 func (h *ClientConfig) UpdateParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) (*humioapi.Parser, error) {
@@ -316,20 +766,85 @@ func (h *ClientConfig) UpdateParser(config *humioapi.Config, req reconcile.Reque
 		&parser,
 		true,
 	)
+	h.readCache.invalidate(newReadCacheKey(config, readCacheKindParser, hp.Spec.RepositoryName, hp.Spec.Name))
 	return &parser, err
 }
 
 func (h *ClientConfig) DeleteParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) error {
+	defer h.readCache.invalidate(newReadCacheKey(config, readCacheKindParser, hp.Spec.RepositoryName, hp.Spec.Name))
 	return h.GetHumioClient(config, req).Parsers().Remove(hp.Spec.RepositoryName, hp.Spec.Name)
 }
 
+// TestParserInput is the input of the testParser mutation
+type TestParserInput struct {
+	RepositoryName graphql.String   `json:"repositoryName"`
+	ParserName     graphql.String   `json:"parserName"`
+	ParserScript   graphql.String   `json:"parserScript"`
+	TestData       []graphql.String `json:"testData"`
+}
+
+// TestParser runs the parser script of the given parser against its test data without saving the parser, and returns
+// the errors reported for the test events which could not be parsed
+func (h *ClientConfig) TestParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) ([]string, error) {
+	var mutation struct {
+		TestParser struct {
+			Results []struct {
+				ErrorMessage *graphql.String `graphql:"errorMessage"`
+			} `graphql:"results"`
+		} `graphql:"testParser(input: $input)"`
+	}
+	testData := make([]graphql.String, 0, len(hp.Spec.TestData))
+	for _, event := range hp.Spec.TestData {
+		testData = append(testData, graphql.String(event))
+	}
+	err := h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": TestParserInput{
+			RepositoryName: graphql.String(hp.Spec.RepositoryName),
+			ParserName:     graphql.String(hp.Spec.Name),
+			ParserScript:   graphql.String(hp.Spec.ParserScript),
+			TestData:       testData,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var parseErrors []string
+	for _, result := range mutation.TestParser.Results {
+		if result.ErrorMessage != nil {
+			parseErrors = append(parseErrors, string(*result.ErrorMessage))
+		}
+	}
+	return parseErrors, nil
+}
+
 func (h *ClientConfig) AddRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) (*humioapi.Repository, error) {
 	repository := humioapi.Repository{Name: hr.Spec.Name}
 	err := h.GetHumioClient(config, req).Repositories().Create(hr.Spec.Name)
+	h.invalidateRepository(config, hr.Spec.Name)
+	if err == nil {
+		h.searchDomainIndex.add(searchDomainCluster(config), searchDomainRepository, hr.Spec.Name)
+	}
 	return &repository, err
 }
 
 func (h *ClientConfig) GetRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) (*humioapi.Repository, error) {
+	if h.bulkListingEnabled() {
+		return h.bulkGetRepository(config, req, hr.Spec.Name)
+	}
+
+	if h.searchDomainIndex != nil {
+		exists, err := h.searchDomainExists(config, req, hr.Spec.Name, searchDomainRepository)
+		if err != nil || !exists {
+			return &humioapi.Repository{}, err
+		}
+		repository, err := h.GetHumioClient(config, req).Repositories().Get(hr.Spec.Name)
+		if err != nil {
+			// The repository may have been deleted outside the operator
+			h.searchDomainIndex.invalidateCluster(searchDomainCluster(config))
+		}
+		return &repository, err
+	}
+
 	repoList, err := h.GetHumioClient(config, req).Repositories().List()
 	if err != nil {
 		return &humioapi.Repository{}, fmt.Errorf("could not list repositories: %w", err)
@@ -349,6 +864,7 @@ func (h *ClientConfig) UpdateRepository(config *humioapi.Config, req reconcile.R
 	if err != nil {
 		return &humioapi.Repository{}, err
 	}
+	defer h.invalidateRepository(config, hr.Spec.Name)
 
 	if curRepository.Description != hr.Spec.Description {
 		err = h.GetHumioClient(config, req).Repositories().UpdateDescription(
@@ -393,11 +909,14 @@ func (h *ClientConfig) UpdateRepository(config *humioapi.Config, req reconcile.R
 		}
 	}
 
+	h.invalidateRepository(config, hr.Spec.Name)
 	return h.GetRepository(config, req, hr)
 }
 
 func (h *ClientConfig) DeleteRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) error {
 	// TODO: perhaps we should allow calls to DeleteRepository() to include the reason instead of hardcoding it
+	defer h.searchDomainIndex.remove(searchDomainCluster(config), searchDomainRepository, hr.Spec.Name)
+	defer h.invalidateRepository(config, hr.Spec.Name)
 	return h.GetHumioClient(config, req).Repositories().Delete(
 		hr.Spec.Name,
 		"deleted by humio-operator",
@@ -405,7 +924,122 @@ func (h *ClientConfig) DeleteRepository(config *humioapi.Config, req reconcile.R
 	)
 }
 
+// invalidateRepository drops the cached repository along with the cached view of the same name, since views may also be
+// looked up by the name of a repository
+func (h *ClientConfig) invalidateRepository(config *humioapi.Config, name string) {
+	h.readCache.invalidate(newReadCacheKey(config, readCacheKindRepository, "", name))
+	h.readCache.invalidate(newReadCacheKey(config, readCacheKindView, "", name))
+}
+
+// GetRepositoryIngestUsage returns the number of bytes ingested into the given repository since the given relative
+// time, e.g. "1h"
+func (h *ClientConfig) GetRepositoryIngestUsage(config *humioapi.Config, req reconcile.Request, repositoryName string, start string) (int64, error) {
+	result, err := runQuery(h.GetHumioClient(config, req), ingestUsageRepository, humioapi.Query{
+		QueryString: fmt.Sprintf("repo=%s | %s", strconv.Quote(repositoryName), ingestUsageQuery),
+		Start:       start,
+	}, ingestUsageTimeout)
+	if err != nil {
+		return 0, err
+	}
+	return parseIngestUsage(result.Events)[repositoryName], nil
+}
+
+// BlockIngest rejects all data sent to the given repository for the given duration
+func (h *ClientConfig) BlockIngest(config *humioapi.Config, req reconcile.Request, repositoryName string, duration time.Duration) error {
+	var mutation struct {
+		BlockIngest struct {
+			// We have to make a selection, so just take __typename
+			Typename graphql.String `graphql:"__typename"`
+		} `graphql:"blockIngest(repositoryName: $repositoryName, durationInSeconds: $durationInSeconds)"`
+	}
+	return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"repositoryName":    graphql.String(repositoryName),
+		"durationInSeconds": graphql.Int(duration.Seconds()),
+	})
+}
+
+// UnblockIngest lifts a block of ingest into the given repository
+func (h *ClientConfig) UnblockIngest(config *humioapi.Config, req reconcile.Request, repositoryName string) error {
+	var mutation struct {
+		UnblockIngest struct {
+			// We have to make a selection, so just take __typename
+			Typename graphql.String `graphql:"__typename"`
+		} `graphql:"unblockIngest(repositoryName: $repositoryName)"`
+	}
+	return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"repositoryName": graphql.String(repositoryName),
+	})
+}
+
+// GetRepositoryStatistics returns the size, segment count and oldest event of the given repository. Segments and
+// oldest events are reported per datasource, so they are summed up and compared across the datasources.
+func (h *ClientConfig) GetRepositoryStatistics(config *humioapi.Config, req reconcile.Request, repositoryName string) (*RepositoryStatistics, error) {
+	var query struct {
+		Repository struct {
+			CompressedByteSize   int64 `graphql:"compressedByteSize"`
+			UncompressedByteSize int64 `graphql:"uncompressedByteSize"`
+			Datasources          []struct {
+				OldestTimestamp graphql.String `graphql:"oldestTimestamp"`
+				Segments        []struct {
+					ID graphql.String `graphql:"id"`
+				} `graphql:"segments"`
+			} `graphql:"datasources"`
+		} `graphql:"repository(name: $repositoryName)"`
+	}
+	err := h.GetHumioClient(config, req).Query(&query, map[string]interface{}{
+		"repositoryName": graphql.String(repositoryName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statistics := &RepositoryStatistics{
+		CompressedByteSize:   query.Repository.CompressedByteSize,
+		UncompressedByteSize: query.Repository.UncompressedByteSize,
+	}
+	for _, datasource := range query.Repository.Datasources {
+		statistics.SegmentCount += len(datasource.Segments)
+		if datasource.OldestTimestamp == "" {
+			continue
+		}
+		oldest, err := time.Parse(time.RFC3339Nano, string(datasource.OldestTimestamp))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse oldest timestamp of repository %s: %w", repositoryName, err)
+		}
+		if statistics.OldestEventTime == nil || oldest.Before(*statistics.OldestEventTime) {
+			statistics.OldestEventTime = &oldest
+		}
+	}
+	return statistics, nil
+}
+
 func (h *ClientConfig) GetView(config *humioapi.Config, req reconcile.Request, hv *humiov1alpha1.HumioView) (*humioapi.View, error) {
+	key := newReadCacheKey(config, readCacheKindView, "", hv.Spec.Name)
+	if cached, ok := h.readCache.get(key); ok {
+		view := cached.(humioapi.View)
+		return &view, nil
+	}
+
+	if h.bulkListingEnabled() {
+		return h.bulkGetView(config, req, hv.Spec.Name)
+	}
+
+	if h.searchDomainIndex != nil {
+		// Views may also be looked up by the name of a repository
+		exists, err := h.searchDomainExists(config, req, hv.Spec.Name, searchDomainView, searchDomainRepository)
+		if err != nil || !exists {
+			return &humioapi.View{}, err
+		}
+		view, err := h.GetHumioClient(config, req).Views().Get(hv.Spec.Name)
+		if err != nil {
+			// The view may have been deleted outside the operator
+			h.searchDomainIndex.invalidateCluster(searchDomainCluster(config))
+		} else if view != nil {
+			h.readCache.set(key, *view)
+		}
+		return view, err
+	}
+
 	viewList, err := h.GetHumioClient(config, req).Views().List()
 	if err != nil {
 		return &humioapi.View{}, fmt.Errorf("could not list views: %w", err)
@@ -414,6 +1048,9 @@ func (h *ClientConfig) GetView(config *humioapi.Config, req reconcile.Request, h
 		if v.Name == hv.Spec.Name {
 			// we now know the view exists
 			view, err := h.GetHumioClient(config, req).Views().Get(hv.Spec.Name)
+			if err == nil && view != nil {
+				h.readCache.set(key, *view)
+			}
 			return view, err
 		}
 	}
@@ -431,6 +1068,10 @@ func (h *ClientConfig) AddView(config *humioapi.Config, req reconcile.Request, h
 	description := ""
 
 	err := h.GetHumioClient(config, req).Views().Create(hv.Spec.Name, description, getConnectionMap(viewConnections))
+	h.readCache.invalidate(newReadCacheKey(config, readCacheKindView, "", hv.Spec.Name))
+	if err == nil {
+		h.searchDomainIndex.add(searchDomainCluster(config), searchDomainView, hv.Spec.Name)
+	}
 	return &view, err
 }
 
@@ -449,6 +1090,7 @@ func (h *ClientConfig) UpdateView(config *humioapi.Config, req reconcile.Request
 		hv.Spec.Name,
 		getConnectionMap(connections),
 	)
+	h.readCache.invalidate(newReadCacheKey(config, readCacheKindView, "", hv.Spec.Name))
 	if err != nil {
 		return &humioapi.View{}, err
 	}
@@ -457,109 +1099,974 @@ func (h *ClientConfig) UpdateView(config *humioapi.Config, req reconcile.Request
 }
 
 func (h *ClientConfig) DeleteView(config *humioapi.Config, req reconcile.Request, hv *humiov1alpha1.HumioView) error {
+	defer h.readCache.invalidate(newReadCacheKey(config, readCacheKindView, "", hv.Spec.Name))
+	defer h.searchDomainIndex.remove(searchDomainCluster(config), searchDomainView, hv.Spec.Name)
 	return h.GetHumioClient(config, req).Views().Delete(hv.Spec.Name, "Deleted by humio-operator")
 }
 
-func (h *ClientConfig) validateView(config *humioapi.Config, req reconcile.Request, viewName string) error {
-	view := &humiov1alpha1.HumioView{
-		Spec: humiov1alpha1.HumioViewSpec{
-			Name: viewName,
-		},
-	}
+// multiClusterViewClusterIdentityTag is the tag holding the cluster identity of multi-cluster view connections
+const multiClusterViewClusterIdentityTag = "clusteridentity"
 
-	viewResult, err := h.GetView(config, req, view)
-	if err != nil {
-		return fmt.Errorf("failed to verify view %s exists. error: %w", viewName, err)
-	}
+// ClusterConnectionInputTag is a tag of a connection of a multi-cluster view
+type ClusterConnectionInputTag struct {
+	Key   graphql.String `json:"key"`
+	Value graphql.String `json:"value"`
+}
 
-	emptyView := &humioapi.View{}
-	if reflect.DeepEqual(emptyView, viewResult) {
-		return fmt.Errorf("view %s does not exist", viewName)
-	}
+// CreateMultiClusterSearchViewInput is the input of the createMultiClusterSearchView mutation
+type CreateMultiClusterSearchViewInput struct {
+	Name        graphql.String `json:"name"`
+	Description graphql.String `json:"description"`
+}
 
-	return nil
+// CreateLocalClusterConnectionInput is the input of the createLocalClusterConnection mutation
+type CreateLocalClusterConnectionInput struct {
+	MultiClusterViewName graphql.String              `json:"multiClusterViewName"`
+	TargetViewName       graphql.String              `json:"targetViewName"`
+	Tags                 []ClusterConnectionInputTag `json:"tags"`
+	QueryPrefix          graphql.String              `json:"queryPrefix"`
 }
 
-func (h *ClientConfig) GetAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
-	err := h.validateView(config, req, ha.Spec.ViewName)
-	if err != nil {
-		return nil, fmt.Errorf("problem getting view for action %s: %w", ha.Spec.Name, err)
-	}
+// UpdateLocalClusterConnectionInput is the input of the updateLocalClusterConnection mutation
+type UpdateLocalClusterConnectionInput struct {
+	MultiClusterViewName graphql.String              `json:"multiClusterViewName"`
+	ConnectionID         graphql.String              `json:"connectionId"`
+	TargetViewName       graphql.String              `json:"targetViewName"`
+	Tags                 []ClusterConnectionInputTag `json:"tags"`
+	QueryPrefix          graphql.String              `json:"queryPrefix"`
+}
 
-	action, err := h.GetHumioClient(config, req).Actions().Get(ha.Spec.ViewName, ha.Spec.Name)
-	if err != nil {
-		return action, fmt.Errorf("error when trying to get action %+v, name=%s, view=%s: %w", action, ha.Spec.Name, ha.Spec.ViewName, err)
-	}
+// CreateRemoteClusterConnectionInput is the input of the createRemoteClusterConnection mutation
+type CreateRemoteClusterConnectionInput struct {
+	MultiClusterViewName graphql.String              `json:"multiClusterViewName"`
+	PublicURL            graphql.String              `json:"publicUrl"`
+	Token                graphql.String              `json:"token"`
+	Tags                 []ClusterConnectionInputTag `json:"tags"`
+	QueryPrefix          graphql.String              `json:"queryPrefix"`
+}
 
-	if action == nil || action.Name == "" {
-		return nil, nil
-	}
+// UpdateRemoteClusterConnectionInput is the input of the updateRemoteClusterConnection mutation
+type UpdateRemoteClusterConnectionInput struct {
+	MultiClusterViewName graphql.String              `json:"multiClusterViewName"`
+	ConnectionID         graphql.String              `json:"connectionId"`
+	PublicURL            graphql.String              `json:"publicUrl"`
+	Token                graphql.String              `json:"token"`
+	Tags                 []ClusterConnectionInputTag `json:"tags"`
+	QueryPrefix          graphql.String              `json:"queryPrefix"`
+}
 
-	return action, nil
+// DeleteClusterConnectionInput is the input of the deleteClusterConnection mutation
+type DeleteClusterConnectionInput struct {
+	MultiClusterViewName graphql.String `json:"multiClusterViewName"`
+	ConnectionID         graphql.String `json:"connectionId"`
 }
 
-func (h *ClientConfig) AddAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
-	err := h.validateView(config, req, ha.Spec.ViewName)
+// GetMultiClusterView returns the multi-cluster view, or an empty multi-cluster view if it does not exist
+func (h *ClientConfig) GetMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) (*MultiClusterView, error) {
+	viewList, err := h.GetHumioClient(config, req).Views().List()
 	if err != nil {
-		return nil, fmt.Errorf("problem getting view for action %s: %w", ha.Spec.Name, err)
+		return &MultiClusterView{}, fmt.Errorf("could not list views: %w", err)
+	}
+	found := false
+	for _, v := range viewList {
+		if v.Name == hmcv.Spec.Name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return &MultiClusterView{}, nil
 	}
 
-	action, err := ActionFromActionCR(ha)
+	var query struct {
+		SearchDomain struct {
+			View struct {
+				Description        *graphql.String `graphql:"description"`
+				IsFederated        graphql.Boolean `graphql:"isFederated"`
+				ClusterConnections []struct {
+					ID          graphql.String `graphql:"id"`
+					QueryPrefix graphql.String `graphql:"queryPrefix"`
+					Tags        []struct {
+						Key   graphql.String `graphql:"key"`
+						Value graphql.String `graphql:"value"`
+					} `graphql:"tags"`
+					Local struct {
+						TargetViewName graphql.String `graphql:"targetViewName"`
+					} `graphql:"... on LocalClusterConnection"`
+					Remote struct {
+						PublicURL graphql.String `graphql:"publicUrl"`
+					} `graphql:"... on RemoteClusterConnection"`
+				} `graphql:"clusterConnections"`
+			} `graphql:"... on View"`
+		} `graphql:"searchDomain(name: $name)"`
+	}
+	err = h.GetHumioClient(config, req).Query(&query, map[string]interface{}{
+		"name": graphql.String(hmcv.Spec.Name),
+	})
 	if err != nil {
-		return action, err
+		return &MultiClusterView{}, err
+	}
+	if !query.SearchDomain.View.IsFederated {
+		return &MultiClusterView{}, fmt.Errorf("view %s exists and is not a multi-cluster view", hmcv.Spec.Name)
 	}
 
-	createdAction, err := h.GetHumioClient(config, req).Actions().Add(ha.Spec.ViewName, action)
-	if err != nil {
-		return createdAction, fmt.Errorf("got error when attempting to add action: %w", err)
+	view := &MultiClusterView{Name: hmcv.Spec.Name}
+	if query.SearchDomain.View.Description != nil {
+		view.Description = string(*query.SearchDomain.View.Description)
 	}
-	return createdAction, nil
+	for _, c := range query.SearchDomain.View.ClusterConnections {
+		connection := MultiClusterViewConnection{
+			ID:             string(c.ID),
+			TargetViewName: string(c.Local.TargetViewName),
+			PublicURL:      string(c.Remote.PublicURL),
+			QueryPrefix:    string(c.QueryPrefix),
+		}
+		for _, tag := range c.Tags {
+			if string(tag.Key) == multiClusterViewClusterIdentityTag {
+				connection.ClusterIdentity = string(tag.Value)
+			}
+		}
+		view.Connections = append(view.Connections, connection)
+	}
+	return view, nil
 }
 
-func (h *ClientConfig) UpdateAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
-	err := h.validateView(config, req, ha.Spec.ViewName)
-	if err != nil {
-		return nil, fmt.Errorf("problem getting view for action %s: %w", ha.Spec.Name, err)
+func (h *ClientConfig) AddMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	var mutation struct {
+		CreateMultiClusterSearchView struct {
+			// We have to make a selection, so just take __typename
+			Typename graphql.String `graphql:"__typename"`
+		} `graphql:"createMultiClusterSearchView(input: $input)"`
 	}
+	return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": CreateMultiClusterSearchViewInput{
+			Name:        graphql.String(hmcv.Spec.Name),
+			Description: graphql.String(hmcv.Spec.Description),
+		},
+	})
+}
 
-	action, err := ActionFromActionCR(ha)
-	if err != nil {
-		return action, err
-	}
+func (h *ClientConfig) UpdateMultiClusterViewDescription(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	return h.GetHumioClient(config, req).Views().UpdateDescription(hmcv.Spec.Name, hmcv.Spec.Description)
+}
 
-	return h.GetHumioClient(config, req).Actions().Update(ha.Spec.ViewName, action)
+func (h *ClientConfig) DeleteMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	return h.GetHumioClient(config, req).Views().Delete(hmcv.Spec.Name, "Deleted by humio-operator")
 }
 
-func (h *ClientConfig) DeleteAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) error {
-	return h.GetHumioClient(config, req).Actions().Delete(ha.Spec.ViewName, ha.Spec.Name)
+func (h *ClientConfig) AddMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connection MultiClusterViewConnection) error {
+	tags := []ClusterConnectionInputTag{{Key: multiClusterViewClusterIdentityTag, Value: graphql.String(connection.ClusterIdentity)}}
+	if connection.IsRemote() {
+		var mutation struct {
+			CreateRemoteClusterConnection struct {
+				// We have to make a selection, so just take __typename
+				Typename graphql.String `graphql:"__typename"`
+			} `graphql:"createRemoteClusterConnection(input: $input)"`
+		}
+		return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+			"input": CreateRemoteClusterConnectionInput{
+				MultiClusterViewName: graphql.String(hmcv.Spec.Name),
+				PublicURL:            graphql.String(connection.PublicURL),
+				Token:                graphql.String(connection.Token),
+				Tags:                 tags,
+				QueryPrefix:          graphql.String(connection.QueryPrefix),
+			},
+		})
+	}
+	var mutation struct {
+		CreateLocalClusterConnection struct {
+			// We have to make a selection, so just take __typename
+			Typename graphql.String `graphql:"__typename"`
+		} `graphql:"createLocalClusterConnection(input: $input)"`
+	}
+	return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": CreateLocalClusterConnectionInput{
+			MultiClusterViewName: graphql.String(hmcv.Spec.Name),
+			TargetViewName:       graphql.String(connection.TargetViewName),
+			Tags:                 tags,
+			QueryPrefix:          graphql.String(connection.QueryPrefix),
+		},
+	})
 }
 
-func getConnectionMap(viewConnections []humioapi.ViewConnection) []humioapi.ViewConnectionInput {
-	connectionMap := make([]humioapi.ViewConnectionInput, 0)
-	for _, connection := range viewConnections {
-		connectionMap = append(connectionMap, humioapi.ViewConnectionInput{
-			RepositoryName: graphql.String(connection.RepoName),
-			Filter:         graphql.String(connection.Filter),
+func (h *ClientConfig) UpdateMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connection MultiClusterViewConnection) error {
+	tags := []ClusterConnectionInputTag{{Key: multiClusterViewClusterIdentityTag, Value: graphql.String(connection.ClusterIdentity)}}
+	if connection.IsRemote() {
+		var mutation struct {
+			UpdateRemoteClusterConnection struct {
+				// We have to make a selection, so just take __typename
+				Typename graphql.String `graphql:"__typename"`
+			} `graphql:"updateRemoteClusterConnection(input: $input)"`
+		}
+		return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+			"input": UpdateRemoteClusterConnectionInput{
+				MultiClusterViewName: graphql.String(hmcv.Spec.Name),
+				ConnectionID:         graphql.String(connection.ID),
+				PublicURL:            graphql.String(connection.PublicURL),
+				Token:                graphql.String(connection.Token),
+				Tags:                 tags,
+				QueryPrefix:          graphql.String(connection.QueryPrefix),
+			},
 		})
 	}
-	return connectionMap
+	var mutation struct {
+		UpdateLocalClusterConnection struct {
+			// We have to make a selection, so just take __typename
+			Typename graphql.String `graphql:"__typename"`
+		} `graphql:"updateLocalClusterConnection(input: $input)"`
+	}
+	return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": UpdateLocalClusterConnectionInput{
+			MultiClusterViewName: graphql.String(hmcv.Spec.Name),
+			ConnectionID:         graphql.String(connection.ID),
+			TargetViewName:       graphql.String(connection.TargetViewName),
+			Tags:                 tags,
+			QueryPrefix:          graphql.String(connection.QueryPrefix),
+		},
+	})
 }
 
-func (h *ClientConfig) GetLicense(config *humioapi.Config, req reconcile.Request) (humioapi.License, error) {
-	return h.GetHumioClient(config, req).Licenses().Get()
+func (h *ClientConfig) DeleteMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connectionID string) error {
+	var mutation struct {
+		DeleteClusterConnection graphql.Boolean `graphql:"deleteClusterConnection(input: $input)"`
+	}
+	return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": DeleteClusterConnectionInput{
+			MultiClusterViewName: graphql.String(hmcv.Spec.Name),
+			ConnectionID:         graphql.String(connectionID),
+		},
+	})
 }
 
-func (h *ClientConfig) InstallLicense(config *humioapi.Config, req reconcile.Request, license string) error {
-	return h.GetHumioClient(config, req).Licenses().Install(license)
+// InputDictionaryEntry is a key and value passed to Humio as part of an input type
+type InputDictionaryEntry struct {
+	Key   graphql.String `json:"key"`
+	Value graphql.String `json:"value"`
 }
 
-func (h *ClientConfig) GetAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (*humioapi.Alert, error) {
+// CreateSavedQueryInput is the input of the createSavedQuery mutation
+type CreateSavedQueryInput struct {
+	Name           graphql.String         `json:"name"`
+	ViewName       graphql.String         `json:"viewName"`
+	Description    graphql.String         `json:"description"`
+	QueryString    graphql.String         `json:"queryString"`
+	Start          graphql.String         `json:"start"`
+	End            graphql.String         `json:"end"`
+	IsLive         graphql.Boolean        `json:"isLive"`
+	QueryArguments []InputDictionaryEntry `json:"queryArguments"`
+}
+
+// UpdateSavedQueryInput is the input of the updateSavedQuery mutation
+type UpdateSavedQueryInput struct {
+	ID             graphql.String         `json:"id"`
+	ViewName       graphql.String         `json:"viewName"`
+	Name           graphql.String         `json:"name"`
+	Description    graphql.String         `json:"description"`
+	QueryString    graphql.String         `json:"queryString"`
+	Start          graphql.String         `json:"start"`
+	End            graphql.String         `json:"end"`
+	IsLive         graphql.Boolean        `json:"isLive"`
+	QueryArguments []InputDictionaryEntry `json:"queryArguments"`
+}
+
+// DeleteSavedQueryInput is the input of the deleteSavedQuery mutation
+type DeleteSavedQueryInput struct {
+	ID       graphql.String `json:"id"`
+	ViewName graphql.String `json:"viewName"`
+}
+
+// savedQueryArguments returns the arguments of the saved query sorted by name, so the input is stable
+func savedQueryArguments(arguments map[string]string) []InputDictionaryEntry {
+	entries := []InputDictionaryEntry{}
+	for k, v := range arguments {
+		entries = append(entries, InputDictionaryEntry{Key: graphql.String(k), Value: graphql.String(v)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// GetSavedQuery returns the saved query, or an empty saved query if it does not exist
+func (h *ClientConfig) GetSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error) {
+	err := h.validateView(config, req, hsq.Spec.ViewName)
+	if err != nil {
+		return &SavedQuery{}, fmt.Errorf("problem getting view for saved query %s: %w", hsq.Spec.Name, err)
+	}
+
+	savedQueries, err := h.ListSavedQueries(config, req, hsq.Spec.ViewName)
+	if err != nil {
+		return &SavedQuery{}, err
+	}
+	for _, savedQuery := range savedQueries {
+		if savedQuery.Name == hsq.Spec.Name {
+			return &savedQuery, nil
+		}
+	}
+	return &SavedQuery{}, nil
+}
+
+// ListSavedQueries returns the saved queries of the given view
+func (h *ClientConfig) ListSavedQueries(config *humioapi.Config, req reconcile.Request, viewName string) ([]SavedQuery, error) {
+	var query struct {
+		SearchDomain struct {
+			SavedQueries []struct {
+				ID          graphql.String  `graphql:"id"`
+				Name        graphql.String  `graphql:"name"`
+				Description *graphql.String `graphql:"description"`
+				Query       struct {
+					QueryString graphql.String  `graphql:"queryString"`
+					Start       graphql.String  `graphql:"start"`
+					End         graphql.String  `graphql:"end"`
+					IsLive      graphql.Boolean `graphql:"isLive"`
+					Arguments   []struct {
+						Key   graphql.String `graphql:"key"`
+						Value graphql.String `graphql:"value"`
+					} `graphql:"arguments"`
+				} `graphql:"query"`
+			} `graphql:"savedQueries"`
+		} `graphql:"searchDomain(name: $viewName)"`
+	}
+	err := h.GetHumioClient(config, req).Query(&query, map[string]interface{}{
+		"viewName": graphql.String(viewName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list saved queries of view %s: %w", viewName, err)
+	}
+
+	var savedQueries []SavedQuery
+	for _, sq := range query.SearchDomain.SavedQueries {
+		savedQuery := SavedQuery{
+			ID:          string(sq.ID),
+			Name:        string(sq.Name),
+			QueryString: string(sq.Query.QueryString),
+			Start:       string(sq.Query.Start),
+			End:         string(sq.Query.End),
+			IsLive:      bool(sq.Query.IsLive),
+		}
+		if sq.Description != nil {
+			savedQuery.Description = string(*sq.Description)
+		}
+		if len(sq.Query.Arguments) > 0 {
+			savedQuery.Arguments = make(map[string]string, len(sq.Query.Arguments))
+			for _, argument := range sq.Query.Arguments {
+				savedQuery.Arguments[string(argument.Key)] = string(argument.Value)
+			}
+		}
+		savedQueries = append(savedQueries, savedQuery)
+	}
+	return savedQueries, nil
+}
+
+func (h *ClientConfig) AddSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error) {
+	err := h.validateView(config, req, hsq.Spec.ViewName)
+	if err != nil {
+		return &SavedQuery{}, fmt.Errorf("problem getting view for saved query %s: %w", hsq.Spec.Name, err)
+	}
+
+	savedQuery := SavedQueryTransform(hsq)
+	var mutation struct {
+		CreateSavedQuery struct {
+			SavedQuery struct {
+				ID graphql.String `graphql:"id"`
+			} `graphql:"savedQuery"`
+		} `graphql:"createSavedQuery(input: $input)"`
+	}
+	err = h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": CreateSavedQueryInput{
+			Name:           graphql.String(savedQuery.Name),
+			ViewName:       graphql.String(hsq.Spec.ViewName),
+			Description:    graphql.String(savedQuery.Description),
+			QueryString:    graphql.String(savedQuery.QueryString),
+			Start:          graphql.String(savedQuery.Start),
+			End:            graphql.String(savedQuery.End),
+			IsLive:         graphql.Boolean(savedQuery.IsLive),
+			QueryArguments: savedQueryArguments(savedQuery.Arguments),
+		},
+	})
+	if err != nil {
+		return &SavedQuery{}, fmt.Errorf("got error when attempting to add saved query: %w", err)
+	}
+	savedQuery.ID = string(mutation.CreateSavedQuery.SavedQuery.ID)
+	return savedQuery, nil
+}
+
+func (h *ClientConfig) UpdateSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error) {
+	currentSavedQuery, err := h.GetSavedQuery(config, req, hsq)
+	if err != nil {
+		return &SavedQuery{}, err
+	}
+	if currentSavedQuery.ID == "" {
+		return &SavedQuery{}, fmt.Errorf("could not find saved query with name: %q", hsq.Spec.Name)
+	}
+
+	savedQuery := SavedQueryTransform(hsq)
+	savedQuery.ID = currentSavedQuery.ID
+	var mutation struct {
+		UpdateSavedQuery struct {
+			// We have to make a selection, so just take __typename
+			Typename graphql.String `graphql:"__typename"`
+		} `graphql:"updateSavedQuery(input: $input)"`
+	}
+	err = h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": UpdateSavedQueryInput{
+			ID:             graphql.String(savedQuery.ID),
+			ViewName:       graphql.String(hsq.Spec.ViewName),
+			Name:           graphql.String(savedQuery.Name),
+			Description:    graphql.String(savedQuery.Description),
+			QueryString:    graphql.String(savedQuery.QueryString),
+			Start:          graphql.String(savedQuery.Start),
+			End:            graphql.String(savedQuery.End),
+			IsLive:         graphql.Boolean(savedQuery.IsLive),
+			QueryArguments: savedQueryArguments(savedQuery.Arguments),
+		},
+	})
+	if err != nil {
+		return &SavedQuery{}, fmt.Errorf("got error when attempting to update saved query: %w", err)
+	}
+	return savedQuery, nil
+}
+
+func (h *ClientConfig) DeleteSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) error {
+	currentSavedQuery, err := h.GetSavedQuery(config, req, hsq)
+	if err != nil {
+		return err
+	}
+	if currentSavedQuery.ID == "" {
+		return nil
+	}
+
+	var mutation struct {
+		DeleteSavedQuery graphql.Boolean `graphql:"deleteSavedQuery(input: $input)"`
+	}
+	return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": DeleteSavedQueryInput{
+			ID:       graphql.String(currentSavedQuery.ID),
+			ViewName: graphql.String(hsq.Spec.ViewName),
+		},
+	})
+}
+
+// CreateDashboardFromTemplateV2Input is the input of the createDashboardFromTemplateV2 mutation
+type CreateDashboardFromTemplateV2Input struct {
+	ViewName     graphql.String  `json:"viewName"`
+	Name         graphql.String  `json:"name"`
+	YamlTemplate graphql.String  `json:"yamlTemplate"`
+	OverrideName graphql.Boolean `json:"overrideName"`
+}
+
+// DeleteDashboardInput is the input of the deleteDashboard mutation
+type DeleteDashboardInput struct {
+	ID graphql.String `json:"id"`
+}
+
+// GetDashboard returns the dashboard, or an empty dashboard if it does not exist
+func (h *ClientConfig) GetDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard) (*Dashboard, error) {
+	err := h.validateView(config, req, hd.Spec.ViewName)
+	if err != nil {
+		return &Dashboard{}, fmt.Errorf("problem getting view for dashboard %s: %w", hd.Spec.Name, err)
+	}
+
+	dashboards, err := h.ListDashboards(config, req, hd.Spec.ViewName)
+	if err != nil {
+		return &Dashboard{}, err
+	}
+	for _, dashboard := range dashboards {
+		if dashboard.Name == hd.Spec.Name {
+			return &dashboard, nil
+		}
+	}
+	return &Dashboard{}, nil
+}
+
+// ListDashboards returns the dashboards of the given view
+func (h *ClientConfig) ListDashboards(config *humioapi.Config, req reconcile.Request, viewName string) ([]Dashboard, error) {
+	var query struct {
+		SearchDomain struct {
+			Dashboards []struct {
+				ID           graphql.String `graphql:"id"`
+				Name         graphql.String `graphql:"name"`
+				TemplateYaml graphql.String `graphql:"templateYaml"`
+			} `graphql:"dashboards"`
+		} `graphql:"searchDomain(name: $viewName)"`
+	}
+	err := h.GetHumioClient(config, req).Query(&query, map[string]interface{}{
+		"viewName": graphql.String(viewName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list dashboards of view %s: %w", viewName, err)
+	}
+
+	var dashboards []Dashboard
+	for _, d := range query.SearchDomain.Dashboards {
+		dashboards = append(dashboards, Dashboard{
+			ID:           string(d.ID),
+			Name:         string(d.Name),
+			TemplateYaml: string(d.TemplateYaml),
+		})
+	}
+	return dashboards, nil
+}
+
+// AddDashboard creates the dashboard from the given YAML template
+func (h *ClientConfig) AddDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard, template string) (*Dashboard, error) {
+	err := h.validateView(config, req, hd.Spec.ViewName)
+	if err != nil {
+		return &Dashboard{}, fmt.Errorf("problem getting view for dashboard %s: %w", hd.Spec.Name, err)
+	}
+
+	var mutation struct {
+		CreateDashboardFromTemplateV2 struct {
+			ID   graphql.String `graphql:"id"`
+			Name graphql.String `graphql:"name"`
+		} `graphql:"createDashboardFromTemplateV2(input: $input)"`
+	}
+	err = h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": CreateDashboardFromTemplateV2Input{
+			ViewName:     graphql.String(hd.Spec.ViewName),
+			Name:         graphql.String(hd.Spec.Name),
+			YamlTemplate: graphql.String(template),
+			OverrideName: graphql.Boolean(true),
+		},
+	})
+	if err != nil {
+		return &Dashboard{}, fmt.Errorf("got error when attempting to add dashboard: %w", err)
+	}
+	return &Dashboard{
+		ID:   string(mutation.CreateDashboardFromTemplateV2.ID),
+		Name: string(mutation.CreateDashboardFromTemplateV2.Name),
+	}, nil
+}
+
+func (h *ClientConfig) DeleteDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard) error {
+	currentDashboard, err := h.GetDashboard(config, req, hd)
+	if err != nil {
+		return err
+	}
+	if currentDashboard.ID == "" {
+		return nil
+	}
+
+	var mutation struct {
+		DeleteDashboard struct {
+			// We have to make a selection, so just take __typename
+			Typename graphql.String `graphql:"__typename"`
+		} `graphql:"deleteDashboard(input: $input)"`
+	}
+	return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": DeleteDashboardInput{
+			ID: graphql.String(currentDashboard.ID),
+		},
+	})
+}
+
+func (h *ClientConfig) validateView(config *humioapi.Config, req reconcile.Request, viewName string) error {
+	view := &humiov1alpha1.HumioView{
+		Spec: humiov1alpha1.HumioViewSpec{
+			Name: viewName,
+		},
+	}
+
+	viewResult, err := h.GetView(config, req, view)
+	if err != nil {
+		return fmt.Errorf("failed to verify view %s exists. error: %w", viewName, err)
+	}
+
+	emptyView := &humioapi.View{}
+	if reflect.DeepEqual(emptyView, viewResult) {
+		return fmt.Errorf("view %s does not exist", viewName)
+	}
+
+	return nil
+}
+
+func (h *ClientConfig) GetAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
+	err := h.validateView(config, req, ha.Spec.ViewName)
+	if err != nil {
+		return nil, fmt.Errorf("problem getting view for action %s: %w", ha.Spec.Name, err)
+	}
+
+	key := newReadCacheKey(config, readCacheKindAction, ha.Spec.ViewName, ha.Spec.Name)
+	if cached, ok := h.readCache.get(key); ok {
+		action := cached.(humioapi.Action)
+		return &action, nil
+	}
+
+	var action *humioapi.Action
+	if h.bulkListingEnabled() {
+		action, err = h.bulkGetAction(config, req, ha.Spec.ViewName, ha.Spec.Name)
+	} else {
+		action, err = h.GetHumioClient(config, req).Actions().Get(ha.Spec.ViewName, ha.Spec.Name)
+	}
+	if err != nil {
+		return action, fmt.Errorf("error when trying to get action %+v, name=%s, view=%s: %w", action, ha.Spec.Name, ha.Spec.ViewName, err)
+	}
+
+	if action == nil || action.Name == "" {
+		return nil, nil
+	}
+
+	h.readCache.set(key, *action)
+	return action, nil
+}
+
+func (h *ClientConfig) AddAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
+	err := h.validateView(config, req, ha.Spec.ViewName)
+	if err != nil {
+		return nil, fmt.Errorf("problem getting view for action %s: %w", ha.Spec.Name, err)
+	}
+
+	action, err := ActionFromActionCR(ha)
+	if err != nil {
+		return action, err
+	}
+
+	createdAction, err := h.GetHumioClient(config, req).Actions().Add(ha.Spec.ViewName, action)
+	h.readCache.invalidate(newReadCacheKey(config, readCacheKindAction, ha.Spec.ViewName, ha.Spec.Name))
+	if err != nil {
+		return createdAction, fmt.Errorf("got error when attempting to add action: %w", err)
+	}
+	return createdAction, nil
+}
+
+func (h *ClientConfig) UpdateAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
+	err := h.validateView(config, req, ha.Spec.ViewName)
+	if err != nil {
+		return nil, fmt.Errorf("problem getting view for action %s: %w", ha.Spec.Name, err)
+	}
+
+	action, err := ActionFromActionCR(ha)
+	if err != nil {
+		return action, err
+	}
+
+	defer h.readCache.invalidate(newReadCacheKey(config, readCacheKindAction, ha.Spec.ViewName, ha.Spec.Name))
+	return h.GetHumioClient(config, req).Actions().Update(ha.Spec.ViewName, action)
+}
+
+func (h *ClientConfig) DeleteAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) error {
+	defer h.readCache.invalidate(newReadCacheKey(config, readCacheKindAction, ha.Spec.ViewName, ha.Spec.Name))
+	return h.GetHumioClient(config, req).Actions().Delete(ha.Spec.ViewName, ha.Spec.Name)
+}
+
+// TestActionResult is the outcome of invoking an action once with a synthetic event
+type TestActionResult struct {
+	Success bool
+	Message string
+}
+
+type testActionResult struct {
+	Success graphql.Boolean `graphql:"success"`
+	Message graphql.String  `graphql:"message"`
+}
+
+// TestEmailAction is the input of the testEmailAction mutation
+type TestEmailAction struct {
+	ViewName        graphql.String   `json:"viewName"`
+	Name            graphql.String   `json:"name"`
+	Recipients      []graphql.String `json:"recipients"`
+	SubjectTemplate graphql.String   `json:"subjectTemplate"`
+	BodyTemplate    graphql.String   `json:"bodyTemplate"`
+	UseProxy        graphql.Boolean  `json:"useProxy"`
+	TriggerName     graphql.String   `json:"triggerName"`
+	EventData       graphql.String   `json:"eventData"`
+}
+
+// TestHumioRepoAction is the input of the testHumioRepoAction mutation
+type TestHumioRepoAction struct {
+	ViewName    graphql.String `json:"viewName"`
+	Name        graphql.String `json:"name"`
+	IngestToken graphql.String `json:"ingestToken"`
+	TriggerName graphql.String `json:"triggerName"`
+	EventData   graphql.String `json:"eventData"`
+}
+
+// TestOpsGenieAction is the input of the testOpsGenieAction mutation
+type TestOpsGenieAction struct {
+	ViewName    graphql.String  `json:"viewName"`
+	Name        graphql.String  `json:"name"`
+	ApiUrl      graphql.String  `json:"apiUrl"`
+	GenieKey    graphql.String  `json:"genieKey"`
+	UseProxy    graphql.Boolean `json:"useProxy"`
+	TriggerName graphql.String  `json:"triggerName"`
+	EventData   graphql.String  `json:"eventData"`
+}
+
+// TestPagerDutyAction is the input of the testPagerDutyAction mutation
+type TestPagerDutyAction struct {
+	ViewName    graphql.String  `json:"viewName"`
+	Name        graphql.String  `json:"name"`
+	Severity    graphql.String  `json:"severity"`
+	RoutingKey  graphql.String  `json:"routingKey"`
+	UseProxy    graphql.Boolean `json:"useProxy"`
+	TriggerName graphql.String  `json:"triggerName"`
+	EventData   graphql.String  `json:"eventData"`
+}
+
+// TestSlackAction is the input of the testSlackAction mutation
+type TestSlackAction struct {
+	ViewName    graphql.String                  `json:"viewName"`
+	Name        graphql.String                  `json:"name"`
+	Url         graphql.String                  `json:"url"`
+	Fields      []humioapi.SlackFieldEntryInput `json:"fields"`
+	UseProxy    graphql.Boolean                 `json:"useProxy"`
+	TriggerName graphql.String                  `json:"triggerName"`
+	EventData   graphql.String                  `json:"eventData"`
+}
+
+// TestSlackPostMessageAction is the input of the testSlackPostMessageAction mutation
+type TestSlackPostMessageAction struct {
+	ViewName    graphql.String                  `json:"viewName"`
+	Name        graphql.String                  `json:"name"`
+	ApiToken    graphql.String                  `json:"apiToken"`
+	Channels    []graphql.String                `json:"channels"`
+	Fields      []humioapi.SlackFieldEntryInput `json:"fields"`
+	UseProxy    graphql.Boolean                 `json:"useProxy"`
+	TriggerName graphql.String                  `json:"triggerName"`
+	EventData   graphql.String                  `json:"eventData"`
+}
+
+// TestVictorOpsAction is the input of the testVictorOpsAction mutation
+type TestVictorOpsAction struct {
+	ViewName    graphql.String  `json:"viewName"`
+	Name        graphql.String  `json:"name"`
+	MessageType graphql.String  `json:"messageType"`
+	NotifyUrl   graphql.String  `json:"notifyUrl"`
+	UseProxy    graphql.Boolean `json:"useProxy"`
+	TriggerName graphql.String  `json:"triggerName"`
+	EventData   graphql.String  `json:"eventData"`
+}
+
+// TestWebhookAction is the input of the testWebhookAction mutation
+type TestWebhookAction struct {
+	ViewName     graphql.String                  `json:"viewName"`
+	Name         graphql.String                  `json:"name"`
+	Url          graphql.String                  `json:"url"`
+	Method       graphql.String                  `json:"method"`
+	Headers      []humioapi.HttpHeaderEntryInput `json:"headers"`
+	BodyTemplate graphql.String                  `json:"bodyTemplate"`
+	IgnoreSSL    graphql.Boolean                 `json:"ignoreSSL"`
+	UseProxy     graphql.Boolean                 `json:"useProxy"`
+	TriggerName  graphql.String                  `json:"triggerName"`
+	EventData    graphql.String                  `json:"eventData"`
+}
+
+// TestAction invokes the action once with the event data, as if it was triggered by the trigger with the given name.
+// Humio reports whether the notification was delivered in the result.
+func (h *ClientConfig) TestAction(config *humioapi.Config, req reconcile.Request, viewName string, action *humioapi.Action, triggerName, eventData string) (*TestActionResult, error) {
+	client := h.GetHumioClient(config, req)
+	name := graphql.String(action.Name)
+	view := graphql.String(viewName)
+	trigger := graphql.String(triggerName)
+	event := graphql.String(eventData)
+
+	var result testActionResult
+	var err error
+	switch {
+	case !reflect.ValueOf(action.EmailAction).IsZero():
+		var mutation struct {
+			TestEmailAction testActionResult `graphql:"testEmailAction(input: $input)"`
+		}
+		recipients := make([]graphql.String, len(action.EmailAction.Recipients))
+		for i, recipient := range action.EmailAction.Recipients {
+			recipients[i] = graphql.String(recipient)
+		}
+		err = client.Mutate(&mutation, map[string]interface{}{"input": TestEmailAction{
+			ViewName:        view,
+			Name:            name,
+			Recipients:      recipients,
+			SubjectTemplate: graphql.String(action.EmailAction.SubjectTemplate),
+			BodyTemplate:    graphql.String(action.EmailAction.BodyTemplate),
+			UseProxy:        graphql.Boolean(action.EmailAction.UseProxy),
+			TriggerName:     trigger,
+			EventData:       event,
+		}})
+		result = mutation.TestEmailAction
+	case !reflect.ValueOf(action.HumioRepoAction).IsZero():
+		var mutation struct {
+			TestHumioRepoAction testActionResult `graphql:"testHumioRepoAction(input: $input)"`
+		}
+		err = client.Mutate(&mutation, map[string]interface{}{"input": TestHumioRepoAction{
+			ViewName:    view,
+			Name:        name,
+			IngestToken: graphql.String(action.HumioRepoAction.IngestToken),
+			TriggerName: trigger,
+			EventData:   event,
+		}})
+		result = mutation.TestHumioRepoAction
+	case !reflect.ValueOf(action.OpsGenieAction).IsZero():
+		var mutation struct {
+			TestOpsGenieAction testActionResult `graphql:"testOpsGenieAction(input: $input)"`
+		}
+		err = client.Mutate(&mutation, map[string]interface{}{"input": TestOpsGenieAction{
+			ViewName:    view,
+			Name:        name,
+			ApiUrl:      graphql.String(action.OpsGenieAction.ApiUrl),
+			GenieKey:    graphql.String(action.OpsGenieAction.GenieKey),
+			UseProxy:    graphql.Boolean(action.OpsGenieAction.UseProxy),
+			TriggerName: trigger,
+			EventData:   event,
+		}})
+		result = mutation.TestOpsGenieAction
+	case !reflect.ValueOf(action.PagerDutyAction).IsZero():
+		var mutation struct {
+			TestPagerDutyAction testActionResult `graphql:"testPagerDutyAction(input: $input)"`
+		}
+		err = client.Mutate(&mutation, map[string]interface{}{"input": TestPagerDutyAction{
+			ViewName:    view,
+			Name:        name,
+			Severity:    graphql.String(action.PagerDutyAction.Severity),
+			RoutingKey:  graphql.String(action.PagerDutyAction.RoutingKey),
+			UseProxy:    graphql.Boolean(action.PagerDutyAction.UseProxy),
+			TriggerName: trigger,
+			EventData:   event,
+		}})
+		result = mutation.TestPagerDutyAction
+	case !reflect.ValueOf(action.SlackAction).IsZero():
+		var mutation struct {
+			TestSlackAction testActionResult `graphql:"testSlackAction(input: $input)"`
+		}
+		err = client.Mutate(&mutation, map[string]interface{}{"input": TestSlackAction{
+			ViewName:    view,
+			Name:        name,
+			Url:         graphql.String(action.SlackAction.Url),
+			Fields:      action.SlackAction.Fields,
+			UseProxy:    graphql.Boolean(action.SlackAction.UseProxy),
+			TriggerName: trigger,
+			EventData:   event,
+		}})
+		result = mutation.TestSlackAction
+	case !reflect.ValueOf(action.SlackPostMessageAction).IsZero():
+		var mutation struct {
+			TestSlackPostMessageAction testActionResult `graphql:"testSlackPostMessageAction(input: $input)"`
+		}
+		channels := make([]graphql.String, len(action.SlackPostMessageAction.Channels))
+		for i, channel := range action.SlackPostMessageAction.Channels {
+			channels[i] = graphql.String(channel)
+		}
+		err = client.Mutate(&mutation, map[string]interface{}{"input": TestSlackPostMessageAction{
+			ViewName:    view,
+			Name:        name,
+			ApiToken:    graphql.String(action.SlackPostMessageAction.ApiToken),
+			Channels:    channels,
+			Fields:      action.SlackPostMessageAction.Fields,
+			UseProxy:    graphql.Boolean(action.SlackPostMessageAction.UseProxy),
+			TriggerName: trigger,
+			EventData:   event,
+		}})
+		result = mutation.TestSlackPostMessageAction
+	case !reflect.ValueOf(action.VictorOpsAction).IsZero():
+		var mutation struct {
+			TestVictorOpsAction testActionResult `graphql:"testVictorOpsAction(input: $input)"`
+		}
+		err = client.Mutate(&mutation, map[string]interface{}{"input": TestVictorOpsAction{
+			ViewName:    view,
+			Name:        name,
+			MessageType: graphql.String(action.VictorOpsAction.MessageType),
+			NotifyUrl:   graphql.String(action.VictorOpsAction.NotifyUrl),
+			UseProxy:    graphql.Boolean(action.VictorOpsAction.UseProxy),
+			TriggerName: trigger,
+			EventData:   event,
+		}})
+		result = mutation.TestVictorOpsAction
+	case !reflect.ValueOf(action.WebhookAction).IsZero():
+		var mutation struct {
+			TestWebhookAction testActionResult `graphql:"testWebhookAction(input: $input)"`
+		}
+		err = client.Mutate(&mutation, map[string]interface{}{"input": TestWebhookAction{
+			ViewName:     view,
+			Name:         name,
+			Url:          graphql.String(action.WebhookAction.Url),
+			Method:       graphql.String(action.WebhookAction.Method),
+			Headers:      action.WebhookAction.Headers,
+			BodyTemplate: graphql.String(action.WebhookAction.BodyTemplate),
+			IgnoreSSL:    graphql.Boolean(action.WebhookAction.IgnoreSSL),
+			UseProxy:     graphql.Boolean(action.WebhookAction.UseProxy),
+			TriggerName:  trigger,
+			EventData:    event,
+		}})
+		result = mutation.TestWebhookAction
+	default:
+		return nil, fmt.Errorf("action %s has an unsupported type %s", action.Name, action.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("got error when attempting to test action %s: %w", action.Name, err)
+	}
+	return &TestActionResult{
+		Success: bool(result.Success),
+		Message: string(result.Message),
+	}, nil
+}
+
+func getConnectionMap(viewConnections []humioapi.ViewConnection) []humioapi.ViewConnectionInput {
+	connectionMap := make([]humioapi.ViewConnectionInput, 0)
+	for _, connection := range viewConnections {
+		connectionMap = append(connectionMap, humioapi.ViewConnectionInput{
+			RepositoryName: graphql.String(connection.RepoName),
+			Filter:         graphql.String(connection.Filter),
+		})
+	}
+	return connectionMap
+}
+
+func (h *ClientConfig) GetLicense(config *humioapi.Config, req reconcile.Request) (humioapi.License, error) {
+	return h.GetHumioClient(config, req).Licenses().Get()
+}
+
+func (h *ClientConfig) InstallLicense(config *humioapi.Config, req reconcile.Request, license string) error {
+	return h.GetHumioClient(config, req).Licenses().Install(license)
+}
+
+// RotateUserAPIToken rotates the personal API token of the given user and returns the new API token. The previous API
+// token of the user stops working immediately.
+func (h *ClientConfig) RotateUserAPIToken(config *humioapi.Config, req reconcile.Request, username string) (string, error) {
+	user, err := h.getUser(config, req, username)
+	if err != nil {
+		return "", fmt.Errorf("could not get user %s: %w", username, err)
+	}
+	return h.GetHumioClient(config, req).Users().RotateToken(user.ID)
+}
+
+// EnsureRootUser creates the given user with root permissions, or grants root permissions to the user if it already
+// exists
+func (h *ClientConfig) EnsureRootUser(config *humioapi.Config, req reconcile.Request, username string) error {
+	isRoot := true
+	users, err := h.listUsers(config, req, username)
+	if err != nil {
+		return fmt.Errorf("could not list users: %w", err)
+	}
+	for _, user := range users {
+		if user.Username != username {
+			continue
+		}
+		if user.IsRoot {
+			return nil
+		}
+		_, err = h.GetHumioClient(config, req).Users().Update(username, humioapi.UserChangeSet{IsRoot: &isRoot})
+		return err
+	}
+	_, err = h.GetHumioClient(config, req).Users().Add(username, humioapi.UserChangeSet{IsRoot: &isRoot})
+	return err
+}
+
+func (h *ClientConfig) GetAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (*humioapi.Alert, error) {
 	err := h.validateView(config, req, ha.Spec.ViewName)
 	if err != nil {
 		return &humioapi.Alert{}, fmt.Errorf("problem getting view for action %s: %w", ha.Spec.Name, err)
 	}
 
-	alert, err := h.GetHumioClient(config, req).Alerts().Get(ha.Spec.ViewName, ha.Spec.Name)
+	key := newReadCacheKey(config, readCacheKindAlert, ha.Spec.ViewName, ha.Spec.Name)
+	if cached, ok := h.readCache.get(key); ok {
+		alert := cached.(humioapi.Alert)
+		return &alert, nil
+	}
+
+	var alert *humioapi.Alert
+	if h.bulkListingEnabled() {
+		alert, err = h.bulkGetAlert(config, req, ha.Spec.ViewName, ha.Spec.Name)
+	} else {
+		alert, err = h.GetHumioClient(config, req).Alerts().Get(ha.Spec.ViewName, ha.Spec.Name)
+	}
 	if err != nil {
 		return alert, fmt.Errorf("error when trying to get alert %+v, name=%s, view=%s: %w", alert, ha.Spec.Name, ha.Spec.ViewName, err)
 	}
@@ -568,6 +2075,7 @@ func (h *ClientConfig) GetAlert(config *humioapi.Config, req reconcile.Request,
 		return nil, nil
 	}
 
+	h.readCache.set(key, *alert)
 	return alert, nil
 }
 
@@ -587,6 +2095,7 @@ func (h *ClientConfig) AddAlert(config *humioapi.Config, req reconcile.Request,
 	}
 
 	createdAlert, err := h.GetHumioClient(config, req).Alerts().Add(ha.Spec.ViewName, alert)
+	h.readCache.invalidate(newReadCacheKey(config, readCacheKindAlert, ha.Spec.ViewName, ha.Spec.Name))
 	if err != nil {
 		return createdAlert, fmt.Errorf("got error when attempting to add alert: %w, alert: %#v", err, *alert)
 	}
@@ -614,43 +2123,535 @@ func (h *ClientConfig) UpdateAlert(config *humioapi.Config, req reconcile.Reques
 	}
 	alert.ID = currentAlert.ID
 
+	defer h.readCache.invalidate(newReadCacheKey(config, readCacheKindAlert, ha.Spec.ViewName, ha.Spec.Name))
 	return h.GetHumioClient(config, req).Alerts().Update(ha.Spec.ViewName, alert)
 }
 
 func (h *ClientConfig) DeleteAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) error {
+	defer h.readCache.invalidate(newReadCacheKey(config, readCacheKindAlert, ha.Spec.ViewName, ha.Spec.Name))
 	return h.GetHumioClient(config, req).Alerts().Delete(ha.Spec.ViewName, ha.Spec.Name)
 }
 
-func (h *ClientConfig) getAndValidateAction(config *humioapi.Config, req reconcile.Request, actionName string, viewName string) (*humioapi.Action, error) {
-	action := &humiov1alpha1.HumioAction{
-		Spec: humiov1alpha1.HumioActionSpec{
-			Name:     actionName,
-			ViewName: viewName,
+// ErrActionNotFound is returned when an alert refers to an action which does not exist
+var ErrActionNotFound = errors.New("action does not exist")
+
+// GetActionIDsMapForAlerts returns a mapping from action names to action IDs for all actions referenced by the alert.
+// All actions in the view are fetched using a single listing, rather than looking up each action individually.
+func (h *ClientConfig) GetActionIDsMapForAlerts(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (map[string]string, error) {
+	actionIdMap := make(map[string]string)
+	if len(ha.Spec.Actions) == 0 {
+		return actionIdMap, nil
+	}
+
+	err := h.validateView(config, req, ha.Spec.ViewName)
+	if err != nil {
+		return actionIdMap, fmt.Errorf("problem getting view for alert %s: %w", ha.Spec.Name, err)
+	}
+
+	actions, err := h.listActions(config, req, ha.Spec.ViewName)
+	if err != nil {
+		return actionIdMap, fmt.Errorf("problem getting actions for alert %s: %w", ha.Spec.Name, err)
+	}
+	actionIDsByName := make(map[string]string, len(actions))
+	for _, action := range actions {
+		actionIDsByName[action.Name] = action.ID
+	}
+
+	for _, actionNameForAlert := range ha.Spec.Actions {
+		actionID, found := actionIDsByName[actionNameForAlert]
+		if !found {
+			return actionIdMap, fmt.Errorf("problem getting action for alert %s: %w: %s", ha.Spec.Name, ErrActionNotFound, actionNameForAlert)
+		}
+		actionIdMap[actionNameForAlert] = actionID
+	}
+	return actionIdMap, nil
+}
+
+// CreateAggregateAlert is the input of the createAggregateAlert mutation
+type CreateAggregateAlert struct {
+	ViewName              graphql.String   `json:"viewName"`
+	Name                  graphql.String   `json:"name"`
+	Description           graphql.String   `json:"description"`
+	QueryString           graphql.String   `json:"queryString"`
+	ActionIdsOrNames      []graphql.String `json:"actionIdsOrNames"`
+	Labels                []graphql.String `json:"labels"`
+	Enabled               graphql.Boolean  `json:"enabled"`
+	ThrottleField         *graphql.String  `json:"throttleField,omitempty"`
+	ThrottleTimeSeconds   graphql.Int      `json:"throttleTimeSeconds"`
+	TriggerMode           graphql.String   `json:"triggerMode"`
+	SearchIntervalSeconds graphql.Int      `json:"searchIntervalSeconds"`
+	QueryTimestampType    graphql.String   `json:"queryTimestampType"`
+	QueryOwnershipType    graphql.String   `json:"queryOwnershipType"`
+}
+
+// UpdateAggregateAlert is the input of the updateAggregateAlert mutation
+type UpdateAggregateAlert struct {
+	ID graphql.String `json:"id"`
+	CreateAggregateAlert
+}
+
+// DeleteAggregateAlert is the input of the deleteAggregateAlert mutation
+type DeleteAggregateAlert struct {
+	ID       graphql.String `json:"id"`
+	ViewName graphql.String `json:"viewName"`
+}
+
+// queryOwnershipTypeOrganization runs the queries of aggregate alerts and scheduled searches with the permissions of
+// the organization, so they keep running independently of the user of the operator's API token
+const queryOwnershipTypeOrganization = "Organization"
+
+// graphqlStrings converts the strings to GraphQL strings, and always returns a non-nil slice since Humio rejects
+// null for list fields
+func graphqlStrings(values []string) []graphql.String {
+	strs := make([]graphql.String, 0, len(values))
+	for _, value := range values {
+		strs = append(strs, graphql.String(value))
+	}
+	return strs
+}
+
+// optionalGraphqlString returns nil for an empty string, so optional fields are left unset rather than set to ""
+func optionalGraphqlString(value string) *graphql.String {
+	if value == "" {
+		return nil
+	}
+	str := graphql.String(value)
+	return &str
+}
+
+func createAggregateAlertInput(viewName string, aggregateAlert *AggregateAlert) CreateAggregateAlert {
+	return CreateAggregateAlert{
+		ViewName:              graphql.String(viewName),
+		Name:                  graphql.String(aggregateAlert.Name),
+		Description:           graphql.String(aggregateAlert.Description),
+		QueryString:           graphql.String(aggregateAlert.QueryString),
+		ActionIdsOrNames:      graphqlStrings(aggregateAlert.Actions),
+		Labels:                graphqlStrings(aggregateAlert.Labels),
+		Enabled:               graphql.Boolean(aggregateAlert.Enabled),
+		ThrottleField:         optionalGraphqlString(aggregateAlert.ThrottleField),
+		ThrottleTimeSeconds:   graphql.Int(aggregateAlert.ThrottleTimeSeconds),
+		TriggerMode:           graphql.String(aggregateAlert.TriggerMode),
+		SearchIntervalSeconds: graphql.Int(aggregateAlert.SearchIntervalSeconds),
+		QueryTimestampType:    graphql.String(aggregateAlert.QueryTimestampType),
+		QueryOwnershipType:    graphql.String(queryOwnershipTypeOrganization),
+	}
+}
+
+// GetAggregateAlert returns the aggregate alert, or an empty aggregate alert if it does not exist
+func (h *ClientConfig) GetAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error) {
+	err := h.validateView(config, req, haa.Spec.ViewName)
+	if err != nil {
+		return &AggregateAlert{}, fmt.Errorf("problem getting view for aggregate alert %s: %w", haa.Spec.Name, err)
+	}
+
+	aggregateAlerts, err := h.ListAggregateAlerts(config, req, haa.Spec.ViewName)
+	if err != nil {
+		return &AggregateAlert{}, err
+	}
+	for _, aggregateAlert := range aggregateAlerts {
+		if aggregateAlert.Name == haa.Spec.Name {
+			return &aggregateAlert, nil
+		}
+	}
+	return &AggregateAlert{}, nil
+}
+
+// ListAggregateAlerts returns the aggregate alerts of the given view
+func (h *ClientConfig) ListAggregateAlerts(config *humioapi.Config, req reconcile.Request, viewName string) ([]AggregateAlert, error) {
+	var query struct {
+		SearchDomain struct {
+			AggregateAlerts []struct {
+				ID                    graphql.String   `graphql:"id"`
+				Name                  graphql.String   `graphql:"name"`
+				Description           *graphql.String  `graphql:"description"`
+				QueryString           graphql.String   `graphql:"queryString"`
+				SearchIntervalSeconds graphql.Int      `graphql:"searchIntervalSeconds"`
+				QueryTimestampType    graphql.String   `graphql:"queryTimestampType"`
+				TriggerMode           graphql.String   `graphql:"triggerMode"`
+				ThrottleTimeSeconds   graphql.Int      `graphql:"throttleTimeSeconds"`
+				ThrottleField         *graphql.String  `graphql:"throttleField"`
+				Enabled               graphql.Boolean  `graphql:"enabled"`
+				Labels                []graphql.String `graphql:"labels"`
+				Actions               []struct {
+					Name graphql.String `graphql:"name"`
+				} `graphql:"actions"`
+			} `graphql:"aggregateAlerts"`
+		} `graphql:"searchDomain(name: $viewName)"`
+	}
+	err := h.GetHumioClient(config, req).Query(&query, map[string]interface{}{
+		"viewName": graphql.String(viewName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list aggregate alerts of view %s: %w", viewName, err)
+	}
+
+	var aggregateAlerts []AggregateAlert
+	for _, aa := range query.SearchDomain.AggregateAlerts {
+		aggregateAlert := AggregateAlert{
+			ID:                    string(aa.ID),
+			Name:                  string(aa.Name),
+			QueryString:           string(aa.QueryString),
+			SearchIntervalSeconds: int(aa.SearchIntervalSeconds),
+			QueryTimestampType:    string(aa.QueryTimestampType),
+			TriggerMode:           string(aa.TriggerMode),
+			ThrottleTimeSeconds:   int(aa.ThrottleTimeSeconds),
+			Enabled:               bool(aa.Enabled),
+		}
+		if aa.Description != nil {
+			aggregateAlert.Description = string(*aa.Description)
+		}
+		if aa.ThrottleField != nil {
+			aggregateAlert.ThrottleField = string(*aa.ThrottleField)
+		}
+		for _, action := range aa.Actions {
+			aggregateAlert.Actions = append(aggregateAlert.Actions, string(action.Name))
+		}
+		for _, label := range aa.Labels {
+			aggregateAlert.Labels = append(aggregateAlert.Labels, string(label))
+		}
+		aggregateAlerts = append(aggregateAlerts, aggregateAlert)
+	}
+	return aggregateAlerts, nil
+}
+
+func (h *ClientConfig) AddAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error) {
+	err := h.validateView(config, req, haa.Spec.ViewName)
+	if err != nil {
+		return &AggregateAlert{}, fmt.Errorf("problem getting view for aggregate alert %s: %w", haa.Spec.Name, err)
+	}
+
+	aggregateAlert := AggregateAlertTransform(haa)
+	var mutation struct {
+		CreateAggregateAlert struct {
+			ID graphql.String `graphql:"id"`
+		} `graphql:"createAggregateAlert(input: $input)"`
+	}
+	err = h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": createAggregateAlertInput(haa.Spec.ViewName, aggregateAlert),
+	})
+	if err != nil {
+		return &AggregateAlert{}, fmt.Errorf("got error when attempting to add aggregate alert: %w", err)
+	}
+	aggregateAlert.ID = string(mutation.CreateAggregateAlert.ID)
+	return aggregateAlert, nil
+}
+
+func (h *ClientConfig) UpdateAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error) {
+	currentAggregateAlert, err := h.GetAggregateAlert(config, req, haa)
+	if err != nil {
+		return &AggregateAlert{}, err
+	}
+	if currentAggregateAlert.ID == "" {
+		return &AggregateAlert{}, fmt.Errorf("could not find aggregate alert with name: %q", haa.Spec.Name)
+	}
+
+	aggregateAlert := AggregateAlertTransform(haa)
+	aggregateAlert.ID = currentAggregateAlert.ID
+	var mutation struct {
+		UpdateAggregateAlert struct {
+			ID graphql.String `graphql:"id"`
+		} `graphql:"updateAggregateAlert(input: $input)"`
+	}
+	err = h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": UpdateAggregateAlert{
+			ID:                   graphql.String(aggregateAlert.ID),
+			CreateAggregateAlert: createAggregateAlertInput(haa.Spec.ViewName, aggregateAlert),
 		},
+	})
+	if err != nil {
+		return &AggregateAlert{}, fmt.Errorf("got error when attempting to update aggregate alert: %w", err)
 	}
+	return aggregateAlert, nil
+}
 
-	actionResult, err := h.GetAction(config, req, action)
+func (h *ClientConfig) DeleteAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) error {
+	currentAggregateAlert, err := h.GetAggregateAlert(config, req, haa)
 	if err != nil {
-		return actionResult, fmt.Errorf("failed to verify action %s exists. error: %w", actionName, err)
+		return err
+	}
+	if currentAggregateAlert.ID == "" {
+		return nil
 	}
 
-	emptyAction := &humioapi.Action{}
-	if reflect.DeepEqual(emptyAction, actionResult) {
-		return actionResult, fmt.Errorf("action %s does not exist", actionName)
+	var mutation struct {
+		DeleteAggregateAlert graphql.Boolean `graphql:"deleteAggregateAlert(input: $input)"`
 	}
+	return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": DeleteAggregateAlert{
+			ID:       graphql.String(currentAggregateAlert.ID),
+			ViewName: graphql.String(haa.Spec.ViewName),
+		},
+	})
+}
 
-	return actionResult, nil
+// CreateScheduledSearch is the input of the createScheduledSearch mutation
+type CreateScheduledSearch struct {
+	ViewName           graphql.String   `json:"viewName"`
+	Name               graphql.String   `json:"name"`
+	Description        graphql.String   `json:"description"`
+	QueryString        graphql.String   `json:"queryString"`
+	QueryStart         graphql.String   `json:"queryStart"`
+	QueryEnd           graphql.String   `json:"queryEnd"`
+	Schedule           graphql.String   `json:"schedule"`
+	TimeZone           graphql.String   `json:"timeZone"`
+	BackfillLimit      graphql.Int      `json:"backfillLimit"`
+	Enabled            graphql.Boolean  `json:"enabled"`
+	ActionIdsOrNames   []graphql.String `json:"actionIdsOrNames"`
+	Labels             []graphql.String `json:"labels"`
+	QueryOwnershipType graphql.String   `json:"queryOwnershipType"`
 }
 
-func (h *ClientConfig) GetActionIDsMapForAlerts(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (map[string]string, error) {
-	actionIdMap := make(map[string]string)
-	for _, actionNameForAlert := range ha.Spec.Actions {
-		action, err := h.getAndValidateAction(config, req, actionNameForAlert, ha.Spec.ViewName)
+// UpdateScheduledSearch is the input of the updateScheduledSearch mutation
+type UpdateScheduledSearch struct {
+	ID graphql.String `json:"id"`
+	CreateScheduledSearch
+}
+
+// DeleteScheduledSearch is the input of the deleteScheduledSearch mutation
+type DeleteScheduledSearch struct {
+	ID       graphql.String `json:"id"`
+	ViewName graphql.String `json:"viewName"`
+}
+
+func createScheduledSearchInput(viewName string, scheduledSearch *ScheduledSearch) CreateScheduledSearch {
+	return CreateScheduledSearch{
+		ViewName:           graphql.String(viewName),
+		Name:               graphql.String(scheduledSearch.Name),
+		Description:        graphql.String(scheduledSearch.Description),
+		QueryString:        graphql.String(scheduledSearch.QueryString),
+		QueryStart:         graphql.String(scheduledSearch.QueryStart),
+		QueryEnd:           graphql.String(scheduledSearch.QueryEnd),
+		Schedule:           graphql.String(scheduledSearch.Schedule),
+		TimeZone:           graphql.String(scheduledSearch.TimeZone),
+		BackfillLimit:      graphql.Int(scheduledSearch.BackfillLimit),
+		Enabled:            graphql.Boolean(scheduledSearch.Enabled),
+		ActionIdsOrNames:   graphqlStrings(scheduledSearch.Actions),
+		Labels:             graphqlStrings(scheduledSearch.Labels),
+		QueryOwnershipType: graphql.String(queryOwnershipTypeOrganization),
+	}
+}
+
+// GetScheduledSearch returns the scheduled search, or an empty scheduled search if it does not exist
+func (h *ClientConfig) GetScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error) {
+	err := h.validateView(config, req, hss.Spec.ViewName)
+	if err != nil {
+		return &ScheduledSearch{}, fmt.Errorf("problem getting view for scheduled search %s: %w", hss.Spec.Name, err)
+	}
+
+	scheduledSearches, err := h.ListScheduledSearches(config, req, hss.Spec.ViewName)
+	if err != nil {
+		return &ScheduledSearch{}, err
+	}
+	for _, scheduledSearch := range scheduledSearches {
+		if scheduledSearch.Name == hss.Spec.Name {
+			return &scheduledSearch, nil
+		}
+	}
+	return &ScheduledSearch{}, nil
+}
+
+// ListScheduledSearches returns the scheduled searches of the given view
+func (h *ClientConfig) ListScheduledSearches(config *humioapi.Config, req reconcile.Request, viewName string) ([]ScheduledSearch, error) {
+	var query struct {
+		SearchDomain struct {
+			ScheduledSearches []struct {
+				ID            graphql.String   `graphql:"id"`
+				Name          graphql.String   `graphql:"name"`
+				Description   *graphql.String  `graphql:"description"`
+				QueryString   graphql.String   `graphql:"queryString"`
+				Start         graphql.String   `graphql:"start"`
+				End           graphql.String   `graphql:"end"`
+				Schedule      graphql.String   `graphql:"schedule"`
+				TimeZone      graphql.String   `graphql:"timeZone"`
+				BackfillLimit graphql.Int      `graphql:"backfillLimit"`
+				Enabled       graphql.Boolean  `graphql:"enabled"`
+				Labels        []graphql.String `graphql:"labels"`
+				Actions       []struct {
+					Name graphql.String `graphql:"name"`
+				} `graphql:"actionsV2"`
+			} `graphql:"scheduledSearches"`
+		} `graphql:"searchDomain(name: $viewName)"`
+	}
+	err := h.GetHumioClient(config, req).Query(&query, map[string]interface{}{
+		"viewName": graphql.String(viewName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list scheduled searches of view %s: %w", viewName, err)
+	}
+
+	var scheduledSearches []ScheduledSearch
+	for _, ss := range query.SearchDomain.ScheduledSearches {
+		scheduledSearch := ScheduledSearch{
+			ID:            string(ss.ID),
+			Name:          string(ss.Name),
+			QueryString:   string(ss.QueryString),
+			QueryStart:    string(ss.Start),
+			QueryEnd:      string(ss.End),
+			Schedule:      string(ss.Schedule),
+			TimeZone:      string(ss.TimeZone),
+			BackfillLimit: int(ss.BackfillLimit),
+			Enabled:       bool(ss.Enabled),
+		}
+		if ss.Description != nil {
+			scheduledSearch.Description = string(*ss.Description)
+		}
+		for _, action := range ss.Actions {
+			scheduledSearch.Actions = append(scheduledSearch.Actions, string(action.Name))
+		}
+		for _, label := range ss.Labels {
+			scheduledSearch.Labels = append(scheduledSearch.Labels, string(label))
+		}
+		scheduledSearches = append(scheduledSearches, scheduledSearch)
+	}
+	return scheduledSearches, nil
+}
+
+func (h *ClientConfig) AddScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error) {
+	err := h.validateView(config, req, hss.Spec.ViewName)
+	if err != nil {
+		return &ScheduledSearch{}, fmt.Errorf("problem getting view for scheduled search %s: %w", hss.Spec.Name, err)
+	}
+
+	scheduledSearch := ScheduledSearchTransform(hss)
+	var mutation struct {
+		CreateScheduledSearch struct {
+			ID graphql.String `graphql:"id"`
+		} `graphql:"createScheduledSearch(input: $input)"`
+	}
+	err = h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": createScheduledSearchInput(hss.Spec.ViewName, scheduledSearch),
+	})
+	if err != nil {
+		return &ScheduledSearch{}, fmt.Errorf("got error when attempting to add scheduled search: %w", err)
+	}
+	scheduledSearch.ID = string(mutation.CreateScheduledSearch.ID)
+	return scheduledSearch, nil
+}
+
+func (h *ClientConfig) UpdateScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error) {
+	currentScheduledSearch, err := h.GetScheduledSearch(config, req, hss)
+	if err != nil {
+		return &ScheduledSearch{}, err
+	}
+	if currentScheduledSearch.ID == "" {
+		return &ScheduledSearch{}, fmt.Errorf("could not find scheduled search with name: %q", hss.Spec.Name)
+	}
+
+	scheduledSearch := ScheduledSearchTransform(hss)
+	scheduledSearch.ID = currentScheduledSearch.ID
+	var mutation struct {
+		UpdateScheduledSearch struct {
+			ID graphql.String `graphql:"id"`
+		} `graphql:"updateScheduledSearch(input: $input)"`
+	}
+	err = h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": UpdateScheduledSearch{
+			ID:                    graphql.String(scheduledSearch.ID),
+			CreateScheduledSearch: createScheduledSearchInput(hss.Spec.ViewName, scheduledSearch),
+		},
+	})
+	if err != nil {
+		return &ScheduledSearch{}, fmt.Errorf("got error when attempting to update scheduled search: %w", err)
+	}
+	return scheduledSearch, nil
+}
+
+func (h *ClientConfig) DeleteScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) error {
+	currentScheduledSearch, err := h.GetScheduledSearch(config, req, hss)
+	if err != nil {
+		return err
+	}
+	if currentScheduledSearch.ID == "" {
+		return nil
+	}
+
+	var mutation struct {
+		DeleteScheduledSearch graphql.Boolean `graphql:"deleteScheduledSearch(input: $input)"`
+	}
+	return h.GetHumioClient(config, req).Mutate(&mutation, map[string]interface{}{
+		"input": DeleteScheduledSearch{
+			ID:       graphql.String(currentScheduledSearch.ID),
+			ViewName: graphql.String(hss.Spec.ViewName),
+		},
+	})
+}
+
+// CreateQueryJob starts a query job against the given repository or view and returns the ID of the query job. Query
+// jobs are stopped by Humio if they are not polled regularly.
+func (h *ClientConfig) CreateQueryJob(config *humioapi.Config, req reconcile.Request, repositoryName string, query humioapi.Query) (string, error) {
+	return h.GetHumioClient(config, req).QueryJobs().Create(repositoryName, query)
+}
+
+// PollQueryJob returns the current result of the given query job
+func (h *ClientConfig) PollQueryJob(config *humioapi.Config, req reconcile.Request, repositoryName string, id string) (humioapi.QueryResult, error) {
+	return h.GetHumioClient(config, req).QueryJobs().Poll(repositoryName, id)
+}
+
+// DeleteQueryJob stops the given query job
+func (h *ClientConfig) DeleteQueryJob(config *humioapi.Config, req reconcile.Request, repositoryName string, id string) error {
+	return h.GetHumioClient(config, req).QueryJobs().Delete(repositoryName, id)
+}
+
+func (h *ClientConfig) GetRepositoryUsage(config *humioapi.Config, req reconcile.Request) ([]RepositoryUsage, error) {
+	var query struct {
+		Repositories []RepositoryUsage `graphql:"repositories"`
+	}
+	err := h.GetHumioClient(config, req).Query(&query, nil)
+	return query.Repositories, err
+}
+
+// GetIngestUsage returns the number of bytes ingested into each repository during the last 24 hours
+func (h *ClientConfig) GetIngestUsage(config *humioapi.Config, req reconcile.Request) (map[string]int64, error) {
+	result, err := runQuery(h.GetHumioClient(config, req), ingestUsageRepository, humioapi.Query{
+		QueryString: ingestUsageQuery,
+		Start:       ingestUsageStart,
+	}, ingestUsageTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return parseIngestUsage(result.Events), nil
+}
+
+// parseIngestUsage returns the ingested bytes of each repository from the events returned by the ingest usage query.
+// Numbers are returned as strings or floats depending on the Humio version, so both are accepted.
+func parseIngestUsage(events []map[string]interface{}) map[string]int64 {
+	usage := map[string]int64{}
+	for _, event := range events {
+		repository, ok := event["repo"].(string)
+		if !ok || repository == "" {
+			continue
+		}
+		bytes, err := strconv.ParseFloat(fmt.Sprint(event["bytes"]), 64)
 		if err != nil {
-			return actionIdMap, fmt.Errorf("problem getting action for alert %s: %w", ha.Spec.Name, err)
+			continue
 		}
-		actionIdMap[actionNameForAlert] = action.ID
+		usage[repository] += int64(bytes)
+	}
+	return usage
+}
 
+// CountUsers returns the number of users, which is reported along with every page of users
+func (h *ClientConfig) CountUsers(config *humioapi.Config, req reconcile.Request) (int, error) {
+	page, err := h.getUsersPage(config, req, "", 1, 1)
+	if err != nil {
+		return 0, fmt.Errorf("could not count users: %w", err)
 	}
-	return actionIdMap, nil
+	return page.PageInfo.TotalNumberOfRows, nil
+}
+
+// GetThreadDump returns a thread dump of the Humio node the config points at
+func (h *ClientConfig) GetThreadDump(config *humioapi.Config, req reconcile.Request) (string, error) {
+	resp, err := h.GetHumioClient(config, req).HTTPRequestContext(context.Background(), http.MethodGet, threadDumpPath, nil, "text/plain")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("unexpected response from %s: %s", threadDumpPath, resp.Status)
+	}
+	return string(body), nil
 }
@@ -0,0 +1,523 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	auditOperationCreate = "create"
+	auditOperationUpdate = "update"
+	auditOperationDelete = "delete"
+
+	auditResultSuccess = "success"
+	auditResultError   = "error"
+)
+
+// AuditRecord describes a single change the operator performed against the Humio API
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Cluster   string    `json:"cluster"`
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Operation string    `json:"operation"`
+	Diff      string    `json:"diff,omitempty"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuditSink receives an AuditRecord for every change the operator performs against the Humio API. Write must not
+// block for long, as it is called as part of the reconcile performing the change.
+type AuditSink interface {
+	Write(AuditRecord)
+}
+
+// AuditedClient wraps a Client and logs an audit record for every call that changes the state of the Humio cluster.
+// Records are also passed to any configured sinks.
+type AuditedClient struct {
+	Client
+	logger logr.Logger
+	sinks  []AuditSink
+}
+
+// NewAuditedClient returns a Client which audits all changes performed through the given client
+func NewAuditedClient(client Client, logger logr.Logger, sinks ...AuditSink) *AuditedClient {
+	return &AuditedClient{
+		Client: client,
+		logger: logger.WithName("audit"),
+		sinks:  sinks,
+	}
+}
+
+func (c *AuditedClient) audit(config *humioapi.Config, req reconcile.Request, kind, operation string, before, after interface{}, err error) {
+	record := AuditRecord{
+		Time:      time.Now().UTC(),
+		Kind:      kind,
+		Namespace: req.Namespace,
+		Name:      req.Name,
+		Operation: operation,
+		Diff:      cmp.Diff(before, after),
+		Result:    auditResultSuccess,
+	}
+	if config.Address != nil {
+		record.Cluster = config.Address.String()
+	}
+	if err != nil {
+		record.Result = auditResultError
+		record.Error = err.Error()
+	}
+
+	c.logger.Info(fmt.Sprintf("%s %s %s/%s: %s", operation, kind, req.Namespace, req.Name, record.Result),
+		"Audit.Cluster", record.Cluster, "Audit.Diff", record.Diff, "Audit.Error", record.Error)
+	for _, sink := range c.sinks {
+		sink.Write(record)
+	}
+}
+
+// entityRequest returns the request of the given custom resource, so changes made in a batch on behalf of several
+// custom resources are recorded against each of them
+func entityRequest(obj metav1.Object) reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}}
+}
+
+// auditValue dereferences pointers, so entities are compared by value and a nil pointer is recorded as nothing
+func auditValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return v
+	}
+	if rv.IsNil() {
+		return nil
+	}
+	return rv.Elem().Interface()
+}
+
+// redacted replaces a secret with a short fingerprint, so the audit trail shows when a secret changes without
+// revealing it
+func redacted(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return fmt.Sprintf("<redacted sha256:%x>", sum[:4])
+}
+
+func auditIngestToken(token *humioapi.IngestToken) interface{} {
+	if token == nil {
+		return nil
+	}
+	t := *token
+	t.Token = redacted(t.Token)
+	return t
+}
+
+func auditMultiClusterView(view *MultiClusterView) interface{} {
+	if view == nil {
+		return nil
+	}
+	v := *view
+	v.Connections = nil
+	for _, connection := range view.Connections {
+		v.Connections = append(v.Connections, auditMultiClusterViewConnection(connection))
+	}
+	return v
+}
+
+func auditMultiClusterViewConnection(connection MultiClusterViewConnection) MultiClusterViewConnection {
+	connection.Token = redacted(connection.Token)
+	return connection
+}
+
+func auditAction(action *humioapi.Action) interface{} {
+	if action == nil {
+		return nil
+	}
+	a := *action
+	a.HumioRepoAction.IngestToken = redacted(a.HumioRepoAction.IngestToken)
+	a.OpsGenieAction.GenieKey = redacted(a.OpsGenieAction.GenieKey)
+	a.PagerDutyAction.RoutingKey = redacted(a.PagerDutyAction.RoutingKey)
+	a.SlackAction.Url = redacted(a.SlackAction.Url)
+	a.SlackPostMessageAction.ApiToken = redacted(a.SlackPostMessageAction.ApiToken)
+	a.VictorOpsAction.NotifyUrl = redacted(a.VictorOpsAction.NotifyUrl)
+	a.WebhookAction.Url = redacted(a.WebhookAction.Url)
+	if a.WebhookAction.Headers != nil {
+		headers := make([]humioapi.HttpHeaderEntryInput, len(a.WebhookAction.Headers))
+		for i, header := range a.WebhookAction.Headers {
+			headers[i] = humioapi.HttpHeaderEntryInput{Header: header.Header, Value: redacted(header.Value)}
+		}
+		a.WebhookAction.Headers = headers
+	}
+	return a
+}
+
+func (c *AuditedClient) UpdateStoragePartitionScheme(config *humioapi.Config, req reconcile.Request, spi []humioapi.StoragePartitionInput) error {
+	err := c.Client.UpdateStoragePartitionScheme(config, req, spi)
+	c.audit(config, req, "StoragePartitionScheme", auditOperationUpdate, nil, spi, err)
+	return err
+}
+
+func (c *AuditedClient) MoveRoutesAwayFromClusterNode(config *humioapi.Config, req reconcile.Request, nodeID int) error {
+	err := c.Client.MoveRoutesAwayFromClusterNode(config, req, nodeID)
+	c.audit(config, req, "ClusterNodeRoutes", auditOperationUpdate, nil, nodeID, err)
+	return err
+}
+
+func (c *AuditedClient) UnregisterClusterNode(config *humioapi.Config, req reconcile.Request, nodeID int) error {
+	err := c.Client.UnregisterClusterNode(config, req, nodeID)
+	c.audit(config, req, "ClusterNode", auditOperationDelete, nodeID, nil, err)
+	return err
+}
+
+func (c *AuditedClient) UpdateIngestPartitionScheme(config *humioapi.Config, req reconcile.Request, ipi []humioapi.IngestPartitionInput) error {
+	err := c.Client.UpdateIngestPartitionScheme(config, req, ipi)
+	c.audit(config, req, "IngestPartitionScheme", auditOperationUpdate, nil, ipi, err)
+	return err
+}
+
+func (c *AuditedClient) AddIngestToken(config *humioapi.Config, req reconcile.Request, hit *humiov1alpha1.HumioIngestToken) (*humioapi.IngestToken, error) {
+	token, err := c.Client.AddIngestToken(config, req, hit)
+	c.audit(config, req, "HumioIngestToken", auditOperationCreate, nil, auditIngestToken(token), err)
+	return token, err
+}
+
+func (c *AuditedClient) UpdateIngestToken(config *humioapi.Config, req reconcile.Request, hit *humiov1alpha1.HumioIngestToken) (*humioapi.IngestToken, error) {
+	current, _ := c.Client.GetIngestToken(config, req, hit)
+	before := auditIngestToken(current)
+	token, err := c.Client.UpdateIngestToken(config, req, hit)
+	c.audit(config, req, "HumioIngestToken", auditOperationUpdate, before, auditIngestToken(token), err)
+	return token, err
+}
+
+func (c *AuditedClient) DeleteIngestToken(config *humioapi.Config, req reconcile.Request, hit *humiov1alpha1.HumioIngestToken) error {
+	current, _ := c.Client.GetIngestToken(config, req, hit)
+	before := auditIngestToken(current)
+	err := c.Client.DeleteIngestToken(config, req, hit)
+	c.audit(config, req, "HumioIngestToken", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) (*humioapi.Parser, error) {
+	parser, err := c.Client.AddParser(config, req, hp)
+	c.audit(config, req, "HumioParser", auditOperationCreate, nil, auditValue(parser), err)
+	return parser, err
+}
+
+func (c *AuditedClient) UpdateParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) (*humioapi.Parser, error) {
+	current, _ := c.Client.GetParser(config, req, hp)
+	before := auditValue(current)
+	parser, err := c.Client.UpdateParser(config, req, hp)
+	c.audit(config, req, "HumioParser", auditOperationUpdate, before, auditValue(parser), err)
+	return parser, err
+}
+
+func (c *AuditedClient) UpdateParsers(config *humioapi.Config, req reconcile.Request, hps []*humiov1alpha1.HumioParser) error {
+	before := make([]interface{}, len(hps))
+	for i, hp := range hps {
+		current, _ := c.Client.GetParser(config, req, hp)
+		before[i] = auditValue(current)
+	}
+	err := c.Client.UpdateParsers(config, req, hps)
+	for i, hp := range hps {
+		parser, _ := c.Client.GetParser(config, req, hp)
+		c.audit(config, entityRequest(hp), "HumioParser", auditOperationUpdate, before[i], auditValue(parser), err)
+	}
+	return err
+}
+
+func (c *AuditedClient) DeleteParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) error {
+	current, _ := c.Client.GetParser(config, req, hp)
+	before := auditValue(current)
+	err := c.Client.DeleteParser(config, req, hp)
+	c.audit(config, req, "HumioParser", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) (*humioapi.Repository, error) {
+	repository, err := c.Client.AddRepository(config, req, hr)
+	c.audit(config, req, "HumioRepository", auditOperationCreate, nil, auditValue(repository), err)
+	return repository, err
+}
+
+func (c *AuditedClient) UpdateRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) (*humioapi.Repository, error) {
+	current, _ := c.Client.GetRepository(config, req, hr)
+	before := auditValue(current)
+	repository, err := c.Client.UpdateRepository(config, req, hr)
+	c.audit(config, req, "HumioRepository", auditOperationUpdate, before, auditValue(repository), err)
+	return repository, err
+}
+
+func (c *AuditedClient) DeleteRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) error {
+	current, _ := c.Client.GetRepository(config, req, hr)
+	before := auditValue(current)
+	err := c.Client.DeleteRepository(config, req, hr)
+	c.audit(config, req, "HumioRepository", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) BlockIngest(config *humioapi.Config, req reconcile.Request, repositoryName string, duration time.Duration) error {
+	err := c.Client.BlockIngest(config, req, repositoryName, duration)
+	c.audit(config, req, "RepositoryIngestBlock", auditOperationCreate, nil, duration.String(), err)
+	return err
+}
+
+func (c *AuditedClient) UnblockIngest(config *humioapi.Config, req reconcile.Request, repositoryName string) error {
+	err := c.Client.UnblockIngest(config, req, repositoryName)
+	c.audit(config, req, "RepositoryIngestBlock", auditOperationDelete, nil, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddView(config *humioapi.Config, req reconcile.Request, hv *humiov1alpha1.HumioView) (*humioapi.View, error) {
+	view, err := c.Client.AddView(config, req, hv)
+	c.audit(config, req, "HumioView", auditOperationCreate, nil, auditValue(view), err)
+	return view, err
+}
+
+func (c *AuditedClient) UpdateView(config *humioapi.Config, req reconcile.Request, hv *humiov1alpha1.HumioView) (*humioapi.View, error) {
+	current, _ := c.Client.GetView(config, req, hv)
+	before := auditValue(current)
+	view, err := c.Client.UpdateView(config, req, hv)
+	c.audit(config, req, "HumioView", auditOperationUpdate, before, auditValue(view), err)
+	return view, err
+}
+
+func (c *AuditedClient) DeleteView(config *humioapi.Config, req reconcile.Request, hv *humiov1alpha1.HumioView) error {
+	current, _ := c.Client.GetView(config, req, hv)
+	before := auditValue(current)
+	err := c.Client.DeleteView(config, req, hv)
+	c.audit(config, req, "HumioView", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	err := c.Client.AddMultiClusterView(config, req, hmcv)
+	c.audit(config, req, "HumioMultiClusterView", auditOperationCreate, nil, MultiClusterView{Name: hmcv.Spec.Name, Description: hmcv.Spec.Description}, err)
+	return err
+}
+
+func (c *AuditedClient) UpdateMultiClusterViewDescription(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	current, _ := c.Client.GetMultiClusterView(config, req, hmcv)
+	before := ""
+	if current != nil {
+		before = current.Description
+	}
+	err := c.Client.UpdateMultiClusterViewDescription(config, req, hmcv)
+	c.audit(config, req, "HumioMultiClusterView", auditOperationUpdate, before, hmcv.Spec.Description, err)
+	return err
+}
+
+func (c *AuditedClient) DeleteMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	current, _ := c.Client.GetMultiClusterView(config, req, hmcv)
+	before := auditMultiClusterView(current)
+	err := c.Client.DeleteMultiClusterView(config, req, hmcv)
+	c.audit(config, req, "HumioMultiClusterView", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connection MultiClusterViewConnection) error {
+	err := c.Client.AddMultiClusterViewConnection(config, req, hmcv, connection)
+	c.audit(config, req, "HumioMultiClusterViewConnection", auditOperationCreate, nil, auditMultiClusterViewConnection(connection), err)
+	return err
+}
+
+func (c *AuditedClient) UpdateMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connection MultiClusterViewConnection) error {
+	var before interface{}
+	if current, _ := c.Client.GetMultiClusterView(config, req, hmcv); current != nil {
+		for _, currentConnection := range current.Connections {
+			if currentConnection.ID == connection.ID {
+				before = auditMultiClusterViewConnection(currentConnection)
+			}
+		}
+	}
+	err := c.Client.UpdateMultiClusterViewConnection(config, req, hmcv, connection)
+	c.audit(config, req, "HumioMultiClusterViewConnection", auditOperationUpdate, before, auditMultiClusterViewConnection(connection), err)
+	return err
+}
+
+func (c *AuditedClient) DeleteMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connectionID string) error {
+	err := c.Client.DeleteMultiClusterViewConnection(config, req, hmcv, connectionID)
+	c.audit(config, req, "HumioMultiClusterViewConnection", auditOperationDelete, connectionID, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error) {
+	savedQuery, err := c.Client.AddSavedQuery(config, req, hsq)
+	c.audit(config, req, "HumioSavedQuery", auditOperationCreate, nil, auditValue(savedQuery), err)
+	return savedQuery, err
+}
+
+func (c *AuditedClient) UpdateSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error) {
+	current, _ := c.Client.GetSavedQuery(config, req, hsq)
+	before := auditValue(current)
+	savedQuery, err := c.Client.UpdateSavedQuery(config, req, hsq)
+	c.audit(config, req, "HumioSavedQuery", auditOperationUpdate, before, auditValue(savedQuery), err)
+	return savedQuery, err
+}
+
+func (c *AuditedClient) DeleteSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) error {
+	current, _ := c.Client.GetSavedQuery(config, req, hsq)
+	before := auditValue(current)
+	err := c.Client.DeleteSavedQuery(config, req, hsq)
+	c.audit(config, req, "HumioSavedQuery", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error) {
+	aggregateAlert, err := c.Client.AddAggregateAlert(config, req, haa)
+	c.audit(config, req, "HumioAggregateAlert", auditOperationCreate, nil, auditValue(aggregateAlert), err)
+	return aggregateAlert, err
+}
+
+func (c *AuditedClient) UpdateAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error) {
+	current, _ := c.Client.GetAggregateAlert(config, req, haa)
+	before := auditValue(current)
+	aggregateAlert, err := c.Client.UpdateAggregateAlert(config, req, haa)
+	c.audit(config, req, "HumioAggregateAlert", auditOperationUpdate, before, auditValue(aggregateAlert), err)
+	return aggregateAlert, err
+}
+
+func (c *AuditedClient) DeleteAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) error {
+	current, _ := c.Client.GetAggregateAlert(config, req, haa)
+	before := auditValue(current)
+	err := c.Client.DeleteAggregateAlert(config, req, haa)
+	c.audit(config, req, "HumioAggregateAlert", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error) {
+	scheduledSearch, err := c.Client.AddScheduledSearch(config, req, hss)
+	c.audit(config, req, "HumioScheduledSearch", auditOperationCreate, nil, auditValue(scheduledSearch), err)
+	return scheduledSearch, err
+}
+
+func (c *AuditedClient) UpdateScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error) {
+	current, _ := c.Client.GetScheduledSearch(config, req, hss)
+	before := auditValue(current)
+	scheduledSearch, err := c.Client.UpdateScheduledSearch(config, req, hss)
+	c.audit(config, req, "HumioScheduledSearch", auditOperationUpdate, before, auditValue(scheduledSearch), err)
+	return scheduledSearch, err
+}
+
+func (c *AuditedClient) DeleteScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) error {
+	current, _ := c.Client.GetScheduledSearch(config, req, hss)
+	before := auditValue(current)
+	err := c.Client.DeleteScheduledSearch(config, req, hss)
+	c.audit(config, req, "HumioScheduledSearch", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard, template string) (*Dashboard, error) {
+	dashboard, err := c.Client.AddDashboard(config, req, hd, template)
+	c.audit(config, req, "HumioDashboard", auditOperationCreate, nil, auditValue(dashboard), err)
+	return dashboard, err
+}
+
+func (c *AuditedClient) DeleteDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard) error {
+	current, _ := c.Client.GetDashboard(config, req, hd)
+	before := auditValue(current)
+	err := c.Client.DeleteDashboard(config, req, hd)
+	c.audit(config, req, "HumioDashboard", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
+	action, err := c.Client.AddAction(config, req, ha)
+	c.audit(config, req, "HumioAction", auditOperationCreate, nil, auditAction(action), err)
+	return action, err
+}
+
+func (c *AuditedClient) UpdateAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (*humioapi.Action, error) {
+	current, _ := c.Client.GetAction(config, req, ha)
+	before := auditAction(current)
+	action, err := c.Client.UpdateAction(config, req, ha)
+	c.audit(config, req, "HumioAction", auditOperationUpdate, before, auditAction(action), err)
+	return action, err
+}
+
+func (c *AuditedClient) DeleteAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) error {
+	current, _ := c.Client.GetAction(config, req, ha)
+	before := auditAction(current)
+	err := c.Client.DeleteAction(config, req, ha)
+	c.audit(config, req, "HumioAction", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) AddAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (*humioapi.Alert, error) {
+	alert, err := c.Client.AddAlert(config, req, ha)
+	c.audit(config, req, "HumioAlert", auditOperationCreate, nil, auditValue(alert), err)
+	return alert, err
+}
+
+func (c *AuditedClient) UpdateAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (*humioapi.Alert, error) {
+	current, _ := c.Client.GetAlert(config, req, ha)
+	before := auditValue(current)
+	alert, err := c.Client.UpdateAlert(config, req, ha)
+	c.audit(config, req, "HumioAlert", auditOperationUpdate, before, auditValue(alert), err)
+	return alert, err
+}
+
+func (c *AuditedClient) UpdateAlerts(config *humioapi.Config, req reconcile.Request, has []*humiov1alpha1.HumioAlert) error {
+	before := make([]interface{}, len(has))
+	for i, ha := range has {
+		current, _ := c.Client.GetAlert(config, req, ha)
+		before[i] = auditValue(current)
+	}
+	err := c.Client.UpdateAlerts(config, req, has)
+	for i, ha := range has {
+		alert, _ := c.Client.GetAlert(config, req, ha)
+		c.audit(config, entityRequest(ha), "HumioAlert", auditOperationUpdate, before[i], auditValue(alert), err)
+	}
+	return err
+}
+
+func (c *AuditedClient) DeleteAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) error {
+	current, _ := c.Client.GetAlert(config, req, ha)
+	before := auditValue(current)
+	err := c.Client.DeleteAlert(config, req, ha)
+	c.audit(config, req, "HumioAlert", auditOperationDelete, before, nil, err)
+	return err
+}
+
+func (c *AuditedClient) InstallLicense(config *humioapi.Config, req reconcile.Request, license string) error {
+	err := c.Client.InstallLicense(config, req, license)
+	c.audit(config, req, "License", auditOperationUpdate, nil, nil, err)
+	return err
+}
+
+func (c *AuditedClient) EnsureRootUser(config *humioapi.Config, req reconcile.Request, username string) error {
+	err := c.Client.EnsureRootUser(config, req, username)
+	c.audit(config, req, "RootUser", auditOperationUpdate, nil, username, err)
+	return err
+}
+
+func (c *AuditedClient) RotateUserAPIToken(config *humioapi.Config, req reconcile.Request, username string) (string, error) {
+	token, err := c.Client.RotateUserAPIToken(config, req, username)
+	c.audit(config, req, "UserAPIToken", auditOperationUpdate, nil, nil, err)
+	return token, err
+}
@@ -0,0 +1,78 @@
+package humio
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type recordingAuditSink struct {
+	records []AuditRecord
+}
+
+func (s *recordingAuditSink) Write(record AuditRecord) {
+	s.records = append(s.records, record)
+}
+
+func TestAuditedClient(t *testing.T) {
+	sink := &recordingAuditSink{}
+	client := NewAuditedClient(NewMockClient(humioapi.Cluster{}, nil, nil, nil), logr.Discard(), sink)
+	config := &humioapi.Config{}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "parser"}}
+
+	hp := &humiov1alpha1.HumioParser{Spec: humiov1alpha1.HumioParserSpec{Name: "parser", ParserScript: "kvParse()"}}
+	if _, err := client.AddParser(config, req, hp); err != nil {
+		t.Fatalf("unable to add parser: %s", err)
+	}
+	hp.Spec.ParserScript = "parseJson()"
+	if _, err := client.UpdateParser(config, req, hp); err != nil {
+		t.Fatalf("unable to update parser: %s", err)
+	}
+	if err := client.DeleteParser(config, req, hp); err != nil {
+		t.Fatalf("unable to delete parser: %s", err)
+	}
+
+	hit := &humiov1alpha1.HumioIngestToken{Spec: humiov1alpha1.HumioIngestTokenSpec{Name: "token"}}
+	if _, err := client.AddIngestToken(config, req, hit); err != nil {
+		t.Fatalf("unable to add ingest token: %s", err)
+	}
+
+	tests := []struct {
+		operation   string
+		kind        string
+		contains    []string
+		notContains []string
+	}{
+		{auditOperationCreate, "HumioParser", []string{"kvParse()"}, nil},
+		{auditOperationUpdate, "HumioParser", []string{"-", "kvParse()", "+", "parseJson()"}, nil},
+		{auditOperationDelete, "HumioParser", []string{"parseJson()"}, nil},
+		{auditOperationCreate, "HumioIngestToken", []string{"<redacted sha256:"}, []string{"mocktoken"}},
+	}
+	if len(sink.records) != len(tests) {
+		t.Fatalf("expected %d audit records, got %d", len(tests), len(sink.records))
+	}
+	for i, tt := range tests {
+		record := sink.records[i]
+		if record.Operation != tt.operation || record.Kind != tt.kind {
+			t.Errorf("expected record %d to be %s of %s, got %s of %s", i, tt.operation, tt.kind, record.Operation, record.Kind)
+		}
+		if record.Result != auditResultSuccess || record.Namespace != "default" || record.Name != "parser" {
+			t.Errorf("unexpected record %d: %+v", i, record)
+		}
+		for _, s := range tt.contains {
+			if !strings.Contains(record.Diff, s) {
+				t.Errorf("expected diff of record %d to contain %q, got %s", i, s, record.Diff)
+			}
+		}
+		for _, s := range tt.notContains {
+			if strings.Contains(record.Diff, s) {
+				t.Errorf("expected diff of record %d not to contain %q, got %s", i, s, record.Diff)
+			}
+		}
+	}
+}
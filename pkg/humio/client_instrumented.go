@@ -0,0 +1,492 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	apiCallOutcomeSuccess = "success"
+	apiCallOutcomeError   = "error"
+)
+
+var (
+	humioAPIPrometheusMetrics = newHumioAPIPrometheusCollection()
+)
+
+type humioAPIPrometheusCollection struct {
+	RequestDuration *prometheus.HistogramVec
+	RequestsTotal   *prometheus.CounterVec
+}
+
+func newHumioAPIPrometheusCollection() humioAPIPrometheusCollection {
+	return humioAPIPrometheusCollection{
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "humio_api_request_duration_seconds",
+			Help:    "Duration of calls against the Humio API",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"endpoint", "cluster"}),
+		RequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "humio_api_requests_total",
+			Help: "Total number of calls against the Humio API",
+		}, []string{"endpoint", "cluster", "outcome"}),
+	}
+}
+
+func init() {
+	metrics.Registry.MustRegister(
+		humioAPIPrometheusMetrics.RequestDuration,
+		humioAPIPrometheusMetrics.RequestsTotal,
+	)
+}
+
+// observeAPICall records the duration and outcome of a call against the Humio API. It is meant to be deferred, so err
+// points to the error returned by the call.
+func observeAPICall(endpoint string, config *humioapi.Config, start time.Time, err *error) {
+	cluster := ""
+	if config != nil && config.Address != nil {
+		cluster = config.Address.Host
+	}
+	outcome := apiCallOutcomeSuccess
+	if *err != nil {
+		outcome = apiCallOutcomeError
+	}
+	humioAPIPrometheusMetrics.RequestDuration.WithLabelValues(endpoint, cluster).Observe(time.Since(start).Seconds())
+	humioAPIPrometheusMetrics.RequestsTotal.WithLabelValues(endpoint, cluster, outcome).Inc()
+}
+
+// InstrumentedClient wraps a Client and records Prometheus metrics for all calls against the Humio API
+type InstrumentedClient struct {
+	Client
+}
+
+// NewInstrumentedClient returns a Client which records Prometheus metrics for all calls made through the given client
+func NewInstrumentedClient(client Client) *InstrumentedClient {
+	return &InstrumentedClient{Client: client}
+}
+
+func (c *InstrumentedClient) GetClusters(config *humioapi.Config, req reconcile.Request) (_ humioapi.Cluster, err error) {
+	defer observeAPICall("GetClusters", config, time.Now(), &err)
+	return c.Client.GetClusters(config, req)
+}
+
+func (c *InstrumentedClient) UpdateStoragePartitionScheme(config *humioapi.Config, req reconcile.Request, spi []humioapi.StoragePartitionInput) (err error) {
+	defer observeAPICall("UpdateStoragePartitionScheme", config, time.Now(), &err)
+	return c.Client.UpdateStoragePartitionScheme(config, req, spi)
+}
+
+func (c *InstrumentedClient) MoveRoutesAwayFromClusterNode(config *humioapi.Config, req reconcile.Request, nodeID int) (err error) {
+	defer observeAPICall("MoveRoutesAwayFromClusterNode", config, time.Now(), &err)
+	return c.Client.MoveRoutesAwayFromClusterNode(config, req, nodeID)
+}
+
+func (c *InstrumentedClient) UnregisterClusterNode(config *humioapi.Config, req reconcile.Request, nodeID int) (err error) {
+	defer observeAPICall("UnregisterClusterNode", config, time.Now(), &err)
+	return c.Client.UnregisterClusterNode(config, req, nodeID)
+}
+
+func (c *InstrumentedClient) UpdateIngestPartitionScheme(config *humioapi.Config, req reconcile.Request, ipi []humioapi.IngestPartitionInput) (err error) {
+	defer observeAPICall("UpdateIngestPartitionScheme", config, time.Now(), &err)
+	return c.Client.UpdateIngestPartitionScheme(config, req, ipi)
+}
+
+func (c *InstrumentedClient) SuggestedStoragePartitions(config *humioapi.Config, req reconcile.Request) (_ []humioapi.StoragePartitionInput, err error) {
+	defer observeAPICall("SuggestedStoragePartitions", config, time.Now(), &err)
+	return c.Client.SuggestedStoragePartitions(config, req)
+}
+
+func (c *InstrumentedClient) SuggestedIngestPartitions(config *humioapi.Config, req reconcile.Request) (_ []humioapi.IngestPartitionInput, err error) {
+	defer observeAPICall("SuggestedIngestPartitions", config, time.Now(), &err)
+	return c.Client.SuggestedIngestPartitions(config, req)
+}
+
+func (c *InstrumentedClient) TestAPIToken(config *humioapi.Config, req reconcile.Request) (_ string, err error) {
+	defer observeAPICall("TestAPIToken", config, time.Now(), &err)
+	return c.Client.TestAPIToken(config, req)
+}
+
+func (c *InstrumentedClient) TestOrganizationAPIToken(config *humioapi.Config, req reconcile.Request) (err error) {
+	defer observeAPICall("TestOrganizationAPIToken", config, time.Now(), &err)
+	return c.Client.TestOrganizationAPIToken(config, req)
+}
+
+func (c *InstrumentedClient) TestQuery(config *humioapi.Config, req reconcile.Request, repository, query string) (err error) {
+	defer observeAPICall("TestQuery", config, time.Now(), &err)
+	return c.Client.TestQuery(config, req, repository, query)
+}
+
+func (c *InstrumentedClient) Status(config *humioapi.Config, req reconcile.Request) (_ humioapi.StatusResponse, err error) {
+	defer observeAPICall("Status", config, time.Now(), &err)
+	return c.Client.Status(config, req)
+}
+
+func (c *InstrumentedClient) AddIngestToken(config *humioapi.Config, req reconcile.Request, hit *humiov1alpha1.HumioIngestToken) (_ *humioapi.IngestToken, err error) {
+	defer observeAPICall("AddIngestToken", config, time.Now(), &err)
+	return c.Client.AddIngestToken(config, req, hit)
+}
+
+func (c *InstrumentedClient) GetIngestToken(config *humioapi.Config, req reconcile.Request, hit *humiov1alpha1.HumioIngestToken) (_ *humioapi.IngestToken, err error) {
+	defer observeAPICall("GetIngestToken", config, time.Now(), &err)
+	return c.Client.GetIngestToken(config, req, hit)
+}
+
+func (c *InstrumentedClient) UpdateIngestToken(config *humioapi.Config, req reconcile.Request, hit *humiov1alpha1.HumioIngestToken) (_ *humioapi.IngestToken, err error) {
+	defer observeAPICall("UpdateIngestToken", config, time.Now(), &err)
+	return c.Client.UpdateIngestToken(config, req, hit)
+}
+
+func (c *InstrumentedClient) DeleteIngestToken(config *humioapi.Config, req reconcile.Request, hit *humiov1alpha1.HumioIngestToken) (err error) {
+	defer observeAPICall("DeleteIngestToken", config, time.Now(), &err)
+	return c.Client.DeleteIngestToken(config, req, hit)
+}
+
+func (c *InstrumentedClient) AddParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) (_ *humioapi.Parser, err error) {
+	defer observeAPICall("AddParser", config, time.Now(), &err)
+	return c.Client.AddParser(config, req, hp)
+}
+
+func (c *InstrumentedClient) GetParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) (_ *humioapi.Parser, err error) {
+	defer observeAPICall("GetParser", config, time.Now(), &err)
+	return c.Client.GetParser(config, req, hp)
+}
+
+func (c *InstrumentedClient) UpdateParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) (_ *humioapi.Parser, err error) {
+	defer observeAPICall("UpdateParser", config, time.Now(), &err)
+	return c.Client.UpdateParser(config, req, hp)
+}
+
+func (c *InstrumentedClient) UpdateParsers(config *humioapi.Config, req reconcile.Request, hps []*humiov1alpha1.HumioParser) (err error) {
+	defer observeAPICall("UpdateParsers", config, time.Now(), &err)
+	return c.Client.UpdateParsers(config, req, hps)
+}
+
+func (c *InstrumentedClient) DeleteParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) (err error) {
+	defer observeAPICall("DeleteParser", config, time.Now(), &err)
+	return c.Client.DeleteParser(config, req, hp)
+}
+
+func (c *InstrumentedClient) TestParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) (_ []string, err error) {
+	defer observeAPICall("TestParser", config, time.Now(), &err)
+	return c.Client.TestParser(config, req, hp)
+}
+
+func (c *InstrumentedClient) AddRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) (_ *humioapi.Repository, err error) {
+	defer observeAPICall("AddRepository", config, time.Now(), &err)
+	return c.Client.AddRepository(config, req, hr)
+}
+
+func (c *InstrumentedClient) GetRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) (_ *humioapi.Repository, err error) {
+	defer observeAPICall("GetRepository", config, time.Now(), &err)
+	return c.Client.GetRepository(config, req, hr)
+}
+
+func (c *InstrumentedClient) UpdateRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) (_ *humioapi.Repository, err error) {
+	defer observeAPICall("UpdateRepository", config, time.Now(), &err)
+	return c.Client.UpdateRepository(config, req, hr)
+}
+
+func (c *InstrumentedClient) DeleteRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) (err error) {
+	defer observeAPICall("DeleteRepository", config, time.Now(), &err)
+	return c.Client.DeleteRepository(config, req, hr)
+}
+
+func (c *InstrumentedClient) GetRepositoryIngestUsage(config *humioapi.Config, req reconcile.Request, repositoryName string, start string) (_ int64, err error) {
+	defer observeAPICall("GetRepositoryIngestUsage", config, time.Now(), &err)
+	return c.Client.GetRepositoryIngestUsage(config, req, repositoryName, start)
+}
+
+func (c *InstrumentedClient) BlockIngest(config *humioapi.Config, req reconcile.Request, repositoryName string, duration time.Duration) (err error) {
+	defer observeAPICall("BlockIngest", config, time.Now(), &err)
+	return c.Client.BlockIngest(config, req, repositoryName, duration)
+}
+
+func (c *InstrumentedClient) UnblockIngest(config *humioapi.Config, req reconcile.Request, repositoryName string) (err error) {
+	defer observeAPICall("UnblockIngest", config, time.Now(), &err)
+	return c.Client.UnblockIngest(config, req, repositoryName)
+}
+
+func (c *InstrumentedClient) GetRepositoryStatistics(config *humioapi.Config, req reconcile.Request, repositoryName string) (_ *RepositoryStatistics, err error) {
+	defer observeAPICall("GetRepositoryStatistics", config, time.Now(), &err)
+	return c.Client.GetRepositoryStatistics(config, req, repositoryName)
+}
+
+func (c *InstrumentedClient) AddView(config *humioapi.Config, req reconcile.Request, hv *humiov1alpha1.HumioView) (_ *humioapi.View, err error) {
+	defer observeAPICall("AddView", config, time.Now(), &err)
+	return c.Client.AddView(config, req, hv)
+}
+
+func (c *InstrumentedClient) GetView(config *humioapi.Config, req reconcile.Request, hv *humiov1alpha1.HumioView) (_ *humioapi.View, err error) {
+	defer observeAPICall("GetView", config, time.Now(), &err)
+	return c.Client.GetView(config, req, hv)
+}
+
+func (c *InstrumentedClient) UpdateView(config *humioapi.Config, req reconcile.Request, hv *humiov1alpha1.HumioView) (_ *humioapi.View, err error) {
+	defer observeAPICall("UpdateView", config, time.Now(), &err)
+	return c.Client.UpdateView(config, req, hv)
+}
+
+func (c *InstrumentedClient) DeleteView(config *humioapi.Config, req reconcile.Request, hv *humiov1alpha1.HumioView) (err error) {
+	defer observeAPICall("DeleteView", config, time.Now(), &err)
+	return c.Client.DeleteView(config, req, hv)
+}
+
+func (c *InstrumentedClient) GetMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) (_ *MultiClusterView, err error) {
+	defer observeAPICall("GetMultiClusterView", config, time.Now(), &err)
+	return c.Client.GetMultiClusterView(config, req, hmcv)
+}
+
+func (c *InstrumentedClient) AddMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) (err error) {
+	defer observeAPICall("AddMultiClusterView", config, time.Now(), &err)
+	return c.Client.AddMultiClusterView(config, req, hmcv)
+}
+
+func (c *InstrumentedClient) UpdateMultiClusterViewDescription(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) (err error) {
+	defer observeAPICall("UpdateMultiClusterViewDescription", config, time.Now(), &err)
+	return c.Client.UpdateMultiClusterViewDescription(config, req, hmcv)
+}
+
+func (c *InstrumentedClient) DeleteMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) (err error) {
+	defer observeAPICall("DeleteMultiClusterView", config, time.Now(), &err)
+	return c.Client.DeleteMultiClusterView(config, req, hmcv)
+}
+
+func (c *InstrumentedClient) AddMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connection MultiClusterViewConnection) (err error) {
+	defer observeAPICall("AddMultiClusterViewConnection", config, time.Now(), &err)
+	return c.Client.AddMultiClusterViewConnection(config, req, hmcv, connection)
+}
+
+func (c *InstrumentedClient) UpdateMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connection MultiClusterViewConnection) (err error) {
+	defer observeAPICall("UpdateMultiClusterViewConnection", config, time.Now(), &err)
+	return c.Client.UpdateMultiClusterViewConnection(config, req, hmcv, connection)
+}
+
+func (c *InstrumentedClient) DeleteMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connectionID string) (err error) {
+	defer observeAPICall("DeleteMultiClusterViewConnection", config, time.Now(), &err)
+	return c.Client.DeleteMultiClusterViewConnection(config, req, hmcv, connectionID)
+}
+
+func (c *InstrumentedClient) AddSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (_ *SavedQuery, err error) {
+	defer observeAPICall("AddSavedQuery", config, time.Now(), &err)
+	return c.Client.AddSavedQuery(config, req, hsq)
+}
+
+func (c *InstrumentedClient) GetSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (_ *SavedQuery, err error) {
+	defer observeAPICall("GetSavedQuery", config, time.Now(), &err)
+	return c.Client.GetSavedQuery(config, req, hsq)
+}
+
+func (c *InstrumentedClient) UpdateSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (_ *SavedQuery, err error) {
+	defer observeAPICall("UpdateSavedQuery", config, time.Now(), &err)
+	return c.Client.UpdateSavedQuery(config, req, hsq)
+}
+
+func (c *InstrumentedClient) DeleteSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (err error) {
+	defer observeAPICall("DeleteSavedQuery", config, time.Now(), &err)
+	return c.Client.DeleteSavedQuery(config, req, hsq)
+}
+
+func (c *InstrumentedClient) AddAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (_ *AggregateAlert, err error) {
+	defer observeAPICall("AddAggregateAlert", config, time.Now(), &err)
+	return c.Client.AddAggregateAlert(config, req, haa)
+}
+
+func (c *InstrumentedClient) GetAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (_ *AggregateAlert, err error) {
+	defer observeAPICall("GetAggregateAlert", config, time.Now(), &err)
+	return c.Client.GetAggregateAlert(config, req, haa)
+}
+
+func (c *InstrumentedClient) UpdateAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (_ *AggregateAlert, err error) {
+	defer observeAPICall("UpdateAggregateAlert", config, time.Now(), &err)
+	return c.Client.UpdateAggregateAlert(config, req, haa)
+}
+
+func (c *InstrumentedClient) DeleteAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (err error) {
+	defer observeAPICall("DeleteAggregateAlert", config, time.Now(), &err)
+	return c.Client.DeleteAggregateAlert(config, req, haa)
+}
+
+func (c *InstrumentedClient) AddScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (_ *ScheduledSearch, err error) {
+	defer observeAPICall("AddScheduledSearch", config, time.Now(), &err)
+	return c.Client.AddScheduledSearch(config, req, hss)
+}
+
+func (c *InstrumentedClient) GetScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (_ *ScheduledSearch, err error) {
+	defer observeAPICall("GetScheduledSearch", config, time.Now(), &err)
+	return c.Client.GetScheduledSearch(config, req, hss)
+}
+
+func (c *InstrumentedClient) UpdateScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (_ *ScheduledSearch, err error) {
+	defer observeAPICall("UpdateScheduledSearch", config, time.Now(), &err)
+	return c.Client.UpdateScheduledSearch(config, req, hss)
+}
+
+func (c *InstrumentedClient) DeleteScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (err error) {
+	defer observeAPICall("DeleteScheduledSearch", config, time.Now(), &err)
+	return c.Client.DeleteScheduledSearch(config, req, hss)
+}
+
+func (c *InstrumentedClient) AddDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard, template string) (_ *Dashboard, err error) {
+	defer observeAPICall("AddDashboard", config, time.Now(), &err)
+	return c.Client.AddDashboard(config, req, hd, template)
+}
+
+func (c *InstrumentedClient) GetDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard) (_ *Dashboard, err error) {
+	defer observeAPICall("GetDashboard", config, time.Now(), &err)
+	return c.Client.GetDashboard(config, req, hd)
+}
+
+func (c *InstrumentedClient) DeleteDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard) (err error) {
+	defer observeAPICall("DeleteDashboard", config, time.Now(), &err)
+	return c.Client.DeleteDashboard(config, req, hd)
+}
+
+func (c *InstrumentedClient) ListSavedQueries(config *humioapi.Config, req reconcile.Request, viewName string) (_ []SavedQuery, err error) {
+	defer observeAPICall("ListSavedQueries", config, time.Now(), &err)
+	return c.Client.ListSavedQueries(config, req, viewName)
+}
+
+func (c *InstrumentedClient) ListAggregateAlerts(config *humioapi.Config, req reconcile.Request, viewName string) (_ []AggregateAlert, err error) {
+	defer observeAPICall("ListAggregateAlerts", config, time.Now(), &err)
+	return c.Client.ListAggregateAlerts(config, req, viewName)
+}
+
+func (c *InstrumentedClient) ListScheduledSearches(config *humioapi.Config, req reconcile.Request, viewName string) (_ []ScheduledSearch, err error) {
+	defer observeAPICall("ListScheduledSearches", config, time.Now(), &err)
+	return c.Client.ListScheduledSearches(config, req, viewName)
+}
+
+func (c *InstrumentedClient) ListDashboards(config *humioapi.Config, req reconcile.Request, viewName string) (_ []Dashboard, err error) {
+	defer observeAPICall("ListDashboards", config, time.Now(), &err)
+	return c.Client.ListDashboards(config, req, viewName)
+}
+
+func (c *InstrumentedClient) AddAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (_ *humioapi.Action, err error) {
+	defer observeAPICall("AddAction", config, time.Now(), &err)
+	return c.Client.AddAction(config, req, ha)
+}
+
+func (c *InstrumentedClient) GetAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (_ *humioapi.Action, err error) {
+	defer observeAPICall("GetAction", config, time.Now(), &err)
+	return c.Client.GetAction(config, req, ha)
+}
+
+func (c *InstrumentedClient) UpdateAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (_ *humioapi.Action, err error) {
+	defer observeAPICall("UpdateAction", config, time.Now(), &err)
+	return c.Client.UpdateAction(config, req, ha)
+}
+
+func (c *InstrumentedClient) DeleteAction(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAction) (err error) {
+	defer observeAPICall("DeleteAction", config, time.Now(), &err)
+	return c.Client.DeleteAction(config, req, ha)
+}
+
+func (c *InstrumentedClient) TestAction(config *humioapi.Config, req reconcile.Request, viewName string, action *humioapi.Action, triggerName, eventData string) (_ *TestActionResult, err error) {
+	defer observeAPICall("TestAction", config, time.Now(), &err)
+	return c.Client.TestAction(config, req, viewName, action, triggerName, eventData)
+}
+
+func (c *InstrumentedClient) AddAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (_ *humioapi.Alert, err error) {
+	defer observeAPICall("AddAlert", config, time.Now(), &err)
+	return c.Client.AddAlert(config, req, ha)
+}
+
+func (c *InstrumentedClient) GetAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (_ *humioapi.Alert, err error) {
+	defer observeAPICall("GetAlert", config, time.Now(), &err)
+	return c.Client.GetAlert(config, req, ha)
+}
+
+func (c *InstrumentedClient) UpdateAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (_ *humioapi.Alert, err error) {
+	defer observeAPICall("UpdateAlert", config, time.Now(), &err)
+	return c.Client.UpdateAlert(config, req, ha)
+}
+
+func (c *InstrumentedClient) UpdateAlerts(config *humioapi.Config, req reconcile.Request, has []*humiov1alpha1.HumioAlert) (err error) {
+	defer observeAPICall("UpdateAlerts", config, time.Now(), &err)
+	return c.Client.UpdateAlerts(config, req, has)
+}
+
+func (c *InstrumentedClient) DeleteAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (err error) {
+	defer observeAPICall("DeleteAlert", config, time.Now(), &err)
+	return c.Client.DeleteAlert(config, req, ha)
+}
+
+func (c *InstrumentedClient) GetActionIDsMapForAlerts(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (_ map[string]string, err error) {
+	defer observeAPICall("GetActionIDsMapForAlerts", config, time.Now(), &err)
+	return c.Client.GetActionIDsMapForAlerts(config, req, ha)
+}
+
+func (c *InstrumentedClient) GetLicense(config *humioapi.Config, req reconcile.Request) (_ humioapi.License, err error) {
+	defer observeAPICall("GetLicense", config, time.Now(), &err)
+	return c.Client.GetLicense(config, req)
+}
+
+func (c *InstrumentedClient) InstallLicense(config *humioapi.Config, req reconcile.Request, license string) (err error) {
+	defer observeAPICall("InstallLicense", config, time.Now(), &err)
+	return c.Client.InstallLicense(config, req, license)
+}
+
+func (c *InstrumentedClient) EnsureRootUser(config *humioapi.Config, req reconcile.Request, username string) (err error) {
+	defer observeAPICall("EnsureRootUser", config, time.Now(), &err)
+	return c.Client.EnsureRootUser(config, req, username)
+}
+
+func (c *InstrumentedClient) RotateUserAPIToken(config *humioapi.Config, req reconcile.Request, username string) (_ string, err error) {
+	defer observeAPICall("RotateUserAPIToken", config, time.Now(), &err)
+	return c.Client.RotateUserAPIToken(config, req, username)
+}
+
+func (c *InstrumentedClient) CreateQueryJob(config *humioapi.Config, req reconcile.Request, repositoryName string, query humioapi.Query) (_ string, err error) {
+	defer observeAPICall("CreateQueryJob", config, time.Now(), &err)
+	return c.Client.CreateQueryJob(config, req, repositoryName, query)
+}
+
+func (c *InstrumentedClient) PollQueryJob(config *humioapi.Config, req reconcile.Request, repositoryName string, id string) (_ humioapi.QueryResult, err error) {
+	defer observeAPICall("PollQueryJob", config, time.Now(), &err)
+	return c.Client.PollQueryJob(config, req, repositoryName, id)
+}
+
+func (c *InstrumentedClient) DeleteQueryJob(config *humioapi.Config, req reconcile.Request, repositoryName string, id string) (err error) {
+	defer observeAPICall("DeleteQueryJob", config, time.Now(), &err)
+	return c.Client.DeleteQueryJob(config, req, repositoryName, id)
+}
+
+func (c *InstrumentedClient) GetRepositoryUsage(config *humioapi.Config, req reconcile.Request) (_ []RepositoryUsage, err error) {
+	defer observeAPICall("GetRepositoryUsage", config, time.Now(), &err)
+	return c.Client.GetRepositoryUsage(config, req)
+}
+
+func (c *InstrumentedClient) GetIngestUsage(config *humioapi.Config, req reconcile.Request) (_ map[string]int64, err error) {
+	defer observeAPICall("GetIngestUsage", config, time.Now(), &err)
+	return c.Client.GetIngestUsage(config, req)
+}
+
+func (c *InstrumentedClient) CountUsers(config *humioapi.Config, req reconcile.Request) (_ int, err error) {
+	defer observeAPICall("CountUsers", config, time.Now(), &err)
+	return c.Client.CountUsers(config, req)
+}
+
+func (c *InstrumentedClient) GetThreadDump(config *humioapi.Config, req reconcile.Request) (_ string, err error) {
+	defer observeAPICall("GetThreadDump", config, time.Now(), &err)
+	return c.Client.GetThreadDump(config, req)
+}
@@ -22,6 +22,9 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	humioapi "github.com/humio/cli/api"
 	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
@@ -42,6 +45,22 @@ type ClientMock struct {
 	OnPremLicense                     humioapi.OnPremLicense
 	Action                            humioapi.Action
 	Alert                             humioapi.Alert
+	TestedActions                     []string
+	TestActionFailures                map[string]string
+	RepositoryIngestUsage             map[string]int64
+	BlockedIngest                     map[string]bool
+	RepositoryStatistics              map[string]RepositoryStatistics
+	ParserTestErrors                  []string
+	MultiClusterViews                 map[string]*MultiClusterView
+	multiClusterViewConnectionID      int
+	SavedQueries                      map[string]SavedQuery
+	savedQueryID                      int
+	Dashboards                        map[string]Dashboard
+	dashboardID                       int
+	AggregateAlerts                   map[string]AggregateAlert
+	aggregateAlertID                  int
+	ScheduledSearches                 map[string]ScheduledSearch
+	scheduledSearchID                 int
 }
 
 type MockClientConfig struct {
@@ -65,6 +84,15 @@ func NewMockClient(cluster humioapi.Cluster, clusterError error, updateStoragePa
 			OnPremLicense:                     humioapi.OnPremLicense{},
 			Action:                            humioapi.Action{},
 			Alert:                             humioapi.Alert{},
+			TestActionFailures:                map[string]string{},
+			RepositoryIngestUsage:             map[string]int64{},
+			BlockedIngest:                     map[string]bool{},
+			RepositoryStatistics:              map[string]RepositoryStatistics{},
+			MultiClusterViews:                 map[string]*MultiClusterView{},
+			SavedQueries:                      map[string]SavedQuery{},
+			Dashboards:                        map[string]Dashboard{},
+			AggregateAlerts:                   map[string]AggregateAlert{},
+			ScheduledSearches:                 map[string]ScheduledSearch{},
 		},
 	}
 
@@ -106,6 +134,21 @@ func (h *MockClientConfig) UpdateStoragePartitionScheme(config *humioapi.Config,
 	return nil
 }
 
+func (h *MockClientConfig) MoveRoutesAwayFromClusterNode(config *humioapi.Config, req reconcile.Request, nodeID int) error {
+	return nil
+}
+
+func (h *MockClientConfig) UnregisterClusterNode(config *humioapi.Config, req reconcile.Request, nodeID int) error {
+	var nodes []humioapi.ClusterNode
+	for _, node := range h.apiClient.Cluster.Nodes {
+		if node.Id != nodeID {
+			nodes = append(nodes, node)
+		}
+	}
+	h.apiClient.Cluster.Nodes = nodes
+	return nil
+}
+
 func (h *MockClientConfig) UpdateIngestPartitionScheme(config *humioapi.Config, req reconcile.Request, ips []humioapi.IngestPartitionInput) error {
 	if h.apiClient.UpdateIngestPartitionSchemeError != nil {
 		return h.apiClient.UpdateIngestPartitionSchemeError
@@ -137,7 +180,23 @@ func (h *MockClientConfig) GetBaseURL(config *humioapi.Config, req reconcile.Req
 	return baseURL
 }
 
-func (h *MockClientConfig) TestAPIToken(config *humioapi.Config, req reconcile.Request) error {
+func (h *MockClientConfig) TestAPIToken(config *humioapi.Config, req reconcile.Request) (string, error) {
+	return "mockuser", nil
+}
+
+func (h *MockClientConfig) TestOrganizationAPIToken(config *humioapi.Config, req reconcile.Request) error {
+	return nil
+}
+
+func (h *MockClientConfig) TestQuery(config *humioapi.Config, req reconcile.Request, repository, query string) error {
+	return nil
+}
+
+func (h *MockClientConfig) RotateUserAPIToken(config *humioapi.Config, req reconcile.Request, username string) (string, error) {
+	return "mockrotatedtoken", nil
+}
+
+func (h *MockClientConfig) EnsureRootUser(config *humioapi.Config, req reconcile.Request, username string) error {
 	return nil
 }
 
@@ -185,11 +244,29 @@ func (h *MockClientConfig) UpdateParser(config *humioapi.Config, req reconcile.R
 	return h.AddParser(config, req, hp)
 }
 
+func (h *MockClientConfig) UpdateParsers(config *humioapi.Config, req reconcile.Request, hps []*humiov1alpha1.HumioParser) error {
+	for _, hp := range hps {
+		if _, err := h.UpdateParser(config, req, hp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *MockClientConfig) DeleteParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) error {
 	h.apiClient.Parser = humioapi.Parser{}
 	return nil
 }
 
+func (h *MockClientConfig) TestParser(config *humioapi.Config, req reconcile.Request, hp *humiov1alpha1.HumioParser) ([]string, error) {
+	return h.apiClient.ParserTestErrors, nil
+}
+
+// SetParserTestErrors sets the errors returned by TestParser
+func (h *MockClientConfig) SetParserTestErrors(parseErrors []string) {
+	h.apiClient.ParserTestErrors = parseErrors
+}
+
 func (h *MockClientConfig) AddRepository(config *humioapi.Config, req reconcile.Request, hr *humiov1alpha1.HumioRepository) (*humioapi.Repository, error) {
 	h.apiClient.Repository = humioapi.Repository{
 		ID:                     kubernetes.RandomString(),
@@ -215,6 +292,41 @@ func (h *MockClientConfig) DeleteRepository(config *humioapi.Config, req reconci
 	return nil
 }
 
+func (h *MockClientConfig) GetRepositoryIngestUsage(config *humioapi.Config, req reconcile.Request, repositoryName string, start string) (int64, error) {
+	return h.apiClient.RepositoryIngestUsage[fmt.Sprintf("%s/%s", repositoryName, start)], nil
+}
+
+// SetRepositoryIngestUsage sets the number of bytes returned by GetRepositoryIngestUsage for the given repository and
+// start of the search interval
+func (h *MockClientConfig) SetRepositoryIngestUsage(repositoryName string, start string, bytes int64) {
+	h.apiClient.RepositoryIngestUsage[fmt.Sprintf("%s/%s", repositoryName, start)] = bytes
+}
+
+func (h *MockClientConfig) BlockIngest(config *humioapi.Config, req reconcile.Request, repositoryName string, duration time.Duration) error {
+	h.apiClient.BlockedIngest[repositoryName] = true
+	return nil
+}
+
+func (h *MockClientConfig) UnblockIngest(config *humioapi.Config, req reconcile.Request, repositoryName string) error {
+	delete(h.apiClient.BlockedIngest, repositoryName)
+	return nil
+}
+
+func (h *MockClientConfig) GetRepositoryStatistics(config *humioapi.Config, req reconcile.Request, repositoryName string) (*RepositoryStatistics, error) {
+	statistics := h.apiClient.RepositoryStatistics[repositoryName]
+	return &statistics, nil
+}
+
+// SetRepositoryStatistics sets the statistics returned by GetRepositoryStatistics for the given repository
+func (h *MockClientConfig) SetRepositoryStatistics(repositoryName string, statistics RepositoryStatistics) {
+	h.apiClient.RepositoryStatistics[repositoryName] = statistics
+}
+
+// IngestBlocked returns whether ingest into the given repository has been blocked
+func (h *MockClientConfig) IngestBlocked(repositoryName string) bool {
+	return h.apiClient.BlockedIngest[repositoryName]
+}
+
 func (h *MockClientConfig) GetView(config *humioapi.Config, req reconcile.Request, hv *humiov1alpha1.HumioView) (*humioapi.View, error) {
 	return &h.apiClient.View, nil
 }
@@ -244,6 +356,268 @@ func (h *MockClientConfig) DeleteView(config *humioapi.Config, req reconcile.Req
 	return nil
 }
 
+func (h *MockClientConfig) GetMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) (*MultiClusterView, error) {
+	view, ok := h.apiClient.MultiClusterViews[hmcv.Spec.Name]
+	if !ok {
+		return &MultiClusterView{}, nil
+	}
+	viewCopy := *view
+	viewCopy.Connections = append([]MultiClusterViewConnection(nil), view.Connections...)
+	return &viewCopy, nil
+}
+
+func (h *MockClientConfig) AddMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	h.apiClient.MultiClusterViews[hmcv.Spec.Name] = &MultiClusterView{
+		Name:        hmcv.Spec.Name,
+		Description: hmcv.Spec.Description,
+	}
+	return nil
+}
+
+func (h *MockClientConfig) UpdateMultiClusterViewDescription(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	view, ok := h.apiClient.MultiClusterViews[hmcv.Spec.Name]
+	if !ok {
+		return fmt.Errorf("multi-cluster view %s not found", hmcv.Spec.Name)
+	}
+	view.Description = hmcv.Spec.Description
+	return nil
+}
+
+func (h *MockClientConfig) DeleteMultiClusterView(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView) error {
+	delete(h.apiClient.MultiClusterViews, hmcv.Spec.Name)
+	return nil
+}
+
+func (h *MockClientConfig) AddMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connection MultiClusterViewConnection) error {
+	view, ok := h.apiClient.MultiClusterViews[hmcv.Spec.Name]
+	if !ok {
+		return fmt.Errorf("multi-cluster view %s not found", hmcv.Spec.Name)
+	}
+	h.apiClient.multiClusterViewConnectionID++
+	connection.ID = fmt.Sprintf("%d", h.apiClient.multiClusterViewConnectionID)
+	connection.Token = ""
+	view.Connections = append(view.Connections, connection)
+	return nil
+}
+
+func (h *MockClientConfig) UpdateMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connection MultiClusterViewConnection) error {
+	view, ok := h.apiClient.MultiClusterViews[hmcv.Spec.Name]
+	if !ok {
+		return fmt.Errorf("multi-cluster view %s not found", hmcv.Spec.Name)
+	}
+	for i := range view.Connections {
+		if view.Connections[i].ID == connection.ID {
+			connection.Token = ""
+			view.Connections[i] = connection
+			return nil
+		}
+	}
+	return fmt.Errorf("connection %s not found", connection.ID)
+}
+
+func (h *MockClientConfig) DeleteMultiClusterViewConnection(config *humioapi.Config, req reconcile.Request, hmcv *humiov1alpha1.HumioMultiClusterView, connectionID string) error {
+	view, ok := h.apiClient.MultiClusterViews[hmcv.Spec.Name]
+	if !ok {
+		return fmt.Errorf("multi-cluster view %s not found", hmcv.Spec.Name)
+	}
+	for i := range view.Connections {
+		if view.Connections[i].ID == connectionID {
+			view.Connections = append(view.Connections[:i], view.Connections[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("connection %s not found", connectionID)
+}
+
+func (h *MockClientConfig) AddSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error) {
+	key := fmt.Sprintf("%s/%s", hsq.Spec.ViewName, hsq.Spec.Name)
+	if _, ok := h.apiClient.SavedQueries[key]; ok {
+		return &SavedQuery{}, fmt.Errorf("saved query %s already exists", hsq.Spec.Name)
+	}
+	h.apiClient.savedQueryID++
+	savedQuery := *SavedQueryTransform(hsq)
+	savedQuery.ID = fmt.Sprintf("%d", h.apiClient.savedQueryID)
+	h.apiClient.SavedQueries[key] = savedQuery
+	return &savedQuery, nil
+}
+
+func (h *MockClientConfig) GetSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error) {
+	savedQuery := h.apiClient.SavedQueries[fmt.Sprintf("%s/%s", hsq.Spec.ViewName, hsq.Spec.Name)]
+	return &savedQuery, nil
+}
+
+func (h *MockClientConfig) UpdateSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) (*SavedQuery, error) {
+	key := fmt.Sprintf("%s/%s", hsq.Spec.ViewName, hsq.Spec.Name)
+	current, ok := h.apiClient.SavedQueries[key]
+	if !ok {
+		return &SavedQuery{}, fmt.Errorf("could not find saved query with name: %q", hsq.Spec.Name)
+	}
+	savedQuery := *SavedQueryTransform(hsq)
+	savedQuery.ID = current.ID
+	h.apiClient.SavedQueries[key] = savedQuery
+	return &savedQuery, nil
+}
+
+func (h *MockClientConfig) DeleteSavedQuery(config *humioapi.Config, req reconcile.Request, hsq *humiov1alpha1.HumioSavedQuery) error {
+	delete(h.apiClient.SavedQueries, fmt.Sprintf("%s/%s", hsq.Spec.ViewName, hsq.Spec.Name))
+	return nil
+}
+
+func (h *MockClientConfig) AddAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error) {
+	key := fmt.Sprintf("%s/%s", haa.Spec.ViewName, haa.Spec.Name)
+	if _, ok := h.apiClient.AggregateAlerts[key]; ok {
+		return &AggregateAlert{}, fmt.Errorf("aggregate alert %s already exists", haa.Spec.Name)
+	}
+	h.apiClient.aggregateAlertID++
+	aggregateAlert := *AggregateAlertTransform(haa)
+	aggregateAlert.ID = fmt.Sprintf("%d", h.apiClient.aggregateAlertID)
+	h.apiClient.AggregateAlerts[key] = aggregateAlert
+	return &aggregateAlert, nil
+}
+
+func (h *MockClientConfig) GetAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error) {
+	aggregateAlert := h.apiClient.AggregateAlerts[fmt.Sprintf("%s/%s", haa.Spec.ViewName, haa.Spec.Name)]
+	return &aggregateAlert, nil
+}
+
+func (h *MockClientConfig) UpdateAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) (*AggregateAlert, error) {
+	key := fmt.Sprintf("%s/%s", haa.Spec.ViewName, haa.Spec.Name)
+	current, ok := h.apiClient.AggregateAlerts[key]
+	if !ok {
+		return &AggregateAlert{}, fmt.Errorf("could not find aggregate alert with name: %q", haa.Spec.Name)
+	}
+	aggregateAlert := *AggregateAlertTransform(haa)
+	aggregateAlert.ID = current.ID
+	h.apiClient.AggregateAlerts[key] = aggregateAlert
+	return &aggregateAlert, nil
+}
+
+func (h *MockClientConfig) DeleteAggregateAlert(config *humioapi.Config, req reconcile.Request, haa *humiov1alpha1.HumioAggregateAlert) error {
+	delete(h.apiClient.AggregateAlerts, fmt.Sprintf("%s/%s", haa.Spec.ViewName, haa.Spec.Name))
+	return nil
+}
+
+func (h *MockClientConfig) AddScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error) {
+	key := fmt.Sprintf("%s/%s", hss.Spec.ViewName, hss.Spec.Name)
+	if _, ok := h.apiClient.ScheduledSearches[key]; ok {
+		return &ScheduledSearch{}, fmt.Errorf("scheduled search %s already exists", hss.Spec.Name)
+	}
+	h.apiClient.scheduledSearchID++
+	scheduledSearch := *ScheduledSearchTransform(hss)
+	scheduledSearch.ID = fmt.Sprintf("%d", h.apiClient.scheduledSearchID)
+	h.apiClient.ScheduledSearches[key] = scheduledSearch
+	return &scheduledSearch, nil
+}
+
+func (h *MockClientConfig) GetScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error) {
+	scheduledSearch := h.apiClient.ScheduledSearches[fmt.Sprintf("%s/%s", hss.Spec.ViewName, hss.Spec.Name)]
+	return &scheduledSearch, nil
+}
+
+func (h *MockClientConfig) UpdateScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) (*ScheduledSearch, error) {
+	key := fmt.Sprintf("%s/%s", hss.Spec.ViewName, hss.Spec.Name)
+	current, ok := h.apiClient.ScheduledSearches[key]
+	if !ok {
+		return &ScheduledSearch{}, fmt.Errorf("could not find scheduled search with name: %q", hss.Spec.Name)
+	}
+	scheduledSearch := *ScheduledSearchTransform(hss)
+	scheduledSearch.ID = current.ID
+	h.apiClient.ScheduledSearches[key] = scheduledSearch
+	return &scheduledSearch, nil
+}
+
+func (h *MockClientConfig) DeleteScheduledSearch(config *humioapi.Config, req reconcile.Request, hss *humiov1alpha1.HumioScheduledSearch) error {
+	delete(h.apiClient.ScheduledSearches, fmt.Sprintf("%s/%s", hss.Spec.ViewName, hss.Spec.Name))
+	return nil
+}
+
+// AddDashboard creates the dashboard with the template as its exported template, as the mock does not reformat
+// templates the way Humio does
+func (h *MockClientConfig) AddDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard, template string) (*Dashboard, error) {
+	key := fmt.Sprintf("%s/%s", hd.Spec.ViewName, hd.Spec.Name)
+	if _, ok := h.apiClient.Dashboards[key]; ok {
+		return &Dashboard{}, fmt.Errorf("dashboard %s already exists", hd.Spec.Name)
+	}
+	h.apiClient.dashboardID++
+	dashboard := Dashboard{
+		ID:           fmt.Sprintf("%d", h.apiClient.dashboardID),
+		Name:         hd.Spec.Name,
+		TemplateYaml: template,
+	}
+	h.apiClient.Dashboards[key] = dashboard
+	return &Dashboard{ID: dashboard.ID, Name: dashboard.Name}, nil
+}
+
+func (h *MockClientConfig) GetDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard) (*Dashboard, error) {
+	dashboard := h.apiClient.Dashboards[fmt.Sprintf("%s/%s", hd.Spec.ViewName, hd.Spec.Name)]
+	return &dashboard, nil
+}
+
+// SetDashboardTemplate replaces the exported template of the dashboard, as if it had been changed in the Humio UI
+func (h *MockClientConfig) SetDashboardTemplate(viewName, name, template string) {
+	key := fmt.Sprintf("%s/%s", viewName, name)
+	dashboard := h.apiClient.Dashboards[key]
+	dashboard.TemplateYaml = template
+	h.apiClient.Dashboards[key] = dashboard
+}
+
+func (h *MockClientConfig) DeleteDashboard(config *humioapi.Config, req reconcile.Request, hd *humiov1alpha1.HumioDashboard) error {
+	delete(h.apiClient.Dashboards, fmt.Sprintf("%s/%s", hd.Spec.ViewName, hd.Spec.Name))
+	return nil
+}
+
+func (h *MockClientConfig) ListSavedQueries(config *humioapi.Config, req reconcile.Request, viewName string) ([]SavedQuery, error) {
+	var savedQueries []SavedQuery
+	for key, savedQuery := range h.apiClient.SavedQueries {
+		if strings.HasPrefix(key, viewName+"/") {
+			savedQueries = append(savedQueries, savedQuery)
+		}
+	}
+	sort.Slice(savedQueries, func(i, j int) bool {
+		return savedQueries[i].Name < savedQueries[j].Name
+	})
+	return savedQueries, nil
+}
+
+func (h *MockClientConfig) ListAggregateAlerts(config *humioapi.Config, req reconcile.Request, viewName string) ([]AggregateAlert, error) {
+	var aggregateAlerts []AggregateAlert
+	for key, aggregateAlert := range h.apiClient.AggregateAlerts {
+		if strings.HasPrefix(key, viewName+"/") {
+			aggregateAlerts = append(aggregateAlerts, aggregateAlert)
+		}
+	}
+	sort.Slice(aggregateAlerts, func(i, j int) bool {
+		return aggregateAlerts[i].Name < aggregateAlerts[j].Name
+	})
+	return aggregateAlerts, nil
+}
+
+func (h *MockClientConfig) ListScheduledSearches(config *humioapi.Config, req reconcile.Request, viewName string) ([]ScheduledSearch, error) {
+	var scheduledSearches []ScheduledSearch
+	for key, scheduledSearch := range h.apiClient.ScheduledSearches {
+		if strings.HasPrefix(key, viewName+"/") {
+			scheduledSearches = append(scheduledSearches, scheduledSearch)
+		}
+	}
+	sort.Slice(scheduledSearches, func(i, j int) bool {
+		return scheduledSearches[i].Name < scheduledSearches[j].Name
+	})
+	return scheduledSearches, nil
+}
+
+func (h *MockClientConfig) ListDashboards(config *humioapi.Config, req reconcile.Request, viewName string) ([]Dashboard, error) {
+	var dashboards []Dashboard
+	for key, dashboard := range h.apiClient.Dashboards {
+		if strings.HasPrefix(key, viewName+"/") {
+			dashboards = append(dashboards, dashboard)
+		}
+	}
+	sort.Slice(dashboards, func(i, j int) bool {
+		return dashboards[i].Name < dashboards[j].Name
+	})
+	return dashboards, nil
+}
+
 func (h *MockClientConfig) GetLicense(config *humioapi.Config, req reconcile.Request) (humioapi.License, error) {
 	emptyOnPremLicense := humioapi.OnPremLicense{}
 
@@ -294,6 +668,24 @@ func (h *MockClientConfig) DeleteAction(config *humioapi.Config, req reconcile.R
 	return nil
 }
 
+func (h *MockClientConfig) TestAction(config *humioapi.Config, req reconcile.Request, viewName string, action *humioapi.Action, triggerName, eventData string) (*TestActionResult, error) {
+	h.apiClient.TestedActions = append(h.apiClient.TestedActions, action.Name)
+	if message, ok := h.apiClient.TestActionFailures[action.Name]; ok {
+		return &TestActionResult{Success: false, Message: message}, nil
+	}
+	return &TestActionResult{Success: true, Message: "Test notification sent"}, nil
+}
+
+// SetTestActionFailure makes testing the action with the given name report a failure with the given message
+func (h *MockClientConfig) SetTestActionFailure(actionName, message string) {
+	h.apiClient.TestActionFailures[actionName] = message
+}
+
+// TestedActions returns the names of the actions which have been tested
+func (h *MockClientConfig) TestedActions() []string {
+	return h.apiClient.TestedActions
+}
+
 func (h *MockClientConfig) GetAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) (*humioapi.Alert, error) {
 	if h.apiClient.Alert.Name == "" {
 		return nil, fmt.Errorf("could not find alert in view %q with name %q, err=%w", ha.Spec.ViewName, ha.Spec.Name, humioapi.EntityNotFound{})
@@ -318,6 +710,15 @@ func (h *MockClientConfig) UpdateAlert(config *humioapi.Config, req reconcile.Re
 	return h.AddAlert(config, req, ha)
 }
 
+func (h *MockClientConfig) UpdateAlerts(config *humioapi.Config, req reconcile.Request, has []*humiov1alpha1.HumioAlert) error {
+	for _, ha := range has {
+		if _, err := h.UpdateAlert(config, req, ha); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *MockClientConfig) DeleteAlert(config *humioapi.Config, req reconcile.Request, ha *humiov1alpha1.HumioAlert) error {
 	h.apiClient.Alert = humioapi.Alert{}
 	return nil
@@ -332,11 +733,50 @@ func (h *MockClientConfig) GetActionIDsMapForAlerts(config *humioapi.Config, req
 	return actionIdMap, nil
 }
 
+func (h *MockClientConfig) CreateQueryJob(config *humioapi.Config, req reconcile.Request, repositoryName string, query humioapi.Query) (string, error) {
+	return kubernetes.RandomString(), nil
+}
+
+func (h *MockClientConfig) PollQueryJob(config *humioapi.Config, req reconcile.Request, repositoryName string, id string) (humioapi.QueryResult, error) {
+	return humioapi.QueryResult{Done: true}, nil
+}
+
+func (h *MockClientConfig) DeleteQueryJob(config *humioapi.Config, req reconcile.Request, repositoryName string, id string) error {
+	return nil
+}
+
+func (h *MockClientConfig) GetRepositoryUsage(config *humioapi.Config, req reconcile.Request) ([]RepositoryUsage, error) {
+	return []RepositoryUsage{{
+		Name:                 h.apiClient.Repository.Name,
+		CompressedByteSize:   h.apiClient.Repository.SpaceUsed,
+		UncompressedByteSize: h.apiClient.Repository.SpaceUsed,
+	}}, nil
+}
+
+func (h *MockClientConfig) GetIngestUsage(config *humioapi.Config, req reconcile.Request) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+func (h *MockClientConfig) CountUsers(config *humioapi.Config, req reconcile.Request) (int, error) {
+	return 1, nil
+}
+
+func (h *MockClientConfig) GetThreadDump(config *humioapi.Config, req reconcile.Request) (string, error) {
+	return fmt.Sprintf("thread dump of %s", config.Address), nil
+}
+
 func (h *MockClientConfig) GetHumioClient(config *humioapi.Config, req ctrl.Request) *humioapi.Client {
 	clusterURL, _ := url.Parse("http://localhost:8080/")
 	return humioapi.NewClient(humioapi.Config{Address: clusterURL})
 }
 
+func (h *MockClientConfig) InvalidateReadCache(config *humioapi.Config) {
+}
+
+func (h *MockClientConfig) PrimeSearchDomainIndex(config *humioapi.Config, req reconcile.Request) error {
+	return nil
+}
+
 func (h *MockClientConfig) ClearHumioClientConnections() {
 	h.apiClient.IngestToken = humioapi.IngestToken{}
 	h.apiClient.Parser = humioapi.Parser{}
@@ -345,4 +785,11 @@ func (h *MockClientConfig) ClearHumioClientConnections() {
 	h.apiClient.OnPremLicense = humioapi.OnPremLicense{}
 	h.apiClient.Action = humioapi.Action{}
 	h.apiClient.Alert = humioapi.Alert{}
+	h.apiClient.TestedActions = nil
+	h.apiClient.TestActionFailures = map[string]string{}
+	h.apiClient.MultiClusterViews = map[string]*MultiClusterView{}
+	h.apiClient.SavedQueries = map[string]SavedQuery{}
+	h.apiClient.Dashboards = map[string]Dashboard{}
+	h.apiClient.AggregateAlerts = map[string]AggregateAlert{}
+	h.apiClient.ScheduledSearches = map[string]ScheduledSearch{}
 }
@@ -0,0 +1,77 @@
+package humio
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	"github.com/humio/humio-operator/pkg/helpers"
+)
+
+func TestWithConnectTimeout(t *testing.T) {
+	dial := withConnectTimeout(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, 10*time.Millisecond)
+
+	start := time.Now()
+	_, err := dial(context.Background(), "tcp", "humio.example.com:443")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected dial to give up after the connect timeout, took %s", time.Since(start))
+	}
+}
+
+func TestNewHttpTransportAppliesReadTimeout(t *testing.T) {
+	address, _ := url.Parse("https://humio.example.com/")
+	h := NewClient(logr.Discard(), &humioapi.Config{}, "")
+
+	if transport := h.newHttpTransport(humioapi.Config{Address: address}, helpers.RequestTimeouts{}, time.Time{}); transport.ResponseHeaderTimeout != 0 {
+		t.Errorf("expected no read timeout by default, got %s", transport.ResponseHeaderTimeout)
+	}
+	if transport := h.newHttpTransport(humioapi.Config{Address: address}, helpers.RequestTimeouts{Read: time.Minute}, time.Time{}); transport.ResponseHeaderTimeout != time.Minute {
+		t.Errorf("expected read timeout %s, got %s", time.Minute, transport.ResponseHeaderTimeout)
+	}
+}
+
+func TestGetTransportIsPooledPerCluster(t *testing.T) {
+	address, _ := url.Parse("https://pool.humio.example.com/")
+	otherAddress, _ := url.Parse("https://other.humio.example.com/")
+	h := NewClient(logr.Discard(), &humioapi.Config{}, "")
+
+	transport := h.getTransport(humioapi.Config{Address: address, Token: "token"})
+	if h.getTransport(humioapi.Config{Address: address, Token: "rotated-token"}) != transport {
+		t.Error("expected connections to be reused when the api token changes")
+	}
+	if h.getTransport(humioapi.Config{Address: otherAddress}) == transport {
+		t.Error("expected separate connections for different clusters")
+	}
+	if transport.TLSClientConfig.ClientSessionCache == nil {
+		t.Error("expected tls sessions to be cached")
+	}
+	if h.getTransport(humioapi.Config{Address: address, CACertificatePEM: "ca"}) == transport {
+		t.Error("expected connections to be replaced when the CA changes")
+	}
+}
+
+func TestParseIngestUsage(t *testing.T) {
+	usage := parseIngestUsage([]map[string]interface{}{
+		{"repo": "web", "bytes": "2048"},
+		{"repo": "web", "bytes": 1024.0},
+		{"repo": "audit", "bytes": "1.5e3"},
+		{"repo": "", "bytes": "100"},
+		{"repo": "broken", "bytes": "n/a"},
+	})
+	expected := map[string]int64{"web": 3072, "audit": 1500}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Errorf("expected %v, got %v", expected, usage)
+	}
+}
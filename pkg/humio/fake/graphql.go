@@ -0,0 +1,416 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// selection is a field or an inline fragment of a GraphQL selection set
+type selection struct {
+	alias string
+	name  string
+	args  map[string]interface{}
+	// typeCondition is set for inline fragments, which only apply to objects of that type
+	typeCondition string
+	selections    []selection
+}
+
+func (s selection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// operation is a parsed GraphQL query or mutation
+type operation struct {
+	mutation   bool
+	selections []selection
+}
+
+// variableRef refers to a variable of the request until the arguments are resolved
+type variableRef string
+
+// parseOperation parses the subset of GraphQL sent by the Humio API client: a single anonymous or named query or
+// mutation with fields, aliases, arguments and inline fragments. Named fragments and directives are not supported.
+func parseOperation(query string, variables map[string]interface{}) (*operation, error) {
+	p := &queryParser{tokens: tokenize(query)}
+	op := &operation{}
+	if p.peek() == "query" || p.peek() == "mutation" {
+		op.mutation = p.next() == "mutation"
+		if p.peek() != "(" && p.peek() != "{" {
+			p.next() // operation name
+		}
+		if p.peek() == "(" {
+			// Variable definitions are skipped, as variables are used as they are sent
+			if err := p.skipBalanced("(", ")"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != "" {
+		return nil, fmt.Errorf("unexpected %q after the operation", p.peek())
+	}
+	if err := resolveVariables(selections, variables); err != nil {
+		return nil, err
+	}
+	op.selections = selections
+	return op, nil
+}
+
+func resolveVariables(selections []selection, variables map[string]interface{}) error {
+	for i := range selections {
+		for name, value := range selections[i].args {
+			resolved, err := resolveValue(value, variables)
+			if err != nil {
+				return err
+			}
+			selections[i].args[name] = resolved
+		}
+		if err := resolveVariables(selections[i].selections, variables); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveValue(value interface{}, variables map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case variableRef:
+		resolved, ok := variables[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", v)
+		}
+		return resolved, nil
+	case map[string]interface{}:
+		for key, field := range v {
+			resolved, err := resolveValue(field, variables)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	case []interface{}:
+		for i, item := range v {
+			resolved, err := resolveValue(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	}
+	return value, nil
+}
+
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *queryParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *queryParser) expect(token string) error {
+	if next := p.next(); next != token {
+		return fmt.Errorf("expected %q, got %q", token, next)
+	}
+	return nil
+}
+
+func (p *queryParser) skipBalanced(open, closing string) error {
+	depth := 0
+	for {
+		switch p.next() {
+		case open:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		case "":
+			return fmt.Errorf("expected %q", closing)
+		}
+	}
+}
+
+func (p *queryParser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for p.peek() != "}" {
+		if p.peek() == "" {
+			return nil, fmt.Errorf("expected %q", "}")
+		}
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	p.next()
+	return selections, nil
+}
+
+func (p *queryParser) selection() (selection, error) {
+	var s selection
+	if p.peek() == "..." {
+		p.next()
+		if err := p.expect("on"); err != nil {
+			return s, fmt.Errorf("only inline fragments are supported: %w", err)
+		}
+		s.typeCondition = p.next()
+		selections, err := p.selectionSet()
+		s.selections = selections
+		return s, err
+	}
+
+	s.name = p.next()
+	if !isName(s.name) {
+		return s, fmt.Errorf("expected a field name, got %q", s.name)
+	}
+	if p.peek() == ":" {
+		p.next()
+		s.alias, s.name = s.name, p.next()
+	}
+	if p.peek() == "(" {
+		p.next()
+		s.args = map[string]interface{}{}
+		for p.peek() != ")" {
+			name := p.next()
+			if err := p.expect(":"); err != nil {
+				return s, err
+			}
+			value, err := p.value()
+			if err != nil {
+				return s, err
+			}
+			s.args[name] = value
+		}
+		p.next()
+	}
+	if p.peek() == "{" {
+		selections, err := p.selectionSet()
+		if err != nil {
+			return s, err
+		}
+		s.selections = selections
+	}
+	return s, nil
+}
+
+func (p *queryParser) value() (interface{}, error) {
+	token := p.next()
+	switch {
+	case token == "$":
+		return variableRef(p.next()), nil
+	case token == "{":
+		object := map[string]interface{}{}
+		for p.peek() != "}" {
+			name := p.next()
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			object[name] = value
+		}
+		p.next()
+		return object, nil
+	case token == "[":
+		list := []interface{}{}
+		for p.peek() != "]" {
+			if p.peek() == "" {
+				return nil, fmt.Errorf("expected %q", "]")
+			}
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		p.next()
+		return list, nil
+	case strings.HasPrefix(token, `"`):
+		return strconv.Unquote(token)
+	case token == "true" || token == "false":
+		return token == "true", nil
+	case token == "null":
+		return nil, nil
+	case token != "" && (unicode.IsDigit(rune(token[0])) || token[0] == '-'):
+		return json.Number(token), nil
+	case isName(token):
+		// Enum values are passed on as strings
+		return token, nil
+	}
+	return nil, fmt.Errorf("unexpected %q in argument value", token)
+}
+
+func isName(token string) bool {
+	if token == "" {
+		return false
+	}
+	for i, r := range token {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenize splits a GraphQL document into names, punctuators, strings and numbers. Commas are insignificant in GraphQL
+// and are dropped along with whitespace and comments.
+func tokenize(document string) []string {
+	var tokens []string
+	for i := 0; i < len(document); {
+		c := document[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(document) && document[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(document[i:], "..."):
+			tokens = append(tokens, "...")
+			i += 3
+		case c == '"':
+			end := i + 1
+			for end < len(document) && document[end] != '"' {
+				if document[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(document) {
+				end++
+			}
+			tokens = append(tokens, document[i:end])
+			i = end
+		case c == '_' || c == '-' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			number := c == '-' || unicode.IsDigit(rune(c))
+			end := i + 1
+			for end < len(document) && (document[end] == '_' || (number && document[end] == '.') || unicode.IsLetter(rune(document[end])) || unicode.IsDigit(rune(document[end]))) {
+				end++
+			}
+			tokens = append(tokens, document[i:end])
+			i = end
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+// object is a GraphQL object. Fields which take arguments are resolved by calling a fieldResolver.
+type object map[string]interface{}
+
+type fieldResolver func(args map[string]interface{}) (interface{}, error)
+
+// execute returns the response data of the selections on the given value
+func execute(selections []selection, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case object:
+		result := map[string]interface{}{}
+		if err := executeObject(selections, v, result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	case []object:
+		results := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			result, err := execute(selections, item)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+		return results, nil
+	case []interface{}:
+		if len(selections) == 0 {
+			return v, nil
+		}
+		results := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			result, err := execute(selections, item)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+		return results, nil
+	}
+	if len(selections) > 0 {
+		return nil, fmt.Errorf("cannot select fields of a scalar value")
+	}
+	return value, nil
+}
+
+func executeObject(selections []selection, obj object, result map[string]interface{}) error {
+	typeName, _ := obj["__typename"].(string)
+	for _, s := range selections {
+		if s.typeCondition != "" {
+			if s.typeCondition == typeName {
+				if err := executeObject(s.selections, obj, result); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		value, ok := obj[s.name]
+		if !ok {
+			return fmt.Errorf("Cannot query field %q on type %q", s.name, typeName)
+		}
+		if resolve, isResolver := value.(fieldResolver); isResolver {
+			var err error
+			if value, err = resolve(s.args); err != nil {
+				return err
+			}
+		}
+		fieldResult, err := execute(s.selections, value)
+		if err != nil {
+			return err
+		}
+		result[s.responseKey()] = fieldResult
+	}
+	return nil
+}
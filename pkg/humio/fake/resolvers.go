@@ -0,0 +1,732 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// actionFields lists the fields of each type of action, which are set from the inputs of the create and update
+// mutations of that type
+var actionFields = map[string][]string{
+	"EmailAction":            {"recipients", "subjectTemplate", "bodyTemplate", "useProxy"},
+	"HumioRepoAction":        {"ingestToken"},
+	"OpsGenieAction":         {"apiUrl", "genieKey", "useProxy"},
+	"PagerDutyAction":        {"severity", "routingKey", "useProxy"},
+	"SlackAction":            {"url", "fields", "useProxy"},
+	"SlackPostMessageAction": {"apiToken", "channels", "fields", "useProxy"},
+	"VictorOpsAction":        {"messageType", "notifyUrl", "useProxy"},
+	"WebhookAction":          {"method", "url", "headers", "bodyTemplate", "ignoreSSL", "useProxy"},
+}
+
+// alertFields lists the fields of alerts which are set from the inputs of the create and update mutations
+var alertFields = []string{"name", "description", "queryString", "queryStart", "throttleTimeMillis", "throttleField", "enabled", "actions", "labels"}
+
+// searchDomain is a repository or a view
+type searchDomain struct {
+	id           string
+	name         string
+	description  string
+	isRepository bool
+
+	// Retention of repositories, where zero means no retention
+	timeBasedRetention        float64
+	ingestSizeBasedRetention  float64
+	storageSizeBasedRetention float64
+	parsers                   map[string]*parser
+	ingestTokens              map[string]*ingestToken
+
+	// Connections of views
+	connections []viewConnection
+
+	actions map[string]*entity
+	alerts  map[string]*entity
+}
+
+type viewConnection struct {
+	repositoryName string
+	filter         string
+}
+
+type parser struct {
+	id         string
+	name       string
+	sourceCode string
+	testData   []string
+	tagFields  []string
+}
+
+type ingestToken struct {
+	name   string
+	token  string
+	parser string
+}
+
+// entity is an action or an alert, which are stored as the GraphQL fields set by their inputs
+type entity struct {
+	typeName string
+	id       string
+	fields   map[string]interface{}
+}
+
+func (s *Server) queryRoot() object {
+	return object{
+		"__typename": "Query",
+		"viewer": object{
+			"__typename": "Account",
+			"username":   Username,
+		},
+		"repositories": fieldResolver(func(map[string]interface{}) (interface{}, error) {
+			return s.searchDomainObjects(true), nil
+		}),
+		"searchDomains": fieldResolver(func(map[string]interface{}) (interface{}, error) {
+			return s.searchDomainObjects(false), nil
+		}),
+		"repository": fieldResolver(func(args map[string]interface{}) (interface{}, error) {
+			repository, err := s.repository(stringArg(args, "name"))
+			if err != nil {
+				return nil, err
+			}
+			return s.searchDomainObject(repository), nil
+		}),
+		"searchDomain": fieldResolver(func(args map[string]interface{}) (interface{}, error) {
+			sd, err := s.searchDomain(stringArg(args, "name"))
+			if err != nil {
+				return nil, err
+			}
+			return s.searchDomainObject(sd), nil
+		}),
+		"usersPage": fieldResolver(s.usersPage),
+	}
+}
+
+// usersPage returns a page of the users whose username contains the search, ordered by username
+func (s *Server) usersPage(args map[string]interface{}) (interface{}, error) {
+	pageNumber, pageSize := int(floatArg(args, "pageNumber")), int(floatArg(args, "pageSize"))
+	if pageNumber < 1 || pageSize < 1 {
+		return nil, fmt.Errorf("invalid page %d of size %d", pageNumber, pageSize)
+	}
+	search := strings.ToLower(stringArg(args, "search"))
+	var usernames []string
+	for _, username := range s.users {
+		if strings.Contains(strings.ToLower(username), search) {
+			usernames = append(usernames, username)
+		}
+	}
+	sort.Strings(usernames)
+
+	page := []object{}
+	for i := (pageNumber - 1) * pageSize; i < len(usernames) && i < pageNumber*pageSize; i++ {
+		page = append(page, object{
+			"__typename":  "User",
+			"id":          "user-" + usernames[i],
+			"username":    usernames[i],
+			"fullName":    "",
+			"email":       "",
+			"company":     "",
+			"countryCode": "",
+			"picture":     "",
+			"isRoot":      usernames[i] == Username,
+			"createdAt":   "",
+		})
+	}
+	return object{
+		"__typename": "UsersPage",
+		"pageInfo": object{
+			"__typename":        "PageType",
+			"number":            pageNumber,
+			"totalNumberOfRows": len(usernames),
+		},
+		"page": page,
+	}, nil
+}
+
+func (s *Server) mutationRoot() object {
+	root := object{
+		"__typename":                       "Mutation",
+		"createRepository":                 fieldResolver(s.createRepository),
+		"createView":                       fieldResolver(s.createView),
+		"updateView":                       fieldResolver(s.updateView),
+		"deleteSearchDomain":               fieldResolver(s.deleteSearchDomain),
+		"updateDescriptionForSearchDomain": fieldResolver(s.updateDescription),
+		"updateRetention":                  fieldResolver(s.updateRetention),
+		"createParser":                     fieldResolver(s.createParser),
+		"removeParser":                     fieldResolver(s.removeParser),
+		"testParser":                       fieldResolver(s.testParser),
+		"addIngestTokenV3":                 fieldResolver(s.addIngestToken),
+		"assignParserToIngestTokenV2":      fieldResolver(s.assignParserToIngestToken),
+		"unassignIngestToken":              fieldResolver(s.unassignIngestToken),
+		"removeIngestToken":                fieldResolver(s.removeIngestToken),
+		"deleteAction":                     fieldResolver(s.deleteAction),
+		"createAlert":                      fieldResolver(s.createAlert),
+		"updateAlert":                      fieldResolver(s.updateAlert),
+		"deleteAlert":                      fieldResolver(s.deleteAlert),
+	}
+	for typeName := range actionFields {
+		typeName := typeName
+		root["create"+typeName] = fieldResolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.saveAction(typeName, inputArg(args), false)
+		})
+		root["update"+typeName] = fieldResolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.saveAction(typeName, inputArg(args), true)
+		})
+	}
+	return root
+}
+
+func (s *Server) searchDomain(name string) (*searchDomain, error) {
+	sd, ok := s.searchDomains[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("search domain %q does not exist", name)
+	}
+	return sd, nil
+}
+
+func (s *Server) repository(name string) (*searchDomain, error) {
+	sd, ok := s.searchDomains[strings.ToLower(name)]
+	if !ok || !sd.isRepository {
+		return nil, fmt.Errorf("repository %q does not exist", name)
+	}
+	return sd, nil
+}
+
+func (s *Server) view(name string) (*searchDomain, error) {
+	sd, ok := s.searchDomains[strings.ToLower(name)]
+	if !ok || sd.isRepository {
+		return nil, fmt.Errorf("view %q does not exist", name)
+	}
+	return sd, nil
+}
+
+func (s *Server) searchDomainObjects(repositoriesOnly bool) []object {
+	var objects []object
+	for _, sd := range s.searchDomains {
+		if sd.isRepository || !repositoriesOnly {
+			objects = append(objects, s.searchDomainObject(sd))
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i]["name"].(string) < objects[j]["name"].(string)
+	})
+	return objects
+}
+
+func (s *Server) searchDomainObject(sd *searchDomain) object {
+	obj := object{
+		"__typename":  "View",
+		"id":          sd.id,
+		"name":        sd.name,
+		"description": sd.description,
+		"actions":     entityObjects(sd.actions),
+		"action": fieldResolver(func(args map[string]interface{}) (interface{}, error) {
+			if action, ok := sd.actions[stringArg(args, "id")]; ok {
+				return action.object(), nil
+			}
+			return nil, nil
+		}),
+		"alerts": entityObjects(sd.alerts),
+	}
+	if !sd.isRepository {
+		var connections []object
+		for _, connection := range sd.connections {
+			connections = append(connections, object{
+				"__typename": "ViewConnection",
+				"repository": object{"__typename": "Repository", "name": connection.repositoryName},
+				"filter":     connection.filter,
+			})
+		}
+		obj["connections"] = connections
+		return obj
+	}
+
+	obj["__typename"] = "Repository"
+	obj["timeBasedRetention"] = optionalFloat(sd.timeBasedRetention)
+	obj["ingestSizeBasedRetention"] = optionalFloat(sd.ingestSizeBasedRetention)
+	obj["storageSizeBasedRetention"] = optionalFloat(sd.storageSizeBasedRetention)
+	obj["compressedByteSize"] = 0
+	obj["uncompressedByteSize"] = 0
+	obj["datasources"] = []object{}
+
+	var parsers []object
+	for _, p := range sortedParsers(sd.parsers) {
+		parsers = append(parsers, p.object())
+	}
+	obj["parsers"] = parsers
+	obj["parser"] = fieldResolver(func(args map[string]interface{}) (interface{}, error) {
+		if p, ok := sd.parsers[stringArg(args, "name")]; ok {
+			return p.object(), nil
+		}
+		return nil, nil
+	})
+
+	var tokens []object
+	for _, token := range sd.ingestTokens {
+		tokens = append(tokens, sd.ingestTokenObject(token))
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i]["name"].(string) < tokens[j]["name"].(string)
+	})
+	obj["ingestTokens"] = tokens
+	return obj
+}
+
+func (p *parser) object() object {
+	return object{
+		"__typename": "Parser",
+		"id":         p.id,
+		"name":       p.name,
+		"sourceCode": p.sourceCode,
+		"testData":   p.testData,
+		"tagFields":  p.tagFields,
+		"isBuiltIn":  false,
+	}
+}
+
+func sortedParsers(parsers map[string]*parser) []*parser {
+	sorted := make([]*parser, 0, len(parsers))
+	for _, p := range parsers {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].name < sorted[j].name
+	})
+	return sorted
+}
+
+func (sd *searchDomain) ingestTokenObject(token *ingestToken) object {
+	obj := object{
+		"__typename": "IngestToken",
+		"name":       token.name,
+		"token":      token.token,
+		"parser":     nil,
+	}
+	if token.parser != "" {
+		obj["parser"] = object{"__typename": "Parser", "name": token.parser}
+	}
+	return obj
+}
+
+func (e *entity) object() object {
+	obj := object{"__typename": e.typeName, "id": e.id}
+	for name, value := range e.fields {
+		obj[name] = value
+	}
+	return obj
+}
+
+func entityObjects(entities map[string]*entity) []object {
+	var objects []object
+	for _, e := range entities {
+		objects = append(objects, e.object())
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i]["name"].(string) < objects[j]["name"].(string)
+	})
+	return objects
+}
+
+func booleanResult() object {
+	return object{"__typename": "BooleanResultType", "result": true}
+}
+
+func (s *Server) addSearchDomain(sd *searchDomain) error {
+	key := strings.ToLower(sd.name)
+	if sd.name == "" {
+		return fmt.Errorf("the name of a search domain cannot be empty")
+	}
+	if _, exists := s.searchDomains[key]; exists {
+		return fmt.Errorf("a search domain with the name %q already exists", sd.name)
+	}
+	sd.id = s.nextID()
+	sd.actions = map[string]*entity{}
+	sd.alerts = map[string]*entity{}
+	s.searchDomains[key] = sd
+	return nil
+}
+
+func (s *Server) createRepository(args map[string]interface{}) (interface{}, error) {
+	repository := &searchDomain{
+		name:         stringArg(args, "name"),
+		isRepository: true,
+		parsers:      map[string]*parser{},
+		ingestTokens: map[string]*ingestToken{},
+	}
+	if err := s.addSearchDomain(repository); err != nil {
+		return nil, err
+	}
+	return object{
+		"__typename": "CreateRepositoryMutation",
+		"repository": s.searchDomainObject(repository),
+	}, nil
+}
+
+func (s *Server) viewConnections(args map[string]interface{}) ([]viewConnection, error) {
+	var connections []viewConnection
+	list, _ := args["connections"].([]interface{})
+	for _, item := range list {
+		input, _ := item.(map[string]interface{})
+		connection := viewConnection{
+			repositoryName: stringArg(input, "repositoryName"),
+			filter:         stringArg(input, "filter"),
+		}
+		if _, err := s.repository(connection.repositoryName); err != nil {
+			return nil, err
+		}
+		connections = append(connections, connection)
+	}
+	return connections, nil
+}
+
+func (s *Server) createView(args map[string]interface{}) (interface{}, error) {
+	connections, err := s.viewConnections(args)
+	if err != nil {
+		return nil, err
+	}
+	view := &searchDomain{
+		name:        stringArg(args, "name"),
+		description: stringArg(args, "description"),
+		connections: connections,
+	}
+	if err := s.addSearchDomain(view); err != nil {
+		return nil, err
+	}
+	return s.searchDomainObject(view), nil
+}
+
+func (s *Server) updateView(args map[string]interface{}) (interface{}, error) {
+	view, err := s.view(stringArg(args, "viewName"))
+	if err != nil {
+		return nil, err
+	}
+	connections, err := s.viewConnections(args)
+	if err != nil {
+		return nil, err
+	}
+	view.connections = connections
+	return s.searchDomainObject(view), nil
+}
+
+func (s *Server) deleteSearchDomain(args map[string]interface{}) (interface{}, error) {
+	sd, err := s.searchDomain(stringArg(args, "name"))
+	if err != nil {
+		return nil, err
+	}
+	delete(s.searchDomains, strings.ToLower(sd.name))
+	return booleanResult(), nil
+}
+
+func (s *Server) updateDescription(args map[string]interface{}) (interface{}, error) {
+	sd, err := s.searchDomain(stringArg(args, "name"))
+	if err != nil {
+		return nil, err
+	}
+	sd.description = stringArg(args, "newDescription")
+	return booleanResult(), nil
+}
+
+func (s *Server) updateRetention(args map[string]interface{}) (interface{}, error) {
+	repository, err := s.repository(stringArg(args, "repositoryName"))
+	if err != nil {
+		return nil, err
+	}
+	// Only the retention settings passed as arguments are changed, and null removes the retention setting
+	for name, retention := range map[string]*float64{
+		"timeBasedRetention":        &repository.timeBasedRetention,
+		"ingestSizeBasedRetention":  &repository.ingestSizeBasedRetention,
+		"storageSizeBasedRetention": &repository.storageSizeBasedRetention,
+	} {
+		if _, ok := args[name]; ok {
+			*retention = floatArg(args, name)
+		}
+	}
+	return booleanResult(), nil
+}
+
+func (s *Server) createParser(args map[string]interface{}) (interface{}, error) {
+	input := inputArg(args)
+	repository, err := s.repository(stringArg(input, "repositoryName"))
+	if err != nil {
+		return nil, err
+	}
+	name := stringArg(input, "name")
+	existing, exists := repository.parsers[name]
+	if exists && !boolArg(input, "force") {
+		return nil, fmt.Errorf("a parser with the name %q already exists", name)
+	}
+	p := &parser{
+		name:       name,
+		sourceCode: stringArg(input, "sourceCode"),
+		testData:   stringListArg(input, "testData"),
+		tagFields:  stringListArg(input, "tagFields"),
+	}
+	if exists {
+		p.id = existing.id
+	} else {
+		p.id = s.nextID()
+	}
+	repository.parsers[name] = p
+	return p.object(), nil
+}
+
+func (s *Server) removeParser(args map[string]interface{}) (interface{}, error) {
+	input := inputArg(args)
+	repository, err := s.repository(stringArg(input, "repositoryName"))
+	if err != nil {
+		return nil, err
+	}
+	for name, p := range repository.parsers {
+		if p.id == stringArg(input, "id") {
+			delete(repository.parsers, name)
+			return booleanResult(), nil
+		}
+	}
+	return nil, fmt.Errorf("parser with id %q does not exist", stringArg(input, "id"))
+}
+
+// testParser reports every test event as parsed, as the fake does not run parser scripts
+func (s *Server) testParser(args map[string]interface{}) (interface{}, error) {
+	input := inputArg(args)
+	if _, err := s.repository(stringArg(input, "repositoryName")); err != nil {
+		return nil, err
+	}
+	results := []object{}
+	for range stringListArg(input, "testData") {
+		results = append(results, object{"__typename": "ParserTestResult", "errorMessage": nil})
+	}
+	return object{"__typename": "TestParserResult", "results": results}, nil
+}
+
+func (s *Server) addIngestToken(args map[string]interface{}) (interface{}, error) {
+	input := inputArg(args)
+	repository, err := s.repository(stringArg(input, "repositoryName"))
+	if err != nil {
+		return nil, err
+	}
+	name := stringArg(input, "name")
+	if _, exists := repository.ingestTokens[name]; exists {
+		return nil, fmt.Errorf("an ingest token with the name %q already exists", name)
+	}
+	token := &ingestToken{
+		name:   name,
+		token:  "ingest-token-" + s.nextID(),
+		parser: stringArg(input, "parser"),
+	}
+	repository.ingestTokens[name] = token
+	return repository.ingestTokenObject(token), nil
+}
+
+func (s *Server) ingestToken(repositoryName, tokenName string) (*searchDomain, *ingestToken, error) {
+	repository, err := s.repository(repositoryName)
+	if err != nil {
+		return nil, nil, err
+	}
+	token, ok := repository.ingestTokens[tokenName]
+	if !ok {
+		return nil, nil, fmt.Errorf("ingest token %q does not exist", tokenName)
+	}
+	return repository, token, nil
+}
+
+func (s *Server) assignParserToIngestToken(args map[string]interface{}) (interface{}, error) {
+	input := inputArg(args)
+	_, token, err := s.ingestToken(stringArg(input, "repositoryName"), stringArg(input, "tokenName"))
+	if err != nil {
+		return nil, err
+	}
+	token.parser = stringArg(input, "parser")
+	return booleanResult(), nil
+}
+
+func (s *Server) unassignIngestToken(args map[string]interface{}) (interface{}, error) {
+	_, token, err := s.ingestToken(stringArg(args, "repositoryName"), stringArg(args, "tokenName"))
+	if err != nil {
+		return nil, err
+	}
+	token.parser = ""
+	return booleanResult(), nil
+}
+
+func (s *Server) removeIngestToken(args map[string]interface{}) (interface{}, error) {
+	repository, token, err := s.ingestToken(stringArg(args, "repositoryName"), stringArg(args, "name"))
+	if err != nil {
+		return nil, err
+	}
+	delete(repository.ingestTokens, token.name)
+	return booleanResult(), nil
+}
+
+// saveEntity creates or updates the action or alert in the given collection. Names are unique within a search domain.
+func (s *Server) saveEntity(entities map[string]*entity, typeName string, fieldNames []string, input map[string]interface{}, update bool) (*entity, error) {
+	e := &entity{typeName: typeName, fields: map[string]interface{}{}}
+	if update {
+		existing, ok := entities[stringArg(input, "id")]
+		if !ok || existing.typeName != typeName {
+			return nil, fmt.Errorf("%s with id %q does not exist", typeName, stringArg(input, "id"))
+		}
+		e.id = existing.id
+	} else {
+		e.id = s.nextID()
+	}
+	e.fields["name"] = stringArg(input, "name")
+	for _, name := range fieldNames {
+		e.fields[name] = storedValue(input[name])
+	}
+	for _, other := range entities {
+		if other.id != e.id && other.fields["name"] == e.fields["name"] {
+			return nil, fmt.Errorf("%s with the name %q already exists", typeName, e.fields["name"])
+		}
+	}
+	entities[e.id] = e
+	return e, nil
+}
+
+func (s *Server) saveAction(typeName string, input map[string]interface{}, update bool) (interface{}, error) {
+	view, err := s.searchDomain(stringArg(input, "viewName"))
+	if err != nil {
+		return nil, err
+	}
+	action, err := s.saveEntity(view.actions, typeName, actionFields[typeName], input, update)
+	if err != nil {
+		return nil, err
+	}
+	return action.object(), nil
+}
+
+func (s *Server) deleteAction(args map[string]interface{}) (interface{}, error) {
+	return s.deleteEntity(args, func(sd *searchDomain) map[string]*entity { return sd.actions })
+}
+
+func (s *Server) saveAlert(args map[string]interface{}, update bool) (interface{}, error) {
+	input := inputArg(args)
+	view, err := s.searchDomain(stringArg(input, "viewName"))
+	if err != nil {
+		return nil, err
+	}
+	for _, actionID := range stringListArg(input, "actions") {
+		if _, ok := view.actions[actionID]; !ok {
+			return nil, fmt.Errorf("action with id %q does not exist", actionID)
+		}
+	}
+	alert, err := s.saveEntity(view.alerts, "Alert", alertFields, input, update)
+	if err != nil {
+		return nil, err
+	}
+	alert.fields["timeOfLastTrigger"] = nil
+	alert.fields["isStarred"] = false
+	alert.fields["lastError"] = nil
+	return alert.object(), nil
+}
+
+func (s *Server) createAlert(args map[string]interface{}) (interface{}, error) {
+	return s.saveAlert(args, false)
+}
+
+func (s *Server) updateAlert(args map[string]interface{}) (interface{}, error) {
+	return s.saveAlert(args, true)
+}
+
+func (s *Server) deleteAlert(args map[string]interface{}) (interface{}, error) {
+	return s.deleteEntity(args, func(sd *searchDomain) map[string]*entity { return sd.alerts })
+}
+
+func (s *Server) deleteEntity(args map[string]interface{}, entities func(*searchDomain) map[string]*entity) (interface{}, error) {
+	input := inputArg(args)
+	view, err := s.searchDomain(stringArg(input, "viewName"))
+	if err != nil {
+		return nil, err
+	}
+	id := stringArg(input, "id")
+	if _, ok := entities(view)[id]; !ok {
+		return nil, fmt.Errorf("entity with id %q does not exist", id)
+	}
+	delete(entities(view), id)
+	return true, nil
+}
+
+// storedValue converts input objects to GraphQL objects, so fields of lists such as the headers of webhook actions can
+// be selected
+func storedValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		obj := object{}
+		for name, field := range v {
+			obj[name] = storedValue(field)
+		}
+		return obj
+	case []interface{}:
+		if len(v) > 0 {
+			if _, isObject := v[0].(map[string]interface{}); isObject {
+				objects := make([]object, 0, len(v))
+				for _, item := range v {
+					objects = append(objects, storedValue(item).(object))
+				}
+				return objects
+			}
+		}
+	}
+	return value
+}
+
+func optionalFloat(value float64) interface{} {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
+
+func inputArg(args map[string]interface{}) map[string]interface{} {
+	input, _ := args["input"].(map[string]interface{})
+	if input == nil {
+		return map[string]interface{}{}
+	}
+	return input
+}
+
+func stringArg(args map[string]interface{}, name string) string {
+	value, _ := args[name].(string)
+	return value
+}
+
+func boolArg(args map[string]interface{}, name string) bool {
+	value, _ := args[name].(bool)
+	return value
+}
+
+func floatArg(args map[string]interface{}, name string) float64 {
+	switch value := args[name].(type) {
+	case json.Number:
+		f, _ := value.Float64()
+		return f
+	case float64:
+		return value
+	}
+	return 0
+}
+
+func stringListArg(args map[string]interface{}, name string) []string {
+	list, _ := args[name].([]interface{})
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides an in-process fake of the LogScale API, so the operator can be run against HumioExternalCluster
+// resources in integration tests without a real LogScale cluster.
+//
+// The fake keeps its state in memory and implements the subset of the API the operator uses to manage repositories,
+// views, parsers, ingest tokens, actions and alerts, to list users, and to check the status of external clusters. Any API token is
+// accepted. Queries for fields outside this subset fail with a GraphQL error naming the field.
+//
+// To use it with envtest, start a server, point a HumioExternalCluster at Server.URL and store any token in the
+// secret referenced by its apiTokenSecretName:
+//
+//	server := fake.NewServer()
+//	defer server.Close()
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	humioapi "github.com/humio/cli/api"
+)
+
+const (
+	// Version is the LogScale version reported by the status endpoint
+	Version = "1.118.0"
+	// Username is the user every API token belongs to
+	Username = "admin"
+	// Token is an API token which can be used with the fake. Any other non-empty token is accepted as well.
+	Token = "fake-api-token"
+)
+
+// Server is an in-process fake of the LogScale API
+type Server struct {
+	server *httptest.Server
+
+	mu            sync.Mutex
+	lastID        int
+	searchDomains map[string]*searchDomain
+	users         []string
+}
+
+// NewServer starts a fake LogScale API server. It must be closed when no longer used.
+func NewServer() *Server {
+	s := &Server{searchDomains: map[string]*searchDomain{}, users: []string{Username}}
+	s.server = httptest.NewServer(s)
+	return s
+}
+
+// URL returns the base URL of the server, which ends with a slash like the URLs of HumioExternalClusters
+func (s *Server) URL() string {
+	return s.server.URL + "/"
+}
+
+// Config returns a config for the Humio API client which connects to the server
+func (s *Server) Config() *humioapi.Config {
+	address, _ := url.Parse(s.URL())
+	return &humioapi.Config{Address: address, Token: Token}
+}
+
+// AddUsers adds users with the given usernames, besides the user every API token belongs to
+func (s *Server) AddUsers(usernames ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = append(s.users, usernames...)
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// ServeHTTP serves the GraphQL API and the status endpoint
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/v1/status":
+		writeJSON(w, humioapi.StatusResponse{Status: "OK", Version: Version})
+	case "/graphql":
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || r.Header.Get("Authorization") == "Bearer " {
+			http.Error(w, "missing API token", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "GraphQL requests must be sent as POST", http.StatusMethodNotAllowed)
+			return
+		}
+		s.serveGraphQL(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+type graphQLResponse struct {
+	Data   interface{}    `json:"data"`
+	Errors []graphQLError `json:"errors,omitempty"`
+}
+
+func (s *Server) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var request graphQLRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("could not decode GraphQL request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	data, err := s.execute(request)
+	if err != nil {
+		writeJSON(w, graphQLResponse{Errors: []graphQLError{{Message: err.Error()}}})
+		return
+	}
+	writeJSON(w, graphQLResponse{Data: data})
+}
+
+func (s *Server) execute(request graphQLRequest) (interface{}, error) {
+	op, err := parseOperation(request.Query, request.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse query: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	root := s.queryRoot()
+	if op.mutation {
+		root = s.mutationRoot()
+	}
+	return execute(op.selections, root)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) nextID() string {
+	s.lastID++
+	return fmt.Sprintf("fake-%d", s.lastID)
+}
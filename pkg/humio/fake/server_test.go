@@ -0,0 +1,226 @@
+package fake
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+	"github.com/humio/humio-operator/pkg/humio"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		document string
+		want     []string
+	}{
+		{`{viewer{username}}`, []string{"{", "viewer", "{", "username", "}", "}"}},
+		{`query($name:String!){repository(name: $name){id,name}}`, []string{"query", "(", "$", "name", ":", "String", "!", ")", "{", "repository", "(", "name", ":", "$", "name", ")", "{", "id", "name", "}", "}"}},
+		{`{a(x: "b,\"c", y: -1.5)}`, []string{"{", "a", "(", "x", ":", `"b,\"c"`, "y", ":", "-1.5", ")", "}"}},
+		{`{... on View{name}}`, []string{"{", "...", "on", "View", "{", "name", "}", "}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.document, func(t *testing.T) {
+			if got := tokenize(tt.document); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("tokenize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerRejectsMissingToken(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	resp, err := http.Post(server.URL()+"graphql", "application/json", strings.NewReader(`{"query":"{viewer{username}}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestServerRejectsUnsupportedFields(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	var query struct {
+		Viewer struct {
+			Username string
+			IsRoot   bool
+		}
+	}
+	err := humioapi.NewClient(*server.Config()).Query(&query, nil)
+	if err == nil || !strings.Contains(err.Error(), `Cannot query field "isRoot"`) {
+		t.Errorf("expected an error naming the unsupported field, got %v", err)
+	}
+}
+
+// TestServerWithOperatorClient manages entities through the client used by the operator, to make sure the fake
+// understands the queries it sends
+func TestServerWithOperatorClient(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	config := server.Config()
+	req := reconcile.Request{}
+	client := humio.NewClient(logr.Discard(), config, "")
+
+	status, err := client.Status(config, req)
+	if err != nil || status.IsDown() {
+		t.Fatalf("expected the server to be up, got %+v, %v", status, err)
+	}
+	if username, err := client.TestAPIToken(config, req); err != nil || username != Username {
+		t.Fatalf("expected username %s, got %q, %v", Username, username, err)
+	}
+	if err := client.TestOrganizationAPIToken(config, req); err != nil {
+		t.Fatalf("expected the token to be accepted, got %v", err)
+	}
+
+	hr := &humiov1alpha1.HumioRepository{Spec: humiov1alpha1.HumioRepositorySpec{
+		Name:              "logs",
+		Description:       "application logs",
+		Retention:         humiov1alpha1.HumioRetention{TimeInDays: 30, IngestSizeInGB: 10},
+		AllowDataDeletion: true,
+	}}
+	if _, err := client.AddRepository(config, req, hr); err != nil {
+		t.Fatalf("could not add repository: %s", err)
+	}
+	if _, err := client.AddRepository(config, req, hr); err == nil {
+		t.Errorf("expected an error when adding a repository twice")
+	}
+	repository, err := client.UpdateRepository(config, req, hr)
+	if err != nil {
+		t.Fatalf("could not update repository: %s", err)
+	}
+	if repository.Description != "application logs" || repository.RetentionDays != 30 || repository.IngestRetentionSizeGB != 10 || repository.StorageRetentionSizeGB != 0 {
+		t.Errorf("unexpected repository %+v", repository)
+	}
+
+	hv := &humiov1alpha1.HumioView{Spec: humiov1alpha1.HumioViewSpec{
+		Name:        "all-logs",
+		Connections: []humiov1alpha1.HumioViewConnection{{RepositoryName: "logs", Filter: "*"}},
+	}}
+	if _, err := client.AddView(config, req, hv); err != nil {
+		t.Fatalf("could not add view: %s", err)
+	}
+	hv.Spec.Connections[0].Filter = "level=error"
+	view, err := client.UpdateView(config, req, hv)
+	if err != nil {
+		t.Fatalf("could not update view: %s", err)
+	}
+	if len(view.Connections) != 1 || view.Connections[0].RepoName != "logs" || view.Connections[0].Filter != "level=error" {
+		t.Errorf("unexpected view %+v", view)
+	}
+
+	hp := &humiov1alpha1.HumioParser{Spec: humiov1alpha1.HumioParserSpec{
+		Name:           "json",
+		RepositoryName: "logs",
+		ParserScript:   "parseJson()",
+		TestData:       []string{`{"level":"info"}`},
+	}}
+	if _, err := client.AddParser(config, req, hp); err != nil {
+		t.Fatalf("could not add parser: %s", err)
+	}
+	hp.Spec.ParserScript = "parseJson() | level := lower(level)"
+	if _, err := client.UpdateParser(config, req, hp); err != nil {
+		t.Fatalf("could not update parser: %s", err)
+	}
+	parser, err := client.GetParser(config, req, hp)
+	if err != nil || parser.Script != hp.Spec.ParserScript || len(parser.Tests) != 1 {
+		t.Errorf("unexpected parser %+v, %v", parser, err)
+	}
+	if parseErrors, err := client.TestParser(config, req, hp); err != nil || len(parseErrors) != 0 {
+		t.Errorf("expected the test data to parse, got %v, %v", parseErrors, err)
+	}
+
+	hit := &humiov1alpha1.HumioIngestToken{Spec: humiov1alpha1.HumioIngestTokenSpec{
+		Name:           "shipper",
+		RepositoryName: "logs",
+		ParserName:     "json",
+	}}
+	token, err := client.AddIngestToken(config, req, hit)
+	if err != nil || token.Token == "" || token.AssignedParser != "json" {
+		t.Fatalf("unexpected ingest token %+v, %v", token, err)
+	}
+	hit.Spec.ParserName = ""
+	if token, err = client.UpdateIngestToken(config, req, hit); err != nil || token.AssignedParser != "" {
+		t.Errorf("expected the parser to be unassigned, got %+v, %v", token, err)
+	}
+	if err := client.DeleteIngestToken(config, req, hit); err != nil {
+		t.Errorf("could not delete ingest token: %s", err)
+	}
+	if token, err = client.GetIngestToken(config, req, hit); err != nil || token.Name != "" {
+		t.Errorf("expected the ingest token to be deleted, got %+v, %v", token, err)
+	}
+
+	ha := &humiov1alpha1.HumioAction{Spec: humiov1alpha1.HumioActionSpec{
+		Name:     "webhook",
+		ViewName: "all-logs",
+		WebhookProperties: &humiov1alpha1.HumioActionWebhookProperties{
+			BodyTemplate: "{alert_name}",
+			Headers:      map[string]string{"Content-Type": "application/json"},
+			Method:       http.MethodPost,
+			Url:          "https://example.com/hook",
+		},
+	}}
+	createdAction, err := client.AddAction(config, req, ha)
+	if err != nil {
+		t.Fatalf("could not add action: %s", err)
+	}
+	ha.Annotations = map[string]string{humio.ActionIdentifierAnnotation: createdAction.ID}
+	ha.Spec.WebhookProperties.Url = "https://example.com/other-hook"
+	if _, err := client.UpdateAction(config, req, ha); err != nil {
+		t.Fatalf("could not update action: %s", err)
+	}
+	action, err := client.GetAction(config, req, ha)
+	if err != nil || action.WebhookAction.Url != "https://example.com/other-hook" || len(action.WebhookAction.Headers) != 1 {
+		t.Errorf("unexpected action %+v, %v", action, err)
+	}
+
+	hal := &humiov1alpha1.HumioAlert{Spec: humiov1alpha1.HumioAlertSpec{
+		Name:               "errors",
+		ViewName:           "all-logs",
+		Query:              humiov1alpha1.HumioQuery{QueryString: "level=error", Start: "1h"},
+		ThrottleTimeMillis: 60000,
+		Actions:            []string{"webhook"},
+	}}
+	if _, err := client.AddAlert(config, req, hal); err != nil {
+		t.Fatalf("could not add alert: %s", err)
+	}
+	hal.Spec.Silenced = true
+	if _, err := client.UpdateAlert(config, req, hal); err != nil {
+		t.Fatalf("could not update alert: %s", err)
+	}
+	alert, err := client.GetAlert(config, req, hal)
+	if err != nil || alert.Enabled || alert.ThrottleTimeMillis != 60000 || len(alert.Actions) != 1 || alert.Actions[0] != action.ID {
+		t.Errorf("unexpected alert %+v, %v", alert, err)
+	}
+	if err := client.DeleteAlert(config, req, hal); err != nil {
+		t.Errorf("could not delete alert: %s", err)
+	}
+	if err := client.DeleteAction(config, req, ha); err != nil {
+		t.Errorf("could not delete action: %s", err)
+	}
+
+	if err := client.DeleteParser(config, req, hp); err != nil {
+		t.Errorf("could not delete parser: %s", err)
+	}
+	if _, err := client.GetParser(config, req, hp); !errors.As(err, &humioapi.EntityNotFound{}) {
+		t.Errorf("expected the parser to be deleted, got %v", err)
+	}
+	if err := client.DeleteView(config, req, hv); err != nil {
+		t.Errorf("could not delete view: %s", err)
+	}
+	if err := client.DeleteRepository(config, req, hr); err != nil {
+		t.Errorf("could not delete repository: %s", err)
+	}
+	if repository, err := client.GetRepository(config, req, hr); err != nil || repository.Name != "" {
+		t.Errorf("expected the repository to be deleted, got %+v, %v", repository, err)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+type fault int
+
+const (
+	faultNone fault = iota
+	faultTimeout
+	faultServerError
+	faultSlowCall
+)
+
+func (f fault) String() string {
+	switch f {
+	case faultTimeout:
+		return "timeout"
+	case faultServerError:
+		return "server error"
+	case faultSlowCall:
+		return "slow call"
+	}
+	return "none"
+}
+
+// faultInjectionResponseBody is the body of the responses to calls failed with a server error
+const faultInjectionResponseBody = "fault injected by humio-operator"
+
+// faultInjector makes a fraction of the calls against the Humio API time out, fail with a server error or take longer
+// than they otherwise would, so the recovery of the operator and the alerting on it can be tested. Faults are spread
+// evenly across the three kinds. A nil faultInjector is valid and never injects any faults.
+type faultInjector struct {
+	rate  float64
+	delay time.Duration
+
+	mutex  sync.Mutex
+	random *rand.Rand
+}
+
+func newFaultInjector(rate float64, delay time.Duration) *faultInjector {
+	if rate <= 0 {
+		return nil
+	}
+	return &faultInjector{
+		rate:   rate,
+		delay:  delay,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())), // #nosec G404
+	}
+}
+
+// next picks the fault to inject into the next call
+func (f *faultInjector) next() fault {
+	if f == nil {
+		return faultNone
+	}
+	f.mutex.Lock()
+	r := f.random.Float64()
+	f.mutex.Unlock()
+	if r >= f.rate {
+		return faultNone
+	}
+	return faultTimeout + fault(r/f.rate*3)
+}
+
+// inject picks the fault to inject into the next call against the Humio cluster running on the given host. Slow calls
+// are delayed before returning nil, so the call is sent as usual. For timeouts and server errors, a transport is
+// returned which does not connect to Humio, but answers the call from within the operator.
+func (f *faultInjector) inject(logger logr.Logger, host string) *http.Transport {
+	injected := f.next()
+	if injected == faultNone {
+		return nil
+	}
+	logger.Info("injecting fault into call against the Humio API", "host", host, "fault", injected.String())
+	if injected == faultSlowCall {
+		time.Sleep(f.delay)
+		return nil
+	}
+	return newFaultTransport(injected, f.delay)
+}
+
+// newFaultTransport returns a transport whose connections are served by serveFault. Calls with a timeout fault give up
+// after the given delay while waiting for a response.
+func newFaultTransport(injected fault, delay time.Duration) *http.Transport {
+	dial := func(_ context.Context, _, _ string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go serveFault(server, injected)
+		return client, nil
+	}
+	return &http.Transport{
+		DialContext:           dial,
+		DialTLSContext:        dial,
+		ResponseHeaderTimeout: delay,
+		DisableKeepAlives:     true,
+	}
+}
+
+// serveFault reads a single request from the given connection and answers it with a 503 response, or not at all in
+// case of a timeout fault
+func serveFault(conn net.Conn, injected fault) {
+	defer conn.Close()
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil {
+		return
+	}
+	// The connection is synchronous, so the request body must be read for the client to finish sending the request
+	_, _ = io.Copy(io.Discard, req.Body)
+	if injected == faultTimeout {
+		// Hold the connection until the client gives up on the request and closes it
+		_, _ = io.Copy(io.Discard, conn)
+		return
+	}
+	resp := &http.Response{
+		StatusCode:    http.StatusServiceUnavailable,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"text/plain"}},
+		Body:          io.NopCloser(strings.NewReader(faultInjectionResponseBody)),
+		ContentLength: int64(len(faultInjectionResponseBody)),
+		Close:         true,
+	}
+	_ = resp.Write(conn)
+}
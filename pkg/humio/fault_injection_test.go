@@ -0,0 +1,99 @@
+package humio
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestFaultInjectorNext(t *testing.T) {
+	if injector := newFaultInjector(0, time.Second); injector != nil || injector.next() != faultNone {
+		t.Fatal("expected fault injection to be disabled")
+	}
+
+	injector := newFaultInjector(0.3, time.Second)
+	injector.random = rand.New(rand.NewSource(1))
+	counts := map[fault]int{}
+	for i := 0; i < 30000; i++ {
+		counts[injector.next()]++
+	}
+	if counts[faultNone] < 20000 || counts[faultNone] > 22000 {
+		t.Errorf("expected about 70%% of the calls to be left alone, got %d of 30000", counts[faultNone])
+	}
+	for _, f := range []fault{faultTimeout, faultServerError, faultSlowCall} {
+		if counts[f] < 2700 || counts[f] > 3300 {
+			t.Errorf("expected about 10%% of the calls to get a %s, got %d of 30000", f, counts[f])
+		}
+	}
+}
+
+func TestFaultTransport(t *testing.T) {
+	tests := []struct {
+		fault      fault
+		wantStatus int
+		wantErr    bool
+	}{
+		{fault: faultServerError, wantStatus: http.StatusServiceUnavailable},
+		{fault: faultTimeout, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fault.String(), func(t *testing.T) {
+			for _, address := range []string{"http://humio.example.com/graphql", "https://humio.example.com/graphql"} {
+				client := &http.Client{Transport: newFaultTransport(tt.fault, 50*time.Millisecond)}
+				resp, err := client.Post(address, "application/json", strings.NewReader(`{"query":"{viewer{username}}"}`))
+				if tt.wantErr {
+					var netErr net.Error
+					if !errors.As(err, &netErr) || !netErr.Timeout() {
+						t.Errorf("expected a timeout from %s, got %v", address, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("expected a response from %s, got %s", address, err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus || string(body) != faultInjectionResponseBody {
+					t.Errorf("expected status %d from %s, got %d with body %q", tt.wantStatus, address, resp.StatusCode, body)
+				}
+			}
+		})
+	}
+}
+
+func TestClientWithFaultInjection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"status":"OK","version":"1.118.0"}`)
+	}))
+	defer server.Close()
+	address, _ := url.Parse(server.URL + "/")
+	config := &humioapi.Config{Address: address, Token: "token"}
+
+	client := NewClient(logr.Discard(), config, "test").WithFaultInjection(1, 20*time.Millisecond)
+	client.faultInjector.random = rand.New(rand.NewSource(1))
+	outcomes := map[bool]int{}
+	for i := 0; i < 30; i++ {
+		_, err := client.Status(config, reconcile.Request{})
+		outcomes[err == nil]++
+	}
+	// Slow calls still succeed, while timeouts and server errors fail
+	if outcomes[true] == 0 || outcomes[false] == 0 {
+		t.Errorf("expected both failed and delayed calls, got %d successful and %d failed calls", outcomes[true], outcomes[false])
+	}
+
+	client.WithFaultInjection(0, 0)
+	if _, err := client.Status(config, reconcile.Request{}); err != nil {
+		t.Errorf("expected call to succeed without fault injection, got %s", err)
+	}
+}
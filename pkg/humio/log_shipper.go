@@ -0,0 +1,233 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	logShipperBufferSize    = 10000
+	logShipperBatchSize     = 500
+	logShipperFlushInterval = 5 * time.Second
+)
+
+// HumioIngestLogShipper ships the logs and audit trail of the operator to a Humio repository using the unstructured
+// ingest API. Each line is a JSON object, so the ingest token must use a parser which parses JSON. Lines are buffered
+// until a target is set and sent in batches in the background. Lines are dropped if the buffer is full.
+type HumioIngestLogShipper struct {
+	// logger is used to report errors shipping lines, and must not ship its own lines
+	logger logr.Logger
+	lines  chan []byte
+	ready  chan struct{}
+
+	mu         sync.RWMutex
+	url        string
+	token      string
+	ca         string
+	httpClient *http.Client
+}
+
+// NewHumioIngestLogShipper returns a log shipper which holds lines until SetTarget is called
+func NewHumioIngestLogShipper(logger logr.Logger) *HumioIngestLogShipper {
+	s := &HumioIngestLogShipper{
+		logger: logger.WithName("log-shipper"),
+		lines:  make(chan []byte, logShipperBufferSize),
+		ready:  make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// SetTarget sets the Humio cluster and ingest token lines are shipped to. The CA certificate is used to verify the
+// certificate of the Humio cluster, and may be empty if the cluster does not use TLS or uses a public CA.
+func (s *HumioIngestLogShipper) SetTarget(url, token, caCertificatePEM string) error {
+	url = strings.TrimSuffix(url, "/") + "/api/v1/ingest/humio-unstructured"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.url == url && s.token == token && s.ca == caCertificatePEM {
+		return nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caCertificatePEM != "" {
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM([]byte(caCertificatePEM)) {
+			return fmt.Errorf("unable to parse CA certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	}
+	first := s.url == ""
+	s.url, s.token, s.ca = url, token, caCertificatePEM
+	s.httpClient = &http.Client{Timeout: 30 * time.Second, Transport: transport}
+	if first {
+		close(s.ready)
+	}
+	return nil
+}
+
+// WrapCore returns a core which writes to the given core and also ships the entries. It can be passed to
+// zap.WrapCore.
+func (s *HumioIngestLogShipper) WrapCore(core zapcore.Core) zapcore.Core {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    "func",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+	return zapcore.NewTee(core, &logShipperCore{
+		LevelEnabler: core,
+		encoder:      zapcore.NewJSONEncoder(encoderConfig),
+		shipper:      s,
+	})
+}
+
+// Write ships the audit record, which makes the log shipper an AuditSink
+func (s *HumioIngestLogShipper) Write(record AuditRecord) {
+	line, err := json.Marshal(struct {
+		Type string `json:"type"`
+		AuditRecord
+	}{"audit", record})
+	if err != nil {
+		s.logger.Error(err, "unable to encode audit record")
+		return
+	}
+	s.enqueue(line)
+}
+
+func (s *HumioIngestLogShipper) enqueue(line []byte) {
+	select {
+	case s.lines <- line:
+	default:
+		// Logging each dropped line would only add to the lines which cannot be shipped
+	}
+}
+
+func (s *HumioIngestLogShipper) run() {
+	<-s.ready
+	ticker := time.NewTicker(logShipperFlushInterval)
+	defer ticker.Stop()
+	var batch []string
+	for {
+		select {
+		case line := <-s.lines:
+			batch = append(batch, string(line))
+			if len(batch) < logShipperBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := s.send(batch); err != nil {
+			s.logger.Error(err, fmt.Sprintf("unable to ship %d log lines", len(batch)))
+		}
+		batch = nil
+	}
+}
+
+type humioUnstructuredEvents struct {
+	Fields   map[string]string `json:"fields"`
+	Messages []string          `json:"messages"`
+}
+
+func (s *HumioIngestLogShipper) send(lines []string) error {
+	body, err := json.Marshal([]humioUnstructuredEvents{{
+		Fields:   map[string]string{"source": "humio-operator"},
+		Messages: lines,
+	}})
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	url, token, httpClient := s.url, s.token, s.httpClient
+	s.mu.RUnlock()
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected response from %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// logShipperCore is a zapcore.Core which encodes entries as JSON and passes them to the log shipper
+type logShipperCore struct {
+	zapcore.LevelEnabler
+	encoder zapcore.Encoder
+	shipper *HumioIngestLogShipper
+}
+
+func (c *logShipperCore) With(fields []zapcore.Field) zapcore.Core {
+	encoder := c.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	return &logShipperCore{LevelEnabler: c.LevelEnabler, encoder: encoder, shipper: c.shipper}
+}
+
+func (c *logShipperCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *logShipperCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	line := bytes.TrimSuffix(buf.Bytes(), []byte(zapcore.DefaultLineEnding))
+	c.shipper.enqueue(append([]byte(nil), line...))
+	buf.Free()
+	return nil
+}
+
+func (c *logShipperCore) Sync() error {
+	return nil
+}
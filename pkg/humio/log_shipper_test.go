@@ -0,0 +1,76 @@
+package humio
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestHumioIngestLogShipper(t *testing.T) {
+	batches := make(chan []humioUnstructuredEvents, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/ingest/humio-unstructured" || r.Header.Get("Authorization") != "Bearer ingest-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var events []humioUnstructuredEvents
+		if err := json.Unmarshal(body, &events); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batches <- events
+	}))
+	defer server.Close()
+
+	shipper := NewHumioIngestLogShipper(logr.Discard())
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zap.InfoLevel)
+	logger := zap.New(core).WithOptions(zap.WrapCore(shipper.WrapCore)).With(zap.String("Request.Name", "example"))
+	// Lines logged before the target is set are held until it is
+	logger.Info("reconciling")
+	shipper.Write(AuditRecord{Kind: "HumioRepository", Operation: "create"})
+	if err := shipper.SetTarget(server.URL+"/", "ingest-token", ""); err != nil {
+		t.Fatalf("SetTarget() error = %v", err)
+	}
+
+	var lines []map[string]interface{}
+	timeout := time.After(logShipperFlushInterval * 3)
+	for len(lines) < 2 {
+		select {
+		case events := <-batches:
+			if events[0].Fields["source"] != "humio-operator" {
+				t.Errorf("expected source field to be set, got %v", events[0].Fields)
+			}
+			for _, message := range events[0].Messages {
+				var line map[string]interface{}
+				if err := json.Unmarshal([]byte(message), &line); err != nil {
+					t.Fatalf("expected line to be JSON, got %s", message)
+				}
+				lines = append(lines, line)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for lines to be shipped, got %v", lines)
+		}
+	}
+
+	if lines[0]["msg"] != "reconciling" || lines[0]["Request.Name"] != "example" {
+		t.Errorf("unexpected log line %v", lines[0])
+	}
+	if lines[1]["type"] != "audit" || lines[1]["kind"] != "HumioRepository" {
+		t.Errorf("unexpected audit line %v", lines[1])
+	}
+}
+
+func TestHumioIngestLogShipperInvalidCACertificate(t *testing.T) {
+	shipper := NewHumioIngestLogShipper(logr.Discard())
+	if err := shipper.SetTarget("https://humio.example.com", "ingest-token", "not a certificate"); err == nil {
+		t.Errorf("SetTarget() expected error for invalid CA certificate")
+	}
+}
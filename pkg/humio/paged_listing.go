@@ -0,0 +1,86 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"fmt"
+
+	graphql "github.com/cli/shurcooL-graphql"
+	humioapi "github.com/humio/cli/api"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Users are listed a page at a time, as clusters can have many thousands of users. The other listings the operator
+// uses, such as the actions, alerts and parsers of a view or repository, are returned in full by the API, which offers
+// no paging arguments for them.
+
+// listPageSize is the number of entries requested per page
+const listPageSize = 100
+
+// usersPage is a single page of users, along with the total number of users matching the search
+type usersPage struct {
+	PageInfo struct {
+		TotalNumberOfRows int
+	}
+	Page []humioapi.User
+}
+
+// getUsersPage returns the given page of users matching the search, or of all users if search is empty
+func (h *ClientConfig) getUsersPage(config *humioapi.Config, req reconcile.Request, search string, pageNumber, pageSize int) (usersPage, error) {
+	var query struct {
+		UsersPage usersPage `graphql:"usersPage(search: $search, pageNumber: $pageNumber, pageSize: $pageSize)"`
+	}
+	var searchArg *graphql.String
+	if search != "" {
+		searchArg = graphql.NewString(graphql.String(search))
+	}
+	err := h.GetHumioClient(config, req).Query(&query, map[string]interface{}{
+		"search":     searchArg,
+		"pageNumber": graphql.Int(pageNumber),
+		"pageSize":   graphql.Int(pageSize),
+	})
+	return query.UsersPage, err
+}
+
+// listUsers returns all users matching the search, or all users if search is empty, fetching them a page at a time
+func (h *ClientConfig) listUsers(config *humioapi.Config, req reconcile.Request, search string) ([]humioapi.User, error) {
+	var users []humioapi.User
+	for pageNumber := 1; ; pageNumber++ {
+		page, err := h.getUsersPage(config, req, search, pageNumber, listPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("could not list page %d of users: %w", pageNumber, err)
+		}
+		users = append(users, page.Page...)
+		if len(page.Page) < listPageSize || len(users) >= page.PageInfo.TotalNumberOfRows {
+			return users, nil
+		}
+	}
+}
+
+// getUser returns the user with the given username
+func (h *ClientConfig) getUser(config *humioapi.Config, req reconcile.Request, username string) (humioapi.User, error) {
+	users, err := h.listUsers(config, req, username)
+	if err != nil {
+		return humioapi.User{}, err
+	}
+	for _, user := range users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return humioapi.User{}, humioapi.ErrUserNotFound
+}
@@ -0,0 +1,54 @@
+package humio
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/humio/humio-operator/pkg/humio/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestListUsersFetchesEveryPage(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	for i := 0; i < 2*listPageSize+10; i++ {
+		server.AddUsers(fmt.Sprintf("user-%03d", i))
+	}
+	counting := newCountingServer(server)
+	defer counting.Close()
+	config := counting.config()
+	req := reconcile.Request{}
+	h := NewClient(logr.Discard(), config, "")
+
+	users, err := h.listUsers(config, req, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2*listPageSize+11 {
+		t.Errorf("expected %d users, got %d", 2*listPageSize+11, len(users))
+	}
+	if queries := atomic.LoadInt32(&counting.queries); queries != 3 {
+		t.Errorf("expected the users to be listed in 3 pages, got %d queries", queries)
+	}
+
+	count, err := h.CountUsers(config, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2*listPageSize+11 {
+		t.Errorf("expected %d users to be counted, got %d", 2*listPageSize+11, count)
+	}
+
+	user, err := h.getUser(config, req, "user-205")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "user-205" {
+		t.Errorf("expected user %s, got %s", "user-205", user.Username)
+	}
+	if _, err := h.getUser(config, req, "missing"); err == nil {
+		t.Error("expected an error looking up a user which does not exist")
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"sync"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+)
+
+const (
+	readCacheKindAction     = "action"
+	readCacheKindAlert      = "alert"
+	readCacheKindParser     = "parser"
+	readCacheKindRepository = "repository"
+	readCacheKindView       = "view"
+)
+
+// readCacheKey identifies a single Humio entity on a specific Humio cluster, as seen using a specific API token. The
+// scope is the name of the view or repository the entity belongs to, and is empty for entities that are not scoped.
+// The credential is a hash of the API token, so entries read with one API token are never returned to callers using
+// another API token which may not have access to them.
+type readCacheKey struct {
+	cluster, credential, kind, scope, name string
+}
+
+type readCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// readCache holds results of read calls against the Humio API for a short amount of time. A nil readCache is valid
+// and never returns any entries.
+type readCache struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[readCacheKey]readCacheEntry
+}
+
+func newReadCache(ttl time.Duration) *readCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &readCache{
+		ttl:     ttl,
+		entries: map[readCacheKey]readCacheEntry{},
+	}
+}
+
+func newReadCacheKey(config *humioapi.Config, kind, scope, name string) readCacheKey {
+	key := readCacheKey{kind: kind, scope: scope, name: name}
+	if config.Address != nil {
+		key.cluster = config.Address.String()
+	}
+	if config.Token != "" {
+		hash := sha256.Sum256([]byte(config.Token))
+		key.credential = hex.EncodeToString(hash[:])
+	}
+	return key
+}
+
+// get returns the cached value for the given key. Values are deep-copied when stored and returned, so callers always
+// get their own copy, including the slices and maps within it.
+func (c *readCache) get(key readCacheKey) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return deepCopy(entry.value), true
+}
+
+func (c *readCache) set(key readCacheKey, value interface{}) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[key] = readCacheEntry{
+		value:   deepCopy(value),
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// invalidate removes the entry for the given key, along with the listing of all entities in the same scope. Entries
+// read using any API token are removed, as the entity has changed for all of them.
+func (c *readCache) invalidate(key readCacheKey) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	scopeKey := key.scopeKey()
+	for k := range c.entries {
+		withCredential := k
+		withCredential.credential = key.credential
+		if withCredential == key || withCredential == scopeKey {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// invalidateCluster removes all entries for the given Humio cluster
+func (c *readCache) invalidateCluster(cluster string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key := range c.entries {
+		if key.cluster == cluster {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// scopeKey returns the key used to mark that all entities of the same kind within the same scope have been listed.
+// Humio does not allow entities with empty names, so the scope key never collides with the key of an entity.
+func (k readCacheKey) scopeKey() readCacheKey {
+	return readCacheKey{cluster: k.cluster, credential: k.credential, kind: k.kind, scope: k.scope}
+}
+
+// deepCopy returns a copy of the given value which shares no slices, maps or pointers with it
+func deepCopy(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return deepCopyValue(reflect.ValueOf(value)).Interface()
+}
+
+func deepCopyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Elem().Type())
+		copied.Elem().Set(deepCopyValue(v.Elem()))
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopyValue(iter.Value()))
+		}
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(deepCopyValue(v.Field(i)))
+			}
+		}
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(deepCopyValue(v.Elem()))
+		return copied
+	}
+	return v
+}
@@ -0,0 +1,116 @@
+package humio
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+)
+
+func TestReadCache(t *testing.T) {
+	address, _ := url.Parse("https://humio.example.com/")
+	config := &humioapi.Config{Address: address}
+	key := newReadCacheKey(config, readCacheKindAlert, "view", "alert")
+
+	cache := newReadCache(time.Hour)
+	cache.set(key, humioapi.Alert{Name: "alert"})
+	if cached, ok := cache.get(key); !ok || cached.(humioapi.Alert).Name != "alert" {
+		t.Errorf("expected cached alert, got %v", cached)
+	}
+
+	otherCluster, _ := url.Parse("https://other.example.com/")
+	if _, ok := cache.get(newReadCacheKey(&humioapi.Config{Address: otherCluster}, readCacheKindAlert, "view", "alert")); ok {
+		t.Error("expected cache entries to be scoped to a single cluster")
+	}
+
+	cache.set(key.scopeKey(), struct{}{})
+	cache.invalidate(key)
+	if _, ok := cache.get(key); ok {
+		t.Error("expected invalidated entry to be removed")
+	}
+	if _, ok := cache.get(key.scopeKey()); ok {
+		t.Error("expected listing of the scope to be invalidated along with the entry")
+	}
+
+	cache.ttl = -time.Second
+	cache.set(key, humioapi.Alert{Name: "alert"})
+	if _, ok := cache.get(key); ok {
+		t.Error("expected expired entry to be removed")
+	}
+}
+
+func TestReadCacheInvalidateCluster(t *testing.T) {
+	address, _ := url.Parse("https://humio.example.com/")
+	otherAddress, _ := url.Parse("https://other.example.com/")
+	config := &humioapi.Config{Address: address}
+	otherConfig := &humioapi.Config{Address: otherAddress}
+
+	cache := newReadCache(time.Hour)
+	alertKey := newReadCacheKey(config, readCacheKindAlert, "view", "alert")
+	parserKey := newReadCacheKey(config, readCacheKindParser, "repo", "parser")
+	otherKey := newReadCacheKey(otherConfig, readCacheKindAlert, "view", "alert")
+	cache.set(alertKey, humioapi.Alert{Name: "alert"})
+	cache.set(alertKey.scopeKey(), struct{}{})
+	cache.set(parserKey, humioapi.Parser{Name: "parser"})
+	cache.set(otherKey, humioapi.Alert{Name: "alert"})
+
+	cache.invalidateCluster(address.String())
+	for _, key := range []readCacheKey{alertKey, alertKey.scopeKey(), parserKey} {
+		if _, ok := cache.get(key); ok {
+			t.Errorf("expected entry %v of the invalidated cluster to be removed", key)
+		}
+	}
+	if _, ok := cache.get(otherKey); !ok {
+		t.Error("expected entries of other clusters to be kept")
+	}
+}
+
+func TestReadCacheDisabled(t *testing.T) {
+	cache := newReadCache(0)
+	key := readCacheKey{kind: readCacheKindParser, name: "parser"}
+	cache.set(key, humioapi.Parser{Name: "parser"})
+	if _, ok := cache.get(key); ok {
+		t.Error("expected disabled cache to never return entries")
+	}
+}
+
+func TestReadCacheCredentials(t *testing.T) {
+	address, _ := url.Parse("https://humio.example.com/")
+	config := &humioapi.Config{Address: address, Token: "token"}
+	otherConfig := &humioapi.Config{Address: address, Token: "other-token"}
+	key := newReadCacheKey(config, readCacheKindAlert, "view", "alert")
+	otherKey := newReadCacheKey(otherConfig, readCacheKindAlert, "view", "alert")
+
+	cache := newReadCache(time.Hour)
+	cache.set(key, humioapi.Alert{Name: "alert"})
+	if _, ok := cache.get(otherKey); ok {
+		t.Error("expected cache entries to be scoped to a single API token")
+	}
+
+	cache.set(otherKey, humioapi.Alert{Name: "alert"})
+	cache.set(otherKey.scopeKey(), struct{}{})
+	cache.invalidate(key)
+	for _, k := range []readCacheKey{key, otherKey, otherKey.scopeKey()} {
+		if _, ok := cache.get(k); ok {
+			t.Errorf("expected entry %+v to be invalidated for all API tokens", k)
+		}
+	}
+}
+
+func TestReadCacheCopies(t *testing.T) {
+	key := newReadCacheKey(&humioapi.Config{}, readCacheKindAlert, "view", "alert")
+	cache := newReadCache(time.Hour)
+
+	alert := humioapi.Alert{Name: "alert", Actions: []string{"email"}}
+	cache.set(key, alert)
+	alert.Actions[0] = "changed before get"
+
+	cached, _ := cache.get(key)
+	cached.(humioapi.Alert).Actions[0] = "changed after get"
+
+	cached, _ = cache.get(key)
+	if actions := cached.(humioapi.Alert).Actions; len(actions) != 1 || actions[0] != "email" {
+		t.Errorf("expected cached alert to be unaffected by changes to copies, got actions %v", actions)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// humioClientTimeout is the timeout the Humio API client applies to every call
+const humioClientTimeout = 30 * time.Second
+
+// The calls of the Humio API client take no context. Instead, the contexts of running reconciles are tracked by their
+// reconcile request, which is passed along with every call, so calls are not started once their reconcile is done and
+// are given up at the deadline of their reconcile.
+var (
+	reconcileContexts      = map[reconcile.Request][]*trackedContext{}
+	reconcileContextsMutex sync.Mutex
+)
+
+type trackedContext struct {
+	ctx context.Context
+}
+
+// TrackReconcileContext makes calls for the given reconcile request honor the given context of the reconcile, until
+// the returned function is called
+func TrackReconcileContext(ctx context.Context, req reconcile.Request) func() {
+	tracked := &trackedContext{ctx: ctx}
+	reconcileContextsMutex.Lock()
+	defer reconcileContextsMutex.Unlock()
+	reconcileContexts[req] = append(reconcileContexts[req], tracked)
+
+	return func() {
+		reconcileContextsMutex.Lock()
+		defer reconcileContextsMutex.Unlock()
+		contexts := reconcileContexts[req]
+		for i := range contexts {
+			if contexts[i] == tracked {
+				contexts = append(contexts[:i], contexts[i+1:]...)
+				break
+			}
+		}
+		if len(contexts) == 0 {
+			delete(reconcileContexts, req)
+			return
+		}
+		reconcileContexts[req] = contexts
+	}
+}
+
+// reconcileDeadline returns the deadline for calls made for the given reconcile request, or the zero time if there is
+// none, and an error if the reconcile is done. Custom resources of different kinds may be reconciled using the same
+// request at the same time, in which case the earliest deadline is used.
+func reconcileDeadline(req reconcile.Request) (time.Time, error) {
+	reconcileContextsMutex.Lock()
+	defer reconcileContextsMutex.Unlock()
+
+	var deadline time.Time
+	for _, tracked := range reconcileContexts[req] {
+		if err := tracked.ctx.Err(); err != nil {
+			return time.Time{}, err
+		}
+		if d, ok := tracked.ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+			deadline = d
+		}
+	}
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		return time.Time{}, context.DeadlineExceeded
+	}
+	return deadline, nil
+}
+
+// withDeadline wraps the given dial function so connection attempts are given up at the given deadline, and reads and
+// writes on the connections fail once the deadline has passed
+func withDeadline(dial dialContextFunc, deadline time.Time) dialContextFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.SetDeadline(deadline); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// newFailingTransport returns a transport which fails every call with the given error without connecting to Humio
+func newFailingTransport(err error) *http.Transport {
+	dial := func(_ context.Context, _, _ string) (net.Conn, error) {
+		return nil, err
+	}
+	return &http.Transport{
+		DialContext:       dial,
+		DialTLSContext:    dial,
+		DisableKeepAlives: true,
+	}
+}
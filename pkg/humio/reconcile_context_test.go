@@ -0,0 +1,72 @@
+package humio
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	humioapi "github.com/humio/cli/api"
+	"github.com/humio/humio-operator/pkg/humio/fake"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCallsHonorReconcileContext(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	counting := newCountingServer(server)
+	defer counting.Close()
+	config := counting.config()
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "cancelled"}}
+	h := NewClient(logr.Discard(), config, "")
+
+	// Calls are not started once the reconcile is done
+	ctx, cancel := context.WithCancel(context.Background())
+	untrack := TrackReconcileContext(ctx, req)
+	cancel()
+	if _, err := h.GetHumioClient(config, req).Views().List(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected call for a cancelled reconcile to fail, got %v", err)
+	}
+	if queries := atomic.LoadInt32(&counting.queries); queries != 0 {
+		t.Errorf("expected call for a cancelled reconcile not to reach Humio, got %d queries", queries)
+	}
+
+	// Calls for other requests, and calls once the reconcile has finished, are not affected
+	if _, err := h.GetHumioClient(config, reconcile.Request{}).Views().List(); err != nil {
+		t.Errorf("expected call for another request to succeed, got %v", err)
+	}
+	untrack()
+	if _, err := h.GetHumioClient(config, req).Views().List(); err != nil {
+		t.Errorf("expected call after the reconcile finished to succeed, got %v", err)
+	}
+}
+
+func TestCallsGiveUpAtReconcileDeadline(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	address, _ := url.Parse(slow.URL + "/")
+	config := &humioapi.Config{Address: address, Token: fake.Token}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "deadline"}}
+	h := NewClient(logr.Discard(), config, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	defer TrackReconcileContext(ctx, req)()
+	start := time.Now()
+	if _, err := h.GetHumioClient(config, req).Views().List(); err == nil {
+		t.Error("expected call to fail at the deadline of the reconcile")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected call to be given up at the deadline of the reconcile, took %s", elapsed)
+	}
+}
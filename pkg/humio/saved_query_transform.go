@@ -0,0 +1,32 @@
+package humio
+
+import (
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+// SavedQueryTransform returns the saved query described by the HumioSavedQuery, with the defaults Humio applies to
+// unset fields filled in so it can be compared to the saved query returned by Humio
+func SavedQueryTransform(hsq *humiov1alpha1.HumioSavedQuery) *SavedQuery {
+	savedQuery := &SavedQuery{
+		ID:          hsq.Status.ID,
+		Name:        hsq.Spec.Name,
+		Description: hsq.Spec.Description,
+		QueryString: hsq.Spec.QueryString,
+		Start:       hsq.Spec.Start,
+		End:         hsq.Spec.End,
+		IsLive:      hsq.Spec.IsLive,
+	}
+	if savedQuery.Start == "" {
+		savedQuery.Start = "24h"
+	}
+	if savedQuery.End == "" {
+		savedQuery.End = "now"
+	}
+	if len(hsq.Spec.Arguments) > 0 {
+		savedQuery.Arguments = make(map[string]string, len(hsq.Spec.Arguments))
+		for k, v := range hsq.Spec.Arguments {
+			savedQuery.Arguments[k] = v
+		}
+	}
+	return savedQuery
+}
@@ -0,0 +1,31 @@
+package humio
+
+import (
+	humiov1alpha1 "github.com/humio/humio-operator/api/v1alpha1"
+)
+
+// ScheduledSearchTransform returns the scheduled search described by the HumioScheduledSearch, with the defaults
+// Humio applies to unset fields filled in so it can be compared to the scheduled search returned by Humio
+func ScheduledSearchTransform(hss *humiov1alpha1.HumioScheduledSearch) *ScheduledSearch {
+	scheduledSearch := &ScheduledSearch{
+		ID:            hss.Status.ID,
+		Name:          hss.Spec.Name,
+		Description:   hss.Spec.Description,
+		QueryString:   hss.Spec.QueryString,
+		QueryStart:    hss.Spec.QueryStart,
+		QueryEnd:      hss.Spec.QueryEnd,
+		Schedule:      hss.Spec.Schedule,
+		TimeZone:      hss.Spec.TimeZone,
+		BackfillLimit: hss.Spec.BackfillLimit,
+		Enabled:       !hss.Spec.Silenced,
+		Actions:       copyStrings(hss.Spec.Actions),
+		Labels:        copyStrings(hss.Spec.Labels),
+	}
+	if scheduledSearch.QueryEnd == "" {
+		scheduledSearch.QueryEnd = "now"
+	}
+	if scheduledSearch.TimeZone == "" {
+		scheduledSearch.TimeZone = "UTC"
+	}
+	return scheduledSearch
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package humio
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// searchDomainRepository and searchDomainView are the GraphQL type names of the search domains of a Humio cluster
+	searchDomainRepository = "Repository"
+	searchDomainView       = "View"
+)
+
+// searchDomains holds the names of the repositories and views of a single Humio cluster by their type name
+type searchDomains struct {
+	listed time.Time
+	names  map[string]map[string]struct{}
+}
+
+// searchDomainIndex holds the names of all repositories and views of each Humio cluster, so looking up a repository or
+// view does not require listing all of them. The index of a cluster is built by listing its search domains once, is
+// kept up to date from the changes the operator makes, and is rebuilt after the resync period to pick up changes made
+// outside the operator. A nil searchDomainIndex is valid and never has any entries.
+type searchDomainIndex struct {
+	resyncPeriod time.Duration
+	mutex        sync.Mutex
+	clusters     map[string]*searchDomains
+}
+
+func newSearchDomainIndex(resyncPeriod time.Duration) *searchDomainIndex {
+	if resyncPeriod <= 0 {
+		return nil
+	}
+	return &searchDomainIndex{
+		resyncPeriod: resyncPeriod,
+		clusters:     map[string]*searchDomains{},
+	}
+}
+
+// indexed returns true if the search domains of the cluster have been listed within the resync period
+func (i *searchDomainIndex) indexed(cluster string) bool {
+	if i == nil {
+		return false
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	domains, ok := i.clusters[cluster]
+	return ok && time.Since(domains.listed) < i.resyncPeriod
+}
+
+// exists returns whether the cluster has a search domain of the given type with the given name. It returns false if
+// the search domains of the cluster are not indexed.
+func (i *searchDomainIndex) exists(cluster, typename, name string) bool {
+	if i == nil {
+		return false
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	domains, ok := i.clusters[cluster]
+	if !ok {
+		return false
+	}
+	_, found := domains.names[typename][name]
+	return found
+}
+
+// set replaces the index of the cluster with the given listing of its search domains
+func (i *searchDomainIndex) set(cluster string, items []humioapi.ViewListItem) {
+	if i == nil {
+		return
+	}
+	domains := &searchDomains{
+		listed: time.Now(),
+		names: map[string]map[string]struct{}{
+			searchDomainRepository: {},
+			searchDomainView:       {},
+		},
+	}
+	for _, item := range items {
+		if _, ok := domains.names[item.Typename]; ok {
+			domains.names[item.Typename][item.Name] = struct{}{}
+		}
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.clusters[cluster] = domains
+}
+
+// add records that the operator created the search domain, if the cluster is indexed
+func (i *searchDomainIndex) add(cluster, typename, name string) {
+	if i == nil {
+		return
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if domains, ok := i.clusters[cluster]; ok {
+		domains.names[typename][name] = struct{}{}
+	}
+}
+
+// remove records that the search domain no longer exists, if the cluster is indexed
+func (i *searchDomainIndex) remove(cluster, typename, name string) {
+	if i == nil {
+		return
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if domains, ok := i.clusters[cluster]; ok {
+		delete(domains.names[typename], name)
+	}
+}
+
+// invalidateCluster removes the index of the given Humio cluster, so it is rebuilt on the next lookup
+func (i *searchDomainIndex) invalidateCluster(cluster string) {
+	if i == nil {
+		return
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	delete(i.clusters, cluster)
+}
+
+// WithSearchDomainIndex makes lookups of repositories and views use an index of the names of all repositories and
+// views of each Humio cluster, instead of listing all of them on every lookup. The index is rebuilt after the given
+// resync period to pick up repositories and views changed outside the operator. A resync period of zero disables the
+// index.
+func (h *ClientConfig) WithSearchDomainIndex(resyncPeriod time.Duration) *ClientConfig {
+	h.searchDomainIndex = newSearchDomainIndex(resyncPeriod)
+	return h
+}
+
+// PrimeSearchDomainIndex lists the repositories and views of the Humio cluster of the given config and stores them in
+// the index, so the first reconciles of the repositories and views of the cluster do not each list them. It does
+// nothing if the index is disabled or the cluster is already indexed.
+func (h *ClientConfig) PrimeSearchDomainIndex(config *humioapi.Config, req reconcile.Request) error {
+	if h.searchDomainIndex == nil || h.searchDomainIndex.indexed(searchDomainCluster(config)) {
+		return nil
+	}
+	return h.listSearchDomains(config, req)
+}
+
+// searchDomainExists returns whether the Humio cluster has a search domain with the given name and any of the given
+// type names, listing the search domains of the cluster if they are not indexed
+func (h *ClientConfig) searchDomainExists(config *humioapi.Config, req reconcile.Request, name string, typenames ...string) (bool, error) {
+	cluster := searchDomainCluster(config)
+	if !h.searchDomainIndex.indexed(cluster) {
+		if err := h.listSearchDomains(config, req); err != nil {
+			return false, err
+		}
+	}
+	for _, typename := range typenames {
+		if h.searchDomainIndex.exists(cluster, typename, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (h *ClientConfig) listSearchDomains(config *humioapi.Config, req reconcile.Request) error {
+	items, err := h.GetHumioClient(config, req).Views().List()
+	if err != nil {
+		return fmt.Errorf("could not list search domains: %w", err)
+	}
+	h.searchDomainIndex.set(searchDomainCluster(config), items)
+	return nil
+}
+
+func searchDomainCluster(config *humioapi.Config) string {
+	if config.Address == nil {
+		return ""
+	}
+	return config.Address.String()
+}
@@ -0,0 +1,72 @@
+package humio
+
+import (
+	"testing"
+	"time"
+
+	humioapi "github.com/humio/cli/api"
+)
+
+func TestSearchDomainIndex(t *testing.T) {
+	cluster := "https://humio.example.com/"
+	otherCluster := "https://other.example.com/"
+
+	index := newSearchDomainIndex(time.Hour)
+	index.add(cluster, searchDomainRepository, "logs")
+	if index.indexed(cluster) || index.exists(cluster, searchDomainRepository, "logs") {
+		t.Error("expected additions to be ignored until the cluster is indexed")
+	}
+
+	index.set(cluster, []humioapi.ViewListItem{
+		{Name: "logs", Typename: searchDomainRepository},
+		{Name: "all", Typename: searchDomainView},
+	})
+	if !index.indexed(cluster) {
+		t.Fatal("expected the cluster to be indexed")
+	}
+	if !index.exists(cluster, searchDomainRepository, "logs") || !index.exists(cluster, searchDomainView, "all") {
+		t.Error("expected the listed search domains to be indexed")
+	}
+	if index.exists(cluster, searchDomainView, "logs") {
+		t.Error("expected search domains to be indexed by their type")
+	}
+	if index.indexed(otherCluster) || index.exists(otherCluster, searchDomainRepository, "logs") {
+		t.Error("expected the index to be scoped to a single cluster")
+	}
+
+	index.add(cluster, searchDomainView, "audit")
+	if !index.exists(cluster, searchDomainView, "audit") {
+		t.Error("expected added view to be indexed")
+	}
+	index.remove(cluster, searchDomainRepository, "logs")
+	if index.exists(cluster, searchDomainRepository, "logs") {
+		t.Error("expected removed repository not to be indexed")
+	}
+
+	index.invalidateCluster(cluster)
+	if index.indexed(cluster) || index.exists(cluster, searchDomainView, "all") {
+		t.Error("expected invalidated cluster not to be indexed")
+	}
+
+	index.resyncPeriod = -time.Second
+	index.set(cluster, nil)
+	if index.indexed(cluster) {
+		t.Error("expected the cluster to be listed again after the resync period")
+	}
+}
+
+func TestSearchDomainIndexDisabled(t *testing.T) {
+	cluster := "https://humio.example.com/"
+
+	index := newSearchDomainIndex(0)
+	if index != nil {
+		t.Fatal("expected a resync period of zero to disable the index")
+	}
+	index.set(cluster, []humioapi.ViewListItem{{Name: "logs", Typename: searchDomainRepository}})
+	index.add(cluster, searchDomainRepository, "logs")
+	if index.indexed(cluster) || index.exists(cluster, searchDomainRepository, "logs") {
+		t.Error("expected a disabled index to never have any entries")
+	}
+	index.remove(cluster, searchDomainRepository, "logs")
+	index.invalidateCluster(cluster)
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	// FieldManager is the field manager the operator uses when applying objects with server-side apply
+	FieldManager = "humio-operator"
+	// legacyFieldManager is the field manager of the fields set by versions of the operator which created and updated
+	// objects without server-side apply. It is derived from the name of the operator binary.
+	legacyFieldManager = "manager"
+)
+
+// ApplyConflictError is returned when applying an object would change fields managed by someone else, such as a user
+// or another controller. The object is left unchanged.
+type ApplyConflictError struct {
+	Kind string
+	Name string
+	Err  error
+}
+
+func (e *ApplyConflictError) Error() string {
+	return fmt.Sprintf("%s %s has fields managed by another field manager which differ from what the operator sets, "+
+		"remove the fields from the other field manager or stop setting them: %s", e.Kind, e.Name, e.Err)
+}
+
+func (e *ApplyConflictError) Unwrap() error {
+	return e.Err
+}
+
+// IsApplyConflict returns true if the error is an ApplyConflictError
+func IsApplyConflict(err error) bool {
+	var conflict *ApplyConflictError
+	return errors.As(err, &conflict)
+}
+
+// Apply creates or updates the object using server-side apply. The operator only owns the fields set on obj, so fields
+// added by users or other controllers, such as annotations added by service meshes, are kept, while fields the operator
+// stops setting are removed. If obj sets a field managed by someone else to a different value, an ApplyConflictError
+// is returned instead of overwriting the field.
+//
+// obj must be the complete desired object, as fields the operator applied earlier but which are not set on obj are
+// removed from the object.
+func Apply(ctx context.Context, c client.Client, obj client.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+
+	existing, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return fmt.Errorf("%T is not a client.Object", obj)
+	}
+	err = c.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if k8serrors.IsNotFound(err) {
+		return c.Create(ctx, obj, client.FieldOwner(FieldManager))
+	}
+	if err != nil {
+		return err
+	}
+
+	// Fields the operator set when creating the object, or set using updates before it used server-side apply, are
+	// handed over to the field manager used for applying, so they can be removed when the operator stops setting them
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(existing, sets.New(legacyFieldManager, FieldManager), FieldManager)
+	if err != nil {
+		return fmt.Errorf("unable to upgrade managed fields of %s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	if patch != nil {
+		if err := c.Patch(ctx, existing, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+			return fmt.Errorf("unable to upgrade managed fields of %s %s: %w", gvk.Kind, obj.GetName(), err)
+		}
+	}
+
+	err = c.Patch(ctx, obj, client.Apply, client.FieldOwner(FieldManager))
+	if k8serrors.IsConflict(err) {
+		return &ApplyConflictError{Kind: gvk.Kind, Name: obj.GetName(), Err: err}
+	}
+	return err
+}
+
+// ApplyLabels sets the given labels on the object using server-side apply, keeping all other labels of the object. This
+// must only be used for objects which are not applied using Apply, as the fields applied by Apply would be removed.
+func ApplyLabels(ctx context.Context, c client.Client, obj client.Object, labels map[string]string) error {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return err
+	}
+	// Only the labels are sent, as typed objects would also include empty fields, such as the containers of pods
+	partial := &unstructured.Unstructured{}
+	partial.SetGroupVersionKind(gvk)
+	partial.SetNamespace(obj.GetNamespace())
+	partial.SetName(obj.GetName())
+	partial.SetLabels(labels)
+
+	// Labels the operator set using updates before it used server-side apply are handed over to the field manager used
+	// for applying, as they would otherwise conflict with the labels being applied
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(gvk)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		return err
+	}
+	patch, err := upgradeLabelManagedFieldsPatch(existing, labels)
+	if err != nil {
+		return fmt.Errorf("unable to upgrade managed fields of %s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	if patch != nil {
+		if err := c.Patch(ctx, existing, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+			return fmt.Errorf("unable to upgrade managed fields of %s %s: %w", gvk.Kind, obj.GetName(), err)
+		}
+	}
+
+	err = c.Patch(ctx, partial, client.Apply, client.FieldOwner(FieldManager))
+	if k8serrors.IsConflict(err) {
+		return &ApplyConflictError{Kind: gvk.Kind, Name: obj.GetName(), Err: err}
+	}
+	if err != nil {
+		return err
+	}
+	obj.SetLabels(partial.GetLabels())
+	obj.SetResourceVersion(partial.GetResourceVersion())
+	return nil
+}
+
+// upgradeLabelManagedFieldsPatch returns a JSON patch which hands the given labels over from the legacy field manager
+// to the field manager used for applying, or nil if the legacy field manager owns none of them. Unlike in Apply, the
+// other fields of the legacy field manager are left alone, as ApplyLabels only applies labels and would remove any
+// other fields handed over to the field manager used for applying.
+func upgradeLabelManagedFieldsPatch(existing client.Object, labels map[string]string) ([]byte, error) {
+	var managedFields []metav1.ManagedFieldsEntry
+	moved := map[string]interface{}{}
+	applyEntry := -1
+	for _, entry := range existing.GetManagedFields() {
+		if entry.Manager == FieldManager && entry.Operation == metav1.ManagedFieldsOperationApply && entry.Subresource == "" {
+			applyEntry = len(managedFields)
+		}
+		if entry.Manager != legacyFieldManager || entry.Operation != metav1.ManagedFieldsOperationUpdate ||
+			entry.Subresource != "" || entry.FieldsV1 == nil {
+			managedFields = append(managedFields, entry)
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			return nil, err
+		}
+		metadata, _ := fields["f:metadata"].(map[string]interface{})
+		labelFields, _ := metadata["f:labels"].(map[string]interface{})
+		for key := range labels {
+			if field, ok := labelFields["f:"+key]; ok {
+				moved["f:"+key] = field
+				delete(labelFields, "f:"+key)
+			}
+		}
+		if len(labelFields) == 0 {
+			delete(metadata, "f:labels")
+		}
+		if len(metadata) == 0 {
+			delete(fields, "f:metadata")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		raw, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		entry.FieldsV1 = &metav1.FieldsV1{Raw: raw}
+		managedFields = append(managedFields, entry)
+	}
+	if len(moved) == 0 {
+		return nil, nil
+	}
+
+	fields := map[string]interface{}{}
+	if applyEntry == -1 {
+		now := metav1.Now()
+		managedFields = append(managedFields, metav1.ManagedFieldsEntry{
+			Manager:    FieldManager,
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: existing.GetObjectKind().GroupVersionKind().GroupVersion().String(),
+			Time:       &now,
+			FieldsType: "FieldsV1",
+		})
+		applyEntry = len(managedFields) - 1
+	} else if managedFields[applyEntry].FieldsV1 != nil {
+		if err := json.Unmarshal(managedFields[applyEntry].FieldsV1.Raw, &fields); err != nil {
+			return nil, err
+		}
+	}
+	metadata, ok := fields["f:metadata"].(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+		fields["f:metadata"] = metadata
+	}
+	labelFields, ok := metadata["f:labels"].(map[string]interface{})
+	if !ok {
+		labelFields = map[string]interface{}{}
+		metadata["f:labels"] = labelFields
+	}
+	for key, field := range moved {
+		labelFields[key] = field
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	managedFields[applyEntry].FieldsV1 = &metav1.FieldsV1{Raw: raw}
+
+	// The resource version is tested, so the managed fields are not replaced if the object changed in the meantime
+	return json.Marshal([]map[string]interface{}{
+		{"op": "test", "path": "/metadata/resourceVersion", "value": existing.GetResourceVersion()},
+		{"op": "replace", "path": "/metadata/managedFields", "value": managedFields},
+	})
+}
@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApply(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "humio"}
+
+	desired := func(selector string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, Labels: map[string]string{"app.kubernetes.io/name": "humio"}},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"humio.com/node-pool": selector},
+				Ports:    []corev1.ServicePort{{Name: "http", Port: 8080}},
+			},
+		}
+	}
+	if err := Apply(ctx, c, desired("humio")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fields added by others, and the fields the operator set using updates before it used server-side apply
+	var service corev1.Service
+	if err := c.Get(ctx, key, &service); err != nil {
+		t.Fatal(err)
+	}
+	service.Annotations = map[string]string{"mesh.example.com/inject": "true"}
+	service.ManagedFields = []metav1.ManagedFieldsEntry{{
+		Manager:    legacyFieldManager,
+		Operation:  metav1.ManagedFieldsOperationUpdate,
+		APIVersion: "v1",
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:selector":{}}}`)},
+	}}
+	if err := c.Update(ctx, &service); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Apply(ctx, c, desired("ingest")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, key, &service); err != nil {
+		t.Fatal(err)
+	}
+	if service.Spec.Selector["humio.com/node-pool"] != "ingest" {
+		t.Errorf("expected the selector to be updated, got %v", service.Spec.Selector)
+	}
+	if service.Annotations["mesh.example.com/inject"] != "true" {
+		t.Errorf("expected annotations added by others to be kept, got %v", service.Annotations)
+	}
+	if len(service.ManagedFields) != 1 || service.ManagedFields[0].Manager != FieldManager || service.ManagedFields[0].Operation != metav1.ManagedFieldsOperationApply {
+		t.Errorf("expected the fields of the legacy field manager to be handed over, got %+v", service.ManagedFields)
+	}
+
+	if err := ApplyLabels(ctx, c, &service, map[string]string{"humio.com/node-id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, key, &service); err != nil {
+		t.Fatal(err)
+	}
+	if service.Labels["humio.com/node-id"] != "1" || service.Labels["app.kubernetes.io/name"] != "humio" {
+		t.Errorf("expected the label to be added to the existing labels, got %v", service.Labels)
+	}
+	if service.Spec.Selector["humio.com/node-pool"] != "ingest" {
+		t.Errorf("expected the spec to be kept when applying labels, got %v", service.Spec.Selector)
+	}
+}
+
+func TestApplyLabelsUpgradesLegacyLabels(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "humio-core-abcdef"}
+
+	// A pod labeled by a version of the operator which did not use server-side apply
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      key.Name,
+		Namespace: key.Namespace,
+		Labels:    map[string]string{"humio.com/node-id": "1", "app.kubernetes.io/name": "humio"},
+		ManagedFields: []metav1.ManagedFieldsEntry{{
+			Manager:    legacyFieldManager,
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{".":{},"f:app.kubernetes.io/name":{},"f:humio.com/node-id":{}}},"f:spec":{"f:nodeName":{}}}`)},
+		}},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+
+	if err := ApplyLabels(ctx, c, pod, map[string]string{"humio.com/node-id": "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, key, pod); err != nil {
+		t.Fatal(err)
+	}
+	if pod.Labels["humio.com/node-id"] != "2" || pod.Labels["app.kubernetes.io/name"] != "humio" {
+		t.Errorf("expected the label to be updated and the other labels kept, got %v", pod.Labels)
+	}
+	fields := map[string]string{}
+	for _, entry := range pod.ManagedFields {
+		fields[entry.Manager+"/"+string(entry.Operation)] = string(entry.FieldsV1.Raw)
+	}
+	legacy := fields[legacyFieldManager+"/"+string(metav1.ManagedFieldsOperationUpdate)]
+	if strings.Contains(legacy, "humio.com/node-id") || !strings.Contains(legacy, "f:app.kubernetes.io/name") || !strings.Contains(legacy, "f:nodeName") {
+		t.Errorf("expected only the applied label to be taken from the legacy field manager, got %s", legacy)
+	}
+	if applied := fields[FieldManager+"/"+string(metav1.ManagedFieldsOperationApply)]; !strings.Contains(applied, "f:humio.com/node-id") {
+		t.Errorf("expected the applied label to be handed over, got %s", applied)
+	}
+}
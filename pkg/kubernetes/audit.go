@@ -0,0 +1,135 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	auditOperationCreate      = "create"
+	auditOperationUpdate      = "update"
+	auditOperationPatch       = "patch"
+	auditOperationDelete      = "delete"
+	auditOperationDeleteAllOf = "deletecollection"
+
+	auditResultSuccess = "success"
+	auditResultError   = "error"
+
+	// AuditActor identifies the operator as the actor of the changes in audit records
+	AuditActor = "humio-operator"
+)
+
+// AuditRecord describes a single change the operator performed against a Kubernetes object
+type AuditRecord struct {
+	Time            time.Time `json:"time"`
+	Actor           string    `json:"actor"`
+	APIVersion      string    `json:"apiVersion"`
+	Kind            string    `json:"kind"`
+	Namespace       string    `json:"namespace"`
+	Name            string    `json:"name"`
+	Operation       string    `json:"operation"`
+	ResourceVersion string    `json:"resourceVersion,omitempty"`
+	Result          string    `json:"result"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// AuditSink receives an AuditRecord for every change the operator performs against Kubernetes objects. Write must not
+// block for long, as it is called as part of the reconcile performing the change.
+type AuditSink interface {
+	Write(AuditRecord)
+}
+
+// AuditedClient wraps a client.Client and logs an audit record for every call that creates, updates or deletes a
+// Kubernetes object. Records are also passed to any configured sinks. Updates of the status subresource are not
+// audited, as they only report what the operator observed.
+type AuditedClient struct {
+	client.Client
+	logger logr.Logger
+	sinks  []AuditSink
+}
+
+// NewAuditedClient returns a client.Client which audits all changes performed through the given client
+func NewAuditedClient(c client.Client, logger logr.Logger, sinks ...AuditSink) *AuditedClient {
+	return &AuditedClient{
+		Client: c,
+		logger: logger.WithName("kubernetes-audit"),
+		sinks:  sinks,
+	}
+}
+
+func (c *AuditedClient) audit(obj client.Object, operation string, err error) {
+	record := AuditRecord{
+		Time:            time.Now().UTC(),
+		Actor:           AuditActor,
+		Namespace:       obj.GetNamespace(),
+		Name:            obj.GetName(),
+		Operation:       operation,
+		ResourceVersion: obj.GetResourceVersion(),
+		Result:          auditResultSuccess,
+	}
+	if gvk, gvkErr := apiutil.GVKForObject(obj, c.Scheme()); gvkErr == nil {
+		record.APIVersion = gvk.GroupVersion().String()
+		record.Kind = gvk.Kind
+	}
+	if err != nil {
+		record.Result = auditResultError
+		record.Error = err.Error()
+	}
+
+	c.logger.Info(fmt.Sprintf("%s %s %s/%s: %s", operation, record.Kind, record.Namespace, record.Name, record.Result),
+		"Audit.APIVersion", record.APIVersion, "Audit.ResourceVersion", record.ResourceVersion, "Audit.Error", record.Error)
+	for _, sink := range c.sinks {
+		sink.Write(record)
+	}
+}
+
+func (c *AuditedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	err := c.Client.Create(ctx, obj, opts...)
+	c.audit(obj, auditOperationCreate, err)
+	return err
+}
+
+func (c *AuditedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	err := c.Client.Update(ctx, obj, opts...)
+	c.audit(obj, auditOperationUpdate, err)
+	return err
+}
+
+func (c *AuditedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	c.audit(obj, auditOperationPatch, err)
+	return err
+}
+
+func (c *AuditedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	err := c.Client.Delete(ctx, obj, opts...)
+	c.audit(obj, auditOperationDelete, err)
+	return err
+}
+
+func (c *AuditedClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	err := c.Client.DeleteAllOf(ctx, obj, opts...)
+	c.audit(obj, auditOperationDeleteAllOf, err)
+	return err
+}
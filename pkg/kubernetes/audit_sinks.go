@@ -0,0 +1,137 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const auditSinkBufferSize = 1000
+
+// auditSink writes audit records in the background, so an unavailable sink never blocks reconciles. Records are
+// dropped and logged if the buffer is full.
+type auditSink struct {
+	logger  logr.Logger
+	records chan AuditRecord
+	send    func(AuditRecord) error
+}
+
+func newAuditSink(logger logr.Logger, send func(AuditRecord) error) *auditSink {
+	s := &auditSink{
+		logger:  logger,
+		records: make(chan AuditRecord, auditSinkBufferSize),
+		send:    send,
+	}
+	go s.run()
+	return s
+}
+
+// Write queues the record
+func (s *auditSink) Write(record AuditRecord) {
+	select {
+	case s.records <- record:
+	default:
+		s.logger.Info("dropping audit record as the buffer is full", "Audit.Kind", record.Kind,
+			"Audit.Namespace", record.Namespace, "Audit.Name", record.Name, "Audit.Operation", record.Operation)
+	}
+}
+
+func (s *auditSink) run() {
+	for record := range s.records {
+		if err := s.send(record); err != nil {
+			s.logger.Error(err, "unable to write audit record", "Audit.Kind", record.Kind,
+				"Audit.Namespace", record.Namespace, "Audit.Name", record.Name, "Audit.Operation", record.Operation)
+		}
+	}
+}
+
+// NewEventAuditSink returns a sink which records audit records as Events in the given namespace. The Events refer to
+// the namespace, and relate to the changed object. The client must not be audited itself.
+func NewEventAuditSink(logger logr.Logger, c client.Client, namespace string) AuditSink {
+	return newAuditSink(logger.WithName("kubernetes-audit-events"), func(record AuditRecord) error {
+		return c.Create(context.Background(), auditEvent(record, namespace))
+	})
+}
+
+// auditEvent returns the Event recording the audit record in the given namespace
+func auditEvent(record AuditRecord, namespace string) *corev1.Event {
+	message, _ := json.Marshal(record)
+	eventType := corev1.EventTypeNormal
+	if record.Result != auditResultSuccess {
+		eventType = corev1.EventTypeWarning
+	}
+	eventTime := metav1.NewTime(record.Time)
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "humio-operator-audit-",
+			Namespace:    namespace,
+			Labels:       map[string]string{"app.kubernetes.io/managed-by": AuditActor},
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+			Name:       namespace,
+		},
+		Related: &corev1.ObjectReference{
+			APIVersion:      record.APIVersion,
+			Kind:            record.Kind,
+			Namespace:       record.Namespace,
+			Name:            record.Name,
+			ResourceVersion: record.ResourceVersion,
+		},
+		Reason:              fmt.Sprintf("Operator%s", strings.ToUpper(record.Operation[:1])+record.Operation[1:]),
+		Action:              record.Operation,
+		Message:             string(message),
+		Type:                eventType,
+		FirstTimestamp:      eventTime,
+		LastTimestamp:       eventTime,
+		Count:               1,
+		Source:              corev1.EventSource{Component: AuditActor},
+		ReportingController: AuditActor,
+	}
+}
+
+// NewWebhookAuditSink returns a sink which posts every audit record as JSON to the given URL
+func NewWebhookAuditSink(logger logr.Logger, url string) AuditSink {
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	return newAuditSink(logger.WithName("kubernetes-audit-webhook"), func(record AuditRecord) error {
+		body, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		resp, err := httpClient.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("unexpected response from %s: %s", url, resp.Status)
+		}
+		return nil
+	})
+}
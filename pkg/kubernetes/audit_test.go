@@ -0,0 +1,67 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type recordingAuditSink struct {
+	records []AuditRecord
+}
+
+func (s *recordingAuditSink) Write(record AuditRecord) {
+	s.records = append(s.records, record)
+}
+
+func TestAuditedClient(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	sink := &recordingAuditSink{}
+	c := NewAuditedClient(fake.NewClientBuilder().WithScheme(scheme).Build(), logr.Discard(), sink)
+	ctx := context.Background()
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "humiocluster-admin-token", Namespace: "logging"}}
+	if err := c.Create(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(secret), secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, secret); err == nil {
+		t.Fatal("expected deleting a missing secret to fail")
+	}
+
+	expected := []struct{ operation, result string }{
+		{auditOperationCreate, auditResultSuccess},
+		{auditOperationDelete, auditResultSuccess},
+		{auditOperationDelete, auditResultError},
+	}
+	if len(sink.records) != len(expected) {
+		t.Fatalf("expected %d audit records, got %+v", len(expected), sink.records)
+	}
+	for idx, e := range expected {
+		record := sink.records[idx]
+		if record.Operation != e.operation || record.Result != e.result {
+			t.Errorf("expected record %d to be %s with result %s, got %+v", idx, e.operation, e.result, record)
+		}
+		if record.Actor != AuditActor || record.APIVersion != "v1" || record.Kind != "Secret" || record.Namespace != "logging" || record.Name != "humiocluster-admin-token" {
+			t.Errorf("expected record %d to describe the secret, got %+v", idx, record)
+		}
+	}
+
+	event := auditEvent(sink.records[0], "humio-audit")
+	if event.Namespace != "humio-audit" || event.InvolvedObject.Name != "humio-audit" || event.Related.Name != "humiocluster-admin-token" || event.Reason != "OperatorCreate" {
+		t.Errorf("expected an event in the audit namespace relating to the secret, got %+v", event)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+var (
+	errNotFound = errors.New("not found")
+
+	// ErrNoSignature is returned when an image has no signature which is valid for the public key
+	ErrNoSignature = errors.New("no valid signature found")
+)
+
+// cosignManifest is the part of the OCI manifest holding the signatures of an image, as pushed by cosign
+type cosignManifest struct {
+	Layers []struct {
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// cosignPayload is the simple signing payload signed by cosign
+type cosignPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// ParsePublicKey parses a PEM encoded public key, as generated by cosign generate-key-pair
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse public key: %w", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// VerifySignature verifies that the image with the given digest has a cosign signature made with the private key
+// belonging to the public key. Signatures are looked up using the tag convention of cosign, so only signatures stored
+// in the repository of the image are found. ErrNoSignature is returned if the image is not signed with the key.
+func (c *Client) VerifySignature(ctx context.Context, ref Reference, digest string, publicKey crypto.PublicKey) error {
+	algorithm, hex, found := strings.Cut(digest, ":")
+	if !found {
+		return fmt.Errorf("invalid digest %q", digest)
+	}
+	manifestData, err := c.getManifest(ctx, ref, fmt.Sprintf("%s-%s.sig", algorithm, hex))
+	if errors.Is(err, errNotFound) {
+		return ErrNoSignature
+	}
+	if err != nil {
+		return fmt.Errorf("could not get signatures of %s: %w", ref.Repository, err)
+	}
+	var manifest cosignManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return fmt.Errorf("could not parse signatures of %s: %w", ref.Repository, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		encodedSignature, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+		if err != nil {
+			continue
+		}
+		payload, err := c.getBlob(ctx, ref, layer.Digest)
+		if err != nil {
+			return fmt.Errorf("could not get signature payload of %s: %w", ref.Repository, err)
+		}
+		if !verify(publicKey, payload, signature) {
+			continue
+		}
+		var p cosignPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			continue
+		}
+		// The signature must be for the image itself, and not just be a signature copied from another image
+		if p.Critical.Image.DockerManifestDigest == digest {
+			return nil
+		}
+	}
+	return ErrNoSignature
+}
+
+func verify(publicKey crypto.PublicKey, payload, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, hash[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, signature)
+	default:
+		return false
+	}
+}
+
+// CredentialsFromDockerConfig returns the credentials for each registry in a .dockerconfigjson, as stored in image
+// pull secrets
+func CredentialsFromDockerConfig(data []byte, credentials map[string]Credentials) error {
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("could not parse docker config: %w", err)
+	}
+	for server, auth := range config.Auths {
+		c := Credentials{Username: auth.Username, Password: auth.Password}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return fmt.Errorf("could not decode credentials for %s: %w", server, err)
+			}
+			c.Username, c.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		credentials[registryFromServer(server)] = c
+	}
+	return nil
+}
+
+// registryFromServer returns the registry of a server in a docker config, which may be a URL
+func registryFromServer(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	server, _, _ = strings.Cut(server, "/")
+	if server == "index.docker.io" || server == dockerHubHost {
+		return dockerHubRegistry
+	}
+	return server
+}
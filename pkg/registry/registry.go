@@ -0,0 +1,297 @@
+/*
+Copyright 2020 Humio https://humio.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	dockerHubRegistry = "docker.io"
+	dockerHubHost     = "registry-1.docker.io"
+
+	maxManifestSize = 4 << 20
+)
+
+// manifestMediaTypes are the manifest media types accepted when resolving an image, so the digest of multi-platform
+// images refers to the index rather than the manifest of a single platform
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed image reference
+type Reference struct {
+	// Registry is the registry of the image, e.g. docker.io
+	Registry string
+	// Repository is the repository of the image within the registry, e.g. humio/humio-core
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an image reference such as humio/humio-core:1.100.0 or
+// registry.example.com/humio-core@sha256:.... Images without a registry are pulled from Docker Hub, and images without
+// a tag or digest refer to the latest tag.
+func ParseReference(image string) (Reference, error) {
+	var ref Reference
+	name, digest, found := strings.Cut(image, "@")
+	if found {
+		if !strings.HasPrefix(digest, "sha256:") || len(digest) != len("sha256:")+64 {
+			return Reference{}, fmt.Errorf("invalid digest in image reference %q", image)
+		}
+		ref.Digest = digest
+	}
+
+	ref.Registry = dockerHubRegistry
+	if registry, remainder, found := strings.Cut(name, "/"); found && (strings.ContainsAny(registry, ".:") || registry == "localhost") {
+		ref.Registry = registry
+		name = remainder
+	}
+	if idx := strings.LastIndex(name, ":"); idx != -1 && !strings.Contains(name[idx:], "/") {
+		ref.Tag = name[idx+1:]
+		name = name[:idx]
+	}
+	if name == "" {
+		return Reference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+	if ref.Registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.Repository = name
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref, nil
+}
+
+// host returns the host serving the registry API
+func (r Reference) host() string {
+	if r.Registry == dockerHubRegistry {
+		return dockerHubHost
+	}
+	return r.Registry
+}
+
+// Credentials are the credentials used to authenticate towards a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client talks to OCI registries using the distribution API
+type Client struct {
+	httpClient *http.Client
+	// credentials holds the credentials to use for each registry
+	credentials map[string]Credentials
+}
+
+// NewClient returns a client using the given credentials for each registry. Registries without credentials are
+// accessed anonymously.
+func NewClient(credentials map[string]Credentials) *Client {
+	return &Client{
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		credentials: credentials,
+	}
+}
+
+// Digest returns the digest of the manifest the reference points to. References which already contain a digest are
+// not resolved.
+func (c *Client) Digest(ctx context.Context, ref Reference) (string, error) {
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+	resp, err := c.get(ctx, ref, http.MethodHead, fmt.Sprintf("manifests/%s", ref.Tag), manifestMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// Registries are not required to return the digest, in which case it is computed from the manifest itself
+	manifest, err := c.getManifest(ctx, ref, ref.Tag)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(manifest)), nil
+}
+
+func (c *Client) getManifest(ctx context.Context, ref Reference, tagOrDigest string) ([]byte, error) {
+	resp, err := c.get(ctx, ref, http.MethodGet, fmt.Sprintf("manifests/%s", tagOrDigest), manifestMediaTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+}
+
+func (c *Client) getBlob(ctx context.Context, ref Reference, digest string) ([]byte, error) {
+	resp, err := c.get(ctx, ref, http.MethodGet, fmt.Sprintf("blobs/%s", digest), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	blob, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return nil, err
+	}
+	if actual := fmt.Sprintf("sha256:%x", sha256.Sum256(blob)); actual != digest {
+		return nil, fmt.Errorf("blob %s of %s has digest %s", digest, ref.Repository, actual)
+	}
+	return blob, nil
+}
+
+// get performs a request against the repository of the reference, authenticating using a bearer token if the
+// registry asks for one. A 404 is returned as errNotFound.
+func (c *Client) get(ctx context.Context, ref Reference, method, path string, accept []string) (*http.Response, error) {
+	scheme := "https"
+	if strings.HasPrefix(ref.host(), "localhost") || strings.HasPrefix(ref.host(), "127.0.0.1") {
+		scheme = "http"
+	}
+	requestURL := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, ref.host(), ref.Repository, path)
+
+	resp, err := c.do(ctx, method, requestURL, accept, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		authorization, err := c.authorize(ctx, ref, challenge)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = c.do(ctx, method, requestURL, accept, authorization)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, errNotFound
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response from %s: %s", requestURL, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method, requestURL string, accept []string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return c.httpClient.Do(req)
+}
+
+// authorize returns the Authorization header answering the challenge of the registry
+func (c *Client) authorize(ctx context.Context, ref Reference, challenge string) (string, error) {
+	credentials, hasCredentials := c.credentials[ref.Registry]
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasCredentials {
+			return "", fmt.Errorf("registry %s requires credentials", ref.Registry)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("registry %s returned an unsupported authentication challenge %q", ref.Registry, challenge)
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("registry %s returned an invalid authentication challenge %q", ref.Registry, challenge)
+	}
+	query := tokenURL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", ref.Repository)
+	}
+	query.Set("scope", scope)
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if hasCredentials {
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to get token for %s from %s: %s", ref.Repository, tokenURL.Host, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("unable to decode token for %s from %s: %w", ref.Repository, tokenURL.Host, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return fmt.Sprintf("Bearer %s", token.Token), nil
+}
+
+// parseChallenge parses a WWW-Authenticate header such as Bearer realm="https://auth.docker.io/token",service="x"
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return scheme, params
+}
@@ -0,0 +1,177 @@
+package registry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	tt := []struct {
+		image    string
+		expected Reference
+	}{
+		{"humio/humio-core:1.100.0", Reference{Registry: "docker.io", Repository: "humio/humio-core", Tag: "1.100.0"}},
+		{"ubuntu", Reference{Registry: "docker.io", Repository: "library/ubuntu", Tag: "latest"}},
+		{"registry.example.com:5000/humio/humio-core:1.100.0", Reference{Registry: "registry.example.com:5000", Repository: "humio/humio-core", Tag: "1.100.0"}},
+		{"localhost/humio-core@" + digest, Reference{Registry: "localhost", Repository: "humio-core", Digest: digest}},
+		{"humio/humio-core:1.100.0@" + digest, Reference{Registry: "docker.io", Repository: "humio/humio-core", Tag: "1.100.0", Digest: digest}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.image, func(t *testing.T) {
+			ref, err := ParseReference(tc.image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ref != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, ref)
+			}
+		})
+	}
+	if _, err := ParseReference("humio/humio-core@sha256:abc"); err == nil {
+		t.Error("expected an invalid digest to be rejected")
+	}
+}
+
+// fakeRegistry serves manifests and blobs of a single repository, and requires a bearer token for all requests
+type fakeRegistry struct {
+	manifests map[string][]byte
+	blobs     map[string][]byte
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		if r.URL.Query().Get("scope") != "repository:humio/humio-core:pull" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="fake"`, r.Host))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var content []byte
+	var found bool
+	if reference, ok := strings.CutPrefix(r.URL.Path, "/v2/humio/humio-core/manifests/"); ok {
+		content, found = f.manifests[reference]
+		if found {
+			w.Header().Set("Docker-Content-Digest", fmt.Sprintf("sha256:%x", sha256.Sum256(content)))
+		}
+	}
+	if digest, ok := strings.CutPrefix(r.URL.Path, "/v2/humio/humio-core/blobs/"); ok {
+		content, found = f.blobs[digest]
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(content)
+	}
+}
+
+func (f *fakeRegistry) sign(t *testing.T, key *ecdsa.PrivateKey, digest, signedDigest string) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"humio/humio-core"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, signedDigest))
+	hash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloadDigest := fmt.Sprintf("sha256:%x", hash)
+	f.blobs[payloadDigest] = payload
+	f.manifests[strings.Replace(digest, ":", "-", 1)+".sig"] = []byte(fmt.Sprintf(`{"schemaVersion":2,"layers":[{"digest":%q,"annotations":{%q:%q}}]}`,
+		payloadDigest, cosignSignatureAnnotation, base64.StdEncoding.EncodeToString(signature)))
+}
+
+func TestVerifySignature(t *testing.T) {
+	manifest := []byte(`{"schemaVersion":2}`)
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+	registry := &fakeRegistry{
+		manifests: map[string][]byte{"1.100.0": manifest, digest: manifest},
+		blobs:     map[string][]byte{},
+	}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	c := NewClient(nil)
+	ref, err := ParseReference(fmt.Sprintf("%s/humio/humio-core:1.100.0", strings.TrimPrefix(server.URL, "http://")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := c.Digest(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != digest {
+		t.Fatalf("expected digest %s, got %s", digest, resolved)
+	}
+
+	if err := c.VerifySignature(ctx, ref, digest, publicKey); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("expected an unsigned image to be rejected, got %v", err)
+	}
+
+	otherDigest := "sha256:" + strings.Repeat("b", 64)
+	registry.sign(t, key, digest, otherDigest)
+	if err := c.VerifySignature(ctx, ref, digest, publicKey); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("expected a signature of another image to be rejected, got %v", err)
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.sign(t, otherKey, digest, digest)
+	if err := c.VerifySignature(ctx, ref, digest, publicKey); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("expected a signature made with another key to be rejected, got %v", err)
+	}
+
+	registry.sign(t, key, digest, digest)
+	if err := c.VerifySignature(ctx, ref, digest, publicKey); err != nil {
+		t.Errorf("expected the signature to be valid, got %v", err)
+	}
+}
+
+func TestCredentialsFromDockerConfig(t *testing.T) {
+	config := fmt.Sprintf(`{"auths":{"https://index.docker.io/v1/":{"auth":%q},"registry.example.com":{"username":"user","password":"pass"}}}`,
+		base64.StdEncoding.EncodeToString([]byte("humio:token")))
+	credentials := map[string]Credentials{}
+	if err := CredentialsFromDockerConfig([]byte(config), credentials); err != nil {
+		t.Fatal(err)
+	}
+	if credentials["docker.io"] != (Credentials{Username: "humio", Password: "token"}) {
+		t.Errorf("unexpected credentials for docker.io: %+v", credentials["docker.io"])
+	}
+	if credentials["registry.example.com"] != (Credentials{Username: "user", Password: "pass"}) {
+		t.Errorf("unexpected credentials for registry.example.com: %+v", credentials["registry.example.com"])
+	}
+}